@@ -1133,6 +1133,48 @@ func main() {
 				},
 			},
 		},
+		{
+			Name:    "test",
+			Aliases: []string{"test"},
+			Usage:   "test rule -f $test_case_file",
+			Subcommands: []cli.Command{
+				{
+					Name:  "rule",
+					Usage: "test rule -f $test_case_file",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:     "file, f",
+							Usage:    "the location of the test case file with the rule, inputs and expected outputs",
+							FilePath: "/home/mycase.json",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						sfile := c.String("file")
+						if sfile == "" {
+							fmt.Printf("Expect the test case file.\n")
+							return nil
+						}
+						if !fileExists(sfile) {
+							fmt.Printf("The specified test case file %s is not existed.\n", sfile)
+							return nil
+						}
+						tc, err := os.ReadFile(sfile)
+						if err != nil {
+							fmt.Printf("Failed to read from test case file %s.\n", sfile)
+							return nil
+						}
+						var reply string
+						err = client.Call("Server.TestRule", &model.RPCArgDesc{Json: string(tc)}, &reply)
+						if err != nil {
+							fmt.Println(err)
+						} else {
+							fmt.Println(reply)
+						}
+						return nil
+					},
+				},
+			},
+		},
 		{
 			Name:    "register",
 			Aliases: []string{"register"},
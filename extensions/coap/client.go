@@ -0,0 +1,146 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coap
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	piondtls "github.com/pion/dtls/v2"
+	"github.com/plgd-dev/go-coap/v3/dtls"
+	"github.com/plgd-dev/go-coap/v3/message"
+	"github.com/plgd-dev/go-coap/v3/message/codes"
+	"github.com/plgd-dev/go-coap/v3/message/pool"
+	"github.com/plgd-dev/go-coap/v3/udp"
+	"github.com/plgd-dev/go-coap/v3/udp/client"
+
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+var mediaTypes = map[string]message.MediaType{
+	"text/plain":               message.TextPlain,
+	"application/json":         message.AppJSON,
+	"application/cbor":         message.AppCBOR,
+	"application/octet-stream": message.AppOctets,
+}
+
+// ClientConf is the connection configuration shared by the coap source and sink
+type ClientConf struct {
+	// Server is like coap://127.0.0.1:5683 or coaps://127.0.0.1:5684 for DTLS
+	Server string `json:"server"`
+	// PskIdentity and Psk are the DTLS pre-shared key credentials for coaps
+	PskIdentity string `json:"pskIdentity"`
+	Psk         string `json:"psk"`
+	// Timeout in ms of each request
+	Timeout int `json:"timeout"`
+
+	host   string
+	secure bool
+}
+
+func GetClientConf(props map[string]interface{}) (*ClientConf, error) {
+	c := &ClientConf{
+		Timeout: 5000,
+	}
+	if err := cast.MapToStruct(props, c); err != nil {
+		return nil, fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *ClientConf) validate() error {
+	if c.Server == "" {
+		return fmt.Errorf("server is required")
+	}
+	u, err := url.Parse(c.Server)
+	if err != nil {
+		return fmt.Errorf("invalid server %s: %v", c.Server, err)
+	}
+	port := u.Port()
+	switch u.Scheme {
+	case "coap":
+		if port == "" {
+			port = "5683"
+		}
+	case "coaps":
+		if port == "" {
+			port = "5684"
+		}
+		if c.PskIdentity == "" || c.Psk == "" {
+			return fmt.Errorf("pskIdentity and psk are required for coaps")
+		}
+		c.secure = true
+	default:
+		return fmt.Errorf("server must start with coap:// or coaps://")
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("invalid server %s: host is required", c.Server)
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+	c.host = u.Hostname() + ":" + port
+	return nil
+}
+
+// Dial connects to the server, with DTLS if the scheme is coaps
+func (c *ClientConf) Dial() (*client.Conn, error) {
+	var (
+		conn *client.Conn
+		err  error
+	)
+	if c.secure {
+		conn, err = dtls.Dial(c.host, &piondtls.Config{
+			PSK: func(_ []byte) ([]byte, error) {
+				return []byte(c.Psk), nil
+			},
+			PSKIdentityHint: []byte(c.PskIdentity),
+			CipherSuites:    []piondtls.CipherSuiteID{piondtls.TLS_PSK_WITH_AES_128_CCM_8, piondtls.TLS_PSK_WITH_AES_128_GCM_SHA256},
+		})
+	} else {
+		conn, err = udp.Dial(c.host)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fail to connect to %s: %v", c.Server, err)
+	}
+	return conn, nil
+}
+
+// RequestContext returns the context with the request timeout
+func (c *ClientConf) RequestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(c.Timeout)*time.Millisecond)
+}
+
+// MediaType converts the content type name to the coap content format
+func MediaType(contentType string) (message.MediaType, error) {
+	mt, ok := mediaTypes[contentType]
+	if !ok {
+		return 0, fmt.Errorf("unsupported content type %s, must be text/plain, application/json, application/cbor or application/octet-stream", contentType)
+	}
+	return mt, nil
+}
+
+// CheckResponse returns the error if the response code is not success
+func CheckResponse(resp *pool.Message) error {
+	if resp.Code() < codes.Created || resp.Code() >= codes.BadRequest {
+		return fmt.Errorf("response code %v", resp.Code())
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coap
+
+import (
+	"testing"
+
+	"github.com/plgd-dev/go-coap/v3/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetClientConf(t *testing.T) {
+	tests := []struct {
+		name   string
+		props  map[string]interface{}
+		host   string
+		secure bool
+		err    string
+	}{
+		{
+			name:  "default port",
+			props: map[string]interface{}{"server": "coap://127.0.0.1"},
+			host:  "127.0.0.1:5683",
+		},
+		{
+			name:  "custom port",
+			props: map[string]interface{}{"server": "coap://device1:15683"},
+			host:  "device1:15683",
+		},
+		{
+			name:   "dtls",
+			props:  map[string]interface{}{"server": "coaps://127.0.0.1", "pskIdentity": "id", "psk": "key"},
+			host:   "127.0.0.1:5684",
+			secure: true,
+		},
+		{
+			name:  "dtls without psk",
+			props: map[string]interface{}{"server": "coaps://127.0.0.1:5684"},
+			err:   "pskIdentity and psk are required for coaps",
+		},
+		{
+			name:  "missing server",
+			props: map[string]interface{}{},
+			err:   "server is required",
+		},
+		{
+			name:  "invalid scheme",
+			props: map[string]interface{}{"server": "udp://127.0.0.1:5683"},
+			err:   "server must start with coap:// or coaps://",
+		},
+		{
+			name:  "invalid timeout",
+			props: map[string]interface{}{"server": "coap://127.0.0.1", "timeout": 0},
+			err:   "timeout must be positive",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := GetClientConf(tt.props)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.host, c.host)
+			assert.Equal(t, tt.secure, c.secure)
+		})
+	}
+}
+
+func TestMediaType(t *testing.T) {
+	mt, err := MediaType("application/json")
+	require.NoError(t, err)
+	assert.Equal(t, message.AppJSON, mt)
+	_, err = MediaType("application/xml")
+	assert.EqualError(t, err, "unsupported content type application/xml, must be text/plain, application/json, application/cbor or application/octet-stream")
+}
@@ -0,0 +1,175 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+/*
+#cgo LDFLAGS: -ldl
+#define _GNU_SOURCE
+#include <dlfcn.h>
+#include <stdint.h>
+#include <stdlib.h>
+#include <string.h>
+
+typedef void *(*create_fn)(const void *);
+typedef void (*delete_fn)(void *);
+
+struct edgetpu_device {
+	int type;
+	const char *path;
+};
+typedef struct edgetpu_device *(*list_devices_fn)(size_t *);
+typedef void (*free_devices_fn)(struct edgetpu_device *);
+typedef void *(*create_edgetpu_fn)(int, const char *, const void *, size_t);
+
+// load_symbol finds the symbol in the library, or in the loaded libraries if lib is NULL
+static void *load_symbol(const char *lib, const char *name) {
+	void *handle = RTLD_DEFAULT;
+	if (lib != NULL) {
+		handle = dlopen(lib, RTLD_NOW | RTLD_GLOBAL);
+		if (handle == NULL) {
+			return NULL;
+		}
+	}
+	return dlsym(handle, name);
+}
+
+static void *create_xnnpack(void *create, int32_t threads) {
+	// The options begin with num_threads in all the versions, the other fields are left as zero
+	char opts[256];
+	memset(opts, 0, sizeof(opts));
+	memcpy(opts, &threads, sizeof(threads));
+	return ((create_fn)create)(opts);
+}
+
+static void *create_gpu(void *create) {
+	return ((create_fn)create)(NULL);
+}
+
+// create_edgetpu creates the delegate of the first edge tpu device
+static void *create_edgetpu(void *list, void *freeDevices, void *create) {
+	size_t n = 0;
+	struct edgetpu_device *devices = ((list_devices_fn)list)(&n);
+	if (devices == NULL) {
+		return NULL;
+	}
+	void *d = NULL;
+	if (n > 0) {
+		d = ((create_edgetpu_fn)create)(devices[0].type, devices[0].path, NULL, 0);
+	}
+	((free_devices_fn)freeDevices)(devices);
+	return d;
+}
+
+static void delete_delegate(void *del, void *d) {
+	((delete_fn)del)(d);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+const (
+	delegateXNNPACK = "xnnpack"
+	delegateGPU     = "gpu"
+	delegateEdgeTPU = "edgetpu"
+)
+
+// The delegates are loaded from the shared libraries when used, so that the plugin can run without them. The xnnpack
+// delegate is built in the tensorflow lite library.
+var delegateLibs = map[string]string{
+	delegateXNNPACK: "",
+	delegateGPU:     "libtensorflowlite_gpu_delegate.so",
+	delegateEdgeTPU: "libedgetpu.so.1",
+}
+
+// delegate implements delegates.Delegater of go-tflite
+type delegate struct {
+	name     string
+	ptr      unsafe.Pointer
+	deleteFn unsafe.Pointer
+}
+
+func (d *delegate) Ptr() unsafe.Pointer {
+	return d.ptr
+}
+
+func (d *delegate) Delete() {
+	if d.ptr != nil {
+		C.delete_delegate(d.deleteFn, d.ptr)
+		d.ptr = nil
+	}
+}
+
+func loadSymbol(lib string, name string) (unsafe.Pointer, error) {
+	var clib *C.char
+	if lib != "" {
+		clib = C.CString(lib)
+		defer C.free(unsafe.Pointer(clib))
+	}
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	p := C.load_symbol(clib, cname)
+	if p == nil {
+		if lib == "" {
+			return nil, fmt.Errorf("symbol %s not found", name)
+		}
+		return nil, fmt.Errorf("symbol %s not found in %s", name, lib)
+	}
+	return p, nil
+}
+
+func newDelegate(name string, threads int) (*delegate, error) {
+	lib, ok := delegateLibs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown delegate %s", name)
+	}
+	var (
+		symbols []string
+		create  func(fns []unsafe.Pointer) unsafe.Pointer
+	)
+	switch name {
+	case delegateXNNPACK:
+		symbols = []string{"TfLiteXNNPackDelegateCreate", "TfLiteXNNPackDelegateDelete"}
+		create = func(fns []unsafe.Pointer) unsafe.Pointer {
+			return C.create_xnnpack(fns[0], C.int32_t(threads))
+		}
+	case delegateGPU:
+		symbols = []string{"TfLiteGpuDelegateV2Create", "TfLiteGpuDelegateV2Delete"}
+		create = func(fns []unsafe.Pointer) unsafe.Pointer {
+			return C.create_gpu(fns[0])
+		}
+	case delegateEdgeTPU:
+		symbols = []string{"edgetpu_create_delegate", "edgetpu_free_delegate", "edgetpu_list_devices", "edgetpu_free_devices"}
+		create = func(fns []unsafe.Pointer) unsafe.Pointer {
+			return C.create_edgetpu(fns[2], fns[3], fns[0])
+		}
+	}
+	fns := make([]unsafe.Pointer, len(symbols))
+	for i, s := range symbols {
+		p, err := loadSymbol(lib, s)
+		if err != nil {
+			return nil, err
+		}
+		fns[i] = p
+	}
+	p := create(fns)
+	if p == nil {
+		return nil, fmt.Errorf("fail to create %s delegate", name)
+	}
+	return &delegate{name: name, ptr: p, deleteFn: fns[1]}, nil
+}
@@ -1,4 +1,4 @@
-// Copyright 2022-2023 EMQ Technologies Co., Ltd.
+// Copyright 2022-2024 EMQ Technologies Co., Ltd.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -16,14 +16,20 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/mattn/go-tflite"
 
 	"github.com/lf-edge/ekuiper/internal/conf"
 )
 
+const defaultThreads = 4
+
 var ipManager *interpreterManager
 
 func init() {
@@ -31,23 +37,89 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
+	c, err := loadConf(filepath.Join(path, "functions", "tfLite", "tfLite.yaml"))
+	if err != nil {
+		conf.Log.Errorf("fail to load tfLite configuration, use the default options: %v", err)
+	}
 	ipManager = &interpreterManager{
-		registry: make(map[string]*tflite.Interpreter),
+		registry: make(map[string]*loadedModel),
 		path:     filepath.Join(path, "uploads"),
+		conf:     c,
+	}
+}
+
+// modelOptions is the acceleration options of a model
+type modelOptions struct {
+	// Threads is the number of threads used by the interpreter
+	Threads int `json:"threads"`
+	// Delegates are tried in order, and the first one created successfully is used
+	Delegates []string `json:"delegates"`
+}
+
+type tfLiteConf struct {
+	Default modelOptions             `json:"default"`
+	Models  map[string]*modelOptions `json:"models"`
+}
+
+func loadConf(p string) (*tfLiteConf, error) {
+	c := &tfLiteConf{Default: modelOptions{Threads: defaultThreads}}
+	if _, err := os.Stat(p); os.IsNotExist(err) {
+		return c, nil
+	}
+	if err := conf.LoadConfigFromPath(p, c); err != nil {
+		return &tfLiteConf{Default: modelOptions{Threads: defaultThreads}}, err
 	}
+	return c, nil
+}
+
+// options returns the options of the model. The model options override the default options.
+func (c *tfLiteConf) options(name string) modelOptions {
+	opts := c.Default
+	if opts.Threads <= 0 {
+		opts.Threads = defaultThreads
+	}
+	// The keys of the configuration are in lower case
+	if mo, ok := c.Models[strings.ToLower(name)]; ok && mo != nil {
+		if mo.Threads > 0 {
+			opts.Threads = mo.Threads
+		}
+		if mo.Delegates != nil {
+			opts.Delegates = mo.Delegates
+		}
+	}
+	return opts
+}
+
+type loadedModel struct {
+	ip       *tflite.Interpreter
+	delegate *delegate
+	status   ModelStatus
+}
+
+// ModelStatus is the status of a loaded model which is reported by the rest api
+type ModelStatus struct {
+	Name     string `json:"name"`
+	Threads  int    `json:"threads"`
+	Delegate string `json:"delegate"`
+	// ModelBytes is the size of the model file
+	ModelBytes int64 `json:"modelBytes"`
+	// TensorBytes is the size of all the input and output tensors
+	TensorBytes int64     `json:"tensorBytes"`
+	LoadedAt    time.Time `json:"loadedAt"`
 }
 
 type interpreterManager struct {
 	sync.Mutex
-	registry map[string]*tflite.Interpreter
+	registry map[string]*loadedModel
 	path     string
+	conf     *tfLiteConf
 }
 
 func (m *interpreterManager) GetOrCreate(name string) (*tflite.Interpreter, error) {
 	log := conf.Log
 	m.Lock()
 	defer m.Unlock()
-	ip, ok := m.registry[name]
+	lm, ok := m.registry[name]
 	if !ok {
 		mf := filepath.Join(m.path, name+".tflite")
 		model := tflite.NewModelFromFile(mf)
@@ -57,21 +129,74 @@ func (m *interpreterManager) GetOrCreate(name string) (*tflite.Interpreter, erro
 		}
 		log.Infof("success load model: %s", mf)
 		defer model.Delete()
+		opts := m.conf.options(name)
 		options := tflite.NewInterpreterOptions()
-		options.SetNumThread(4)
+		options.SetNumThread(opts.Threads)
 		options.SetErrorReporter(func(msg string, user_data interface{}) {
 			fmt.Println(msg)
 		}, nil)
 		defer options.Delete()
-		ip = tflite.NewInterpreter(model, options)
+		lm = &loadedModel{
+			status: ModelStatus{Name: name, Threads: opts.Threads, Delegate: "cpu"},
+		}
+		for _, dn := range opts.Delegates {
+			d, err := newDelegate(strings.ToLower(dn), opts.Threads)
+			if err != nil {
+				log.Warnf("fail to create %s delegate for model %s, try the next one: %v", dn, name, err)
+				continue
+			}
+			options.AddDelegate(d)
+			lm.delegate = d
+			lm.status.Delegate = d.name
+			break
+		}
+		ip := tflite.NewInterpreter(model, options)
+		if ip == nil {
+			lm.deleteDelegate()
+			return nil, fmt.Errorf("fail to create interpreter for model %s with %s delegate", name, lm.status.Delegate)
+		}
 		status := ip.AllocateTensors()
 		if status != tflite.OK {
 			log.Errorf("allocate tensors failed for: %s", mf)
 			ip.Delete()
+			lm.deleteDelegate()
 			return nil, fmt.Errorf("allocate failed: %v", status)
 		}
-		log.Infof("success allocate tensors for: %s", mf)
-		m.registry[name] = ip
+		log.Infof("success allocate tensors for: %s with %d threads and %s delegate", mf, opts.Threads, lm.status.Delegate)
+		lm.ip = ip
+		if fi, err := os.Stat(mf); err == nil {
+			lm.status.ModelBytes = fi.Size()
+		}
+		for i := 0; i < ip.GetInputTensorCount(); i++ {
+			lm.status.TensorBytes += int64(ip.GetInputTensor(i).ByteSize())
+		}
+		for i := 0; i < ip.GetOutputTensorCount(); i++ {
+			lm.status.TensorBytes += int64(ip.GetOutputTensor(i).ByteSize())
+		}
+		lm.status.LoadedAt = time.Now()
+		m.registry[name] = lm
+	}
+	return lm.ip, nil
+}
+
+// List returns the status of all the loaded models sorted by name
+func (m *interpreterManager) List() []ModelStatus {
+	m.Lock()
+	defer m.Unlock()
+	result := make([]ModelStatus, 0, len(m.registry))
+	for _, lm := range m.registry {
+		result = append(result, lm.status)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// deleteDelegate releases the delegate which must be deleted after the interpreter
+func (lm *loadedModel) deleteDelegate() {
+	if lm.delegate != nil {
+		lm.delegate.Delete()
+		lm.delegate = nil
 	}
-	return ip, nil
 }
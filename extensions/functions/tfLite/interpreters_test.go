@@ -0,0 +1,80 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModelOptions(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "tfLite.yaml")
+	err := os.WriteFile(p, []byte(`
+default:
+  threads: 2
+  delegates: [xnnpack]
+models:
+  MobileNet:
+    threads: 1
+    delegates: [edgetpu, xnnpack]
+  sin_model:
+    threads: 8
+  xor_model:
+    delegates: []
+`), 0o644)
+	require.NoError(t, err)
+	c, err := loadConf(p)
+	require.NoError(t, err)
+	tests := []struct {
+		name string
+		want modelOptions
+	}{
+		{
+			name: "mobilenet",
+			want: modelOptions{Threads: 1, Delegates: []string{"edgetpu", "xnnpack"}},
+		},
+		{
+			name: "MobileNet",
+			want: modelOptions{Threads: 1, Delegates: []string{"edgetpu", "xnnpack"}},
+		},
+		{
+			name: "sin_model",
+			want: modelOptions{Threads: 8, Delegates: []string{"xnnpack"}},
+		},
+		{
+			name: "xor_model",
+			want: modelOptions{Threads: 2, Delegates: []string{}},
+		},
+		{
+			name: "fizzbuzz_model",
+			want: modelOptions{Threads: 2, Delegates: []string{"xnnpack"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, c.options(tt.name))
+		})
+	}
+}
+
+func TestModelOptionsDefault(t *testing.T) {
+	c, err := loadConf(filepath.Join(t.TempDir(), "tfLite.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, modelOptions{Threads: defaultThreads}, c.options("sin_model"))
+}
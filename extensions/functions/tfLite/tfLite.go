@@ -1,4 +1,4 @@
-// Copyright 2022 EMQ Technologies Co., Ltd.
+// Copyright 2022-2024 EMQ Technologies Co., Ltd.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -39,6 +39,11 @@ func (f *Tffunc) IsAggregate() bool {
 	return false
 }
 
+// Status returns the loaded models with their options and memory footprint
+func (f *Tffunc) Status() interface{} {
+	return ipManager.List()
+}
+
 func (f *Tffunc) Exec(args []interface{}, ctx api.FunctionContext) (interface{}, bool) {
 	model, ok := args[0].(string)
 	if !ok {
@@ -0,0 +1,232 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modbus
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/simonvetter/modbus"
+
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"github.com/lf-edge/ekuiper/pkg/errorx"
+)
+
+// Conf is the connection and the register map shared by the modbus source and sink
+type Conf struct {
+	// Url is like tcp://127.0.0.1:502, rtu:///dev/ttyUSB0 or rtuovertcp://127.0.0.1:5020
+	Url    string `json:"url"`
+	UnitId int    `json:"unitId"`
+	// Serial settings for rtu
+	Speed    int    `json:"speed"`
+	DataBits int    `json:"dataBits"`
+	Parity   string `json:"parity"`
+	StopBits int    `json:"stopBits"`
+	// Timeout in ms
+	Timeout   int         `json:"timeout"`
+	ByteOrder string      `json:"byteOrder"`
+	WordOrder string      `json:"wordOrder"`
+	Registers []*Register `json:"registers"`
+}
+
+func GetConf(props map[string]interface{}) (*Conf, error) {
+	c := &Conf{
+		UnitId:    1,
+		Speed:     9600,
+		DataBits:  8,
+		Parity:    "none",
+		Timeout:   1000,
+		ByteOrder: ByteOrderBig,
+		WordOrder: WordOrderHigh,
+	}
+	if err := cast.MapToStruct(props, c); err != nil {
+		return nil, fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Conf) validate() error {
+	if c.Url == "" {
+		return fmt.Errorf("url is required")
+	}
+	if !strings.HasPrefix(c.Url, "tcp://") && !strings.HasPrefix(c.Url, "rtu://") && !strings.HasPrefix(c.Url, "rtuovertcp://") {
+		return fmt.Errorf("url must start with tcp://, rtu:// or rtuovertcp://")
+	}
+	if c.UnitId < 0 || c.UnitId > math.MaxUint8 {
+		return fmt.Errorf("unitId %d is out of range", c.UnitId)
+	}
+	if _, ok := parities[c.Parity]; !ok {
+		return fmt.Errorf("parity must be none, even or odd")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+	if len(c.Registers) == 0 {
+		return fmt.Errorf("registers are required")
+	}
+	names := make(map[string]struct{}, len(c.Registers))
+	for _, r := range c.Registers {
+		if r == nil {
+			return fmt.Errorf("register must not be empty")
+		}
+		if err := r.validate(c.ByteOrder, c.WordOrder); err != nil {
+			return err
+		}
+		if _, ok := names[r.Name]; ok {
+			return fmt.Errorf("register name %s is duplicate", r.Name)
+		}
+		names[r.Name] = struct{}{}
+	}
+	return nil
+}
+
+var parities = map[string]uint{
+	"none": modbus.PARITY_NONE,
+	"even": modbus.PARITY_EVEN,
+	"odd":  modbus.PARITY_ODD,
+}
+
+// Client wraps the modbus client to read and write the registers by the register map
+type Client struct {
+	c   *Conf
+	cli *modbus.ModbusClient
+}
+
+func NewClient(c *Conf) *Client {
+	return &Client{c: c}
+}
+
+// Connect opens the connection if it is not opened yet
+func (m *Client) Connect() error {
+	if m.cli != nil {
+		return nil
+	}
+	cli, err := modbus.NewClient(&modbus.ClientConfiguration{
+		URL:      m.c.Url,
+		Speed:    uint(m.c.Speed),
+		DataBits: uint(m.c.DataBits),
+		Parity:   parities[m.c.Parity],
+		StopBits: uint(m.c.StopBits),
+		Timeout:  time.Duration(m.c.Timeout) * time.Millisecond,
+	})
+	if err != nil {
+		return err
+	}
+	if err := cli.Open(); err != nil {
+		return fmt.Errorf("fail to connect to %s: %v", m.c.Url, err)
+	}
+	if err := cli.SetUnitId(uint8(m.c.UnitId)); err != nil {
+		_ = cli.Close()
+		return err
+	}
+	m.cli = cli
+	return nil
+}
+
+// Read reads all the registers and returns the typed values by the register names
+func (m *Client) Read() (map[string]interface{}, error) {
+	if err := m.Connect(); err != nil {
+		return nil, err
+	}
+	result := make(map[string]interface{}, len(m.c.Registers))
+	for _, r := range m.c.Registers {
+		var (
+			regs []uint16
+			err  error
+		)
+		addr := uint16(r.Address)
+		switch r.Area {
+		case AreaCoil, AreaDiscrete:
+			var bits []bool
+			if r.Area == AreaCoil {
+				bits, err = m.cli.ReadCoils(addr, 1)
+			} else {
+				bits, err = m.cli.ReadDiscreteInputs(addr, 1)
+			}
+			if err == nil && len(bits) > 0 && bits[0] {
+				regs = []uint16{1}
+			} else {
+				regs = []uint16{0}
+			}
+		case AreaInput:
+			regs, err = m.cli.ReadRegisters(addr, r.Quantity(), modbus.INPUT_REGISTER)
+		default:
+			regs, err = m.cli.ReadRegisters(addr, r.Quantity(), modbus.HOLDING_REGISTER)
+		}
+		if err != nil {
+			m.Close()
+			return nil, fmt.Errorf("fail to read register %s: %v", r.Name, err)
+		}
+		v, err := r.Decode(regs)
+		if err != nil {
+			return nil, err
+		}
+		result[r.Name] = v
+	}
+	return result, nil
+}
+
+// Write writes the values of the writable registers whose names are in the data. The values are all encoded before
+// writing so that an invalid value writes nothing.
+func (m *Client) Write(data map[string]interface{}) error {
+	var (
+		regs    []*Register
+		payload [][]uint16
+	)
+	for _, r := range m.c.Registers {
+		v, ok := data[r.Name]
+		if !ok || !r.Writable() {
+			continue
+		}
+		p, err := r.Encode(v)
+		if err != nil {
+			return err
+		}
+		regs = append(regs, r)
+		payload = append(payload, p)
+	}
+	if len(regs) == 0 {
+		return nil
+	}
+	if err := m.Connect(); err != nil {
+		return errorx.NewIOErr(err.Error())
+	}
+	for i, r := range regs {
+		var err error
+		if r.Area == AreaCoil {
+			err = m.cli.WriteCoil(uint16(r.Address), payload[i][0] != 0)
+		} else {
+			err = m.cli.WriteRegisters(uint16(r.Address), payload[i])
+		}
+		if err != nil {
+			m.Close()
+			return errorx.NewIOErr(fmt.Sprintf("fail to write register %s: %v", r.Name, err))
+		}
+	}
+	return nil
+}
+
+// Close closes the connection, it will be reopened by the next read or write
+func (m *Client) Close() {
+	if m.cli != nil {
+		_ = m.cli.Close()
+		m.cli = nil
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modbus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+const (
+	AreaCoil     = "coil"
+	AreaDiscrete = "discrete"
+	AreaInput    = "input"
+	AreaHolding  = "holding"
+
+	ByteOrderBig    = "big"
+	ByteOrderLittle = "little"
+	WordOrderHigh   = "high"
+	WordOrderLow    = "low"
+)
+
+// quantities are the count of the 16 bits registers of each type
+var quantities = map[string]uint16{
+	"bool":    1,
+	"int16":   1,
+	"uint16":  1,
+	"int32":   2,
+	"uint32":  2,
+	"float32": 2,
+	"int64":   4,
+	"uint64":  4,
+	"float64": 4,
+}
+
+// Register maps a field to the registers of a modbus device
+type Register struct {
+	Name    string `json:"name"`
+	Address int    `json:"address"`
+	// Area is coil, discrete, input or holding
+	Area string `json:"area"`
+	Type string `json:"type"`
+	// Scale multiplies the raw value when reading and divides the value when writing
+	Scale float64 `json:"scale"`
+	// ByteOrder and WordOrder override the global orders
+	ByteOrder string `json:"byteOrder"`
+	WordOrder string `json:"wordOrder"`
+}
+
+func (r *Register) validate(byteOrder, wordOrder string) error {
+	if r.Name == "" {
+		return fmt.Errorf("register name is required")
+	}
+	if r.Address < 0 || r.Address > math.MaxUint16 {
+		return fmt.Errorf("register %s address %d is out of range", r.Name, r.Address)
+	}
+	if r.Area == "" {
+		r.Area = AreaHolding
+	}
+	switch r.Area {
+	case AreaCoil, AreaDiscrete:
+		if r.Type == "" {
+			r.Type = "bool"
+		}
+		if r.Type != "bool" {
+			return fmt.Errorf("register %s in %s area must be bool type", r.Name, r.Area)
+		}
+	case AreaInput, AreaHolding:
+		if r.Type == "" {
+			r.Type = "uint16"
+		}
+	default:
+		return fmt.Errorf("register %s area must be coil, discrete, input or holding", r.Name)
+	}
+	if _, ok := quantities[r.Type]; !ok {
+		return fmt.Errorf("register %s has unsupported type %s", r.Name, r.Type)
+	}
+	if r.Scale == 0 {
+		r.Scale = 1
+	}
+	if r.ByteOrder == "" {
+		r.ByteOrder = byteOrder
+	}
+	if r.WordOrder == "" {
+		r.WordOrder = wordOrder
+	}
+	if r.ByteOrder != ByteOrderBig && r.ByteOrder != ByteOrderLittle {
+		return fmt.Errorf("register %s byteOrder must be big or little", r.Name)
+	}
+	if r.WordOrder != WordOrderHigh && r.WordOrder != WordOrderLow {
+		return fmt.Errorf("register %s wordOrder must be high or low", r.Name)
+	}
+	return nil
+}
+
+// Quantity is the count of the registers or bits to read
+func (r *Register) Quantity() uint16 {
+	return quantities[r.Type]
+}
+
+// Writable checks if the register area can be written
+func (r *Register) Writable() bool {
+	return r.Area == AreaCoil || r.Area == AreaHolding
+}
+
+// Decode converts the raw registers to the typed and scaled value
+func (r *Register) Decode(regs []uint16) (interface{}, error) {
+	if len(regs) < int(r.Quantity()) {
+		return nil, fmt.Errorf("register %s needs %d registers but got %d", r.Name, r.Quantity(), len(regs))
+	}
+	if r.Type == "bool" {
+		return regs[0] != 0, nil
+	}
+	b := r.toBytes(regs[:r.Quantity()])
+	var v interface{}
+	switch r.Type {
+	case "int16":
+		v = int64(int16(binary.BigEndian.Uint16(b)))
+	case "uint16":
+		v = int64(binary.BigEndian.Uint16(b))
+	case "int32":
+		v = int64(int32(binary.BigEndian.Uint32(b)))
+	case "uint32":
+		v = int64(binary.BigEndian.Uint32(b))
+	case "float32":
+		v = float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+	case "int64":
+		v = int64(binary.BigEndian.Uint64(b))
+	case "uint64":
+		v = binary.BigEndian.Uint64(b)
+	case "float64":
+		v = math.Float64frombits(binary.BigEndian.Uint64(b))
+	}
+	if r.Scale == 1 {
+		return v, nil
+	}
+	f, err := cast.ToFloat64(v, cast.CONVERT_SAMEKIND)
+	if err != nil {
+		return nil, err
+	}
+	return f * r.Scale, nil
+}
+
+// Encode converts the value to the raw registers to write
+func (r *Register) Encode(v interface{}) ([]uint16, error) {
+	if r.Type == "bool" {
+		b, err := cast.ToBool(v, cast.CONVERT_ALL)
+		if err != nil {
+			return nil, fmt.Errorf("register %s: %v", r.Name, err)
+		}
+		if b {
+			return []uint16{1}, nil
+		}
+		return []uint16{0}, nil
+	}
+	f, err := cast.ToFloat64(v, cast.CONVERT_SAMEKIND)
+	if err != nil {
+		return nil, fmt.Errorf("register %s: %v", r.Name, err)
+	}
+	f = f / r.Scale
+	b := make([]byte, r.Quantity()*2)
+	switch r.Type {
+	case "int16":
+		if f < math.MinInt16 || f > math.MaxInt16 {
+			return nil, fmt.Errorf("register %s value %v overflows int16", r.Name, f)
+		}
+		binary.BigEndian.PutUint16(b, uint16(int16(math.Round(f))))
+	case "uint16":
+		if f < 0 || f > math.MaxUint16 {
+			return nil, fmt.Errorf("register %s value %v overflows uint16", r.Name, f)
+		}
+		binary.BigEndian.PutUint16(b, uint16(math.Round(f)))
+	case "int32":
+		if f < math.MinInt32 || f > math.MaxInt32 {
+			return nil, fmt.Errorf("register %s value %v overflows int32", r.Name, f)
+		}
+		binary.BigEndian.PutUint32(b, uint32(int32(math.Round(f))))
+	case "uint32":
+		if f < 0 || f > math.MaxUint32 {
+			return nil, fmt.Errorf("register %s value %v overflows uint32", r.Name, f)
+		}
+		binary.BigEndian.PutUint32(b, uint32(math.Round(f)))
+	case "float32":
+		binary.BigEndian.PutUint32(b, math.Float32bits(float32(f)))
+	case "int64":
+		binary.BigEndian.PutUint64(b, uint64(int64(math.Round(f))))
+	case "uint64":
+		if f < 0 {
+			return nil, fmt.Errorf("register %s value %v overflows uint64", r.Name, f)
+		}
+		binary.BigEndian.PutUint64(b, uint64(math.Round(f)))
+	case "float64":
+		binary.BigEndian.PutUint64(b, math.Float64bits(f))
+	}
+	return r.fromBytes(b), nil
+}
+
+// toBytes orders the registers in big endian bytes with the high word first
+func (r *Register) toBytes(regs []uint16) []byte {
+	b := make([]byte, len(regs)*2)
+	for i, reg := range regs {
+		j := i
+		if r.WordOrder == WordOrderLow {
+			j = len(regs) - 1 - i
+		}
+		if r.ByteOrder == ByteOrderLittle {
+			reg = reg<<8 | reg>>8
+		}
+		binary.BigEndian.PutUint16(b[j*2:], reg)
+	}
+	return b
+}
+
+// fromBytes is the reverse of toBytes
+func (r *Register) fromBytes(b []byte) []uint16 {
+	n := len(b) / 2
+	regs := make([]uint16, n)
+	for i := range regs {
+		j := i
+		if r.WordOrder == WordOrderLow {
+			j = n - 1 - i
+		}
+		reg := binary.BigEndian.Uint16(b[j*2:])
+		if r.ByteOrder == ByteOrderLittle {
+			reg = reg<<8 | reg>>8
+		}
+		regs[i] = reg
+	}
+	return regs
+}
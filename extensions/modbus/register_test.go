@@ -0,0 +1,181 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modbus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterCodec(t *testing.T) {
+	tests := []struct {
+		name  string
+		r     *Register
+		regs  []uint16
+		value interface{}
+	}{
+		{
+			name:  "bool",
+			r:     &Register{Name: "a", Area: AreaCoil},
+			regs:  []uint16{1},
+			value: true,
+		},
+		{
+			name:  "int16",
+			r:     &Register{Name: "a", Type: "int16"},
+			regs:  []uint16{0xFFFE},
+			value: int64(-2),
+		},
+		{
+			name:  "int16 little endian",
+			r:     &Register{Name: "a", Type: "int16", ByteOrder: ByteOrderLittle},
+			regs:  []uint16{0xFEFF},
+			value: int64(-2),
+		},
+		{
+			name:  "uint32",
+			r:     &Register{Name: "a", Type: "uint32"},
+			regs:  []uint16{0x0001, 0x0002},
+			value: int64(65538),
+		},
+		{
+			name:  "uint32 low word first",
+			r:     &Register{Name: "a", Type: "uint32", WordOrder: WordOrderLow},
+			regs:  []uint16{0x0002, 0x0001},
+			value: int64(65538),
+		},
+		{
+			name:  "float32",
+			r:     &Register{Name: "a", Type: "float32"},
+			regs:  []uint16{0x4048, 0xF5C3},
+			value: float64(float32(3.14)),
+		},
+		{
+			name:  "scaled int16",
+			r:     &Register{Name: "a", Type: "int16", Scale: 0.1},
+			regs:  []uint16{0x00FB},
+			value: 25.1,
+		},
+		{
+			name:  "float64 little endian low word first",
+			r:     &Register{Name: "a", Type: "float64", ByteOrder: ByteOrderLittle, WordOrder: WordOrderLow},
+			regs:  []uint16{0x0000, 0x0000, 0x0000, 0xF03F},
+			value: 1.0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.r.validate(ByteOrderBig, WordOrderHigh))
+			v, err := tt.r.Decode(tt.regs)
+			require.NoError(t, err)
+			assert.InDelta(t, toFloat(tt.value), toFloat(v), 1e-9)
+			assert.IsType(t, tt.value, v)
+			regs, err := tt.r.Encode(v)
+			require.NoError(t, err)
+			assert.Equal(t, tt.regs, regs)
+		})
+	}
+}
+
+func toFloat(v interface{}) float64 {
+	switch vt := v.(type) {
+	case bool:
+		if vt {
+			return 1
+		}
+		return 0
+	case int64:
+		return float64(vt)
+	case float64:
+		return vt
+	}
+	return 0
+}
+
+func TestEncodeOverflow(t *testing.T) {
+	r := &Register{Name: "a", Type: "uint16", Scale: 10}
+	require.NoError(t, r.validate(ByteOrderBig, WordOrderHigh))
+	_, err := r.Encode(700000)
+	assert.EqualError(t, err, "register a value 70000 overflows uint16")
+	_, err = r.Encode("abc")
+	assert.Error(t, err)
+}
+
+func TestGetConf(t *testing.T) {
+	tests := []struct {
+		name  string
+		props map[string]interface{}
+		err   string
+	}{
+		{
+			name:  "no url",
+			props: map[string]interface{}{},
+			err:   "url is required",
+		},
+		{
+			name:  "wrong url",
+			props: map[string]interface{}{"url": "udp://127.0.0.1:502"},
+			err:   "url must start with tcp://, rtu:// or rtuovertcp://",
+		},
+		{
+			name:  "no registers",
+			props: map[string]interface{}{"url": "tcp://127.0.0.1:502"},
+			err:   "registers are required",
+		},
+		{
+			name: "wrong type",
+			props: map[string]interface{}{
+				"url":       "tcp://127.0.0.1:502",
+				"registers": []interface{}{map[string]interface{}{"name": "a", "address": 1, "area": "coil", "type": "int16"}},
+			},
+			err: "register a in coil area must be bool type",
+		},
+		{
+			name: "duplicate",
+			props: map[string]interface{}{
+				"url": "tcp://127.0.0.1:502",
+				"registers": []interface{}{
+					map[string]interface{}{"name": "a", "address": 1},
+					map[string]interface{}{"name": "a", "address": 2},
+				},
+			},
+			err: "register name a is duplicate",
+		},
+		{
+			name: "valid",
+			props: map[string]interface{}{
+				"url": "tcp://127.0.0.1:502",
+				"registers": []interface{}{
+					map[string]interface{}{"name": "temperature", "address": 1, "type": "int16", "scale": 0.1},
+					map[string]interface{}{"name": "running", "address": 0, "area": "coil"},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := GetConf(tt.props)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, &Register{Name: "temperature", Address: 1, Area: AreaHolding, Type: "int16", Scale: 0.1, ByteOrder: ByteOrderBig, WordOrder: WordOrderHigh}, c.Registers[0])
+			assert.Equal(t, &Register{Name: "running", Address: 0, Area: AreaCoil, Type: "bool", Scale: 1, ByteOrder: ByteOrderBig, WordOrder: WordOrderHigh}, c.Registers[1])
+		})
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package amqp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	amqpConf "github.com/lf-edge/ekuiper/extensions/amqp"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"github.com/lf-edge/ekuiper/pkg/errorx"
+)
+
+type sinkConf struct {
+	// RoutingKey can be a data template
+	RoutingKey  string `json:"routingKey"`
+	ContentType string `json:"contentType"`
+	// Persistent sets the delivery mode to persistent so that the messages survive the broker restart
+	Persistent bool `json:"persistent"`
+	// Mandatory returns the message if it cannot be routed to any queue
+	Mandatory bool `json:"mandatory"`
+	// Confirm waits for the broker to confirm each message
+	Confirm bool `json:"confirm"`
+	// ConfirmTimeout is the timeout in ms to wait for the confirmation
+	ConfirmTimeout int `json:"confirmTimeout"`
+}
+
+type amqpSink struct {
+	c    *amqpConf.Conf
+	sc   *sinkConf
+	conn *amqp.Connection
+	ch   *amqp.Channel
+}
+
+func (m *amqpSink) Configure(props map[string]interface{}) error {
+	c, err := amqpConf.GetConf(props)
+	if err != nil {
+		return err
+	}
+	sc := &sinkConf{
+		ContentType:    "application/json",
+		Persistent:     true,
+		Confirm:        true,
+		ConfirmTimeout: 5000,
+	}
+	if err := cast.MapToStruct(props, sc); err != nil {
+		return fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	if c.Exchange == "" && sc.RoutingKey == "" {
+		return fmt.Errorf("routingKey is required to publish to the default exchange")
+	}
+	if sc.Confirm && sc.ConfirmTimeout <= 0 {
+		return fmt.Errorf("confirmTimeout must be positive")
+	}
+	m.c = c
+	m.sc = sc
+	return nil
+}
+
+func (m *amqpSink) Open(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Opening amqp sink")
+	conn, ch, err := m.c.Connect("amqp-sink")
+	if err != nil {
+		return err
+	}
+	if m.sc.Confirm {
+		if err := ch.Confirm(false); err != nil {
+			_ = conn.Close()
+			return fmt.Errorf("fail to enable publisher confirms: %v", err)
+		}
+	}
+	if m.sc.Mandatory {
+		returns := ch.NotifyReturn(make(chan amqp.Return, 1))
+		go func() {
+			for r := range returns {
+				ctx.GetLogger().Warnf("amqp message returned by exchange %s with routing key %s: %s", r.Exchange, r.RoutingKey, r.ReplyText)
+			}
+		}()
+	}
+	m.conn = conn
+	m.ch = ch
+	return nil
+}
+
+func (m *amqpSink) Collect(ctx api.StreamContext, item interface{}) error {
+	logger := ctx.GetLogger()
+	body, _, err := ctx.TransformOutput(item)
+	if err != nil {
+		return err
+	}
+	key := m.sc.RoutingKey
+	if dt, ok := item.(map[string]interface{}); ok {
+		key, err = ctx.ParseTemplate(m.sc.RoutingKey, dt)
+		if err != nil {
+			return err
+		}
+	}
+	msg := amqp.Publishing{
+		ContentType: m.sc.ContentType,
+		Body:        body,
+		Timestamp:   time.Now(),
+	}
+	if m.sc.Persistent {
+		msg.DeliveryMode = amqp.Persistent
+	}
+	logger.Debugf("amqp sink publish %s to exchange %s with routing key %s", body, m.c.Exchange, key)
+	if !m.sc.Confirm {
+		if err := m.ch.PublishWithContext(ctx, m.c.Exchange, key, m.sc.Mandatory, false, msg); err != nil {
+			return errorx.NewIOErr(fmt.Sprintf("amqp sink fails to publish: %v", err))
+		}
+		return nil
+	}
+	dc, err := m.ch.PublishWithDeferredConfirmWithContext(ctx, m.c.Exchange, key, m.sc.Mandatory, false, msg)
+	if err != nil {
+		return errorx.NewIOErr(fmt.Sprintf("amqp sink fails to publish: %v", err))
+	}
+	wctx, cancel := context.WithTimeout(ctx, time.Duration(m.sc.ConfirmTimeout)*time.Millisecond)
+	defer cancel()
+	ack, err := dc.WaitContext(wctx)
+	if err != nil {
+		return errorx.NewIOErr(fmt.Sprintf("amqp sink fails to wait for the confirmation: %v", err))
+	}
+	if !ack {
+		return errorx.NewIOErr("amqp sink message is nacked by the broker")
+	}
+	return nil
+}
+
+func (m *amqpSink) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Closing amqp sink")
+	if m.conn != nil {
+		return m.conn.Close()
+	}
+	return nil
+}
+
+func GetSink() api.Sink {
+	return &amqpSink{}
+}
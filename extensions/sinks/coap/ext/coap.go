@@ -0,0 +1,127 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coap
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/plgd-dev/go-coap/v3/message"
+	"github.com/plgd-dev/go-coap/v3/message/pool"
+	"github.com/plgd-dev/go-coap/v3/udp/client"
+
+	"github.com/lf-edge/ekuiper/extensions/coap"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"github.com/lf-edge/ekuiper/pkg/errorx"
+)
+
+type sinkConf struct {
+	// Path is the resource path, it can be a data template
+	Path string `json:"path"`
+	// Method is POST or PUT
+	Method      string `json:"method"`
+	ContentType string `json:"contentType"`
+}
+
+type coapSink struct {
+	sc   *sinkConf
+	mt   message.MediaType
+	c    *coap.ClientConf
+	conn *client.Conn
+}
+
+func (m *coapSink) Configure(props map[string]interface{}) error {
+	sc := &sinkConf{
+		Method:      http.MethodPost,
+		ContentType: "application/json",
+	}
+	if err := cast.MapToStruct(props, sc); err != nil {
+		return fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	if sc.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	if sc.Method != http.MethodPost && sc.Method != http.MethodPut {
+		return fmt.Errorf("method %s is not supported, must be POST or PUT", sc.Method)
+	}
+	mt, err := coap.MediaType(sc.ContentType)
+	if err != nil {
+		return err
+	}
+	c, err := coap.GetClientConf(props)
+	if err != nil {
+		return err
+	}
+	m.sc = sc
+	m.mt = mt
+	m.c = c
+	return nil
+}
+
+func (m *coapSink) Open(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Opening coap sink to %s", m.c.Server)
+	conn, err := m.c.Dial()
+	if err != nil {
+		return err
+	}
+	m.conn = conn
+	return nil
+}
+
+func (m *coapSink) Collect(ctx api.StreamContext, item interface{}) error {
+	logger := ctx.GetLogger()
+	payload, _, err := ctx.TransformOutput(item)
+	if err != nil {
+		return err
+	}
+	path := m.sc.Path
+	if dt, ok := item.(map[string]interface{}); ok {
+		path, err = ctx.ParseTemplate(m.sc.Path, dt)
+		if err != nil {
+			return err
+		}
+	}
+	logger.Debugf("coap sink send %s to %s", payload, path)
+	rctx, cancel := m.c.RequestContext(ctx)
+	defer cancel()
+	var resp *pool.Message
+	if m.sc.Method == http.MethodPut {
+		resp, err = m.conn.Put(rctx, path, m.mt, bytes.NewReader(payload))
+	} else {
+		resp, err = m.conn.Post(rctx, path, m.mt, bytes.NewReader(payload))
+	}
+	if err != nil {
+		logger.Errorf("coap sink send error: %v", err)
+		return errorx.NewIOErr(fmt.Sprintf("coap sink fails to send to %s: %v", path, err))
+	}
+	if err := coap.CheckResponse(resp); err != nil {
+		return fmt.Errorf("coap sink fails to send to %s: %v", path, err)
+	}
+	return nil
+}
+
+func (m *coapSink) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Closing coap sink")
+	if m.conn != nil {
+		return m.conn.Close()
+	}
+	return nil
+}
+
+func GetSink() api.Sink {
+	return &coapSink{}
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/lf-edge/ekuiper/extensions/kafka"
 	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/pkg/cert"
+	"github.com/lf-edge/ekuiper/internal/pkg/netx"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/cast"
 	"github.com/lf-edge/ekuiper/pkg/errorx"
@@ -35,6 +36,7 @@ type kafkaSink struct {
 	c              *sinkConf
 	kc             *kafkaConf
 	tlsConfig      *tls.Config
+	dialer         *netx.Dialer
 	sc             kafka.SaslConf
 	headersMap     map[string]string
 	headerTemplate string
@@ -92,6 +94,10 @@ func (m *kafkaSink) Configure(props map[string]interface{}) error {
 		return err
 	}
 	m.tlsConfig = tlsConfig
+	m.dialer, err = netx.NewDialer(props)
+	if err != nil {
+		return err
+	}
 	kc := &kafkaConf{
 		RequiredACKs: -1,
 		MaxAttempts:  1,
@@ -125,6 +131,7 @@ func (m *kafkaSink) buildKafkaWriter() error {
 		Transport: &kafkago.Transport{
 			SASL: mechanism,
 			TLS:  m.tlsConfig,
+			Dial: m.dialer.DialContext,
 		},
 	}
 	m.writer = w
@@ -301,6 +308,7 @@ func (m *kafkaSink) ping(address string) error {
 	d := &kafkago.Dialer{
 		TLS:           m.tlsConfig,
 		SASLMechanism: mechanism,
+		DialFunc:      m.dialer.DialContext,
 	}
 	c, err := d.Dial("tcp", address)
 	if err != nil {
@@ -0,0 +1,115 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modbus
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/extensions/modbus"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+type sinkConf struct {
+	DataTemplate string `json:"dataTemplate"`
+}
+
+type modbusSink struct {
+	sc     *sinkConf
+	c      *modbus.Conf
+	client *modbus.Client
+}
+
+func (m *modbusSink) Configure(props map[string]interface{}) error {
+	sc := &sinkConf{}
+	if err := cast.MapToStruct(props, sc); err != nil {
+		return fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	c, err := modbus.GetConf(props)
+	if err != nil {
+		return err
+	}
+	writable := false
+	for _, r := range c.Registers {
+		if r.Writable() {
+			writable = true
+			break
+		}
+	}
+	if !writable {
+		return fmt.Errorf("at least one coil or holding register is required to write")
+	}
+	m.sc = sc
+	m.c = c
+	return nil
+}
+
+func (m *modbusSink) Open(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Opening modbus sink to %s", m.c.Url)
+	m.client = modbus.NewClient(m.c)
+	return m.client.Connect()
+}
+
+func (m *modbusSink) Collect(ctx api.StreamContext, item interface{}) error {
+	logger := ctx.GetLogger()
+	logger.Debugf("modbus sink receive %s", item)
+	if m.sc.DataTemplate != "" {
+		jsonBytes, _, err := ctx.TransformOutput(item)
+		if err != nil {
+			return err
+		}
+		tm := make(map[string]interface{})
+		err = json.Unmarshal(jsonBytes, &tm)
+		if err != nil {
+			return fmt.Errorf("fail to decode data %s after applying dataTemplate for error %v", string(jsonBytes), err)
+		}
+		item = tm
+	}
+	switch v := item.(type) {
+	case map[string]interface{}:
+		return m.write(ctx, v)
+	case []map[string]interface{}:
+		for _, d := range v {
+			if err := m.write(ctx, d); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("modbus sink receive unsupported data %v", item)
+	}
+}
+
+func (m *modbusSink) write(ctx api.StreamContext, data map[string]interface{}) error {
+	// the connection is closed on io error and reopened in the next write
+	if err := m.client.Write(data); err != nil {
+		ctx.GetLogger().Errorf("modbus sink write error: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (m *modbusSink) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Closing modbus sink")
+	if m.client != nil {
+		m.client.Close()
+	}
+	return nil
+}
+
+func GetSink() api.Sink {
+	return &modbusSink{}
+}
@@ -0,0 +1,246 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+// dialect is the sql dialect to build the prepared statements for upsert and table creation
+type dialect int
+
+const (
+	dialectUnknown dialect = iota
+	dialectPostgres
+	dialectMysql
+	dialectSqlite
+)
+
+func getDialect(driver string) dialect {
+	switch driver {
+	case "postgres", "pgx":
+		return dialectPostgres
+	case "mysql", "mymysql":
+		return dialectMysql
+	case "sqlite", "sqlite3", "moderncsqlite":
+		return dialectSqlite
+	default:
+		return dialectUnknown
+	}
+}
+
+// maxPlaceholders is the max count of the parameters in one statement. A batch exceeding it is split.
+func (d dialect) maxPlaceholders() int {
+	switch d {
+	case dialectSqlite:
+		return 32766
+	default:
+		return 65535
+	}
+}
+
+func (d dialect) placeholder(i int) string {
+	if d == dialectPostgres {
+		return fmt.Sprintf("$%d", i+1)
+	}
+	return "?"
+}
+
+// upsertClause builds the clause to update the non-key columns if a row with the same conflict keys exists
+func (d dialect) upsertClause(cols []string, conflictKeys []string) string {
+	var updates []string
+	for _, col := range cols {
+		if slices.Contains(conflictKeys, col) {
+			continue
+		}
+		if d == dialectMysql {
+			updates = append(updates, fmt.Sprintf("%s=VALUES(%s)", col, col))
+		} else {
+			updates = append(updates, fmt.Sprintf("%s=EXCLUDED.%s", col, col))
+		}
+	}
+	if d == dialectMysql {
+		if len(updates) == 0 {
+			updates = append(updates, fmt.Sprintf("%s=%s", conflictKeys[0], conflictKeys[0]))
+		}
+		return " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ",")
+	}
+	if len(updates) == 0 {
+		return fmt.Sprintf(" ON CONFLICT (%s) DO NOTHING", strings.Join(conflictKeys, ","))
+	}
+	return fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(conflictKeys, ","), strings.Join(updates, ","))
+}
+
+// columnType infers the column type by the go type of the value
+func (d dialect) columnType(v interface{}, isKey bool) string {
+	switch v.(type) {
+	case bool:
+		return "BOOLEAN"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "BIGINT"
+	case float32, float64:
+		switch d {
+		case dialectPostgres:
+			return "DOUBLE PRECISION"
+		case dialectMysql:
+			return "DOUBLE"
+		default:
+			return "REAL"
+		}
+	case []byte:
+		if d == dialectPostgres {
+			return "BYTEA"
+		}
+		return "BLOB"
+	case time.Time:
+		if d == dialectMysql {
+			return "DATETIME"
+		}
+		return "TIMESTAMP"
+	default:
+		// mysql cannot index a TEXT column without the length
+		if d == dialectMysql && isKey {
+			return "VARCHAR(255)"
+		}
+		return "TEXT"
+	}
+}
+
+// buildCreateTableSQL builds the statement to create the table if absent with the columns inferred from the row
+func buildCreateTableSQL(d dialect, table string, cols []string, row map[string]interface{}, primaryKeys []string) string {
+	defs := make([]string, 0, len(cols)+1)
+	for _, col := range cols {
+		defs = append(defs, col+" "+d.columnType(row[col], slices.Contains(primaryKeys, col)))
+	}
+	if len(primaryKeys) > 0 {
+		defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(primaryKeys, ",")))
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", table, strings.Join(defs, ", "))
+}
+
+// buildPreparedInsertSQL builds the statement to insert the rows with placeholders and returns the arguments in order
+func buildPreparedInsertSQL(d dialect, table string, cols []string, rows []map[string]interface{}, conflictKeys []string) (string, []interface{}, error) {
+	args := make([]interface{}, 0, len(cols)*len(rows))
+	values := make([]string, 0, len(rows))
+	for _, row := range rows {
+		phs := make([]string, len(cols))
+		for i, col := range cols {
+			arg, err := toArg(row[col])
+			if err != nil {
+				return "", nil, fmt.Errorf("invalid value of column %s: %v", col, err)
+			}
+			phs[i] = d.placeholder(len(args))
+			args = append(args, arg)
+		}
+		values = append(values, "("+strings.Join(phs, ",")+")")
+	}
+	sqlStr := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", table, strings.Join(cols, ","), strings.Join(values, ","))
+	if len(conflictKeys) > 0 {
+		sqlStr += d.upsertClause(cols, conflictKeys)
+	}
+	return sqlStr, args, nil
+}
+
+// toArg converts the composite values to json which the drivers cannot bind
+func toArg(v interface{}) (interface{}, error) {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}, []map[string]interface{}:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	default:
+		return v, nil
+	}
+}
+
+func toRows(item interface{}) ([]map[string]interface{}, error) {
+	switch v := item.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{v}, nil
+	case []map[string]interface{}:
+		return v, nil
+	case []interface{}:
+		rows := make([]map[string]interface{}, 0, len(v))
+		for _, data := range v {
+			mapData, ok := data.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("unsupported type: %T", data)
+			}
+			rows = append(rows, mapData)
+		}
+		return rows, nil
+	default:
+		return nil, fmt.Errorf("unsupported type: %T", item)
+	}
+}
+
+// columns returns the configured fields or the sorted keys of the row
+func (t *sqlConfig) columns(row map[string]interface{}) []string {
+	if len(t.Fields) > 0 {
+		return t.Fields
+	}
+	cols := make([]string, 0, len(row))
+	for k := range row {
+		cols = append(cols, k)
+	}
+	sort.Strings(cols)
+	return cols
+}
+
+// writeRows writes the rows with prepared statements. The table is created by the schema of the first row if
+// createTable is set, and a batch is split if it exceeds the max parameters of a statement.
+func (m *sqlSink) writeRows(ctx api.StreamContext, table string, rows []map[string]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	cols := m.conf.columns(rows[0])
+	if len(cols) == 0 {
+		return fmt.Errorf("data is empty.")
+	}
+	if m.conf.CreateTable && !m.created[table] {
+		sqlStr := buildCreateTableSQL(m.dialect, table, cols, rows[0], m.conf.UpsertKeys)
+		if err := m.writeToDB(ctx, &sqlStr); err != nil {
+			return err
+		}
+		m.created[table] = true
+	}
+	size := m.dialect.maxPlaceholders() / len(cols)
+	if size == 0 {
+		return fmt.Errorf("too many columns %d", len(cols))
+	}
+	for start := 0; start < len(rows); start += size {
+		end := start + size
+		if end > len(rows) {
+			end = len(rows)
+		}
+		sqlStr, args, err := buildPreparedInsertSQL(m.dialect, table, cols, rows[start:end], m.conf.UpsertKeys)
+		if err != nil {
+			return err
+		}
+		if err := m.writeToDB(ctx, &sqlStr, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,4 +1,4 @@
-// Copyright 2022-2023 EMQ Technologies Co., Ltd.
+// Copyright 2022-2024 EMQ Technologies Co., Ltd.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/lf-edge/ekuiper/extensions/sqldatabase"
@@ -38,6 +39,18 @@ type sqlConfig struct {
 	DataField      string   `json:"dataField"`
 	RowkindField   string   `json:"rowkindField"`
 	KeyField       string   `json:"keyField"`
+	// TokenField is the column to save the write token. The rows whose token exists are skipped.
+	TokenField string `json:"tokenField"`
+	// UpsertKeys are the conflict keys to update the existing rows instead of inserting
+	UpsertKeys []string `json:"upsertKeys"`
+	// CreateTable creates the table by the schema of the first row if it does not exist
+	CreateTable bool `json:"createTable"`
+	// Prepared writes with prepared statements instead of the sql literals. It is implied by upsertKeys and createTable.
+	Prepared bool `json:"prepared"`
+}
+
+func (t *sqlConfig) usePrepared() bool {
+	return t.Prepared || t.CreateTable || len(t.UpsertKeys) > 0
 }
 
 func (t *sqlConfig) buildInsertSql(ctx api.StreamContext, mapData map[string]interface{}) ([]string, string, error) {
@@ -89,10 +102,13 @@ func (t *sqlConfig) getKeyValues(ctx api.StreamContext, mapData map[string]inter
 }
 
 type sqlSink struct {
-	driver string
-	conf   *sqlConfig
+	driver  string
+	dialect dialect
+	conf    *sqlConfig
 	// The db connection instance
 	db sqldatabase.DB
+	// The tables created by createTable
+	created map[string]bool
 }
 
 func (m *sqlSink) Ping(_ string, props map[string]interface{}) error {
@@ -124,12 +140,34 @@ func (m *sqlSink) Configure(props map[string]interface{}) error {
 	if cfg.RowkindField != "" && cfg.KeyField == "" {
 		return fmt.Errorf("keyField is required when rowkindField is set")
 	}
-	m.conf = cfg
-	sqlDriver, err := util.ParseDriver(m.conf.Url)
+	if cfg.RowkindField != "" && cfg.TokenField != "" {
+		return fmt.Errorf("tokenField cannot be used with rowkindField")
+	}
+	if cfg.TokenField != "" && len(cfg.Fields) > 0 && !slices.Contains(cfg.Fields, cfg.TokenField) {
+		cfg.Fields = append(cfg.Fields, cfg.TokenField)
+	}
+	sqlDriver, err := util.ParseDriver(cfg.Url)
 	if err != nil {
 		return err
 	}
+	d := getDialect(sqlDriver)
+	if cfg.usePrepared() {
+		if d == dialectUnknown {
+			return fmt.Errorf("upsertKeys, createTable and prepared are only supported by mysql, postgres and sqlite, but got %s", sqlDriver)
+		}
+		if cfg.RowkindField != "" || cfg.TokenField != "" {
+			return fmt.Errorf("upsertKeys, createTable and prepared cannot be used with rowkindField or tokenField")
+		}
+		for _, k := range cfg.UpsertKeys {
+			if len(cfg.Fields) > 0 && !slices.Contains(cfg.Fields, k) {
+				return fmt.Errorf("upsert key %s is not in fields", k)
+			}
+		}
+	}
+	m.conf = cfg
 	m.driver = sqlDriver
+	m.dialect = d
+	m.created = make(map[string]bool)
 	return nil
 }
 
@@ -145,9 +183,9 @@ func (m *sqlSink) Open(ctx api.StreamContext) (err error) {
 	return
 }
 
-func (m *sqlSink) writeToDB(ctx api.StreamContext, sqlStr *string) error {
+func (m *sqlSink) writeToDB(ctx api.StreamContext, sqlStr *string, args ...interface{}) error {
 	ctx.GetLogger().Debugf(*sqlStr)
-	r, err := m.db.Exec(*sqlStr)
+	r, err := m.db.Exec(*sqlStr, args...)
 	if err != nil {
 		ctx.GetLogger().Errorf("sql sink writeDB failed, err:%v , sql: %v", err, *sqlStr)
 		return errorx.NewIOErr(err.Error())
@@ -201,6 +239,14 @@ func (m *sqlSink) Collect(ctx api.StreamContext, item interface{}) error {
 		}
 	}
 
+	if m.conf.usePrepared() {
+		rows, err := toRows(item)
+		if err != nil {
+			return err
+		}
+		return m.writeRows(ctx, table, rows)
+	}
+
 	var keys []string = nil
 	var values []string = nil
 	var vars string
@@ -297,6 +343,44 @@ func (m *sqlSink) Collect(ctx api.StreamContext, item interface{}) error {
 	}
 }
 
+// CollectWithToken inserts the rows only if the write token does not exist in the table. So the rows replayed after
+// the rule recovers from a checkpoint are not inserted again. Each row has its own token suffixed by the row index.
+func (m *sqlSink) CollectWithToken(ctx api.StreamContext, item interface{}, token api.WriteToken) error {
+	if m.conf.TokenField == "" {
+		return m.Collect(ctx, item)
+	}
+	var rows []map[string]interface{}
+	switch v := item.(type) {
+	case map[string]interface{}:
+		rows = []map[string]interface{}{v}
+	case []map[string]interface{}:
+		rows = v
+	default:
+		return fmt.Errorf("unsupported type: %T", item)
+	}
+	if m.conf.DataTemplate != "" || m.conf.DataField != "" {
+		return fmt.Errorf("tokenField cannot be used with dataTemplate or dataField")
+	}
+	for i, row := range rows {
+		table, err := ctx.ParseTemplate(m.conf.Table, row)
+		if err != nil {
+			ctx.GetLogger().Errorf("parse template for table %s error: %v", m.conf.Table, err)
+			return err
+		}
+		tk := fmt.Sprintf("%d-%d-%d", token.Seq, token.Index, i)
+		row[m.conf.TokenField] = tk
+		keys, vars, err := m.conf.buildInsertSql(ctx, row)
+		if err != nil {
+			return err
+		}
+		sqlStr := buildInsertIfAbsentSQL(m.driver, table, keys, vars, m.conf.TokenField, tk)
+		if err := m.writeToDB(ctx, &sqlStr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (m *sqlSink) Close(_ api.StreamContext) error {
 	if m.db != nil {
 		return util.ReturnDBFromOneNode(util.GlobalPool, m.conf.Url)
@@ -390,6 +474,18 @@ func buildInsertSQLByKV(table string, keys []string, values []string, withOption
 	return sql
 }
 
+// buildInsertIfAbsentSQL builds a statement to insert the values which are wrapped by parentheses only if
+// no row has the token
+func buildInsertIfAbsentSQL(driver, table string, keys []string, values string, tokenField string, token string) string {
+	from := ""
+	switch driver {
+	case "mysql", "oracle", "dm":
+		from = " FROM DUAL"
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) SELECT %s%s WHERE NOT EXISTS (SELECT 1 FROM %s WHERE %s = '%s')",
+		table, strings.Join(keys, ","), strings.TrimSuffix(strings.TrimPrefix(values, "("), ")"), from, table, tokenField, token)
+}
+
 type buildInsertOption struct {
 	addSemiColon bool
 }
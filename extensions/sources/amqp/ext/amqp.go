@@ -0,0 +1,243 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package amqp
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	amqpConf "github.com/lf-edge/ekuiper/extensions/amqp"
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"github.com/lf-edge/ekuiper/pkg/infra"
+)
+
+type sourceConf struct {
+	// DeclareQueue declares the queue if it does not exist
+	DeclareQueue bool `json:"declareQueue"`
+	Exclusive    bool `json:"exclusive"`
+	// BindingKeys bind the queue to the exchange
+	BindingKeys []string `json:"bindingKeys"`
+	// PrefetchCount is the max unacknowledged messages delivered to the source
+	PrefetchCount int    `json:"prefetchCount"`
+	ConsumerTag   string `json:"consumerTag"`
+	// EnableAck is set by the rule. The messages are only acknowledged after the data is delivered
+	EnableAck bool `json:"enableAck"`
+}
+
+// amqpTuple carries the delivery tag so that the message can be acknowledged after delivery
+type amqpTuple struct {
+	*api.DefaultSourceTuple
+	tag uint64
+}
+
+func (t *amqpTuple) Offset() interface{} {
+	if t.tag == 0 {
+		return nil
+	}
+	return t.tag
+}
+
+type amqpSource struct {
+	queue string
+	c     *amqpConf.Conf
+	sc    *sourceConf
+	conn  *amqp.Connection
+	ch    *amqp.Channel
+
+	mu sync.Mutex
+	// tag is the delivery tag of the last emitted message
+	tag uint64
+	// acked is the delivery tag of the last committed message. Acknowledging a tag twice closes the channel.
+	acked uint64
+}
+
+func (s *amqpSource) Configure(queue string, props map[string]interface{}) error {
+	if queue == "" {
+		return fmt.Errorf("datasource which indicates the queue should be defined")
+	}
+	c, err := amqpConf.GetConf(props)
+	if err != nil {
+		return err
+	}
+	sc := &sourceConf{
+		PrefetchCount: 100,
+	}
+	if err := cast.MapToStruct(props, sc); err != nil {
+		return fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	if sc.PrefetchCount < 0 {
+		return fmt.Errorf("prefetchCount must not be negative")
+	}
+	if len(sc.BindingKeys) > 0 && c.Exchange == "" {
+		return fmt.Errorf("exchange is required to bind the queue")
+	}
+	s.queue = queue
+	s.c = c
+	s.sc = sc
+	return nil
+}
+
+func (s *amqpSource) Open(ctx api.StreamContext, consumer chan<- api.SourceTuple, errCh chan<- error) {
+	logger := ctx.GetLogger()
+	deliveries, err := s.connect()
+	if err != nil {
+		infra.DrainError(ctx, err, errCh)
+		return
+	}
+	logger.Infof("amqp source consumes queue %s", s.queue)
+	closed := s.conn.NotifyClose(make(chan *amqp.Error, 1))
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-closed:
+			if e != nil {
+				infra.DrainError(ctx, fmt.Errorf("amqp connection closed: %v", e), errCh)
+			}
+			return
+		case d, ok := <-deliveries:
+			if !ok {
+				if ctx.Err() == nil {
+					infra.DrainError(ctx, fmt.Errorf("amqp consumer of queue %s is cancelled", s.queue), errCh)
+				}
+				return
+			}
+			if err := s.send(ctx, consumer, d); err != nil {
+				infra.DrainError(ctx, err, errCh)
+				return
+			}
+		}
+	}
+}
+
+func (s *amqpSource) connect() (<-chan amqp.Delivery, error) {
+	conn, ch, err := s.c.Connect("amqp-source")
+	if err != nil {
+		return nil, err
+	}
+	s.conn = conn
+	s.ch = ch
+	if s.sc.DeclareQueue {
+		if _, err := ch.QueueDeclare(s.queue, s.c.Durable, s.c.AutoDelete, s.sc.Exclusive, false, nil); err != nil {
+			return nil, fmt.Errorf("fail to declare queue %s: %v", s.queue, err)
+		}
+	}
+	for _, key := range s.sc.BindingKeys {
+		if err := ch.QueueBind(s.queue, key, s.c.Exchange, false, nil); err != nil {
+			return nil, fmt.Errorf("fail to bind queue %s to exchange %s with key %s: %v", s.queue, s.c.Exchange, key, err)
+		}
+	}
+	if err := ch.Qos(s.sc.PrefetchCount, 0, false); err != nil {
+		return nil, fmt.Errorf("fail to set prefetch count: %v", err)
+	}
+	deliveries, err := ch.Consume(s.queue, s.sc.ConsumerTag, false, s.sc.Exclusive, false, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fail to consume queue %s: %v", s.queue, err)
+	}
+	return deliveries, nil
+}
+
+func (s *amqpSource) send(ctx api.StreamContext, consumer chan<- api.SourceTuple, d amqp.Delivery) error {
+	logger := ctx.GetLogger()
+	rcvTime := conf.GetNow()
+	meta := map[string]interface{}{
+		"exchange":    d.Exchange,
+		"routingKey":  d.RoutingKey,
+		"contentType": d.ContentType,
+		"messageId":   d.MessageId,
+		"redelivered": d.Redelivered,
+	}
+	if len(d.Headers) > 0 {
+		meta["headers"] = map[string]interface{}(d.Headers)
+	}
+	results, err := ctx.DecodeIntoList(d.Body)
+	if err != nil {
+		// The invalid message is rejected without requeue to not be delivered again
+		logger.Errorf("Invalid data format, cannot decode %s with error %s", d.Body, err)
+		return d.Nack(false, false)
+	}
+	s.mu.Lock()
+	s.tag = d.DeliveryTag
+	s.mu.Unlock()
+	for i, result := range results {
+		tuple := &amqpTuple{DefaultSourceTuple: api.NewDefaultSourceTupleWithTime(result, meta, rcvTime)}
+		// Only acknowledge the message when all its decoded data are delivered
+		if s.sc.EnableAck && i == len(results)-1 {
+			tuple.tag = d.DeliveryTag
+		}
+		select {
+		case consumer <- tuple:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	if !s.sc.EnableAck || len(results) == 0 {
+		if err := d.Ack(false); err != nil {
+			return fmt.Errorf("fail to ack message: %v", err)
+		}
+	}
+	return nil
+}
+
+func (s *amqpSource) GetOffset() (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tag, nil
+}
+
+// Rewind does nothing because the broker redelivers the unacknowledged messages after reconnecting
+func (s *amqpSource) Rewind(_ interface{}) error {
+	conf.Log.Infof("amqp source resumes from the unacknowledged messages of queue %s", s.queue)
+	return nil
+}
+
+func (s *amqpSource) ResetOffset(_ map[string]interface{}) error {
+	return errors.New("amqp source not support reset offset")
+}
+
+// CommitOffset acknowledges all the messages up to the delivery tag
+func (s *amqpSource) CommitOffset(_ api.StreamContext, offset interface{}) error {
+	tag, ok := offset.(uint64)
+	if !ok {
+		return fmt.Errorf("amqp source cannot commit offset %v", offset)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ch == nil || tag <= s.acked {
+		return nil
+	}
+	if err := s.ch.Ack(tag, true); err != nil {
+		return err
+	}
+	s.acked = tag
+	return nil
+}
+
+func (s *amqpSource) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Closing amqp source")
+	if s.conn != nil {
+		return s.conn.Close()
+	}
+	return nil
+}
+
+func GetSource() api.Source {
+	return &amqpSource{}
+}
@@ -0,0 +1,176 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coap
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/plgd-dev/go-coap/v3/message/pool"
+	"github.com/plgd-dev/go-coap/v3/udp/client"
+
+	"github.com/lf-edge/ekuiper/extensions/coap"
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+type sourceConf struct {
+	// Observe registers as an observer of the resource, otherwise the resource is polled by GET
+	Observe bool `json:"observe"`
+	// Interval is the polling interval in ms if observe is false
+	Interval int `json:"interval"`
+}
+
+type coapSource struct {
+	path string
+	sc   *sourceConf
+	c    *coap.ClientConf
+	conn *client.Conn
+}
+
+func (s *coapSource) Configure(path string, props map[string]interface{}) error {
+	sc := &sourceConf{
+		Observe:  true,
+		Interval: 1000,
+	}
+	if err := cast.MapToStruct(props, sc); err != nil {
+		return fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	if !sc.Observe && sc.Interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+	if path == "" {
+		return fmt.Errorf("datasource is required to specify the resource path")
+	}
+	c, err := coap.GetClientConf(props)
+	if err != nil {
+		return err
+	}
+	s.path = path
+	s.sc = sc
+	s.c = c
+	return nil
+}
+
+func (s *coapSource) Open(ctx api.StreamContext, consumer chan<- api.SourceTuple, errCh chan<- error) {
+	logger := ctx.GetLogger()
+	conn, err := s.c.Dial()
+	if err != nil {
+		errCh <- err
+		return
+	}
+	s.conn = conn
+	if s.sc.Observe {
+		s.observe(ctx, consumer, errCh)
+	} else {
+		s.poll(ctx, consumer)
+	}
+	logger.Infof("coap source of %s%s done", s.c.Server, s.path)
+}
+
+func (s *coapSource) observe(ctx api.StreamContext, consumer chan<- api.SourceTuple, errCh chan<- error) {
+	logger := ctx.GetLogger()
+	obs, err := s.conn.Observe(ctx, s.path, func(msg *pool.Message) {
+		s.send(ctx, consumer, msg)
+	})
+	if err != nil {
+		errCh <- fmt.Errorf("fail to observe %s: %v", s.path, err)
+		return
+	}
+	logger.Infof("coap source observes %s%s", s.c.Server, s.path)
+	select {
+	case <-ctx.Done():
+	case <-s.conn.Done():
+		errCh <- fmt.Errorf("coap connection to %s is closed", s.c.Server)
+	}
+	cctx, cancel := s.c.RequestContext(context.Background())
+	defer cancel()
+	_ = obs.Cancel(cctx)
+}
+
+func (s *coapSource) poll(ctx api.StreamContext, consumer chan<- api.SourceTuple) {
+	logger := ctx.GetLogger()
+	logger.Infof("coap source polls %s%s every %d ms", s.c.Server, s.path, s.sc.Interval)
+	t := time.NewTicker(time.Duration(s.sc.Interval) * time.Millisecond)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			rctx, cancel := s.c.RequestContext(ctx)
+			msg, err := s.conn.Get(rctx, s.path)
+			cancel()
+			if err != nil {
+				logger.Errorf("coap source get %s error: %v", s.path, err)
+				continue
+			}
+			s.send(ctx, consumer, msg)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *coapSource) send(ctx api.StreamContext, consumer chan<- api.SourceTuple, msg *pool.Message) {
+	logger := ctx.GetLogger()
+	if err := coap.CheckResponse(msg); err != nil {
+		logger.Errorf("coap source receive error of %s: %v", s.path, err)
+		return
+	}
+	payload, err := msg.ReadBody()
+	if err != nil {
+		logger.Errorf("coap source read body error: %v", err)
+		return
+	}
+	if len(payload) == 0 {
+		return
+	}
+	rcvTime := conf.GetNow()
+	meta := map[string]interface{}{
+		"path": s.path,
+		"code": msg.Code().String(),
+	}
+	if cf, err := msg.ContentFormat(); err == nil {
+		meta["contentFormat"] = cf.String()
+	}
+	if seq, err := msg.Observe(); err == nil {
+		meta["observe"] = seq
+	}
+	results, err := ctx.DecodeIntoList(payload)
+	if err != nil {
+		logger.Errorf("Invalid data format, cannot decode %v with error %s", payload, err)
+		return
+	}
+	for _, result := range results {
+		select {
+		case consumer <- api.NewDefaultSourceTupleWithTime(result, meta, rcvTime):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *coapSource) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Closing coap source")
+	if s.conn != nil {
+		return s.conn.Close()
+	}
+	return nil
+}
+
+func GetSource() api.Source {
+	return &coapSource{}
+}
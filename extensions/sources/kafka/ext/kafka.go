@@ -26,6 +26,7 @@ import (
 	"github.com/lf-edge/ekuiper/extensions/kafka"
 	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/pkg/cert"
+	"github.com/lf-edge/ekuiper/internal/pkg/netx"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/cast"
 )
@@ -34,7 +35,10 @@ type KafkaSource struct {
 	reader    *kafkago.Reader
 	offset    int64
 	tlsConfig *tls.Config
+	dialer    *netx.Dialer
 	sc        *kafkaSourceConf
+	// partition is set by the rule in partitioned mode, -1 if not set
+	partition int
 }
 
 type kafkaSourceConf struct {
@@ -44,6 +48,21 @@ type kafkaSourceConf struct {
 	Partition   int    `json:"partition"`
 	MaxAttempts int    `json:"maxAttempts"`
 	MaxBytes    int    `json:"maxBytes"`
+	// EnableAck is set by the rule. The consumer group offset is only committed after the data is delivered
+	EnableAck bool `json:"enableAck"`
+}
+
+// kafkaTuple carries the message position so that the consumer group offset can be committed after delivery
+type kafkaTuple struct {
+	*api.DefaultSourceTuple
+	msg *kafkago.Message
+}
+
+func (t *kafkaTuple) Offset() interface{} {
+	if t.msg == nil {
+		return nil
+	}
+	return *t.msg
 }
 
 func (s *KafkaSource) Ping(d string, props map[string]interface{}) error {
@@ -51,7 +70,7 @@ func (s *KafkaSource) Ping(d string, props map[string]interface{}) error {
 		return err
 	}
 	for _, broker := range strings.Split(s.sc.Brokers, ",") {
-		err := ping(s.tlsConfig, broker)
+		err := ping(s.tlsConfig, s.dialer, broker)
 		if err != nil {
 			return err
 		}
@@ -102,6 +121,12 @@ func (s *KafkaSource) Configure(topic string, props map[string]interface{}) erro
 	if err := kConf.validate(); err != nil {
 		return err
 	}
+	if s.partition >= 0 {
+		if kConf.GroupID != "" {
+			return fmt.Errorf("kafka source with groupID cannot run in partitioned mode, the partitions are assigned by the consumer group")
+		}
+		kConf.Partition = s.partition
+	}
 	tlsConfig, err := cert.GenTLSConfig(props, "kafka-source")
 	if err != nil {
 		conf.Log.Errorf("kafka tls conf error: %v", err)
@@ -121,6 +146,11 @@ func (s *KafkaSource) Configure(topic string, props map[string]interface{}) erro
 		conf.Log.Errorf("kafka sasl mechanism error: %v", err)
 		return err
 	}
+	dialer, err := netx.NewDialer(props)
+	if err != nil {
+		return err
+	}
+	s.dialer = dialer
 	readerConfig := kConf.GetReaderConfig(topic)
 	conf.Log.Infof("topic: %s, brokers: %v", readerConfig.Topic, readerConfig.Brokers)
 	readerConfig.Dialer = &kafkago.Dialer{
@@ -128,17 +158,30 @@ func (s *KafkaSource) Configure(topic string, props map[string]interface{}) erro
 		DualStack:     true,
 		TLS:           tlsConfig,
 		SASLMechanism: mechanism,
+		DialFunc:      dialer.DialContext,
 	}
 	reader := kafkago.NewReader(readerConfig)
 	s.reader = reader
 	s.sc = kConf
-	if err := s.reader.SetOffset(kafkago.LastOffset); err != nil {
-		return err
+	// The consumer group manages the offset by itself
+	if kConf.GroupID == "" {
+		if kConf.EnableAck {
+			conf.Log.Warnf("kafka source without groupID cannot commit offset, ack is ignored")
+		}
+		if err := s.reader.SetOffset(kafkago.LastOffset); err != nil {
+			return err
+		}
 	}
 	conf.Log.Infof("kafka source got configured.")
 	return nil
 }
 
+// SetPartition reads the partition of the topic only. The partitions should be the count of the topic partitions.
+func (s *KafkaSource) SetPartition(partition int, _ int) error {
+	s.partition = partition
+	return nil
+}
+
 func (s *KafkaSource) Open(ctx api.StreamContext, consumer chan<- api.SourceTuple, errCh chan<- error) {
 	defer s.reader.Close()
 	logger := ctx.GetLogger()
@@ -148,7 +191,16 @@ func (s *KafkaSource) Open(ctx api.StreamContext, consumer chan<- api.SourceTupl
 			return
 		default:
 		}
-		msg, err := s.reader.ReadMessage(ctx)
+		var (
+			msg kafkago.Message
+			err error
+		)
+		manualCommit := s.sc.EnableAck && s.sc.GroupID != ""
+		if manualCommit {
+			msg, err = s.reader.FetchMessage(ctx)
+		} else {
+			msg, err = s.reader.ReadMessage(ctx)
+		}
 		if err != nil {
 			logger.Errorf("Recv kafka error %v", err)
 			errCh <- err
@@ -161,9 +213,19 @@ func (s *KafkaSource) Open(ctx api.StreamContext, consumer chan<- api.SourceTupl
 			errCh <- err
 			return
 		}
-		for _, data := range dataList {
+		for i, data := range dataList {
 			rcvTime := conf.GetNow()
-			consumer <- api.NewDefaultSourceTupleWithTime(data, nil, rcvTime)
+			tuple := api.NewDefaultSourceTupleWithTime(data, nil, rcvTime)
+			if manualCommit {
+				kt := &kafkaTuple{DefaultSourceTuple: tuple}
+				// Only commit the message when all its decoded data are delivered
+				if i == len(dataList)-1 {
+					kt.msg = &kafkago.Message{Topic: msg.Topic, Partition: msg.Partition, Offset: msg.Offset}
+				}
+				consumer <- kt
+			} else {
+				consumer <- tuple
+			}
 		}
 	}
 }
@@ -173,6 +235,10 @@ func (s *KafkaSource) Close(_ api.StreamContext) error {
 }
 
 func (s *KafkaSource) Rewind(offset interface{}) error {
+	if s.sc != nil && s.sc.GroupID != "" {
+		conf.Log.Infof("kafka source with consumer group %s resumes from the committed offset", s.sc.GroupID)
+		return nil
+	}
 	conf.Log.Infof("set kafka source offset: %v", offset)
 	offsetV := s.offset //nolint:staticcheck
 	switch v := offset.(type) {
@@ -200,12 +266,20 @@ func (s *KafkaSource) GetOffset() (interface{}, error) {
 	return s.offset, nil
 }
 
+func (s *KafkaSource) CommitOffset(ctx api.StreamContext, offset interface{}) error {
+	msg, ok := offset.(kafkago.Message)
+	if !ok {
+		return fmt.Errorf("kafka source cannot commit offset %v", offset)
+	}
+	return s.reader.CommitMessages(ctx, msg)
+}
+
 func GetSource() api.Source {
-	return &KafkaSource{}
+	return &KafkaSource{partition: -1}
 }
 
-func ping(tlsConfig *tls.Config, address string) error {
-	d := &kafkago.Dialer{TLS: tlsConfig}
+func ping(tlsConfig *tls.Config, dialer *netx.Dialer, address string) error {
+	d := &kafkago.Dialer{TLS: tlsConfig, DialFunc: dialer.DialContext}
 	c, err := d.Dial("tcp", address)
 	if err != nil {
 		return err
@@ -0,0 +1,99 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modbus
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lf-edge/ekuiper/extensions/modbus"
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+type sourceConf struct {
+	// Interval is the polling interval in ms
+	Interval int `json:"interval"`
+}
+
+type modbusSource struct {
+	interval int
+	c        *modbus.Conf
+	client   *modbus.Client
+}
+
+func (s *modbusSource) Configure(_ string, props map[string]interface{}) error {
+	sc := &sourceConf{Interval: 1000}
+	if err := cast.MapToStruct(props, sc); err != nil {
+		return fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	if sc.Interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+	c, err := modbus.GetConf(props)
+	if err != nil {
+		return err
+	}
+	s.interval = sc.Interval
+	s.c = c
+	return nil
+}
+
+func (s *modbusSource) Open(ctx api.StreamContext, consumer chan<- api.SourceTuple, errCh chan<- error) {
+	logger := ctx.GetLogger()
+	s.client = modbus.NewClient(s.c)
+	if err := s.client.Connect(); err != nil {
+		errCh <- err
+		return
+	}
+	logger.Infof("modbus source polls %d registers of %s every %d ms", len(s.c.Registers), s.c.Url, s.interval)
+	t := time.NewTicker(time.Duration(s.interval) * time.Millisecond)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			// the connection is closed on error and reopened in the next poll
+			result, err := s.client.Read()
+			if err != nil {
+				logger.Errorf("modbus source read error: %v", err)
+				continue
+			}
+			meta := map[string]interface{}{
+				"url":    s.c.Url,
+				"unitId": s.c.UnitId,
+			}
+			select {
+			case consumer <- api.NewDefaultSourceTupleWithTime(result, meta, conf.GetNow()):
+			case <-ctx.Done():
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *modbusSource) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Closing modbus source")
+	if s.client != nil {
+		s.client.Close()
+	}
+	return nil
+}
+
+func GetSource() api.Source {
+	return &modbusSource{}
+}
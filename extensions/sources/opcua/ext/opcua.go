@@ -0,0 +1,396 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opcua
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gopcua/opcua"
+	"github.com/gopcua/opcua/id"
+	"github.com/gopcua/opcua/ua"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+const (
+	deadbandNone     = "none"
+	deadbandAbsolute = "absolute"
+	deadbandPercent  = "percent"
+
+	authAnonymous   = "anonymous"
+	authUsername    = "username"
+	authCertificate = "certificate"
+
+	// maxBrowseDepth limits the recursive browsing of the configured nodes
+	maxBrowseDepth = 10
+)
+
+type sourceConf struct {
+	Endpoint string `json:"endpoint"`
+	// Nodes are the node ids like ns=2;s=Temperature. The node ids in the datasource are appended
+	Nodes []string `json:"nodes"`
+	// Browse monitors all the variables under the nodes instead of the nodes themselves
+	Browse bool `json:"browse"`
+	// SamplingInterval is the interval in ms of the server to sample the values
+	SamplingInterval int `json:"samplingInterval"`
+	// PublishingInterval is the interval in ms of the server to send the change notifications
+	PublishingInterval int     `json:"publishingInterval"`
+	QueueSize          int     `json:"queueSize"`
+	DeadbandType       string  `json:"deadbandType"`
+	DeadbandValue      float64 `json:"deadbandValue"`
+	SecurityPolicy     string  `json:"securityPolicy"`
+	SecurityMode       string  `json:"securityMode"`
+	CertificationPath  string  `json:"certificationPath"`
+	PrivateKeyPath     string  `json:"privateKeyPath"`
+	AuthMode           string  `json:"authMode"`
+	Username           string  `json:"username"`
+	Password           string  `json:"password"`
+}
+
+// monitoredNode is a node to subscribe, the client handle of its monitored item is its index
+type monitoredNode struct {
+	id   *ua.NodeID
+	name string
+}
+
+type opcuaSource struct {
+	c      *sourceConf
+	ids    []*ua.NodeID
+	client *opcua.Client
+}
+
+func (s *opcuaSource) Configure(datasource string, props map[string]interface{}) error {
+	c := &sourceConf{
+		SamplingInterval:   1000,
+		PublishingInterval: 1000,
+		QueueSize:          1,
+		DeadbandType:       deadbandNone,
+		SecurityPolicy:     "None",
+		SecurityMode:       "None",
+		AuthMode:           authAnonymous,
+	}
+	if err := cast.MapToStruct(props, c); err != nil {
+		return fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	if c.Endpoint == "" {
+		return fmt.Errorf("endpoint is required")
+	}
+	for _, n := range strings.Split(datasource, ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			c.Nodes = append(c.Nodes, n)
+		}
+	}
+	if len(c.Nodes) == 0 {
+		return fmt.Errorf("nodes are required, set them in the nodes property or the datasource")
+	}
+	ids := make([]*ua.NodeID, 0, len(c.Nodes))
+	for _, n := range c.Nodes {
+		nid, err := ua.ParseNodeID(n)
+		if err != nil {
+			return fmt.Errorf("invalid node id %s: %v", n, err)
+		}
+		ids = append(ids, nid)
+	}
+	if c.SamplingInterval < 0 || c.PublishingInterval <= 0 {
+		return fmt.Errorf("samplingInterval must not be negative and publishingInterval must be positive")
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = 1
+	}
+	switch c.DeadbandType {
+	case deadbandNone, deadbandAbsolute, deadbandPercent:
+	default:
+		return fmt.Errorf("deadbandType must be none, absolute or percent")
+	}
+	if c.DeadbandValue < 0 || (c.DeadbandType == deadbandPercent && c.DeadbandValue > 100) {
+		return fmt.Errorf("invalid deadbandValue %v", c.DeadbandValue)
+	}
+	if ua.MessageSecurityModeFromString(c.SecurityMode) == ua.MessageSecurityModeInvalid {
+		return fmt.Errorf("securityMode must be None, Sign or SignAndEncrypt")
+	}
+	if (c.SecurityMode != "None" || c.AuthMode == authCertificate) && (c.CertificationPath == "" || c.PrivateKeyPath == "") {
+		return fmt.Errorf("certificationPath and privateKeyPath are required for the security mode %s or the certificate auth", c.SecurityMode)
+	}
+	switch c.AuthMode {
+	case authAnonymous, authCertificate:
+	case authUsername:
+		if c.Username == "" {
+			return fmt.Errorf("username is required for the username auth")
+		}
+	default:
+		return fmt.Errorf("authMode must be anonymous, username or certificate")
+	}
+	s.c = c
+	s.ids = ids
+	return nil
+}
+
+func (s *opcuaSource) connect(ctx context.Context) error {
+	c := s.c
+	endpoints, err := opcua.GetEndpoints(ctx, c.Endpoint)
+	if err != nil {
+		return fmt.Errorf("fail to get the endpoints of %s: %v", c.Endpoint, err)
+	}
+	policy := ua.FormatSecurityPolicyURI(c.SecurityPolicy)
+	ep := opcua.SelectEndpoint(endpoints, policy, ua.MessageSecurityModeFromString(c.SecurityMode))
+	if ep == nil {
+		return fmt.Errorf("no endpoint of %s matches the security policy %s and mode %s", c.Endpoint, c.SecurityPolicy, c.SecurityMode)
+	}
+	opts := []opcua.Option{
+		opcua.SecurityPolicy(policy),
+		opcua.SecurityModeString(c.SecurityMode),
+	}
+	if c.CertificationPath != "" && c.PrivateKeyPath != "" {
+		opts = append(opts, opcua.CertificateFile(c.CertificationPath), opcua.PrivateKeyFile(c.PrivateKeyPath))
+	}
+	switch c.AuthMode {
+	case authUsername:
+		opts = append(opts, opcua.AuthUsername(c.Username, c.Password), opcua.SecurityFromEndpoint(ep, ua.UserTokenTypeUserName))
+	case authCertificate:
+		cert, err := tls.LoadX509KeyPair(c.CertificationPath, c.PrivateKeyPath)
+		if err != nil {
+			return fmt.Errorf("fail to load the certificate: %v", err)
+		}
+		opts = append(opts, opcua.AuthCertificate(cert.Certificate[0]), opcua.SecurityFromEndpoint(ep, ua.UserTokenTypeCertificate))
+	default:
+		opts = append(opts, opcua.AuthAnonymous(), opcua.SecurityFromEndpoint(ep, ua.UserTokenTypeAnonymous))
+	}
+	client, err := opcua.NewClient(ep.EndpointURL, opts...)
+	if err != nil {
+		return err
+	}
+	if err := client.Connect(ctx); err != nil {
+		return fmt.Errorf("fail to connect to %s: %v", ep.EndpointURL, err)
+	}
+	s.client = client
+	return nil
+}
+
+// nodes returns the nodes to monitor. If browse is set, the variables under the configured nodes are returned.
+func (s *opcuaSource) nodes(ctx context.Context) ([]monitoredNode, error) {
+	var result []monitoredNode
+	for _, nid := range s.ids {
+		n := s.client.Node(nid)
+		if !s.c.Browse {
+			result = append(result, monitoredNode{id: nid, name: s.nodeName(ctx, n)})
+			continue
+		}
+		if err := s.browse(ctx, n, 0, &result); err != nil {
+			return nil, fmt.Errorf("fail to browse node %s: %v", nid, err)
+		}
+	}
+	// use the node id as the field name if the browse names are duplicate
+	names := make(map[string]int, len(result))
+	for _, n := range result {
+		names[n.name]++
+	}
+	for i, n := range result {
+		if names[n.name] > 1 {
+			result[i].name = n.id.String()
+		}
+	}
+	return result, nil
+}
+
+func (s *opcuaSource) browse(ctx context.Context, n *opcua.Node, level int, result *[]monitoredNode) error {
+	if level > maxBrowseDepth {
+		return nil
+	}
+	nc, err := n.NodeClass(ctx)
+	if err != nil {
+		return err
+	}
+	if nc == ua.NodeClassVariable {
+		*result = append(*result, monitoredNode{id: n.ID, name: s.nodeName(ctx, n)})
+	}
+	refs, err := n.ReferencedNodes(ctx, id.HierarchicalReferences, ua.BrowseDirectionForward, ua.NodeClassAll, true)
+	if err != nil {
+		return err
+	}
+	for _, r := range refs {
+		if err := s.browse(ctx, r, level+1, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *opcuaSource) nodeName(ctx context.Context, n *opcua.Node) string {
+	bn, err := n.BrowseName(ctx)
+	if err != nil || bn == nil || bn.Name == "" {
+		return n.ID.String()
+	}
+	return bn.Name
+}
+
+func (s *opcuaSource) monitorRequest(nid *ua.NodeID, handle uint32) *ua.MonitoredItemCreateRequest {
+	params := &ua.MonitoringParameters{
+		ClientHandle:     handle,
+		SamplingInterval: float64(s.c.SamplingInterval),
+		QueueSize:        uint32(s.c.QueueSize),
+		DiscardOldest:    true,
+	}
+	if s.c.DeadbandType != deadbandNone {
+		dt := ua.DeadbandTypeAbsolute
+		if s.c.DeadbandType == deadbandPercent {
+			dt = ua.DeadbandTypePercent
+		}
+		params.Filter = ua.NewExtensionObject(&ua.DataChangeFilter{
+			Trigger:       ua.DataChangeTriggerStatusValue,
+			DeadbandType:  uint32(dt),
+			DeadbandValue: s.c.DeadbandValue,
+		})
+	}
+	return &ua.MonitoredItemCreateRequest{
+		ItemToMonitor: &ua.ReadValueID{
+			NodeID:      nid,
+			AttributeID: ua.AttributeIDValue,
+		},
+		MonitoringMode:      ua.MonitoringModeReporting,
+		RequestedParameters: params,
+	}
+}
+
+func (s *opcuaSource) Open(ctx api.StreamContext, consumer chan<- api.SourceTuple, errCh chan<- error) {
+	logger := ctx.GetLogger()
+	if err := s.connect(ctx); err != nil {
+		errCh <- err
+		return
+	}
+	nodes, err := s.nodes(ctx)
+	if err != nil {
+		errCh <- err
+		return
+	}
+	if len(nodes) == 0 {
+		errCh <- fmt.Errorf("no variable node is found to monitor")
+		return
+	}
+	notifyCh := make(chan *opcua.PublishNotificationData, 16)
+	sub, err := s.client.Subscribe(ctx, &opcua.SubscriptionParameters{
+		Interval: time.Duration(s.c.PublishingInterval) * time.Millisecond,
+	}, notifyCh)
+	if err != nil {
+		errCh <- fmt.Errorf("fail to create the subscription: %v", err)
+		return
+	}
+	defer func() {
+		_ = sub.Cancel(context.Background())
+	}()
+	reqs := make([]*ua.MonitoredItemCreateRequest, len(nodes))
+	for i, n := range nodes {
+		reqs[i] = s.monitorRequest(n.id, uint32(i))
+	}
+	res, err := sub.Monitor(ctx, ua.TimestampsToReturnBoth, reqs...)
+	if err != nil {
+		errCh <- fmt.Errorf("fail to monitor the nodes: %v", err)
+		return
+	}
+	for i, r := range res.Results {
+		if r.StatusCode != ua.StatusOK {
+			logger.Warnf("fail to monitor node %s: %v", nodes[i].id, r.StatusCode)
+		}
+	}
+	logger.Infof("opcua source subscribed %d nodes of %s", len(nodes), s.c.Endpoint)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-notifyCh:
+			if n.Error != nil {
+				logger.Errorf("opcua subscription error: %v", n.Error)
+				continue
+			}
+			dc, ok := n.Value.(*ua.DataChangeNotification)
+			if !ok {
+				continue
+			}
+			result, meta := changes(dc, nodes)
+			if len(result) == 0 {
+				continue
+			}
+			select {
+			case consumer <- api.NewDefaultSourceTupleWithTime(result, meta, conf.GetNow()):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// changes converts the data change notification to the tuple message and the meta of each field
+func changes(dc *ua.DataChangeNotification, nodes []monitoredNode) (map[string]interface{}, map[string]interface{}) {
+	result := make(map[string]interface{}, len(dc.MonitoredItems))
+	meta := make(map[string]interface{}, len(dc.MonitoredItems))
+	for _, item := range dc.MonitoredItems {
+		if item == nil || item.Value == nil || int(item.ClientHandle) >= len(nodes) {
+			continue
+		}
+		n := nodes[item.ClientHandle]
+		dv := item.Value
+		if dv.Value != nil {
+			result[n.name] = convert(dv.Value.Value())
+		} else {
+			result[n.name] = nil
+		}
+		m := map[string]interface{}{
+			"nodeId": n.id.String(),
+			"status": dv.Status.Error(),
+		}
+		if !dv.SourceTimestamp.IsZero() {
+			m["sourceTimestamp"] = dv.SourceTimestamp.UnixMilli()
+		}
+		if !dv.ServerTimestamp.IsZero() {
+			m["serverTimestamp"] = dv.ServerTimestamp.UnixMilli()
+		}
+		meta[n.name] = m
+	}
+	return result, meta
+}
+
+func convert(v interface{}) interface{} {
+	switch vt := v.(type) {
+	case *ua.LocalizedText:
+		return vt.Text
+	case *ua.QualifiedName:
+		return vt.Name
+	case *ua.NodeID:
+		return vt.String()
+	case *ua.ExpandedNodeID:
+		return vt.String()
+	case ua.StatusCode:
+		return uint32(vt)
+	default:
+		return v
+	}
+}
+
+func (s *opcuaSource) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Closing opcua source")
+	if s.client != nil {
+		return s.client.Close(context.Background())
+	}
+	return nil
+}
+
+func GetSource() api.Source {
+	return &opcuaSource{}
+}
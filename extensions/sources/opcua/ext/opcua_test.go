@@ -0,0 +1,107 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opcua
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gopcua/opcua/ua"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigure(t *testing.T) {
+	tests := []struct {
+		name  string
+		ds    string
+		props map[string]interface{}
+		err   string
+	}{
+		{
+			name:  "datasource nodes",
+			ds:    "ns=2;s=Temperature, ns=2;i=1001",
+			props: map[string]interface{}{"endpoint": "opc.tcp://127.0.0.1:4840"},
+		},
+		{
+			name:  "no endpoint",
+			ds:    "ns=2;s=Temperature",
+			props: map[string]interface{}{},
+			err:   "endpoint is required",
+		},
+		{
+			name:  "no nodes",
+			props: map[string]interface{}{"endpoint": "opc.tcp://127.0.0.1:4840"},
+			err:   "nodes are required, set them in the nodes property or the datasource",
+		},
+		{
+			name:  "invalid deadband",
+			props: map[string]interface{}{"endpoint": "opc.tcp://127.0.0.1:4840", "nodes": []interface{}{"ns=2;s=Temperature"}, "deadbandType": "relative"},
+			err:   "deadbandType must be none, absolute or percent",
+		},
+		{
+			name:  "invalid percent",
+			props: map[string]interface{}{"endpoint": "opc.tcp://127.0.0.1:4840", "nodes": []interface{}{"ns=2;s=Temperature"}, "deadbandType": "percent", "deadbandValue": 120},
+			err:   "invalid deadbandValue 120",
+		},
+		{
+			name:  "no certificate",
+			props: map[string]interface{}{"endpoint": "opc.tcp://127.0.0.1:4840", "nodes": []interface{}{"ns=2;s=Temperature"}, "securityPolicy": "Basic256Sha256", "securityMode": "SignAndEncrypt"},
+			err:   "certificationPath and privateKeyPath are required for the security mode SignAndEncrypt or the certificate auth",
+		},
+		{
+			name:  "no username",
+			props: map[string]interface{}{"endpoint": "opc.tcp://127.0.0.1:4840", "nodes": []interface{}{"ns=2;s=Temperature"}, "authMode": "username"},
+			err:   "username is required for the username auth",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &opcuaSource{}
+			err := s.Configure(tt.ds, tt.props)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, s.ids, 2)
+			assert.Equal(t, 1000, s.c.SamplingInterval)
+		})
+	}
+}
+
+func TestChanges(t *testing.T) {
+	ts := time.UnixMilli(1700000000000)
+	nodes := []monitoredNode{
+		{id: ua.NewStringNodeID(2, "Temperature"), name: "Temperature"},
+		{id: ua.NewNumericNodeID(2, 1001), name: "State"},
+	}
+	dc := &ua.DataChangeNotification{
+		MonitoredItems: []*ua.MonitoredItemNotification{
+			{ClientHandle: 0, Value: &ua.DataValue{Value: ua.MustVariant(23.5), Status: ua.StatusOK, SourceTimestamp: ts}},
+			{ClientHandle: 1, Value: &ua.DataValue{Value: ua.MustVariant(ua.NewLocalizedText("running"))}},
+			// unknown handle
+			{ClientHandle: 5, Value: &ua.DataValue{Value: ua.MustVariant(int32(1))}},
+		},
+	}
+	result, meta := changes(dc, nodes)
+	assert.Equal(t, map[string]interface{}{"Temperature": 23.5, "State": "running"}, result)
+	assert.Equal(t, map[string]interface{}{
+		"nodeId":          "ns=2;s=Temperature",
+		"status":          ua.StatusOK.Error(),
+		"sourceTimestamp": int64(1700000000000),
+	}, meta["Temperature"])
+	assert.Equal(t, "ns=2;i=1001", meta["State"].(map[string]interface{})["nodeId"])
+}
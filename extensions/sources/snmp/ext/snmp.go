@@ -0,0 +1,331 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snmp
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gosnmp/gosnmp"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+const (
+	version2c = "v2c"
+	version3  = "v3"
+
+	levelNoAuthNoPriv = "noAuthNoPriv"
+	levelAuthNoPriv   = "authNoPriv"
+	levelAuthPriv     = "authPriv"
+)
+
+var (
+	authProtocols = map[string]gosnmp.SnmpV3AuthProtocol{
+		"MD5":    gosnmp.MD5,
+		"SHA":    gosnmp.SHA,
+		"SHA224": gosnmp.SHA224,
+		"SHA256": gosnmp.SHA256,
+		"SHA384": gosnmp.SHA384,
+		"SHA512": gosnmp.SHA512,
+	}
+	privProtocols = map[string]gosnmp.SnmpV3PrivProtocol{
+		"DES":    gosnmp.DES,
+		"AES":    gosnmp.AES,
+		"AES192": gosnmp.AES192,
+		"AES256": gosnmp.AES256,
+	}
+)
+
+// object is an oid to get or a subtree to walk, the name is the field name of the result
+type object struct {
+	Name string `json:"name"`
+	Oid  string `json:"oid"`
+}
+
+type sourceConf struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+	// Version is v2c or v3
+	Version   string `json:"version"`
+	Community string `json:"community"`
+	// Interval is the polling interval in ms
+	Interval int `json:"interval"`
+	// Timeout is the timeout in ms of each request
+	Timeout int `json:"timeout"`
+	Retries int `json:"retries"`
+	// Oids are got in each poll
+	Oids []*object `json:"oids"`
+	// Walks are the subtrees walked in each poll
+	Walks []*object `json:"walks"`
+	// The v3 security settings
+	User          string `json:"user"`
+	SecurityLevel string `json:"securityLevel"`
+	AuthProtocol  string `json:"authProtocol"`
+	AuthPassword  string `json:"authPassword"`
+	PrivProtocol  string `json:"privProtocol"`
+	PrivPassword  string `json:"privPassword"`
+	ContextName   string `json:"contextName"`
+}
+
+type snmpSource struct {
+	c      *sourceConf
+	client *gosnmp.GoSNMP
+}
+
+func (s *snmpSource) Configure(_ string, props map[string]interface{}) error {
+	c := &sourceConf{
+		Port:          161,
+		Version:       version2c,
+		Community:     "public",
+		Interval:      10000,
+		Timeout:       2000,
+		Retries:       1,
+		SecurityLevel: levelNoAuthNoPriv,
+		AuthProtocol:  "SHA",
+		PrivProtocol:  "AES",
+	}
+	if err := cast.MapToStruct(props, c); err != nil {
+		return fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	if err := c.validate(); err != nil {
+		return err
+	}
+	s.c = c
+	return nil
+}
+
+func (c *sourceConf) validate() error {
+	if c.Host == "" {
+		return fmt.Errorf("host is required")
+	}
+	if c.Port <= 0 || c.Port > 65535 {
+		return fmt.Errorf("port %d is out of range", c.Port)
+	}
+	if c.Interval <= 0 || c.Timeout <= 0 || c.Retries < 0 {
+		return fmt.Errorf("interval and timeout must be positive and retries must not be negative")
+	}
+	if len(c.Oids) == 0 && len(c.Walks) == 0 {
+		return fmt.Errorf("oids or walks are required")
+	}
+	names := make(map[string]struct{}, len(c.Oids)+len(c.Walks))
+	for _, o := range append(append([]*object{}, c.Oids...), c.Walks...) {
+		if o == nil || o.Name == "" || o.Oid == "" {
+			return fmt.Errorf("name and oid are required for each oid and walk")
+		}
+		if _, ok := names[o.Name]; ok {
+			return fmt.Errorf("name %s is duplicate", o.Name)
+		}
+		names[o.Name] = struct{}{}
+		o.Oid = strings.TrimPrefix(o.Oid, ".")
+	}
+	switch c.Version {
+	case version2c:
+		if c.Community == "" {
+			return fmt.Errorf("community is required for v2c")
+		}
+	case version3:
+		if c.User == "" {
+			return fmt.Errorf("user is required for v3")
+		}
+		switch c.SecurityLevel {
+		case levelNoAuthNoPriv:
+		case levelAuthPriv:
+			if _, ok := privProtocols[c.PrivProtocol]; !ok {
+				return fmt.Errorf("privProtocol must be DES, AES, AES192 or AES256")
+			}
+			if c.PrivPassword == "" {
+				return fmt.Errorf("privPassword is required for authPriv")
+			}
+			fallthrough
+		case levelAuthNoPriv:
+			if _, ok := authProtocols[c.AuthProtocol]; !ok {
+				return fmt.Errorf("authProtocol must be MD5, SHA, SHA224, SHA256, SHA384 or SHA512")
+			}
+			if c.AuthPassword == "" {
+				return fmt.Errorf("authPassword is required for %s", c.SecurityLevel)
+			}
+		default:
+			return fmt.Errorf("securityLevel must be noAuthNoPriv, authNoPriv or authPriv")
+		}
+	default:
+		return fmt.Errorf("version must be v2c or v3")
+	}
+	return nil
+}
+
+func (s *snmpSource) connect() error {
+	c := s.c
+	g := &gosnmp.GoSNMP{
+		Target:    c.Host,
+		Port:      uint16(c.Port),
+		Community: c.Community,
+		Version:   gosnmp.Version2c,
+		Timeout:   time.Duration(c.Timeout) * time.Millisecond,
+		Retries:   c.Retries,
+		MaxOids:   gosnmp.MaxOids,
+	}
+	if c.Version == version3 {
+		g.Version = gosnmp.Version3
+		g.SecurityModel = gosnmp.UserSecurityModel
+		g.ContextName = c.ContextName
+		sp := &gosnmp.UsmSecurityParameters{UserName: c.User}
+		switch c.SecurityLevel {
+		case levelAuthPriv:
+			g.MsgFlags = gosnmp.AuthPriv
+			sp.PrivacyProtocol = privProtocols[c.PrivProtocol]
+			sp.PrivacyPassphrase = c.PrivPassword
+			sp.AuthenticationProtocol = authProtocols[c.AuthProtocol]
+			sp.AuthenticationPassphrase = c.AuthPassword
+		case levelAuthNoPriv:
+			g.MsgFlags = gosnmp.AuthNoPriv
+			sp.AuthenticationProtocol = authProtocols[c.AuthProtocol]
+			sp.AuthenticationPassphrase = c.AuthPassword
+		default:
+			g.MsgFlags = gosnmp.NoAuthNoPriv
+		}
+		g.SecurityParameters = sp
+	}
+	if err := g.Connect(); err != nil {
+		return fmt.Errorf("fail to connect to %s:%d: %v", c.Host, c.Port, err)
+	}
+	s.client = g
+	return nil
+}
+
+// poll gets the oids and walks the subtrees. The walk result is a map of the oid suffixes under the subtree to the
+// values such as {"1": "eth0", "2": "eth1"} for the interface descriptions.
+func (s *snmpSource) poll() (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(s.c.Oids)+len(s.c.Walks))
+	for start := 0; start < len(s.c.Oids); start += gosnmp.MaxOids {
+		end := start + gosnmp.MaxOids
+		if end > len(s.c.Oids) {
+			end = len(s.c.Oids)
+		}
+		oids := make([]string, 0, end-start)
+		byOid := make(map[string]string, end-start)
+		for _, o := range s.c.Oids[start:end] {
+			oids = append(oids, o.Oid)
+			byOid[o.Oid] = o.Name
+		}
+		pkt, err := s.client.Get(oids)
+		if err != nil {
+			return nil, fmt.Errorf("fail to get oids: %v", err)
+		}
+		for _, v := range pkt.Variables {
+			if name, ok := byOid[strings.TrimPrefix(v.Name, ".")]; ok {
+				result[name] = convert(v)
+			}
+		}
+	}
+	for _, w := range s.c.Walks {
+		pdus, err := s.client.BulkWalkAll(w.Oid)
+		if err != nil {
+			return nil, fmt.Errorf("fail to walk %s: %v", w.Oid, err)
+		}
+		result[w.Name] = subtree(w.Oid, pdus)
+	}
+	return result, nil
+}
+
+func subtree(root string, pdus []gosnmp.SnmpPDU) map[string]interface{} {
+	m := make(map[string]interface{}, len(pdus))
+	for _, v := range pdus {
+		suffix := strings.TrimPrefix(strings.TrimPrefix(v.Name, "."), root)
+		m[strings.TrimPrefix(suffix, ".")] = convert(v)
+	}
+	return m
+}
+
+// convert changes the pdu value to the eKuiper types
+func convert(v gosnmp.SnmpPDU) interface{} {
+	switch v.Type {
+	case gosnmp.NoSuchObject, gosnmp.NoSuchInstance, gosnmp.EndOfMibView, gosnmp.Null:
+		return nil
+	case gosnmp.OctetString:
+		b, ok := v.Value.([]byte)
+		if !ok {
+			return v.Value
+		}
+		if utf8.Valid(b) {
+			return string(b)
+		}
+		return b
+	case gosnmp.ObjectIdentifier:
+		if oid, ok := v.Value.(string); ok {
+			return strings.TrimPrefix(oid, ".")
+		}
+		return v.Value
+	}
+	switch vt := v.Value.(type) {
+	case int:
+		return int64(vt)
+	case uint:
+		return int64(vt)
+	case uint32:
+		return int64(vt)
+	case float32:
+		return float64(vt)
+	default:
+		return v.Value
+	}
+}
+
+func (s *snmpSource) Open(ctx api.StreamContext, consumer chan<- api.SourceTuple, errCh chan<- error) {
+	logger := ctx.GetLogger()
+	if err := s.connect(); err != nil {
+		errCh <- err
+		return
+	}
+	logger.Infof("snmp source polls %s every %d ms", s.c.Host, s.c.Interval)
+	t := time.NewTicker(time.Duration(s.c.Interval) * time.Millisecond)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			result, err := s.poll()
+			if err != nil {
+				logger.Errorf("snmp source poll error: %v", err)
+				continue
+			}
+			meta := map[string]interface{}{
+				"host": s.c.Host,
+			}
+			select {
+			case consumer <- api.NewDefaultSourceTupleWithTime(result, meta, conf.GetNow()):
+			case <-ctx.Done():
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *snmpSource) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Closing snmp source")
+	if s.client != nil && s.client.Conn != nil {
+		return s.client.Conn.Close()
+	}
+	return nil
+}
+
+func GetSource() api.Source {
+	return &snmpSource{}
+}
@@ -0,0 +1,105 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snmp
+
+import (
+	"testing"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigure(t *testing.T) {
+	oids := []interface{}{map[string]interface{}{"name": "uptime", "oid": ".1.3.6.1.2.1.1.3.0"}}
+	tests := []struct {
+		name  string
+		props map[string]interface{}
+		err   string
+	}{
+		{
+			name:  "v2c",
+			props: map[string]interface{}{"host": "127.0.0.1", "oids": oids, "walks": []interface{}{map[string]interface{}{"name": "ifDescr", "oid": "1.3.6.1.2.1.2.2.1.2"}}},
+		},
+		{
+			name:  "no host",
+			props: map[string]interface{}{"oids": oids},
+			err:   "host is required",
+		},
+		{
+			name:  "no oids",
+			props: map[string]interface{}{"host": "127.0.0.1"},
+			err:   "oids or walks are required",
+		},
+		{
+			name:  "duplicate name",
+			props: map[string]interface{}{"host": "127.0.0.1", "oids": oids, "walks": []interface{}{map[string]interface{}{"name": "uptime", "oid": "1.3.6.1.2.1.1"}}},
+			err:   "name uptime is duplicate",
+		},
+		{
+			name:  "wrong version",
+			props: map[string]interface{}{"host": "127.0.0.1", "oids": oids, "version": "v1"},
+			err:   "version must be v2c or v3",
+		},
+		{
+			name:  "v3 no user",
+			props: map[string]interface{}{"host": "127.0.0.1", "oids": oids, "version": "v3"},
+			err:   "user is required for v3",
+		},
+		{
+			name:  "v3 no priv password",
+			props: map[string]interface{}{"host": "127.0.0.1", "oids": oids, "version": "v3", "user": "admin", "securityLevel": "authPriv", "authPassword": "pass1234"},
+			err:   "privPassword is required for authPriv",
+		},
+		{
+			name:  "v3 wrong auth protocol",
+			props: map[string]interface{}{"host": "127.0.0.1", "oids": oids, "version": "v3", "user": "admin", "securityLevel": "authNoPriv", "authProtocol": "SHA1", "authPassword": "pass1234"},
+			err:   "authProtocol must be MD5, SHA, SHA224, SHA256, SHA384 or SHA512",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &snmpSource{}
+			err := s.Configure("", tt.props)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "1.3.6.1.2.1.1.3.0", s.c.Oids[0].Oid)
+			assert.Equal(t, 161, s.c.Port)
+			assert.Equal(t, 10000, s.c.Interval)
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	assert.Equal(t, "router1", convert(gosnmp.SnmpPDU{Type: gosnmp.OctetString, Value: []byte("router1")}))
+	assert.Equal(t, []byte{0xff, 0x01}, convert(gosnmp.SnmpPDU{Type: gosnmp.OctetString, Value: []byte{0xff, 0x01}}))
+	assert.Equal(t, int64(5), convert(gosnmp.SnmpPDU{Type: gosnmp.Integer, Value: 5}))
+	assert.Equal(t, int64(100), convert(gosnmp.SnmpPDU{Type: gosnmp.Counter32, Value: uint(100)}))
+	assert.Equal(t, int64(360000), convert(gosnmp.SnmpPDU{Type: gosnmp.TimeTicks, Value: uint32(360000)}))
+	assert.Equal(t, uint64(1<<40), convert(gosnmp.SnmpPDU{Type: gosnmp.Counter64, Value: uint64(1 << 40)}))
+	assert.Equal(t, "1.3.6.1.4.1.9", convert(gosnmp.SnmpPDU{Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.4.1.9"}))
+	assert.Nil(t, convert(gosnmp.SnmpPDU{Type: gosnmp.NoSuchInstance}))
+}
+
+func TestSubtree(t *testing.T) {
+	pdus := []gosnmp.SnmpPDU{
+		{Name: ".1.3.6.1.2.1.2.2.1.2.1", Type: gosnmp.OctetString, Value: []byte("lo")},
+		{Name: ".1.3.6.1.2.1.2.2.1.2.2", Type: gosnmp.OctetString, Value: []byte("eth0")},
+	}
+	assert.Equal(t, map[string]interface{}{"1": "lo", "2": "eth0"}, subtree("1.3.6.1.2.1.2.2.1.2", pdus))
+}
@@ -32,6 +32,8 @@ import (
 type sqlConConfig struct {
 	Interval int    `json:"interval"`
 	Url      string `json:"url"`
+	// Bounded queries once and ends instead of polling, such as when the rule runs once
+	Bounded bool `json:"bounded"`
 
 	displayURL string
 }
@@ -60,7 +62,7 @@ func (m *sqlsource) Configure(_ string, props map[string]interface{}) error {
 	if cfg.Url == "" {
 		return fmt.Errorf("property Url is required")
 	}
-	if cfg.Interval == 0 {
+	if cfg.Interval == 0 && !cfg.Bounded {
 		return fmt.Errorf("property interval is required")
 	}
 
@@ -90,65 +92,77 @@ func (m *sqlsource) Configure(_ string, props map[string]interface{}) error {
 }
 
 func (m *sqlsource) Open(ctx api.StreamContext, consumer chan<- api.SourceTuple, errCh chan<- error) {
-	logger := ctx.GetLogger()
+	if m.conf.Bounded {
+		m.query(ctx, consumer, errCh)
+		return
+	}
 	t := time.NewTicker(time.Duration(m.conf.Interval) * time.Millisecond)
 	defer t.Stop()
 	for {
 		select {
 		case <-t.C:
-			rcvTime := conf.GetNow()
-			query, err := m.Query.SqlQueryStatement()
-			if err != nil {
-				logger.Errorf("Get sql query error %v", err)
-			}
-			logger.Debugf("Query the database with %s", query)
-			rows, err := m.db.Query(query)
-			if err != nil {
-				logger.Errorf("sql source meet error, try to reconnection, err:%v, query:%v", err, query)
-				if !isConnectionError(err) {
-					errCh <- err
-					continue
-				}
-				err2 := m.Reconnect()
-				if err2 != nil {
-					errCh <- fmt.Errorf("reconnect failed, reconnect err:%v", err2)
-				} else {
-					logger.Info("sql source reconnect successfully")
-				}
-				continue
-			}
-
-			cols, _ := rows.Columns()
-
-			types, err := rows.ColumnTypes()
-			if err != nil {
-				logger.Errorf("row ColumnTypes error %v", query, err)
-				errCh <- err
+			if !m.query(ctx, consumer, errCh) {
 				return
 			}
-			for rows.Next() {
-				data := make(map[string]interface{})
-				columns := make([]interface{}, len(cols))
-				prepareValues(columns, types, cols)
-
-				err := rows.Scan(columns...)
-				if err != nil {
-					logger.Errorf("Run sql scan(%s) error %v", query, err)
-					errCh <- err
-					return
-				}
-
-				scanIntoMap(data, columns, cols)
-				m.Query.UpdateMaxIndexValue(data)
-				consumer <- api.NewDefaultSourceTupleWithTime(data, nil, rcvTime)
-				rcvTime = conf.GetNow()
-			}
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
+// query runs the query once and sends the rows. It returns false if the source cannot continue.
+func (m *sqlsource) query(ctx api.StreamContext, consumer chan<- api.SourceTuple, errCh chan<- error) bool {
+	logger := ctx.GetLogger()
+	rcvTime := conf.GetNow()
+	query, err := m.Query.SqlQueryStatement()
+	if err != nil {
+		logger.Errorf("Get sql query error %v", err)
+	}
+	logger.Debugf("Query the database with %s", query)
+	rows, err := m.db.Query(query)
+	if err != nil {
+		logger.Errorf("sql source meet error, try to reconnection, err:%v, query:%v", err, query)
+		if !isConnectionError(err) {
+			errCh <- err
+			return true
+		}
+		err2 := m.Reconnect()
+		if err2 != nil {
+			errCh <- fmt.Errorf("reconnect failed, reconnect err:%v", err2)
+		} else {
+			logger.Info("sql source reconnect successfully")
+		}
+		return true
+	}
+
+	cols, _ := rows.Columns()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		logger.Errorf("row ColumnTypes error %v", query, err)
+		errCh <- err
+		return false
+	}
+	for rows.Next() {
+		data := make(map[string]interface{})
+		columns := make([]interface{}, len(cols))
+		prepareValues(columns, types, cols)
+
+		err := rows.Scan(columns...)
+		if err != nil {
+			logger.Errorf("Run sql scan(%s) error %v", query, err)
+			errCh <- err
+			return false
+		}
+
+		scanIntoMap(data, columns, cols)
+		m.Query.UpdateMaxIndexValue(data)
+		consumer <- api.NewDefaultSourceTupleWithTime(data, nil, rcvTime)
+		rcvTime = conf.GetNow()
+	}
+	return true
+}
+
 func (m *sqlsource) GetOffset() (interface{}, error) {
 	return m.Query.GetIndexValue(), nil
 }
@@ -31,7 +31,7 @@ func (q *CommonQueryGenerator) quoteIdentifier(identifier string) string {
 }
 
 func (q *CommonQueryGenerator) getSelect() string {
-	return "select * from " + q.Table + " "
+	return "select " + q.selectColumns() + " from " + q.Table + " "
 }
 
 func (q *CommonQueryGenerator) getCondition() (string, error) {
@@ -98,7 +98,7 @@ func (q *OracleQueryGenerate) UpdateMaxIndexValue(row map[string]interface{}) {
 
 func getCondition(cfg *InternalSqlQueryCfg, quoteIdentifier func(string) string) (string, error) {
 	fieldlist := cfg.store.GetFieldList()
-	if len(fieldlist) > 0 {
+	if len(fieldlist) > 0 || cfg.predicate != "" {
 		b := bytes.NewBufferString("where")
 		index := 0
 		for _, w := range fieldlist {
@@ -109,11 +109,16 @@ func getCondition(cfg *InternalSqlQueryCfg, quoteIdentifier func(string) string)
 			b.WriteString(" ")
 			b.WriteString(condition)
 			b.WriteString(" ")
-			if index < len(fieldlist)-1 {
+			if index < len(fieldlist)-1 || cfg.predicate != "" {
 				b.WriteString("AND")
 			}
 			index++
 		}
+		if cfg.predicate != "" {
+			b.WriteString(" (")
+			b.WriteString(cfg.predicate)
+			b.WriteString(") ")
+		}
 		return b.String(), nil
 	}
 	return "", nil
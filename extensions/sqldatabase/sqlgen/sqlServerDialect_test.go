@@ -85,6 +85,59 @@ func TestQueryGenerator_SqlQueryStatement(t *testing.T) {
 	}
 }
 
+func TestQueryProjection(t *testing.T) {
+	props := map[string]interface{}{
+		"internalSqlQueryCfg": map[string]interface{}{
+			"table":      "t",
+			"limit":      2,
+			"indexField": "ts",
+			"indexValue": 10,
+		},
+		"projection": []string{"a", "b"},
+	}
+	g, err := GetQueryGenerator("mysql", props)
+	require.NoError(t, err)
+	query, err := g.SqlQueryStatement()
+	require.NoError(t, err)
+	require.Equal(t, "select a, b, ts from t where ts > '10' order by 'ts' ASC limit 2", query)
+
+	g, err = GetQueryGenerator("sqlserver", props)
+	require.NoError(t, err)
+	query, err = g.SqlQueryStatement()
+	require.NoError(t, err)
+	require.Equal(t, "select top 2 a, b, ts from t where ts > '10' order by ts ASC", query)
+
+	delete(props, "projection")
+	g, err = GetQueryGenerator("mysql", props)
+	require.NoError(t, err)
+	query, err = g.SqlQueryStatement()
+	require.NoError(t, err)
+	require.Equal(t, "select * from t where ts > '10' order by 'ts' ASC limit 2", query)
+}
+
+func TestQueryPredicate(t *testing.T) {
+	props := map[string]interface{}{
+		"internalSqlQueryCfg": map[string]interface{}{
+			"table":      "t",
+			"indexField": "ts",
+			"indexValue": 10,
+		},
+		"predicate": "a > 1 AND (b = 'x' OR b = 'y')",
+	}
+	g, err := GetQueryGenerator("mysql", props)
+	require.NoError(t, err)
+	query, err := g.SqlQueryStatement()
+	require.NoError(t, err)
+	require.Equal(t, "select * from t where ts > '10' AND (a > 1 AND (b = 'x' OR b = 'y')) order by 'ts' ASC", query)
+
+	props["internalSqlQueryCfg"] = map[string]interface{}{"table": "t", "limit": 1}
+	g, err = GetQueryGenerator("oracle", props)
+	require.NoError(t, err)
+	query, err = g.SqlQueryStatement()
+	require.NoError(t, err)
+	require.Equal(t, "select * from (select * from t where (a > 1 AND (b = 'x' OR b = 'y')) ) where rownum <= 1", query)
+}
+
 func TestOracleQuery(t *testing.T) {
 	cfg := &InternalSqlQueryCfg{
 		Table: "t",
@@ -16,6 +16,7 @@ package sqlgen
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/lf-edge/ekuiper/pkg/cast"
 	"github.com/lf-edge/ekuiper/pkg/store"
@@ -44,6 +45,10 @@ type InternalSqlQueryCfg struct {
 	IndexFieldDateTimeFormat string              `json:"dateTimeFormat"`
 	IndexFields              []*store.IndexField `json:"indexFields"`
 	store                    *store.IndexFieldStoreWrap
+	// the columns to query, all columns are queried if empty
+	columns []string
+	// the extra where condition pushed down from the rule
+	predicate string
 }
 
 func (i *InternalSqlQueryCfg) InitIndexFieldStore() {
@@ -61,6 +66,39 @@ func (i *InternalSqlQueryCfg) InitIndexFieldStore() {
 	i.store.Init(i.IndexFields...)
 }
 
+// SetColumns sets the columns to query. The index fields are always queried to update the index value.
+func (i *InternalSqlQueryCfg) SetColumns(cols []string) {
+	if len(cols) == 0 {
+		i.columns = nil
+		return
+	}
+	i.columns = append([]string{}, cols...)
+	for _, f := range i.store.GetFieldList() {
+		found := false
+		for _, c := range i.columns {
+			if c == f.IndexFieldName {
+				found = true
+				break
+			}
+		}
+		if !found {
+			i.columns = append(i.columns, f.IndexFieldName)
+		}
+	}
+}
+
+// SetPredicate sets the extra where condition combined with the index conditions by AND
+func (i *InternalSqlQueryCfg) SetPredicate(predicate string) {
+	i.predicate = predicate
+}
+
+func (i *InternalSqlQueryCfg) selectColumns() string {
+	if len(i.columns) == 0 {
+		return "*"
+	}
+	return strings.Join(i.columns, ", ")
+}
+
 func (i *InternalSqlQueryCfg) SetIndexValue(v interface{}) {
 	switch vv := v.(type) {
 	case *store.IndexFieldStore:
@@ -83,6 +121,10 @@ func (i *InternalSqlQueryCfg) GetIndexValue() interface{} {
 type sqlConfig struct {
 	TemplateSqlQueryCfg *TemplateSqlQueryCfg `json:"templateSqlQueryCfg"`
 	InternalSqlQueryCfg *InternalSqlQueryCfg `json:"internalSqlQueryCfg"`
+	// Projection is the columns used by the rule which is set by the rule planner
+	Projection []string `json:"projection"`
+	// Predicate is the where condition of the rule which is set by the rule planner
+	Predicate string `json:"predicate"`
 }
 
 func (cfg *sqlConfig) Init(props map[string]interface{}) error {
@@ -131,6 +173,8 @@ func (cfg *sqlConfig) Init(props map[string]interface{}) error {
 			return err
 		}
 		cfg.InternalSqlQueryCfg.InitIndexFieldStore()
+		cfg.InternalSqlQueryCfg.SetColumns(cfg.Projection)
+		cfg.InternalSqlQueryCfg.SetPredicate(cfg.Predicate)
 	}
 
 	return nil
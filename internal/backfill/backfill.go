@@ -0,0 +1,217 @@
+// Copyright 2023-2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package backfill runs an existing rule against the bounded history data in a separate instance of its topology.
+package backfill
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/pkg/namespace"
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/internal/topo"
+	"github.com/lf-edge/ekuiper/internal/topo/node"
+	"github.com/lf-edge/ekuiper/internal/topo/node/metric"
+	"github.com/lf-edge/ekuiper/internal/topo/planner"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+// idleInterval is the interval to check whether the rule is idle after all the sources end.
+// The job completes when no data flows in the rule for two intervals.
+var idleInterval = 500 * time.Millisecond
+
+type Request struct {
+	// Sources are the replayable sources to read the history of each stream of the rule
+	Sources map[string]*planner.SourceOverride `json:"sources"`
+	// Start and End are the event time range in milliseconds to backfill. 0 means no bound.
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+	// Actions are the alternate sinks to write the results. Default to the actions of the rule.
+	Actions []map[string]interface{} `json:"actions"`
+	// sinks replace the actions to collect the results inside the process, such as the replay
+	sinks []*node.SinkNode
+}
+
+type Result struct {
+	// Id is the id of the topology instance of the backfill job
+	Id string `json:"id"`
+	// Sinks are the count of the written results of each sink
+	Sinks    map[string]int64 `json:"sinks"`
+	Duration int64            `json:"durationMs"`
+}
+
+// Run runs the rule in a separate instance with the streams read from the sources of the request.
+// The rule runs with event time, and it completes when all the sources finish reading and the rule is idle.
+func Run(ctx context.Context, rule *api.Rule, req *Request) (*Result, error) {
+	start := conf.GetNow()
+	br, err := prepare(rule, req)
+	if err != nil {
+		return nil, err
+	}
+	ended := make(chan struct{}, len(req.Sources))
+	for _, src := range req.Sources {
+		src.Bound = &node.SourceBound{
+			Start: req.Start,
+			End:   req.End,
+			OnEnd: func() {
+				ended <- struct{}{}
+			},
+		}
+	}
+	tp, err := planner.PlanSQLWithSourceOverrides(br, req.Sources, req.sinks)
+	if err != nil {
+		return nil, err
+	}
+	conf.Log.Infof("backfill rule %s as %s in [%d, %d)", rule.Id, br.Id, req.Start, req.End)
+	var (
+		count  int
+		ticker *time.Ticker
+		tickCh <-chan time.Time
+		last   int64 = -1
+		idle   int
+	)
+	errCh := tp.Open()
+	defer func() {
+		if ticker != nil {
+			ticker.Stop()
+		}
+		tp.Cancel()
+		tp.RemoveMetrics()
+	}()
+	for {
+		select {
+		case err := <-errCh:
+			if err == nil {
+				return nil, fmt.Errorf("backfill %s is stopped", br.Id)
+			}
+			return nil, err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ended:
+			count++
+			if count == len(req.Sources) {
+				conf.Log.Infof("backfill %s finishes reading all the sources", br.Id)
+				ticker = time.NewTicker(idleInterval)
+				tickCh = ticker.C
+			}
+		case <-tickCh:
+			progress := getProgress(tp)
+			if progress != last {
+				last = progress
+				idle = 0
+				continue
+			}
+			idle++
+			if idle >= 2 {
+				return &Result{
+					Id:       br.Id,
+					Sinks:    getSinkResults(tp),
+					Duration: conf.GetNow().Sub(start).Milliseconds(),
+				}, nil
+			}
+		}
+	}
+}
+
+// prepare validates the request and creates the rule of the backfill job
+func prepare(rule *api.Rule, req *Request) (*api.Rule, error) {
+	if rule.Sql == "" {
+		return nil, fmt.Errorf("backfill only supports the rule defined by sql")
+	}
+	if req.End > 0 && req.End <= req.Start {
+		return nil, fmt.Errorf("invalid range [%d, %d), end must be after start", req.Start, req.End)
+	}
+	stmt, err := xsql.GetStatementFromSql(rule.Sql)
+	if err != nil {
+		return nil, err
+	}
+	ns, _ := namespace.Split(rule.Id)
+	kv, err := store.GetKV(namespace.Table("stream", ns))
+	if err != nil {
+		return nil, err
+	}
+	streams := make(map[string]struct{})
+	for _, name := range xsql.GetStreams(stmt) {
+		info, err := xsql.GetDataSourceStatement(kv, name)
+		if err != nil {
+			return nil, err
+		}
+		// Tables keep reading their sources as the dimension data
+		if info.StreamType != ast.TypeStream {
+			continue
+		}
+		if _, ok := req.Sources[name]; !ok {
+			return nil, fmt.Errorf("source of stream %s is required to backfill", name)
+		}
+		streams[name] = struct{}{}
+	}
+	if len(streams) == 0 {
+		return nil, fmt.Errorf("rule %s has no stream to backfill", rule.Id)
+	}
+	for name := range req.Sources {
+		if _, ok := streams[name]; !ok {
+			return nil, fmt.Errorf("%s is not a stream of rule %s", name, rule.Id)
+		}
+	}
+	opts := *rule.Options
+	opts.IsEventTime = true
+	// The job runs once, so no checkpoint is needed
+	opts.Qos = api.AtMostOnce
+	// The job completes by its own sources
+	opts.RunOnce = false
+	br := &api.Rule{
+		Triggered: true,
+		Id:        fmt.Sprintf("%s_backfill_%d", rule.Id, conf.GetNowInMilli()),
+		Name:      rule.Name,
+		Sql:       rule.Sql,
+		Actions:   rule.Actions,
+		Options:   &opts,
+	}
+	if len(req.Actions) > 0 {
+		br.Actions = req.Actions
+	}
+	return br, nil
+}
+
+// getProgress sums up the processed records of all nodes. The rule is idle if it does not change.
+func getProgress(tp *topo.Topo) int64 {
+	var sum int64
+	keys, values := tp.GetMetrics()
+	for i, k := range keys {
+		if strings.HasSuffix(k, metric.RecordsInTotal) || strings.HasSuffix(k, metric.RecordsOutTotal) || strings.HasSuffix(k, metric.ExceptionsTotal) {
+			v, _ := cast.ToInt64(values[i], cast.CONVERT_ALL)
+			sum += v
+		}
+	}
+	return sum
+}
+
+func getSinkResults(tp *topo.Topo) map[string]int64 {
+	result := make(map[string]int64)
+	keys, values := tp.GetMetrics()
+	for i, k := range keys {
+		if strings.HasPrefix(k, "sink_") && strings.HasSuffix(k, "_0_"+metric.RecordsOutTotal) {
+			v, _ := cast.ToInt64(values[i], cast.CONVERT_ALL)
+			result[strings.TrimSuffix(strings.TrimPrefix(k, "sink_"), "_0_"+metric.RecordsOutTotal)] = v
+		}
+	}
+	return result
+}
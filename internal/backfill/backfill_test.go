@@ -0,0 +1,206 @@
+// Copyright 2023-2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backfill
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/io/record"
+	"github.com/lf-edge/ekuiper/internal/io/sink"
+	"github.com/lf-edge/ekuiper/internal/processor"
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"github.com/lf-edge/ekuiper/internal/topo/planner"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+func init() {
+	testx.InitEnv("backfill")
+	idleInterval = 50 * time.Millisecond
+}
+
+func TestBackfill(t *testing.T) {
+	p := processor.NewStreamProcessor()
+	_, _ = p.ExecStmt("DROP STREAM bfDemo")
+	_, err := p.ExecStmt(`CREATE STREAM bfDemo (ts BIGINT, v BIGINT) WITH (DATASOURCE="demo", TYPE="mqtt", FORMAT="json", TIMESTAMP="ts")`)
+	require.NoError(t, err)
+	defer p.ExecStmt("DROP STREAM bfDemo")
+	_, _ = p.ExecStmt("DROP TABLE bfTable")
+	_, err = p.ExecStmt(`CREATE TABLE bfTable () WITH (DATASOURCE="lookup.json", TYPE="file")`)
+	require.NoError(t, err)
+	defer p.ExecStmt("DROP TABLE bfTable")
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "history.json"), []byte(`[
+{"ts":500,"v":1},{"ts":1000,"v":2},{"ts":1500,"v":3},{"ts":2500,"v":4},{"ts":3000,"v":5},{"ts":4500,"v":6},{"ts":5500,"v":7}
+]`), 0o644))
+	rule := api.GetDefaultRule("bfRule", "SELECT count(*) AS c, sum(v) AS s FROM bfDemo GROUP BY TumblingWindow(ss, 2)")
+	rule.Actions = []map[string]interface{}{{"mqtt": map[string]interface{}{"server": "tcp://127.0.0.1:1883", "topic": "result"}}}
+	newReq := func() *Request {
+		return &Request{
+			Sources: map[string]*planner.SourceOverride{
+				"bfDemo": {Type: "file", DataSource: "history.json", Props: map[string]interface{}{"path": dir, "fileType": "json"}},
+			},
+			Start:   1000,
+			End:     5000,
+			Actions: []map[string]interface{}{{"logToMemory": map[string]interface{}{}}},
+		}
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		req := newReq()
+		req.Sources = nil
+		_, err := Run(context.Background(), rule, req)
+		assert.EqualError(t, err, "source of stream bfDemo is required to backfill")
+		req = newReq()
+		req.Sources["bfTable"] = &planner.SourceOverride{}
+		_, err = Run(context.Background(), rule, req)
+		assert.EqualError(t, err, "bfTable is not a stream of rule bfRule")
+		req = newReq()
+		req.End = 1000
+		_, err = Run(context.Background(), rule, req)
+		assert.EqualError(t, err, "invalid range [1000, 1000), end must be after start")
+	})
+
+	t.Run("windows in range", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		r, err := Run(ctx, rule, newReq())
+		require.NoError(t, err)
+		// The window [4000, 6000) is not complete in the range
+		assert.Equal(t, map[string]int64{"logToMemory_0": 2}, r.Sinks)
+		sink.QR.Mux.Lock()
+		defer sink.QR.Mux.Unlock()
+		assert.Equal(t, []string{`[{"c":2,"s":5}]`, `[{"c":2,"s":9}]`}, sink.QR.Results)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		req := newReq()
+		// The file is read repeatedly, so the source never ends
+		req.Sources["bfDemo"].Props["interval"] = 1000
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		_, err := Run(ctx, rule, req)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestReplay(t *testing.T) {
+	p := processor.NewStreamProcessor()
+	_, _ = p.ExecStmt("DROP STREAM rpDemo")
+	_, err := p.ExecStmt(`CREATE STREAM rpDemo (v BIGINT) WITH (DATASOURCE="demo", TYPE="mqtt", FORMAT="json")`)
+	require.NoError(t, err)
+	defer p.ExecStmt("DROP STREAM rpDemo")
+
+	file := filepath.Join(t.TempDir(), "demo.jsonl")
+	require.NoError(t, os.WriteFile(file, []byte(`{"timestamp":1000,"payload":"eyJ2IjoxfQ=="}
+{"timestamp":1500,"message":{"v":2}}
+{"timestamp":2500,"message":{"v":3}}
+{"timestamp":3200,"message":{"v":4}}
+{"timestamp":4100,"message":{"v":5}}
+`), 0o644))
+	rule := api.GetDefaultRule("rpRule", "SELECT count(*) AS c FROM rpDemo GROUP BY TumblingWindow(ss, 2)")
+	rule.Actions = []map[string]interface{}{{"mqtt": map[string]interface{}{"server": "tcp://127.0.0.1:1883", "topic": "result"}}}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	r, err := Replay(ctx, rule, &ReplayRequest{Sources: map[string]string{"rpDemo": file}})
+	require.NoError(t, err)
+	// The window [4000, 6000) is not complete until the end of the records
+	assert.Equal(t, []any{
+		[]any{map[string]any{"c": float64(2)}},
+		[]any{map[string]any{"c": float64(2)}},
+	}, r.Outputs)
+
+	r, err = Replay(ctx, rule, &ReplayRequest{Sources: map[string]string{"rpDemo": file}, Sql: "SELECT v FROM rpDemo WHERE v > 2"})
+	require.NoError(t, err)
+	assert.Equal(t, []any{
+		[]any{map[string]any{"v": float64(3)}},
+		[]any{map[string]any{"v": float64(4)}},
+		[]any{map[string]any{"v": float64(5)}},
+	}, r.Outputs)
+
+	_, err = Replay(ctx, rule, &ReplayRequest{Sources: map[string]string{"rpDemo": ""}})
+	assert.EqualError(t, err, "record file of stream rpDemo is required")
+}
+
+func TestRunCase(t *testing.T) {
+	p := processor.NewStreamProcessor()
+	_, _ = p.ExecStmt("DROP STREAM caseDemo")
+	_, err := p.ExecStmt(`CREATE STREAM caseDemo (v BIGINT) WITH (DATASOURCE="demo", TYPE="mqtt", FORMAT="json")`)
+	require.NoError(t, err)
+	defer p.ExecStmt("DROP STREAM caseDemo")
+	rule := api.GetDefaultRule("caseRule", "SELECT v FROM caseDemo WHERE v > 1")
+	rule.Actions = []map[string]interface{}{{"log": map[string]interface{}{}}}
+	inputs := map[string][]*record.Record{
+		"caseDemo": {
+			{Timestamp: 100, Message: map[string]any{"v": 1}},
+			{Timestamp: 200, Message: map[string]any{"v": 2}},
+			{Timestamp: 300, Message: map[string]any{"v": 3}},
+		},
+	}
+	out := func(v float64) any {
+		return []any{map[string]any{"v": v}}
+	}
+	tests := []struct {
+		name string
+		c    *Case
+		pass bool
+		diff []*OutputDiff
+	}{
+		{
+			name: "pass",
+			c:    &Case{Inputs: inputs, Expected: []any{out(2), out(3)}},
+			pass: true,
+		},
+		{
+			name: "wrong order",
+			c:    &Case{Inputs: inputs, Expected: []any{out(3), out(2)}},
+			diff: []*OutputDiff{{Index: 0, Expected: out(3), Actual: out(2)}, {Index: 1, Expected: out(2), Actual: out(3)}},
+		},
+		{
+			name: "unordered",
+			c:    &Case{Inputs: inputs, Expected: []any{out(3), out(2)}, Unordered: true},
+			pass: true,
+		},
+		{
+			name: "missing",
+			c:    &Case{Inputs: inputs, Expected: []any{out(2), out(3), out(4)}},
+			diff: []*OutputDiff{{Index: 2, Expected: out(4)}},
+		},
+		{
+			name: "unexpected unordered",
+			c:    &Case{Inputs: inputs, Expected: []any{out(3)}, Unordered: true},
+			diff: []*OutputDiff{{Index: -1, Actual: out(2)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			r, err := RunCase(ctx, rule, tt.c)
+			require.NoError(t, err)
+			assert.Equal(t, tt.pass, r.Pass)
+			assert.Equal(t, tt.diff, r.Diff)
+			assert.Equal(t, []any{out(2), out(3)}, r.Outputs)
+		})
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backfill
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/lf-edge/ekuiper/internal/io/record"
+	"github.com/lf-edge/ekuiper/internal/topo/planner"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+// Case is a unit test case of a rule with the mocked inputs and the expected outputs
+type Case struct {
+	// Inputs are the tuples of each stream of the rule. The timestamp of a tuple is its event time
+	// if the stream has no timestamp field.
+	Inputs map[string][]*record.Record `json:"inputs"`
+	// Expected are the expected outputs of the rule in order, such as [{"c":2}] for each window
+	Expected []any `json:"expected"`
+	// Unordered compares the outputs regardless of the order
+	Unordered bool `json:"unordered"`
+	// End is the event time in milliseconds when the inputs end, so the windows ending before it are emitted.
+	// The inputs at or after it are dropped. Default to the max timestamp of the inputs.
+	End int64 `json:"end"`
+}
+
+type CaseReport struct {
+	Pass    bool  `json:"pass"`
+	Outputs []any `json:"outputs"`
+	// Diff is the mismatched outputs. Expected or Actual is nil if the output is missing or unexpected.
+	Diff     []*OutputDiff `json:"diff,omitempty"`
+	Duration int64         `json:"durationMs"`
+}
+
+type OutputDiff struct {
+	// Index is the index of the output in order. It is -1 for the unexpected output if unordered.
+	Index    int `json:"index"`
+	Expected any `json:"expected"`
+	Actual   any `json:"actual"`
+}
+
+// RunCase runs the rule against the inputs of the case as fast as possible and compares the outputs with
+// the expected. The time of the rule is decided by the timestamps of the inputs, so the result is repeatable.
+func RunCase(ctx context.Context, rule *api.Rule, c *Case) (*CaseReport, error) {
+	sources := make(map[string]*planner.SourceOverride, len(c.Inputs))
+	for name, inputs := range c.Inputs {
+		if inputs == nil {
+			inputs = []*record.Record{}
+		}
+		for i, in := range inputs {
+			if in == nil {
+				return nil, fmt.Errorf("input %d of stream %s is null", i, name)
+			}
+		}
+		sources[name] = &planner.SourceOverride{
+			Type:  "replay",
+			Props: map[string]interface{}{"records": inputs, "speed": 0},
+			// The records carry the event time
+			SourceTime: true,
+		}
+	}
+	result, err := replay(ctx, rule, sources, c.End)
+	if err != nil {
+		return nil, err
+	}
+	expected := c.Expected
+	if expected == nil {
+		expected = []any{}
+	}
+	var diff []*OutputDiff
+	if c.Unordered {
+		diff = diffUnordered(expected, result.Outputs)
+	} else {
+		diff = diffOrdered(expected, result.Outputs)
+	}
+	return &CaseReport{
+		Pass:     len(diff) == 0 && !result.Truncated,
+		Outputs:  result.Outputs,
+		Diff:     diff,
+		Duration: result.Duration,
+	}, nil
+}
+
+func diffOrdered(expected, actual []any) []*OutputDiff {
+	var result []*OutputDiff
+	for i := 0; i < len(expected) || i < len(actual); i++ {
+		var e, a any
+		if i < len(expected) {
+			e = expected[i]
+		}
+		if i < len(actual) {
+			a = actual[i]
+		}
+		if i >= len(expected) || i >= len(actual) || !reflect.DeepEqual(e, a) {
+			result = append(result, &OutputDiff{Index: i, Expected: e, Actual: a})
+		}
+	}
+	return result
+}
+
+func diffUnordered(expected, actual []any) []*OutputDiff {
+	var result []*OutputDiff
+	matched := make([]bool, len(actual))
+	for i, e := range expected {
+		found := false
+		for j, a := range actual {
+			if !matched[j] && reflect.DeepEqual(e, a) {
+				matched[j] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, &OutputDiff{Index: i, Expected: e})
+		}
+	}
+	for j, a := range actual {
+		if !matched[j] {
+			result = append(result, &OutputDiff{Index: -1, Actual: a})
+		}
+	}
+	return result
+}
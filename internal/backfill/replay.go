@@ -0,0 +1,141 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backfill
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/lf-edge/ekuiper/internal/topo/node"
+	"github.com/lf-edge/ekuiper/internal/topo/planner"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+// maxReplayOutputs is the max count of the collected outputs of a replay, the later outputs are dropped
+var maxReplayOutputs = 10000
+
+type ReplayRequest struct {
+	// Sources are the record files of each stream of the rule
+	Sources map[string]string `json:"sources"`
+	// Speed is the multiple of the original speed. 0 replays as fast as possible.
+	Speed *float64 `json:"speed"`
+	// Sql replaces the sql of the rule to test a modified version against the same records
+	Sql string `json:"sql"`
+}
+
+type ReplayResult struct {
+	Id string `json:"id"`
+	// Outputs are the results of the rule in order. The json results are decoded.
+	Outputs   []any `json:"outputs"`
+	Truncated bool  `json:"truncated,omitempty"`
+	Duration  int64 `json:"durationMs"`
+}
+
+// Replay runs the rule against the record files in a separate instance and collects the outputs instead of
+// writing to the actions of the rule.
+func Replay(ctx context.Context, rule *api.Rule, req *ReplayRequest) (*ReplayResult, error) {
+	speed := float64(0)
+	if req.Speed != nil {
+		speed = *req.Speed
+	}
+	sources := make(map[string]*planner.SourceOverride, len(req.Sources))
+	for name, file := range req.Sources {
+		if file == "" {
+			return nil, fmt.Errorf("record file of stream %s is required", name)
+		}
+		sources[name] = &planner.SourceOverride{
+			Type:  "replay",
+			Props: map[string]interface{}{"path": file, "speed": speed},
+			// The records carry the event time
+			SourceTime: true,
+		}
+	}
+	r := rule
+	if req.Sql != "" {
+		rc := *rule
+		rc.Sql = req.Sql
+		r = &rc
+	}
+	return replay(ctx, r, sources, 0)
+}
+
+// replay runs the rule with the replay sources until the end of the event time and collects the outputs
+func replay(ctx context.Context, rule *api.Rule, sources map[string]*planner.SourceOverride, end int64) (*ReplayResult, error) {
+	c := &collector{}
+	result, err := Run(ctx, rule, &Request{
+		Sources: sources,
+		End:     end,
+		sinks:   []*node.SinkNode{node.NewSinkNodeWithSink("replay", c, map[string]interface{}{})},
+	})
+	if err != nil {
+		return nil, err
+	}
+	outputs, truncated := c.results()
+	return &ReplayResult{
+		Id:        result.Id,
+		Outputs:   outputs,
+		Truncated: truncated,
+		Duration:  result.Duration,
+	}, nil
+}
+
+// collector is the sink to collect the outputs of a replay in memory
+type collector struct {
+	sync.Mutex
+	outputs   []any
+	truncated bool
+}
+
+func (c *collector) Configure(_ map[string]interface{}) error {
+	return nil
+}
+
+func (c *collector) Open(_ api.StreamContext) error {
+	return nil
+}
+
+func (c *collector) Collect(ctx api.StreamContext, data interface{}) error {
+	bs, _, err := ctx.TransformOutput(data)
+	if err != nil {
+		return err
+	}
+	var v any
+	if err := json.Unmarshal(bs, &v); err != nil {
+		v = string(bs)
+	}
+	c.Lock()
+	defer c.Unlock()
+	if len(c.outputs) >= maxReplayOutputs {
+		c.truncated = true
+		return nil
+	}
+	c.outputs = append(c.outputs, v)
+	return nil
+}
+
+func (c *collector) Close(_ api.StreamContext) error {
+	return nil
+}
+
+func (c *collector) results() ([]any, bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.outputs == nil {
+		return []any{}, c.truncated
+	}
+	return c.outputs, c.truncated
+}
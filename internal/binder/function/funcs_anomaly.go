@@ -0,0 +1,580 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package function
+
+import (
+	"encoding/gob"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+const (
+	// defaultAnomalyThreshold is the default absolute score to report an anomaly
+	defaultAnomalyThreshold = 3.0
+	// defaultEsdAlpha is the default significance level of the seasonal ESD test
+	defaultEsdAlpha = 0.05
+)
+
+func init() {
+	// The states are saved in the checkpoints
+	gob.Register(&anomalyWindow{})
+	gob.Register(&ewmaState{})
+}
+
+// anomalyWindow keeps the latest values of a partition. Seq is the count of all the recorded values
+// which is used to find out the season phase of each value.
+type anomalyWindow struct {
+	Values []float64
+	Seq    int64
+}
+
+func (w *anomalyWindow) push(v float64, size int) {
+	w.Values = append(w.Values, v)
+	if len(w.Values) > size {
+		w.Values = append(w.Values[:0], w.Values[len(w.Values)-size:]...)
+	}
+	w.Seq++
+}
+
+type ewmaState struct {
+	Mean     float64
+	Variance float64
+	Count    int64
+}
+
+// registerAnomalyFunc registers the analytic functions which detect the anomaly of the value
+// comparing to the history values of the partition.
+// The last two parameters are the when condition and the partition key like other analytic functions.
+// If the when condition is false, the value is checked but not recorded.
+func registerAnomalyFunc() {
+	builtins["anomaly_zscore"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			key, validData, x, ok, err := anomalyArgs(args)
+			if err != nil {
+				return err, false
+			}
+			if !ok {
+				return nil, true
+			}
+			size, err := cast.ToInt(args[1], cast.STRICT)
+			if err != nil || size < 2 {
+				return fmt.Errorf("the window size must be an integer no less than 2 but got %v", args[1]), false
+			}
+			threshold, err := anomalyThreshold(args, 2)
+			if err != nil {
+				return err, false
+			}
+			w, err := getAnomalyWindow(ctx, key)
+			if err != nil {
+				return err, false
+			}
+			var result interface{}
+			// The value is compared with the previous values
+			if len(w.Values) >= 2 {
+				mean, stddev := meanStddev(w.Values)
+				result = anomalyResult(x, mean, stddev, threshold)
+			}
+			if validData {
+				w.push(x, size)
+				if err := ctx.PutState(key, w); err != nil {
+					return fmt.Errorf("error setting state for %s: %v", key, err), false
+				}
+			}
+			return result, true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if len(args) != 2 && len(args) != 3 {
+				return fmt.Errorf("Expect 2 or 3 arguments but found %d.", len(args))
+			}
+			if err := validateAnomalyNumber(args[0], 0); err != nil {
+				return err
+			}
+			if err := validateAnomalyInt(args[1], 1, 2, "window size"); err != nil {
+				return err
+			}
+			if len(args) == 3 {
+				return validateAnomalyThreshold(args[2], 2)
+			}
+			return nil
+		},
+	}
+	builtins["anomaly_ewma"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			key, validData, x, ok, err := anomalyArgs(args)
+			if err != nil {
+				return err, false
+			}
+			if !ok {
+				return nil, true
+			}
+			alpha, err := cast.ToFloat64(args[1], cast.CONVERT_SAMEKIND)
+			if err != nil || alpha < 0 || alpha > 1 {
+				return fmt.Errorf("the smoothing factor of parameter 2 must be between 0 and 1 but got %v", args[1]), false
+			}
+			threshold, err := anomalyThreshold(args, 2)
+			if err != nil {
+				return err, false
+			}
+			v, err := ctx.GetState(key)
+			if err != nil {
+				return fmt.Errorf("error getting state for %s: %v", key, err), false
+			}
+			s, ok := v.(*ewmaState)
+			if !ok {
+				s = &ewmaState{}
+			}
+			var result interface{}
+			// The variance needs at least two values
+			if s.Count >= 2 {
+				result = anomalyResult(x, s.Mean, math.Sqrt(s.Variance), threshold)
+			}
+			if validData {
+				if s.Count == 0 {
+					s.Mean = x
+				} else {
+					diff := x - s.Mean
+					s.Mean += alpha * diff
+					s.Variance = (1 - alpha) * (s.Variance + alpha*diff*diff)
+				}
+				s.Count++
+				if err := ctx.PutState(key, s); err != nil {
+					return fmt.Errorf("error setting state for %s: %v", key, err), false
+				}
+			}
+			return result, true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if len(args) != 2 && len(args) != 3 {
+				return fmt.Errorf("Expect 2 or 3 arguments but found %d.", len(args))
+			}
+			for i, arg := range args {
+				if err := validateAnomalyNumber(arg, i); err != nil {
+					return err
+				}
+			}
+			if err := validateSmoothingFactor(args[1], 1); err != nil {
+				return err
+			}
+			if len(args) == 3 {
+				return validateAnomalyThreshold(args[2], 2)
+			}
+			return nil
+		},
+	}
+	builtins["anomaly_seasonal_esd"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			key, validData, x, ok, err := anomalyArgs(args)
+			if err != nil {
+				return err, false
+			}
+			if !ok {
+				return nil, true
+			}
+			size, err := cast.ToInt(args[1], cast.STRICT)
+			if err != nil || size < 3 {
+				return fmt.Errorf("the window size must be an integer no less than 3 but got %v", args[1]), false
+			}
+			period, err := cast.ToInt(args[2], cast.STRICT)
+			if err != nil || period < 1 {
+				return fmt.Errorf("the season period must be an integer no less than 1 but got %v", args[2]), false
+			}
+			if size < 2*period {
+				return fmt.Errorf("the window size %d must cover at least two season periods of %d", size, period), false
+			}
+			alpha := defaultEsdAlpha
+			if len(args) == 6 {
+				alpha, err = cast.ToFloat64(args[3], cast.CONVERT_SAMEKIND)
+				if err != nil || alpha <= 0 || alpha >= 1 {
+					return fmt.Errorf("the significance level must be between 0 and 1 but got %v", args[3]), false
+				}
+			}
+			w, err := getAnomalyWindow(ctx, key)
+			if err != nil {
+				return err, false
+			}
+			var result interface{}
+			// The value is tested together with the previous values once the window is full
+			if len(w.Values) >= size-1 {
+				values := make([]float64, size)
+				copy(values, w.Values[len(w.Values)-size+1:])
+				values[size-1] = x
+				result = seasonalEsd(values, w.Seq-int64(size-1), period, alpha)
+			}
+			if validData {
+				w.push(x, size)
+				if err := ctx.PutState(key, w); err != nil {
+					return fmt.Errorf("error setting state for %s: %v", key, err), false
+				}
+			}
+			return result, true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if len(args) != 3 && len(args) != 4 {
+				return fmt.Errorf("Expect 3 or 4 arguments but found %d.", len(args))
+			}
+			if err := validateAnomalyNumber(args[0], 0); err != nil {
+				return err
+			}
+			if err := validateAnomalyInt(args[1], 1, 3, "window size"); err != nil {
+				return err
+			}
+			if err := validateAnomalyInt(args[2], 2, 1, "season period"); err != nil {
+				return err
+			}
+			size, ok1 := args[1].(*ast.IntegerLiteral)
+			period, ok2 := args[2].(*ast.IntegerLiteral)
+			if ok1 && ok2 && size.Val < 2*period.Val {
+				return fmt.Errorf("the window size %d must cover at least two season periods of %d", size.Val, period.Val)
+			}
+			if len(args) == 4 {
+				if ast.IsStringArg(args[3]) || ast.IsTimeArg(args[3]) || ast.IsBooleanArg(args[3]) {
+					return ProduceErrInfo(3, "float")
+				}
+				if a, ok := args[3].(*ast.NumberLiteral); ok && (a.Val <= 0 || a.Val >= 1) {
+					return fmt.Errorf("the significance level must be between 0 and 1 but got %v", a.Val)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// anomalyArgs reads the common arguments of the anomaly functions. If the value is nil, ok is false.
+func anomalyArgs(args []interface{}) (string, bool, float64, bool, error) {
+	key := args[len(args)-1].(string)
+	validData, ok := args[len(args)-2].(bool)
+	if !ok {
+		return "", false, 0, false, fmt.Errorf("when arg is not a bool but got %v", args[len(args)-2])
+	}
+	if args[0] == nil {
+		return key, validData, 0, false, nil
+	}
+	x, err := cast.ToFloat64(args[0], cast.CONVERT_SAMEKIND)
+	if err != nil {
+		return "", false, 0, false, fmt.Errorf("the value should be number but got %[1]T(%[1]v)", args[0])
+	}
+	return key, validData, x, true, nil
+}
+
+// anomalyThreshold reads the optional threshold argument at the index
+func anomalyThreshold(args []interface{}, index int) (float64, error) {
+	// The args end with the when condition and the partition key
+	if len(args)-2 <= index {
+		return defaultAnomalyThreshold, nil
+	}
+	t, err := cast.ToFloat64(args[index], cast.CONVERT_SAMEKIND)
+	if err != nil || t <= 0 {
+		return 0, fmt.Errorf("the threshold must be a positive number but got %v", args[index])
+	}
+	return t, nil
+}
+
+func getAnomalyWindow(ctx api.FunctionContext, key string) (*anomalyWindow, error) {
+	v, err := ctx.GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("error getting state for %s: %v", key, err)
+	}
+	if w, ok := v.(*anomalyWindow); ok {
+		return w, nil
+	}
+	return &anomalyWindow{}, nil
+}
+
+func validateAnomalyNumber(arg ast.Expr, index int) error {
+	if ast.IsStringArg(arg) || ast.IsTimeArg(arg) || ast.IsBooleanArg(arg) {
+		return ProduceErrInfo(index, "number - float or int")
+	}
+	return nil
+}
+
+func validateAnomalyInt(arg ast.Expr, index int, min int64, name string) error {
+	if ast.IsFloatArg(arg) || ast.IsStringArg(arg) || ast.IsTimeArg(arg) || ast.IsBooleanArg(arg) {
+		return ProduceErrInfo(index, "int")
+	}
+	if p, ok := arg.(*ast.IntegerLiteral); ok && p.Val < min {
+		return fmt.Errorf("the %s must be an integer no less than %d but got %d", name, min, p.Val)
+	}
+	return nil
+}
+
+func validateAnomalyThreshold(arg ast.Expr, index int) error {
+	if err := validateAnomalyNumber(arg, index); err != nil {
+		return err
+	}
+	var v float64
+	switch a := arg.(type) {
+	case *ast.NumberLiteral:
+		v = a.Val
+	case *ast.IntegerLiteral:
+		v = float64(a.Val)
+	default:
+		return nil
+	}
+	if v <= 0 {
+		return fmt.Errorf("the threshold must be a positive number but got %v", v)
+	}
+	return nil
+}
+
+// anomalyResult scores the value by the expected value and the standard deviation. If the standard
+// deviation is 0, the score is 0 and any value different from the expected one is an anomaly.
+func anomalyResult(x, expected, stddev, threshold float64) map[string]interface{} {
+	var (
+		score   float64
+		anomaly bool
+	)
+	if stddev > 0 {
+		score = (x - expected) / stddev
+		anomaly = math.Abs(score) > threshold
+	} else {
+		anomaly = x != expected
+	}
+	return map[string]interface{}{
+		"anomaly":  anomaly,
+		"score":    score,
+		"expected": expected,
+		"stddev":   stddev,
+	}
+}
+
+func meanStddev(values []float64) (float64, float64) {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	var sq float64
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(sq / float64(len(values)))
+}
+
+func median(values []float64) float64 {
+	s := make([]float64, len(values))
+	copy(s, values)
+	sort.Float64s(s)
+	n := len(s)
+	if n%2 == 1 {
+		return s[n/2]
+	}
+	return (s[n/2-1] + s[n/2]) / 2
+}
+
+// seasonalEsd tests if the last value is an anomaly with the seasonal ESD algorithm. The seasonal
+// component is the median of the values in the same phase, which is removed before running the
+// generalized ESD test on the residuals. start is the sequence of the first value to get its phase.
+func seasonalEsd(values []float64, start int64, period int, alpha float64) map[string]interface{} {
+	n := len(values)
+	phase := func(i int) int {
+		return int((start + int64(i)) % int64(period))
+	}
+	groups := make([][]float64, period)
+	for i, v := range values {
+		groups[phase(i)] = append(groups[phase(i)], v)
+	}
+	seasonal := make([]float64, period)
+	for p, g := range groups {
+		seasonal[p] = median(g)
+	}
+	residuals := make([]float64, n)
+	for i, v := range values {
+		residuals[i] = v - seasonal[phase(i)]
+	}
+	// The expected value is not affected by the anomalies with the median
+	_, stddev := meanStddev(residuals)
+	x := values[n-1]
+	expected := seasonal[phase(n-1)] + median(residuals)
+	var score float64
+	if stddev > 0 {
+		score = (x - expected) / stddev
+	}
+	// Test at most 10% of the values as anomalies
+	k := n / 10
+	if k < 1 {
+		k = 1
+	}
+	anomaly := false
+	for _, i := range generalizedEsd(residuals, k, alpha) {
+		if i == n-1 {
+			anomaly = true
+			break
+		}
+	}
+	return map[string]interface{}{
+		"anomaly":  anomaly,
+		"score":    score,
+		"expected": expected,
+		"stddev":   stddev,
+	}
+}
+
+// generalizedEsd returns the indexes of the outliers found by the generalized ESD test with at most k outliers
+func generalizedEsd(values []float64, k int, alpha float64) []int {
+	n := len(values)
+	if k > n-2 {
+		k = n - 2
+	}
+	critical := esdCriticalValues(n, k, alpha)
+	idx := make([]int, n)
+	for i := range idx {
+		idx[i] = i
+	}
+	removed := make([]int, 0, k)
+	outliers := 0
+	for i := 0; i < k; i++ {
+		rest := make([]float64, len(idx))
+		for j, id := range idx {
+			rest[j] = values[id]
+		}
+		mean, stddev := meanStddev(rest)
+		if stddev == 0 {
+			break
+		}
+		maxJ, maxR := 0, -1.0
+		for j, v := range rest {
+			if r := math.Abs(v-mean) / stddev; r > maxR {
+				maxJ, maxR = j, r
+			}
+		}
+		removed = append(removed, idx[maxJ])
+		idx = append(idx[:maxJ], idx[maxJ+1:]...)
+		if maxR > critical[i] {
+			outliers = i + 1
+		}
+	}
+	return removed[:outliers]
+}
+
+type esdKey struct {
+	n     int
+	k     int
+	alpha float64
+}
+
+// esdCache caches the critical values which only depend on the window size and the significance level
+var esdCache sync.Map
+
+func esdCriticalValues(n, k int, alpha float64) []float64 {
+	key := esdKey{n: n, k: k, alpha: alpha}
+	if v, ok := esdCache.Load(key); ok {
+		return v.([]float64)
+	}
+	result := make([]float64, k)
+	for i := 0; i < k; i++ {
+		m := float64(n - i)
+		p := 1 - alpha/(2*m)
+		t := studentTQuantile(p, m-2)
+		result[i] = (m - 1) * t / math.Sqrt((m-2+t*t)*m)
+	}
+	esdCache.Store(key, result)
+	return result
+}
+
+// studentTQuantile returns the quantile of the student's t distribution for p > 0.5 by bisection
+func studentTQuantile(p, df float64) float64 {
+	lo, hi := 0.0, 1.0
+	for studentTCdf(hi, df) < p && hi < 1e10 {
+		lo, hi = hi, hi*2
+	}
+	for i := 0; i < 100; i++ {
+		mid := (lo + hi) / 2
+		if studentTCdf(mid, df) < p {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// studentTCdf returns the cumulative probability of the student's t distribution for t >= 0
+func studentTCdf(t, df float64) float64 {
+	return 1 - 0.5*regIncBeta(df/(df+t*t), df/2, 0.5)
+}
+
+// regIncBeta is the regularized incomplete beta function
+func regIncBeta(x, a, b float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	bt := math.Exp(lab - la - lb + a*math.Log(x) + b*math.Log(1-x))
+	if x < (a+1)/(a+b+2) {
+		return bt * betaContinuedFraction(x, a, b) / a
+	}
+	return 1 - bt*betaContinuedFraction(1-x, b, a)/b
+}
+
+// betaContinuedFraction evaluates the continued fraction of the incomplete beta function by the modified Lentz's method
+func betaContinuedFraction(x, a, b float64) float64 {
+	const (
+		maxIter = 300
+		eps     = 1e-15
+		tiny    = 1e-300
+	)
+	qab, qap, qam := a+b, a+1, a-1
+	c, d := 1.0, 1-qab*x/qap
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= maxIter; m++ {
+		fm := float64(m)
+		m2 := 2 * fm
+		aa := fm * (b - fm) * x / ((qam + m2) * (a + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+		aa = -(a + fm) * (qab + fm) * x / ((a + m2) * (qap + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < eps {
+			break
+		}
+	}
+	return h
+}
@@ -0,0 +1,316 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package function
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	kctx "github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/topo/state"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+func anomalyFuncContext() api.FunctionContext {
+	contextLogger := conf.Log.WithField("rule", "testExec")
+	ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
+	tempStore, _ := state.CreateStore("mockRule0", api.AtMostOnce)
+	return kctx.NewDefaultFuncContext(ctx.WithMeta("mockRule0", "test", tempStore), 2)
+}
+
+func TestAnomalyZscoreExec(t *testing.T) {
+	f, ok := builtins["anomaly_zscore"]
+	require.True(t, ok)
+	fctx := anomalyFuncContext()
+	tests := []struct {
+		args   []interface{}
+		result interface{}
+	}{
+		{ // 0 warm up
+			args:   []interface{}{10, 4, true, "self"},
+			result: nil,
+		},
+		{ // 1 warm up
+			args:   []interface{}{12, 4, true, "self"},
+			result: nil,
+		},
+		{ // 2
+			args:   []interface{}{12.0, 4, true, "self"},
+			result: map[string]interface{}{"anomaly": false, "score": 1.0, "expected": 11.0, "stddev": 1.0},
+		},
+		{ // 3 another partition
+			args:   []interface{}{100, 4, true, "p2"},
+			result: nil,
+		},
+		{ // 4 checked but not recorded
+			args:   []interface{}{1000, 4, 3, false, "self"},
+			result: map[string]interface{}{"anomaly": true, "score": 1048.63935649965, "expected": 34.0 / 3, "stddev": 0.9428090415820634},
+		},
+		{ // 5 nil is ignored
+			args:   []interface{}{nil, 4, true, "self"},
+			result: nil,
+		},
+		{ // 6
+			args:   []interface{}{10, 4, 0.5, true, "self"},
+			result: map[string]interface{}{"anomaly": true, "score": -1.4142135623730956, "expected": 34.0 / 3, "stddev": 0.9428090415820634},
+		},
+		{ // 7
+			args:   []interface{}{"abc", 4, true, "self"},
+			result: errors.New("the value should be number but got string(abc)"),
+		},
+		{ // 8
+			args:   []interface{}{10, 1, true, "self"},
+			result: errors.New("the window size must be an integer no less than 2 but got 1"),
+		},
+		{ // 9
+			args:   []interface{}{10, 4, -1, true, "self"},
+			result: errors.New("the threshold must be a positive number but got -1"),
+		},
+	}
+	for i, tt := range tests {
+		r, _ := f.exec(fctx, tt.args)
+		assertAnomalyResult(t, i, tt.result, r)
+	}
+}
+
+func TestAnomalyZscoreWindow(t *testing.T) {
+	f, ok := builtins["anomaly_zscore"]
+	require.True(t, ok)
+	fctx := anomalyFuncContext()
+	for _, v := range []interface{}{1, 2, 100, 100, 100} {
+		_, ok := f.exec(fctx, []interface{}{v, 3, true, "self"})
+		require.True(t, ok)
+	}
+	// The old values slide out of the window
+	r, ok := f.exec(fctx, []interface{}{100, 3, true, "self"})
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"anomaly": false, "score": 0.0, "expected": 100.0, "stddev": 0.0}, r)
+	r, ok = f.exec(fctx, []interface{}{101, 3, true, "self"})
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"anomaly": true, "score": 0.0, "expected": 100.0, "stddev": 0.0}, r)
+}
+
+func TestAnomalyEwmaExec(t *testing.T) {
+	f, ok := builtins["anomaly_ewma"]
+	require.True(t, ok)
+	fctx := anomalyFuncContext()
+	tests := []struct {
+		args   []interface{}
+		result interface{}
+	}{
+		{ // 0 warm up
+			args:   []interface{}{10, 0.5, true, "self"},
+			result: nil,
+		},
+		{ // 1 warm up, mean 11 variance 1
+			args:   []interface{}{12, 0.5, true, "self"},
+			result: nil,
+		},
+		{ // 2 mean 12 variance 1.5
+			args:   []interface{}{13, 0.5, true, "self"},
+			result: map[string]interface{}{"anomaly": false, "score": 2.0, "expected": 11.0, "stddev": 1.0},
+		},
+		{ // 3
+			args:   []interface{}{30, 0.5, 3, false, "self"},
+			result: map[string]interface{}{"anomaly": true, "score": 14.696938456699069, "expected": 12.0, "stddev": 1.224744871391589},
+		},
+		{ // 4
+			args:   []interface{}{12, 0.5, 3, true, "self"},
+			result: map[string]interface{}{"anomaly": false, "score": 0.0, "expected": 12.0, "stddev": 1.224744871391589},
+		},
+		{ // 5
+			args:   []interface{}{12, 2, true, "self"},
+			result: errors.New("the smoothing factor of parameter 2 must be between 0 and 1 but got 2"),
+		},
+	}
+	for i, tt := range tests {
+		r, _ := f.exec(fctx, tt.args)
+		assertAnomalyResult(t, i, tt.result, r)
+	}
+}
+
+func TestAnomalySeasonalEsdExec(t *testing.T) {
+	f, ok := builtins["anomaly_seasonal_esd"]
+	require.True(t, ok)
+	fctx := anomalyFuncContext()
+	season := []float64{10, 20, 30, 20}
+	// warm up with 4 seasons with small noise
+	for i := 0; i < 15; i++ {
+		noise := float64(i%3) * 0.1
+		r, ok := f.exec(fctx, []interface{}{season[i%4] + noise, 16, 4, true, "self"})
+		require.True(t, ok)
+		require.Nil(t, r, "index %d", i)
+	}
+	// The 16th value is in the phase 3
+	r, ok := f.exec(fctx, []interface{}{20.0, 16, 4, false, "self"})
+	require.True(t, ok)
+	assert.Equal(t, false, r.(map[string]interface{})["anomaly"])
+	// 30 is normal in the phase 2 but an anomaly in the phase 3
+	r, ok = f.exec(fctx, []interface{}{30.0, 16, 4, 0.01, false, "self"})
+	require.True(t, ok)
+	assert.Equal(t, true, r.(map[string]interface{})["anomaly"])
+	assert.InDelta(t, 20.0, r.(map[string]interface{})["expected"], 0.2)
+	r, ok = f.exec(fctx, []interface{}{20.1, 16, 4, true, "self"})
+	require.True(t, ok)
+	assert.Equal(t, false, r.(map[string]interface{})["anomaly"])
+	// phase 0
+	r, ok = f.exec(fctx, []interface{}{30.0, 16, 4, true, "self"})
+	require.True(t, ok)
+	assert.Equal(t, true, r.(map[string]interface{})["anomaly"])
+	assert.InDelta(t, 10.0, r.(map[string]interface{})["expected"], 0.2)
+	// phase 1
+	r, ok = f.exec(fctx, []interface{}{20.0, 16, 4, true, "self"})
+	require.True(t, ok)
+	assert.Equal(t, false, r.(map[string]interface{})["anomaly"])
+
+	_, ok = f.exec(fctx, []interface{}{20.0, 6, 4, true, "self"})
+	assert.False(t, ok)
+	r, ok = f.exec(fctx, []interface{}{20.0, 16, 4, 1, true, "self"})
+	assert.False(t, ok)
+	assert.EqualError(t, r.(error), "the significance level must be between 0 and 1 but got 1")
+}
+
+func TestStudentTQuantile(t *testing.T) {
+	tests := []struct {
+		p  float64
+		df float64
+		t  float64
+	}{
+		{p: 0.975, df: 1, t: 12.706},
+		{p: 0.975, df: 10, t: 2.228},
+		{p: 0.995, df: 30, t: 2.750},
+		{p: 0.9995, df: 5, t: 6.869},
+	}
+	for _, tt := range tests {
+		assert.InDelta(t, tt.t, studentTQuantile(tt.p, tt.df), 0.001)
+	}
+}
+
+func TestGeneralizedEsd(t *testing.T) {
+	// The example from the NIST handbook of the generalized ESD test
+	values := []float64{
+		-0.25, 0.68, 0.94, 1.15, 1.20, 1.26, 1.26, 1.34, 1.38, 1.43, 1.49, 1.49, 1.55, 1.56, 1.58, 1.65, 1.69, 1.70, 1.76, 1.77, 1.81, 1.91, 1.94, 1.96, 1.99, 2.06, 2.09, 2.10, 2.14, 2.15, 2.23, 2.24, 2.26, 2.35, 2.37, 2.40, 2.47, 2.54, 2.62, 2.64, 2.90, 2.92, 2.92, 2.93, 3.21, 3.26, 3.30, 3.59, 3.68, 4.30, 4.64, 5.34, 5.42, 6.01,
+	}
+	critical := esdCriticalValues(len(values), 10, 0.05)
+	assert.InDelta(t, 3.158, critical[0], 0.001)
+	assert.InDelta(t, 3.085, critical[9], 0.001)
+	outliers := generalizedEsd(values, 10, 0.05)
+	assert.ElementsMatch(t, []int{53, 52, 51}, outliers)
+}
+
+func TestAnomalyValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		args []ast.Expr
+		err  string
+	}{
+		{
+			name: "anomaly_zscore",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}},
+			err:  "Expect 2 or 3 arguments but found 1.",
+		},
+		{
+			name: "anomaly_zscore",
+			args: []ast.Expr{&ast.StringLiteral{Val: "a"}, &ast.IntegerLiteral{Val: 10}},
+			err:  "Expect number - float or int type for parameter 1",
+		},
+		{
+			name: "anomaly_zscore",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.NumberLiteral{Val: 10.5}},
+			err:  "Expect int type for parameter 2",
+		},
+		{
+			name: "anomaly_zscore",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.IntegerLiteral{Val: 1}},
+			err:  "the window size must be an integer no less than 2 but got 1",
+		},
+		{
+			name: "anomaly_zscore",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.IntegerLiteral{Val: 10}, &ast.IntegerLiteral{Val: 0}},
+			err:  "the threshold must be a positive number but got 0",
+		},
+		{
+			name: "anomaly_zscore",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.IntegerLiteral{Val: 10}, &ast.NumberLiteral{Val: 2.5}},
+		},
+		{
+			name: "anomaly_ewma",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.NumberLiteral{Val: 1.5}},
+			err:  "the smoothing factor of parameter 2 must be between 0 and 1 but got 1.5",
+		},
+		{
+			name: "anomaly_ewma",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.NumberLiteral{Val: 0.3}, &ast.BooleanLiteral{Val: true}},
+			err:  "Expect number - float or int type for parameter 3",
+		},
+		{
+			name: "anomaly_ewma",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.NumberLiteral{Val: 0.3}},
+		},
+		{
+			name: "anomaly_seasonal_esd",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.IntegerLiteral{Val: 10}},
+			err:  "Expect 3 or 4 arguments but found 2.",
+		},
+		{
+			name: "anomaly_seasonal_esd",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.IntegerLiteral{Val: 10}, &ast.IntegerLiteral{Val: 0}},
+			err:  "the season period must be an integer no less than 1 but got 0",
+		},
+		{
+			name: "anomaly_seasonal_esd",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.IntegerLiteral{Val: 10}, &ast.IntegerLiteral{Val: 6}},
+			err:  "the window size 10 must cover at least two season periods of 6",
+		},
+		{
+			name: "anomaly_seasonal_esd",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.IntegerLiteral{Val: 24}, &ast.IntegerLiteral{Val: 6}, &ast.NumberLiteral{Val: 1.5}},
+			err:  "the significance level must be between 0 and 1 but got 1.5",
+		},
+		{
+			name: "anomaly_seasonal_esd",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.IntegerLiteral{Val: 24}, &ast.IntegerLiteral{Val: 6}, &ast.NumberLiteral{Val: 0.01}},
+		},
+	}
+	for i, tt := range tests {
+		f, ok := builtins[tt.name]
+		require.True(t, ok)
+		err := f.val(nil, tt.args)
+		if tt.err == "" {
+			assert.NoError(t, err, "case %d", i)
+		} else {
+			assert.EqualError(t, err, tt.err, "case %d", i)
+		}
+	}
+}
+
+func assertAnomalyResult(t *testing.T, i int, expected, actual interface{}) {
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		require.True(t, ok, "case %d: %v", i, actual)
+		assert.Equal(t, e["anomaly"], a["anomaly"], "case %d", i)
+		for _, k := range []string{"score", "expected", "stddev"} {
+			assert.InDelta(t, e[k], a[k], 1e-9, "case %d %s", i, k)
+		}
+	default:
+		assert.Equal(t, expected, actual, "case %d", i)
+	}
+}
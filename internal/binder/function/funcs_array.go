@@ -1,4 +1,4 @@
-// Copyright 2023 EMQ Technologies Co., Ltd.
+// Copyright 2023-2024 EMQ Technologies Co., Ltd.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -32,6 +32,7 @@ var (
 	errorArraySecondArgumentNotArrayError  = fmt.Errorf("second argument should be array of interface{}")
 	errorArrayFirstArgumentNotIntError     = fmt.Errorf("first argument should be int")
 	errorArrayFirstArgumentNotStringError  = fmt.Errorf("first argument should be string")
+	errorArrayFirstArgumentNotLambdaError  = fmt.Errorf("first argument should be lambda expression")
 	errorArraySecondArgumentNotIntError    = fmt.Errorf("second argument should be int")
 	errorArraySecondArgumentNotStringError = fmt.Errorf("second argument should be string")
 	errorArrayThirdArgumentNotIntError     = fmt.Errorf("third argument should be int")
@@ -503,6 +504,21 @@ func registerArrayFunc() {
 	builtins["array_map"] = builtinFunc{
 		fType: ast.FuncTypeScalar,
 		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			if f, ok := args[0].(Lambda); ok {
+				array, ok := args[1].([]interface{})
+				if !ok {
+					return errorArraySecondArgumentNotArrayError, false
+				}
+				mapped := make([]interface{}, 0, len(array))
+				for _, v := range array {
+					r, err := callLambda(f, v)
+					if err != nil {
+						return err, false
+					}
+					mapped = append(mapped, r)
+				}
+				return mapped, true
+			}
 			funcName, ok := args[0].(string)
 			if !ok {
 				return errorArrayFirstArgumentNotStringError, false
@@ -540,7 +556,80 @@ func registerArrayFunc() {
 			return mapped, true
 		},
 		val: func(ctx api.FunctionContext, args []ast.Expr) error {
-			return ValidateLen(2, len(args))
+			if err := ValidateLen(2, len(args)); err != nil {
+				return err
+			}
+			if _, ok := args[0].(*ast.LambdaExpr); ok {
+				return validateLambda(args[0], 1)
+			}
+			return nil
+		},
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["array_filter"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			f, ok := args[0].(Lambda)
+			if !ok {
+				return errorArrayFirstArgumentNotLambdaError, false
+			}
+			array, ok := args[1].([]interface{})
+			if !ok {
+				return errorArraySecondArgumentNotArrayError, false
+			}
+			filtered := make([]interface{}, 0, len(array))
+			for _, v := range array {
+				r, err := callLambda(f, v)
+				if err != nil {
+					return err, false
+				}
+				if r == nil {
+					continue
+				}
+				b, ok := r.(bool)
+				if !ok {
+					return fmt.Errorf("the lambda expression should return a bool but got %v", r), false
+				}
+				if b {
+					filtered = append(filtered, v)
+				}
+			}
+			return filtered, true
+		},
+		val: func(ctx api.FunctionContext, args []ast.Expr) error {
+			if err := ValidateLen(2, len(args)); err != nil {
+				return err
+			}
+			return validateLambda(args[0], 1)
+		},
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["array_reduce"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			f, ok := args[0].(Lambda)
+			if !ok {
+				return errorArrayFirstArgumentNotLambdaError, false
+			}
+			array, ok := args[1].([]interface{})
+			if !ok {
+				return errorArraySecondArgumentNotArrayError, false
+			}
+			acc := args[2]
+			for _, v := range array {
+				r, err := callLambda(f, acc, v)
+				if err != nil {
+					return err, false
+				}
+				acc = r
+			}
+			return acc, true
+		},
+		val: func(ctx api.FunctionContext, args []ast.Expr) error {
+			if err := ValidateLen(3, len(args)); err != nil {
+				return err
+			}
+			return validateLambda(args[0], 2)
 		},
 		check: returnNilIfHasAnyNil,
 	}
@@ -0,0 +1,330 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package function
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+// forecastZ is the z score of the 95% confidence interval returned by forecast functions
+const forecastZ = 1.96
+
+// registerForecastFunc registers the aggregate functions which fit a simple model over the
+// values of the current group and predict the next value.
+func registerForecastFunc() {
+	builtins["holt_winters"] = builtinFunc{
+		fType: ast.FuncTypeAgg,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			series, err := toForecastSeries(args[0])
+			if err != nil {
+				return err, false
+			}
+			if len(series) == 0 {
+				return nil, true
+			}
+			params := make([]float64, len(args)-1)
+			for i := 1; i < len(args); i++ {
+				v, ok, err := forecastParam(args[i], i)
+				if err != nil {
+					return err, false
+				}
+				if !ok {
+					return nil, true
+				}
+				params[i-1] = v
+			}
+			for i := 0; i < len(params) && i < 3; i++ {
+				if params[i] < 0 || params[i] > 1 {
+					return fmt.Errorf("the smoothing factor of parameter %d must be between 0 and 1 but got %v", i+2, params[i]), false
+				}
+			}
+			var (
+				f      float64
+				stddev float64
+			)
+			if len(params) == 2 {
+				f, stddev = holtForecast(series, params[0], params[1])
+			} else {
+				period := params[3]
+				if period != math.Trunc(period) || period < 2 {
+					return fmt.Errorf("the season period must be an integer no less than 2 but got %v", period), false
+				}
+				f, stddev = holtWintersForecast(series, params[0], params[1], params[2], int(period))
+			}
+			if math.IsNaN(f) {
+				return nil, true
+			}
+			return forecastResult(f, stddev), true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if len(args) != 3 && len(args) != 5 {
+				return fmt.Errorf("Expect 3 or 5 arguments but found %d.", len(args))
+			}
+			for i, arg := range args {
+				if ast.IsStringArg(arg) || ast.IsTimeArg(arg) || ast.IsBooleanArg(arg) {
+					return ProduceErrInfo(i, "number - float or int")
+				}
+			}
+			for i := 1; i < len(args) && i < 4; i++ {
+				if err := validateSmoothingFactor(args[i], i); err != nil {
+					return err
+				}
+			}
+			if len(args) == 5 {
+				if ast.IsFloatArg(args[4]) {
+					return ProduceErrInfo(4, "int")
+				}
+				if p, ok := args[4].(*ast.IntegerLiteral); ok && p.Val < 2 {
+					return fmt.Errorf("the season period must be an integer no less than 2 but got %d", p.Val)
+				}
+			}
+			return nil
+		},
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["arima_forecast"] = builtinFunc{
+		fType: ast.FuncTypeAgg,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			series, err := toForecastSeries(args[0])
+			if err != nil {
+				return err, false
+			}
+			if len(series) == 0 {
+				return nil, true
+			}
+			p, ok, err := forecastParam(args[1], 1)
+			if err != nil {
+				return err, false
+			}
+			if !ok {
+				return nil, true
+			}
+			if p != math.Trunc(p) || p < 1 {
+				return fmt.Errorf("the autoregressive order must be an integer no less than 1 but got %v", p), false
+			}
+			f, stddev := arimaForecast(series, int(p))
+			if math.IsNaN(f) {
+				return nil, true
+			}
+			return forecastResult(f, stddev), true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if err := ValidateLen(2, len(args)); err != nil {
+				return err
+			}
+			if ast.IsStringArg(args[0]) || ast.IsTimeArg(args[0]) || ast.IsBooleanArg(args[0]) {
+				return ProduceErrInfo(0, "number - float or int")
+			}
+			if ast.IsFloatArg(args[1]) || ast.IsStringArg(args[1]) || ast.IsTimeArg(args[1]) || ast.IsBooleanArg(args[1]) {
+				return ProduceErrInfo(1, "int")
+			}
+			if p, ok := args[1].(*ast.IntegerLiteral); ok && p.Val < 1 {
+				return fmt.Errorf("the autoregressive order must be an integer no less than 1 but got %d", p.Val)
+			}
+			return nil
+		},
+		check: returnNilIfHasAnyNil,
+	}
+}
+
+func validateSmoothingFactor(arg ast.Expr, index int) error {
+	var v float64
+	switch a := arg.(type) {
+	case *ast.NumberLiteral:
+		v = a.Val
+	case *ast.IntegerLiteral:
+		v = float64(a.Val)
+	default:
+		return nil
+	}
+	if v < 0 || v > 1 {
+		return fmt.Errorf("the smoothing factor of parameter %d must be between 0 and 1 but got %v", index+1, v)
+	}
+	return nil
+}
+
+func toForecastSeries(arg interface{}) ([]float64, error) {
+	arg0, ok := arg.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("the first argument to the aggregate function should be []interface but found %[1]T(%[1]v)", arg)
+	}
+	series, err := cast.ToFloat64Slice(arg0, cast.CONVERT_SAMEKIND, cast.IGNORE_NIL)
+	if err != nil {
+		return nil, fmt.Errorf("requires float64 slice but found %[1]T(%[1]v)", arg0)
+	}
+	return series, nil
+}
+
+// forecastParam reads the parameter of the aggregate function. The parameter is evaluated for
+// each row, so the first valid value is used. If the parameter is absent, ok is false.
+func forecastParam(arg interface{}, index int) (float64, bool, error) {
+	args, ok := arg.([]interface{})
+	if !ok {
+		return 0, false, fmt.Errorf("the parameter %d requires []interface but found %[2]T(%[2]v)", index+1, arg)
+	}
+	v := getFirstValidArg(args)
+	if v == nil {
+		return 0, false, nil
+	}
+	r, err := cast.ToFloat64(v, cast.CONVERT_SAMEKIND)
+	if err != nil {
+		return 0, false, fmt.Errorf("the parameter %d requires float64 but found %[2]T(%[2]v)", index+1, v)
+	}
+	return r, true, nil
+}
+
+func forecastResult(f, stddev float64) map[string]interface{} {
+	return map[string]interface{}{
+		"forecast": f,
+		"stddev":   stddev,
+		"lower":    f - forecastZ*stddev,
+		"upper":    f + forecastZ*stddev,
+	}
+}
+
+// holtForecast fits the double exponential smoothing (Holt's linear trend) model and
+// returns the one step ahead forecast and the standard deviation of the in-sample one
+// step ahead errors. NaN is returned if there are not enough values to fit the model.
+func holtForecast(series []float64, alpha, beta float64) (float64, float64) {
+	if len(series) < 2 {
+		return math.NaN(), 0
+	}
+	level := series[0]
+	trend := series[1] - series[0]
+	residuals := make([]float64, 0, len(series)-1)
+	for i := 1; i < len(series); i++ {
+		residuals = append(residuals, series[i]-(level+trend))
+		lastLevel := level
+		level = alpha*series[i] + (1-alpha)*(level+trend)
+		trend = beta*(level-lastLevel) + (1-beta)*trend
+	}
+	return level + trend, residualStddev(residuals)
+}
+
+// holtWintersForecast fits the additive triple exponential smoothing (Holt-Winters) model.
+// At least two full seasons are required to initialize the seasonal components.
+func holtWintersForecast(series []float64, alpha, beta, gamma float64, period int) (float64, float64) {
+	if len(series) < 2*period {
+		return math.NaN(), 0
+	}
+	var first, second float64
+	for i := 0; i < period; i++ {
+		first += series[i]
+		second += series[i+period]
+	}
+	first /= float64(period)
+	second /= float64(period)
+	level := first
+	trend := (second - first) / float64(period)
+	seasonal := make([]float64, period)
+	for i := 0; i < period; i++ {
+		seasonal[i] = series[i] - first
+	}
+	residuals := make([]float64, 0, len(series)-period)
+	for i := period; i < len(series); i++ {
+		s := seasonal[i%period]
+		residuals = append(residuals, series[i]-(level+trend+s))
+		lastLevel := level
+		level = alpha*(series[i]-s) + (1-alpha)*(level+trend)
+		trend = beta*(level-lastLevel) + (1-beta)*trend
+		seasonal[i%period] = gamma*(series[i]-level) + (1-gamma)*s
+	}
+	return level + trend + seasonal[len(series)%period], residualStddev(residuals)
+}
+
+// arimaForecast fits an ARIMA(p,1,0) model: the series is differenced once and an AR(p)
+// model is fitted to the differences with the Yule-Walker equations.
+func arimaForecast(series []float64, p int) (float64, float64) {
+	// need at least p+1 differences to have one in-sample residual
+	if len(series) < p+2 {
+		return math.NaN(), 0
+	}
+	diff := make([]float64, len(series)-1)
+	var mean float64
+	for i := 1; i < len(series); i++ {
+		diff[i-1] = series[i] - series[i-1]
+		mean += diff[i-1]
+	}
+	mean /= float64(len(diff))
+	centered := make([]float64, len(diff))
+	for i, d := range diff {
+		centered[i] = d - mean
+	}
+	phi := yuleWalker(centered, p)
+	predict := func(end int) float64 {
+		r := mean
+		for j := 0; j < p; j++ {
+			r += phi[j] * centered[end-1-j]
+		}
+		return r
+	}
+	residuals := make([]float64, 0, len(diff)-p)
+	for i := p; i < len(diff); i++ {
+		residuals = append(residuals, diff[i]-predict(i))
+	}
+	return series[len(series)-1] + predict(len(diff)), residualStddev(residuals)
+}
+
+// yuleWalker solves the Yule-Walker equations with the Levinson-Durbin recursion.
+// A constant series results in zero coefficients.
+func yuleWalker(x []float64, p int) []float64 {
+	n := len(x)
+	r := make([]float64, p+1)
+	for k := 0; k <= p; k++ {
+		for i := k; i < n; i++ {
+			r[k] += x[i] * x[i-k]
+		}
+		r[k] /= float64(n)
+	}
+	phi := make([]float64, p)
+	if r[0] == 0 {
+		return phi
+	}
+	e := r[0]
+	prev := make([]float64, p)
+	for k := 1; k <= p; k++ {
+		acc := r[k]
+		for j := 1; j < k; j++ {
+			acc -= prev[j-1] * r[k-j]
+		}
+		lambda := acc / e
+		phi[k-1] = lambda
+		for j := 1; j < k; j++ {
+			phi[j-1] = prev[j-1] - lambda*prev[k-j-1]
+		}
+		e *= 1 - lambda*lambda
+		if e <= 0 {
+			break
+		}
+		copy(prev, phi)
+	}
+	return phi
+}
+
+func residualStddev(residuals []float64) float64 {
+	if len(residuals) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, r := range residuals {
+		sum += r * r
+	}
+	return math.Sqrt(sum / float64(len(residuals)))
+}
@@ -0,0 +1,231 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package function
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	kctx "github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/topo/state"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+func repeatArg(v interface{}, n int) []interface{} {
+	r := make([]interface{}, n)
+	for i := range r {
+		r[i] = v
+	}
+	return r
+}
+
+func TestForecastExec(t *testing.T) {
+	contextLogger := conf.Log.WithField("rule", "testExec")
+	ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
+	tempStore, _ := state.CreateStore("mockRule0", api.AtMostOnce)
+	fctx := kctx.NewDefaultFuncContext(ctx.WithMeta("mockRule0", "test", tempStore), 2)
+	linear := []interface{}{int64(10), int64(12), int64(14), int64(16), int64(18), int64(20)}
+	seasonal := []interface{}{1.0, 5.0, 1.0, 5.0, 1.0, 5.0, 1.0, 5.0}
+	tests := []struct {
+		name   string
+		args   []interface{}
+		result interface{}
+		ok     bool
+	}{
+		{ // 0
+			name:   "holt_winters",
+			args:   []interface{}{linear, repeatArg(0.5, 6), repeatArg(0.5, 6)},
+			result: map[string]interface{}{"forecast": 22.0, "stddev": 0.0, "lower": 22.0, "upper": 22.0},
+			ok:     true,
+		},
+		{ // 1
+			name:   "holt_winters",
+			args:   []interface{}{seasonal, repeatArg(0.5, 8), repeatArg(0.1, 8), repeatArg(0.5, 8), repeatArg(int64(2), 8)},
+			result: map[string]interface{}{"forecast": 1.0, "stddev": 0.0, "lower": 1.0, "upper": 1.0},
+			ok:     true,
+		},
+		{ // 2 not enough values for the trend
+			name:   "holt_winters",
+			args:   []interface{}{[]interface{}{1.0}, repeatArg(0.5, 1), repeatArg(0.5, 1)},
+			result: nil,
+			ok:     true,
+		},
+		{ // 3 not enough values for two seasons
+			name:   "holt_winters",
+			args:   []interface{}{[]interface{}{1.0, 5.0, 1.0, 5.0, 1.0}, repeatArg(0.5, 5), repeatArg(0.1, 5), repeatArg(0.5, 5), repeatArg(int64(3), 5)},
+			result: nil,
+			ok:     true,
+		},
+		{ // 4 empty group
+			name:   "holt_winters",
+			args:   []interface{}{[]interface{}{}, []interface{}{}, []interface{}{}},
+			result: nil,
+			ok:     true,
+		},
+		{ // 5
+			name:   "holt_winters",
+			args:   []interface{}{[]interface{}{"a", "b"}, repeatArg(0.5, 2), repeatArg(0.5, 2)},
+			result: errors.New("requires float64 slice but found []interface {}([a b])"),
+			ok:     false,
+		},
+		{ // 6 smoothing factor from a field out of range
+			name:   "holt_winters",
+			args:   []interface{}{linear, repeatArg(1.5, 6), repeatArg(0.5, 6)},
+			result: errors.New("the smoothing factor of parameter 2 must be between 0 and 1 but got 1.5"),
+			ok:     false,
+		},
+		{ // 7
+			name:   "holt_winters",
+			args:   []interface{}{seasonal, repeatArg(0.5, 8), repeatArg(0.1, 8), repeatArg(-0.1, 8), repeatArg(int64(2), 8)},
+			result: errors.New("the smoothing factor of parameter 4 must be between 0 and 1 but got -0.1"),
+			ok:     false,
+		},
+		{ // 8 period from a field which is not an integer
+			name:   "holt_winters",
+			args:   []interface{}{seasonal, repeatArg(0.5, 8), repeatArg(0.1, 8), repeatArg(0.5, 8), repeatArg(2.7, 8)},
+			result: errors.New("the season period must be an integer no less than 2 but got 2.7"),
+			ok:     false,
+		},
+		{ // 9
+			name:   "holt_winters",
+			args:   []interface{}{seasonal, repeatArg(0.5, 8), repeatArg(0.1, 8), repeatArg(0.5, 8), repeatArg(int64(1), 8)},
+			result: errors.New("the season period must be an integer no less than 2 but got 1"),
+			ok:     false,
+		},
+		{ // 10
+			name:   "holt_winters",
+			args:   []interface{}{linear, repeatArg("a", 6), repeatArg(0.5, 6)},
+			result: errors.New("the parameter 2 requires float64 but found string(a)"),
+			ok:     false,
+		},
+		{ // 11
+			name:   "arima_forecast",
+			args:   []interface{}{linear, repeatArg(int64(1), 6)},
+			result: map[string]interface{}{"forecast": 22.0, "stddev": 0.0, "lower": 22.0, "upper": 22.0},
+			ok:     true,
+		},
+		{ // 12
+			name:   "arima_forecast",
+			args:   []interface{}{[]interface{}{1.0, 2.0}, repeatArg(int64(1), 2)},
+			result: nil,
+			ok:     true,
+		},
+		{ // 13 empty group
+			name:   "arima_forecast",
+			args:   []interface{}{[]interface{}{}, []interface{}{}},
+			result: nil,
+			ok:     true,
+		},
+		{ // 14 order from a field which is not an integer
+			name:   "arima_forecast",
+			args:   []interface{}{linear, repeatArg(1.5, 6)},
+			result: errors.New("the autoregressive order must be an integer no less than 1 but got 1.5"),
+			ok:     false,
+		},
+		{ // 15
+			name:   "arima_forecast",
+			args:   []interface{}{linear, repeatArg(int64(0), 6)},
+			result: errors.New("the autoregressive order must be an integer no less than 1 but got 0"),
+			ok:     false,
+		},
+	}
+	for i, tt := range tests {
+		f, ok := builtins[tt.name]
+		require.True(t, ok)
+		r, ok := f.exec(fctx, tt.args)
+		assert.Equal(t, tt.ok, ok, "%d", i)
+		if m, ok := r.(map[string]interface{}); ok {
+			exp, ok := tt.result.(map[string]interface{})
+			require.True(t, ok, "%d: unexpected result %v", i, r)
+			for k, v := range exp {
+				assert.InDelta(t, v, m[k], 1e-9, "%d: key %s", i, k)
+			}
+		} else {
+			assert.Equal(t, tt.result, r, "%d", i)
+		}
+	}
+}
+
+func TestForecastNoise(t *testing.T) {
+	series := []float64{10, 11, 10.5, 12, 11.5, 13, 12.5, 14, 13.5, 15}
+	f, stddev := holtForecast(series, 0.6, 0.3)
+	assert.InDelta(t, 15.5, f, 1.0)
+	assert.Greater(t, stddev, 0.0)
+	f, stddev = arimaForecast(series, 2)
+	assert.InDelta(t, 15, f, 1.5)
+	assert.Greater(t, stddev, 0.0)
+}
+
+func TestForecastValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		args []ast.Expr
+		err  string
+	}{
+		{
+			name: "holt_winters",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.NumberLiteral{Val: 0.5}, &ast.NumberLiteral{Val: 0.5}},
+		},
+		{
+			name: "holt_winters",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.NumberLiteral{Val: 0.5}},
+			err:  "Expect 3 or 5 arguments but found 2.",
+		},
+		{
+			name: "holt_winters",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.NumberLiteral{Val: 1.5}, &ast.NumberLiteral{Val: 0.5}},
+			err:  "the smoothing factor of parameter 2 must be between 0 and 1 but got 1.5",
+		},
+		{
+			name: "holt_winters",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.NumberLiteral{Val: 0.5}, &ast.NumberLiteral{Val: 0.5}, &ast.NumberLiteral{Val: 0.5}, &ast.IntegerLiteral{Val: 1}},
+			err:  "the season period must be an integer no less than 2 but got 1",
+		},
+		{
+			name: "holt_winters",
+			args: []ast.Expr{&ast.StringLiteral{Val: "a"}, &ast.NumberLiteral{Val: 0.5}, &ast.NumberLiteral{Val: 0.5}},
+			err:  "Expect number - float or int type for parameter 1",
+		},
+		{
+			name: "arima_forecast",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.IntegerLiteral{Val: 2}},
+		},
+		{
+			name: "arima_forecast",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.NumberLiteral{Val: 2.5}},
+			err:  "Expect int type for parameter 2",
+		},
+		{
+			name: "arima_forecast",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.IntegerLiteral{Val: 0}},
+			err:  "the autoregressive order must be an integer no less than 1 but got 0",
+		},
+	}
+	for i, tt := range tests {
+		f, ok := builtins[tt.name]
+		require.True(t, ok)
+		err := f.val(nil, tt.args)
+		if tt.err == "" {
+			assert.NoError(t, err, "%d", i)
+		} else {
+			assert.EqualError(t, err, tt.err, "%d", i)
+		}
+	}
+}
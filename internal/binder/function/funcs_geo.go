@@ -0,0 +1,159 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package function
+
+import (
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/internal/pkg/geo"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+// registerGeoFunc registers the geospatial functions. The locations are represented by the latitude
+// and longitude in degrees.
+func registerGeoFunc() {
+	builtins["st_distance"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			p1, err := geoPoint(args, 0)
+			if err != nil {
+				return err, false
+			}
+			p2, err := geoPoint(args, 2)
+			if err != nil {
+				return err, false
+			}
+			return geo.Distance(p1, p2), true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if err := ValidateLen(4, len(args)); err != nil {
+				return err
+			}
+			return validateGeoArgs(args, 4)
+		},
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["st_within"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			p, err := geoPoint(args, 0)
+			if err != nil {
+				return err, false
+			}
+			name, ok := args[2].(string)
+			if !ok {
+				return fmt.Errorf("the geofence name must be a string but got %v", args[2]), false
+			}
+			r, err := geo.Within(name, p)
+			if err != nil {
+				return err, false
+			}
+			return r, true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if err := ValidateLen(3, len(args)); err != nil {
+				return err
+			}
+			if err := validateGeoArgs(args, 2); err != nil {
+				return err
+			}
+			if ast.IsNumericArg(args[2]) || ast.IsTimeArg(args[2]) || ast.IsBooleanArg(args[2]) {
+				return ProduceErrInfo(2, "string")
+			}
+			return nil
+		},
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["geohash_encode"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			p, err := geoPoint(args, 0)
+			if err != nil {
+				return err, false
+			}
+			precision := 12
+			if len(args) == 3 {
+				precision, err = cast.ToInt(args[2], cast.STRICT)
+				if err != nil {
+					return fmt.Errorf("the precision must be an integer but got %v", args[2]), false
+				}
+			}
+			r, err := geo.GeohashEncode(p, precision)
+			if err != nil {
+				return err, false
+			}
+			return r, true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if len(args) != 2 && len(args) != 3 {
+				return fmt.Errorf("Expect 2 or 3 arguments but found %d.", len(args))
+			}
+			if err := validateGeoArgs(args, 2); err != nil {
+				return err
+			}
+			if len(args) == 3 {
+				if ast.IsFloatArg(args[2]) || ast.IsStringArg(args[2]) || ast.IsTimeArg(args[2]) || ast.IsBooleanArg(args[2]) {
+					return ProduceErrInfo(2, "int")
+				}
+				if p, ok := args[2].(*ast.IntegerLiteral); ok && (p.Val < 1 || p.Val > 12) {
+					return fmt.Errorf("geohash precision must be between 1 and 12 but got %d", p.Val)
+				}
+			}
+			return nil
+		},
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["geohash_decode"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			hash, ok := args[0].(string)
+			if !ok {
+				return fmt.Errorf("the geohash must be a string but got %v", args[0]), false
+			}
+			p, err := geo.GeohashDecode(hash)
+			if err != nil {
+				return err, false
+			}
+			return map[string]interface{}{"lat": p.Lat, "lon": p.Lon}, true
+		},
+		val:   ValidateOneStrArg,
+		check: returnNilIfHasAnyNil,
+	}
+}
+
+// geoPoint reads the latitude and longitude arguments from the index
+func geoPoint(args []interface{}, index int) (geo.Point, error) {
+	lat, err := cast.ToFloat64(args[index], cast.CONVERT_SAMEKIND)
+	if err != nil {
+		return geo.Point{}, fmt.Errorf("the latitude must be a number but got %v", args[index])
+	}
+	lon, err := cast.ToFloat64(args[index+1], cast.CONVERT_SAMEKIND)
+	if err != nil {
+		return geo.Point{}, fmt.Errorf("the longitude must be a number but got %v", args[index+1])
+	}
+	return geo.Point{Lat: lat, Lon: lon}, nil
+}
+
+// validateGeoArgs validates the first n arguments are numbers of the coordinates
+func validateGeoArgs(args []ast.Expr, n int) error {
+	for i := 0; i < n; i++ {
+		if ast.IsStringArg(args[i]) || ast.IsTimeArg(args[i]) || ast.IsBooleanArg(args[i]) {
+			return ProduceErrInfo(i, "number - float or int")
+		}
+	}
+	return nil
+}
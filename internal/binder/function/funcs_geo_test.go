@@ -0,0 +1,164 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package function
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/pkg/geo"
+	kctx "github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/topo/state"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+func TestGeoExec(t *testing.T) {
+	require.NoError(t, geo.Create(&geo.Fence{
+		Name:    "zone1",
+		Polygon: []geo.Point{{Lat: 0, Lon: 0}, {Lat: 0, Lon: 1}, {Lat: 1, Lon: 1}, {Lat: 1, Lon: 0}},
+	}))
+	defer func() {
+		_ = geo.Delete("zone1")
+	}()
+	contextLogger := conf.Log.WithField("rule", "testExec")
+	ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
+	tempStore, _ := state.CreateStore("mockRule0", api.AtMostOnce)
+	fctx := kctx.NewDefaultFuncContext(ctx.WithMeta("mockRule0", "test", tempStore), 2)
+	tests := []struct {
+		name   string
+		args   []interface{}
+		result interface{}
+	}{
+		{
+			name:   "st_within",
+			args:   []interface{}{0.5, 0.5, "zone1"},
+			result: true,
+		},
+		{
+			name:   "st_within",
+			args:   []interface{}{1.5, int64(0), "zone1"},
+			result: false,
+		},
+		{
+			name:   "st_within",
+			args:   []interface{}{0.5, 0.5, "zone2"},
+			result: errors.New("geofence zone2 is not found"),
+		},
+		{
+			name:   "st_within",
+			args:   []interface{}{"a", 0.5, "zone1"},
+			result: errors.New("the latitude must be a number but got a"),
+		},
+		{
+			name:   "geohash_encode",
+			args:   []interface{}{57.64911, 10.40744, 11},
+			result: "u4pruydqqvj",
+		},
+		{
+			name:   "geohash_encode",
+			args:   []interface{}{57.64911, 10.40744},
+			result: "u4pruydqqvj8",
+		},
+		{
+			name:   "geohash_encode",
+			args:   []interface{}{57.64911, 190, 5},
+			result: errors.New("longitude 190 is out of range [-180, 180]"),
+		},
+		{
+			name:   "geohash_decode",
+			args:   []interface{}{"s"},
+			result: map[string]interface{}{"lat": 22.5, "lon": 22.5},
+		},
+		{
+			name:   "geohash_decode",
+			args:   []interface{}{"sa"},
+			result: errors.New("invalid geohash sa"),
+		},
+	}
+	for i, tt := range tests {
+		f, ok := builtins[tt.name]
+		require.True(t, ok)
+		r, _ := f.exec(fctx, tt.args)
+		if e, ok := tt.result.(error); ok {
+			rerr, ok := r.(error)
+			require.True(t, ok, "case %d", i)
+			assert.EqualError(t, rerr, e.Error(), "case %d", i)
+		} else {
+			assert.Equal(t, tt.result, r, "case %d", i)
+		}
+	}
+	f := builtins["st_distance"]
+	r, ok := f.exec(fctx, []interface{}{31.2304, 121.4737, 39.9042, int64(116)})
+	require.True(t, ok)
+	assert.InDelta(t, 1067000, r, 50000)
+}
+
+func TestGeoValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		args []ast.Expr
+		err  string
+	}{
+		{
+			name: "st_distance",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.FieldRef{Name: "b"}, &ast.FieldRef{Name: "c"}},
+			err:  "Expect 4 arguments but found 3.",
+		},
+		{
+			name: "st_distance",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.FieldRef{Name: "b"}, &ast.FieldRef{Name: "c"}, &ast.StringLiteral{Val: "d"}},
+			err:  "Expect number - float or int type for parameter 4",
+		},
+		{
+			name: "st_within",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.FieldRef{Name: "b"}, &ast.IntegerLiteral{Val: 1}},
+			err:  "Expect string type for parameter 3",
+		},
+		{
+			name: "st_within",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.FieldRef{Name: "b"}, &ast.StringLiteral{Val: "zone1"}},
+		},
+		{
+			name: "geohash_encode",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.FieldRef{Name: "b"}, &ast.IntegerLiteral{Val: 13}},
+			err:  "geohash precision must be between 1 and 12 but got 13",
+		},
+		{
+			name: "geohash_encode",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.FieldRef{Name: "b"}, &ast.NumberLiteral{Val: 1.5}},
+			err:  "Expect int type for parameter 3",
+		},
+		{
+			name: "geohash_decode",
+			args: []ast.Expr{&ast.IntegerLiteral{Val: 1}},
+			err:  "Expect string type for parameter 1",
+		},
+	}
+	for i, tt := range tests {
+		f, ok := builtins[tt.name]
+		require.True(t, ok)
+		err := f.val(nil, tt.args)
+		if tt.err == "" {
+			assert.NoError(t, err, "case %d", i)
+		} else {
+			assert.EqualError(t, err, tt.err, "case %d", i)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package function
+
+import (
+	"bytes"
+	b64 "encoding/base64"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+// registerImageFunc registers the functions to process the binary payload such as the frames of a camera. The images
+// are decoded from and encoded to bytea, the supported formats are jpeg, png and gif.
+func registerImageFunc() {
+	builtins["resize_image"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			img, format, err := decodeImage(args[0])
+			if err != nil {
+				return err, false
+			}
+			width, err := imageIntArg(args[1], 1)
+			if err != nil {
+				return err, false
+			}
+			height, err := imageIntArg(args[2], 2)
+			if err != nil {
+				return err, false
+			}
+			b := img.Bounds()
+			switch {
+			case width == 0 && height == 0:
+				return fmt.Errorf("the width and height cannot both be 0"), false
+			case width == 0:
+				width = b.Dx() * height / b.Dy()
+			case height == 0:
+				height = b.Dy() * width / b.Dx()
+			}
+			if width < 1 {
+				width = 1
+			}
+			if height < 1 {
+				height = 1
+			}
+			return encodeImage(resizeImage(img, width, height), format, jpeg.DefaultQuality)
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if err := ValidateLen(3, len(args)); err != nil {
+				return err
+			}
+			return validateImageArgs(args)
+		},
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["crop"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			img, format, err := decodeImage(args[0])
+			if err != nil {
+				return err, false
+			}
+			var p [4]int
+			for i := range p {
+				p[i], err = imageIntArg(args[i+1], i+1)
+				if err != nil {
+					return err, false
+				}
+			}
+			b := img.Bounds()
+			r := image.Rect(p[0], p[1], p[0]+p[2], p[1]+p[3]).Add(b.Min).Intersect(b)
+			if r.Empty() {
+				return fmt.Errorf("the crop area %dx%d at (%d,%d) is out of the image bounds %dx%d", p[2], p[3], p[0], p[1], b.Dx(), b.Dy()), false
+			}
+			// All the images decoded by the standard library support sub image
+			sub := img.(interface {
+				SubImage(r image.Rectangle) image.Image
+			}).SubImage(r)
+			return encodeImage(sub, format, jpeg.DefaultQuality)
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if err := ValidateLen(5, len(args)); err != nil {
+				return err
+			}
+			return validateImageArgs(args)
+		},
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["to_jpeg"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			img, _, err := decodeImage(args[0])
+			if err != nil {
+				return err, false
+			}
+			quality := jpeg.DefaultQuality
+			if len(args) > 1 {
+				quality, err = imageIntArg(args[1], 1)
+				if err != nil {
+					return err, false
+				}
+				if quality < 1 || quality > 100 {
+					return fmt.Errorf("the jpeg quality must be between 1 and 100 but got %d", quality), false
+				}
+			}
+			return encodeImage(img, "jpeg", quality)
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if len(args) != 1 && len(args) != 2 {
+				return fmt.Errorf("Expect 1 or 2 arguments but found %d.", len(args))
+			}
+			if err := validateImageArgs(args); err != nil {
+				return err
+			}
+			if len(args) > 1 {
+				if q, ok := args[1].(*ast.IntegerLiteral); ok && (q.Val < 1 || q.Val > 100) {
+					return fmt.Errorf("the jpeg quality must be between 1 and 100 but got %d", q.Val)
+				}
+			}
+			return nil
+		},
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["to_png"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			img, _, err := decodeImage(args[0])
+			if err != nil {
+				return err, false
+			}
+			return encodeImage(img, "png", 0)
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if err := ValidateLen(1, len(args)); err != nil {
+				return err
+			}
+			return validateImageArgs(args)
+		},
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["to_base64"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			switch v := args[0].(type) {
+			case []byte:
+				return b64.StdEncoding.EncodeToString(v), true
+			case string:
+				return b64.StdEncoding.EncodeToString([]byte(v)), true
+			default:
+				return fmt.Errorf("expect bytea or string type for parameter 1 but got %v", args[0]), false
+			}
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if err := ValidateLen(1, len(args)); err != nil {
+				return err
+			}
+			if ast.IsNumericArg(args[0]) || ast.IsTimeArg(args[0]) || ast.IsBooleanArg(args[0]) {
+				return ProduceErrInfo(0, "bytea or string")
+			}
+			return nil
+		},
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["from_base64"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			v, ok := args[0].(string)
+			if !ok {
+				return fmt.Errorf("expect string type for parameter 1 but got %v", args[0]), false
+			}
+			r, err := b64.StdEncoding.DecodeString(v)
+			if err != nil {
+				return fmt.Errorf("fail to decode base64 string: %v", err), false
+			}
+			return r, true
+		},
+		val:   ValidateOneStrArg,
+		check: returnNilIfHasAnyNil,
+	}
+}
+
+// validateImageArgs validates the image as the first argument and the int parameters after it
+func validateImageArgs(args []ast.Expr) error {
+	if ast.IsNumericArg(args[0]) || ast.IsStringArg(args[0]) || ast.IsTimeArg(args[0]) || ast.IsBooleanArg(args[0]) {
+		return ProduceErrInfo(0, "bytea")
+	}
+	for i := 1; i < len(args); i++ {
+		if ast.IsFloatArg(args[i]) || ast.IsStringArg(args[i]) || ast.IsTimeArg(args[i]) || ast.IsBooleanArg(args[i]) {
+			return ProduceErrInfo(i, "int")
+		}
+		if v, ok := args[i].(*ast.IntegerLiteral); ok && v.Val < 0 {
+			return fmt.Errorf("parameter %d must not be negative but got %d", i+1, v.Val)
+		}
+	}
+	return nil
+}
+
+func imageIntArg(arg interface{}, index int) (int, error) {
+	v, err := cast.ToInt(arg, cast.STRICT)
+	if err != nil {
+		return 0, fmt.Errorf("expect int type for parameter %d but got %v", index+1, arg)
+	}
+	if v < 0 {
+		return 0, fmt.Errorf("parameter %d must not be negative but got %d", index+1, v)
+	}
+	return v, nil
+}
+
+func decodeImage(arg interface{}) (image.Image, string, error) {
+	data, ok := arg.([]byte)
+	if !ok {
+		return nil, "", fmt.Errorf("expect bytea type for parameter 1 but got %T", arg)
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("fail to decode image: %v", err)
+	}
+	return img, format, nil
+}
+
+// encodeImage encodes the image and returns it as the function result
+func encodeImage(img image.Image, format string, quality int) (interface{}, bool) {
+	var (
+		buf bytes.Buffer
+		err error
+	)
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	case "png":
+		err = png.Encode(&buf, img)
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	default:
+		err = fmt.Errorf("unsupported image format %s", format)
+	}
+	if err != nil {
+		return fmt.Errorf("fail to encode image: %v", err), false
+	}
+	return buf.Bytes(), true
+}
+
+// resizeImage scales the image to the given size with bilinear interpolation
+func resizeImage(img image.Image, width, height int) image.Image {
+	b := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	sw, sh := b.Dx(), b.Dy()
+	xScale := float64(sw) / float64(width)
+	yScale := float64(sh) / float64(height)
+	for y := 0; y < height; y++ {
+		sy := (float64(y)+0.5)*yScale - 0.5
+		y0, y1, fy := bilinearNeighbors(sy, sh)
+		for x := 0; x < width; x++ {
+			sx := (float64(x)+0.5)*xScale - 0.5
+			x0, x1, fx := bilinearNeighbors(sx, sw)
+			p00 := src.PixOffset(x0, y0)
+			p01 := src.PixOffset(x1, y0)
+			p10 := src.PixOffset(x0, y1)
+			p11 := src.PixOffset(x1, y1)
+			d := dst.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				top := float64(src.Pix[p00+c])*(1-fx) + float64(src.Pix[p01+c])*fx
+				bottom := float64(src.Pix[p10+c])*(1-fx) + float64(src.Pix[p11+c])*fx
+				dst.Pix[d+c] = uint8(top*(1-fy) + bottom*fy + 0.5)
+			}
+		}
+	}
+	return dst
+}
+
+// bilinearNeighbors returns the two source pixels around the position and the weight of the second one
+func bilinearNeighbors(pos float64, size int) (int, int, float64) {
+	if pos <= 0 {
+		return 0, 0, 0
+	}
+	i := int(pos)
+	if i >= size-1 {
+		return size - 1, size - 1, 0
+	}
+	return i, i + 1, pos - float64(i)
+}
@@ -0,0 +1,262 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package function
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	kctx "github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/topo/state"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+// testImage returns a 4x2 png whose left half is red and right half is blue
+func testImage(t *testing.T) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			if x < 2 {
+				img.Set(x, y, color.NRGBA{R: 255, A: 255})
+			} else {
+				img.Set(x, y, color.NRGBA{B: 255, A: 255})
+			}
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestImageExec(t *testing.T) {
+	contextLogger := conf.Log.WithField("rule", "testExec")
+	ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
+	tempStore, _ := state.CreateStore("mockRule0", api.AtMostOnce)
+	fctx := kctx.NewDefaultFuncContext(ctx.WithMeta("mockRule0", "test", tempStore), 2)
+	raw := testImage(t)
+	tests := []struct {
+		name   string
+		args   []interface{}
+		format string
+		size   image.Point
+		// the expected color of the top left pixel
+		color color.Color
+		err   string
+	}{
+		{ // 0
+			name:   "resize_image",
+			args:   []interface{}{raw, 2, 1},
+			format: "png",
+			size:   image.Pt(2, 1),
+			color:  color.RGBA{R: 255, A: 255},
+		},
+		{ // 1 keep the aspect ratio
+			name:   "resize_image",
+			args:   []interface{}{raw, int64(8), int64(0)},
+			format: "png",
+			size:   image.Pt(8, 4),
+			color:  color.RGBA{R: 255, A: 255},
+		},
+		{ // 2
+			name: "resize_image",
+			args: []interface{}{raw, 0, 0},
+			err:  "the width and height cannot both be 0",
+		},
+		{ // 3
+			name: "resize_image",
+			args: []interface{}{raw, -1, 2},
+			err:  "parameter 2 must not be negative but got -1",
+		},
+		{ // 4
+			name: "resize_image",
+			args: []interface{}{[]byte("not an image"), 2, 1},
+			err:  "fail to decode image: image: unknown format",
+		},
+		{ // 5
+			name: "resize_image",
+			args: []interface{}{"abc", 2, 1},
+			err:  "expect bytea type for parameter 1 but got string",
+		},
+		{ // 6
+			name:   "crop",
+			args:   []interface{}{raw, 2, 0, 2, 2},
+			format: "png",
+			size:   image.Pt(2, 2),
+			color:  color.RGBA{B: 255, A: 255},
+		},
+		{ // 7 clipped by the image bounds
+			name:   "crop",
+			args:   []interface{}{raw, 1, 1, 10, 10},
+			format: "png",
+			size:   image.Pt(3, 1),
+			color:  color.RGBA{R: 255, A: 255},
+		},
+		{ // 8
+			name: "crop",
+			args: []interface{}{raw, 4, 0, 2, 2},
+			err:  "the crop area 2x2 at (4,0) is out of the image bounds 4x2",
+		},
+		{ // 9
+			name:   "to_jpeg",
+			args:   []interface{}{raw},
+			format: "jpeg",
+			size:   image.Pt(4, 2),
+		},
+		{ // 10
+			name:   "to_jpeg",
+			args:   []interface{}{raw, 90},
+			format: "jpeg",
+			size:   image.Pt(4, 2),
+		},
+		{ // 11
+			name: "to_jpeg",
+			args: []interface{}{raw, 101},
+			err:  "the jpeg quality must be between 1 and 100 but got 101",
+		},
+		{ // 12
+			name:   "to_png",
+			args:   []interface{}{raw},
+			format: "png",
+			size:   image.Pt(4, 2),
+			color:  color.RGBA{R: 255, A: 255},
+		},
+	}
+	for i, tt := range tests {
+		f, ok := builtins[tt.name]
+		require.True(t, ok)
+		r, b := f.exec(fctx, tt.args)
+		if tt.err != "" {
+			assert.False(t, b, "%d", i)
+			assert.EqualError(t, r.(error), tt.err, "%d", i)
+			continue
+		}
+		require.True(t, b, "%d: %v", i, r)
+		img, format, err := image.Decode(bytes.NewReader(r.([]byte)))
+		require.NoError(t, err, "%d", i)
+		assert.Equal(t, tt.format, format, "%d", i)
+		assert.Equal(t, tt.size, img.Bounds().Size(), "%d", i)
+		if tt.color != nil {
+			assert.Equal(t, tt.color, img.At(img.Bounds().Min.X, img.Bounds().Min.Y), "%d", i)
+		}
+	}
+}
+
+func TestBase64Exec(t *testing.T) {
+	contextLogger := conf.Log.WithField("rule", "testExec")
+	ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
+	tempStore, _ := state.CreateStore("mockRule0", api.AtMostOnce)
+	fctx := kctx.NewDefaultFuncContext(ctx.WithMeta("mockRule0", "test", tempStore), 2)
+	enc := builtins["to_base64"]
+	dec := builtins["from_base64"]
+
+	r, ok := enc.exec(fctx, []interface{}{[]byte{0xff, 0x00, 0x01}})
+	require.True(t, ok)
+	assert.Equal(t, "/wAB", r)
+	r, ok = enc.exec(fctx, []interface{}{"hello"})
+	require.True(t, ok)
+	assert.Equal(t, "aGVsbG8=", r)
+	r, ok = enc.exec(fctx, []interface{}{1})
+	assert.False(t, ok)
+	assert.EqualError(t, r.(error), "expect bytea or string type for parameter 1 but got 1")
+
+	r, ok = dec.exec(fctx, []interface{}{"/wAB"})
+	require.True(t, ok)
+	assert.Equal(t, []byte{0xff, 0x00, 0x01}, r)
+	r, ok = dec.exec(fctx, []interface{}{"!!"})
+	assert.False(t, ok)
+	assert.EqualError(t, r.(error), "fail to decode base64 string: illegal base64 data at input byte 0")
+}
+
+func TestImageValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		args []ast.Expr
+		err  string
+	}{
+		{
+			name: "resize_image",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.IntegerLiteral{Val: 2}, &ast.FieldRef{Name: "h"}},
+		},
+		{
+			name: "resize_image",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.IntegerLiteral{Val: 2}},
+			err:  "Expect 3 arguments but found 2.",
+		},
+		{
+			name: "resize_image",
+			args: []ast.Expr{&ast.StringLiteral{Val: "a"}, &ast.IntegerLiteral{Val: 2}, &ast.IntegerLiteral{Val: 2}},
+			err:  "Expect bytea type for parameter 1",
+		},
+		{
+			name: "resize_image",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.NumberLiteral{Val: 2.5}, &ast.IntegerLiteral{Val: 2}},
+			err:  "Expect int type for parameter 2",
+		},
+		{
+			name: "crop",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.IntegerLiteral{Val: 0}, &ast.IntegerLiteral{Val: 0}, &ast.IntegerLiteral{Val: 2}, &ast.IntegerLiteral{Val: 2}},
+		},
+		{
+			name: "crop",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.IntegerLiteral{Val: -1}, &ast.IntegerLiteral{Val: 0}, &ast.IntegerLiteral{Val: 2}, &ast.IntegerLiteral{Val: 2}},
+			err:  "parameter 2 must not be negative but got -1",
+		},
+		{
+			name: "to_jpeg",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}},
+		},
+		{
+			name: "to_jpeg",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.IntegerLiteral{Val: 0}},
+			err:  "the jpeg quality must be between 1 and 100 but got 0",
+		},
+		{
+			name: "to_jpeg",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.IntegerLiteral{Val: 80}, &ast.IntegerLiteral{Val: 80}},
+			err:  "Expect 1 or 2 arguments but found 3.",
+		},
+		{
+			name: "to_png",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}},
+		},
+		{
+			name: "to_base64",
+			args: []ast.Expr{&ast.IntegerLiteral{Val: 1}},
+			err:  "Expect bytea or string type for parameter 1",
+		},
+		{
+			name: "from_base64",
+			args: []ast.Expr{&ast.StringLiteral{Val: "/wAB"}},
+		},
+	}
+	for i, tt := range tests {
+		f, ok := builtins[tt.name]
+		require.True(t, ok)
+		err := f.val(nil, tt.args)
+		if tt.err == "" {
+			assert.NoError(t, err, "%d", i)
+		} else {
+			assert.EqualError(t, err, tt.err, "%d", i)
+		}
+	}
+}
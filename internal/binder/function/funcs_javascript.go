@@ -0,0 +1,113 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package function
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/dop251/goja"
+
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+// jsTimeout is the max time to run the script for a tuple, the script is interrupted when exceeded
+var jsTimeout = time.Second
+
+// jsFunc runs an inline javascript function expression such as `(msg, meta) => msg.a + 1` with the rest
+// arguments. Each instance has its own vm and the script is compiled once.
+type jsFunc struct {
+	script string
+	vm     *goja.Runtime
+	fn     goja.Callable
+	// state, use this to avoid creating new array each time
+	args []goja.Value
+}
+
+func (f *jsFunc) Validate(args []interface{}) error {
+	if err := ValidateAtLeast(1, len(args)); err != nil {
+		return err
+	}
+	s, ok := args[0].(*ast.StringLiteral)
+	if !ok {
+		return ProduceErrInfo(0, "string literal")
+	}
+	_, _, err := compileJs(s.Val)
+	return err
+}
+
+func (f *jsFunc) Exec(args []interface{}, ctx api.FunctionContext) (interface{}, bool) {
+	script := cast.ToStringAlways(args[0])
+	if f.fn == nil || f.script != script {
+		ctx.GetLogger().Infof("compiling js function")
+		vm, fn, err := compileJs(script)
+		if err != nil {
+			return err, false
+		}
+		f.vm, f.fn, f.script = vm, fn, script
+	}
+	if len(args)-1 != len(f.args) {
+		f.args = make([]goja.Value, len(args)-1)
+	}
+	for i, arg := range args[1:] {
+		f.args[i] = f.vm.ToValue(arg)
+	}
+	timer := time.AfterFunc(jsTimeout, func() {
+		f.vm.Interrupt(fmt.Sprintf("timeout after %v", jsTimeout))
+	})
+	val, err := f.fn(goja.Undefined(), f.args...)
+	timer.Stop()
+	f.vm.ClearInterrupt()
+	if err != nil {
+		return fmt.Errorf("failed to execute js: %v", err), false
+	}
+	result := val.Export()
+	if t, ok := result.(float64); ok {
+		if math.IsNaN(t) {
+			return fmt.Errorf("result is NaN"), false
+		}
+		if math.IsInf(t, 0) {
+			return fmt.Errorf("result is Inf"), false
+		}
+	}
+	return result, true
+}
+
+func (f *jsFunc) IsAggregate() bool {
+	return false
+}
+
+// compileJs evaluates the script as an expression which must return a function
+func compileJs(script string) (*goja.Runtime, goja.Callable, error) {
+	vm := goja.New()
+	// The expression itself can run any code such as a loop, so it is limited by the timeout too
+	timer := time.AfterFunc(jsTimeout, func() {
+		vm.Interrupt(fmt.Sprintf("timeout after %v", jsTimeout))
+	})
+	v, err := vm.RunString("(" + script + ")")
+	timer.Stop()
+	vm.ClearInterrupt()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid js: %v", err)
+	}
+	fn, ok := goja.AssertFunction(v)
+	if !ok {
+		return nil, nil, fmt.Errorf("js must be a function expression such as (msg, meta) => msg")
+	}
+	return vm, fn, nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package function
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	kctx "github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/topo/state"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+func TestJsValidate(t *testing.T) {
+	ff, ok := builtinStatfulFuncs["js"]
+	if !ok {
+		t.Fatal("builtin not found")
+	}
+	tests := []struct {
+		args []interface{}
+		err  string
+	}{
+		{
+			args: []interface{}{},
+			err:  "At least has 1 argument but found 0.",
+		}, {
+			args: []interface{}{&ast.FieldRef{Name: "a"}},
+			err:  "Expect string literal type for parameter 1",
+		}, {
+			args: []interface{}{&ast.StringLiteral{Val: "1 + 1"}},
+			err:  "js must be a function expression such as (msg, meta) => msg",
+		}, {
+			args: []interface{}{&ast.StringLiteral{Val: "(msg) => msg.a +"}},
+			err:  "invalid js",
+		}, {
+			args: []interface{}{&ast.StringLiteral{Val: "(msg, meta) => msg.a + 1"}, &ast.Wildcard{Token: ast.ASTERISK}},
+		},
+	}
+	for i, tt := range tests {
+		err := ff().Validate(tt.args)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%d: unexpected error %v", i, err)
+			}
+		} else if err == nil || len(err.Error()) < len(tt.err) || err.Error()[:len(tt.err)] != tt.err {
+			t.Errorf("%d: error mismatch, got %v, want %s", i, err, tt.err)
+		}
+	}
+}
+
+func TestJsExec(t *testing.T) {
+	ff, ok := builtinStatfulFuncs["js"]
+	if !ok {
+		t.Fatal("builtin not found")
+	}
+	f := ff()
+	contextLogger := conf.Log.WithField("rule", "testJsExec")
+	ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
+	tempStore, _ := state.CreateStore("mockRule0", api.AtMostOnce)
+	fctx := kctx.NewDefaultFuncContext(ctx.WithMeta("mockRule0", "test", tempStore), 2)
+	script := "(msg, meta) => ({f: msg.temperature * 1.8 + 32, topic: meta.topic})"
+	tests := []struct {
+		args   []interface{}
+		result interface{}
+	}{
+		{
+			args:   []interface{}{script, map[string]interface{}{"temperature": 20}, map[string]interface{}{"topic": "demo"}},
+			result: map[string]interface{}{"f": int64(68), "topic": "demo"},
+		}, {
+			args:   []interface{}{script, map[string]interface{}{"temperature": 0.5}, map[string]interface{}{"topic": "demo2"}},
+			result: map[string]interface{}{"f": 32.9, "topic": "demo2"},
+		}, {
+			args:   []interface{}{"(a, b) => a / b", 1, 0},
+			result: errors.New("result is Inf"),
+		}, {
+			args:   []interface{}{"(a) => a.toUpperCase()", "hello"},
+			result: "HELLO",
+		},
+	}
+	for i, tt := range tests {
+		result, _ := f.Exec(tt.args, fctx)
+		if !reflect.DeepEqual(result, tt.result) {
+			t.Errorf("%d result mismatch,\ngot:\t%v \nwant:\t%v", i, result, tt.result)
+		}
+	}
+}
+
+func TestJsTimeout(t *testing.T) {
+	old := jsTimeout
+	jsTimeout = 10 * time.Millisecond
+	defer func() {
+		jsTimeout = old
+	}()
+	f := builtinStatfulFuncs["js"]()
+	contextLogger := conf.Log.WithField("rule", "testJsTimeout")
+	ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
+	tempStore, _ := state.CreateStore("mockRule0", api.AtMostOnce)
+	fctx := kctx.NewDefaultFuncContext(ctx.WithMeta("mockRule0", "test", tempStore), 2)
+	result, ok := f.Exec([]interface{}{"(a) => { while (true) {} }", 1}, fctx)
+	if ok {
+		t.Fatalf("expect timeout error but got %v", result)
+	}
+	// The vm is still usable after the interruption
+	result, ok = f.Exec([]interface{}{"(a) => { while (true) {} }", 1}, fctx)
+	if ok {
+		t.Fatalf("expect timeout error but got %v", result)
+	}
+	result, ok = f.Exec([]interface{}{"(a) => a + 1", 1}, fctx)
+	if !ok || result != int64(2) {
+		t.Fatalf("expect 2 but got %v", result)
+	}
+	// The script which loops when evaluated is interrupted in validation and compilation
+	loop := "(function(){ while (true) {} })()"
+	if err := f.Validate([]interface{}{&ast.StringLiteral{Val: loop}}); err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Fatalf("expect timeout error but got %v", err)
+	}
+	result, ok = f.Exec([]interface{}{loop, 1}, fctx)
+	if ok {
+		t.Fatalf("expect timeout error but got %v", result)
+	}
+}
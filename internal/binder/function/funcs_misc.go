@@ -1,4 +1,4 @@
-// Copyright 2022-2023 EMQ Technologies Co., Ltd.
+// Copyright 2022-2024 EMQ Technologies Co., Ltd.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -31,6 +31,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jmespath/go-jmespath"
 
 	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/keyedstate"
@@ -367,6 +368,10 @@ func registerMiscFunc() {
 		conf.Log.Infof("initializing decompress function")
 		return &decompressFunc{}
 	}
+	builtinStatfulFuncs["js"] = func() api.Function {
+		conf.Log.Infof("initializing js function")
+		return &jsFunc{}
+	}
 	builtins["isnull"] = builtinFunc{
 		fType: ast.FuncTypeScalar,
 		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
@@ -543,6 +548,32 @@ func registerMiscFunc() {
 		},
 		val: ValidateJsonFunc,
 	}
+	builtins["jmespath_query"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			expr, ok := args[1].(string)
+			if !ok {
+				return fmt.Errorf("invalid jmespath expression, must be a string but got %v", args[1]), false
+			}
+			result, err := jmespath.Search(expr, toJmespathData(args[0]))
+			if err != nil {
+				return err, false
+			}
+			return result, true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if err := ValidateJsonFunc(nil, args); err != nil {
+				return err
+			}
+			if s, ok := args[1].(*ast.StringLiteral); ok {
+				if _, err := jmespath.Compile(s.Val); err != nil {
+					return fmt.Errorf("invalid jmespath expression %s: %v", s.Val, err)
+				}
+			}
+			return nil
+		},
+		check: returnNilIfHasAnyNil,
+	}
 	builtins["window_start"] = builtinFunc{
 		fType: ast.FuncTypeScalar,
 		exec:  nil, // directly return in the valuer
@@ -553,6 +584,11 @@ func registerMiscFunc() {
 		exec:  nil, // directly return in the valuer
 		val:   ValidateNoArg,
 	}
+	builtins["window_partial"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec:  nil, // directly return in the valuer
+		val:   ValidateNoArg,
+	}
 	builtins["event_time"] = builtinFunc{
 		fType: ast.FuncTypeScalar,
 		exec:  nil, // directly return in the valuer
@@ -664,6 +700,35 @@ func jsonCall(ctx api.StreamContext, args []interface{}) (interface{}, error) {
 	return ctx.ParseJsonPath(jp, args[0])
 }
 
+// toJmespathData converts the numbers to float64 recursively because jmespath only compares and calculates float64 numbers
+func toJmespathData(v interface{}) interface{} {
+	switch vt := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(vt))
+		for k, e := range vt {
+			m[k] = toJmespathData(e)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(vt))
+		for i, e := range vt {
+			a[i] = toJmespathData(e)
+		}
+		return a
+	case []map[string]interface{}:
+		a := make([]interface{}, len(vt))
+		for i, e := range vt {
+			a[i] = toJmespathData(e)
+		}
+		return a
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32:
+		f, _ := cast.ToFloat64(vt, cast.CONVERT_SAMEKIND)
+		return f
+	default:
+		return v
+	}
+}
+
 // page Rotate storage for in memory cache
 // Not thread safe!
 type ringqueue struct {
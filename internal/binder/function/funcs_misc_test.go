@@ -494,6 +494,72 @@ func TestHexIntFunctions(t *testing.T) {
 	}
 }
 
+func TestJmespathQuery(t *testing.T) {
+	f, ok := builtins["jmespath_query"]
+	if !ok {
+		t.Fatal("builtin not found")
+	}
+	contextLogger := conf.Log.WithField("rule", "testExec")
+	ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
+	tempStore, _ := state.CreateStore("mockRule0", api.AtMostOnce)
+	fctx := kctx.NewDefaultFuncContext(ctx.WithMeta("mockRule0", "test", tempStore), 2)
+	data := map[string]interface{}{
+		"device": "d1",
+		"sensors": []interface{}{
+			map[string]interface{}{"name": "temp", "value": 25, "unit": "C"},
+			map[string]interface{}{"name": "hum", "value": int64(60), "unit": "%"},
+			map[string]interface{}{"name": "pressure", "value": 1013.2},
+		},
+		"tags": []map[string]interface{}{
+			{"key": "area", "value": "a1"},
+		},
+	}
+	tests := []struct {
+		expr   string
+		result interface{}
+		err    bool
+	}{
+		{
+			expr:   "device",
+			result: "d1",
+		}, {
+			expr:   "sensors[?value > `50`].name",
+			result: []interface{}{"hum", "pressure"},
+		}, {
+			expr:   "sensors[?unit].{n: name, v: value}",
+			result: []interface{}{map[string]interface{}{"n": "temp", "v": 25.0}, map[string]interface{}{"n": "hum", "v": 60.0}},
+		}, {
+			expr:   "max(sensors[*].value)",
+			result: 1013.2,
+		}, {
+			expr:   "tags[0].value",
+			result: "a1",
+		}, {
+			expr:   "notExist",
+			result: nil,
+		}, {
+			expr: "abs(device)",
+			err:  true,
+		},
+	}
+	for i, tt := range tests {
+		result, ok := f.exec(fctx, []interface{}{data, tt.expr})
+		if tt.err {
+			require.False(t, ok, i)
+			require.Error(t, result.(error), i)
+		} else {
+			require.True(t, ok, i)
+			require.Equal(t, tt.result, result, i)
+		}
+	}
+	err := f.val(nil, []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.StringLiteral{Val: "a.["}})
+	require.Error(t, err)
+	err = f.val(nil, []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.IntegerLiteral{Val: 1}})
+	require.EqualError(t, err, "Expect string type for parameter 2")
+	err = f.val(nil, []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.StringLiteral{Val: "a.b"}})
+	require.NoError(t, err)
+}
+
 func TestMiscFuncNil(t *testing.T) {
 	contextLogger := conf.Log.WithField("rule", "testExec")
 	ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
@@ -507,7 +573,7 @@ func TestMiscFuncNil(t *testing.T) {
 	registerMiscFunc()
 	for name, function := range builtins {
 		switch name {
-		case "compress", "decompress", "newuuid", "tstamp", "rule_id", "rule_start", "window_start", "window_end", "event_time",
+		case "compress", "decompress", "newuuid", "tstamp", "rule_id", "rule_start", "window_start", "window_end", "window_partial", "event_time",
 			"json_path_query", "json_path_query_first", "coalesce", "meta", "json_path_exists":
 			continue
 		case "isnull":
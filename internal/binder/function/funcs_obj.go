@@ -313,6 +313,73 @@ func registerObjectFunc() {
 		val:   ValidateOneArg,
 		check: returnNilIfHasAnyNil,
 	}
+	builtins["object_map"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			f, ok := args[0].(Lambda)
+			if !ok {
+				return fmt.Errorf("the first argument should be lambda expression"), false
+			}
+			obj, ok := args[1].(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("the second argument should be map[string]interface{}, got %v", args[1]), false
+			}
+			res := make(map[string]interface{}, len(obj))
+			for k, v := range obj {
+				r, err := callLambda(f, k, v)
+				if err != nil {
+					return err, false
+				}
+				res[k] = r
+			}
+			return res, true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if err := ValidateLen(2, len(args)); err != nil {
+				return err
+			}
+			return validateLambda(args[0], 2)
+		},
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["object_filter"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			f, ok := args[0].(Lambda)
+			if !ok {
+				return fmt.Errorf("the first argument should be lambda expression"), false
+			}
+			obj, ok := args[1].(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("the second argument should be map[string]interface{}, got %v", args[1]), false
+			}
+			res := make(map[string]interface{}, len(obj))
+			for k, v := range obj {
+				r, err := callLambda(f, k, v)
+				if err != nil {
+					return err, false
+				}
+				if r == nil {
+					continue
+				}
+				b, ok := r.(bool)
+				if !ok {
+					return fmt.Errorf("the lambda expression should return a bool but got %v", r), false
+				}
+				if b {
+					res[k] = v
+				}
+			}
+			return res, true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if err := ValidateLen(2, len(args)); err != nil {
+				return err
+			}
+			return validateLambda(args[0], 2)
+		},
+		check: returnNilIfHasAnyNil,
+	}
 }
 
 func pick(ctx api.FunctionContext, res map[string]any, argMap map[string]any, k string) {
@@ -346,7 +413,6 @@ func pick(ctx api.FunctionContext, res map[string]any, argMap map[string]any, k
 	}
 }
 
-
 func sliceStringContains(s []string, target string) bool {
 	for _, v := range s {
 		if target == v {
@@ -28,4 +28,18 @@ func registerWindowFunc() {
 		},
 		val: ValidateNoArg,
 	}
+	builtins["rank"] = builtinFunc{
+		fType: ast.FuncTypeWindow,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			return nil, true
+		},
+		val: ValidateNoArg,
+	}
+	builtins["dense_rank"] = builtinFunc{
+		fType: ast.FuncTypeWindow,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			return nil, true
+		},
+		val: ValidateNoArg,
+	}
 }
@@ -31,6 +31,14 @@ func TestWindowFuncValidate(t *testing.T) {
 			name: "row_number",
 			args: nil,
 		},
+		{
+			name: "rank",
+			args: nil,
+		},
+		{
+			name: "dense_rank",
+			args: nil,
+		},
 	}
 
 	for _, tc := range testcases {
@@ -1,4 +1,4 @@
-// Copyright 2022-2023 EMQ Technologies Co., Ltd.
+// Copyright 2022-2024 EMQ Technologies Co., Ltd.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -59,6 +59,10 @@ func init() {
 	registerDateTimeFunc()
 	registerGlobalAggFunc()
 	registerWindowFunc()
+	registerForecastFunc()
+	registerImageFunc()
+	registerAnomalyFunc()
+	registerGeoFunc()
 }
 
 //var funcWithAsteriskSupportMap = map[string]string{
@@ -76,10 +80,38 @@ var analyticFuncs = map[string]struct{}{
 	"acc_max":     {},
 	"acc_avg":     {},
 	"acc_count":   {},
+
+	"anomaly_zscore":       {},
+	"anomaly_ewma":         {},
+	"anomaly_seasonal_esd": {},
+}
+
+// lambdaFuncs are the higher order functions whose first argument can be a lambda expression
+var lambdaFuncs = map[string]struct{}{
+	"array_map":     {},
+	"array_filter":  {},
+	"array_reduce":  {},
+	"object_map":    {},
+	"object_filter": {},
+}
+
+// Lambda is the evaluated lambda expression argument. It evaluates the lambda body with the arguments bound to the
+// lambda parameters in order.
+type Lambda func(args []interface{}) interface{}
+
+// callLambda calls the lambda and converts the error result to the returned error
+func callLambda(f Lambda, args ...interface{}) (interface{}, error) {
+	r := f(args)
+	if err, ok := r.(error); ok {
+		return nil, err
+	}
+	return r, nil
 }
 
 var windowFuncs = map[string]struct{}{
 	"row_number": {},
+	"rank":       {},
+	"dense_rank": {},
 }
 
 const AnalyticPrefix = "$$a"
@@ -94,6 +126,11 @@ func IsAnalyticFunc(name string) bool {
 	return ok
 }
 
+func IsLambdaFunc(name string) bool {
+	_, ok := lambdaFuncs[name]
+	return ok
+}
+
 type Manager struct{}
 
 // Function the name is converted to lowercase if needed during parsing
@@ -1,4 +1,4 @@
-// Copyright 2022 EMQ Technologies Co., Ltd.
+// Copyright 2022-2024 EMQ Technologies Co., Ltd.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -133,3 +133,15 @@ func ValidateJsonFunc(_ api.FunctionContext, args []ast.Expr) error {
 	}
 	return nil
 }
+
+// validateLambda validates the argument is a lambda expression with n parameters
+func validateLambda(arg ast.Expr, n int) error {
+	l, ok := arg.(*ast.LambdaExpr)
+	if !ok {
+		return ProduceErrInfo(0, "lambda expression")
+	}
+	if len(l.Params) != n {
+		return fmt.Errorf("Expect %d parameters for the lambda expression but found %d.", n, len(l.Params))
+	}
+	return nil
+}
@@ -15,13 +15,19 @@
 package io
 
 import (
+	"github.com/lf-edge/ekuiper/internal/io/cloudiot"
 	"github.com/lf-edge/ekuiper/internal/io/file"
+	"github.com/lf-edge/ekuiper/internal/io/grpc"
 	"github.com/lf-edge/ekuiper/internal/io/http"
 	"github.com/lf-edge/ekuiper/internal/io/memory"
 	"github.com/lf-edge/ekuiper/internal/io/mqtt"
 	"github.com/lf-edge/ekuiper/internal/io/neuron"
+	"github.com/lf-edge/ekuiper/internal/io/record"
 	"github.com/lf-edge/ekuiper/internal/io/simulator"
 	"github.com/lf-edge/ekuiper/internal/io/sink"
+	"github.com/lf-edge/ekuiper/internal/io/snapshot"
+	"github.com/lf-edge/ekuiper/internal/io/socket"
+	"github.com/lf-edge/ekuiper/internal/io/view"
 	"github.com/lf-edge/ekuiper/internal/io/websocket"
 	plugin2 "github.com/lf-edge/ekuiper/internal/plugin"
 	"github.com/lf-edge/ekuiper/pkg/api"
@@ -37,6 +43,10 @@ func init() {
 	modules.RegisterSource("neuron", func() api.Source { return neuron.GetSource() })
 	modules.RegisterSource("websocket", func() api.Source { return &websocket.WebsocketSource{} })
 	modules.RegisterSource("simulator", func() api.Source { return &simulator.Source{} })
+	modules.RegisterSource("socket", func() api.Source { return &socket.Source{} })
+	modules.RegisterSource("view", func() api.Source { return view.GetSource() })
+	modules.RegisterSource("replay", func() api.Source { return &record.Source{} })
+	modules.RegisterSource("grpc", func() api.Source { return &grpc.Source{} })
 
 	modules.RegisterSink("log", sink.NewLogSink)
 	modules.RegisterSink("logToMemory", sink.NewLogSinkToMemory)
@@ -47,9 +57,15 @@ func init() {
 	modules.RegisterSink("neuron", func() api.Sink { return neuron.GetSink() })
 	modules.RegisterSink("file", func() api.Sink { return file.File() })
 	modules.RegisterSink("websocket", func() api.Sink { return &websocket.WebSocketSink{} })
+	modules.RegisterSink("snapshot", func() api.Sink { return snapshot.GetSink() })
+	modules.RegisterSink("view", func() api.Sink { return view.GetSink() })
+	modules.RegisterSink("azureIoTHub", cloudiot.AzureIoTHub)
+	modules.RegisterSink("awsIoT", cloudiot.AWSIoT)
 
 	modules.RegisterLookupSource("memory", func() api.LookupSource { return memory.GetLookupSource() })
 	modules.RegisterLookupSource("httppull", func() api.LookupSource { return http.GetLookUpSource() })
+	modules.RegisterLookupSource("snapshot", func() api.LookupSource { return snapshot.GetLookupSource() })
+	modules.RegisterLookupSource("view", func() api.LookupSource { return view.GetLookupSource() })
 }
 
 type Manager struct{}
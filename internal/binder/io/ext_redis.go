@@ -19,6 +19,7 @@ package io
 import (
 	"github.com/lf-edge/ekuiper/internal/io/redis"
 	"github.com/lf-edge/ekuiper/internal/io/redis/pubsub"
+	"github.com/lf-edge/ekuiper/internal/io/redis/stream"
 	"github.com/lf-edge/ekuiper/pkg/modules"
 )
 
@@ -27,4 +28,5 @@ func init() {
 	modules.RegisterSink("redis", redis.GetSink)
 	modules.RegisterSink("redisPub", pubsub.RedisPub)
 	modules.RegisterSource("redisSub", pubsub.RedisSub)
+	modules.RegisterSource("redisStream", stream.RedisStream)
 }
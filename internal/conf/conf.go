@@ -15,6 +15,8 @@
 package conf
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
@@ -30,6 +32,7 @@ import (
 	"github.com/yisaer/file-rotatelogs"
 
 	"github.com/lf-edge/ekuiper/internal/conf/logger"
+	"github.com/lf-edge/ekuiper/internal/conf/secret"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/cast"
 	"github.com/lf-edge/ekuiper/pkg/schedule"
@@ -66,8 +69,16 @@ type SinkConf struct {
 	ResendAlterQueue     bool   `json:"resendAlterQueue" yaml:"resendAlterQueue"`
 	ResendPriority       int    `json:"resendPriority" yaml:"resendPriority"`
 	ResendIndicatorField string `json:"resendIndicatorField" yaml:"resendIndicatorField"`
+	// OverflowPolicy is the behavior when the cache is full, must be one of dropOldest(default), dropNewest and block
+	OverflowPolicy string `json:"overflowPolicy" yaml:"overflowPolicy"`
 }
 
+const (
+	OverflowDropOldest = "dropOldest"
+	OverflowDropNewest = "dropNewest"
+	OverflowBlock      = "block"
+)
+
 // Validate the configuration and reset to the default value for invalid values.
 func (sc *SinkConf) Validate() error {
 	var errs error
@@ -116,6 +127,13 @@ func (sc *SinkConf) Validate() error {
 		Log.Warnf("resendPriority is not in [-1, 1], set to 0")
 		errs = errors.Join(errs, errors.New("resendPriority:resendPriority must be -1, 0 or 1"))
 	}
+	switch sc.OverflowPolicy {
+	case "", OverflowDropOldest, OverflowDropNewest, OverflowBlock:
+	default:
+		Log.Warnf("overflowPolicy %s is invalid, set to %s", sc.OverflowPolicy, OverflowDropOldest)
+		sc.OverflowPolicy = OverflowDropOldest
+		errs = errors.Join(errs, errors.New("overflowPolicy:overflowPolicy must be dropOldest, dropNewest or block"))
+	}
 	return errs
 }
 
@@ -123,6 +141,11 @@ type SourceConf struct {
 	HttpServerIp   string   `json:"httpServerIp" yaml:"httpServerIp"`
 	HttpServerPort int      `json:"httpServerPort" yaml:"httpServerPort"`
 	HttpServerTls  *tlsConf `json:"httpServerTls" yaml:"httpServerTls"`
+	GrpcServerIp   string   `json:"grpcServerIp" yaml:"grpcServerIp"`
+	GrpcServerPort int      `json:"grpcServerPort" yaml:"grpcServerPort"`
+	GrpcServerTls  *tlsConf `json:"grpcServerTls" yaml:"grpcServerTls"`
+	// AutoShare shares the source and decoding of a stream among the rules with the same source settings
+	AutoShare bool `json:"autoShare" yaml:"autoShare"`
 }
 
 func (sc *SourceConf) Validate() error {
@@ -135,6 +158,16 @@ func (sc *SourceConf) Validate() error {
 		errs = errors.Join(errs, errors.New("invalidHttpServerPort:httpServerPort must between 0 and 65535"))
 		sc.HttpServerPort = 10081
 	}
+	if sc.GrpcServerIp == "" {
+		sc.GrpcServerIp = "0.0.0.0"
+	}
+	if sc.GrpcServerPort == 0 {
+		sc.GrpcServerPort = 10082
+	} else if sc.GrpcServerPort < 0 || sc.GrpcServerPort > 65535 {
+		Log.Warnf("invalid source.grpcServerPort configuration %d, set to 10082", sc.GrpcServerPort)
+		errs = errors.Join(errs, errors.New("invalidGrpcServerPort:grpcServerPort must between 0 and 65535"))
+		sc.GrpcServerPort = 10082
+	}
 	return errs
 }
 
@@ -193,6 +226,8 @@ type KuiperConf struct {
 		RulePatrolInterval  string      `yaml:"rulePatrolInterval"`
 		CfgStorageType      string      `yaml:"cfgStorageType"`
 		EnableOpenZiti      bool        `yaml:"enableOpenZiti"`
+		MinFreeDiskSpace    int64       `yaml:"minFreeDiskSpace"`
+		MaxRuleVersions     int         `yaml:"maxRuleVersions"`
 	}
 	Rule   api.RuleOption
 	Sink   *SinkConf
@@ -200,6 +235,7 @@ type KuiperConf struct {
 	Store  struct {
 		Type         string `yaml:"type"`
 		ExtStateType string `yaml:"extStateType"`
+		StateType    string `yaml:"stateType"`
 		Redis        struct {
 			Host               string `yaml:"host"`
 			Port               int    `yaml:"port"`
@@ -217,7 +253,420 @@ type KuiperConf struct {
 	Portable struct {
 		PythonBin   string `yaml:"pythonBin"`
 		InitTimeout int    `yaml:"initTimeout"`
+		// HeartbeatInterval is the interval in ms to check the liveness of the plugin process. Negative to disable.
+		HeartbeatInterval int `yaml:"heartbeatInterval"`
+		// MaxRestarts is the max consecutive crashes to restart the plugin. Negative for unlimited.
+		MaxRestarts int `yaml:"maxRestarts"`
+	}
+	Network       NetworkConf       `yaml:"network"`
+	Broker        BrokerConf        `yaml:"broker"`
+	OpenTelemetry OpenTelemetryConf `yaml:"openTelemetry"`
+	QueueAlert    QueueAlertConf    `yaml:"queueAlert"`
+	Rbac          RbacConf          `yaml:"rbac"`
+	Audit         AuditConf         `yaml:"audit"`
+	Throttle      ThrottleConf      `yaml:"throttle"`
+	Cluster       ClusterConf       `yaml:"cluster"`
+	Sync          SyncConf          `yaml:"sync"`
+	Archive       ArchiveConf       `yaml:"archive"`
+	Secret        secret.Conf       `yaml:"secret"`
+}
+
+// AuditConf records the management operations of the REST API
+type AuditConf struct {
+	Enable bool `yaml:"enable"`
+	// Retention is how long to keep the records such as 720h. Empty means forever
+	Retention string `yaml:"retention"`
+	// MaxRecords is the max count of the records to keep. 0 means unlimited
+	MaxRecords int `yaml:"maxRecords"`
+
+	retention time.Duration
+}
+
+func (c *AuditConf) Validate() error {
+	if c.Retention == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(c.Retention)
+	if err != nil || d < 0 {
+		Log.Warnf("invalid audit.retention configuration %s, keep the records forever", c.Retention)
+		c.Retention = ""
+		return errors.New("invalidRetention:retention must be a positive duration like 720h")
+	}
+	c.retention = d
+	return nil
+}
+
+func (c *AuditConf) GetRetention() time.Duration {
+	return c.retention
+}
+
+// RbacConf is the role based access control of the REST API. It takes effect when authentication is enabled
+type RbacConf struct {
+	// UsersFile is the yaml file of the users and their roles for the basic authentication
+	UsersFile string `yaml:"usersFile"`
+	// DefaultRole is the role of the jwt tokens in etc/mgmt without the role claim. Default to admin
+	DefaultRole string   `yaml:"defaultRole"`
+	Oidc        OidcConf `yaml:"oidc"`
+}
+
+// OidcConf is the external OIDC provider to issue the tokens
+type OidcConf struct {
+	Enable bool   `yaml:"enable"`
+	Issuer string `yaml:"issuer"`
+	// Audience is usually the client id of eKuiper in the provider
+	Audience string `yaml:"audience"`
+	// JwksUrl is discovered from the issuer if not set
+	JwksUrl string `yaml:"jwksUrl"`
+	// RoleClaim is the claim of the role names such as realm_access.roles
+	RoleClaim string `yaml:"roleClaim"`
+}
+
+// QueueAlertConf alerts when a queue between the nodes of a rule stays above the threshold, which usually means
+// the downstream node such as a sink is too slow
+type QueueAlertConf struct {
+	Enable bool `yaml:"enable"`
+	// Threshold is the ratio of the queue length to its capacity
+	Threshold float64 `yaml:"threshold"`
+	// Duration is how long the queue stays above the threshold before alerting
+	Duration string `yaml:"duration"`
+	// Interval is the interval to sample the queue length
+	Interval string `yaml:"interval"`
+	// Mqtt is the mqtt client props and the topic to publish the alerts. The alerts are only logged if it is not set
+	Mqtt map[string]any `yaml:"mqtt"`
+
+	duration time.Duration
+	interval time.Duration
+}
+
+func (c *QueueAlertConf) Validate() error {
+	var errs error
+	if c.Threshold <= 0 || c.Threshold > 1 {
+		if c.Threshold != 0 {
+			Log.Warnf("invalid queueAlert.threshold configuration %v, set to 0.8", c.Threshold)
+			errs = errors.Join(errs, errors.New("invalidThreshold:threshold must be in (0, 1]"))
+		}
+		c.Threshold = 0.8
+	}
+	if c.Duration == "" {
+		c.Duration = "30s"
+	}
+	d, err := time.ParseDuration(c.Duration)
+	if err != nil || d < 0 {
+		Log.Warnf("invalid queueAlert.duration configuration %s, set to 30s", c.Duration)
+		errs = errors.Join(errs, errors.New("invalidDuration:duration must be a positive duration like 30s"))
+		c.Duration = "30s"
+		d = 30 * time.Second
+	}
+	c.duration = d
+	if c.Interval == "" {
+		c.Interval = "5s"
+	}
+	d, err = time.ParseDuration(c.Interval)
+	if err != nil || d <= 0 {
+		Log.Warnf("invalid queueAlert.interval configuration %s, set to 5s", c.Interval)
+		errs = errors.Join(errs, errors.New("invalidInterval:interval must be a positive duration like 5s"))
+		c.Interval = "5s"
+		d = 5 * time.Second
+	}
+	c.interval = d
+	return errs
+}
+
+func (c *QueueAlertConf) GetDuration() time.Duration {
+	return c.duration
+}
+
+func (c *QueueAlertConf) GetInterval() time.Duration {
+	if c.interval <= 0 {
+		return 5 * time.Second
+	}
+	return c.interval
+}
+
+// ThrottleConf throttles the rules of lower priority when the host is cpu saturated
+type ThrottleConf struct {
+	Enable bool `yaml:"enable"`
+	// CpuThreshold is the ratio of the host cpu usage to regard the host as saturated
+	CpuThreshold float64 `yaml:"cpuThreshold"`
+	// Interval is the interval to sample the cpu usage and adjust the throttling
+	Interval string `yaml:"interval"`
+
+	interval time.Duration
+}
+
+func (c *ThrottleConf) Validate() error {
+	var errs error
+	if c.CpuThreshold <= 0 || c.CpuThreshold > 1 {
+		if c.CpuThreshold != 0 {
+			Log.Warnf("invalid throttle.cpuThreshold configuration %v, set to 0.9", c.CpuThreshold)
+			errs = errors.Join(errs, errors.New("invalidCpuThreshold:cpuThreshold must be in (0, 1]"))
+		}
+		c.CpuThreshold = 0.9
+	}
+	if c.Interval == "" {
+		c.Interval = "1s"
+	}
+	d, err := time.ParseDuration(c.Interval)
+	if err != nil || d <= 0 {
+		Log.Warnf("invalid throttle.interval configuration %s, set to 1s", c.Interval)
+		errs = errors.Join(errs, errors.New("invalidInterval:interval must be a positive duration like 1s"))
+		c.Interval = "1s"
+		d = time.Second
 	}
+	c.interval = d
+	return errs
+}
+
+func (c *ThrottleConf) GetInterval() time.Duration {
+	if c.interval <= 0 {
+		return time.Second
+	}
+	return c.interval
+}
+
+// ClusterConf runs the rules across the eKuiper nodes which share the store. Each rule is placed on one live node
+// and fails over to another node when its node dies.
+type ClusterConf struct {
+	Enable bool `yaml:"enable"`
+	// NodeId is the unique id of the node in the cluster. Use the hostname if empty
+	NodeId string `yaml:"nodeId"`
+	// Labels are matched by the nodeSelector of the rules to constrain the placement
+	Labels map[string]string `yaml:"labels"`
+	// HeartbeatInterval is the interval to report the liveness and reconcile the rules
+	HeartbeatInterval string `yaml:"heartbeatInterval"`
+	// NodeTimeout is how long a node is regarded as dead without heartbeat
+	NodeTimeout string `yaml:"nodeTimeout"`
+
+	heartbeatInterval time.Duration
+	nodeTimeout       time.Duration
+}
+
+// Validate checks the configuration with the type of the store. The nodes coordinate through the store, so it must be
+// shared by all nodes and expire the keys on the store side.
+func (c *ClusterConf) Validate(storeType string) error {
+	var errs error
+	if storeType != "redis" {
+		errs = errors.Join(errs, fmt.Errorf("invalidStore:cluster mode requires the redis store shared by all nodes, but the store type is %s", storeType))
+	}
+	if c.NodeId == "" {
+		h, err := os.Hostname()
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("invalidNodeId:fail to get the hostname as the node id: %v", err))
+		}
+		c.NodeId = h
+	}
+	if c.HeartbeatInterval == "" {
+		c.HeartbeatInterval = "2s"
+	}
+	d, err := time.ParseDuration(c.HeartbeatInterval)
+	if err != nil || d <= 0 {
+		Log.Warnf("invalid cluster.heartbeatInterval configuration %s, set to 2s", c.HeartbeatInterval)
+		errs = errors.Join(errs, errors.New("invalidHeartbeatInterval:heartbeatInterval must be a positive duration like 2s"))
+		c.HeartbeatInterval = "2s"
+		d = 2 * time.Second
+	}
+	c.heartbeatInterval = d
+	if c.NodeTimeout == "" {
+		c.NodeTimeout = "10s"
+	}
+	d, err = time.ParseDuration(c.NodeTimeout)
+	// The isolated node stops its rules after half of the timeout, so it must miss a heartbeat at least before that
+	if err != nil || d <= 2*c.heartbeatInterval {
+		Log.Warnf("invalid cluster.nodeTimeout configuration %s, set to 5 times of the heartbeat interval", c.NodeTimeout)
+		errs = errors.Join(errs, errors.New("invalidNodeTimeout:nodeTimeout must be a duration longer than twice of heartbeatInterval"))
+		d = 5 * c.heartbeatInterval
+		c.NodeTimeout = d.String()
+	}
+	c.nodeTimeout = d
+	return errs
+}
+
+func (c *ClusterConf) GetHeartbeatInterval() time.Duration {
+	if c.heartbeatInterval <= 0 {
+		return 2 * time.Second
+	}
+	return c.heartbeatInterval
+}
+
+func (c *ClusterConf) GetNodeTimeout() time.Duration {
+	if c.nodeTimeout <= 0 {
+		return 10 * time.Second
+	}
+	return c.nodeTimeout
+}
+
+// SyncConf pulls the configuration bundle from a remote endpoint periodically and reconciles the local rules, streams,
+// tables and configurations to match it, so that a fleet of nodes can be managed declaratively.
+type SyncConf struct {
+	Enable bool `yaml:"enable"`
+	// Url is the http(s) url or the file url of the bundle in the format of the data export. If the git repo is set,
+	// it is the path of the bundle in the repo.
+	Url string `yaml:"url"`
+	// Git is the repository to pull the bundle from. Empty means the bundle is read from the url directly
+	Git SyncGitConf `yaml:"git"`
+	// SignatureUrl is the url of the base64 encoded ed25519 signature of the bundle. Default to the url with .sig suffix
+	SignatureUrl string `yaml:"signatureUrl"`
+	// PublicKey is the base64 encoded ed25519 public key to verify the bundle. It is required because the bundle can
+	// install plugins and rules
+	PublicKey string `yaml:"publicKey"`
+	// Interval is the interval to pull the bundle
+	Interval string `yaml:"interval"`
+	// Prune deletes the local rules, streams and tables which are not in the bundle
+	Prune bool `yaml:"prune"`
+	// DryRun only reports the drift without applying the bundle
+	DryRun bool `yaml:"dryRun"`
+
+	interval  time.Duration
+	publicKey ed25519.PublicKey
+}
+
+// SyncGitConf is the git repository which keeps the bundle. It is cloned by the git command.
+type SyncGitConf struct {
+	// Repo is the url of the repository which can be cloned by git
+	Repo string `yaml:"repo"`
+	// Branch is the branch to follow. Default to the default branch of the repository
+	Branch string `yaml:"branch"`
+}
+
+func (c *SyncConf) Validate() error {
+	var errs error
+	if c.Url == "" {
+		errs = errors.Join(errs, errors.New("invalidSyncUrl:url must be set"))
+	}
+	if c.SignatureUrl == "" && c.Url != "" {
+		c.SignatureUrl = c.Url + ".sig"
+	}
+	if c.Git.Repo != "" {
+		if c.Url != "" && !filepath.IsLocal(c.Url) {
+			errs = errors.Join(errs, errors.New("invalidSyncUrl:url must be a relative path in the git repo"))
+		}
+		if c.SignatureUrl != "" && !filepath.IsLocal(c.SignatureUrl) {
+			errs = errors.Join(errs, errors.New("invalidSyncSignatureUrl:signatureUrl must be a relative path in the git repo"))
+		}
+	}
+	if c.Interval == "" {
+		c.Interval = "1m"
+	}
+	d, err := time.ParseDuration(c.Interval)
+	if err != nil || d <= 0 {
+		Log.Warnf("invalid sync.interval configuration %s, set to 1m", c.Interval)
+		errs = errors.Join(errs, errors.New("invalidSyncInterval:interval must be a positive duration like 1m"))
+		c.Interval = "1m"
+		d = time.Minute
+	}
+	c.interval = d
+	c.publicKey = nil
+	if c.PublicKey == "" {
+		errs = errors.Join(errs, errors.New("invalidSyncPublicKey:publicKey must be set to verify the bundle"))
+	} else {
+		k, err := base64.StdEncoding.DecodeString(c.PublicKey)
+		if err != nil || len(k) != ed25519.PublicKeySize {
+			errs = errors.Join(errs, errors.New("invalidSyncPublicKey:publicKey must be a base64 encoded ed25519 public key"))
+		} else {
+			c.publicKey = k
+		}
+	}
+	return errs
+}
+
+func (c *SyncConf) GetInterval() time.Duration {
+	if c.interval <= 0 {
+		return time.Minute
+	}
+	return c.interval
+}
+
+// GetPublicKey returns the key to verify the bundle, or nil if the key is invalid
+func (c *SyncConf) GetPublicKey() ed25519.PublicKey {
+	return c.publicKey
+}
+
+// ArchiveConf is the keys to sign the exported archive and verify the imported archive
+type ArchiveConf struct {
+	// PrivateKey is the base64 encoded ed25519 private key to sign the exported archive. Empty means not signed
+	PrivateKey string `yaml:"privateKey"`
+	// PublicKey is the base64 encoded ed25519 public key to verify the imported archive. Empty means only the
+	// checksums are verified
+	PublicKey string `yaml:"publicKey"`
+	// MaxSize is the max bytes of the imported archive and of the files extracted from it. Default 100MB
+	MaxSize int64 `yaml:"maxSize"`
+
+	privateKey ed25519.PrivateKey
+	publicKey  ed25519.PublicKey
+}
+
+const defaultArchiveMaxSize = 100 * 1024 * 1024
+
+func (c *ArchiveConf) Validate() error {
+	var errs error
+	c.privateKey, c.publicKey = nil, nil
+	if c.MaxSize < 0 {
+		Log.Warnf("invalid archive.maxSize configuration %d, set to %d", c.MaxSize, defaultArchiveMaxSize)
+		errs = errors.Join(errs, errors.New("invalidArchiveMaxSize:maxSize must be positive"))
+		c.MaxSize = 0
+	}
+	if c.PrivateKey != "" {
+		k, err := base64.StdEncoding.DecodeString(c.PrivateKey)
+		if err != nil || len(k) != ed25519.PrivateKeySize {
+			errs = errors.Join(errs, errors.New("invalidArchivePrivateKey:privateKey must be a base64 encoded ed25519 private key"))
+		} else {
+			c.privateKey = k
+		}
+	}
+	if c.PublicKey != "" {
+		k, err := base64.StdEncoding.DecodeString(c.PublicKey)
+		if err != nil || len(k) != ed25519.PublicKeySize {
+			errs = errors.Join(errs, errors.New("invalidArchivePublicKey:publicKey must be a base64 encoded ed25519 public key"))
+		} else {
+			c.publicKey = k
+		}
+	}
+	return errs
+}
+
+// GetMaxSize returns the max bytes of the imported archive and of the files extracted from it
+func (c *ArchiveConf) GetMaxSize() int64 {
+	if c.MaxSize <= 0 {
+		return defaultArchiveMaxSize
+	}
+	return c.MaxSize
+}
+
+// GetPrivateKey returns the key to sign the archive, or nil if not signed
+func (c *ArchiveConf) GetPrivateKey() ed25519.PrivateKey {
+	return c.privateKey
+}
+
+// GetPublicKey returns the key to verify the archive, or nil if not verified
+func (c *ArchiveConf) GetPublicKey() ed25519.PublicKey {
+	return c.publicKey
+}
+
+// OpenTelemetryConf is the exporter of the tracing spans of the rules
+type OpenTelemetryConf struct {
+	Enable      bool   `yaml:"enable"`
+	ServiceName string `yaml:"serviceName"`
+	// Endpoint is the host and port of the OTLP/HTTP collector
+	Endpoint string `yaml:"endpoint"`
+	Insecure bool   `yaml:"insecure"`
+}
+
+// BrokerConf is the embedded MQTT broker for the standalone deployment
+type BrokerConf struct {
+	Enable           bool              `yaml:"enable"`
+	Address          string            `yaml:"address"`
+	WebsocketAddress string            `yaml:"websocketAddress"`
+	CertFile         string            `yaml:"certFile"`
+	KeyFile          string            `yaml:"keyFile"`
+	Users            map[string]string `yaml:"users"`
+}
+
+// NetworkConf is the proxy and DNS resolution of the outbound connections.
+// It can be overridden by the properties of each connector.
+type NetworkConf struct {
+	Proxy      string            `json:"proxy" yaml:"proxy"`
+	DnsServers []string          `json:"dnsServers" yaml:"dnsServers"`
+	Hosts      map[string]string `json:"hosts" yaml:"hosts"`
 }
 
 func SetLogLevel(level string, debug bool) {
@@ -296,11 +745,12 @@ func InitConf() {
 	}
 	kc := KuiperConf{
 		Rule: api.RuleOption{
-			LateTol:            1000,
-			Concurrency:        1,
-			BufferLength:       1024,
-			CheckpointInterval: 300000, // 5 minutes
-			SendError:          true,
+			LateTol:                1000,
+			Concurrency:            1,
+			BufferLength:           1024,
+			CheckpointInterval:     300000, // 5 minutes
+			FullCheckpointInterval: 10,
+			SendError:              true,
 			RestartStrategy: &api.RestartStrategy{
 				Attempts:     0,
 				Delay:        1000,
@@ -324,6 +774,10 @@ func InitConf() {
 		Config.Basic.RestIp = "0.0.0.0"
 	}
 
+	if Config.Basic.MaxRuleVersions == 0 {
+		Config.Basic.MaxRuleVersions = 20
+	}
+
 	if len(Config.Basic.RulePatrolInterval) < 1 {
 		Config.Basic.RulePatrolInterval = "10s"
 	}
@@ -362,6 +816,10 @@ func InitConf() {
 		}
 	}
 
+	secret.Init(Config.Secret, cpath)
+	if Config.Store.Redis.Password, err = secret.Resolve(Config.Store.Redis.Password); err != nil {
+		Log.Fatal(err)
+	}
 	if Config.Store.Type == "redis" && Config.Store.Redis.ConnectionSelector != "" {
 		if err := RedisStorageConSelectorApply(Config.Store.Redis.ConnectionSelector, Config); err != nil {
 			Log.Fatal(err)
@@ -377,14 +835,45 @@ func InitConf() {
 	if Config.Portable.InitTimeout <= 0 {
 		Config.Portable.InitTimeout = 5000
 	}
+	if Config.Portable.HeartbeatInterval == 0 {
+		Config.Portable.HeartbeatInterval = 10000
+	}
+	if Config.Portable.MaxRestarts == 0 {
+		Config.Portable.MaxRestarts = 5
+	}
 	if Config.Source == nil {
 		Config.Source = &SourceConf{}
 	}
 	if Config.Basic.CfgStorageType == "" {
 		Config.Basic.CfgStorageType = "file"
 	}
+	if Config.Broker.Address == "" {
+		Config.Broker.Address = ":1883"
+	}
+	if Config.OpenTelemetry.ServiceName == "" {
+		Config.OpenTelemetry.ServiceName = "kuiperd"
+	}
+	if Config.OpenTelemetry.Endpoint == "" {
+		Config.OpenTelemetry.Endpoint = "localhost:4318"
+	}
 
 	_ = Config.Source.Validate()
+	_ = Config.QueueAlert.Validate()
+	_ = Config.Audit.Validate()
+	_ = Config.Throttle.Validate()
+	if Config.Cluster.Enable {
+		if err := Config.Cluster.Validate(Config.Store.Type); err != nil {
+			Log.Warnf("invalid cluster configuration: %v", err)
+		}
+	}
+	if Config.Sync.Enable {
+		if err := Config.Sync.Validate(); err != nil {
+			Log.Warnf("invalid sync configuration: %v", err)
+		}
+	}
+	if err := Config.Archive.Validate(); err != nil {
+		Log.Warnf("invalid archive configuration: %v", err)
+	}
 	if Config.Sink == nil {
 		Config.Sink = &SinkConf{}
 	}
@@ -408,6 +897,21 @@ func ValidateRuleOption(option *api.RuleOption) error {
 		Log.Warnf("checkpointInterval is negative, set to 0")
 		errs = errors.Join(errs, errors.New("invalidCheckpointInterval:checkpointInterval must be greater than 0"))
 	}
+	if option.WindowMemoryLimit < 0 {
+		option.WindowMemoryLimit = 0
+		Log.Warnf("windowMemoryLimit is negative, set to 0")
+		errs = errors.Join(errs, errors.New("invalidWindowMemoryLimit:windowMemoryLimit must be greater than 0"))
+	}
+	if option.TraceSampleRate < 0 || option.TraceSampleRate > 1 {
+		option.TraceSampleRate = 0
+		Log.Warnf("traceSampleRate is out of range, set to 0")
+		errs = errors.Join(errs, errors.New("invalidTraceSampleRate:traceSampleRate must be between 0 and 1"))
+	}
+	if option.FullCheckpointInterval < 0 {
+		option.FullCheckpointInterval = 0
+		Log.Warnf("fullCheckpointInterval is negative, set to 0")
+		errs = errors.Join(errs, errors.New("invalidFullCheckpointInterval:fullCheckpointInterval must be greater than 0"))
+	}
 	if option.Concurrency < 0 {
 		option.Concurrency = 1
 		Log.Warnf("concurrency is negative, set to 1")
@@ -418,6 +922,20 @@ func ValidateRuleOption(option *api.RuleOption) error {
 		Log.Warnf("bufferLength is negative, set to 1024")
 		errs = errors.Join(errs, errors.New("invalidBufferLength:bufferLength must be greater than 0"))
 	}
+	if option.EnableAck && option.Qos < api.AtLeastOnce {
+		Log.Warnf("enableAck requires qos to be at least once")
+		errs = errors.Join(errs, errors.New("invalidEnableAck:enableAck requires qos to be at least 1"))
+	}
+	if option.AllowedLateness < 0 {
+		option.AllowedLateness = 0
+		Log.Warnf("allowedLateness is negative, set to 0")
+		errs = errors.Join(errs, errors.New("invalidAllowedLateness:allowedLateness must be greater than 0"))
+	}
+	if option.EarlyFireInterval < 0 {
+		option.EarlyFireInterval = 0
+		Log.Warnf("earlyFireInterval is negative, set to 0")
+		errs = errors.Join(errs, errors.New("invalidEarlyFireInterval:earlyFireInterval must be greater than 0"))
+	}
 	if option.LateTol < 0 {
 		option.LateTol = 1000
 		Log.Warnf("lateTol is negative, set to 1000")
@@ -449,6 +967,94 @@ func ValidateRuleOption(option *api.RuleOption) error {
 			Log.Warnf("restart jitterFactor must between 0 and 1, set to 0.1")
 			errs = errors.Join(errs, errors.New("invalidRestartJitterFactor:restart jitterFactor must between [0, 1)"))
 		}
+		if option.RestartStrategy.ResetWindow < 0 {
+			option.RestartStrategy.ResetWindow = 0
+			Log.Warnf("restart resetWindow is negative, set to 0")
+			errs = errors.Join(errs, errors.New("invalidRestartResetWindow:restart resetWindow must not be negative"))
+		}
+	}
+	if option.Quota != nil {
+		q := option.Quota
+		if q.MaxBufferedTuples < 0 {
+			q.MaxBufferedTuples = 0
+			Log.Warnf("quota maxBufferedTuples is negative, set to 0")
+			errs = errors.Join(errs, errors.New("invalidQuotaMaxBufferedTuples:quota maxBufferedTuples must be greater than 0"))
+		}
+		if q.MaxStateSize < 0 {
+			q.MaxStateSize = 0
+			Log.Warnf("quota maxStateSize is negative, set to 0")
+			errs = errors.Join(errs, errors.New("invalidQuotaMaxStateSize:quota maxStateSize must be greater than 0"))
+		}
+		if q.CpuShare < 0 {
+			q.CpuShare = 0
+			Log.Warnf("quota cpuShare is negative, set to 0")
+			errs = errors.Join(errs, errors.New("invalidQuotaCpuShare:quota cpuShare must be greater than 0"))
+		}
+		switch q.Policy {
+		case "":
+			q.Policy = api.QuotaPause
+		case api.QuotaPause, api.QuotaDrop, api.QuotaError:
+		default:
+			Log.Warnf("quota policy %s is invalid, set to pause", q.Policy)
+			errs = errors.Join(errs, fmt.Errorf("invalidQuotaPolicy:quota policy must be one of %s, %s and %s", api.QuotaPause, api.QuotaDrop, api.QuotaError))
+			q.Policy = api.QuotaPause
+		}
+	}
+	if option.Clock != nil {
+		c := option.Clock
+		switch c.Mode {
+		case "":
+			c.Mode = api.ClockWall
+		case api.ClockWall, api.ClockEvent:
+		case api.ClockAccelerated:
+			if c.Speed <= 0 {
+				c.Speed = 1
+				Log.Warnf("clock speed is not positive, set to 1")
+				errs = errors.Join(errs, errors.New("invalidClockSpeed:clock speed must be greater than 0"))
+			}
+		default:
+			Log.Warnf("clock mode %s is invalid, set to wall", c.Mode)
+			errs = errors.Join(errs, fmt.Errorf("invalidClockMode:clock mode must be one of %s, %s and %s", api.ClockWall, api.ClockAccelerated, api.ClockEvent))
+			c.Mode = api.ClockWall
+		}
+		if c.Start < 0 {
+			c.Start = 0
+			Log.Warnf("clock start is negative, set to 0")
+			errs = errors.Join(errs, errors.New("invalidClockStart:clock start must be greater than 0"))
+		}
+	}
+	if option.MicroBatchSize < 0 {
+		option.MicroBatchSize = 0
+		Log.Warnf("microBatchSize is negative, set to 0")
+		errs = errors.Join(errs, errors.New("invalidMicroBatchSize:microBatchSize must be greater than 0"))
+	}
+	for name, p := range option.Parallelism {
+		if p == nil {
+			continue
+		}
+		if p.Concurrency < 1 {
+			p.Concurrency = 1
+			Log.Warnf("parallelism concurrency of operator %s is not positive, set to 1", name)
+			errs = errors.Join(errs, fmt.Errorf("invalidParallelismConcurrency:parallelism concurrency of operator %s must be greater than 0", name))
+		}
+	}
+	if _, err := schedule.LoadTimezone(option.Timezone); err != nil {
+		Log.Warnf("timezone %s is invalid, set to default", option.Timezone)
+		errs = errors.Join(errs, fmt.Errorf("invalidTimezone:timezone %s is invalid: %v", option.Timezone, err))
+		option.Timezone = ""
+	}
+	if option.Priority < 0 || option.Priority > api.MaxRulePriority {
+		Log.Warnf("priority %d is out of range, set to 0", option.Priority)
+		errs = errors.Join(errs, fmt.Errorf("invalidPriority:priority must be in [0, %d]", api.MaxRulePriority))
+		option.Priority = 0
+	}
+	if option.Partitions < 0 {
+		Log.Warnf("partitions %d is negative, set to 0", option.Partitions)
+		errs = errors.Join(errs, errors.New("invalidPartitions:partitions must not be negative"))
+		option.Partitions = 0
+	}
+	if option.Partitions > 0 && (option.Cron != "" || len(option.CronDatetimeRange) > 0 || option.RunOnce) {
+		errs = errors.Join(errs, errors.New("invalidPartitions:partitioned rule cannot be scheduled or run once"))
 	}
 	if err := schedule.ValidateRanges(option.CronDatetimeRange); err != nil {
 		errs = errors.Join(errs, fmt.Errorf("validate cronDatetimeRange failed, err:%v", err))
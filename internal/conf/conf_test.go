@@ -21,6 +21,7 @@ import (
 	"path"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -39,6 +40,8 @@ func TestSourceConfValidate(t *testing.T) {
 			e: &SourceConf{
 				HttpServerIp:   "0.0.0.0",
 				HttpServerPort: 10081,
+				GrpcServerIp:   "0.0.0.0",
+				GrpcServerPort: 10082,
 			},
 			err: "invalidHttpServerPort:httpServerPort must between 0 and 65535",
 		}, {
@@ -48,6 +51,8 @@ func TestSourceConfValidate(t *testing.T) {
 			e: &SourceConf{
 				HttpServerIp:   "192.168.0.1",
 				HttpServerPort: 10081,
+				GrpcServerIp:   "0.0.0.0",
+				GrpcServerPort: 10082,
 			},
 			err: "invalidHttpServerPort:httpServerPort must between 0 and 65535",
 		}, {
@@ -57,6 +62,8 @@ func TestSourceConfValidate(t *testing.T) {
 			e: &SourceConf{
 				HttpServerIp:   "0.0.0.0",
 				HttpServerPort: 10081,
+				GrpcServerIp:   "0.0.0.0",
+				GrpcServerPort: 10082,
 			},
 			err: "invalidHttpServerPort:httpServerPort must between 0 and 65535",
 		}, {
@@ -70,11 +77,25 @@ func TestSourceConfValidate(t *testing.T) {
 			e: &SourceConf{
 				HttpServerIp:   "0.0.0.0",
 				HttpServerPort: 9090,
+				GrpcServerIp:   "0.0.0.0",
+				GrpcServerPort: 10082,
 				HttpServerTls: &tlsConf{
 					Certfile: "certfile",
 					Keyfile:  "keyfile",
 				},
 			},
+		}, {
+			s: &SourceConf{
+				HttpServerPort: 9090,
+				GrpcServerPort: 99999,
+			},
+			e: &SourceConf{
+				HttpServerIp:   "0.0.0.0",
+				HttpServerPort: 9090,
+				GrpcServerIp:   "0.0.0.0",
+				GrpcServerPort: 10082,
+			},
+			err: "invalidGrpcServerPort:grpcServerPort must between 0 and 65535",
 		},
 	}
 	fmt.Printf("The test bucket size is %d.\n\n", len(tests))
@@ -220,6 +241,183 @@ func TestRuleOptionValidate(t *testing.T) {
 			},
 			err: "multiple errors",
 		},
+		{
+			s: &api.RuleOption{
+				LateTol:            1000,
+				Concurrency:        1,
+				BufferLength:       1024,
+				CheckpointInterval: 300000, // 5 minutes
+				SendError:          true,
+				EnableAck:          true,
+			},
+			e: &api.RuleOption{
+				LateTol:            1000,
+				Concurrency:        1,
+				BufferLength:       1024,
+				CheckpointInterval: 300000, // 5 minutes
+				SendError:          true,
+				EnableAck:          true,
+			},
+			err: "invalidEnableAck:enableAck requires qos to be at least 1",
+		},
+		{
+			s: &api.RuleOption{
+				LateTol:            1000,
+				Concurrency:        1,
+				BufferLength:       1024,
+				CheckpointInterval: 300000, // 5 minutes
+				SendError:          true,
+				EarlyFireInterval:  -1,
+			},
+			e: &api.RuleOption{
+				LateTol:            1000,
+				Concurrency:        1,
+				BufferLength:       1024,
+				CheckpointInterval: 300000, // 5 minutes
+				SendError:          true,
+			},
+			err: "invalidEarlyFireInterval:earlyFireInterval must be greater than 0",
+		},
+		{
+			s: &api.RuleOption{
+				LateTol:            1000,
+				Concurrency:        1,
+				BufferLength:       1024,
+				CheckpointInterval: 300000, // 5 minutes
+				SendError:          true,
+				AllowedLateness:    -1,
+			},
+			e: &api.RuleOption{
+				LateTol:            1000,
+				Concurrency:        1,
+				BufferLength:       1024,
+				CheckpointInterval: 300000, // 5 minutes
+				SendError:          true,
+			},
+			err: "invalidAllowedLateness:allowedLateness must be greater than 0",
+		},
+		{
+			s: &api.RuleOption{
+				Quota: &api.RuleQuota{MaxBufferedTuples: 100},
+			},
+			e: &api.RuleOption{
+				Quota: &api.RuleQuota{MaxBufferedTuples: 100, Policy: api.QuotaPause},
+			},
+		},
+		{
+			s: &api.RuleOption{
+				Quota: &api.RuleQuota{MaxStateSize: -1, CpuShare: 0.5, Policy: "block"},
+			},
+			e: &api.RuleOption{
+				Quota: &api.RuleQuota{CpuShare: 0.5, Policy: api.QuotaPause},
+			},
+			err: "multiple errors",
+		},
+		{
+			s: &api.RuleOption{
+				Clock: &api.RuleClock{Speed: 60},
+			},
+			e: &api.RuleOption{
+				Clock: &api.RuleClock{Mode: api.ClockWall, Speed: 60},
+			},
+		},
+		{
+			s: &api.RuleOption{
+				Clock: &api.RuleClock{Mode: api.ClockAccelerated},
+			},
+			e: &api.RuleOption{
+				Clock: &api.RuleClock{Mode: api.ClockAccelerated, Speed: 1},
+			},
+			err: "invalidClockSpeed:clock speed must be greater than 0",
+		},
+		{
+			s: &api.RuleOption{
+				Clock: &api.RuleClock{Mode: "mock", Start: -1},
+			},
+			e: &api.RuleOption{
+				Clock: &api.RuleClock{Mode: api.ClockWall},
+			},
+			err: "multiple errors",
+		},
+		{
+			s: &api.RuleOption{
+				Parallelism: map[string]*api.OperatorParallelism{"project": {Concurrency: 0, PartitionBy: "a"}},
+			},
+			e: &api.RuleOption{
+				Parallelism: map[string]*api.OperatorParallelism{"project": {Concurrency: 1, PartitionBy: "a"}},
+			},
+			err: "invalidParallelismConcurrency:parallelism concurrency of operator project must be greater than 0",
+		},
+		{
+			s: &api.RuleOption{
+				MicroBatchSize: -1,
+			},
+			e: &api.RuleOption{
+				MicroBatchSize: 0,
+			},
+			err: "invalidMicroBatchSize:microBatchSize must be greater than 0",
+		},
+		{
+			s: &api.RuleOption{
+				Timezone: "Mars/Base",
+			},
+			e: &api.RuleOption{
+				Timezone: "",
+			},
+			err: "invalidTimezone:timezone Mars/Base is invalid: unknown time zone Mars/Base",
+		},
+		{
+			s: &api.RuleOption{
+				Priority: 10,
+			},
+			e: &api.RuleOption{
+				Priority: 0,
+			},
+			err: "invalidPriority:priority must be in [0, 9]",
+		},
+		{
+			s: &api.RuleOption{
+				RestartStrategy: &api.RestartStrategy{
+					Attempts:     3,
+					Delay:        1000,
+					Multiplier:   2,
+					MaxDelay:     30000,
+					JitterFactor: 0.1,
+					ResetWindow:  -1,
+				},
+			},
+			e: &api.RuleOption{
+				RestartStrategy: &api.RestartStrategy{
+					Attempts:     3,
+					Delay:        1000,
+					Multiplier:   2,
+					MaxDelay:     30000,
+					JitterFactor: 0.1,
+					ResetWindow:  0,
+				},
+			},
+			err: "invalidRestartResetWindow:restart resetWindow must not be negative",
+		},
+		{
+			s: &api.RuleOption{
+				Partitions: -1,
+			},
+			e: &api.RuleOption{
+				Partitions: 0,
+			},
+			err: "invalidPartitions:partitions must not be negative",
+		},
+		{
+			s: &api.RuleOption{
+				Partitions: 2,
+				RunOnce:    true,
+			},
+			e: &api.RuleOption{
+				Partitions: 2,
+				RunOnce:    true,
+			},
+			err: "invalidPartitions:partitioned rule cannot be scheduled or run once",
+		},
 	}
 	fmt.Printf("The test bucket size is %d.\n\n", len(tests))
 	for i, tt := range tests {
@@ -379,6 +577,17 @@ func TestSinkConf_Validate(t *testing.T) {
 			},
 			wantErr: errors.Join(errors.New("resendPriority:resendPriority must be -1, 0 or 1")),
 		},
+		{
+			name: "invalid overflowPolicy",
+			sc: SinkConf{
+				MemoryCacheThreshold: 1024,
+				MaxDiskCache:         1024000,
+				BufferPageSize:       256,
+				EnableCache:          true,
+				OverflowPolicy:       "dropAll",
+			},
+			wantErr: errors.Join(errors.New("overflowPolicy:overflowPolicy must be dropOldest, dropNewest or block")),
+		},
 	}
 
 	for _, tt := range tests {
@@ -446,3 +655,104 @@ func TestJitterFactor(t *testing.T) {
 	require.NoError(t, json.Unmarshal([]byte(b), r))
 	require.Equal(t, 0.3, r.JitterFactor)
 }
+
+func TestQueueAlertValidate(t *testing.T) {
+	c := &QueueAlertConf{}
+	require.NoError(t, c.Validate())
+	assert.Equal(t, 0.8, c.Threshold)
+	assert.Equal(t, 30*time.Second, c.GetDuration())
+	assert.Equal(t, 5*time.Second, c.GetInterval())
+
+	c = &QueueAlertConf{Threshold: 0.5, Duration: "0s", Interval: "100ms"}
+	require.NoError(t, c.Validate())
+	assert.Equal(t, 0.5, c.Threshold)
+	assert.Equal(t, time.Duration(0), c.GetDuration())
+	assert.Equal(t, 100*time.Millisecond, c.GetInterval())
+
+	c = &QueueAlertConf{Threshold: 2, Duration: "abc", Interval: "-1s"}
+	assert.Error(t, c.Validate())
+	assert.Equal(t, 0.8, c.Threshold)
+	assert.Equal(t, "30s", c.Duration)
+	assert.Equal(t, 5*time.Second, c.GetInterval())
+}
+
+func TestThrottleValidate(t *testing.T) {
+	c := &ThrottleConf{}
+	require.NoError(t, c.Validate())
+	assert.Equal(t, 0.9, c.CpuThreshold)
+	assert.Equal(t, time.Second, c.GetInterval())
+
+	c = &ThrottleConf{CpuThreshold: 0.7, Interval: "500ms"}
+	require.NoError(t, c.Validate())
+	assert.Equal(t, 0.7, c.CpuThreshold)
+	assert.Equal(t, 500*time.Millisecond, c.GetInterval())
+
+	c = &ThrottleConf{CpuThreshold: 1.5, Interval: "abc"}
+	assert.Error(t, c.Validate())
+	assert.Equal(t, 0.9, c.CpuThreshold)
+	assert.Equal(t, "1s", c.Interval)
+}
+
+func TestClusterValidate(t *testing.T) {
+	c := &ClusterConf{NodeId: "node1"}
+	require.NoError(t, c.Validate("redis"))
+	assert.Equal(t, 2*time.Second, c.GetHeartbeatInterval())
+	assert.Equal(t, 10*time.Second, c.GetNodeTimeout())
+
+	c = &ClusterConf{HeartbeatInterval: "5s", NodeTimeout: "8s"}
+	assert.EqualError(t, c.Validate("redis"), "invalidNodeTimeout:nodeTimeout must be a duration longer than twice of heartbeatInterval")
+	assert.NotEqual(t, "", c.NodeId)
+	assert.Equal(t, 25*time.Second, c.GetNodeTimeout())
+
+	c = &ClusterConf{NodeId: "node1", HeartbeatInterval: "abc"}
+	assert.EqualError(t, c.Validate("redis"), "invalidHeartbeatInterval:heartbeatInterval must be a positive duration like 2s")
+	assert.Equal(t, 2*time.Second, c.GetHeartbeatInterval())
+
+	c = &ClusterConf{NodeId: "node1"}
+	assert.EqualError(t, c.Validate("sqlite"), "invalidStore:cluster mode requires the redis store shared by all nodes, but the store type is sqlite")
+}
+
+func TestSyncValidate(t *testing.T) {
+	c := &SyncConf{Url: "https://example.com/bundle.json", PublicKey: "11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo="}
+	assert.NoError(t, c.Validate())
+	assert.Equal(t, "https://example.com/bundle.json.sig", c.SignatureUrl)
+	assert.Equal(t, time.Minute, c.GetInterval())
+	assert.Len(t, c.GetPublicKey(), 32)
+
+	c = &SyncConf{Url: "https://example.com/bundle.json"}
+	assert.EqualError(t, c.Validate(), "invalidSyncPublicKey:publicKey must be set to verify the bundle")
+	assert.Nil(t, c.GetPublicKey())
+
+	c = &SyncConf{Url: "https://example.com/bundle.json", Interval: "-1s", PublicKey: "AAAA"}
+	assert.EqualError(t, c.Validate(), "invalidSyncInterval:interval must be a positive duration like 1m\ninvalidSyncPublicKey:publicKey must be a base64 encoded ed25519 public key")
+	assert.Equal(t, time.Minute, c.GetInterval())
+	assert.Nil(t, c.GetPublicKey())
+
+	c = &SyncConf{PublicKey: "11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo="}
+	assert.EqualError(t, c.Validate(), "invalidSyncUrl:url must be set")
+	assert.Len(t, c.GetPublicKey(), 32)
+
+	c = &SyncConf{Url: "gateways/bundle.json", Git: SyncGitConf{Repo: "https://example.com/fleet.git"}, PublicKey: "11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo="}
+	assert.NoError(t, c.Validate())
+	assert.Equal(t, "gateways/bundle.json.sig", c.SignatureUrl)
+
+	c = &SyncConf{Url: "../bundle.json", SignatureUrl: "/etc/bundle.sig", Git: SyncGitConf{Repo: "https://example.com/fleet.git"}, PublicKey: "11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo="}
+	assert.EqualError(t, c.Validate(), "invalidSyncUrl:url must be a relative path in the git repo\ninvalidSyncSignatureUrl:signatureUrl must be a relative path in the git repo")
+}
+
+func TestArchiveValidate(t *testing.T) {
+	c := &ArchiveConf{}
+	assert.NoError(t, c.Validate())
+	assert.Nil(t, c.GetPrivateKey())
+	assert.Nil(t, c.GetPublicKey())
+	assert.Equal(t, int64(100*1024*1024), c.GetMaxSize())
+
+	c = &ArchiveConf{MaxSize: -1}
+	assert.EqualError(t, c.Validate(), "invalidArchiveMaxSize:maxSize must be positive")
+	assert.Equal(t, int64(100*1024*1024), c.GetMaxSize())
+
+	c = &ArchiveConf{PrivateKey: "AAAA", PublicKey: "11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo="}
+	assert.EqualError(t, c.Validate(), "invalidArchivePrivateKey:privateKey must be a base64 encoded ed25519 private key")
+	assert.Nil(t, c.GetPrivateKey())
+	assert.Len(t, c.GetPublicKey(), 32)
+}
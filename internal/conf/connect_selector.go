@@ -3,6 +3,8 @@ package conf
 import (
 	"fmt"
 	"strings"
+
+	"github.com/lf-edge/ekuiper/internal/conf/secret"
 )
 
 type ConSelector struct {
@@ -45,6 +47,11 @@ func (c *ConSelector) ReadCfgFromYaml() (props map[string]interface{}, err error
 		jsonPath = "mqtt_source.json"
 	}
 
+	props, err = secret.ResolveProps(props)
+	if err != nil {
+		return nil, fmt.Errorf("fail to resolve the secrets of connection %s.%s: %v", c.Type, c.CfgKey, err)
+	}
+
 	err = CorrectsConfigKeysByJson(props, jsonPath)
 	return props, err
 }
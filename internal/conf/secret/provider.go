@@ -0,0 +1,180 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// vaultEnvs are the credentials of the vault provider which are never exposed by the env provider
+var vaultEnvs = []string{"VAULT_ADDR", "VAULT_TOKEN", "VAULT_NAMESPACE"}
+
+// envProvider reads the environment variable named by the key. Only the variables with the allowed prefixes can be
+// read so that the rules cannot read the other variables of the server. It is disabled if no prefix is set
+type envProvider struct {
+	prefixes []string
+}
+
+func (p *envProvider) Get(key string) (string, error) {
+	if len(p.prefixes) == 0 {
+		return "", errors.New("env provider is disabled, set secret.envPrefixes to enable it")
+	}
+	if !p.allowed(key) {
+		return "", fmt.Errorf("environment variable %s is not allowed, it must start with one of %v", key, p.prefixes)
+	}
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", key)
+	}
+	return v, nil
+}
+
+func (p *envProvider) allowed(key string) bool {
+	for _, e := range vaultEnvs {
+		if key == e {
+			return false
+		}
+	}
+	for _, prefix := range p.prefixes {
+		if prefix != "" && strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// fileProvider reads the file of the key in the secret folder such as the docker or kubernetes secrets. The trailing
+// line break is trimmed. It is disabled if the folder is not set
+type fileProvider struct {
+	dir string
+}
+
+func newFileProvider(dir string, confDir string) *fileProvider {
+	if dir != "" && !filepath.IsAbs(dir) {
+		dir = filepath.Join(confDir, dir)
+	}
+	return &fileProvider{dir: dir}
+}
+
+func (p *fileProvider) Get(key string) (string, error) {
+	if p.dir == "" {
+		return "", errors.New("file provider is disabled, set secret.fileDir to enable it")
+	}
+	// Reject the absolute paths and the paths out of the secret folder like ../x
+	if !filepath.IsLocal(key) {
+		return "", fmt.Errorf("invalid key %s, it must be a relative path in the secret folder", key)
+	}
+	b, err := os.ReadFile(filepath.Join(p.dir, key))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// vaultProvider reads the HashiCorp Vault kv secrets engine by the key like secret/data/db#password. The field
+// defaults to value.
+type vaultProvider struct {
+	address   string
+	token     string
+	namespace string
+	client    *http.Client
+}
+
+func newVaultProvider(c VaultConf) *vaultProvider {
+	p := &vaultProvider{
+		address:   c.Address,
+		token:     c.Token,
+		namespace: c.Namespace,
+	}
+	if p.address == "" {
+		p.address = os.Getenv("VAULT_ADDR")
+	}
+	if p.token == "" {
+		p.token = os.Getenv("VAULT_TOKEN")
+	}
+	if p.namespace == "" {
+		p.namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+	timeout := 5 * time.Second
+	if c.Timeout != "" {
+		if d, err := time.ParseDuration(c.Timeout); err == nil && d > 0 {
+			timeout = d
+		}
+	}
+	p.client = &http.Client{Timeout: timeout}
+	return p
+}
+
+func (p *vaultProvider) Get(key string) (string, error) {
+	if p.address == "" {
+		return "", fmt.Errorf("vault address is not set")
+	}
+	sp, field, ok := strings.Cut(key, "#")
+	if !ok || field == "" {
+		field = "value"
+	}
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(p.address, "/")+"/v1/"+strings.TrimPrefix(sp, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+	if p.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.namespace)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returns status %d: %s", resp.StatusCode, string(body))
+	}
+	r := struct {
+		Data map[string]interface{} `json:"data"`
+	}{}
+	if err := json.Unmarshal(body, &r); err != nil {
+		return "", fmt.Errorf("invalid vault response: %v", err)
+	}
+	data := r.Data
+	// kv version 2 wraps the secret in data.data
+	if d, ok := data["data"].(map[string]interface{}); ok {
+		if _, isMeta := data["metadata"]; isMeta {
+			data = d
+		}
+	}
+	v, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("field %s is not found in vault secret %s", field, sp)
+	}
+	switch vt := v.(type) {
+	case string:
+		return vt, nil
+	default:
+		b, _ := json.Marshal(vt)
+		return string(b), nil
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package secret resolves the configuration values written as secret://<provider>/<key> at runtime, so that the
+// passwords, tokens and keys are not saved in plaintext in the yaml files and the kv store.
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const Prefix = "secret://"
+
+// Provider gets the secret value by the key
+type Provider interface {
+	Get(key string) (string, error)
+}
+
+// Conf is the secret section of kuiper.yaml
+type Conf struct {
+	// EnvPrefixes is the prefixes of the environment variables the env provider can read. Empty disables the env
+	// provider
+	EnvPrefixes []string `yaml:"envPrefixes"`
+	// FileDir is the folder of the keys of the file provider. Relative to the etc folder. Empty disables the file provider
+	FileDir string    `yaml:"fileDir"`
+	Vault   VaultConf `yaml:"vault"`
+}
+
+// VaultConf is the HashiCorp Vault server. The environment variables VAULT_ADDR, VAULT_TOKEN and VAULT_NAMESPACE are
+// used if not set
+type VaultConf struct {
+	Address   string `yaml:"address"`
+	Token     string `yaml:"token"`
+	Namespace string `yaml:"namespace"`
+	// Timeout is the request timeout such as 5s
+	Timeout string `yaml:"timeout"`
+}
+
+var (
+	lock      sync.RWMutex
+	providers = map[string]Provider{
+		"env":   &envProvider{},
+		"file":  newFileProvider("", ""),
+		"vault": newVaultProvider(VaultConf{}),
+	}
+)
+
+// Init sets the configuration of the built-in providers
+func Init(c Conf, confDir string) {
+	lock.Lock()
+	defer lock.Unlock()
+	providers["env"] = &envProvider{prefixes: c.EnvPrefixes}
+	providers["file"] = newFileProvider(c.FileDir, confDir)
+	providers["vault"] = newVaultProvider(c.Vault)
+}
+
+// Register adds or replaces a provider
+func Register(name string, p Provider) {
+	lock.Lock()
+	defer lock.Unlock()
+	providers[name] = p
+}
+
+// IsSecret checks if the value refers to a secret
+func IsSecret(v string) bool {
+	return strings.HasPrefix(v, Prefix)
+}
+
+// Resolve returns the secret value if v refers to a secret, otherwise v itself
+func Resolve(v string) (string, error) {
+	if !IsSecret(v) {
+		return v, nil
+	}
+	name, key, ok := strings.Cut(strings.TrimPrefix(v, Prefix), "/")
+	if !ok || key == "" {
+		return "", fmt.Errorf("invalid secret %s, it must be like secret://provider/key", v)
+	}
+	lock.RLock()
+	p, ok := providers[name]
+	lock.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("secret provider %s is not found", name)
+	}
+	r, err := p.Get(key)
+	if err != nil {
+		return "", fmt.Errorf("fail to get secret %s: %v", v, err)
+	}
+	return r, nil
+}
+
+// ResolveProps returns a copy of the props with all the secrets resolved including the nested maps and arrays. The
+// props are not modified so that the resolved values are not kept in the rule or the configuration
+func ResolveProps(props map[string]interface{}) (map[string]interface{}, error) {
+	var errs error
+	result := make(map[string]interface{}, len(props))
+	for k, v := range props {
+		r, err := resolveValue(v)
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%s: %v", k, err))
+		}
+		result[k] = r
+	}
+	return result, errs
+}
+
+func resolveValue(v interface{}) (interface{}, error) {
+	switch vt := v.(type) {
+	case string:
+		r, err := Resolve(vt)
+		if err != nil {
+			return vt, err
+		}
+		return r, nil
+	case map[string]interface{}:
+		return ResolveProps(vt)
+	case []interface{}:
+		var errs error
+		result := make([]interface{}, len(vt))
+		for i, e := range vt {
+			r, err := resolveValue(e)
+			if err != nil {
+				errs = errors.Join(errs, err)
+			}
+			result[i] = r
+		}
+		return result, errs
+	case []string:
+		var errs error
+		result := make([]string, len(vt))
+		for i, e := range vt {
+			r, err := Resolve(e)
+			if err != nil {
+				errs = errors.Join(errs, err)
+				r = e
+			}
+			result[i] = r
+		}
+		return result, errs
+	default:
+		return v, nil
+	}
+}
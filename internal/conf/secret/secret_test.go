@@ -0,0 +1,123 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	t.Setenv("KUIPER_TEST_SECRET", "envPass")
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "secrets"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "secrets", "db"), []byte("filePass\n"), 0o600))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/db":
+			_, _ = w.Write([]byte(`{"data":{"data":{"password":"vaultPass","port":3306},"metadata":{"version":1}}}`))
+		case "/v1/kv/db":
+			_, _ = w.Write([]byte(`{"data":{"value":"kv1Pass"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	t.Setenv("KUIPER_OTHER", "other")
+	t.Setenv("VAULT_TOKEN", "root")
+	Init(Conf{EnvPrefixes: []string{"KUIPER_TEST_", "VAULT_"}, FileDir: "secrets", Vault: VaultConf{Address: ts.URL, Token: "root"}}, dir)
+	defer Init(Conf{}, "")
+
+	tests := []struct {
+		v    string
+		r    string
+		err  string
+		name string
+	}{
+		{name: "plain", v: "password", r: "password"},
+		{name: "env", v: "secret://env/KUIPER_TEST_SECRET", r: "envPass"},
+		{name: "env not set", v: "secret://env/KUIPER_TEST_NONE", err: "fail to get secret secret://env/KUIPER_TEST_NONE: environment variable KUIPER_TEST_NONE is not set"},
+		{name: "env not allowed", v: "secret://env/KUIPER_OTHER", err: "fail to get secret secret://env/KUIPER_OTHER: environment variable KUIPER_OTHER is not allowed, it must start with one of [KUIPER_TEST_ VAULT_]"},
+		{name: "env vault token", v: "secret://env/VAULT_TOKEN", err: "fail to get secret secret://env/VAULT_TOKEN: environment variable VAULT_TOKEN is not allowed, it must start with one of [KUIPER_TEST_ VAULT_]"},
+		{name: "file", v: "secret://file/db", r: "filePass"},
+		{name: "absolute file", v: "secret://file//etc/passwd", err: "fail to get secret secret://file//etc/passwd: invalid key /etc/passwd, it must be a relative path in the secret folder"},
+		{name: "file out of folder", v: "secret://file/../secrets/db", err: "fail to get secret secret://file/../secrets/db: invalid key ../secrets/db, it must be a relative path in the secret folder"},
+		{name: "vault kv2", v: "secret://vault/secret/data/db#password", r: "vaultPass"},
+		{name: "vault number", v: "secret://vault/secret/data/db#port", r: "3306"},
+		{name: "vault kv1 default field", v: "secret://vault/kv/db", r: "kv1Pass"},
+		{name: "vault field not found", v: "secret://vault/secret/data/db#user", err: "fail to get secret secret://vault/secret/data/db#user: field user is not found in vault secret secret/data/db"},
+		{name: "unknown provider", v: "secret://aws/db", err: "secret provider aws is not found"},
+		{name: "invalid", v: "secret://env", err: "invalid secret secret://env, it must be like secret://provider/key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Resolve(tt.v)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.r, r)
+		})
+	}
+}
+
+func TestResolveProps(t *testing.T) {
+	t.Setenv("KUIPER_TEST_SECRET", "envPass")
+	Init(Conf{EnvPrefixes: []string{"KUIPER_TEST_"}}, "")
+	defer Init(Conf{}, "")
+	props := map[string]interface{}{
+		"password": "secret://env/KUIPER_TEST_SECRET",
+		"port":     3306,
+		"headers":  map[string]interface{}{"Authorization": "secret://env/KUIPER_TEST_SECRET"},
+		"tokens":   []interface{}{"a", "secret://env/KUIPER_TEST_SECRET"},
+	}
+	r, err := ResolveProps(props)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"password": "envPass",
+		"port":     3306,
+		"headers":  map[string]interface{}{"Authorization": "envPass"},
+		"tokens":   []interface{}{"a", "envPass"},
+	}, r)
+	// The original props keep the references
+	assert.Equal(t, "secret://env/KUIPER_TEST_SECRET", props["password"])
+	assert.Equal(t, "secret://env/KUIPER_TEST_SECRET", props["headers"].(map[string]interface{})["Authorization"])
+	assert.Equal(t, "secret://env/KUIPER_TEST_SECRET", props["tokens"].([]interface{})[1])
+
+	_, err = ResolveProps(map[string]interface{}{"password": "secret://env/KUIPER_TEST_NONE"})
+	assert.Error(t, err)
+}
+
+func TestFileProviderDisabled(t *testing.T) {
+	_, err := Resolve("secret://file/db")
+	assert.EqualError(t, err, "fail to get secret secret://file/db: file provider is disabled, set secret.fileDir to enable it")
+}
+
+func TestEnvProviderDisabled(t *testing.T) {
+	t.Setenv("KUIPER_TEST_SECRET", "envPass")
+	_, err := Resolve("secret://env/KUIPER_TEST_SECRET")
+	assert.EqualError(t, err, "fail to get secret secret://env/KUIPER_TEST_SECRET: env provider is disabled, set secret.envPrefixes to enable it")
+}
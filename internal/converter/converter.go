@@ -31,6 +31,9 @@ func init() {
 	modules.RegisterConverter(message.FormatJson, func(_ string, _ string, _ string) (message.Converter, error) {
 		return json.GetConverter()
 	})
+	modules.RegisterConverter(message.FormatFastJson, func(_ string, _ string, _ string) (message.Converter, error) {
+		return json.GetFastConverter()
+	})
 	modules.RegisterConverter(message.FormatBinary, func(_ string, _ string, _ string) (message.Converter, error) {
 		return binary.GetConverter()
 	})
@@ -50,12 +53,20 @@ func GetOrCreateConverter(options *ast.Options) (c message.Converter, err error)
 	if t == "" {
 		t = message.FormatJson
 	}
-	if t == message.FormatJson {
+	if t == message.FormatJson || t == message.FormatFastJson {
+		fast := t == message.FormatFastJson
 		// it's unit test
 		if options.RuleID == "" || options.StreamName == "" {
+			if fast {
+				return json.GetFastConverter()
+			}
 			return json.GetConverter()
 		}
-		return json.NewFastJsonConverter(options.RuleID, options.StreamName, options.Schema, options.IsWildCard, options.IsSchemaLess), nil
+		fc := json.NewFastJsonConverter(options.RuleID, options.StreamName, options.Schema, options.IsWildCard, options.IsSchemaLess)
+		if fast {
+			fc.UseFastDecoder()
+		}
+		return fc, nil
 	}
 
 	schemaFile := ""
@@ -68,7 +79,17 @@ func GetOrCreateConverter(options *ast.Options) (c message.Converter, err error)
 		}
 	}
 	if c, ok := modules.Converters[t]; ok {
-		return c(schemaFile, schemaName, options.DELIMITER)
+		conv, err := c(schemaFile, schemaName, options.DELIMITER)
+		if err != nil {
+			return nil, err
+		}
+		// project the fields used by the rule if the converter supports it
+		if mc, ok := conv.(message.SchemaMergeAbleConverter); ok && options.RuleID != "" && options.Schema != nil {
+			if err := mc.MergeSchema(options.RuleID, options.StreamName, options.Schema, options.IsWildCard); err != nil {
+				return nil, err
+			}
+		}
+		return conv, nil
 	}
 	return nil, fmt.Errorf("format type %s not supported", t)
 }
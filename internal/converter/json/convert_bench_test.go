@@ -99,6 +99,33 @@ func BenchmarkComplexTuplesWithSchema(b *testing.B) {
 	benchmarkByFiles("./testdata/MDFD.json", b, schema)
 }
 
+func BenchmarkFastSmallJSON(b *testing.B) {
+	benchmarkFastByFiles("./testdata/small.json", b)
+}
+
+func BenchmarkFastMediumJSON(b *testing.B) {
+	benchmarkFastByFiles("./testdata/medium.json", b)
+}
+
+func BenchmarkFastLargeJSON(b *testing.B) {
+	benchmarkFastByFiles("./testdata/large.json", b)
+}
+
+func BenchmarkFastComplexTuples(b *testing.B) {
+	benchmarkFastByFiles("./testdata/MDFD.json", b)
+}
+
+func benchmarkFastByFiles(filePath string, b *testing.B) {
+	payload, err := os.ReadFile(filePath)
+	if err != nil {
+		b.Fatalf(err.Error())
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fastConverter.Decode(payload)
+	}
+}
+
 func benchmarkByFiles(filePath string, b *testing.B, schema map[string]*ast.JsonStreamField) {
 	payload, err := os.ReadFile(filePath)
 	if err != nil {
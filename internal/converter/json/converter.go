@@ -28,14 +28,24 @@ import (
 	"github.com/lf-edge/ekuiper/pkg/message"
 )
 
-type Converter struct{}
+type Converter struct {
+	unmarshal func([]byte, interface{}) error
+}
 
-var converter = &Converter{}
+var (
+	converter     = &Converter{unmarshal: json.Unmarshal}
+	fastConverter = &Converter{unmarshal: fastUnmarshal}
+)
 
 func GetConverter() (message.Converter, error) {
 	return converter, nil
 }
 
+// GetFastConverter returns the converter of the fastjson format which decodes with the high performance decoder
+func GetFastConverter() (message.Converter, error) {
+	return fastConverter, nil
+}
+
 func (c *Converter) Encode(d interface{}) (b []byte, err error) {
 	return json.Marshal(d)
 }
@@ -47,7 +57,7 @@ func (c *Converter) Decode(b []byte) (m interface{}, err error) {
 		}
 	}()
 	var r0 interface{}
-	err = json.Unmarshal(b, &r0)
+	err = c.unmarshal(b, &r0)
 	if err != nil {
 		return nil, err
 	}
@@ -64,6 +74,10 @@ type FastJsonConverter struct {
 	schema    map[string]*ast.JsonStreamField
 	// ruleID -> wildcard
 	wildcardMap map[string]struct{}
+	// decoder for the wildcard rules
+	generic *Converter
+	// reuse the parsers in fast mode, nil to create a new parser for each decode
+	parsers *fastjson.ParserPool
 }
 
 func NewFastJsonConverter(ruleID, streamName string, schema map[string]*ast.JsonStreamField, isWildcard, isSchemaLess bool) *FastJsonConverter {
@@ -73,6 +87,7 @@ func NewFastJsonConverter(ruleID, streamName string, schema map[string]*ast.Json
 		wildcardMap:  make(map[string]struct{}),
 		isSchemaLess: isSchemaLess,
 		streamMap:    map[string]string{},
+		generic:      converter,
 	}
 	f.schemaMap[ruleID] = schema
 	f.streamMap[ruleID] = streamName
@@ -83,6 +98,13 @@ func NewFastJsonConverter(ruleID, streamName string, schema map[string]*ast.Json
 	return f
 }
 
+// UseFastDecoder switches to the fastjson format which decodes the wildcard rules with the high performance decoder
+// and reuses the parsers for the schema decoding. It must be called before decoding.
+func (c *FastJsonConverter) UseFastDecoder() {
+	c.generic = fastConverter
+	c.parsers = &fastjson.ParserPool{}
+}
+
 func (c *FastJsonConverter) MergeSchema(ruleID, dataSource string, newSchema map[string]*ast.JsonStreamField, isWildcard bool) error {
 	c.Lock()
 	defer c.Unlock()
@@ -157,13 +179,20 @@ func (c *FastJsonConverter) Decode(b []byte) (m interface{}, err error) {
 	c.RLock()
 	defer c.RUnlock()
 	if len(c.wildcardMap) > 0 {
-		return converter.Decode(b)
+		return c.generic.Decode(b)
 	}
 	return c.decodeWithSchema(b, c.schema)
 }
 
 func (f *FastJsonConverter) decodeWithSchema(b []byte, schema map[string]*ast.JsonStreamField) (interface{}, error) {
-	var p fastjson.Parser
+	var p *fastjson.Parser
+	if f.parsers != nil {
+		// the decoded values are copied out of the parser, so it can be reused once decoding is done
+		p = f.parsers.Get()
+		defer f.parsers.Put(p)
+	} else {
+		p = &fastjson.Parser{}
+	}
 	v, err := p.ParseBytes(b)
 	if err != nil {
 		return nil, err
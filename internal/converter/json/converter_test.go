@@ -957,3 +957,54 @@ func TestAttachDetachSchema(t *testing.T) {
 	}
 	require.Equal(t, r, er)
 }
+
+func TestFastDecode(t *testing.T) {
+	files := []string{"simple.json", "small.json", "medium.json", "large.json", "MDFD.json"}
+	for _, file := range files {
+		t.Run(file, func(t *testing.T) {
+			payload, err := os.ReadFile(path.Join("testdata", file))
+			require.NoError(t, err)
+			exp, err := converter.Decode(payload)
+			require.NoError(t, err)
+			got, err := fastConverter.Decode(payload)
+			require.NoError(t, err)
+			require.Equal(t, exp, got)
+		})
+	}
+	_, err := fastConverter.Decode([]byte(`{"a":`))
+	require.Error(t, err)
+	errWithCode, ok := err.(errorx.ErrorWithCode)
+	require.True(t, ok)
+	require.Equal(t, errorx.CovnerterErr, errWithCode.Code())
+}
+
+func TestFastDecodeWithSchema(t *testing.T) {
+	schema := map[string]*ast.JsonStreamField{
+		"a": {
+			Type: "bigint",
+		},
+		"b": {
+			Type: "string",
+		},
+	}
+	f := NewFastJsonConverter("1", "", schema, false, false)
+	f.UseFastDecoder()
+	bs := []byte(`{"a":1,"b":"hello","c":3}`)
+	// decode several times to reuse the pooled parser
+	for i := 0; i < 3; i++ {
+		d, err := f.Decode(bs)
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{
+			"a": int64(1),
+			"b": "hello",
+		}, d)
+	}
+	require.NoError(t, f.MergeSchema("2", "", nil, true))
+	d, err := f.Decode(bs)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"a": float64(1),
+		"b": "hello",
+		"c": float64(3),
+	}, d)
+}
@@ -0,0 +1,29 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build (amd64 || arm64) && !nofastjson
+
+package json
+
+import (
+	gojson "github.com/goccy/go-json"
+)
+
+// FastDecodeSupported reports whether the fastjson format uses the high performance decoder.
+// It is only built on the 64-bit platforms that the decoder is tuned and tested for.
+const FastDecodeSupported = true
+
+func fastUnmarshal(b []byte, v interface{}) error {
+	return gojson.Unmarshal(b, v)
+}
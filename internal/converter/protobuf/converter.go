@@ -16,12 +16,15 @@ package protobuf
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/builder"
 	"github.com/jhump/protoreflect/desc/protoparse"
 
 	kconf "github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/converter/static"
+	"github.com/lf-edge/ekuiper/pkg/ast"
 	"github.com/lf-edge/ekuiper/pkg/errorx"
 	"github.com/lf-edge/ekuiper/pkg/message"
 )
@@ -29,6 +32,11 @@ import (
 type Converter struct {
 	descriptor *desc.MessageDescriptor
 	fc         *FieldConverter
+	sync.RWMutex
+	// ruleID -> the top level fields used by the rule, nil for all fields
+	projections map[string]map[string]struct{}
+	// decodeDescriptor only has the fields used by any rule. The other fields are skipped when decoding.
+	decodeDescriptor *desc.MessageDescriptor
 }
 
 var protoParser *protoparse.Parser
@@ -51,8 +59,10 @@ func NewConverter(schemaFile string, soFile string, messageName string) (message
 				return nil, fmt.Errorf("message type %s not found in schema file %s", messageName, schemaFile)
 			}
 			return &Converter{
-				descriptor: messageDescriptor,
-				fc:         GetFieldConverter(),
+				descriptor:       messageDescriptor,
+				fc:               GetFieldConverter(),
+				projections:      make(map[string]map[string]struct{}),
+				decodeDescriptor: messageDescriptor,
 			}, nil
 		}
 	}
@@ -82,10 +92,79 @@ func (c *Converter) Decode(b []byte) (m interface{}, err error) {
 			err = errorx.NewWithCode(errorx.CovnerterErr, err.Error())
 		}
 	}()
-	result := mf.NewDynamicMessage(c.descriptor)
+	c.RLock()
+	md := c.decodeDescriptor
+	c.RUnlock()
+	result := mf.NewDynamicMessage(md)
 	err = result.Unmarshal(b)
 	if err != nil {
 		return nil, err
 	}
-	return c.fc.DecodeMessage(result, c.descriptor), nil
+	return c.fc.DecodeMessage(result, md), nil
+}
+
+// MergeSchema adds the fields used by a rule. Only the top level fields are projected.
+func (c *Converter) MergeSchema(ruleID, _ string, newSchema map[string]*ast.JsonStreamField, isWildcard bool) error {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.projections[ruleID]; ok {
+		return nil
+	}
+	var fields map[string]struct{}
+	if !isWildcard && newSchema != nil {
+		fields = make(map[string]struct{}, len(newSchema))
+		for k := range newSchema {
+			fields[k] = struct{}{}
+		}
+	}
+	c.projections[ruleID] = fields
+	return c.buildDecodeDescriptor()
+}
+
+func (c *Converter) DetachSchema(ruleID string) error {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.projections[ruleID]; !ok {
+		return nil
+	}
+	delete(c.projections, ruleID)
+	return c.buildDecodeDescriptor()
+}
+
+// buildDecodeDescriptor builds the descriptor with the union of the projected fields. The full descriptor is used if
+// any rule uses all fields.
+func (c *Converter) buildDecodeDescriptor() error {
+	c.decodeDescriptor = c.descriptor
+	if len(c.projections) == 0 {
+		return nil
+	}
+	used := make(map[string]struct{})
+	for _, fields := range c.projections {
+		if fields == nil {
+			return nil
+		}
+		for k := range fields {
+			used[k] = struct{}{}
+		}
+	}
+	mb, err := builder.FromMessage(c.descriptor)
+	if err != nil {
+		return err
+	}
+	for _, f := range c.descriptor.GetFields() {
+		if _, ok := used[f.GetName()]; !ok {
+			mb.RemoveField(f.GetName())
+		}
+	}
+	for _, oneOf := range c.descriptor.GetOneOfs() {
+		if oob := mb.GetOneOf(oneOf.GetName()); oob != nil && len(oob.GetChildren()) == 0 {
+			mb.RemoveOneOf(oneOf.GetName())
+		}
+	}
+	md, err := mb.Build()
+	if err != nil {
+		return fmt.Errorf("build the projected message type of %s failed: %v", c.descriptor.GetName(), err)
+	}
+	c.decodeDescriptor = md
+	return nil
 }
@@ -27,6 +27,7 @@ import (
 	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/schema"
 	"github.com/lf-edge/ekuiper/internal/testx"
+	"github.com/lf-edge/ekuiper/pkg/ast"
 	"github.com/lf-edge/ekuiper/pkg/errorx"
 )
 
@@ -46,6 +47,32 @@ func TestOneOfDecode(t *testing.T) {
 	}, v)
 }
 
+func TestProjectionDecode(t *testing.T) {
+	c, err := NewConverter("../../schema/test/test1.proto", "", "Person")
+	require.NoError(t, err)
+	pc := c.(*Converter)
+	data := []byte{0x0a, 0x04, 0x74, 0x65, 0x73, 0x74, 0x10, 0x01, 0x1a, 0x04, 0x44, 0x64, 0x64, 0x64}
+	require.NoError(t, pc.MergeSchema("r1", "demo", map[string]*ast.JsonStreamField{"id": nil}, false))
+	v, err := c.Decode(data)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"id": int64(1)}, v)
+	// merge the fields of another rule
+	require.NoError(t, pc.MergeSchema("r2", "demo", map[string]*ast.JsonStreamField{"email": nil}, false))
+	v, err = c.Decode(data)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"id": int64(1), "email": "Dddd"}, v)
+	// wildcard rule decodes all fields
+	require.NoError(t, pc.MergeSchema("r3", "demo", nil, true))
+	v, err = c.Decode(data)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"name": "test", "id": int64(1), "email": "Dddd", "code": []interface{}{}}, v)
+	require.NoError(t, pc.DetachSchema("r3"))
+	require.NoError(t, pc.DetachSchema("r1"))
+	v, err = c.Decode(data)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"email": "Dddd"}, v)
+}
+
 func TestEncode(t *testing.T) {
 	c, err := NewConverter("../../schema/test/test1.proto", "", "Person")
 	if err != nil {
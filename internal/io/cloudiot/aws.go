@@ -0,0 +1,233 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudiot
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	MQTT "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/lf-edge/ekuiper/internal/pkg/cert"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+const (
+	awsAuthCertificate = "certificate"
+	awsAuthSigV4       = "sigv4"
+	awsAuthCustom      = "custom"
+)
+
+type awsConf struct {
+	Endpoint  string `json:"endpoint"`
+	ClientId  string `json:"clientId"`
+	ThingName string `json:"thingName"`
+	// Auth is the authentication method: certificate, sigv4 or custom
+	Auth string `json:"auth"`
+	// For sigv4
+	Region          string `json:"region"`
+	AccessKeyId     string `json:"accessKeyId"`
+	SecretAccessKey string `json:"secretAccessKey"`
+	SessionToken    string `json:"sessionToken"`
+	// For custom authorizer
+	AuthorizerName string `json:"authorizerName"`
+	Username       string `json:"username"`
+	Password       string `json:"password"`
+
+	Topic string `json:"topic"`
+	Qos   byte   `json:"qos"`
+	// Shadow publishes the result as the reported state of the thing shadow
+	Shadow     bool   `json:"shadow"`
+	ShadowName string `json:"shadowName"`
+	// BasicIngestRule publishes to the rule directly by basic ingest to save the messaging cost
+	BasicIngestRule string `json:"basicIngestRule"`
+}
+
+type awsSink struct {
+	conf *awsConf
+	cli  *client
+}
+
+func AWSIoT() api.Sink {
+	return &awsSink{}
+}
+
+func (s *awsSink) Configure(props map[string]interface{}) error {
+	c := &awsConf{
+		Auth: awsAuthCertificate,
+	}
+	if err := cast.MapToStruct(props, c); err != nil {
+		return fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	if c.Endpoint == "" {
+		return fmt.Errorf("endpoint is required")
+	}
+	if c.ClientId == "" {
+		c.ClientId = c.ThingName
+	}
+	if c.ClientId == "" {
+		return fmt.Errorf("one of clientId or thingName is required")
+	}
+	if c.Qos > 1 {
+		return fmt.Errorf("invalid qos value %d, AWS IoT only supports qos 0 or 1", c.Qos)
+	}
+	if c.Shadow {
+		if c.ThingName == "" {
+			return fmt.Errorf("thingName is required to update the shadow")
+		}
+		if c.BasicIngestRule != "" {
+			return fmt.Errorf("shadow and basicIngestRule cannot be set together")
+		}
+	} else if c.Topic == "" {
+		return fmt.Errorf("topic is required")
+	}
+	tlsConf, err := cert.GenTLSConfig(props, "awsIoT")
+	if err != nil {
+		return err
+	}
+	if tlsConf == nil {
+		tlsConf = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	cli := &client{clientId: c.ClientId, tls: tlsConf}
+	switch c.Auth {
+	case awsAuthCertificate:
+		if len(tlsConf.Certificates) == 0 {
+			return fmt.Errorf("the client certificate and private key are required for certificate auth")
+		}
+		cli.server = fmt.Sprintf("ssl://%s:8883", c.Endpoint)
+	case awsAuthSigV4:
+		if c.Region == "" || c.AccessKeyId == "" || c.SecretAccessKey == "" {
+			return fmt.Errorf("region, accessKeyId and secretAccessKey are required for sigv4 auth")
+		}
+		cli.server = fmt.Sprintf("wss://%s:443/mqtt", c.Endpoint)
+		// The url is presigned for each connection attempt so that reconnecting always carries a valid signature
+		cli.openConn = func(_ *url.URL, options MQTT.ClientOptions) (net.Conn, error) {
+			u := presignURL(c.Endpoint, c.Region, c.AccessKeyId, c.SecretAccessKey, c.SessionToken, time.Now())
+			return MQTT.NewWebsocket(u, options.TLSConfig, options.ConnectTimeout, options.HTTPHeaders, options.WebsocketOptions)
+		}
+	case awsAuthCustom:
+		if c.AuthorizerName == "" {
+			return fmt.Errorf("authorizerName is required for custom auth")
+		}
+		// Custom authentication over mqtt requires port 443 with the ALPN extension
+		cli.server = fmt.Sprintf("ssl://%s:443", c.Endpoint)
+		cli.tls.NextProtos = []string{"mqtt"}
+		username := fmt.Sprintf("%s?x-amz-customauthorizer-name=%s", c.Username, url.QueryEscape(c.AuthorizerName))
+		cli.credentials = func() (string, string) {
+			return username, c.Password
+		}
+	default:
+		return fmt.Errorf("invalid auth %s, must be one of certificate, sigv4 or custom", c.Auth)
+	}
+	s.conf = c
+	s.cli = cli
+	return nil
+}
+
+// presignURL generates the websocket url signed by AWS Signature Version 4
+func presignURL(host, region, accessKey, secretKey, sessionToken string, t time.Time) string {
+	const (
+		service   = "iotdevicegateway"
+		algorithm = "AWS4-HMAC-SHA256"
+	)
+	t = t.UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", date, region, service)
+	query := fmt.Sprintf("X-Amz-Algorithm=%s&X-Amz-Credential=%s&X-Amz-Date=%s&X-Amz-Expires=86400&X-Amz-SignedHeaders=host",
+		algorithm, url.QueryEscape(accessKey+"/"+scope), amzDate)
+	payloadHash := sha256.Sum256(nil)
+	canonicalRequest := strings.Join([]string{
+		"GET", "/mqtt", query, "host:" + host + "\n", "host", hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{algorithm, amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+	signature := hex.EncodeToString(hmacSHA256(signingKey(secretKey, date, region, service), stringToSign))
+	u := fmt.Sprintf("wss://%s/mqtt?%s&X-Amz-Signature=%s", host, query, signature)
+	// The security token is appended after signing
+	if sessionToken != "" {
+		u += "&X-Amz-Security-Token=" + url.QueryEscape(sessionToken)
+	}
+	return u
+}
+
+func signingKey(secretKey, date, region, service string) []byte {
+	k := hmacSHA256([]byte("AWS4"+secretKey), date)
+	k = hmacSHA256(k, region)
+	k = hmacSHA256(k, service)
+	return hmacSHA256(k, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// publishTopic returns the topic to publish and the payload wrapped by the topic convention
+func (s *awsSink) publishTopic(ctx api.StreamContext, item interface{}, payload []byte) (string, []byte, error) {
+	if s.conf.Shadow {
+		trimmed := bytes.TrimSpace(payload)
+		if len(trimmed) == 0 || trimmed[0] != '{' {
+			return "", nil, fmt.Errorf("the shadow update requires a json object payload, set sendSingle to true")
+		}
+		topic := fmt.Sprintf("$aws/things/%s/shadow/update", s.conf.ThingName)
+		if s.conf.ShadowName != "" {
+			topic = fmt.Sprintf("$aws/things/%s/shadow/name/%s/update", s.conf.ThingName, s.conf.ShadowName)
+		}
+		return topic, append(append([]byte(`{"state":{"reported":`), trimmed...), "}}"...), nil
+	}
+	topic, err := ctx.ParseTemplate(s.conf.Topic, item)
+	if err != nil {
+		return "", nil, err
+	}
+	if s.conf.BasicIngestRule != "" {
+		topic = fmt.Sprintf("$aws/rules/%s/%s", s.conf.BasicIngestRule, topic)
+	}
+	return topic, payload, nil
+}
+
+func (s *awsSink) Open(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Connecting to AWS IoT %s as %s", s.conf.Endpoint, s.conf.ClientId)
+	return s.cli.connect()
+}
+
+func (s *awsSink) Collect(ctx api.StreamContext, item interface{}) error {
+	payload, _, err := ctx.TransformOutput(item)
+	if err != nil {
+		return err
+	}
+	topic, payload, err := s.publishTopic(ctx, item, payload)
+	if err != nil {
+		return err
+	}
+	ctx.GetLogger().Debugf("%s publish %s to %s", ctx.GetOpId(), payload, topic)
+	return s.cli.publish(topic, s.conf.Qos, payload)
+}
+
+func (s *awsSink) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Closing AWS IoT sink")
+	s.cli.close()
+	return nil
+}
@@ -0,0 +1,195 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudiot
+
+import (
+	"encoding/hex"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mockContext "github.com/lf-edge/ekuiper/pkg/mock/context"
+)
+
+func TestSigningKey(t *testing.T) {
+	// The example in the AWS signature version 4 document
+	k := signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	assert.Equal(t, "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d", hex.EncodeToString(k))
+}
+
+func TestPresignURL(t *testing.T) {
+	tm := time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)
+	u1 := presignURL("abc-ats.iot.us-east-1.amazonaws.com", "us-east-1", "AKIDEXAMPLE", "secret", "", tm)
+	pu, err := url.Parse(u1)
+	require.NoError(t, err)
+	assert.Equal(t, "wss", pu.Scheme)
+	assert.Equal(t, "/mqtt", pu.Path)
+	q := pu.Query()
+	assert.Equal(t, "AWS4-HMAC-SHA256", q.Get("X-Amz-Algorithm"))
+	assert.Equal(t, "AKIDEXAMPLE/20240301/us-east-1/iotdevicegateway/aws4_request", q.Get("X-Amz-Credential"))
+	assert.Equal(t, "20240301T083000Z", q.Get("X-Amz-Date"))
+	assert.Equal(t, "host", q.Get("X-Amz-SignedHeaders"))
+	assert.Len(t, q.Get("X-Amz-Signature"), 64)
+	assert.Empty(t, q.Get("X-Amz-Security-Token"))
+	// deterministic
+	assert.Equal(t, u1, presignURL("abc-ats.iot.us-east-1.amazonaws.com", "us-east-1", "AKIDEXAMPLE", "secret", "", tm))
+	// the token is appended without changing the signature
+	u2 := presignURL("abc-ats.iot.us-east-1.amazonaws.com", "us-east-1", "AKIDEXAMPLE", "secret", "to+ken/=", tm)
+	pu2, err := url.Parse(u2)
+	require.NoError(t, err)
+	assert.Equal(t, "to+ken/=", pu2.Query().Get("X-Amz-Security-Token"))
+	assert.Equal(t, q.Get("X-Amz-Signature"), pu2.Query().Get("X-Amz-Signature"))
+}
+
+func TestAWSConfigure(t *testing.T) {
+	tests := []struct {
+		name   string
+		props  map[string]interface{}
+		server string
+		err    string
+	}{
+		{
+			name: "sigv4",
+			props: map[string]interface{}{
+				"endpoint": "abc-ats.iot.us-east-1.amazonaws.com", "thingName": "thing1", "topic": "a/b",
+				"auth": "sigv4", "region": "us-east-1", "accessKeyId": "id", "secretAccessKey": "key",
+			},
+			server: "wss://abc-ats.iot.us-east-1.amazonaws.com:443/mqtt",
+		},
+		{
+			name: "custom",
+			props: map[string]interface{}{
+				"endpoint": "abc-ats.iot.us-east-1.amazonaws.com", "clientId": "c1", "topic": "a/b",
+				"auth": "custom", "authorizerName": "myAuth", "username": "u", "password": "p",
+			},
+			server: "ssl://abc-ats.iot.us-east-1.amazonaws.com:443",
+		},
+		{
+			name: "certificate without cert",
+			props: map[string]interface{}{
+				"endpoint": "abc-ats.iot.us-east-1.amazonaws.com", "thingName": "thing1", "topic": "a/b",
+			},
+			err: "the client certificate and private key are required for certificate auth",
+		},
+		{
+			name:  "missing client id",
+			props: map[string]interface{}{"endpoint": "e", "topic": "a/b"},
+			err:   "one of clientId or thingName is required",
+		},
+		{
+			name:  "missing topic",
+			props: map[string]interface{}{"endpoint": "e", "clientId": "c"},
+			err:   "topic is required",
+		},
+		{
+			name:  "shadow without thing",
+			props: map[string]interface{}{"endpoint": "e", "clientId": "c", "shadow": true},
+			err:   "thingName is required to update the shadow",
+		},
+		{
+			name:  "invalid auth",
+			props: map[string]interface{}{"endpoint": "e", "clientId": "c", "topic": "a", "auth": "none"},
+			err:   "invalid auth none, must be one of certificate, sigv4 or custom",
+		},
+		{
+			name:  "sigv4 missing key",
+			props: map[string]interface{}{"endpoint": "e", "clientId": "c", "topic": "a", "auth": "sigv4", "region": "r"},
+			err:   "region, accessKeyId and secretAccessKey are required for sigv4 auth",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &awsSink{}
+			err := s.Configure(tt.props)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.server, s.cli.server)
+		})
+	}
+	s := &awsSink{}
+	require.NoError(t, s.Configure(map[string]interface{}{
+		"endpoint": "e", "clientId": "c", "topic": "a", "auth": "custom", "authorizerName": "my auth", "username": "u", "password": "p",
+	}))
+	u, p := s.cli.credentials()
+	assert.Equal(t, "u?x-amz-customauthorizer-name=my+auth", u)
+	assert.Equal(t, "p", p)
+	assert.Equal(t, []string{"mqtt"}, s.cli.tls.NextProtos)
+}
+
+func TestAWSPublishTopic(t *testing.T) {
+	ctx := mockContext.NewMockContext("rule1", "op1")
+	tests := []struct {
+		name    string
+		conf    *awsConf
+		payload string
+		topic   string
+		result  string
+		err     string
+	}{
+		{
+			name:    "template",
+			conf:    &awsConf{Topic: "devices/{{.id}}/data"},
+			payload: `{"id":"d1"}`,
+			topic:   "devices/d1/data",
+			result:  `{"id":"d1"}`,
+		},
+		{
+			name:    "basic ingest",
+			conf:    &awsConf{Topic: "data", BasicIngestRule: "rule1"},
+			payload: `{"id":"d1"}`,
+			topic:   "$aws/rules/rule1/data",
+			result:  `{"id":"d1"}`,
+		},
+		{
+			name:    "shadow",
+			conf:    &awsConf{ThingName: "thing1", Shadow: true},
+			payload: `{"temperature":20}`,
+			topic:   "$aws/things/thing1/shadow/update",
+			result:  `{"state":{"reported":{"temperature":20}}}`,
+		},
+		{
+			name:    "named shadow",
+			conf:    &awsConf{ThingName: "thing1", Shadow: true, ShadowName: "s1"},
+			payload: ` {"temperature":20}`,
+			topic:   "$aws/things/thing1/shadow/name/s1/update",
+			result:  `{"state":{"reported":{"temperature":20}}}`,
+		},
+		{
+			name:    "shadow with list",
+			conf:    &awsConf{ThingName: "thing1", Shadow: true},
+			payload: `[{"temperature":20}]`,
+			err:     "the shadow update requires a json object payload, set sendSingle to true",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &awsSink{conf: tt.conf}
+			topic, payload, err := s.publishTopic(ctx, map[string]interface{}{"id": "d1"}, []byte(tt.payload))
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.topic, topic)
+			assert.Equal(t, tt.result, string(payload))
+		})
+	}
+}
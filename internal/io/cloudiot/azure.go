@@ -0,0 +1,199 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudiot
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/ekuiper/internal/pkg/cert"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+const azureApiVersion = "2021-04-12"
+
+type azureConf struct {
+	ConnectionString string `json:"connectionString"`
+	HostName         string `json:"hostName"`
+	DeviceId         string `json:"deviceId"`
+	SharedAccessKey  string `json:"sharedAccessKey"`
+	// SasToken is a pre-generated token which cannot be renewed
+	SasToken string `json:"sasToken"`
+	// TokenTTL is the valid duration in seconds of the generated sas tokens
+	TokenTTL        int               `json:"tokenTTL"`
+	Qos             byte              `json:"qos"`
+	ContentType     string            `json:"contentType"`
+	ContentEncoding string            `json:"contentEncoding"`
+	Properties      map[string]string `json:"properties"`
+}
+
+// parseConnectionString parses the device connection string like
+// HostName=xxx.azure-devices.net;DeviceId=xxx;SharedAccessKey=xxx
+func (c *azureConf) parseConnectionString() error {
+	for _, part := range strings.Split(c.ConnectionString, ";") {
+		if part == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(part, "=")
+		if !ok {
+			return fmt.Errorf("invalid connectionString part %s", part)
+		}
+		switch k {
+		case "HostName":
+			c.HostName = v
+		case "DeviceId":
+			c.DeviceId = v
+		case "SharedAccessKey":
+			c.SharedAccessKey = v
+		}
+	}
+	return nil
+}
+
+type azureSink struct {
+	conf  *azureConf
+	topic string
+	cli   *client
+}
+
+func AzureIoTHub() api.Sink {
+	return &azureSink{}
+}
+
+func (s *azureSink) Configure(props map[string]interface{}) error {
+	c := &azureConf{
+		TokenTTL:        3600,
+		Qos:             1,
+		ContentType:     "application/json",
+		ContentEncoding: "utf-8",
+	}
+	if err := cast.MapToStruct(props, c); err != nil {
+		return fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	if c.ConnectionString != "" {
+		if err := c.parseConnectionString(); err != nil {
+			return err
+		}
+	}
+	if c.HostName == "" {
+		return fmt.Errorf("hostName is required")
+	}
+	if c.DeviceId == "" {
+		return fmt.Errorf("deviceId is required")
+	}
+	if c.Qos > 1 {
+		return fmt.Errorf("invalid qos value %d, IoT Hub only supports qos 0 or 1", c.Qos)
+	}
+	if c.TokenTTL <= 0 {
+		return fmt.Errorf("tokenTTL must be positive")
+	}
+	tlsConf, err := cert.GenTLSConfig(props, "azureIoTHub")
+	if err != nil {
+		return err
+	}
+	cli := &client{
+		server:   fmt.Sprintf("ssl://%s:8883", c.HostName),
+		clientId: c.DeviceId,
+		tls:      tlsConf,
+	}
+	username := fmt.Sprintf("%s/%s/?api-version=%s", c.HostName, c.DeviceId, azureApiVersion)
+	switch {
+	case c.SharedAccessKey != "":
+		key, err := base64.StdEncoding.DecodeString(c.SharedAccessKey)
+		if err != nil {
+			return fmt.Errorf("invalid sharedAccessKey: %v", err)
+		}
+		resource := c.HostName + "/devices/" + c.DeviceId
+		ttl := time.Duration(c.TokenTTL) * time.Second
+		cli.credentials = func() (string, string) {
+			return username, sasToken(resource, key, time.Now().Add(ttl))
+		}
+		// renew when 90% of the token lifetime passed
+		cli.renewAfter = ttl * 9 / 10
+	case c.SasToken != "":
+		cli.credentials = func() (string, string) {
+			return username, c.SasToken
+		}
+	case tlsConf != nil && len(tlsConf.Certificates) > 0:
+		// X.509 authentication, the device is identified by the client certificate
+		cli.credentials = func() (string, string) {
+			return username, ""
+		}
+	default:
+		return fmt.Errorf("one of sharedAccessKey, sasToken or the client certificate is required")
+	}
+	s.conf = c
+	s.cli = cli
+	s.topic = azureEventTopic(c)
+	return nil
+}
+
+// sasToken generates the shared access signature to access the resource until the expiry time
+func sasToken(resource string, key []byte, expiry time.Time) string {
+	sr := url.QueryEscape(resource)
+	se := strconv.FormatInt(expiry.Unix(), 10)
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(sr + "\n" + se))
+	sig := base64.StdEncoding.EncodeToString(h.Sum(nil))
+	return fmt.Sprintf("SharedAccessSignature sr=%s&sig=%s&se=%s", sr, url.QueryEscape(sig), se)
+}
+
+// azureEventTopic builds the device-to-cloud topic with the message properties appended as the property bag
+func azureEventTopic(c *azureConf) string {
+	var props []string
+	if c.ContentType != "" {
+		props = append(props, "$.ct="+url.QueryEscape(c.ContentType))
+	}
+	if c.ContentEncoding != "" {
+		props = append(props, "$.ce="+url.QueryEscape(c.ContentEncoding))
+	}
+	keys := make([]string, 0, len(c.Properties))
+	for k := range c.Properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		props = append(props, url.QueryEscape(k)+"="+url.QueryEscape(c.Properties[k]))
+	}
+	return fmt.Sprintf("devices/%s/messages/events/%s", c.DeviceId, strings.Join(props, "&"))
+}
+
+func (s *azureSink) Open(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Connecting to IoT Hub %s as device %s", s.conf.HostName, s.conf.DeviceId)
+	return s.cli.connect()
+}
+
+func (s *azureSink) Collect(ctx api.StreamContext, item interface{}) error {
+	payload, _, err := ctx.TransformOutput(item)
+	if err != nil {
+		return err
+	}
+	ctx.GetLogger().Debugf("%s publish %s", ctx.GetOpId(), payload)
+	return s.cli.publish(s.topic, s.conf.Qos, payload)
+}
+
+func (s *azureSink) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Closing IoT Hub sink")
+	s.cli.close()
+	return nil
+}
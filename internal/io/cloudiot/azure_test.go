@@ -0,0 +1,107 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudiot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSasToken(t *testing.T) {
+	tk := sasToken("myhub.azure-devices.net/devices/dev1", []byte("testkeytestkeytestkey"), time.Unix(1700000000, 0))
+	assert.Equal(t, "SharedAccessSignature sr=myhub.azure-devices.net%2Fdevices%2Fdev1&sig=7Af%2BZqjn1ML3gy0xAzJEYhNJ%2B9x8Qhh%2FNnYi9Bi7%2FOI%3D&se=1700000000", tk)
+}
+
+func TestAzureConfigure(t *testing.T) {
+	tests := []struct {
+		name  string
+		props map[string]interface{}
+		topic string
+		user  string
+		err   string
+	}{
+		{
+			name: "connection string",
+			props: map[string]interface{}{
+				"connectionString": "HostName=myhub.azure-devices.net;DeviceId=dev1;SharedAccessKey=dGVzdGtleXRlc3RrZXl0ZXN0a2V5",
+				"properties":       map[string]interface{}{"level": "high", "a b": "c&d"},
+			},
+			topic: "devices/dev1/messages/events/$.ct=application%2Fjson&$.ce=utf-8&a+b=c%26d&level=high",
+			user:  "myhub.azure-devices.net/dev1/?api-version=2021-04-12",
+		},
+		{
+			name: "sas token",
+			props: map[string]interface{}{
+				"hostName":    "myhub.azure-devices.net",
+				"deviceId":    "dev2",
+				"sasToken":    "SharedAccessSignature sr=xx",
+				"contentType": "",
+			},
+			topic: "devices/dev2/messages/events/$.ce=utf-8",
+			user:  "myhub.azure-devices.net/dev2/?api-version=2021-04-12",
+		},
+		{
+			name:  "missing host",
+			props: map[string]interface{}{"deviceId": "dev1", "sasToken": "a"},
+			err:   "hostName is required",
+		},
+		{
+			name:  "missing key",
+			props: map[string]interface{}{"hostName": "h", "deviceId": "dev1"},
+			err:   "one of sharedAccessKey, sasToken or the client certificate is required",
+		},
+		{
+			name:  "invalid qos",
+			props: map[string]interface{}{"hostName": "h", "deviceId": "dev1", "sasToken": "a", "qos": 2},
+			err:   "invalid qos value 2, IoT Hub only supports qos 0 or 1",
+		},
+		{
+			name:  "invalid key",
+			props: map[string]interface{}{"hostName": "h", "deviceId": "dev1", "sharedAccessKey": "!!"},
+			err:   "invalid sharedAccessKey: illegal base64 data at input byte 0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &azureSink{}
+			err := s.Configure(tt.props)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.topic, s.topic)
+			assert.Equal(t, "ssl://"+s.conf.HostName+":8883", s.cli.server)
+			assert.Equal(t, s.conf.DeviceId, s.cli.clientId)
+			u, p := s.cli.credentials()
+			assert.Equal(t, tt.user, u)
+			assert.NotEmpty(t, p)
+		})
+	}
+}
+
+func TestAzureRenew(t *testing.T) {
+	s := &azureSink{}
+	require.NoError(t, s.Configure(map[string]interface{}{
+		"connectionString": "HostName=myhub.azure-devices.net;DeviceId=dev1;SharedAccessKey=dGVzdGtleXRlc3RrZXl0ZXN0a2V5",
+		"tokenTTL":         100,
+	}))
+	assert.Equal(t, 90*time.Second, s.cli.renewAfter)
+	_, p := s.cli.credentials()
+	assert.Contains(t, p, "SharedAccessSignature sr=myhub.azure-devices.net%2Fdevices%2Fdev1&sig=")
+}
@@ -0,0 +1,114 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cloudiot implements the sinks to publish to the cloud IoT platforms. They are built on top of MQTT but handle
+// the provider specific authentication and topic conventions.
+package cloudiot
+
+import (
+	"crypto/tls"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	MQTT "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/pkg/errorx"
+)
+
+// client is the mqtt connection to the cloud. The credentials and the connection function are evaluated for each
+// connection attempt so that short-lived credentials are renewed when reconnecting.
+type client struct {
+	server      string
+	clientId    string
+	tls         *tls.Config
+	credentials MQTT.CredentialsProvider
+	openConn    MQTT.OpenConnectionFunc
+	// renewAfter is the duration after which the connection is re-established to renew the credentials, 0 for never
+	renewAfter time.Duration
+
+	conn        MQTT.Client
+	connectedAt atomic.Int64
+}
+
+func (c *client) connect() error {
+	opts := MQTT.NewClientOptions().AddBroker(c.server).SetProtocolVersion(4).SetClientID(c.clientId)
+	if c.tls != nil {
+		opts = opts.SetTLSConfig(c.tls)
+	}
+	if c.credentials != nil {
+		opts = opts.SetCredentialsProvider(c.credentials)
+	}
+	if c.openConn != nil {
+		opts = opts.SetCustomOpenConnectionFn(c.openConn)
+	}
+	opts = opts.SetAutoReconnect(true)
+	opts.OnConnect = func(MQTT.Client) {
+		c.connectedAt.Store(time.Now().UnixNano())
+		conf.Log.Infof("Connected to %s with client id %s", c.server, c.clientId)
+	}
+	opts.OnConnectionLost = func(_ MQTT.Client, err error) {
+		conf.Log.Warnf("Connection to %s lost: %v", c.server, err)
+	}
+	opts.OnReconnecting = func(MQTT.Client, *MQTT.ClientOptions) {
+		conf.Log.Infof("Reconnecting to %s with client id %s", c.server, c.clientId)
+	}
+	conn := MQTT.NewClient(opts)
+	if err := handleToken(conn.Connect()); err != nil {
+		return fmt.Errorf("found error when connecting to %s: %s", c.server, err)
+	}
+	c.conn = conn
+	return nil
+}
+
+// renew reconnects with fresh credentials before the current ones expire. The cloud closes the connection once the
+// credentials expire, so renew in advance to avoid losing messages.
+func (c *client) renew() error {
+	if c.renewAfter <= 0 || c.conn == nil {
+		return nil
+	}
+	if time.Since(time.Unix(0, c.connectedAt.Load())) < c.renewAfter {
+		return nil
+	}
+	conf.Log.Infof("Renew the credentials of the connection to %s", c.server)
+	c.conn.Disconnect(1000)
+	return c.connect()
+}
+
+func (c *client) publish(topic string, qos byte, payload []byte) error {
+	if err := c.renew(); err != nil {
+		return errorx.NewIOErr(err.Error())
+	}
+	if err := handleToken(c.conn.Publish(topic, qos, false, payload)); err != nil {
+		return errorx.NewIOErr(fmt.Sprintf("found error when publishing to %s of topic %s: %s", c.server, topic, err))
+	}
+	return nil
+}
+
+func (c *client) close() {
+	if c.conn != nil && c.conn.IsConnected() {
+		conf.Log.Infof("Closing the connection to %s", c.server)
+		c.conn.Disconnect(5000)
+	}
+}
+
+func handleToken(token MQTT.Token) error {
+	if !token.WaitTimeout(5 * time.Second) {
+		return errorx.NewIOErr("timeout")
+	} else if token.Error() != nil {
+		return errorx.NewIOErr(token.Error().Error())
+	}
+	return nil
+}
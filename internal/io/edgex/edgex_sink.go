@@ -25,6 +25,7 @@ import (
 	v3 "github.com/edgexfoundry/go-mod-core-contracts/v3/common"
 	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos"
 	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos/requests"
+	"github.com/fxamacker/cbor/v2"
 
 	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/topo/connection/clients"
@@ -75,8 +76,8 @@ func (ems *EdgexMsgBusSink) Configure(ps map[string]interface{}) error {
 		return fmt.Errorf("specified wrong messageType value %s", c.MessageType)
 	}
 
-	if c.MessageType == MessageTypeEvent && c.ContentType != "application/json" {
-		return fmt.Errorf("specified wrong contentType value %s: only 'application/json' is supported if messageType is event", c.ContentType)
+	if c.ContentType != v3.ContentTypeJSON && c.ContentType != v3.ContentTypeCBOR {
+		return fmt.Errorf("specified wrong contentType value %s: only 'application/json' and 'application/cbor' are supported", c.ContentType)
 	}
 
 	if c.Topic != "" && c.TopicPrefix != "" {
@@ -118,21 +119,27 @@ func (ems *EdgexMsgBusSink) Open(ctx api.StreamContext) error {
 }
 
 func (ems *EdgexMsgBusSink) produceEvents(ctx api.StreamContext, item interface{}) (*dtos.Event, error) {
+	evt, _, err := ems.produce(ctx, item)
+	return evt, err
+}
+
+// produce converts the item to the event and returns the metadata to set the message envelope
+func (ems *EdgexMsgBusSink) produce(ctx api.StreamContext, item interface{}) (*dtos.Event, *meta, error) {
 	if ems.c.DataTemplate != "" {
 		jsonBytes, _, err := ctx.TransformOutput(item)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		tm := make(map[string]interface{})
 		err = json.Unmarshal(jsonBytes, &tm)
 		if err != nil {
-			return nil, fmt.Errorf("fail to decode data %s after applying dataTemplate for error %v", string(jsonBytes), err)
+			return nil, nil, fmt.Errorf("fail to decode data %s after applying dataTemplate for error %v", string(jsonBytes), err)
 		}
 		item = tm
 	} else {
 		tm, _, err := transform.TransItem(item, ems.c.DataField, ems.c.Fields)
 		if err != nil {
-			return nil, fmt.Errorf("fail to select fields %v for data %v", ems.c.Fields, item)
+			return nil, nil, fmt.Errorf("fail to select fields %v for data %v", ems.c.Fields, item)
 		}
 		item = tm
 	}
@@ -144,7 +151,7 @@ func (ems *EdgexMsgBusSink) produceEvents(ctx api.StreamContext, item interface{
 		m = payload
 	default:
 		// impossible
-		return nil, fmt.Errorf("receive invalid data %v", item)
+		return nil, nil, fmt.Errorf("receive invalid data %v", item)
 	}
 	m1 := ems.getMeta(m)
 	event := m1.createEvent()
@@ -201,7 +208,7 @@ func (ems *EdgexMsgBusSink) produceEvents(ctx api.StreamContext, item interface{
 			}
 		}
 	}
-	return event, nil
+	return event, m1, nil
 }
 
 func getValueType(v interface{}) (string, interface{}, error) {
@@ -485,7 +492,7 @@ func (ems *EdgexMsgBusSink) getMeta(result []map[string]interface{}) *meta {
 
 func (ems *EdgexMsgBusSink) Collect(ctx api.StreamContext, item interface{}) error {
 	logger := ctx.GetLogger()
-	evt, err := ems.produceEvents(ctx, item)
+	evt, m, err := ems.produce(ctx, item)
 	if err != nil {
 		return fmt.Errorf("Failed to convert to EdgeX event: %s.", err.Error())
 	}
@@ -493,14 +500,24 @@ func (ems *EdgexMsgBusSink) Collect(ctx api.StreamContext, item interface{}) err
 		data  []byte
 		topic string
 	)
+	params := ems.envelopeParams(m)
+	isCbor := params["contentType"] == v3.ContentTypeCBOR
 	if ems.c.MessageType == MessageTypeRequest {
 		req := requests.NewAddEventRequest(*evt)
-		data, _, err = req.Encode()
+		if isCbor {
+			data, err = cbor.Marshal(req)
+		} else {
+			data, _, err = req.Encode()
+		}
 		if err != nil {
 			return fmt.Errorf("unexpected error encode event %v", err)
 		}
 	} else {
-		data, err = json.Marshal(evt)
+		if isCbor {
+			data, err = cbor.Marshal(evt)
+		} else {
+			data, err = json.Marshal(evt)
+		}
 		if err != nil {
 			return fmt.Errorf("unexpected error MarshalEvent %v", err)
 		}
@@ -512,7 +529,7 @@ func (ems *EdgexMsgBusSink) Collect(ctx api.StreamContext, item interface{}) err
 		topic = ems.topic
 	}
 
-	if e := ems.cli.Publish(ctx, topic, data, ems.sendParams); e != nil {
+	if e := ems.cli.Publish(ctx, topic, data, params); e != nil {
 		logger.Errorf("%s: found error %s when publish to EdgeX message bus.\n", e.Error(), e.Error())
 		return errorx.NewIOErr(e.Error())
 	}
@@ -521,6 +538,24 @@ func (ems *EdgexMsgBusSink) Collect(ctx api.StreamContext, item interface{}) err
 	return nil
 }
 
+// envelopeParams propagates the correlation id and the content type in the metadata to the message envelope
+func (ems *EdgexMsgBusSink) envelopeParams(m *meta) map[string]any {
+	if m == nil || (m.correlationId == "" && m.contentType == "") {
+		return ems.sendParams
+	}
+	params := make(map[string]any, len(ems.sendParams)+1)
+	for k, v := range ems.sendParams {
+		params[k] = v
+	}
+	if m.correlationId != "" {
+		params["correlationId"] = m.correlationId
+	}
+	if m.contentType == v3.ContentTypeJSON || m.contentType == v3.ContentTypeCBOR {
+		params["contentType"] = m.contentType
+	}
+	return params
+}
+
 func (ems *EdgexMsgBusSink) Close(ctx api.StreamContext) error {
 	logger := ctx.GetLogger()
 	logger.Infof("Closing edgex sink")
@@ -537,6 +572,9 @@ type eventMeta struct {
 	sourceName  string
 	origin      *int64
 	tags        map[string]interface{}
+	// the message envelope of EdgeX v3
+	correlationId string
+	contentType   string
 }
 
 type readingMeta struct {
@@ -607,6 +645,16 @@ func newMetaFromMap(m1 map[string]interface{}) *meta {
 			if v1, ok1 := v.(map[string]interface{}); ok1 {
 				result.tags = v1
 			}
+		case "correlationid", "correlationId":
+			if v1, ok := v.(string); ok {
+				result.correlationId = v1
+			}
+		case "contentType":
+			if v1, ok := v.(string); ok {
+				result.contentType = v1
+			}
+		case "receivedTopic":
+			// only for the source, ignore
 		default:
 			if result.readingMetas == nil {
 				result.readingMetas = make(map[string]interface{})
@@ -617,3 +617,33 @@ func TestEdgeXTemplate_Apply(t1 *testing.T) {
 		}
 	}
 }
+
+func TestEnvelopeParams(t *testing.T) {
+	ems := EdgexMsgBusSink{}
+	assert.EqualError(t, ems.Configure(map[string]interface{}{"contentType": "text/plain"}), "specified wrong contentType value text/plain: only 'application/json' and 'application/cbor' are supported")
+	assert.NoError(t, ems.Configure(map[string]interface{}{"metadata": "meta"}))
+
+	m := ems.getMeta([]map[string]interface{}{{
+		"meta": map[string]interface{}{
+			"correlationid": "14a42ea6-c394-41c3-8bcd-a29b9f5e6840",
+			"contentType":   "application/cbor",
+			"receivedTopic": "edgex/events/device/demo",
+			"deviceName":    "demo",
+		},
+	}})
+	assert.Nil(t, m.readingMetas)
+	assert.Equal(t, map[string]any{
+		"contentType":   "application/cbor",
+		"correlationId": "14a42ea6-c394-41c3-8bcd-a29b9f5e6840",
+	}, ems.envelopeParams(m))
+	// keep the configured content type if the meta is not a supported one
+	m = newMetaFromMap(map[string]interface{}{"correlationId": "abc", "contentType": "text/plain"})
+	assert.Equal(t, map[string]any{
+		"contentType":   "application/json",
+		"correlationId": "abc",
+	}, ems.envelopeParams(m))
+	// no envelope meta
+	assert.Equal(t, map[string]any{
+		"contentType": "application/json",
+	}, ems.envelopeParams(newMetaFromMap(nil)))
+}
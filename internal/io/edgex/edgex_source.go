@@ -201,6 +201,8 @@ func (es *EdgexSource) Open(ctx api.StreamContext, consumer chan<- api.SourceTup
 					meta["origin"] = e.Origin
 					meta["tags"] = e.Tags
 					meta["correlationid"] = env.CorrelationID
+					meta["contentType"] = env.ContentType
+					meta["receivedTopic"] = env.ReceivedTopic
 
 					select {
 					case consumer <- api.NewDefaultSourceTupleWithTime(result, meta, rcvTime):
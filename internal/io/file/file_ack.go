@@ -0,0 +1,141 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+// fileAck is a file whose tuples are waiting to be delivered in ack mode. The tuples are numbered by a sequence across
+// the files, and the files are read one by one, so the committed sequence tells how far each file is delivered.
+type fileAck struct {
+	file string
+	// ft is the tracker to save the delivered offset in the watch mode
+	ft *fileTracker
+	// base is the sequence before the first tuple of the file, so the tuple n of the file has the sequence base+n
+	base int64
+	// start is the sequence when the file starts to be read in this run
+	start int64
+	// tagged is the sequence of the last tuple sent with the sequence. The error tuples do not carry it.
+	tagged int64
+	// last is the sequence to deliver before the file is finished, -1 if the file is still being read
+	last int64
+}
+
+// ackTuple is the tuple with the sequence to commit after delivered
+type ackTuple struct {
+	*api.DefaultSourceTuple
+	seq int64
+}
+
+func (t *ackTuple) Offset() interface{} {
+	return t.seq
+}
+
+// startAck registers the file before sending its tuples. The skipped tuples have been delivered before the restart.
+// It returns nil if nothing needs to be done after the file is delivered.
+func (fs *FileSource) startAck(file string, ft *fileTracker, skipped int64) *fileAck {
+	if ft == nil && fs.config.ActionAfterRead == 0 {
+		return nil
+	}
+	fs.ackMu.Lock()
+	defer fs.ackMu.Unlock()
+	a := &fileAck{file: file, ft: ft, base: fs.seq - skipped, start: fs.seq, last: -1}
+	fs.acks = append(fs.acks, a)
+	return a
+}
+
+// nextSeq returns the sequence of the next tuple of the file
+func (fs *FileSource) nextSeq() int64 {
+	fs.ackMu.Lock()
+	defer fs.ackMu.Unlock()
+	fs.seq++
+	return fs.seq
+}
+
+// endAck is called when the file is read completely. The file is finished once its last tuple is delivered.
+func (fs *FileSource) endAck(ctx api.StreamContext, a *fileAck) {
+	fs.ackMu.Lock()
+	defer fs.ackMu.Unlock()
+	a.last = a.start
+	if a.tagged > 0 {
+		a.last = a.tagged
+	}
+	fs.advance(ctx)
+}
+
+// dropAck gives up the file which fails to be read
+func (fs *FileSource) dropAck(a *fileAck) {
+	fs.ackMu.Lock()
+	defer fs.ackMu.Unlock()
+	for i, b := range fs.acks {
+		if b == a {
+			fs.acks = append(fs.acks[:i], fs.acks[i+1:]...)
+			break
+		}
+	}
+}
+
+// CommitOffset is called after the tuples up to the sequence are delivered. The delivered offset of the file being
+// read is saved, and the files read completely are moved or deleted.
+func (fs *FileSource) CommitOffset(ctx api.StreamContext, offset interface{}) error {
+	seq, err := cast.ToInt64(offset, cast.CONVERT_SAMEKIND)
+	if err != nil {
+		return fmt.Errorf("invalid offset %v for file source: %v", offset, err)
+	}
+	fs.ackMu.Lock()
+	defer fs.ackMu.Unlock()
+	if seq > fs.committed {
+		fs.committed = seq
+	}
+	fs.advance(ctx)
+	return nil
+}
+
+// advance finishes the delivered files in order and saves the offset of the first file not finished
+func (fs *FileSource) advance(ctx api.StreamContext) {
+	for len(fs.acks) > 0 {
+		a := fs.acks[0]
+		// A file without tuples to deliver in this run has nothing to wait for
+		if a.last >= 0 && (a.last <= fs.committed || a.last == a.start) {
+			fs.finish(ctx, a)
+			fs.acks = fs.acks[1:]
+			continue
+		}
+		if a.ft != nil && fs.committed > a.base && fs.committed-a.base > a.ft.state.Offset {
+			a.ft.state.Offset = fs.committed - a.base
+			a.ft.commit(ctx)
+		}
+		return
+	}
+}
+
+func (fs *FileSource) finish(ctx api.StreamContext, a *fileAck) {
+	if err := fs.afterRead(ctx, a.file); err != nil {
+		ctx.GetLogger().Errorf("fail to handle file %s after read: %v", a.file, err)
+	}
+	if a.ft != nil {
+		if fs.config.ActionAfterRead == 0 {
+			a.ft.done(ctx)
+		} else {
+			a.ft.remove(ctx)
+		}
+	}
+}
+
+var _ api.OffsetCommitter = &FileSource{}
@@ -0,0 +1,222 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/ekuiper/internal/compressor"
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+var compressionExts = map[string]string{
+	GZIP: ".gz",
+	ZSTD: ".zst",
+}
+
+// roll closes the file writer of the file name, then compresses the rolled file and cleans up the old rolled files
+// according to the retention policy. It must be called with the lock held.
+func (m *fileSink) roll(ctx api.StreamContext, fn string, fw *fileWriter) error {
+	ctx.GetLogger().Debugf("rolling file %s", fn)
+	delete(m.fws, fn)
+	// The file will be created when the next item comes
+	fw.Count = 0
+	fw.Size = 0
+	fw.Written = false
+	if err := fw.Close(ctx); err != nil {
+		return err
+	}
+	if m.c.RollingCompression != "" {
+		if err := compressFile(fw.File.Name(), m.c.RollingCompression); err != nil {
+			return fmt.Errorf("fail to compress rolled file %s: %v", fw.File.Name(), err)
+		}
+	}
+	if m.hasRetention() {
+		m.rolled[fn] = struct{}{}
+		m.retain(ctx, fn)
+	}
+	return nil
+}
+
+// compressFile compresses the file to a new file with the compression extension and removes the original one
+func compressFile(name string, algorithm string) (ge error) {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	target := name + compressionExts[algorithm]
+	dst, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if ge != nil {
+			_ = dst.Close()
+			_ = os.Remove(target)
+		}
+	}()
+	buf := bufio.NewWriter(dst)
+	w, err := compressor.GetCompressWriter(algorithm, buf)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		return err
+	}
+	if c, ok := w.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			return err
+		}
+	}
+	if err := buf.Flush(); err != nil {
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	_ = src.Close()
+	return os.Remove(name)
+}
+
+func (m *fileSink) hasRetention() bool {
+	return m.c.MaxFiles > 0 || m.c.MaxAge > 0 || m.c.MaxTotalSize > 0
+}
+
+// rolledPattern returns the glob pattern to match the rolled files of the file name
+func (m *fileSink) rolledPattern(fn string) string {
+	dir, name := filepath.Split(fn)
+	var p string
+	switch m.c.RollingNamePattern {
+	case "prefix":
+		p = "*-" + name
+	case "suffix":
+		ext := filepath.Ext(name)
+		p = strings.TrimSuffix(name, ext) + "-*" + ext
+	}
+	if m.c.RollingCompression != "" {
+		p += compressionExts[m.c.RollingCompression]
+	}
+	return filepath.Join(dir, escapeGlob(p))
+}
+
+// escapeGlob escapes the glob meta characters except the inserted wildcard
+func escapeGlob(p string) string {
+	r := strings.NewReplacer("[", "\\[", "?", "\\?", "\\", "\\\\")
+	return r.Replace(p)
+}
+
+// retain removes the rolled files of the file name exceeding the maxAge, maxFiles or maxTotalSize from the oldest.
+// It must be called with the lock held.
+func (m *fileSink) retain(ctx api.StreamContext, fn string) {
+	matches, err := filepath.Glob(m.rolledPattern(fn))
+	if err != nil {
+		ctx.GetLogger().Errorf("fail to list rolled files of %s: %v", fn, err)
+		return
+	}
+	opened := make(map[string]struct{}, len(m.fws))
+	for _, fw := range m.fws {
+		opened[fw.File.Name()] = struct{}{}
+	}
+	type rolledFile struct {
+		name    string
+		size    int64
+		modTime time.Time
+	}
+	files := make([]rolledFile, 0, len(matches))
+	for _, name := range matches {
+		if _, ok := opened[name]; ok {
+			continue
+		}
+		fi, err := os.Stat(name)
+		if err != nil || fi.IsDir() {
+			continue
+		}
+		files = append(files, rolledFile{name: name, size: fi.Size(), modTime: fi.ModTime()})
+	}
+	// oldest first
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].modTime.Equal(files[j].modTime) {
+			return files[i].name < files[j].name
+		}
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+	remove := func(f rolledFile, reason string) {
+		if err := os.Remove(f.name); err != nil {
+			ctx.GetLogger().Errorf("fail to remove rolled file %s: %v", f.name, err)
+			return
+		}
+		ctx.GetLogger().Infof("Remove rolled file %s due to %s", f.name, reason)
+	}
+	for len(files) > 0 {
+		f := files[0]
+		switch {
+		case m.c.MaxAge > 0 && time.Since(f.modTime) > time.Duration(m.c.MaxAge)*time.Millisecond:
+			remove(f, "maxAge")
+		case m.c.MaxFiles > 0 && len(files) > m.c.MaxFiles:
+			remove(f, "maxFiles")
+		case m.c.MaxTotalSize > 0 && total > m.c.MaxTotalSize:
+			remove(f, "maxTotalSize")
+		default:
+			return
+		}
+		files = files[1:]
+		total -= f.size
+	}
+}
+
+// rollingStamp returns the timestamp to name the rolling file. It is the event time of the item if timeField is set,
+// otherwise the current time.
+func (m *fileSink) rollingStamp(ctx api.StreamContext, item interface{}) string {
+	t := conf.GetNow()
+	if m.c.TimeField != "" {
+		var v interface{}
+		switch d := item.(type) {
+		case map[string]interface{}:
+			v = d[m.c.TimeField]
+		case []map[string]interface{}:
+			if len(d) > 0 {
+				v = d[0][m.c.TimeField]
+			}
+		}
+		if v != nil {
+			et, err := cast.InterfaceToTime(v, "")
+			if err != nil {
+				ctx.GetLogger().Warnf("invalid event time %v of field %s, use the current time instead: %v", v, m.c.TimeField, err)
+			} else {
+				t = et
+			}
+		}
+	}
+	if m.c.RollingTimeFormat == "" {
+		return fmt.Sprintf("%d", t.UnixMilli())
+	}
+	// The format is validated when configuring
+	s, _ := cast.FormatTime(t, m.c.RollingTimeFormat)
+	return s
+}
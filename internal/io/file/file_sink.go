@@ -33,6 +33,13 @@ type sinkConf struct {
 	RollingInterval    int64    `json:"rollingInterval"`
 	RollingCount       int      `json:"rollingCount"`
 	RollingNamePattern string   `json:"rollingNamePattern"` // where to add the timestamp to the file name
+	RollingSize        int64    `json:"rollingSize"`
+	RollingCompression string   `json:"rollingCompression"` // compress the rolled files
+	RollingTimeFormat  string   `json:"rollingTimeFormat"`  // the format of the timestamp in the rolling file name
+	TimeField          string   `json:"timeField"`          // the field of the event time to name the rolling files
+	MaxFiles           int      `json:"maxFiles"`
+	MaxAge             int64    `json:"maxAge"`
+	MaxTotalSize       int64    `json:"maxTotalSize"`
 	CheckInterval      int64    `json:"checkInterval"`
 	Path               string   `json:"path"` // support dynamic property, when rolling, make sure the path is updated
 	FileType           FileType `json:"fileType"`
@@ -48,6 +55,8 @@ type fileSink struct {
 
 	mux sync.Mutex
 	fws map[string]*fileWriter
+	// the file names which have rolled files to apply the retention policy
+	rolled map[string]struct{}
 }
 
 func (m *fileSink) Configure(props map[string]interface{}) error {
@@ -66,12 +75,15 @@ func (m *fileSink) Configure(props map[string]interface{}) error {
 	if c.RollingCount < 0 {
 		return fmt.Errorf("rollingCount must be positive")
 	}
+	if c.RollingSize < 0 {
+		return fmt.Errorf("rollingSize must be positive")
+	}
 
 	if c.CheckInterval < 0 {
 		return fmt.Errorf("checkInterval must be positive")
 	}
-	if c.RollingInterval == 0 && c.RollingCount == 0 {
-		return fmt.Errorf("one of rollingInterval and rollingCount must be set")
+	if c.RollingInterval == 0 && c.RollingCount == 0 && c.RollingSize == 0 {
+		return fmt.Errorf("one of rollingInterval, rollingCount and rollingSize must be set")
 	}
 	if c.RollingNamePattern != "" && c.RollingNamePattern != "prefix" && c.RollingNamePattern != "suffix" && c.RollingNamePattern != "none" {
 		return fmt.Errorf("rollingNamePattern must be one of prefix, suffix or none")
@@ -95,9 +107,29 @@ func (m *fileSink) Configure(props map[string]interface{}) error {
 	if _, ok := compressionTypes[c.Compression]; !ok && c.Compression != "" {
 		return fmt.Errorf("compression must be one of gzip, zstd")
 	}
+	if c.RollingCompression != "" {
+		if _, ok := compressionTypes[c.RollingCompression]; !ok {
+			return fmt.Errorf("rollingCompression must be one of gzip, zstd")
+		}
+		if c.Compression != "" {
+			return fmt.Errorf("compression and rollingCompression cannot be set together")
+		}
+	}
+	if c.RollingTimeFormat != "" {
+		if _, err := cast.FormatTime(time.Now(), c.RollingTimeFormat); err != nil {
+			return fmt.Errorf("invalid rollingTimeFormat %s: %v", c.RollingTimeFormat, err)
+		}
+	}
+	if c.MaxFiles < 0 || c.MaxAge < 0 || c.MaxTotalSize < 0 {
+		return fmt.Errorf("maxFiles, maxAge and maxTotalSize must be positive")
+	}
+	if (c.RollingCompression != "" || c.MaxFiles > 0 || c.MaxAge > 0 || c.MaxTotalSize > 0) && c.RollingNamePattern != "prefix" && c.RollingNamePattern != "suffix" {
+		return fmt.Errorf("rollingNamePattern must be prefix or suffix to compress or clean up the rolled files")
+	}
 
 	m.c = c
 	m.fws = make(map[string]*fileWriter)
+	m.rolled = make(map[string]struct{})
 	return nil
 }
 
@@ -113,16 +145,18 @@ func (m *fileSink) Open(ctx api.StreamContext) error {
 				case now := <-t.C:
 					m.mux.Lock()
 					for k, v := range m.fws {
-						if now.Sub(v.Start) > time.Duration(m.c.RollingInterval)*time.Millisecond {
-							ctx.GetLogger().Debugf("rolling file %s", k)
-							err := v.Close(ctx)
+						if m.c.RollingInterval > 0 && now.Sub(v.Start) > time.Duration(m.c.RollingInterval)*time.Millisecond {
+							err := m.roll(ctx, k, v)
 							// TODO how to inform this error to the rule
 							if err != nil {
-								ctx.GetLogger().Errorf("file sink fails to close file %s with error %s.", k, err)
+								ctx.GetLogger().Errorf("file sink fails to roll file %s with error %s.", k, err)
 							}
-							delete(m.fws, k)
-							// The file will be created when the next item comes
-							v.Written = false
+						}
+					}
+					// Clean up the expired files even if no new file is rolled
+					if m.c.MaxAge > 0 {
+						for k := range m.rolled {
+							m.retain(ctx, k)
 						}
 					}
 					m.mux.Unlock()
@@ -151,28 +185,22 @@ func (m *fileSink) Collect(ctx api.StreamContext, item interface{}) error {
 		m.mux.Lock()
 		defer m.mux.Unlock()
 		if fw.Written {
-			_, e := fw.Writer.Write(fw.Hook.Line())
+			n, e := fw.Writer.Write(fw.Hook.Line())
 			if e != nil {
 				return e
 			}
+			fw.Size += int64(n)
 		} else {
 			fw.Written = true
 		}
-		_, e := fw.Writer.Write(v)
+		n, e := fw.Writer.Write(v)
 		if e != nil {
 			return e
 		}
-		if m.c.RollingCount > 0 {
-			fw.Count++
-			if fw.Count >= m.c.RollingCount {
-				e = fw.Close(ctx)
-				if e != nil {
-					return e
-				}
-				delete(m.fws, fn)
-				fw.Count = 0
-				fw.Written = false
-			}
+		fw.Size += int64(n)
+		fw.Count++
+		if (m.c.RollingCount > 0 && fw.Count >= m.c.RollingCount) || (m.c.RollingSize > 0 && fw.Size >= m.c.RollingSize) {
+			return m.roll(ctx, fn, fw)
 		}
 	} else {
 		return fmt.Errorf("file sink transform data error: %v", err)
@@ -182,9 +210,12 @@ func (m *fileSink) Collect(ctx api.StreamContext, item interface{}) error {
 
 func (m *fileSink) Close(ctx api.StreamContext) error {
 	ctx.GetLogger().Infof("Closing file sink")
+	m.mux.Lock()
+	defer m.mux.Unlock()
 	var errs []error
+	// The next run always creates new files, so roll the current ones to compress and clean up
 	for k, v := range m.fws {
-		if e := v.Close(ctx); e != nil {
+		if e := m.roll(ctx, k, v); e != nil {
 			ctx.GetLogger().Errorf("failed to close file %s: %v", k, e)
 			errs = append(errs, e)
 		}
@@ -236,10 +267,10 @@ func (m *fileSink) GetFws(ctx api.StreamContext, fn string, item interface{}) (*
 			fileName := filepath.Base(fn)
 			switch m.c.RollingNamePattern {
 			case "prefix":
-				newFile = fmt.Sprintf("%d-%s", conf.GetNowInMilli(), fileName)
+				newFile = fmt.Sprintf("%s-%s", m.rollingStamp(ctx, item), fileName)
 			case "suffix":
 				ext := filepath.Ext(fn)
-				newFile = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(fileName, ext), conf.GetNowInMilli(), ext)
+				newFile = fmt.Sprintf("%s-%s%s", strings.TrimSuffix(fileName, ext), m.rollingStamp(ctx, item), ext)
 			default:
 				newFile = fileName
 			}
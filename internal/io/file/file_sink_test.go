@@ -16,6 +16,7 @@ package file
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -191,6 +192,34 @@ func TestFileSink_Configure(t *testing.T) {
 				"fields":          []string{"c", "a", "b"},
 			},
 		},
+		{
+			name: "rolling size and retention",
+			c: &sinkConf{
+				CheckInterval:      defaultCheckInterval,
+				Path:               "cache",
+				FileType:           LINES_TYPE,
+				RollingCount:       0,
+				RollingSize:        1024,
+				RollingNamePattern: "suffix",
+				RollingCompression: "gzip",
+				RollingTimeFormat:  "YYYYMMdd",
+				TimeField:          "ts",
+				MaxFiles:           10,
+				MaxAge:             3600000,
+				MaxTotalSize:       1048576,
+			},
+			p: map[string]interface{}{
+				"rollingCount":       0,
+				"rollingSize":        1024,
+				"rollingNamePattern": "suffix",
+				"rollingCompression": "gzip",
+				"rollingTimeFormat":  "YYYYMMdd",
+				"timeField":          "ts",
+				"maxFiles":           10,
+				"maxAge":             3600000,
+				"maxTotalSize":       1048576,
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -812,3 +841,117 @@ func TestFileSinkReopen(t *testing.T) {
 		t.Errorf("\nexpected\t %q \nbut got\t\t %q", string(exp), string(contents))
 	}
 }
+
+func TestFileSinkRollingConfigureError(t *testing.T) {
+	tests := []struct {
+		name string
+		p    map[string]interface{}
+		err  string
+	}{
+		{
+			name: "no rolling",
+			p:    map[string]interface{}{"rollingCount": 0},
+			err:  "one of rollingInterval, rollingCount and rollingSize must be set",
+		},
+		{
+			name: "negative size",
+			p:    map[string]interface{}{"rollingSize": -1},
+			err:  "rollingSize must be positive",
+		},
+		{
+			name: "invalid rolling compression",
+			p:    map[string]interface{}{"rollingCompression": "zip", "rollingNamePattern": "prefix"},
+			err:  "rollingCompression must be one of gzip, zstd",
+		},
+		{
+			name: "both compression",
+			p:    map[string]interface{}{"rollingCompression": "gzip", "compression": "gzip", "rollingNamePattern": "prefix"},
+			err:  "compression and rollingCompression cannot be set together",
+		},
+		{
+			name: "retention without pattern",
+			p:    map[string]interface{}{"maxFiles": 3},
+			err:  "rollingNamePattern must be prefix or suffix to compress or clean up the rolled files",
+		},
+		{
+			name: "negative retention",
+			p:    map[string]interface{}{"maxAge": -3, "rollingNamePattern": "prefix"},
+			err:  "maxFiles, maxAge and maxTotalSize must be positive",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&fileSink{}).Configure(tt.p)
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("fileSink.Configure() error = %v, want %s", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestFileSinkRollingSize(t *testing.T) {
+	conf.IsTesting = true
+	dir := t.TempDir()
+	contextLogger := conf.Log.WithField("rule", "testRollingSize")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger)
+	tf, _ := transform.GenTransform("", "json", "", "", "", []string{})
+	vCtx := context.WithValue(ctx, context.TransKey, tf)
+
+	sink := &fileSink{}
+	err := sink.Configure(map[string]interface{}{
+		"path":               filepath.Join(dir, "out.log"),
+		"fileType":           LINES_TYPE,
+		"format":             "json",
+		"rollingCount":       0,
+		"rollingSize":        30,
+		"rollingNamePattern": "prefix",
+		"rollingCompression": "gzip",
+		"timeField":          "ts",
+		"maxFiles":           2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = sink.Open(vCtx); err != nil {
+		t.Fatal(err)
+	}
+	// Each item is 22 bytes, so each file has 2 items
+	for i := 1; i <= 6; i++ {
+		m := map[string]interface{}{"key": fmt.Sprintf("v%d", i), "ts": i * 1000}
+		if err := sink.Collect(vCtx, m); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+	}
+	if err = sink.Close(vCtx); err != nil {
+		t.Errorf("unexpected close error: %s", err)
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	// The oldest file is removed by maxFiles
+	if exp := []string{"3000-out.log.gz", "5000-out.log.gz"}; !reflect.DeepEqual(names, exp) {
+		t.Fatalf("expect files %v, but got %v", exp, names)
+	}
+	f, err := os.Open(filepath.Join(dir, "5000-out.log.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := compressor.GetDecompressReader(GZIP, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	contents, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "{\"key\":\"v5\",\"ts\":5000}\n{\"key\":\"v6\",\"ts\":6000}"; string(contents) != exp {
+		t.Errorf("\nexpected\t %q \nbut got\t\t %q", exp, string(contents))
+	}
+}
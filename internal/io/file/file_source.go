@@ -31,6 +31,9 @@ import (
 	"github.com/lf-edge/ekuiper/pkg/cast"
 )
 
+// errInterrupted is returned when the rule stops before the file is read completely. The file is kept as is.
+var errInterrupted = errors.New("interrupted")
+
 type FileSourceConfig struct {
 	FileType         FileType `json:"fileType"`
 	Path             string   `json:"path"`
@@ -46,6 +49,16 @@ type FileSourceConfig struct {
 	IgnoreEndLines   int      `json:"ignoreEndLines"`
 	Delimiter        string   `json:"delimiter"`
 	Decompression    string   `json:"decompression"`
+	// Watch makes the source watch the directory and process the new files as they appear
+	Watch bool `json:"watch"`
+	// SettleInterval is the milliseconds that a new file must stay unchanged before it is processed in the watch mode
+	SettleInterval int    `json:"settleInterval"`
+	MoveErrorTo    string `json:"moveErrorTo"`
+	// Bounded reads the files once and ends, ignoring the interval and the watch mode, such as when the rule runs once
+	Bounded bool `json:"bounded"`
+	// EnableAck is set by the rule in ack mode. The offsets are saved and the files are moved or deleted only after
+	// the tuples are delivered.
+	EnableAck bool `json:"enableAck"`
 }
 
 // FileSource The BATCH to load data from file at once
@@ -53,6 +66,12 @@ type FileSource struct {
 	file   string
 	isDir  bool
 	config *FileSourceConfig
+
+	// the files waiting to be delivered in ack mode
+	ackMu     sync.Mutex
+	seq       int64
+	committed int64
+	acks      []*fileAck
 }
 
 func (fs *FileSource) Close(ctx api.StreamContext) error {
@@ -63,7 +82,8 @@ func (fs *FileSource) Close(ctx api.StreamContext) error {
 
 func (fs *FileSource) Configure(fileName string, props map[string]interface{}) error {
 	cfg := &FileSourceConfig{
-		FileType: JSON_TYPE,
+		FileType:       JSON_TYPE,
+		SettleInterval: 1000,
 	}
 	err := cast.MapToStruct(props, cfg)
 	if err != nil {
@@ -108,24 +128,40 @@ func (fs *FileSource) Configure(fileName string, props map[string]interface{}) e
 	if cfg.ActionAfterRead == 2 {
 		if cfg.MoveTo == "" {
 			return fmt.Errorf("missing moveTo when actionAfterRead is 2")
-		} else {
-			if !filepath.IsAbs(cfg.MoveTo) {
-				cfg.MoveTo, err = conf.GetLoc(cfg.MoveTo)
-				if err != nil {
-					return fmt.Errorf("invalid moveTo %s: %v", cfg.MoveTo, err)
-				}
-			}
-			fileInfo, err := os.Stat(cfg.MoveTo)
-			if err != nil {
-				err := os.MkdirAll(cfg.MoveTo, os.ModePerm)
-				if err != nil {
-					return fmt.Errorf("fail to create dir for moveTo %s: %v", cfg.MoveTo, err)
-				}
-			} else if !fileInfo.IsDir() {
-				return fmt.Errorf("moveTo %s is not a directory", cfg.MoveTo)
-			}
+		}
+		cfg.MoveTo, err = prepareDir(cfg.MoveTo)
+		if err != nil {
+			return fmt.Errorf("invalid moveTo: %v", err)
+		}
+	}
+	if cfg.MoveErrorTo != "" {
+		cfg.MoveErrorTo, err = prepareDir(cfg.MoveErrorTo)
+		if err != nil {
+			return fmt.Errorf("invalid moveErrorTo: %v", err)
 		}
 	}
+	if cfg.Bounded {
+		cfg.Watch = false
+		cfg.Interval = 0
+	}
+	if cfg.Watch {
+		if fileName != "/$$TEST_CONNECTION$$" && !fs.isDir {
+			return fmt.Errorf("watch mode requires %s to be a directory", fs.file)
+		}
+		if cfg.IsTable {
+			return fmt.Errorf("watch mode cannot be used by a table")
+		}
+		if cfg.Interval > 0 {
+			return fmt.Errorf("watch mode cannot be used with interval")
+		}
+		if cfg.SettleInterval <= 0 {
+			return fmt.Errorf("settleInterval must be positive")
+		}
+	}
+	if cfg.EnableAck && cfg.Parallel {
+		conf.Log.Warnf("file source %s reads the files one by one in ack mode", fs.file)
+		cfg.Parallel = false
+	}
 	if cfg.Delimiter == "" {
 		cfg.Delimiter = ","
 	}
@@ -138,7 +174,32 @@ func (fs *FileSource) Configure(fileName string, props map[string]interface{}) e
 	return nil
 }
 
+// prepareDir returns the absolute path of the directory and creates it if not exist
+func prepareDir(dir string) (string, error) {
+	var err error
+	if !filepath.IsAbs(dir) {
+		dir, err = conf.GetLoc(dir)
+		if err != nil {
+			return "", fmt.Errorf("invalid dir %s: %v", dir, err)
+		}
+	}
+	fileInfo, err := os.Stat(dir)
+	if err != nil {
+		err := os.MkdirAll(dir, os.ModePerm)
+		if err != nil {
+			return "", fmt.Errorf("fail to create dir %s: %v", dir, err)
+		}
+	} else if !fileInfo.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", dir)
+	}
+	return dir, nil
+}
+
 func (fs *FileSource) Open(ctx api.StreamContext, consumer chan<- api.SourceTuple, errCh chan<- error) {
+	if fs.config.Watch {
+		fs.watch(ctx, consumer, errCh)
+		return
+	}
 	err := fs.Load(ctx, consumer)
 	if err != nil {
 		select {
@@ -185,8 +246,8 @@ func (fs *FileSource) Load(ctx api.StreamContext, consumer chan<- api.SourceTupl
 				wg.Add(1)
 				go func(file string) {
 					defer wg.Done()
-					err := fs.parseFile(ctx, file, consumer)
-					if err != nil {
+					err := fs.parseFile(ctx, file, consumer, nil)
+					if err != nil && err != errInterrupted {
 						ctx.GetLogger().Errorf("Failed to parse file %s: %v", file, err)
 					}
 				}(filepath.Join(fs.file, entry.Name()))
@@ -198,7 +259,10 @@ func (fs *FileSource) Load(ctx api.StreamContext, consumer chan<- api.SourceTupl
 					continue
 				}
 				file := filepath.Join(fs.file, entry.Name())
-				err := fs.parseFile(ctx, file, consumer)
+				err := fs.parseFile(ctx, file, consumer, nil)
+				if err == errInterrupted {
+					return nil
+				}
 				if err != nil {
 					ctx.GetLogger().Errorf("parse file %s fail with error: %v", file, err)
 					continue
@@ -206,7 +270,10 @@ func (fs *FileSource) Load(ctx api.StreamContext, consumer chan<- api.SourceTupl
 			}
 		}
 	} else {
-		err := fs.parseFile(ctx, fs.file, consumer)
+		err := fs.parseFile(ctx, fs.file, consumer, nil)
+		if err == errInterrupted {
+			return nil
+		}
 		if err != nil {
 			return err
 		}
@@ -224,7 +291,18 @@ func (fs *FileSource) Load(ctx api.StreamContext, consumer chan<- api.SourceTupl
 	return nil
 }
 
-func (fs *FileSource) parseFile(ctx api.StreamContext, file string, consumer chan<- api.SourceTuple) (result error) {
+// parseFile reads the file and sends the tuples. If the tracker is set, the tuples before the tracked offset are skipped.
+func (fs *FileSource) parseFile(ctx api.StreamContext, file string, consumer chan<- api.SourceTuple, ft *fileTracker) (result error) {
+	defer func() {
+		if result != nil && result != errInterrupted && fs.config.MoveErrorTo != "" {
+			targetFile := filepath.Join(fs.config.MoveErrorTo, filepath.Base(file))
+			if err := os.Rename(file, targetFile); err != nil {
+				ctx.GetLogger().Errorf("fail to move file %s to %s: %v", file, targetFile, err)
+				return
+			}
+			ctx.GetLogger().Debugf("Move failed file %s to %s", file, targetFile)
+		}
+	}()
 	var fr FormatReader
 	switch fs.config.FileType {
 	case JSON_TYPE, CSV_TYPE, LINES_TYPE:
@@ -251,35 +329,60 @@ func (fs *FileSource) parseFile(ctx api.StreamContext, file string, consumer cha
 		}
 	}
 
+	var skipped int64
+	if ft != nil {
+		skipped = ft.state.Offset
+	}
+	var a *fileAck
+	if fs.config.EnableAck {
+		a = fs.startAck(file, ft, skipped)
+	}
 	defer func() {
 		fr.Close()
 		ctx.GetLogger().Debugf("Finish loading from file %s", file)
-		if result == nil {
-			switch fs.config.ActionAfterRead {
-			case 1:
-				if err := os.Remove(file); err != nil {
-					result = err
-				}
-				ctx.GetLogger().Debugf("Remove file %s", file)
-			case 2:
-				targetFile := filepath.Join(fs.config.MoveTo, filepath.Base(file))
-				if err := os.Rename(file, targetFile); err != nil {
-					result = err
-				}
-				ctx.GetLogger().Debugf("Move file %s to %s", file, targetFile)
-			}
+		switch {
+		case result == nil && a != nil:
+			// The file is moved or deleted after the tuples are delivered
+			fs.endAck(ctx, a)
+		case result == nil:
+			result = fs.afterRead(ctx, file)
+		case result != errInterrupted && a != nil:
+			fs.dropAck(a)
 		}
 	}()
 
-	return fs.publish(ctx, fr, consumer, map[string]any{"file": file})
+	return fs.publish(ctx, fr, consumer, map[string]any{"file": file}, ft, skipped, a)
+}
+
+// afterRead moves or deletes the file which has been read completely
+func (fs *FileSource) afterRead(ctx api.StreamContext, file string) error {
+	switch fs.config.ActionAfterRead {
+	case 1:
+		if err := os.Remove(file); err != nil {
+			return err
+		}
+		ctx.GetLogger().Debugf("Remove file %s", file)
+	case 2:
+		targetFile := filepath.Join(fs.config.MoveTo, filepath.Base(file))
+		if err := os.Rename(file, targetFile); err != nil {
+			return err
+		}
+		ctx.GetLogger().Debugf("Move file %s to %s", file, targetFile)
+	}
+	return nil
 }
 
-func (fs *FileSource) publish(ctx api.StreamContext, fr FormatReader, consumer chan<- api.SourceTuple, meta map[string]any) error {
+// publish sends the tuples of the file after the skipped ones. In ack mode, the tuples carry the sequence to commit and
+// the tracker is updated by the commit instead.
+func (fs *FileSource) publish(ctx api.StreamContext, fr FormatReader, consumer chan<- api.SourceTuple, meta map[string]any, ft *fileTracker, skipped int64, a *fileAck) error {
 	ctx.GetLogger().Debug("Start to load")
 	rcvTime := conf.GetNow()
 	ctx.GetLogger().Debug("Sending tuples")
 
-	var m map[string]interface{}
+	var (
+		m map[string]interface{}
+		n int64
+	)
 	for {
 		var err error
 		var tuple api.SourceTuple
@@ -297,11 +400,26 @@ func (fs *FileSource) publish(ctx api.StreamContext, fr FormatReader, consumer c
 			}
 			tuple = api.NewDefaultSourceTupleWithTime(m, meta, rcvTime)
 		}
+		n++
+		// Skip the tuples which have been sent before the restart
+		if n <= skipped {
+			continue
+		}
+		if a != nil {
+			seq := fs.nextSeq()
+			if dt, ok := tuple.(*api.DefaultSourceTuple); ok {
+				tuple = &ackTuple{DefaultSourceTuple: dt, seq: seq}
+				a.tagged = seq
+			}
+		}
 
 		select {
 		case consumer <- tuple:
 		case <-ctx.Done():
-			return nil
+			return errInterrupted
+		}
+		if ft != nil && a == nil {
+			ft.sent(ctx, n)
 		}
 
 		if fs.config.SendInterval > 0 {
@@ -324,6 +442,7 @@ func (fs *FileSource) prepareFile(ctx api.StreamContext, file string) (io.Reader
 	if fs.config.Decompression != "" {
 		reader, err = compressor.GetDecompressReader(fs.config.Decompression, f)
 		if err != nil {
+			_ = f.Close()
 			return nil, err
 		}
 	} else {
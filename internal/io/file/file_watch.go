@@ -0,0 +1,208 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/kv"
+)
+
+// offsetCommitInterval is the minimum interval to save the offset of the file being processed
+const offsetCommitInterval = time.Second
+
+// fileOffset is the processing state of a file saved in the kv store
+type fileOffset struct {
+	// Offset is the number of the tuples sent
+	Offset  int64
+	Size    int64
+	ModTime int64
+	Done    bool
+}
+
+// fileTracker tracks the offset of a file being processed in the watch mode so that the processing can resume after
+// a restart without sending the same tuples again
+type fileTracker struct {
+	kv         kv.KeyValue
+	file       string
+	state      fileOffset
+	lastCommit time.Time
+}
+
+// newFileTracker loads the saved state of the file. It returns nil if the file has been processed completely and not
+// changed since then.
+func newFileTracker(ctx api.StreamContext, offsets kv.KeyValue, file string, fi os.FileInfo) *fileTracker {
+	ft := &fileTracker{kv: offsets, file: file, lastCommit: time.Now()}
+	var state fileOffset
+	found, err := offsets.Get(file, &state)
+	if err != nil {
+		ctx.GetLogger().Warnf("fail to load the offset of file %s: %v", file, err)
+	}
+	size, modTime := fi.Size(), fi.ModTime().UnixMilli()
+	if found {
+		if !state.Done {
+			ctx.GetLogger().Infof("Resume file %s from offset %d", file, state.Offset)
+			ft.state.Offset = state.Offset
+		} else if state.Size == size && state.ModTime == modTime {
+			ctx.GetLogger().Debugf("Skip processed file %s", file)
+			return nil
+		}
+	}
+	ft.state.Size = size
+	ft.state.ModTime = modTime
+	return ft
+}
+
+func (ft *fileTracker) sent(ctx api.StreamContext, n int64) {
+	ft.state.Offset = n
+	if time.Since(ft.lastCommit) >= offsetCommitInterval {
+		ft.commit(ctx)
+	}
+}
+
+func (ft *fileTracker) commit(ctx api.StreamContext) {
+	if err := ft.kv.Set(ft.file, ft.state); err != nil {
+		ctx.GetLogger().Warnf("fail to save the offset of file %s: %v", ft.file, err)
+	}
+	ft.lastCommit = time.Now()
+}
+
+func (ft *fileTracker) done(ctx api.StreamContext) {
+	ft.state.Done = true
+	ft.commit(ctx)
+}
+
+// remove drops the state once the file is moved or deleted after processing
+func (ft *fileTracker) remove(ctx api.StreamContext) {
+	if err := ft.kv.Delete(ft.file); err != nil {
+		ctx.GetLogger().Debugf("fail to delete the offset of file %s: %v", ft.file, err)
+	}
+}
+
+// watch processes the existing files in the directory, then watches the directory and processes the new files once
+// they stop changing for the settle interval
+func (fs *FileSource) watch(ctx api.StreamContext, consumer chan<- api.SourceTuple, errCh chan<- error) {
+	logger := ctx.GetLogger()
+	offsets, err := store.GetKV(fmt.Sprintf("fileOffset_%s_%s", ctx.GetRuleId(), ctx.GetOpId()))
+	if err != nil {
+		errCh <- fmt.Errorf("fail to get the offset store: %v", err)
+		return
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		errCh <- fmt.Errorf("fail to create the watcher: %v", err)
+		return
+	}
+	defer watcher.Close()
+	if err := watcher.Add(fs.file); err != nil {
+		errCh <- fmt.Errorf("fail to watch dir %s: %v", fs.file, err)
+		return
+	}
+	logger.Infof("Watch dir %s", fs.file)
+	// The files created while the rule is stopped are processed first
+	entries, err := os.ReadDir(fs.file)
+	if err != nil {
+		errCh <- err
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		fs.processWatched(ctx, filepath.Join(fs.file, entry.Name()), consumer, offsets)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+
+	settle := time.Duration(fs.config.SettleInterval) * time.Millisecond
+	ticker := time.NewTicker(settle / 2)
+	defer ticker.Stop()
+	// the files changed recently with their last change time
+	pending := make(map[string]time.Time)
+	for {
+		select {
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if ev.Has(fsnotify.Create) || ev.Has(fsnotify.Write) {
+				pending[ev.Name] = time.Now()
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Warnf("Watch dir %s error: %v", fs.file, err)
+		case <-ticker.C:
+			var ready []string
+			for file, t := range pending {
+				if time.Since(t) >= settle {
+					ready = append(ready, file)
+				}
+			}
+			sort.Strings(ready)
+			for _, file := range ready {
+				delete(pending, file)
+				fs.processWatched(ctx, file, consumer, offsets)
+				if ctx.Err() != nil {
+					return
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (fs *FileSource) processWatched(ctx api.StreamContext, file string, consumer chan<- api.SourceTuple, offsets kv.KeyValue) {
+	fi, err := os.Stat(file)
+	// The file may be removed or moved already
+	if err != nil || fi.IsDir() {
+		return
+	}
+	ft := newFileTracker(ctx, offsets, file, fi)
+	if ft == nil {
+		return
+	}
+	err = fs.parseFile(ctx, file, consumer, ft)
+	switch {
+	case err == errInterrupted && fs.config.EnableAck:
+		// The delivered offset is saved by the commit
+	case err == errInterrupted:
+		ft.commit(ctx)
+	case err != nil:
+		ctx.GetLogger().Errorf("parse file %s fail with error: %v", file, err)
+		if fs.config.MoveErrorTo != "" {
+			ft.remove(ctx)
+		} else {
+			ft.commit(ctx)
+		}
+	case fs.config.EnableAck:
+		// The tracker is done or removed after the tuples are delivered
+	case fs.config.ActionAfterRead == 0:
+		ft.done(ctx)
+	default:
+		ft.remove(ctx)
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/converter"
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	mockContext "github.com/lf-edge/ekuiper/pkg/mock/context"
+)
+
+func watchContext(ruleId string) (api.StreamContext, func()) {
+	ctx, cancel := mockContext.NewMockContext(ruleId, "op1").WithCancel()
+	cv, _ := converter.GetOrCreateConverter(&ast.Options{FORMAT: "json"})
+	return context.WithValue(ctx.(*context.DefaultContext), context.DecodeKey, cv), cancel
+}
+
+func receive(t *testing.T, consumer chan api.SourceTuple, n int) []int {
+	var ids []int
+	for i := 0; i < n; i++ {
+		select {
+		case tuple := <-consumer:
+			ids = append(ids, int(tuple.Message()["id"].(float64)))
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout after receiving %v", ids)
+		}
+	}
+	return ids
+}
+
+func TestWatchFolder(t *testing.T) {
+	testx.InitEnv("file")
+	dir := t.TempDir()
+	archive := filepath.Join(t.TempDir(), "archive")
+	failed := filepath.Join(t.TempDir(), "failed")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.lines"), []byte("{\"id\":1}\n{\"id\":2}\n"), 0o644))
+
+	r := &FileSource{}
+	require.NoError(t, r.Configure("", map[string]interface{}{
+		"path":            dir,
+		"fileType":        "lines",
+		"watch":           true,
+		"settleInterval":  100,
+		"actionAfterRead": 2,
+		"moveTo":          archive,
+		"moveErrorTo":     failed,
+		"decompression":   "",
+	}))
+	ctx, cancel := watchContext("ruleWatch")
+	defer cancel()
+	consumer := make(chan api.SourceTuple)
+	errCh := make(chan error)
+	go r.Open(ctx, consumer, errCh)
+	// the existing file
+	assert.Equal(t, []int{1, 2}, receive(t, consumer, 2))
+	// the new file
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.lines"), []byte("{\"id\":3}\n"), 0o644))
+	assert.Equal(t, []int{3}, receive(t, consumer, 1))
+	assert.Eventually(t, func() bool {
+		entries, err := os.ReadDir(archive)
+		return err == nil && len(entries) == 2
+	}, 2*time.Second, 50*time.Millisecond)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestWatchFolderError(t *testing.T) {
+	testx.InitEnv("file")
+	dir := t.TempDir()
+	failed := filepath.Join(t.TempDir(), "failed")
+	// not a gzip file
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.lines"), []byte("{\"id\":1}\n"), 0o644))
+
+	r := &FileSource{}
+	require.NoError(t, r.Configure("", map[string]interface{}{
+		"path":           dir,
+		"fileType":       "lines",
+		"watch":          true,
+		"settleInterval": 100,
+		"moveErrorTo":    failed,
+		"decompression":  "gzip",
+	}))
+	ctx, cancel := watchContext("ruleWatchError")
+	defer cancel()
+	go r.Open(ctx, make(chan api.SourceTuple), make(chan error))
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(filepath.Join(failed, "a.lines"))
+		return err == nil
+	}, 2*time.Second, 50*time.Millisecond)
+}
+
+func TestWatchResume(t *testing.T) {
+	testx.InitEnv("file")
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.lines")
+	b := filepath.Join(dir, "b.lines")
+	require.NoError(t, os.WriteFile(a, []byte("{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n"), 0o644))
+	require.NoError(t, os.WriteFile(b, []byte("{\"id\":4}\n"), 0o644))
+	offsets, err := store.GetKV("fileOffset_ruleResume_op1")
+	require.NoError(t, err)
+	defer func() {
+		_ = offsets.Clean()
+	}()
+	// a.lines was interrupted after sending 2 tuples and b.lines was processed
+	require.NoError(t, offsets.Set(a, fileOffset{Offset: 2}))
+	fi, err := os.Stat(b)
+	require.NoError(t, err)
+	require.NoError(t, offsets.Set(b, fileOffset{Offset: 1, Size: fi.Size(), ModTime: fi.ModTime().UnixMilli(), Done: true}))
+
+	r := &FileSource{}
+	require.NoError(t, r.Configure("", map[string]interface{}{
+		"path":     dir,
+		"fileType": "lines",
+		"watch":    true,
+	}))
+	ctx, cancel := watchContext("ruleResume")
+	consumer := make(chan api.SourceTuple)
+	go r.Open(ctx, consumer, make(chan error))
+	assert.Equal(t, []int{3}, receive(t, consumer, 1))
+	select {
+	case tuple := <-consumer:
+		t.Fatalf("unexpected tuple %v", tuple.Message())
+	case <-time.After(200 * time.Millisecond):
+	}
+	cancel()
+
+	var state fileOffset
+	found, err := offsets.Get(a, &state)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, int64(3), state.Offset)
+	assert.True(t, state.Done)
+}
+
+func TestWatchAck(t *testing.T) {
+	testx.InitEnv("file")
+	dir := t.TempDir()
+	archive := filepath.Join(t.TempDir(), "archive")
+	a := filepath.Join(dir, "a.lines")
+	require.NoError(t, os.WriteFile(a, []byte("{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n"), 0o644))
+	offsets, err := store.GetKV("fileOffset_ruleAck_op1")
+	require.NoError(t, err)
+	defer func() {
+		_ = offsets.Clean()
+	}()
+
+	r := &FileSource{}
+	require.NoError(t, r.Configure("", map[string]interface{}{
+		"path":            dir,
+		"fileType":        "lines",
+		"watch":           true,
+		"actionAfterRead": 2,
+		"moveTo":          archive,
+		"enableAck":       true,
+	}))
+	ctx, cancel := watchContext("ruleAck")
+	consumer := make(chan api.SourceTuple)
+	go r.Open(ctx, consumer, make(chan error))
+	var seqs []interface{}
+	for i := 0; i < 3; i++ {
+		select {
+		case tuple := <-consumer:
+			seqs = append(seqs, tuple.(api.OffsetTuple).Offset())
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout")
+		}
+	}
+	assert.Equal(t, []interface{}{int64(1), int64(2), int64(3)}, seqs)
+	// The file is kept until all its tuples are delivered
+	require.NoError(t, r.CommitOffset(ctx, int64(2)))
+	var state fileOffset
+	found, err := offsets.Get(a, &state)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, int64(2), state.Offset)
+	_, err = os.Stat(a)
+	require.NoError(t, err)
+
+	require.NoError(t, r.CommitOffset(ctx, int64(3)))
+	_, err = os.Stat(filepath.Join(archive, "a.lines"))
+	require.NoError(t, err)
+	found, err = offsets.Get(a, &state)
+	require.NoError(t, err)
+	assert.False(t, found)
+	cancel()
+}
+
+func TestWatchConfigure(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.lines"), []byte("{}"), 0o644))
+	tests := []struct {
+		name  string
+		file  string
+		props map[string]interface{}
+		err   string
+	}{
+		{
+			name:  "not dir",
+			file:  "a.lines",
+			props: map[string]interface{}{"path": dir, "watch": true},
+			err:   "watch mode requires " + filepath.Join(dir, "a.lines") + " to be a directory",
+		},
+		{
+			name:  "table",
+			props: map[string]interface{}{"path": dir, "watch": true, "isTable": true},
+			err:   "watch mode cannot be used by a table",
+		},
+		{
+			name:  "interval",
+			props: map[string]interface{}{"path": dir, "watch": true, "interval": 1000},
+			err:   "watch mode cannot be used with interval",
+		},
+		{
+			name:  "settle",
+			props: map[string]interface{}{"path": dir, "watch": true, "settleInterval": 0},
+			err:   "settleInterval must be positive",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&FileSource{}).Configure(tt.file, tt.props)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
@@ -29,11 +29,13 @@ import (
 )
 
 type fileWriter struct {
-	File       *os.File
-	Writer     io.Writer
-	Hook       writerHooks
-	Start      time.Time
-	Count      int
+	File   *os.File
+	Writer io.Writer
+	Hook   writerHooks
+	Start  time.Time
+	Count  int
+	// Size is the bytes written before compression
+	Size       int64
 	Compress   string
 	fileBuffer *writer.BufioWrapWriter
 	// Whether the file has written any data. It is only used to determine if new line is needed when writing data.
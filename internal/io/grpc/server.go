@@ -0,0 +1,188 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/converter/protobuf"
+	"github.com/lf-edge/ekuiper/internal/io/memory/pubsub"
+	kctx "github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+// manage the global grpc data server. The methods are not registered to the server as generated services, instead
+// all calls are handled by the unknown service handler and dispatched by the full method name, so that the endpoints
+// can be added and removed while the server is running.
+
+const TopicPrefix = "$$grpc/"
+
+type endpoint struct {
+	md       *desc.MethodDescriptor
+	refCount int
+}
+
+var (
+	lock      sync.Mutex
+	server    *grpc.Server
+	endpoints = make(map[string]*endpoint)
+	done      chan struct{}
+	sctx      api.StreamContext
+	mf        = dynamic.NewMessageFactoryWithDefaults()
+)
+
+func init() {
+	contextLogger := conf.Log.WithField("grpc_connection", 0)
+	sctx = kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
+}
+
+// FullMethod returns the grpc method name like /helloworld.Greeter/SayHello
+func FullMethod(md *desc.MethodDescriptor) string {
+	return fmt.Sprintf("/%s/%s", md.GetService().GetFullyQualifiedName(), md.GetName())
+}
+
+// RegisterEndpoint starts the server if needed and returns the topic of the method to subscribe. The same method can
+// be registered several times and the data is sent to all the subscribers of the topic.
+func RegisterEndpoint(md *desc.MethodDescriptor) (string, chan struct{}, error) {
+	lock.Lock()
+	defer lock.Unlock()
+	if md.IsServerStreaming() {
+		return "", nil, fmt.Errorf("server streaming method %s is not supported", md.GetName())
+	}
+	if server == nil {
+		if err := createDataServer(); err != nil {
+			return "", nil, err
+		}
+	}
+	name := FullMethod(md)
+	topic := TopicPrefix + name
+	if ep, ok := endpoints[name]; ok {
+		ep.refCount++
+	} else {
+		endpoints[name] = &endpoint{md: md, refCount: 1}
+		pubsub.CreatePub(topic)
+	}
+	return topic, done, nil
+}
+
+func UnregisterEndpoint(md *desc.MethodDescriptor) {
+	lock.Lock()
+	defer lock.Unlock()
+	name := FullMethod(md)
+	ep, ok := endpoints[name]
+	if !ok {
+		return
+	}
+	ep.refCount--
+	if ep.refCount > 0 {
+		return
+	}
+	delete(endpoints, name)
+	pubsub.RemovePub(TopicPrefix + name)
+	if len(endpoints) == 0 {
+		shutdown()
+	}
+}
+
+func getEndpoint(name string) *endpoint {
+	lock.Lock()
+	defer lock.Unlock()
+	return endpoints[name]
+}
+
+// handle receives the messages of a unary or client streaming call and replies an empty output message
+func handle(_ interface{}, stream grpc.ServerStream) error {
+	name, ok := grpc.MethodFromServerStream(stream)
+	if !ok {
+		return status.Error(codes.Internal, "fail to get the method")
+	}
+	ep := getEndpoint(name)
+	if ep == nil {
+		return status.Errorf(codes.Unimplemented, "method %s is not found", name)
+	}
+	it := ep.md.GetInputType()
+	fc := protobuf.GetFieldConverter()
+	for {
+		msg := mf.NewDynamicMessage(it)
+		if err := stream.RecvMsg(msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+		data, ok := fc.DecodeMessage(msg, it).(map[string]interface{})
+		if !ok {
+			return status.Errorf(codes.InvalidArgument, "input type %s of method %s must be a message", it.GetName(), name)
+		}
+		sctx.GetLogger().Debugf("grpc server received message %v", data)
+		pubsub.Produce(sctx, TopicPrefix+name, data)
+		if !ep.md.IsClientStreaming() {
+			break
+		}
+	}
+	return stream.SendMsg(mf.NewDynamicMessage(ep.md.GetOutputType()))
+}
+
+// shutdown stops the server without waiting for the running handlers because they may wait for the lock. Must run
+// inside lock
+func shutdown() {
+	sctx.GetLogger().Infof("shutting down grpc data server...")
+	if server != nil {
+		server.Stop()
+		sctx.GetLogger().Infof("grpc data server exiting")
+	}
+	server = nil
+}
+
+// createDataServer creates a new grpc data server. Must run inside lock
+func createDataServer() error {
+	addr := cast.JoinHostPortInt(conf.Config.Source.GrpcServerIp, conf.Config.Source.GrpcServerPort)
+	opts := []grpc.ServerOption{grpc.UnknownServiceHandler(handle)}
+	if tc := conf.Config.Source.GrpcServerTls; tc != nil {
+		creds, err := credentials.NewServerTLSFromFile(tc.Certfile, tc.Keyfile)
+		if err != nil {
+			return fmt.Errorf("fail to load the grpc server certificate: %v", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("fail to listen grpc data server on %s: %v", addr, err)
+	}
+	s := grpc.NewServer(opts...)
+	done = make(chan struct{})
+	go func(done chan struct{}) {
+		if err := s.Serve(lis); err != nil {
+			sctx.GetLogger().Errorf("grpc data server error: %v", err)
+			close(done)
+		}
+	}(done)
+	server = s
+	sctx.GetLogger().Infof("Serving grpc data server on %s", addr)
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"fmt"
+
+	"github.com/jhump/protoreflect/desc"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/io/memory/pubsub"
+	"github.com/lf-edge/ekuiper/internal/service/protoschema"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"github.com/lf-edge/ekuiper/pkg/infra"
+)
+
+type SourceConf struct {
+	// SchemaFile is the proto file in the schemas folder of the service manager
+	SchemaFile string `json:"schemaFile"`
+	// Service is optional if the method name is unique in the schema file
+	Service      string `json:"service"`
+	BufferLength int    `json:"bufferLength"`
+	Method       string `json:"method"`
+}
+
+// Source receives the messages pushed to a method of the global grpc data server. The datasource is the method name.
+type Source struct {
+	conf *SourceConf
+	md   *desc.MethodDescriptor
+}
+
+func (s *Source) Configure(method string, props map[string]interface{}) error {
+	cfg := &SourceConf{
+		BufferLength: 1024,
+	}
+	err := cast.MapToStruct(props, cfg)
+	if err != nil {
+		return err
+	}
+	if cfg.SchemaFile == "" {
+		return fmt.Errorf("property `schemaFile` is required")
+	}
+	if method == "" {
+		return fmt.Errorf("datasource is required to specify the method name")
+	}
+	md, err := protoschema.FindMethod(cfg.SchemaFile, cfg.Service, method)
+	if err != nil {
+		return err
+	}
+	if md.IsServerStreaming() {
+		return fmt.Errorf("server streaming method %s is not supported", method)
+	}
+	if cfg.BufferLength <= 0 {
+		return fmt.Errorf("property `bufferLength` must be positive")
+	}
+	cfg.Method = method
+	s.conf = cfg
+	s.md = md
+	conf.Log.Debugf("Initialized with configurations %#v.", cfg)
+	return nil
+}
+
+func (s *Source) Open(ctx api.StreamContext, consumer chan<- api.SourceTuple, errCh chan<- error) {
+	t, done, err := RegisterEndpoint(s.md)
+	if err != nil {
+		infra.DrainError(ctx, err, errCh)
+		return
+	}
+	defer UnregisterEndpoint(s.md)
+	id := fmt.Sprintf("%s_%s_%d", ctx.GetRuleId(), ctx.GetOpId(), ctx.GetInstanceId())
+	ch := pubsub.CreateSub(t, nil, id, s.conf.BufferLength)
+	defer pubsub.CloseSourceConsumerChannel(t, id)
+	ctx.GetLogger().Infof("grpc source receives the messages of %s", FullMethod(s.md))
+	for {
+		select {
+		case <-done: // grpc data server error
+			infra.DrainError(ctx, fmt.Errorf("grpc data server shutdown"), errCh)
+			return
+		case v, opened := <-ch:
+			if !opened {
+				return
+			}
+			consumer <- v
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Source) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Closing grpc source")
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/jhump/protoreflect/dynamic/grpcdynamic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/service/protoschema"
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	mockContext "github.com/lf-edge/ekuiper/pkg/mock/context"
+)
+
+func TestConfigure(t *testing.T) {
+	testx.InitEnv("grpc")
+	tests := []struct {
+		method string
+		props  map[string]interface{}
+		err    string
+	}{
+		{
+			method: "SayHello",
+			props:  map[string]interface{}{},
+			err:    "property `schemaFile` is required",
+		},
+		{
+			method: "",
+			props:  map[string]interface{}{"schemaFile": "hw.proto"},
+			err:    "datasource is required to specify the method name",
+		},
+		{
+			method: "SayBye",
+			props:  map[string]interface{}{"schemaFile": "hw.proto"},
+			err:    "method SayBye not found in schema file hw.proto",
+		},
+		{
+			method: "SayHello",
+			props:  map[string]interface{}{"schemaFile": "hw.proto", "service": "Farewell"},
+			err:    "method SayHello of service Farewell not found in schema file hw.proto",
+		},
+	}
+	for _, tt := range tests {
+		s := &Source{}
+		assert.EqualError(t, s.Configure(tt.method, tt.props), tt.err)
+	}
+	s := &Source{}
+	require.NoError(t, s.Configure("SayHello", map[string]interface{}{"schemaFile": "hw.proto", "service": "helloworld.Greeter"}))
+	assert.Equal(t, "/helloworld.Greeter/SayHello", FullMethod(s.md))
+	assert.Equal(t, 1024, s.conf.BufferLength)
+}
+
+func TestSource(t *testing.T) {
+	testx.InitEnv("grpc")
+	conf.Config.Source.GrpcServerPort = 10182
+	s := &Source{}
+	require.NoError(t, s.Configure("SayHello", map[string]interface{}{"schemaFile": "hw.proto"}))
+	ctx, cancel := mockContext.NewMockContext("ruleGrpc", "op1").WithCancel()
+	consumer := make(chan api.SourceTuple, 10)
+	errCh := make(chan error, 1)
+	done := make(chan struct{})
+	go func() {
+		s.Open(ctx, consumer, errCh)
+		close(done)
+	}()
+
+	conn, err := grpc.NewClient("127.0.0.1:10182", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	stub := grpcdynamic.NewStub(conn)
+	req := dynamic.NewMessage(s.md.GetInputType())
+	req.SetFieldByName("name", "world")
+	// wait for the endpoint to be registered
+	for i := 0; i < 10; i++ {
+		_, err = stub.InvokeRpc(context.Background(), s.md, req)
+		if err == nil {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	require.NoError(t, err)
+	select {
+	case r := <-consumer:
+		assert.Equal(t, map[string]interface{}{"name": "world"}, r.Message())
+	case <-time.After(time.Second):
+		require.Fail(t, "timeout to receive data")
+	}
+
+	// the other methods are not served
+	md, err := protoschema.FindMethod("hw.proto", "", "Compute")
+	require.NoError(t, err)
+	_, err = stub.InvokeRpc(context.Background(), md, dynamic.NewMessage(md.GetInputType()))
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("source is not closed")
+	}
+	lock.Lock()
+	assert.Nil(t, server)
+	assert.Empty(t, endpoints)
+	lock.Unlock()
+}
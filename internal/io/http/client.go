@@ -31,6 +31,8 @@ import (
 	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/pkg/cert"
 	"github.com/lf-edge/ekuiper/internal/pkg/httpx"
+	"github.com/lf-edge/ekuiper/internal/pkg/netx"
+	"github.com/lf-edge/ekuiper/internal/pkg/oauth2"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/cast"
 	"github.com/lf-edge/ekuiper/pkg/message"
@@ -63,12 +65,23 @@ type RawConf struct {
 	// Could be code or body
 	ResponseType string                            `json:"responseType"`
 	OAuth        map[string]map[string]interface{} `json:"oauth"`
+	// OAuth2Selector is the name of the shared oauth2 client credentials configuration in connection.yaml
+	OAuth2Selector string `json:"oauth2Selector"`
 	// source specific properties
 	Interval    int    `json:"interval"`
 	Incremental bool   `json:"incremental"`
 	ResendUrl   string `json:"resendDestination"`
+	// PredicateParams maps the stream fields to the url query params. The equality conditions of the rule on the
+	// fields are sent by the params for the server to filter the data
+	PredicateParams map[string]string `json:"predicateParams"`
+	// PredicateEquals are the equality conditions of the rule which are set by the rule planner
+	PredicateEquals map[string]interface{} `json:"predicateEquals"`
 	// sink specific properties
 	SendSingle bool `json:"sendSingle"`
+	// ItemStatusPath is the json path to get the status array of each item from the response of a batch
+	ItemStatusPath string `json:"itemStatusPath"`
+	// ItemSuccessValues are the status values of the successful items, compared as string
+	ItemSuccessValues []interface{} `json:"itemSuccessValues"`
 	// inferred properties
 	HeadersTemplate string
 	HeadersMap      map[string]string
@@ -110,10 +123,12 @@ type WithClientConfOption func(clientConf *ClientConfOption)
 // set in an init() call
 var newTransport = getTransport
 
-func getTransport(tlscfg *tls.Config, logger *logrus.Logger) *http.Transport {
-	return &http.Transport{
+func getTransport(tlscfg *tls.Config, dialer *netx.Dialer, logger *logrus.Logger) *http.Transport {
+	tr := &http.Transport{
 		TLSClientConfig: tlscfg,
 	}
+	dialer.ApplyTransport(tr)
+	return tr
 }
 
 func WithCheckInterval(checkInterval bool) WithClientConfOption {
@@ -228,7 +243,17 @@ func (cc *ClientConf) InitConf(device string, props map[string]interface{}, with
 		}
 	}
 
-	tr := newTransport(tlscfg, conf.Log)
+	if c.OAuth2Selector != "" {
+		if err := oauth2.Validate(c.OAuth2Selector); err != nil {
+			return err
+		}
+	}
+
+	dialer, err := netx.NewDialer(props)
+	if err != nil {
+		return err
+	}
+	tr := newTransport(tlscfg, dialer, conf.Log)
 
 	cc.client = &http.Client{
 		Transport: tr,
@@ -356,6 +381,14 @@ func (cc *ClientConf) parseHeaders(ctx api.StreamContext, data interface{}) (map
 			return nil, fmt.Errorf("parsed header template is not json: %s", tstr)
 		}
 	}
+	if cc.config.OAuth2Selector != "" {
+		if _, ok := headers["Authorization"]; !ok {
+			headers["Authorization"], err = oauth2.AuthHeader(cc.config.OAuth2Selector)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
 	return headers, nil
 }
 
@@ -384,6 +417,10 @@ func (cc *ClientConf) responseBodyDecompress(ctx api.StreamContext, resp *http.R
 // parse the response status. For rest sink, it will not return the body by default if not need to debug
 func (cc *ClientConf) parseResponse(ctx api.StreamContext, resp *http.Response, returnBody bool, omd5 *string, skipDecompression bool) ([]map[string]interface{}, []byte, error) {
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if resp.StatusCode == http.StatusUnauthorized && cc.config.OAuth2Selector != "" {
+			// the shared token may be revoked, fetch a new one next time
+			oauth2.Invalidate(cc.config.OAuth2Selector)
+		}
 		c, err := io.ReadAll(resp.Body)
 		if err != nil {
 			return nil, []byte("fail to read body"),
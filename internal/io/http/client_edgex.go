@@ -29,6 +29,7 @@ import (
 
 	"github.com/lf-edge/ekuiper/internal/conf"
 	edgex_vault "github.com/lf-edge/ekuiper/internal/edgex"
+	"github.com/lf-edge/ekuiper/internal/pkg/netx"
 )
 
 func init() {
@@ -45,7 +46,7 @@ func (d *ZitiUnderlayDialer) Dial(network, address string) (net.Conn, error) {
 	return netDialer.Dial(network, address)
 }
 
-func newZeroTrustTransport(tlscfg *tls.Config, logger *logrus.Logger) *http.Transport {
+func newZeroTrustTransport(tlscfg *tls.Config, dialer *netx.Dialer, logger *logrus.Logger) *http.Transport {
 	if conf.Config != nil && conf.Config.Basic.EnableOpenZiti == true {
 		logger.Info("using Transport 'zerotrust'")
 		// attempt to locate an existing client for this existing token
@@ -66,6 +67,6 @@ func newZeroTrustTransport(tlscfg *tls.Config, logger *logrus.Logger) *http.Tran
 			return zitiTransport
 		}
 	} else {
-		return getTransport(tlscfg, logger)
+		return getTransport(tlscfg, dialer, logger)
 	}
 }
@@ -16,6 +16,7 @@ package http
 
 import (
 	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/lf-edge/ekuiper/internal/conf"
@@ -34,11 +35,42 @@ type PullSource struct {
 	ClientConf
 
 	t *pullTimeMeta
+	// the query params of the pushed down conditions
+	predicateQuery url.Values
 }
 
 func (hps *PullSource) Configure(device string, props map[string]interface{}) error {
 	conf.Log.Infof("Initialized Httppull source with configurations %#v.", props)
-	return hps.InitConf(device, props, WithCheckInterval(true))
+	if err := hps.InitConf(device, props, WithCheckInterval(true)); err != nil {
+		return err
+	}
+	hps.predicateQuery = nil
+	for field, param := range hps.config.PredicateParams {
+		if v, ok := hps.config.PredicateEquals[field]; ok {
+			if hps.predicateQuery == nil {
+				hps.predicateQuery = url.Values{}
+			}
+			hps.predicateQuery.Set(param, fmt.Sprintf("%v", v))
+		}
+	}
+	return nil
+}
+
+// addPredicateQuery appends the query params of the pushed down conditions to the url
+func (hps *PullSource) addPredicateQuery(rawUrl string) (string, error) {
+	if len(hps.predicateQuery) == 0 {
+		return rawUrl, nil
+	}
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	for k, v := range hps.predicateQuery {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
 }
 
 func (hps *PullSource) Open(ctx api.StreamContext, consumer chan<- api.SourceTuple, errCh chan<- error) {
@@ -97,6 +129,9 @@ func (hps *PullSource) doPull(ctx api.StreamContext, rcvTime time.Time, omd5 *st
 	tempProps["PullTime"] = hps.t.PullTime
 	// Parse url which may contain dynamic time range
 	url, err := ctx.ParseTemplate(hps.config.Url, tempProps)
+	if err == nil {
+		url, err = hps.addPredicateQuery(url)
+	}
 	if err != nil {
 		return []api.SourceTuple{
 			&xsql.ErrorSourceTuple{
@@ -910,6 +910,38 @@ func TestPullUrlTimeRange(t *testing.T) {
 	mock.TestSourceOpen(r, exp, t)
 }
 
+func TestPullPredicateParams(t *testing.T) {
+	r := &PullSource{}
+	server, closer := httptestx.MockAuthServer(
+		httptestx.WithBuiltinTestDataEndpoints(),
+	)
+	server.Start()
+	defer closer()
+	err := r.Configure("", map[string]interface{}{
+		"url":             "http://localhost:52345/data4?start={{.LastPullTime}}&end={{.PullTime}}",
+		"interval":        110,
+		"responseType":    "body",
+		"predicateParams": map[string]interface{}{"device_id": "device"},
+		"predicateEquals": map[string]interface{}{"device_id": "d2", "temperature": 20},
+	})
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	// Mock time
+	mockclock.ResetClock(143)
+	exp := []api.SourceTuple{
+		api.NewDefaultSourceTupleWithTime(map[string]interface{}{"code": float64(200), "data": map[string]interface{}{"device_id": "d2", "humidity": float64(43), "temperature": float64(33)}}, map[string]interface{}{}, time.UnixMilli(143)),
+		api.NewDefaultSourceTupleWithTime(map[string]interface{}{"code": float64(200), "data": map[string]interface{}{"device_id": "d2", "humidity": float64(53), "temperature": float64(43)}}, map[string]interface{}{}, time.UnixMilli(253)),
+	}
+	c := mockclock.GetMockClock()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		c.Add(350 * time.Millisecond)
+	}()
+	mock.TestSourceOpen(r, exp, t)
+}
+
 func TestPullBodyTimeRange(t *testing.T) {
 	r := &PullSource{}
 	server, closer := httptestx.MockAuthServer(
@@ -26,16 +26,43 @@ import (
 	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/pkg/httpx"
 	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
 	"github.com/lf-edge/ekuiper/pkg/errorx"
 )
 
 type RestSink struct {
 	ClientConf
+	itemStatus conf.JsonPathEval
+	// the status values of successful items
+	itemSuccess map[string]struct{}
 }
 
+var defaultItemSuccessValues = []interface{}{"true", "200", "201", "202", "204"}
+
 func (ms *RestSink) Validate(props map[string]interface{}) error {
 	conf.Log.Infof("valiadte rest sink with configurations %#v.", props)
-	return ms.InitConf("", props)
+	err := ms.InitConf("", props)
+	if err != nil {
+		return err
+	}
+	if ms.config.ItemStatusPath != "" {
+		if ms.config.SendSingle {
+			return fmt.Errorf("itemStatusPath is only supported when sendSingle is false")
+		}
+		ms.itemStatus, err = conf.GetJsonPathEval(ms.config.ItemStatusPath)
+		if err != nil {
+			return fmt.Errorf("invalid itemStatusPath %s: %v", ms.config.ItemStatusPath, err)
+		}
+		values := ms.config.ItemSuccessValues
+		if len(values) == 0 {
+			values = defaultItemSuccessValues
+		}
+		ms.itemSuccess = make(map[string]struct{}, len(values))
+		for _, v := range values {
+			ms.itemSuccess[cast.ToStringAlways(v)] = struct{}{}
+		}
+	}
+	return nil
 }
 
 func (ms *RestSink) Configure(ps map[string]interface{}) error {
@@ -83,7 +110,7 @@ func (ms *RestSink) collectWithUrl(ctx api.StreamContext, item interface{}, desU
 		)
 	} else {
 		logger.Debugf("rest sink got response %v", resp)
-		_, b, err := ms.parseResponse(ctx, resp, ms.config.DebugResp, nil, false)
+		_, b, err := ms.parseResponse(ctx, resp, ms.config.DebugResp || ms.itemStatus != nil, nil, false)
 		// do not record response body error as it is not an error in the sink action.
 		if err != nil && !strings.HasPrefix(err.Error(), BODY_ERR) {
 			if strings.HasPrefix(err.Error(), BODY_ERR) {
@@ -101,10 +128,44 @@ func (ms *RestSink) collectWithUrl(ctx api.StreamContext, item interface{}, desU
 		if ms.config.DebugResp {
 			logger.Infof("Response raw content: %s\n", string(b))
 		}
+		if ms.itemStatus != nil {
+			if items, ok := item.([]map[string]interface{}); ok {
+				failed, err := ms.failedItems(b, len(items))
+				if err != nil {
+					return fmt.Errorf(`parse item status error: %s. | method=%s path="%s" response_body="%s"`, err, ms.config.Method, ms.config.Url, b)
+				}
+				if len(failed) > 0 {
+					return errorx.NewPartialIOErr(fmt.Sprintf(`rest sink fails to send out %d of %d items: failed=%v method=%s path="%s" response_body="%s"`,
+						len(failed), len(items), failed, ms.config.Method, ms.config.Url, b), failed)
+				}
+			}
+		}
 	}
 	return nil
 }
 
+// failedItems gets the status of each item by the itemStatusPath and returns the indexes of the failed items
+func (ms *RestSink) failedItems(body []byte, size int) ([]int, error) {
+	r, err := ms.itemStatus.Eval(string(body))
+	if err != nil {
+		return nil, err
+	}
+	statuses, ok := r.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("item status must be an array but got %v", r)
+	}
+	if len(statuses) != size {
+		return nil, fmt.Errorf("got %d item statuses for %d items", len(statuses), size)
+	}
+	var failed []int
+	for i, s := range statuses {
+		if _, ok := ms.itemSuccess[cast.ToStringAlways(s)]; !ok {
+			failed = append(failed, i)
+		}
+	}
+	return failed, nil
+}
+
 func isRecoverAbleError(err error) bool {
 	return errorx.IsRestRecoverAbleError(err)
 }
@@ -715,3 +715,51 @@ func TestRestSinkMethod(t *testing.T) {
 	err := rs.Validate(map[string]interface{}{"method": "head"})
 	require.Error(t, err)
 }
+
+func TestRestSinkItemStatus(t *testing.T) {
+	contextLogger := conf.Log.WithField("rule", "TestRestSinkItemStatus")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var items []map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&items)
+		results := make([]map[string]interface{}, len(items))
+		for i, item := range items {
+			if item["ok"] == true {
+				results[i] = map[string]interface{}{"status": 200}
+			} else {
+				results[i] = map[string]interface{}{"status": 500}
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+	}))
+	defer ts.Close()
+
+	s := &RestSink{}
+	require.NoError(t, s.Configure(map[string]interface{}{
+		"url":            ts.URL,
+		"method":         "post",
+		"itemStatusPath": "$.results[*].status",
+	}))
+	require.NoError(t, s.Open(ctx))
+	vCtx := context.WithValue(ctx, context.TransKey, transform.TransFunc(func(d interface{}) ([]byte, bool, error) {
+		r, err := json.Marshal(d)
+		return r, true, err
+	}))
+	err := s.Collect(vCtx, []map[string]interface{}{{"ok": true}, {"ok": false}, {"ok": true}, {"ok": false}})
+	require.Error(t, err)
+	require.True(t, errorx.IsIOError(err))
+	failed, ok := errorx.GetFailedItems(err)
+	require.True(t, ok)
+	assert.Equal(t, []int{1, 3}, failed)
+
+	err = s.Collect(vCtx, []map[string]interface{}{{"ok": true}, {"ok": true}})
+	require.NoError(t, err)
+	require.NoError(t, s.Close(ctx))
+
+	err = s.Validate(map[string]interface{}{
+		"url":            ts.URL,
+		"sendSingle":     true,
+		"itemStatusPath": "$.results[*].status",
+	})
+	require.EqualError(t, err, "itemStatusPath is only supported when sendSingle is false")
+}
@@ -15,8 +15,11 @@
 package pubsub
 
 import (
+	"hash/fnv"
 	"regexp"
+	"sort"
 	"sync"
+	"sync/atomic"
 
 	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/xsql"
@@ -29,6 +32,10 @@ type pubConsumers struct {
 	count             int
 	consumers         map[string]chan api.SourceTuple // The consumer channel list [sourceId]chan
 	consumersReplaced map[string]int
+	// next is the round-robin counter to dispatch the messages without order key to the consumer groups
+	next uint64
+	// durables are the queues of the durable consumers which receive the messages even when offline [sourceId]queue
+	durables map[string]*queue
 }
 
 type subChan struct {
@@ -39,7 +46,11 @@ type subChan struct {
 var (
 	pubTopics = make(map[string]*pubConsumers)
 	subExps   = make(map[string]*subChan)
-	mu        = sync.RWMutex{}
+	// subGroups are the consumer groups of the sources [sourceId]group
+	subGroups = make(map[string]string)
+	// queues are the buffers of the durable subscriptions [sourceId]queue
+	queues = make(map[string]*queue)
+	mu     = sync.RWMutex{}
 )
 
 func CreatePub(topic string) {
@@ -63,8 +74,48 @@ func CreatePub(topic string) {
 }
 
 func CreateSub(wildcard string, regex *regexp.Regexp, sourceId string, bufferLength int) chan api.SourceTuple {
+	return CreateGroupSub(wildcard, regex, sourceId, "", bufferLength)
+}
+
+// CreateGroupSub creates a subscription in the consumer group. Instead of broadcasting, each message is sent to only one
+// source of a group. The messages with the same order key are always sent to the same source as long as the group
+// members do not change, so that their order is kept even if they are consumed by several rules in parallel.
+func CreateGroupSub(wildcard string, regex *regexp.Regexp, sourceId string, group string, bufferLength int) chan api.SourceTuple {
 	mu.Lock()
 	defer mu.Unlock()
+	if group != "" {
+		subGroups[sourceId] = group
+	} else {
+		delete(subGroups, sourceId)
+	}
+	// The subscription is not durable anymore
+	if q, ok := queues[sourceId]; ok {
+		dropQueue(sourceId, q)
+	}
+	return createSub(wildcard, regex, sourceId, bufferLength)
+}
+
+// CreateDurableSub creates a subscription whose messages are buffered in a bounded queue while the consumer is offline
+// or slow. When subscribing again with the same source id, the buffered messages are delivered first.
+func CreateDurableSub(wildcard string, regex *regexp.Regexp, sourceId string, bufferLength int, opts QueueOptions) chan api.SourceTuple {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(subGroups, sourceId)
+	q, ok := queues[sourceId]
+	if !ok {
+		q = newQueue(sourceId, opts)
+		queues[sourceId] = q
+	} else {
+		q.Lock()
+		q.opts.Size = opts.Size
+		q.Unlock()
+	}
+	ch := createSub(wildcard, regex, sourceId, bufferLength)
+	q.online(ch)
+	return ch
+}
+
+func createSub(wildcard string, regex *regexp.Regexp, sourceId string, bufferLength int) chan api.SourceTuple {
 	ch := make(chan api.SourceTuple, bufferLength)
 	if regex != nil {
 		subExps[sourceId] = &subChan{
@@ -86,6 +137,12 @@ func CloseSourceConsumerChannel(topic string, sourceId string) {
 	mu.Lock()
 	defer mu.Unlock()
 
+	// Keep the queue to buffer the messages while offline. If the consumer is replaced, the queue is delivering to the new one.
+	if q, ok := queues[sourceId]; ok {
+		if c, exists := pubTopics[topic]; !exists || c.consumersReplaced[sourceId] == 0 {
+			q.offline()
+		}
+	}
 	if sc, exists := subExps[sourceId]; exists {
 		close(sc.ch)
 		delete(subExps, sourceId)
@@ -97,6 +154,14 @@ func CloseSourceConsumerChannel(topic string, sourceId string) {
 			removePubConsumer(topic, sourceId, sinkConsumerChannels)
 		}
 	}
+	if _, exists := subGroups[sourceId]; exists {
+		for _, c := range pubTopics {
+			if _, ok := c.consumers[sourceId]; ok {
+				return
+			}
+		}
+		delete(subGroups, sourceId)
+	}
 }
 
 func RemovePub(topic string) {
@@ -105,45 +170,94 @@ func RemovePub(topic string) {
 
 	if sinkConsumerChannels, exists := pubTopics[topic]; exists {
 		sinkConsumerChannels.count -= 1
-		if len(sinkConsumerChannels.consumers) == 0 && sinkConsumerChannels.count == 0 {
+		if sinkConsumerChannels.isIdle() {
 			delete(pubTopics, topic)
 		}
 	}
 }
 
 func Produce(ctx api.StreamContext, topic string, data map[string]interface{}) {
-	doProduce(ctx, topic, api.NewDefaultSourceTupleWithTime(data, map[string]interface{}{"topic": topic}, conf.GetNow()))
+	ProduceWithKey(ctx, topic, data, "")
+}
+
+// ProduceWithKey produces the data with the order key. The consumer groups dispatch the data by the key.
+func ProduceWithKey(ctx api.StreamContext, topic string, data map[string]interface{}, key string) {
+	doProduce(ctx, topic, api.NewDefaultSourceTupleWithTime(data, map[string]interface{}{"topic": topic}, conf.GetNow()), key)
 }
 
 func ProduceUpdatable(ctx api.StreamContext, topic string, data map[string]interface{}, rowkind string, keyval interface{}) {
+	ProduceUpdatableWithKey(ctx, topic, data, rowkind, keyval, "")
+}
+
+func ProduceUpdatableWithKey(ctx api.StreamContext, topic string, data map[string]interface{}, rowkind string, keyval interface{}, key string) {
 	doProduce(ctx, topic, &UpdatableTuple{
 		DefaultSourceTuple: api.NewDefaultSourceTupleWithTime(data, map[string]interface{}{"topic": topic}, conf.GetNow()),
 		Rowkind:            rowkind,
 		Keyval:             keyval,
-	})
+	}, key)
 }
 
-func doProduce(ctx api.StreamContext, topic string, data api.SourceTuple) {
+func doProduce(ctx api.StreamContext, topic string, data api.SourceTuple, key string) {
 	c, exists := pubTopics[topic]
 	if !exists {
 		return
 	}
-	logger := ctx.GetLogger()
 	mu.RLock()
 	defer mu.RUnlock()
-	// broadcast to all consumers
+	var groups map[string][]string
+	// broadcast to all consumers without group
 	for name, out := range c.consumers {
-		select {
-		case out <- data:
-			logger.Debugf("memory source broadcast from topic %s to %s done", topic, name)
-		case <-ctx.Done():
-			// rule stop so stop waiting
-		default:
-			logger.Errorf("memory source topic %s drop message to %s", topic, name)
+		if _, ok := c.durables[name]; ok {
+			continue
+		}
+		if g, ok := subGroups[name]; ok {
+			if groups == nil {
+				groups = make(map[string][]string)
+			}
+			groups[g] = append(groups[g], name)
+			continue
 		}
+		send(ctx, topic, name, out, data)
+	}
+	// send to one consumer of each group
+	for _, members := range groups {
+		name := pickMember(c, members, key)
+		send(ctx, topic, name, c.consumers[name], data)
+	}
+	// buffer for the durable consumers whether online or not
+	for _, q := range c.durables {
+		q.push(data)
 	}
 }
 
+func send(ctx api.StreamContext, topic string, name string, out chan api.SourceTuple, data api.SourceTuple) {
+	select {
+	case out <- data:
+		ctx.GetLogger().Debugf("memory source broadcast from topic %s to %s done", topic, name)
+	case <-ctx.Done():
+		// rule stop so stop waiting
+	default:
+		ctx.GetLogger().Errorf("memory source topic %s drop message to %s", topic, name)
+	}
+}
+
+// pickMember picks the consumer by the hash of the order key. The messages without key are dispatched by round-robin.
+func pickMember(c *pubConsumers, members []string, key string) string {
+	if len(members) == 1 {
+		return members[0]
+	}
+	sort.Strings(members)
+	var i uint64
+	if key == "" {
+		i = atomic.AddUint64(&c.next, 1) - 1
+	} else {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(key))
+		i = uint64(h.Sum32())
+	}
+	return members[i%uint64(len(members))]
+}
+
 func ProduceError(ctx api.StreamContext, topic string, err error) {
 	c, exists := pubTopics[topic]
 	if !exists {
@@ -192,6 +306,12 @@ func addPubConsumer(topic string, sourceId string, ch chan api.SourceTuple) {
 		}
 	}
 	sinkConsumerChannels.consumers[sourceId] = ch
+	if q, ok := queues[sourceId]; ok {
+		if sinkConsumerChannels.durables == nil {
+			sinkConsumerChannels.durables = make(map[string]*queue)
+		}
+		sinkConsumerChannels.durables[sourceId] = q
+	}
 }
 
 func removePubConsumer(topic string, sourceId string, c *pubConsumers) {
@@ -205,13 +325,42 @@ func removePubConsumer(topic string, sourceId string, c *pubConsumers) {
 		}
 		delete(c.consumers, sourceId)
 	}
-	if len(c.consumers) == 0 && c.count == 0 {
+	if c.isIdle() {
 		delete(pubTopics, topic)
 	}
 }
 
+// isIdle checks if the topic has no producer, no consumer and no durable consumer to buffer
+func (c *pubConsumers) isIdle() bool {
+	return len(c.consumers) == 0 && c.count == 0 && len(c.durables) == 0
+}
+
+// DropQueues removes the queues of the durable subscriptions of the rule
+func DropQueues(ruleId string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for sourceId, q := range queues {
+		if q.opts.RuleId == ruleId {
+			dropQueue(sourceId, q)
+		}
+	}
+}
+
+func dropQueue(sourceId string, q *queue) {
+	q.offline()
+	delete(queues, sourceId)
+	for topic, c := range pubTopics {
+		delete(c.durables, sourceId)
+		if c.isIdle() {
+			delete(pubTopics, topic)
+		}
+	}
+}
+
 // Reset For testing only
 func Reset() {
 	pubTopics = make(map[string]*pubConsumers)
 	subExps = make(map[string]*subChan)
+	subGroups = make(map[string]string)
+	queues = make(map[string]*queue)
 }
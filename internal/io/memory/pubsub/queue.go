@@ -0,0 +1,267 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsub
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/kv"
+)
+
+const queueMetaKey = "meta"
+
+// QueueOptions are the options of a durable subscription. The messages are buffered in a bounded queue while the
+// consumer is offline or slow instead of being dropped.
+type QueueOptions struct {
+	// RuleId is the rule of the consumer. Its queues are dropped when the rule is deleted
+	RuleId string
+	// Size is the max count of the buffered messages. The oldest message is dropped when the queue is full
+	Size int
+	// Store persists the buffered messages to survive the restarts if set
+	Store kv.KeyValue
+}
+
+// queuedTuple is the serializable form of the buffered source tuple
+type queuedTuple struct {
+	Message   map[string]interface{} `json:"message"`
+	Meta      map[string]interface{} `json:"meta"`
+	Timestamp int64                  `json:"timestamp"`
+	Rowkind   string                 `json:"rowkind,omitempty"`
+	Keyval    interface{}            `json:"keyval,omitempty"`
+}
+
+func newQueuedTuple(t api.SourceTuple) *queuedTuple {
+	qt := &queuedTuple{Message: t.Message(), Meta: t.Meta(), Timestamp: t.Timestamp().UnixMilli()}
+	if ut, ok := t.(*UpdatableTuple); ok {
+		qt.Rowkind = ut.Rowkind
+		qt.Keyval = ut.Keyval
+	}
+	return qt
+}
+
+func (qt *queuedTuple) tuple() api.SourceTuple {
+	t := api.NewDefaultSourceTupleWithTime(qt.Message, qt.Meta, time.UnixMilli(qt.Timestamp))
+	if qt.Rowkind != "" {
+		return &UpdatableTuple{DefaultSourceTuple: t, Rowkind: qt.Rowkind, Keyval: qt.Keyval}
+	}
+	return t
+}
+
+type queueMeta struct {
+	Head int64
+	Tail int64
+}
+
+// queue buffers the messages of a durable consumer. When the consumer is online, a goroutine moves the messages from
+// the queue to the consumer channel in order.
+type queue struct {
+	sync.Mutex
+	id    string
+	opts  QueueOptions
+	items []*queuedTuple
+	// the sequences of the first and next items to persist them by keys
+	head   int64
+	tail   int64
+	notify chan struct{}
+	// online states
+	ch      chan api.SourceTuple
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func newQueue(id string, opts QueueOptions) *queue {
+	q := &queue{id: id, opts: opts, notify: make(chan struct{}, 1)}
+	if opts.Store != nil {
+		q.load()
+	}
+	return q
+}
+
+// load restores the persisted messages
+func (q *queue) load() {
+	m := &queueMeta{}
+	if ok, err := q.opts.Store.Get(queueMetaKey, m); !ok || err != nil {
+		return
+	}
+	for seq := m.Head; seq < m.Tail; seq++ {
+		var s string
+		if ok, err := q.opts.Store.Get(strconv.FormatInt(seq, 10), &s); !ok || err != nil {
+			continue
+		}
+		qt := &queuedTuple{}
+		if err := json.Unmarshal([]byte(s), qt); err != nil {
+			conf.Log.Warnf("memory queue %s drops the invalid persisted message %s: %v", q.id, s, err)
+			continue
+		}
+		q.items = append(q.items, qt)
+	}
+	q.head = m.Head
+	q.tail = m.Head + int64(len(q.items))
+	// rewrite to make the sequences continuous
+	for i, qt := range q.items {
+		q.save(q.head+int64(i), qt)
+	}
+	q.saveMeta()
+	conf.Log.Infof("memory queue %s restores %d messages", q.id, len(q.items))
+}
+
+func (q *queue) save(seq int64, qt *queuedTuple) {
+	if q.opts.Store == nil {
+		return
+	}
+	bs, err := json.Marshal(qt)
+	if err != nil {
+		conf.Log.Warnf("memory queue %s cannot persist message %v: %v", q.id, qt.Message, err)
+		return
+	}
+	if err := q.opts.Store.Set(strconv.FormatInt(seq, 10), string(bs)); err != nil {
+		conf.Log.Warnf("memory queue %s cannot persist message %v: %v", q.id, qt.Message, err)
+	}
+}
+
+func (q *queue) remove(seq int64) {
+	if q.opts.Store == nil {
+		return
+	}
+	_ = q.opts.Store.Delete(strconv.FormatInt(seq, 10))
+}
+
+func (q *queue) saveMeta() {
+	if q.opts.Store == nil {
+		return
+	}
+	if err := q.opts.Store.Set(queueMetaKey, &queueMeta{Head: q.head, Tail: q.tail}); err != nil {
+		conf.Log.Warnf("memory queue %s cannot persist the meta: %v", q.id, err)
+	}
+}
+
+// push appends the message. If the queue is full, the oldest message is dropped.
+func (q *queue) push(t api.SourceTuple) {
+	q.Lock()
+	if q.opts.Size > 0 && len(q.items) >= q.opts.Size {
+		conf.Log.Warnf("memory queue %s is full, drop the oldest message", q.id)
+		q.items[0] = nil
+		q.items = q.items[1:]
+		q.remove(q.head)
+		q.head++
+	}
+	qt := newQueuedTuple(t)
+	q.items = append(q.items, qt)
+	q.save(q.tail, qt)
+	q.tail++
+	q.saveMeta()
+	q.Unlock()
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// pushFront puts back the messages which are not consumed to the head in order
+func (q *queue) pushFront(items ...*queuedTuple) {
+	if len(items) == 0 {
+		return
+	}
+	q.Lock()
+	defer q.Unlock()
+	q.items = append(items, q.items...)
+	q.head -= int64(len(items))
+	for i, qt := range items {
+		q.save(q.head+int64(i), qt)
+	}
+	q.saveMeta()
+}
+
+func (q *queue) pop() (*queuedTuple, bool) {
+	q.Lock()
+	defer q.Unlock()
+	if len(q.items) == 0 {
+		return nil, false
+	}
+	qt := q.items[0]
+	q.items[0] = nil
+	q.items = q.items[1:]
+	q.remove(q.head)
+	q.head++
+	q.saveMeta()
+	return qt, true
+}
+
+func (q *queue) len() int {
+	q.Lock()
+	defer q.Unlock()
+	return len(q.items)
+}
+
+// online starts to deliver the messages to the consumer channel
+func (q *queue) online(ch chan api.SourceTuple) {
+	q.offline()
+	q.ch = ch
+	q.done = make(chan struct{})
+	q.stopped = make(chan struct{})
+	go q.run(ch, q.done, q.stopped)
+}
+
+func (q *queue) run(ch chan api.SourceTuple, done chan struct{}, stopped chan struct{}) {
+	defer close(stopped)
+	for {
+		qt, ok := q.pop()
+		if !ok {
+			select {
+			case <-q.notify:
+				continue
+			case <-done:
+				return
+			}
+		}
+		select {
+		case ch <- qt.tuple():
+		case <-done:
+			q.pushFront(qt)
+			return
+		}
+	}
+}
+
+// offline stops the delivery and puts back the messages which are still in the consumer channel
+func (q *queue) offline() {
+	if q.done == nil {
+		return
+	}
+	close(q.done)
+	<-q.stopped
+	var rest []*queuedTuple
+	for {
+		select {
+		case t := <-q.ch:
+			if _, ok := t.(*xsql.ErrorSourceTuple); !ok {
+				rest = append(rest, newQueuedTuple(t))
+			}
+			continue
+		default:
+		}
+		break
+	}
+	q.pushFront(rest...)
+	q.ch = nil
+	q.done = nil
+	q.stopped = nil
+}
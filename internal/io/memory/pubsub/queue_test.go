@@ -0,0 +1,108 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+func receive(t *testing.T, ch chan api.SourceTuple, n int) []interface{} {
+	var result []interface{}
+	for i := 0; i < n; i++ {
+		select {
+		case v := <-ch:
+			result = append(result, v.Message()["id"])
+		case <-time.After(time.Second):
+			t.Fatalf("expect %d messages but got %v", n, result)
+		}
+	}
+	return result
+}
+
+func TestDurableSub(t *testing.T) {
+	Reset()
+	ctx, cancel := context.WithValue(context.Background(), context.LoggerKey, conf.Log).WithCancel()
+	defer cancel()
+	CreatePub("durable")
+	defer RemovePub("durable")
+	ch := CreateDurableSub("durable", nil, "r1_op_0", 10, QueueOptions{RuleId: "r1", Size: 3})
+	Produce(ctx, "durable", map[string]interface{}{"id": 1})
+	assert.Equal(t, []interface{}{1}, receive(t, ch, 1))
+	// buffered while the consumer is offline and the oldest one is dropped when full
+	Produce(ctx, "durable", map[string]interface{}{"id": 2})
+	CloseSourceConsumerChannel("durable", "r1_op_0")
+	for i := 3; i <= 6; i++ {
+		Produce(ctx, "durable", map[string]interface{}{"id": i})
+	}
+	assert.Equal(t, 3, queues["r1_op_0"].len())
+	ch = CreateDurableSub("durable", nil, "r1_op_0", 10, QueueOptions{RuleId: "r1", Size: 3})
+	assert.Equal(t, []interface{}{4, 5, 6}, receive(t, ch, 3))
+	Produce(ctx, "durable", map[string]interface{}{"id": 7})
+	assert.Equal(t, []interface{}{7}, receive(t, ch, 1))
+	// a normal subscription does not buffer
+	CloseSourceConsumerChannel("durable", "r1_op_0")
+	ch2 := CreateSub("durable", nil, "r2_op_0", 10)
+	CloseSourceConsumerChannel("durable", "r2_op_0")
+	Produce(ctx, "durable", map[string]interface{}{"id": 8})
+	assert.Len(t, ch2, 0)
+	DropQueues("r1")
+	assert.Len(t, queues, 0)
+	assert.Len(t, pubTopics["durable"].durables, 0)
+}
+
+func TestDurableSubPersist(t *testing.T) {
+	Reset()
+	testx.InitEnv("pubsub")
+	ctx, cancel := context.WithValue(context.Background(), context.LoggerKey, conf.Log).WithCancel()
+	defer cancel()
+	db, err := store.GetCacheKV("source/r3/op/memory/0")
+	require.NoError(t, err)
+	require.NoError(t, db.Clean())
+	CreatePub("persist")
+	defer RemovePub("persist")
+	_ = CreateDurableSub("persist", nil, "r3_op_0", 10, QueueOptions{RuleId: "r3", Size: 10, Store: db})
+	CloseSourceConsumerChannel("persist", "r3_op_0")
+	Produce(ctx, "persist", map[string]interface{}{"id": 1})
+	ProduceUpdatable(ctx, "persist", map[string]interface{}{"id": 2}, "delete", 2)
+	// restart
+	Reset()
+	CreatePub("persist")
+	ch := CreateDurableSub("persist", nil, "r3_op_0", 10, QueueOptions{RuleId: "r3", Size: 10, Store: db})
+	var result []api.SourceTuple
+	for i := 0; i < 2; i++ {
+		select {
+		case v := <-ch:
+			result = append(result, v)
+		case <-time.After(time.Second):
+			t.Fatal("no message restored")
+		}
+	}
+	assert.Equal(t, map[string]interface{}{"id": float64(1)}, result[0].Message())
+	ut, ok := result[1].(*UpdatableTuple)
+	require.True(t, ok)
+	assert.Equal(t, "delete", ut.Rowkind)
+	assert.Equal(t, float64(2), ut.Keyval)
+	DropQueues("r3")
+}
@@ -0,0 +1,68 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsub
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+var (
+	// topicSchemas is the result schema of the producing rules of the memory topics [topic][ruleId]schema
+	topicSchemas = make(map[string]map[string]ast.StreamFields)
+	schemaMu     = sync.RWMutex{}
+)
+
+// RegisterSchema registers the result schema of the rule which publishes to the topic
+func RegisterSchema(ruleId string, topic string, schema ast.StreamFields) {
+	schemaMu.Lock()
+	defer schemaMu.Unlock()
+	rs, ok := topicSchemas[topic]
+	if !ok {
+		rs = make(map[string]ast.StreamFields)
+		topicSchemas[topic] = rs
+	}
+	rs[ruleId] = schema
+}
+
+// DropSchemas removes all the schemas registered by the rule
+func DropSchemas(ruleId string) {
+	schemaMu.Lock()
+	defer schemaMu.Unlock()
+	for topic, rs := range topicSchemas {
+		delete(rs, ruleId)
+		if len(rs) == 0 {
+			delete(topicSchemas, topic)
+		}
+	}
+}
+
+// GetSchema returns the registered schema of the topic.
+// Return nil if no schema is registered or the producing rules register different schemas.
+func GetSchema(topic string) ast.StreamFields {
+	schemaMu.RLock()
+	defer schemaMu.RUnlock()
+	var result ast.StreamFields
+	for _, s := range topicSchemas[topic] {
+		if result == nil {
+			result = s
+		} else if !reflect.DeepEqual(result, s) {
+			return nil
+		}
+	}
+	return result
+}
@@ -34,6 +34,8 @@ type config struct {
 	Fields       []string `json:"fields"`
 	DataField    string   `json:"dataField"`
 	ResendTopic  string   `json:"resendDestination"`
+	// OrderKeyField is the field whose value is the order key to dispatch the data to the consumer groups
+	OrderKeyField string `json:"orderKeyField"`
 }
 
 type sink struct {
@@ -44,6 +46,7 @@ type sink struct {
 	fields       []string
 	dataField    string
 	resendTopic  string
+	orderKey     string
 }
 
 func (s *sink) Open(ctx api.StreamContext) error {
@@ -72,6 +75,7 @@ func (s *sink) Configure(props map[string]interface{}) error {
 	if s.rowkindField != "" && s.keyField == "" {
 		return fmt.Errorf("keyField is required when rowkindField is set")
 	}
+	s.orderKey = cfg.OrderKeyField
 	s.resendTopic = cfg.ResendTopic
 	if s.resendTopic == "" {
 		s.resendTopic = s.topic
@@ -138,6 +142,12 @@ func (s *sink) Close(ctx api.StreamContext) error {
 }
 
 func (s *sink) publish(ctx api.StreamContext, topic string, el map[string]interface{}) error {
+	var orderKey string
+	if s.orderKey != "" {
+		if v, ok := el[s.orderKey]; ok && v != nil {
+			orderKey = cast.ToStringAlways(v)
+		}
+	}
 	if s.rowkindField != "" {
 		c, ok := el[s.rowkindField]
 		var rowkind string
@@ -156,9 +166,9 @@ func (s *sink) publish(ctx api.StreamContext, topic string, el map[string]interf
 		if !ok {
 			return fmt.Errorf("key field %s not found in data %v", s.keyField, el)
 		}
-		pubsub.ProduceUpdatable(ctx, topic, el, rowkind, key)
+		pubsub.ProduceUpdatableWithKey(ctx, topic, el, rowkind, key, orderKey)
 	} else {
-		pubsub.Produce(ctx, topic, el)
+		pubsub.ProduceWithKey(ctx, topic, el, orderKey)
 	}
 	return nil
 }
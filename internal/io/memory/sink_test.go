@@ -20,6 +20,8 @@ import (
 	"testing"
 
 	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/io/memory/pubsub"
@@ -86,3 +88,40 @@ func TestUpdate(t *testing.T) {
 		t.Errorf("expect %v but got %v", expects, actual)
 	}
 }
+
+func TestOrderKey(t *testing.T) {
+	contextLogger := conf.Log.WithField("rule", "TestOrderKey")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger)
+	ms := GetSink()
+	require.NoError(t, ms.Configure(map[string]interface{}{"topic": "testorder", "orderKeyField": "id"}))
+	require.NoError(t, ms.Open(ctx))
+	// Two sources in a group share the messages and another source without group receives all
+	g1 := pubsub.CreateGroupSub("testorder", nil, "groupSource1", "g", 100)
+	g2 := pubsub.CreateGroupSub("testorder", nil, "groupSource2", "g", 100)
+	all := pubsub.CreateSub("testorder", nil, "allSource", 100)
+	for i := 0; i < 20; i++ {
+		require.NoError(t, ms.Collect(ctx, map[string]interface{}{"id": i % 4, "seq": i}))
+	}
+	assert.Len(t, all, 20)
+	assert.Equal(t, 20, len(g1)+len(g2))
+	// The messages of a key are all sent to the same source in order
+	owners := make(map[interface{}]string)
+	lastSeq := make(map[interface{}]int)
+	for name, ch := range map[string]chan api.SourceTuple{"groupSource1": g1, "groupSource2": g2} {
+		for len(ch) > 0 {
+			m := (<-ch).Message()
+			id := m["id"]
+			if o, ok := owners[id]; ok {
+				assert.Equal(t, o, name)
+				assert.Greater(t, m["seq"], lastSeq[id])
+			}
+			owners[id] = name
+			lastSeq[id] = m["seq"].(int)
+		}
+	}
+	assert.Len(t, owners, 4)
+	pubsub.CloseSourceConsumerChannel("testorder", "groupSource1")
+	pubsub.CloseSourceConsumerChannel("testorder", "groupSource2")
+	pubsub.CloseSourceConsumerChannel("testorder", "allSource")
+	require.NoError(t, ms.Close(ctx))
+}
@@ -16,22 +16,48 @@ package memory
 
 import (
 	"fmt"
+	"path"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/lf-edge/ekuiper/internal/io/memory/pubsub"
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/cast"
+	"github.com/lf-edge/ekuiper/pkg/infra"
 )
 
+const defaultQueueSize = 1000
+
 type source struct {
 	topic        string
 	topicRegex   *regexp.Regexp
 	bufferLength int
+	group        string
+	// qos at least once buffers the messages in a queue while the rule is stopped or slow
+	qos       api.Qos
+	queueSize int
+	persist   bool
 }
 
-func (s *source) Open(ctx api.StreamContext, consumer chan<- api.SourceTuple, _ chan<- error) {
-	ch := pubsub.CreateSub(s.topic, s.topicRegex, fmt.Sprintf("%s_%s_%d", ctx.GetRuleId(), ctx.GetOpId(), ctx.GetInstanceId()), s.bufferLength)
+func (s *source) Open(ctx api.StreamContext, consumer chan<- api.SourceTuple, errCh chan<- error) {
+	var ch chan api.SourceTuple
+	sourceId := fmt.Sprintf("%s_%s_%d", ctx.GetRuleId(), ctx.GetOpId(), ctx.GetInstanceId())
+	if s.qos >= api.AtLeastOnce {
+		opts := pubsub.QueueOptions{RuleId: ctx.GetRuleId(), Size: s.queueSize}
+		if s.persist {
+			db, err := store.GetCacheKV(path.Join("source", ctx.GetRuleId(), ctx.GetOpId(), "memory", strconv.Itoa(ctx.GetInstanceId())))
+			if err != nil {
+				infra.DrainError(ctx, fmt.Errorf("fail to open the memory queue store: %v", err), errCh)
+				return
+			}
+			opts.Store = db
+		}
+		ch = pubsub.CreateDurableSub(s.topic, s.topicRegex, sourceId, s.bufferLength, opts)
+	} else {
+		ch = pubsub.CreateGroupSub(s.topic, s.topicRegex, sourceId, s.group, s.bufferLength)
+	}
 	for {
 		select {
 		case v, opened := <-ch:
@@ -53,6 +79,34 @@ func (s *source) Configure(datasource string, props map[string]interface{}) erro
 			s.bufferLength = bl
 		}
 	}
+	if g, ok := props["group"]; ok {
+		s.group = cast.ToStringAlways(g)
+	}
+	s.queueSize = defaultQueueSize
+	if c, ok := props["qos"]; ok {
+		q, err := cast.ToInt(c, cast.CONVERT_SAMEKIND)
+		if err != nil || q < int(api.AtMostOnce) || q > int(api.AtLeastOnce) {
+			return fmt.Errorf("invalid qos %v, it must be 0 or 1", c)
+		}
+		s.qos = api.Qos(q)
+	}
+	if c, ok := props["queueSize"]; ok {
+		qs, err := cast.ToInt(c, cast.CONVERT_SAMEKIND)
+		if err != nil || qs <= 0 {
+			return fmt.Errorf("invalid queueSize %v, it must be a positive integer", c)
+		}
+		s.queueSize = qs
+	}
+	if c, ok := props["persist"]; ok {
+		p, err := cast.ToBool(c, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return fmt.Errorf("invalid persist %v: %v", c, err)
+		}
+		s.persist = p
+	}
+	if s.qos >= api.AtLeastOnce && s.group != "" {
+		return fmt.Errorf("qos 1 is not supported for the consumer group")
+	}
 	if strings.ContainsAny(datasource, "+#") {
 		r, err := getRegexp(datasource)
 		if err != nil {
@@ -20,6 +20,7 @@ import (
 	"testing"
 
 	"github.com/lf-edge/ekuiper/internal/testx"
+	"github.com/lf-edge/ekuiper/pkg/api"
 )
 
 func TestTopic(t *testing.T) {
@@ -88,3 +89,45 @@ func TestTopic(t *testing.T) {
 		}
 	}
 }
+
+func TestSourceConfigureQos(t *testing.T) {
+	tests := []struct {
+		props map[string]interface{}
+		exp   *source
+		err   string
+	}{
+		{
+			props: map[string]interface{}{"qos": 1, "persist": true},
+			exp:   &source{topic: "demo", bufferLength: 1024, qos: api.AtLeastOnce, queueSize: 1000, persist: true},
+		},
+		{
+			props: map[string]interface{}{"qos": 1, "queueSize": 10},
+			exp:   &source{topic: "demo", bufferLength: 1024, qos: api.AtLeastOnce, queueSize: 10},
+		},
+		{
+			props: map[string]interface{}{"qos": 2},
+			err:   "invalid qos 2, it must be 0 or 1",
+		},
+		{
+			props: map[string]interface{}{"qos": 1, "queueSize": 0},
+			err:   "invalid queueSize 0, it must be a positive integer",
+		},
+		{
+			props: map[string]interface{}{"qos": 1, "group": "g1"},
+			err:   "qos 1 is not supported for the consumer group",
+		},
+	}
+	for i, tt := range tests {
+		s := &source{}
+		err := s.Configure("demo", tt.props)
+		if tt.err != "" {
+			if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+				t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(tt.exp, s) {
+			t.Errorf("%d: mismatch:\n  exp=%+v\n  got=%+v\n\n", i, tt.exp, s)
+		}
+	}
+}
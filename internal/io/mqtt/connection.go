@@ -18,6 +18,8 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"net"
+	neturl "net/url"
 	"sync/atomic"
 	"time"
 
@@ -26,6 +28,7 @@ import (
 
 	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/pkg/cert"
+	"github.com/lf-edge/ekuiper/internal/pkg/netx"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/cast"
 	"github.com/lf-edge/ekuiper/pkg/errorx"
@@ -49,6 +52,10 @@ type ConnectionConfig struct {
 	Password string `json:"password"`
 	pversion uint   // 3 or 4
 	tls      *tls.Config
+	dialer   *netx.Dialer
+
+	// EnableAck is set by the rule in ack mode. The messages are acked manually in a persistent session.
+	EnableAck bool `json:"enableAck"`
 }
 
 type SubscriptionInfo struct {
@@ -137,6 +144,11 @@ func CreateClient(ctx api.StreamContext, selId string, props map[string]any) (*C
 	opts := pahoMqtt.NewClientOptions().AddBroker(c.Server).SetProtocolVersion(c.pversion)
 
 	opts = opts.SetTLSConfig(c.tls)
+	if !c.dialer.IsDefault() {
+		opts = opts.SetCustomOpenConnectionFn(func(uri *neturl.URL, options pahoMqtt.ClientOptions) (net.Conn, error) {
+			return c.dialer.DialURL(uri, options.TLSConfig, options.ConnectTimeout)
+		})
+	}
 
 	if c.Uname != "" {
 		opts = opts.SetUsername(c.Uname)
@@ -146,6 +158,10 @@ func CreateClient(ctx api.StreamContext, selId string, props map[string]any) (*C
 	}
 	opts = opts.SetClientID(c.ClientId)
 	opts = opts.SetAutoReconnect(true)
+	if c.EnableAck {
+		// Keep the session so that the broker sends the messages not acked again after reconnected
+		opts = opts.SetAutoAckDisabled(true).SetCleanSession(false)
+	}
 
 	con := &Connection{
 		logger:        ctx.GetLogger(),
@@ -180,6 +196,9 @@ func validateConfig(props map[string]any) (*ConnectionConfig, error) {
 	}
 
 	if c.ClientId == "" {
+		if c.EnableAck {
+			return nil, fmt.Errorf("clientid must be set to resume the session when ack is enabled")
+		}
 		c.ClientId = uuid.New().String()
 	}
 	// Default to MQTT 3.1.1 or NanoMQ cannot connect
@@ -196,6 +215,10 @@ func validateConfig(props map[string]any) (*ConnectionConfig, error) {
 		return nil, err
 	}
 	c.tls = tlsConfig
+	c.dialer, err = netx.NewDialer(props)
+	if err != nil {
+		return nil, err
+	}
 	return c, nil
 }
 
@@ -16,6 +16,8 @@ package mqtt
 
 import (
 	"fmt"
+	"sync"
+	"sync/atomic"
 
 	pahoMqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/pingcap/failpoint"
@@ -38,11 +40,33 @@ type SourceConnector struct {
 	cli      *Connection
 	consumer chan<- api.SourceTuple
 	stats    metric.StatManager
+
+	// In ack mode, the messages are acked to the broker after the results are delivered
+	seq       atomic.Int64
+	pendingMu sync.Mutex
+	pending   []pendingMessage
 }
 
 type Conf struct {
-	Qos       int `json:"qos"`
-	BufferLen int `json:"bufferLength"`
+	Qos       int  `json:"qos"`
+	BufferLen int  `json:"bufferLength"`
+	EnableAck bool `json:"enableAck"`
+}
+
+// pendingMessage is the received message waiting to be acked. The seq is the offset of the tuple.
+type pendingMessage struct {
+	seq int64
+	msg pahoMqtt.Message
+}
+
+// ackTuple is the tuple of the message to ack after delivered
+type ackTuple struct {
+	*api.DefaultSourceTuple
+	seq int64
+}
+
+func (t *ackTuple) Offset() interface{} {
+	return t.seq
 }
 
 func (ms *SourceConnector) SetupStats(stats metric.StatManager) {
@@ -60,6 +84,11 @@ func (ms *SourceConnector) Configure(topic string, props map[string]any) error {
 	if err != nil {
 		return fmt.Errorf("read properties %v fail with error: %v", props, err)
 	}
+	if cfg.EnableAck && cfg.Qos == 0 {
+		conf.Log.Warnf("the messages of qos 0 from topic %s are not acked by the broker, ack is disabled", topic)
+		cfg.EnableAck = false
+		props["enableAck"] = false
+	}
 	_, err = validateConfig(props)
 	if err != nil {
 		return err
@@ -124,17 +153,46 @@ func (ms *SourceConnector) onMessage(ctx api.StreamContext, msg pahoMqtt.Message
 		ctx.GetLogger().Debugf("Received message %s from topic %s", string(msg.Payload()), msg.Topic())
 	}
 	rcvTime := conf.GetNow()
-	select {
-	case ms.consumer <- api.NewDefaultRawTuple(msg.Payload(), map[string]interface{}{
+	var tuple api.SourceTuple = api.NewDefaultRawTuple(msg.Payload(), map[string]interface{}{
 		"topic":     msg.Topic(),
 		"qos":       msg.Qos(),
 		"messageId": msg.MessageID(),
-	}, rcvTime):
+	}, rcvTime)
+	var seq int64
+	if ms.cfg != nil && ms.cfg.EnableAck {
+		seq = ms.seq.Add(1)
+		tuple = &ackTuple{DefaultSourceTuple: tuple.(*api.DefaultSourceTuple), seq: seq}
+	}
+	select {
+	case ms.consumer <- tuple:
+		if seq > 0 {
+			ms.pendingMu.Lock()
+			ms.pending = append(ms.pending, pendingMessage{seq: seq, msg: msg})
+			ms.pendingMu.Unlock()
+		}
 	default:
-		ms.stats.IncTotalExceptions("buffer full from mqtt connector, drop message")
+		// In ack mode, the dropped message is not acked so that the broker sends it again after reconnected
+		ms.stats.IncTotalExceptionsOf(metric.ErrorResource, "buffer full from mqtt connector, drop message")
 	}
 }
 
+// CommitOffset acks the messages up to the offset to the broker. It is called after the results are delivered.
+func (ms *SourceConnector) CommitOffset(ctx api.StreamContext, offset interface{}) error {
+	seq, err := cast.ToInt64(offset, cast.CONVERT_SAMEKIND)
+	if err != nil {
+		return fmt.Errorf("invalid offset %v for mqtt source: %v", offset, err)
+	}
+	ms.pendingMu.Lock()
+	i := 0
+	for ; i < len(ms.pending) && ms.pending[i].seq <= seq; i++ {
+		ms.pending[i].msg.Ack()
+	}
+	ms.pending = ms.pending[i:]
+	ms.pendingMu.Unlock()
+	ctx.GetLogger().Debugf("mqtt source acked %d messages to offset %d", i, seq)
+	return nil
+}
+
 func (ms *SourceConnector) onError(ctx api.StreamContext, err error) {
 	select {
 	case <-ctx.Done():
@@ -157,7 +215,7 @@ func (ms *SourceConnector) onError(ctx api.StreamContext, err error) {
 		Error: err,
 	}:
 	default:
-		ms.stats.IncTotalExceptions("buffer full from mqtt connector, drop err")
+		ms.stats.IncTotalExceptionsOf(metric.ErrorResource, "buffer full from mqtt connector, drop err")
 	}
 }
 
@@ -177,4 +235,7 @@ func (ms *SourceConnector) Close(ctx api.StreamContext) error {
 	return nil
 }
 
-var _ api.SourceConnector = &SourceConnector{}
+var (
+	_ api.SourceConnector = &SourceConnector{}
+	_ api.OffsetCommitter = &SourceConnector{}
+)
@@ -139,6 +139,46 @@ func TestOnMsgCancel(t *testing.T) {
 	sc.onError(ctx, nil)
 }
 
+func TestAckAfterCommit(t *testing.T) {
+	consumer := make(chan api.SourceTuple, 1)
+	sc := &SourceConnector{cfg: &Conf{Qos: 1, EnableAck: true}, consumer: consumer}
+	ctx := mockContext.NewMockContext("1", "1")
+	acked := make([]int, 0)
+	sc.onMessage(ctx, &ackMessage{id: 1, acked: &acked})
+	tuple := <-consumer
+	assert.Equal(t, int64(1), tuple.(api.OffsetTuple).Offset())
+	sc.onMessage(ctx, &ackMessage{id: 2, acked: &acked})
+	<-consumer
+	sc.onMessage(ctx, &ackMessage{id: 3, acked: &acked})
+	// the messages are not acked before committed
+	assert.Empty(t, acked)
+	require.NoError(t, sc.CommitOffset(ctx, int64(2)))
+	assert.Equal(t, []int{1, 2}, acked)
+	require.NoError(t, sc.CommitOffset(ctx, int64(3)))
+	assert.Equal(t, []int{1, 2, 3}, acked)
+}
+
+func TestAckConfigure(t *testing.T) {
+	err := (&SourceConnector{}).Configure("topic", map[string]any{"server": url, "qos": 1, "enableAck": true})
+	assert.EqualError(t, err, "clientid must be set to resume the session when ack is enabled")
+	props := map[string]any{"server": url, "enableAck": true}
+	sc := &SourceConnector{}
+	require.NoError(t, sc.Configure("topic", props))
+	// qos 0 messages cannot be acked
+	assert.False(t, sc.cfg.EnableAck)
+	assert.Equal(t, false, props["enableAck"])
+}
+
+type ackMessage struct {
+	MockMessage
+	id    int
+	acked *[]int
+}
+
+func (m *ackMessage) Ack() {
+	*m.acked = append(*m.acked, m.id)
+}
+
 // MockMessage implements the Message interface and allows for control over the returned data when a MessageHandler is
 // invoked.
 type MockMessage struct {
@@ -0,0 +1,109 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package record records the payloads received by a source to a file and replays them later as a source.
+package record
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+)
+
+// Record is a line of the record file
+type Record struct {
+	// Timestamp is the event time or the receiving time in milliseconds
+	Timestamp int64 `json:"timestamp"`
+	// Payload is the raw payload which is decoded by the format of the stream when replaying
+	Payload []byte `json:"payload,omitempty"`
+	// Message is the decoded message if the source does not provide the raw payload
+	Message map[string]any `json:"message,omitempty"`
+	Meta    map[string]any `json:"meta,omitempty"`
+}
+
+// Recorder appends the records to a file as json lines
+type Recorder struct {
+	path string
+	mu   sync.Mutex
+	f    *os.File
+	w    *bufio.Writer
+}
+
+// NewRecorder opens the record file to append. A relative path is relative to the data directory.
+func NewRecorder(file string) (*Recorder, error) {
+	fp, err := Path(file)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(fp), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("fail to create the directory of record file %s: %v", fp, err)
+	}
+	f, err := os.OpenFile(fp, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("fail to open record file %s: %v", fp, err)
+	}
+	return &Recorder{path: fp, f: f, w: bufio.NewWriter(f)}, nil
+}
+
+func (r *Recorder) Path() string {
+	return r.path
+}
+
+// Record writes a record and flushes it so that the file can be read while recording
+func (r *Recorder) Record(rec *Record) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return fmt.Errorf("record file %s is closed", r.path)
+	}
+	if _, err := r.w.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return r.w.Flush()
+}
+
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	err := r.w.Flush()
+	if e := r.f.Close(); err == nil {
+		err = e
+	}
+	r.f = nil
+	return err
+}
+
+// Path resolves the relative record file path to the data directory
+func Path(file string) (string, error) {
+	if filepath.IsAbs(file) {
+		return file, nil
+	}
+	dataDir, err := conf.GetDataLoc()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, file), nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package record
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sub", "demo.jsonl")
+	r, err := NewRecorder(file)
+	require.NoError(t, err)
+	assert.Equal(t, file, r.Path())
+	require.NoError(t, r.Record(&Record{Timestamp: 1000, Payload: []byte(`{"v":1}`), Meta: map[string]any{"topic": "demo"}}))
+	require.NoError(t, r.Record(&Record{Timestamp: 2000, Message: map[string]any{"v": 2}}))
+	require.NoError(t, r.Close())
+	assert.EqualError(t, r.Record(&Record{Timestamp: 3000}), "record file "+file+" is closed")
+	bs, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, `{"timestamp":1000,"payload":"eyJ2IjoxfQ==","meta":{"topic":"demo"}}
+{"timestamp":2000,"message":{"v":2}}
+`, string(bs))
+}
+
+func TestSourceConfigure(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "demo.jsonl")
+	require.NoError(t, os.WriteFile(file, nil, 0o644))
+	tests := []struct {
+		name  string
+		props map[string]interface{}
+		err   string
+	}{
+		{name: "valid", props: map[string]interface{}{"path": file, "speed": 2}},
+		{name: "no path", props: map[string]interface{}{}, err: "path is required"},
+		{name: "negative speed", props: map[string]interface{}{"path": file, "speed": -1}, err: "speed must not be negative but got -1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&Source{}).Configure("", tt.props)
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+	err := (&Source{}).Configure("", map[string]interface{}{"path": filepath.Join(t.TempDir(), "none.jsonl")})
+	assert.ErrorContains(t, err, "is not found")
+}
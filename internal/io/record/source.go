@@ -0,0 +1,168 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package record
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"github.com/lf-edge/ekuiper/pkg/infra"
+)
+
+type sourceConf struct {
+	// Path is the record file to replay
+	Path string `json:"path"`
+	// Records are replayed instead of the file if set, such as the inputs of a rule test case
+	Records []*Record `json:"records"`
+	// Speed is the multiple of the original speed by the timestamps of the records. 0 replays as fast as possible.
+	Speed float64 `json:"speed"`
+}
+
+// Source replays the records of a record file or the records in the props. It finishes after all the records are sent.
+type Source struct {
+	c    *sourceConf
+	path string
+}
+
+func (s *Source) Configure(_ string, props map[string]interface{}) error {
+	c := &sourceConf{Speed: 1}
+	if err := cast.MapToStruct(props, c); err != nil {
+		return fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	if c.Speed < 0 {
+		return fmt.Errorf("speed must not be negative but got %v", c.Speed)
+	}
+	if c.Records != nil {
+		s.c = c
+		return nil
+	}
+	if c.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	fp, err := Path(c.Path)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(fp); err != nil {
+		return fmt.Errorf("record file %s is not found: %v", fp, err)
+	}
+	s.c = c
+	s.path = fp
+	return nil
+}
+
+func (s *Source) Open(ctx api.StreamContext, consumer chan<- api.SourceTuple, errCh chan<- error) {
+	logger := ctx.GetLogger()
+	if s.c.Records != nil {
+		var last int64
+		for _, rec := range s.c.Records {
+			if !s.wait(ctx, last, rec.Timestamp) {
+				return
+			}
+			last = rec.Timestamp
+			s.send(ctx, consumer, rec)
+		}
+		logger.Infof("replay finished with %d records", len(s.c.Records))
+		return
+	}
+	f, err := os.Open(s.path)
+	if err != nil {
+		infra.DrainError(ctx, err, errCh)
+		return
+	}
+	defer f.Close()
+	logger.Infof("replay record file %s at speed %v", s.path, s.c.Speed)
+	reader := bufio.NewReader(f)
+	var (
+		last  int64
+		count int
+	)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			rec := &Record{}
+			if e := json.Unmarshal(line, rec); e != nil {
+				logger.Warnf("skip invalid record in %s: %v", s.path, e)
+			} else {
+				if !s.wait(ctx, last, rec.Timestamp) {
+					return
+				}
+				last = rec.Timestamp
+				s.send(ctx, consumer, rec)
+				count++
+			}
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				infra.DrainError(ctx, err, errCh)
+			}
+			break
+		}
+	}
+	logger.Infof("replay record file %s finished with %d records", s.path, count)
+}
+
+// wait the interval of the records by the speed. Return false if the rule stops.
+func (s *Source) wait(ctx api.StreamContext, last, ts int64) bool {
+	if s.c.Speed == 0 || last == 0 || ts <= last {
+		return true
+	}
+	timer := time.NewTimer(time.Duration(float64(ts-last) / s.c.Speed * float64(time.Millisecond)))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (s *Source) send(ctx api.StreamContext, consumer chan<- api.SourceTuple, rec *Record) {
+	ts := time.UnixMilli(rec.Timestamp)
+	var tuples []api.SourceTuple
+	if rec.Payload != nil {
+		dataList, err := ctx.DecodeIntoList(rec.Payload)
+		if err != nil {
+			tuples = []api.SourceTuple{&xsql.ErrorSourceTuple{Error: fmt.Errorf("decode record of %d error: %v", rec.Timestamp, err)}}
+		} else {
+			tuples = make([]api.SourceTuple, 0, len(dataList))
+			for _, m := range dataList {
+				tuples = append(tuples, api.NewDefaultSourceTupleWithTime(m, rec.Meta, ts))
+			}
+		}
+	} else {
+		tuples = []api.SourceTuple{api.NewDefaultSourceTupleWithTime(rec.Message, rec.Meta, ts)}
+	}
+	for _, t := range tuples {
+		select {
+		case consumer <- t:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Source) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("closing replay source")
+	return nil
+}
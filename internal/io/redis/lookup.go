@@ -25,6 +25,7 @@ import (
 	"github.com/redis/go-redis/v9"
 
 	cnf "github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/io/redis/pool"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/cast"
 )
@@ -36,6 +37,8 @@ type conf struct {
 	// Optional password. Must match the password specified in the
 	Password string `json:"password,omitempty"`
 	DataType string `json:"dataType,omitempty"`
+	// the named connection to share the client
+	ConnectionSelector string `json:"connectionSelector,omitempty"`
 }
 
 type lookupSource struct {
@@ -48,7 +51,7 @@ func (s *lookupSource) Ping(dataSource string, props map[string]interface{}) err
 	err := s.Configure(dataSource, props)
 	defer func() {
 		if s.cli != nil {
-			s.cli.Close()
+			_ = pool.Release(s.c.ConnectionSelector, s.cli)
 		}
 	}()
 	return err
@@ -86,7 +89,7 @@ func (s *lookupSource) Configure(datasource string, props map[string]interface{}
 	if err := s.Validate(props); err != nil {
 		return err
 	}
-	s.cli = redis.NewClient(&redis.Options{
+	s.cli = pool.Get(s.c.ConnectionSelector, &redis.Options{
 		Addr:     s.c.Addr,
 		Username: s.c.Username,
 		Password: s.c.Password,
@@ -145,7 +148,7 @@ func (s *lookupSource) Lookup(ctx api.StreamContext, _ []string, keys []string,
 
 func (s *lookupSource) Close(ctx api.StreamContext) error {
 	ctx.GetLogger().Infof("Closing redis lookup source")
-	return s.cli.Close()
+	return pool.Release(s.c.ConnectionSelector, s.cli)
 }
 
 func GetLookupSource() api.LookupSource {
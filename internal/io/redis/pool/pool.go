@@ -0,0 +1,80 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pool shares the redis clients among the sources and sinks which refer to the same named connection by the
+// connectionSelector property. Each client has its own connection pool, so sharing it saves the connections to the
+// redis server when many rules use the same server.
+package pool
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type sharedClient struct {
+	cli      *redis.Client
+	refCount int
+}
+
+var (
+	lock    sync.Mutex
+	clients = make(map[string]*sharedClient)
+)
+
+func key(selector string, db int) string {
+	return fmt.Sprintf("%s/%d", selector, db)
+}
+
+// Get returns the client shared by the selector and the db. The options are only used to create the client when it
+// does not exist. Without selector, a new client is created.
+func Get(selector string, opts *redis.Options) *redis.Client {
+	if selector == "" {
+		return redis.NewClient(opts)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	k := key(selector, opts.DB)
+	sc, ok := clients[k]
+	if !ok {
+		sc = &sharedClient{cli: redis.NewClient(opts)}
+		clients[k] = sc
+	}
+	sc.refCount++
+	return sc.cli
+}
+
+// Release returns the client got by Get. The shared client is closed when it is not referred anymore.
+func Release(selector string, cli *redis.Client) error {
+	if cli == nil {
+		return nil
+	}
+	if selector == "" {
+		return cli.Close()
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	k := key(selector, cli.Options().DB)
+	sc, ok := clients[k]
+	if !ok || sc.cli != cli {
+		return cli.Close()
+	}
+	sc.refCount--
+	if sc.refCount > 0 {
+		return nil
+	}
+	delete(clients, k)
+	return cli.Close()
+}
@@ -0,0 +1,46 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharedClient(t *testing.T) {
+	opts := &redis.Options{Addr: "localhost:6379"}
+	c1 := Get("redis.local", opts)
+	c2 := Get("redis.local", opts)
+	assert.Same(t, c1, c2)
+	c3 := Get("redis.local", &redis.Options{Addr: "localhost:6379", DB: 1})
+	assert.NotSame(t, c1, c3)
+	c4 := Get("", opts)
+	assert.NotSame(t, c1, c4)
+	assert.Len(t, clients, 2)
+
+	require.NoError(t, Release("redis.local", c1))
+	// still referred
+	assert.Equal(t, 1, clients["redis.local/0"].refCount)
+	require.NoError(t, Release("redis.local", c2))
+	assert.ErrorIs(t, c2.Ping(context.Background()).Err(), redis.ErrClosed)
+	require.NoError(t, Release("redis.local", c3))
+	require.NoError(t, Release("", c4))
+	assert.ErrorIs(t, c4.Ping(context.Background()).Err(), redis.ErrClosed)
+	assert.Len(t, clients, 0)
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/redis/go-redis/v9"
 
 	"github.com/lf-edge/ekuiper/internal/compressor"
+	"github.com/lf-edge/ekuiper/internal/io/redis/pool"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/cast"
 	"github.com/lf-edge/ekuiper/pkg/errorx"
@@ -41,6 +42,8 @@ type redisPubConfig struct {
 	Channel       string `json:"channel"`
 	Compression   string `json:"compression"`
 	ResendChannel string `json:"resendDestination"`
+	// the named connection to share the client
+	ConnectionSelector string `json:"connectionSelector"`
 }
 
 func (r *redisPub) Validate(props map[string]interface{}) error {
@@ -90,7 +93,7 @@ func (r *redisPub) Configure(props map[string]interface{}) error {
 
 func (r *redisPub) Open(ctx api.StreamContext) error {
 	ctx.GetLogger().Infof("redisPub sink opening")
-	r.conn = redis.NewClient(&redis.Options{
+	r.conn = pool.Get(r.conf.ConnectionSelector, &redis.Options{
 		Addr:     r.conf.Address,
 		Username: r.conf.Username,
 		Password: r.conf.Password,
@@ -134,7 +137,7 @@ func (r *redisPub) collectWithChannel(ctx api.StreamContext, item interface{}, c
 func (r *redisPub) Close(ctx api.StreamContext) error {
 	ctx.GetLogger().Infof("Closing redisPub sink")
 	if r.conn != nil {
-		err := r.conn.Close()
+		err := pool.Release(r.conf.ConnectionSelector, r.conn)
 		if err != nil {
 			return err
 		}
@@ -25,6 +25,7 @@ import (
 
 	"github.com/redis/go-redis/v9"
 
+	"github.com/lf-edge/ekuiper/internal/io/redis/pool"
 	"github.com/lf-edge/ekuiper/internal/topo/transform"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/ast"
@@ -39,6 +40,8 @@ type config struct {
 	Password string `json:"password,omitempty"`
 	// Database to be selected after connecting to the server.
 	Db int `json:"db,omitempty"`
+	// the named connection to share the client
+	ConnectionSelector string `json:"connectionSelector,omitempty"`
 	// key of field
 	Field string `json:"field,omitempty"`
 	// key define
@@ -104,7 +107,7 @@ func (r *RedisSink) Open(ctx api.StreamContext) (err error) {
 	logger := ctx.GetLogger()
 	logger.Debug("Opening redis sink")
 
-	r.cli = redis.NewClient(&redis.Options{
+	r.cli = pool.Get(r.c.ConnectionSelector, &redis.Options{
 		Addr:     r.c.Addr,
 		Username: r.c.Username,
 		Password: r.c.Password,
@@ -159,7 +162,7 @@ func (r *RedisSink) Collect(ctx api.StreamContext, data interface{}) error {
 
 func (r *RedisSink) Close(ctx api.StreamContext) error {
 	ctx.GetLogger().Infof("Closing redis sink")
-	err := r.cli.Close()
+	err := pool.Release(r.c.ConnectionSelector, r.cli)
 	return err
 }
 
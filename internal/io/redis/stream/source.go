@@ -0,0 +1,399 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"github.com/lf-edge/ekuiper/pkg/infra"
+)
+
+type sourceConf struct {
+	Address  string `json:"address"`
+	Db       int    `json:"db"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// Group is the consumer group to read the stream
+	Group string `json:"group"`
+	// Consumer is the consumer name in the group, default to the rule id
+	Consumer string `json:"consumer"`
+	// StartId is the id to start reading when the group is created, $ for the new entries and 0 for all entries
+	StartId string `json:"startId"`
+	// PayloadField is the entry field to decode by the stream format. If not set, the entry fields are the data.
+	PayloadField string `json:"payloadField"`
+	// Count is the max entries of each read
+	Count int64 `json:"count"`
+	// Block is the max blocking time in ms of each read
+	Block int `json:"block"`
+	// ClaimMinIdle is the min idle time in ms of the pending entries of other consumers to claim on start.
+	// Negative value disables the claim.
+	ClaimMinIdle int `json:"claimMinIdle"`
+	// EnableAck is set by the rule. The entries are only acknowledged after the data is delivered
+	EnableAck bool `json:"enableAck"`
+}
+
+// streamTuple carries the entry id so that the entry can be acknowledged after delivery
+type streamTuple struct {
+	*api.DefaultSourceTuple
+	id string
+}
+
+func (t *streamTuple) Offset() interface{} {
+	if t.id == "" {
+		return nil
+	}
+	return t.id
+}
+
+type source struct {
+	key  string
+	c    *sourceConf
+	conn *redis.Client
+
+	mu sync.Mutex
+	// offset is the id of the last emitted entry
+	offset string
+	// rewindTo is the id to set the group to when opening
+	rewindTo string
+	// unacked are the ids of the emitted entries to acknowledge when the offset is committed
+	unacked []string
+}
+
+func (s *source) Configure(key string, props map[string]interface{}) error {
+	if key == "" {
+		return fmt.Errorf("datasource which indicates the stream key should be defined")
+	}
+	c := &sourceConf{
+		StartId:      "$",
+		Count:        100,
+		Block:        1000,
+		ClaimMinIdle: 60000,
+	}
+	if err := cast.MapToStruct(props, c); err != nil {
+		return fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	if c.Db < 0 || c.Db > 15 {
+		return fmt.Errorf("redisStream db should be in range 0-15")
+	}
+	if c.Group == "" {
+		return fmt.Errorf("group is required")
+	}
+	if c.Count <= 0 {
+		return fmt.Errorf("count must be positive")
+	}
+	if c.Block <= 0 {
+		return fmt.Errorf("block must be positive")
+	}
+	s.key = key
+	s.c = c
+	s.conn = redis.NewClient(&redis.Options{
+		Addr:     c.Address,
+		Username: c.Username,
+		Password: c.Password,
+		DB:       c.Db,
+	})
+	return nil
+}
+
+func (s *source) Ping(key string, props map[string]interface{}) error {
+	if err := s.Configure(key, props); err != nil {
+		return err
+	}
+	defer s.conn.Close()
+	if err := s.conn.Ping(context.Background()).Err(); err != nil {
+		return fmt.Errorf("Ping Redis failed with error: %v", err)
+	}
+	return nil
+}
+
+func (s *source) Open(ctx api.StreamContext, consumer chan<- api.SourceTuple, errCh chan<- error) {
+	logger := ctx.GetLogger()
+	if s.c.Consumer == "" {
+		s.c.Consumer = ctx.GetRuleId()
+	}
+	logger.Infof("redisStream source reads stream %s as consumer %s of group %s", s.key, s.c.Consumer, s.c.Group)
+	if err := s.read(ctx, consumer); err != nil && ctx.Err() == nil {
+		infra.DrainError(ctx, err, errCh)
+	}
+}
+
+func (s *source) read(ctx api.StreamContext, consumer chan<- api.SourceTuple) error {
+	if err := s.createGroup(ctx); err != nil {
+		return err
+	}
+	if err := s.claim(ctx); err != nil {
+		return err
+	}
+	// Read the pending entries of this consumer first, then the new entries
+	id := "0"
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		streams, err := s.conn.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    s.c.Group,
+			Consumer: s.c.Consumer,
+			Streams:  []string{s.key, id},
+			Count:    s.c.Count,
+			Block:    time.Duration(s.c.Block) * time.Millisecond,
+		}).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			return fmt.Errorf("read stream %s error: %v", s.key, err)
+		}
+		var msgs []redis.XMessage
+		if len(streams) > 0 {
+			msgs = streams[0].Messages
+		}
+		if id == "0" && len(msgs) == 0 {
+			id = ">"
+			continue
+		}
+		for _, msg := range msgs {
+			if err := s.send(ctx, consumer, msg); err != nil {
+				return err
+			}
+		}
+		if id == "0" && len(msgs) > 0 {
+			id = msgs[len(msgs)-1].ID
+		}
+	}
+}
+
+func (s *source) createGroup(ctx api.StreamContext) error {
+	err := s.conn.XGroupCreateMkStream(ctx, s.key, s.c.Group, s.c.StartId).Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("create group %s of stream %s error: %v", s.c.Group, s.key, err)
+	}
+	s.mu.Lock()
+	rewindTo := s.rewindTo
+	s.rewindTo = ""
+	s.mu.Unlock()
+	if rewindTo != "" {
+		ctx.GetLogger().Infof("redisStream source rewinds group %s to %s", s.c.Group, rewindTo)
+		if err := s.conn.XGroupSetID(ctx, s.key, s.c.Group, rewindTo).Err(); err != nil {
+			return fmt.Errorf("rewind group %s of stream %s error: %v", s.c.Group, s.key, err)
+		}
+	}
+	return nil
+}
+
+// claim takes over the pending entries of the other consumers which have been idle for claimMinIdle, such as the
+// entries of a consumer which is gone. They are read with the pending entries of this consumer.
+func (s *source) claim(ctx api.StreamContext) error {
+	if s.c.ClaimMinIdle < 0 {
+		return nil
+	}
+	start := "0-0"
+	for {
+		_, next, err := s.conn.XAutoClaimJustID(ctx, &redis.XAutoClaimArgs{
+			Stream:   s.key,
+			Group:    s.c.Group,
+			Consumer: s.c.Consumer,
+			MinIdle:  time.Duration(s.c.ClaimMinIdle) * time.Millisecond,
+			Start:    start,
+			Count:    s.c.Count,
+		}).Result()
+		if err != nil {
+			return fmt.Errorf("claim pending entries of stream %s error: %v", s.key, err)
+		}
+		if next == "0-0" || next == "" {
+			return nil
+		}
+		start = next
+	}
+}
+
+func (s *source) send(ctx api.StreamContext, consumer chan<- api.SourceTuple, msg redis.XMessage) error {
+	logger := ctx.GetLogger()
+	rcvTime := conf.GetNow()
+	meta := map[string]interface{}{
+		"stream": s.key,
+		"id":     msg.ID,
+	}
+	var results []map[string]interface{}
+	if s.c.PayloadField == "" {
+		results = []map[string]interface{}{msg.Values}
+	} else {
+		payload, ok := msg.Values[s.c.PayloadField]
+		if !ok {
+			logger.Warnf("entry %s of stream %s does not have field %s", msg.ID, s.key, s.c.PayloadField)
+		} else {
+			var err error
+			results, err = ctx.DecodeIntoList([]byte(cast.ToStringAlways(payload)))
+			if err != nil {
+				logger.Errorf("Invalid data format, cannot decode entry %s with error %s", msg.ID, err)
+			}
+		}
+	}
+	s.mu.Lock()
+	s.offset = msg.ID
+	if s.c.EnableAck {
+		s.unacked = append(s.unacked, msg.ID)
+	}
+	s.mu.Unlock()
+	for i, result := range results {
+		tuple := &streamTuple{DefaultSourceTuple: api.NewDefaultSourceTupleWithTime(result, meta, rcvTime)}
+		// Only acknowledge the entry when all its decoded data are delivered
+		if s.c.EnableAck && i == len(results)-1 {
+			tuple.id = msg.ID
+		}
+		select {
+		case consumer <- tuple:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	if !s.c.EnableAck || len(results) == 0 {
+		// The invalid entries are acknowledged to not block the pending list
+		if err := s.conn.XAck(ctx, s.key, s.c.Group, msg.ID).Err(); err != nil {
+			return fmt.Errorf("ack entry %s of stream %s error: %v", msg.ID, s.key, err)
+		}
+		if s.c.EnableAck {
+			s.removeUnacked(msg.ID)
+		}
+	}
+	return nil
+}
+
+func (s *source) removeUnacked(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, u := range s.unacked {
+		if u == id {
+			s.unacked = append(s.unacked[:i], s.unacked[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *source) GetOffset() (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.offset, nil
+}
+
+// Rewind sets the group to the checkpointed entry id. If ack is enabled, the pending entries of the consumer are
+// read again instead.
+func (s *source) Rewind(offset interface{}) error {
+	if s.c != nil && s.c.EnableAck {
+		conf.Log.Infof("redisStream source with ack resumes from the pending entries of group %s", s.c.Group)
+		return nil
+	}
+	id, ok := offset.(string)
+	if !ok {
+		return fmt.Errorf("%v can't be set as offset", offset)
+	}
+	if id == "" {
+		return nil
+	}
+	if _, _, err := parseID(id); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.rewindTo = id
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *source) ResetOffset(_ map[string]interface{}) error {
+	return errors.New("redisStream source not support reset offset")
+}
+
+// CommitOffset acknowledges all the emitted entries up to the offset
+func (s *source) CommitOffset(ctx api.StreamContext, offset interface{}) error {
+	id, ok := offset.(string)
+	if !ok {
+		return fmt.Errorf("redisStream source cannot commit offset %v", offset)
+	}
+	s.mu.Lock()
+	var ids []string
+	n := 0
+	for _, u := range s.unacked {
+		if compareID(u, id) <= 0 {
+			ids = append(ids, u)
+		} else {
+			s.unacked[n] = u
+			n++
+		}
+	}
+	s.unacked = s.unacked[:n]
+	s.mu.Unlock()
+	if len(ids) == 0 {
+		return nil
+	}
+	return s.conn.XAck(ctx, s.key, s.c.Group, ids...).Err()
+}
+
+func (s *source) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Closing redisStream source")
+	if s.conn != nil {
+		return s.conn.Close()
+	}
+	return nil
+}
+
+// parseID parses the entry id like 1526919030474-55
+func parseID(id string) (uint64, uint64, error) {
+	ms, seq, found := strings.Cut(id, "-")
+	t, err := strconv.ParseUint(ms, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid entry id %s", id)
+	}
+	if !found {
+		return t, 0, nil
+	}
+	q, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid entry id %s", id)
+	}
+	return t, q, nil
+}
+
+func compareID(a, b string) int {
+	at, aq, _ := parseID(a)
+	bt, bq, _ := parseID(b)
+	switch {
+	case at < bt:
+		return -1
+	case at > bt:
+		return 1
+	case aq < bq:
+		return -1
+	case aq > bq:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func RedisStream() api.Source {
+	return &source{}
+}
@@ -0,0 +1,168 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stream
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/converter"
+	kctx "github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	mockContext "github.com/lf-edge/ekuiper/pkg/mock/context"
+)
+
+func TestConfigure(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		props map[string]interface{}
+		err   string
+	}{
+		{
+			name:  "no key",
+			props: map[string]interface{}{"group": "g1"},
+			err:   "datasource which indicates the stream key should be defined",
+		},
+		{
+			name:  "no group",
+			key:   "s1",
+			props: map[string]interface{}{},
+			err:   "group is required",
+		},
+		{
+			name:  "invalid db",
+			key:   "s1",
+			props: map[string]interface{}{"group": "g1", "db": 16},
+			err:   "redisStream db should be in range 0-15",
+		},
+		{
+			name:  "invalid count",
+			key:   "s1",
+			props: map[string]interface{}{"group": "g1", "count": 0},
+			err:   "count must be positive",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RedisStream().Configure(tt.key, tt.props)
+			require.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestCompareID(t *testing.T) {
+	assert.Equal(t, -1, compareID("1-1", "1-2"))
+	assert.Equal(t, 1, compareID("10-0", "9-5"))
+	assert.Equal(t, 0, compareID("5", "5-0"))
+	_, _, err := parseID("a-1")
+	assert.EqualError(t, err, "invalid entry id a-1")
+}
+
+func setup(t *testing.T, props map[string]interface{}) (*source, *redis.Client, api.StreamContext, context.CancelFunc, chan api.SourceTuple) {
+	mr := miniredis.RunT(t)
+	cli := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = cli.Close() })
+	for _, d := range []string{`{"temperature":20}`, `{"temperature":21}`} {
+		require.NoError(t, cli.XAdd(context.Background(), &redis.XAddArgs{Stream: "s1", Values: []string{"data", d}}).Err())
+	}
+	props["address"] = mr.Addr()
+	s := RedisStream().(*source)
+	require.NoError(t, s.Configure("s1", props))
+	ctx, cancel := mockContext.NewMockContext("ruleStream", "op1").WithCancel()
+	cv, _ := converter.GetOrCreateConverter(&ast.Options{FORMAT: "json"})
+	ctx = kctx.WithValue(ctx.(*kctx.DefaultContext), kctx.DecodeKey, cv)
+	consumer := make(chan api.SourceTuple)
+	errCh := make(chan error)
+	go s.Open(ctx, consumer, errCh)
+	go func() {
+		select {
+		case err := <-errCh:
+			t.Errorf("received error: %v", err)
+		case <-ctx.Done():
+		}
+	}()
+	return s, cli, ctx, cancel, consumer
+}
+
+func receive(t *testing.T, consumer chan api.SourceTuple, n int) []api.SourceTuple {
+	var result []api.SourceTuple
+	for i := 0; i < n; i++ {
+		select {
+		case tuple := <-consumer:
+			result = append(result, tuple)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout to receive tuple %d", i)
+		}
+	}
+	return result
+}
+
+func TestRead(t *testing.T) {
+	s, cli, ctx, cancel, consumer := setup(t, map[string]interface{}{
+		"group":        "g1",
+		"startId":      "0",
+		"payloadField": "data",
+		"block":        100,
+	})
+	defer cancel()
+	tuples := receive(t, consumer, 2)
+	assert.Equal(t, map[string]interface{}{"temperature": float64(20)}, tuples[0].Message())
+	assert.Equal(t, map[string]interface{}{"temperature": float64(21)}, tuples[1].Message())
+	assert.Equal(t, "s1", tuples[0].Meta()["stream"])
+	offset, err := s.GetOffset()
+	require.NoError(t, err)
+	assert.Equal(t, tuples[1].Meta()["id"], offset)
+	// acknowledged after emitted
+	assert.Eventually(t, func() bool {
+		p, err := cli.XPending(ctx, "s1", "g1").Result()
+		return err == nil && p.Count == 0
+	}, 2*time.Second, 50*time.Millisecond)
+	cancel()
+	require.NoError(t, s.Close(ctx))
+}
+
+func TestReadWithAck(t *testing.T) {
+	s, cli, ctx, cancel, consumer := setup(t, map[string]interface{}{
+		"group":     "g1",
+		"startId":   "0",
+		"enableAck": true,
+		"block":     100,
+	})
+	defer cancel()
+	tuples := receive(t, consumer, 2)
+	assert.Equal(t, map[string]interface{}{"data": `{"temperature":20}`}, tuples[0].Message())
+	p, err := cli.XPending(ctx, "s1", "g1").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), p.Count)
+	// commit the first entry
+	require.NoError(t, s.CommitOffset(ctx, tuples[0].(api.OffsetTuple).Offset()))
+	p, err = cli.XPending(ctx, "s1", "g1").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), p.Count)
+	require.NoError(t, s.CommitOffset(ctx, tuples[1].(api.OffsetTuple).Offset()))
+	p, err = cli.XPending(ctx, "s1", "g1").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), p.Count)
+	cancel()
+	require.NoError(t, s.Close(ctx))
+}
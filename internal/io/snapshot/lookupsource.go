@@ -0,0 +1,60 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+// lookupsource reads the snapshot table which is written by the snapshot sinks
+type lookupsource struct {
+	name  string
+	key   string
+	table *Table
+}
+
+func (s *lookupsource) Configure(datasource string, props map[string]interface{}) error {
+	s.name = datasource
+	if k, ok := props["key"]; ok {
+		if kk, ok := k.(string); ok {
+			s.key = kk
+		}
+	}
+	return nil
+}
+
+func (s *lookupsource) Open(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("lookup source for snapshot table %s is opened with key %v", s.name, s.key)
+	t, err := getTable(s.name)
+	if err != nil {
+		return err
+	}
+	s.table = t
+	return nil
+}
+
+func (s *lookupsource) Lookup(ctx api.StreamContext, _ []string, keys []string, values []interface{}) ([]api.SourceTuple, error) {
+	ctx.GetLogger().Debugf("lookup snapshot table %s with keys %v and values %v", s.name, keys, values)
+	return s.table.Read(s.key, keys, values)
+}
+
+func (s *lookupsource) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("lookup source for snapshot table %s is closing", s.name)
+	return nil
+}
+
+func GetLookupSource() api.LookupSource {
+	return &lookupsource{}
+}
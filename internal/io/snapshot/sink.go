@@ -0,0 +1,118 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/internal/topo/transform"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+type config struct {
+	Table        string   `json:"table"`
+	KeyField     string   `json:"keyField"`
+	RowkindField string   `json:"rowkindField"`
+	Fields       []string `json:"fields"`
+	DataField    string   `json:"dataField"`
+}
+
+// sink keeps the latest row of each key in the snapshot table
+type sink struct {
+	c     *config
+	table *Table
+}
+
+func (s *sink) Configure(props map[string]interface{}) error {
+	c := &config{}
+	if err := cast.MapToStruct(props, c); err != nil {
+		return err
+	}
+	if c.Table == "" {
+		return fmt.Errorf("property table is required")
+	}
+	if c.KeyField == "" {
+		return fmt.Errorf("property keyField is required")
+	}
+	s.c = c
+	return nil
+}
+
+func (s *sink) Open(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("opening snapshot sink for table %s", s.c.Table)
+	t, err := getTable(s.c.Table)
+	if err != nil {
+		return err
+	}
+	s.table = t
+	return nil
+}
+
+func (s *sink) Collect(ctx api.StreamContext, data interface{}) error {
+	ctx.GetLogger().Debugf("snapshot sink receive %+v", data)
+	d, _, err := transform.TransItem(data, s.c.DataField, s.c.Fields)
+	if err != nil {
+		return fmt.Errorf("fail to select fields %v for data %v", s.c.Fields, data)
+	}
+	switch m := d.(type) {
+	case []map[string]interface{}:
+		for _, el := range m {
+			if err := s.save(el); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		return s.save(m)
+	default:
+		return fmt.Errorf("unrecognized format of %s", d)
+	}
+	return nil
+}
+
+func (s *sink) save(el map[string]interface{}) error {
+	k, ok := el[s.c.KeyField]
+	if !ok || k == nil {
+		return fmt.Errorf("key field %s not found in data %v", s.c.KeyField, el)
+	}
+	key := cast.ToStringAlways(k)
+	rowkind := ast.RowkindUpsert
+	if s.c.RowkindField != "" {
+		if c, ok := el[s.c.RowkindField]; ok {
+			rowkind, ok = c.(string)
+			if !ok {
+				return fmt.Errorf("rowkind field %s is not a string in data %v", s.c.RowkindField, el)
+			}
+		}
+	}
+	switch rowkind {
+	case ast.RowkindInsert, ast.RowkindUpdate, ast.RowkindUpsert:
+		return s.table.Upsert(key, el)
+	case ast.RowkindDelete:
+		return s.table.Delete(key)
+	default:
+		return fmt.Errorf("invalid rowkind %s", rowkind)
+	}
+}
+
+func (s *sink) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("closing snapshot sink for table %s", s.c.Table)
+	return nil
+}
+
+func GetSink() api.Sink {
+	return &sink{}
+}
@@ -0,0 +1,133 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/pkg/errorx"
+)
+
+func init() {
+	testx.InitEnv("snapshot")
+}
+
+func TestSnapshot(t *testing.T) {
+	contextLogger := conf.Log.WithField("rule", "test")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger)
+	s := GetSink()
+	require.EqualError(t, s.Configure(map[string]interface{}{"table": "devices"}), "property keyField is required")
+	require.NoError(t, s.Configure(map[string]interface{}{"table": "devices", "keyField": "id", "rowkindField": "action"}))
+	require.NoError(t, s.Open(ctx))
+	defer func() {
+		_ = Drop("devices")
+	}()
+	require.NoError(t, s.Collect(ctx, []map[string]interface{}{
+		{"id": 1, "temp": 20.5},
+		{"id": 2, "temp": 30.0},
+		{"id": 1, "temp": 21.5},
+	}))
+	require.NoError(t, s.Collect(ctx, map[string]interface{}{"id": 3, "temp": 25.0}))
+	require.NoError(t, s.Collect(ctx, map[string]interface{}{"id": 3, "action": "delete"}))
+	require.EqualError(t, s.Collect(ctx, map[string]interface{}{"temp": 25.0}), "key field id not found in data map[temp:25]")
+	require.EqualError(t, s.Collect(ctx, map[string]interface{}{"id": 4, "action": "drop"}), "invalid rowkind drop")
+
+	names, err := List()
+	require.NoError(t, err)
+	assert.Contains(t, names, "devices")
+	row, err := Get("devices", "1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"id": 1, "temp": 21.5}, row)
+	_, err = Get("devices", "3")
+	assert.Error(t, err)
+
+	// the rows are reloaded from the KV store
+	lock.Lock()
+	delete(tables, "devices")
+	lock.Unlock()
+	all, err := GetAll("devices")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]interface{}{
+		"1": {"id": 1.0, "temp": 21.5},
+		"2": {"id": 2.0, "temp": 30.0},
+	}, all)
+
+	ls := GetLookupSource()
+	require.NoError(t, ls.Configure("devices", map[string]interface{}{"key": "id"}))
+	require.NoError(t, ls.Open(ctx))
+	r, err := ls.Lookup(ctx, nil, []string{"id"}, []interface{}{int64(2)})
+	require.NoError(t, err)
+	require.Len(t, r, 1)
+	assert.Equal(t, map[string]interface{}{"id": 2.0, "temp": 30.0}, r[0].Message())
+	r, err = ls.Lookup(ctx, nil, []string{"temp"}, []interface{}{21.5})
+	require.NoError(t, err)
+	require.Len(t, r, 1)
+	assert.Equal(t, map[string]interface{}{"id": 1.0, "temp": 21.5}, r[0].Message())
+	r, err = ls.Lookup(ctx, nil, []string{"id"}, []interface{}{5})
+	require.NoError(t, err)
+	assert.Empty(t, r)
+	require.NoError(t, ls.Close(ctx))
+	require.NoError(t, s.Close(ctx))
+
+	require.NoError(t, Drop("devices"))
+	_, err = GetAll("devices")
+	var e *errorx.Error
+	require.ErrorAs(t, err, &e)
+	assert.Equal(t, errorx.NOT_FOUND, e.Code())
+}
+
+func TestDropWhileRunning(t *testing.T) {
+	contextLogger := conf.Log.WithField("rule", "test")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger)
+	s := GetSink()
+	require.NoError(t, s.Configure(map[string]interface{}{"table": "running", "keyField": "id"}))
+	require.NoError(t, s.Open(ctx))
+	ls := GetLookupSource()
+	require.NoError(t, ls.Configure("running", map[string]interface{}{"key": "id"}))
+	require.NoError(t, ls.Open(ctx))
+	require.NoError(t, s.Collect(ctx, map[string]interface{}{"id": 1, "temp": 20.5}))
+	r, err := ls.Lookup(ctx, nil, []string{"id"}, []interface{}{1})
+	require.NoError(t, err)
+	require.Len(t, r, 1)
+
+	require.NoError(t, Drop("running"))
+	require.EqualError(t, s.Collect(ctx, map[string]interface{}{"id": 2, "temp": 30.0}), "snapshot table running is dropped")
+	_, err = ls.Lookup(ctx, nil, []string{"id"}, []interface{}{1})
+	require.EqualError(t, err, "snapshot table running is dropped")
+	// the dropped table is not recreated by the running sink
+	names, err := List()
+	require.NoError(t, err)
+	assert.NotContains(t, names, "running")
+	require.NoError(t, ls.Close(ctx))
+	require.NoError(t, s.Close(ctx))
+
+	// a new rule creates a new table
+	s2 := GetSink()
+	require.NoError(t, s2.Configure(map[string]interface{}{"table": "running", "keyField": "id"}))
+	require.NoError(t, s2.Open(ctx))
+	defer func() {
+		_ = Drop("running")
+	}()
+	require.NoError(t, s2.Collect(ctx, map[string]interface{}{"id": 3, "temp": 25.0}))
+	all, err := GetAll("running")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]interface{}{"3": {"id": 3, "temp": 25.0}}, all)
+}
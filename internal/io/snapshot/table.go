@@ -0,0 +1,250 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package snapshot materializes the latest value of each key into a table. The table is persisted
+// in the KV store so that it survives restarts and can be read by lookup tables and the REST API.
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"github.com/lf-edge/ekuiper/pkg/errorx"
+	"github.com/lf-edge/ekuiper/pkg/kv"
+)
+
+const (
+	indexTable  = "snapshotTables"
+	tablePrefix = "snapshot_"
+)
+
+// Table is the latest value of each key. All the rows are cached in memory and written through to the KV store.
+// Once the table is dropped, all the operations of the sinks and lookup sources which still hold it will fail.
+type Table struct {
+	sync.RWMutex
+	name    string
+	db      kv.KeyValue
+	rows    map[string]map[string]interface{}
+	dropped bool
+}
+
+var (
+	tables = make(map[string]*Table)
+	lock   sync.Mutex
+)
+
+// getTable returns the table of the name and creates it if not exist
+func getTable(name string) (*Table, error) {
+	lock.Lock()
+	defer lock.Unlock()
+	if t, ok := tables[name]; ok {
+		return t, nil
+	}
+	index, err := store.GetKV(indexTable)
+	if err != nil {
+		return nil, err
+	}
+	if err := index.Set(name, name); err != nil {
+		return nil, err
+	}
+	t, err := loadTable(name)
+	if err != nil {
+		return nil, err
+	}
+	tables[name] = t
+	return t, nil
+}
+
+func loadTable(name string) (*Table, error) {
+	db, err := store.GetKV(tablePrefix + name)
+	if err != nil {
+		return nil, err
+	}
+	all, err := db.All()
+	if err != nil {
+		return nil, err
+	}
+	t := &Table{
+		name: name,
+		db:   db,
+		rows: make(map[string]map[string]interface{}, len(all)),
+	}
+	for k, v := range all {
+		row := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(v), &row); err != nil {
+			return nil, fmt.Errorf("fail to decode row %s of snapshot table %s: %v", k, name, err)
+		}
+		t.rows[k] = row
+	}
+	return t, nil
+}
+
+// Upsert sets the row of the key
+func (t *Table) Upsert(key string, row map[string]interface{}) error {
+	b, err := json.Marshal(row)
+	if err != nil {
+		return fmt.Errorf("fail to encode row %v: %v", row, err)
+	}
+	t.Lock()
+	defer t.Unlock()
+	if t.dropped {
+		return t.droppedErr()
+	}
+	if err := t.db.Set(key, string(b)); err != nil {
+		return err
+	}
+	t.rows[key] = row
+	return nil
+}
+
+// Delete removes the row of the key
+func (t *Table) Delete(key string) error {
+	t.Lock()
+	defer t.Unlock()
+	if t.dropped {
+		return t.droppedErr()
+	}
+	if _, ok := t.rows[key]; !ok {
+		return nil
+	}
+	delete(t.rows, key)
+	return t.db.Delete(key)
+}
+
+// Read returns the rows which match all the key values. If the table key is in the keys, it is used to find the row directly.
+func (t *Table) Read(key string, keys []string, values []interface{}) ([]api.SourceTuple, error) {
+	t.RLock()
+	defer t.RUnlock()
+	if t.dropped {
+		return nil, t.droppedErr()
+	}
+	match := func(row map[string]interface{}) bool {
+		for i, k := range keys {
+			if val, ok := row[k]; !ok || cast.ToStringAlways(val) != cast.ToStringAlways(values[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	for i, k := range keys {
+		if k == key {
+			if row, ok := t.rows[cast.ToStringAlways(values[i])]; ok && match(row) {
+				return []api.SourceTuple{api.NewDefaultSourceTuple(copyRow(row), nil)}, nil
+			}
+			return nil, nil
+		}
+	}
+	var result []api.SourceTuple
+	for _, row := range t.rows {
+		if match(row) {
+			result = append(result, api.NewDefaultSourceTuple(copyRow(row), nil))
+		}
+	}
+	return result, nil
+}
+
+func (t *Table) droppedErr() error {
+	return fmt.Errorf("snapshot table %s is dropped", t.name)
+}
+
+func copyRow(row map[string]interface{}) map[string]interface{} {
+	r := make(map[string]interface{}, len(row))
+	for k, v := range row {
+		r[k] = v
+	}
+	return r
+}
+
+// List returns the names of all the snapshot tables
+func List() ([]string, error) {
+	index, err := store.GetKV(indexTable)
+	if err != nil {
+		return nil, err
+	}
+	names, err := index.Keys()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func existTable(name string) (*Table, error) {
+	index, err := store.GetKV(indexTable)
+	if err != nil {
+		return nil, err
+	}
+	var v string
+	if ok, _ := index.Get(name, &v); !ok {
+		return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("snapshot table %s is not found", name))
+	}
+	return getTable(name)
+}
+
+// GetAll returns all the rows of the table keyed by the key value
+func GetAll(name string) (map[string]map[string]interface{}, error) {
+	t, err := existTable(name)
+	if err != nil {
+		return nil, err
+	}
+	t.RLock()
+	defer t.RUnlock()
+	result := make(map[string]map[string]interface{}, len(t.rows))
+	for k, row := range t.rows {
+		result[k] = copyRow(row)
+	}
+	return result, nil
+}
+
+// Get returns the row of the key value
+func Get(name string, key string) (map[string]interface{}, error) {
+	t, err := existTable(name)
+	if err != nil {
+		return nil, err
+	}
+	t.RLock()
+	defer t.RUnlock()
+	row, ok := t.rows[key]
+	if !ok {
+		return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("key %s is not found in snapshot table %s", key, name))
+	}
+	return copyRow(row), nil
+}
+
+// Drop deletes the table and all its rows. The running sinks and lookup sources of the table fail afterwards.
+func Drop(name string) error {
+	t, err := existTable(name)
+	if err != nil {
+		return err
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	t.Lock()
+	t.dropped = true
+	t.Unlock()
+	delete(tables, name)
+	index, err := store.GetKV(indexTable)
+	if err != nil {
+		return err
+	}
+	if err := index.Delete(name); err != nil {
+		return err
+	}
+	return store.DropKV(tablePrefix + name)
+}
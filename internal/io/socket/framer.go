@@ -0,0 +1,113 @@
+// Copyright 2022-2023 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package socket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	FramingNone      = "none"
+	FramingDelimiter = "delimiter"
+	FramingLength    = "length"
+	FramingFixed     = "fixed"
+)
+
+// splitFunc returns the function to split the frames out of the byte stream by the framing options.
+// Framing none means each read, namely each datagram for UDP, is a frame.
+func (c *sourceConf) splitFunc() (bufio.SplitFunc, error) {
+	switch c.Framing {
+	case FramingNone:
+		return func(data []byte, atEOF bool) (int, []byte, error) {
+			if len(data) == 0 {
+				return 0, nil, nil
+			}
+			return len(data), data, nil
+		}, nil
+	case FramingDelimiter:
+		if c.Delimiter == "" {
+			return nil, fmt.Errorf("delimiter is required for delimiter framing")
+		}
+		delim := []byte(c.Delimiter)
+		return func(data []byte, atEOF bool) (int, []byte, error) {
+			if i := bytes.Index(data, delim); i >= 0 {
+				return i + len(delim), data[:i], nil
+			}
+			// The remaining data without the trailing delimiter is the last frame
+			if atEOF && len(data) > 0 {
+				return len(data), data, nil
+			}
+			return 0, nil, nil
+		}, nil
+	case FramingLength:
+		var order binary.ByteOrder
+		switch c.ByteOrder {
+		case "big":
+			order = binary.BigEndian
+		case "little":
+			order = binary.LittleEndian
+		default:
+			return nil, fmt.Errorf("invalid byteOrder %s, must be big or little", c.ByteOrder)
+		}
+		size := c.LengthFieldSize
+		if size != 1 && size != 2 && size != 4 {
+			return nil, fmt.Errorf("invalid lengthFieldSize %d, must be 1, 2 or 4", size)
+		}
+		return func(data []byte, atEOF bool) (int, []byte, error) {
+			if len(data) < size {
+				return 0, nil, incomplete(data, atEOF)
+			}
+			var l int
+			switch size {
+			case 1:
+				l = int(data[0])
+			case 2:
+				l = int(order.Uint16(data))
+			case 4:
+				l = int(order.Uint32(data))
+			}
+			if l > c.MaxFrameSize {
+				return 0, nil, fmt.Errorf("frame length %d exceeds maxFrameSize %d", l, c.MaxFrameSize)
+			}
+			if len(data) < size+l {
+				return 0, nil, incomplete(data, atEOF)
+			}
+			return size + l, data[size : size+l], nil
+		}, nil
+	case FramingFixed:
+		if c.FrameSize <= 0 {
+			return nil, fmt.Errorf("frameSize must be positive for fixed framing")
+		}
+		return func(data []byte, atEOF bool) (int, []byte, error) {
+			if len(data) < c.FrameSize {
+				return 0, nil, incomplete(data, atEOF)
+			}
+			return c.FrameSize, data[:c.FrameSize], nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid framing %s, must be none, delimiter, length or fixed", c.Framing)
+	}
+}
+
+// incomplete reports the error if the stream ends in the middle of a frame. Otherwise, request more data.
+func incomplete(data []byte, atEOF bool) error {
+	if atEOF && len(data) > 0 {
+		return fmt.Errorf("incomplete frame of %d bytes at the end of the stream", len(data))
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+// Copyright 2022-2023 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package socket
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	goio "io"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/io"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"github.com/lf-edge/ekuiper/pkg/infra"
+)
+
+// the max payload size of a UDP datagram
+const maxDatagramSize = 65535
+
+type sourceConf struct {
+	Protocol        string `json:"protocol"`
+	Framing         string `json:"framing"`
+	Delimiter       string `json:"delimiter"`
+	LengthFieldSize int    `json:"lengthFieldSize"`
+	ByteOrder       string `json:"byteOrder"`
+	FrameSize       int    `json:"frameSize"`
+	MaxFrameSize    int    `json:"maxFrameSize"`
+	addr            string
+}
+
+// Source listens on a TCP or UDP address and splits the received bytes into frames.
+// Each frame is decoded by the stream format.
+type Source struct {
+	c     *sourceConf
+	split bufio.SplitFunc
+	// the id of the last accepted TCP connection
+	connId int64
+}
+
+func (s *Source) Configure(datasource string, props map[string]interface{}) error {
+	c := &sourceConf{
+		Protocol:        "tcp",
+		Delimiter:       "\n",
+		LengthFieldSize: 4,
+		ByteOrder:       "big",
+		MaxFrameSize:    65536,
+	}
+	err := cast.MapToStruct(props, c)
+	if err != nil {
+		return err
+	}
+	if datasource == "" {
+		return fmt.Errorf("the listening address is required as the datasource, such as :9000")
+	}
+	c.addr = datasource
+	switch c.Protocol {
+	case "tcp":
+		if c.Framing == "" {
+			c.Framing = FramingDelimiter
+		}
+	case "udp":
+		if c.Framing == "" {
+			c.Framing = FramingNone
+		}
+	default:
+		return fmt.Errorf("invalid protocol %s, must be tcp or udp", c.Protocol)
+	}
+	if c.MaxFrameSize <= 0 {
+		return fmt.Errorf("maxFrameSize must be positive")
+	}
+	s.split, err = c.splitFunc()
+	if err != nil {
+		return err
+	}
+	s.c = c
+	conf.Log.Debugf("Initialized socket source with configurations %#v.", c)
+	return nil
+}
+
+func (s *Source) Open(ctx api.StreamContext, consumer chan<- api.SourceTuple, errCh chan<- error) {
+	if s.c.Protocol == "udp" {
+		s.serveUDP(ctx, consumer, errCh)
+	} else {
+		s.serveTCP(ctx, consumer, errCh)
+	}
+}
+
+func (s *Source) serveTCP(ctx api.StreamContext, consumer chan<- api.SourceTuple, errCh chan<- error) {
+	logger := ctx.GetLogger()
+	ln, err := net.Listen("tcp", s.c.addr)
+	if err != nil {
+		infra.DrainError(ctx, err, errCh)
+		return
+	}
+	logger.Infof("socket source listens on tcp %s", ln.Addr())
+	go func() {
+		<-ctx.Done()
+		_ = ln.Close()
+	}()
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				infra.DrainError(ctx, fmt.Errorf("accept tcp connection error: %v", err), errCh)
+			}
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.readConn(ctx, conn, consumer)
+		}()
+	}
+}
+
+// readConn reads the frames of a TCP connection until it is closed by the peer or the rule stops
+func (s *Source) readConn(ctx api.StreamContext, conn net.Conn, consumer chan<- api.SourceTuple) {
+	logger := ctx.GetLogger()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+			_ = conn.Close()
+		}
+	}()
+	meta := map[string]interface{}{
+		"protocol":     "tcp",
+		"remoteAddr":   conn.RemoteAddr().String(),
+		"localAddr":    conn.LocalAddr().String(),
+		"connectionId": atomic.AddInt64(&s.connId, 1),
+	}
+	logger.Infof("socket source accepts connection %v from %s", meta["connectionId"], meta["remoteAddr"])
+	scanner := s.newScanner(conn)
+	for scanner.Scan() {
+		s.emit(ctx, consumer, scanner.Bytes(), meta)
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		io.ReceiveTuples(ctx, consumer, []api.SourceTuple{
+			&xsql.ErrorSourceTuple{Error: fmt.Errorf("read frame from %s error: %v", meta["remoteAddr"], err)},
+		})
+	}
+	logger.Infof("socket source closes connection %v from %s", meta["connectionId"], meta["remoteAddr"])
+}
+
+func (s *Source) serveUDP(ctx api.StreamContext, consumer chan<- api.SourceTuple, errCh chan<- error) {
+	pc, err := net.ListenPacket("udp", s.c.addr)
+	if err != nil {
+		infra.DrainError(ctx, err, errCh)
+		return
+	}
+	ctx.GetLogger().Infof("socket source listens on udp %s", pc.LocalAddr())
+	go func() {
+		<-ctx.Done()
+		_ = pc.Close()
+	}()
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, raddr, err := pc.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() == nil {
+				infra.DrainError(ctx, fmt.Errorf("read udp datagram error: %v", err), errCh)
+			}
+			return
+		}
+		meta := map[string]interface{}{
+			"protocol":   "udp",
+			"remoteAddr": raddr.String(),
+			"localAddr":  pc.LocalAddr().String(),
+		}
+		// A datagram may contain several frames, and a frame never spans datagrams
+		scanner := s.newScanner(bytes.NewReader(buf[:n]))
+		for scanner.Scan() {
+			s.emit(ctx, consumer, scanner.Bytes(), meta)
+		}
+		if err := scanner.Err(); err != nil {
+			io.ReceiveTuples(ctx, consumer, []api.SourceTuple{
+				&xsql.ErrorSourceTuple{Error: fmt.Errorf("read frame from %s error: %v", raddr, err)},
+			})
+		}
+	}
+}
+
+func (s *Source) newScanner(r goio.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	// leave room for the length field and the delimiter
+	scanner.Buffer(make([]byte, 0, 4096), s.c.MaxFrameSize+len(s.c.Delimiter)+s.c.LengthFieldSize)
+	scanner.Split(s.split)
+	return scanner
+}
+
+// emit decodes a frame and sends the result to the source node
+func (s *Source) emit(ctx api.StreamContext, consumer chan<- api.SourceTuple, frame []byte, meta map[string]interface{}) {
+	// The scanner reuses its buffer, so copy the frame in case the decoder keeps it
+	data := make([]byte, len(frame))
+	copy(data, frame)
+	var tuples []api.SourceTuple
+	dataList, err := ctx.DecodeIntoList(data)
+	if err != nil {
+		tuples = []api.SourceTuple{
+			&xsql.ErrorSourceTuple{Error: fmt.Errorf("decode frame from %s error: %v", meta["remoteAddr"], err)},
+		}
+	} else {
+		rcvTime := conf.GetNow()
+		tuples = make([]api.SourceTuple, 0, len(dataList))
+		for _, m := range dataList {
+			tuples = append(tuples, api.NewDefaultSourceTupleWithTime(m, meta, rcvTime))
+		}
+	}
+	io.ReceiveTuples(ctx, consumer, tuples)
+}
+
+func (s *Source) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("closing socket source")
+	return nil
+}
@@ -0,0 +1,260 @@
+// Copyright 2022-2023 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package socket
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/converter"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	mockContext "github.com/lf-edge/ekuiper/pkg/mock/context"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name   string
+		props  map[string]interface{}
+		input  []byte
+		frames []string
+		err    string
+	}{
+		{
+			name:   "default delimiter",
+			props:  map[string]interface{}{},
+			input:  []byte("a\nbc\n\nd"),
+			frames: []string{"a", "bc", "", "d"},
+		},
+		{
+			name:   "multi-byte delimiter",
+			props:  map[string]interface{}{"delimiter": "\r\n"},
+			input:  []byte("a\r\nb\nc\r\n"),
+			frames: []string{"a", "b\nc"},
+		},
+		{
+			name:   "length big endian",
+			props:  map[string]interface{}{"framing": "length", "lengthFieldSize": 2},
+			input:  []byte{0, 2, 'a', 'b', 0, 0, 0, 1, 'c'},
+			frames: []string{"ab", "", "c"},
+		},
+		{
+			name:   "length little endian",
+			props:  map[string]interface{}{"framing": "length", "lengthFieldSize": 4, "byteOrder": "little"},
+			input:  []byte{3, 0, 0, 0, 'a', 'b', 'c'},
+			frames: []string{"abc"},
+		},
+		{
+			name:   "length incomplete",
+			props:  map[string]interface{}{"framing": "length", "lengthFieldSize": 1},
+			input:  []byte{1, 'a', 3, 'b'},
+			frames: []string{"a"},
+			err:    "incomplete frame of 2 bytes at the end of the stream",
+		},
+		{
+			name:   "length too large",
+			props:  map[string]interface{}{"framing": "length", "lengthFieldSize": 1, "maxFrameSize": 2},
+			input:  []byte{3, 'a', 'b', 'c'},
+			frames: nil,
+			err:    "frame length 3 exceeds maxFrameSize 2",
+		},
+		{
+			name:   "fixed",
+			props:  map[string]interface{}{"framing": "fixed", "frameSize": 2},
+			input:  []byte("abcd"),
+			frames: []string{"ab", "cd"},
+		},
+		{
+			name:   "none",
+			props:  map[string]interface{}{"protocol": "udp"},
+			input:  []byte("a\nb"),
+			frames: []string{"a\nb"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Source{}
+			require.NoError(t, s.Configure(":9000", tt.props))
+			scanner := s.newScanner(bytes.NewReader(tt.input))
+			var frames []string
+			for scanner.Scan() {
+				frames = append(frames, scanner.Text())
+			}
+			assert.Equal(t, tt.frames, frames)
+			if tt.err != "" {
+				assert.EqualError(t, scanner.Err(), tt.err)
+			} else {
+				assert.NoError(t, scanner.Err())
+			}
+		})
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	tests := []struct {
+		datasource string
+		props      map[string]interface{}
+		err        string
+	}{
+		{
+			props: map[string]interface{}{},
+			err:   "the listening address is required as the datasource, such as :9000",
+		},
+		{
+			datasource: ":9000",
+			props:      map[string]interface{}{"protocol": "http"},
+			err:        "invalid protocol http, must be tcp or udp",
+		},
+		{
+			datasource: ":9000",
+			props:      map[string]interface{}{"framing": "line"},
+			err:        "invalid framing line, must be none, delimiter, length or fixed",
+		},
+		{
+			datasource: ":9000",
+			props:      map[string]interface{}{"framing": "length", "lengthFieldSize": 3},
+			err:        "invalid lengthFieldSize 3, must be 1, 2 or 4",
+		},
+		{
+			datasource: ":9000",
+			props:      map[string]interface{}{"framing": "fixed"},
+			err:        "frameSize must be positive for fixed framing",
+		},
+		{
+			datasource: ":9000",
+			props:      map[string]interface{}{"delimiter": ""},
+			err:        "delimiter is required for delimiter framing",
+		},
+	}
+	for _, tt := range tests {
+		s := &Source{}
+		assert.EqualError(t, s.Configure(tt.datasource, tt.props), tt.err)
+	}
+}
+
+func TestTCPSource(t *testing.T) {
+	addr := "127.0.0.1:19561"
+	consumer, cancel := openSource(t, addr, map[string]interface{}{"framing": "length", "lengthFieldSize": 1})
+	defer cancel()
+
+	conns := make([]net.Conn, 2)
+	for i := range conns {
+		conns[i] = dial(t, "tcp", addr)
+		defer conns[i].Close()
+	}
+	// A frame is split into several writes
+	_, err := conns[0].Write([]byte{9, '{', '"', 'a', '"', ':'})
+	require.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+	_, err = conns[0].Write([]byte{' ', '1', '}', ' '})
+	require.NoError(t, err)
+	r := receive(t, consumer)
+	assert.Equal(t, map[string]interface{}{"a": float64(1)}, r.Message())
+	assert.Equal(t, "tcp", r.Meta()["protocol"])
+	assert.Equal(t, conns[0].LocalAddr().String(), r.Meta()["remoteAddr"])
+	assert.Equal(t, addr, r.Meta()["localAddr"])
+	first := r.Meta()["connectionId"]
+
+	_, err = conns[1].Write(append([]byte{7}, []byte(`{"b":2}`)...))
+	require.NoError(t, err)
+	r = receive(t, consumer)
+	assert.Equal(t, map[string]interface{}{"b": float64(2)}, r.Message())
+	assert.Equal(t, conns[1].LocalAddr().String(), r.Meta()["remoteAddr"])
+	assert.NotEqual(t, first, r.Meta()["connectionId"])
+}
+
+func TestUDPSource(t *testing.T) {
+	addr := "127.0.0.1:19562"
+	consumer, cancel := openSource(t, addr, map[string]interface{}{"protocol": "udp", "framing": "delimiter"})
+	defer cancel()
+
+	conn := dial(t, "udp", addr)
+	defer conn.Close()
+	var r api.SourceTuple
+	// The datagram may be lost or refused before the source listens
+	for i := 0; i < 10 && r == nil; i++ {
+		_, _ = conn.Write([]byte("{\"a\":1}\n{\"a\":2}"))
+		select {
+		case r = <-consumer:
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	require.NotNil(t, r)
+	assert.Equal(t, map[string]interface{}{"a": float64(1)}, r.Message())
+	assert.Equal(t, "udp", r.Meta()["protocol"])
+	assert.Equal(t, conn.LocalAddr().String(), r.Meta()["remoteAddr"])
+	r = receive(t, consumer)
+	assert.Equal(t, map[string]interface{}{"a": float64(2)}, r.Message())
+}
+
+func openSource(t *testing.T, addr string, props map[string]interface{}) (chan api.SourceTuple, func()) {
+	s := &Source{}
+	require.NoError(t, s.Configure(addr, props))
+	ctx, cancel := mockContext.NewMockContext("ruleSocket", "op1").WithCancel()
+	cv, _ := converter.GetOrCreateConverter(&ast.Options{FORMAT: "json"})
+	ctx = context.WithValue(ctx.(*context.DefaultContext), context.DecodeKey, cv)
+	consumer := make(chan api.SourceTuple, 10)
+	errCh := make(chan error, 1)
+	done := make(chan struct{})
+	go func() {
+		s.Open(ctx, consumer, errCh)
+		close(done)
+	}()
+	return consumer, func() {
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Error("source is not closed")
+		}
+		select {
+		case err := <-errCh:
+			t.Errorf("unexpected error %v", err)
+		default:
+		}
+	}
+}
+
+func dial(t *testing.T, network, addr string) net.Conn {
+	var (
+		conn net.Conn
+		err  error
+	)
+	for i := 0; i < 10; i++ {
+		conn, err = net.Dial(network, addr)
+		if err == nil {
+			return conn
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	require.NoError(t, err)
+	return nil
+}
+
+func receive(t *testing.T, consumer chan api.SourceTuple) api.SourceTuple {
+	select {
+	case r := <-consumer:
+		return r
+	case <-time.After(time.Second):
+		require.Fail(t, "timeout to receive data")
+		return nil
+	}
+}
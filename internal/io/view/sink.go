@@ -0,0 +1,100 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package view
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/internal/io/memory/pubsub"
+	"github.com/lf-edge/ekuiper/internal/topo/transform"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+type config struct {
+	Name      string   `json:"name"`
+	Fields    []string `json:"fields"`
+	DataField string   `json:"dataField"`
+}
+
+type sink struct {
+	c    *config
+	view *View
+}
+
+func (s *sink) Configure(props map[string]interface{}) error {
+	c := &config{}
+	if err := cast.MapToStruct(props, c); err != nil {
+		return err
+	}
+	if c.Name == "" {
+		return fmt.Errorf("property name is required")
+	}
+	if strings.ContainsAny(c.Name, "#+/") {
+		return fmt.Errorf("invalid view name %s: must not contain #, + or /", c.Name)
+	}
+	s.c = c
+	return nil
+}
+
+func (s *sink) Open(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("opening view sink for view %s", s.c.Name)
+	v, err := publish(s.c.Name, ctx.GetRuleId())
+	if err != nil {
+		return err
+	}
+	s.view = v
+	pubsub.CreatePub(Topic(s.c.Name))
+	return nil
+}
+
+// Collect replaces the view with the result. Each window result is received as a whole in one collect.
+func (s *sink) Collect(ctx api.StreamContext, data interface{}) error {
+	ctx.GetLogger().Debugf("view sink receive %+v", data)
+	d, _, err := transform.TransItem(data, s.c.DataField, s.c.Fields)
+	if err != nil {
+		return fmt.Errorf("fail to select fields %v for data %v", s.c.Fields, data)
+	}
+	var rows []map[string]interface{}
+	switch m := d.(type) {
+	case []map[string]interface{}:
+		rows = m
+	case map[string]interface{}:
+		rows = []map[string]interface{}{m}
+	default:
+		return fmt.Errorf("unrecognized format of %s", d)
+	}
+	s.view.update(rows)
+	topic := Topic(s.c.Name)
+	for _, row := range rows {
+		pubsub.Produce(ctx, topic, copyRow(row))
+	}
+	return nil
+}
+
+func (s *sink) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("closing view sink for view %s", s.c.Name)
+	if s.view != nil {
+		pubsub.RemovePub(Topic(s.c.Name))
+		unpublish(s.view)
+		s.view = nil
+	}
+	return nil
+}
+
+func GetSink() api.Sink {
+	return &sink{}
+}
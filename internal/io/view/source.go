@@ -0,0 +1,68 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package view
+
+import (
+	"github.com/lf-edge/ekuiper/internal/io/memory"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+// source consumes the rows of a view as a stream. It subscribes the memory topic of the view.
+type source struct {
+	api.Source
+}
+
+func (s *source) Configure(datasource string, props map[string]interface{}) error {
+	return s.Source.Configure(Topic(datasource), props)
+}
+
+type lookupsource struct {
+	name string
+	view *View
+}
+
+func (s *lookupsource) Configure(datasource string, _ map[string]interface{}) error {
+	s.name = datasource
+	return nil
+}
+
+func (s *lookupsource) Open(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("lookup source for view %s is opened", s.name)
+	s.view = getView(s.name)
+	return nil
+}
+
+// Lookup reads the rows of the latest result of the view. The view is empty if it is not published yet.
+func (s *lookupsource) Lookup(ctx api.StreamContext, _ []string, keys []string, values []interface{}) ([]api.SourceTuple, error) {
+	ctx.GetLogger().Debugf("lookup view %s with keys %v and values %v", s.name, keys, values)
+	return s.view.Read(keys, values), nil
+}
+
+func (s *lookupsource) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("lookup source for view %s is closing", s.name)
+	if s.view != nil {
+		releaseView(s.view)
+		s.view = nil
+	}
+	return nil
+}
+
+func GetSource() api.Source {
+	return &source{Source: memory.GetSource()}
+}
+
+func GetLookupSource() api.LookupSource {
+	return &lookupsource{}
+}
@@ -0,0 +1,181 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package view publishes the result of a rule, usually a windowed aggregation, as a named materialized view.
+// The view keeps the rows of the latest result in memory and broadcasts them to the memory topic of the view,
+// so that other rules can consume it as a stream or look it up as a table without recomputing the same window.
+package view
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"github.com/lf-edge/ekuiper/pkg/errorx"
+)
+
+// TopicPrefix is the prefix of the memory topics which the views are broadcast to
+const TopicPrefix = "$view/"
+
+// View is the latest result published by a rule
+type View struct {
+	sync.RWMutex
+	name string
+	// the id of the rule which publishes the view, empty if not published
+	ruleId    string
+	rows      []map[string]interface{}
+	timestamp int64
+	// the count of the sinks and lookup sources referring the view
+	refs int
+}
+
+// Info is the view content for the REST API
+type Info struct {
+	Name      string                   `json:"name"`
+	Rule      string                   `json:"rule"`
+	Timestamp int64                    `json:"timestamp"`
+	Rows      []map[string]interface{} `json:"rows"`
+}
+
+var (
+	views = make(map[string]*View)
+	lock  sync.Mutex
+)
+
+// Topic returns the memory topic of the view
+func Topic(name string) string {
+	return TopicPrefix + name
+}
+
+// getView returns the view of the name and creates it if not exist. Call releaseView when it is no longer used.
+func getView(name string) *View {
+	lock.Lock()
+	defer lock.Unlock()
+	v, ok := views[name]
+	if !ok {
+		v = &View{name: name}
+		views[name] = v
+	}
+	v.refs++
+	return v
+}
+
+func releaseView(v *View) {
+	lock.Lock()
+	defer lock.Unlock()
+	v.refs--
+	if v.refs <= 0 {
+		delete(views, v.name)
+	}
+}
+
+// publish claims the view for the rule. Only one rule can publish a view at the same time.
+func publish(name string, ruleId string) (*View, error) {
+	v := getView(name)
+	v.Lock()
+	owner := v.ruleId
+	if owner == "" {
+		v.ruleId = ruleId
+	}
+	v.Unlock()
+	if owner != "" && owner != ruleId {
+		releaseView(v)
+		return nil, fmt.Errorf("view %s is already published by rule %s", name, owner)
+	}
+	return v, nil
+}
+
+// unpublish clears the view when the publishing rule stops
+func unpublish(v *View) {
+	v.Lock()
+	v.ruleId = ""
+	v.rows = nil
+	v.timestamp = 0
+	v.Unlock()
+	releaseView(v)
+}
+
+// update replaces the rows with the latest result
+func (v *View) update(rows []map[string]interface{}) {
+	v.Lock()
+	defer v.Unlock()
+	v.rows = rows
+	v.timestamp = conf.GetNowInMilli()
+}
+
+// Read returns the rows of the latest result which match all the key values
+func (v *View) Read(keys []string, values []interface{}) []api.SourceTuple {
+	v.RLock()
+	defer v.RUnlock()
+	var result []api.SourceTuple
+	for _, row := range v.rows {
+		matched := true
+		for i, k := range keys {
+			if val, ok := row[k]; !ok || cast.ToStringAlways(val) != cast.ToStringAlways(values[i]) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			result = append(result, api.NewDefaultSourceTuple(copyRow(row), map[string]interface{}{"view": v.name}))
+		}
+	}
+	return result
+}
+
+func copyRow(row map[string]interface{}) map[string]interface{} {
+	r := make(map[string]interface{}, len(row))
+	for k, v := range row {
+		r[k] = v
+	}
+	return r
+}
+
+// List returns the names of the published views
+func List() []string {
+	lock.Lock()
+	defer lock.Unlock()
+	names := make([]string, 0, len(views))
+	for name, v := range views {
+		v.RLock()
+		if v.ruleId != "" {
+			names = append(names, name)
+		}
+		v.RUnlock()
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the latest result of a published view
+func Get(name string) (*Info, error) {
+	lock.Lock()
+	v, ok := views[name]
+	lock.Unlock()
+	if ok {
+		v.RLock()
+		defer v.RUnlock()
+		if v.ruleId != "" {
+			rows := make([]map[string]interface{}, len(v.rows))
+			for i, row := range v.rows {
+				rows[i] = copyRow(row)
+			}
+			return &Info{Name: name, Rule: v.ruleId, Timestamp: v.timestamp, Rows: rows}, nil
+		}
+	}
+	return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("view %s is not found", name))
+}
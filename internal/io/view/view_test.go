@@ -0,0 +1,113 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package view
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/topo/state"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/errorx"
+)
+
+func TestView(t *testing.T) {
+	contextLogger := conf.Log.WithField("rule", "test")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger)
+	pubCtx := ctx.WithMeta("rollup", "view", &state.MemoryStore{})
+
+	s := GetSink()
+	require.EqualError(t, s.Configure(map[string]interface{}{}), "property name is required")
+	require.EqualError(t, s.Configure(map[string]interface{}{"name": "a/b"}), "invalid view name a/b: must not contain #, + or /")
+	require.NoError(t, s.Configure(map[string]interface{}{"name": "rollup1m"}))
+
+	// The lookup table can be opened before the view is published
+	ls := GetLookupSource()
+	require.NoError(t, ls.Configure("rollup1m", nil))
+	require.NoError(t, ls.Open(ctx))
+	r, err := ls.Lookup(ctx, nil, []string{"deviceId"}, []interface{}{"d1"})
+	require.NoError(t, err)
+	assert.Empty(t, r)
+	_, err = Get("rollup1m")
+	assert.Error(t, err)
+
+	require.NoError(t, s.Open(pubCtx))
+	// Another rule cannot publish the same view
+	s2 := GetSink()
+	require.NoError(t, s2.Configure(map[string]interface{}{"name": "rollup1m"}))
+	require.EqualError(t, s2.Open(ctx.WithMeta("another", "view", &state.MemoryStore{})), "view rollup1m is already published by rule rollup")
+
+	// Consume the view as a stream
+	src := GetSource()
+	require.NoError(t, src.Configure("rollup1m", map[string]interface{}{}))
+	srcCtx, cancel := ctx.WithMeta("consumer", "source", &state.MemoryStore{}).WithCancel()
+	consumer := make(chan api.SourceTuple, 10)
+	go src.Open(srcCtx, consumer, nil)
+	defer func() {
+		cancel()
+		_ = src.Close(srcCtx)
+	}()
+	// Wait for the subscription
+	time.Sleep(10 * time.Millisecond)
+
+	require.NoError(t, s.Collect(pubCtx, []map[string]interface{}{
+		{"deviceId": "d1", "avg": 20.5},
+		{"deviceId": "d2", "avg": 30.0},
+	}))
+	for _, exp := range []map[string]interface{}{{"deviceId": "d1", "avg": 20.5}, {"deviceId": "d2", "avg": 30.0}} {
+		select {
+		case tuple := <-consumer:
+			assert.Equal(t, exp, tuple.Message())
+		case <-time.After(time.Second):
+			t.Fatal("timeout to receive the view rows")
+		}
+	}
+
+	r, err = ls.Lookup(ctx, nil, []string{"deviceId"}, []interface{}{"d2"})
+	require.NoError(t, err)
+	require.Len(t, r, 1)
+	assert.Equal(t, map[string]interface{}{"deviceId": "d2", "avg": 30.0}, r[0].Message())
+
+	// The next window result replaces the whole view
+	require.NoError(t, s.Collect(pubCtx, map[string]interface{}{"deviceId": "d1", "avg": 21.5}))
+	r, err = ls.Lookup(ctx, nil, []string{"deviceId"}, []interface{}{"d2"})
+	require.NoError(t, err)
+	assert.Empty(t, r)
+	assert.Equal(t, []string{"rollup1m"}, List())
+	info, err := Get("rollup1m")
+	require.NoError(t, err)
+	assert.Equal(t, "rollup", info.Rule)
+	assert.Equal(t, []map[string]interface{}{{"deviceId": "d1", "avg": 21.5}}, info.Rows)
+
+	// The view is cleared when the publishing rule stops
+	require.NoError(t, s.Close(pubCtx))
+	assert.Empty(t, List())
+	r, err = ls.Lookup(ctx, nil, []string{"deviceId"}, []interface{}{"d1"})
+	require.NoError(t, err)
+	assert.Empty(t, r)
+	_, err = Get("rollup1m")
+	var e *errorx.Error
+	require.ErrorAs(t, err, &e)
+	assert.Equal(t, errorx.NOT_FOUND, e.Code())
+	require.NoError(t, ls.Close(ctx))
+	lock.Lock()
+	assert.Empty(t, views)
+	lock.Unlock()
+}
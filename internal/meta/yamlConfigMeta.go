@@ -22,6 +22,7 @@ import (
 
 	"github.com/lf-edge/ekuiper/internal/binder/io"
 	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/pkg/oauth2"
 	"github.com/lf-edge/ekuiper/internal/pkg/store"
 	"github.com/lf-edge/ekuiper/internal/pkg/util"
 	"github.com/lf-edge/ekuiper/pkg/cast"
@@ -155,6 +156,9 @@ func DelConnectionConfKey(plgName, confKey, language string) (err error) {
 		}
 	}()
 	configOperatorKey := fmt.Sprintf(ConnectionCfgOperatorKeyTemplate, plgName)
+	if plgName == oauth2.ConnectionType {
+		oauth2.Invalidate(confKey)
+	}
 	return delConfKey(configOperatorKey, confKey, language)
 }
 
@@ -427,6 +431,9 @@ func AddConnectionConfKey(plgName, confKey, language string, reqField map[string
 	if err := cfgOps.AddConfKey(confKey, reqField); err != nil {
 		return err
 	}
+	if plgName == oauth2.ConnectionType {
+		oauth2.Invalidate(confKey)
+	}
 
 	err = cfgOps.SaveCfgToStorage()
 	if err != nil {
@@ -0,0 +1,241 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package audit records the management operations such as creating, updating, deleting, starting and stopping
+// the rules, streams, plugins and services. The records are saved in a kv table keyed by the time so that they
+// can be queried in order and purged by the retention policy.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/ekuiper/pkg/kv"
+)
+
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+
+	defaultLimit = 100
+)
+
+type Record struct {
+	Id        string `json:"id"`
+	Timestamp int64  `json:"timestamp"`
+	User      string `json:"user"`
+	Role      string `json:"role,omitempty"`
+	// Action is create, update, delete or the operation in the path such as start and stop
+	Action string `json:"action"`
+	// Resource is the kind of the resource such as rule, stream and plugin
+	Resource string `json:"resource"`
+	Name     string `json:"name,omitempty"`
+	Method   string `json:"method"`
+	Path     string `json:"path"`
+	// Body is the request body with the passwords hidden
+	Body interface{} `json:"body,omitempty"`
+	// Diff is the changed fields of the resource by an update
+	Diff   map[string]*Change `json:"diff,omitempty"`
+	Status int                `json:"status"`
+	Result string             `json:"result"`
+	Error  string             `json:"error,omitempty"`
+}
+
+type Change struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// Query filters the records. The empty fields are not filtered.
+type Query struct {
+	User     string
+	Resource string
+	Name     string
+	Action   string
+	Result   string
+	// Since and Until are the unix milliseconds
+	Since int64
+	Until int64
+	// Limit is the max count of the newest records to return
+	Limit int
+}
+
+type Store struct {
+	db         kv.KeyValue
+	retention  time.Duration
+	maxRecords int
+
+	sync.Mutex
+	lastTs int64
+	seq    int
+}
+
+// NewStore creates the audit store. Retention and maxRecords are not limited if they are 0.
+func NewStore(db kv.KeyValue, retention time.Duration, maxRecords int) *Store {
+	return &Store{db: db, retention: retention, maxRecords: maxRecords}
+}
+
+// Add saves the record and assigns its id. The id is ordered by the timestamp.
+func (s *Store) Add(r *Record) error {
+	s.Lock()
+	if r.Timestamp == 0 {
+		r.Timestamp = time.Now().UnixMilli()
+	}
+	if r.Timestamp == s.lastTs {
+		s.seq++
+	} else {
+		s.lastTs = r.Timestamp
+		s.seq = 0
+	}
+	r.Id = fmt.Sprintf("%013d-%04d", r.Timestamp, s.seq)
+	s.Unlock()
+	bs, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return s.db.Set(r.Id, string(bs))
+}
+
+// Query returns the matched records from the newest to the oldest
+func (s *Store) Query(q *Query) ([]*Record, error) {
+	all, err := s.db.All()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(all))
+	for k := range all {
+		keys = append(keys, k)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	result := make([]*Record, 0)
+	for _, k := range keys {
+		r := &Record{}
+		if err := json.Unmarshal([]byte(all[k]), r); err != nil {
+			continue
+		}
+		if !q.match(r) {
+			continue
+		}
+		result = append(result, r)
+		if len(result) >= limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+func (q *Query) match(r *Record) bool {
+	if q.User != "" && q.User != r.User {
+		return false
+	}
+	if q.Resource != "" && q.Resource != r.Resource {
+		return false
+	}
+	if q.Name != "" && q.Name != r.Name {
+		return false
+	}
+	if q.Action != "" && q.Action != r.Action {
+		return false
+	}
+	if q.Result != "" && q.Result != r.Result {
+		return false
+	}
+	if q.Since > 0 && r.Timestamp < q.Since {
+		return false
+	}
+	if q.Until > 0 && r.Timestamp > q.Until {
+		return false
+	}
+	return true
+}
+
+// Purge deletes the records older than the retention or exceeding the max count, and returns the deleted count
+func (s *Store) Purge(now time.Time) (int, error) {
+	keys, err := s.db.Keys()
+	if err != nil {
+		return 0, err
+	}
+	sort.Strings(keys)
+	expired := 0
+	if s.retention > 0 {
+		bound := fmt.Sprintf("%013d", now.Add(-s.retention).UnixMilli())
+		expired = sort.SearchStrings(keys, bound)
+	}
+	if s.maxRecords > 0 && len(keys)-expired > s.maxRecords {
+		expired = len(keys) - s.maxRecords
+	}
+	for _, k := range keys[:expired] {
+		if err := s.db.Delete(k); err != nil {
+			return 0, err
+		}
+	}
+	return expired, nil
+}
+
+// Diff returns the changed fields between the old and new definition. The nested fields are compared as a whole.
+func Diff(old, new map[string]interface{}) map[string]*Change {
+	result := make(map[string]*Change)
+	for k, ov := range old {
+		nv, ok := new[k]
+		if !ok || !reflect.DeepEqual(ov, nv) {
+			result[k] = &Change{Old: ov, New: nv}
+		}
+	}
+	for k, nv := range new {
+		if _, ok := old[k]; !ok {
+			result[k] = &Change{New: nv}
+		}
+	}
+	return result
+}
+
+// ParseResource returns the kind of the resource by the first part of the route path such as rule for /rules/{name}
+func ParseResource(path string) string {
+	if strings.HasPrefix(path, "/namespaces/{namespace}/") {
+		path = strings.TrimPrefix(path, "/namespaces/{namespace}")
+	}
+	first, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	return strings.TrimSuffix(first, "s")
+}
+
+// ParseAction returns the action of the request by the method and the last part of the route path
+func ParseAction(method, path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	last := parts[len(parts)-1]
+	if len(parts) > 1 && !strings.HasPrefix(last, "{") {
+		switch last {
+		case "start", "stop", "restart", "reset_state", "backfill", "snapshot", "replay", "register", "import", "cancel", "rollback", "validate", "validate-plan", "unittest", "bulk":
+			return last
+		}
+	}
+	switch method {
+	case "POST":
+		return "create"
+	case "PUT", "PATCH":
+		return "update"
+	case "DELETE":
+		return "delete"
+	default:
+		return strings.ToLower(method)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
+)
+
+func TestStore(t *testing.T) {
+	require.NoError(t, store.SetupDefault(t.TempDir()))
+	db, err := store.GetKV("audit")
+	require.NoError(t, err)
+	s := NewStore(db, time.Hour, 3)
+
+	now := time.Now()
+	records := []*Record{
+		{Timestamp: now.Add(-2 * time.Hour).UnixMilli(), User: "alice", Action: "create", Resource: "rule", Name: "rule1", Result: ResultSuccess},
+		{Timestamp: now.Add(-time.Minute).UnixMilli(), User: "alice", Action: "start", Resource: "rule", Name: "rule1", Result: ResultSuccess},
+		{Timestamp: now.Add(-time.Minute).UnixMilli(), User: "bob", Action: "delete", Resource: "stream", Name: "demo", Result: ResultFailure},
+		{Timestamp: now.UnixMilli(), User: "bob", Action: "update", Resource: "rule", Name: "rule1", Result: ResultSuccess},
+	}
+	for _, r := range records {
+		require.NoError(t, s.Add(r))
+	}
+	assert.NotEqual(t, records[1].Id, records[2].Id)
+
+	result, err := s.Query(&Query{Resource: "rule"})
+	require.NoError(t, err)
+	require.Len(t, result, 3)
+	assert.Equal(t, "update", result[0].Action)
+	assert.Equal(t, "create", result[2].Action)
+
+	result, err = s.Query(&Query{User: "bob", Result: ResultFailure})
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, "demo", result[0].Name)
+
+	result, err = s.Query(&Query{Since: now.Add(-time.Hour).UnixMilli(), Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.Equal(t, "bob", result[0].User)
+
+	n, err := s.Purge(now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	result, err = s.Query(&Query{})
+	require.NoError(t, err)
+	assert.Len(t, result, 3)
+}
+
+func TestDiff(t *testing.T) {
+	diff := Diff(map[string]interface{}{
+		"id":      "rule1",
+		"sql":     "SELECT * FROM demo",
+		"actions": []interface{}{map[string]interface{}{"log": map[string]interface{}{}}},
+		"options": map[string]interface{}{"qos": 0},
+	}, map[string]interface{}{
+		"id":      "rule1",
+		"sql":     "SELECT a FROM demo",
+		"actions": []interface{}{map[string]interface{}{"log": map[string]interface{}{}}},
+		"tags":    []interface{}{"t1"},
+	})
+	assert.Equal(t, map[string]*Change{
+		"sql":     {Old: "SELECT * FROM demo", New: "SELECT a FROM demo"},
+		"options": {Old: map[string]interface{}{"qos": 0}},
+		"tags":    {New: []interface{}{"t1"}},
+	}, diff)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		method   string
+		path     string
+		resource string
+		action   string
+	}{
+		{"POST", "/rules", "rule", "create"},
+		{"PUT", "/rules/{name}", "rule", "update"},
+		{"POST", "/rules/{name}/stop", "rule", "stop"},
+		{"POST", "/namespaces/{namespace}/rules/{name}/start", "rule", "start"},
+		{"POST", "/rules/bulk", "rule", "bulk"},
+		{"DELETE", "/namespaces/{namespace}", "namespace", "delete"},
+		{"DELETE", "/streams/{name}", "stream", "delete"},
+		{"POST", "/plugins/sources", "plugin", "create"},
+		{"PUT", "/services/{name}", "service", "update"},
+		{"PATCH", "/configs", "config", "update"},
+		{"POST", "/data/import", "data", "import"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.resource, ParseResource(tt.path), tt.path)
+		assert.Equal(t, tt.action, ParseAction(tt.method, tt.path), tt.path)
+	}
+}
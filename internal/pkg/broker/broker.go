@@ -0,0 +1,88 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package broker provides the embedded MQTT broker so that a standalone deployment can accept the device connections directly.
+package broker
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+)
+
+// Broker is the embedded MQTT broker
+type Broker struct {
+	server *mqtt.Server
+}
+
+// New creates the broker and starts to listen on the configured addresses
+func New(c *conf.BrokerConf) (*Broker, error) {
+	server := mqtt.New(&mqtt.Options{
+		Logger: slog.New(slog.NewTextHandler(conf.Log.Out, &slog.HandlerOptions{Level: slog.LevelWarn})),
+	})
+	if err := server.AddHook(new(auth.Hook), &auth.Options{Ledger: newLedger(c.Users)}); err != nil {
+		return nil, fmt.Errorf("fail to set the broker authentication: %v", err)
+	}
+	tcpConf := listeners.Config{ID: "tcp", Address: c.Address}
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("fail to load the broker certification: %v", err)
+		}
+		tcpConf.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	}
+	if err := server.AddListener(listeners.NewTCP(tcpConf)); err != nil {
+		return nil, fmt.Errorf("fail to listen the broker address %s: %v", c.Address, err)
+	}
+	if c.WebsocketAddress != "" {
+		if err := server.AddListener(listeners.NewWebsocket(listeners.Config{ID: "ws", Address: c.WebsocketAddress})); err != nil {
+			_ = server.Close()
+			return nil, fmt.Errorf("fail to listen the broker websocket address %s: %v", c.WebsocketAddress, err)
+		}
+	}
+	if err := server.Serve(); err != nil {
+		_ = server.Close()
+		return nil, err
+	}
+	return &Broker{server: server}, nil
+}
+
+// newLedger allows all clients if no user is configured. Otherwise, only the configured users are allowed.
+func newLedger(users map[string]string) *auth.Ledger {
+	if len(users) == 0 {
+		return &auth.Ledger{
+			Auth: auth.AuthRules{{Allow: true}},
+			ACL:  auth.ACLRules{{}},
+		}
+	}
+	l := &auth.Ledger{
+		Users: make(auth.Users, len(users)),
+		ACL:   auth.ACLRules{{}},
+	}
+	for u, p := range users {
+		l.Users[u] = auth.UserRule{Username: auth.RString(u), Password: auth.RString(p)}
+	}
+	return l
+}
+
+// Close disconnects all the clients and stops the listeners
+func (b *Broker) Close() error {
+	return b.server.Close()
+}
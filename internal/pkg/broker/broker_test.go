@@ -0,0 +1,91 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	pahoMqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+)
+
+func freeAddress(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+	return addr
+}
+
+func connect(addr, user, password string) (pahoMqtt.Client, error) {
+	opts := pahoMqtt.NewClientOptions().AddBroker("tcp://" + addr).SetUsername(user).SetPassword(password).SetConnectTimeout(time.Second)
+	cli := pahoMqtt.NewClient(opts)
+	token := cli.Connect()
+	token.WaitTimeout(2 * time.Second)
+	return cli, token.Error()
+}
+
+func TestBroker(t *testing.T) {
+	addr := freeAddress(t)
+	b, err := New(&conf.BrokerConf{Address: addr})
+	require.NoError(t, err)
+	defer b.Close()
+
+	sub, err := connect(addr, "", "")
+	require.NoError(t, err)
+	defer sub.Disconnect(0)
+	received := make(chan string, 1)
+	token := sub.Subscribe("devices/+", 0, func(_ pahoMqtt.Client, msg pahoMqtt.Message) {
+		received <- msg.Topic() + " " + string(msg.Payload())
+	})
+	require.True(t, token.WaitTimeout(2*time.Second))
+	require.NoError(t, token.Error())
+
+	pub, err := connect(addr, "", "")
+	require.NoError(t, err)
+	defer pub.Disconnect(0)
+	token = pub.Publish("devices/d1", 0, false, `{"temperature":20}`)
+	require.True(t, token.WaitTimeout(2*time.Second))
+	select {
+	case msg := <-received:
+		assert.Equal(t, `devices/d1 {"temperature":20}`, msg)
+	case <-time.After(2 * time.Second):
+		t.Fatal("message not received")
+	}
+
+	// Address in use
+	_, err = New(&conf.BrokerConf{Address: addr})
+	assert.Error(t, err)
+}
+
+func TestBrokerUsers(t *testing.T) {
+	addr := freeAddress(t)
+	b, err := New(&conf.BrokerConf{Address: addr, Users: map[string]string{"admin": "public"}})
+	require.NoError(t, err)
+	defer b.Close()
+
+	cli, err := connect(addr, "admin", "public")
+	require.NoError(t, err)
+	cli.Disconnect(0)
+	_, err = connect(addr, "admin", "wrong")
+	assert.Error(t, err)
+	_, err = connect(addr, "", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,270 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cluster places the rules across the eKuiper nodes which share a control plane. The nodes report their
+// heartbeats to the control plane. Each node sees the same live nodes and computes the same placement by the
+// rendezvous hashing, so no leader is needed. When a node dies, its heartbeat expires on the store side and its rules
+// are placed on the other nodes. A node holds the lease of a rule while running it, so the rule starts on the new node
+// only after the lease is released or expired.
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/ekuiper/pkg/kv"
+)
+
+type Node struct {
+	Id     string            `json:"id"`
+	Labels map[string]string `json:"labels,omitempty"`
+	// Heartbeat is the unix milliseconds of the last heartbeat by the clock of the node. It is informative only, the
+	// liveness is decided by the expiry on the store side.
+	Heartbeat int64 `json:"heartbeat"`
+}
+
+// Match returns whether the node has all the labels of the selector
+func (n *Node) Match(selector map[string]string) bool {
+	for k, v := range selector {
+		if n.Labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// ControlPlane is the membership and the rule leases shared by all the nodes
+type ControlPlane interface {
+	// Heartbeat registers or refreshes the node which expires after the ttl without heartbeat
+	Heartbeat(n *Node, ttl time.Duration) error
+	// Nodes returns the live nodes
+	Nodes() ([]*Node, error)
+	// Leave removes the node
+	Leave(id string) error
+	// Acquire takes or renews the lease of the rule for the node. Return false if another node holds it.
+	Acquire(ruleId string, nodeId string, ttl time.Duration) (bool, error)
+	// Release drops the lease of the rule if the node holds it
+	Release(ruleId string, nodeId string) error
+}
+
+// Store is a kv table of the shared store which expires the keys on the store side such as redis
+type Store interface {
+	kv.KeyValue
+	// SetEx sets the key which expires after the ttl
+	SetEx(key string, value interface{}, ttl time.Duration) error
+	// Acquire sets the key to the value with the ttl if the key does not exist or already holds the value. Return
+	// whether the key holds the value.
+	Acquire(key string, value interface{}, ttl time.Duration) (bool, error)
+	// Release deletes the key if it holds the value
+	Release(key string, value interface{}) error
+}
+
+// kvControlPlane saves the nodes and the leases in the kv tables of the shared store
+type kvControlPlane struct {
+	nodes  Store
+	leases Store
+}
+
+// NewKVControlPlane creates the control plane on the tables of the nodes and the leases. The tables must expire the
+// keys on the store side so that the liveness does not depend on the clocks of the nodes.
+func NewKVControlPlane(nodes, leases kv.KeyValue) (ControlPlane, error) {
+	ns, ok := nodes.(Store)
+	if !ok {
+		return nil, fmt.Errorf("the store does not expire the keys, use the shared store such as redis")
+	}
+	ls, ok := leases.(Store)
+	if !ok {
+		return nil, fmt.Errorf("the store does not expire the keys, use the shared store such as redis")
+	}
+	return &kvControlPlane{nodes: ns, leases: ls}, nil
+}
+
+func (p *kvControlPlane) Heartbeat(n *Node, ttl time.Duration) error {
+	b, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	return p.nodes.SetEx(n.Id, string(b), ttl)
+}
+
+func (p *kvControlPlane) Nodes() ([]*Node, error) {
+	all, err := p.nodes.All()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Node, 0, len(all))
+	for id, v := range all {
+		n := &Node{}
+		if err := json.Unmarshal([]byte(v), n); err != nil {
+			return nil, fmt.Errorf("invalid node %s: %v", id, err)
+		}
+		result = append(result, n)
+	}
+	return result, nil
+}
+
+func (p *kvControlPlane) Leave(id string) error {
+	return p.nodes.Delete(id)
+}
+
+func (p *kvControlPlane) Acquire(ruleId string, nodeId string, ttl time.Duration) (bool, error) {
+	return p.leases.Acquire(ruleId, nodeId, ttl)
+}
+
+func (p *kvControlPlane) Release(ruleId string, nodeId string) error {
+	return p.leases.Release(ruleId, nodeId)
+}
+
+// Cluster is the view of the cluster from a node. It is safe to call concurrently.
+type Cluster struct {
+	self    Node
+	cp      ControlPlane
+	timeout time.Duration
+
+	mu sync.RWMutex
+	// alive is the live nodes sorted by id in the last sync
+	alive []*Node
+	// lastSync is the time of the last successful heartbeat by the clock of this node. The node fences itself after
+	// half of the timeout without it, before its leases expire on the store side, so that its rules are not run twice
+	// when it is isolated from the control plane.
+	lastSync time.Time
+}
+
+func New(id string, labels map[string]string, cp ControlPlane, timeout time.Duration) *Cluster {
+	return &Cluster{
+		self:    Node{Id: id, Labels: labels},
+		cp:      cp,
+		timeout: timeout,
+	}
+}
+
+func (c *Cluster) Self() string {
+	return c.self.Id
+}
+
+// Sync reports the heartbeat and refreshes the live nodes at now. Return whether the live nodes change.
+func (c *Cluster) Sync(now time.Time) (bool, error) {
+	n := c.self
+	n.Heartbeat = now.UnixMilli()
+	err := c.cp.Heartbeat(&n, c.timeout)
+	var nodes []*Node
+	if err == nil {
+		nodes, err = c.cp.Nodes()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		if now.Sub(c.lastSync) <= c.timeout/2 {
+			return false, err
+		}
+		// Isolated, no rule is placed on any node from this view
+		changed := len(c.alive) > 0
+		c.alive = nil
+		return changed, err
+	}
+	c.lastSync = now
+	// The dead nodes have expired on the store side
+	alive := nodes
+	sort.Slice(alive, func(i, j int) bool {
+		return alive[i].Id < alive[j].Id
+	})
+	changed := !sameNodes(c.alive, alive)
+	c.alive = alive
+	return changed, nil
+}
+
+// Leave removes the node from the cluster so that its rules fail over immediately
+func (c *Cluster) Leave() error {
+	return c.cp.Leave(c.self.Id)
+}
+
+// Acquire takes or renews the lease of the rule before running it on this node. The lease expires after the node
+// timeout on the store side if not renewed.
+func (c *Cluster) Acquire(ruleId string) (bool, error) {
+	return c.cp.Acquire(ruleId, c.self.Id, c.timeout)
+}
+
+// Release drops the lease of the rule after it stops on this node so that the new owner takes it over immediately
+func (c *Cluster) Release(ruleId string) error {
+	return c.cp.Release(ruleId, c.self.Id)
+}
+
+// Alive returns the live nodes sorted by id
+func (c *Cluster) Alive() []*Node {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.alive
+}
+
+// Owner returns the node to run the rule. Return empty if no live node matches the selector.
+func (c *Cluster) Owner(ruleId string, selector map[string]string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return Place(ruleId, selector, c.alive)
+}
+
+// IsOwner returns whether the rule is placed on this node
+func (c *Cluster) IsOwner(ruleId string, selector map[string]string) bool {
+	return c.Owner(ruleId, selector) == c.self.Id
+}
+
+// Place selects the node of the highest score by the rendezvous hashing among the nodes which match the selector.
+// Only the rules of a dead node move when the nodes change.
+func Place(ruleId string, selector map[string]string, nodes []*Node) string {
+	var (
+		owner string
+		top   uint64
+	)
+	for _, n := range nodes {
+		if !n.Match(selector) {
+			continue
+		}
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(n.Id))
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(ruleId))
+		score := mix(h.Sum64())
+		if owner == "" || score > top || (score == top && n.Id < owner) {
+			owner, top = n.Id, score
+		}
+	}
+	return owner
+}
+
+// mix is the finalizer of murmur3 to spread the fnv hash whose inputs only differ in few bytes
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+func sameNodes(a, b []*Node) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Id != b[i].Id || !maps.Equal(a[i].Labels, b[i].Labels) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,233 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/pkg/kv"
+)
+
+func TestPlace(t *testing.T) {
+	nodes := []*Node{
+		{Id: "n1", Labels: map[string]string{"zone": "a"}},
+		{Id: "n2", Labels: map[string]string{"zone": "b", "gpu": "true"}},
+		{Id: "n3", Labels: map[string]string{"zone": "a"}},
+	}
+	assert.Equal(t, "n2", Place("rule1", map[string]string{"gpu": "true"}, nodes))
+	assert.Equal(t, "", Place("rule1", map[string]string{"zone": "c"}, nodes))
+	// Spread the rules and only move the rules of the removed node
+	placed := make(map[string]string)
+	count := make(map[string]int)
+	for i := 0; i < 300; i++ {
+		id := fmt.Sprintf("rule%d", i)
+		placed[id] = Place(id, nil, nodes)
+		count[placed[id]]++
+	}
+	for _, n := range nodes {
+		assert.Greater(t, count[n.Id], 50)
+	}
+	for id, owner := range placed {
+		p := Place(id, nil, []*Node{nodes[0], nodes[2]})
+		if owner != "n2" {
+			assert.Equal(t, owner, p)
+		} else {
+			assert.NotEqual(t, "n2", p)
+		}
+	}
+}
+
+// memStore is a kv table expiring the keys by a fake clock like redis
+type memStore struct {
+	kv.KeyValue
+	now    time.Time
+	values map[string]string
+	expiry map[string]time.Time
+}
+
+func newMemStore(now time.Time) *memStore {
+	return &memStore{now: now, values: make(map[string]string), expiry: make(map[string]time.Time)}
+}
+
+func (s *memStore) expire() {
+	for k, e := range s.expiry {
+		if !s.now.Before(e) {
+			delete(s.values, k)
+			delete(s.expiry, k)
+		}
+	}
+}
+
+func (s *memStore) SetEx(key string, value interface{}, ttl time.Duration) error {
+	s.values[key] = value.(string)
+	s.expiry[key] = s.now.Add(ttl)
+	return nil
+}
+
+func (s *memStore) Acquire(key string, value interface{}, ttl time.Duration) (bool, error) {
+	s.expire()
+	if v, ok := s.values[key]; ok && v != value.(string) {
+		return false, nil
+	}
+	return true, s.SetEx(key, value, ttl)
+}
+
+func (s *memStore) Release(key string, value interface{}) error {
+	s.expire()
+	if s.values[key] == value.(string) {
+		return s.Delete(key)
+	}
+	return nil
+}
+
+func (s *memStore) Delete(key string) error {
+	delete(s.values, key)
+	delete(s.expiry, key)
+	return nil
+}
+
+func (s *memStore) All() (map[string]string, error) {
+	s.expire()
+	result := make(map[string]string, len(s.values))
+	for k, v := range s.values {
+		result[k] = v
+	}
+	return result, nil
+}
+
+type failingControlPlane struct {
+	ControlPlane
+	fail bool
+}
+
+func (p *failingControlPlane) Heartbeat(n *Node, ttl time.Duration) error {
+	if p.fail {
+		return errors.New("connection refused")
+	}
+	return p.ControlPlane.Heartbeat(n, ttl)
+}
+
+func TestNewKVControlPlane(t *testing.T) {
+	require.NoError(t, store.SetupDefault(t.TempDir()))
+	db, err := store.GetKV("cluster")
+	require.NoError(t, err)
+	_, err = NewKVControlPlane(db, db)
+	assert.EqualError(t, err, "the store does not expire the keys, use the shared store such as redis")
+}
+
+func TestSync(t *testing.T) {
+	now := time.UnixMilli(100000)
+	db := newMemStore(now)
+	kcp, err := NewKVControlPlane(db, newMemStore(now))
+	require.NoError(t, err)
+	cp := &failingControlPlane{ControlPlane: kcp}
+	c1 := New("n1", nil, cp, 10*time.Second)
+	c2 := New("n2", map[string]string{"zone": "b"}, kcp, 10*time.Second)
+
+	changed, err := c1.Sync(now)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	changed, err = c2.Sync(now)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	changed, err = c1.Sync(now.Add(2 * time.Second))
+	require.NoError(t, err)
+	assert.True(t, changed)
+	require.Len(t, c1.Alive(), 2)
+	assert.Equal(t, "n2", c1.Alive()[1].Id)
+	assert.True(t, c1.IsOwner("rule1", nil) != c2.IsOwner("rule1", nil))
+	assert.Equal(t, "n2", c1.Owner("rule1", map[string]string{"zone": "b"}))
+
+	// The clock of n1 is far ahead, which does not affect the liveness
+	changed, err = c1.Sync(now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	// n2 dies and expires in the store, its rules fail over to n1
+	db.now = now.Add(11 * time.Second)
+	changed, err = c1.Sync(now.Add(time.Hour + 2*time.Second))
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Len(t, c1.Alive(), 1)
+	assert.True(t, c1.IsOwner("rule1", nil))
+	assert.Equal(t, "", c1.Owner("rule1", map[string]string{"zone": "b"}))
+
+	// n1 is isolated from the control plane and fences itself after half of the timeout
+	cp.fail = true
+	changed, err = c1.Sync(now.Add(time.Hour + 6*time.Second))
+	assert.Error(t, err)
+	assert.False(t, changed)
+	assert.True(t, c1.IsOwner("rule1", nil))
+	changed, err = c1.Sync(now.Add(time.Hour + 8*time.Second))
+	assert.Error(t, err)
+	assert.True(t, changed)
+	assert.False(t, c1.IsOwner("rule1", nil))
+	// Recover
+	cp.fail = false
+	changed, err = c1.Sync(now.Add(time.Hour + 10*time.Second))
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.True(t, c1.IsOwner("rule1", nil))
+
+	require.NoError(t, c2.Leave())
+	nodes, err := cp.Nodes()
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	assert.Equal(t, "n1", nodes[0].Id)
+}
+
+func TestLease(t *testing.T) {
+	now := time.UnixMilli(100000)
+	leases := newMemStore(now)
+	cp, err := NewKVControlPlane(newMemStore(now), leases)
+	require.NoError(t, err)
+	c1 := New("n1", nil, cp, 10*time.Second)
+	c2 := New("n2", nil, cp, 10*time.Second)
+
+	ok, err := c1.Acquire("rule1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	// The new owner waits for the previous one to stop the rule
+	ok, err = c2.Acquire("rule1")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	// Renew
+	leases.now = now.Add(8 * time.Second)
+	ok, err = c1.Acquire("rule1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	leases.now = now.Add(12 * time.Second)
+	ok, err = c2.Acquire("rule1")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	// Release
+	require.NoError(t, c2.Release("rule1"))
+	require.NoError(t, c1.Release("rule1"))
+	ok, err = c2.Acquire("rule1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	// Expire without renewing
+	leases.now = now.Add(30 * time.Second)
+	ok, err = c1.Acquire("rule1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
@@ -0,0 +1,206 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geo
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/pkg/errorx"
+	"github.com/lf-edge/ekuiper/pkg/kv"
+)
+
+const fenceTable = "geofence"
+
+var nameRegex = regexp.MustCompile(`^[A-Za-z0-9_\-]+$`)
+
+// Fence is a named area which is either a polygon or a circle
+type Fence struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Polygon is the vertices of the polygon fence
+	Polygon []Point `json:"polygon,omitempty"`
+	// Center and Radius in meters define the circle fence
+	Center *Point  `json:"center,omitempty"`
+	Radius float64 `json:"radius,omitempty"`
+}
+
+// Contains checks if the point is inside the fence
+func (f *Fence) Contains(p Point) bool {
+	if f.Center != nil {
+		return Distance(*f.Center, p) <= f.Radius
+	}
+	return InPolygon(p, f.Polygon)
+}
+
+func (f *Fence) validate() error {
+	if f.Name == "" {
+		return fmt.Errorf("geofence name is required")
+	}
+	if !nameRegex.MatchString(f.Name) {
+		return fmt.Errorf("invalid geofence name %s, only letters, digits, '_' and '-' are allowed", f.Name)
+	}
+	switch {
+	case f.Center != nil && len(f.Polygon) > 0:
+		return fmt.Errorf("geofence %s must be either a polygon or a circle", f.Name)
+	case f.Center != nil:
+		if f.Radius <= 0 {
+			return fmt.Errorf("the radius of geofence %s must be positive", f.Name)
+		}
+		return f.Center.validate()
+	case len(f.Polygon) >= 3:
+		for _, p := range f.Polygon {
+			if err := p.validate(); err != nil {
+				return err
+			}
+		}
+		return nil
+	case len(f.Polygon) > 0:
+		return fmt.Errorf("the polygon of geofence %s must have at least 3 points", f.Name)
+	default:
+		return fmt.Errorf("geofence %s must have a polygon or a center and radius", f.Name)
+	}
+}
+
+// The fences are cached in memory and written through to the KV store
+var (
+	lock   sync.Mutex
+	db     kv.KeyValue
+	fences map[string]*Fence
+)
+
+// load reads all the fences from the store at the first time. Must be called with the lock held.
+func load() error {
+	if fences != nil {
+		return nil
+	}
+	d, err := store.GetKV(fenceTable)
+	if err != nil {
+		return fmt.Errorf("can not initialize store for the geofences at path '%s': %v", fenceTable, err)
+	}
+	keys, err := d.Keys()
+	if err != nil {
+		return err
+	}
+	m := make(map[string]*Fence, len(keys))
+	for _, k := range keys {
+		f := &Fence{}
+		if ok, err := d.Get(k, f); ok && err == nil {
+			m[k] = f
+		}
+	}
+	db, fences = d, m
+	return nil
+}
+
+func notFound(name string) error {
+	return errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("geofence %s is not found", name))
+}
+
+// Create saves the new fence
+func Create(f *Fence) error {
+	if err := f.validate(); err != nil {
+		return err
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if err := load(); err != nil {
+		return err
+	}
+	if _, ok := fences[f.Name]; ok {
+		return fmt.Errorf("geofence %s already exists", f.Name)
+	}
+	return save(f)
+}
+
+// Update replaces the fence, the running rules use the new fence at once
+func Update(f *Fence) error {
+	if err := f.validate(); err != nil {
+		return err
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if err := load(); err != nil {
+		return err
+	}
+	if _, ok := fences[f.Name]; !ok {
+		return notFound(f.Name)
+	}
+	return save(f)
+}
+
+func save(f *Fence) error {
+	if err := db.Set(f.Name, f); err != nil {
+		return err
+	}
+	fences[f.Name] = f
+	return nil
+}
+
+func Delete(name string) error {
+	lock.Lock()
+	defer lock.Unlock()
+	if err := load(); err != nil {
+		return err
+	}
+	if _, ok := fences[name]; !ok {
+		return notFound(name)
+	}
+	if err := db.Delete(name); err != nil {
+		return err
+	}
+	delete(fences, name)
+	return nil
+}
+
+func Get(name string) (*Fence, error) {
+	lock.Lock()
+	defer lock.Unlock()
+	if err := load(); err != nil {
+		return nil, err
+	}
+	f, ok := fences[name]
+	if !ok {
+		return nil, notFound(name)
+	}
+	return f, nil
+}
+
+// List returns the sorted names of all the fences
+func List() ([]string, error) {
+	lock.Lock()
+	defer lock.Unlock()
+	if err := load(); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(fences))
+	for k := range fences {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Within checks if the point is inside the named fence
+func Within(name string, p Point) (bool, error) {
+	f, err := Get(name)
+	if err != nil {
+		return false, err
+	}
+	return f.Contains(p), nil
+}
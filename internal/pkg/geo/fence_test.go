@@ -0,0 +1,114 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/testx"
+	"github.com/lf-edge/ekuiper/pkg/errorx"
+)
+
+func init() {
+	testx.InitEnv("geo")
+}
+
+func TestFence(t *testing.T) {
+	lock.Lock()
+	fences = nil
+	lock.Unlock()
+	depot := &Fence{
+		Name:    "depot",
+		Polygon: []Point{{Lat: 0, Lon: 0}, {Lat: 0, Lon: 1}, {Lat: 1, Lon: 1}, {Lat: 1, Lon: 0}},
+	}
+	hq := &Fence{
+		Name:   "hq",
+		Center: &Point{Lat: 31.2304, Lon: 121.4737},
+		Radius: 1000,
+	}
+	require.NoError(t, Create(depot))
+	require.NoError(t, Create(hq))
+	defer func() {
+		_ = Delete("depot")
+		_ = Delete("hq")
+	}()
+	assert.EqualError(t, Create(hq), "geofence hq already exists")
+
+	names, err := List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"depot", "hq"}, names)
+
+	in, err := Within("depot", Point{Lat: 0.5, Lon: 0.5})
+	require.NoError(t, err)
+	assert.True(t, in)
+	in, err = Within("hq", Point{Lat: 31.2304, Lon: 121.48})
+	require.NoError(t, err)
+	assert.True(t, in)
+	in, err = Within("hq", Point{Lat: 31.2304, Lon: 121.49})
+	require.NoError(t, err)
+	assert.False(t, in)
+	_, err = Within("none", Point{})
+	assert.EqualError(t, err, "geofence none is not found")
+	code, ok := errorx.GetErrorCode(err)
+	assert.True(t, ok)
+	assert.Equal(t, errorx.NOT_FOUND, code)
+
+	require.NoError(t, Update(&Fence{Name: "hq", Center: &Point{Lat: 31.2304, Lon: 121.4737}, Radius: 5000}))
+	in, err = Within("hq", Point{Lat: 31.2304, Lon: 121.49})
+	require.NoError(t, err)
+	assert.True(t, in)
+	assert.Error(t, Update(&Fence{Name: "none", Center: &Point{}, Radius: 1}))
+
+	// reload from the store
+	lock.Lock()
+	fences = nil
+	lock.Unlock()
+	f, err := Get("hq")
+	require.NoError(t, err)
+	assert.Equal(t, 5000.0, f.Radius)
+
+	require.NoError(t, Delete("depot"))
+	_, err = Get("depot")
+	assert.Error(t, err)
+	assert.Error(t, Delete("depot"))
+}
+
+func TestFenceValidate(t *testing.T) {
+	tests := []struct {
+		f   *Fence
+		err string
+	}{
+		{f: &Fence{}, err: "geofence name is required"},
+		{f: &Fence{Name: "a b"}, err: "invalid geofence name a b, only letters, digits, '_' and '-' are allowed"},
+		{f: &Fence{Name: "a"}, err: "geofence a must have a polygon or a center and radius"},
+		{f: &Fence{Name: "a", Polygon: []Point{{}, {}}}, err: "the polygon of geofence a must have at least 3 points"},
+		{f: &Fence{Name: "a", Polygon: []Point{{}, {}, {Lat: 100}}}, err: "latitude 100 is out of range [-90, 90]"},
+		{f: &Fence{Name: "a", Center: &Point{}}, err: "the radius of geofence a must be positive"},
+		{f: &Fence{Name: "a", Center: &Point{Lon: 200}, Radius: 1}, err: "longitude 200 is out of range [-180, 180]"},
+		{f: &Fence{Name: "a", Center: &Point{}, Radius: 1, Polygon: []Point{{}, {}, {}}}, err: "geofence a must be either a polygon or a circle"},
+		{f: &Fence{Name: "a", Center: &Point{}, Radius: 1}},
+	}
+	for i, tt := range tests {
+		err := tt.f.validate()
+		if tt.err == "" {
+			assert.NoError(t, err, "case %d", i)
+		} else {
+			assert.EqualError(t, err, tt.err, "case %d", i)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package geo provides the geospatial calculations and the named geofences which are used by the geo functions.
+package geo
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// earthRadius is the mean radius of the earth in meters
+const earthRadius = 6371008.8
+
+const base32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Point is a location with the latitude and longitude in degrees
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+func (p Point) validate() error {
+	if p.Lat < -90 || p.Lat > 90 {
+		return fmt.Errorf("latitude %v is out of range [-90, 90]", p.Lat)
+	}
+	if p.Lon < -180 || p.Lon > 180 {
+		return fmt.Errorf("longitude %v is out of range [-180, 180]", p.Lon)
+	}
+	return nil
+}
+
+// Distance returns the great-circle distance in meters between two points by the haversine formula
+func Distance(p1, p2 Point) float64 {
+	lat1, lat2 := toRadians(p1.Lat), toRadians(p2.Lat)
+	dLat := lat2 - lat1
+	dLon := toRadians(p2.Lon - p1.Lon)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+func toRadians(d float64) float64 {
+	return d * math.Pi / 180
+}
+
+// InPolygon checks if the point is inside the polygon by the ray casting algorithm. The edges are
+// treated as straight lines in the latitude and longitude plane, which is accurate enough for the
+// small areas such as the geofences. The polygon is closed automatically.
+func InPolygon(p Point, polygon []Point) bool {
+	in := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) &&
+			p.Lon < (b.Lon-a.Lon)*(p.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			in = !in
+		}
+	}
+	return in
+}
+
+// GeohashEncode encodes the point into a geohash with the precision from 1 to 12
+func GeohashEncode(p Point, precision int) (string, error) {
+	if err := p.validate(); err != nil {
+		return "", err
+	}
+	if precision < 1 || precision > 12 {
+		return "", fmt.Errorf("geohash precision must be between 1 and 12 but got %d", precision)
+	}
+	latRange := [2]float64{-90, 90}
+	lonRange := [2]float64{-180, 180}
+	var sb strings.Builder
+	bit, ch, even := 0, 0, true
+	for sb.Len() < precision {
+		r, v := &latRange, p.Lat
+		if even {
+			r, v = &lonRange, p.Lon
+		}
+		mid := (r[0] + r[1]) / 2
+		ch <<= 1
+		if v >= mid {
+			ch |= 1
+			r[0] = mid
+		} else {
+			r[1] = mid
+		}
+		even = !even
+		bit++
+		if bit == 5 {
+			sb.WriteByte(base32[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return sb.String(), nil
+}
+
+// GeohashDecode decodes the geohash into the center point of its cell
+func GeohashDecode(hash string) (Point, error) {
+	if hash == "" {
+		return Point{}, fmt.Errorf("geohash must not be empty")
+	}
+	latRange := [2]float64{-90, 90}
+	lonRange := [2]float64{-180, 180}
+	even := true
+	for _, c := range strings.ToLower(hash) {
+		idx := strings.IndexRune(base32, c)
+		if idx < 0 {
+			return Point{}, fmt.Errorf("invalid geohash %s", hash)
+		}
+		for i := 4; i >= 0; i-- {
+			r := &latRange
+			if even {
+				r = &lonRange
+			}
+			mid := (r[0] + r[1]) / 2
+			if idx>>i&1 == 1 {
+				r[0] = mid
+			} else {
+				r[1] = mid
+			}
+			even = !even
+		}
+	}
+	return Point{
+		Lat: (latRange[0] + latRange[1]) / 2,
+		Lon: (lonRange[0] + lonRange[1]) / 2,
+	}, nil
+}
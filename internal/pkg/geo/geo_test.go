@@ -0,0 +1,81 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		p1, p2 Point
+		d      float64
+	}{
+		{p1: Point{Lat: 51.5007, Lon: 0.1246}, p2: Point{Lat: 40.6892, Lon: 74.0445}, d: 5574840},
+		{p1: Point{Lat: 31.2304, Lon: 121.4737}, p2: Point{Lat: 39.9042, Lon: 116.4074}, d: 1067300},
+		{p1: Point{Lat: 10, Lon: 20}, p2: Point{Lat: 10, Lon: 20}, d: 0},
+	}
+	for _, tt := range tests {
+		// within 0.1%
+		assert.InDelta(t, tt.d, Distance(tt.p1, tt.p2), tt.d/1000+1)
+	}
+}
+
+func TestInPolygon(t *testing.T) {
+	square := []Point{{Lat: 0, Lon: 0}, {Lat: 0, Lon: 10}, {Lat: 10, Lon: 10}, {Lat: 10, Lon: 0}}
+	concave := []Point{{Lat: 0, Lon: 0}, {Lat: 0, Lon: 10}, {Lat: 10, Lon: 10}, {Lat: 5, Lon: 5}, {Lat: 10, Lon: 0}}
+	tests := []struct {
+		p       Point
+		polygon []Point
+		in      bool
+	}{
+		{p: Point{Lat: 5, Lon: 5}, polygon: square, in: true},
+		{p: Point{Lat: 11, Lon: 5}, polygon: square, in: false},
+		{p: Point{Lat: -1, Lon: -1}, polygon: square, in: false},
+		{p: Point{Lat: 2, Lon: 5}, polygon: concave, in: true},
+		{p: Point{Lat: 8, Lon: 5}, polygon: concave, in: false},
+		{p: Point{Lat: 8, Lon: 1}, polygon: concave, in: true},
+	}
+	for i, tt := range tests {
+		assert.Equal(t, tt.in, InPolygon(tt.p, tt.polygon), "case %d", i)
+	}
+}
+
+func TestGeohash(t *testing.T) {
+	h, err := GeohashEncode(Point{Lat: 57.64911, Lon: 10.40744}, 11)
+	require.NoError(t, err)
+	assert.Equal(t, "u4pruydqqvj", h)
+	h, err = GeohashEncode(Point{Lat: 39.9042, Lon: 116.4074}, 6)
+	require.NoError(t, err)
+	assert.Equal(t, "wx4g0b", h)
+	p, err := GeohashDecode("u4pruydqqvj")
+	require.NoError(t, err)
+	assert.InDelta(t, 57.64911, p.Lat, 1e-5)
+	assert.InDelta(t, 10.40744, p.Lon, 1e-5)
+	p, err = GeohashDecode("WX4G0B")
+	require.NoError(t, err)
+	assert.InDelta(t, 39.9042, p.Lat, 0.01)
+	assert.InDelta(t, 116.4074, p.Lon, 0.01)
+
+	_, err = GeohashEncode(Point{Lat: 91, Lon: 0}, 5)
+	assert.EqualError(t, err, "latitude 91 is out of range [-90, 90]")
+	_, err = GeohashEncode(Point{Lat: 0, Lon: 0}, 13)
+	assert.EqualError(t, err, "geohash precision must be between 1 and 12 but got 13")
+	_, err = GeohashDecode("abc")
+	assert.EqualError(t, err, "invalid geohash abc")
+}
@@ -26,11 +26,18 @@ const ExpireTimeMinutes = 10
 
 type Token struct {
 	jwt.RegisteredClaims
+	// Role is the role of the caller for the rbac, such as admin, operator or viewer
+	Role string `json:"role,omitempty"`
 }
 
 // CreateToken Only for tests
 func CreateToken(signKeyName, issuer string, aud []string) (string, error) {
-	tk := &Token{}
+	return CreateTokenWithRole(signKeyName, issuer, aud, "")
+}
+
+// CreateTokenWithRole Only for tests
+func CreateTokenWithRole(signKeyName, issuer string, aud []string, role string) (string, error) {
+	tk := &Token{Role: role}
 	tk.Issuer = issuer
 	tk.Audience = aud
 	tk.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Duration(ExpireTimeMinutes) * time.Minute))
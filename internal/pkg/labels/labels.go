@@ -0,0 +1,89 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package labels is the user defined key values of the rules and streams. The labels and the selectors are written
+// in the text format like `app=vibration,site=plant3`. A selector matches the resources which have all its labels.
+package labels
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	keyRegex   = regexp.MustCompile(`^[A-Za-z0-9_\-./]+$`)
+	valueRegex = regexp.MustCompile(`^[A-Za-z0-9_\-./]*$`)
+)
+
+// Parse parses the labels in the format of k1=v1,k2=v2
+func Parse(s string) (map[string]string, error) {
+	result := make(map[string]string)
+	if strings.TrimSpace(s) == "" {
+		return result, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid label %s, it must be key=value", strings.TrimSpace(pair))
+		}
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if _, exists := result[k]; exists {
+			return nil, fmt.Errorf("duplicate label %s", k)
+		}
+		result[k] = v
+	}
+	if err := Validate(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Validate checks the keys and values. Only letters, digits, underscore, hyphen, dot and slash are allowed.
+func Validate(labels map[string]string) error {
+	for k, v := range labels {
+		if !keyRegex.MatchString(k) {
+			return fmt.Errorf("invalid label key %q, only letters, digits, underscore, hyphen, dot and slash are allowed", k)
+		}
+		if !valueRegex.MatchString(v) {
+			return fmt.Errorf("invalid label value %q of %s, only letters, digits, underscore, hyphen, dot and slash are allowed", v, k)
+		}
+	}
+	return nil
+}
+
+// Match returns whether the labels have all the key values of the selector. An empty selector matches all.
+func Match(labels, selector map[string]string) bool {
+	for k, v := range selector {
+		if l, ok := labels[k]; !ok || l != v {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the labels sorted by the keys
+func String(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + labels[k]
+	}
+	return strings.Join(pairs, ",")
+}
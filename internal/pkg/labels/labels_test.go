@@ -0,0 +1,57 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labels
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		s   string
+		exp map[string]string
+		err string
+	}{
+		{s: "", exp: map[string]string{}},
+		{s: "app=vibration, site=plant3", exp: map[string]string{"app": "vibration", "site": "plant3"}},
+		{s: "example.com/tier=", exp: map[string]string{"example.com/tier": ""}},
+		{s: "app", err: "invalid label app, it must be key=value"},
+		{s: "app=a,app=b", err: "duplicate label app"},
+		{s: "=a", err: `invalid label key "", only letters, digits, underscore, hyphen, dot and slash are allowed`},
+		{s: "app=a b", err: `invalid label value "a b" of app, only letters, digits, underscore, hyphen, dot and slash are allowed`},
+	}
+	for _, tt := range tests {
+		r, err := Parse(tt.s)
+		if tt.err != "" {
+			assert.EqualError(t, err, tt.err, tt.s)
+			continue
+		}
+		assert.NoError(t, err, tt.s)
+		assert.Equal(t, tt.exp, r, tt.s)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	l := map[string]string{"app": "vibration", "site": "plant3"}
+	assert.True(t, Match(l, nil))
+	assert.True(t, Match(l, map[string]string{"site": "plant3"}))
+	assert.True(t, Match(l, l))
+	assert.False(t, Match(l, map[string]string{"site": "plant4"}))
+	assert.False(t, Match(l, map[string]string{"app": "vibration", "line": "1"}))
+	assert.False(t, Match(nil, map[string]string{"line": ""}))
+	assert.Equal(t, "app=vibration,site=plant3", String(l))
+}
@@ -0,0 +1,65 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package namespace isolates the names of the streams, tables, rules and plugins of different tenants.
+// The definitions of a namespace are saved in their own tables and the runtime resources such as the
+// rule registry and the shared sources are keyed by the qualified name like `ns/name`. The default
+// namespace is not qualified so that the existing definitions are kept as is.
+package namespace
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	Default   = "default"
+	separator = "/"
+)
+
+var nameRegex = regexp.MustCompile(`^[A-Za-z0-9_\-]+$`)
+
+// Validate checks the name of a namespace to create
+func Validate(ns string) error {
+	if !nameRegex.MatchString(ns) {
+		return fmt.Errorf("invalid namespace %s, only letters, digits, underscore and hyphen are allowed", ns)
+	}
+	return nil
+}
+
+// Qualify returns the runtime name of a resource in the namespace. The name can be empty when the
+// name will be specified later such as a rule id in the rule json.
+func Qualify(ns, name string) string {
+	if ns == "" || ns == Default {
+		return name
+	}
+	return ns + separator + name
+}
+
+// Split returns the namespace and the local name of a qualified name
+func Split(qualified string) (string, string) {
+	if i := strings.Index(qualified, separator); i > 0 {
+		return qualified[:i], qualified[i+1:]
+	}
+	return Default, qualified
+}
+
+// Table returns the store table of the definitions in the namespace
+func Table(base, ns string) string {
+	if ns == "" || ns == Default {
+		return base
+	}
+	return base + separator + ns
+}
@@ -0,0 +1,56 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespace
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQualify(t *testing.T) {
+	tests := []struct {
+		ns    string
+		name  string
+		q     string
+		table string
+	}{
+		{ns: "", name: "rule1", q: "rule1", table: "rule"},
+		{ns: Default, name: "rule1", q: "rule1", table: "rule"},
+		{ns: "team1", name: "rule1", q: "team1/rule1", table: "rule/team1"},
+	}
+	for _, tt := range tests {
+		q := Qualify(tt.ns, tt.name)
+		assert.Equal(t, tt.q, q)
+		assert.Equal(t, tt.table, Table("rule", tt.ns))
+		ns, name := Split(q)
+		if tt.ns == "" {
+			assert.Equal(t, Default, ns)
+		} else {
+			assert.Equal(t, tt.ns, ns)
+		}
+		assert.Equal(t, tt.name, name)
+	}
+	ns, name := Split(Qualify("team1", ""))
+	assert.Equal(t, "team1", ns)
+	assert.Equal(t, "", name)
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate("team-1_a"))
+	assert.Error(t, Validate(""))
+	assert.Error(t, Validate("team/1"))
+	assert.Error(t, Validate("team 1"))
+}
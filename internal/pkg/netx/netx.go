@@ -0,0 +1,298 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package netx provides the proxy and DNS resolution for the outbound connections of the connectors.
+// The engine-wide configuration is in the network section of kuiper.yaml which can be overridden by
+// the proxy, dnsServers and hosts properties of each connector.
+package netx
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+const defaultDialTimeout = 30 * time.Second
+
+// Dialer dials the outbound connections with the configured proxy, DNS servers and static hosts
+type Dialer struct {
+	conf     *conf.NetworkConf
+	proxyURL *url.URL
+	resolver *net.Resolver
+}
+
+// NewDialer creates the dialer from the engine-wide network configuration and the connector props
+func NewDialer(props map[string]interface{}) (*Dialer, error) {
+	c := &conf.NetworkConf{}
+	if conf.Config != nil {
+		c.Proxy = conf.Config.Network.Proxy
+		c.DnsServers = conf.Config.Network.DnsServers
+		c.Hosts = conf.Config.Network.Hosts
+	}
+	pc := &conf.NetworkConf{}
+	if err := cast.MapToStruct(props, pc); err != nil {
+		return nil, fmt.Errorf("read network properties %v fail with error: %v", props, err)
+	}
+	if pc.Proxy != "" {
+		c.Proxy = pc.Proxy
+	}
+	if len(pc.DnsServers) > 0 {
+		c.DnsServers = pc.DnsServers
+	}
+	if len(pc.Hosts) > 0 {
+		hosts := make(map[string]string, len(c.Hosts)+len(pc.Hosts))
+		for k, v := range c.Hosts {
+			hosts[k] = v
+		}
+		for k, v := range pc.Hosts {
+			hosts[k] = v
+		}
+		c.Hosts = hosts
+	}
+	d := &Dialer{conf: c}
+	if c.Proxy != "" {
+		u, err := url.Parse(c.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %s: %v", c.Proxy, err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %s, only http, https and socks5 are supported", u.Scheme)
+		}
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy %s: missing host", c.Proxy)
+		}
+		d.proxyURL = u
+	}
+	if len(c.DnsServers) > 0 {
+		servers := make([]string, len(c.DnsServers))
+		for i, s := range c.DnsServers {
+			if _, _, err := net.SplitHostPort(s); err != nil {
+				s = net.JoinHostPort(s, "53")
+			}
+			servers[i] = s
+		}
+		dialer := &net.Dialer{Timeout: 5 * time.Second}
+		d.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var err error
+				for _, s := range servers {
+					var conn net.Conn
+					conn, err = dialer.DialContext(ctx, network, s)
+					if err == nil {
+						return conn, nil
+					}
+				}
+				return nil, err
+			},
+		}
+	}
+	return d, nil
+}
+
+// IsDefault returns true if no proxy, DNS server or host is configured so that the default dialer can be used.
+// A nil dialer is the default dialer.
+func (d *Dialer) IsDefault() bool {
+	return d == nil || d.proxyURL == nil && d.resolver == nil && len(d.conf.Hosts) == 0
+}
+
+// HasProxy returns true if the proxy is configured
+func (d *Dialer) HasProxy() bool {
+	return d.proxyURL != nil
+}
+
+// resolve returns the address with the host replaced by the static host or the IP resolved by the DNS servers
+func (d *Dialer) resolve(ctx context.Context, addr string, lookup bool) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	if h, ok := d.conf.Hosts[host]; ok {
+		return net.JoinHostPort(h, port), nil
+	}
+	if lookup && d.resolver != nil && net.ParseIP(host) == nil {
+		ips, err := d.resolver.LookupHost(ctx, host)
+		if err != nil {
+			return "", fmt.Errorf("fail to resolve %s: %v", host, err)
+		}
+		if len(ips) == 0 {
+			return "", fmt.Errorf("fail to resolve %s: no address", host)
+		}
+		return net.JoinHostPort(ips[0], port), nil
+	}
+	return addr, nil
+}
+
+// DialDirect connects to the address without proxy. The static hosts and DNS servers are applied.
+func (d *Dialer) DialDirect(ctx context.Context, network, addr string) (net.Conn, error) {
+	target, err := d.resolve(ctx, addr, true)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: defaultDialTimeout}
+	return dialer.DialContext(ctx, network, target)
+}
+
+// Dial implements proxy.Dialer to dial the socks5 proxy server
+func (d *Dialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialDirect(context.Background(), network, addr)
+}
+
+// DialContext connects to the address through the proxy if configured
+func (d *Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if d.proxyURL == nil {
+		return d.DialDirect(ctx, network, addr)
+	}
+	// The proxy server resolves the host unless there is a static host
+	target, err := d.resolve(ctx, addr, false)
+	if err != nil {
+		return nil, err
+	}
+	switch d.proxyURL.Scheme {
+	case "socks5":
+		var auth *proxy.Auth
+		if d.proxyURL.User != nil {
+			auth = &proxy.Auth{User: d.proxyURL.User.Username()}
+			auth.Password, _ = d.proxyURL.User.Password()
+		}
+		pd, err := proxy.SOCKS5("tcp", d.proxyURL.Host, auth, d)
+		if err != nil {
+			return nil, err
+		}
+		if cd, ok := pd.(proxy.ContextDialer); ok {
+			return cd.DialContext(ctx, network, target)
+		}
+		return pd.Dial(network, target)
+	default:
+		return d.dialHttpProxy(ctx, target)
+	}
+}
+
+// dialHttpProxy creates a tunnel to the address by the CONNECT method of the http proxy
+func (d *Dialer) dialHttpProxy(ctx context.Context, addr string) (net.Conn, error) {
+	proxyAddr := d.proxyURL.Host
+	if d.proxyURL.Port() == "" {
+		if d.proxyURL.Scheme == "https" {
+			proxyAddr = net.JoinHostPort(proxyAddr, "443")
+		} else {
+			proxyAddr = net.JoinHostPort(proxyAddr, "80")
+		}
+	}
+	conn, err := d.DialDirect(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+	if d.proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: d.proxyURL.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if d.proxyURL.User != nil {
+		pwd, _ := d.proxyURL.User.Password()
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(d.proxyURL.User.Username()+":"+pwd)))
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+		defer func() { _ = conn.SetDeadline(time.Time{}) }()
+	}
+	if err := req.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy %s refused to connect %s: %s", d.proxyURL.Redacted(), addr, resp.Status)
+	}
+	return conn, nil
+}
+
+// DialTLS connects to the address through the proxy if configured and then does the TLS handshake
+func (d *Dialer) DialTLS(ctx context.Context, network, addr string, tlsConfig *tls.Config) (net.Conn, error) {
+	conn, err := d.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{}
+	if tlsConfig != nil {
+		cfg = tlsConfig.Clone()
+	}
+	if cfg.ServerName == "" {
+		host, _, _ := net.SplitHostPort(addr)
+		cfg.ServerName = host
+	}
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// DialURL connects to the tcp or tls url such as the MQTT broker url through the proxy if configured
+func (d *Dialer) DialURL(uri *url.URL, tlsConfig *tls.Config, timeout time.Duration) (net.Conn, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	switch uri.Scheme {
+	case "mqtt", "tcp":
+		return d.DialContext(ctx, "tcp", uri.Host)
+	case "ssl", "tls", "mqtts", "mqtt+ssl", "tcps":
+		return d.DialTLS(ctx, "tcp", uri.Host, tlsConfig)
+	default:
+		return nil, fmt.Errorf("unsupported scheme %s with the network settings", uri.Scheme)
+	}
+}
+
+// ApplyTransport sets the proxy and dialer of the http transport
+func (d *Dialer) ApplyTransport(tr *http.Transport) {
+	if d.IsDefault() {
+		return
+	}
+	if d.proxyURL != nil {
+		tr.Proxy = http.ProxyURL(d.proxyURL)
+	}
+	// The transport dials the proxy server by this function so do not dial the proxy again
+	tr.DialContext = d.DialDirect
+}
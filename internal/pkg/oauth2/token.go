@@ -0,0 +1,152 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package oauth2 manages the OAuth2 client credentials tokens which are shared by the http sources, rest sinks and
+// external rest services. The configurations are named in the oauth2 section of etc/connections/connection.yaml.
+package oauth2
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/conf/secret"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+// ConnectionType is the key of the oauth2 configurations in connection.yaml
+const ConnectionType = "oauth2"
+
+type Conf struct {
+	TokenUrl     string   `json:"tokenUrl"`
+	ClientId     string   `json:"clientId"`
+	ClientSecret string   `json:"clientSecret"`
+	Scopes       []string `json:"scopes"`
+	// Params are the additional parameters sent to the token url such as audience
+	Params map[string]string `json:"params"`
+}
+
+func (c *Conf) validate() error {
+	if c.TokenUrl == "" {
+		return fmt.Errorf("tokenUrl is required")
+	}
+	if c.ClientId == "" {
+		return fmt.Errorf("clientId is required")
+	}
+	return nil
+}
+
+var (
+	lock sync.Mutex
+	// the token sources by name, they cache the token and refresh it when it expires
+	sources = make(map[string]oauth2.TokenSource)
+	// confLoader loads the named configuration, replaced in tests
+	confLoader = loadConf
+)
+
+// Validate checks if the named configuration exists and is valid
+func Validate(name string) error {
+	_, err := confLoader(name)
+	return err
+}
+
+// Token returns the access token of the named configuration. The token is fetched once and shared by all the callers
+// until it expires.
+func Token(name string) (string, error) {
+	ts, err := getSource(name)
+	if err != nil {
+		return "", err
+	}
+	tk, err := ts.Token()
+	if err != nil {
+		return "", fmt.Errorf("fail to get oauth2 token for %s: %v", name, err)
+	}
+	return tk.AccessToken, nil
+}
+
+// AuthHeader returns the authorization header value of the named configuration
+func AuthHeader(name string) (string, error) {
+	tk, err := Token(name)
+	if err != nil {
+		return "", err
+	}
+	return "Bearer " + tk, nil
+}
+
+// Invalidate drops the cached token so that a new one is fetched next time, such as when the token is rejected or
+// the configuration is updated
+func Invalidate(name string) {
+	lock.Lock()
+	defer lock.Unlock()
+	delete(sources, name)
+}
+
+func getSource(name string) (oauth2.TokenSource, error) {
+	lock.Lock()
+	defer lock.Unlock()
+	if ts, ok := sources[name]; ok {
+		return ts, nil
+	}
+	c, err := confLoader(name)
+	if err != nil {
+		return nil, err
+	}
+	cc := &clientcredentials.Config{
+		ClientID:     c.ClientId,
+		ClientSecret: c.ClientSecret,
+		TokenURL:     c.TokenUrl,
+		Scopes:       c.Scopes,
+	}
+	if len(c.Params) > 0 {
+		cc.EndpointParams = make(map[string][]string, len(c.Params))
+		for k, v := range c.Params {
+			cc.EndpointParams[k] = []string{v}
+		}
+	}
+	ts := cc.TokenSource(context.Background())
+	sources[name] = ts
+	return ts, nil
+}
+
+func loadConf(name string) (*Conf, error) {
+	ops, err := conf.NewConfigOperatorFromConnectionStorage(ConnectionType)
+	if err != nil {
+		return nil, err
+	}
+	cfg := ops.CopyConfContent()
+	props, ok := cfg[name]
+	if !ok {
+		props, ok = cfg[strings.ToLower(name)]
+	}
+	if !ok {
+		return nil, fmt.Errorf("oauth2 configuration %s not found", name)
+	}
+	props, err = secret.ResolveProps(props)
+	if err != nil {
+		return nil, fmt.Errorf("invalid oauth2 configuration %s: %v", name, err)
+	}
+	c := &Conf{}
+	if err := cast.MapToStruct(props, c); err != nil {
+		return nil, fmt.Errorf("invalid oauth2 configuration %s: %v", name, err)
+	}
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("invalid oauth2 configuration %s: %v", name, err)
+	}
+	return c, nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth2
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToken(t *testing.T) {
+	var count atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.Form.Get("grant_type") != "client_credentials" || r.Form.Get("scope") != "read write" {
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		if id, secret, ok := r.BasicAuth(); !ok || id != "client1" || secret != "secret1" {
+			http.Error(w, "invalid client", http.StatusUnauthorized)
+			return
+		}
+		n := count.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"access_token":"token%d","token_type":"bearer","expires_in":3600}`, n)
+	}))
+	defer ts.Close()
+
+	confLoader = func(name string) (*Conf, error) {
+		if name != "auth1" {
+			return nil, fmt.Errorf("oauth2 configuration %s not found", name)
+		}
+		return &Conf{
+			TokenUrl:     ts.URL,
+			ClientId:     "client1",
+			ClientSecret: "secret1",
+			Scopes:       []string{"read", "write"},
+		}, nil
+	}
+	defer func() {
+		confLoader = loadConf
+		Invalidate("auth1")
+	}()
+
+	require.NoError(t, Validate("auth1"))
+	require.EqualError(t, Validate("auth2"), "oauth2 configuration auth2 not found")
+
+	tk, err := Token("auth1")
+	require.NoError(t, err)
+	assert.Equal(t, "token1", tk)
+	// shared and cached
+	h, err := AuthHeader("auth1")
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer token1", h)
+	assert.Equal(t, int32(1), count.Load())
+	// fetch again after invalidation
+	Invalidate("auth1")
+	tk, err = Token("auth1")
+	require.NoError(t, err)
+	assert.Equal(t, "token2", tk)
+
+	_, err = Token("auth2")
+	require.Error(t, err)
+}
+
+func TestConfValidate(t *testing.T) {
+	assert.EqualError(t, (&Conf{ClientId: "a"}).validate(), "tokenUrl is required")
+	assert.EqualError(t, (&Conf{TokenUrl: "http://localhost"}).validate(), "clientId is required")
+	assert.NoError(t, (&Conf{TokenUrl: "http://localhost", ClientId: "a"}).validate())
+}
@@ -0,0 +1,146 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/pkg/jwt"
+)
+
+// ErrMissingCredential is returned when the request has no authorization header
+var ErrMissingCredential = errors.New("missing_token")
+
+type authenticator struct {
+	users       map[string]*user
+	oidc        *oidcVerifier
+	defaultRole Role
+}
+
+var current atomic.Pointer[authenticator]
+
+func init() {
+	current.Store(&authenticator{defaultRole: Admin})
+}
+
+// Init loads the users file and sets up the OIDC provider
+func Init(c *conf.RbacConf) error {
+	// The jwt tokens without role were all-or-nothing, keep them as admin if not configured
+	a := &authenticator{defaultRole: Admin}
+	if c.DefaultRole != "" {
+		r, err := ParseRole(c.DefaultRole)
+		if err != nil {
+			return fmt.Errorf("invalid rbac.defaultRole: %v", err)
+		}
+		a.defaultRole = r
+	}
+	if c.UsersFile != "" {
+		path := c.UsersFile
+		if !filepath.IsAbs(path) {
+			dir, err := conf.GetConfLoc()
+			if err != nil {
+				return err
+			}
+			path = filepath.Join(dir, path)
+		}
+		users, err := loadUsers(path)
+		if err != nil {
+			return err
+		}
+		a.users = users
+	}
+	if c.Oidc.Enable {
+		if c.Oidc.Issuer == "" {
+			return errors.New("rbac.oidc.issuer is required")
+		}
+		a.oidc = newOidcVerifier(&c.Oidc)
+	}
+	current.Store(a)
+	return nil
+}
+
+// Identity is the authenticated caller
+type Identity struct {
+	Name string
+	Role Role
+}
+
+type identityKey struct{}
+
+// WithIdentity saves the caller in the context of the request
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// IdentityFrom returns the caller of the request, or nil if the authentication is disabled
+func IdentityFrom(ctx context.Context) *Identity {
+	id, _ := ctx.Value(identityKey{}).(*Identity)
+	return id
+}
+
+// Authenticate returns the caller and its role. The basic authentication is checked against the users file.
+// The bearer token is validated by the OIDC provider if it is the issuer, otherwise by the public keys in etc/mgmt.
+func Authenticate(r *http.Request) (*Identity, error) {
+	a := current.Load()
+	if name, password, ok := r.BasicAuth(); ok {
+		u, found := a.users[name]
+		if !found || !u.verify(password) {
+			return nil, errors.New("invalid username or password")
+		}
+		return &Identity{Name: name, Role: u.role}, nil
+	}
+	token := r.Header.Get("Authorization")
+	if token == "" {
+		return nil, ErrMissingCredential
+	}
+	if len(token) > 7 && strings.EqualFold(token[:7], "Bearer ") {
+		token = token[7:]
+	}
+	if a.oidc != nil && a.oidc.accepts(token) {
+		return a.oidc.verify(token)
+	}
+	tk, err := jwt.ParseToken(token)
+	if err != nil {
+		return nil, err
+	}
+	hit := false
+	for _, value := range tk.RegisteredClaims.Audience {
+		if value == "eKuiper" {
+			hit = true
+			break
+		}
+	}
+	if !hit {
+		return nil, fmt.Errorf("audience field should contain eKuiper, but got %s", tk.RegisteredClaims.Audience)
+	}
+	id := &Identity{Name: tk.Subject, Role: a.defaultRole}
+	if id.Name == "" {
+		id.Name = tk.Issuer
+	}
+	if tk.Role != "" {
+		id.Role, err = ParseRole(tk.Role)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return id, nil
+}
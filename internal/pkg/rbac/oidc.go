@@ -0,0 +1,219 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+)
+
+// the minimum interval to refresh the keys when a token is signed by an unknown key
+const jwksRefreshInterval = time.Minute
+
+// oidcVerifier validates the id tokens or access tokens issued by an OIDC provider with its public keys
+type oidcVerifier struct {
+	issuer    string
+	audience  string
+	jwksUrl   string
+	roleClaim string
+	client    *http.Client
+
+	sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	refreshed time.Time
+}
+
+func newOidcVerifier(c *conf.OidcConf) *oidcVerifier {
+	roleClaim := c.RoleClaim
+	if roleClaim == "" {
+		roleClaim = "roles"
+	}
+	return &oidcVerifier{
+		issuer:    strings.TrimSuffix(c.Issuer, "/"),
+		audience:  c.Audience,
+		jwksUrl:   c.JwksUrl,
+		roleClaim: roleClaim,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		keys:      make(map[string]*rsa.PublicKey),
+	}
+}
+
+// accepts returns whether the token is issued by the provider without validating it
+func (v *oidcVerifier) accepts(token string) bool {
+	claims := jwt.MapClaims{}
+	_, _, err := jwt.NewParser().ParseUnverified(token, claims)
+	if err != nil {
+		return false
+	}
+	iss, _ := claims.GetIssuer()
+	return strings.TrimSuffix(iss, "/") == v.issuer
+}
+
+// verify validates the token and returns the caller with the highest role in the role claim
+func (v *oidcVerifier) verify(token string) (*Identity, error) {
+	opts := []jwt.ParserOption{jwt.WithValidMethods([]string{"RS256", "RS384", "RS512"}), jwt.WithExpirationRequired()}
+	if v.audience != "" {
+		opts = append(opts, jwt.WithAudience(v.audience))
+	}
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.key(kid)
+	}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("validate oidc token error: %v", err)
+	}
+	role, err := roleOfClaim(claims, v.roleClaim)
+	if err != nil {
+		return nil, err
+	}
+	name, _ := claims["preferred_username"].(string)
+	if name == "" {
+		name, _ = claims.GetSubject()
+	}
+	return &Identity{Name: name, Role: role}, nil
+}
+
+func (v *oidcVerifier) key(kid string) (*rsa.PublicKey, error) {
+	v.Lock()
+	defer v.Unlock()
+	if k, ok := v.lookup(kid); ok {
+		return k, nil
+	}
+	// The provider may rotate the keys, refresh them but not too often for the forged tokens
+	if time.Since(v.refreshed) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %s", kid)
+	}
+	if err := v.refresh(); err != nil {
+		return nil, err
+	}
+	if k, ok := v.lookup(kid); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %s", kid)
+}
+
+func (v *oidcVerifier) lookup(kid string) (*rsa.PublicKey, bool) {
+	if kid == "" && len(v.keys) == 1 {
+		for _, k := range v.keys {
+			return k, true
+		}
+	}
+	k, ok := v.keys[kid]
+	return k, ok
+}
+
+func (v *oidcVerifier) refresh() error {
+	v.refreshed = time.Now()
+	if v.jwksUrl == "" {
+		discovery := struct {
+			JwksUri string `json:"jwks_uri"`
+		}{}
+		if err := v.getJson(v.issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+			return err
+		}
+		if discovery.JwksUri == "" {
+			return errors.New("jwks_uri is not found in the openid configuration")
+		}
+		v.jwksUrl = discovery.JwksUri
+	}
+	jwks := struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}{}
+	if err := v.getJson(v.jwksUrl, &jwks); err != nil {
+		return err
+	}
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return fmt.Errorf("invalid modulus of key %s: %v", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return fmt.Errorf("invalid exponent of key %s: %v", k.Kid, err)
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	v.keys = keys
+	return nil
+}
+
+func (v *oidcVerifier) getJson(url string, result interface{}) error {
+	resp, err := v.client.Get(url)
+	if err != nil {
+		return fmt.Errorf("fail to get %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fail to get %s: status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// roleOfClaim returns the highest role in the claim. The claim can be a path like realm_access.roles
+// and its value can be a role name or a list of the role names. The unknown names are ignored.
+func roleOfClaim(claims map[string]interface{}, claim string) (Role, error) {
+	var val interface{} = claims
+	for _, key := range strings.Split(claim, ".") {
+		m, ok := val.(map[string]interface{})
+		if !ok {
+			val = nil
+			break
+		}
+		val = m[key]
+	}
+	var names []string
+	switch vt := val.(type) {
+	case string:
+		names = strings.Fields(strings.ReplaceAll(vt, ",", " "))
+	case []interface{}:
+		for _, n := range vt {
+			if s, ok := n.(string); ok {
+				names = append(names, s)
+			}
+		}
+	}
+	result := None
+	for _, n := range names {
+		if r, err := ParseRole(n); err == nil && r > result {
+			result = r
+		}
+	}
+	if result == None {
+		return None, fmt.Errorf("no valid role found in the claim %s", claim)
+	}
+	return result, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"net/http"
+	"strings"
+)
+
+const namespacePrefix = "/namespaces/{namespace}"
+
+// operatorRoutes are the write operations which only change the streams, tables and rules.
+// The other write operations such as installing plugins or importing data require admin.
+var operatorRoutes = map[string][]string{
+	"/streams":                  {http.MethodPost},
+	"/streams/{name}":           {http.MethodPut, http.MethodDelete},
+	"/tables":                   {http.MethodPost},
+	"/tables/{name}":            {http.MethodPut, http.MethodDelete},
+	"/rules":                    {http.MethodPost},
+	"/rules/{name}":             {http.MethodPut, http.MethodDelete},
+	"/rules/bulk":               {http.MethodPost},
+	"/rules/{name}/start":       {http.MethodPost},
+	"/rules/{name}/stop":        {http.MethodPost},
+	"/rules/{name}/restart":     {http.MethodPost},
+	"/rules/validate":           {http.MethodPost},
+	"/rules/validate-plan":      {http.MethodPost},
+	"/rules/unittest":           {http.MethodPost},
+	"/rules/{name}/reset_state": {http.MethodPut},
+	"/rules/{name}/snapshot":    {http.MethodGet, http.MethodPut},
+	"/rules/{name}/backfill":    {http.MethodPost},
+	"/rules/{name}/replay":      {http.MethodPost},
+	"/rules/{name}/profile":     {http.MethodGet},
+	"/rules/{name}/tap":         {http.MethodGet},
+	"/ruletest":                 {http.MethodPost},
+	"/ruletest/{name}/start":    {http.MethodPost},
+	"/ruletest/{name}":          {http.MethodDelete},
+	"/sinks/{name}/replay":      {http.MethodPost},
+
+	"/rules/{name}/versions/{version}/rollback": {http.MethodPost},
+}
+
+// adminReads are the sensitive read operations such as exporting the data, reading the audit records or stopping the server
+var adminReads = map[string]bool{
+	"/stop":         true,
+	"/data/export":  true,
+	"/data/archive": true,
+	"/audit":        true,
+}
+
+// RequiredRole returns the minimum role to call the route. The path is the template of the route
+// such as /rules/{name}/start. The read operations only require viewer except the sensitive ones,
+// the writes of the streams, tables and rules require operator and the others require admin.
+func RequiredRole(method, path string) Role {
+	if strings.HasPrefix(path, namespacePrefix+"/") {
+		path = strings.TrimPrefix(path, namespacePrefix)
+	}
+	if method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions {
+		if adminReads[path] {
+			return Admin
+		}
+		for _, m := range operatorRoutes[path] {
+			if m == method {
+				return Operator
+			}
+		}
+		return Viewer
+	}
+	for _, m := range operatorRoutes[path] {
+		if m == method {
+			return Operator
+		}
+	}
+	return Admin
+}
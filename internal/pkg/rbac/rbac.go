@@ -0,0 +1,65 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rbac is the role based access control of the management REST API. The caller is
+// authenticated by the users file, an external OIDC provider or the RSA jwt token in etc/mgmt,
+// and then each route is checked against the minimum role it requires.
+package rbac
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Role is ordered so that a higher role has all the permissions of the lower roles
+type Role int
+
+const (
+	None Role = iota
+	Viewer
+	Operator
+	Admin
+)
+
+func (r Role) String() string {
+	switch r {
+	case Viewer:
+		return "viewer"
+	case Operator:
+		return "operator"
+	case Admin:
+		return "admin"
+	default:
+		return "none"
+	}
+}
+
+// ParseRole returns the role of the name which is case-insensitive
+func ParseRole(name string) (Role, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "viewer":
+		return Viewer, nil
+	case "operator":
+		return Operator, nil
+	case "admin":
+		return Admin, nil
+	default:
+		return None, fmt.Errorf("unknown role %s, must be admin, operator or viewer", name)
+	}
+}
+
+// Allows returns whether the role has the permissions of the required role
+func (r Role) Allows(required Role) bool {
+	return r >= required
+}
@@ -0,0 +1,170 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+)
+
+func TestRequiredRole(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		role   Role
+	}{
+		{http.MethodGet, "/rules", Viewer},
+		{http.MethodGet, "/rules/{name}/status", Viewer},
+		{http.MethodGet, "/plugins/sources", Viewer},
+		{http.MethodPost, "/rules", Operator},
+		{http.MethodDelete, "/rules/{name}", Operator},
+		{http.MethodPost, "/rules/{name}/start", Operator},
+		{http.MethodPost, "/rules/bulk", Operator},
+		{http.MethodPost, "/namespaces/{namespace}/rules/{name}/stop", Operator},
+		{http.MethodGet, "/namespaces/{namespace}/rules", Viewer},
+		{http.MethodGet, "/rules/{name}/snapshot", Operator},
+		{http.MethodPost, "/plugins/sources", Admin},
+		{http.MethodPost, "/namespaces", Admin},
+		{http.MethodDelete, "/namespaces/{namespace}", Admin},
+		{http.MethodPatch, "/configs", Admin},
+		{http.MethodGet, "/data/export", Admin},
+		{http.MethodGet, "/data/archive", Admin},
+		{http.MethodPost, "/data/archive", Admin},
+		{http.MethodGet, "/stop", Admin},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.role, RequiredRole(tt.method, tt.path), "%s %s", tt.method, tt.path)
+	}
+	assert.True(t, Admin.Allows(Operator))
+	assert.False(t, Viewer.Allows(Operator))
+}
+
+func TestBasicAuth(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "users.yaml")
+	content := "users:\n  - name: alice\n    password: " + string(hash) + "\n    role: operator\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	require.NoError(t, Init(&conf.RbacConf{UsersFile: path}))
+	defer Init(&conf.RbacConf{})
+
+	req := httptest.NewRequest(http.MethodGet, "/rules", nil)
+	req.SetBasicAuth("alice", "secret")
+	id, err := Authenticate(req)
+	require.NoError(t, err)
+	assert.Equal(t, &Identity{Name: "alice", Role: Operator}, id)
+
+	req.SetBasicAuth("alice", "wrong")
+	_, err = Authenticate(req)
+	assert.Error(t, err)
+
+	req = httptest.NewRequest(http.MethodGet, "/rules", nil)
+	_, err = Authenticate(req)
+	assert.ErrorIs(t, err, ErrMissingCredential)
+
+	require.NoError(t, os.WriteFile(path, []byte("users:\n  - name: bob\n    role: root\n"), 0o600))
+	assert.Error(t, Init(&conf.RbacConf{UsersFile: path}))
+}
+
+func TestOidc(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	var issuer string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			_ = json.NewEncoder(w).Encode(map[string]string{"issuer": issuer, "jwks_uri": issuer + "/keys"})
+		case "/keys":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+				"kid": "k1",
+				"kty": "RSA",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	issuer = srv.URL
+	require.NoError(t, Init(&conf.RbacConf{Oidc: conf.OidcConf{Enable: true, Issuer: issuer, Audience: "kuiper", RoleClaim: "realm_access.roles"}}))
+	defer Init(&conf.RbacConf{})
+
+	sign := func(claims jwt.MapClaims) string {
+		tk := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		tk.Header["kid"] = "k1"
+		s, err := tk.SignedString(key)
+		require.NoError(t, err)
+		return s
+	}
+	exp := time.Now().Add(time.Minute).Unix()
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		role   Role
+		err    bool
+	}{
+		{
+			name:   "highest role",
+			claims: jwt.MapClaims{"iss": issuer, "aud": "kuiper", "exp": exp, "preferred_username": "alice", "realm_access": map[string]interface{}{"roles": []string{"viewer", "operator", "other"}}},
+			role:   Operator,
+		},
+		{
+			name:   "wrong audience",
+			claims: jwt.MapClaims{"iss": issuer, "aud": "other", "exp": exp, "realm_access": map[string]interface{}{"roles": []string{"admin"}}},
+			err:    true,
+		},
+		{
+			name:   "no role",
+			claims: jwt.MapClaims{"iss": issuer, "aud": "kuiper", "exp": exp},
+			err:    true,
+		},
+		{
+			name:   "expired",
+			claims: jwt.MapClaims{"iss": issuer, "aud": "kuiper", "exp": time.Now().Add(-time.Minute).Unix(), "realm_access": map[string]interface{}{"roles": []string{"admin"}}},
+			err:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/rules", nil)
+			req.Header.Set("Authorization", "Bearer "+sign(tt.claims))
+			id, err := Authenticate(req)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.role, id.Role)
+			assert.Equal(t, "alice", id.Name)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+type user struct {
+	Name string `yaml:"name"`
+	// Password is the bcrypt hash of the password
+	Password string `yaml:"password"`
+	Role     string `yaml:"role"`
+
+	role Role
+}
+
+type usersFile struct {
+	Users []*user `yaml:"users"`
+}
+
+// loadUsers reads the users and their roles for the basic authentication
+func loadUsers(path string) (map[string]*user, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read the users file %s: %v", path, err)
+	}
+	f := &usersFile{}
+	if err := yaml.Unmarshal(content, f); err != nil {
+		return nil, fmt.Errorf("fail to parse the users file %s: %v", path, err)
+	}
+	result := make(map[string]*user, len(f.Users))
+	for _, u := range f.Users {
+		if u.Name == "" {
+			return nil, fmt.Errorf("user name is required in the users file %s", path)
+		}
+		if _, ok := result[u.Name]; ok {
+			return nil, fmt.Errorf("duplicate user %s in the users file %s", u.Name, path)
+		}
+		u.role, err = ParseRole(u.Role)
+		if err != nil {
+			return nil, fmt.Errorf("invalid role of user %s: %v", u.Name, err)
+		}
+		result[u.Name] = u
+	}
+	return result, nil
+}
+
+func (u *user) verify(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) == nil
+}
@@ -22,9 +22,11 @@ type Database interface {
 type Config struct {
 	Type         string
 	ExtStateType string
-	Redis        RedisConfig
-	Sqlite       SqliteConfig
-	Fdb          FdbConfig
+	// StateType is the default store type of the rule states. Use the Type if not set
+	StateType string
+	Redis     RedisConfig
+	Sqlite    SqliteConfig
+	Fdb       FdbConfig
 }
 
 type RedisConfig struct {
@@ -21,6 +21,7 @@ import (
 	"encoding/gob"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 
@@ -68,6 +69,49 @@ func (kv redisKvStore) Set(key string, value interface{}) error {
 	return kv.database.Set(context.Background(), kv.tableKey(key), b, 0).Err()
 }
 
+// SetEx sets the key which expires after the ttl on the store side
+func (kv redisKvStore) SetEx(key string, value interface{}, ttl time.Duration) error {
+	b, err := kvEncoding.Encode(value)
+	if nil != err {
+		return err
+	}
+	return kv.database.Set(context.Background(), kv.tableKey(key), b, ttl).Err()
+}
+
+var acquireScript = redis.NewScript(`
+local v = redis.call('GET', KEYS[1])
+if v == false or v == ARGV[1] then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 1
+end
+return 0`)
+
+// Acquire sets the key to the value with the ttl if the key does not exist or already holds the value. Return whether
+// the key holds the value.
+func (kv redisKvStore) Acquire(key string, value interface{}, ttl time.Duration) (bool, error) {
+	b, err := kvEncoding.Encode(value)
+	if nil != err {
+		return false, err
+	}
+	r, err := acquireScript.Run(context.Background(), kv.database, []string{kv.tableKey(key)}, b, ttl.Milliseconds()).Int()
+	return r == 1, err
+}
+
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0`)
+
+// Release deletes the key if it holds the value
+func (kv redisKvStore) Release(key string, value interface{}) error {
+	b, err := kvEncoding.Encode(value)
+	if nil != err {
+		return err
+	}
+	return releaseScript.Run(context.Background(), kv.database, []string{kv.tableKey(key)}, b).Err()
+}
+
 func (kv redisKvStore) Get(key string, value interface{}) (bool, error) {
 	val, err := kv.database.Get(context.Background(), kv.tableKey(key)).Result()
 	if err != nil {
@@ -19,9 +19,12 @@ package redis
 import (
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/alicebob/miniredis/v2"
 	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/lf-edge/ekuiper/internal/pkg/store/test/common"
 	"github.com/lf-edge/ekuiper/pkg/kv"
@@ -74,6 +77,47 @@ func TestRedisKvGetKeyedState(t *testing.T) {
 	common.TestKvGetKeyedState(ks, t)
 }
 
+func TestRedisKvLease(t *testing.T) {
+	ks, db, minRedis := setupRedisKv()
+	defer cleanRedisKv(db, minRedis)
+	s := ks.(*redisKvStore)
+
+	require.NoError(t, s.SetEx("n1", "v", time.Second))
+	var v string
+	found, _ := s.Get("n1", &v)
+	assert.True(t, found)
+	minRedis.FastForward(2 * time.Second)
+	found, _ = s.Get("n1", &v)
+	assert.False(t, found)
+
+	ok, err := s.Acquire("rule1", "n1", time.Second)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = s.Acquire("rule1", "n2", time.Second)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	// renew by the holder
+	minRedis.FastForward(600 * time.Millisecond)
+	ok, err = s.Acquire("rule1", "n1", time.Second)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	minRedis.FastForward(600 * time.Millisecond)
+	ok, err = s.Acquire("rule1", "n2", time.Second)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	// only the holder releases it
+	require.NoError(t, s.Release("rule1", "n2"))
+	ok, _ = s.Acquire("rule1", "n2", time.Second)
+	assert.False(t, ok)
+	require.NoError(t, s.Release("rule1", "n1"))
+	ok, _ = s.Acquire("rule1", "n2", time.Second)
+	assert.True(t, ok)
+	// expires when not renewed
+	minRedis.FastForward(2 * time.Second)
+	ok, _ = s.Acquire("rule1", "n1", time.Second)
+	assert.True(t, ok)
+}
+
 func setupRedisKv() (kv.KeyValue, *redis.Client, *miniredis.Miniredis) {
 	minRedis, err := miniredis.Run()
 	if err != nil {
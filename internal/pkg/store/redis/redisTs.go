@@ -101,7 +101,7 @@ func (t *ts) Delete(key int64) error {
 }
 
 func (t *ts) DeleteBefore(key int64) error {
-	return t.db.ZRemRangeByScore(context.Background(), t.key, "-inf", strconv.FormatInt(key, 10)).Err()
+	return t.db.ZRemRangeByScore(context.Background(), t.key, "-inf", "("+strconv.FormatInt(key, 10)).Err()
 }
 
 func (t *ts) Close() error {
@@ -21,6 +21,7 @@ import (
 type StoreConf struct {
 	Type         string
 	ExtStateType string
+	StateType    string
 	RedisConfig  definition.RedisConfig
 	SqliteConfig definition.SqliteConfig
 	FdbConfig    definition.FdbConfig
@@ -45,6 +46,7 @@ func SetupWithConfig(sc *StoreConf) error {
 	c := definition.Config{
 		Type:         sc.Type,
 		ExtStateType: sc.ExtStateType,
+		StateType:    sc.StateType,
 		Redis:        sc.RedisConfig,
 		Sqlite:       sc.SqliteConfig,
 		Fdb:          sc.FdbConfig,
@@ -68,5 +70,9 @@ func Setup(config definition.Config) error {
 		return err
 	}
 	extStateStores = s
+	globalConfig = config
+	stateMu.Lock()
+	stateStores = make(map[string]*stores)
+	stateMu.Unlock()
 	return nil
 }
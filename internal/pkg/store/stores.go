@@ -34,6 +34,10 @@ var (
 	globalStores   *stores = nil
 	cacheStores    *stores = nil
 	extStateStores *stores = nil
+	// stateStores are the stores of the rule states whose type is different from the global store
+	stateStores  = make(map[string]*stores)
+	stateMu      sync.Mutex
+	globalConfig definition.Config
 )
 
 type stores struct {
@@ -104,7 +108,7 @@ func (s *stores) DropKV(table string) {
 
 	if ks, contains := s.kv[table]; contains {
 		_ = ks.Drop()
-		delete(s.ts, table)
+		delete(s.kv, table)
 	}
 }
 
@@ -174,6 +178,50 @@ func DropKV(table string) error {
 	return nil
 }
 
+// GetStateTS returns the checkpoint storage in the state store of the given type.
+// The default state store type is used if the type is empty.
+func GetStateTS(stateType string, table string) (kv.Tskv, error) {
+	s, err := getStateStores(stateType)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTS(table)
+}
+
+func DropStateTS(stateType string, table string) error {
+	s, err := getStateStores(stateType)
+	if err != nil {
+		return err
+	}
+	s.DropTS(table)
+	return nil
+}
+
+func getStateStores(stateType string) (*stores, error) {
+	if globalStores == nil {
+		return nil, fmt.Errorf("global stores are not initialized")
+	}
+	if stateType == "" {
+		stateType = globalConfig.StateType
+	}
+	if stateType == "" || stateType == globalConfig.Type {
+		return globalStores, nil
+	}
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	if s, ok := stateStores[stateType]; ok {
+		return s, nil
+	}
+	c := globalConfig
+	c.Type = stateType
+	s, err := newStores(c, "state.db")
+	if err != nil {
+		return nil, fmt.Errorf("fail to create state store %s: %v", stateType, err)
+	}
+	stateStores[stateType] = s
+	return s, nil
+}
+
 func GetCacheKV(table string) (kv.KeyValue, error) {
 	if cacheStores == nil {
 		return nil, fmt.Errorf("cache stores are not initialized")
@@ -194,6 +242,7 @@ func DropCacheKVForRule(rule string) error {
 		return fmt.Errorf("cache stores are not initialized")
 	}
 	cacheStores.DropRefKVs(path.Join("sink", rule))
+	cacheStores.DropRefKVs(path.Join("source", rule))
 	return nil
 }
 
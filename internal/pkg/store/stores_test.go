@@ -0,0 +1,68 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build redisdb || !core
+
+package store
+
+import (
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/pkg/store/definition"
+)
+
+func TestGetStateTS(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	host, portStr, err := net.SplitHostPort(mr.Addr())
+	require.NoError(t, err)
+	port, err := strconv.Atoi(portStr)
+	require.NoError(t, err)
+
+	err = Setup(definition.Config{
+		Type:         "sqlite",
+		ExtStateType: "sqlite",
+		StateType:    "redis",
+		Sqlite:       definition.SqliteConfig{Path: t.TempDir()},
+		Redis:        definition.RedisConfig{Host: host, Port: port},
+	})
+	require.NoError(t, err)
+
+	// The default state store is redis
+	ts, err := GetStateTS("", "rule1")
+	require.NoError(t, err)
+	ok, err := ts.Set(1, "v1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.True(t, mr.Exists("KV:TS:rule1"))
+	// The rule specifies the sqlite store which is the global store
+	ts, err = GetStateTS("sqlite", "rule2")
+	require.NoError(t, err)
+	gts, err := GetTS("rule2")
+	require.NoError(t, err)
+	assert.Same(t, gts, ts)
+
+	require.NoError(t, DropStateTS("", "rule1"))
+	assert.False(t, mr.Exists("KV:TS:rule1"))
+
+	_, err = GetStateTS("unknown", "rule3")
+	assert.EqualError(t, err, "fail to create state store unknown: unknown database type: unknown")
+}
@@ -109,6 +109,14 @@ func TestTsDeleteBefore(ks kv.Tskv, t *testing.T) {
 	} else {
 		t.Errorf("Should find key 3500.")
 	}
+
+	// The key itself is not deleted
+	if err := ks.DeleteBefore(3000); nil != err {
+		t.Error(err)
+	}
+	if ok, _ := ks.Get(3000, &value); !ok {
+		t.Errorf("Should find key 3000 after deleting before it.")
+	}
 }
 
 func load(ks kv.Tskv, t *testing.T) {
@@ -0,0 +1,47 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package onnx
+
+import (
+	"strings"
+
+	"github.com/lf-edge/ekuiper/internal/plugin"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+func (m *Manager) Function(name string) (api.Function, error) {
+	if name != FuncName {
+		return nil, nil
+	}
+	return newFunc(m), nil
+}
+
+func (m *Manager) HasFunctionSet(_ string) bool {
+	return false
+}
+
+func (m *Manager) FunctionPluginInfo(funcName string) (plugin.EXTENSION_TYPE, string, string) {
+	_, ok := m.ConvName(funcName)
+	if !ok {
+		return plugin.NONE_EXTENSION, "", ""
+	} else {
+		return plugin.INTERNAL, "", ""
+	}
+}
+
+func (m *Manager) ConvName(n string) (string, bool) {
+	name := strings.ToLower(n)
+	return name, name == FuncName
+}
@@ -0,0 +1,138 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package onnx
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+// FuncName is the name of the function to run the onnx models
+const FuncName = "onnx"
+
+type loadedModel struct {
+	s       session
+	version int64
+}
+
+// Func is stateful
+// Each instance is created for a rule operator and keeps the loaded models warm until the rule is dropped
+type Func struct {
+	sync.Mutex
+	m      *Manager
+	models map[string]*loadedModel
+}
+
+func newFunc(m *Manager) *Func {
+	f := &Func{
+		m:      m,
+		models: make(map[string]*loadedModel),
+	}
+	// The function has no close hook, release the sessions when the rule is gone
+	runtime.SetFinalizer(f, (*Func).Close)
+	return f
+}
+
+func (f *Func) Validate(args []interface{}) error {
+	if len(args) < 2 {
+		return fmt.Errorf("The onnx function must have at least 2 parameters, but got %d", len(args))
+	}
+	if n, ok := args[0].(*ast.StringLiteral); ok {
+		if f.m.version(n.Val) == 0 {
+			return fmt.Errorf("onnx model %s not found", n.Val)
+		}
+	}
+	return nil
+}
+
+func (f *Func) Exec(args []interface{}, ctx api.FunctionContext) (interface{}, bool) {
+	name, ok := args[0].(string)
+	if !ok {
+		return fmt.Errorf("the model name must be a string but got %v", args[0]), false
+	}
+	f.Lock()
+	defer f.Unlock()
+	s, err := f.load(ctx, name)
+	if err != nil {
+		return err, false
+	}
+	inputs := s.inputs()
+	if len(args)-1 != len(inputs) {
+		return fmt.Errorf("model %s expects %d inputs but got %d", name, len(inputs), len(args)-1), false
+	}
+	tensors := make([]*tensor, len(inputs))
+	for i, in := range inputs {
+		tensors[i], err = toTensor(args[i+1], in)
+		if err != nil {
+			return err, false
+		}
+	}
+	outs, err := s.run(tensors)
+	if err != nil {
+		return err, false
+	}
+	result := make(map[string]interface{}, len(outs))
+	for i, o := range s.outputs() {
+		if i >= len(outs) {
+			break
+		}
+		result[o.Name], err = fromTensor(outs[i])
+		if err != nil {
+			return fmt.Errorf("invalid output %s: %v", o.Name, err), false
+		}
+	}
+	return result, true
+}
+
+// load returns the cached session of the model and reloads it if the model is updated
+func (f *Func) load(ctx api.FunctionContext, name string) (session, error) {
+	v := f.m.version(name)
+	lm, ok := f.models[name]
+	if ok && lm.version == v {
+		return lm.s, nil
+	}
+	if ok {
+		lm.s.close()
+		delete(f.models, name)
+	}
+	if v == 0 {
+		return nil, fmt.Errorf("onnx model %s not found", name)
+	}
+	ctx.GetLogger().Infof("loading onnx model %s", name)
+	s, err := sessionLoader(f.m.path(name))
+	if err != nil {
+		return nil, err
+	}
+	f.models[name] = &loadedModel{s: s, version: v}
+	return s, nil
+}
+
+func (f *Func) IsAggregate() bool {
+	return false
+}
+
+func (f *Func) Close() error {
+	f.Lock()
+	defer f.Unlock()
+	for k, lm := range f.models {
+		lm.s.close()
+		delete(f.models, k)
+	}
+	return nil
+}
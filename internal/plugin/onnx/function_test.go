@@ -0,0 +1,187 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package onnx
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/testx"
+	kctx "github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/topo/state"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+func init() {
+	testx.InitEnv("onnx")
+	// Wait for other db tests to finish to avoid db lock
+	for i := 0; i < 10; i++ {
+		if err := InitManager(); err != nil {
+			time.Sleep(10 * time.Millisecond)
+		} else {
+			break
+		}
+	}
+}
+
+// mockSession doubles the float input, the model file content is used as the output name
+type mockSession struct {
+	out    string
+	closed bool
+}
+
+func (s *mockSession) inputs() []TensorInfo {
+	return []TensorInfo{newTensorInfo("x", elemFloat, []int64{-1, 2})}
+}
+
+func (s *mockSession) outputs() []TensorInfo {
+	return []TensorInfo{newTensorInfo(s.out, elemDouble, []int64{-1, 2})}
+}
+
+func (s *mockSession) run(inputs []*tensor) ([]*tensor, error) {
+	v, err := fromTensor(inputs[0])
+	if err != nil {
+		return nil, err
+	}
+	var values []interface{}
+	for _, row := range v.([]interface{}) {
+		for _, f := range row.([]interface{}) {
+			values = append(values, f.(float64)*2)
+		}
+	}
+	return []*tensor{mustTensor(values, s.outputs()[0])}, nil
+}
+
+func (s *mockSession) close() {
+	s.closed = true
+}
+
+func mustTensor(values []interface{}, info TensorInfo) *tensor {
+	t, err := toTensor(values, info)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func mockLoader(loaded *[]*mockSession) func(string) (session, error) {
+	return func(path string) (session, error) {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) == 0 {
+			return nil, errors.New("empty model")
+		}
+		s := &mockSession{out: string(b)}
+		*loaded = append(*loaded, s)
+		return s, nil
+	}
+}
+
+func TestModelManager(t *testing.T) {
+	var loaded []*mockSession
+	sessionLoader = mockLoader(&loaded)
+	defer func() {
+		sessionLoader = openSession
+	}()
+	m := GetManager()
+	require.NoError(t, m.Create(&Model{Name: "m1", Description: "test model"}, strings.NewReader("y")))
+	assert.FileExists(t, m.path("m1"))
+	assert.EqualError(t, m.Create(&Model{Name: "m1"}, strings.NewReader("y")), "model m1 already exists")
+	assert.EqualError(t, m.Create(&Model{Name: "../m2"}, strings.NewReader("y")), "invalid model name ../m2, only letters, digits, '_' and '-' are allowed")
+	assert.EqualError(t, m.Create(&Model{Name: "m2"}, strings.NewReader("")), "invalid model m2: empty model")
+	assert.NoFileExists(t, m.path("m2"))
+
+	model, err := m.GetModel("m1")
+	require.NoError(t, err)
+	assert.Equal(t, "test model", model.Description)
+	assert.Equal(t, []TensorInfo{{Name: "x", Type: "float", Shape: []int64{-1, 2}}}, model.Inputs)
+	assert.Equal(t, []TensorInfo{{Name: "y", Type: "double", Shape: []int64{-1, 2}}}, model.Outputs)
+	keys, err := m.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"m1"}, keys)
+
+	require.NoError(t, m.Delete("m1"))
+	assert.NoFileExists(t, m.path("m1"))
+	assert.EqualError(t, m.Delete("m1"), "model m1 not found")
+	_, err = m.GetModel("m1")
+	assert.EqualError(t, err, "model m1 not found")
+}
+
+func TestFuncExec(t *testing.T) {
+	var loaded []*mockSession
+	sessionLoader = mockLoader(&loaded)
+	defer func() {
+		sessionLoader = openSession
+	}()
+	m := GetManager()
+	require.NoError(t, m.Create(&Model{Name: "double"}, strings.NewReader("y")))
+	defer func() {
+		_ = m.Delete("double")
+	}()
+	loaded = nil
+
+	fn, err := m.Function(FuncName)
+	require.NoError(t, err)
+	f := fn.(*Func)
+	assert.NoError(t, f.Validate([]interface{}{&ast.StringLiteral{Val: "double"}, &ast.FieldRef{Name: "a"}}))
+	assert.EqualError(t, f.Validate([]interface{}{&ast.StringLiteral{Val: "double"}}), "The onnx function must have at least 2 parameters, but got 1")
+	assert.EqualError(t, f.Validate([]interface{}{&ast.StringLiteral{Val: "none"}, &ast.FieldRef{Name: "a"}}), "onnx model none not found")
+
+	contextLogger := conf.Log.WithField("rule", "testExec")
+	ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
+	tempStore, _ := state.CreateStore("mockRule0", api.AtMostOnce)
+	fctx := kctx.NewDefaultFuncContext(ctx.WithMeta("mockRule0", "test", tempStore), 1)
+
+	r, ok := f.Exec([]interface{}{"double", []interface{}{1.0, 2.5}}, fctx)
+	require.True(t, ok, r)
+	assert.Equal(t, map[string]interface{}{"y": []interface{}{[]interface{}{2.0, 5.0}}}, r)
+	// the model is kept warm
+	r, ok = f.Exec([]interface{}{"double", []interface{}{3.0, 4.0, 5.0, 6.0}}, fctx)
+	require.True(t, ok, r)
+	assert.Equal(t, map[string]interface{}{"y": []interface{}{[]interface{}{6.0, 8.0}, []interface{}{10.0, 12.0}}}, r)
+	assert.Len(t, loaded, 1)
+
+	r, ok = f.Exec([]interface{}{"double", []interface{}{1.0, 2.0, 3.0}}, fctx)
+	assert.False(t, ok)
+	assert.EqualError(t, r.(error), "invalid input x: expect 2 elements for shape [-1 2] but got 3")
+	r, ok = f.Exec([]interface{}{"double", 1.0, 2.0}, fctx)
+	assert.False(t, ok)
+	assert.EqualError(t, r.(error), "model double expects 1 inputs but got 2")
+	r, ok = f.Exec([]interface{}{"none", 1.0}, fctx)
+	assert.False(t, ok)
+	assert.EqualError(t, r.(error), "onnx model none not found")
+
+	// reload after the model is updated
+	require.NoError(t, m.Update(&Model{Name: "double"}, strings.NewReader("z")))
+	loaded = loaded[:1]
+	r, ok = f.Exec([]interface{}{"double", []interface{}{1.0, 2.0}}, fctx)
+	require.True(t, ok, r)
+	assert.Equal(t, map[string]interface{}{"z": []interface{}{[]interface{}{2.0, 4.0}}}, r)
+	require.Len(t, loaded, 2)
+	assert.True(t, loaded[0].closed)
+
+	require.NoError(t, f.Close())
+	assert.True(t, loaded[1].closed)
+}
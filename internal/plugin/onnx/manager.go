@@ -0,0 +1,203 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package onnx
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/pkg/kv"
+)
+
+var (
+	manager   *Manager
+	nameRegex = regexp.MustCompile(`^[A-Za-z0-9_\-]+$`)
+)
+
+func GetManager() *Manager {
+	return manager
+}
+
+// session is a loaded model which is run by the ONNX Runtime
+type session interface {
+	inputs() []TensorInfo
+	outputs() []TensorInfo
+	run(inputs []*tensor) ([]*tensor, error)
+	close()
+}
+
+// sessionLoader loads the model file, replaced in tests
+var sessionLoader = openSession
+
+// Model is the uploaded ONNX model. The model file is saved in the data/models/onnx folder.
+type Model struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Inputs      []TensorInfo `json:"inputs"`
+	Outputs     []TensorInfo `json:"outputs"`
+}
+
+// Manager manages the model files. The loaded models are cached by each function instance, the version is increased
+// when a model is updated or deleted so that the function instances reload it.
+type Manager struct {
+	sync.RWMutex
+	dir      string
+	db       kv.KeyValue
+	versions map[string]int64
+	seq      int64
+}
+
+// InitManager initialize the manager, only called once by the server
+func InitManager() error {
+	dataDir, err := conf.GetDataLoc()
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(dataDir, "models", "onnx")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("can not create the onnx model folder %s: %v", dir, err)
+	}
+	db, err := store.GetKV("onnxModel")
+	if err != nil {
+		return fmt.Errorf("can not initialize store for the onnx model manager at path 'onnxModel': %v", err)
+	}
+	manager = &Manager{
+		dir:      dir,
+		db:       db,
+		versions: make(map[string]int64),
+	}
+	keys, err := db.Keys()
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		manager.bump(k)
+	}
+	return nil
+}
+
+func (m *Manager) path(name string) string {
+	return filepath.Join(m.dir, name+".onnx")
+}
+
+// Create saves the new model file read from the reader
+func (m *Manager) Create(model *Model, r io.Reader) error {
+	if err := validate(model); err != nil {
+		return err
+	}
+	if m.version(model.Name) > 0 {
+		return fmt.Errorf("model %s already exists", model.Name)
+	}
+	return m.save(model, r)
+}
+
+// Update saves the model file read from the reader, the running rules will reload the model
+func (m *Manager) Update(model *Model, r io.Reader) error {
+	if err := validate(model); err != nil {
+		return err
+	}
+	return m.save(model, r)
+}
+
+func validate(model *Model) error {
+	if model.Name == "" {
+		return fmt.Errorf("model name is required")
+	}
+	if !nameRegex.MatchString(model.Name) {
+		return fmt.Errorf("invalid model name %s, only letters, digits, '_' and '-' are allowed", model.Name)
+	}
+	return nil
+}
+
+// save writes the file to a temporary path and loads it to verify before replacing the model
+func (m *Manager) save(model *Model, r io.Reader) error {
+	p := m.path(model.Name)
+	tmp := p + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	_ = f.Close()
+	if err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("fail to save model %s: %v", model.Name, err)
+	}
+	s, err := sessionLoader(tmp)
+	if err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("invalid model %s: %v", model.Name, err)
+	}
+	model.Inputs, model.Outputs = s.inputs(), s.outputs()
+	s.close()
+	if err := os.Rename(tmp, p); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := m.db.Set(model.Name, model); err != nil {
+		return err
+	}
+	m.bump(model.Name)
+	return nil
+}
+
+func (m *Manager) GetModel(name string) (*Model, error) {
+	result := &Model{}
+	ok, err := m.db.Get(name, result)
+	if !ok && err == nil {
+		return nil, fmt.Errorf("model %s not found", name)
+	}
+	return result, err
+}
+
+func (m *Manager) List() ([]string, error) {
+	return m.db.Keys()
+}
+
+func (m *Manager) Delete(name string) error {
+	if m.version(name) == 0 {
+		return fmt.Errorf("model %s not found", name)
+	}
+	if err := m.db.Delete(name); err != nil {
+		return err
+	}
+	m.Lock()
+	delete(m.versions, name)
+	m.Unlock()
+	if err := os.Remove(m.path(name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) bump(name string) {
+	m.Lock()
+	defer m.Unlock()
+	m.seq++
+	m.versions[name] = m.seq
+}
+
+// version returns the current version of the model or 0 if not found
+func (m *Manager) version(name string) int64 {
+	m.RLock()
+	defer m.RUnlock()
+	return m.versions[name]
+}
@@ -0,0 +1,357 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build onnx
+
+package onnx
+
+/*
+#cgo LDFLAGS: -lonnxruntime
+#include <stdlib.h>
+#include <string.h>
+#include <onnxruntime_c_api.h>
+
+static const OrtApi *ort_api(void) {
+	static const OrtApi *api = NULL;
+	if (api == NULL) {
+		api = OrtGetApiBase()->GetApi(ORT_API_VERSION);
+	}
+	return api;
+}
+
+// ort_error returns the error message of the status which must be freed by the caller, or NULL if succeeded
+static char *ort_error(OrtStatus *status) {
+	if (status == NULL) {
+		return NULL;
+	}
+	char *msg = strdup(ort_api()->GetErrorMessage(status));
+	ort_api()->ReleaseStatus(status);
+	return msg;
+}
+
+static char *ort_create_env(OrtEnv **env) {
+	if (ort_api() == NULL) {
+		return strdup("the onnx runtime library does not support the api version");
+	}
+	return ort_error(ort_api()->CreateEnv(ORT_LOGGING_LEVEL_WARNING, "ekuiper", env));
+}
+
+static char *ort_create_session(OrtEnv *env, const char *path, OrtSession **session) {
+	OrtSessionOptions *opts;
+	char *err = ort_error(ort_api()->CreateSessionOptions(&opts));
+	if (err != NULL) {
+		return err;
+	}
+	err = ort_error(ort_api()->CreateSession(env, path, opts, session));
+	ort_api()->ReleaseSessionOptions(opts);
+	return err;
+}
+
+static void ort_release_session(OrtSession *session) {
+	ort_api()->ReleaseSession(session);
+}
+
+static char *ort_io_count(OrtSession *session, int input, size_t *count) {
+	if (input) {
+		return ort_error(ort_api()->SessionGetInputCount(session, count));
+	}
+	return ort_error(ort_api()->SessionGetOutputCount(session, count));
+}
+
+// ort_shape reads the element type and the shape, the dims must be freed by the caller
+static char *ort_shape(const OrtTensorTypeAndShapeInfo *info, int *elemType, int64_t **dims, size_t *ndims) {
+	ONNXTensorElementDataType t;
+	char *err = ort_error(ort_api()->GetTensorElementType(info, &t));
+	if (err != NULL) {
+		return err;
+	}
+	*elemType = t;
+	err = ort_error(ort_api()->GetDimensionsCount(info, ndims));
+	if (err != NULL) {
+		return err;
+	}
+	*dims = malloc(sizeof(int64_t) * (*ndims + 1));
+	err = ort_error(ort_api()->GetDimensions(info, *dims, *ndims));
+	if (err != NULL) {
+		free(*dims);
+		*dims = NULL;
+	}
+	return err;
+}
+
+// ort_io_info reads the name, element type and shape of the input or output, the name and dims must be freed by the caller
+static char *ort_io_info(OrtSession *session, int input, size_t i, char **name, int *elemType, int64_t **dims, size_t *ndims) {
+	OrtAllocator *alloc;
+	char *err = ort_error(ort_api()->GetAllocatorWithDefaultOptions(&alloc));
+	if (err != NULL) {
+		return err;
+	}
+	char *n;
+	if (input) {
+		err = ort_error(ort_api()->SessionGetInputName(session, i, alloc, &n));
+	} else {
+		err = ort_error(ort_api()->SessionGetOutputName(session, i, alloc, &n));
+	}
+	if (err != NULL) {
+		return err;
+	}
+	*name = strdup(n);
+	ort_api()->AllocatorFree(alloc, n);
+	OrtTypeInfo *typeInfo;
+	if (input) {
+		err = ort_error(ort_api()->SessionGetInputTypeInfo(session, i, &typeInfo));
+	} else {
+		err = ort_error(ort_api()->SessionGetOutputTypeInfo(session, i, &typeInfo));
+	}
+	if (err != NULL) {
+		return err;
+	}
+	const OrtTensorTypeAndShapeInfo *info;
+	err = ort_error(ort_api()->CastTypeInfoToTensorInfo(typeInfo, &info));
+	if (err == NULL && info == NULL) {
+		err = strdup("only tensor is supported");
+	}
+	if (err == NULL) {
+		err = ort_shape(info, elemType, dims, ndims);
+	}
+	ort_api()->ReleaseTypeInfo(typeInfo);
+	return err;
+}
+
+// ort_create_tensor creates the tensor with the data which must be valid until the tensor is released
+static char *ort_create_tensor(void *data, size_t size, int64_t *shape, size_t ndims, int elemType, OrtValue **value) {
+	OrtMemoryInfo *mem;
+	char *err = ort_error(ort_api()->CreateCpuMemoryInfo(OrtArenaAllocator, OrtMemTypeDefault, &mem));
+	if (err != NULL) {
+		return err;
+	}
+	err = ort_error(ort_api()->CreateTensorWithDataAsOrtValue(mem, data, size, shape, ndims, elemType, value));
+	ort_api()->ReleaseMemoryInfo(mem);
+	return err;
+}
+
+// ort_tensor_data reads the data and shape of the tensor, the dims must be freed by the caller
+static char *ort_tensor_data(OrtValue *value, void **data, size_t *count, int *elemType, int64_t **dims, size_t *ndims) {
+	OrtTensorTypeAndShapeInfo *info;
+	char *err = ort_error(ort_api()->GetTensorTypeAndShape(value, &info));
+	if (err != NULL) {
+		return err;
+	}
+	err = ort_error(ort_api()->GetTensorShapeElementCount(info, count));
+	if (err == NULL) {
+		err = ort_shape(info, elemType, dims, ndims);
+	}
+	ort_api()->ReleaseTensorTypeAndShapeInfo(info);
+	if (err == NULL) {
+		err = ort_error(ort_api()->GetTensorMutableData(value, data));
+		if (err != NULL) {
+			free(*dims);
+			*dims = NULL;
+		}
+	}
+	return err;
+}
+
+static void ort_release_value(OrtValue *value) {
+	ort_api()->ReleaseValue(value);
+}
+
+static char *ort_run(OrtSession *session, char **inNames, OrtValue **inputs, size_t nIn, char **outNames, OrtValue **outputs, size_t nOut) {
+	return ort_error(ort_api()->Run(session, NULL, (const char *const *)inNames, (const OrtValue *const *)inputs, nIn, (const char *const *)outNames, nOut, outputs));
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"unsafe"
+)
+
+var (
+	envOnce sync.Once
+	env     *C.OrtEnv
+	envErr  error
+)
+
+// ortSession is the model loaded by ONNX Runtime. A session can be run concurrently.
+type ortSession struct {
+	session    *C.OrtSession
+	inputInfo  []TensorInfo
+	outputInfo []TensorInfo
+	// the input and output names in C memory
+	inNames  []*C.char
+	outNames []*C.char
+}
+
+func cError(msg *C.char) error {
+	if msg == nil {
+		return nil
+	}
+	defer C.free(unsafe.Pointer(msg))
+	return errors.New(C.GoString(msg))
+}
+
+func cShape(dims *C.int64_t, ndims C.size_t) []int64 {
+	shape := make([]int64, int(ndims))
+	for i, d := range unsafe.Slice(dims, int(ndims)) {
+		shape[i] = int64(d)
+	}
+	C.free(unsafe.Pointer(dims))
+	return shape
+}
+
+func openSession(path string) (session, error) {
+	envOnce.Do(func() {
+		envErr = cError(C.ort_create_env(&env))
+	})
+	if envErr != nil {
+		return nil, fmt.Errorf("fail to initialize onnx runtime: %v", envErr)
+	}
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	s := &ortSession{}
+	if err := cError(C.ort_create_session(env, cpath, &s.session)); err != nil {
+		return nil, fmt.Errorf("fail to load model %s: %v", path, err)
+	}
+	var err error
+	s.inputInfo, s.inNames, err = s.ioInfo(1)
+	if err == nil {
+		s.outputInfo, s.outNames, err = s.ioInfo(0)
+	}
+	if err != nil {
+		s.close()
+		return nil, fmt.Errorf("fail to read model %s: %v", path, err)
+	}
+	return s, nil
+}
+
+func (s *ortSession) ioInfo(input C.int) ([]TensorInfo, []*C.char, error) {
+	var count C.size_t
+	if err := cError(C.ort_io_count(s.session, input, &count)); err != nil {
+		return nil, nil, err
+	}
+	infos := make([]TensorInfo, int(count))
+	names := make([]*C.char, int(count))
+	for i := range infos {
+		var (
+			name     *C.char
+			elemType C.int
+			dims     *C.int64_t
+			ndims    C.size_t
+		)
+		err := cError(C.ort_io_info(s.session, input, C.size_t(i), &name, &elemType, &dims, &ndims))
+		if name != nil {
+			names[i] = name
+		}
+		if err != nil {
+			freeNames(names)
+			return nil, nil, err
+		}
+		infos[i] = newTensorInfo(C.GoString(name), int(elemType), cShape(dims, ndims))
+	}
+	return infos, names, nil
+}
+
+func freeNames(names []*C.char) {
+	for _, n := range names {
+		if n != nil {
+			C.free(unsafe.Pointer(n))
+		}
+	}
+}
+
+func (s *ortSession) inputs() []TensorInfo {
+	return s.inputInfo
+}
+
+func (s *ortSession) outputs() []TensorInfo {
+	return s.outputInfo
+}
+
+func (s *ortSession) run(inputs []*tensor) ([]*tensor, error) {
+	values := make([]*C.OrtValue, len(inputs))
+	defer func() {
+		for _, v := range values {
+			if v != nil {
+				C.ort_release_value(v)
+			}
+		}
+	}()
+	for i, t := range inputs {
+		// The tensor refers to the data without copying, so the data must be in C memory during the run
+		data := C.CBytes(t.data)
+		defer C.free(data)
+		shape := make([]C.int64_t, len(t.shape)+1)
+		for j, d := range t.shape {
+			shape[j] = C.int64_t(d)
+		}
+		if err := cError(C.ort_create_tensor(data, C.size_t(len(t.data)), &shape[0], C.size_t(len(t.shape)), C.int(t.elemType), &values[i])); err != nil {
+			return nil, fmt.Errorf("fail to create tensor for input %s: %v", s.inputInfo[i].Name, err)
+		}
+	}
+	outputs := make([]*C.OrtValue, len(s.outNames))
+	defer func() {
+		for _, v := range outputs {
+			if v != nil {
+				C.ort_release_value(v)
+			}
+		}
+	}()
+	var (
+		inNames  **C.char
+		inValues **C.OrtValue
+	)
+	if len(values) > 0 {
+		inNames, inValues = &s.inNames[0], &values[0]
+	}
+	if err := cError(C.ort_run(s.session, inNames, inValues, C.size_t(len(values)), &s.outNames[0], &outputs[0], C.size_t(len(outputs)))); err != nil {
+		return nil, fmt.Errorf("fail to run model: %v", err)
+	}
+	result := make([]*tensor, len(outputs))
+	for i, v := range outputs {
+		var (
+			data     unsafe.Pointer
+			count    C.size_t
+			elemType C.int
+			dims     *C.int64_t
+			ndims    C.size_t
+		)
+		if err := cError(C.ort_tensor_data(v, &data, &count, &elemType, &dims, &ndims)); err != nil {
+			return nil, fmt.Errorf("fail to read output %s: %v", s.outputInfo[i].Name, err)
+		}
+		et, ok := elemTypes[int(elemType)]
+		if !ok {
+			C.free(unsafe.Pointer(dims))
+			return nil, fmt.Errorf("output %s has unsupported type %d", s.outputInfo[i].Name, int(elemType))
+		}
+		result[i] = &tensor{
+			elemType: int(elemType),
+			shape:    cShape(dims, ndims),
+			data:     C.GoBytes(data, C.int(int(count)*et.size)),
+		}
+	}
+	return result, nil
+}
+
+func (s *ortSession) close() {
+	if s.session != nil {
+		C.ort_release_session(s.session)
+		s.session = nil
+	}
+	freeNames(s.inNames)
+	freeNames(s.outNames)
+	s.inNames, s.outNames = nil, nil
+}
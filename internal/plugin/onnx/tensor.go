@@ -0,0 +1,243 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package onnx
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+// The tensor element types, the values are the same as ONNXTensorElementDataType of ONNX Runtime
+const (
+	elemFloat  = 1
+	elemUint8  = 2
+	elemInt8   = 3
+	elemInt16  = 5
+	elemInt32  = 6
+	elemInt64  = 7
+	elemBool   = 9
+	elemDouble = 11
+)
+
+var elemTypes = map[int]struct {
+	name string
+	size int
+}{
+	elemFloat:  {"float", 4},
+	elemUint8:  {"uint8", 1},
+	elemInt8:   {"int8", 1},
+	elemInt16:  {"int16", 2},
+	elemInt32:  {"int32", 4},
+	elemInt64:  {"int64", 8},
+	elemBool:   {"bool", 1},
+	elemDouble: {"double", 8},
+}
+
+// TensorInfo describes an input or output of the model. A negative dimension of the shape means it is dynamic.
+type TensorInfo struct {
+	Name     string  `json:"name"`
+	Type     string  `json:"type"`
+	Shape    []int64 `json:"shape"`
+	elemType int
+}
+
+func newTensorInfo(name string, elemType int, shape []int64) TensorInfo {
+	t := TensorInfo{Name: name, Shape: shape, elemType: elemType}
+	if et, ok := elemTypes[elemType]; ok {
+		t.Type = et.name
+	} else {
+		t.Type = fmt.Sprintf("unsupported(%d)", elemType)
+	}
+	return t
+}
+
+// tensor is the data exchanged with the runtime, the data is in the native byte order
+type tensor struct {
+	elemType int
+	shape    []int64
+	data     []byte
+}
+
+// toTensor converts the function argument to the tensor of the model input. The argument could be a number, a nested
+// array of numbers or a bytea which is the raw data of the tensor. The dynamic dimensions are inferred from the data
+// size, the first one takes the remaining size and the others are set to 1.
+func toTensor(arg interface{}, info TensorInfo) (*tensor, error) {
+	et, ok := elemTypes[info.elemType]
+	if !ok {
+		return nil, fmt.Errorf("input %s has unsupported type %s", info.Name, info.Type)
+	}
+	var (
+		data  []byte
+		count int64
+	)
+	if b, ok := arg.([]byte); ok {
+		if len(b)%et.size != 0 {
+			return nil, fmt.Errorf("the bytea size %d of input %s is not a multiple of the %s size %d", len(b), info.Name, et.name, et.size)
+		}
+		data = b
+		count = int64(len(b) / et.size)
+	} else {
+		var err error
+		data, err = appendElems(nil, arg, info.elemType)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input %s: %v", info.Name, err)
+		}
+		count = int64(len(data) / et.size)
+	}
+	shape, err := resolveShape(info.Shape, count)
+	if err != nil {
+		return nil, fmt.Errorf("invalid input %s: %v", info.Name, err)
+	}
+	return &tensor{elemType: info.elemType, shape: shape, data: data}, nil
+}
+
+func appendElems(data []byte, arg interface{}, elemType int) ([]byte, error) {
+	if arr, ok := arg.([]interface{}); ok {
+		var err error
+		for _, v := range arr {
+			data, err = appendElems(data, v, elemType)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return data, nil
+	}
+	switch elemType {
+	case elemFloat:
+		f, err := cast.ToFloat64(arg, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return nil, err
+		}
+		return binary.NativeEndian.AppendUint32(data, math.Float32bits(float32(f))), nil
+	case elemDouble:
+		f, err := cast.ToFloat64(arg, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return nil, err
+		}
+		return binary.NativeEndian.AppendUint64(data, math.Float64bits(f)), nil
+	case elemBool:
+		b, err := cast.ToBool(arg, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return nil, err
+		}
+		if b {
+			return append(data, 1), nil
+		}
+		return append(data, 0), nil
+	default:
+		i, err := cast.ToInt64(arg, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return nil, err
+		}
+		switch elemType {
+		case elemUint8, elemInt8:
+			return append(data, byte(i)), nil
+		case elemInt16:
+			return binary.NativeEndian.AppendUint16(data, uint16(i)), nil
+		case elemInt32:
+			return binary.NativeEndian.AppendUint32(data, uint32(i)), nil
+		default:
+			return binary.NativeEndian.AppendUint64(data, uint64(i)), nil
+		}
+	}
+}
+
+func resolveShape(dims []int64, count int64) ([]int64, error) {
+	shape := make([]int64, len(dims))
+	var (
+		known   int64 = 1
+		dynamic       = -1
+	)
+	for i, d := range dims {
+		if d < 0 {
+			if dynamic < 0 {
+				dynamic = i
+			}
+			shape[i] = 1
+		} else {
+			shape[i] = d
+			known *= d
+		}
+	}
+	if dynamic >= 0 && known > 0 && count%known == 0 {
+		shape[dynamic] = count / known
+		known = count
+	}
+	if known != count {
+		return nil, fmt.Errorf("expect %d elements for shape %v but got %d", known, dims, count)
+	}
+	return shape, nil
+}
+
+// fromTensor converts the output tensor to the function result. The floats are converted to float64 and the integers
+// are converted to int64, then they are put into the nested arrays according to the shape.
+func fromTensor(t *tensor) (interface{}, error) {
+	et, ok := elemTypes[t.elemType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported output type %d", t.elemType)
+	}
+	values := make([]interface{}, len(t.data)/et.size)
+	for i := range values {
+		b := t.data[i*et.size:]
+		switch t.elemType {
+		case elemFloat:
+			values[i] = float64(math.Float32frombits(binary.NativeEndian.Uint32(b)))
+		case elemDouble:
+			values[i] = math.Float64frombits(binary.NativeEndian.Uint64(b))
+		case elemBool:
+			values[i] = b[0] != 0
+		case elemUint8:
+			values[i] = int64(b[0])
+		case elemInt8:
+			values[i] = int64(int8(b[0]))
+		case elemInt16:
+			values[i] = int64(int16(binary.NativeEndian.Uint16(b)))
+		case elemInt32:
+			values[i] = int64(int32(binary.NativeEndian.Uint32(b)))
+		case elemInt64:
+			values[i] = int64(binary.NativeEndian.Uint64(b))
+		}
+	}
+	if len(t.shape) == 0 {
+		if len(values) != 1 {
+			return nil, fmt.Errorf("expect a scalar output but got %d elements", len(values))
+		}
+		return values[0], nil
+	}
+	var size int64 = 1
+	for _, d := range t.shape {
+		size *= d
+	}
+	if size != int64(len(values)) {
+		return nil, fmt.Errorf("expect %d elements for shape %v but got %d", size, t.shape, len(values))
+	}
+	return reshape(values, t.shape), nil
+}
+
+func reshape(values []interface{}, shape []int64) []interface{} {
+	if len(shape) == 1 {
+		return values
+	}
+	n := int(shape[0])
+	r := make([]interface{}, n)
+	step := len(values) / max(n, 1)
+	for i := range r {
+		r[i] = reshape(values[i*step:(i+1)*step], shape[1:])
+	}
+	return r
+}
@@ -0,0 +1,131 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package onnx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTensorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		arg    interface{}
+		info   TensorInfo
+		shape  []int64
+		result interface{}
+	}{
+		{
+			name:   "float matrix",
+			arg:    []interface{}{[]interface{}{1.5, 2}, []interface{}{int64(3), 4.25}},
+			info:   newTensorInfo("x", elemFloat, []int64{2, 2}),
+			shape:  []int64{2, 2},
+			result: []interface{}{[]interface{}{1.5, 2.0}, []interface{}{3.0, 4.25}},
+		},
+		{
+			name:   "dynamic batch",
+			arg:    []interface{}{1, 2, 3, 4, 5, 6},
+			info:   newTensorInfo("x", elemInt64, []int64{-1, 3}),
+			shape:  []int64{2, 3},
+			result: []interface{}{[]interface{}{int64(1), int64(2), int64(3)}, []interface{}{int64(4), int64(5), int64(6)}},
+		},
+		{
+			name:   "multiple dynamic dims",
+			arg:    []interface{}{1.0, 2.0},
+			info:   newTensorInfo("x", elemDouble, []int64{-1, -1}),
+			shape:  []int64{2, 1},
+			result: []interface{}{[]interface{}{1.0}, []interface{}{2.0}},
+		},
+		{
+			name:   "raw bytea",
+			arg:    []byte{1, 255, 3},
+			info:   newTensorInfo("x", elemUint8, []int64{1, 3}),
+			shape:  []int64{1, 3},
+			result: []interface{}{[]interface{}{int64(1), int64(255), int64(3)}},
+		},
+		{
+			name:   "int8",
+			arg:    []interface{}{-1, 2},
+			info:   newTensorInfo("x", elemInt8, []int64{2}),
+			shape:  []int64{2},
+			result: []interface{}{int64(-1), int64(2)},
+		},
+		{
+			name:   "bool scalar",
+			arg:    true,
+			info:   newTensorInfo("x", elemBool, []int64{}),
+			shape:  []int64{},
+			result: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, err := toTensor(tt.arg, tt.info)
+			require.NoError(t, err)
+			assert.Equal(t, tt.shape, ts.shape)
+			r, err := fromTensor(ts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.result, r)
+		})
+	}
+}
+
+func TestToTensorError(t *testing.T) {
+	tests := []struct {
+		name string
+		arg  interface{}
+		info TensorInfo
+		err  string
+	}{
+		{
+			name: "size mismatch",
+			arg:  []interface{}{1.0, 2.0, 3.0},
+			info: newTensorInfo("x", elemFloat, []int64{1, 2}),
+			err:  "invalid input x: expect 2 elements for shape [1 2] but got 3",
+		},
+		{
+			name: "dynamic size mismatch",
+			arg:  []interface{}{1.0, 2.0, 3.0},
+			info: newTensorInfo("x", elemFloat, []int64{-1, 2}),
+			err:  "invalid input x: expect 2 elements for shape [-1 2] but got 3",
+		},
+		{
+			name: "bytea size",
+			arg:  []byte{1, 2, 3},
+			info: newTensorInfo("x", elemFloat, []int64{-1}),
+			err:  "the bytea size 3 of input x is not a multiple of the float size 4",
+		},
+		{
+			name: "invalid value",
+			arg:  []interface{}{"a"},
+			info: newTensorInfo("x", elemFloat, []int64{1}),
+			err:  "invalid input x: cannot convert string(a) to float64",
+		},
+		{
+			name: "unsupported type",
+			arg:  []interface{}{"a"},
+			info: newTensorInfo("x", 8, []int64{1}),
+			err:  "input x has unsupported type unsupported(8)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := toTensor(tt.arg, tt.info)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
@@ -86,6 +86,8 @@ func NewPluginByType(t PluginType) Plugin {
 		return &FuncPlugin{}
 	case WASM:
 		return &FuncPlugin{}
+	case PORTABLE:
+		return &PortablePlugin{}
 	default:
 		return &IOPlugin{}
 	}
@@ -101,6 +103,28 @@ func (fp *FuncPlugin) GetSymbols() []string {
 	return fp.Functions
 }
 
+// PortablePlugin is the portable plugin model. The environment variables and the mounted files are passed to
+// the plugin process when it starts, so that the deployment configurations are not packaged in the plugin.
+type PortablePlugin struct {
+	IOPlugin
+	Environment map[string]string `json:"environment,omitempty"`
+	Mounts      []Mount           `json:"mounts,omitempty"`
+}
+
+func (p *PortablePlugin) GetInstallScripts() []byte {
+	marshal, err := json.Marshal(p)
+	if err != nil {
+		return nil
+	}
+	return marshal
+}
+
+// Mount copies the source file to the target path relative to the plugin directory
+type Mount struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
 type EXTENSION_TYPE int
 
 const (
@@ -42,9 +42,16 @@ func (m *Manager) SourcePluginInfo(name string) (plugin.EXTENSION_TYPE, string,
 	}
 }
 
-func (m *Manager) LookupSource(_ string) (api.LookupSource, error) {
-	// TODO add support
-	return nil, nil
+func (m *Manager) LookupSource(name string) (api.LookupSource, error) {
+	pname, ok := m.reg.GetLookupSymbol(name)
+	if !ok {
+		return nil, nil
+	}
+	pinfo, ok := m.reg.Get(pname)
+	if !ok {
+		return nil, nil
+	}
+	return runtime.NewPortableLookupSource(name, &pinfo.PluginMeta), nil
 }
 
 func (m *Manager) Sink(name string) (api.Sink, error) {
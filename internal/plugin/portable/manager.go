@@ -41,6 +41,9 @@ import (
 
 var manager *Manager
 
+// defaultVenvDir is the venv directory in the plugin directory if env is not set
+const defaultVenvDir = ".venv"
+
 type Manager struct {
 	pluginDir     string
 	pluginConfDir string
@@ -67,6 +70,7 @@ func InitManager() (*Manager, error) {
 		sources:   make(map[string]string),
 		sinks:     make(map[string]string),
 		functions: make(map[string]string),
+		lookups:   make(map[string]string),
 	}
 	// Read plugin info from file system
 	pluginDir = filepath.Join(pluginDir, "portable")
@@ -75,10 +79,6 @@ func InitManager() (*Manager, error) {
 		pluginConfDir: dataDir,
 		reg:           reg,
 	}
-	err = m.syncRegistry()
-	if err != nil {
-		return nil, err
-	}
 	plgDb, err := store.GetKV("portablePlugin")
 	if err != nil {
 		return nil, fmt.Errorf("error when opening portablePlugin: %v", err)
@@ -89,6 +89,11 @@ func InitManager() (*Manager, error) {
 	}
 	m.plgInstallDb = plgDb
 	m.plgStatusDb = plgStatusDb
+	// The install scripts are read to restore the environment and mounts
+	err = m.syncRegistry()
+	if err != nil {
+		return nil, err
+	}
 	manager = m
 	return m, nil
 }
@@ -104,6 +109,7 @@ func MockManager(plugins map[string]*PluginInfo) (*Manager, error) {
 		sources:   make(map[string]string),
 		sinks:     make(map[string]string),
 		functions: make(map[string]string),
+		lookups:   make(map[string]string),
 	}
 	for name, pi := range plugins {
 		err := pi.Validate(name)
@@ -138,20 +144,73 @@ func (m *Manager) parsePlugin(name string) error {
 	if err != nil {
 		return err
 	}
+	var installScript string
+	if found, _ := m.plgInstallDb.Get(name, &installScript); found {
+		p := plugin.NewPluginByType(plugin.PORTABLE)
+		if err := json.Unmarshal(cast.StringToBytes(installScript), p); err != nil {
+			conf.Log.Warnf("invalid install script of portable plugin %s: %v", name, err)
+		} else {
+			setRuntimeConf(pi, p)
+		}
+	}
 	return m.doRegister(name, pi, true)
 }
 
+// setRuntimeConf sets the environment variables and mounts defined when installing the plugin
+func setRuntimeConf(pi *PluginInfo, p plugin.Plugin) {
+	if pp, ok := p.(*plugin.PortablePlugin); ok {
+		pi.Environment = pp.Environment
+		pi.Mounts = pp.Mounts
+	}
+}
+
+func validateRuntimeConf(p plugin.Plugin) error {
+	pp, ok := p.(*plugin.PortablePlugin)
+	if !ok {
+		return nil
+	}
+	for k := range pp.Environment {
+		if k == "" || strings.ContainsAny(k, "= ") {
+			return fmt.Errorf("invalid environment variable name %q", k)
+		}
+	}
+	for _, mt := range pp.Mounts {
+		if mt.Source == "" {
+			return fmt.Errorf("mount source of %s is required", mt.Target)
+		}
+		if _, err := runtime.MountTarget("", mt.Target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (m *Manager) doRegister(name string, pi *PluginInfo, isInit bool) error {
 	exeAbs := filepath.Clean(filepath.Join(m.pluginDir, name, pi.Executable))
 	if _, err := os.Stat(exeAbs); err != nil {
 		return fmt.Errorf("cannot find executable `%s` when loading portable plugins: %v", exeAbs, err)
 	}
 	pi.Executable = exeAbs
+	if pi.VirtualType != nil && *pi.VirtualType == runtime.VenvType {
+		// The venv may be missing if the plugin is copied into the plugin directory manually
+		if _, err := os.Stat(filepath.Join(m.pluginDir, name, venvPath(pi))); os.IsNotExist(err) {
+			if err := createVenv(filepath.Join(m.pluginDir, name), venvPath(pi)); err != nil {
+				return err
+			}
+		}
+		venvDir := filepath.Join(m.pluginDir, name, venvPath(pi))
+		pi.Env = &venvDir
+	}
 	m.reg.Set(name, pi)
 
 	if !isInit {
 		for _, s := range pi.Sources {
-			if err := meta.ReadSourceMetaFile(path.Join(m.pluginConfDir, plugin.PluginTypes[plugin.SOURCE], s+`.json`), true, false); nil != err {
+			if err := meta.ReadSourceMetaFile(path.Join(m.pluginConfDir, plugin.PluginTypes[plugin.SOURCE], s+`.json`), true, pi.isLookup(s)); nil != err {
+				conf.Log.Errorf("read source json file:%v", err)
+			}
+		}
+		for _, s := range pi.lookupOnlySources() {
+			if err := meta.ReadSourceMetaFile(path.Join(m.pluginConfDir, plugin.PluginTypes[plugin.SOURCE], s+`.json`), false, true); nil != err {
 				conf.Log.Errorf("read source json file:%v", err)
 			}
 		}
@@ -192,7 +251,7 @@ func (m *Manager) removePluginInstallScript(name string) {
 }
 
 func (m *Manager) Register(p plugin.Plugin) error {
-	name, uri, shellParas := p.GetName(), p.GetFile(), p.GetShellParas()
+	name, uri := p.GetName(), p.GetFile()
 	name = strings.Trim(name, " ")
 	if name == "" {
 		return fmt.Errorf("invalid name %s: should not be empty", name)
@@ -204,6 +263,9 @@ func (m *Manager) Register(p plugin.Plugin) error {
 	if _, ok := m.reg.Get(name); ok {
 		return fmt.Errorf("invalid name %s: duplicate", name)
 	}
+	if err := validateRuntimeConf(p); err != nil {
+		return err
+	}
 
 	zipPath := path.Join(m.pluginDir, name+".zip")
 	// clean up: delete zip file and unzip files in error
@@ -214,7 +276,7 @@ func (m *Manager) Register(p plugin.Plugin) error {
 		return fmt.Errorf("fail to download file %s: %s", uri, err)
 	}
 	// unzip and copy to destination
-	err = m.install(name, zipPath, shellParas)
+	err = m.install(name, zipPath, p)
 	if err != nil { // Revert for any errors
 		return fmt.Errorf("fail to install plugin: %s", err)
 	}
@@ -222,13 +284,14 @@ func (m *Manager) Register(p plugin.Plugin) error {
 	return nil
 }
 
-func (m *Manager) install(name, src string, shellParas []string) (resultErr error) {
+func (m *Manager) install(name, src string, p plugin.Plugin) (resultErr error) {
 	var (
 		jsonName     = name + ".json"
 		pluginTarget = filepath.Join(m.pluginDir, name)
 		// The map of install files. Used to check if all required files are installed and for reverting
 		installedMap  = make(map[string]string)
 		requiredFiles = []string{jsonName}
+		venvDir       string
 	)
 	defer func() {
 		// remove all installed files if err happens
@@ -236,6 +299,9 @@ func (m *Manager) install(name, src string, shellParas []string) (resultErr erro
 			for _, p := range installedMap {
 				_ = os.Remove(p)
 			}
+			if venvDir != "" {
+				_ = os.RemoveAll(venvDir)
+			}
 			_ = os.Remove(pluginTarget)
 		}
 	}()
@@ -275,11 +341,15 @@ func (m *Manager) install(name, src string, shellParas []string) (resultErr erro
 	if _, ok := m.reg.Get(pi.Name); ok {
 		return fmt.Errorf("portable plugin %s already exists", pi.Name)
 	}
+	setRuntimeConf(pi, p)
 
 	requiredFiles = append(requiredFiles, pi.Executable)
 	for _, src := range pi.Sources {
 		requiredFiles = append(requiredFiles, fmt.Sprintf("sources/%s.yaml", src))
 	}
+	for _, src := range pi.lookupOnlySources() {
+		requiredFiles = append(requiredFiles, fmt.Sprintf("sources/%s.yaml", src))
+	}
 
 	// file copying
 	d := filepath.Clean(pluginTarget)
@@ -318,8 +388,16 @@ func (m *Manager) install(name, src string, shellParas []string) (resultErr erro
 		}
 	}
 
+	if pi.VirtualType != nil && *pi.VirtualType == runtime.VenvType {
+		venvDir = filepath.Join(pluginTarget, venvPath(pi))
+		if err := createVenv(pluginTarget, venvPath(pi)); err != nil {
+			return err
+		}
+	}
+
 	if needInstall {
 		// run install script if there is
+		shellParas := p.GetShellParas()
 		shell := make([]string, len(shellParas))
 		copy(shell, shellParas)
 		spath := path.Join(pluginTarget, "install.sh")
@@ -344,6 +422,44 @@ func (m *Manager) install(name, src string, shellParas []string) (resultErr erro
 	return m.doRegister(name, pi, false)
 }
 
+// venvPath returns the relative venv directory in the plugin directory
+func venvPath(pi *PluginInfo) string {
+	if pi.Env == nil || *pi.Env == "" {
+		return defaultVenvDir
+	}
+	return *pi.Env
+}
+
+// createVenv creates the python virtual environment for the plugin and installs the requirements.txt if there is.
+// Each plugin has its own venv so that the dependencies do not conflict.
+func createVenv(pluginTarget, venv string) error {
+	dir := filepath.Join(pluginTarget, venv)
+	conf.Log.Infof("create python venv %s", dir)
+	if err := runInstallCmd(pluginTarget, conf.Config.Portable.PythonBin, "-m", "venv", dir); err != nil {
+		return fmt.Errorf("fail to create venv: %v", err)
+	}
+	req := filepath.Join(pluginTarget, "requirements.txt")
+	if _, err := os.Stat(req); err == nil {
+		if err := runInstallCmd(pluginTarget, runtime.VenvPython(dir), "-m", "pip", "install", "-r", req); err != nil {
+			return fmt.Errorf("fail to install requirements: %v", err)
+		}
+	}
+	return nil
+}
+
+func runInstallCmd(dir string, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	var outb, errb bytes.Buffer
+	cmd.Stdout = &outb
+	cmd.Stderr = &errb
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf(`err:%v stdout:%s stderr:%s`, err, outb.String(), errb.String())
+	}
+	conf.Log.Infof(`%s output: %s`, name, outb.String())
+	return nil
+}
+
 func (m *Manager) List() []*PluginInfo {
 	return m.reg.List()
 }
@@ -376,7 +492,8 @@ func (m *Manager) Delete(name string) error {
 	// unregister the plugin
 	m.reg.Delete(name)
 	// delete files and uninstall metas
-	for _, s := range pinfo.Sources {
+	sources := append(append([]string{}, pinfo.Sources...), pinfo.lookupOnlySources()...)
+	for _, s := range sources {
 		p := path.Join(m.pluginConfDir, plugin.PluginTypes[plugin.SOURCE], s+".yaml")
 		os.Remove(p)
 		p = path.Join(m.pluginConfDir, plugin.PluginTypes[plugin.SOURCE], s+".json")
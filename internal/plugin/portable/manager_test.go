@@ -104,6 +104,44 @@ func TestManager_Install(t *testing.T) {
 	}
 }
 
+func TestManager_InstallRuntimeConf(t *testing.T) {
+	data := []struct {
+		p   *plugin.PortablePlugin
+		err error
+	}{
+		{
+			p: &plugin.PortablePlugin{
+				IOPlugin:    plugin.IOPlugin{Name: "envError", File: "file:///tmp/envError.zip"},
+				Environment: map[string]string{"A=B": "c"},
+			},
+			err: errors.New(`invalid environment variable name "A=B"`),
+		}, {
+			p: &plugin.PortablePlugin{
+				IOPlugin: plugin.IOPlugin{Name: "mountError", File: "file:///tmp/mountError.zip"},
+				Mounts:   []plugin.Mount{{Source: "/tmp/a.json", Target: "../a.json"}},
+			},
+			err: errors.New("mount target ../a.json must be inside the plugin directory"),
+		}, {
+			p: &plugin.PortablePlugin{
+				IOPlugin: plugin.IOPlugin{Name: "mountError", File: "file:///tmp/mountError.zip"},
+				Mounts:   []plugin.Mount{{Target: "a.json"}},
+			},
+			err: errors.New("mount source of a.json is required"),
+		},
+	}
+	for i, tt := range data {
+		err := manager.Register(tt.p)
+		if !reflect.DeepEqual(tt.err, err) {
+			t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, err)
+		}
+	}
+	pi := &PluginInfo{}
+	setRuntimeConf(pi, &plugin.PortablePlugin{Environment: map[string]string{"MODEL_PATH": "/models/a"}})
+	if !reflect.DeepEqual(map[string]string{"MODEL_PATH": "/models/a"}, pi.Environment) {
+		t.Errorf("environment mismatch, got %v", pi.Environment)
+	}
+}
+
 func TestManager_Read(t *testing.T) {
 	expPlugins := []*PluginInfo{
 		{
@@ -16,6 +16,8 @@ package portable
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
 
 	"github.com/lf-edge/ekuiper/internal/plugin/portable/runtime"
 )
@@ -25,6 +27,8 @@ type PluginInfo struct {
 	Sources   []string `json:"sources"`
 	Sinks     []string `json:"sinks"`
 	Functions []string `json:"functions"`
+	// LookupSources are the sources which can be used as lookup tables. They can have the same names as the Sources
+	LookupSources []string `json:"lookupSources,omitempty"`
 }
 
 var langMap = map[string]bool{
@@ -43,11 +47,58 @@ func (p *PluginInfo) Validate(expectedName string) error {
 	if p.Executable == "" {
 		return fmt.Errorf("invalid plugin, missing executable")
 	}
-	if len(p.Sources)+len(p.Sinks)+len(p.Functions) == 0 {
+	if len(p.Sources)+len(p.Sinks)+len(p.Functions)+len(p.LookupSources) == 0 {
 		return fmt.Errorf("invalid plugin, must define at lease one source, sink or function")
 	}
 	if l, ok := langMap[p.Language]; !ok || !l {
 		return fmt.Errorf("invalid plugin, language '%s' is not supported", p.Language)
 	}
+	if p.VirtualType != nil {
+		if p.Language != "python" {
+			return fmt.Errorf("invalid plugin, virtualEnvType is only supported by python")
+		}
+		switch *p.VirtualType {
+		case "conda":
+			if p.Env == nil || *p.Env == "" {
+				return fmt.Errorf("invalid plugin, env is required for conda")
+			}
+		case runtime.VenvType:
+			if p.Env != nil && *p.Env != "" {
+				e := filepath.Clean(*p.Env)
+				if filepath.IsAbs(e) || e == ".." || strings.HasPrefix(e, ".."+string(filepath.Separator)) {
+					return fmt.Errorf("invalid plugin, env %s of venv must be a relative path inside the plugin directory", *p.Env)
+				}
+			}
+		default:
+			return fmt.Errorf("invalid plugin, virtualEnvType '%s' is not supported", *p.VirtualType)
+		}
+	}
 	return nil
 }
+
+func (p *PluginInfo) isLookup(source string) bool {
+	for _, s := range p.LookupSources {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupOnlySources returns the lookup sources which are not scan sources
+func (p *PluginInfo) lookupOnlySources() []string {
+	var result []string
+	for _, s := range p.LookupSources {
+		found := false
+		for _, ss := range p.Sources {
+			if s == ss {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -96,6 +96,67 @@ func TestValidate(t *testing.T) {
 				Functions: []string{"aa"},
 			},
 			err: "invalid plugin, language 'c' is not supported",
+		}, {
+			p: &PluginInfo{
+				PluginMeta: runtime.PluginMeta{
+					Name:        "mirror",
+					Version:     "1.0.0",
+					Language:    "python",
+					Executable:  "mirror.py",
+					VirtualType: strPtr("venv"),
+				},
+				Functions: []string{"aa"},
+			},
+			err: "",
+		}, {
+			p: &PluginInfo{
+				PluginMeta: runtime.PluginMeta{
+					Name:        "mirror",
+					Version:     "1.0.0",
+					Language:    "python",
+					Executable:  "mirror.py",
+					VirtualType: strPtr("venv"),
+					Env:         strPtr("../other"),
+				},
+				Functions: []string{"aa"},
+			},
+			err: "invalid plugin, env ../other of venv must be a relative path inside the plugin directory",
+		}, {
+			p: &PluginInfo{
+				PluginMeta: runtime.PluginMeta{
+					Name:        "mirror",
+					Version:     "1.0.0",
+					Language:    "python",
+					Executable:  "mirror.py",
+					VirtualType: strPtr("conda"),
+				},
+				Functions: []string{"aa"},
+			},
+			err: "invalid plugin, env is required for conda",
+		}, {
+			p: &PluginInfo{
+				PluginMeta: runtime.PluginMeta{
+					Name:        "mirror",
+					Version:     "1.0.0",
+					Language:    "go",
+					Executable:  "mirror.exe",
+					VirtualType: strPtr("venv"),
+				},
+				Functions: []string{"aa"},
+			},
+			err: "invalid plugin, virtualEnvType is only supported by python",
+		}, {
+			p: &PluginInfo{
+				PluginMeta: runtime.PluginMeta{
+					Name:        "mirror",
+					Version:     "1.0.0",
+					Language:    "python",
+					Executable:  "mirror.py",
+					VirtualType: strPtr("pipenv"),
+				},
+				Functions: []string{"aa"},
+			},
+			err: "invalid plugin, virtualEnvType 'pipenv' is not supported",
 		},
 	}
 	fmt.Printf("The test bucket size is %d.\n\n", len(tests))
@@ -106,3 +167,7 @@ func TestValidate(t *testing.T) {
 		}
 	}
 }
+
+func strPtr(s string) *string {
+	return &s
+}
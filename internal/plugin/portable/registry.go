@@ -27,6 +27,7 @@ type registry struct {
 	sources   map[string]string
 	sinks     map[string]string
 	functions map[string]string
+	lookups   map[string]string
 }
 
 // Set prerequisite: the pluginInfo must have been validated that the names are valid
@@ -43,6 +44,9 @@ func (r *registry) Set(name string, pi *PluginInfo) {
 	for _, s := range pi.Functions {
 		r.functions[s] = name
 	}
+	for _, s := range pi.LookupSources {
+		r.lookups[s] = name
+	}
 }
 
 func (r *registry) Get(name string) (*PluginInfo, bool) {
@@ -68,6 +72,14 @@ func (r *registry) GetSymbol(pt plugin.PluginType, symbolName string) (string, b
 	}
 }
 
+// GetLookupSymbol returns the plugin name of the lookup source
+func (r *registry) GetLookupSymbol(symbolName string) (string, bool) {
+	r.RLock()
+	defer r.RUnlock()
+	s, ok := r.lookups[symbolName]
+	return s, ok
+}
+
 func (r *registry) List() []*PluginInfo {
 	r.RLock()
 	defer r.RUnlock()
@@ -96,4 +108,7 @@ func (r *registry) Delete(name string) {
 	for _, s := range pi.Functions {
 		delete(r.functions, s)
 	}
+	for _, s := range pi.LookupSources {
+		delete(r.lookups, s)
+	}
 }
@@ -30,6 +30,7 @@ func TestConcurrent(t *testing.T) {
 		sources:   make(map[string]string),
 		sinks:     make(map[string]string),
 		functions: make(map[string]string),
+		lookups:   make(map[string]string),
 	}
 	allPlugins := []*PluginInfo{
 		{
@@ -39,9 +40,10 @@ func TestConcurrent(t *testing.T) {
 				Language:   "go",
 				Executable: "mirror",
 			},
-			Sources:   []string{"random"},
-			Sinks:     []string{"file"},
-			Functions: []string{"echo"},
+			Sources:       []string{"random"},
+			Sinks:         []string{"file"},
+			Functions:     []string{"echo"},
+			LookupSources: []string{"random", "cache"},
 		}, {
 			PluginMeta: runtime.PluginMeta{
 				Name:       "next",
@@ -126,6 +128,20 @@ outer:
 	if pn != "dummy" {
 		t.Errorf("GetSymbol wrong, expect dummy but got %s", pn)
 	}
+	expectedLookups := map[string]string{
+		"cache": "mirror", "random": "mirror",
+	}
+	if !reflect.DeepEqual(expectedLookups, r.lookups) {
+		t.Errorf("lookups mismatch:\n\nexp=%#v\n\ngot=%#v\n\n", expectedLookups, r.lookups)
+		return
+	}
+	pn, ok = r.GetLookupSymbol("cache")
+	if !ok || pn != "mirror" {
+		t.Errorf("GetLookupSymbol wrong, expect mirror but got %s", pn)
+	}
+	if got := allPlugins[0].lookupOnlySources(); !reflect.DeepEqual([]string{"cache"}, got) {
+		t.Errorf("lookupOnlySources wrong, expect [cache] but got %v", got)
+	}
 
 	// Delete concurrently
 	for n := range expectedPlugins {
@@ -141,4 +157,7 @@ outer:
 		t.Errorf("list plugins count mismatch: expected no plugins, got %v", result)
 		return
 	}
+	if len(r.lookups) != 0 {
+		t.Errorf("lookups should be deleted, got %v", r.lookups)
+	}
 }
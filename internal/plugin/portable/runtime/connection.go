@@ -15,6 +15,7 @@
 package runtime
 
 import (
+	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
@@ -48,6 +49,8 @@ type Closable interface {
 type ControlChannel interface {
 	Handshake() error
 	SendCmd(arg []byte) error
+	// Ping checks the liveness of the plugin in the timeout
+	Ping(timeout time.Duration) error
 	Closable
 }
 
@@ -86,6 +89,39 @@ func (r *NanomsgReqChannel) SendCmd(arg []byte) error {
 	return nil
 }
 
+// Ping sends the ping command and waits for the reply. Any reply means alive because the old plugins reply an error
+// for the unknown command.
+func (r *NanomsgReqChannel) Ping(timeout time.Duration) error {
+	r.Lock()
+	defer r.Unlock()
+	t, err := r.sock.GetOption(mangos.OptionRecvDeadline)
+	if err != nil {
+		return err
+	}
+	if err := r.sock.SetOption(mangos.OptionRecvDeadline, timeout); err != nil {
+		return err
+	}
+	defer func() {
+		_ = r.sock.SetOption(mangos.OptionRecvDeadline, t)
+	}()
+	arg, _ := json.Marshal(Command{Cmd: CMD_PING, Arg: "{}"})
+	if err := r.sock.Send(arg); err != nil {
+		if err != mangos.ErrProtoState {
+			return fmt.Errorf("can't send ping: %v", err)
+		}
+		if _, err = r.sock.Recv(); err != nil {
+			return fmt.Errorf("can't send ping: %v", err)
+		}
+		if err = r.sock.Send(arg); err != nil {
+			return fmt.Errorf("can't send ping: %v", err)
+		}
+	}
+	if _, err := r.sock.Recv(); err != nil {
+		return fmt.Errorf("no reply of ping: %v", err)
+	}
+	return nil
+}
+
 // Handshake should only be called once
 func (r *NanomsgReqChannel) Handshake() error {
 	t, err := r.sock.GetOption(mangos.OptionRecvDeadline)
@@ -206,6 +242,27 @@ func CreateFunctionChannel(symbolName string) (DataReqChannel, error) {
 	return &NanomsgReqRepChannel{sock: sock}, nil
 }
 
+func CreateLookupChannel(ctx api.StreamContext) (DataReqChannel, error) {
+	var (
+		sock mangos.Socket
+		err  error
+	)
+	if sock, err = rep.NewSocket(); err != nil {
+		return nil, fmt.Errorf("can't get new rep socket: %s", err)
+	}
+	setSockOptions(sock, map[string]interface{}{
+		mangos.OptionRecvDeadline: 5000 * time.Millisecond,
+		mangos.OptionSendDeadline: 1000 * time.Millisecond,
+		mangos.OptionRetryTime:    0,
+	})
+	url := fmt.Sprintf("ipc:///tmp/lookup_%s_%s_%d.ipc", ctx.GetRuleId(), ctx.GetOpId(), ctx.GetInstanceId())
+	if err = listenWithRetry(sock, url); err != nil {
+		return nil, fmt.Errorf("can't listen on rep socket for %s: %s", url, err.Error())
+	}
+	conf.Log.Infof("lookup channel created: %s", url)
+	return &NanomsgReqRepChannel{sock: sock}, nil
+}
+
 func CreateSinkChannel(ctx api.StreamContext) (DataOutChannel, error) {
 	var (
 		sock mangos.Socket
@@ -30,6 +30,10 @@ import (
 // Thus, it is possible to hot reload, which is simply attach a new nng client to the same channel
 // without changing the server(plugin runtime) side
 // TODO think about ending a portable func when needed.
+// portableStatesKey is the key of all the states of a portable function instance in the function context.
+// The states are sent to the plugin for each execution and saved back if changed so that they are checkpointed along with the rule.
+const portableStatesKey = "portable_states"
+
 type PortableFunc struct {
 	symbolName string
 	reg        *PluginMeta // initial plugin meta, only used for initialize the function instance
@@ -101,7 +105,11 @@ func (f *PortableFunc) Validate(args []interface{}) error {
 
 func (f *PortableFunc) Exec(args []interface{}, ctx api.FunctionContext) (interface{}, bool) {
 	ctx.GetLogger().Debugf("running portable func with args %+v", args)
-	ctxRaw, err := encodeCtx(ctx)
+	states, err := ctx.GetState(portableStatesKey)
+	if err != nil {
+		return err, false
+	}
+	ctxRaw, err := encodeCtx(ctx, states)
 	if err != nil {
 		return err, false
 	}
@@ -118,6 +126,11 @@ func (f *PortableFunc) Exec(args []interface{}, ctx api.FunctionContext) (interf
 	if err != nil {
 		return fmt.Errorf("Failed to unmarshal function result %s", string(res)), false
 	}
+	if fr.States != nil {
+		if err := ctx.PutState(portableStatesKey, fr.States); err != nil {
+			return err, false
+		}
+	}
 	if !fr.State {
 		if fr.Result != nil {
 			return fmt.Errorf("%s", fr.Result), false
@@ -181,7 +194,7 @@ func encode(funcName string, arg interface{}) ([]byte, error) {
 	return json.Marshal(c)
 }
 
-func encodeCtx(ctx api.FunctionContext) (string, error) {
+func encodeCtx(ctx api.FunctionContext, states interface{}) (string, error) {
 	m := FuncMeta{
 		Meta: Meta{
 			RuleId:     ctx.GetRuleId(),
@@ -190,6 +203,9 @@ func encodeCtx(ctx api.FunctionContext) (string, error) {
 		},
 		FuncId: ctx.GetFuncId(),
 	}
+	if s, ok := states.(map[string]interface{}); ok {
+		m.States = s
+	}
 	bs, err := json.Marshal(m)
 	return string(bs), err
 }
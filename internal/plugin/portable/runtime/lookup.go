@@ -0,0 +1,141 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+// PortableLookupSource sends the lookup request to the plugin by a req/rep channel for each rule instance
+type PortableLookupSource struct {
+	symbolName string
+	reg        *PluginMeta
+	dataCh     DataReqChannel
+	clean      func() error
+
+	topic string
+	props map[string]interface{}
+}
+
+func NewPortableLookupSource(symbolName string, reg *PluginMeta) *PortableLookupSource {
+	return &PortableLookupSource{
+		symbolName: symbolName,
+		reg:        reg,
+	}
+}
+
+func (ps *PortableLookupSource) Configure(datasource string, props map[string]interface{}) error {
+	ps.topic = datasource
+	ps.props = props
+	return nil
+}
+
+func (ps *PortableLookupSource) Open(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Start running portable lookup source %s with datasource %s and conf %+v", ps.symbolName, ps.topic, ps.props)
+	pm := GetPluginInsManager()
+	ins, err := pm.getOrStartProcess(ps.reg, PortbleConf)
+	if err != nil {
+		return err
+	}
+	ctx.GetLogger().Infof("Plugin started successfully")
+
+	// listen before starting the symbol which will dial the channel
+	dataCh, err := CreateLookupChannel(ctx)
+	if err != nil {
+		return err
+	}
+	c := &Control{
+		Meta: Meta{
+			RuleId:     ctx.GetRuleId(),
+			OpId:       ctx.GetOpId(),
+			InstanceId: ctx.GetInstanceId(),
+		},
+		SymbolName: ps.symbolName,
+		PluginType: TYPE_LOOKUP,
+		DataSource: ps.topic,
+		Config:     ps.props,
+	}
+	err = ins.StartSymbol(ctx, c)
+	if err != nil {
+		_ = dataCh.Close()
+		return err
+	}
+	ps.dataCh = dataCh
+	ps.clean = func() error {
+		ctx.GetLogger().Info("clean up lookup source")
+		err1 := dataCh.Close()
+		err2 := ins.StopSymbol(ctx, c)
+		if err1 != nil {
+			err1 = fmt.Errorf("%s:%v", "dataCh", err1)
+		}
+		if err2 != nil {
+			err2 = fmt.Errorf("%s:%v", "symbol", err2)
+		}
+		return errors.Join(err1, err2)
+	}
+	return nil
+}
+
+func (ps *PortableLookupSource) Lookup(ctx api.StreamContext, fields []string, keys []string, values []interface{}) ([]api.SourceTuple, error) {
+	ctx.GetLogger().Debugf("lookup portable source %s with keys %v and values %v", ps.symbolName, keys, values)
+	arg, err := json.Marshal(&LookupData{
+		Fields: fields,
+		Keys:   keys,
+		Values: values,
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, err := ps.dataCh.Req(arg)
+	if err != nil {
+		return nil, err
+	}
+	lr := &LookupReply{}
+	err = json.Unmarshal(res, lr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal lookup result %s", string(res))
+	}
+	if !lr.State {
+		var msg string
+		_ = json.Unmarshal(lr.Result, &msg)
+		return nil, fmt.Errorf("lookup error: %s", msg)
+	}
+	var tuples []*api.DefaultSourceTuple
+	if err := json.Unmarshal(lr.Result, &tuples); err != nil {
+		return nil, fmt.Errorf("invalid lookup result %s: %v", string(lr.Result), err)
+	}
+	now := conf.GetNow()
+	result := make([]api.SourceTuple, 0, len(tuples))
+	for _, t := range tuples {
+		if t != nil {
+			t.Time = now
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+func (ps *PortableLookupSource) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("Closing lookup source %s", ps.symbolName)
+	if ps.clean != nil {
+		return ps.clean()
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.nanomsg.org/mangos/v3"
+	"go.nanomsg.org/mangos/v3/protocol/req"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/topo/state"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+// runMockReplier mocks the plugin side of a req/rep channel which replies each request by the reply function
+func runMockReplier(t *testing.T, url string, reply func([]byte) []byte) mangos.Socket {
+	sock, err := req.NewSocket()
+	require.NoError(t, err)
+	require.NoError(t, sock.Dial(url))
+	go func() {
+		if err := sock.Send([]byte("handshake")); err != nil {
+			return
+		}
+		for {
+			msg, err := sock.Recv()
+			if err != nil {
+				return
+			}
+			if err := sock.Send(reply(msg)); err != nil {
+				return
+			}
+		}
+	}()
+	return sock
+}
+
+func TestPortableLookup(t *testing.T) {
+	ctx := context.WithValue(context.Background(), context.LoggerKey, conf.Log).WithMeta("rule1", "op1", &state.MemoryStore{}).WithInstance(2)
+	ch, err := CreateLookupChannel(ctx)
+	require.NoError(t, err)
+	defer ch.Close()
+	client := runMockReplier(t, fmt.Sprintf("ipc:///tmp/lookup_%s_%s_%d.ipc", "rule1", "op1", 2), func(msg []byte) []byte {
+		d := &LookupData{}
+		if err := json.Unmarshal(msg, d); err != nil {
+			return []byte(err.Error())
+		}
+		if len(d.Values) == 0 || d.Values[0] == nil {
+			r, _ := json.Marshal(FuncReply{State: false, Result: "missing value"})
+			return r
+		}
+		r, _ := json.Marshal(FuncReply{State: true, Result: []map[string]interface{}{
+			{"message": map[string]interface{}{d.Keys[0]: d.Values[0], "name": "a"}, "meta": map[string]interface{}{"table": "t1"}},
+		}})
+		return r
+	})
+	defer client.Close()
+
+	ls := NewPortableLookupSource("test", &PluginMeta{Name: "test"})
+	require.NoError(t, ls.Configure("t1", map[string]interface{}{}))
+	ls.dataCh = ch
+	result, err := ls.Lookup(ctx, []string{"id", "name"}, []string{"id"}, []interface{}{1})
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, map[string]interface{}{"id": float64(1), "name": "a"}, result[0].Message())
+	assert.Equal(t, map[string]interface{}{"table": "t1"}, result[0].Meta())
+
+	_, err = ls.Lookup(ctx, []string{"id"}, []string{"id"}, []interface{}{nil})
+	assert.EqualError(t, err, "lookup error: missing value")
+}
+
+func TestPortableFuncStates(t *testing.T) {
+	ch, err := CreateFunctionChannel("stateful")
+	require.NoError(t, err)
+	defer ch.Close()
+	// The mock plugin function counts the calls in the state
+	client := runMockReplier(t, "ipc:///tmp/func_stateful.ipc", func(msg []byte) []byte {
+		d := &FuncData{}
+		if err := json.Unmarshal(msg, d); err != nil {
+			return []byte(err.Error())
+		}
+		args := d.Arg.([]interface{})
+		m := &FuncMeta{}
+		if err := json.Unmarshal([]byte(args[len(args)-1].(string)), m); err != nil {
+			return []byte(err.Error())
+		}
+		var c float64
+		if m.States != nil {
+			c, _ = m.States["count"].(float64)
+		}
+		c++
+		r, _ := json.Marshal(FuncReply{State: true, Result: c, States: map[string]interface{}{"count": c}})
+		return r
+	})
+	defer client.Close()
+
+	f := &PortableFunc{symbolName: "stateful", dataCh: ch}
+	ctx := context.WithValue(context.Background(), context.LoggerKey, conf.Log).WithMeta("rule1", "op1", &state.MemoryStore{})
+	fctx := context.NewDefaultFuncContext(ctx, 1)
+	for i := 1; i <= 3; i++ {
+		r, ok := f.Exec([]interface{}{"a"}, fctx)
+		require.True(t, ok)
+		assert.Equal(t, float64(i), r)
+	}
+	s, err := fctx.GetState(portableStatesKey)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"count": float64(3)}, s)
+	// Another function instance has its own states
+	fctx2 := context.NewDefaultFuncContext(ctx, 2)
+	r, ok := f.Exec([]interface{}{"a"}, fctx2)
+	require.True(t, ok)
+	assert.Equal(t, float64(1), r)
+}
+
+var _ api.LookupSource = &PortableLookupSource{}
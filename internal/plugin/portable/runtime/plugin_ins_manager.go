@@ -20,10 +20,15 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	goruntime "runtime"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/plugin"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/infra"
 )
@@ -33,6 +38,15 @@ var (
 	pm   *pluginInsManager
 )
 
+// reloadTimeout is the max time to wait for the old process to exit when reloading the plugin
+var reloadTimeout = 5 * time.Second
+
+// the backoff delay range to restart the crashed plugin process
+var (
+	restartBaseDelay = time.Second
+	restartMaxDelay  = time.Minute
+)
+
 // TODO setting configuration
 var PortbleConf = &PortableConfig{
 	SendTimeout: 1000,
@@ -49,6 +63,50 @@ type PluginIns struct {
 	// audit the commands, so that when restarting the plugin, we can replay the commands
 	commands map[Meta][]byte
 	process  *os.Process // created when used by rule and deleted when no rule uses it
+	// exited is closed when the current process exits
+	exited chan struct{}
+	// meta is the plugin meta of the current process to restart it
+	meta *PluginMeta
+	// stopping is set when the process is stopped intentionally so that it is not restarted
+	stopping atomic.Bool
+	// supervision states
+	restarts  int
+	crashes   int // consecutive crashes, reset when the process replies the heartbeat
+	lastCrash int64
+	lastError string
+	failed    bool // the process is not restarted anymore after too many crashes
+}
+
+// PluginStatus is the supervision status of the plugin process
+type PluginStatus struct {
+	Name    string `json:"name"`
+	Running bool   `json:"running"`
+	Pid     int    `json:"pid,omitempty"`
+	// Rules are the rules using the plugin
+	Rules              []string `json:"rules"`
+	Restarts           int      `json:"restarts"`
+	ConsecutiveCrashes int      `json:"consecutiveCrashes"`
+	LastCrash          int64    `json:"lastCrash,omitempty"`
+	LastError          string   `json:"lastError,omitempty"`
+	// Failed is true if the process is not restarted after too many consecutive crashes
+	Failed bool `json:"failed"`
+}
+
+// FailHandler fails the rules using the plugin after too many consecutive crashes
+type FailHandler func(pluginName string, ruleIds []string, err error)
+
+// ruleIds returns the sorted rules using the plugin. Must be called with lock.
+func (i *PluginIns) ruleIds() []string {
+	m := make(map[string]struct{})
+	for k := range i.commands {
+		m[k.RuleId] = struct{}{}
+	}
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
 }
 
 func NewPluginIns(name string, ctrlChan ControlChannel, process *os.Process) *PluginIns {
@@ -149,6 +207,7 @@ func (i *PluginIns) StopSymbol(ctx api.StreamContext, ctrl *Control) error {
 // Stop intentionally
 func (i *PluginIns) Stop() error {
 	var err error
+	i.stopping.Store(true)
 	i.RLock()
 	defer i.RUnlock()
 	if i.process != nil { // will also trigger process exit clean up
@@ -159,7 +218,8 @@ func (i *PluginIns) Stop() error {
 
 // Manager plugin process and control socket
 type pluginInsManager struct {
-	instances map[string]*PluginIns
+	instances   map[string]*PluginIns
+	failHandler FailHandler
 	sync.RWMutex
 }
 
@@ -193,14 +253,160 @@ func (p *pluginInsManager) AddPluginIns(name string, ins *PluginIns) {
 	p.instances[name] = ins
 }
 
-// CreateIns Run when plugin is created/updated
+// CreateIns Run when plugin is created/updated. If the plugin is used by rules, the process is restarted with the
+// new executable and the symbols are restored so that the rules keep running without restart.
 func (p *pluginInsManager) CreateIns(pluginMeta *PluginMeta) {
 	p.Lock()
 	defer p.Unlock()
 	if ins, ok := p.instances[pluginMeta.Name]; ok {
 		if len(ins.commands) != 0 {
-			go p.getOrStartProcess(pluginMeta, PortbleConf)
+			go p.reload(ins, pluginMeta)
+		}
+	}
+}
+
+// reload waits for the old process to exit before starting the new one. Otherwise, the old process may be reused.
+func (p *pluginInsManager) reload(ins *PluginIns, pluginMeta *PluginMeta) {
+	ins.RLock()
+	process, exited := ins.process, ins.exited
+	ins.RUnlock()
+	if process != nil {
+		_ = ins.Stop()
+		select {
+		case <-exited:
+		case <-time.After(reloadTimeout):
+			conf.Log.Errorf("reload plugin %s error: the old process %d does not exit", pluginMeta.Name, process.Pid)
+			return
+		}
+	}
+	// The new version starts over
+	ins.Lock()
+	ins.crashes = 0
+	ins.failed = false
+	ins.Unlock()
+	if _, err := p.getOrStartProcess(pluginMeta, PortbleConf); err != nil {
+		conf.Log.Errorf("reload plugin %s error: %v", pluginMeta.Name, err)
+		return
+	}
+	conf.Log.Infof("reloaded plugin %s", pluginMeta.Name)
+}
+
+// SetFailHandler sets the handler to fail the rules when the plugin is not restarted anymore
+func (p *pluginInsManager) SetFailHandler(h FailHandler) {
+	p.Lock()
+	defer p.Unlock()
+	p.failHandler = h
+}
+
+// Status returns the supervision status of the plugin process
+func (p *pluginInsManager) Status(name string) *PluginStatus {
+	ins, ok := p.getPluginIns(name)
+	if !ok {
+		return &PluginStatus{Name: name, Rules: []string{}}
+	}
+	ins.RLock()
+	defer ins.RUnlock()
+	st := &PluginStatus{
+		Name:               name,
+		Running:            ins.process != nil,
+		Rules:              ins.ruleIds(),
+		Restarts:           ins.restarts,
+		ConsecutiveCrashes: ins.crashes,
+		LastCrash:          ins.lastCrash,
+		LastError:          ins.lastError,
+		Failed:             ins.failed,
+	}
+	if ins.process != nil {
+		st.Pid = ins.process.Pid
+	}
+	return st
+}
+
+// handleCrash restarts the process with the backoff delay or fails the rules after too many consecutive crashes
+func (p *pluginInsManager) handleCrash(ins *PluginIns, reason string) {
+	ins.Lock()
+	ins.crashes++
+	ins.lastCrash = conf.GetNowInMilli()
+	ins.lastError = reason
+	crashes := ins.crashes
+	if limit := conf.Config.Portable.MaxRestarts; limit >= 0 && crashes > limit {
+		ins.failed = true
+	}
+	failed := ins.failed
+	rules := ins.ruleIds()
+	ins.Unlock()
+	if failed {
+		err := fmt.Errorf("plugin %s crashed %d times consecutively: %s", ins.name, crashes, reason)
+		conf.Log.Error(err)
+		p.RLock()
+		h := p.failHandler
+		p.RUnlock()
+		if h != nil {
+			h(ins.name, rules, err)
+		}
+		return
+	}
+	d := restartDelay(crashes)
+	conf.Log.Warnf("plugin %s crashed: %s, restart in %v", ins.name, reason, d)
+	time.AfterFunc(d, func() {
+		p.restart(ins)
+	})
+}
+
+// restartDelay returns the exponential backoff delay of the consecutive crashes
+func restartDelay(crashes int) time.Duration {
+	d := restartBaseDelay
+	for i := 1; i < crashes && d < restartMaxDelay; i++ {
+		d *= 2
+	}
+	if d > restartMaxDelay {
+		d = restartMaxDelay
+	}
+	return d
+}
+
+// restart starts the process again if it is still required by rules
+func (p *pluginInsManager) restart(ins *PluginIns) {
+	cur, ok := p.getPluginIns(ins.name)
+	ins.RLock()
+	meta := ins.meta
+	skip := !ok || cur != ins || ins.process != nil || len(ins.commands) == 0 || ins.stopping.Load() || ins.failed || meta == nil
+	ins.RUnlock()
+	if skip {
+		return
+	}
+	if _, err := p.getOrStartProcess(meta, PortbleConf); err != nil {
+		p.handleCrash(ins, err.Error())
+		return
+	}
+	ins.Lock()
+	ins.restarts++
+	ins.Unlock()
+	conf.Log.Infof("restarted plugin %s", ins.name)
+}
+
+// heartbeat pings the process periodically, and kills it if it does not reply so that it is restarted
+func (p *pluginInsManager) heartbeat(ins *PluginIns, process *os.Process, exited <-chan struct{}) {
+	interval := time.Duration(conf.Config.Portable.HeartbeatInterval) * time.Millisecond
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-exited:
+			return
+		case <-ticker.C:
 		}
+		if err := ins.ctrlChan.Ping(interval); err != nil {
+			conf.Log.Errorf("plugin %s heartbeat error: %v, kill the process %d", ins.name, err, process.Pid)
+			_ = process.Kill()
+			return
+		}
+		ins.Lock()
+		ins.crashes = 0
+		ins.Unlock()
 	}
 }
 
@@ -255,6 +461,8 @@ func (p *pluginInsManager) getOrStartProcess(pluginMeta *PluginMeta, pconf *Port
 				switch *pluginMeta.VirtualType {
 				case "conda":
 					cmd = exec.Command("conda", "run", "-n", *pluginMeta.Env, conf.Config.Portable.PythonBin, pluginMeta.Executable, string(jsonArg))
+				case VenvType:
+					cmd = exec.Command(VenvPython(*pluginMeta.Env), pluginMeta.Executable, string(jsonArg))
 				default:
 					return fmt.Errorf("unsupported virtual type: %s", *pluginMeta.VirtualType)
 				}
@@ -274,6 +482,11 @@ func (p *pluginInsManager) getOrStartProcess(pluginMeta *PluginMeta, pconf *Port
 	cmd.Stdout = conf.Log.Out
 	cmd.Stderr = conf.Log.Out
 	cmd.Dir = filepath.Dir(pluginMeta.Executable)
+	cmd.Env = pluginEnv(pluginMeta)
+	err = mountFiles(pluginMeta)
+	if err != nil {
+		return nil, fmt.Errorf("fail to mount files for plugin %s: %v", pluginMeta.Name, err)
+	}
 
 	conf.Log.Println("plugin starting")
 	err = cmd.Start()
@@ -281,6 +494,7 @@ func (p *pluginInsManager) getOrStartProcess(pluginMeta *PluginMeta, pconf *Port
 		return nil, fmt.Errorf("plugin executable %s stops with error %v", pluginMeta.Executable, err)
 	}
 	process := cmd.Process
+	exited := make(chan struct{})
 	conf.Log.Printf("plugin started pid: %d\n", process.Pid)
 	defer func() {
 		if e != nil {
@@ -288,6 +502,7 @@ func (p *pluginInsManager) getOrStartProcess(pluginMeta *PluginMeta, pconf *Port
 		}
 	}()
 	go infra.SafeRun(func() error { // just print out error inside
+		defer close(exited)
 		err = cmd.Wait()
 		if err != nil {
 			conf.Log.Printf("plugin executable %s stops with error %v", pluginMeta.Executable, err)
@@ -296,6 +511,7 @@ func (p *pluginInsManager) getOrStartProcess(pluginMeta *PluginMeta, pconf *Port
 		// clean up for stop unintentionally
 		if ins, ok := p.getPluginIns(pluginMeta.Name); ok && ins.process == cmd.Process {
 			ins.Lock()
+			crashed := len(ins.commands) > 0 && !ins.stopping.Load()
 			if len(ins.commands) == 0 {
 				if ins.ctrlChan != nil {
 					_ = ins.ctrlChan.Close()
@@ -304,6 +520,14 @@ func (p *pluginInsManager) getOrStartProcess(pluginMeta *PluginMeta, pconf *Port
 			}
 			ins.process = nil
 			ins.Unlock()
+			// restart the process used by rules
+			if crashed {
+				reason := "process exited unexpectedly"
+				if err != nil {
+					reason = fmt.Sprintf("process exited: %v", err)
+				}
+				p.handleCrash(ins, reason)
+			}
 		}
 		return nil
 	})
@@ -312,8 +536,15 @@ func (p *pluginInsManager) getOrStartProcess(pluginMeta *PluginMeta, pconf *Port
 	if err != nil {
 		return nil, fmt.Errorf("plugin %s control handshake error: %v", pluginMeta.Executable, err)
 	}
+	ins.Lock()
 	ins.process = process
+	ins.exited = exited
+	ins.meta = pluginMeta
+	ins.failed = false
+	ins.Unlock()
+	ins.stopping.Store(false)
 	p.instances[pluginMeta.Name] = ins
+	go p.heartbeat(ins, process, exited)
 	conf.Log.Println("plugin start running")
 	// restore symbols by sending commands when restarting plugin
 	conf.Log.Info("restore plugin symbols")
@@ -351,11 +582,94 @@ func (p *pluginInsManager) KillAll() error {
 	return nil
 }
 
+// Unhealthy returns the names of the plugins which are used by rules but whose process is not running
+func (p *pluginInsManager) Unhealthy() []string {
+	p.RLock()
+	defer p.RUnlock()
+	var result []string
+	for name, ins := range p.instances {
+		ins.RLock()
+		if len(ins.commands) > 0 && ins.process == nil {
+			result = append(result, name)
+		}
+		ins.RUnlock()
+	}
+	sort.Strings(result)
+	return result
+}
+
 type PluginMeta struct {
 	Name        string  `json:"name"`
 	Version     string  `json:"version"`
 	Language    string  `json:"language"`
 	Executable  string  `json:"executable"`
 	VirtualType *string `json:"virtualEnvType,omitempty"`
-	Env         *string `json:"env,omitempty"`
+	// Env is the conda environment name or the venv directory. The venv directory is relative to the plugin directory
+	// in the package and is resolved to the absolute path when installed.
+	Env *string `json:"env,omitempty"`
+	// Environment and Mounts are set when installing the plugin instead of in the plugin package
+	Environment map[string]string `json:"environment,omitempty"`
+	Mounts      []plugin.Mount    `json:"mounts,omitempty"`
+}
+
+// VenvType is the virtual environment created by python venv for each plugin to isolate the dependencies
+const VenvType = "venv"
+
+// VenvPython returns the python executable inside the venv directory
+func VenvPython(dir string) string {
+	if goruntime.GOOS == "windows" {
+		return filepath.Join(dir, "Scripts", "python.exe")
+	}
+	return filepath.Join(dir, "bin", "python")
+}
+
+// pluginEnv returns the environment variables of the plugin process. It inherits the eKuiper process environment.
+func pluginEnv(pluginMeta *PluginMeta) []string {
+	if len(pluginMeta.Environment) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(pluginMeta.Environment))
+	for k := range pluginMeta.Environment {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	env := os.Environ()
+	for _, k := range keys {
+		env = append(env, k+"="+pluginMeta.Environment[k])
+	}
+	return env
+}
+
+// mountFiles copies the mounted files into the plugin directory. They are copied at each start to pick up the changes.
+func mountFiles(pluginMeta *PluginMeta) error {
+	dir := filepath.Dir(pluginMeta.Executable)
+	for _, m := range pluginMeta.Mounts {
+		target, err := MountTarget(dir, m.Target)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(m.Source)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, content, 0o600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MountTarget returns the absolute path of the mount target which must be inside the plugin directory
+func MountTarget(dir, target string) (string, error) {
+	if target == "" || filepath.IsAbs(target) {
+		return "", fmt.Errorf("mount target %s must be a relative path", target)
+	}
+	t := filepath.Clean(target)
+	if t == ".." || strings.HasPrefix(t, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("mount target %s must be inside the plugin directory", target)
+	}
+	return filepath.Join(dir, t), nil
 }
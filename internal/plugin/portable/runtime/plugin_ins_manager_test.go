@@ -16,13 +16,20 @@ package runtime
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.nanomsg.org/mangos/v3"
 	"go.nanomsg.org/mangos/v3/protocol/req"
 
 	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/plugin"
 	"github.com/lf-edge/ekuiper/internal/topo/context"
 	"github.com/lf-edge/ekuiper/internal/topo/state"
 )
@@ -184,3 +191,94 @@ func createMockClient(pluginName string) (mangos.Socket, error) {
 	}
 	return sock, nil
 }
+
+func TestPluginEnvAndMounts(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "creds.json")
+	require.NoError(t, os.WriteFile(src, []byte(`{"token":"abc"}`), 0o600))
+	pluginDir := filepath.Join(dir, "plugin")
+	meta := &PluginMeta{
+		Name:        "test",
+		Executable:  filepath.Join(pluginDir, "test.py"),
+		Environment: map[string]string{"MODEL_PATH": "/models/a", "API_TOKEN": "abc"},
+		Mounts:      []plugin.Mount{{Source: src, Target: "conf/creds.json"}},
+	}
+	env := pluginEnv(meta)
+	assert.Equal(t, []string{"API_TOKEN=abc", "MODEL_PATH=/models/a"}, env[len(env)-2:])
+	assert.Nil(t, pluginEnv(&PluginMeta{}))
+
+	require.NoError(t, mountFiles(meta))
+	content, err := os.ReadFile(filepath.Join(pluginDir, "conf", "creds.json"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"token":"abc"}`, string(content))
+
+	_, err = MountTarget(pluginDir, "../creds.json")
+	assert.EqualError(t, err, "mount target ../creds.json must be inside the plugin directory")
+	_, err = MountTarget(pluginDir, "/etc/creds.json")
+	assert.EqualError(t, err, "mount target /etc/creds.json must be a relative path")
+	meta.Mounts = []plugin.Mount{{Source: filepath.Join(dir, "none"), Target: "none"}}
+	assert.Error(t, mountFiles(meta))
+}
+
+func TestUnhealthyPlugins(t *testing.T) {
+	m := &pluginInsManager{instances: make(map[string]*PluginIns)}
+	m.AddPluginIns("idle", NewPluginIns("idle", nil, nil))
+	running := NewPluginIns("running", nil, &os.Process{Pid: os.Getpid()})
+	running.commands[Meta{RuleId: "rule1", OpId: "op1"}] = []byte{}
+	m.AddPluginIns("running", running)
+	m.AddPluginIns("crashed", NewPluginInsForTest("crashed", nil))
+	assert.Equal(t, []string{"crashed"}, m.Unhealthy())
+}
+
+func TestVenvPython(t *testing.T) {
+	dir := filepath.Join("plugins", "portable", "pysam", ".venv")
+	if goruntime.GOOS == "windows" {
+		assert.Equal(t, filepath.Join(dir, "Scripts", "python.exe"), VenvPython(dir))
+	} else {
+		assert.Equal(t, filepath.Join(dir, "bin", "python"), VenvPython(dir))
+	}
+}
+
+func TestRestartDelay(t *testing.T) {
+	assert.Equal(t, time.Second, restartDelay(1))
+	assert.Equal(t, 2*time.Second, restartDelay(2))
+	assert.Equal(t, 8*time.Second, restartDelay(4))
+	assert.Equal(t, time.Minute, restartDelay(7))
+	assert.Equal(t, time.Minute, restartDelay(100))
+}
+
+func TestPluginCrashFail(t *testing.T) {
+	old := conf.Config.Portable.MaxRestarts
+	conf.Config.Portable.MaxRestarts = 1
+	defer func() {
+		conf.Config.Portable.MaxRestarts = old
+	}()
+	m := &pluginInsManager{instances: make(map[string]*PluginIns)}
+	ins := NewPluginIns("crashy", nil, nil)
+	ins.commands[Meta{RuleId: "rule2", OpId: "op1"}] = []byte{}
+	ins.commands[Meta{RuleId: "rule1", OpId: "op1"}] = []byte{}
+	ins.commands[Meta{RuleId: "rule1", OpId: "op2"}] = []byte{}
+	// the previous crash is waiting for restart
+	ins.crashes = 1
+	m.AddPluginIns("crashy", ins)
+	var (
+		failedRules []string
+		failedErr   error
+	)
+	m.SetFailHandler(func(pluginName string, ruleIds []string, err error) {
+		assert.Equal(t, "crashy", pluginName)
+		failedRules = ruleIds
+		failedErr = err
+	})
+	m.handleCrash(ins, "process exited: exit status 1")
+	assert.Equal(t, []string{"rule1", "rule2"}, failedRules)
+	assert.EqualError(t, failedErr, "plugin crashy crashed 2 times consecutively: process exited: exit status 1")
+
+	st := m.Status("crashy")
+	assert.False(t, st.Running)
+	assert.True(t, st.Failed)
+	assert.Equal(t, 2, st.ConsecutiveCrashes)
+	assert.Equal(t, "process exited: exit status 1", st.LastError)
+	assert.Equal(t, []string{"rule1", "rule2"}, st.Rules)
+	assert.Equal(t, &PluginStatus{Name: "none", Rules: []string{}}, m.Status("none"))
+}
@@ -14,10 +14,13 @@
 
 package runtime
 
+import "encoding/json"
+
 const (
 	TYPE_SOURCE = "source"
 	TYPE_SINK   = "sink"
 	TYPE_FUNC   = "func"
+	TYPE_LOOKUP = "lookup"
 )
 
 type Meta struct {
@@ -28,7 +31,8 @@ type Meta struct {
 
 type FuncMeta struct {
 	Meta
-	FuncId int `json:"funcId"`
+	FuncId int                    `json:"funcId"`
+	States map[string]interface{} `json:"states,omitempty"`
 }
 
 type Control struct {
@@ -47,6 +51,8 @@ type Command struct {
 const (
 	CMD_START = "start"
 	CMD_STOP  = "stop"
+	// CMD_PING checks the liveness of the plugin process
+	CMD_PING = "ping"
 )
 
 const (
@@ -65,4 +71,17 @@ type FuncData struct {
 type FuncReply struct {
 	State  bool        `json:"state"`
 	Result interface{} `json:"result"`
+	// States is only set by the stateful functions when the states are changed
+	States map[string]interface{} `json:"states,omitempty"`
+}
+
+type LookupData struct {
+	Fields []string      `json:"fields"`
+	Keys   []string      `json:"keys"`
+	Values []interface{} `json:"values"`
+}
+
+type LookupReply struct {
+	State  bool            `json:"state"`
+	Result json.RawMessage `json:"result"`
 }
@@ -26,7 +26,7 @@ func (m *Manager) Function(name string) (api.Function, error) {
 	if !ok {
 		return nil, nil
 	}
-	f, err := runtime.NewWasmFunc(name, meta)
+	f, err := runtime.NewFunc(name, meta)
 	if err != nil {
 		conf.Log.Errorf("Error creating function %v", err)
 		return nil, err
@@ -345,6 +345,7 @@ func (m *Manager) Delete(name string) error {
 	}
 	// unregister the plugin
 	m.reg.Delete(name)
+	runtime.ReleaseWazero(pinfo.WasmFile)
 	// delete files and uninstall metas
 	for _, s := range pinfo.Functions {
 		p := path.Join(m.etcDir, plugin.PluginTypes[plugin.FUNCTION], s+".json")
@@ -35,5 +35,23 @@ func (p *PluginInfo) Validate(expectedName string) error {
 	if p.WasmEngine == "" {
 		return fmt.Errorf("invalid WasmEngine")
 	}
+	if p.WasmEngine != runtime.EngineWasmEdge && p.WasmEngine != runtime.EngineWazero {
+		return fmt.Errorf("invalid WasmEngine %s, must be %s or %s", p.WasmEngine, runtime.EngineWasmEdge, runtime.EngineWazero)
+	}
+	if p.MemoryLimit < 0 || p.Timeout < 0 {
+		return fmt.Errorf("invalid plugin, memoryLimit and timeout must not be negative")
+	}
+	for _, a := range p.Aggregates {
+		found := false
+		for _, f := range p.Functions {
+			if a == f {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("invalid plugin, aggregate %s is not in the functions", a)
+		}
+	}
 	return nil
 }
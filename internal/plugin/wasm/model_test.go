@@ -67,6 +67,51 @@ func TestValidate(t *testing.T) {
 				Functions: []string{"fib"},
 			},
 			err: "invalid plugin, expect name 'fibonacci' but got 'wrong'",
+		}, { // 4
+			p: &PluginInfo{
+				PluginMeta: runtime.PluginMeta{
+					Name:        "fibonacci",
+					Version:     "1.0.0",
+					WasmEngine:  "wazero",
+					Aggregates:  []string{"fibSum"},
+					MemoryLimit: 16,
+					Timeout:     100,
+				},
+				Functions: []string{"fib", "fibSum"},
+			},
+			err: "",
+		}, { // 5
+			p: &PluginInfo{
+				PluginMeta: runtime.PluginMeta{
+					Name:       "fibonacci",
+					Version:    "1.0.0",
+					WasmEngine: "wasmer",
+				},
+				Functions: []string{"fib"},
+			},
+			err: "invalid WasmEngine wasmer, must be wasmedge or wazero",
+		}, { // 6
+			p: &PluginInfo{
+				PluginMeta: runtime.PluginMeta{
+					Name:       "fibonacci",
+					Version:    "1.0.0",
+					WasmEngine: "wazero",
+					Aggregates: []string{"fibSum"},
+				},
+				Functions: []string{"fib"},
+			},
+			err: "invalid plugin, aggregate fibSum is not in the functions",
+		}, { // 7
+			p: &PluginInfo{
+				PluginMeta: runtime.PluginMeta{
+					Name:        "fibonacci",
+					Version:     "1.0.0",
+					WasmEngine:  "wazero",
+					MemoryLimit: -1,
+				},
+				Functions: []string{"fib"},
+			},
+			err: "invalid plugin, memoryLimit and timeout must not be negative",
 		},
 	}
 	fmt.Printf("The test bucket size is %d.\n\n", len(tests))
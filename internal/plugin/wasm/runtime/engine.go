@@ -0,0 +1,41 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+const (
+	EngineWasmEdge = "wasmedge"
+	EngineWazero   = "wazero"
+)
+
+// FuncCreator creates a function instance of the symbol in the wasm plugin
+type FuncCreator func(symbolName string, reg *PluginMeta) (api.Function, error)
+
+// engines are the wasm engines built in. The engine registers itself by build tags.
+var engines = map[string]FuncCreator{}
+
+// NewFunc creates the function by the engine of the plugin
+func NewFunc(symbolName string, reg *PluginMeta) (api.Function, error) {
+	c, ok := engines[reg.WasmEngine]
+	if !ok {
+		return nil, fmt.Errorf("wasm engine %s is not supported in this build", reg.WasmEngine)
+	}
+	return c(symbolName, reg)
+}
@@ -13,6 +13,8 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+//go:build wasmedge
+
 package runtime
 
 import (
@@ -25,6 +27,12 @@ import (
 	"github.com/lf-edge/ekuiper/pkg/api"
 )
 
+func init() {
+	engines[EngineWasmEdge] = func(symbolName string, reg *PluginMeta) (api.Function, error) {
+		return NewWasmFunc(symbolName, reg)
+	}
+}
+
 type WasmFunc struct {
 	symbolName string
 	reg        *PluginMeta
@@ -49,4 +49,10 @@ type PluginMeta struct {
 	Version    string `json:"version"`
 	WasmFile   string `json:"wasmFile"`
 	WasmEngine string `json:"wasmEngine"`
+	// Aggregates are the functions which are aggregate functions
+	Aggregates []string `json:"aggregates,omitempty"`
+	// MemoryLimit is the max memory in MiB of each function instance. Only supported by wazero.
+	MemoryLimit int `json:"memoryLimit,omitempty"`
+	// Timeout is the max time in milliseconds of each function call. Only supported by wazero.
+	Timeout int `json:"timeout,omitempty"`
 }
@@ -0,0 +1,233 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	wapi "github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+const (
+	defaultWazeroMemoryLimit = 64 // MiB
+	defaultWazeroTimeout     = 1000
+	// wasmPageSize is the size of a wasm memory page
+	wasmPageSize = 64 * 1024
+)
+
+func init() {
+	engines[EngineWazero] = func(symbolName string, reg *PluginMeta) (api.Function, error) {
+		return NewWazeroFunc(symbolName, reg)
+	}
+}
+
+// wazeroModule is the compiled module of a wasm file shared by all the function instances
+type wazeroModule struct {
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+}
+
+var (
+	wazeroModules = make(map[string]*wazeroModule)
+	wazeroMu      sync.Mutex
+)
+
+// getWazeroModule compiles the wasm file once with the memory limit of the plugin
+func getWazeroModule(reg *PluginMeta) (*wazeroModule, error) {
+	wazeroMu.Lock()
+	defer wazeroMu.Unlock()
+	if m, ok := wazeroModules[reg.WasmFile]; ok {
+		return m, nil
+	}
+	bin, err := os.ReadFile(reg.WasmFile)
+	if err != nil {
+		return nil, fmt.Errorf("read wasm file %s error: %v", reg.WasmFile, err)
+	}
+	limit := reg.MemoryLimit
+	if limit <= 0 {
+		limit = defaultWazeroMemoryLimit
+	}
+	ctx := context.Background()
+	rc := wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(uint32(limit * 1024 * 1024 / wasmPageSize)).
+		WithCloseOnContextDone(true)
+	r := wazero.NewRuntimeWithConfig(ctx, rc)
+	// Only the wasi functions are imported, the module cannot access the host otherwise
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, r); err != nil {
+		_ = r.Close(ctx)
+		return nil, err
+	}
+	compiled, err := r.CompileModule(ctx, bin)
+	if err != nil {
+		_ = r.Close(ctx)
+		return nil, fmt.Errorf("compile wasm file %s error: %v", reg.WasmFile, err)
+	}
+	m := &wazeroModule{runtime: r, compiled: compiled}
+	wazeroModules[reg.WasmFile] = m
+	return m, nil
+}
+
+// ReleaseWazero closes the compiled module of the wasm file, the running instances will fail
+func ReleaseWazero(wasmFile string) {
+	wazeroMu.Lock()
+	defer wazeroMu.Unlock()
+	if m, ok := wazeroModules[wasmFile]; ok {
+		_ = m.runtime.Close(context.Background())
+		delete(wazeroModules, wasmFile)
+	}
+}
+
+// WazeroFunc runs the function in a sandboxed wasm instance. The module must export `alloc(size i32) i32` to
+// allocate the memory for the input, and the function `symbol(ptr i32, len i32) i64` receives the json array
+// of the arguments and returns the pointer and length of the json result packed as ptr<<32|len.
+// An optional `dealloc(ptr i32, size i32)` is called to free the input and the result.
+type WazeroFunc struct {
+	symbolName string
+	reg        *PluginMeta
+	isAgg      bool
+	timeout    time.Duration
+	module     *wazeroModule
+	// instance is created lazily and recreated after a failure such as the timeout
+	instance wapi.Module
+}
+
+func NewWazeroFunc(symbolName string, reg *PluginMeta) (*WazeroFunc, error) {
+	m, err := getWazeroModule(reg)
+	if err != nil {
+		return nil, err
+	}
+	timeout := reg.Timeout
+	if timeout <= 0 {
+		timeout = defaultWazeroTimeout
+	}
+	f := &WazeroFunc{
+		symbolName: symbolName,
+		reg:        reg,
+		timeout:    time.Duration(timeout) * time.Millisecond,
+		module:     m,
+	}
+	for _, a := range reg.Aggregates {
+		if a == symbolName {
+			f.isAgg = true
+			break
+		}
+	}
+	return f, nil
+}
+
+func (f *WazeroFunc) Validate(_ []interface{}) error {
+	return nil
+}
+
+func (f *WazeroFunc) Exec(args []interface{}, _ api.FunctionContext) (interface{}, bool) {
+	r, err := f.call(args)
+	if err != nil {
+		// The instance may be closed or corrupted, recreate it in the next call
+		f.closeInstance()
+		return fmt.Errorf("run wasm function %s error: %v", f.symbolName, err), false
+	}
+	return r, true
+}
+
+func (f *WazeroFunc) IsAggregate() bool {
+	return f.isAgg
+}
+
+func (f *WazeroFunc) Close() error {
+	f.closeInstance()
+	return nil
+}
+
+func (f *WazeroFunc) call(args []interface{}) (interface{}, error) {
+	input, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("encode the arguments error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), f.timeout)
+	defer cancel()
+	if f.instance == nil {
+		f.instance, err = f.module.runtime.InstantiateModule(ctx, f.module.compiled,
+			wazero.NewModuleConfig().WithName("").WithStartFunctions("_initialize"))
+		if err != nil {
+			return nil, fmt.Errorf("instantiate error: %v", err)
+		}
+	}
+	fn := f.instance.ExportedFunction(f.symbolName)
+	if fn == nil {
+		return nil, fmt.Errorf("function is not exported")
+	}
+	alloc := f.instance.ExportedFunction("alloc")
+	if alloc == nil {
+		return nil, fmt.Errorf("alloc is not exported")
+	}
+	dealloc := f.instance.ExportedFunction("dealloc")
+	mem := f.instance.Memory()
+	size := uint64(len(input))
+	rets, err := alloc.Call(ctx, size)
+	if err != nil {
+		return nil, f.wrapErr(ctx, err)
+	}
+	ptr := rets[0]
+	if !mem.Write(uint32(ptr), input) {
+		return nil, fmt.Errorf("write the arguments out of memory range")
+	}
+	rets, err = fn.Call(ctx, ptr, size)
+	if err != nil {
+		return nil, f.wrapErr(ctx, err)
+	}
+	if dealloc != nil {
+		if _, err := dealloc.Call(ctx, ptr, size); err != nil {
+			return nil, f.wrapErr(ctx, err)
+		}
+	}
+	rptr, rlen := uint32(rets[0]>>32), uint32(rets[0])
+	output, ok := mem.Read(rptr, rlen)
+	if !ok {
+		return nil, fmt.Errorf("read the result out of memory range")
+	}
+	var result interface{}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, fmt.Errorf("decode the result %s error: %v", string(output), err)
+	}
+	if dealloc != nil {
+		if _, err := dealloc.Call(ctx, uint64(rptr), uint64(rlen)); err != nil {
+			return nil, f.wrapErr(ctx, err)
+		}
+	}
+	return result, nil
+}
+
+func (f *WazeroFunc) wrapErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("timeout after %v", f.timeout)
+	}
+	return err
+}
+
+func (f *WazeroFunc) closeInstance() {
+	if f.instance != nil {
+		_ = f.instance.Close(context.Background())
+		f.instance = nil
+	}
+}
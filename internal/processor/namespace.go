@@ -0,0 +1,139 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/internal/pkg/namespace"
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/pkg/errorx"
+	"github.com/lf-edge/ekuiper/pkg/kv"
+)
+
+// NamespaceProcessor manages the namespaces and the owners of the plugins. The definitions of the
+// streams and rules in a namespace are managed by the stream and rule processors.
+type NamespaceProcessor struct {
+	db kv.KeyValue
+	// pluginDb saves the owner namespace of the plugins created in a namespace, keyed by type/name
+	pluginDb kv.KeyValue
+}
+
+func NewNamespaceProcessor() *NamespaceProcessor {
+	db, err := store.GetKV("namespace")
+	if err != nil {
+		panic(fmt.Sprintf("Can not initialize store for the namespace processor at path 'namespace': %v", err))
+	}
+	pluginDb, err := store.GetKV("namespacePlugin")
+	if err != nil {
+		panic(fmt.Sprintf("Can not initialize store for the namespace processor at path 'namespacePlugin': %v", err))
+	}
+	return &NamespaceProcessor{
+		db:       db,
+		pluginDb: pluginDb,
+	}
+}
+
+func (p *NamespaceProcessor) Create(ns string) error {
+	if ns == namespace.Default {
+		return fmt.Errorf("namespace %s already exists", ns)
+	}
+	if err := namespace.Validate(ns); err != nil {
+		return err
+	}
+	if err := p.db.Setnx(ns, ns); err != nil {
+		return fmt.Errorf("namespace %s already exists", ns)
+	}
+	log.Infof("Namespace %s is created.", ns)
+	return nil
+}
+
+// Exists returns true for the default namespace or the created namespaces
+func (p *NamespaceProcessor) Exists(ns string) bool {
+	if ns == "" || ns == namespace.Default {
+		return true
+	}
+	var v string
+	ok, _ := p.db.Get(ns, &v)
+	return ok
+}
+
+// List returns the created namespaces without the default namespace
+func (p *NamespaceProcessor) List() ([]string, error) {
+	keys, err := p.db.Keys()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Drop deletes the namespace and its definition tables. The rules and streams must be dropped before.
+func (p *NamespaceProcessor) Drop(ns string) error {
+	if ns == namespace.Default {
+		return fmt.Errorf("the default namespace cannot be deleted")
+	}
+	if !p.Exists(ns) {
+		return errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("Namespace %s is not found.", ns))
+	}
+	for _, table := range []string{"stream", "rule", "ruleVersion", "pipeline"} {
+		if err := store.DropKV(namespace.Table(table, ns)); err != nil {
+			return err
+		}
+	}
+	if err := p.db.Delete(ns); err != nil {
+		return err
+	}
+	log.Infof("Namespace %s is dropped.", ns)
+	return nil
+}
+
+// SetPluginOwner records the plugin is created in the namespace
+func (p *NamespaceProcessor) SetPluginOwner(ptype, name, ns string) error {
+	return p.pluginDb.Set(ptype+"/"+name, ns)
+}
+
+// GetPluginOwner returns the namespace which creates the plugin. The plugins without owner are
+// created in the default namespace.
+func (p *NamespaceProcessor) GetPluginOwner(ptype, name string) string {
+	var ns string
+	if ok, _ := p.pluginDb.Get(ptype+"/"+name, &ns); ok {
+		return ns
+	}
+	return namespace.Default
+}
+
+func (p *NamespaceProcessor) RemovePluginOwner(ptype, name string) error {
+	return p.pluginDb.Delete(ptype + "/" + name)
+}
+
+// GetPlugins returns the names of the plugins of the type created in the namespace
+func (p *NamespaceProcessor) GetPlugins(ns, ptype string) ([]string, error) {
+	all, err := p.pluginDb.All()
+	if err != nil {
+		return nil, err
+	}
+	prefix := ptype + "/"
+	result := make([]string, 0)
+	for k, v := range all {
+		if v == ns && strings.HasPrefix(k, prefix) {
+			result = append(result, strings.TrimPrefix(k, prefix))
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+func TestNamespace(t *testing.T) {
+	np := NewNamespaceProcessor()
+	require.NoError(t, np.Create("team1"))
+	defer np.Drop("team1")
+	assert.Error(t, np.Create("team1"))
+	assert.Error(t, np.Create("default"))
+	assert.Error(t, np.Create("team/2"))
+	assert.True(t, np.Exists("team1"))
+	assert.True(t, np.Exists("default"))
+	assert.False(t, np.Exists("team2"))
+	nss, err := np.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"team1"}, nss)
+
+	// The same names in different namespaces are isolated
+	sp := NewStreamProcessor()
+	defer sp.db.Clean()
+	nsp, err := sp.In("team1")
+	require.NoError(t, err)
+	_, err = sp.ExecStmt(`CREATE STREAM demo () WITH (DATASOURCE="users", FORMAT="JSON")`)
+	require.NoError(t, err)
+	_, err = nsp.ExecStmt(`CREATE STREAM demo () WITH (DATASOURCE="team1", FORMAT="JSON")`)
+	require.NoError(t, err)
+	_, err = nsp.ExecStmt(`CREATE STREAM demo2 () WITH (DATASOURCE="team1", FORMAT="JSON")`)
+	require.NoError(t, err)
+	streams, err := nsp.ShowStream(ast.TypeStream)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"demo", "demo2"}, streams)
+	streams, err = sp.ShowStream(ast.TypeStream)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"demo"}, streams)
+
+	rp := NewRuleProcessor()
+	defer rp.db.Clean()
+	ruleJson := `{"id": "rule1","sql": "SELECT * FROM demo","actions": [{"log": {}}]}`
+	_, err = rp.ExecCreateWithValidation("rule1", ruleJson)
+	require.NoError(t, err)
+	r, err := rp.ExecCreateWithValidation("team1/rule1", ruleJson)
+	require.NoError(t, err)
+	assert.Equal(t, "team1/rule1", r.Id)
+	r, err = rp.GetRuleById("team1/rule1")
+	require.NoError(t, err)
+	assert.Equal(t, "team1/rule1", r.Id)
+	// The id in the json is local
+	_, err = rp.GetRuleByJson("team1/rule2", ruleJson)
+	assert.EqualError(t, err, "RuleId is not consistent with rule id.")
+	r, err = rp.GetRuleByJson("team1/", `{"id": "rule2","sql": "SELECT * FROM demo","actions": [{"log": {}}]}`)
+	require.NoError(t, err)
+	assert.Equal(t, "team1/rule2", r.Id)
+
+	r, err = rp.ExecReplaceRuleState("team1/rule1", false)
+	require.NoError(t, err)
+	assert.Equal(t, "team1/rule1", r.Id)
+	j, err := rp.GetRuleJson("team1/rule1")
+	require.NoError(t, err)
+	assert.Contains(t, j, `"id":"rule1"`)
+
+	ids, err := rp.GetRulesIn("team1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"team1/rule1"}, ids)
+	ids, err = rp.GetAllRules()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"rule1"}, ids)
+
+	_, err = rp.ExecDrop("team1/rule1")
+	require.NoError(t, err)
+	assert.False(t, rp.ExecExists("team1/rule1"))
+	assert.True(t, rp.ExecExists("rule1"))
+
+	// Plugin owners
+	require.NoError(t, np.SetPluginOwner("sinks", "mysink", "team1"))
+	defer np.RemovePluginOwner("sinks", "mysink")
+	assert.Equal(t, "team1", np.GetPluginOwner("sinks", "mysink"))
+	assert.Equal(t, "default", np.GetPluginOwner("sinks", "other"))
+	plugins, err := np.GetPlugins("team1", "sinks")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mysink"}, plugins)
+	plugins, err = np.GetPlugins("team1", "sources")
+	require.NoError(t, err)
+	assert.Empty(t, plugins)
+
+	require.NoError(t, np.Drop("team1"))
+	assert.False(t, np.Exists("team1"))
+	assert.Error(t, np.Drop("team1"))
+}
@@ -0,0 +1,349 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/internal/pkg/namespace"
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"github.com/lf-edge/ekuiper/pkg/errorx"
+	"github.com/lf-edge/ekuiper/pkg/kv"
+	"github.com/lf-edge/ekuiper/pkg/validate"
+)
+
+// Pipeline is a group of rules wired by the memory topics. The rules are deployed, started and stopped as a unit.
+type Pipeline struct {
+	Id string `json:"id"`
+	// Topics are the memory topics connecting the rules. Each topic must be published and subscribed by the rules.
+	Topics []string `json:"topics"`
+	// Rules are the rule definitions
+	Rules []json.RawMessage `json:"rules"`
+	// Triggered starts the pipeline after creation
+	Triggered bool `json:"triggered"`
+}
+
+// PipelineRule is a rule of the pipeline and the pipeline topics it publishes and subscribes
+type PipelineRule struct {
+	Rule       *api.Rule
+	Json       string
+	Publishes  []string
+	Subscribes []string
+}
+
+type PipelineProcessor struct {
+	db kv.KeyValue
+	r  *RuleProcessor
+	s  *StreamProcessor
+}
+
+func NewPipelineProcessor(r *RuleProcessor, s *StreamProcessor) *PipelineProcessor {
+	db, err := store.GetKV("pipeline")
+	if err != nil {
+		panic(fmt.Sprintf("Can not initialize store for the pipeline processor at path 'pipeline': %v", err))
+	}
+	return &PipelineProcessor{
+		db: db,
+		r:  r,
+		s:  s,
+	}
+}
+
+// dbOf returns the table and the key of a pipeline. The id of a pipeline in a namespace is qualified by the namespace.
+func (p *PipelineProcessor) dbOf(id string) (kv.KeyValue, string, error) {
+	ns, name := namespace.Split(id)
+	if ns == namespace.Default {
+		return p.db, name, nil
+	}
+	db, err := store.GetKV(namespace.Table("pipeline", ns))
+	if err != nil {
+		return nil, "", err
+	}
+	return db, name, nil
+}
+
+// GetPipelineByJson parses and validates the pipeline. The returned rules are sorted from the upstream to the
+// downstream. The id is qualified by the namespace of the pipeline and its rules.
+func (p *PipelineProcessor) GetPipelineByJson(id, pipelineJson string) (*Pipeline, []*PipelineRule, error) {
+	pl := &Pipeline{Triggered: true}
+	if err := json.Unmarshal(cast.StringToBytes(pipelineJson), pl); err != nil {
+		return nil, nil, fmt.Errorf("Parse pipeline %s error : %s.", pipelineJson, err)
+	}
+	ns, id := namespace.Split(id)
+	if pl.Id == "" {
+		pl.Id = id
+	}
+	if id != "" && id != pl.Id {
+		return nil, nil, fmt.Errorf("PipelineId is not consistent with pipeline id.")
+	}
+	if err := validate.ValidateID(pl.Id); err != nil {
+		return nil, nil, err
+	}
+	if len(pl.Rules) == 0 {
+		return nil, nil, fmt.Errorf("pipeline %s has no rules", pl.Id)
+	}
+	sp, err := p.s.In(ns)
+	if err != nil {
+		return nil, nil, err
+	}
+	topics := make(map[string]bool, len(pl.Topics))
+	for _, t := range pl.Topics {
+		if t == "" || strings.ContainsAny(t, "#+") {
+			return nil, nil, fmt.Errorf("invalid pipeline topic %q, it must be a memory topic without wildcard", t)
+		}
+		if topics[t] {
+			return nil, nil, fmt.Errorf("duplicate pipeline topic %s", t)
+		}
+		topics[t] = true
+	}
+	rules := make([]*PipelineRule, 0, len(pl.Rules))
+	ids := make(map[string]bool, len(pl.Rules))
+	for _, raw := range pl.Rules {
+		rule, err := p.r.GetRuleByJson(namespace.Qualify(ns, ""), string(raw))
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid rule in pipeline %s: %v", pl.Id, err)
+		}
+		if ids[rule.Id] {
+			return nil, nil, fmt.Errorf("duplicate rule %s in pipeline %s", rule.Id, pl.Id)
+		}
+		ids[rule.Id] = true
+		if rule.Sql == "" {
+			return nil, nil, fmt.Errorf("rule %s in pipeline %s must be defined by sql", rule.Id, pl.Id)
+		}
+		pr := &PipelineRule{Rule: rule, Json: string(raw)}
+		pr.Subscribes, err = subscribedTopics(sp.db, rule, topics)
+		if err != nil {
+			return nil, nil, err
+		}
+		pr.Publishes = publishedTopics(rule, topics)
+		rules = append(rules, pr)
+	}
+	for _, t := range pl.Topics {
+		var pub, sub bool
+		for _, pr := range rules {
+			pub = pub || contains(pr.Publishes, t)
+			sub = sub || contains(pr.Subscribes, t)
+		}
+		if !pub {
+			return nil, nil, fmt.Errorf("pipeline topic %s is not published by any rule", t)
+		}
+		if !sub {
+			return nil, nil, fmt.Errorf("pipeline topic %s is not subscribed by any rule", t)
+		}
+	}
+	sorted, err := sortPipelineRules(rules)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid pipeline %s: %v", pl.Id, err)
+	}
+	pl.Id = namespace.Qualify(ns, pl.Id)
+	return pl, sorted, nil
+}
+
+// subscribedTopics returns the pipeline topics read by the memory streams and tables of the rule
+func subscribedTopics(db kv.KeyValue, rule *api.Rule, topics map[string]bool) ([]string, error) {
+	stmt, err := xsql.GetStatementFromSql(rule.Sql)
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for _, name := range xsql.GetStreams(stmt) {
+		s, err := xsql.GetDataSource(db, name)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %v", rule.Id, err)
+		}
+		if s.Options == nil || !strings.EqualFold(s.Options.TYPE, "memory") {
+			continue
+		}
+		if topics[s.Options.DATASOURCE] && !contains(result, s.Options.DATASOURCE) {
+			result = append(result, s.Options.DATASOURCE)
+		}
+	}
+	return result, nil
+}
+
+// publishedTopics returns the pipeline topics written by the memory sinks of the rule
+func publishedTopics(rule *api.Rule, topics map[string]bool) []string {
+	var result []string
+	for _, action := range rule.Actions {
+		props, ok := action["memory"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		t, _ := props["topic"].(string)
+		if topics[t] && !contains(result, t) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// sortPipelineRules sorts the rules topologically by the topics. The order of the declaration is kept if possible.
+func sortPipelineRules(rules []*PipelineRule) ([]*PipelineRule, error) {
+	indegree := make([]int, len(rules))
+	downstreams := make([][]int, len(rules))
+	for i, from := range rules {
+		for j, to := range rules {
+			for _, t := range from.Publishes {
+				if contains(to.Subscribes, t) {
+					if i == j {
+						return nil, fmt.Errorf("rule %s subscribes its own topic %s", from.Rule.Id, t)
+					}
+					downstreams[i] = append(downstreams[i], j)
+					indegree[j]++
+					break
+				}
+			}
+		}
+	}
+	result := make([]*PipelineRule, 0, len(rules))
+	visited := make([]bool, len(rules))
+	for len(result) < len(rules) {
+		next := -1
+		for i := range rules {
+			if !visited[i] && indegree[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			var cycle []string
+			for i, pr := range rules {
+				if !visited[i] {
+					cycle = append(cycle, pr.Rule.Id)
+				}
+			}
+			return nil, fmt.Errorf("the rules %s are wired in a cycle", strings.Join(cycle, ", "))
+		}
+		visited[next] = true
+		result = append(result, rules[next])
+		for _, j := range downstreams[next] {
+			indegree[j]--
+		}
+	}
+	return result, nil
+}
+
+func contains(s []string, t string) bool {
+	for _, v := range s {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+// pipelineRecord is the stored pipeline with the local ids of its rules sorted from the upstream to the downstream
+type pipelineRecord struct {
+	Definition string   `json:"definition"`
+	Rules      []string `json:"rules"`
+}
+
+func (p *PipelineProcessor) ExecCreate(id, pipelineJson string, rules []*PipelineRule) error {
+	db, key, err := p.dbOf(id)
+	if err != nil {
+		return err
+	}
+	rec := &pipelineRecord{Definition: pipelineJson, Rules: make([]string, len(rules))}
+	for i, pr := range rules {
+		_, rec.Rules[i] = namespace.Split(pr.Rule.Id)
+	}
+	bs, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if err := db.Setnx(key, string(bs)); err != nil {
+		return fmt.Errorf("pipeline %s already exists", id)
+	}
+	log.Infof("Pipeline %s is created.", id)
+	return nil
+}
+
+func (p *PipelineProcessor) ExecExists(id string) bool {
+	_, err := p.getRecord(id)
+	return err == nil
+}
+
+func (p *PipelineProcessor) getRecord(id string) (*pipelineRecord, error) {
+	db, key, err := p.dbOf(id)
+	if err != nil {
+		return nil, err
+	}
+	var s string
+	if ok, _ := db.Get(key, &s); !ok {
+		return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("Pipeline %s is not found.", id))
+	}
+	rec := &pipelineRecord{}
+	if err := json.Unmarshal(cast.StringToBytes(s), rec); err != nil {
+		return nil, fmt.Errorf("Parse pipeline %s error : %s.", id, err)
+	}
+	return rec, nil
+}
+
+// GetPipelineJson returns the pipeline definition as created
+func (p *PipelineProcessor) GetPipelineJson(id string) (string, error) {
+	rec, err := p.getRecord(id)
+	if err != nil {
+		return "", err
+	}
+	return rec.Definition, nil
+}
+
+// GetPipelineRules returns the qualified ids of the pipeline rules sorted from the upstream to the downstream
+func (p *PipelineProcessor) GetPipelineRules(id string) ([]string, error) {
+	rec, err := p.getRecord(id)
+	if err != nil {
+		return nil, err
+	}
+	ns, _ := namespace.Split(id)
+	result := make([]string, len(rec.Rules))
+	for i, r := range rec.Rules {
+		result[i] = namespace.Qualify(ns, r)
+	}
+	return result, nil
+}
+
+// GetPipelinesIn returns the qualified ids of the pipelines in the namespace
+func (p *PipelineProcessor) GetPipelinesIn(ns string) ([]string, error) {
+	db, _, err := p.dbOf(namespace.Qualify(ns, ""))
+	if err != nil {
+		return nil, err
+	}
+	keys, err := db.Keys()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		keys[i] = namespace.Qualify(ns, k)
+	}
+	return keys, nil
+}
+
+func (p *PipelineProcessor) ExecDrop(id string) (string, error) {
+	db, key, err := p.dbOf(id)
+	if err != nil {
+		return "", err
+	}
+	if err := db.Delete(key); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Pipeline %s is dropped.", id), nil
+}
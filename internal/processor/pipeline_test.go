@@ -0,0 +1,122 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipeline(t *testing.T) {
+	sp := NewStreamProcessor()
+	defer sp.db.Clean()
+	for _, s := range []string{
+		`CREATE STREAM plA () WITH (TYPE="memory", DATASOURCE="topicA", FORMAT="json")`,
+		`CREATE STREAM plB () WITH (TYPE="memory", DATASOURCE="topicB", FORMAT="json")`,
+		`CREATE STREAM plC () WITH (DATASOURCE="topicC", FORMAT="json")`,
+	} {
+		_, err := sp.ExecStmt(s)
+		require.NoError(t, err)
+	}
+	p := NewPipelineProcessor(NewRuleProcessor(), sp)
+	defer p.db.Clean()
+
+	tests := []struct {
+		name  string
+		json  string
+		rules []string
+		err   string
+	}{
+		{
+			name: "sorted",
+			json: `{"id":"p1","topics":["topicA","topicB"],"rules":[
+				{"id":"r3","sql":"SELECT * FROM plB","actions":[{"log":{}}]},
+				{"id":"r2","sql":"SELECT * FROM plA","actions":[{"memory":{"topic":"topicB"}}]},
+				{"id":"r1","sql":"SELECT * FROM plC","actions":[{"memory":{"topic":"topicA"}},{"log":{}}]}]}`,
+			rules: []string{"r1", "r2", "r3"},
+		},
+		{
+			name: "cycle",
+			json: `{"id":"p1","topics":["topicA","topicB"],"rules":[
+				{"id":"r1","sql":"SELECT * FROM plA","actions":[{"memory":{"topic":"topicB"}}]},
+				{"id":"r2","sql":"SELECT * FROM plB","actions":[{"memory":{"topic":"topicA"}}]}]}`,
+			err: "invalid pipeline p1: the rules r1, r2 are wired in a cycle",
+		},
+		{
+			name: "self loop",
+			json: `{"id":"p1","topics":["topicA"],"rules":[
+				{"id":"r1","sql":"SELECT * FROM plA","actions":[{"memory":{"topic":"topicA"}}]}]}`,
+			err: "invalid pipeline p1: rule r1 subscribes its own topic topicA",
+		},
+		{
+			name: "not subscribed",
+			json: `{"id":"p1","topics":["topicA"],"rules":[
+				{"id":"r1","sql":"SELECT * FROM plC","actions":[{"memory":{"topic":"topicA"}}]}]}`,
+			err: "pipeline topic topicA is not subscribed by any rule",
+		},
+		{
+			name: "wildcard",
+			json: `{"id":"p1","topics":["topic/#"],"rules":[{"id":"r1","sql":"SELECT * FROM plC","actions":[{"log":{}}]}]}`,
+			err:  `invalid pipeline topic "topic/#", it must be a memory topic without wildcard`,
+		},
+		{
+			name: "duplicate rule",
+			json: `{"id":"p1","rules":[{"id":"r1","sql":"SELECT * FROM plC","actions":[{"log":{}}]},{"id":"r1","sql":"SELECT * FROM plC","actions":[{"log":{}}]}]}`,
+			err:  "duplicate rule r1 in pipeline p1",
+		},
+		{
+			name: "no rules",
+			json: `{"id":"p1"}`,
+			err:  "pipeline p1 has no rules",
+		},
+		{
+			name: "graph",
+			json: `{"id":"p1","rules":[{"id":"r1","graph":{}}]}`,
+			err:  "rule r1 in pipeline p1 must be defined by sql",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pl, rules, err := p.GetPipelineByJson("", tt.json)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, pl.Triggered)
+			ids := make([]string, len(rules))
+			for i, r := range rules {
+				ids[i] = r.Rule.Id
+			}
+			assert.Equal(t, tt.rules, ids)
+			require.NoError(t, p.ExecCreate(pl.Id, tt.json, rules))
+			assert.Error(t, p.ExecCreate(pl.Id, tt.json, rules))
+			ruleIds, err := p.GetPipelineRules(pl.Id)
+			require.NoError(t, err)
+			assert.Equal(t, tt.rules, ruleIds)
+			s, err := p.GetPipelineJson(pl.Id)
+			require.NoError(t, err)
+			assert.Equal(t, tt.json, s)
+			all, err := p.GetPipelinesIn("default")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"p1"}, all)
+			_, err = p.ExecDrop(pl.Id)
+			require.NoError(t, err)
+			assert.False(t, p.ExecExists(pl.Id))
+		})
+	}
+}
@@ -21,6 +21,8 @@ import (
 
 	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/meta"
+	"github.com/lf-edge/ekuiper/internal/pkg/labels"
+	"github.com/lf-edge/ekuiper/internal/pkg/namespace"
 	"github.com/lf-edge/ekuiper/internal/pkg/store"
 	"github.com/lf-edge/ekuiper/internal/xsql"
 	"github.com/lf-edge/ekuiper/pkg/api"
@@ -51,29 +53,55 @@ func NewRuleProcessor() *RuleProcessor {
 	return processor
 }
 
+// dbOf returns the table and the key of a rule. The id of a rule in a namespace is qualified by the namespace.
+func (p *RuleProcessor) dbOf(id string) (kv.KeyValue, string, error) {
+	ns, name := namespace.Split(id)
+	if ns == namespace.Default {
+		return p.db, name, nil
+	}
+	db, err := store.GetKV(namespace.Table("rule", ns))
+	if err != nil {
+		return nil, "", err
+	}
+	return db, name, nil
+}
+
 func (p *RuleProcessor) ExecCreateWithValidation(name, ruleJson string) (*api.Rule, error) {
 	rule, err := p.GetRuleByJson(name, ruleJson)
 	if err != nil {
 		return nil, err
 	}
-
-	err = p.db.Setnx(rule.Id, ruleJson)
+	db, key, err := p.dbOf(rule.Id)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Setnx(key, ruleJson)
 	if err != nil {
 		return nil, err
 	} else {
 		log.Infof("Rule %s is created.", rule.Id)
 	}
+	if err := p.saveVersion(rule.Id, ruleJson); err != nil {
+		log.Warnf("Save version of rule %s error: %v", rule.Id, err)
+	}
 
 	return rule, nil
 }
 
 func (p *RuleProcessor) ExecCreate(name, ruleJson string) error {
-	err := p.db.Setnx(name, ruleJson)
+	db, key, err := p.dbOf(name)
+	if err != nil {
+		return err
+	}
+	err = db.Setnx(key, ruleJson)
 	if err != nil {
 		return err
 	} else {
 		log.Infof("Rule %s is created.", name)
 	}
+	if err := p.saveVersion(name, ruleJson); err != nil {
+		log.Warnf("Save version of rule %s error: %v", name, err)
+	}
 
 	return nil
 }
@@ -83,12 +111,19 @@ func (p *RuleProcessor) ExecUpdate(name, ruleJson string) (*api.Rule, error) {
 	if err != nil {
 		return nil, err
 	}
-	err = p.db.Set(rule.Id, ruleJson)
+	db, key, err := p.dbOf(rule.Id)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Set(key, ruleJson)
 	if err != nil {
 		return nil, err
 	} else {
 		log.Infof("Rule %s is update.", rule.Id)
 	}
+	if err := p.saveVersion(rule.Id, ruleJson); err != nil {
+		log.Warnf("Save version of rule %s error: %v", rule.Id, err)
+	}
 
 	return rule, nil
 }
@@ -99,13 +134,20 @@ func (p *RuleProcessor) ExecReplaceRuleState(name string, triggered bool) (*api.
 		return nil, err
 	}
 
+	db, key, err := p.dbOf(name)
+	if err != nil {
+		return nil, err
+	}
 	rule.Triggered = triggered
+	// The rule json saves the local id
+	rule.Id = key
 	ruleJson, err := json.Marshal(rule)
+	rule.Id = name
 	if err != nil {
 		return nil, fmt.Errorf("Marshal rule %s error : %s.", name, err)
 	}
 
-	err = p.db.Set(name, string(ruleJson))
+	err = db.Set(key, string(ruleJson))
 	if err != nil {
 		return nil, err
 	} else {
@@ -115,8 +157,12 @@ func (p *RuleProcessor) ExecReplaceRuleState(name string, triggered bool) (*api.
 }
 
 func (p *RuleProcessor) GetRuleJson(id string) (string, error) {
+	db, key, err := p.dbOf(id)
+	if err != nil {
+		return "", err
+	}
 	var s1 string
-	f, _ := p.db.Get(id, &s1)
+	f, _ := db.Get(key, &s1)
 	if !f {
 		return "", errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("Rule %s is not found.", id))
 	}
@@ -124,12 +170,23 @@ func (p *RuleProcessor) GetRuleJson(id string) (string, error) {
 }
 
 func (p *RuleProcessor) GetRuleById(id string) (*api.Rule, error) {
+	db, key, err := p.dbOf(id)
+	if err != nil {
+		return nil, err
+	}
 	var s1 string
-	f, _ := p.db.Get(id, &s1)
+	f, _ := db.Get(key, &s1)
 	if !f {
 		return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("Rule %s is not found.", id))
 	}
-	return p.GetRuleByJsonValidated(s1)
+	rule, err := p.GetRuleByJsonValidated(s1)
+	if err != nil {
+		return nil, err
+	}
+	if ns, _ := namespace.Split(id); ns != namespace.Default {
+		rule.Id = id
+	}
+	return rule, nil
 }
 
 // GetRuleByJsonValidated called when the json is getting from trusted source like db
@@ -164,6 +221,8 @@ func (p *RuleProcessor) GetRuleByJson(id, ruleJson string) (*api.Rule, error) {
 	if err != nil {
 		return rule, err
 	}
+	// The id may be qualified by the namespace while the id in the json is always local
+	ns, id := namespace.Split(id)
 	// validation
 	if rule.Id == "" && id == "" {
 		return nil, fmt.Errorf("Missing rule id.")
@@ -177,6 +236,7 @@ func (p *RuleProcessor) GetRuleByJson(id, ruleJson string) (*api.Rule, error) {
 	if err := validateRuleID(rule.Id); err != nil {
 		return nil, err
 	}
+	rule.Id = namespace.Qualify(ns, rule.Id)
 	if rule.Sql != "" {
 		if rule.Graph != nil {
 			return nil, fmt.Errorf("Rule %s has both sql and graph.", rule.Id)
@@ -196,6 +256,9 @@ func (p *RuleProcessor) GetRuleByJson(id, ruleJson string) (*api.Rule, error) {
 	if err != nil {
 		return nil, fmt.Errorf("Rule %s has invalid options: %s.", rule.Id, err)
 	}
+	if err := labels.Validate(rule.Labels); err != nil {
+		return nil, fmt.Errorf("Rule %s has invalid labels: %s.", rule.Id, err)
+	}
 	return rule, nil
 }
 
@@ -204,15 +267,25 @@ func validateRuleID(id string) error {
 }
 
 func clone(opt api.RuleOption) *api.RuleOption {
-	return &api.RuleOption{
-		IsEventTime:        opt.IsEventTime,
-		LateTol:            opt.LateTol,
-		Concurrency:        opt.Concurrency,
-		BufferLength:       opt.BufferLength,
-		SendMetaToSink:     opt.SendMetaToSink,
-		SendError:          opt.SendError,
-		Qos:                opt.Qos,
-		CheckpointInterval: opt.CheckpointInterval,
+	result := &api.RuleOption{
+		IsEventTime:            opt.IsEventTime,
+		LateTol:                opt.LateTol,
+		AllowedLateness:        opt.AllowedLateness,
+		LateDataTopic:          opt.LateDataTopic,
+		Concurrency:            opt.Concurrency,
+		BufferLength:           opt.BufferLength,
+		SendMetaToSink:         opt.SendMetaToSink,
+		SendError:              opt.SendError,
+		Qos:                    opt.Qos,
+		CheckpointInterval:     opt.CheckpointInterval,
+		FullCheckpointInterval: opt.FullCheckpointInterval,
+		EnableAck:              opt.EnableAck,
+		EarlyFireInterval:      opt.EarlyFireInterval,
+		EarlyFireOnElement:     opt.EarlyFireOnElement,
+		WindowMemoryLimit:      opt.WindowMemoryLimit,
+		StateStore:             opt.StateStore,
+		TraceSampleRate:        opt.TraceSampleRate,
+		MicroBatchSize:         opt.MicroBatchSize,
 		RestartStrategy: &api.RestartStrategy{
 			Attempts:     opt.RestartStrategy.Attempts,
 			Delay:        opt.RestartStrategy.Delay,
@@ -221,17 +294,44 @@ func clone(opt api.RuleOption) *api.RuleOption {
 			JitterFactor: opt.RestartStrategy.JitterFactor,
 		},
 	}
+	if opt.Quota != nil {
+		q := *opt.Quota
+		result.Quota = &q
+	}
+	if opt.Clock != nil {
+		c := *opt.Clock
+		result.Clock = &c
+	}
+	if opt.Parallelism != nil {
+		result.Parallelism = make(map[string]*api.OperatorParallelism, len(opt.Parallelism))
+		for name, p := range opt.Parallelism {
+			if p != nil {
+				c := *p
+				p = &c
+			}
+			result.Parallelism[name] = p
+		}
+	}
+	return result
 }
 
 func (p *RuleProcessor) ExecExists(name string) bool {
+	db, key, err := p.dbOf(name)
+	if err != nil {
+		return false
+	}
 	var s1 string
-	f, _ := p.db.Get(name, &s1)
+	f, _ := db.Get(key, &s1)
 	return f
 }
 
 func (p *RuleProcessor) ExecDesc(name string) (string, error) {
+	db, key, err := p.dbOf(name)
+	if err != nil {
+		return "", err
+	}
 	var s1 string
-	f, _ := p.db.Get(name, &s1)
+	f, _ := db.Get(key, &s1)
 	if !f {
 		return "", fmt.Errorf("Rule %s is not found.", name)
 	}
@@ -247,36 +347,60 @@ func (p *RuleProcessor) GetAllRules() ([]string, error) {
 	return p.db.Keys()
 }
 
+// GetRulesIn returns the qualified ids of the rules in the namespace
+func (p *RuleProcessor) GetRulesIn(ns string) ([]string, error) {
+	db, _, err := p.dbOf(namespace.Qualify(ns, ""))
+	if err != nil {
+		return nil, err
+	}
+	keys, err := db.Keys()
+	if err != nil {
+		return nil, err
+	}
+	for i, k := range keys {
+		keys[i] = namespace.Qualify(ns, k)
+	}
+	return keys, nil
+}
+
 func (p *RuleProcessor) GetAllRulesJson() (map[string]string, error) {
 	return p.db.All()
 }
 
 func (p *RuleProcessor) ExecDrop(name string) (string, error) {
+	db, key, err := p.dbOf(name)
+	if err != nil {
+		return "", err
+	}
 	result := fmt.Sprintf("Rule %s is dropped.", name)
 	var ruleJson string
-	if ok, _ := p.db.Get(name, &ruleJson); ok {
+	if ok, _ := db.Get(key, &ruleJson); ok {
 		if err := cleanSinkCache(name); err != nil {
 			result = fmt.Sprintf("%s. Clean sink cache faile: %s.", result, err)
 		}
-		if err := cleanCheckpoint(name); err != nil {
+		if err := cleanCheckpoint(name, ruleJson); err != nil {
 			result = fmt.Sprintf("%s. Clean checkpoint cache faile: %s.", result, err)
 		}
 
 	}
-	err := p.db.Delete(name)
+	err = db.Delete(key)
 	if err != nil {
 		return "", err
-	} else {
-		return result, nil
 	}
+	if err := p.dropVersions(name); err != nil {
+		result = fmt.Sprintf("%s. Clean rule versions failed: %s.", result, err)
+	}
+	return result, nil
 }
 
-func cleanCheckpoint(name string) error {
-	err := store.DropTS(name)
-	if err != nil {
-		return err
+func cleanCheckpoint(name string, ruleJson string) error {
+	// The checkpoints are saved in the state store specified by the rule options
+	var stateType string
+	r := &api.Rule{}
+	if err := json.Unmarshal(cast.StringToBytes(ruleJson), r); err == nil && r.Options != nil {
+		stateType = r.Options.StateStore
 	}
-	return nil
+	return store.DropStateTS(stateType, name)
 }
 
 func cleanSinkCache(name string) error {
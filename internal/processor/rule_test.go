@@ -82,14 +82,15 @@ func TestRuleActionParse_Apply(t *testing.T) {
 					},
 				},
 				Options: &api.RuleOption{
-					IsEventTime:        false,
-					LateTol:            1000,
-					Concurrency:        1,
-					BufferLength:       1024,
-					SendMetaToSink:     false,
-					Qos:                api.AtMostOnce,
-					CheckpointInterval: 300000,
-					SendError:          true,
+					IsEventTime:            false,
+					LateTol:                1000,
+					Concurrency:            1,
+					BufferLength:           1024,
+					SendMetaToSink:         false,
+					Qos:                    api.AtMostOnce,
+					CheckpointInterval:     300000,
+					FullCheckpointInterval: 10,
+					SendError:              true,
 					RestartStrategy: &api.RestartStrategy{
 						Attempts:     20,
 						Delay:        1000,
@@ -145,14 +146,15 @@ func TestRuleActionParse_Apply(t *testing.T) {
 					},
 				},
 				Options: &api.RuleOption{
-					IsEventTime:        true,
-					LateTol:            1000,
-					Concurrency:        1,
-					BufferLength:       10240,
-					SendMetaToSink:     false,
-					Qos:                api.ExactlyOnce,
-					CheckpointInterval: 60000,
-					SendError:          true,
+					IsEventTime:            true,
+					LateTol:                1000,
+					Concurrency:            1,
+					BufferLength:           10240,
+					SendMetaToSink:         false,
+					Qos:                    api.ExactlyOnce,
+					CheckpointInterval:     60000,
+					FullCheckpointInterval: 10,
+					SendError:              true,
 					RestartStrategy: &api.RestartStrategy{
 						Attempts:     0,
 						Delay:        1000,
@@ -181,14 +183,15 @@ func TestRuleActionParse_Apply(t *testing.T) {
 					},
 				},
 				Options: &api.RuleOption{
-					IsEventTime:        false,
-					LateTol:            1000,
-					Concurrency:        1,
-					BufferLength:       1024,
-					SendMetaToSink:     false,
-					Qos:                api.AtMostOnce,
-					CheckpointInterval: 300000,
-					SendError:          true,
+					IsEventTime:            false,
+					LateTol:                1000,
+					Concurrency:            1,
+					BufferLength:           1024,
+					SendMetaToSink:         false,
+					Qos:                    api.AtMostOnce,
+					CheckpointInterval:     300000,
+					FullCheckpointInterval: 10,
+					SendError:              true,
 					RestartStrategy: &api.RestartStrategy{
 						Attempts:     0,
 						Delay:        1000,
@@ -0,0 +1,222 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/pkg/audit"
+	"github.com/lf-edge/ekuiper/internal/pkg/namespace"
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/pkg/errorx"
+	"github.com/lf-edge/ekuiper/pkg/kv"
+)
+
+const (
+	// the digits of the version in the key so that the keys of a rule are sorted by the version
+	versionDigits = 8
+	// the key suffix of the time when the rule is created
+	createdSuffix = "created"
+)
+
+// RuleVersion is a saved definition of a rule. A version is saved when the rule is created or updated.
+type RuleVersion struct {
+	Version   int    `json:"version"`
+	Timestamp int64  `json:"timestamp"`
+	Rule      string `json:"rule"`
+}
+
+// versionDbOf returns the version table and the key prefix of a rule
+func versionDbOf(id string) (kv.KeyValue, string, error) {
+	ns, name := namespace.Split(id)
+	db, err := store.GetKV(namespace.Table("ruleVersion", ns))
+	if err != nil {
+		return nil, "", err
+	}
+	return db, name + "@", nil
+}
+
+// saveVersion saves the rule json as the next version if it is changed and removes the oldest versions exceeding the limit
+func (p *RuleProcessor) saveVersion(id, ruleJson string) error {
+	versions, err := p.GetRuleVersions(id)
+	if err != nil {
+		return err
+	}
+	db, prefix, err := versionDbOf(id)
+	if err != nil {
+		return err
+	}
+	next := 1
+	if len(versions) == 0 {
+		_ = db.Setnx(prefix+createdSuffix, strconv.FormatInt(time.Now().UnixMilli(), 10))
+	} else {
+		last := versions[len(versions)-1]
+		if last.Rule == ruleJson {
+			return nil
+		}
+		next = last.Version + 1
+	}
+	v := &RuleVersion{Version: next, Timestamp: time.Now().UnixMilli(), Rule: ruleJson}
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := db.Set(versionKey(prefix, next), string(bs)); err != nil {
+		return err
+	}
+	if conf.Config == nil {
+		return nil
+	}
+	if limit := conf.Config.Basic.MaxRuleVersions; limit > 0 && len(versions)+1 > limit {
+		for _, old := range versions[:len(versions)+1-limit] {
+			_ = db.Delete(versionKey(prefix, old.Version))
+		}
+	}
+	return nil
+}
+
+func versionKey(prefix string, version int) string {
+	return fmt.Sprintf("%s%0*d", prefix, versionDigits, version)
+}
+
+// GetRuleVersions returns the saved versions of a rule from the oldest to the newest
+func (p *RuleProcessor) GetRuleVersions(id string) ([]*RuleVersion, error) {
+	db, prefix, err := versionDbOf(id)
+	if err != nil {
+		return nil, err
+	}
+	all, err := db.All()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*RuleVersion, 0)
+	for k, val := range all {
+		// The rule id may contain @, make sure the rest is exactly the version
+		rest, ok := strings.CutPrefix(k, prefix)
+		if !ok || len(rest) != versionDigits {
+			continue
+		}
+		if _, err := strconv.Atoi(rest); err != nil {
+			continue
+		}
+		v := &RuleVersion{}
+		if err := json.Unmarshal([]byte(val), v); err != nil {
+			return nil, fmt.Errorf("invalid version %s of rule %s: %v", rest, id, err)
+		}
+		result = append(result, v)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+	return result, nil
+}
+
+// GetRuleVersion returns a saved version of a rule
+func (p *RuleProcessor) GetRuleVersion(id string, version int) (*RuleVersion, error) {
+	db, prefix, err := versionDbOf(id)
+	if err != nil {
+		return nil, err
+	}
+	var val string
+	if ok, _ := db.Get(versionKey(prefix, version), &val); !ok {
+		return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("Version %d of rule %s is not found.", version, id))
+	}
+	v := &RuleVersion{}
+	if err := json.Unmarshal([]byte(val), v); err != nil {
+		return nil, fmt.Errorf("invalid version %d of rule %s: %v", version, id, err)
+	}
+	return v, nil
+}
+
+// DiffRuleVersions returns the changed fields of the rule from one version to another
+func (p *RuleProcessor) DiffRuleVersions(id string, from, to int) (map[string]*audit.Change, error) {
+	var defs [2]map[string]interface{}
+	for i, version := range []int{from, to} {
+		v, err := p.GetRuleVersion(id, version)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(v.Rule), &defs[i]); err != nil {
+			return nil, fmt.Errorf("invalid version %d of rule %s: %v", version, id, err)
+		}
+	}
+	return audit.Diff(defs[0], defs[1]), nil
+}
+
+// dropVersions deletes all the versions of a dropped rule
+func (p *RuleProcessor) dropVersions(id string) error {
+	versions, err := p.GetRuleVersions(id)
+	if err != nil {
+		return err
+	}
+	db, prefix, err := versionDbOf(id)
+	if err != nil {
+		return err
+	}
+	for _, v := range versions {
+		if err := db.Delete(versionKey(prefix, v.Version)); err != nil {
+			return err
+		}
+	}
+	_ = db.Delete(prefix + createdSuffix)
+	return nil
+}
+
+// GetRulesCreated returns the unix milliseconds when each rule in the namespace is created by the qualified id. For
+// the rules saved before the creation time is recorded, it is the time of the oldest saved version.
+func (p *RuleProcessor) GetRulesCreated(ns string) (map[string]int64, error) {
+	db, _, err := versionDbOf(namespace.Qualify(ns, ""))
+	if err != nil {
+		return nil, err
+	}
+	all, err := db.All()
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]int64)
+	oldest := make(map[string]*RuleVersion)
+	for k, val := range all {
+		i := strings.LastIndex(k, "@")
+		if i < 0 {
+			continue
+		}
+		id, rest := namespace.Qualify(ns, k[:i]), k[i+1:]
+		if rest == createdSuffix {
+			if ts, err := strconv.ParseInt(val, 10, 64); err == nil {
+				result[id] = ts
+			}
+			continue
+		}
+		v := &RuleVersion{}
+		if len(rest) != versionDigits || json.Unmarshal([]byte(val), v) != nil {
+			continue
+		}
+		if o, ok := oldest[id]; !ok || v.Version < o.Version {
+			oldest[id] = v
+		}
+	}
+	for id, v := range oldest {
+		if _, ok := result[id]; !ok {
+			result[id] = v.Timestamp
+		}
+	}
+	return result, nil
+}
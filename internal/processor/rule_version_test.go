@@ -0,0 +1,84 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/pkg/audit"
+)
+
+func TestRuleVersions(t *testing.T) {
+	sp := NewStreamProcessor()
+	defer sp.db.Clean()
+	_, err := sp.ExecStmt(`CREATE STREAM versionDemo () WITH (DATASOURCE="users", FORMAT="JSON")`)
+	require.NoError(t, err)
+	limit := conf.Config.Basic.MaxRuleVersions
+	conf.Config.Basic.MaxRuleVersions = 3
+	defer func() { conf.Config.Basic.MaxRuleVersions = limit }()
+
+	rp := NewRuleProcessor()
+	defer rp.db.Clean()
+	v1 := `{"id":"versionRule","sql":"SELECT * FROM versionDemo","actions":[{"log":{}}]}`
+	v2 := `{"id":"versionRule","sql":"SELECT a FROM versionDemo","actions":[{"log":{}}]}`
+	_, err = rp.ExecCreateWithValidation("versionRule", v1)
+	require.NoError(t, err)
+	_, err = rp.ExecUpdate("versionRule", v2)
+	require.NoError(t, err)
+	// Not changed, no new version
+	_, err = rp.ExecUpdate("versionRule", v2)
+	require.NoError(t, err)
+	versions, err := rp.GetRuleVersions("versionRule")
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, 1, versions[0].Version)
+	assert.Equal(t, v1, versions[0].Rule)
+	assert.Equal(t, 2, versions[1].Version)
+
+	diff, err := rp.DiffRuleVersions("versionRule", 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]*audit.Change{"sql": {Old: "SELECT * FROM versionDemo", New: "SELECT a FROM versionDemo"}}, diff)
+	_, err = rp.GetRuleVersion("versionRule", 5)
+	assert.Error(t, err)
+
+	// The oldest versions exceeding the limit are removed
+	_, err = rp.ExecUpdate("versionRule", v1)
+	require.NoError(t, err)
+	_, err = rp.ExecUpdate("versionRule", v2)
+	require.NoError(t, err)
+	versions, err = rp.GetRuleVersions("versionRule")
+	require.NoError(t, err)
+	require.Len(t, versions, 3)
+	assert.Equal(t, 2, versions[0].Version)
+	assert.Equal(t, 4, versions[2].Version)
+	// The creation time is kept after the first version is removed
+	created, err := rp.GetRulesCreated("")
+	require.NoError(t, err)
+	assert.Contains(t, created, "versionRule")
+	assert.LessOrEqual(t, created["versionRule"], versions[0].Timestamp)
+
+	_, err = rp.ExecDrop("versionRule")
+	require.NoError(t, err)
+	versions, err = rp.GetRuleVersions("versionRule")
+	require.NoError(t, err)
+	assert.Empty(t, versions)
+	created, err = rp.GetRulesCreated("")
+	require.NoError(t, err)
+	assert.NotContains(t, created, "versionRule")
+}
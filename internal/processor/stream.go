@@ -24,6 +24,9 @@ import (
 	"golang.org/x/text/language"
 
 	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/io/memory/pubsub"
+	"github.com/lf-edge/ekuiper/internal/pkg/labels"
+	"github.com/lf-edge/ekuiper/internal/pkg/namespace"
 	"github.com/lf-edge/ekuiper/internal/pkg/store"
 	"github.com/lf-edge/ekuiper/internal/schema"
 	"github.com/lf-edge/ekuiper/internal/topo/lookup"
@@ -37,15 +40,18 @@ import (
 var log = conf.Log
 
 type StreamProcessor struct {
+	// ns is the namespace of the streams, which is empty for the default namespace
+	ns             string
 	db             kv.KeyValue
 	streamStatusDb kv.KeyValue
 	tableStatusDb  kv.KeyValue
 }
 
 type StreamDetail struct {
-	Name   string `json:"name"`
-	Type   string `json:"type"`
-	Format string `json:"format"`
+	Name   string            `json:"name"`
+	Type   string            `json:"type"`
+	Format string            `json:"format"`
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 func NewStreamProcessor() *StreamProcessor {
@@ -69,6 +75,23 @@ func NewStreamProcessor() *StreamProcessor {
 	return processor
 }
 
+// In returns the processor of the streams and tables in the namespace
+func (p *StreamProcessor) In(ns string) (*StreamProcessor, error) {
+	if ns == "" || ns == namespace.Default {
+		return p, nil
+	}
+	db, err := store.GetKV(namespace.Table("stream", ns))
+	if err != nil {
+		return nil, err
+	}
+	return &StreamProcessor{
+		ns:             ns,
+		db:             db,
+		streamStatusDb: p.streamStatusDb,
+		tableStatusDb:  p.tableStatusDb,
+	}, nil
+}
+
 func (p *StreamProcessor) ExecStmt(statement string) (result []string, err error) {
 	defer func() {
 		if err != nil {
@@ -150,7 +173,7 @@ func (p *StreamProcessor) RecoverLookupTable() (err error) {
 				switch s := stmt.(type) {
 				case *ast.StreamStmt:
 					log.Infof("Starting lookup table %s", s.Name)
-					e = lookup.CreateInstance(string(s.Name), s.Options.TYPE, s.Options)
+					e = lookup.CreateInstance(namespace.Qualify(p.ns, string(s.Name)), s.Options.TYPE, s.Options)
 					if e != nil {
 						log.Errorf("%s", e.Error())
 					}
@@ -167,7 +190,7 @@ func (p *StreamProcessor) RecoverLookupTable() (err error) {
 func (p *StreamProcessor) execSave(stmt *ast.StreamStmt, statement string, replace bool) error {
 	if stmt.StreamType == ast.TypeTable && stmt.Options.KIND == ast.StreamKindLookup {
 		log.Infof("Creating lookup table %s", stmt.Name)
-		err := lookup.CreateInstance(string(stmt.Name), stmt.Options.TYPE, stmt.Options)
+		err := lookup.CreateInstance(namespace.Qualify(p.ns, string(stmt.Name)), stmt.Options.TYPE, stmt.Options)
 		if err != nil {
 			return err
 		}
@@ -301,7 +324,7 @@ func (p *StreamProcessor) ShowStreamOrTableDetails(kind string, st ast.StreamTyp
 			if f == "" {
 				f = "json"
 			}
-			streamDetails = append(streamDetails, StreamDetail{Name: name, Type: strings.ToLower(t), Format: strings.ToLower(f)})
+			streamDetails = append(streamDetails, StreamDetail{Name: name, Type: strings.ToLower(t), Format: strings.ToLower(f), Labels: v.Options.LABELS})
 		}
 	}
 
@@ -379,6 +402,9 @@ func (p *StreamProcessor) execDescribe(stmt ast.NameNode, st ast.StreamType) (r
 		for _, f := range s.StreamFields {
 			buff.WriteString(f.Name + "\t")
 			buff.WriteString(printFieldType(f.FieldType))
+			if c := printColumnExpr(f); c != "" {
+				buff.WriteString("\t" + c)
+			}
 			buff.WriteString("\n")
 		}
 		buff.WriteString("\n")
@@ -414,6 +440,12 @@ func printOptions(opts *ast.Options, buff *bytes.Buffer) {
 	if opts.STRICT_VALIDATION {
 		buff.WriteString(fmt.Sprintf("STRICT_VALIDATION: %v\n", opts.STRICT_VALIDATION))
 	}
+	if opts.VALIDATION != "" {
+		buff.WriteString(fmt.Sprintf("VALIDATION: %s\n", opts.VALIDATION))
+	}
+	if opts.ERROR_TOPIC != "" {
+		buff.WriteString(fmt.Sprintf("ERROR_TOPIC: %s\n", opts.ERROR_TOPIC))
+	}
 	if opts.TIMESTAMP != "" {
 		buff.WriteString(fmt.Sprintf("TIMESTAMP: %s\n", opts.TIMESTAMP))
 	}
@@ -423,6 +455,9 @@ func printOptions(opts *ast.Options, buff *bytes.Buffer) {
 	if opts.TYPE != "" {
 		buff.WriteString(fmt.Sprintf("TYPE: %s\n", opts.TYPE))
 	}
+	if len(opts.LABELS) > 0 {
+		buff.WriteString(fmt.Sprintf("LABELS: %s\n", labels.String(opts.LABELS)))
+	}
 }
 
 func (p *StreamProcessor) DescStream(name string, st ast.StreamType) (r ast.Statement, err error) {
@@ -445,6 +480,18 @@ func (p *StreamProcessor) DescStream(name string, st ast.StreamType) (r ast.Stat
 	return stream, nil
 }
 
+// GetLabels returns the labels of the stream or table
+func (p *StreamProcessor) GetLabels(name string, st ast.StreamType) (map[string]string, error) {
+	sd, err := p.DescStream(name, st)
+	if err != nil {
+		return nil, err
+	}
+	if s, ok := sd.(*ast.StreamStmt); ok {
+		return s.Options.LABELS, nil
+	}
+	return nil, nil
+}
+
 func (p *StreamProcessor) GetInferredSchema(name string, st ast.StreamType) (r ast.StreamFields, err error) {
 	defer func() {
 		if err != nil {
@@ -469,6 +516,9 @@ func (p *StreamProcessor) GetInferredSchema(name string, st ast.StreamType) (r a
 	if stmt.Options.SCHEMAID != "" {
 		return schema.InferFromSchemaFile(stmt.Options.FORMAT, stmt.Options.SCHEMAID)
 	}
+	if stmt.StreamFields == nil && stmt.Options.TYPE == "memory" {
+		return pubsub.GetSchema(stmt.Options.DATASOURCE), nil
+	}
 	return nil, nil
 }
 
@@ -500,6 +550,8 @@ func (p *StreamProcessor) GetInferredJsonSchema(name string, st ast.StreamType)
 		if err != nil {
 			return nil, err
 		}
+	} else if sfs == nil && stmt.Options.TYPE == "memory" {
+		sfs = pubsub.GetSchema(stmt.Options.DATASOURCE)
 	}
 	return sfs.ToJsonSchema(), nil
 }
@@ -529,7 +581,7 @@ func (p *StreamProcessor) DropStream(name string, st ast.StreamType) (r string,
 		}
 	}()
 	if st == ast.TypeTable {
-		err := lookup.DropInstance(name)
+		err := lookup.DropInstance(namespace.Qualify(p.ns, name))
 		if err != nil {
 			return "", err
 		}
@@ -575,6 +627,17 @@ func printFieldType(ft ast.FieldType) (result string) {
 	return
 }
 
+// printColumnExpr prints the default value or computed expression of the field as in the definition
+func printColumnExpr(f ast.StreamField) string {
+	if f.Default != nil {
+		return "DEFAULT " + f.Default.Text
+	}
+	if f.Computed != nil {
+		return "AS (" + f.Computed.Text + ")"
+	}
+	return ""
+}
+
 // GetAll return all streams and tables defined to export.
 func (p *StreamProcessor) GetAll() (result map[string]map[string]string, err error) {
 	defs, e := p.db.All()
@@ -608,6 +671,8 @@ func DescribeToJson(s string) string {
 	fields, options := sections[0], sections[1]
 	type field struct {
 		Name, Type string
+		Default    string `json:",omitempty"`
+		Computed   string `json:",omitempty"`
 	}
 	type output struct {
 		Fields  []field
@@ -616,8 +681,15 @@ func DescribeToJson(s string) string {
 	o := output{Options: make(map[string]string)}
 	for _, f := range strings.Split(fields, "\n") {
 		split := strings.Split(f, "\t")
-		n, t := split[0], split[1]
-		o.Fields = append(o.Fields, field{Name: n, Type: t})
+		fd := field{Name: split[0], Type: split[1]}
+		if len(split) > 2 {
+			if c, ok := strings.CutPrefix(split[2], "DEFAULT "); ok {
+				fd.Default = c
+			} else if c, ok := strings.CutPrefix(split[2], "AS "); ok {
+				fd.Computed = strings.TrimSuffix(strings.TrimPrefix(c, "("), ")")
+			}
+		}
+		o.Fields = append(o.Fields, fd)
 	}
 	for _, f := range strings.Split(strings.Trim(options, "\n"), "\n") {
 		split := strings.Split(f, " ")
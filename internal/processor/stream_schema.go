@@ -0,0 +1,471 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	"github.com/lf-edge/ekuiper/pkg/errorx"
+)
+
+// SchemaInference is the schema inferred from the sampled data of a stream
+type SchemaInference struct {
+	Samples int               `json:"samples"`
+	Fields  []*FieldInference `json:"fields"`
+	// Definition is the field definitions of the inferred schema, which can be applied to the stream by UpdateSchema
+	Definition string `json:"definition"`
+}
+
+type FieldInference struct {
+	Name string `json:"name"`
+	// Type is the type in the stream definition, such as bigint or array(string)
+	Type string `json:"type"`
+	// Confidence is the ratio of the non-null values which match the type
+	Confidence float64 `json:"confidence"`
+	// Presence is the ratio of the samples which have the field
+	Presence float64 `json:"presence"`
+	// Ambiguous means the values have conflicting types, the type is inferred from the most common ones
+	Ambiguous bool `json:"ambiguous"`
+	// Types are the count of the sampled values by type including null
+	Types map[string]int `json:"types"`
+}
+
+type fieldStats struct {
+	present int
+	counts  map[string]int
+	// the merged type and the count of the matched values of each type group
+	merged  map[string]ast.FieldType
+	matched map[string]int
+}
+
+// InferSchema infers the field types from the sampled messages. Integers and floats are compatible and inferred as
+// float. The struct fields and the array elements are merged among the samples.
+func InferSchema(samples []map[string]interface{}) *SchemaInference {
+	stats := make(map[string]*fieldStats)
+	for _, sample := range samples {
+		for k, v := range sample {
+			s, ok := stats[k]
+			if !ok {
+				s = &fieldStats{counts: make(map[string]int), merged: make(map[string]ast.FieldType), matched: make(map[string]int)}
+				stats[k] = s
+			}
+			s.present++
+			ft, ok := valueType(v)
+			name := valueTypeName(v, ft)
+			s.counts[name]++
+			if name == "null" || !ok {
+				continue
+			}
+			group := typeGroup(ft)
+			if merged, ok := mergeType(s.merged[group], ft); ok {
+				s.merged[group] = merged
+				s.matched[group]++
+			}
+		}
+	}
+	result := &SchemaInference{Samples: len(samples), Fields: make([]*FieldInference, 0, len(stats))}
+	names := make([]string, 0, len(stats))
+	for k := range stats {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	defs := make([]string, 0, len(names))
+	for _, name := range names {
+		s := stats[name]
+		fi := &FieldInference{
+			Name:     name,
+			Presence: ratio(s.present, len(samples)),
+			Types:    s.counts,
+		}
+		nonNull := s.present - s.counts["null"]
+		// pick the type group with the most matched values
+		var (
+			ft    ast.FieldType
+			best  string
+			total int
+		)
+		groups := make(map[string]int)
+		for tn, c := range s.counts {
+			if tn != "null" {
+				groups[groupOfTypeName(tn)] += c
+			}
+		}
+		for g, c := range s.matched {
+			if c > s.matched[best] || (c == s.matched[best] && g < best) {
+				best = g
+			}
+		}
+		if best != "" {
+			ft = s.merged[best]
+			total = s.matched[best]
+		}
+		if ft == nil {
+			// all values are null or conflicted, read as string
+			ft = &ast.BasicType{Type: ast.STRINGS}
+		}
+		fi.Type = fieldTypeDefinition(ft)
+		fi.Confidence = ratio(total, nonNull)
+		fi.Ambiguous = len(groups) > 1 || total < nonNull || nonNull == 0
+		result.Fields = append(result.Fields, fi)
+		defs = append(defs, quoteFieldName(name)+" "+fi.Type)
+	}
+	result.Definition = strings.Join(defs, ", ")
+	return result
+}
+
+func ratio(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(n)/float64(total)*10000) / 10000
+}
+
+// valueType returns the field type of the value. It returns false if the value has no valid type, such as an array
+// with conflicting elements.
+func valueType(v interface{}) (ast.FieldType, bool) {
+	switch vt := v.(type) {
+	case nil:
+		return nil, true
+	case json.Number:
+		if _, err := vt.Int64(); err == nil {
+			return &ast.BasicType{Type: ast.BIGINT}, true
+		}
+		return &ast.BasicType{Type: ast.FLOAT}, true
+	case float64:
+		if vt == math.Trunc(vt) {
+			return &ast.BasicType{Type: ast.BIGINT}, true
+		}
+		return &ast.BasicType{Type: ast.FLOAT}, true
+	case float32:
+		if vt == float32(math.Trunc(float64(vt))) {
+			return &ast.BasicType{Type: ast.BIGINT}, true
+		}
+		return &ast.BasicType{Type: ast.FLOAT}, true
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return &ast.BasicType{Type: ast.BIGINT}, true
+	case string:
+		return &ast.BasicType{Type: ast.STRINGS}, true
+	case bool:
+		return &ast.BasicType{Type: ast.BOOLEAN}, true
+	case []byte:
+		return &ast.BasicType{Type: ast.BYTEA}, true
+	case time.Time:
+		return &ast.BasicType{Type: ast.DATETIME}, true
+	case map[string]interface{}:
+		rt := &ast.RecType{StreamFields: make(ast.StreamFields, 0, len(vt))}
+		keys := make([]string, 0, len(vt))
+		for k := range vt {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			ft, ok := valueType(vt[k])
+			if !ok {
+				return rt, false
+			}
+			// null fields are not inferred
+			if ft != nil {
+				rt.StreamFields = append(rt.StreamFields, ast.StreamField{Name: k, FieldType: ft})
+			}
+		}
+		return rt, true
+	case []interface{}:
+		var elem ast.FieldType
+		for _, e := range vt {
+			et, ok := valueType(e)
+			if !ok {
+				return nil, false
+			}
+			if elem, ok = mergeType(elem, et); !ok {
+				return nil, false
+			}
+		}
+		return toArrayType(elem), true
+	default:
+		return nil, false
+	}
+}
+
+func valueTypeName(v interface{}, ft ast.FieldType) string {
+	if v == nil {
+		return "null"
+	}
+	if ft != nil {
+		return typeName(ft)
+	}
+	switch v.(type) {
+	case []interface{}:
+		return ast.ARRAY.String()
+	case map[string]interface{}:
+		return ast.STRUCT.String()
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func typeName(ft ast.FieldType) string {
+	switch t := ft.(type) {
+	case *ast.BasicType:
+		return t.Type.String()
+	case *ast.ArrayType:
+		return ast.ARRAY.String()
+	default:
+		return ast.STRUCT.String()
+	}
+}
+
+// typeGroup returns the group of the compatible types
+func typeGroup(ft ast.FieldType) string {
+	return groupOfTypeName(typeName(ft))
+}
+
+func groupOfTypeName(name string) string {
+	if name == ast.FLOAT.String() {
+		return ast.BIGINT.String()
+	}
+	return name
+}
+
+// mergeType merges the types of two values. It returns false if they are conflicted.
+func mergeType(a, b ast.FieldType) (ast.FieldType, bool) {
+	if a == nil {
+		return b, true
+	}
+	if b == nil {
+		return a, true
+	}
+	switch at := a.(type) {
+	case *ast.BasicType:
+		bt, ok := b.(*ast.BasicType)
+		if !ok {
+			return a, false
+		}
+		if at.Type == bt.Type {
+			return a, true
+		}
+		if (at.Type == ast.BIGINT || at.Type == ast.FLOAT) && (bt.Type == ast.BIGINT || bt.Type == ast.FLOAT) {
+			return &ast.BasicType{Type: ast.FLOAT}, true
+		}
+		return a, false
+	case *ast.RecType:
+		bt, ok := b.(*ast.RecType)
+		if !ok {
+			return a, false
+		}
+		return mergeRecType(at, bt)
+	case *ast.ArrayType:
+		bt, ok := b.(*ast.ArrayType)
+		if !ok {
+			return a, false
+		}
+		elem, ok := mergeType(arrayElemType(at), arrayElemType(bt))
+		if !ok {
+			return a, false
+		}
+		return toArrayType(elem), true
+	}
+	return a, false
+}
+
+func mergeRecType(a, b *ast.RecType) (ast.FieldType, bool) {
+	result := &ast.RecType{StreamFields: make(ast.StreamFields, len(a.StreamFields), len(a.StreamFields)+len(b.StreamFields))}
+	copy(result.StreamFields, a.StreamFields)
+	for _, bf := range b.StreamFields {
+		found := false
+		for i, af := range result.StreamFields {
+			if af.Name == bf.Name {
+				ft, ok := mergeType(af.FieldType, bf.FieldType)
+				if !ok {
+					return a, false
+				}
+				result.StreamFields[i].FieldType = ft
+				found = true
+				break
+			}
+		}
+		if !found {
+			result.StreamFields = append(result.StreamFields, bf)
+		}
+	}
+	return result, true
+}
+
+// arrayElemType returns the element type of the array, nil if unknown such as the empty array
+func arrayElemType(at *ast.ArrayType) ast.FieldType {
+	switch {
+	case at.FieldType != nil:
+		return at.FieldType
+	case at.Type == ast.UNKNOWN:
+		return nil
+	default:
+		return &ast.BasicType{Type: at.Type}
+	}
+}
+
+func toArrayType(elem ast.FieldType) *ast.ArrayType {
+	switch et := elem.(type) {
+	case *ast.BasicType:
+		return &ast.ArrayType{Type: et.Type}
+	case *ast.RecType:
+		return &ast.ArrayType{Type: ast.STRUCT, FieldType: et}
+	case *ast.ArrayType:
+		return &ast.ArrayType{Type: ast.ARRAY, FieldType: et}
+	default:
+		return &ast.ArrayType{Type: ast.UNKNOWN}
+	}
+}
+
+var simpleFieldName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+func quoteFieldName(name string) string {
+	// the keywords must be quoted
+	if simpleFieldName.MatchString(name) {
+		if tok, lit := xsql.NewScanner(strings.NewReader(name)).Scan(); tok == ast.IDENT && lit == name {
+			return name
+		}
+	}
+	return "`" + name + "`"
+}
+
+// fieldTypeDefinition prints the field type in the stream definition. The unknown types are read as string.
+func fieldTypeDefinition(ft ast.FieldType) string {
+	switch t := ft.(type) {
+	case *ast.BasicType:
+		return t.Type.String()
+	case *ast.ArrayType:
+		if elem := arrayElemType(t); elem != nil {
+			return "array(" + fieldTypeDefinition(elem) + ")"
+		}
+		return "array(" + ast.STRINGS.String() + ")"
+	case *ast.RecType:
+		return "struct(" + fieldsDefinition(t.StreamFields) + ")"
+	default:
+		return ast.STRINGS.String()
+	}
+}
+
+func fieldsDefinition(fields ast.StreamFields) string {
+	defs := make([]string, 0, len(fields))
+	for _, f := range fields {
+		def := quoteFieldName(f.Name) + " " + fieldTypeDefinition(f.FieldType)
+		if c := printColumnExpr(f); c != "" {
+			def += " " + c
+		}
+		defs = append(defs, def)
+	}
+	return strings.Join(defs, ", ")
+}
+
+// UpdateSchema replaces the fields of a stream or table with the field definitions in place while keeping its name
+// and options. If merge is true, the fields not in the definitions are kept. An empty definition with merge false
+// makes the stream schemaless.
+func (p *StreamProcessor) UpdateSchema(name string, st ast.StreamType, definition string, merge bool) (info string, err error) {
+	defer func() {
+		if err != nil {
+			if _, ok := err.(errorx.ErrorWithCode); !ok {
+				err = errorx.NewWithCode(errorx.StreamTableError, err.Error())
+			}
+		}
+	}()
+	statement, err := p.GetStream(name, st)
+	if err != nil {
+		return "", err
+	}
+	head, with, err := splitStreamStatement(statement)
+	if err != nil {
+		return "", err
+	}
+	newStatement := head + "(" + definition + ") " + with
+	if merge {
+		old, err := parseStreamStmt(statement)
+		if err != nil {
+			return "", err
+		}
+		updated, err := parseStreamStmt(newStatement)
+		if err != nil {
+			return "", fmt.Errorf("invalid field definitions %s: %v", definition, err)
+		}
+		fields := make(ast.StreamFields, 0, len(old.StreamFields)+len(updated.StreamFields))
+		for _, f := range old.StreamFields {
+			if !hasField(updated.StreamFields, f.Name) {
+				fields = append(fields, f)
+			}
+		}
+		fields = append(fields, updated.StreamFields...)
+		newStatement = head + "(" + fieldsDefinition(fields) + ") " + with
+	}
+	return p.ExecReplaceStream(name, newStatement, st)
+}
+
+func parseStreamStmt(statement string) (*ast.StreamStmt, error) {
+	stmt, err := xsql.Language.Parse(xsql.NewParser(strings.NewReader(statement)))
+	if err != nil {
+		return nil, err
+	}
+	s, ok := stmt.(*ast.StreamStmt)
+	if !ok {
+		return nil, fmt.Errorf("invalid stream statement: %s", statement)
+	}
+	return s, nil
+}
+
+func hasField(fields ast.StreamFields, name string) bool {
+	for _, f := range fields {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// splitStreamStatement splits the create statement into the part before the field definitions and the WITH clause
+func splitStreamStatement(statement string) (string, string, error) {
+	var (
+		depth int
+		start = -1
+		quote rune
+	)
+	for i, c := range statement {
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '`', '"', '\'':
+			quote = c
+		case '(':
+			if start < 0 {
+				start = i
+			}
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return statement[:start], strings.TrimSpace(statement[i+1:]), nil
+			}
+		}
+	}
+	return "", "", fmt.Errorf("invalid stream statement: %s", statement)
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+func TestInferSchema(t *testing.T) {
+	samples := []map[string]interface{}{
+		{"id": json.Number("1"), "temp": json.Number("20"), "name": "a", "tags": []interface{}{"x"}, "loc": map[string]interface{}{"lat": 1.5}, "from": "s1"},
+		{"id": json.Number("2"), "temp": json.Number("20.5"), "name": "b", "tags": []interface{}{}, "loc": map[string]interface{}{"lng": 2.0}, "from": true},
+		{"id": json.Number("3"), "temp": nil, "name": "c", "tags": []interface{}{"y", "z"}, "from": "s2", "ok": nil},
+		{"id": json.Number("4"), "name": json.Number("4"), "from": "s3"},
+	}
+	r := InferSchema(samples)
+	assert.Equal(t, 4, r.Samples)
+	assert.Equal(t, []*FieldInference{
+		{Name: "from", Type: "string", Confidence: 0.75, Presence: 1, Ambiguous: true, Types: map[string]int{"string": 3, "boolean": 1}},
+		{Name: "id", Type: "bigint", Confidence: 1, Presence: 1, Types: map[string]int{"bigint": 4}},
+		{Name: "loc", Type: "struct(lat float, lng bigint)", Confidence: 1, Presence: 0.5, Types: map[string]int{"struct": 2}},
+		{Name: "name", Type: "string", Confidence: 0.75, Presence: 1, Ambiguous: true, Types: map[string]int{"string": 3, "bigint": 1}},
+		{Name: "ok", Type: "string", Confidence: 0, Presence: 0.25, Ambiguous: true, Types: map[string]int{"null": 1}},
+		{Name: "tags", Type: "array(string)", Confidence: 1, Presence: 0.75, Types: map[string]int{"array": 3}},
+		{Name: "temp", Type: "float", Confidence: 1, Presence: 0.75, Types: map[string]int{"bigint": 1, "float": 1, "null": 1}},
+	}, r.Fields)
+	assert.Equal(t, "`from` string, id bigint, loc struct(lat float, lng bigint), name string, ok string, tags array(string), temp float", r.Definition)
+	// The inferred definition can create a stream
+	_, err := parseStreamStmt("CREATE STREAM demo (" + r.Definition + `) WITH (DATASOURCE="demo")`)
+	require.NoError(t, err)
+}
+
+func TestUpdateSchema(t *testing.T) {
+	p := NewStreamProcessor()
+	p.db.Clean()
+	defer p.db.Clean()
+	_, err := p.ExecStmt(`CREATE STREAM demoSchema (id bigint, name string) WITH (DATASOURCE="demo(1)", FORMAT="json", TIMESTAMP="ts")`)
+	require.NoError(t, err)
+	tests := []struct {
+		definition string
+		merge      bool
+		statement  string
+		err        string
+	}{
+		{
+			definition: "id float, ts bigint",
+			merge:      true,
+			statement:  `CREATE STREAM demoSchema (name string, id float, ts bigint) WITH (DATASOURCE="demo(1)", FORMAT="json", TIMESTAMP="ts")`,
+		},
+		{
+			definition: "id bigint, ts bigint",
+			statement:  `CREATE STREAM demoSchema (id bigint, ts bigint) WITH (DATASOURCE="demo(1)", FORMAT="json", TIMESTAMP="ts")`,
+		},
+		{
+			definition: "id unknown",
+			err:        "found \"unknown\", expect valid stream field types(BIGINT | FLOAT | STRING | DATETIME | BOOLEAN | BYTEA | ARRAY | STRUCT).",
+		},
+		{
+			definition: "",
+			statement:  `CREATE STREAM demoSchema () WITH (DATASOURCE="demo(1)", FORMAT="json", TIMESTAMP="ts")`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.definition, func(t *testing.T) {
+			_, err := p.UpdateSchema("demoSchema", ast.TypeStream, tt.definition, tt.merge)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			s, err := p.GetStream("demoSchema", ast.TypeStream)
+			require.NoError(t, err)
+			assert.Equal(t, tt.statement, s)
+		})
+	}
+	// the default values and computed expressions are kept when merging
+	_, err = p.UpdateSchema("demoSchema", ast.TypeStream, "id bigint DEFAULT 0, idx bigint AS (id * 10)", false)
+	require.NoError(t, err)
+	_, err = p.UpdateSchema("demoSchema", ast.TypeStream, "name string", true)
+	require.NoError(t, err)
+	s, err := p.GetStream("demoSchema", ast.TypeStream)
+	require.NoError(t, err)
+	assert.Equal(t, `CREATE STREAM demoSchema (id bigint DEFAULT 0, idx bigint AS (id * 10), name string) WITH (DATASOURCE="demo(1)", FORMAT="json", TIMESTAMP="ts")`, s)
+	desc, err := p.DescStream("demoSchema", ast.TypeStream)
+	require.NoError(t, err)
+	assert.Equal(t, "id * 10", desc.(*ast.StreamStmt).StreamFields[1].Computed.Text)
+	_, err = p.UpdateSchema("demoSchema", ast.TypeTable, "id bigint", false)
+	assert.EqualError(t, err, "table demoSchema is not found")
+}
@@ -424,6 +424,27 @@ func TestDescribeToJson(t *testing.T) {
 		"FORMAT:": "JSON",
 		"KEY:": "USERID"
 	}
+}`,
+		},
+		{
+			s: "Fields\n--------------------------------------------------------------------------------\n" +
+				"temp\tfloat\tDEFAULT 0\ntempF\tfloat\tAS (temp * 1.8 + 32)\n\nDATASOURCE: demo\n",
+			r: `{
+	"Fields": [
+		{
+			"Name": "temp",
+			"Type": "float",
+			"Default": "0"
+		},
+		{
+			"Name": "tempF",
+			"Type": "float",
+			"Computed": "temp * 1.8 + 32"
+		}
+	],
+	"Options": {
+		"DATASOURCE:": "demo"
+	}
 }`,
 		},
 	}
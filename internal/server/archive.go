@@ -0,0 +1,357 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/topo/rule"
+	"github.com/lf-edge/ekuiper/internal/topo/state"
+	"github.com/lf-edge/ekuiper/pkg/errorx"
+)
+
+// The archive is a zip file of the whole instance. The manifest has the checksums of all the other files and is
+// signed by the private key if configured.
+const (
+	archiveVersion       = 1
+	archiveManifestFile  = "manifest.json"
+	archiveSignatureFile = "manifest.sig"
+	archiveConfigFile    = "configuration.json"
+	archiveStatesPrefix  = "states/"
+	archiveStateSuffix   = ".snapshot"
+)
+
+type archiveManifest struct {
+	Version int `json:"version"`
+	// CreatedAt is the unix milliseconds when the archive is created
+	CreatedAt int64 `json:"createdAt"`
+	// Files is the sha256 of each file in the archive except the manifest and its signature
+	Files map[string]string `json:"files"`
+}
+
+// archiveOptions selects the parts to restore
+type archiveOptions struct {
+	// categories is the configuration categories to restore, nil for all
+	categories map[string]bool
+	// state restores the rule state snapshots in the archive
+	state bool
+	// partial keeps the existing configuration and only creates or updates the items in the archive
+	partial bool
+}
+
+type archiveImportStatus struct {
+	ImportConfigurationStatus
+	// States is the result of restoring the state of each rule
+	States map[string]string `json:"states,omitempty"`
+}
+
+// archiveExport packs the configuration and optionally the last checkpoint states of the rules
+func archiveExport(withState bool) ([]byte, error) {
+	files := map[string][]byte{}
+	cfg, err := configurationExport()
+	if err != nil {
+		return nil, err
+	}
+	files[archiveConfigFile] = cfg
+	if withState {
+		ids, err := allRuleIds()
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			r, err := ruleProcessor.GetRuleById(id)
+			if err != nil {
+				logger.Warnf("archive rule %s state error: %v", id, err)
+				continue
+			}
+			data, err := state.ExportSnapshot(id, r.Options.StateStore)
+			if err != nil {
+				// The rule without checkpoint has no state
+				if e, ok := err.(errorx.ErrorWithCode); !ok || e.Code() != errorx.NOT_FOUND {
+					logger.Warnf("archive rule %s state error: %v", id, err)
+				}
+				continue
+			}
+			files[archiveStatesPrefix+url.PathEscape(id)+archiveStateSuffix] = data
+		}
+	}
+	m := &archiveManifest{Version: archiveVersion, CreatedAt: time.Now().UnixMilli(), Files: make(map[string]string, len(files))}
+	for name, data := range files {
+		m.Files[name] = checksum(data)
+	}
+	mb, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	files[archiveManifestFile] = mb
+	if key := conf.Config.Archive.GetPrivateKey(); key != nil {
+		files[archiveSignatureFile] = []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(key, mb)))
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(files[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// readArchive returns the files of the archive after verifying the checksums and the signature
+func readArchive(data []byte) (map[string][]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive: %v", err)
+	}
+	files := make(map[string][]byte, len(zr.File))
+	// Limit the extracted size regardless of the sizes in the headers so that a crafted archive cannot exhaust the memory
+	remain := conf.Config.Archive.GetMaxSize()
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive file %s: %v", f.Name, err)
+		}
+		b, err := io.ReadAll(io.LimitReader(rc, remain+1))
+		_ = rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive file %s: %v", f.Name, err)
+		}
+		if int64(len(b)) > remain {
+			return nil, fmt.Errorf("invalid archive: the extracted files exceed the max size %d bytes", conf.Config.Archive.GetMaxSize())
+		}
+		remain -= int64(len(b))
+		files[f.Name] = b
+	}
+	mb, ok := files[archiveManifestFile]
+	if !ok {
+		return nil, errors.New("invalid archive: no manifest")
+	}
+	ac := &conf.Config.Archive
+	if key := ac.GetPublicKey(); key != nil {
+		sig, ok := files[archiveSignatureFile]
+		if !ok {
+			return nil, errors.New("archive is not signed")
+		}
+		sb, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+		if err != nil || !ed25519.Verify(key, mb, sb) {
+			return nil, errors.New("archive signature verification failed")
+		}
+	} else if ac.PublicKey != "" {
+		return nil, errors.New("archive publicKey is invalid")
+	}
+	m := &archiveManifest{}
+	if err := json.Unmarshal(mb, m); err != nil {
+		return nil, fmt.Errorf("invalid archive manifest: %v", err)
+	}
+	if m.Version > archiveVersion {
+		return nil, fmt.Errorf("archive version %d is not supported", m.Version)
+	}
+	delete(files, archiveManifestFile)
+	delete(files, archiveSignatureFile)
+	for name, data := range files {
+		sum, ok := m.Files[name]
+		if !ok {
+			return nil, fmt.Errorf("archive file %s is not in the manifest", name)
+		}
+		if sum != checksum(data) {
+			return nil, fmt.Errorf("archive file %s checksum mismatch", name)
+		}
+	}
+	for name := range m.Files {
+		if _, ok := files[name]; !ok {
+			return nil, fmt.Errorf("archive file %s is missing", name)
+		}
+	}
+	return files, nil
+}
+
+// archiveImport restores the configuration and the rule states selected by the options
+func archiveImport(ctx context.Context, data []byte, opts *archiveOptions) (*archiveImportStatus, error) {
+	files, err := readArchive(data)
+	if err != nil {
+		return nil, err
+	}
+	cfg, ok := files[archiveConfigFile]
+	if !ok {
+		return nil, errors.New("invalid archive: no configuration")
+	}
+	if opts.categories != nil {
+		c := &Configuration{}
+		if err := json.Unmarshal(cfg, c); err != nil {
+			return nil, fmt.Errorf("invalid archive configuration: %v", err)
+		}
+		for _, cc := range configCategories {
+			if !opts.categories[cc.name] {
+				*cc.get(c) = nil
+			}
+		}
+		if cfg, err = json.Marshal(c); err != nil {
+			return nil, err
+		}
+	}
+	status := &archiveImportStatus{}
+	// Only restore the selected categories without resetting the others
+	if opts.partial || opts.categories != nil {
+		status.ImportConfigurationStatus = configurationPartialImport(ctx, cfg)
+	} else {
+		configurationReset()
+		status.ImportConfigurationStatus = configurationImport(ctx, cfg, false)
+	}
+	if status.ErrorMsg != "" {
+		return status, errors.New(status.ErrorMsg)
+	}
+	if opts.state {
+		status.States = restoreArchiveStates(files)
+	}
+	return status, nil
+}
+
+// restoreArchiveStates restores the states of the rules which exist after importing. The running rules are restarted
+// to load the restored states.
+func restoreArchiveStates(files map[string][]byte) map[string]string {
+	result := make(map[string]string)
+	for name, data := range files {
+		if !strings.HasPrefix(name, archiveStatesPrefix) || !strings.HasSuffix(name, archiveStateSuffix) {
+			continue
+		}
+		id, err := url.PathUnescape(strings.TrimSuffix(strings.TrimPrefix(name, archiveStatesPrefix), archiveStateSuffix))
+		if err != nil {
+			continue
+		}
+		if err := restoreRuleState(id, data); err != nil {
+			result[id] = err.Error()
+		} else {
+			result[id] = "restored"
+		}
+	}
+	return result
+}
+
+func restoreRuleState(id string, data []byte) error {
+	r, err := ruleProcessor.GetRuleById(id)
+	if err != nil {
+		return err
+	}
+	running := false
+	if rs, ok := registry.Load(id); ok {
+		if st, err := rs.GetState(); err == nil && st == rule.RuleStarted {
+			running = true
+			// The running rule keeps its states in memory and would overwrite the restored ones
+			if err := rs.Stop(); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := state.RestoreSnapshot(id, r.Options.StateStore, data); err != nil {
+		return err
+	}
+	if running {
+		return startRuleInternal(id)
+	}
+	return nil
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// archiveExportHandler downloads the archive. Set state=1 to include the rule states.
+func archiveExportHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := archiveExport(r.URL.Query().Get("state") == "1")
+	if err != nil {
+		handleError(w, err, "export archive error", logger)
+		return
+	}
+	w.Header().Set(ContentType, "application/zip")
+	w.Header().Set("Content-Disposition", "attachment; filename=ekuiper_archive.zip")
+	http.ServeContent(w, r, "ekuiper_archive.zip", time.Now(), bytes.NewReader(data))
+}
+
+// archiveImportHandler restores the archive in the body. Set state=1 to restore the rule states, partial=1 to keep
+// the existing configuration and categories to restore the listed categories only.
+func archiveImportHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	q := r.URL.Query()
+	opts := &archiveOptions{
+		state:   q.Get("state") == "1",
+		partial: q.Get("partial") == "1",
+	}
+	if c := q.Get("categories"); c != "" {
+		opts.categories = make(map[string]bool)
+		for _, name := range strings.Split(c, ",") {
+			opts.categories[strings.TrimSpace(name)] = true
+		}
+		for name := range opts.categories {
+			if !isConfigCategory(name) {
+				handleError(w, fmt.Errorf("unknown category %s", name), "", logger)
+				return
+			}
+		}
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, conf.Config.Archive.GetMaxSize()))
+	if err != nil {
+		var me *http.MaxBytesError
+		if errors.As(err, &me) {
+			err = fmt.Errorf("the archive exceeds the max size %d bytes", me.Limit)
+		}
+		handleError(w, err, "Invalid body", logger)
+		return
+	}
+	status, err := archiveImport(context.Background(), data, opts)
+	if err != nil {
+		handleError(w, err, "import archive error", logger)
+		return
+	}
+	jsonResponse(status, w, logger)
+}
+
+func isConfigCategory(name string) bool {
+	for _, cc := range configCategories {
+		if cc.name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/meta"
+	"github.com/lf-edge/ekuiper/internal/topo/state"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+func (suite *RestTestSuite) TestArchive() {
+	meta.InitYamlConfigManager()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(suite.T(), err)
+	old := conf.Config.Archive
+	defer func() { conf.Config.Archive = old }()
+	conf.Config.Archive = conf.ArchiveConf{PrivateKey: base64.StdEncoding.EncodeToString(priv), PublicKey: base64.StdEncoding.EncodeToString(pub)}
+	require.NoError(suite.T(), conf.Config.Archive.Validate())
+	request := func(method, url, body string) (int, string) {
+		req, _ := http.NewRequest(method, "http://localhost:8080"+url, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		suite.r.ServeHTTP(w, req)
+		b, _ := io.ReadAll(w.Result().Body)
+		return w.Code, string(b)
+	}
+	defer func() {
+		_ = deleteRule("archiveRule")
+		_, _ = ruleProcessor.ExecDrop("archiveRule")
+		_, _ = streamProcessor.DropStream("archiveDemo", ast.TypeStream)
+	}()
+
+	code, _ := request(http.MethodPost, "/streams", `{"sql":"CREATE STREAM archiveDemo () WITH (TYPE=\"memory\", DATASOURCE=\"archiveTopic\", FORMAT=\"json\")"}`)
+	require.Equal(suite.T(), http.StatusCreated, code)
+	code, _ = request(http.MethodPost, "/rules", `{"id":"archiveRule","triggered":false,"sql":"SELECT count(*) FROM archiveDemo GROUP BY CountWindow(5)","actions":[{"nop":{}}]}`)
+	require.Equal(suite.T(), http.StatusCreated, code)
+	buf := &bytes.Buffer{}
+	require.NoError(suite.T(), gob.NewEncoder(buf).Encode(&state.RuleSnapshot{RuleId: "archiveRule", CheckpointId: 1, States: map[string]interface{}{"op_window": "state1"}}))
+	// The snapshot is saved as the checkpoint of the current time
+	advance := func() {
+		if m, ok := conf.Clock.(*clock.Mock); ok {
+			m.Add(time.Second)
+		}
+	}
+	advance()
+	_, err = state.RestoreSnapshot("archiveRule", "", buf.Bytes())
+	require.NoError(suite.T(), err)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost:8080/data/archive?state=1", nil)
+	w := httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	archive, _ := io.ReadAll(w.Result().Body)
+	files, err := readArchive(archive)
+	require.NoError(suite.T(), err)
+	assert.Contains(suite.T(), files, archiveConfigFile)
+	assert.Contains(suite.T(), files, "states/archiveRule.snapshot")
+
+	// Restore the rule and its state selectively
+	_ = deleteRule("archiveRule")
+	_, _ = ruleProcessor.ExecDrop("archiveRule")
+	advance()
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/data/archive/import?state=1&categories=streams,rules", bytes.NewReader(archive))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	body, _ := io.ReadAll(w.Result().Body)
+	require.Equal(suite.T(), http.StatusOK, w.Code, string(body))
+	assert.Contains(suite.T(), string(body), `"archiveRule":"restored"`)
+	_, err = ruleProcessor.GetRuleJson("archiveRule")
+	require.NoError(suite.T(), err)
+	data, err := state.ExportSnapshot("archiveRule", "")
+	require.NoError(suite.T(), err)
+	snapshot := &state.RuleSnapshot{}
+	require.NoError(suite.T(), gob.NewDecoder(bytes.NewReader(data)).Decode(snapshot))
+	assert.Equal(suite.T(), map[string]interface{}{"op_window": "state1"}, snapshot.States)
+
+	// The tampered archive is rejected
+	tampered := rewriteArchive(suite, archive, archiveConfigFile, []byte(`{"rules":{}}`))
+	_, err = readArchive(tampered)
+	assert.EqualError(suite.T(), err, "archive file configuration.json checksum mismatch")
+
+	// The archive signed by another key is rejected
+	_, other, _ := ed25519.GenerateKey(rand.Reader)
+	conf.Config.Archive.PrivateKey = base64.StdEncoding.EncodeToString(other)
+	require.NoError(suite.T(), conf.Config.Archive.Validate())
+	archive, err = archiveExport(false)
+	require.NoError(suite.T(), err)
+	_, err = readArchive(archive)
+	assert.EqualError(suite.T(), err, "archive signature verification failed")
+
+	// Unknown category
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/data/archive/import?categories=foo", bytes.NewReader(archive))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	// The archive and the extracted files exceeding the max size are rejected
+	conf.Config.Archive.MaxSize = int64(len(archive)) - 1
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/data/archive/import", bytes.NewReader(archive))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	body, _ = io.ReadAll(w.Result().Body)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(suite.T(), string(body), "the archive exceeds the max size")
+	bomb := rewriteArchive(suite, archive, archiveConfigFile, bytes.Repeat([]byte(" "), 1<<20))
+	conf.Config.Archive.MaxSize = int64(len(bomb)) * 2
+	_, err = readArchive(bomb)
+	assert.EqualError(suite.T(), err, fmt.Sprintf("invalid archive: the extracted files exceed the max size %d bytes", len(bomb)*2))
+}
+
+// rewriteArchive replaces the content of a file in the archive
+func rewriteArchive(suite *RestTestSuite, archive []byte, name string, content []byte) []byte {
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(suite.T(), err)
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for _, f := range zr.File {
+		w, err := zw.Create(f.Name)
+		require.NoError(suite.T(), err)
+		if f.Name == name {
+			_, _ = w.Write(content)
+			continue
+		}
+		rc, err := f.Open()
+		require.NoError(suite.T(), err)
+		_, _ = io.Copy(w, rc)
+		_ = rc.Close()
+	}
+	require.NoError(suite.T(), zw.Close())
+	return buf.Bytes()
+}
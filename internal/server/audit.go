@@ -0,0 +1,267 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/felixge/httpsnoop"
+	"github.com/gorilla/mux"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/pkg/audit"
+	"github.com/lf-edge/ekuiper/internal/pkg/namespace"
+	"github.com/lf-edge/ekuiper/internal/pkg/rbac"
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	"github.com/lf-edge/ekuiper/pkg/hidden"
+)
+
+const (
+	// the max size of the request body to record, the larger body such as an uploaded file is not recorded
+	auditBodyLimit = 64 * 1024
+	// the max size of the error response to record
+	auditErrorLimit    = 1024
+	auditPurgeInterval = time.Hour
+)
+
+var (
+	// auditStore is nil if the audit is disabled
+	auditStore *audit.Store
+	// the name of the stream or table to create
+	createStmtRegex = regexp.MustCompile(`(?i)^\s*CREATE\s+(?:STREAM|TABLE)\s+([^\s(]+)`)
+)
+
+func initAudit(exit <-chan struct{}) {
+	c := &conf.Config.Audit
+	if !c.Enable {
+		return
+	}
+	db, err := store.GetKV("audit")
+	if err != nil {
+		logger.Errorf("init audit store error: %v", err)
+		return
+	}
+	auditStore = audit.NewStore(db, c.GetRetention(), c.MaxRecords)
+	go func() {
+		ticker := time.NewTicker(auditPurgeInterval)
+		defer ticker.Stop()
+		for {
+			if n, err := auditStore.Purge(time.Now()); err != nil {
+				logger.Errorf("purge audit records error: %v", err)
+			} else if n > 0 {
+				logger.Infof("purged %d audit records", n)
+			}
+			select {
+			case <-exit:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// auditMiddleware records the write operations with the caller, the request body, the diff of the updated
+// resource and the result
+func auditMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auditStore == nil || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		path := r.URL.Path
+		if route := mux.CurrentRoute(r); route != nil {
+			if tpl, err := route.GetPathTemplate(); err == nil {
+				path = tpl
+			}
+		}
+		vars := mux.Vars(r)
+		rec := &audit.Record{
+			Timestamp: time.Now().UnixMilli(),
+			User:      "anonymous",
+			Action:    audit.ParseAction(r.Method, path),
+			Resource:  audit.ParseResource(path),
+			Method:    r.Method,
+			Path:      r.URL.Path,
+		}
+		if id := rbac.IdentityFrom(r.Context()); id != nil {
+			rec.User = id.Name
+			rec.Role = id.Role.String()
+		}
+		body := auditBody(r)
+		if body != nil {
+			rec.Body = body
+		}
+		if name := vars["name"]; name != "" {
+			rec.Name = namespace.Qualify(vars["namespace"], name)
+		} else if body != nil {
+			for _, k := range []string{"id", "name"} {
+				if n, ok := body[k].(string); ok {
+					rec.Name = namespace.Qualify(vars["namespace"], n)
+					break
+				}
+			}
+			if sql, ok := body["sql"].(string); ok && rec.Name == "" {
+				if m := createStmtRegex.FindStringSubmatch(sql); m != nil {
+					rec.Name = namespace.Qualify(vars["namespace"], m[1])
+				}
+			}
+		}
+		if r.Method == http.MethodPut && body != nil {
+			if old := auditDefinition(r, rec.Resource); old != nil {
+				rec.Diff = audit.Diff(old, body)
+			}
+		}
+
+		status := http.StatusOK
+		var errBody bytes.Buffer
+		ww := httpsnoop.Wrap(w, httpsnoop.Hooks{
+			WriteHeader: func(next httpsnoop.WriteHeaderFunc) httpsnoop.WriteHeaderFunc {
+				return func(code int) {
+					status = code
+					next(code)
+				}
+			},
+			Write: func(next httpsnoop.WriteFunc) httpsnoop.WriteFunc {
+				return func(b []byte) (int, error) {
+					if status >= http.StatusBadRequest && errBody.Len() < auditErrorLimit {
+						errBody.Write(b[:min(len(b), auditErrorLimit-errBody.Len())])
+					}
+					return next(b)
+				}
+			},
+		})
+		next.ServeHTTP(ww, r)
+
+		rec.Status = status
+		if status < http.StatusBadRequest {
+			rec.Result = audit.ResultSuccess
+		} else {
+			rec.Result = audit.ResultFailure
+			rec.Error = auditErrorMessage(errBody.Bytes())
+		}
+		if err := auditStore.Add(rec); err != nil {
+			logger.Errorf("save audit record error: %v", err)
+		}
+	})
+}
+
+// auditBody returns the json object in the request body with the passwords hidden, and restores the body for the handler
+func auditBody(r *http.Request) map[string]interface{} {
+	if r.Body == nil || strings.HasPrefix(r.Header.Get(ContentType), "multipart/") {
+		return nil
+	}
+	bs, err := io.ReadAll(io.LimitReader(r.Body, auditBodyLimit+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(bs), r.Body), r.Body}
+	if err != nil || len(bs) > auditBodyLimit {
+		return nil
+	}
+	m := make(map[string]interface{})
+	if err := json.Unmarshal(bs, &m); err != nil {
+		return nil
+	}
+	return hidden.HiddenPassword(m)
+}
+
+// auditDefinition returns the current definition of the rule, stream or table to update in the same format of the body
+func auditDefinition(r *http.Request, resource string) map[string]interface{} {
+	switch resource {
+	case "rule":
+		content, err := ruleProcessor.GetRuleJson(requestRuleId(r))
+		if err != nil {
+			return nil
+		}
+		m := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(content), &m); err != nil {
+			return nil
+		}
+		return hidden.HiddenPassword(m)
+	case "stream", "table":
+		sp, err := requestStreamProcessor(r)
+		if err != nil {
+			return nil
+		}
+		st := ast.TypeStream
+		if resource == "table" {
+			st = ast.TypeTable
+		}
+		stmt, err := sp.GetStream(mux.Vars(r)["name"], st)
+		if err != nil {
+			return nil
+		}
+		return map[string]interface{}{"sql": stmt}
+	default:
+		return nil
+	}
+}
+
+func auditErrorMessage(body []byte) string {
+	e := struct {
+		Message string `json:"message"`
+	}{}
+	if err := json.Unmarshal(body, &e); err == nil && e.Message != "" {
+		return e.Message
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// query the audit records by the user, resource, name, action, result and the time range
+func auditHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	if auditStore == nil {
+		handleError(w, fmt.Errorf("audit is not enabled"), "", logger)
+		return
+	}
+	values := r.URL.Query()
+	q := &audit.Query{
+		User:     values.Get("user"),
+		Resource: values.Get("resource"),
+		Name:     values.Get("name"),
+		Action:   values.Get("action"),
+		Result:   values.Get("result"),
+	}
+	var err error
+	for k, p := range map[string]*int64{"since": &q.Since, "until": &q.Until} {
+		if v := values.Get(k); v != "" {
+			if *p, err = strconv.ParseInt(v, 10, 64); err != nil {
+				handleError(w, fmt.Errorf("invalid %s %s, must be unix milliseconds", k, v), "", logger)
+				return
+			}
+		}
+	}
+	if v := values.Get("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil {
+			handleError(w, fmt.Errorf("invalid limit %s", v), "", logger)
+			return
+		}
+	}
+	records, err := auditStore.Query(q)
+	if err != nil {
+		handleError(w, err, "query audit records error", logger)
+		return
+	}
+	jsonResponse(records, w, logger)
+}
@@ -0,0 +1,68 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build broker || !core
+
+package server
+
+import (
+	"fmt"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/pkg/broker"
+)
+
+func init() {
+	c := &brokerComp{}
+	components["broker"] = c
+	servers["broker"] = c
+}
+
+type brokerComp struct {
+	b *broker.Broker
+}
+
+// register starts the broker before the rules are recovered so that the rules can connect to it
+func (c *brokerComp) register() {
+	if !conf.Config.Broker.Enable {
+		return
+	}
+	b, err := broker.New(&conf.Config.Broker)
+	if err != nil {
+		logger.Fatalf("Start embedded mqtt broker error: %v", err)
+	}
+	c.b = b
+	msg := fmt.Sprintf("Serving embedded mqtt broker on %s", conf.Config.Broker.Address)
+	logger.Infof(msg)
+	fmt.Println(msg)
+}
+
+func (c *brokerComp) rest(_ *mux.Router) {
+	// do nothing
+}
+
+func (c *brokerComp) serve() {
+	// started in register
+}
+
+func (c *brokerComp) close() {
+	if c.b != nil {
+		if err := c.b.Close(); err != nil {
+			logger.Warnf("Close embedded mqtt broker error: %v", err)
+		}
+		c.b = nil
+	}
+}
@@ -0,0 +1,395 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/pkg/cluster"
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/errorx"
+)
+
+// ruleCluster is nil if the cluster mode is disabled
+var ruleCluster *clusterManager
+
+// clusterManager runs the rules placed on this node. The rules are saved in the shared store so that they are
+// created, updated, started, stopped and deleted by any node. It reconciles the rules in the registry with the
+// store and the placement in each heartbeat. The rules placed on this node run only while this node holds their leases.
+type clusterManager struct {
+	c *cluster.Cluster
+	// status is the shared store of the partition status which expires after the node timeout
+	status cluster.Store
+
+	mu sync.Mutex
+	// owned is whether the rule is placed on this node when it was applied
+	owned map[string]bool
+	// applied is the rule json when it was applied
+	applied map[string]string
+	// partitioned is the partitioned rules whose partitions are placed on each sync
+	partitioned map[string]*api.Rule
+}
+
+type clusterNode struct {
+	*cluster.Node
+	Rules []string `json:"rules"`
+}
+
+// initCluster joins the cluster before starting the rules so that only the rules placed on this node are started
+func initCluster() error {
+	c := &conf.Config.Cluster
+	if !c.Enable {
+		return nil
+	}
+	if err := c.Validate(conf.Config.Store.Type); err != nil {
+		return err
+	}
+	db, err := store.GetKV("cluster")
+	if err != nil {
+		return fmt.Errorf("init cluster store error: %v", err)
+	}
+	ldb, err := store.GetKV("clusterLease")
+	if err != nil {
+		return fmt.Errorf("init cluster store error: %v", err)
+	}
+	sdb, err := store.GetKV("clusterPartition")
+	if err != nil {
+		return fmt.Errorf("init cluster store error: %v", err)
+	}
+	cp, err := cluster.NewKVControlPlane(db, ldb)
+	if err != nil {
+		return err
+	}
+	status, ok := sdb.(cluster.Store)
+	if !ok {
+		return fmt.Errorf("the store does not expire the keys, use the shared store such as redis")
+	}
+	m := &clusterManager{
+		c:           cluster.New(c.NodeId, c.Labels, cp, c.GetNodeTimeout()),
+		status:      status,
+		owned:       make(map[string]bool),
+		applied:     make(map[string]string),
+		partitioned: make(map[string]*api.Rule),
+	}
+	if _, err := m.c.Sync(time.Now()); err != nil {
+		logger.Errorf("join cluster error: %v", err)
+	}
+	logger.Infof("joined cluster as node %s with %d live nodes", m.c.Self(), len(m.c.Alive()))
+	ruleCluster = m
+	return nil
+}
+
+func runCluster(exit <-chan struct{}) {
+	if ruleCluster == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(conf.Config.Cluster.GetHeartbeatInterval())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-exit:
+				return
+			case <-ticker.C:
+				changed, err := ruleCluster.c.Sync(time.Now())
+				if err != nil {
+					logger.Errorf("cluster heartbeat error: %v", err)
+				}
+				if changed {
+					logger.Infof("cluster live nodes changed to %d nodes", len(ruleCluster.c.Alive()))
+				}
+				ruleCluster.reconcile()
+				ruleCluster.publishPartitions(time.Now())
+			}
+		}
+	}()
+}
+
+// leaveCluster removes this node from the cluster when shutting down so that its rules fail over immediately. The rules
+// and partitions are stopped before their leases are released so that they never run on two nodes.
+func leaveCluster() {
+	if ruleCluster == nil {
+		return
+	}
+	ruleCluster.mu.Lock()
+	var ids []string
+	for id, owned := range ruleCluster.owned {
+		if owned {
+			ids = append(ids, id)
+		}
+	}
+	ruleCluster.mu.Unlock()
+	for _, id := range ids {
+		if rs, ok := registry.Load(id); ok {
+			if err := rs.Stop(); err != nil {
+				logger.Warnf("stop rule %s error: %v", id, err)
+			}
+		}
+		ruleCluster.release(id)
+	}
+	partitionInstances.RLock()
+	pids := make([]string, 0, len(partitionInstances.internal))
+	for id := range partitionInstances.internal {
+		pids = append(pids, id)
+	}
+	partitionInstances.RUnlock()
+	for _, id := range pids {
+		dropPartition(id)
+		ruleCluster.release(id)
+	}
+	if err := ruleCluster.c.Leave(); err != nil {
+		logger.Warnf("leave cluster error: %v", err)
+	}
+}
+
+// localRule returns the rule to apply on this node. The rule placed on other nodes is not triggered locally, neither
+// the rule whose lease is still held by its previous node. The partitioned rule never runs by itself but runs by its
+// partitions.
+func localRule(r *api.Rule) *api.Rule {
+	local := true
+	if ruleCluster != nil {
+		local = ruleCluster.isOwner(r) && (isPartitioned(r) || !r.Triggered || ruleCluster.acquire(r.Id))
+		ruleCluster.mu.Lock()
+		ruleCluster.owned[r.Id] = local
+		ruleCluster.mu.Unlock()
+	}
+	if isPartitioned(r) {
+		local = false
+	}
+	if local || !r.Triggered {
+		return r
+	}
+	c := *r
+	c.Triggered = false
+	return &c
+}
+
+// ruleOwner returns the node to run the rule, or empty if the cluster mode is disabled
+func ruleOwner(r *api.Rule) string {
+	if ruleCluster == nil || r == nil {
+		return ""
+	}
+	var selector map[string]string
+	if r.Options != nil {
+		selector = r.Options.NodeSelector
+	}
+	return ruleCluster.c.Owner(r.Id, selector)
+}
+
+func (m *clusterManager) isOwner(r *api.Rule) bool {
+	return ruleOwner(r) == m.c.Self()
+}
+
+// acquire takes or renews the lease of the rule or partition to run it on this node
+func (m *clusterManager) acquire(id string) bool {
+	ok, err := m.c.Acquire(id)
+	if err != nil {
+		logger.Warnf("acquire lease of %s error: %v", id, err)
+		return false
+	}
+	if !ok {
+		logger.Infof("lease of %s is held by another node, wait for it to be released", id)
+	}
+	return ok
+}
+
+// release drops the lease of the rule or partition after it stops on this node
+func (m *clusterManager) release(id string) {
+	if err := m.c.Release(id); err != nil {
+		logger.Warnf("release lease of %s error: %v", id, err)
+	}
+}
+
+// acquireLease is whether the partition can run on this node
+func acquireLease(id string) bool {
+	return ruleCluster == nil || ruleCluster.acquire(id)
+}
+
+func releaseLease(id string) {
+	if ruleCluster != nil {
+		ruleCluster.release(id)
+	}
+}
+
+// renewLeases renews the leases of the rules running on this node. The rule whose lease is lost is applied again so
+// that it stops.
+func (m *clusterManager) renewLeases() {
+	m.mu.Lock()
+	var ids []string
+	for id, owned := range m.owned {
+		if owned {
+			ids = append(ids, id)
+		}
+	}
+	m.mu.Unlock()
+	for _, id := range ids {
+		rs, ok := registry.Load(id)
+		if !ok || !rs.Rule.Triggered || isPartitioned(rs.Rule) {
+			continue
+		}
+		if !m.acquire(id) {
+			m.mu.Lock()
+			delete(m.applied, id)
+			m.mu.Unlock()
+		}
+	}
+}
+
+func (m *clusterManager) reconcile() {
+	m.renewLeases()
+	ids, err := allRuleIds()
+	if err != nil {
+		logger.Errorf("cluster reconcile rules error: %v", err)
+		return
+	}
+	existed := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		existed[id] = struct{}{}
+		if err := m.reconcileRule(id); err != nil {
+			logger.Errorf("cluster reconcile rule %s error: %v", id, err)
+		}
+	}
+	// Place the partitions again as the live nodes may change
+	m.mu.Lock()
+	partitioned := make([]*api.Rule, 0, len(m.partitioned))
+	for _, r := range m.partitioned {
+		partitioned = append(partitioned, r)
+	}
+	m.mu.Unlock()
+	for _, r := range partitioned {
+		applyPartitions(r)
+	}
+	// Drop the rules deleted by other nodes
+	registry.RLock()
+	var deleted []string
+	for id := range registry.internal {
+		if _, ok := existed[id]; !ok {
+			deleted = append(deleted, id)
+		}
+	}
+	registry.RUnlock()
+	for _, id := range deleted {
+		logger.Info(deleteRule(id))
+		m.release(id)
+		m.mu.Lock()
+		delete(m.owned, id)
+		delete(m.applied, id)
+		delete(m.partitioned, id)
+		m.mu.Unlock()
+	}
+}
+
+func (m *clusterManager) reconcileRule(id string) error {
+	ruleJson, err := ruleProcessor.GetRuleJson(id)
+	if err != nil {
+		return err
+	}
+	rs, ok := registry.Load(id)
+	m.mu.Lock()
+	applied, wasOwned := m.applied[id], m.owned[id]
+	m.mu.Unlock()
+	if ok && applied == ruleJson && m.isOwner(rs.Rule) == wasOwned {
+		return nil
+	}
+	r, err := ruleProcessor.GetRuleById(id)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.applied[id] = ruleJson
+	if isPartitioned(r) {
+		m.partitioned[id] = r
+	} else {
+		delete(m.partitioned, id)
+	}
+	m.mu.Unlock()
+	if !ok {
+		// Created by other nodes
+		logger.Info(recoverRule(r))
+		return nil
+	}
+	// The triggered status of the rule in the registry is whether it runs on this node
+	lr := localRule(r)
+	if sameRule(rs.Rule, lr) && rs.Rule.Triggered == lr.Triggered {
+		return nil
+	}
+	if lr.Triggered {
+		logger.Infof("cluster runs rule %s on this node", id)
+	} else {
+		logger.Infof("cluster stops rule %s on this node, it is placed on node %s", id, ruleOwner(r))
+	}
+	running := rs.Rule.Triggered
+	err = rs.UpdateTopo(lr)
+	if running && !lr.Triggered {
+		// Release after the rule stops so that the new owner starts it without overlap
+		m.release(id)
+	}
+	return err
+}
+
+// sameRule compares the rules except the triggered status
+func sameRule(a, b *api.Rule) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	ac, bc := *a, *b
+	ac.Triggered, bc.Triggered = false, false
+	return reflect.DeepEqual(ac, bc)
+}
+
+// clusterHandler lists the live nodes and the rules placed on them
+func clusterHandler(w http.ResponseWriter, r *http.Request) {
+	if ruleCluster == nil {
+		handleError(w, errorx.NewWithCode(errorx.NOT_FOUND, "cluster mode is disabled"), "", logger)
+		return
+	}
+	nodes := ruleCluster.c.Alive()
+	result := make([]*clusterNode, len(nodes))
+	index := make(map[string]*clusterNode, len(nodes))
+	for i, n := range nodes {
+		result[i] = &clusterNode{Node: n, Rules: []string{}}
+		index[n.Id] = result[i]
+	}
+	ids, err := allRuleIds()
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	for _, id := range ids {
+		rs, ok := registry.Load(id)
+		if !ok {
+			continue
+		}
+		if isPartitioned(rs.Rule) {
+			for p := 0; p < rs.Rule.Options.Partitions; p++ {
+				if n, ok := index[partitionOwner(rs.Rule, p)]; ok {
+					n.Rules = append(n.Rules, partitionId(id, p))
+				}
+			}
+		} else if n, ok := index[ruleOwner(rs.Rule)]; ok {
+			n.Rules = append(n.Rules, id)
+		}
+	}
+	jsonResponse(map[string]any{
+		"self":  ruleCluster.c.Self(),
+		"nodes": result,
+	}, w, logger)
+}
@@ -0,0 +1,152 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build redisdb || !core
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/pkg/cluster"
+	redisStore "github.com/lf-edge/ekuiper/internal/pkg/store/redis"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+func (suite *RestTestSuite) TestCluster() {
+	mr, err := miniredis.Run()
+	require.NoError(suite.T(), err)
+	defer mr.Close()
+	builder := redisStore.NewStoreBuilder(goredis.NewClient(&goredis.Options{Addr: mr.Addr()}))
+	db, err := builder.CreateStore("clusterTest")
+	require.NoError(suite.T(), err)
+	ldb, err := builder.CreateStore("clusterLeaseTest")
+	require.NoError(suite.T(), err)
+	cp, err := cluster.NewKVControlPlane(db, ldb)
+	require.NoError(suite.T(), err)
+	timeout := 10 * time.Second
+	self := cluster.New("self", nil, cp, timeout)
+	peer := cluster.New("peer", nil, cp, timeout)
+	ruleCluster = &clusterManager{c: self, owned: make(map[string]bool), applied: make(map[string]string)}
+	defer func() {
+		ruleCluster = nil
+	}()
+	request := func(method, url, body string) (int, string) {
+		req, _ := http.NewRequest(method, "http://localhost:8080"+url, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		suite.r.ServeHTTP(w, req)
+		returnVal, _ := io.ReadAll(w.Result().Body)
+		return w.Code, string(returnVal)
+	}
+	now := time.Now()
+	_, err = peer.Sync(now)
+	require.NoError(suite.T(), err)
+	_, err = self.Sync(now)
+	require.NoError(suite.T(), err)
+	// Find a rule placed on each node
+	var localId, remoteId string
+	for i := 0; localId == "" || remoteId == ""; i++ {
+		id := fmt.Sprintf("clusterRule%d", i)
+		if self.IsOwner(id, nil) {
+			localId = id
+		} else {
+			remoteId = id
+		}
+	}
+
+	code, _ := request(http.MethodPost, "/streams", `{"sql":"CREATE STREAM clusterDemo () WITH (TYPE=\"memory\", DATASOURCE=\"clusterTopic\", FORMAT=\"json\")"}`)
+	require.Equal(suite.T(), http.StatusCreated, code)
+	for _, id := range []string{localId, remoteId} {
+		code, body := request(http.MethodPost, "/rules", fmt.Sprintf(`{"id":"%s","sql":"SELECT * FROM clusterDemo","actions":[{"nop":{}}]}`, id))
+		require.Equal(suite.T(), http.StatusCreated, code, body)
+		defer deleteRule(id)
+		defer ruleProcessor.ExecDrop(id)
+	}
+	defer streamProcessor.DropStream("clusterDemo", ast.TypeStream)
+	status := func(id string) map[string]any {
+		code, body := request(http.MethodGet, "/rules/"+id+"/status", "")
+		require.Equal(suite.T(), http.StatusOK, code)
+		result := make(map[string]any)
+		require.NoError(suite.T(), json.Unmarshal([]byte(body), &result))
+		return result
+	}
+	assert.Eventually(suite.T(), func() bool {
+		return status(localId)["status"] == "running"
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(suite.T(), map[string]any{"status": "remote", "node": "peer", "message": "Rule is placed on node peer."}, status(remoteId))
+
+	code, body := request(http.MethodGet, "/cluster/nodes", "")
+	require.Equal(suite.T(), http.StatusOK, code)
+	var nodes struct {
+		Self  string `json:"self"`
+		Nodes []struct {
+			Id    string   `json:"id"`
+			Rules []string `json:"rules"`
+		} `json:"nodes"`
+	}
+	require.NoError(suite.T(), json.Unmarshal([]byte(body), &nodes))
+	assert.Equal(suite.T(), "self", nodes.Self)
+	require.Len(suite.T(), nodes.Nodes, 2)
+	assert.Equal(suite.T(), "peer", nodes.Nodes[0].Id)
+	assert.Contains(suite.T(), nodes.Nodes[0].Rules, remoteId)
+	assert.Contains(suite.T(), nodes.Nodes[1].Rules, localId)
+
+	// The peer dies and expires in the store, its rule fails over
+	mr.FastForward(timeout)
+	_, err = self.Sync(now.Add(2 * timeout))
+	require.NoError(suite.T(), err)
+	ruleCluster.reconcile()
+	assert.Eventually(suite.T(), func() bool {
+		return status(remoteId)["status"] == "running"
+	}, time.Second, 10*time.Millisecond)
+
+	// The peer comes back and takes over its rule
+	_, err = peer.Sync(now.Add(2 * timeout))
+	require.NoError(suite.T(), err)
+	_, err = self.Sync(now.Add(2 * timeout))
+	require.NoError(suite.T(), err)
+	ruleCluster.reconcile()
+	assert.Equal(suite.T(), "remote", status(remoteId)["status"])
+	assert.Equal(suite.T(), "running", status(localId)["status"])
+	// The lease is released after the rule stops so that the peer starts it
+	ok, err := peer.Acquire(remoteId)
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), ok)
+	ok, err = peer.Acquire(localId)
+	require.NoError(suite.T(), err)
+	assert.False(suite.T(), ok)
+
+	// The rule stopped by the peer is stopped in this node
+	_, err = ruleProcessor.ExecReplaceRuleState(localId, false)
+	require.NoError(suite.T(), err)
+	ruleCluster.reconcile()
+	assert.Equal(suite.T(), "stopped", status(localId)["status"])
+
+	code, _ = request(http.MethodGet, "/cluster/nodes", "")
+	require.Equal(suite.T(), http.StatusOK, code)
+	ruleCluster = nil
+	code, _ = request(http.MethodGet, "/cluster/nodes", "")
+	require.Equal(suite.T(), http.StatusNotFound, code)
+}
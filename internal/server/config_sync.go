@@ -0,0 +1,417 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/pkg/httpx"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	"github.com/lf-edge/ekuiper/pkg/errorx"
+)
+
+// configSync is nil if the remote configuration sync is disabled
+var configSync *configSyncer
+
+// configSyncer pulls the bundle from the remote endpoint and reconciles the local configuration to match it. The
+// bundle is in the format of the data export, so the bundle can be made by exporting a configured node.
+type configSyncer struct {
+	c *conf.SyncConf
+	// fetch reads the content of the url
+	fetch func(url string) ([]byte, error)
+	// pull updates the source before fetching the bundle, such as pulling the git repo. Nil means nothing to update
+	pull func(ctx context.Context) error
+
+	// runMu makes sure only one sync runs at the same time
+	runMu  sync.Mutex
+	mu     sync.RWMutex
+	status *syncStatus
+}
+
+type syncStatus struct {
+	Url string `json:"url"`
+	// Repo is the git repo of the bundle if the bundle is pulled from git
+	Repo string `json:"repo,omitempty"`
+	// LastSync is the unix milliseconds of the last sync
+	LastSync int64 `json:"lastSync"`
+	// Revision is the sha256 of the last fetched bundle
+	Revision string `json:"revision,omitempty"`
+	DryRun   bool   `json:"dryRun"`
+	// InSync is whether the local configuration matches the bundle after the last sync
+	InSync bool   `json:"inSync"`
+	Error  string `json:"error,omitempty"`
+	// Drift is the difference of the local configuration from the bundle before applying
+	Drift configDrift `json:"drift,omitempty"`
+	// Failed is the items which fail to apply by category
+	Failed map[string]map[string]string `json:"failed,omitempty"`
+}
+
+// itemDrift is the difference of a category such as the rules
+type itemDrift struct {
+	// Missing is the items in the bundle but not in the local
+	Missing []string `json:"missing,omitempty"`
+	// Changed is the items whose content is different from the bundle
+	Changed []string `json:"changed,omitempty"`
+	// Extra is the local items which are not in the bundle
+	Extra []string `json:"extra,omitempty"`
+}
+
+type configDrift map[string]*itemDrift
+
+// syncCategories is the parts of the configuration to sync. The uploaded files are the last and not synced.
+var syncCategories = configCategories[:len(configCategories)-1]
+
+// initConfigSync returns error if the sync is enabled but not configured properly. The bundle can install plugins
+// and rules, so the sync never runs without the key to verify the bundle.
+func initConfigSync() error {
+	c := &conf.Config.Sync
+	if !c.Enable {
+		return nil
+	}
+	if err := c.Validate(); err != nil {
+		return fmt.Errorf("invalid sync configuration: %v", err)
+	}
+	if c.Git.Repo == "" {
+		configSync = newConfigSyncer(c, readUrl)
+		return nil
+	}
+	dataDir, err := conf.GetDataLoc()
+	if err != nil {
+		return err
+	}
+	g := &gitRepo{c: &c.Git, dir: filepath.Join(dataDir, "sync")}
+	configSync = newConfigSyncer(c, g.read)
+	configSync.pull = g.pull
+	return nil
+}
+
+func newConfigSyncer(c *conf.SyncConf, fetch func(url string) ([]byte, error)) *configSyncer {
+	return &configSyncer{
+		c:      c,
+		fetch:  fetch,
+		status: &syncStatus{Url: c.Url, Repo: c.Git.Repo, DryRun: c.DryRun},
+	}
+}
+
+func runConfigSync(exit <-chan struct{}) {
+	if configSync == nil {
+		return
+	}
+	go func() {
+		configSync.sync(context.Background())
+		ticker := time.NewTicker(configSync.c.GetInterval())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-exit:
+				return
+			case <-ticker.C:
+				configSync.sync(context.Background())
+			}
+		}
+	}()
+}
+
+func readUrl(url string) ([]byte, error) {
+	reader, err := httpx.ReadFile(url)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// gitRepo keeps a shallow clone of the repo in the data folder and reads the bundle from the checked out branch
+type gitRepo struct {
+	c   *conf.SyncGitConf
+	dir string
+}
+
+// pull clones the repo at the first time and then fetches the latest commit of the branch
+func (g *gitRepo) pull(ctx context.Context) error {
+	if _, err := os.Stat(filepath.Join(g.dir, ".git")); err != nil {
+		if err := os.RemoveAll(g.dir); err != nil {
+			return err
+		}
+		args := []string{"clone", "--depth", "1"}
+		if g.c.Branch != "" {
+			args = append(args, "--branch", g.c.Branch)
+		}
+		return runGit(ctx, "", append(args, g.c.Repo, g.dir)...)
+	}
+	ref := g.c.Branch
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if err := runGit(ctx, g.dir, "fetch", "--depth", "1", "origin", ref); err != nil {
+		return err
+	}
+	return runGit(ctx, g.dir, "reset", "--hard", "FETCH_HEAD")
+}
+
+// read returns the file of the path in the repo. The path is validated to be local in the configuration.
+func (g *gitRepo) read(path string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(g.dir, path))
+}
+
+func runGit(ctx context.Context, dir string, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s error: %v, %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// sync pulls the bundle and reconciles the local configuration. The status is updated whether it succeeds or not.
+func (s *configSyncer) sync(ctx context.Context) *syncStatus {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+	st := &syncStatus{Url: s.c.Url, Repo: s.c.Git.Repo, DryRun: s.c.DryRun, LastSync: time.Now().UnixMilli()}
+	if err := s.reconcile(ctx, st); err != nil {
+		logger.Errorf("sync configuration from %s error: %v", s.c.Url, err)
+		st.Error = err.Error()
+	}
+	s.mu.Lock()
+	s.status = st
+	s.mu.Unlock()
+	return st
+}
+
+func (s *configSyncer) reconcile(ctx context.Context, st *syncStatus) error {
+	if s.pull != nil {
+		if err := s.pull(ctx); err != nil {
+			return fmt.Errorf("pull bundle error: %v", err)
+		}
+	}
+	bundle, err := s.fetch(s.c.Url)
+	if err != nil {
+		return fmt.Errorf("fetch bundle error: %v", err)
+	}
+	sum := sha256.Sum256(bundle)
+	st.Revision = hex.EncodeToString(sum[:])
+	if err := s.verify(bundle); err != nil {
+		return err
+	}
+	desired := &Configuration{}
+	if err := json.Unmarshal(bundle, desired); err != nil {
+		return fmt.Errorf("invalid bundle: %v", err)
+	}
+	local, err := localConfiguration()
+	if err != nil {
+		return err
+	}
+	st.Drift = diffConfiguration(desired, local)
+	if len(st.Drift) == 0 {
+		st.InSync = true
+		return nil
+	}
+	logger.Infof("configuration drifts from bundle %s: %s", st.Revision, st.Drift)
+	if s.c.DryRun {
+		return nil
+	}
+	st.Failed = s.apply(ctx, desired, st.Drift)
+	st.InSync = len(st.Failed) == 0
+	return nil
+}
+
+// verify checks the signature of the bundle. The bundle without a valid signature is never applied.
+func (s *configSyncer) verify(bundle []byte) error {
+	key := s.c.GetPublicKey()
+	if key == nil {
+		return errors.New("no public key to verify the bundle")
+	}
+	sig, err := s.fetch(s.c.SignatureUrl)
+	if err != nil {
+		return fmt.Errorf("fetch bundle signature error: %v", err)
+	}
+	sigBytes, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil {
+		return fmt.Errorf("invalid bundle signature: %v", err)
+	}
+	if !ed25519.Verify(key, bundle, sigBytes) {
+		return errors.New("bundle signature verification failed")
+	}
+	return nil
+}
+
+// apply imports the missing and changed items and deletes the extra rules, streams and tables if prune is set
+func (s *configSyncer) apply(ctx context.Context, desired *Configuration, drift configDrift) map[string]map[string]string {
+	partial := &Configuration{}
+	count := 0
+	for _, sc := range syncCategories {
+		d, ok := drift[sc.name]
+		if !ok {
+			continue
+		}
+		src := *sc.get(desired)
+		m := make(map[string]string, len(d.Missing)+len(d.Changed))
+		for _, k := range d.Missing {
+			m[k] = src[k]
+		}
+		for _, k := range d.Changed {
+			m[k] = src[k]
+		}
+		*sc.get(partial) = m
+		count += len(m)
+	}
+	failed := make(map[string]map[string]string)
+	// Only the extra items drift
+	if count > 0 {
+		data, err := json.Marshal(partial)
+		if err != nil {
+			failed["bundle"] = map[string]string{"": err.Error()}
+			return failed
+		}
+		result := configurationPartialImport(ctx, data)
+		for _, sc := range syncCategories {
+			if m := *sc.get(&result.ConfigResponse); len(m) > 0 {
+				failed[sc.name] = m
+			}
+		}
+	}
+	if s.c.Prune {
+		s.prune(drift, failed)
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return failed
+}
+
+// prune deletes the extra rules before the streams and tables which they may refer to
+func (s *configSyncer) prune(drift configDrift, failed map[string]map[string]string) {
+	addFailed := func(category, name string, err error) {
+		if failed[category] == nil {
+			failed[category] = make(map[string]string)
+		}
+		failed[category][name] = err.Error()
+	}
+	if d, ok := drift["rules"]; ok {
+		for _, name := range d.Extra {
+			logger.Info(deleteRule(name))
+			if _, err := ruleProcessor.ExecDrop(name); err != nil {
+				addFailed("rules", name, err)
+			}
+		}
+	}
+	for category, st := range map[string]ast.StreamType{"streams": ast.TypeStream, "tables": ast.TypeTable} {
+		if d, ok := drift[category]; ok {
+			for _, name := range d.Extra {
+				if _, err := streamProcessor.DropStream(name, st); err != nil {
+					addFailed(category, name, err)
+				}
+			}
+		}
+	}
+}
+
+func localConfiguration() (*Configuration, error) {
+	data, err := configurationExport()
+	if err != nil {
+		return nil, err
+	}
+	local := &Configuration{}
+	if err := json.Unmarshal(data, local); err != nil {
+		return nil, err
+	}
+	return local, nil
+}
+
+// diffConfiguration returns the drift of the local configuration from the desired one by category
+func diffConfiguration(desired, local *Configuration) configDrift {
+	drift := make(configDrift)
+	for _, sc := range syncCategories {
+		d, l := *sc.get(desired), *sc.get(local)
+		item := &itemDrift{}
+		for k, v := range d {
+			lv, ok := l[k]
+			if !ok {
+				item.Missing = append(item.Missing, k)
+			} else if !sameContent(v, lv) {
+				item.Changed = append(item.Changed, k)
+			}
+		}
+		for k := range l {
+			if _, ok := d[k]; !ok {
+				item.Extra = append(item.Extra, k)
+			}
+		}
+		if len(item.Missing)+len(item.Changed)+len(item.Extra) > 0 {
+			sort.Strings(item.Missing)
+			sort.Strings(item.Changed)
+			sort.Strings(item.Extra)
+			drift[sc.name] = item
+		}
+	}
+	return drift
+}
+
+// sameContent compares the json content regardless of the format, or the text such as the stream statement
+func sameContent(a, b string) bool {
+	var av, bv any
+	if json.Unmarshal([]byte(a), &av) == nil && json.Unmarshal([]byte(b), &bv) == nil {
+		return reflect.DeepEqual(av, bv)
+	}
+	return strings.TrimSpace(a) == strings.TrimSpace(b)
+}
+
+func (d configDrift) String() string {
+	parts := make([]string, 0, len(d))
+	for _, sc := range syncCategories {
+		if item, ok := d[sc.name]; ok {
+			parts = append(parts, fmt.Sprintf("%s(missing %d, changed %d, extra %d)", sc.name, len(item.Missing), len(item.Changed), len(item.Extra)))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (s *configSyncer) getStatus() *syncStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.status
+}
+
+// configSyncHandler shows the status of the last sync by GET and syncs immediately by POST
+func configSyncHandler(w http.ResponseWriter, r *http.Request) {
+	if configSync == nil {
+		handleError(w, errorx.NewWithCode(errorx.NOT_FOUND, "configuration sync is disabled"), "", logger)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		jsonResponse(configSync.getStatus(), w, logger)
+	case http.MethodPost:
+		jsonResponse(configSync.sync(context.Background()), w, logger)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/meta"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+func TestDiffConfiguration(t *testing.T) {
+	desired := &Configuration{
+		Streams: map[string]string{"s1": "CREATE STREAM s1() WITH (TYPE=\"memory\", DATASOURCE=\"t1\")", "s2": "CREATE STREAM s2() WITH (TYPE=\"memory\", DATASOURCE=\"t2\")"},
+		Rules:   map[string]string{"r1": `{"id":"r1","sql":"SELECT * FROM s1"}`, "r2": `{"id":"r2","sql":"SELECT * FROM s2"}`},
+	}
+	local := &Configuration{
+		Streams: map[string]string{"s1": " CREATE STREAM s1() WITH (TYPE=\"memory\", DATASOURCE=\"t1\")\n"},
+		Rules:   map[string]string{"r1": `{"sql": "SELECT * FROM s1", "id": "r1"}`, "r2": `{"id":"r2","sql":"SELECT a FROM s2"}`, "r3": `{"id":"r3"}`},
+	}
+	drift := diffConfiguration(desired, local)
+	assert.Equal(t, configDrift{
+		"streams": {Missing: []string{"s2"}},
+		"rules":   {Changed: []string{"r2"}, Extra: []string{"r3"}},
+	}, drift)
+	assert.Equal(t, "streams(missing 1, changed 0, extra 0), rules(missing 0, changed 1, extra 1)", drift.String())
+	assert.Empty(t, diffConfiguration(desired, desired))
+}
+
+func TestGitRepo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	ctx := context.Background()
+	origin := t.TempDir()
+	commit := func(content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(origin, "bundle.json"), []byte(content), 0o644))
+		require.NoError(t, runGit(ctx, origin, "add", "bundle.json"))
+		require.NoError(t, runGit(ctx, origin, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-m", "update"))
+	}
+	require.NoError(t, runGit(ctx, origin, "init", "-b", "main"))
+	commit(`{"rules":{}}`)
+
+	g := &gitRepo{c: &conf.SyncGitConf{Repo: origin, Branch: "main"}, dir: filepath.Join(t.TempDir(), "sync")}
+	require.NoError(t, g.pull(ctx))
+	b, err := g.read("bundle.json")
+	require.NoError(t, err)
+	assert.Equal(t, `{"rules":{}}`, string(b))
+	// Follow the new commit
+	commit(`{"rules":{"r1":"{}"}}`)
+	require.NoError(t, g.pull(ctx))
+	b, err = g.read("bundle.json")
+	require.NoError(t, err)
+	assert.Equal(t, `{"rules":{"r1":"{}"}}`, string(b))
+
+	g = &gitRepo{c: &conf.SyncGitConf{Repo: origin, Branch: "notexist"}, dir: filepath.Join(t.TempDir(), "sync")}
+	assert.ErrorContains(t, g.pull(ctx), "git clone error")
+}
+
+func TestVerifyWithoutKey(t *testing.T) {
+	s := newConfigSyncer(&conf.SyncConf{Url: "http://bundle"}, func(url string) ([]byte, error) {
+		return []byte("{}"), nil
+	})
+	st := &syncStatus{}
+	assert.EqualError(t, s.reconcile(context.Background(), st), "no public key to verify the bundle")
+}
+
+func (suite *RestTestSuite) TestConfigSync() {
+	meta.InitYamlConfigManager()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(suite.T(), err)
+	c := &conf.SyncConf{Enable: true, Url: "http://bundle", PublicKey: base64.StdEncoding.EncodeToString(pub), Prune: true}
+	require.NoError(suite.T(), c.Validate())
+	files := make(map[string][]byte)
+	publish := func(cfg *Configuration, signer ed25519.PrivateKey) {
+		b, err := json.Marshal(cfg)
+		require.NoError(suite.T(), err)
+		files["http://bundle"] = b
+		files["http://bundle.sig"] = []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(signer, b)))
+	}
+	s := newConfigSyncer(c, func(url string) ([]byte, error) {
+		if b, ok := files[url]; ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("%s not found", url)
+	})
+	defer func() {
+		_ = deleteRule("syncRule")
+		_, _ = ruleProcessor.ExecDrop("syncRule")
+		_, _ = streamProcessor.DropStream("syncDemo", ast.TypeStream)
+	}()
+
+	bundle := &Configuration{
+		Streams: map[string]string{"syncDemo": `CREATE STREAM syncDemo () WITH (TYPE="memory", DATASOURCE="syncTopic", FORMAT="json")`},
+		Rules:   map[string]string{"syncRule": `{"id":"syncRule","triggered":false,"sql":"SELECT * FROM syncDemo","actions":[{"nop":{}}]}`},
+	}
+	// The bundle signed by another key is rejected
+	_, other, _ := ed25519.GenerateKey(rand.Reader)
+	publish(bundle, other)
+	st := s.sync(context.Background())
+	assert.Equal(suite.T(), "bundle signature verification failed", st.Error)
+	_, err = ruleProcessor.GetRuleJson("syncRule")
+	assert.Error(suite.T(), err)
+
+	publish(bundle, priv)
+	st = s.sync(context.Background())
+	assert.Equal(suite.T(), "", st.Error)
+	assert.True(suite.T(), st.InSync)
+	assert.Equal(suite.T(), []string{"syncRule"}, st.Drift["rules"].Missing)
+	_, err = ruleProcessor.GetRuleJson("syncRule")
+	require.NoError(suite.T(), err)
+
+	// No drift if not changed
+	st = s.sync(context.Background())
+	assert.Nil(suite.T(), st.Drift["rules"])
+	assert.Nil(suite.T(), st.Drift["streams"])
+
+	// The rule not in the bundle is pruned
+	publish(&Configuration{Streams: bundle.Streams}, priv)
+	st = s.sync(context.Background())
+	assert.Equal(suite.T(), []string{"syncRule"}, st.Drift["rules"].Extra)
+	assert.True(suite.T(), st.InSync)
+	_, err = ruleProcessor.GetRuleJson("syncRule")
+	assert.Error(suite.T(), err)
+	_, ok := registry.Load("syncRule")
+	assert.False(suite.T(), ok)
+
+	// Only report the drift in dry run
+	publish(bundle, priv)
+	c.DryRun = true
+	st = s.sync(context.Background())
+	assert.True(suite.T(), st.DryRun)
+	assert.False(suite.T(), st.InSync)
+	assert.Equal(suite.T(), []string{"syncRule"}, st.Drift["rules"].Missing)
+	_, err = ruleProcessor.GetRuleJson("syncRule")
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), st, s.getStatus())
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lf-edge/ekuiper/internal/pkg/geo"
+)
+
+// list or create geofences
+func geofencesHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	switch r.Method {
+	case http.MethodGet:
+		names, err := geo.List()
+		if err != nil {
+			handleError(w, err, "geofences list command error", logger)
+			return
+		}
+		jsonResponse(names, w, logger)
+	case http.MethodPost:
+		f := &geo.Fence{}
+		if err := json.NewDecoder(r.Body).Decode(f); err != nil {
+			handleError(w, err, "Invalid body: Error decoding the geofence json", logger)
+			return
+		}
+		if err := geo.Create(f); err != nil {
+			handleError(w, err, "geofence create command error", logger)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, "geofence %s is created", f.Name)
+	}
+}
+
+// describe, update or delete a geofence
+func geofenceHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	vars := mux.Vars(r)
+	name := vars["name"]
+	switch r.Method {
+	case http.MethodGet:
+		f, err := geo.Get(name)
+		if err != nil {
+			handleError(w, err, fmt.Sprintf("describe geofence %s error", name), logger)
+			return
+		}
+		jsonResponse(f, w, logger)
+	case http.MethodPut:
+		f := &geo.Fence{}
+		if err := json.NewDecoder(r.Body).Decode(f); err != nil {
+			handleError(w, err, "Invalid body: Error decoding the geofence json", logger)
+			return
+		}
+		if f.Name != name {
+			handleError(w, fmt.Errorf("name %s does not match %s", f.Name, name), "Invalid body", logger)
+			return
+		}
+		if err := geo.Update(f); err != nil {
+			handleError(w, err, "geofence update command error", logger)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "geofence %s is updated", name)
+	case http.MethodDelete:
+		if err := geo.Delete(name); err != nil {
+			handleError(w, err, fmt.Sprintf("delete geofence %s error", name), logger)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "geofence %s is deleted", name)
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/shirou/gopsutil/disk"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/internal/topo/connection/clients"
+)
+
+const (
+	HealthUp   = "up"
+	HealthDown = "down"
+
+	healthTable = "health"
+)
+
+// serverReady is set when the server finishes starting up and unset when it starts to stop
+var serverReady atomic.Bool
+
+type ComponentHealth struct {
+	Name    string                 `json:"name"`
+	Status  string                 `json:"status"`
+	Message string                 `json:"message,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+type HealthReport struct {
+	Status     string             `json:"status"`
+	Components []*ComponentHealth `json:"components,omitempty"`
+}
+
+// healthChecker is implemented by the components which have runtime dependencies to check for readiness
+type healthChecker interface {
+	health() *ComponentHealth
+}
+
+func livenessHandler(w http.ResponseWriter, _ *http.Request) {
+	healthResponse(w, &HealthReport{Status: HealthUp})
+}
+
+func readinessHandler(w http.ResponseWriter, _ *http.Request) {
+	healthResponse(w, checkReadiness())
+}
+
+func healthResponse(w http.ResponseWriter, report *HealthReport) {
+	b, err := json.Marshal(report)
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	w.Header().Add(ContentType, ContentTypeJSON)
+	w.Header().Add("Content-Length", strconv.Itoa(len(b)))
+	if report.Status != HealthUp {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_, _ = w.Write(b)
+}
+
+func checkReadiness() *HealthReport {
+	report := &HealthReport{
+		Status: HealthUp,
+		Components: []*ComponentHealth{
+			checkServer(),
+			checkStore(),
+			checkConnections(),
+			checkDisk(),
+		},
+	}
+	names := make([]string, 0, len(components))
+	for name := range components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if hc, ok := components[name].(healthChecker); ok {
+			report.Components = append(report.Components, hc.health())
+		}
+	}
+	for _, c := range report.Components {
+		if c.Status != HealthUp {
+			report.Status = HealthDown
+			break
+		}
+	}
+	return report
+}
+
+func checkServer() *ComponentHealth {
+	c := &ComponentHealth{Name: "server", Status: HealthUp}
+	if !serverReady.Load() {
+		c.Status = HealthDown
+		c.Message = "server is starting or stopping"
+	}
+	return c
+}
+
+// checkStore verifies the KV store is writable and readable
+func checkStore() *ComponentHealth {
+	c := &ComponentHealth{Name: "store", Status: HealthUp}
+	err := func() error {
+		kv, err := store.GetKV(healthTable)
+		if err != nil {
+			return err
+		}
+		expected := strconv.FormatInt(time.Now().UnixNano(), 10)
+		if err := kv.Set("probe", expected); err != nil {
+			return err
+		}
+		var actual string
+		found, err := kv.Get("probe", &actual)
+		if err != nil {
+			return err
+		}
+		if !found || actual != expected {
+			return fmt.Errorf("read back %s, expect %s", actual, expected)
+		}
+		return kv.Delete("probe")
+	}()
+	if err != nil {
+		c.Status = HealthDown
+		c.Message = err.Error()
+	}
+	return c
+}
+
+// checkConnections pings the shared connections
+func checkConnections() *ComponentHealth {
+	c := &ComponentHealth{Name: "connections", Status: HealthUp}
+	result := clients.PingSharedClients()
+	if len(result) == 0 {
+		return c
+	}
+	c.Details = make(map[string]interface{}, len(result))
+	for sel, err := range result {
+		if err != nil {
+			c.Status = HealthDown
+			c.Details[sel] = err.Error()
+		} else {
+			c.Details[sel] = HealthUp
+		}
+	}
+	return c
+}
+
+// checkDisk verifies the free space of the data directory is above the threshold
+func checkDisk() *ComponentHealth {
+	c := &ComponentHealth{Name: "disk", Status: HealthUp}
+	dataDir, err := conf.GetDataLoc()
+	if err != nil {
+		c.Status = HealthDown
+		c.Message = err.Error()
+		return c
+	}
+	usage, err := disk.Usage(dataDir)
+	if err != nil {
+		c.Status = HealthDown
+		c.Message = err.Error()
+		return c
+	}
+	threshold := conf.Config.Basic.MinFreeDiskSpace
+	c.Details = map[string]interface{}{
+		"path":      dataDir,
+		"free":      usage.Free,
+		"total":     usage.Total,
+		"threshold": threshold,
+	}
+	if threshold > 0 && usage.Free < uint64(threshold) {
+		c.Status = HealthDown
+		c.Message = fmt.Sprintf("free disk space %d is less than %d", usage.Free, threshold)
+	}
+	return c
+}
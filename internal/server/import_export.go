@@ -69,6 +69,27 @@ type Configuration struct {
 	Scripts          map[string]string `json:"scripts"`
 }
 
+// configCategory is a part of the configuration such as the rules by the json key
+type configCategory struct {
+	name string
+	get  func(c *Configuration) *map[string]string
+}
+
+var configCategories = []configCategory{
+	{"streams", func(c *Configuration) *map[string]string { return &c.Streams }},
+	{"tables", func(c *Configuration) *map[string]string { return &c.Tables }},
+	{"rules", func(c *Configuration) *map[string]string { return &c.Rules }},
+	{"nativePlugins", func(c *Configuration) *map[string]string { return &c.NativePlugins }},
+	{"portablePlugins", func(c *Configuration) *map[string]string { return &c.PortablePlugins }},
+	{"sourceConfig", func(c *Configuration) *map[string]string { return &c.SourceConfig }},
+	{"sinkConfig", func(c *Configuration) *map[string]string { return &c.SinkConfig }},
+	{"connectionConfig", func(c *Configuration) *map[string]string { return &c.ConnectionConfig }},
+	{"Service", func(c *Configuration) *map[string]string { return &c.Service }},
+	{"Schema", func(c *Configuration) *map[string]string { return &c.Schema }},
+	{"scripts", func(c *Configuration) *map[string]string { return &c.Scripts }},
+	{"uploads", func(c *Configuration) *map[string]string { return &c.Uploads }},
+}
+
 func configurationExport() ([]byte, error) {
 	conf := &Configuration{
 		Streams:          make(map[string]string),
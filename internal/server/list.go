@@ -0,0 +1,185 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/internal/pkg/labels"
+)
+
+// TotalCountHeader is the header of the list apis for the count of the items matching the filter before paging
+const TotalCountHeader = "X-Total-Count"
+
+// listQuery is the common query parameters to filter, sort and page the list apis
+type listQuery struct {
+	// limit is the max count of the items to return, 0 means no limit
+	limit  int
+	offset int
+	// name is the wildcard pattern of the names in lower case. * matches any characters and ? matches one character.
+	name string
+	// selector selects the items which have all the labels
+	selector map[string]string
+	// sort is the field to sort by, empty to keep the default order
+	sort string
+	desc bool
+}
+
+// parseListQuery parses the list query of the request. The sorts are the fields that the list can be sorted by.
+func parseListQuery(r *http.Request, sorts ...string) (*listQuery, error) {
+	v := r.URL.Query()
+	q := &listQuery{}
+	for k, p := range map[string]*int{"limit": &q.limit, "offset": &q.offset} {
+		if s := v.Get(k); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid %s %s", k, s)
+			}
+			*p = n
+		}
+	}
+	if name := v.Get("name"); name != "" {
+		q.name = strings.ToLower(name)
+		if _, err := path.Match(q.name, ""); err != nil {
+			return nil, fmt.Errorf("invalid name pattern %s", name)
+		}
+	}
+	selector, err := requestSelector(r)
+	if err != nil {
+		return nil, err
+	}
+	q.selector = selector
+	if s := v.Get("sort"); s != "" {
+		// The - prefix sorts in descending order
+		q.sort, q.desc = strings.CutPrefix(s, "-")
+		if !slices.Contains(sorts, q.sort) {
+			return nil, fmt.Errorf("invalid sort %s, the supported fields are %s", s, strings.Join(sorts, ", "))
+		}
+	}
+	return q, nil
+}
+
+// match returns whether any of the names matches the name pattern case-insensitively
+func (q *listQuery) match(names ...string) bool {
+	if q.name == "" {
+		return true
+	}
+	for _, name := range names {
+		if ok, _ := path.Match(q.name, strings.ToLower(name)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchLabels returns whether the labels match the selector
+func (q *listQuery) matchLabels(l map[string]string) bool {
+	return labels.Match(l, q.selector)
+}
+
+// filter returns the names matching the name pattern and the labels of the name matching the selector. The
+// labelsOf is nil if the items have no labels, so no item matches a selector.
+func (q *listQuery) filter(names []string, labelsOf func(name string) (map[string]string, error)) ([]string, error) {
+	if q.name == "" && q.selector == nil {
+		return names, nil
+	}
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		if !q.match(name) {
+			continue
+		}
+		if q.selector != nil {
+			var l map[string]string
+			if labelsOf != nil {
+				var err error
+				if l, err = labelsOf(name); err != nil {
+					return nil, err
+				}
+			}
+			if !q.matchLabels(l) {
+				continue
+			}
+		}
+		result = append(result, name)
+	}
+	return result, nil
+}
+
+// requestSelector returns the label selector in the query such as selector=app=vibration,site=plant3. Return nil
+// if not set.
+func requestSelector(r *http.Request) (map[string]string, error) {
+	s := r.URL.Query().Get("selector")
+	if s == "" {
+		return nil, nil
+	}
+	selector, err := labels.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector %s: %v", s, err)
+	}
+	return selector, nil
+}
+
+// sortBy sorts the items stably by the compare function of the sort field. The items keep the order if not sorted.
+func sortBy[T any](q *listQuery, items []T, compares map[string]func(a, b T) int) {
+	compare, ok := compares[q.sort]
+	if !ok {
+		return
+	}
+	slices.SortStableFunc(items, func(a, b T) int {
+		if q.desc {
+			return compare(b, a)
+		}
+		return compare(a, b)
+	})
+}
+
+// page returns the items in the page
+func page[T any](q *listQuery, items []T) []T {
+	if q.offset >= len(items) {
+		return make([]T, 0)
+	}
+	items = items[q.offset:]
+	if q.limit > 0 && q.limit < len(items) {
+		items = items[:q.limit]
+	}
+	return items
+}
+
+// listResponse writes the page of the items with the total count in the header
+func listResponse[T any](w http.ResponseWriter, q *listQuery, items []T) {
+	w.Header().Set(TotalCountHeader, strconv.Itoa(len(items)))
+	jsonResponse(page(q, items), w, logger)
+}
+
+// listNamesResponse filters, sorts and pages the names by the list query of the request
+func listNamesResponse(w http.ResponseWriter, r *http.Request, names []string, labelsOf func(name string) (map[string]string, error)) {
+	q, err := parseListQuery(r, "name")
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	names, err = q.filter(names, labelsOf)
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	sortBy(q, names, map[string]func(a, b string) int{"name": strings.Compare})
+	listResponse(w, q, names)
+}
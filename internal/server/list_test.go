@@ -0,0 +1,188 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+func TestParseListQuery(t *testing.T) {
+	tests := []struct {
+		url string
+		q   *listQuery
+		err string
+	}{
+		{url: "/rules", q: &listQuery{}},
+		{url: "/rules?limit=10&offset=20&name=Demo*&sort=-status", q: &listQuery{limit: 10, offset: 20, name: "demo*", sort: "status", desc: true}},
+		{url: "/rules?limit=-1", err: "invalid limit -1"},
+		{url: "/rules?offset=a", err: "invalid offset a"},
+		{url: "/rules?name=[a", err: "invalid name pattern [a"},
+		{url: "/rules?sort=sql", err: "invalid sort sql, the supported fields are name, status"},
+		{url: "/rules?selector=app%3Dvibration,site%3Dplant3", q: &listQuery{selector: map[string]string{"app": "vibration", "site": "plant3"}}},
+		{url: "/rules?selector=app", err: "invalid selector app: invalid label app, it must be key=value"},
+	}
+	for _, tt := range tests {
+		r, _ := http.NewRequest(http.MethodGet, "http://localhost:8080"+tt.url, nil)
+		q, err := parseListQuery(r, "name", "status")
+		if tt.err != "" {
+			assert.EqualError(t, err, tt.err, tt.url)
+			continue
+		}
+		require.NoError(t, err, tt.url)
+		assert.Equal(t, tt.q, q, tt.url)
+	}
+}
+
+func TestListPage(t *testing.T) {
+	q := &listQuery{name: "r?_*", sort: "name", desc: true, offset: 1, limit: 2}
+	names, err := q.filter([]string{"r1_a", "R2_b", "r3_c", "r4", "rr_d", "s1_a"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"r1_a", "R2_b", "r3_c", "rr_d"}, names)
+	sortBy(q, names, map[string]func(a, b string) int{"name": strings.Compare})
+	assert.Equal(t, []string{"rr_d", "r3_c", "r1_a", "R2_b"}, names)
+	assert.Equal(t, []string{"r3_c", "r1_a"}, page(q, names))
+	q.offset = 5
+	assert.Equal(t, []string{}, page(q, names))
+
+	// Filter by the labels
+	q = &listQuery{selector: map[string]string{"site": "plant3"}}
+	names, err = q.filter([]string{"a", "b", "c"}, func(name string) (map[string]string, error) {
+		if name == "b" {
+			return map[string]string{"app": "vibration", "site": "plant3"}, nil
+		}
+		return nil, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, names)
+	// Nothing matches if the items have no labels
+	names, err = q.filter([]string{"a", "b", "c"}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, names)
+}
+
+func (suite *RestTestSuite) TestListRules() {
+	request := func(method, url, body string) (int, string, http.Header) {
+		req, _ := http.NewRequest(method, "http://localhost:8080"+url, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		suite.r.ServeHTTP(w, req)
+		b, _ := io.ReadAll(w.Result().Body)
+		return w.Code, string(b), w.Result().Header
+	}
+	code, _, _ := request(http.MethodPost, "/streams", `{"sql":"CREATE STREAM listDemo () WITH (TYPE=\"memory\", DATASOURCE=\"listTopic\", FORMAT=\"json\")"}`)
+	require.Equal(suite.T(), http.StatusCreated, code)
+	ids := []string{"listRule3", "listRule1", "listRule2"}
+	defer func() {
+		for _, id := range ids {
+			_ = deleteRule(id)
+			_, _ = ruleProcessor.ExecDrop(id)
+		}
+		_, _ = streamProcessor.DropStream("listDemo", ast.TypeStream)
+	}()
+	for _, id := range ids {
+		code, body, _ := request(http.MethodPost, "/rules", fmt.Sprintf(`{"id":"%s","triggered":false,"sql":"SELECT * FROM listDemo","actions":[{"nop":{}}]}`, id))
+		require.Equal(suite.T(), http.StatusCreated, code, body)
+		// Make sure the creation time is different
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	list := func(url string) ([]string, string) {
+		code, body, header := request(http.MethodGet, url, "")
+		require.Equal(suite.T(), http.StatusOK, code, body)
+		var rules []map[string]interface{}
+		require.NoError(suite.T(), json.Unmarshal([]byte(body), &rules))
+		result := make([]string, len(rules))
+		for i, r := range rules {
+			result[i] = r["id"].(string)
+		}
+		return result, header.Get(TotalCountHeader)
+	}
+	result, total := list("/rules?name=LISTRULE*&sort=-name&limit=2")
+	assert.Equal(suite.T(), []string{"listRule3", "listRule2"}, result)
+	assert.Equal(suite.T(), "3", total)
+	result, _ = list("/rules?name=listRule*&sort=created&offset=1")
+	assert.Equal(suite.T(), []string{"listRule1", "listRule2"}, result)
+	result, total = list("/rules?name=listRule?&sort=status&offset=3")
+	assert.Empty(suite.T(), result)
+	assert.Equal(suite.T(), "3", total)
+
+	code, _, _ = request(http.MethodGet, "/rules?sort=sql", "")
+	assert.Equal(suite.T(), http.StatusBadRequest, code)
+	code, body, header := request(http.MethodGet, "/streams?name=list*", "")
+	assert.Equal(suite.T(), http.StatusOK, code)
+	assert.Equal(suite.T(), `["listDemo"]`, body)
+	assert.Equal(suite.T(), "1", header.Get(TotalCountHeader))
+}
+
+func (suite *RestTestSuite) TestListByLabels() {
+	request := func(method, url, body string) (int, string) {
+		req, _ := http.NewRequest(method, "http://localhost:8080"+url, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		suite.r.ServeHTTP(w, req)
+		b, _ := io.ReadAll(w.Result().Body)
+		return w.Code, string(b)
+	}
+	defer func() {
+		for _, id := range []string{"labelRule1", "labelRule2"} {
+			_ = deleteRule(id)
+			_, _ = ruleProcessor.ExecDrop(id)
+		}
+		_, _ = streamProcessor.DropStream("labelDemo1", ast.TypeStream)
+		_, _ = streamProcessor.DropStream("labelDemo2", ast.TypeStream)
+	}()
+	code, body := request(http.MethodPost, "/streams", `{"sql":"CREATE STREAM labelDemo1 () WITH (TYPE=\"memory\", DATASOURCE=\"labelTopic\", LABELS=\"app=vibration,site=plant3\")"}`)
+	require.Equal(suite.T(), http.StatusCreated, code, body)
+	code, body = request(http.MethodPost, "/streams", `{"sql":"CREATE STREAM labelDemo2 () WITH (TYPE=\"memory\", DATASOURCE=\"labelTopic\", LABELS=\"app=vibration,site=plant4\")"}`)
+	require.Equal(suite.T(), http.StatusCreated, code, body)
+	code, body = request(http.MethodPost, "/rules", `{"id":"labelRule1","labels":{"app":"vibration","site":"plant3"},"triggered":false,"sql":"SELECT * FROM labelDemo1","actions":[{"nop":{}}]}`)
+	require.Equal(suite.T(), http.StatusCreated, code, body)
+	code, body = request(http.MethodPost, "/rules", `{"id":"labelRule2","labels":{"app":"vibration"},"triggered":false,"sql":"SELECT * FROM labelDemo2","actions":[{"nop":{}}]}`)
+	require.Equal(suite.T(), http.StatusCreated, code, body)
+
+	code, body = request(http.MethodGet, "/streams?selector=app%3Dvibration,site%3Dplant3", "")
+	assert.Equal(suite.T(), http.StatusOK, code)
+	assert.Equal(suite.T(), `["labelDemo1"]`, body)
+	code, body = request(http.MethodGet, "/streamdetails?selector=site%3Dplant4", "")
+	assert.Equal(suite.T(), http.StatusOK, code)
+	assert.Equal(suite.T(), `[{"name":"labeldemo2","type":"memory","format":"json","labels":{"app":"vibration","site":"plant4"}}]`, strings.ToLower(body))
+	code, body = request(http.MethodGet, "/rules?selector=app%3Dvibration&sort=name", "")
+	assert.Equal(suite.T(), http.StatusOK, code)
+	assert.Contains(suite.T(), body, `"id":"labelRule1"`)
+	assert.Contains(suite.T(), body, `"id":"labelRule2"`)
+	code, body = request(http.MethodGet, "/rules/status/all?selector=site%3Dplant3", "")
+	assert.Equal(suite.T(), http.StatusOK, code)
+	m := map[string]interface{}{}
+	require.NoError(suite.T(), json.Unmarshal([]byte(body), &m))
+	assert.Len(suite.T(), m, 1)
+	assert.Contains(suite.T(), m, "labelRule1")
+
+	code, _ = request(http.MethodGet, "/rules?selector=site", "")
+	assert.Equal(suite.T(), http.StatusBadRequest, code)
+	code, body = request(http.MethodPost, "/rules", `{"id":"labelRule3","labels":{"app":"a b"},"sql":"SELECT * FROM labelDemo1","actions":[{"nop":{}}]}`)
+	assert.Equal(suite.T(), http.StatusBadRequest, code)
+	assert.Contains(suite.T(), body, "invalid labels")
+}
@@ -15,14 +15,18 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
-	"github.com/lf-edge/ekuiper/internal/pkg/jwt"
+	"github.com/gorilla/mux"
+
+	"github.com/lf-edge/ekuiper/internal/pkg/rbac"
 )
 
-var notAuth = []string{"/", "/ping"}
+var notAuth = []string{"/", "/ping", "/health/live", "/health/ready"}
 
+// Auth authenticates the caller and checks whether its role is allowed to call the route
 var Auth = func(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		requestPath := r.URL.Path
@@ -33,29 +37,25 @@ var Auth = func(next http.Handler) http.Handler {
 			}
 		}
 
-		tokenHeader := r.Header.Get("Authorization")
-
-		if tokenHeader == "" {
-			http.Error(w, "missing_token", http.StatusUnauthorized)
-			return
-		}
-		tk, err := jwt.ParseToken(tokenHeader)
+		id, err := rbac.Authenticate(r)
 		if err != nil {
+			if errors.Is(err, rbac.ErrMissingCredential) {
+				w.Header().Set("WWW-Authenticate", `Basic realm="eKuiper"`)
+			}
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
-		hit := false
-		for _, value := range tk.RegisteredClaims.Audience {
-			if value == "eKuiper" {
-				hit = true
-				break
+		path := requestPath
+		if route := mux.CurrentRoute(r); route != nil {
+			if tpl, err := route.GetPathTemplate(); err == nil {
+				path = tpl
 			}
 		}
-		if !hit {
-			http.Error(w, fmt.Sprintf("audience field should contain eKuiper, but got %s", tk.RegisteredClaims.Audience), http.StatusUnauthorized)
+		if required := rbac.RequiredRole(r.Method, path); !id.Role.Allows(required) {
+			http.Error(w, fmt.Sprintf("role %s is not allowed to %s %s, require %s", id.Role, r.Method, requestPath, required), http.StatusForbidden)
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(rbac.WithIdentity(r.Context(), id)))
 	})
 }
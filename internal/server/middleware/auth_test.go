@@ -29,6 +29,11 @@ func genToken(signKeyName, issuer string, aud []string) string {
 	return tkStr
 }
 
+func genRoleToken(role string) string {
+	tkStr, _ := jwt.CreateTokenWithRole("sample_key", "sample_key.pub", []string{"eKuiper"}, role)
+	return tkStr
+}
+
 func Test_AUTH(t *testing.T) {
 	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -75,6 +80,55 @@ func Test_AUTH(t *testing.T) {
 			res:      httptest.NewRecorder(),
 			wantCode: 200,
 		},
+		{
+			name:     "bearer token",
+			args:     args{th: "Bearer " + genToken("sample_key", "sample_key.pub", []string{"eKuiper"})},
+			req:      httptest.NewRequest(http.MethodPost, "http://127.0.0.1:9081/plugins/sources", nil),
+			res:      httptest.NewRecorder(),
+			wantCode: 200,
+		},
+		{
+			name:     "viewer read",
+			args:     args{th: genRoleToken("viewer")},
+			req:      httptest.NewRequest(http.MethodGet, "http://127.0.0.1:9081/rules", nil),
+			res:      httptest.NewRecorder(),
+			wantCode: 200,
+		},
+		{
+			name:     "viewer create rule",
+			args:     args{th: genRoleToken("viewer")},
+			req:      httptest.NewRequest(http.MethodPost, "http://127.0.0.1:9081/rules", nil),
+			res:      httptest.NewRecorder(),
+			wantCode: 403,
+		},
+		{
+			name:     "operator create rule",
+			args:     args{th: genRoleToken("operator")},
+			req:      httptest.NewRequest(http.MethodPost, "http://127.0.0.1:9081/rules", nil),
+			res:      httptest.NewRecorder(),
+			wantCode: 200,
+		},
+		{
+			name:     "operator install plugin",
+			args:     args{th: genRoleToken("operator")},
+			req:      httptest.NewRequest(http.MethodPost, "http://127.0.0.1:9081/plugins/sources", nil),
+			res:      httptest.NewRecorder(),
+			wantCode: 403,
+		},
+		{
+			name:     "unknown role",
+			args:     args{th: genRoleToken("root")},
+			req:      httptest.NewRequest(http.MethodGet, "http://127.0.0.1:9081/rules", nil),
+			res:      httptest.NewRecorder(),
+			wantCode: 401,
+		},
+		{
+			name:     "no need token readiness path",
+			args:     args{th: ""},
+			req:      httptest.NewRequest(http.MethodGet, "http://127.0.0.1:9081/health/ready", nil),
+			res:      httptest.NewRecorder(),
+			wantCode: 200,
+		},
 	}
 
 	for _, tt := range tests {
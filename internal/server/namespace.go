@@ -0,0 +1,239 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lf-edge/ekuiper/internal/pkg/namespace"
+	"github.com/lf-edge/ekuiper/internal/processor"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	"github.com/lf-edge/ekuiper/pkg/errorx"
+)
+
+// namespaceDeleteHooks clean up the resources of the extensions such as plugins when deleting a namespace
+var namespaceDeleteHooks []func(ns string) error
+
+// namespaceRoutes registers the namespace management endpoints and the stream, table and rule endpoints
+// inside a namespace. The endpoints without namespace prefix work on the default namespace.
+func namespaceRoutes(r *mux.Router) {
+	r.HandleFunc("/namespaces", namespacesHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/namespaces/{namespace}", namespaceHandler).Methods(http.MethodDelete)
+	nr := r.PathPrefix("/namespaces/{namespace}").Subrouter()
+	nr.Use(namespaceMiddleware)
+	nr.HandleFunc("/streams", streamsHandler).Methods(http.MethodGet, http.MethodPost)
+	nr.HandleFunc("/streamdetails", streamDetailsHandler).Methods(http.MethodGet)
+	nr.HandleFunc("/streams/{name}", streamHandler).Methods(http.MethodGet, http.MethodDelete, http.MethodPut)
+	nr.HandleFunc("/streams/{name}/schema", streamSchemaHandler).Methods(http.MethodGet, http.MethodPut)
+	nr.HandleFunc("/streams/{name}/schema/infer", streamSchemaInferHandler).Methods(http.MethodPost)
+	nr.HandleFunc("/tables", tablesHandler).Methods(http.MethodGet, http.MethodPost)
+	nr.HandleFunc("/tabledetails", tableDetailsHandler).Methods(http.MethodGet)
+	nr.HandleFunc("/tables/{name}", tableHandler).Methods(http.MethodGet, http.MethodDelete, http.MethodPut)
+	nr.HandleFunc("/tables/{name}/schema", tableSchemaHandler).Methods(http.MethodGet, http.MethodPut)
+	nr.HandleFunc("/rules", rulesHandler).Methods(http.MethodGet, http.MethodPost)
+	nr.HandleFunc("/rules/{name}", ruleHandler).Methods(http.MethodDelete, http.MethodGet, http.MethodPut)
+	nr.HandleFunc("/rules/status/all", getAllRuleStatusHandler).Methods(http.MethodGet)
+	nr.HandleFunc("/rules/bulk", bulkRuleHandler).Methods(http.MethodPost)
+	nr.HandleFunc("/rules/{name}/status", getStatusRuleHandler).Methods(http.MethodGet)
+	nr.HandleFunc("/rules/{name}/start", startRuleHandler).Methods(http.MethodPost)
+	nr.HandleFunc("/rules/{name}/stop", stopRuleHandler).Methods(http.MethodPost)
+	nr.HandleFunc("/rules/{name}/restart", restartRuleHandler).Methods(http.MethodPost)
+	nr.HandleFunc("/rules/{name}/topo", getTopoRuleHandler).Methods(http.MethodGet)
+	nr.HandleFunc("/rules/validate", validateRuleHandler).Methods(http.MethodPost)
+	nr.HandleFunc("/rules/validate-plan", validatePlanHandler).Methods(http.MethodPost)
+	nr.HandleFunc("/rules/unittest", ruleUnitTestHandler).Methods(http.MethodPost)
+	nr.HandleFunc("/rules/{name}/reset_state", ruleStateHandler).Methods(http.MethodPut)
+	nr.HandleFunc("/rules/{name}/snapshot", ruleSnapshotHandler).Methods(http.MethodGet, http.MethodPut)
+	nr.HandleFunc("/rules/{name}/backfill", ruleBackfillHandler).Methods(http.MethodPost)
+	nr.HandleFunc("/rules/{name}/replay", ruleReplayHandler).Methods(http.MethodPost)
+	nr.HandleFunc("/rules/{name}/explain", explainRuleHandler).Methods(http.MethodGet)
+	nr.HandleFunc("/rules/{name}/versions", ruleVersionsHandler).Methods(http.MethodGet)
+	nr.HandleFunc("/rules/{name}/versions/diff", ruleVersionDiffHandler).Methods(http.MethodGet)
+	nr.HandleFunc("/rules/{name}/versions/{version}", ruleVersionHandler).Methods(http.MethodGet)
+	nr.HandleFunc("/rules/{name}/versions/{version}/rollback", ruleRollbackHandler).Methods(http.MethodPost)
+	nr.HandleFunc("/rules/{name}/tap", ruleTapHandler).Methods(http.MethodGet)
+	nr.HandleFunc("/pipelines", pipelinesHandler).Methods(http.MethodGet, http.MethodPost)
+	nr.HandleFunc("/pipelines/{name}", pipelineHandler).Methods(http.MethodGet, http.MethodDelete)
+	nr.HandleFunc("/pipelines/{name}/status", pipelineStatusHandler).Methods(http.MethodGet)
+	nr.HandleFunc("/pipelines/{name}/start", startPipelineHandler).Methods(http.MethodPost)
+	nr.HandleFunc("/pipelines/{name}/stop", stopPipelineHandler).Methods(http.MethodPost)
+}
+
+// namespaceMiddleware rejects the requests to the namespaces which are not created
+func namespaceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ns := mux.Vars(r)["namespace"]
+		if !namespaceProcessor.Exists(ns) {
+			handleError(w, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("namespace %s is not found", ns)), "", logger)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestNamespace returns the namespace in the path, or the default namespace
+func requestNamespace(r *http.Request) string {
+	if ns := mux.Vars(r)["namespace"]; ns != "" {
+		return ns
+	}
+	return namespace.Default
+}
+
+// requestRuleId returns the runtime id of the rule in the path, which is qualified by the namespace
+func requestRuleId(r *http.Request) string {
+	vars := mux.Vars(r)
+	return namespace.Qualify(vars["namespace"], vars["name"])
+}
+
+// requestStreamProcessor returns the processor of the streams and tables in the namespace of the path
+func requestStreamProcessor(r *http.Request) (*processor.StreamProcessor, error) {
+	return streamProcessor.In(mux.Vars(r)["namespace"])
+}
+
+// ruleIdsIn returns the runtime ids of the rules in the namespace
+func ruleIdsIn(ns string) ([]string, error) {
+	if ns == namespace.Default {
+		return ruleProcessor.GetAllRules()
+	}
+	return ruleProcessor.GetRulesIn(ns)
+}
+
+// allRuleIds returns the runtime ids of the rules in all namespaces
+func allRuleIds() ([]string, error) {
+	result, err := ruleProcessor.GetAllRules()
+	if err != nil {
+		return nil, err
+	}
+	nss, err := namespaceProcessor.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, ns := range nss {
+		ids, err := ruleProcessor.GetRulesIn(ns)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ids...)
+	}
+	return result, nil
+}
+
+// recoverNamespaceLookupTables starts the lookup tables of the created namespaces
+func recoverNamespaceLookupTables() {
+	nss, err := namespaceProcessor.List()
+	if err != nil {
+		logger.Errorf("list namespaces error: %v", err)
+		return
+	}
+	for _, ns := range nss {
+		sp, err := streamProcessor.In(ns)
+		if err == nil {
+			err = sp.RecoverLookupTable()
+		}
+		if err != nil {
+			logger.Errorf("recover lookup tables of namespace %s error: %v", ns, err)
+		}
+	}
+}
+
+type namespaceRequest struct {
+	Name string `json:"name"`
+}
+
+// list or create namespaces
+func namespacesHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	switch r.Method {
+	case http.MethodGet:
+		nss, err := namespaceProcessor.List()
+		if err != nil {
+			handleError(w, err, "list namespaces error", logger)
+			return
+		}
+		jsonResponse(append([]string{namespace.Default}, nss...), w, logger)
+	case http.MethodPost:
+		req := &namespaceRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			handleError(w, err, "Invalid body", logger)
+			return
+		}
+		if err := namespaceProcessor.Create(req.Name); err != nil {
+			handleError(w, err, "create namespace error", logger)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, "Namespace %s was created successfully.", req.Name)
+	}
+}
+
+// delete a namespace and all its rules, streams, tables and plugins
+func namespaceHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	ns := mux.Vars(r)["namespace"]
+	if err := deleteNamespace(ns); err != nil {
+		handleError(w, err, "delete namespace error", logger)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Namespace %s was deleted.", ns)
+}
+
+func deleteNamespace(ns string) error {
+	if ns == namespace.Default {
+		return fmt.Errorf("the default namespace cannot be deleted")
+	}
+	if !namespaceProcessor.Exists(ns) {
+		return errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("namespace %s is not found", ns))
+	}
+	// Rules are deleted first to release the streams and tables
+	ids, err := ruleProcessor.GetRulesIn(ns)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		deleteRule(id)
+		if _, err := ruleProcessor.ExecDrop(id); err != nil {
+			logger.Warnf("delete rule %s error: %v", id, err)
+		}
+	}
+	sp, err := streamProcessor.In(ns)
+	if err != nil {
+		return err
+	}
+	all, err := sp.GetAll()
+	if err != nil {
+		return err
+	}
+	for name := range all["streams"] {
+		if _, err := sp.DropStream(name, ast.TypeStream); err != nil {
+			logger.Warnf("delete stream %s in namespace %s error: %v", name, ns, err)
+		}
+	}
+	for name := range all["tables"] {
+		if _, err := sp.DropStream(name, ast.TypeTable); err != nil {
+			logger.Warnf("delete table %s in namespace %s error: %v", name, ns, err)
+		}
+	}
+	for _, hook := range namespaceDeleteHooks {
+		if err := hook(ns); err != nil {
+			logger.Warnf("clean up namespace %s error: %v", ns, err)
+		}
+	}
+	return namespaceProcessor.Drop(ns)
+}
@@ -0,0 +1,154 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build onnx
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lf-edge/ekuiper/internal/binder"
+	"github.com/lf-edge/ekuiper/internal/pkg/httpx"
+	"github.com/lf-edge/ekuiper/internal/plugin/onnx"
+)
+
+func init() {
+	components["onnx"] = onnxComp{}
+}
+
+type onnxComp struct{}
+
+func (p onnxComp) register() {
+	err := onnx.InitManager()
+	if err != nil {
+		panic(err)
+	}
+	entries = append(entries, binder.FactoryEntry{Name: "onnx function", Factory: onnx.GetManager(), Weight: 6})
+}
+
+func (p onnxComp) rest(r *mux.Router) {
+	r.HandleFunc("/models/onnx", onnxModelsHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/models/onnx/{name}", onnxModelHandler).Methods(http.MethodGet, http.MethodDelete, http.MethodPut)
+}
+
+// onnxModelFile reads the model from the request. The model file could be uploaded as the uploadFile field of a
+// multipart form, or be downloaded from the file url of the json body.
+func onnxModelFile(r *http.Request) (*onnx.Model, io.ReadCloser, error) {
+	m := &onnx.Model{}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		// Maximum upload of 1 GB files
+		if err := r.ParseMultipartForm(1024 << 20); err != nil {
+			return nil, nil, fmt.Errorf("fail to parse the multipart form: %v", err)
+		}
+		m.Name = r.FormValue("name")
+		m.Description = r.FormValue("description")
+		file, _, err := r.FormFile("uploadFile")
+		if err != nil {
+			return nil, nil, fmt.Errorf("fail to retrieve the uploadFile: %v", err)
+		}
+		return m, file, nil
+	}
+	body := struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		File        string `json:"file"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, nil, fmt.Errorf("fail to decode the onnx model json: %v", err)
+	}
+	if body.File == "" {
+		return nil, nil, fmt.Errorf("file is required")
+	}
+	m.Name, m.Description = body.Name, body.Description
+	src, err := httpx.ReadFile(body.File)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fail to read model file %s: %v", body.File, err)
+	}
+	return m, src, nil
+}
+
+func onnxModelsHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	switch r.Method {
+	case http.MethodGet:
+		content, err := onnx.GetManager().List()
+		if err != nil {
+			handleError(w, err, "onnx models list command error", logger)
+			return
+		}
+		jsonResponse(content, w, logger)
+	case http.MethodPost:
+		m, src, err := onnxModelFile(r)
+		if err != nil {
+			handleError(w, err, "Invalid body", logger)
+			return
+		}
+		defer src.Close()
+		err = onnx.GetManager().Create(m, src)
+		if err != nil {
+			handleError(w, err, "onnx model create command error", logger)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, "onnx model %s is created", m.Name)
+	}
+}
+
+func onnxModelHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	vars := mux.Vars(r)
+	name := vars["name"]
+	switch r.Method {
+	case http.MethodDelete:
+		err := onnx.GetManager().Delete(name)
+		if err != nil {
+			handleError(w, err, fmt.Sprintf("delete onnx model %s error", name), logger)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "onnx model %s is deleted", name)
+	case http.MethodGet:
+		m, err := onnx.GetManager().GetModel(name)
+		if err != nil {
+			handleError(w, err, fmt.Sprintf("describe onnx model %s error", name), logger)
+			return
+		}
+		jsonResponse(m, w, logger)
+	case http.MethodPut:
+		m, src, err := onnxModelFile(r)
+		if err != nil {
+			handleError(w, err, "Invalid body", logger)
+			return
+		}
+		defer src.Close()
+		if m.Name != name {
+			handleError(w, fmt.Errorf("name %s does not match %s", m.Name, name), "Invalid body", logger)
+			return
+		}
+		err = onnx.GetManager().Update(m, src)
+		if err != nil {
+			handleError(w, err, "onnx model update command error", logger)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "onnx model %s is updated", name)
+	}
+}
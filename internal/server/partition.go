@@ -0,0 +1,367 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/topo/node/metric"
+	"github.com/lf-edge/ekuiper/internal/topo/rule"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+// The rule in partitioned mode does not run by itself. Each partition runs as a rule instance whose id is the rule id
+// with the partition index like rule1#0, so that the instance has its own state and metrics. In the cluster mode, the
+// partitions are placed across the nodes like the rules.
+
+const (
+	partitionRunning = "running"
+	partitionStopped = "stopped"
+	partitionUnknown = "unknown"
+)
+
+// partitionRegistry is the partition instances running on this node by the instance id
+type partitionRegistry struct {
+	sync.RWMutex
+	internal map[string]*rule.RuleState
+}
+
+var partitionInstances = &partitionRegistry{internal: make(map[string]*rule.RuleState)}
+
+func (pr *partitionRegistry) load(id string) (*rule.RuleState, bool) {
+	pr.RLock()
+	defer pr.RUnlock()
+	rs, ok := pr.internal[id]
+	return rs, ok
+}
+
+func (pr *partitionRegistry) store(id string, rs *rule.RuleState) {
+	pr.Lock()
+	defer pr.Unlock()
+	pr.internal[id] = rs
+}
+
+func (pr *partitionRegistry) delete(id string) (*rule.RuleState, bool) {
+	pr.Lock()
+	defer pr.Unlock()
+	rs, ok := pr.internal[id]
+	if ok {
+		delete(pr.internal, id)
+	}
+	return rs, ok
+}
+
+// partitionStatus is the status of a partition instance. The instances in the cluster publish it to the shared
+// store so that any node can show the aggregated status of the rule.
+type partitionStatus struct {
+	Partition int    `json:"partition"`
+	Node      string `json:"node,omitempty"`
+	Status    string `json:"status"`
+	Message   string `json:"message,omitempty"`
+	// Timestamp is the unix milliseconds when the status is published
+	Timestamp int64          `json:"timestamp,omitempty"`
+	Metrics   map[string]any `json:"metrics,omitempty"`
+}
+
+func isPartitioned(r *api.Rule) bool {
+	return r != nil && r.Options != nil && r.Options.Partitions > 0
+}
+
+func partitionId(ruleId string, p int) string {
+	return fmt.Sprintf("%s#%d", ruleId, p)
+}
+
+// partitionRule returns the rule instance to run the partition
+func partitionRule(r *api.Rule, p int) *api.Rule {
+	c := *r
+	c.Id = partitionId(r.Id, p)
+	o := *r.Options
+	o.Partition = p
+	c.Options = &o
+	c.Triggered = true
+	return &c
+}
+
+// partitionOwner returns the node to run the partition, or empty if the cluster mode is disabled
+func partitionOwner(r *api.Rule, p int) string {
+	if ruleCluster == nil {
+		return ""
+	}
+	return ruleCluster.c.Owner(partitionId(r.Id, p), r.Options.NodeSelector)
+}
+
+func ownsPartition(r *api.Rule, p int) bool {
+	return ruleCluster == nil || partitionOwner(r, p) == ruleCluster.c.Self()
+}
+
+// applyPartitions runs the partitions of the rule placed on this node and stops the others
+func applyPartitions(r *api.Rule) {
+	n := 0
+	if isPartitioned(r) {
+		n = r.Options.Partitions
+	}
+	for p := 0; p < n; p++ {
+		id := partitionId(r.Id, p)
+		rs, ok := partitionInstances.load(id)
+		// The lease is renewed on each sync while the partition runs
+		if !r.Triggered || !ownsPartition(r, p) || !acquireLease(id) {
+			if ok {
+				logger.Infof("stop partition %s on this node", id)
+				dropPartition(id)
+				releaseLease(id)
+			}
+			continue
+		}
+		pr := partitionRule(r, p)
+		if ok && sameRule(rs.Rule, pr) {
+			continue
+		}
+		if err := runPartition(rs, pr); err != nil {
+			logger.Errorf("run partition %s error: %v", id, err)
+		}
+	}
+	// Drop the partitions beyond the count after updating the rule
+	dropPartitions(r.Id, n)
+}
+
+func runPartition(rs *rule.RuleState, pr *api.Rule) error {
+	if rs != nil {
+		return rs.UpdateTopo(pr)
+	}
+	rs, err := rule.NewRuleState(pr)
+	if err != nil {
+		return err
+	}
+	partitionInstances.store(pr.Id, rs)
+	logger.Infof("run partition %s on this node", pr.Id)
+	return rs.Start()
+}
+
+// dropPartitions drops the partitions of the rule from the index
+func dropPartitions(ruleId string, from int) {
+	prefix := ruleId + "#"
+	var ids []string
+	partitionInstances.RLock()
+	for id := range partitionInstances.internal {
+		if p, ok := strings.CutPrefix(id, prefix); ok {
+			if i, err := strconv.Atoi(p); err == nil && i >= from {
+				ids = append(ids, id)
+			}
+		}
+	}
+	partitionInstances.RUnlock()
+	for _, id := range ids {
+		dropPartition(id)
+		releaseLease(id)
+	}
+}
+
+func dropPartition(id string) {
+	if rs, ok := partitionInstances.delete(id); ok {
+		if err := rs.Close(); err != nil {
+			logger.Warnf("close partition %s error: %v", id, err)
+		}
+		deleteRuleMetrics(id)
+	}
+}
+
+// localPartitionStatus returns the status of the partition instance running on this node
+func localPartitionStatus(id string, p int) (*partitionStatus, bool) {
+	rs, ok := partitionInstances.load(id)
+	if !ok {
+		return nil, false
+	}
+	ps := &partitionStatus{Partition: p, Status: partitionStopped}
+	if ruleCluster != nil {
+		ps.Node = ruleCluster.c.Self()
+	}
+	s, err := rs.GetState()
+	if err != nil {
+		ps.Message = err.Error()
+		return ps, true
+	}
+	if s != rule.RuleStarted {
+		ps.Message = s
+		return ps, true
+	}
+	ps.Status = partitionRunning
+	keys, values := rs.Topology.GetMetrics()
+	ps.Metrics = make(map[string]any, len(keys))
+	for i, k := range keys {
+		ps.Metrics[k] = values[i]
+	}
+	return ps, true
+}
+
+// getPartitionStatus returns the status of each partition of the rule. The status of the partitions on the other
+// nodes is read from the shared store.
+func getPartitionStatus(r *api.Rule) []*partitionStatus {
+	result := make([]*partitionStatus, r.Options.Partitions)
+	for p := range result {
+		id := partitionId(r.Id, p)
+		if ps, ok := localPartitionStatus(id, p); ok {
+			result[p] = ps
+			continue
+		}
+		ps := &partitionStatus{Partition: p, Node: partitionOwner(r, p), Status: partitionStopped}
+		if r.Triggered && ps.Node != "" && ruleCluster != nil {
+			ps.Status = partitionUnknown
+			if rps, ok := ruleCluster.partitionStatus(id); ok && rps.Node == ps.Node {
+				ps = rps
+			}
+		}
+		result[p] = ps
+	}
+	return result
+}
+
+// getPartitionedRuleStatus aggregates the status of the partitions. The metrics of the same name are summed except
+// the latency which is the max of the partitions.
+func getPartitionedRuleStatus(r *api.Rule) (string, error) {
+	parts := getPartitionStatus(r)
+	running := 0
+	metrics := make(map[string]any)
+	for _, ps := range parts {
+		if ps.Status != partitionRunning {
+			continue
+		}
+		running++
+		for k, v := range ps.Metrics {
+			if agg, ok := aggregateMetric(k, metrics[k], v); ok {
+				metrics[k] = agg
+			}
+		}
+	}
+	result := map[string]any{
+		"partitions": parts,
+	}
+	for k, v := range metrics {
+		result[k] = v
+	}
+	switch running {
+	case len(parts):
+		result["status"] = "running"
+	case 0:
+		result["status"] = "stopped"
+	default:
+		result["status"] = "partial"
+	}
+	re, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	return string(re), nil
+}
+
+// getPartitionedRuleState is the brief state of the partitioned rule in the rule list
+func getPartitionedRuleState(r *api.Rule) string {
+	parts := getPartitionStatus(r)
+	running := 0
+	for _, ps := range parts {
+		if ps.Status == partitionRunning {
+			running++
+		}
+	}
+	switch running {
+	case len(parts):
+		return rule.RuleStarted
+	case 0:
+		return rule.RuleStopped
+	default:
+		return fmt.Sprintf("Partial: %d/%d partitions running.", running, len(parts))
+	}
+}
+
+func aggregateMetric(key string, total, v any) (any, bool) {
+	n, ok := toNumber(v)
+	if !ok {
+		return nil, false
+	}
+	t, _ := toNumber(total)
+	if strings.HasSuffix(key, metric.ProcessLatencyUs) {
+		if n > t {
+			t = n
+		}
+	} else {
+		t += n
+	}
+	if t == float64(int64(t)) {
+		return int64(t), true
+	}
+	return t, true
+}
+
+func toNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+// publishPartitions saves the status of the partitions running on this node to the shared store
+func (m *clusterManager) publishPartitions(now time.Time) {
+	partitionInstances.RLock()
+	ids := make([]string, 0, len(partitionInstances.internal))
+	for id := range partitionInstances.internal {
+		ids = append(ids, id)
+	}
+	partitionInstances.RUnlock()
+	for _, id := range ids {
+		i := strings.LastIndex(id, "#")
+		p, err := strconv.Atoi(id[i+1:])
+		if err != nil {
+			continue
+		}
+		ps, ok := localPartitionStatus(id, p)
+		if !ok {
+			continue
+		}
+		ps.Timestamp = now.UnixMilli()
+		b, err := json.Marshal(ps)
+		if err != nil {
+			logger.Warnf("encode partition %s status error: %v", id, err)
+			continue
+		}
+		if err := m.status.SetEx(id, string(b), conf.Config.Cluster.GetNodeTimeout()); err != nil {
+			logger.Warnf("publish partition %s status error: %v", id, err)
+		}
+	}
+}
+
+// partitionStatus reads the status of the partition published by another node. The status expires on the store side
+// after the node timeout.
+func (m *clusterManager) partitionStatus(id string) (*partitionStatus, bool) {
+	var v string
+	if ok, err := m.status.Get(id, &v); err != nil || !ok {
+		return nil, false
+	}
+	ps := &partitionStatus{}
+	if err := json.Unmarshal([]byte(v), ps); err != nil {
+		return nil, false
+	}
+	return ps, true
+}
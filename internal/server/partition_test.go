@@ -0,0 +1,86 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+func TestPartitionRule(t *testing.T) {
+	r := &api.Rule{
+		Id:        "rule1",
+		Sql:       "select * from demo",
+		Triggered: false,
+		Options:   &api.RuleOption{Partitions: 3, Qos: api.AtLeastOnce},
+	}
+	pr := partitionRule(r, 2)
+	assert.Equal(t, "rule1#2", pr.Id)
+	assert.True(t, pr.Triggered)
+	assert.Equal(t, 2, pr.Options.Partition)
+	assert.Equal(t, api.AtLeastOnce, pr.Options.Qos)
+	// The original rule is not changed
+	assert.Equal(t, 0, r.Options.Partition)
+	assert.False(t, r.Triggered)
+
+	lr := localRule(&api.Rule{Id: "rule1", Triggered: true, Options: r.Options})
+	assert.False(t, lr.Triggered)
+}
+
+func TestAggregateMetric(t *testing.T) {
+	tests := []struct {
+		key   string
+		total any
+		v     any
+		exp   any
+		ok    bool
+	}{
+		{key: "source_demo_0_records_in_total", total: nil, v: int64(3), exp: int64(3), ok: true},
+		{key: "source_demo_0_records_in_total", total: int64(3), v: float64(4), exp: int64(7), ok: true},
+		{key: "op_2_project_0_process_latency_us", total: int64(30), v: int64(20), exp: int64(30), ok: true},
+		{key: "op_2_project_0_process_latency_us", total: int64(30), v: float64(40), exp: int64(40), ok: true},
+		{key: "sink_log_0_0_last_exception", total: nil, v: "error", ok: false},
+	}
+	for _, tt := range tests {
+		r, ok := aggregateMetric(tt.key, tt.total, tt.v)
+		assert.Equal(t, tt.ok, ok)
+		assert.Equal(t, tt.exp, r)
+	}
+}
+
+func (suite *RestTestSuite) TestPartitionedRule() {
+	buf := bytes.NewBuffer([]byte(`{"sql":"CREATE stream partitionDemo() WITH (DATASOURCE=\"partition\", TYPE=\"memory\")"}`))
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/streams", buf)
+	w := httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusCreated, w.Code)
+
+	// The memory source cannot read by partition
+	buf = bytes.NewBuffer([]byte(`{"id":"partitionRule","sql":"select * from partitionDemo","actions":[{"log":{}}],"options":{"partitions":2}}`))
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules", buf)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	body, _ := io.ReadAll(w.Result().Body)
+	require.Contains(suite.T(), string(body), "does not support partitioned mode")
+}
@@ -0,0 +1,293 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lf-edge/ekuiper/internal/pkg/namespace"
+	"github.com/lf-edge/ekuiper/internal/topo/rule"
+	"github.com/lf-edge/ekuiper/pkg/infra"
+)
+
+type pipelineRuleStatus struct {
+	Id     string `json:"id"`
+	Status string `json:"status"`
+}
+
+// pipelineStatus is the combined status of the pipeline rules. The status is running if all rules are running,
+// stopped if no rule is running and degraded otherwise.
+type pipelineStatus struct {
+	Id     string                `json:"id"`
+	Status string                `json:"status"`
+	Rules  []*pipelineRuleStatus `json:"rules"`
+}
+
+// createPipeline validates the pipeline and creates all its rules. If any rule fails, the created rules are
+// deleted so that nothing is left.
+func createPipeline(id, pipelineJson string) (string, error) {
+	pl, rules, err := pipelineProcessor.GetPipelineByJson(id, pipelineJson)
+	if err != nil {
+		return "", err
+	}
+	if pipelineProcessor.ExecExists(pl.Id) {
+		return pl.Id, fmt.Errorf("pipeline %s already exists", pl.Id)
+	}
+	for _, pr := range rules {
+		if ruleProcessor.ExecExists(pr.Rule.Id) {
+			return pl.Id, fmt.Errorf("rule %s already exists", pr.Rule.Id)
+		}
+	}
+	var created []string
+	defer func() {
+		if err != nil {
+			for _, ruleId := range created {
+				deleteRule(ruleId)
+				_, _ = ruleProcessor.ExecDrop(ruleId)
+			}
+		}
+	}()
+	for _, pr := range rules {
+		r := pr.Rule
+		// The rules are started by the pipeline in order
+		r.Triggered = false
+		err = infra.SafeRun(func() error {
+			_, err := createRuleState(r)
+			return err
+		})
+		if err != nil {
+			err = fmt.Errorf("create rule %s error: %v", r.Id, err)
+			return pl.Id, err
+		}
+		created = append(created, r.Id)
+		if err = ruleProcessor.ExecCreate(r.Id, pr.Json); err == nil {
+			_, err = ruleProcessor.ExecReplaceRuleState(r.Id, false)
+		}
+		if err != nil {
+			err = fmt.Errorf("store the rule %s error: %v", r.Id, err)
+			return pl.Id, err
+		}
+	}
+	if err = pipelineProcessor.ExecCreate(pl.Id, pipelineJson, rules); err != nil {
+		return pl.Id, err
+	}
+	if pl.Triggered {
+		if e := startPipeline(pl.Id); e != nil {
+			logger.Errorf("Pipeline %s start failed: %v", pl.Id, e)
+		}
+	}
+	return pl.Id, nil
+}
+
+// startPipeline starts the rules from the downstream to the upstream so that no message is published before its
+// consumers are ready. If any rule fails to start, the started rules are stopped.
+func startPipeline(id string) error {
+	ruleIds, err := pipelineProcessor.GetPipelineRules(id)
+	if err != nil {
+		return err
+	}
+	for i := len(ruleIds) - 1; i >= 0; i-- {
+		if err := startRule(ruleIds[i]); err != nil {
+			for _, started := range ruleIds[i+1:] {
+				if _, e := stopRule(started, 0); e != nil {
+					logger.Warnf("stop rule %s error: %v", started, e)
+				}
+			}
+			return fmt.Errorf("start rule %s error: %v", ruleIds[i], err)
+		}
+	}
+	return nil
+}
+
+// stopPipeline stops the rules from the upstream to the downstream so that the consumers receive the messages
+// in flight
+func stopPipeline(id string) error {
+	ruleIds, err := pipelineProcessor.GetPipelineRules(id)
+	if err != nil {
+		return err
+	}
+	var errs []string
+	for _, ruleId := range ruleIds {
+		if _, err := stopRule(ruleId, 0); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("stop pipeline %s error: %s", id, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func deletePipeline(id string) (string, error) {
+	ruleIds, err := pipelineProcessor.GetPipelineRules(id)
+	if err != nil {
+		return "", err
+	}
+	for _, ruleId := range ruleIds {
+		deleteRule(ruleId)
+		if _, err := ruleProcessor.ExecDrop(ruleId); err != nil {
+			logger.Warnf("delete rule %s error: %v", ruleId, err)
+		}
+	}
+	return pipelineProcessor.ExecDrop(id)
+}
+
+func getPipelineStatus(id string) (*pipelineStatus, error) {
+	ruleIds, err := pipelineProcessor.GetPipelineRules(id)
+	if err != nil {
+		return nil, err
+	}
+	_, localId := namespace.Split(id)
+	result := &pipelineStatus{Id: localId, Rules: make([]*pipelineRuleStatus, len(ruleIds))}
+	running := 0
+	for i, ruleId := range ruleIds {
+		s, err := getRuleState(ruleId)
+		if err != nil {
+			s = fmt.Sprintf("error: %s", err)
+		} else if s == rule.RuleStarted {
+			running++
+		}
+		_, localRuleId := namespace.Split(ruleId)
+		result.Rules[i] = &pipelineRuleStatus{Id: localRuleId, Status: s}
+	}
+	switch running {
+	case len(ruleIds):
+		result.Status = "running"
+	case 0:
+		result.Status = "stopped"
+	default:
+		result.Status = "degraded"
+	}
+	return result, nil
+}
+
+// requestPipelineId returns the id of the pipeline in the path, which is qualified by the namespace
+func requestPipelineId(r *http.Request) string {
+	vars := mux.Vars(r)
+	return namespace.Qualify(vars["namespace"], vars["name"])
+}
+
+// list or create pipelines
+func pipelinesHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	switch r.Method {
+	case http.MethodGet:
+		q, err := parseListQuery(r, "name", "status")
+		if err != nil {
+			handleError(w, err, "", logger)
+			return
+		}
+		ids, err := pipelineProcessor.GetPipelinesIn(requestNamespace(r))
+		if err != nil {
+			handleError(w, err, "pipelines list command error", logger)
+			return
+		}
+		result := make([]*pipelineStatus, 0, len(ids))
+		for _, id := range ids {
+			// The pipelines have no labels to match the selector
+			if _, localId := namespace.Split(id); !q.match(localId) || !q.matchLabels(nil) {
+				continue
+			}
+			s, err := getPipelineStatus(id)
+			if err != nil {
+				handleError(w, err, "pipelines list command error", logger)
+				return
+			}
+			result = append(result, s)
+		}
+		sortBy(q, result, map[string]func(a, b *pipelineStatus) int{
+			"name":   func(a, b *pipelineStatus) int { return strings.Compare(a.Id, b.Id) },
+			"status": func(a, b *pipelineStatus) int { return strings.Compare(a.Status, b.Status) },
+		})
+		listResponse(w, q, result)
+	case http.MethodPost:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			handleError(w, err, "Invalid body", logger)
+			return
+		}
+		id, err := createPipeline(namespace.Qualify(requestNamespace(r), ""), string(body))
+		if err != nil {
+			handleError(w, err, "pipeline create command error", logger)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, "Pipeline %s was created successfully.", id)
+	}
+}
+
+// describe or delete a pipeline
+func pipelineHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id := requestPipelineId(r)
+	switch r.Method {
+	case http.MethodGet:
+		content, err := pipelineProcessor.GetPipelineJson(id)
+		if err != nil {
+			handleError(w, err, "describe pipeline error", logger)
+			return
+		}
+		w.Header().Add(ContentType, ContentTypeJSON)
+		w.Write([]byte(content))
+	case http.MethodDelete:
+		content, err := deletePipeline(id)
+		if err != nil {
+			handleError(w, err, "delete pipeline error", logger)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(content))
+	}
+}
+
+// get the combined status of a pipeline
+func pipelineStatusHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	s, err := getPipelineStatus(requestPipelineId(r))
+	if err != nil {
+		handleError(w, err, "get pipeline status error", logger)
+		return
+	}
+	jsonResponse(s, w, logger)
+}
+
+// start all rules of a pipeline
+func startPipelineHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id := requestPipelineId(r)
+	if err := startPipeline(id); err != nil {
+		handleError(w, err, "start pipeline error", logger)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Pipeline %s was started", id)
+}
+
+// stop all rules of a pipeline
+func stopPipelineHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id := requestPipelineId(r)
+	if err := stopPipeline(id); err != nil {
+		handleError(w, err, "stop pipeline error", logger)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Pipeline %s was stopped", id)
+}
@@ -29,8 +29,10 @@ import (
 
 	"github.com/lf-edge/ekuiper/internal/binder"
 	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/pkg/namespace"
 	"github.com/lf-edge/ekuiper/internal/plugin"
 	"github.com/lf-edge/ekuiper/internal/plugin/native"
+	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/errorx"
 )
 
@@ -38,6 +40,7 @@ var nativeManager *native.Manager
 
 func init() {
 	components["plugin"] = pluginComp{}
+	namespaceDeleteHooks = append(namespaceDeleteHooks, deleteNamespacePlugins)
 }
 
 type pluginComp struct{}
@@ -64,6 +67,14 @@ func (p pluginComp) rest(r *mux.Router) {
 	r.HandleFunc("/plugins/functions/{name}/register", functionRegisterHandler).Methods(http.MethodPost)
 	r.HandleFunc("/plugins/udfs", functionsListHandler).Methods(http.MethodGet)
 	r.HandleFunc("/plugins/udfs/{name}", functionsGetHandler).Methods(http.MethodGet)
+	r.HandleFunc("/plugins/udfs/{name}/status", functionStatusHandler).Methods(http.MethodGet)
+	// The plugins created in a namespace
+	r.Handle("/namespaces/{namespace}/plugins/sources", namespaceMiddleware(http.HandlerFunc(sourcesHandler))).Methods(http.MethodGet, http.MethodPost)
+	r.Handle("/namespaces/{namespace}/plugins/sources/{name}", namespaceMiddleware(http.HandlerFunc(sourceHandler))).Methods(http.MethodDelete, http.MethodGet, http.MethodPut)
+	r.Handle("/namespaces/{namespace}/plugins/sinks", namespaceMiddleware(http.HandlerFunc(sinksHandler))).Methods(http.MethodGet, http.MethodPost)
+	r.Handle("/namespaces/{namespace}/plugins/sinks/{name}", namespaceMiddleware(http.HandlerFunc(sinkHandler))).Methods(http.MethodDelete, http.MethodGet, http.MethodPut)
+	r.Handle("/namespaces/{namespace}/plugins/functions", namespaceMiddleware(http.HandlerFunc(functionsHandler))).Methods(http.MethodGet, http.MethodPost)
+	r.Handle("/namespaces/{namespace}/plugins/functions/{name}", namespaceMiddleware(http.HandlerFunc(functionHandler))).Methods(http.MethodDelete, http.MethodGet, http.MethodPut)
 }
 
 func (p pluginComp) exporter() ConfManager {
@@ -72,9 +83,20 @@ func (p pluginComp) exporter() ConfManager {
 
 func pluginsHandler(w http.ResponseWriter, r *http.Request, t plugin.PluginType) {
 	defer func(Body io.ReadCloser) { _ = Body.Close() }(r.Body)
+	ns := mux.Vars(r)["namespace"]
 	switch r.Method {
 	case http.MethodGet:
-		content := nativeManager.List(t)
+		var content []string
+		if ns == "" {
+			content = nativeManager.List(t)
+		} else {
+			var err error
+			content, err = namespaceProcessor.GetPlugins(ns, plugin.PluginTypes[t])
+			if err != nil {
+				handleError(w, err, fmt.Sprintf("%s plugins list command error", plugin.PluginTypes[t]), logger)
+				return
+			}
+		}
 		jsonResponse(content, w, logger)
 	case http.MethodPost:
 		sd := plugin.NewPluginByType(t)
@@ -89,6 +111,12 @@ func pluginsHandler(w http.ResponseWriter, r *http.Request, t plugin.PluginType)
 			handleError(w, err, fmt.Sprintf("%s plugins create command error", plugin.PluginTypes[t]), logger)
 			return
 		}
+		if ns != "" {
+			if err := namespaceProcessor.SetPluginOwner(plugin.PluginTypes[t], sd.GetName(), ns); err != nil {
+				handleError(w, err, fmt.Sprintf("%s plugins create command error", plugin.PluginTypes[t]), logger)
+				return
+			}
+		}
 		w.WriteHeader(http.StatusCreated)
 		_, _ = fmt.Fprintf(w, "%s plugin %s is created", plugin.PluginTypes[t], sd.GetName())
 	}
@@ -98,6 +126,12 @@ func pluginHandler(w http.ResponseWriter, r *http.Request, t plugin.PluginType)
 	defer func(Body io.ReadCloser) { _ = Body.Close() }(r.Body)
 	vars := mux.Vars(r)
 	name := vars["name"]
+	// A namespace can only access its own plugins
+	owner := namespaceProcessor.GetPluginOwner(plugin.PluginTypes[t], name)
+	if ns := vars["namespace"]; ns != "" && ns != owner {
+		handleError(w, errorx.NewWithCode(errorx.NOT_FOUND, "not found"), fmt.Sprintf("%s plugin %s is not found in namespace %s", plugin.PluginTypes[t], name, ns), logger)
+		return
+	}
 	cb := r.URL.Query().Get("stop")
 	switch r.Method {
 	case http.MethodDelete:
@@ -107,6 +141,9 @@ func pluginHandler(w http.ResponseWriter, r *http.Request, t plugin.PluginType)
 			handleError(w, err, fmt.Sprintf("delete %s plugin %s error", plugin.PluginTypes[t], name), logger)
 			return
 		}
+		if owner != namespace.Default {
+			_ = namespaceProcessor.RemovePluginOwner(plugin.PluginTypes[t], name)
+		}
 		w.WriteHeader(http.StatusOK)
 		result := fmt.Sprintf("%s plugin %s is deleted", plugin.PluginTypes[t], name)
 		if r {
@@ -187,6 +224,27 @@ func functionsGetHandler(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(map[string]string{"name": name, "plugin": j}, w, logger)
 }
 
+// get the runtime status of a user-defined function if the function reports it
+func functionStatusHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	f, err := nativeManager.Function(name)
+	if err != nil {
+		handleError(w, err, fmt.Sprintf("get function %s status error", name), logger)
+		return
+	}
+	if f == nil {
+		handleError(w, errorx.NewWithCode(errorx.NOT_FOUND, "not found"), fmt.Sprintf("get function %s status error", name), logger)
+		return
+	}
+	s, ok := f.(api.FunctionStatus)
+	if !ok {
+		handleError(w, fmt.Errorf("function %s does not report status", name), fmt.Sprintf("get function %s status error", name), logger)
+		return
+	}
+	jsonResponse(s.Status(), w, logger)
+}
+
 // delete a function plugin
 func functionHandler(w http.ResponseWriter, r *http.Request) {
 	pluginHandler(w, r, plugin.FUNCTION)
@@ -291,6 +349,23 @@ func fetchPluginList(t plugin.PluginType, hosts, os, arch string) (result map[st
 	return
 }
 
+// deleteNamespacePlugins deletes the native plugins created in the namespace
+func deleteNamespacePlugins(ns string) error {
+	for _, t := range []plugin.PluginType{plugin.SOURCE, plugin.SINK, plugin.FUNCTION} {
+		names, err := namespaceProcessor.GetPlugins(ns, plugin.PluginTypes[t])
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if err := nativeManager.Delete(t, name, false); err != nil {
+				logger.Warnf("delete %s plugin %s of namespace %s error: %v", plugin.PluginTypes[t], name, ns, err)
+			}
+			_ = namespaceProcessor.RemovePluginOwner(plugin.PluginTypes[t], name)
+		}
+	}
+	return nil
+}
+
 type pluginExporter struct{}
 
 func (e pluginExporter) Import(ctx context.Context, plugins map[string]string) map[string]string {
@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/mux"
 
@@ -28,6 +29,7 @@ import (
 	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/plugin"
 	"github.com/lf-edge/ekuiper/internal/plugin/portable"
+	"github.com/lf-edge/ekuiper/internal/plugin/portable/runtime"
 	"github.com/lf-edge/ekuiper/pkg/errorx"
 )
 
@@ -46,17 +48,50 @@ func (p portableComp) register() {
 		panic(err)
 	}
 	entries = append(entries, binder.FactoryEntry{Name: "portable plugin", Factory: portableManager, Weight: 8})
+	runtime.GetPluginInsManager().SetFailHandler(failPluginRules)
 }
 
 func (p portableComp) rest(r *mux.Router) {
 	r.HandleFunc("/plugins/portables", portablesHandler).Methods(http.MethodGet, http.MethodPost)
 	r.HandleFunc("/plugins/portables/{name}", portableHandler).Methods(http.MethodGet, http.MethodDelete, http.MethodPut)
+	r.HandleFunc("/plugins/portables/{name}/status", portableStatusHandler).Methods(http.MethodGet)
+}
+
+// failPluginRules fails the running rules using the plugin which is crashed too many times
+func failPluginRules(pluginName string, ruleIds []string, err error) {
+	for _, id := range ruleIds {
+		rs, ok := registry.Load(id)
+		if !ok || rs.Topology == nil {
+			continue
+		}
+		logger.Errorf("fail rule %s as plugin %s is down", id, pluginName)
+		rs.Topology.Fail(err)
+	}
+}
+
+func portableStatusHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	name := mux.Vars(r)["name"]
+	if _, ok := portableManager.GetPluginInfo(name); !ok {
+		handleError(w, errorx.NewWithCode(errorx.NOT_FOUND, "not found"), fmt.Sprintf("describe portable plugin %s status error", name), logger)
+		return
+	}
+	jsonResponse(runtime.GetPluginInsManager().Status(name), w, logger)
 }
 
 func (p portableComp) exporter() ConfManager {
 	return portableExporter{}
 }
 
+func (p portableComp) health() *ComponentHealth {
+	c := &ComponentHealth{Name: "portablePlugins", Status: HealthUp}
+	if unhealthy := runtime.GetPluginInsManager().Unhealthy(); len(unhealthy) > 0 {
+		c.Status = HealthDown
+		c.Message = fmt.Sprintf("plugin processes are not running: %s", strings.Join(unhealthy, ","))
+	}
+	return c
+}
+
 func portablesHandler(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 	switch r.Method {
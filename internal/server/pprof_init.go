@@ -17,14 +17,25 @@
 package server
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lf-edge/ekuiper/internal/topo/profiler"
+	"github.com/lf-edge/ekuiper/internal/topo/rule"
 )
 
+const maxProfileSeconds = 60
+
 func init() {
 	servers["pprof"] = pprofComp{}
+	components["pprof"] = pprofComp{}
 }
 
 type pprofComp struct {
@@ -43,3 +54,41 @@ func (p pprofComp) serve() {
 func (p pprofComp) close() {
 	// do nothing
 }
+
+func (p pprofComp) register() {
+	// do nothing
+}
+
+func (p pprofComp) rest(r *mux.Router) {
+	r.HandleFunc("/rules/{name}/profile", ruleProfileHandler).Methods(http.MethodGet)
+}
+
+// profile the cpu of a running rule for several seconds and rank its nodes by the cpu time
+func ruleProfileHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	name := mux.Vars(r)["name"]
+	seconds := 10
+	if v := r.URL.Query().Get("seconds"); v != "" {
+		var err error
+		seconds, err = strconv.Atoi(v)
+		if err != nil || seconds <= 0 || seconds > maxProfileSeconds {
+			handleError(w, fmt.Errorf("invalid seconds %s, it must be an integer in (0, %d]", v, maxProfileSeconds), "", logger)
+			return
+		}
+	}
+	st, err := getRuleState(name)
+	if err != nil {
+		handleError(w, err, "profile rule error", logger)
+		return
+	}
+	if st != rule.RuleStarted {
+		handleError(w, fmt.Errorf("rule %s is not running", name), "profile rule error", logger)
+		return
+	}
+	report, err := profiler.Profile(r.Context(), name, time.Duration(seconds)*time.Second)
+	if err != nil {
+		handleError(w, err, "profile rule error", logger)
+		return
+	}
+	jsonResponse(report, w, logger)
+}
@@ -25,6 +25,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/handlers"
@@ -34,6 +35,8 @@ import (
 
 	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/pkg/httpx"
+	"github.com/lf-edge/ekuiper/internal/pkg/namespace"
+	"github.com/lf-edge/ekuiper/internal/pkg/rbac"
 	"github.com/lf-edge/ekuiper/internal/pkg/store"
 	"github.com/lf-edge/ekuiper/internal/processor"
 	"github.com/lf-edge/ekuiper/internal/server/middleware"
@@ -52,6 +55,8 @@ import (
 const (
 	ContentType     = "Content-Type"
 	ContentTypeJSON = "application/json"
+	// the default timeout to drain the rule before stopping
+	defaultDrainTimeout = 30 * time.Second
 )
 
 var (
@@ -153,25 +158,44 @@ func createRestServer(ip string, port int, needToken bool) *http.Server {
 	r.HandleFunc("/", rootHandler).Methods(http.MethodGet, http.MethodPost)
 	r.HandleFunc("/stop", stopHandler).Methods(http.MethodGet, http.MethodPost)
 	r.HandleFunc("/ping", pingHandler).Methods(http.MethodGet)
+	r.HandleFunc("/health/live", livenessHandler).Methods(http.MethodGet)
+	r.HandleFunc("/health/ready", readinessHandler).Methods(http.MethodGet)
 	r.HandleFunc("/streams", streamsHandler).Methods(http.MethodGet, http.MethodPost)
 	r.HandleFunc("/streamdetails", streamDetailsHandler).Methods(http.MethodGet)
 	r.HandleFunc("/streams/{name}", streamHandler).Methods(http.MethodGet, http.MethodDelete, http.MethodPut)
-	r.HandleFunc("/streams/{name}/schema", streamSchemaHandler).Methods(http.MethodGet)
+	r.HandleFunc("/streams/{name}/schema", streamSchemaHandler).Methods(http.MethodGet, http.MethodPut)
+	r.HandleFunc("/streams/{name}/schema/infer", streamSchemaInferHandler).Methods(http.MethodPost)
 	r.HandleFunc("/tables", tablesHandler).Methods(http.MethodGet, http.MethodPost)
 	r.HandleFunc("/tabledetails", tableDetailsHandler).Methods(http.MethodGet)
 	r.HandleFunc("/tables/{name}", tableHandler).Methods(http.MethodGet, http.MethodDelete, http.MethodPut)
-	r.HandleFunc("/tables/{name}/schema", tableSchemaHandler).Methods(http.MethodGet)
+	r.HandleFunc("/tables/{name}/schema", tableSchemaHandler).Methods(http.MethodGet, http.MethodPut)
 	r.HandleFunc("/rules", rulesHandler).Methods(http.MethodGet, http.MethodPost)
 	r.HandleFunc("/rules/{name}", ruleHandler).Methods(http.MethodDelete, http.MethodGet, http.MethodPut)
 	r.HandleFunc("/rules/status/all", getAllRuleStatusHandler).Methods(http.MethodGet)
+	r.HandleFunc("/rules/bulk", bulkRuleHandler).Methods(http.MethodPost)
 	r.HandleFunc("/rules/{name}/status", getStatusRuleHandler).Methods(http.MethodGet)
 	r.HandleFunc("/rules/{name}/start", startRuleHandler).Methods(http.MethodPost)
 	r.HandleFunc("/rules/{name}/stop", stopRuleHandler).Methods(http.MethodPost)
 	r.HandleFunc("/rules/{name}/restart", restartRuleHandler).Methods(http.MethodPost)
 	r.HandleFunc("/rules/{name}/topo", getTopoRuleHandler).Methods(http.MethodGet)
 	r.HandleFunc("/rules/validate", validateRuleHandler).Methods(http.MethodPost)
+	r.HandleFunc("/rules/validate-plan", validatePlanHandler).Methods(http.MethodPost)
+	r.HandleFunc("/rules/unittest", ruleUnitTestHandler).Methods(http.MethodPost)
 	r.HandleFunc("/rules/{name}/reset_state", ruleStateHandler).Methods(http.MethodPut)
+	r.HandleFunc("/rules/{name}/snapshot", ruleSnapshotHandler).Methods(http.MethodGet, http.MethodPut)
+	r.HandleFunc("/rules/{name}/backfill", ruleBackfillHandler).Methods(http.MethodPost)
+	r.HandleFunc("/rules/{name}/replay", ruleReplayHandler).Methods(http.MethodPost)
 	r.HandleFunc("/rules/{name}/explain", explainRuleHandler).Methods(http.MethodGet)
+	r.HandleFunc("/rules/{name}/versions", ruleVersionsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/rules/{name}/versions/diff", ruleVersionDiffHandler).Methods(http.MethodGet)
+	r.HandleFunc("/rules/{name}/versions/{version}", ruleVersionHandler).Methods(http.MethodGet)
+	r.HandleFunc("/rules/{name}/versions/{version}/rollback", ruleRollbackHandler).Methods(http.MethodPost)
+	r.HandleFunc("/rules/{name}/tap", ruleTapHandler).Methods(http.MethodGet)
+	r.HandleFunc("/pipelines", pipelinesHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/pipelines/{name}", pipelineHandler).Methods(http.MethodGet, http.MethodDelete)
+	r.HandleFunc("/pipelines/{name}/status", pipelineStatusHandler).Methods(http.MethodGet)
+	r.HandleFunc("/pipelines/{name}/start", startPipelineHandler).Methods(http.MethodPost)
+	r.HandleFunc("/pipelines/{name}/stop", stopPipelineHandler).Methods(http.MethodPost)
 	r.HandleFunc("/ruletest", testRuleHandler).Methods(http.MethodPost)
 	r.HandleFunc("/ruletest/{name}/start", testRuleStartHandler).Methods(http.MethodPost)
 	r.HandleFunc("/ruletest/{name}", testRuleStopHandler).Methods(http.MethodDelete)
@@ -183,10 +207,24 @@ func createRestServer(ip string, port int, needToken bool) *http.Server {
 	r.HandleFunc("/data/export", configurationExportHandler).Methods(http.MethodGet, http.MethodPost)
 	r.HandleFunc("/data/import", configurationImportHandler).Methods(http.MethodPost)
 	r.HandleFunc("/data/import/status", configurationStatusHandler).Methods(http.MethodGet)
+	r.HandleFunc("/data/archive", archiveExportHandler).Methods(http.MethodGet)
+	r.HandleFunc("/data/archive/import", archiveImportHandler).Methods(http.MethodPost)
 	r.HandleFunc("/connection/websocket", connectionHandler).Methods(http.MethodGet, http.MethodPost, http.MethodDelete)
 	r.HandleFunc("/async/data/import", registerDataImportTask).Methods(http.MethodPost)
 	r.HandleFunc("/async/task/{id}", queryAsyncTaskStatus).Methods(http.MethodGet)
 	r.HandleFunc("/async/task/{id}/cancel", asyncTaskCancelHandler).Methods(http.MethodPost)
+	r.HandleFunc("/snapshots", snapshotsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/snapshots/{name}", snapshotHandler).Methods(http.MethodGet, http.MethodDelete)
+	r.HandleFunc("/snapshots/{name}/{key}", snapshotRowHandler).Methods(http.MethodGet)
+	r.HandleFunc("/views", viewsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/views/{name}", viewHandler).Methods(http.MethodGet)
+	r.HandleFunc("/geofences", geofencesHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/geofences/{name}", geofenceHandler).Methods(http.MethodGet, http.MethodPut, http.MethodDelete)
+	r.HandleFunc("/sinks/{name}/replay", sinkReplayHandler).Methods(http.MethodPost)
+	r.HandleFunc("/audit", auditHandler).Methods(http.MethodGet)
+	r.HandleFunc("/cluster/nodes", clusterHandler).Methods(http.MethodGet)
+	r.HandleFunc("/sync", configSyncHandler).Methods(http.MethodGet, http.MethodPost)
+	namespaceRoutes(r)
 	// Register extended routes
 	for k, v := range components {
 		logger.Infof("register rest endpoint for component %s", k)
@@ -194,8 +232,13 @@ func createRestServer(ip string, port int, needToken bool) *http.Server {
 	}
 
 	if needToken {
+		if err := rbac.Init(&conf.Config.Rbac); err != nil {
+			panic(err)
+		}
 		r.Use(middleware.Auth)
 	}
+	// after the auth to know the caller
+	r.Use(auditMiddleware)
 
 	server := &http.Server{
 		Addr: cast.JoinHostPortInt(ip, port),
@@ -203,7 +246,7 @@ func createRestServer(ip string, port int, needToken bool) *http.Server {
 		WriteTimeout: time.Second * 60 * 5,
 		ReadTimeout:  time.Second * 60 * 5,
 		IdleTimeout:  time.Second * 60,
-		Handler:      handlers.CORS(handlers.AllowedHeaders([]string{"Accept", "Accept-Language", "Content-Type", "Content-Language", "Origin", "Authorization"}), handlers.AllowedMethods([]string{"POST", "GET", "PUT", "DELETE", "HEAD"}))(r),
+		Handler:      handlers.CORS(handlers.AllowedHeaders([]string{"Accept", "Accept-Language", "Content-Type", "Content-Language", "Origin", "Authorization"}), handlers.AllowedMethods([]string{"POST", "GET", "PUT", "DELETE", "HEAD"}), handlers.ExposedHeaders([]string{TotalCountHeader}))(r),
 	}
 	server.SetKeepAlivesEnabled(false)
 	return server
@@ -267,8 +310,7 @@ func getFile(file *fileContent) error {
 
 func explainRuleHandler(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
-	vars := mux.Vars(r)
-	name := vars["name"]
+	name := requestRuleId(r)
 
 	// fetch the rule which will be explained
 	rule, err := ruleProcessor.GetRuleById(name)
@@ -448,16 +490,40 @@ func sourceDetailsManageHandler(w http.ResponseWriter, r *http.Request, st ast.S
 			kind = ""
 		}
 	}
-	content, err = streamProcessor.ShowStreamOrTableDetails(kind, st)
+	sp, err := requestStreamProcessor(r)
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	q, err := parseListQuery(r, "name")
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	content, err = sp.ShowStreamOrTableDetails(kind, st)
 	if err != nil {
 		handleError(w, err, fmt.Sprintf("%s command error", cases.Title(language.Und).String(ast.StreamTypeMap[st])), logger)
 		return
 	}
-	jsonResponse(content, w, logger)
+	details := make([]processor.StreamDetail, 0, len(content))
+	for _, d := range content {
+		if q.match(d.Name) && q.matchLabels(d.Labels) {
+			details = append(details, d)
+		}
+	}
+	sortBy(q, details, map[string]func(a, b processor.StreamDetail) int{
+		"name": func(a, b processor.StreamDetail) int { return strings.Compare(a.Name, b.Name) },
+	})
+	listResponse(w, q, details)
 }
 
 func sourcesManageHandler(w http.ResponseWriter, r *http.Request, st ast.StreamType) {
 	defer r.Body.Close()
+	sp, err := requestStreamProcessor(r)
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
 	switch r.Method {
 	case http.MethodGet:
 		var (
@@ -476,22 +542,24 @@ func sourcesManageHandler(w http.ResponseWriter, r *http.Request, st ast.StreamT
 			}
 		}
 		if kind != "" {
-			content, err = streamProcessor.ShowTable(kind)
+			content, err = sp.ShowTable(kind)
 		} else {
-			content, err = streamProcessor.ShowStream(st)
+			content, err = sp.ShowStream(st)
 		}
 		if err != nil {
 			handleError(w, err, fmt.Sprintf("%s command error", cases.Title(language.Und).String(ast.StreamTypeMap[st])), logger)
 			return
 		}
-		jsonResponse(content, w, logger)
+		listNamesResponse(w, r, content, func(name string) (map[string]string, error) {
+			return sp.GetLabels(name, st)
+		})
 	case http.MethodPost:
 		v, err := decodeStatementDescriptor(r.Body)
 		if err != nil {
 			handleError(w, err, "Invalid body", logger)
 			return
 		}
-		content, err := streamProcessor.ExecStreamSql(v.Sql)
+		content, err := sp.ExecStreamSql(v.Sql)
 		if err != nil {
 			handleError(w, err, fmt.Sprintf("%s command error", cases.Title(language.Und).String(ast.StreamTypeMap[st])), logger)
 			return
@@ -505,17 +573,22 @@ func sourceManageHandler(w http.ResponseWriter, r *http.Request, st ast.StreamTy
 	defer r.Body.Close()
 	vars := mux.Vars(r)
 	name := vars["name"]
+	sp, err := requestStreamProcessor(r)
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
 
 	switch r.Method {
 	case http.MethodGet:
-		content, err := streamProcessor.DescStream(name, st)
+		content, err := sp.DescStream(name, st)
 		if err != nil {
 			handleError(w, err, fmt.Sprintf("describe %s error", ast.StreamTypeMap[st]), logger)
 			return
 		}
 		jsonResponse(content, w, logger)
 	case http.MethodDelete:
-		content, err := streamProcessor.DropStream(name, st)
+		content, err := sp.DropStream(name, st)
 		if err != nil {
 			handleError(w, err, fmt.Sprintf("delete %s error", ast.StreamTypeMap[st]), logger)
 			return
@@ -528,7 +601,7 @@ func sourceManageHandler(w http.ResponseWriter, r *http.Request, st ast.StreamTy
 			handleError(w, err, "Invalid body", logger)
 			return
 		}
-		content, err := streamProcessor.ExecReplaceStream(name, v.Sql, st)
+		content, err := sp.ExecReplaceStream(name, v.Sql, st)
 		if err != nil {
 			handleError(w, err, fmt.Sprintf("%s command error", cases.Title(language.Und).String(ast.StreamTypeMap[st])), logger)
 			return
@@ -576,9 +649,19 @@ func tableSchemaHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func sourceSchemaHandler(w http.ResponseWriter, r *http.Request, st ast.StreamType) {
+	defer r.Body.Close()
+	if r.Method == http.MethodPut {
+		sourceSchemaUpdate(w, r, st)
+		return
+	}
 	vars := mux.Vars(r)
 	name := vars["name"]
-	content, err := streamProcessor.GetInferredJsonSchema(name, st)
+	sp, err := requestStreamProcessor(r)
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	content, err := sp.GetInferredJsonSchema(name, st)
 	if err != nil {
 		handleError(w, err, fmt.Sprintf("get schema of %s error", ast.StreamTypeMap[st]), logger)
 		return
@@ -596,7 +679,7 @@ func rulesHandler(w http.ResponseWriter, r *http.Request) {
 			handleError(w, err, "Invalid body", logger)
 			return
 		}
-		id, err := createRule("", string(body))
+		id, err := createRule(namespace.Qualify(requestNamespace(r), ""), string(body))
 		if err != nil {
 			handleError(w, err, "", logger)
 			return
@@ -604,11 +687,17 @@ func rulesHandler(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusCreated)
 		fmt.Fprintf(w, "Rule %s was created successfully.", id)
 	case http.MethodGet:
-		content, err := getAllRulesWithStatus()
+		q, err := parseListQuery(r, "name", "status", "created")
+		if err != nil {
+			handleError(w, err, "", logger)
+			return
+		}
+		content, total, err := listRulesWithStatus(requestNamespace(r), q)
 		if err != nil {
 			handleError(w, err, "Show rules error", logger)
 			return
 		}
+		w.Header().Set(TotalCountHeader, strconv.Itoa(total))
 		jsonResponse(content, w, logger)
 	}
 }
@@ -616,8 +705,7 @@ func rulesHandler(w http.ResponseWriter, r *http.Request) {
 // describe or delete a rule
 func ruleHandler(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
-	vars := mux.Vars(r)
-	name := vars["name"]
+	name := requestRuleId(r)
 
 	switch r.Method {
 	case http.MethodGet:
@@ -688,7 +776,12 @@ func ruleHandler(w http.ResponseWriter, r *http.Request) {
 
 func getAllRuleStatusHandler(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
-	s, err := getAllRuleStatus()
+	selector, err := requestSelector(r)
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	s, err := getAllRuleStatus(requestNamespace(r), selector)
 	if err != nil {
 		handleError(w, err, "get rules status error", logger)
 		return
@@ -701,8 +794,7 @@ func getAllRuleStatusHandler(w http.ResponseWriter, r *http.Request) {
 // get status of a rule
 func getStatusRuleHandler(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
-	vars := mux.Vars(r)
-	name := vars["name"]
+	name := requestRuleId(r)
 
 	content, err := getRuleStatus(name)
 	if err != nil {
@@ -717,8 +809,7 @@ func getStatusRuleHandler(w http.ResponseWriter, r *http.Request) {
 // start a rule
 func startRuleHandler(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
-	vars := mux.Vars(r)
-	name := vars["name"]
+	name := requestRuleId(r)
 
 	err := startRule(name)
 	if err != nil {
@@ -732,10 +823,26 @@ func startRuleHandler(w http.ResponseWriter, r *http.Request) {
 // stop a rule
 func stopRuleHandler(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
-	vars := mux.Vars(r)
-	name := vars["name"]
-
-	result, err := stopRule(name)
+	name := requestRuleId(r)
+
+	var drainTimeout time.Duration
+	switch mode := r.URL.Query().Get("mode"); mode {
+	case "", "immediate":
+	case "drain":
+		drainTimeout = defaultDrainTimeout
+		if v := r.URL.Query().Get("timeout"); v != "" {
+			t, err := strconv.Atoi(v)
+			if err != nil || t <= 0 {
+				handleError(w, fmt.Errorf("invalid timeout %s, it must be a positive integer in milliseconds", v), "stop rule error", logger)
+				return
+			}
+			drainTimeout = time.Duration(t) * time.Millisecond
+		}
+	default:
+		handleError(w, fmt.Errorf("invalid stop mode %s, it must be immediate or drain", mode), "stop rule error", logger)
+		return
+	}
+	result, err := stopRule(name, drainTimeout)
 	if err != nil {
 		handleError(w, err, "stop rule error", logger)
 		return
@@ -747,8 +854,7 @@ func stopRuleHandler(w http.ResponseWriter, r *http.Request) {
 // restart a rule
 func restartRuleHandler(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
-	vars := mux.Vars(r)
-	name := vars["name"]
+	name := requestRuleId(r)
 
 	err := restartRule(name)
 	if err != nil {
@@ -762,8 +868,7 @@ func restartRuleHandler(w http.ResponseWriter, r *http.Request) {
 // get topo of a rule
 func getTopoRuleHandler(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
-	vars := mux.Vars(r)
-	name := vars["name"]
+	name := requestRuleId(r)
 
 	content, err := getRuleTopo(name)
 	if err != nil {
@@ -782,7 +887,7 @@ func validateRuleHandler(w http.ResponseWriter, r *http.Request) {
 		handleError(w, err, "Invalid body", logger)
 		return
 	}
-	sources, validate, err := validateRule("", string(body))
+	sources, validate, err := validateRule(namespace.Qualify(requestNamespace(r), ""), string(body))
 	if !validate {
 		w.WriteHeader(http.StatusUnprocessableEntity)
 		w.Write([]byte(err.Error()))
@@ -796,6 +901,32 @@ func validateRuleHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write(bs)
 }
 
+// plan a rule without creating it to verify the physical plan and the optimizations
+func validatePlanHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		handleError(w, err, "Invalid body", logger)
+		return
+	}
+	rule, err := ruleProcessor.GetRuleByJson(namespace.Qualify(requestNamespace(r), ""), string(body))
+	if err != nil {
+		handleError(w, err, "Invalid rule json", logger)
+		return
+	}
+	var info *planner.PlanInfo
+	err = infra.SafeRun(func() error {
+		info, err = planner.DryRun(rule)
+		return err
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	jsonResponse(info, w, logger)
+}
+
 type rulesetInfo struct {
 	Content  string `json:"content"`
 	FilePath string `json:"file"`
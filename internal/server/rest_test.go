@@ -16,29 +16,42 @@ package server
 
 import (
 	"bytes"
+	"encoding/gob"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/gorilla/mux"
 	"github.com/pingcap/failpoint"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/lf-edge/ekuiper/internal/backfill"
 	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/io/memory/pubsub"
+	"github.com/lf-edge/ekuiper/internal/io/snapshot"
+	"github.com/lf-edge/ekuiper/internal/io/view"
 	"github.com/lf-edge/ekuiper/internal/meta"
+	"github.com/lf-edge/ekuiper/internal/pkg/audit"
 	"github.com/lf-edge/ekuiper/internal/pkg/model"
 	"github.com/lf-edge/ekuiper/internal/pkg/store"
+	kvEncoding "github.com/lf-edge/ekuiper/internal/pkg/store/encoding"
 	"github.com/lf-edge/ekuiper/internal/processor"
 	"github.com/lf-edge/ekuiper/internal/testx"
 	"github.com/lf-edge/ekuiper/internal/topo/connection/factory"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/topo/planner"
 	"github.com/lf-edge/ekuiper/internal/topo/rule"
+	"github.com/lf-edge/ekuiper/internal/topo/state"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/ast"
 	"github.com/lf-edge/ekuiper/pkg/errorx"
@@ -49,6 +62,8 @@ func init() {
 	streamProcessor = processor.NewStreamProcessor()
 	ruleProcessor = processor.NewRuleProcessor()
 	rulesetProcessor = processor.NewRulesetProcessor(ruleProcessor, streamProcessor)
+	namespaceProcessor = processor.NewNamespaceProcessor()
+	pipelineProcessor = processor.NewPipelineProcessor(ruleProcessor, streamProcessor)
 	registry = &RuleRegistry{internal: make(map[string]*rule.RuleState)}
 	uploadsDb, _ = store.GetKV("uploads")
 	uploadsStatusDb, _ = store.GetKV("uploadsStatusDb")
@@ -71,14 +86,17 @@ func (suite *RestTestSuite) SetupTest() {
 	r := mux.NewRouter()
 	r.HandleFunc("/", rootHandler).Methods(http.MethodGet, http.MethodPost)
 	r.HandleFunc("/ping", pingHandler).Methods(http.MethodGet)
+	r.HandleFunc("/health/live", livenessHandler).Methods(http.MethodGet)
+	r.HandleFunc("/health/ready", readinessHandler).Methods(http.MethodGet)
 	r.HandleFunc("/streams", streamsHandler).Methods(http.MethodGet, http.MethodPost)
 	r.HandleFunc("/streamdetails", streamDetailsHandler).Methods(http.MethodGet)
 	r.HandleFunc("/streams/{name}", streamHandler).Methods(http.MethodGet, http.MethodDelete, http.MethodPut)
-	r.HandleFunc("/streams/{name}/schema", streamSchemaHandler).Methods(http.MethodGet)
+	r.HandleFunc("/streams/{name}/schema", streamSchemaHandler).Methods(http.MethodGet, http.MethodPut)
+	r.HandleFunc("/streams/{name}/schema/infer", streamSchemaInferHandler).Methods(http.MethodPost)
 	r.HandleFunc("/tables", tablesHandler).Methods(http.MethodGet, http.MethodPost)
 	r.HandleFunc("/tabledetails", tableDetailsHandler).Methods(http.MethodGet)
 	r.HandleFunc("/tables/{name}", tableHandler).Methods(http.MethodGet, http.MethodDelete, http.MethodPut)
-	r.HandleFunc("/tables/{name}/schema", tableSchemaHandler).Methods(http.MethodGet)
+	r.HandleFunc("/tables/{name}/schema", tableSchemaHandler).Methods(http.MethodGet, http.MethodPut)
 	r.HandleFunc("/rules", rulesHandler).Methods(http.MethodGet, http.MethodPost)
 	r.HandleFunc("/rules/{name}", ruleHandler).Methods(http.MethodDelete, http.MethodGet, http.MethodPut)
 	r.HandleFunc("/rules/{name}/status", getStatusRuleHandler).Methods(http.MethodGet)
@@ -87,9 +105,24 @@ func (suite *RestTestSuite) SetupTest() {
 	r.HandleFunc("/rules/{name}/restart", restartRuleHandler).Methods(http.MethodPost)
 	r.HandleFunc("/rules/{name}/topo", getTopoRuleHandler).Methods(http.MethodGet)
 	r.HandleFunc("/rules/{name}/reset_state", ruleStateHandler).Methods(http.MethodPut)
+	r.HandleFunc("/rules/{name}/snapshot", ruleSnapshotHandler).Methods(http.MethodGet, http.MethodPut)
 	r.HandleFunc("/rules/{name}/explain", explainRuleHandler).Methods(http.MethodGet)
+	r.HandleFunc("/rules/{name}/versions", ruleVersionsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/rules/{name}/versions/diff", ruleVersionDiffHandler).Methods(http.MethodGet)
+	r.HandleFunc("/rules/{name}/versions/{version}", ruleVersionHandler).Methods(http.MethodGet)
+	r.HandleFunc("/rules/{name}/versions/{version}/rollback", ruleRollbackHandler).Methods(http.MethodPost)
+	r.HandleFunc("/rules/{name}/tap", ruleTapHandler).Methods(http.MethodGet)
 	r.HandleFunc("/rules/validate", validateRuleHandler).Methods(http.MethodPost)
+	r.HandleFunc("/rules/validate-plan", validatePlanHandler).Methods(http.MethodPost)
+	r.HandleFunc("/rules/unittest", ruleUnitTestHandler).Methods(http.MethodPost)
 	r.HandleFunc("/rules/status/all", getAllRuleStatusHandler).Methods(http.MethodGet)
+	r.HandleFunc("/rules/bulk", bulkRuleHandler).Methods(http.MethodPost)
+	r.HandleFunc("/cluster/nodes", clusterHandler).Methods(http.MethodGet)
+	r.HandleFunc("/pipelines", pipelinesHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/pipelines/{name}", pipelineHandler).Methods(http.MethodGet, http.MethodDelete)
+	r.HandleFunc("/pipelines/{name}/status", pipelineStatusHandler).Methods(http.MethodGet)
+	r.HandleFunc("/pipelines/{name}/start", startPipelineHandler).Methods(http.MethodPost)
+	r.HandleFunc("/pipelines/{name}/stop", stopPipelineHandler).Methods(http.MethodPost)
 	r.HandleFunc("/ruletest", testRuleHandler).Methods(http.MethodPost)
 	r.HandleFunc("/ruletest/{name}/start", testRuleStartHandler).Methods(http.MethodPost)
 	r.HandleFunc("/ruletest/{name}", testRuleStopHandler).Methods(http.MethodDelete)
@@ -101,8 +134,21 @@ func (suite *RestTestSuite) SetupTest() {
 	r.HandleFunc("/data/export", configurationExportHandler).Methods(http.MethodGet, http.MethodPost)
 	r.HandleFunc("/data/import", configurationImportHandler).Methods(http.MethodPost)
 	r.HandleFunc("/data/import/status", configurationStatusHandler).Methods(http.MethodGet)
+	r.HandleFunc("/data/archive", archiveExportHandler).Methods(http.MethodGet)
+	r.HandleFunc("/data/archive/import", archiveImportHandler).Methods(http.MethodPost)
 	r.HandleFunc("/connection/websocket", connectionHandler).Methods(http.MethodGet, http.MethodPost, http.MethodDelete)
 	r.HandleFunc("/metadata/sinks/{name}/confKeys/{confKey}", sinkConfKeyHandler).Methods(http.MethodDelete, http.MethodPut)
+	r.HandleFunc("/snapshots", snapshotsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/snapshots/{name}", snapshotHandler).Methods(http.MethodGet, http.MethodDelete)
+	r.HandleFunc("/snapshots/{name}/{key}", snapshotRowHandler).Methods(http.MethodGet)
+	r.HandleFunc("/views", viewsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/views/{name}", viewHandler).Methods(http.MethodGet)
+	r.HandleFunc("/geofences", geofencesHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/geofences/{name}", geofenceHandler).Methods(http.MethodGet, http.MethodPut, http.MethodDelete)
+	r.HandleFunc("/sinks/{name}/replay", sinkReplayHandler).Methods(http.MethodPost)
+	r.HandleFunc("/audit", auditHandler).Methods(http.MethodGet)
+	namespaceRoutes(r)
+	r.Use(auditMiddleware)
 	suite.r = r
 }
 
@@ -118,6 +164,173 @@ func (suite *RestTestSuite) Test_Connection() {
 	assert.Equal(suite.T(), http.StatusOK, w.Code)
 }
 
+func (suite *RestTestSuite) Test_snapshotHandler() {
+	s := snapshot.GetSink()
+	require.NoError(suite.T(), s.Configure(map[string]interface{}{"table": "restSnapshot", "keyField": "id"}))
+	ctx := context.Background()
+	require.NoError(suite.T(), s.Open(ctx))
+	require.NoError(suite.T(), s.Collect(ctx, map[string]interface{}{"id": "d1", "temp": 20.5}))
+
+	req, _ := http.NewRequest(http.MethodGet, "/snapshots", bytes.NewBufferString(""))
+	w := httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"restSnapshot"`)
+
+	req, _ = http.NewRequest(http.MethodGet, "/snapshots/restSnapshot", bytes.NewBufferString(""))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Equal(suite.T(), `{"d1":{"id":"d1","temp":20.5}}`, w.Body.String())
+
+	req, _ = http.NewRequest(http.MethodGet, "/snapshots/restSnapshot/d1", bytes.NewBufferString(""))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Equal(suite.T(), `{"id":"d1","temp":20.5}`, w.Body.String())
+
+	req, _ = http.NewRequest(http.MethodGet, "/snapshots/restSnapshot/d2", bytes.NewBufferString(""))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	req, _ = http.NewRequest(http.MethodDelete, "/snapshots/restSnapshot", bytes.NewBufferString(""))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest(http.MethodGet, "/snapshots/restSnapshot", bytes.NewBufferString(""))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
+
+func (suite *RestTestSuite) Test_viewHandler() {
+	s := view.GetSink()
+	require.NoError(suite.T(), s.Configure(map[string]interface{}{"name": "restView"}))
+	ctx := context.Background().WithMeta("restViewRule", "op1", &state.MemoryStore{})
+	require.NoError(suite.T(), s.Open(ctx))
+	require.NoError(suite.T(), s.Collect(ctx, []map[string]interface{}{{"id": "d1", "avg": 20.5}}))
+
+	req, _ := http.NewRequest(http.MethodGet, "/views", bytes.NewBufferString(""))
+	w := httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Equal(suite.T(), `["restView"]`, w.Body.String())
+
+	req, _ = http.NewRequest(http.MethodGet, "/views/restView", bytes.NewBufferString(""))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	info := &view.Info{}
+	require.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), info))
+	assert.Equal(suite.T(), "restViewRule", info.Rule)
+	assert.Equal(suite.T(), []map[string]interface{}{{"id": "d1", "avg": 20.5}}, info.Rows)
+
+	require.NoError(suite.T(), s.Close(ctx))
+	req, _ = http.NewRequest(http.MethodGet, "/views/restView", bytes.NewBufferString(""))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
+
+func (suite *RestTestSuite) Test_geofenceHandler() {
+	req, _ := http.NewRequest(http.MethodPost, "/geofences", bytes.NewBufferString(`{"name":"restFence","center":{"lat":31.23,"lon":121.47},"radius":500}`))
+	w := httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+
+	req, _ = http.NewRequest(http.MethodPost, "/geofences", bytes.NewBufferString(`{"name":"restFence2","polygon":[{"lat":0,"lon":0},{"lat":1,"lon":1}]}`))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	req, _ = http.NewRequest(http.MethodGet, "/geofences", bytes.NewBufferString(""))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"restFence"`)
+
+	req, _ = http.NewRequest(http.MethodPut, "/geofences/restFence", bytes.NewBufferString(`{"name":"restFence","polygon":[{"lat":0,"lon":0},{"lat":0,"lon":1},{"lat":1,"lon":1}]}`))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest(http.MethodGet, "/geofences/restFence", bytes.NewBufferString(""))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Equal(suite.T(), `{"name":"restFence","polygon":[{"lat":0,"lon":0},{"lat":0,"lon":1},{"lat":1,"lon":1}]}`, w.Body.String())
+
+	req, _ = http.NewRequest(http.MethodDelete, "/geofences/restFence", bytes.NewBufferString(""))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest(http.MethodGet, "/geofences/restFence", bytes.NewBufferString(""))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
+
+func (suite *RestTestSuite) Test_sinkReplayHandler() {
+	file := filepath.Join(suite.T().TempDir(), "capture.jsonl")
+	require.NoError(suite.T(), os.WriteFile(file, []byte(`{"timestamp":1,"ruleId":"r1","sinkType":"log","data":{"a":1},"payload":"eyJhIjoxfQ=="}
+{"timestamp":2,"ruleId":"r1","sinkType":"log","data":{"a":2},"payload":"eyJhIjoyfQ=="}
+`), 0o600))
+	body, _ := json.Marshal(map[string]any{"file": file, "props": map[string]any{}})
+	req, _ := http.NewRequest(http.MethodPost, "/sinks/log/replay", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Equal(suite.T(), `{"count":2}`, w.Body.String())
+
+	req, _ = http.NewRequest(http.MethodPost, "/sinks/log/replay", bytes.NewBufferString(`{"props":{}}`))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func (suite *RestTestSuite) Test_healthHandler() {
+	req, _ := http.NewRequest(http.MethodGet, "/health/live", nil)
+	w := httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Equal(suite.T(), `{"status":"up"}`, w.Body.String())
+
+	serverReady.Store(true)
+	defer serverReady.Store(false)
+	req, _ = http.NewRequest(http.MethodGet, "/health/ready", nil)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	report := &HealthReport{}
+	require.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), report))
+	assert.Equal(suite.T(), HealthUp, report.Status)
+	names := make([]string, 0, len(report.Components))
+	for _, c := range report.Components {
+		names = append(names, c.Name)
+		assert.Equal(suite.T(), HealthUp, c.Status, c.Name)
+	}
+	assert.Subset(suite.T(), names, []string{"server", "store", "connections", "disk"})
+
+	threshold := conf.Config.Basic.MinFreeDiskSpace
+	conf.Config.Basic.MinFreeDiskSpace = math.MaxInt64
+	defer func() { conf.Config.Basic.MinFreeDiskSpace = threshold }()
+	req, _ = http.NewRequest(http.MethodGet, "/health/ready", nil)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusServiceUnavailable, w.Code)
+	report = &HealthReport{}
+	require.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), report))
+	assert.Equal(suite.T(), HealthDown, report.Status)
+	for _, c := range report.Components {
+		if c.Name == "disk" {
+			assert.Equal(suite.T(), HealthDown, c.Status)
+		}
+	}
+}
+
 func (suite *RestTestSuite) Test_rootHandler() {
 	req, _ := http.NewRequest(http.MethodPost, "/", bytes.NewBufferString("any"))
 	w := httptest.NewRecorder()
@@ -242,6 +455,58 @@ func (suite *RestTestSuite) TestRulesGetStateWrapper() {
 	require.True(suite.T(), len(w) > 0)
 }
 
+func (suite *RestTestSuite) Test_ruleSnapshotHandler() {
+	buf1 := bytes.NewBuffer([]byte(`{"sql":"CREATE stream snapStream() WITH (DATASOURCE=\"0\", TYPE=\"mqtt\")"}`))
+	req1, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/streams", buf1)
+	w1 := httptest.NewRecorder()
+	suite.r.ServeHTTP(w1, req1)
+	buf2 := bytes.NewBuffer([]byte(`{"id": "ruleSnap1","triggered": false,"sql": "select * from snapStream","actions": [{"log": {}}]}`))
+	req2, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/rules", buf2)
+	w2 := httptest.NewRecorder()
+	suite.r.ServeHTTP(w2, req2)
+	require.Equal(suite.T(), http.StatusCreated, w2.Code)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost:8080/rules/ruleSnap1/snapshot", bytes.NewBufferString(""))
+	w := httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	conf.Clock.(*clock.Mock).Set(time.UnixMilli(1000))
+	data, err := kvEncoding.Encode(&state.RuleSnapshot{
+		RuleId:       "ruleOld",
+		CheckpointId: 100,
+		States:       map[string]interface{}{"op1": map[string]interface{}{"count": 3}},
+	})
+	require.NoError(suite.T(), err)
+	req, _ = http.NewRequest(http.MethodPut, "http://localhost:8080/rules/ruleSnap1/snapshot", bytes.NewBuffer(data))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Equal(suite.T(), "Snapshot of checkpoint 100 from rule ruleOld is restored to rule ruleSnap1.", w.Body.String())
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/rules/ruleSnap1/snapshot", bytes.NewBufferString(""))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Equal(suite.T(), "application/octet-stream", w.Header().Get(ContentType))
+	snap := &state.RuleSnapshot{}
+	require.NoError(suite.T(), gob.NewDecoder(w.Body).Decode(snap))
+	assert.Equal(suite.T(), "ruleSnap1", snap.RuleId)
+	assert.Equal(suite.T(), int64(1000), snap.CheckpointId)
+	assert.Equal(suite.T(), map[string]interface{}{"op1": map[string]interface{}{"count": 3}}, snap.States)
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/rules/ruleNotExist/snapshot", bytes.NewBufferString(""))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	// The checkpoints are dropped with the rule
+	req, _ = http.NewRequest(http.MethodDelete, "http://localhost:8080/rules/ruleSnap1", bytes.NewBufferString(""))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+}
+
 func (suite *RestTestSuite) Test_rulesManageHandler() {
 	// Start rules
 	if rules, err := ruleProcessor.GetAllRules(); err != nil {
@@ -451,6 +716,20 @@ func (suite *RestTestSuite) Test_rulesManageHandler() {
 
 	assert.Equal(suite.T(), http.StatusNotFound, w1.Code)
 
+	// stop rule with invalid mode
+	req1, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules/rule321/stop?mode=pause", bytes.NewBufferString("any"))
+	w1 = httptest.NewRecorder()
+	suite.r.ServeHTTP(w1, req1)
+	assert.Equal(suite.T(), http.StatusBadRequest, w1.Code)
+
+	// stop rule with drain mode
+	req1, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules/rule321/stop?mode=drain&timeout=1000", bytes.NewBufferString("any"))
+	w1 = httptest.NewRecorder()
+	suite.r.ServeHTTP(w1, req1)
+	returnVal, _ = io.ReadAll(w1.Result().Body)
+	expect = `Rule rule321 was stopped.`
+	assert.Equal(suite.T(), expect, string(returnVal))
+
 	// update rule, will set rule to triggered
 	ruleJson = `{"id": "rule321","triggered": false,"sql": "select * from alert","actions": [{"nop": {}}]}`
 	buf2 = bytes.NewBuffer([]byte(ruleJson))
@@ -474,7 +753,7 @@ func (suite *RestTestSuite) Test_rulesManageHandler() {
 	suite.r.ServeHTTP(w1, req1)
 
 	returnVal, _ = io.ReadAll(w1.Result().Body)
-	expect = `{"triggered":true,"id":"rule321","sql":"select * from alert","actions":[{"nop":{}}],"options":{"debug":false,"logFilename":"","isEventTime":false,"lateTolerance":1000,"concurrency":1,"bufferLength":1024,"sendMetaToSink":false,"sendError":true,"qos":0,"checkpointInterval":300000,"restartStrategy":{"attempts":0,"delay":1000,"multiplier":2,"maxDelay":30000,"jitterFactor":0.1},"cron":"","duration":"","cronDatetimeRange":null}}`
+	expect = `{"triggered":true,"id":"rule321","sql":"select * from alert","actions":[{"nop":{}}],"options":{"debug":false,"logFilename":"","isEventTime":false,"lateTolerance":1000,"allowedLateness":0,"lateDataTopic":"","concurrency":1,"bufferLength":1024,"sendMetaToSink":false,"sendError":true,"qos":0,"checkpointInterval":300000,"fullCheckpointInterval":10,"enableAck":false,"earlyFireInterval":0,"earlyFireOnElement":false,"restartStrategy":{"attempts":0,"delay":1000,"multiplier":2,"maxDelay":30000,"jitterFactor":0.1},"cron":"","duration":"","cronDatetimeRange":null,"windowMemoryLimit":0,"stateStore":"","traceSampleRate":0}}`
 	assert.Equal(suite.T(), expect, string(returnVal))
 
 	// delete rule
@@ -947,6 +1226,81 @@ func (suite *RestTestSuite) TestGetAllRuleStatus() {
 	require.True(suite.T(), ok)
 }
 
+func (suite *RestTestSuite) TestNamespace() {
+	request := func(method, url, body string) (int, string) {
+		req, _ := http.NewRequest(method, "http://localhost:8080"+url, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		suite.r.ServeHTTP(w, req)
+		returnVal, _ := io.ReadAll(w.Result().Body)
+		return w.Code, string(returnVal)
+	}
+	code, _ := request(http.MethodGet, "/namespaces/nsTeam/streams", "")
+	require.Equal(suite.T(), http.StatusNotFound, code)
+	code, _ = request(http.MethodPost, "/namespaces", `{"name":"ns/team"}`)
+	require.Equal(suite.T(), http.StatusBadRequest, code)
+	code, _ = request(http.MethodPost, "/namespaces", `{"name":"nsTeam"}`)
+	require.Equal(suite.T(), http.StatusCreated, code)
+	code, body := request(http.MethodGet, "/namespaces", "")
+	require.Equal(suite.T(), http.StatusOK, code)
+	require.Equal(suite.T(), `["default","nsTeam"]`, body)
+
+	// The same stream and rule names are isolated in the namespace
+	code, _ = request(http.MethodPost, "/streams", `{"sql":"CREATE stream nsdemo() WITH (DATASOURCE=\"0\", TYPE=\"mqtt\")"}`)
+	require.Equal(suite.T(), http.StatusCreated, code)
+	defer request(http.MethodDelete, "/streams/nsdemo", "")
+	code, _ = request(http.MethodPost, "/namespaces/nsTeam/streams", `{"sql":"CREATE stream nsdemo() WITH (DATASOURCE=\"1\", TYPE=\"mqtt\")"}`)
+	require.Equal(suite.T(), http.StatusCreated, code)
+	code, _ = request(http.MethodPost, "/namespaces/nsTeam/streams", `{"sql":"CREATE stream nsdemo2() WITH (DATASOURCE=\"1\", TYPE=\"mqtt\")"}`)
+	require.Equal(suite.T(), http.StatusCreated, code)
+	code, body = request(http.MethodGet, "/namespaces/nsTeam/streams", "")
+	require.Equal(suite.T(), http.StatusOK, code)
+	var streams []string
+	require.NoError(suite.T(), json.Unmarshal([]byte(body), &streams))
+	require.ElementsMatch(suite.T(), []string{"nsdemo", "nsdemo2"}, streams)
+
+	ruleJson := `{"id":"nsRule","triggered":false,"sql":"select * from nsdemo","actions":[{"log":{}}]}`
+	code, _ = request(http.MethodPost, "/rules", ruleJson)
+	require.Equal(suite.T(), http.StatusCreated, code)
+	defer func() {
+		deleteRule("nsRule")
+		_, _ = ruleProcessor.ExecDrop("nsRule")
+	}()
+	code, body = request(http.MethodPost, "/namespaces/nsTeam/rules", ruleJson)
+	require.Equal(suite.T(), http.StatusCreated, code)
+	require.Equal(suite.T(), "Rule nsTeam/nsRule was created successfully.", body)
+	// The rule can only use the streams of its namespace
+	code, _ = request(http.MethodPost, "/namespaces/nsTeam/rules", `{"id":"nsRule2","triggered":false,"sql":"select * from demo456","actions":[{"log":{}}]}`)
+	require.Equal(suite.T(), http.StatusBadRequest, code)
+	code, body = request(http.MethodGet, "/namespaces/nsTeam/rules", "")
+	require.Equal(suite.T(), http.StatusOK, code)
+	require.Equal(suite.T(), `[{"id":"nsRule","name":"nsRule","status":"Stopped: canceled manually."}]`, body)
+	code, body = request(http.MethodGet, "/namespaces/nsTeam/rules/nsRule", "")
+	require.Equal(suite.T(), http.StatusOK, code)
+	require.Equal(suite.T(), ruleJson, body)
+	code, body = request(http.MethodGet, "/namespaces/nsTeam/rules/nsRule/status", "")
+	require.Equal(suite.T(), http.StatusOK, code)
+	require.Equal(suite.T(), `{"message":"Stopped: canceled manually.","status":"stopped"}`, body)
+	_, ok := registry.Load("nsTeam/nsRule")
+	require.True(suite.T(), ok)
+	code, _ = request(http.MethodGet, "/rules/nsRule", "")
+	require.Equal(suite.T(), http.StatusOK, code)
+
+	// Delete the namespace with all its definitions
+	code, _ = request(http.MethodDelete, "/namespaces/default", "")
+	require.Equal(suite.T(), http.StatusBadRequest, code)
+	code, _ = request(http.MethodDelete, "/namespaces/nsTeam", "")
+	require.Equal(suite.T(), http.StatusOK, code)
+	_, ok = registry.Load("nsTeam/nsRule")
+	require.False(suite.T(), ok)
+	require.False(suite.T(), ruleProcessor.ExecExists("nsTeam/nsRule"))
+	code, _ = request(http.MethodGet, "/namespaces/nsTeam/rules", "")
+	require.Equal(suite.T(), http.StatusNotFound, code)
+	code, _ = request(http.MethodGet, "/streams/nsdemo", "")
+	require.Equal(suite.T(), http.StatusOK, code)
+	_, ok = registry.Load("nsRule")
+	require.True(suite.T(), ok)
+}
+
 func (suite *RestTestSuite) TestSinkHiddenPassword() {
 	buf1 := bytes.NewBuffer([]byte(`{"sql":"CREATE stream demo78() WITH (DATASOURCE=\"0\", TYPE=\"mqtt\")"}`))
 	req1, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/streams", buf1)
@@ -974,3 +1328,310 @@ func (suite *RestTestSuite) TestSinkHiddenPassword() {
 	m := r.Actions[0]["mqtt"].(map[string]interface{})
 	require.Equal(suite.T(), "12345", m["password"])
 }
+
+func (suite *RestTestSuite) TestAudit() {
+	db, err := store.GetKV("auditTest")
+	require.NoError(suite.T(), err)
+	auditStore = audit.NewStore(db, 0, 0)
+	defer func() {
+		auditStore = nil
+		_ = db.Drop()
+	}()
+	request := func(method, url, body string) (int, string) {
+		req, _ := http.NewRequest(method, "http://localhost:8080"+url, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		suite.r.ServeHTTP(w, req)
+		returnVal, _ := io.ReadAll(w.Result().Body)
+		return w.Code, string(returnVal)
+	}
+	code, _ := request(http.MethodPost, "/streams", `{"sql":"CREATE stream auditDemo() WITH (DATASOURCE=\"0\", TYPE=\"mqtt\")"}`)
+	require.Equal(suite.T(), http.StatusCreated, code)
+	code, _ = request(http.MethodPut, "/streams/auditDemo", `{"sql":"CREATE stream auditDemo() WITH (DATASOURCE=\"1\", TYPE=\"mqtt\")"}`)
+	require.Equal(suite.T(), http.StatusOK, code)
+	code, _ = request(http.MethodDelete, "/streams/auditDemo", "")
+	require.Equal(suite.T(), http.StatusOK, code)
+	code, _ = request(http.MethodDelete, "/streams/auditDemo", "")
+	require.NotEqual(suite.T(), http.StatusOK, code)
+
+	code, body := request(http.MethodGet, "/audit?resource=stream&name=auditDemo", "")
+	require.Equal(suite.T(), http.StatusOK, code)
+	var records []*audit.Record
+	require.NoError(suite.T(), json.Unmarshal([]byte(body), &records))
+	require.Len(suite.T(), records, 4)
+	assert.Equal(suite.T(), "delete", records[0].Action)
+	assert.Equal(suite.T(), audit.ResultFailure, records[0].Result)
+	assert.NotEmpty(suite.T(), records[0].Error)
+	assert.Equal(suite.T(), audit.ResultSuccess, records[1].Result)
+	assert.Equal(suite.T(), "update", records[2].Action)
+	assert.Equal(suite.T(), "anonymous", records[2].User)
+	assert.Equal(suite.T(), map[string]*audit.Change{"sql": {
+		Old: `CREATE stream auditDemo() WITH (DATASOURCE="0", TYPE="mqtt")`,
+		New: `CREATE stream auditDemo() WITH (DATASOURCE="1", TYPE="mqtt")`,
+	}}, records[2].Diff)
+	assert.Equal(suite.T(), "create", records[3].Action)
+
+	code, _ = request(http.MethodGet, "/audit?since=abc", "")
+	require.Equal(suite.T(), http.StatusBadRequest, code)
+}
+
+func (suite *RestTestSuite) TestRuleVersionRollback() {
+	request := func(method, url, body string) (int, string) {
+		req, _ := http.NewRequest(method, "http://localhost:8080"+url, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		suite.r.ServeHTTP(w, req)
+		returnVal, _ := io.ReadAll(w.Result().Body)
+		return w.Code, string(returnVal)
+	}
+	code, _ := request(http.MethodPost, "/streams", `{"sql":"CREATE stream versionDemo() WITH (DATASOURCE=\"0\", TYPE=\"mqtt\")"}`)
+	require.Equal(suite.T(), http.StatusCreated, code)
+	defer request(http.MethodDelete, "/streams/versionDemo", "")
+	code, _ = request(http.MethodPost, "/rules", `{"id":"versionRule","triggered":false,"sql":"select * from versionDemo","actions":[{"log":{}}]}`)
+	require.Equal(suite.T(), http.StatusCreated, code)
+	defer request(http.MethodDelete, "/rules/versionRule", "")
+	code, _ = request(http.MethodPut, "/rules/versionRule", `{"id":"versionRule","triggered":false,"sql":"select a from versionDemo","actions":[{"log":{}}]}`)
+	require.Equal(suite.T(), http.StatusOK, code)
+
+	code, body := request(http.MethodGet, "/rules/versionRule/versions", "")
+	require.Equal(suite.T(), http.StatusOK, code)
+	var versions []*ruleVersionResp
+	require.NoError(suite.T(), json.Unmarshal([]byte(body), &versions))
+	require.Len(suite.T(), versions, 2)
+	assert.Equal(suite.T(), 2, versions[0].Version)
+	assert.Equal(suite.T(), "select a from versionDemo", versions[0].Rule["sql"])
+
+	code, body = request(http.MethodGet, "/rules/versionRule/versions/diff", "")
+	require.Equal(suite.T(), http.StatusOK, code)
+	assert.Equal(suite.T(), `{"sql":{"old":"select * from versionDemo","new":"select a from versionDemo"}}`, body)
+	code, _ = request(http.MethodGet, "/rules/versionRule/versions/diff?from=x", "")
+	assert.Equal(suite.T(), http.StatusBadRequest, code)
+
+	code, _ = request(http.MethodPost, "/rules/versionRule/versions/9/rollback", "")
+	assert.Equal(suite.T(), http.StatusNotFound, code)
+	code, body = request(http.MethodPost, "/rules/versionRule/versions/1/rollback", "")
+	require.Equal(suite.T(), http.StatusOK, code)
+	assert.Equal(suite.T(), "Rule versionRule was rolled back to version 1.", body)
+	rule, err := ruleProcessor.GetRuleById("versionRule")
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "select * from versionDemo", rule.Sql)
+	assert.False(suite.T(), rule.Triggered)
+	code, body = request(http.MethodGet, "/rules/versionRule/versions/3", "")
+	require.Equal(suite.T(), http.StatusOK, code)
+	assert.Contains(suite.T(), body, `"version":3`)
+}
+
+func (suite *RestTestSuite) TestValidatePlan() {
+	request := func(method, url, body string) (int, string) {
+		req, _ := http.NewRequest(method, "http://localhost:8080"+url, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		suite.r.ServeHTTP(w, req)
+		returnVal, _ := io.ReadAll(w.Result().Body)
+		return w.Code, string(returnVal)
+	}
+	code, _ := request(http.MethodPost, "/streams", `{"sql":"CREATE stream planDemo(a BIGINT, b STRING) WITH (DATASOURCE=\"0\", TYPE=\"mqtt\")"}`)
+	require.Equal(suite.T(), http.StatusCreated, code)
+	defer request(http.MethodDelete, "/streams/planDemo", "")
+
+	code, body := request(http.MethodPost, "/rules/validate-plan", `{"id":"planRule","sql":"select a from planDemo where a > 1","actions":[{"log":{}}]}`)
+	require.Equal(suite.T(), http.StatusOK, code)
+	info := &planner.PlanInfo{}
+	require.NoError(suite.T(), json.Unmarshal([]byte(body), info))
+	assert.Equal(suite.T(), []string{"source_planDemo"}, info.Topo.Sources)
+	assert.Contains(suite.T(), info.LogicalPlan, "FilterPlan")
+	assert.Contains(suite.T(), info.Optimizations, "columnPruner")
+	// The rule is not created
+	assert.False(suite.T(), ruleProcessor.ExecExists("planRule"))
+
+	code, _ = request(http.MethodPost, "/rules/validate-plan", `{"id":"planRule","sql":"select c from notExist","actions":[{"log":{}}]}`)
+	assert.Equal(suite.T(), http.StatusUnprocessableEntity, code)
+	code, _ = request(http.MethodPost, "/rules/validate-plan", `{"id":"planRule","sql":"select a from planDemo"}`)
+	assert.Equal(suite.T(), http.StatusBadRequest, code)
+}
+
+func (suite *RestTestSuite) TestRuleUnitTest() {
+	request := func(body string) (int, string) {
+		req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/rules/unittest", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		suite.r.ServeHTTP(w, req)
+		returnVal, _ := io.ReadAll(w.Result().Body)
+		return w.Code, string(returnVal)
+	}
+	p := processor.NewStreamProcessor()
+	_, _ = p.ExecStmt("DROP STREAM caseDemo")
+	_, err := p.ExecStmt(`CREATE STREAM caseDemo (a BIGINT) WITH (DATASOURCE="demo", TYPE="mqtt", FORMAT="json")`)
+	require.NoError(suite.T(), err)
+	defer p.ExecStmt("DROP STREAM caseDemo")
+
+	rule := `{"id":"caseRule","sql":"SELECT count(*) AS c FROM caseDemo GROUP BY TumblingWindow(ss, 1)","actions":[{"log":{}}]}`
+	inputs := `{"caseDemo":[{"timestamp":100,"message":{"a":1}},{"timestamp":500,"message":{"a":2}},{"timestamp":1200,"message":{"a":3}}]}`
+	code, body := request(`{"rule":` + rule + `,"inputs":` + inputs + `,"expected":[[{"c":2}],[{"c":1}]],"end":2000}`)
+	require.Equal(suite.T(), http.StatusOK, code)
+	report := &backfill.CaseReport{}
+	require.NoError(suite.T(), json.Unmarshal([]byte(body), report))
+	assert.True(suite.T(), report.Pass)
+	assert.Empty(suite.T(), report.Diff)
+	// The rule is not created
+	assert.False(suite.T(), ruleProcessor.ExecExists("caseRule"))
+
+	code, body = request(`{"rule":` + rule + `,"inputs":` + inputs + `,"expected":[[{"c":2}],[{"c":2}]],"end":2000}`)
+	require.Equal(suite.T(), http.StatusOK, code)
+	report = &backfill.CaseReport{}
+	require.NoError(suite.T(), json.Unmarshal([]byte(body), report))
+	assert.False(suite.T(), report.Pass)
+	assert.Equal(suite.T(), []*backfill.OutputDiff{{
+		Index:    1,
+		Expected: []any{map[string]any{"c": float64(2)}},
+		Actual:   []any{map[string]any{"c": float64(1)}},
+	}}, report.Diff)
+
+	code, _ = request(`{"inputs":` + inputs + `}`)
+	assert.Equal(suite.T(), http.StatusBadRequest, code)
+	code, _ = request(`{"rule":` + rule + `,"inputs":{}}`)
+	assert.Equal(suite.T(), http.StatusBadRequest, code)
+}
+
+func (suite *RestTestSuite) TestRuleTap() {
+	request := func(url string) (int, string) {
+		req, _ := http.NewRequest(http.MethodGet, "http://localhost:8080"+url, nil)
+		w := httptest.NewRecorder()
+		suite.r.ServeHTTP(w, req)
+		returnVal, _ := io.ReadAll(w.Result().Body)
+		return w.Code, string(returnVal)
+	}
+	code, body := request("/rules/tapRule/tap")
+	assert.Equal(suite.T(), http.StatusBadRequest, code)
+	assert.Contains(suite.T(), body, "node is required")
+	code, body = request("/rules/tapRule/tap?node=op_2_filter&seconds=1000")
+	assert.Equal(suite.T(), http.StatusBadRequest, code)
+	assert.Contains(suite.T(), body, "invalid seconds 1000")
+	code, body = request("/rules/tapRule/tap?node=op_2_filter")
+	assert.Equal(suite.T(), http.StatusBadRequest, code)
+	assert.Contains(suite.T(), body, "Rule tapRule is not found in registry")
+}
+
+func (suite *RestTestSuite) TestStreamSchemaInfer() {
+	request := func(method, url, body string) (int, string) {
+		req, _ := http.NewRequest(method, "http://localhost:8080"+url, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		suite.r.ServeHTTP(w, req)
+		returnVal, _ := io.ReadAll(w.Result().Body)
+		return w.Code, string(returnVal)
+	}
+	code, body := request(http.MethodPost, "/streams", `{"sql":"CREATE STREAM schemaDemo () WITH (TYPE=\"memory\", DATASOURCE=\"schemaTopic\", FORMAT=\"json\")"}`)
+	require.Equal(suite.T(), http.StatusCreated, code, body)
+	defer request(http.MethodDelete, "/streams/schemaDemo", "")
+
+	code, body = request(http.MethodPost, "/streams/schemaDemo/schema/infer", `{"seconds":100}`)
+	assert.Equal(suite.T(), http.StatusBadRequest, code)
+	assert.Contains(suite.T(), body, "invalid seconds 100")
+	code, _ = request(http.MethodPost, "/streams/notExist/schema/infer", "")
+	assert.Equal(suite.T(), http.StatusNotFound, code)
+
+	pubsub.CreatePub("schemaTopic")
+	defer pubsub.RemovePub("schemaTopic")
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ctx := context.Background()
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				pubsub.Produce(ctx, "schemaTopic", map[string]interface{}{"id": i, "temp": 20.5, "name": "a"})
+			}
+		}
+	}()
+	code, body = request(http.MethodPost, "/streams/schemaDemo/schema/infer", `{"seconds":10,"limit":3}`)
+	require.Equal(suite.T(), http.StatusOK, code, body)
+	r := &processor.SchemaInference{}
+	require.NoError(suite.T(), json.Unmarshal([]byte(body), r))
+	assert.Equal(suite.T(), 3, r.Samples)
+	assert.Equal(suite.T(), "id bigint, name string, temp float", r.Definition)
+
+	code, body = request(http.MethodPut, "/streams/schemaDemo/schema", fmt.Sprintf(`{"definition":%q}`, r.Definition))
+	require.Equal(suite.T(), http.StatusOK, code, body)
+	assert.Equal(suite.T(), "Stream schemaDemo is replaced.", body)
+	code, body = request(http.MethodPut, "/streams/schemaDemo/schema", `{"definition":"tag string","merge":true}`)
+	require.Equal(suite.T(), http.StatusOK, code, body)
+	code, body = request(http.MethodGet, "/streams/schemaDemo/schema", "")
+	require.Equal(suite.T(), http.StatusOK, code, body)
+	assert.JSONEq(suite.T(), `{"id":{"type":"bigint","Selected":false},"name":{"type":"string","Selected":false},"temp":{"type":"float","Selected":false},"tag":{"type":"string","Selected":false}}`, body)
+}
+
+func (suite *RestTestSuite) TestPipeline() {
+	request := func(method, url, body string) (int, string) {
+		req, _ := http.NewRequest(method, "http://localhost:8080"+url, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		suite.r.ServeHTTP(w, req)
+		returnVal, _ := io.ReadAll(w.Result().Body)
+		return w.Code, string(returnVal)
+	}
+	code, body := request(http.MethodPost, "/streams", `{"sql":"CREATE STREAM plSrc () WITH (TYPE=\"memory\", DATASOURCE=\"plIn\", FORMAT=\"json\")"}`)
+	require.Equal(suite.T(), http.StatusCreated, code, body)
+	defer request(http.MethodDelete, "/streams/plSrc", "")
+	code, body = request(http.MethodPost, "/streams", `{"sql":"CREATE STREAM plMid () WITH (TYPE=\"memory\", DATASOURCE=\"plMid\", FORMAT=\"json\")"}`)
+	require.Equal(suite.T(), http.StatusCreated, code, body)
+	defer request(http.MethodDelete, "/streams/plMid", "")
+
+	// The rules are created together or not at all
+	code, body = request(http.MethodPost, "/pipelines", `{"id":"pl1","topics":["plMid"],"rules":[
+		{"id":"plRule1","sql":"SELECT * FROM plSrc","actions":[{"memory":{"topic":"plMid"}}]},
+		{"id":"plRule2","sql":"SELECT * FROM plMid","actions":[{"notExistSink":{}}]}]}`)
+	require.Equal(suite.T(), http.StatusBadRequest, code)
+	assert.Contains(suite.T(), body, "create rule plRule2 error")
+	assert.False(suite.T(), ruleProcessor.ExecExists("plRule1"))
+	_, ok := registry.Load("plRule1")
+	assert.False(suite.T(), ok)
+	code, body = request(http.MethodPost, "/pipelines", `{"id":"pl1","topics":["plOut"],"rules":[
+		{"id":"plRule1","sql":"SELECT * FROM plSrc","actions":[{"memory":{"topic":"plMid"}}]}]}`)
+	require.Equal(suite.T(), http.StatusBadRequest, code)
+	assert.Contains(suite.T(), body, "pipeline topic plOut is not published by any rule")
+
+	pipelineJson := `{"id":"pl1","topics":["plMid"],"rules":[
+		{"id":"plRule2","sql":"SELECT * FROM plMid","actions":[{"log":{}}]},
+		{"id":"plRule1","sql":"SELECT * FROM plSrc","actions":[{"memory":{"topic":"plMid"}}]}]}`
+	code, body = request(http.MethodPost, "/pipelines", pipelineJson)
+	require.Equal(suite.T(), http.StatusCreated, code, body)
+	assert.Equal(suite.T(), "Pipeline pl1 was created successfully.", body)
+	defer deletePipeline("pl1")
+	code, _ = request(http.MethodPost, "/pipelines", pipelineJson)
+	require.Equal(suite.T(), http.StatusBadRequest, code)
+	code, body = request(http.MethodGet, "/pipelines/pl1", "")
+	require.Equal(suite.T(), http.StatusOK, code)
+	assert.Equal(suite.T(), pipelineJson, body)
+	// The rules start asynchronously and are sorted from the upstream to the downstream
+	pipelineStatusIs := func(status string) func() bool {
+		return func() bool {
+			s, err := getPipelineStatus("pl1")
+			return err == nil && s.Status == status
+		}
+	}
+	require.Eventually(suite.T(), pipelineStatusIs("running"), time.Second, 10*time.Millisecond)
+	code, body = request(http.MethodGet, "/pipelines/pl1/status", "")
+	require.Equal(suite.T(), http.StatusOK, code)
+	assert.Equal(suite.T(), `{"id":"pl1","status":"running","rules":[{"id":"plRule1","status":"Running"},{"id":"plRule2","status":"Running"}]}`, body)
+
+	code, body = request(http.MethodPost, "/pipelines/pl1/stop", "")
+	require.Equal(suite.T(), http.StatusOK, code)
+	assert.Equal(suite.T(), "Pipeline pl1 was stopped", body)
+	code, body = request(http.MethodGet, "/pipelines", "")
+	require.Equal(suite.T(), http.StatusOK, code)
+	assert.Equal(suite.T(), `[{"id":"pl1","status":"stopped","rules":[{"id":"plRule1","status":"Stopped: canceled manually."},{"id":"plRule2","status":"Stopped: canceled manually."}]}]`, body)
+	_, err := stopRule("plRule1", 0)
+	require.NoError(suite.T(), err)
+	require.NoError(suite.T(), startRule("plRule2"))
+	require.Eventually(suite.T(), pipelineStatusIs("degraded"), time.Second, 10*time.Millisecond)
+	code, _ = request(http.MethodPost, "/pipelines/pl1/start", "")
+	require.Equal(suite.T(), http.StatusOK, code)
+	require.Eventually(suite.T(), pipelineStatusIs("running"), time.Second, 10*time.Millisecond)
+
+	code, body = request(http.MethodDelete, "/pipelines/pl1", "")
+	require.Equal(suite.T(), http.StatusOK, code)
+	assert.Equal(suite.T(), "Pipeline pl1 is dropped.", body)
+	assert.False(suite.T(), ruleProcessor.ExecExists("plRule1"))
+	assert.False(suite.T(), ruleProcessor.ExecExists("plRule2"))
+	code, _ = request(http.MethodGet, "/pipelines/pl1/status", "")
+	require.Equal(suite.T(), http.StatusNotFound, code)
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/io/sink"
 	"github.com/lf-edge/ekuiper/internal/pkg/model"
+	"github.com/lf-edge/ekuiper/internal/pkg/namespace"
 	"github.com/lf-edge/ekuiper/internal/topo/rule"
 	"github.com/lf-edge/ekuiper/pkg/cast"
 	"github.com/lf-edge/ekuiper/pkg/infra"
@@ -189,7 +190,7 @@ func (t *Server) StartRule(name string, reply *string) error {
 }
 
 func (t *Server) StopRule(name string, reply *string) error {
-	*reply, _ = stopRule(name)
+	*reply, _ = stopRule(name, 0)
 	return nil
 }
 
@@ -213,7 +214,7 @@ func (t *Server) DescRule(name string, reply *string) error {
 }
 
 func (t *Server) ShowRules(_ int, reply *string) error {
-	r, err := getAllRulesWithStatus()
+	r, err := getAllRulesWithStatus(namespace.Default)
 	if err != nil {
 		return fmt.Errorf("Show rule error : %s.", err)
 	}
@@ -258,6 +259,19 @@ func (t *Server) ValidateRule(rule *model.RPCArgDesc, reply *string) error {
 	return nil
 }
 
+func (t *Server) TestRule(arg *model.RPCArgDesc, reply *string) error {
+	report, err := runRuleCase(context.Background(), "", []byte(arg.Json))
+	if err != nil {
+		return fmt.Errorf("Test rule error : %s.", err)
+	}
+	bs, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	*reply = string(bs)
+	return nil
+}
+
 func (t *Server) Import(file string, reply *string) error {
 	f, err := os.Open(file)
 	if err != nil {
@@ -12,7 +12,7 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-//go:build (!core && !wasmedge) || (rpc && portable && plugin)
+//go:build (!core && !wasmedge && !wazero) || (rpc && portable && plugin)
 
 package server
 
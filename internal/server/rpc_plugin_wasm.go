@@ -12,7 +12,7 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-//go:build !core && wasmedge
+//go:build !core && (wasmedge || wazero)
 
 package server
 
@@ -31,6 +31,7 @@ import (
 	"github.com/lf-edge/ekuiper/internal/plugin/portable"
 	"github.com/lf-edge/ekuiper/internal/schema"
 	"github.com/lf-edge/ekuiper/internal/service"
+	"github.com/lf-edge/ekuiper/internal/topo/rule"
 )
 
 type ServerTestSuite struct {
@@ -225,11 +226,18 @@ func TestServerTestSuite(t *testing.T) {
 
 func TestGetStoppedMessage(t *testing.T) {
 	message := `"123","123","123"`
-	r, err := getStoppedState(message)
+	r, err := getStoppedState(message, nil)
 	require.NoError(t, err)
 	v := map[string]string{}
 	err = json.Unmarshal([]byte(r), &v)
 	require.NoError(t, err)
 	require.Equal(t, "stopped", v["status"])
 	require.Equal(t, message, v["message"])
+
+	r, err = getStoppedState("Stopped: connection refused.", []rule.RestartRecord{
+		{Timestamp: 1000, Error: "connection refused", Attempt: 1, Delay: 1000},
+		{Timestamp: 2000, Error: "connection refused", Attempt: 0, Delay: 0},
+	})
+	require.NoError(t, err)
+	require.Equal(t, `{"message":"Stopped: connection refused.","restartHistory":[{"timestamp":1000,"error":"connection refused","attempt":1,"delay":1000},{"timestamp":2000,"error":"connection refused","attempt":0,"delay":0}],"status":"stopped"}`, r)
 }
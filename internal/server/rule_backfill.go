@@ -0,0 +1,147 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/lf-edge/ekuiper/internal/backfill"
+	"github.com/lf-edge/ekuiper/internal/pkg/async"
+	"github.com/lf-edge/ekuiper/internal/pkg/namespace"
+)
+
+const (
+	backfillAsyncTask = "backfill"
+	replayAsyncTask   = "replay"
+	// the max duration to run a rule test case
+	ruleCaseTimeout = time.Minute
+)
+
+// run the rule against the history data as an async task
+func ruleBackfillHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	name := requestRuleId(r)
+	ru, err := ruleProcessor.GetRuleById(name)
+	if err != nil {
+		handleError(w, err, "backfill rule error", logger)
+		return
+	}
+	req := &backfill.Request{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, err, "Invalid body: Error decoding json", logger)
+		return
+	}
+	taskID := generateTaskID(backfillAsyncTask + "-" + name)
+	subCtx, err := async.GlobalAsyncManager.RegisterTask(taskID)
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	go func() {
+		async.GlobalAsyncManager.StartTask(taskID)
+		result, err := backfill.Run(subCtx, ru, req)
+		if err != nil {
+			// Keep the status of the cancelled task
+			if subCtx.Err() == nil {
+				async.GlobalAsyncManager.TaskFailed(taskID, err)
+			}
+			return
+		}
+		b, _ := json.Marshal(result)
+		async.GlobalAsyncManager.FinishTask(taskID, string(b))
+	}()
+	w.WriteHeader(http.StatusOK)
+	jsonResponse(&asyncTaskResponse{TaskID: taskID}, w, logger)
+}
+
+// replay the record files through the rule as an async task, the outputs are the result of the task
+func ruleReplayHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	name := requestRuleId(r)
+	ru, err := ruleProcessor.GetRuleById(name)
+	if err != nil {
+		handleError(w, err, "replay rule error", logger)
+		return
+	}
+	req := &backfill.ReplayRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, err, "Invalid body: Error decoding json", logger)
+		return
+	}
+	taskID := generateTaskID(replayAsyncTask + "-" + name)
+	subCtx, err := async.GlobalAsyncManager.RegisterTask(taskID)
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	go func() {
+		async.GlobalAsyncManager.StartTask(taskID)
+		result, err := backfill.Replay(subCtx, ru, req)
+		if err != nil {
+			if subCtx.Err() == nil {
+				async.GlobalAsyncManager.TaskFailed(taskID, err)
+			}
+			return
+		}
+		b, _ := json.Marshal(result)
+		async.GlobalAsyncManager.FinishTask(taskID, string(b))
+	}()
+	w.WriteHeader(http.StatusOK)
+	jsonResponse(&asyncTaskResponse{TaskID: taskID}, w, logger)
+}
+
+type ruleCaseRequest struct {
+	// Rule is the definition of the rule to test, which is not created
+	Rule json.RawMessage `json:"rule"`
+	backfill.Case
+}
+
+// run the unit test case of a rule and report the diff of the outputs
+func ruleUnitTestHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		handleError(w, err, "Invalid body", logger)
+		return
+	}
+	report, err := runRuleCase(r.Context(), requestNamespace(r), body)
+	if err != nil {
+		handleError(w, err, "test rule error", logger)
+		return
+	}
+	jsonResponse(report, w, logger)
+}
+
+func runRuleCase(ctx context.Context, ns string, body []byte) (*backfill.CaseReport, error) {
+	req := &ruleCaseRequest{}
+	if err := json.Unmarshal(body, req); err != nil {
+		return nil, fmt.Errorf("Invalid body: Error decoding json: %v", err)
+	}
+	if len(req.Rule) == 0 {
+		return nil, fmt.Errorf("rule is required")
+	}
+	ru, err := ruleProcessor.GetRuleByJson(namespace.Qualify(ns, ""), string(req.Rule))
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, ruleCaseTimeout)
+	defer cancel()
+	return backfill.RunCase(ctx, ru, &req.Case)
+}
@@ -0,0 +1,185 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/lf-edge/ekuiper/internal/pkg/labels"
+	"github.com/lf-edge/ekuiper/internal/pkg/namespace"
+	"github.com/lf-edge/ekuiper/pkg/errorx"
+)
+
+// bulkRuleRequest runs an action on the rules by the id list or by the label selector
+type bulkRuleRequest struct {
+	// Action is start, stop, restart or delete
+	Action string   `json:"action"`
+	Rules  []string `json:"rules,omitempty"`
+	// Selector selects the rules which have all the labels
+	Selector map[string]string `json:"selector,omitempty"`
+	// Mode and Timeout are the stop mode and the drain timeout in milliseconds like stopping a rule
+	Mode    string `json:"mode,omitempty"`
+	Timeout int    `json:"timeout,omitempty"`
+}
+
+type bulkRuleResult struct {
+	Id      string `json:"id"`
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type bulkRuleResponse struct {
+	Total     int               `json:"total"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Results   []*bulkRuleResult `json:"results"`
+}
+
+// bulkRuleActions run the action on a rule by the runtime id and return the message
+var bulkRuleActions = map[string]func(id string, req *bulkRuleRequest) (string, error){
+	"start": func(id string, _ *bulkRuleRequest) (string, error) {
+		if err := startRule(id); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Rule %s was started", id), nil
+	},
+	"stop": func(id string, req *bulkRuleRequest) (string, error) {
+		var drainTimeout time.Duration
+		if req.Mode == "drain" {
+			drainTimeout = defaultDrainTimeout
+			if req.Timeout > 0 {
+				drainTimeout = time.Duration(req.Timeout) * time.Millisecond
+			}
+		}
+		return stopRule(id, drainTimeout)
+	},
+	"restart": func(id string, _ *bulkRuleRequest) (string, error) {
+		if err := restartRule(id); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Rule %s was restarted", id), nil
+	},
+	"delete": func(id string, _ *bulkRuleRequest) (string, error) {
+		deleteRule(id)
+		return ruleProcessor.ExecDrop(id)
+	},
+}
+
+func (req *bulkRuleRequest) validate() error {
+	if _, ok := bulkRuleActions[req.Action]; !ok {
+		return fmt.Errorf("invalid action %s, it must be start, stop, restart or delete", req.Action)
+	}
+	if len(req.Rules) > 0 && len(req.Selector) > 0 {
+		return errors.New("only one of rules and selector can be set")
+	}
+	if len(req.Rules) == 0 && len(req.Selector) == 0 {
+		return errors.New("rules or selector is required")
+	}
+	if err := labels.Validate(req.Selector); err != nil {
+		return fmt.Errorf("invalid selector: %v", err)
+	}
+	switch req.Mode {
+	case "", "immediate", "drain":
+	default:
+		return fmt.Errorf("invalid stop mode %s, it must be immediate or drain", req.Mode)
+	}
+	if req.Timeout < 0 {
+		return fmt.Errorf("invalid timeout %d, it must be a positive integer in milliseconds", req.Timeout)
+	}
+	return nil
+}
+
+// selectRules returns the runtime ids of the rules in the namespace which have all the labels of the selector
+func selectRules(ns string, selector map[string]string) ([]string, error) {
+	ids, err := ruleIdsIn(ns)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(ids)
+	result := make([]string, 0)
+	for _, id := range ids {
+		r, err := ruleProcessor.GetRuleById(id)
+		if err != nil {
+			continue
+		}
+		if labels.Match(r.Labels, selector) {
+			result = append(result, id)
+		}
+	}
+	return result, nil
+}
+
+// bulkRules runs the action on the rules one by one and collects the result of each rule
+func bulkRules(ns string, req *bulkRuleRequest) (*bulkRuleResponse, error) {
+	var ids []string
+	if len(req.Selector) > 0 {
+		var err error
+		if ids, err = selectRules(ns, req.Selector); err != nil {
+			return nil, err
+		}
+	} else {
+		ids = make([]string, len(req.Rules))
+		for i, name := range req.Rules {
+			ids[i] = namespace.Qualify(ns, name)
+		}
+	}
+	action := bulkRuleActions[req.Action]
+	resp := &bulkRuleResponse{Total: len(ids), Results: make([]*bulkRuleResult, len(ids))}
+	for i, id := range ids {
+		_, localId := namespace.Split(id)
+		result := &bulkRuleResult{Id: localId}
+		var err error
+		if !ruleProcessor.ExecExists(id) {
+			err = errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("Rule %s is not found.", id))
+		} else {
+			result.Message, err = action(id, req)
+		}
+		if err != nil {
+			result.Message = ""
+			result.Error = err.Error()
+			resp.Failed++
+		} else {
+			resp.Succeeded++
+		}
+		resp.Results[i] = result
+	}
+	logger.Infof("bulk %s %d rules, %d failed", req.Action, resp.Total, resp.Failed)
+	return resp, nil
+}
+
+// start, stop, restart or delete multiple rules in one call
+func bulkRuleHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	req := &bulkRuleRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, err, "Invalid body", logger)
+		return
+	}
+	if err := req.validate(); err != nil {
+		handleError(w, err, "bulk rules error", logger)
+		return
+	}
+	resp, err := bulkRules(requestNamespace(r), req)
+	if err != nil {
+		handleError(w, err, "bulk rules error", logger)
+		return
+	}
+	jsonResponse(resp, w, logger)
+}
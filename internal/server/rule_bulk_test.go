@@ -0,0 +1,97 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+func (suite *RestTestSuite) TestBulkRules() {
+	request := func(method, url, body string) (int, string) {
+		req, _ := http.NewRequest(method, "http://localhost:8080"+url, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		suite.r.ServeHTTP(w, req)
+		b, _ := io.ReadAll(w.Result().Body)
+		return w.Code, string(b)
+	}
+	code, _ := request(http.MethodPost, "/streams", `{"sql":"CREATE STREAM bulkDemo () WITH (TYPE=\"memory\", DATASOURCE=\"bulkTopic\", FORMAT=\"json\")"}`)
+	require.Equal(suite.T(), http.StatusCreated, code)
+	rules := map[string]string{"bulkRule1": "gw1", "bulkRule2": "gw1", "bulkRule3": "gw2"}
+	defer func() {
+		for id := range rules {
+			_ = deleteRule(id)
+			_, _ = ruleProcessor.ExecDrop(id)
+		}
+		_, _ = streamProcessor.DropStream("bulkDemo", ast.TypeStream)
+	}()
+	for id, gw := range rules {
+		code, body := request(http.MethodPost, "/rules", fmt.Sprintf(`{"id":"%s","labels":{"gateway":"%s"},"sql":"SELECT * FROM bulkDemo","actions":[{"nop":{}}]}`, id, gw))
+		require.Equal(suite.T(), http.StatusCreated, code, body)
+	}
+	bulk := func(body string) *bulkRuleResponse {
+		code, b := request(http.MethodPost, "/rules/bulk", body)
+		require.Equal(suite.T(), http.StatusOK, code, b)
+		resp := &bulkRuleResponse{}
+		require.NoError(suite.T(), json.Unmarshal([]byte(b), resp))
+		return resp
+	}
+	// The expected state of the rule
+	triggered := func(id string) bool {
+		r, err := ruleProcessor.GetRuleById(id)
+		require.NoError(suite.T(), err)
+		return r.Triggered
+	}
+
+	resp := bulk(`{"action":"stop","selector":{"gateway":"gw1"}}`)
+	assert.Equal(suite.T(), &bulkRuleResponse{Total: 2, Succeeded: 2, Results: []*bulkRuleResult{
+		{Id: "bulkRule1", Message: "Rule bulkRule1 was stopped."},
+		{Id: "bulkRule2", Message: "Rule bulkRule2 was stopped."},
+	}}, resp)
+	assert.False(suite.T(), triggered("bulkRule1"))
+	assert.True(suite.T(), triggered("bulkRule3"))
+
+	resp = bulk(`{"action":"start","rules":["bulkRule1","bulkNone"]}`)
+	assert.Equal(suite.T(), 2, resp.Total)
+	assert.Equal(suite.T(), 1, resp.Succeeded)
+	assert.Equal(suite.T(), 1, resp.Failed)
+	assert.Equal(suite.T(), "Rule bulkNone is not found.", resp.Results[1].Error)
+	assert.True(suite.T(), triggered("bulkRule1"))
+
+	resp = bulk(`{"action":"delete","selector":{"gateway":"gw2"}}`)
+	assert.Equal(suite.T(), 1, resp.Succeeded)
+	_, err := ruleProcessor.GetRuleJson("bulkRule3")
+	assert.Error(suite.T(), err)
+
+	for _, body := range []string{
+		`{"action":"pause","rules":["bulkRule1"]}`,
+		`{"action":"stop"}`,
+		`{"action":"stop","rules":["bulkRule1"],"selector":{"gateway":"gw1"}}`,
+		`{"action":"stop","rules":["bulkRule1"],"mode":"later"}`,
+		`{"action":"stop","selector":{"gateway":"gw 1"}}`,
+	} {
+		code, _ = request(http.MethodPost, "/rules/bulk", body)
+		assert.Equal(suite.T(), http.StatusBadRequest, code, body)
+	}
+}
@@ -16,16 +16,21 @@ package server
 
 import (
 	"bytes"
+	"cmp"
 	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/io/memory/pubsub"
 	"github.com/lf-edge/ekuiper/internal/meta"
+	"github.com/lf-edge/ekuiper/internal/pkg/namespace"
 	"github.com/lf-edge/ekuiper/internal/pkg/store"
 	"github.com/lf-edge/ekuiper/internal/server/promMetrics"
+	"github.com/lf-edge/ekuiper/internal/topo"
 	"github.com/lf-edge/ekuiper/internal/topo/planner"
 	"github.com/lf-edge/ekuiper/internal/topo/rule"
 	"github.com/lf-edge/ekuiper/internal/xsql"
@@ -91,8 +96,9 @@ func createRule(name, ruleJson string) (id string, err error) {
 	}
 
 	// Validate the topo
+	lr := localRule(r)
 	err = infra.SafeRun(func() error {
-		rs, err = createRuleState(r)
+		rs, err = createRuleState(lr)
 		return err
 	})
 	if err != nil {
@@ -110,9 +116,12 @@ func createRule(name, ruleJson string) (id string, err error) {
 	if err != nil {
 		return r.Id, fmt.Errorf("store the rule error: %v", err)
 	}
+	if isPartitioned(r) {
+		go applyPartitions(r)
+	}
 
 	// Start the rule asyncly
-	if r.Triggered {
+	if lr.Triggered {
 		go func() {
 			panicOrError := infra.SafeRun(func() error {
 				// Start the rule which runs async
@@ -141,6 +150,13 @@ func createRuleState(r *api.Rule) (*rule.RuleState, error) {
 func recoverRule(r *api.Rule) string {
 	var rs *rule.RuleState = nil
 	var err error = nil
+	triggered := r.Triggered
+	partitions := 0
+	if isPartitioned(r) {
+		partitions = r.Options.Partitions
+		applyPartitions(r)
+	}
+	r = localRule(r)
 	// Validate the topo
 	panicOrError := infra.SafeRun(func() error {
 		rs, err = createRuleState(r)
@@ -153,6 +169,12 @@ func recoverRule(r *api.Rule) string {
 		registry.Store(r.Id, rs)
 	}
 	if !r.Triggered {
+		if triggered && partitions > 0 {
+			return fmt.Sprintf("Rule %s runs in %d partitions.", r.Id, partitions)
+		}
+		if triggered && panicOrError == nil {
+			return fmt.Sprintf("Rule %s is placed on node %s.", r.Id, ruleOwner(r))
+		}
 		return fmt.Sprintf("Rule %s was stopped.", r.Id)
 	} else {
 		panicOrError := infra.SafeRun(func() error {
@@ -272,10 +294,11 @@ func updateRule(ruleId, ruleJson string, replacePasswd bool) error {
 		}
 	}
 	if rs, ok := registry.Load(r.Id); ok {
-		err := rs.UpdateTopo(r)
+		err := rs.UpdateTopo(localRule(r))
 		if err != nil {
 			return err
 		}
+		applyPartitions(r)
 		_, err = ruleProcessor.ExecReplaceRuleState(rs.RuleId, r.Triggered)
 		return err
 	} else {
@@ -286,7 +309,10 @@ func updateRule(ruleId, ruleJson string, replacePasswd bool) error {
 func deleteRule(name string) (result string) {
 	if rs, ok := registry.Delete(name); ok {
 		rs.Close()
+		dropPartitions(name, 0)
 		deleteRuleMetrics(name)
+		pubsub.DropSchemas(name)
+		pubsub.DropQueues(name)
 		result = fmt.Sprintf("Rule %s was deleted.", name)
 	} else {
 		result = fmt.Sprintf("Rule %s was not found.", name)
@@ -319,9 +345,10 @@ func reRunRule(name string, isInternal bool) error {
 				return err
 			} else {
 				rs.Rule = rule
+				applyPartitions(rule)
 			}
 		}
-		return rs.UpdateTopo(rs.Rule)
+		return rs.UpdateTopo(localRule(rs.Rule))
 	}
 }
 
@@ -335,15 +362,23 @@ func stopRuleInternal(name string) {
 	}
 }
 
-func stopRule(name string) (result string, err error) {
+// stopRule stops the rule. If drainTimeout is positive, the data in flight is sent out before stopping until the timeout.
+func stopRule(name string, drainTimeout time.Duration) (result string, err error) {
 	if rs, ok := registry.Load(name); ok {
-		err = rs.Stop()
+		if drainTimeout > 0 {
+			err = rs.StopWithDrain(drainTimeout)
+		} else {
+			err = rs.Stop()
+		}
 		if err != nil {
 			conf.Log.Warn(err)
 		}
-		_, err = ruleProcessor.ExecReplaceRuleState(name, false)
+		var r *api.Rule
+		r, err = ruleProcessor.ExecReplaceRuleState(name, false)
 		if err != nil {
 			conf.Log.Warnf("stop rule found error: %s", err.Error())
+		} else {
+			applyPartitions(r)
 		}
 		result = fmt.Sprintf("Rule %s was stopped.", name)
 	} else {
@@ -357,18 +392,25 @@ func restartRule(name string) error {
 	return reRunRule(name, false)
 }
 
-func getAllRuleStatus() (string, error) {
-	rules, err := ruleProcessor.GetAllRules()
+// getAllRuleStatus returns the status of the rules in the namespace which match the label selector
+func getAllRuleStatus(ns string, selector map[string]string) (string, error) {
+	rules, err := ruleIdsIn(ns)
 	if err != nil {
 		return "", err
 	}
+	if selector != nil {
+		if rules, err = selectRules(ns, selector); err != nil {
+			return "", err
+		}
+	}
 	m := make(map[string]ruleExceptionStatus)
 	for _, ruleID := range rules {
 		s, err := getRuleExceptionStatus(ruleID)
 		if err != nil {
 			return "", err
 		}
-		m[ruleID] = s
+		_, id := namespace.Split(ruleID)
+		m[id] = s
 	}
 	b, _ := json.Marshal(m)
 	return string(b), nil
@@ -427,6 +469,14 @@ type ruleExceptionStatus struct {
 
 func getRuleStatus(name string) (string, error) {
 	if rs, ok := registry.Load(name); ok {
+		if isPartitioned(rs.Rule) {
+			// The rule in the registry is never triggered, read the stored one for the triggered status
+			r, err := ruleProcessor.GetRuleById(name)
+			if err != nil {
+				return "", err
+			}
+			return getPartitionedRuleStatus(r)
+		}
 		result, err := rs.GetState()
 		if err != nil {
 			return "", err
@@ -434,11 +484,29 @@ func getRuleStatus(name string) (string, error) {
 		if result == "Running" {
 			keys, values := (*rs.Topology).GetMetrics()
 			metrics := "{"
-			metrics += `"status": "running",`
+			health := rs.GetHealth()
+			if health != nil && health.Status == topo.HealthDegraded {
+				metrics += `"status": "degraded",`
+				if r, err := json.Marshal(health.Reasons); err == nil {
+					metrics += fmt.Sprintf(`"degradedReasons": %s,`, r)
+				}
+			} else {
+				metrics += `"status": "running",`
+			}
+			if health != nil && len(health.Errors) > 0 {
+				if e, err := json.Marshal(health.Errors); err == nil {
+					metrics += fmt.Sprintf(`"errors": %s,`, e)
+				}
+			}
 			lastStart, lastStop, nextStart := rs.GetScheduleTimestamp()
 			metrics += fmt.Sprintf(`"lastStartTimestamp": "%v",`, lastStart)
 			metrics += fmt.Sprintf(`"lastStopTimestamp": "%v",`, lastStop)
 			metrics += fmt.Sprintf(`"nextStopTimestamp": "%v",`, nextStart)
+			if restarts := rs.GetRestartHistory(); len(restarts) > 0 {
+				if h, err := json.Marshal(restarts); err == nil {
+					metrics += fmt.Sprintf(`"restartHistory": %s,`, h)
+				}
+			}
 			for i, key := range keys {
 				value := values[i]
 				switch value.(type) {
@@ -455,8 +523,10 @@ func getRuleStatus(name string) (string, error) {
 			} else {
 				result = dst.String()
 			}
+		} else if owner := ruleOwner(rs.Rule); owner != "" && owner != ruleCluster.c.Self() {
+			return getRemoteState(owner)
 		} else {
-			return getStoppedState(result)
+			return getStoppedState(result, rs.GetRestartHistory())
 		}
 		return result, nil
 	} else {
@@ -464,11 +534,14 @@ func getRuleStatus(name string) (string, error) {
 	}
 }
 
-func getStoppedState(message string) (string, error) {
-	s := map[string]string{
+func getStoppedState(message string, restarts []rule.RestartRecord) (string, error) {
+	s := map[string]any{
 		"status":  "stopped",
 		"message": message,
 	}
+	if len(restarts) > 0 {
+		s["restartHistory"] = restarts
+	}
 	re, err := json.Marshal(s)
 	if err != nil {
 		return "", err
@@ -476,30 +549,108 @@ func getStoppedState(message string) (string, error) {
 	return string(re), nil
 }
 
-func getAllRulesWithStatus() ([]map[string]interface{}, error) {
-	ruleIds, err := ruleProcessor.GetAllRules()
+// getRemoteState is the status of the rule placed on another node in the cluster mode
+func getRemoteState(node string) (string, error) {
+	re, err := json.Marshal(map[string]any{
+		"status":  "remote",
+		"node":    node,
+		"message": fmt.Sprintf("Rule is placed on node %s.", node),
+	})
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+	return string(re), nil
+}
+
+func getAllRulesWithStatus(ns string) ([]map[string]interface{}, error) {
+	result, _, err := listRulesWithStatus(ns, &listQuery{})
+	return result, err
+}
+
+// ruleListItem is a rule in the list before reading its status
+type ruleListItem struct {
+	id      string
+	localId string
+	name    string
+	status  string
+	created int64
+}
+
+// listRulesWithStatus returns the page of the rules matching the query and the total count. The status is only read
+// for the rules in the page unless sorted by the status.
+func listRulesWithStatus(ns string, q *listQuery) ([]map[string]interface{}, int, error) {
+	ruleIds, err := ruleIdsIn(ns)
+	if err != nil {
+		return nil, 0, err
 	}
 	sort.Strings(ruleIds)
-	result := make([]map[string]interface{}, len(ruleIds))
-	for i, id := range ruleIds {
-		ruleName := id
+	var created map[string]int64
+	if q.sort == "created" {
+		if created, err = ruleProcessor.GetRulesCreated(ns); err != nil {
+			return nil, 0, err
+		}
+	}
+	items := make([]*ruleListItem, 0, len(ruleIds))
+	for _, id := range ruleIds {
+		_, localId := namespace.Split(id)
+		item := &ruleListItem{id: id, localId: localId, name: localId, created: created[id]}
 		rule, _ := ruleProcessor.GetRuleById(id)
-		if rule != nil && rule.Name != "" {
-			ruleName = rule.Name
+		var l map[string]string
+		if rule != nil {
+			if rule.Name != "" {
+				item.name = rule.Name
+			}
+			l = rule.Labels
 		}
-		s, err := getRuleState(id)
-		if err != nil {
-			s = fmt.Sprintf("error: %s", err)
+		if !q.match(item.localId, item.name) || !q.matchLabels(l) {
+			continue
+		}
+		if q.sort == "status" {
+			item.status = ruleStateOf(id)
+		}
+		items = append(items, item)
+	}
+	sortBy(q, items, map[string]func(a, b *ruleListItem) int{
+		"name":    func(a, b *ruleListItem) int { return strings.Compare(a.name, b.name) },
+		"status":  func(a, b *ruleListItem) int { return strings.Compare(a.status, b.status) },
+		"created": func(a, b *ruleListItem) int { return cmp.Compare(a.created, b.created) },
+	})
+	pageItems := page(q, items)
+	result := make([]map[string]interface{}, len(pageItems))
+	for i, item := range pageItems {
+		s := item.status
+		if q.sort != "status" {
+			s = ruleStateOf(item.id)
 		}
 		result[i] = map[string]interface{}{
-			"id":     id,
-			"name":   ruleName,
+			"id":     item.localId,
+			"name":   item.name,
 			"status": s,
 		}
+		if created != nil {
+			result[i]["created"] = item.created
+		}
+		if rs, ok := registry.Load(item.id); ok {
+			if s == "Running" {
+				if h := rs.GetHealth(); h != nil {
+					result[i]["health"] = h.Status
+				}
+			}
+			if owner := ruleOwner(rs.Rule); owner != "" && !isPartitioned(rs.Rule) {
+				result[i]["node"] = owner
+			}
+		}
 	}
-	return result, nil
+	return result, len(items), nil
+}
+
+// ruleStateOf returns the state of the rule or the error message
+func ruleStateOf(id string) string {
+	s, err := getRuleState(id)
+	if err != nil {
+		s = fmt.Sprintf("error: %s", err)
+	}
+	return s
 }
 
 type ruleWrapper struct {
@@ -508,7 +659,7 @@ type ruleWrapper struct {
 }
 
 func getAllRulesWithState() ([]ruleWrapper, error) {
-	ruleIds, err := ruleProcessor.GetAllRules()
+	ruleIds, err := allRuleIds()
 	if err != nil {
 		return nil, err
 	}
@@ -526,6 +677,13 @@ func getAllRulesWithState() ([]ruleWrapper, error) {
 
 func getRuleState(name string) (string, error) {
 	if rs, ok := registry.Load(name); ok {
+		if isPartitioned(rs.Rule) {
+			r, err := ruleProcessor.GetRuleById(name)
+			if err != nil {
+				return "", err
+			}
+			return getPartitionedRuleState(r), nil
+		}
 		return rs.GetState()
 	} else {
 		return "", fmt.Errorf("Rule %s is not found in registry", name)
@@ -558,7 +716,8 @@ func validateRule(name, ruleJson string) ([]string, bool, error) {
 	var sources []string
 	if len(rule.Sql) > 0 {
 		stmt, _ := xsql.GetStatementFromSql(rule.Sql)
-		s, err := store.GetKV("stream")
+		ns, _ := namespace.Split(rule.Id)
+		s, err := store.GetKV(namespace.Table("stream", ns))
 		if err != nil {
 			return nil, false, err
 		}
@@ -23,6 +23,7 @@ import (
 	"github.com/lf-edge/ekuiper/internal/binder/function"
 	"github.com/lf-edge/ekuiper/internal/binder/io"
 	"github.com/lf-edge/ekuiper/internal/meta"
+	"github.com/lf-edge/ekuiper/internal/pkg/namespace"
 	store2 "github.com/lf-edge/ekuiper/internal/pkg/store"
 	"github.com/lf-edge/ekuiper/internal/plugin"
 	"github.com/lf-edge/ekuiper/internal/processor"
@@ -78,7 +79,8 @@ func ruleTraverse(rule *api.Rule, de *dependencies) {
 		if err != nil {
 			return
 		}
-		store, err := store2.GetKV("stream")
+		ns, _ := namespace.Split(rule.Id)
+		store, err := store2.GetKV(namespace.Table("stream", ns))
 		if err != nil {
 			return
 		}
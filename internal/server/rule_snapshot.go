@@ -0,0 +1,66 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/lf-edge/ekuiper/internal/topo/rule"
+	"github.com/lf-edge/ekuiper/internal/topo/state"
+)
+
+// export the operator states of the last checkpoint of a rule or restore them into a stopped rule
+func ruleSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	name := requestRuleId(r)
+	ru, err := ruleProcessor.GetRuleById(name)
+	if err != nil {
+		handleError(w, err, "rule snapshot error", logger)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		data, err := state.ExportSnapshot(name, ru.Options.StateStore)
+		if err != nil {
+			handleError(w, err, "export rule snapshot error", logger)
+			return
+		}
+		w.Header().Set(ContentType, "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.snapshot", name))
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		_, _ = w.Write(data)
+	case http.MethodPut:
+		// The running rule keeps its states in memory and would overwrite the restored ones
+		if st, err := getRuleState(name); err == nil && st == rule.RuleStarted {
+			handleError(w, fmt.Errorf("rule %s is running, stop it before restoring the snapshot", name), "restore rule snapshot error", logger)
+			return
+		}
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			handleError(w, err, "Invalid body", logger)
+			return
+		}
+		snapshot, err := state.RestoreSnapshot(name, ru.Options.StateStore, data)
+		if err != nil {
+			handleError(w, err, "restore rule snapshot error", logger)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "Snapshot of checkpoint %d from rule %s is restored to rule %s.", snapshot.CheckpointId, snapshot.RuleId, name)
+	}
+}
@@ -19,7 +19,6 @@ import (
 	"fmt"
 	"net/http"
 
-	"github.com/gorilla/mux"
 	"github.com/pingcap/failpoint"
 
 	"github.com/lf-edge/ekuiper/internal/topo/rule"
@@ -35,8 +34,7 @@ const (
 
 func ruleStateHandler(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
-	vars := mux.Vars(r)
-	ruleID := vars["name"]
+	ruleID := requestRuleId(r)
 	req := &ruleStateUpdateRequest{}
 	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
 		handleError(w, err, "", logger)
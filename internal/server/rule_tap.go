@@ -0,0 +1,120 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/lf-edge/ekuiper/internal/topo/rule"
+)
+
+const (
+	defaultTapSeconds = 30
+	maxTapSeconds     = 600
+	tapWriteTimeout   = 5 * time.Second
+)
+
+var tapUpgrader = websocket.Upgrader{
+	// always allowed any origin
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// tap the output of a node of a running rule for several seconds and stream the tuples over websocket
+func ruleTapHandler(w http.ResponseWriter, r *http.Request) {
+	name := requestRuleId(r)
+	nodeName := r.URL.Query().Get("node")
+	if nodeName == "" {
+		handleError(w, fmt.Errorf("node is required"), "", logger)
+		return
+	}
+	seconds := defaultTapSeconds
+	if v := r.URL.Query().Get("seconds"); v != "" {
+		var err error
+		seconds, err = strconv.Atoi(v)
+		if err != nil || seconds <= 0 || seconds > maxTapSeconds {
+			handleError(w, fmt.Errorf("invalid seconds %s, it must be an integer in (0, %d]", v, maxTapSeconds), "", logger)
+			return
+		}
+	}
+	st, err := getRuleState(name)
+	if err != nil {
+		handleError(w, err, "tap rule error", logger)
+		return
+	}
+	rs, ok := registry.Load(name)
+	if st != rule.RuleStarted || !ok || rs.Topology == nil {
+		handleError(w, fmt.Errorf("rule %s is not running", name), "tap rule error", logger)
+		return
+	}
+	tp := rs.Topology
+	records, remove, err := tp.Tap(nodeName)
+	if err != nil {
+		handleError(w, err, "tap rule error", logger)
+		return
+	}
+	defer remove()
+	c, err := tapUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has replied the error
+		logger.Errorf("tap rule %s upgrade websocket error: %v", name, err)
+		return
+	}
+	defer c.Close()
+	logger.Infof("tap node %s of rule %s for %d seconds", nodeName, name, seconds)
+
+	// Read to handle the close message from the client
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := c.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	timer := time.NewTimer(time.Duration(seconds) * time.Second)
+	defer timer.Stop()
+	var ruleDone <-chan struct{}
+	if ctx := tp.GetContext(); ctx != nil {
+		ruleDone = ctx.Done()
+	}
+	reason := "tap finished"
+loop:
+	for {
+		select {
+		case rec := <-records:
+			_ = c.SetWriteDeadline(time.Now().Add(tapWriteTimeout))
+			if err := c.WriteJSON(rec); err != nil {
+				logger.Infof("tap rule %s stopped by write error: %v", name, err)
+				return
+			}
+		case <-timer.C:
+			break loop
+		case <-ruleDone:
+			reason = "rule stopped"
+			break loop
+		case <-closed:
+			return
+		}
+	}
+	_ = c.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, reason), time.Now().Add(tapWriteTimeout))
+}
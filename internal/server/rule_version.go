@@ -0,0 +1,174 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lf-edge/ekuiper/internal/processor"
+	"github.com/lf-edge/ekuiper/pkg/errorx"
+	"github.com/lf-edge/ekuiper/pkg/hidden"
+)
+
+type ruleVersionResp struct {
+	Version   int                    `json:"version"`
+	Timestamp int64                  `json:"timestamp"`
+	Rule      map[string]interface{} `json:"rule"`
+}
+
+func toRuleVersionResp(v *processor.RuleVersion) (*ruleVersionResp, error) {
+	m := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(v.Rule), &m); err != nil {
+		return nil, err
+	}
+	return &ruleVersionResp{Version: v.Version, Timestamp: v.Timestamp, Rule: hidden.HiddenPassword(m)}, nil
+}
+
+func requestVersion(r *http.Request, key string) (int, error) {
+	v, err := strconv.Atoi(mux.Vars(r)[key])
+	if err != nil {
+		return 0, fmt.Errorf("invalid version %s", mux.Vars(r)[key])
+	}
+	return v, nil
+}
+
+// list the saved versions of a rule from the newest to the oldest
+func ruleVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	name := requestRuleId(r)
+	if !ruleProcessor.ExecExists(name) {
+		handleError(w, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("Rule %s is not found.", name)), "", logger)
+		return
+	}
+	versions, err := ruleProcessor.GetRuleVersions(name)
+	if err != nil {
+		handleError(w, err, "list rule versions error", logger)
+		return
+	}
+	result := make([]*ruleVersionResp, 0, len(versions))
+	for i := len(versions) - 1; i >= 0; i-- {
+		v, err := toRuleVersionResp(versions[i])
+		if err != nil {
+			handleError(w, err, "list rule versions error", logger)
+			return
+		}
+		result = append(result, v)
+	}
+	jsonResponse(result, w, logger)
+}
+
+func ruleVersionHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	version, err := requestVersion(r, "version")
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	v, err := ruleProcessor.GetRuleVersion(requestRuleId(r), version)
+	if err != nil {
+		handleError(w, err, "get rule version error", logger)
+		return
+	}
+	resp, err := toRuleVersionResp(v)
+	if err != nil {
+		handleError(w, err, "get rule version error", logger)
+		return
+	}
+	jsonResponse(resp, w, logger)
+}
+
+// diff the changed fields of two versions. The default is the latest version and its previous one
+func ruleVersionDiffHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	name := requestRuleId(r)
+	versions, err := ruleProcessor.GetRuleVersions(name)
+	if err != nil {
+		handleError(w, err, "diff rule versions error", logger)
+		return
+	}
+	if len(versions) == 0 {
+		handleError(w, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("Rule %s has no version.", name)), "", logger)
+		return
+	}
+	to := versions[len(versions)-1].Version
+	from := to - 1
+	for k, p := range map[string]*int{"from": &from, "to": &to} {
+		if v := r.URL.Query().Get(k); v != "" {
+			if *p, err = strconv.Atoi(v); err != nil {
+				handleError(w, fmt.Errorf("invalid %s version %s", k, v), "", logger)
+				return
+			}
+		}
+	}
+	diff, err := ruleProcessor.DiffRuleVersions(name, from, to)
+	if err != nil {
+		handleError(w, err, "diff rule versions error", logger)
+		return
+	}
+	for k, c := range diff {
+		c.Old = hidden.HiddenPassword(map[string]interface{}{k: c.Old})[k]
+		c.New = hidden.HiddenPassword(map[string]interface{}{k: c.New})[k]
+	}
+	jsonResponse(diff, w, logger)
+}
+
+// roll back the definition of a rule to a saved version. The running rule is restarted with the definition.
+func ruleRollbackHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	name := requestRuleId(r)
+	version, err := requestVersion(r, "version")
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	current, err := ruleProcessor.GetRuleById(name)
+	if err != nil {
+		handleError(w, err, "Rule not found", logger)
+		return
+	}
+	v, err := ruleProcessor.GetRuleVersion(name, version)
+	if err != nil {
+		handleError(w, err, "rollback rule error", logger)
+		return
+	}
+	// Only the definition is rolled back, keep the rule running or stopped as is
+	m := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(v.Rule), &m); err != nil {
+		handleError(w, err, "rollback rule error", logger)
+		return
+	}
+	m["triggered"] = current.Triggered
+	bs, err := json.Marshal(m)
+	if err != nil {
+		handleError(w, err, "rollback rule error", logger)
+		return
+	}
+	ruleJson := string(bs)
+	if err := updateRule(name, ruleJson, false); err != nil {
+		handleError(w, err, "rollback rule error", logger)
+		return
+	}
+	if _, err := ruleProcessor.ExecUpdate(name, ruleJson); err != nil {
+		handleError(w, err, "rollback rule error, suggest to delete it and recreate", logger)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Rule %s was rolled back to version %d.", name, version)
+}
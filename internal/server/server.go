@@ -42,6 +42,7 @@ import (
 	"github.com/lf-edge/ekuiper/internal/processor"
 	"github.com/lf-edge/ekuiper/internal/server/promMetrics"
 	"github.com/lf-edge/ekuiper/internal/topo/connection/factory"
+	"github.com/lf-edge/ekuiper/internal/topo/quota"
 	"github.com/lf-edge/ekuiper/internal/topo/rule"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/ast"
@@ -57,6 +58,8 @@ var (
 	ruleProcessor          *processor.RuleProcessor
 	streamProcessor        *processor.StreamProcessor
 	rulesetProcessor       *processor.RulesetProcessor
+	namespaceProcessor     *processor.NamespaceProcessor
+	pipelineProcessor      *processor.PipelineProcessor
 	ruleMigrationProcessor *RuleMigrationProcessor
 	stopSignal             chan struct{}
 )
@@ -113,6 +116,7 @@ func getStoreConfigByKuiperConfig(c *conf.KuiperConf) (*store.StoreConf, error)
 	sc := &store.StoreConf{
 		Type:         c.Store.Type,
 		ExtStateType: c.Store.ExtStateType,
+		StateType:    c.Store.StateType,
 		RedisConfig: definition.RedisConfig{
 			Host:     c.Store.Redis.Host,
 			Port:     c.Store.Redis.Port,
@@ -154,6 +158,8 @@ func StartUp(Version string) {
 	ruleProcessor = processor.NewRuleProcessor()
 	streamProcessor = processor.NewStreamProcessor()
 	rulesetProcessor = processor.NewRulesetProcessor(ruleProcessor, streamProcessor)
+	namespaceProcessor = processor.NewNamespaceProcessor()
+	pipelineProcessor = processor.NewPipelineProcessor(ruleProcessor, streamProcessor)
 	ruleMigrationProcessor = NewRuleMigrationProcessor(ruleProcessor, streamProcessor)
 	sysMetrics = NewMetrics()
 
@@ -177,10 +183,17 @@ func StartUp(Version string) {
 	initRuleset()
 
 	registry = &RuleRegistry{internal: make(map[string]*rule.RuleState)}
+	if err := initCluster(); err != nil {
+		panic(err)
+	}
+	if err := initConfigSync(); err != nil {
+		panic(err)
+	}
 	// Start lookup tables
 	streamProcessor.RecoverLookupTable()
+	recoverNamespaceLookupTables()
 	// Start rules
-	if rules, err := ruleProcessor.GetAllRules(); err != nil {
+	if rules, err := allRuleIds(); err != nil {
 		logger.Infof("Start rules error: %s", err)
 	} else {
 		logger.Info("Starting rules")
@@ -201,6 +214,10 @@ func StartUp(Version string) {
 	exit := make(chan struct{})
 	go runScheduleRuleChecker(exit)
 	async.InitManager()
+	initAudit(exit)
+	quota.StartGovernor(&conf.Config.Throttle, exit)
+	runCluster(exit)
+	runConfigSync(exit)
 
 	// Start rest service
 	srvRest := createRestServer(conf.Config.Basic.RestIp, conf.Config.Basic.RestPort, conf.Config.Basic.Authentication)
@@ -231,6 +248,7 @@ func StartUp(Version string) {
 	}
 	// Register conf managers
 	InitConfManagers()
+	serverReady.Store(true)
 
 	// Startup message
 	restHttpType := "http"
@@ -253,7 +271,9 @@ func StartUp(Version string) {
 		time.Sleep(time.Second)
 		conf.Log.Info("eKuiper stopped by Stop request")
 	}
+	serverReady.Store(false)
 	exit <- struct{}{}
+	leaveCluster()
 	conf.Log.Info("start to stop rest server")
 	ctx, cancel := context.WithTimeout(context.TODO(), 3*time.Second)
 	defer cancel()
@@ -434,7 +454,12 @@ const (
 func handleScheduleRule(now time.Time, r *api.Rule, state string) scheduleRuleAction {
 	options := r.Options
 	if options != nil && options.Cron == "" && options.Duration == "" && len(options.CronDatetimeRange) > 0 {
-		isInRange, err := schedule.IsInScheduleRanges(now, options.CronDatetimeRange)
+		loc, err := schedule.LoadTimezone(options.Timezone)
+		if err != nil {
+			conf.Log.Errorf("check rule %v schedule failed, err:%v", r.Id, err)
+			return scheduleRuleActionDoNothing
+		}
+		isInRange, err := schedule.IsInScheduleRangesIn(now, options.CronDatetimeRange, loc)
 		if err != nil {
 			conf.Log.Errorf("check rule %v schedule failed, err:%v", r.Id, err)
 			return scheduleRuleActionDoNothing
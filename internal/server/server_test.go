@@ -85,6 +85,28 @@ func TestHandleScheduleRule(t *testing.T) {
 	}
 }
 
+func TestHandleScheduleRuleWithTimezone(t *testing.T) {
+	now := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	r := &api.Rule{
+		Triggered: true,
+		Options: &api.RuleOption{
+			// 15:04:05 in UTC is 23:04:05 in Shanghai
+			Timezone: "Asia/Shanghai",
+			CronDatetimeRange: []api.DatetimeRange{
+				{
+					Begin: "2006-01-02 23:04:01",
+					End:   "2006-01-02 23:04:06",
+				},
+			},
+		},
+	}
+	require.Equal(t, scheduleRuleActionStart, handleScheduleRule(now, r, rule.RuleWait))
+	r.Options.Timezone = "UTC"
+	require.Equal(t, scheduleRuleActionDoNothing, handleScheduleRule(now, r, rule.RuleWait))
+	r.Options.Timezone = "Mars/Base"
+	require.Equal(t, scheduleRuleActionDoNothing, handleScheduleRule(now, r, rule.RuleWait))
+}
+
 func TestRunScheduleRuleChecker(t *testing.T) {
 	exit := make(chan struct{})
 	go runScheduleRuleCheckerByInterval(3*time.Second, exit)
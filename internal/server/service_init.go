@@ -66,7 +66,7 @@ func servicesHandler(w http.ResponseWriter, r *http.Request) {
 			handleError(w, err, "service list command error", logger)
 			return
 		}
-		jsonResponse(content, w, logger)
+		listNamesResponse(w, r, content, nil)
 	case http.MethodPost:
 		sd := &service.ServiceCreationRequest{}
 		err := json.NewDecoder(r.Body).Decode(sd)
@@ -0,0 +1,62 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/topo/node"
+)
+
+type sinkReplayRequest struct {
+	// File is the capture file written by the sink tap
+	File string `json:"file"`
+	// Interval is the milliseconds to wait between two records
+	Interval int `json:"interval"`
+	// Props are the properties of the sink to send to
+	Props map[string]interface{} `json:"props"`
+}
+
+// resend the payloads of a capture file to the sink
+func sinkReplayHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	vars := mux.Vars(r)
+	sinkNm := vars["name"]
+	req := &sinkReplayRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, err, "Invalid body", logger)
+		return
+	}
+	if req.File == "" {
+		handleError(w, fmt.Errorf("file is required"), "", logger)
+		return
+	}
+	if req.Props == nil {
+		req.Props = map[string]interface{}{}
+	}
+	props := replacePasswdForConfig("sink", sinkNm, req.Props)
+	ctx := context.WithValue(context.Background(), context.LoggerKey, logger)
+	count, err := node.ReplayCapture(ctx, sinkNm, props, req.File, req.Interval)
+	if err != nil {
+		handleError(w, err, fmt.Sprintf("replay capture stopped after %d records", count), logger)
+		return
+	}
+	jsonResponse(map[string]int{"count": count}, w, logger)
+}
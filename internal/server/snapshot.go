@@ -0,0 +1,70 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lf-edge/ekuiper/internal/io/snapshot"
+)
+
+// list all snapshot tables
+func snapshotsHandler(w http.ResponseWriter, _ *http.Request) {
+	names, err := snapshot.List()
+	if err != nil {
+		handleError(w, err, "list snapshot tables error", logger)
+		return
+	}
+	jsonResponse(names, w, logger)
+}
+
+// read all rows or drop a snapshot table
+func snapshotHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	vars := mux.Vars(r)
+	name := vars["name"]
+	switch r.Method {
+	case http.MethodGet:
+		rows, err := snapshot.GetAll(name)
+		if err != nil {
+			handleError(w, err, "read snapshot table error", logger)
+			return
+		}
+		jsonResponse(rows, w, logger)
+	case http.MethodDelete:
+		err := snapshot.Drop(name)
+		if err != nil {
+			handleError(w, err, "drop snapshot table error", logger)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "Snapshot table %s is dropped.", name)
+	}
+}
+
+// read the row of a key in a snapshot table
+func snapshotRowHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	vars := mux.Vars(r)
+	row, err := snapshot.Get(vars["name"], vars["key"])
+	if err != nil {
+		handleError(w, err, "read snapshot table error", logger)
+		return
+	}
+	jsonResponse(row, w, logger)
+}
@@ -0,0 +1,206 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/pkg/namespace"
+	"github.com/lf-edge/ekuiper/internal/processor"
+	"github.com/lf-edge/ekuiper/internal/topo/node"
+	"github.com/lf-edge/ekuiper/internal/topo/planner"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+const (
+	defaultSampleSeconds = 10
+	maxSampleSeconds     = 60
+	defaultSampleLimit   = 1000
+)
+
+type schemaInferRequest struct {
+	// Seconds is the duration to sample the live data
+	Seconds int `json:"seconds"`
+	// Limit is the max count of the samples, the sampling ends early when reached
+	Limit int `json:"limit"`
+}
+
+type schemaUpdateRequest struct {
+	// Definition is the field definitions such as "id bigint, name string"
+	Definition string `json:"definition"`
+	// Merge keeps the fields of the stream which are not in the definition
+	Merge bool `json:"merge"`
+}
+
+// sample the live data of a stream and infer the schema
+func streamSchemaInferHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	name := mux.Vars(r)["name"]
+	req := &schemaInferRequest{}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		handleError(w, err, "Invalid body", logger)
+		return
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, req); err != nil {
+			handleError(w, err, "Invalid body: Error decoding json", logger)
+			return
+		}
+	}
+	if req.Seconds == 0 {
+		req.Seconds = defaultSampleSeconds
+	}
+	if req.Seconds < 0 || req.Seconds > maxSampleSeconds {
+		handleError(w, fmt.Errorf("invalid seconds %d, it must be in (0, %d]", req.Seconds, maxSampleSeconds), "", logger)
+		return
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultSampleLimit
+	}
+	sp, err := requestStreamProcessor(r)
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	if _, err := sp.GetStream(name, ast.TypeStream); err != nil {
+		handleError(w, err, "infer stream schema error", logger)
+		return
+	}
+	samples, err := sampleStream(r.Context(), requestNamespace(r), name, time.Duration(req.Seconds)*time.Second, req.Limit)
+	if err != nil {
+		handleError(w, err, "infer stream schema error", logger)
+		return
+	}
+	jsonResponse(processor.InferSchema(samples), w, logger)
+}
+
+func sourceSchemaUpdate(w http.ResponseWriter, r *http.Request, st ast.StreamType) {
+	name := mux.Vars(r)["name"]
+	req := &schemaUpdateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, err, "Invalid body: Error decoding json", logger)
+		return
+	}
+	sp, err := requestStreamProcessor(r)
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	content, err := sp.UpdateSchema(name, st, req.Definition, req.Merge)
+	if err != nil {
+		handleError(w, err, fmt.Sprintf("update schema of %s error", ast.StreamTypeMap[st]), logger)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(content))
+}
+
+// sampleStream reads the stream in a temporary rule until the duration passes or the limit is reached
+func sampleStream(ctx context.Context, ns string, name string, d time.Duration, limit int) ([]map[string]interface{}, error) {
+	opts := conf.Config.Rule
+	opts.Qos = api.AtMostOnce
+	opts.SendError = false
+	ru := &api.Rule{
+		Triggered: true,
+		Id:        namespace.Qualify(ns, fmt.Sprintf("%s_sample_%d", name, conf.GetNowInMilli())),
+		Sql:       fmt.Sprintf("SELECT * FROM `%s`", name),
+		Options:   &opts,
+	}
+	s := &sampleSink{limit: limit, full: make(chan struct{})}
+	tp, err := planner.PlanSQLWithSourceOverrides(ru, nil, []*node.SinkNode{node.NewSinkNodeWithSink("sample", s, map[string]interface{}{"sendSingle": true})})
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("sample stream %s for %v", name, d)
+	errCh := tp.Open()
+	defer func() {
+		tp.Cancel()
+		tp.RemoveMetrics()
+	}()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return nil, err
+		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+	case <-s.full:
+	}
+	return s.results(), nil
+}
+
+// sampleSink collects the messages of the stream. The numbers are decoded as json.Number to tell the integers.
+type sampleSink struct {
+	sync.Mutex
+	limit   int
+	samples []map[string]interface{}
+	full    chan struct{}
+}
+
+func (s *sampleSink) Configure(_ map[string]interface{}) error {
+	return nil
+}
+
+func (s *sampleSink) Open(_ api.StreamContext) error {
+	return nil
+}
+
+func (s *sampleSink) Collect(ctx api.StreamContext, data interface{}) error {
+	bs, _, err := ctx.TransformOutput(data)
+	if err != nil {
+		return err
+	}
+	d := json.NewDecoder(bytes.NewReader(bs))
+	d.UseNumber()
+	var m map[string]interface{}
+	if err := d.Decode(&m); err != nil {
+		return err
+	}
+	s.Lock()
+	defer s.Unlock()
+	if len(s.samples) >= s.limit {
+		return nil
+	}
+	s.samples = append(s.samples, m)
+	if len(s.samples) == s.limit {
+		close(s.full)
+	}
+	return nil
+}
+
+func (s *sampleSink) Close(_ api.StreamContext) error {
+	return nil
+}
+
+func (s *sampleSink) results() []map[string]interface{} {
+	s.Lock()
+	defer s.Unlock()
+	return s.samples
+}
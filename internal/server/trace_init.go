@@ -0,0 +1,85 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+)
+
+func init() {
+	t := &traceComp{}
+	components["trace"] = t
+	servers["trace"] = t
+}
+
+type traceComp struct {
+	tp *sdktrace.TracerProvider
+}
+
+// register sets the tracer provider before the rules are recovered so that their spans are exported
+func (t *traceComp) register() {
+	c := conf.Config.OpenTelemetry
+	if !c.Enable {
+		return
+	}
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(c.Endpoint)}
+	if c.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		logger.Errorf("Create OpenTelemetry exporter error: %v", err)
+		return
+	}
+	// The sampling is decided by the rules, so all spans are sampled here
+	t.tp = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", c.ServiceName))),
+	)
+	otel.SetTracerProvider(t.tp)
+	msg := fmt.Sprintf("Export OpenTelemetry traces to %s", c.Endpoint)
+	logger.Infof(msg)
+	fmt.Println(msg)
+}
+
+func (t *traceComp) rest(_ *mux.Router) {
+	// do nothing
+}
+
+func (t *traceComp) serve() {
+	// started in register
+}
+
+func (t *traceComp) close() {
+	if t.tp != nil {
+		if err := t.tp.Shutdown(context.Background()); err != nil {
+			logger.Warnf("Shutdown OpenTelemetry tracer provider error: %v", err)
+		}
+		t.tp = nil
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lf-edge/ekuiper/internal/io/view"
+)
+
+// list all published views
+func viewsHandler(w http.ResponseWriter, _ *http.Request) {
+	jsonResponse(view.List(), w, logger)
+}
+
+// read the latest result of a view
+func viewHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	vars := mux.Vars(r)
+	info, err := view.Get(vars["name"])
+	if err != nil {
+		handleError(w, err, "read view error", logger)
+		return
+	}
+	jsonResponse(info, w, logger)
+}
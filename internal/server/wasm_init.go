@@ -12,8 +12,8 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-//go:build wasmedge
-// +build wasmedge
+//go:build wasmedge || wazero
+// +build wasmedge wazero
 
 package server
 
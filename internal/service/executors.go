@@ -33,6 +33,8 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/lf-edge/ekuiper/internal/pkg/httpx"
+	"github.com/lf-edge/ekuiper/internal/pkg/netx"
+	"github.com/lf-edge/ekuiper/internal/pkg/oauth2"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/cast"
 	"github.com/lf-edge/ekuiper/pkg/errorx"
@@ -63,10 +65,15 @@ func newHttpExecutor(desc descriptor, opt *interfaceOpt, i *interfaceInfo) (exec
 	if e != nil {
 		return nil, fmt.Errorf("incorrect rest option: %v", e)
 	}
+	dialer, err := netx.NewDialer(i.Options)
+	if err != nil {
+		return nil, fmt.Errorf("incorrect rest option: %v", err)
+	}
 	exe := &httpExecutor{
 		descriptor:   d,
 		interfaceOpt: opt,
 		restOpt:      o,
+		dialer:       dialer,
 	}
 	return exe, nil
 }
@@ -197,6 +204,7 @@ type httpExecutor struct {
 	descriptor multiplexDescriptor
 	*interfaceOpt
 	restOpt *restOption
+	dialer  *netx.Dialer
 
 	conn *http.Client
 }
@@ -243,6 +251,7 @@ func (h *httpExecutor) invokeFunction(ctx api.FunctionContext, name string, para
 		tr := &http.Transport{
 			TLSClientConfig: &tls.Config{InsecureSkipVerify: h.restOpt.InsecureSkipVerify},
 		}
+		h.dialer.ApplyTransport(tr)
 		h.conn = &http.Client{
 			Transport: tr,
 			Timeout:   time.Duration(h.timeout) * time.Millisecond,
@@ -258,13 +267,28 @@ func (h *httpExecutor) invokeFunction(ctx api.FunctionContext, name string, para
 	if err != nil {
 		return nil, err
 	}
+	headers := h.restOpt.Headers
+	if h.restOpt.OAuth2Selector != "" {
+		auth, err := oauth2.AuthHeader(h.restOpt.OAuth2Selector)
+		if err != nil {
+			return nil, err
+		}
+		headers = make(map[string]string, len(h.restOpt.Headers)+1)
+		for k, v := range h.restOpt.Headers {
+			headers[k] = v
+		}
+		headers["Authorization"] = auth
+	}
 	resp, err := httpx.Send(ctx.GetLogger(), h.conn, u, hm.Method,
-		httpx.WithHeadersMap(h.restOpt.Headers),
+		httpx.WithHeadersMap(headers),
 		httpx.WithBody(hm.Body, "json", false, nil, httpx.EmptyCompressorAlgorithm))
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized && h.restOpt.OAuth2Selector != "" {
+		oauth2.Invalidate(h.restOpt.OAuth2Selector)
+	}
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
 		buf, _ := io.ReadAll(resp.Body)
 		ctx.GetLogger().Debugf("%s\n", string(buf))
@@ -0,0 +1,115 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"fmt"
+
+	kconf "github.com/lf-edge/ekuiper/internal/conf"
+	kctx "github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/modules"
+)
+
+func init() {
+	modules.RegisterLookupSource("service", func() api.LookupSource { return &LookupSource{} })
+}
+
+// LookupSource is a lookup source which queries an external service function.
+// The lookup values of the join condition are passed as the arguments of the function in order.
+// The function must return an object or an array of objects as the matched rows.
+type LookupSource struct {
+	function string
+	fn       api.Function
+}
+
+func (l *LookupSource) Configure(datasource string, _ map[string]interface{}) error {
+	if datasource == "" {
+		return fmt.Errorf("the service function name is required as the datasource")
+	}
+	l.function = datasource
+	return nil
+}
+
+func (l *LookupSource) Open(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("service lookup source %s is opened", l.function)
+	// The tables may be created before the services are loaded, resolve the function when looking up in that case
+	if GetManager() == nil {
+		return nil
+	}
+	return l.resolve()
+}
+
+func (l *LookupSource) resolve() error {
+	m := GetManager()
+	if m == nil {
+		return fmt.Errorf("external service is not initialized")
+	}
+	fn, err := m.Function(l.function)
+	if err != nil {
+		return err
+	}
+	l.fn = fn
+	return nil
+}
+
+func (l *LookupSource) Lookup(ctx api.StreamContext, _ []string, _ []string, values []interface{}) ([]api.SourceTuple, error) {
+	if l.fn == nil {
+		if err := l.resolve(); err != nil {
+			return nil, err
+		}
+	}
+	r, ok := l.fn.Exec(values, kctx.NewDefaultFuncContext(ctx, 0))
+	if !ok {
+		if err, isErr := r.(error); isErr {
+			return nil, fmt.Errorf("lookup service function %s error: %v", l.function, err)
+		}
+		return nil, fmt.Errorf("lookup service function %s error: %v", l.function, r)
+	}
+	return toLookupTuples(l.function, r)
+}
+
+func (l *LookupSource) Close(ctx api.StreamContext) error {
+	ctx.GetLogger().Infof("service lookup source %s is closed", l.function)
+	return nil
+}
+
+func toLookupTuples(function string, r interface{}) ([]api.SourceTuple, error) {
+	meta := map[string]interface{}{"function": function}
+	switch rt := r.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return []api.SourceTuple{api.NewDefaultSourceTupleWithTime(rt, meta, kconf.GetNow())}, nil
+	case []map[string]interface{}:
+		result := make([]api.SourceTuple, 0, len(rt))
+		for _, m := range rt {
+			result = append(result, api.NewDefaultSourceTupleWithTime(m, meta, kconf.GetNow()))
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]api.SourceTuple, 0, len(rt))
+		for _, v := range rt {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("lookup service function %s must return an array of objects, but got element %v", function, v)
+			}
+			result = append(result, api.NewDefaultSourceTupleWithTime(m, meta, kconf.GetNow()))
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("lookup service function %s must return an object or an array of objects, but got %v", function, r)
+	}
+}
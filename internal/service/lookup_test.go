@@ -0,0 +1,76 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	kctx "github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+type mockLookupExecutor struct {
+	args []interface{}
+}
+
+func (m *mockLookupExecutor) InvokeFunction(_ api.FunctionContext, name string, params []interface{}) (interface{}, error) {
+	m.args = params
+	switch params[0] {
+	case "one":
+		return map[string]interface{}{"id": params[0], "name": name}, nil
+	case "many":
+		return []interface{}{map[string]interface{}{"id": 1}, map[string]interface{}{"id": 2}}, nil
+	case "invalid":
+		return []interface{}{1}, nil
+	case "error":
+		return nil, errors.New("service unavailable")
+	default:
+		return nil, nil
+	}
+}
+
+func TestLookupSource(t *testing.T) {
+	l := &LookupSource{}
+	require.EqualError(t, l.Configure("", nil), "the service function name is required as the datasource")
+	require.NoError(t, l.Configure("getDevice", nil))
+	exe := &mockLookupExecutor{}
+	l.fn = &ExternalFunc{exe: exe, methodName: "GetDevice"}
+	ctx := kctx.Background()
+
+	r, err := l.Lookup(ctx, nil, []string{"id"}, []interface{}{"one"})
+	require.NoError(t, err)
+	require.Len(t, r, 1)
+	assert.Equal(t, map[string]interface{}{"id": "one", "name": "GetDevice"}, r[0].Message())
+	assert.Equal(t, map[string]interface{}{"function": "getDevice"}, r[0].Meta())
+	assert.Equal(t, []interface{}{"one"}, exe.args)
+
+	r, err = l.Lookup(ctx, nil, []string{"id"}, []interface{}{"many"})
+	require.NoError(t, err)
+	require.Len(t, r, 2)
+	assert.Equal(t, map[string]interface{}{"id": 2}, r[1].Message())
+
+	r, err = l.Lookup(ctx, nil, []string{"id"}, []interface{}{"none"})
+	require.NoError(t, err)
+	assert.Empty(t, r)
+
+	_, err = l.Lookup(ctx, nil, []string{"id"}, []interface{}{"invalid"})
+	assert.EqualError(t, err, "lookup service function getDevice must return an array of objects, but got element 1")
+	_, err = l.Lookup(ctx, nil, []string{"id"}, []interface{}{"error"})
+	assert.EqualError(t, err, "lookup service function getDevice error: service unavailable")
+}
@@ -37,7 +37,6 @@ import (
 )
 
 var (
-	once      sync.Once
 	mutex     sync.Mutex
 	singleton *Manager // Do not call this directly, use GetServiceManager
 )
@@ -97,6 +97,8 @@ type restOption struct {
 	Headers            map[string]string `json:"headers"`
 	RetryCount         int               `json:"retryCount"`
 	RetryInterval      string            `json:"retryInterval"`
+	// OAuth2Selector is the name of the shared oauth2 client credentials configuration in connection.yaml
+	OAuth2Selector string `json:"oauth2Selector"`
 
 	retryIntervalDuration time.Duration `json:"-"`
 }
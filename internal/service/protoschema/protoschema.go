@@ -0,0 +1,66 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package protoschema parses the protobuf schema files of the service manager. It has no dependency of the service
+// manager so that the grpc source can share the schema files without an import cycle.
+package protoschema
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+
+	kconf "github.com/lf-edge/ekuiper/internal/conf"
+)
+
+var (
+	once   sync.Once
+	parser *protoparse.Parser
+)
+
+// Parser returns the parser of the schema files in data/services/schemas
+func Parser() *protoparse.Parser {
+	once.Do(func() {
+		dir := "data/services/schemas/"
+		if kconf.IsTesting {
+			dir = "service/test/schemas/"
+		}
+		schemaDir, _ := kconf.GetLoc(dir)
+		parser = &protoparse.Parser{ImportPaths: []string{schemaDir}}
+	})
+	return parser
+}
+
+// FindMethod parses the schema file and finds the method. If the service is empty, the method is searched in all the
+// services of the file.
+func FindMethod(file string, service string, method string) (*desc.MethodDescriptor, error) {
+	fds, err := Parser().ParseFiles(file)
+	if err != nil {
+		return nil, fmt.Errorf("parse schema file %s failed: %v", file, err)
+	}
+	for _, s := range fds[0].GetServices() {
+		if service != "" && s.GetName() != service && s.GetFullyQualifiedName() != service {
+			continue
+		}
+		if m := s.FindMethodByName(method); m != nil {
+			return m, nil
+		}
+	}
+	if service != "" {
+		return nil, fmt.Errorf("method %s of service %s not found in schema file %s", method, service, file)
+	}
+	return nil, fmt.Errorf("method %s not found in schema file %s", method, file)
+}
@@ -27,6 +27,7 @@ import (
 
 	kconf "github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/converter/protobuf"
+	"github.com/lf-edge/ekuiper/internal/service/protoschema"
 	"github.com/lf-edge/ekuiper/internal/xsql"
 	"github.com/lf-edge/ekuiper/pkg/cast"
 )
@@ -65,21 +66,12 @@ type multiplexDescriptor interface {
 }
 
 var ( // Do not call these directly, use the get methods
-	protoParser *protoparse.Parser
 	// A buffer of descriptor for schemas
 	reg = &sync.Map{}
 )
 
 func ProtoParser() *protoparse.Parser {
-	once.Do(func() {
-		dir := "data/services/schemas/"
-		if kconf.IsTesting {
-			dir = "service/test/schemas/"
-		}
-		schemaDir, _ := kconf.GetLoc(dir)
-		protoParser = &protoparse.Parser{ImportPaths: []string{schemaDir}}
-	})
-	return protoParser
+	return protoschema.Parser()
 }
 
 func parse(schema schema, file string, schemaless bool) (descriptor, error) {
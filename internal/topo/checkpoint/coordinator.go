@@ -25,6 +25,9 @@ import (
 	"github.com/lf-edge/ekuiper/pkg/infra"
 )
 
+// maxUncommitted is the max count of the completed checkpoints waiting for the delivery to commit
+const maxUncommitted = 100
+
 type pendingCheckpoint struct {
 	checkpointId   int64
 	isDiscarded    bool
@@ -90,6 +93,7 @@ type Coordinator struct {
 	tasksToTrigger          []Responder
 	tasksToWaitFor          []Responder
 	sinkTasks               []SinkTask
+	commitTasks             []CommitTask
 	pendingCheckpoints      *sync.Map
 	completedCheckpoints    *checkpointStore
 	ruleId                  string
@@ -101,15 +105,25 @@ type Coordinator struct {
 	store                   api.Store
 	ctx                     api.StreamContext
 	activated               bool
+	// delivered is the latest delivered checkpoint of each sink and uncommitted is the completed checkpoints in
+	// order whose offsets are not committed because the sinks have not delivered the results yet
+	delivered   map[string]int64
+	uncommitted []int64
 }
 
 func NewCoordinator(ruleId string, sources []StreamTask, operators []NonSourceTask, sinks []SinkTask, qos api.Qos, store api.Store, interval int, ctx api.StreamContext) *Coordinator {
 	logger := ctx.GetLogger()
 	logger.Infof("create new coordinator for rule %s", ruleId)
 	signal := make(chan *Signal, 1024)
-	var allResponders, sourceResponders []Responder
+	var (
+		allResponders, sourceResponders []Responder
+		commitTasks                     []CommitTask
+	)
 	for _, r := range sources {
 		r.SetQos(qos)
+		if ct, ok := r.(CommitTask); ok {
+			commitTasks = append(commitTasks, ct)
+		}
 		re := NewResponderExecutor(signal, r)
 		allResponders = append(allResponders, re)
 		sourceResponders = append(sourceResponders, re)
@@ -128,6 +142,22 @@ func NewCoordinator(ruleId string, sources []StreamTask, operators []NonSourceTa
 		r.SetBarrierHandler(handler)
 		allResponders = append(allResponders, re)
 	}
+	// Only commit the offsets after all the sinks deliver the results
+	delivered := make(map[string]int64)
+	if len(commitTasks) > 0 {
+		for _, r := range sinks {
+			if dt, ok := r.(DeliveryTask); ok {
+				name := r.GetName()
+				delivered[name] = 0
+				dt.SetDeliveryNotifier(func(checkpointId int64) {
+					select {
+					case signal <- &Signal{Message: DELIVERED, Barrier: Barrier{CheckpointId: checkpointId, OpId: name}}:
+					case <-ctx.Done():
+					}
+				})
+			}
+		}
+	}
 	// 5 minutes by default
 	if interval <= 0 {
 		interval = 300000
@@ -136,6 +166,8 @@ func NewCoordinator(ruleId string, sources []StreamTask, operators []NonSourceTa
 		tasksToTrigger:     sourceResponders,
 		tasksToWaitFor:     allResponders,
 		sinkTasks:          sinks,
+		commitTasks:        commitTasks,
+		delivered:          delivered,
 		pendingCheckpoints: new(sync.Map),
 		completedCheckpoints: &checkpointStore{
 			maxNum: 3,
@@ -220,6 +252,12 @@ func (c *Coordinator) Activate() error {
 					case DEC:
 						logger.Debugf("Receive dec from %s for checkpoint %d, cancel it", s.OpId, s.CheckpointId)
 						c.cancel(s.CheckpointId)
+					case DELIVERED:
+						logger.Debugf("Receive delivered from %s for checkpoint %d", s.OpId, s.CheckpointId)
+						if s.CheckpointId > c.delivered[s.OpId] {
+							c.delivered[s.OpId] = s.CheckpointId
+						}
+						c.commit()
 					}
 				case <-c.ctx.Done():
 					logger.Infoln("Cancelling coordinator....")
@@ -277,12 +315,52 @@ func (c *Coordinator) complete(checkpointId int64) {
 			}
 			return true
 		})
+		if len(c.commitTasks) > 0 {
+			c.uncommitted = append(c.uncommitted, checkpointId)
+			// The offsets of the earlier checkpoints are covered by the later ones, so they can be discarded
+			if len(c.uncommitted) > maxUncommitted {
+				c.uncommitted = c.uncommitted[1:]
+			}
+			c.commit()
+		}
 		logger.Debugf("Totally complete checkpoint %d", checkpointId)
 	} else {
 		logger.Infof("Cannot find checkpoint %d to complete", checkpointId)
 	}
 }
 
+// commit commits the offsets of the latest completed checkpoint whose results are delivered by all the sinks
+func (c *Coordinator) commit() {
+	var (
+		target int64
+		i      int
+	)
+	for ; i < len(c.uncommitted); i++ {
+		if !c.isDelivered(c.uncommitted[i]) {
+			break
+		}
+		target = c.uncommitted[i]
+	}
+	if i == 0 {
+		return
+	}
+	c.uncommitted = c.uncommitted[i:]
+	for _, ct := range c.commitTasks {
+		if err := ct.Commit(target); err != nil {
+			c.ctx.GetLogger().Warnf("Fail to commit offset of source %s for checkpoint %d: %v", ct.GetName(), target, err)
+		}
+	}
+}
+
+func (c *Coordinator) isDelivered(checkpointId int64) bool {
+	for _, d := range c.delivered {
+		if d < checkpointId {
+			return false
+		}
+	}
+	return true
+}
+
 // For testing
 func (c *Coordinator) GetCompleteCount() int {
 	return len(c.completedCheckpoints.checkpoints)
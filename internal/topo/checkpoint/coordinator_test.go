@@ -0,0 +1,96 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkpoint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+type mockCommitTask struct {
+	StreamTask
+	committed []int64
+}
+
+func (m *mockCommitTask) GetName() string {
+	return "source"
+}
+
+func (m *mockCommitTask) SetQos(api.Qos) {}
+
+func (m *mockCommitTask) PrepareCommit(int64) {}
+
+func (m *mockCommitTask) Commit(checkpointId int64) error {
+	m.committed = append(m.committed, checkpointId)
+	return nil
+}
+
+type mockDeliveryTask struct {
+	SinkTask
+	name   string
+	notify func(checkpointId int64)
+}
+
+func (m *mockDeliveryTask) GetName() string {
+	return m.name
+}
+
+func (m *mockDeliveryTask) SetQos(api.Qos) {}
+
+func (m *mockDeliveryTask) GetInputCount() int {
+	return 1
+}
+
+func (m *mockDeliveryTask) SetBarrierHandler(BarrierHandler) {}
+
+func (m *mockDeliveryTask) SetDeliveryNotifier(notify func(checkpointId int64)) {
+	m.notify = notify
+}
+
+func TestCommitAfterDelivered(t *testing.T) {
+	s1, s2 := &mockDeliveryTask{name: "sink1"}, &mockDeliveryTask{name: "sink2"}
+	// Only the sources which commit offsets wait for the delivery
+	c := NewCoordinator("test", nil, nil, []SinkTask{s1, s2}, api.AtLeastOnce, nil, 0, context.Background())
+	assert.Nil(t, s1.notify)
+	assert.Empty(t, c.delivered)
+
+	ct := &mockCommitTask{}
+	c = NewCoordinator("test", []StreamTask{ct}, nil, []SinkTask{s1, s2}, api.AtLeastOnce, nil, 0, context.Background())
+	assert.NotNil(t, s1.notify)
+	assert.NotNil(t, s2.notify)
+	assert.Equal(t, map[string]int64{"sink1": 0, "sink2": 0}, c.delivered)
+	c.uncommitted = []int64{1, 2, 3}
+	c.commit()
+	assert.Empty(t, ct.committed)
+	// Commit the latest checkpoint delivered by all the sinks
+	s1.notify(3)
+	s2.notify(2)
+	for i := 0; i < 2; i++ {
+		s := <-c.signal
+		assert.Equal(t, DELIVERED, s.Message)
+		c.delivered[s.OpId] = s.CheckpointId
+	}
+	c.commit()
+	assert.Equal(t, []int64{2}, ct.committed)
+	assert.Equal(t, []int64{3}, c.uncommitted)
+	c.delivered["sink2"] = 4
+	c.commit()
+	assert.Equal(t, []int64{2, 3}, ct.committed)
+	assert.Empty(t, c.uncommitted)
+}
@@ -41,6 +41,22 @@ type SinkTask interface {
 	NonSourceTask
 }
 
+// DeliveryTask is a sink task which reports the latest checkpoint whose results before the barrier are delivered.
+// The results in the sink cache or being retried are not delivered.
+type DeliveryTask interface {
+	SinkTask
+	SetDeliveryNotifier(notify func(checkpointId int64))
+}
+
+// CommitTask is a source task which commits the consumed offset to the external system.
+// The offset is recorded before the barrier is sent and committed once the checkpoint is completed and
+// all sinks have delivered the results of the data before the barrier.
+type CommitTask interface {
+	StreamTask
+	PrepareCommit(checkpointId int64)
+	Commit(checkpointId int64) error
+}
+
 type BufferOrEvent struct {
 	Data    interface{}
 	Channel string
@@ -57,6 +73,8 @@ const (
 	STOP Message = iota
 	ACK
 	DEC
+	// DELIVERED is sent by the sinks when the results before the barrier are delivered
+	DELIVERED
 )
 
 type Signal struct {
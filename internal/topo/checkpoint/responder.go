@@ -55,6 +55,9 @@ func (re *ResponderExecutor) TriggerCheckpoint(checkpointId int64) error {
 		CheckpointId: checkpointId,
 		OpId:         name,
 	}
+	if ct, ok := re.task.(CommitTask); ok {
+		ct.PrepareCommit(checkpointId)
+	}
 	// broadcast barrier
 	re.task.Broadcast(barrier)
 	// Save key state to the global state
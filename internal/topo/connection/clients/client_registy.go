@@ -127,3 +127,18 @@ func ReleaseClient(ctx api.StreamContext, cli api.MessageClient) {
 		gClientRegistry.Lock.Unlock()
 	}
 }
+
+// PingSharedClients pings all the shared clients and returns the ping result of each connection selector
+func PingSharedClients() map[string]error {
+	gClientRegistry.Lock.Lock()
+	wrappers := make(map[string]ClientWrapper, len(gClientRegistry.shareClientStore))
+	for sel, w := range gClientRegistry.shareClientStore {
+		wrappers[sel] = w
+	}
+	gClientRegistry.Lock.Unlock()
+	result := make(map[string]error, len(wrappers))
+	for sel, w := range wrappers {
+		result[sel] = w.Ping()
+	}
+	return result
+}
@@ -90,6 +90,12 @@ func (mc *edgexClientWrapper) Publish(c api.StreamContext, topic string, message
 			env.ContentType = v
 		}
 	}
+	// keep the correlation id of the source message to trace the request through the rule
+	if pk, ok := params["correlationId"]; ok {
+		if v, ok := pk.(string); ok && v != "" {
+			env.CorrelationID = v
+		}
+	}
 	err := mc.cli.Publish(env, topic)
 	if err != nil {
 		return err
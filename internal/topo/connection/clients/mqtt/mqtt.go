@@ -17,6 +17,8 @@ package mqtt
 import (
 	"crypto/tls"
 	"fmt"
+	"net"
+	"net/url"
 	"strings"
 	"time"
 
@@ -25,6 +27,7 @@ import (
 
 	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/pkg/cert"
+	"github.com/lf-edge/ekuiper/internal/pkg/netx"
 	"github.com/lf-edge/ekuiper/pkg/cast"
 	"github.com/lf-edge/ekuiper/pkg/errorx"
 )
@@ -44,6 +47,7 @@ type MQTTClient struct {
 	uName    string
 	password string
 	tls      *tls.Config
+	dialer   *netx.Dialer
 
 	conn MQTT.Client
 }
@@ -82,6 +86,10 @@ func (ms *MQTTClient) CfgValidate(props map[string]interface{}) error {
 	}
 	conf.Log.Infof("Connect MQTT broker %s with TLS configs", ms.srv)
 	ms.tls = tlsConfig
+	ms.dialer, err = netx.NewDialer(props)
+	if err != nil {
+		return err
+	}
 	if err := ms.checkMQTTServer(); err != nil {
 		return err
 	}
@@ -107,6 +115,11 @@ func (ms *MQTTClient) Connect(connHandler MQTT.OnConnectHandler, lostHandler MQT
 	opts := MQTT.NewClientOptions().AddBroker(ms.srv).SetProtocolVersion(4)
 
 	opts = opts.SetTLSConfig(ms.tls)
+	if !ms.dialer.IsDefault() {
+		opts = opts.SetCustomOpenConnectionFn(func(uri *url.URL, options MQTT.ClientOptions) (net.Conn, error) {
+			return ms.dialer.DialURL(uri, options.TLSConfig, options.ConnectTimeout)
+		})
+	}
 
 	if ms.uName != "" {
 		opts = opts.SetUsername(ms.uName)
@@ -16,13 +16,22 @@ package context
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
 )
 
+// keyedStateSweepInterval is the minimum interval in milliseconds to remove the expired keyed states
+const keyedStateSweepInterval int64 = 60000
+
 type DefaultFuncContext struct {
 	api.StreamContext
 	funcId int
+	// the next time to remove the expired keyed states
+	nextSweep int64
 }
 
 func NewDefaultFuncContext(ctx api.StreamContext, id int) *DefaultFuncContext {
@@ -56,6 +65,79 @@ func (c *DefaultFuncContext) GetFuncId() int {
 	return c.funcId
 }
 
+func (c *DefaultFuncContext) GetKeyedState(key string) (interface{}, error) {
+	k := c.convertKeyedKey(key)
+	v, err := c.StreamContext.GetState(k)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	value, expire, err := parseKeyedState(v)
+	if err != nil {
+		return nil, fmt.Errorf("keyed state[%s] is invalid: %v", key, err)
+	}
+	if expire > 0 && expire <= conf.GetNowInMilli() {
+		return nil, c.StreamContext.DeleteState(k)
+	}
+	return value, nil
+}
+
+func (c *DefaultFuncContext) PutKeyedState(key string, value interface{}, ttl time.Duration) error {
+	now := conf.GetNowInMilli()
+	var expire int64
+	if ttl > 0 {
+		expire = now + ttl.Milliseconds()
+	}
+	c.sweepKeyedStates(now)
+	// Save as map so that it can be encoded in the checkpoint
+	return c.StreamContext.PutState(c.convertKeyedKey(key), map[string]interface{}{
+		"value":  value,
+		"expire": expire,
+	})
+}
+
+func (c *DefaultFuncContext) DeleteKeyedState(key string) error {
+	return c.StreamContext.DeleteState(c.convertKeyedKey(key))
+}
+
+// sweepKeyedStates removes the expired keyed states periodically so that the keys which are never read again do not leak
+func (c *DefaultFuncContext) sweepKeyedStates(now int64) {
+	if now < c.nextSweep {
+		return
+	}
+	c.nextSweep = now + keyedStateSweepInterval
+	dc, ok := c.StreamContext.(*DefaultContext)
+	if !ok || dc.state == nil {
+		return
+	}
+	prefix := c.convertKeyedKey("")
+	dc.state.Range(func(k, v interface{}) bool {
+		if ks, ok := k.(string); ok && strings.HasPrefix(ks, prefix) {
+			if _, expire, err := parseKeyedState(v); err == nil && expire > 0 && expire <= now {
+				dc.state.Delete(k)
+			}
+		}
+		return true
+	})
+}
+
+func parseKeyedState(v interface{}) (interface{}, int64, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, 0, fmt.Errorf("expect map but got %v", v)
+	}
+	expire, err := cast.ToInt64(m["expire"], cast.CONVERT_SAMEKIND)
+	if err != nil {
+		return nil, 0, err
+	}
+	return m["value"], expire, nil
+}
+
 func (c *DefaultFuncContext) convertKey(key string) string {
 	return fmt.Sprintf("$$func%d_%s", c.funcId, key)
 }
+
+func (c *DefaultFuncContext) convertKeyedKey(key string) string {
+	return fmt.Sprintf("$$funckeyed%d_%s", c.funcId, key)
+}
+
+var _ api.KeyedStateContext = &DefaultFuncContext{}
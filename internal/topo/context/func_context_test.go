@@ -0,0 +1,94 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package context
+
+import (
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/internal/topo/state"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+func TestKeyedState(t *testing.T) {
+	conf.IsTesting = true
+	conf.InitClock()
+	mc := conf.Clock.(*clock.Mock)
+	data, err := conf.GetDataLoc()
+	require.NoError(t, err)
+	require.NoError(t, store.SetupDefault(data))
+	ruleId := "testKeyedStateRule"
+	cStore, err := state.CreateStore(ruleId, api.AtLeastOnce)
+	require.NoError(t, err)
+	defer cleanStateData()
+	ctx := Background().WithMeta(ruleId, "op1", cStore).(*DefaultContext)
+	fctx := NewDefaultFuncContext(ctx, 1)
+	var fc api.FunctionContext = fctx
+	_, ok := fc.(api.KeyedStateContext)
+	require.True(t, ok)
+
+	require.NoError(t, fctx.PutKeyedState("dev1", 10, time.Second))
+	require.NoError(t, fctx.PutKeyedState("dev2", "a", 0))
+	require.NoError(t, fctx.PutKeyedState("dev3", 30, 5*time.Minute))
+	v, err := fctx.GetKeyedState("dev1")
+	require.NoError(t, err)
+	assert.Equal(t, 10, v)
+	// Keyed states are isolated from the normal states and other functions
+	v, err = fctx.GetState("dev1")
+	require.NoError(t, err)
+	assert.Nil(t, v)
+	v, err = NewDefaultFuncContext(ctx, 2).GetKeyedState("dev1")
+	require.NoError(t, err)
+	assert.Nil(t, v)
+	// Expired
+	mc.Add(time.Second)
+	v, err = fctx.GetKeyedState("dev1")
+	require.NoError(t, err)
+	assert.Nil(t, v)
+	require.NoError(t, fctx.DeleteKeyedState("dev2"))
+	v, err = fctx.GetKeyedState("dev2")
+	require.NoError(t, err)
+	assert.Nil(t, v)
+	require.NoError(t, fctx.PutKeyedState("dev4", true, 0))
+
+	// Restore from the checkpoint
+	require.NoError(t, ctx.Snapshot())
+	require.NoError(t, ctx.SaveState(1))
+	require.NoError(t, cStore.SaveCheckpoint(1))
+	cStore, err = state.CreateStore(ruleId, api.AtLeastOnce)
+	require.NoError(t, err)
+	ctx = Background().WithMeta(ruleId, "op1", cStore).(*DefaultContext)
+	fctx = NewDefaultFuncContext(ctx, 1)
+	v, err = fctx.GetKeyedState("dev3")
+	require.NoError(t, err)
+	assert.Equal(t, 30, v)
+	v, err = fctx.GetKeyedState("dev4")
+	require.NoError(t, err)
+	assert.Equal(t, true, v)
+
+	// The expired states are swept when putting
+	mc.Add(10 * time.Minute)
+	require.NoError(t, fctx.PutKeyedState("dev5", 50, 0))
+	_, ok = ctx.state.Load(fctx.convertKeyedKey("dev3"))
+	assert.False(t, ok)
+	_, ok = ctx.state.Load(fctx.convertKeyedKey("dev4"))
+	assert.True(t, ok)
+}
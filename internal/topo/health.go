@@ -0,0 +1,82 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package topo
+
+import (
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/internal/topo/node"
+	"github.com/lf-edge/ekuiper/internal/topo/node/metric"
+)
+
+const (
+	HealthHealthy  = "healthy"
+	HealthDegraded = "degraded"
+)
+
+// Health is the health of a running rule. A rule is degraded when it keeps running but some of its nodes keep
+// failing, such as a sink in the retry loop or a source reconnecting.
+type Health struct {
+	Status  string   `json:"status"`
+	Reasons []string `json:"reasons,omitempty"`
+	// Errors is the exceptions of all nodes by category
+	Errors map[string]metric.ErrorStats `json:"errors,omitempty"`
+}
+
+// GetHealth evaluates the rule health by the exceptions of all nodes. It is safe to call concurrently.
+func (s *Topo) GetHealth() *Health {
+	h := &Health{Status: HealthHealthy}
+	for _, src := range s.sources {
+		if hn, ok := src.(node.HealthNode); ok {
+			if failing, e := h.merge(hn); failing == metric.ErrorConnection {
+				h.Reasons = append(h.Reasons, fmt.Sprintf("source %s is reconnecting: %s", hn.GetName(), e))
+			}
+		}
+	}
+	for _, op := range s.ops {
+		if hn, ok := op.(node.HealthNode); ok {
+			h.merge(hn)
+		}
+	}
+	for _, snk := range s.sinks {
+		if failing, e := h.merge(snk); failing == metric.ErrorSink {
+			h.Reasons = append(h.Reasons, fmt.Sprintf("sink %s fails to send: %s", snk.GetName(), e))
+		}
+	}
+	if len(h.Reasons) > 0 {
+		h.Status = HealthDegraded
+	}
+	return h
+}
+
+// merge adds the exceptions of the node. If the node keeps failing, return the category and the message of its last exception
+func (h *Health) merge(hn node.HealthNode) (string, string) {
+	stats, failing := hn.GetErrorStats()
+	for c, st := range stats {
+		if h.Errors == nil {
+			h.Errors = make(map[string]metric.ErrorStats)
+		}
+		total := h.Errors[c]
+		total.Count += st.Count
+		if st.LastTime >= total.LastTime {
+			total.LastError, total.LastTime = st.LastError, st.LastTime
+		}
+		h.Errors[c] = total
+	}
+	if failing == "" {
+		return "", ""
+	}
+	return failing, stats[failing].LastError
+}
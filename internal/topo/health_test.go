@@ -0,0 +1,81 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package topo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lf-edge/ekuiper/internal/topo/node"
+	"github.com/lf-edge/ekuiper/internal/topo/node/metric"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+type mockHealthSource struct {
+	name    string
+	stats   map[string]metric.ErrorStats
+	failing string
+}
+
+func (m *mockHealthSource) AddOutput(_ chan<- interface{}, _ string) error {
+	return nil
+}
+
+func (m *mockHealthSource) Open(_ api.StreamContext, _ chan<- error) {}
+
+func (m *mockHealthSource) GetName() string {
+	return m.name
+}
+
+func (m *mockHealthSource) GetMetrics() []any {
+	return nil
+}
+
+func (m *mockHealthSource) RemoveMetrics(_ string) {}
+
+func (m *mockHealthSource) GetErrorStats() (map[string]metric.ErrorStats, string) {
+	return m.stats, m.failing
+}
+
+func TestGetHealth(t *testing.T) {
+	src1 := &mockHealthSource{name: "src1"}
+	src2 := &mockHealthSource{name: "src2", stats: map[string]metric.ErrorStats{
+		metric.ErrorDecode: {Count: 2, LastError: "invalid json", LastTime: 2000},
+	}, failing: metric.ErrorDecode}
+	tp := &Topo{sources: []node.DataSourceNode{src1, src2}}
+	// Decode errors do not degrade the rule
+	assert.Equal(t, &Health{Status: HealthHealthy, Errors: map[string]metric.ErrorStats{
+		metric.ErrorDecode: {Count: 2, LastError: "invalid json", LastTime: 2000},
+	}}, tp.GetHealth())
+
+	src1.stats = map[string]metric.ErrorStats{
+		metric.ErrorConnection: {Count: 1, LastError: "connection refused", LastTime: 3000},
+		metric.ErrorDecode:     {Count: 1, LastError: "invalid csv", LastTime: 1000},
+	}
+	src1.failing = metric.ErrorConnection
+	assert.Equal(t, &Health{
+		Status:  HealthDegraded,
+		Reasons: []string{"source src1 is reconnecting: connection refused"},
+		Errors: map[string]metric.ErrorStats{
+			metric.ErrorConnection: {Count: 1, LastError: "connection refused", LastTime: 3000},
+			metric.ErrorDecode:     {Count: 3, LastError: "invalid json", LastTime: 2000},
+		},
+	}, tp.GetHealth())
+
+	// Recovered
+	src1.failing = ""
+	assert.Equal(t, HealthHealthy, tp.GetHealth().Status)
+}
@@ -15,6 +15,7 @@
 package cache
 
 import (
+	"container/list"
 	"context"
 	"sync"
 
@@ -23,23 +24,30 @@ import (
 )
 
 type item struct {
+	key        string
 	data       []api.SourceTuple
 	expiration int64
 }
 
+// Cache is a LRU cache of the lookup results with expiration. It is safe to share between rules.
 type Cache struct {
 	expireTime      int
 	cacheMissingKey bool
-	cancel          context.CancelFunc
-	items           map[string]*item
-	sync.RWMutex
+	// the max number of cached keys, the least recently used keys are evicted if exceeded. 0 means unlimited.
+	maxEntries int
+	cancel     context.CancelFunc
+	items      map[string]*list.Element
+	ll         *list.List
+	sync.Mutex
 }
 
-func NewCache(expireTime int, cacheMissingKey bool) *Cache {
+func NewCache(expireTime int, cacheMissingKey bool, maxEntries int) *Cache {
 	c := &Cache{
 		expireTime:      expireTime,
 		cacheMissingKey: cacheMissingKey,
-		items:           make(map[string]*item),
+		maxEntries:      maxEntries,
+		items:           make(map[string]*list.Element),
+		ll:              list.New(),
 	}
 	if expireTime > 0 {
 		ctx, cancel := context.WithCancel(context.Background())
@@ -66,8 +74,10 @@ func (c *Cache) run(ctx context.Context) {
 func (c *Cache) deleteExpired() {
 	now := conf.GetNowInMilli()
 	c.Lock()
-	for k, v := range c.items {
+	for k, e := range c.items {
+		v := e.Value.(*item)
 		if v.expiration > 0 && now > v.expiration {
+			c.ll.Remove(e)
 			delete(c.items, k)
 		}
 	}
@@ -80,28 +90,55 @@ func (c *Cache) Set(key string, value []api.SourceTuple) {
 	}
 	c.Lock()
 	defer c.Unlock()
+	if c.items == nil {
+		return
+	}
+	var expiration int64
 	if c.expireTime > 0 {
-		c.items[key] = &item{data: value, expiration: conf.GetNowInMilli() + int64(c.expireTime*1000)}
-	} else {
-		c.items[key] = &item{data: value}
+		expiration = conf.GetNowInMilli() + int64(c.expireTime*1000)
+	}
+	if e, ok := c.items[key]; ok {
+		v := e.Value.(*item)
+		v.data = value
+		v.expiration = expiration
+		c.ll.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&item{key: key, data: value, expiration: expiration})
+	if c.maxEntries > 0 && c.ll.Len() > c.maxEntries {
+		last := c.ll.Back()
+		c.ll.Remove(last)
+		delete(c.items, last.Value.(*item).key)
 	}
 }
 
 func (c *Cache) Get(key string) ([]api.SourceTuple, bool) {
-	c.RLock()
-	defer c.RUnlock()
-	if v, ok := c.items[key]; ok {
+	c.Lock()
+	defer c.Unlock()
+	if e, ok := c.items[key]; ok {
+		v := e.Value.(*item)
 		if v.expiration > 0 && conf.GetNowInMilli() > v.expiration {
 			return nil, false
 		}
+		c.ll.MoveToFront(e)
 		return v.data, true
 	}
 	return nil, false
 }
 
+// Len returns the number of the cached keys including the expired ones not removed yet
+func (c *Cache) Len() int {
+	c.Lock()
+	defer c.Unlock()
+	return len(c.items)
+}
+
 func (c *Cache) Close() {
 	if c.cancel != nil {
 		c.cancel()
 	}
+	c.Lock()
 	c.items = nil
+	c.ll = nil
+	c.Unlock()
 }
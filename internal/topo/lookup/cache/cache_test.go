@@ -26,7 +26,7 @@ import (
 )
 
 func TestExpiration(t *testing.T) {
-	c := NewCache(20, false)
+	c := NewCache(20, false, 0)
 	defer c.Close()
 	clock := conf.Clock.(*clock.Mock)
 	expects := [][]api.SourceTuple{
@@ -84,7 +84,7 @@ func TestExpiration(t *testing.T) {
 }
 
 func TestNoExpiration(t *testing.T) {
-	c := NewCache(0, true)
+	c := NewCache(0, true, 0)
 	defer c.Close()
 	clock := conf.Clock.(*clock.Mock)
 	expects := [][]api.SourceTuple{
@@ -120,3 +120,29 @@ func TestNoExpiration(t *testing.T) {
 		return
 	}
 }
+
+func TestEviction(t *testing.T) {
+	c := NewCache(0, true, 2)
+	defer c.Close()
+	a := []api.SourceTuple{api.NewDefaultSourceTuple(map[string]interface{}{"a": 1}, nil)}
+	c.Set("a", a)
+	c.Set("b", nil)
+	// a is used recently, so b is evicted
+	if _, ok := c.Get("a"); !ok {
+		t.Error("a should exist")
+		return
+	}
+	c.Set("c", nil)
+	if c.Len() != 2 {
+		t.Errorf("expect 2 keys but got %d", c.Len())
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Error("b should be evicted")
+	}
+	if r, ok := c.Get("a"); !ok || !reflect.DeepEqual(r, a) {
+		t.Errorf("expect %v but get %v", a, r)
+	}
+	if _, ok := c.Get("c"); !ok {
+		t.Error("c should exist")
+	}
+}
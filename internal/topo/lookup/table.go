@@ -22,6 +22,7 @@ import (
 	"github.com/lf-edge/ekuiper/internal/binder/io"
 	"github.com/lf-edge/ekuiper/internal/conf"
 	kctx "github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/topo/lookup/cache"
 	nodeConf "github.com/lf-edge/ekuiper/internal/topo/node/conf"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/ast"
@@ -33,6 +34,8 @@ import (
 type info struct {
 	ls    api.LookupSource
 	count int32
+	// cache is shared by all the rules using the table
+	cache *cache.Cache
 }
 
 var (
@@ -51,6 +54,20 @@ func Attach(name string) (api.LookupSource, error) {
 	return nil, fmt.Errorf("lookup table %s is not found", name)
 }
 
+// AttachCache called by lookup nodes to get the cache of the table. The cache is created by the first caller with its
+// configurations and shared by all the rules, so that the rules joining the same keys can reuse the results.
+func AttachCache(name string, ttl int, cacheMissingKey bool, maxEntries int) (*cache.Cache, error) {
+	lock.Lock()
+	defer lock.Unlock()
+	if i, ok := instances[name]; ok {
+		if i.cache == nil {
+			i.cache = cache.NewCache(ttl, cacheMissingKey, maxEntries)
+		}
+		return i.cache, nil
+	}
+	return nil, fmt.Errorf("lookup table %s is not found", name)
+}
+
 // Detach called by lookup nodes when it is closed
 func Detach(name string) error {
 	lock.Lock()
@@ -68,7 +85,10 @@ func CreateInstance(name string, sourceType string, options *ast.Options) error
 	defer lock.Unlock()
 	contextLogger := conf.Log.WithField("table", name)
 	ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
-	props := nodeConf.GetSourceConf(sourceType, options)
+	props, err := nodeConf.GetSourceConf(sourceType, options)
+	if err != nil {
+		return err
+	}
 	ctx.GetLogger().Infof("open lookup table with props %v", conf.Printable(props))
 	// Create the lookup source according to the source options
 	ns, err := io.LookupSource(sourceType)
@@ -99,6 +119,9 @@ func DropInstance(name string) error {
 		if atomic.LoadInt32(&i.count) > 0 {
 			return fmt.Errorf("lookup table %s is still in use, stop all using rules before dropping it", name)
 		}
+		if i.cache != nil {
+			i.cache.Close()
+		}
 		delete(instances, name)
 		return nil
 	} else {
@@ -16,9 +16,10 @@ package node
 
 import (
 	"fmt"
+	"time"
 
-	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/topo/node/metric"
+	"github.com/lf-edge/ekuiper/internal/topo/vclock"
 	"github.com/lf-edge/ekuiper/internal/xsql"
 	"github.com/lf-edge/ekuiper/pkg/api"
 )
@@ -56,6 +57,13 @@ func (b *BatchOp) Exec(ctx api.StreamContext, _ chan<- error) {
 	ctx.GetLogger().Infof("batch op started")
 	b.statManager = metric.NewStatManager(ctx, "op")
 	b.ctx = ctx
+	// Send the incomplete batch when draining
+	b.drainHandler = func() {
+		if b.currIndex > 0 {
+			b.send()
+			b.statManager.IncTotalRecordsOut()
+		}
+	}
 	switch {
 	case b.batchSize > 0 && b.lingerInterval > 0:
 		b.runWithTickerAndBatchSize(ctx)
@@ -67,7 +75,7 @@ func (b *BatchOp) Exec(ctx api.StreamContext, _ chan<- error) {
 }
 
 func (b *BatchOp) runWithTickerAndBatchSize(ctx api.StreamContext) {
-	ticker := conf.GetTicker(int64(b.lingerInterval))
+	ticker := vclock.Get(ctx).Ticker(time.Duration(b.lingerInterval) * time.Millisecond)
 	go func() {
 		defer ticker.Stop()
 		for {
@@ -145,7 +153,7 @@ func (b *BatchOp) runWithBatchSize(ctx api.StreamContext) {
 }
 
 func (b *BatchOp) runWithTicker(ctx api.StreamContext) {
-	ticker := conf.GetTicker(int64(b.lingerInterval))
+	ticker := vclock.Get(ctx).Ticker(time.Duration(b.lingerInterval) * time.Millisecond)
 	go func() {
 		defer ticker.Stop()
 		for {
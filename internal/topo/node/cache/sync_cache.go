@@ -17,6 +17,7 @@ package cache
 import (
 	"path"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/lf-edge/ekuiper/internal/conf"
@@ -26,10 +27,15 @@ import (
 	"github.com/lf-edge/ekuiper/pkg/kv"
 )
 
+// defaultRetryInterval is the interval to resend the failed item if resendInterval is not set
+const defaultRetryInterval = time.Second
+
 // page Rotates storage for in memory cache
 // Not thread safe!
 type page struct {
 	Data [][]map[string]interface{}
+	// Ts is the time in milli when each item is cached. Pages saved by the old versions have no Ts
+	Ts   []int64
 	H    int
 	T    int
 	L    int
@@ -41,6 +47,7 @@ type page struct {
 func newPage(size int) *page {
 	return &page{
 		Data: make([][]map[string]interface{}, size),
+		Ts:   make([]int64, size),
 		H:    0, // When deleting, head++, if tail == head, it is empty
 		T:    0, // When append, tail++, if tail== head, it is full
 		Size: size,
@@ -53,6 +60,10 @@ func (p *page) append(item []map[string]interface{}) bool {
 		return false
 	}
 	p.Data[p.T] = item
+	if len(p.Ts) != p.Size {
+		p.Ts = make([]int64, p.Size)
+	}
+	p.Ts[p.T] = conf.GetNowInMilli()
 	p.T++
 	if p.T == p.Size {
 		p.T = 0
@@ -69,6 +80,23 @@ func (p *page) peak() ([]map[string]interface{}, bool) {
 	return p.Data[p.H], true
 }
 
+// oldest get the cached time of the first item, return 0 if unknown
+func (p *page) oldest() int64 {
+	if p.L == 0 || len(p.Ts) != p.Size {
+		return 0
+	}
+	return p.Ts[p.H]
+}
+
+// replaceHead replaces the first item and keeps its cached time
+func (p *page) replaceHead(item []map[string]interface{}) bool {
+	if p.L == 0 {
+		return false
+	}
+	p.Data[p.H] = item
+	return true
+}
+
 func (p *page) delete() bool {
 	if p.L == 0 {
 		return false
@@ -96,11 +124,16 @@ type SyncCache struct {
 	in      <-chan []map[string]interface{}
 	Out     chan []map[string]interface{}
 	Ack     chan bool
+	Retry   chan []map[string]interface{} // a false ack to resend the failed part of the sending item only
 	errorCh chan<- error
 	// cache config
 	cacheConf   *conf.SinkConf
 	maxDiskPage int
 	maxMemPage  int
+	// capacity is the max count of items for dropNewest and block policy
+	capacity int
+	// Restored is the count of the items restored from the disk when the cache is created. It is read only.
+	Restored int
 	// cache storage
 	memCache       []*page
 	diskBufferPage *page
@@ -110,6 +143,10 @@ type SyncCache struct {
 	diskPageTail int // init from the database
 	diskPageHead int
 	sendStatus   int // 0: idle, 1: sending and waiting for ack, 2: stopped for error
+	// stats for metrics which are read by other goroutines
+	length     atomic.Int64
+	oldestTime atomic.Int64
+	dropped    atomic.Int64
 	// serialize
 	store kv.KeyValue
 
@@ -128,12 +165,17 @@ func NewSyncCache(ctx api.StreamContext, in <-chan []map[string]interface{}, err
 		in:         in,
 		Out:        make(chan []map[string]interface{}, bufferLength),
 		Ack:        make(chan bool, 10),
+		Retry:      make(chan []map[string]interface{}, 10),
 		errorCh:    errCh,
 		maxMemPage: cacheConf.MemoryCacheThreshold / cacheConf.BufferPageSize,
 		memCache:   make([]*page, 0),
 		// add one more slot so that there will be at least one slot between head and tail to find out the head/tail id
 		maxDiskPage: (cacheConf.MaxDiskCache / cacheConf.BufferPageSize) + 1,
+		capacity:    cacheConf.MemoryCacheThreshold + cacheConf.MaxDiskCache,
 	}
+	// Restore before running so that the restored items are known by the sink
+	c.initStore(ctx)
+	c.Restored = c.CacheLength
 	go func() {
 		err := infra.SafeRun(func() error {
 			c.run(ctx)
@@ -147,14 +189,21 @@ func NewSyncCache(ctx api.StreamContext, in <-chan []map[string]interface{}, err
 }
 
 func (c *SyncCache) run(ctx api.StreamContext) {
-	c.initStore(ctx)
 	defer c.onClose(ctx)
 	if c.CacheLength > 0 { // start to send the cache
 		c.send(ctx)
 	}
+	// retry the failed item even if no new data comes in, such as a long network outage
+	var retryCh <-chan time.Time
 	for {
+		c.updateStats()
+		in := c.in
+		// stop reading so that the upstream is blocked
+		if c.cacheConf.OverflowPolicy == conf.OverflowBlock && c.isFull() {
+			in = nil
+		}
 		select {
-		case item := <-c.in:
+		case item := <-in:
 			ctx.GetLogger().Debugf("adding cache %v", item)
 			// hack here: nil is a signal to continue sending, so not adding nil to cache
 			if item != nil {
@@ -180,12 +229,28 @@ func (c *SyncCache) run(ctx api.StreamContext) {
 				ctx.GetLogger().Debug("send status to 0 after true ack")
 			} else {
 				c.sendStatus = 2
+				retryCh = time.After(c.retryInterval())
 				ctx.GetLogger().Debug("send status to 2 after false ack")
 			}
 			ctx.GetLogger().Debugf("cache status %d", c.sendStatus)
 			if c.sendStatus == 0 {
 				c.send(ctx)
 			}
+		case failed := <-c.Retry:
+			ctx.GetLogger().Debugf("cache retry %d items", len(failed))
+			if len(c.memCache) > 0 {
+				c.memCache[0].replaceHead(failed)
+			}
+			c.sendStatus = 2
+			retryCh = time.After(c.retryInterval())
+			ctx.GetLogger().Debug("send status to 2 after retry")
+		case <-retryCh:
+			retryCh = nil
+			if c.sendStatus == 2 {
+				c.sendStatus = 0
+				ctx.GetLogger().Debug("send status to 0 to retry in error state")
+				c.send(ctx)
+			}
 		case <-ctx.Done():
 			ctx.GetLogger().Infof("sink node %s instance cache %d done", ctx.GetOpId(), ctx.GetInstanceId())
 			return
@@ -193,6 +258,42 @@ func (c *SyncCache) run(ctx api.StreamContext) {
 	}
 }
 
+// Stats returns the count of the cached items and the cached time in milli of the oldest one.
+// It is safe to call in other goroutines.
+func (c *SyncCache) Stats() (int64, int64) {
+	return c.length.Load(), c.oldestTime.Load()
+}
+
+// Dropped returns the count of the items dropped due to overflow. It is safe to call in other goroutines.
+func (c *SyncCache) Dropped() int64 {
+	return c.dropped.Load()
+}
+
+func (c *SyncCache) isFull() bool {
+	return c.CacheLength >= c.capacity
+}
+
+func (c *SyncCache) updateStats() {
+	c.length.Store(int64(c.CacheLength))
+	var oldest int64
+	if c.CacheLength > 0 {
+		for _, p := range c.memCache {
+			if !p.isEmpty() {
+				oldest = p.oldest()
+				break
+			}
+		}
+	}
+	c.oldestTime.Store(oldest)
+}
+
+func (c *SyncCache) retryInterval() time.Duration {
+	if c.cacheConf.ResendInterval > 0 {
+		return time.Duration(c.cacheConf.ResendInterval) * time.Millisecond
+	}
+	return defaultRetryInterval
+}
+
 func (c *SyncCache) send(ctx api.StreamContext) {
 	if c.CacheLength > 1 && c.cacheConf.ResendInterval > 0 {
 		time.Sleep(time.Duration(c.cacheConf.ResendInterval) * time.Millisecond)
@@ -215,7 +316,13 @@ func (c *SyncCache) send(ctx api.StreamContext) {
 
 // addCache not thread safe!
 func (c *SyncCache) addCache(ctx api.StreamContext, item []map[string]interface{}) {
-	isNotFull := c.appendMemCache(item)
+	if c.cacheConf.OverflowPolicy == conf.OverflowDropNewest && c.isFull() {
+		c.dropped.Add(1)
+		ctx.GetLogger().Debugf("cache is full, drop the newest item %v", item)
+		return
+	}
+	// To keep the order, only add to memory when there are no older items in the disk or disk buffer
+	isNotFull := c.diskSize == 0 && (c.diskBufferPage == nil || c.diskBufferPage.isEmpty()) && c.appendMemCache(item)
 	if !isNotFull {
 		if c.diskBufferPage == nil {
 			c.diskBufferPage = newPage(c.cacheConf.BufferPageSize)
@@ -294,6 +401,7 @@ func (c *SyncCache) loadFromDisk(ctx api.StreamContext) {
 		_ = c.store.Delete(strconv.Itoa(c.diskPageHead))
 		if len(c.memCache) >= c.maxMemPage {
 			ctx.GetLogger().Warnf("drop a page of %d items in memory", c.memCache[0].L)
+			c.dropped.Add(int64(c.memCache[0].L))
 			c.CacheLength -= c.memCache[0].L
 			c.memCache = c.memCache[1:]
 		}
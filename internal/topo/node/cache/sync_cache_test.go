@@ -22,6 +22,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/benbjohnson/clock"
+
 	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/testx"
 	"github.com/lf-edge/ekuiper/internal/topo/context"
@@ -30,6 +32,8 @@ import (
 )
 
 func TestPage(t *testing.T) {
+	// the mock clock starts from 0 which is regarded as unknown time
+	conf.Clock.(*clock.Mock).Add(time.Second)
 	p := newPage(2)
 	if !p.isEmpty() {
 		t.Errorf("page is not empty")
@@ -49,6 +53,9 @@ func TestPage(t *testing.T) {
 	}) {
 		t.Fatal("should append fail")
 	}
+	if p.oldest() == 0 {
+		t.Fatal("oldest time is not set")
+	}
 	v, ok := p.peak()
 	if !ok {
 		t.Fatal("peak failed")
@@ -257,3 +264,164 @@ func deleteCachedb() {
 		fmt.Println(err)
 	}
 }
+
+func TestRetryAndStats(t *testing.T) {
+	testx.InitEnv("cache")
+	tempStore, _ := state.CreateStore("mock", api.AtMostOnce)
+	conf.Clock.(*clock.Mock).Add(time.Second)
+	contextLogger := conf.Log.WithField("rule", "TestRetryAndStats")
+	ctx, cancel := context.WithValue(context.Background(), context.LoggerKey, contextLogger).WithMeta("ruleRetry", "opRetry", tempStore).WithCancel()
+	defer cancel()
+	in := make(chan []map[string]interface{})
+	errCh := make(chan error, 1)
+	sc := NewSyncCache(ctx, in, errCh, &conf.SinkConf{
+		MemoryCacheThreshold: 2,
+		MaxDiskCache:         8,
+		BufferPageSize:       1,
+		EnableCache:          true,
+		ResendInterval:       10,
+		CleanCacheAtStop:     true,
+	}, 10)
+	for i := 1; i <= 4; i++ {
+		in <- []map[string]interface{}{{"a": i}}
+	}
+	// wait for the stats to update
+	time.Sleep(10 * time.Millisecond)
+	l, oldest := sc.Stats()
+	if l != 4 {
+		t.Errorf("expect cache length 4 but got %d", l)
+	}
+	if oldest == 0 || oldest > conf.GetNowInMilli() {
+		t.Errorf("invalid oldest time %d", oldest)
+	}
+	var result []interface{}
+	r := <-sc.Out
+	result = append(result, r)
+	// fail the first one, it should be resent without new data
+	sc.Ack <- false
+	select {
+	case r = <-sc.Out:
+		result = append(result, r)
+	case <-time.After(time.Second):
+		t.Fatal("failed item is not resent")
+	}
+	for i := 0; i < 4; i++ {
+		sc.Ack <- true
+		if i == 3 {
+			break
+		}
+		select {
+		case r = <-sc.Out:
+			result = append(result, r)
+		case <-time.After(time.Second):
+			t.Fatal("no data")
+		}
+	}
+	exp := []interface{}{
+		[]map[string]interface{}{{"a": 1}},
+		[]map[string]interface{}{{"a": 1}},
+		[]map[string]interface{}{{"a": 2}},
+		[]map[string]interface{}{{"a": 3}},
+		[]map[string]interface{}{{"a": 4}},
+	}
+	if !reflect.DeepEqual(exp, result) {
+		t.Errorf("expect\t%v\nbut got\t%v", exp, result)
+	}
+	time.Sleep(10 * time.Millisecond)
+	l, oldest = sc.Stats()
+	if l != 0 || oldest != 0 {
+		t.Errorf("expect empty stats but got %d, %d", l, oldest)
+	}
+}
+
+func TestOverflowPolicy(t *testing.T) {
+	testx.InitEnv("cache")
+	tempStore, _ := state.CreateStore("mock", api.AtMostOnce)
+	for _, policy := range []string{conf.OverflowDropNewest, conf.OverflowBlock} {
+		t.Run(policy, func(t *testing.T) {
+			contextLogger := conf.Log.WithField("rule", "TestOverflowPolicy")
+			ctx, cancel := context.WithValue(context.Background(), context.LoggerKey, contextLogger).WithMeta("rule"+policy, "op"+policy, tempStore).WithCancel()
+			defer cancel()
+			in := make(chan []map[string]interface{})
+			errCh := make(chan error, 1)
+			sc := NewSyncCache(ctx, in, errCh, &conf.SinkConf{
+				MemoryCacheThreshold: 2,
+				MaxDiskCache:         2,
+				BufferPageSize:       1,
+				EnableCache:          true,
+				CleanCacheAtStop:     true,
+				OverflowPolicy:       policy,
+			}, 10)
+			for i := 1; i <= 4; i++ {
+				in <- []map[string]interface{}{{"a": i}}
+			}
+			select {
+			case in <- []map[string]interface{}{{"a": 5}}:
+				if policy == conf.OverflowBlock {
+					t.Fatal("should block when the cache is full")
+				}
+			case <-time.After(50 * time.Millisecond):
+				if policy != conf.OverflowBlock {
+					t.Fatal("should not block")
+				}
+				// ack the first one to make room
+				<-sc.Out
+				sc.Ack <- true
+				in <- []map[string]interface{}{{"a": 5}}
+			}
+			time.Sleep(10 * time.Millisecond)
+			l, _ := sc.Stats()
+			if policy == conf.OverflowBlock {
+				if l != 4 || sc.Dropped() != 0 {
+					t.Errorf("expect 4 cached and 0 dropped but got %d and %d", l, sc.Dropped())
+				}
+			} else {
+				if l != 4 || sc.Dropped() != 1 {
+					t.Errorf("expect 4 cached and 1 dropped but got %d and %d", l, sc.Dropped())
+				}
+			}
+		})
+	}
+}
+
+func TestRetryFailedPart(t *testing.T) {
+	testx.InitEnv("cache")
+	tempStore, _ := state.CreateStore("mock", api.AtMostOnce)
+	contextLogger := conf.Log.WithField("rule", "TestRetryFailedPart")
+	ctx, cancel := context.WithValue(context.Background(), context.LoggerKey, contextLogger).WithMeta("rulePart", "opPart", tempStore).WithCancel()
+	defer cancel()
+	in := make(chan []map[string]interface{})
+	errCh := make(chan error, 1)
+	sc := NewSyncCache(ctx, in, errCh, &conf.SinkConf{
+		MemoryCacheThreshold: 2,
+		MaxDiskCache:         2,
+		BufferPageSize:       1,
+		EnableCache:          true,
+		ResendInterval:       10,
+		CleanCacheAtStop:     true,
+	}, 10)
+	in <- []map[string]interface{}{{"a": 1}, {"a": 2}, {"a": 3}}
+	in <- []map[string]interface{}{{"a": 4}}
+	r := <-sc.Out
+	if !reflect.DeepEqual([]map[string]interface{}{{"a": 1}, {"a": 2}, {"a": 3}}, r) {
+		t.Fatalf("unexpected first item %v", r)
+	}
+	sc.Retry <- []map[string]interface{}{{"a": 2}}
+	select {
+	case r = <-sc.Out:
+	case <-time.After(time.Second):
+		t.Fatal("failed part is not resent")
+	}
+	if !reflect.DeepEqual([]map[string]interface{}{{"a": 2}}, r) {
+		t.Fatalf("expect the failed part only but got %v", r)
+	}
+	sc.Ack <- true
+	select {
+	case r = <-sc.Out:
+	case <-time.After(time.Second):
+		t.Fatal("no data")
+	}
+	if !reflect.DeepEqual([]map[string]interface{}{{"a": 4}}, r) {
+		t.Fatalf("unexpected next item %v", r)
+	}
+}
@@ -15,12 +15,23 @@
 package node
 
 import (
+	"github.com/lf-edge/ekuiper/internal/topo/checkpoint"
 	"github.com/lf-edge/ekuiper/pkg/api"
 )
 
 type workerFunc func(item any) []any
 
+// drainBarrier is passed through all workers when draining. The merger acks it after all the results before it are sent.
+type drainBarrier struct {
+	acks chan struct{}
+}
+
 func runWithOrder(ctx api.StreamContext, node *defaultSinkNode, numWorkers int, wf workerFunc) {
+	runWorkersWithOrder(ctx, node, numWorkers, func(int) workerFunc { return wf })
+}
+
+// runWorkersWithOrder is the same as runWithOrder but creates the worker function for each worker
+func runWorkersWithOrder(ctx api.StreamContext, node *defaultSinkNode, numWorkers int, newWorker func(i int) workerFunc) {
 	workerChans := make([]chan any, numWorkers)
 	workerOutChans := make([]chan []any, numWorkers)
 	for i := range workerChans {
@@ -30,7 +41,7 @@ func runWithOrder(ctx api.StreamContext, node *defaultSinkNode, numWorkers int,
 
 	// Start worker goroutines
 	for i := 0; i < numWorkers; i++ {
-		go worker(ctx, i, wf, workerChans[i], workerOutChans[i])
+		go worker(ctx, i, newWorker(i), workerChans[i], workerOutChans[i])
 	}
 	// start merger goroutine
 	output := make(chan any)
@@ -48,16 +59,7 @@ func merge(ctx api.StreamContext, node *defaultSinkNode, output chan any, channe
 		for _, ch := range channels {
 			select {
 			case data := <-ch:
-				for _, d := range data {
-					node.Broadcast(d)
-					switch dt := d.(type) {
-					case error:
-						node.statManager.IncTotalExceptions(dt.Error())
-					default:
-						node.statManager.IncTotalRecordsOut()
-					}
-				}
-				node.statManager.IncTotalMessagesProcessed(1)
+				emit(node, data)
 			case <-ctx.Done():
 				ctx.GetLogger().Infof("merge done")
 				return
@@ -66,8 +68,62 @@ func merge(ctx api.StreamContext, node *defaultSinkNode, output chan any, channe
 	}
 }
 
+// emit sends out the results of a worker or acks the drain barrier
+func emit(node *defaultSinkNode, data []any) {
+	if len(data) == 1 {
+		if b, ok := data[0].(*drainBarrier); ok {
+			b.acks <- struct{}{}
+			return
+		}
+	}
+	for _, d := range data {
+		node.Broadcast(d)
+		switch dt := d.(type) {
+		case error:
+			node.statManager.IncTotalExceptions(dt.Error())
+		default:
+			node.statManager.IncTotalRecordsOut()
+		}
+	}
+	node.statManager.IncTotalMessagesProcessed(1)
+}
+
+// flushWorkers waits for the workers to finish the data in hand and the results to be sent out.
+// The workers are visited from the start so that the ordered merger receives the barriers in turn.
+func flushWorkers(ctx api.StreamContext, start int, workerChans []chan any) {
+	numWorkers := len(workerChans)
+	b := &drainBarrier{acks: make(chan struct{}, numWorkers)}
+	for i := 0; i < numWorkers; i++ {
+		select {
+		case workerChans[(start+i)%numWorkers] <- b:
+		case <-ctx.Done():
+			return
+		}
+	}
+	for i := 0; i < numWorkers; i++ {
+		select {
+		case <-b.acks:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// isBarrier reports whether the item is a checkpoint barrier which must not overtake the data in the workers
+func isBarrier(item any) bool {
+	if b, ok := item.(*checkpoint.BufferOrEvent); ok {
+		_, ok = b.Data.(*checkpoint.Barrier)
+		return ok
+	}
+	return false
+}
+
 func distribute(ctx api.StreamContext, node *defaultSinkNode, numWorkers int, workerChans []chan any) {
 	var counter int
+	// Wait for the workers to finish the data in hand before sending out the drain signal
+	node.drainHandler = func() {
+		flushWorkers(ctx, counter, workerChans)
+	}
 	for {
 		node.statManager.SetBufferLength(int64(len(node.input)))
 		// Round-robin
@@ -80,9 +136,13 @@ func distribute(ctx api.StreamContext, node *defaultSinkNode, numWorkers int, wo
 			return
 		case item := <-node.input:
 			ctx.GetLogger().Debugf("distributor receive %v", item)
+			if isBarrier(item) {
+				flushWorkers(ctx, counter, workerChans)
+			}
 			processed := false
 			if item, processed = node.preprocess(item); processed {
-				break
+				// keep the order of the workers as the merger reads them in turn
+				continue
 			}
 			node.statManager.IncTotalRecordsIn()
 			workerChans[counter] <- item
@@ -96,8 +156,14 @@ func worker(ctx api.StreamContext, i int, wf workerFunc, inputRaw chan any, outp
 		select {
 		case data := <-inputRaw:
 			ctx.GetLogger().Debugf("worker %d received %v", i, data)
+			var result []any
+			if _, ok := data.(*drainBarrier); ok {
+				result = []any{data}
+			} else {
+				result = wf(data)
+			}
 			select {
-			case output <- wf(data):
+			case output <- result:
 			case <-ctx.Done():
 				ctx.GetLogger().Debugf("worker %d done", i)
 				return
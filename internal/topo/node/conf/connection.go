@@ -0,0 +1,72 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"strings"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+)
+
+const ConnectionSelector = "connectionSelector"
+
+// nativeSelectorTypes read the connection by themselves to share the client
+var nativeSelectorTypes = map[string]struct{}{
+	"mqtt":  {},
+	"edgex": {},
+}
+
+// ApplyConnection fills the props with the named connection referred by the connectionSelector property like
+// redis.local. The props of the source or sink override those of the connection. The selector is kept in the props so
+// that the io can share the client by it.
+func ApplyConnection(props map[string]interface{}) map[string]interface{} {
+	var selector string
+	for k, v := range props {
+		if strings.EqualFold(k, ConnectionSelector) {
+			selector, _ = v.(string)
+			break
+		}
+	}
+	if selector == "" {
+		return props
+	}
+	sel := &conf.ConSelector{ConnSelectorStr: selector}
+	if err := sel.Init(); err != nil {
+		conf.Log.Warnf("%v", err)
+		return props
+	}
+	if _, ok := nativeSelectorTypes[sel.Type]; ok {
+		return props
+	}
+	cp, err := sel.ReadCfgFromYaml()
+	if cp == nil {
+		conf.Log.Warnf("fail to read connection %s: %v", selector, err)
+		return props
+	}
+	// the keys of the connections in etc folder may be lowercase, compare the keys case-insensitively to override
+	keys := make(map[string]struct{}, len(props))
+	result := make(map[string]interface{}, len(cp)+len(props))
+	for k, v := range props {
+		keys[strings.ToLower(k)] = struct{}{}
+		result[k] = v
+	}
+	for k, v := range cp {
+		if _, ok := keys[strings.ToLower(k)]; !ok {
+			result[k] = v
+		}
+	}
+	conf.Log.Debugf("apply connection %s: %v", selector, printable(result))
+	return result
+}
@@ -0,0 +1,65 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyConnection(t *testing.T) {
+	tests := []struct {
+		name  string
+		props map[string]interface{}
+		want  map[string]interface{}
+	}{
+		{
+			name:  "no selector",
+			props: map[string]interface{}{"addr": "127.0.0.1:6380"},
+			want:  map[string]interface{}{"addr": "127.0.0.1:6380"},
+		},
+		{
+			name:  "merge",
+			props: map[string]interface{}{"connectionSelector": "redis.localRedis", "key": "test"},
+			want:  map[string]interface{}{"connectionSelector": "redis.localRedis", "key": "test", "addr": "127.0.0.1:6379"},
+		},
+		{
+			name:  "override",
+			props: map[string]interface{}{"connectionSelector": "redis.localRedis", "Addr": "127.0.0.1:6380"},
+			want:  map[string]interface{}{"connectionSelector": "redis.localRedis", "Addr": "127.0.0.1:6380"},
+		},
+		{
+			name:  "native",
+			props: map[string]interface{}{"connectionSelector": "mqtt.localConnection"},
+			want:  map[string]interface{}{"connectionSelector": "mqtt.localConnection"},
+		},
+		{
+			name:  "not found",
+			props: map[string]interface{}{"connectionSelector": "redis.none"},
+			want:  map[string]interface{}{"connectionSelector": "redis.none"},
+		},
+		{
+			name:  "invalid",
+			props: map[string]interface{}{"connectionSelector": "redis"},
+			want:  map[string]interface{}{"connectionSelector": "redis"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ApplyConnection(tt.props))
+		})
+	}
+}
@@ -15,12 +15,24 @@
 package conf
 
 import (
+	"fmt"
+
 	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/conf/secret"
 )
 
 const ResourceID = "resourceId"
 
-func GetSinkConf(sinkType string, action map[string]interface{}) map[string]interface{} {
+// GetSinkConf returns the props of the sink with the secrets resolved. The action is not modified by the resolution
+func GetSinkConf(sinkType string, action map[string]interface{}) (map[string]interface{}, error) {
+	props, err := secret.ResolveProps(ApplyConnection(getResourceConf(sinkType, action)))
+	if err != nil {
+		return nil, fmt.Errorf("fail to resolve the secrets for sink %s: %v", sinkType, err)
+	}
+	return props, nil
+}
+
+func getResourceConf(sinkType string, action map[string]interface{}) map[string]interface{} {
 	resourceId, ok := action[ResourceID].(string)
 	if !ok {
 		return action
@@ -0,0 +1,39 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/conf/secret"
+)
+
+func TestGetSinkConfSecret(t *testing.T) {
+	t.Setenv("KUIPER_TEST_SINK_SECRET", "sinkPass")
+	secret.Init(secret.Conf{EnvPrefixes: []string{"KUIPER_TEST_"}}, "")
+	defer secret.Init(secret.Conf{}, "")
+	action := map[string]interface{}{"password": "secret://env/KUIPER_TEST_SINK_SECRET"}
+	props, err := GetSinkConf("log", action)
+	require.NoError(t, err)
+	assert.Equal(t, "sinkPass", props["password"])
+	// The rule action keeps the reference
+	assert.Equal(t, "secret://env/KUIPER_TEST_SINK_SECRET", action["password"])
+
+	_, err = GetSinkConf("log", map[string]interface{}{"password": "secret://env/KUIPER_TEST_SINK_NONE"})
+	assert.EqualError(t, err, "fail to resolve the secrets for sink log: password: fail to get secret secret://env/KUIPER_TEST_SINK_NONE: environment variable KUIPER_TEST_SINK_NONE is not set")
+}
@@ -15,13 +15,15 @@
 package conf
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/conf/secret"
 	"github.com/lf-edge/ekuiper/pkg/ast"
 )
 
-func GetSourceConf(sourceType string, options *ast.Options) map[string]interface{} {
+func GetSourceConf(sourceType string, options *ast.Options) (map[string]interface{}, error) {
 	confkey := options.CONF_KEY
 
 	yamlOps, err := conf.NewConfigOperatorFromSourceStorage(sourceType)
@@ -59,10 +61,14 @@ func GetSourceConf(sourceType string, options *ast.Options) map[string]interface
 	if f == "" {
 		f = "json"
 	}
+	props, err = secret.ResolveProps(ApplyConnection(props))
+	if err != nil {
+		return nil, fmt.Errorf("fail to resolve the secrets for source %s: %v", sourceType, err)
+	}
 	props["format"] = strings.ToLower(f)
 	props["key"] = options.KEY
 	conf.Log.Debugf("get conf for %s with conf key %s: %v", sourceType, confkey, printable(props))
-	return props
+	return props, nil
 }
 
 func printable(m map[string]interface{}) map[string]interface{} {
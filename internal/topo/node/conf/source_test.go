@@ -69,7 +69,8 @@ func TestGetSourceConf(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := GetSourceConf(tt.args.sourceType, tt.args.options)
+			got, err := GetSourceConf(tt.args.sourceType, tt.args.options)
+			assert.NoError(t, err)
 			assert.Equal(t, tt.want, got)
 		})
 	}
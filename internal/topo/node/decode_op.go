@@ -16,6 +16,7 @@ package node
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/converter"
@@ -74,7 +75,7 @@ func NewDecodeOp(name, StreamName string, ruleId string, rOpt *api.RuleOption, o
 func (o *DecodeOp) Exec(ctx api.StreamContext, errCh chan<- error) {
 	// TODO move this to new
 	ctx.GetLogger().Infof("decode op started")
-	o.statManager = metric.NewStatManager(ctx, "op")
+	o.statManager = metric.NewStatManagerWithCategory(ctx, "op", metric.ErrorDecode)
 	o.ctx = ctx
 	go func() {
 		err := infra.SafeRun(func() error {
@@ -90,6 +91,23 @@ func (o *DecodeOp) Exec(ctx api.StreamContext, errCh chan<- error) {
 func (o *DecodeOp) Worker(item any) []any {
 	o.statManager.ProcessTimeStart()
 	defer o.statManager.ProcessTimeEnd()
+	span := startSpan(o.ctx, o.name, item)
+	start := time.Now()
+	result := o.decode(item)
+	o.chargeQuota(start)
+	var err error
+	for _, r := range result {
+		if e, ok := r.(error); ok {
+			err = e
+		} else {
+			span.output(r)
+		}
+	}
+	span.end(err)
+	return result
+}
+
+func (o *DecodeOp) decode(item any) []any {
 	switch d := item.(type) {
 	case error:
 		return []any{d}
@@ -0,0 +1,102 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"github.com/lf-edge/ekuiper/internal/topo/checkpoint"
+)
+
+// DrainSignal is sent by the sources after they stop reading when the rule is draining.
+// It follows all the data sent before, so a node handles it after receiving it from all inputs:
+// the node flushes its buffered data such as the open windows and then forwards the signal.
+type DrainSignal struct{}
+
+// Drainable is the source node which can stop reading to drain the rule
+type Drainable interface {
+	Drain()
+}
+
+func isDrainSignal(val any) bool {
+	if boe, ok := val.(*checkpoint.BufferOrEvent); ok {
+		val = boe.Data
+	}
+	_, ok := val.(*DrainSignal)
+	return ok
+}
+
+// drain is called in the node goroutine when receiving the drain signal from an input
+func (o *defaultSinkNode) drain() {
+	if int(o.drainCount.Add(1)) < o.inputCount {
+		return
+	}
+	o.drainCount.Store(0)
+	o.ctx.GetLogger().Infof("node %s is drained", o.name)
+	if o.drainHandler != nil {
+		o.drainHandler()
+	}
+	o.Broadcast(&DrainSignal{})
+}
+
+// Drain makes the source node stop reading and send the drain signal
+func (m *SourceNode) Drain() {
+	select {
+	case m.drainChan() <- struct{}{}:
+	default:
+	}
+}
+
+func (m *SourceNode) drainChan() chan struct{} {
+	m.drainMu.Lock()
+	defer m.drainMu.Unlock()
+	if m.drainCh == nil {
+		m.drainCh = make(chan struct{}, 1)
+	}
+	return m.drainCh
+}
+
+// Drained returns the channel which is closed after the drain signal is received and all buffered results are sent
+func (m *SinkNode) Drained() <-chan struct{} {
+	m.drainMu.Lock()
+	defer m.drainMu.Unlock()
+	if m.drained == nil {
+		m.drained = make(chan struct{})
+	}
+	return m.drained
+}
+
+func (m *SinkNode) resetDrain() {
+	m.drainMu.Lock()
+	defer m.drainMu.Unlock()
+	m.drained = nil
+	m.drainReceived.Store(false)
+	m.pending.Store(0)
+}
+
+// checkDrained is called by all sink instances after the pending results change
+func (m *SinkNode) checkDrained() {
+	if !m.drainReceived.Load() || m.pending.Load() > 0 {
+		return
+	}
+	m.drainMu.Lock()
+	defer m.drainMu.Unlock()
+	if m.drained == nil {
+		m.drained = make(chan struct{})
+	}
+	select {
+	case <-m.drained:
+	default:
+		close(m.drained)
+	}
+}
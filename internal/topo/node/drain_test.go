@@ -0,0 +1,87 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/topo/node/metric"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	mockContext "github.com/lf-edge/ekuiper/pkg/mock/context"
+)
+
+func receive(t *testing.T, out <-chan any) any {
+	select {
+	case r := <-out:
+		return r
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timeout")
+		return nil
+	}
+}
+
+func TestBatchDrain(t *testing.T) {
+	op, err := NewBatchOp("test", &api.RuleOption{BufferLength: 10}, 10, 0)
+	require.NoError(t, err)
+	// Two upstream nodes
+	op.AddInputCount()
+	op.AddInputCount()
+	out := make(chan any, 10)
+	require.NoError(t, op.AddOutput(out, "test"))
+	ctx, cancel := mockContext.NewMockContext("test1", "batch_drain").WithCancel()
+	defer cancel()
+	op.Exec(ctx, make(chan error))
+	op.input <- &xsql.Tuple{Emitter: "test", Message: map[string]any{"a": 1}}
+	op.input <- &DrainSignal{}
+	op.input <- &xsql.Tuple{Emitter: "test", Message: map[string]any{"a": 2}}
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, out, 0)
+	// The incomplete batch is sent after all inputs are drained
+	op.input <- &DrainSignal{}
+	w, ok := receive(t, out).(*xsql.WindowTuples)
+	require.True(t, ok)
+	assert.Len(t, w.Content, 2)
+	assert.IsType(t, &DrainSignal{}, receive(t, out))
+}
+
+func TestRunWithOrderDrain(t *testing.T) {
+	ctx, cancel := mockContext.NewMockContext("test1", "order_drain").WithCancel()
+	defer cancel()
+	n := newDefaultSinkNode("test", &api.RuleOption{BufferLength: 10})
+	n.ctx = ctx
+	n.statManager = metric.NewStatManager(ctx, "op")
+	n.AddInputCount()
+	out := make(chan any, 10)
+	require.NoError(t, n.AddOutput(out, "test"))
+	go runWithOrder(ctx, n, 3, func(item any) []any {
+		// The earlier item is slower
+		time.Sleep(time.Duration(5-item.(int)) * 10 * time.Millisecond)
+		return []any{item}
+	})
+	for i := 0; i < 5; i++ {
+		n.input <- i
+	}
+	n.input <- &DrainSignal{}
+	// The drain signal must not overtake the items in the workers
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, i, receive(t, out))
+	}
+	assert.IsType(t, &DrainSignal{}, receive(t, out))
+}
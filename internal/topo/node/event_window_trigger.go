@@ -115,6 +115,25 @@ func (o *WindowOperator) execEventWindow(ctx api.StreamContext, inputs []*xsql.T
 		prevWindowEndTs int64
 		lastTicked      bool
 	)
+	// The watermark op has sent out all pending events, so only the open window is left
+	o.drainHandler = func() {
+		if len(inputs) == 0 {
+			return
+		}
+		end := nextWindowEndTs
+		if end == math.MaxInt64 || end <= 0 {
+			end = 0
+		}
+		o.flush(ctx, inputs, end, inputs[len(inputs)-1].Timestamp+1)
+	}
+	if o.allowedLateness > 0 {
+		// The tuples before the last emitted window end are late and update the restored windows
+		end, err := o.restoreEmittedWindows(ctx)
+		if err != nil {
+			log.Warnf("restore emitted windows fails: %v", err)
+		}
+		prevWindowEndTs = end
+	}
 	for {
 		select {
 		// process incoming item
@@ -168,6 +187,9 @@ func (o *WindowOperator) execEventWindow(ctx api.StreamContext, inputs []*xsql.T
 								o.triggerTS = o.triggerTS[1:]
 							}
 						} else {
+							if o.allowedLateness > 0 {
+								o.retainWindow(inputs, windowEndTs)
+							}
 							inputs = o.scan(inputs, windowEndTs, ctx)
 						}
 					}
@@ -182,6 +204,10 @@ func (o *WindowOperator) execEventWindow(ctx api.StreamContext, inputs []*xsql.T
 				}
 				nextWindowEndTs = windowEndTs
 				log.Debugf("next window end %d", nextWindowEndTs)
+				if o.allowedLateness > 0 {
+					o.gcEmittedWindows(watermarkTs)
+					_ = ctx.PutState(EmittedWindowsKey, o.emittedWindows)
+				}
 			case *xsql.Tuple:
 				ctx.GetLogger().Debug("Tuple", d.GetTimestamp())
 				o.statManager.ProcessTimeStart()
@@ -194,7 +220,22 @@ func (o *WindowOperator) execEventWindow(ctx api.StreamContext, inputs []*xsql.T
 				if o.window.Type == ast.SLIDING_WINDOW && o.isMatchCondition(ctx, d) {
 					o.triggerTS = append(o.triggerTS, d.GetTimestamp())
 				}
-				inputs = append(inputs, d)
+				if o.allowedLateness > 0 && d.Timestamp < prevWindowEndTs {
+					// late tuple within the allowed lateness, update the emitted windows
+					if !o.updateEmittedWindows(ctx, d) {
+						log.Debugf("drop late tuple at %d which is not in any window", d.Timestamp)
+					} else {
+						_ = ctx.PutState(EmittedWindowsKey, o.emittedWindows)
+					}
+					// The late tuple may also belong to the next hopping windows
+					if o.window.Type == ast.HOPPING_WINDOW {
+						inputs = insertTuple(inputs, d)
+						o.spill.add(ctx, d, inputs)
+					}
+				} else {
+					inputs = append(inputs, d)
+					o.spill.add(ctx, d, inputs)
+				}
 				o.statManager.ProcessTimeEnd()
 				_ = ctx.PutState(WindowInputsKey, inputs)
 			default:
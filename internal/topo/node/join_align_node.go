@@ -139,8 +139,9 @@ func convertToTupleSlice(content []xsql.Row) []*xsql.Tuple {
 	return tuples
 }
 
-func (n *JoinAlignNode) alignBatch(_ api.StreamContext, input any) {
+func (n *JoinAlignNode) alignBatch(ctx api.StreamContext, input any) {
 	n.statManager.ProcessTimeStart()
+	span := startSpan(ctx, n.name, input)
 	var w *xsql.WindowTuples
 	switch t := input.(type) {
 	case *xsql.Tuple:
@@ -158,6 +159,8 @@ func (n *JoinAlignNode) alignBatch(_ api.StreamContext, input any) {
 			}
 		}
 	}
+	span.output(w)
+	span.end(nil)
 	n.Broadcast(w)
 	n.statManager.ProcessTimeEnd()
 	n.statManager.IncTotalRecordsOut()
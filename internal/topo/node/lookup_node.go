@@ -16,8 +16,10 @@ package node
 
 import (
 	"fmt"
+	"sync/atomic"
 
 	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/pkg/namespace"
 	"github.com/lf-edge/ekuiper/internal/topo/lookup"
 	"github.com/lf-edge/ekuiper/internal/topo/lookup/cache"
 	nodeConf "github.com/lf-edge/ekuiper/internal/topo/node/conf"
@@ -33,8 +35,16 @@ type LookupConf struct {
 	Cache           bool `json:"cache"`
 	CacheTTL        int  `json:"cacheTtl"`
 	CacheMissingKey bool `json:"cacheMissingKey"`
+	// CacheMaxEntries is the max number of cached keys. The least recently used keys are evicted if exceeded.
+	CacheMaxEntries int `json:"cacheMaxEntries"`
 }
 
+const (
+	LookupCacheHitsTotal   = "lookup_cache_hits_total"
+	LookupCacheMissesTotal = "lookup_cache_misses_total"
+	LookupCacheHitRatio    = "lookup_cache_hit_ratio"
+)
+
 // LookupNode will look up the data from the external source when receiving an event
 type LookupNode struct {
 	*defaultSinkNode
@@ -46,6 +56,9 @@ type LookupNode struct {
 	conf       *LookupConf
 	fields     []string
 	keys       []string
+	// the cache metrics of this rule, read by the rule status
+	cacheHits   int64
+	cacheMisses int64
 }
 
 func NewLookupNode(name string, fields []string, keys []string, joinType ast.JoinType, vals []ast.Expr, srcOptions *ast.Options, options *api.RuleOption) (*LookupNode, error) {
@@ -53,7 +66,10 @@ func NewLookupNode(name string, fields []string, keys []string, joinType ast.Joi
 	if t == "" {
 		return nil, fmt.Errorf("source type is not specified")
 	}
-	props := nodeConf.GetSourceConf(t, srcOptions)
+	props, err := nodeConf.GetSourceConf(t, srcOptions)
+	if err != nil {
+		return nil, err
+	}
 	lookupConf := &LookupConf{}
 	if lc, ok := props["lookup"].(map[string]interface{}); ok {
 		err := cast.MapToStruct(lc, lookupConf)
@@ -81,16 +97,21 @@ func (n *LookupNode) Exec(ctx api.StreamContext, errCh chan<- error) {
 	n.statManager = metric.NewStatManager(ctx, "op")
 	go func() {
 		err := infra.SafeRun(func() error {
-			ns, err := lookup.Attach(n.name)
+			// The lookup table instance is in the namespace of the rule
+			rns, _ := namespace.Split(ctx.GetRuleId())
+			table := namespace.Qualify(rns, n.name)
+			ns, err := lookup.Attach(table)
 			if err != nil {
 				return err
 			}
-			defer lookup.Detach(n.name)
+			defer lookup.Detach(table)
 			fv, _ := xsql.NewFunctionValuersForOp(ctx)
 			var c *cache.Cache
 			if n.conf.Cache {
-				c = cache.NewCache(n.conf.CacheTTL, n.conf.CacheMissingKey)
-				defer c.Close()
+				c, err = lookup.AttachCache(table, n.conf.CacheTTL, n.conf.CacheMissingKey, n.conf.CacheMaxEntries)
+				if err != nil {
+					return err
+				}
 			}
 			// Start the lookup source loop
 			for {
@@ -118,7 +139,10 @@ func (n *LookupNode) Exec(ctx api.StreamContext, errCh chan<- error) {
 						log.Debugf("Lookup Node receive tuple input %s", d)
 						n.statManager.ProcessTimeStart()
 						sets := &xsql.JoinTuples{Content: make([]*xsql.JoinTuple, 0)}
+						span := startSpan(ctx, n.name, d)
 						err := n.lookup(ctx, d, fv, ns, sets, c)
+						span.output(sets)
+						span.end(err)
 						if err != nil {
 							n.Broadcast(err)
 							n.statManager.IncTotalExceptions(err.Error())
@@ -133,6 +157,7 @@ func (n *LookupNode) Exec(ctx api.StreamContext, errCh chan<- error) {
 						log.Debugf("Lookup Node receive window input %s", d)
 						n.statManager.ProcessTimeStart()
 						sets := &xsql.JoinTuples{Content: make([]*xsql.JoinTuple, 0), WindowRange: item.(*xsql.WindowTuples).GetWindowRange()}
+						span := startSpan(ctx, n.name, d)
 						err := d.Range(func(i int, r xsql.ReadonlyRow) (bool, error) {
 							tr, ok := r.(xsql.Row)
 							if !ok {
@@ -144,6 +169,8 @@ func (n *LookupNode) Exec(ctx api.StreamContext, errCh chan<- error) {
 							}
 							return true, nil
 						})
+						span.output(sets)
+						span.end(err)
 						if err != nil {
 							n.Broadcast(err)
 							n.statManager.IncTotalExceptions(err.Error())
@@ -188,9 +215,13 @@ func (n *LookupNode) lookup(ctx api.StreamContext, d xsql.Row, fv *xsql.Function
 	)
 	if !hasNil { // if any of the value is nil, the lookup will always return empty result
 		if c != nil {
-			k := fmt.Sprintf("%v", cvs)
+			// The cache is shared by the rules which may look up different fields and keys
+			k := fmt.Sprintf("%v|%v|%v", n.fields, n.keys, cvs)
 			r, ok = c.Get(k)
-			if !ok {
+			if ok {
+				atomic.AddInt64(&n.cacheHits, 1)
+			} else {
+				atomic.AddInt64(&n.cacheMisses, 1)
 				r, e = ns.Lookup(ctx, n.fields, n.keys, cvs)
 				if e != nil {
 					return e
@@ -230,9 +261,24 @@ func (n *LookupNode) lookup(ctx api.StreamContext, d xsql.Row, fv *xsql.Function
 	}
 }
 
+// GetExtraMetrics returns the cache metrics if the cache is enabled
+func (n *LookupNode) GetExtraMetrics() ([]string, []any) {
+	if !n.conf.Cache {
+		return nil, nil
+	}
+	hits, misses := atomic.LoadInt64(&n.cacheHits), atomic.LoadInt64(&n.cacheMisses)
+	var ratio float64
+	if hits+misses > 0 {
+		ratio = float64(hits) / float64(hits+misses)
+	}
+	return []string{LookupCacheHitsTotal, LookupCacheMissesTotal, LookupCacheHitRatio}, []any{hits, misses, ratio}
+}
+
 func (n *LookupNode) merge(ctx api.StreamContext, d xsql.Row, r []map[string]interface{}) {
 	n.statManager.ProcessTimeStart()
 	sets := &xsql.JoinTuples{Content: make([]*xsql.JoinTuple, 0)}
+	span := startSpan(ctx, n.name, d)
+	defer span.end(nil)
 
 	if len(r) == 0 {
 		if n.joinType == ast.LEFT_JOIN {
@@ -256,6 +302,7 @@ func (n *LookupNode) merge(ctx api.StreamContext, d xsql.Row, r []map[string]int
 		sets.Content = append(sets.Content, merged)
 	}
 
+	span.output(sets)
 	n.Broadcast(sets)
 	n.statManager.ProcessTimeEnd()
 	n.statManager.IncTotalRecordsOut()
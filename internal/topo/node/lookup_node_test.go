@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
 
 	"github.com/lf-edge/ekuiper/internal/binder"
 	"github.com/lf-edge/ekuiper/internal/binder/io"
@@ -539,4 +540,7 @@ func TestCachedLookup(t *testing.T) {
 		t.Error("send message timeout")
 		return
 	}
+	names, values := l.GetExtraMetrics()
+	assert.Equal(t, []string{LookupCacheHitsTotal, LookupCacheMissesTotal, LookupCacheHitRatio}, names)
+	assert.Equal(t, []any{int64(1), int64(2), float64(1) / 3}, values)
 }
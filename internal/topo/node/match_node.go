@@ -0,0 +1,441 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"encoding/gob"
+	"fmt"
+	"time"
+
+	"github.com/lf-edge/ekuiper/internal/topo/node/metric"
+	"github.com/lf-edge/ekuiper/internal/topo/vclock"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	"github.com/lf-edge/ekuiper/pkg/infra"
+)
+
+const (
+	MatchStateKey = "$$matchPartitions"
+	// maxMatchRuns is the max partial matches kept for a partition. The oldest ones are dropped if exceeded.
+	maxMatchRuns = 10000
+)
+
+func init() {
+	gob.Register(map[string]*matchPartition{})
+}
+
+// matchRun is a partial match of the pattern
+type matchRun struct {
+	// Start is the sequence number of the first row in the partition
+	Start int64
+	// Term is the index of the pattern term which the last row is mapped to
+	Term int
+	// Count is the number of continuous rows mapped to the current term
+	Count  int
+	Rows   []*xsql.Tuple
+	Labels []int
+}
+
+type matchPartition struct {
+	Seq  int64
+	Runs []*matchRun
+}
+
+// matcher runs the pattern as a NFA for each partition. All possible partial matches are tracked
+// and the first completed one is emitted. The rows in a partition must match the pattern continuously.
+type matcher struct {
+	mr         *ast.MatchRecognize
+	vars       map[string]struct{}
+	partitions map[string]*matchPartition
+	fv         *xsql.FunctionValuer
+	afv        *xsql.AggregateFunctionValuer
+}
+
+func newMatcher(mr *ast.MatchRecognize, fv *xsql.FunctionValuer, afv *xsql.AggregateFunctionValuer) *matcher {
+	vars := make(map[string]struct{}, len(mr.Pattern))
+	for _, t := range mr.Pattern {
+		vars[t.Name] = struct{}{}
+	}
+	return &matcher{
+		mr:         mr,
+		vars:       vars,
+		partitions: make(map[string]*matchPartition),
+		fv:         fv,
+		afv:        afv,
+	}
+}
+
+func (m *matcher) keyOf(tuple *xsql.Tuple) (string, error) {
+	var key string
+	ve := &xsql.ValuerEval{Valuer: xsql.MultiValuer(tuple, m.fv)}
+	for _, d := range m.mr.PartitionBy {
+		r := ve.Eval(d.Expr)
+		if err, ok := r.(error); ok {
+			return "", fmt.Errorf("evaluate partition key error: %v", err)
+		}
+		key += fmt.Sprintf("%v,", r)
+	}
+	return key, nil
+}
+
+// process feeds the tuple to its partition and returns the completed matches
+func (m *matcher) process(tuple *xsql.Tuple) ([]*xsql.Tuple, error) {
+	key, err := m.keyOf(tuple)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := m.partitions[key]
+	if !ok {
+		p = &matchPartition{}
+		m.partitions[key] = p
+	}
+	p.Seq++
+	candidates := append(p.Runs, &matchRun{Start: p.Seq, Term: -1})
+	var runs []*matchRun
+	for _, run := range candidates {
+		if m.mr.Within > 0 && len(run.Rows) > 0 && tuple.Timestamp-run.Rows[0].Timestamp > m.mr.Within {
+			continue
+		}
+		next, err := m.advance(run, tuple)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, next...)
+	}
+	if len(runs) > maxMatchRuns {
+		runs = runs[len(runs)-maxMatchRuns:]
+	}
+	var results []*xsql.Tuple
+	for {
+		var matched *matchRun
+		for _, run := range runs {
+			if m.isComplete(run) {
+				matched = run
+				break
+			}
+		}
+		if matched == nil {
+			break
+		}
+		r, err := m.measure(key, matched)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+		if !m.mr.SkipToNextRow {
+			runs = nil
+			break
+		}
+		remain := runs[:0]
+		for _, run := range runs {
+			if run.Start > matched.Start {
+				remain = append(remain, run)
+			}
+		}
+		runs = remain
+	}
+	if len(runs) == 0 {
+		delete(m.partitions, key)
+	} else {
+		p.Runs = runs
+	}
+	return results, nil
+}
+
+// expire discards the partial matches which exceed the WITHIN span at the time, so that the partitions which receive
+// no more rows do not keep them. Return true if any is discarded.
+func (m *matcher) expire(now int64) bool {
+	if m.mr.Within <= 0 {
+		return false
+	}
+	changed := false
+	for key, p := range m.partitions {
+		runs := p.Runs[:0]
+		for _, run := range p.Runs {
+			if len(run.Rows) > 0 && now-run.Rows[0].Timestamp > m.mr.Within {
+				changed = true
+				continue
+			}
+			runs = append(runs, run)
+		}
+		if len(runs) == 0 {
+			delete(m.partitions, key)
+		} else {
+			p.Runs = runs
+		}
+	}
+	return changed
+}
+
+// advance returns all the runs derived from the run by mapping the tuple to the possible terms.
+// The runs are in the preference order that the current term is continued before moving to the next term.
+func (m *matcher) advance(run *matchRun, tuple *xsql.Tuple) ([]*matchRun, error) {
+	var (
+		result  []*matchRun
+		targets []int
+	)
+	pattern := m.mr.Pattern
+	if run.Term >= 0 && (pattern[run.Term].Max < 0 || run.Count < pattern[run.Term].Max) {
+		targets = append(targets, run.Term)
+	}
+	if run.Term < 0 || run.Count >= pattern[run.Term].Min {
+		for i := run.Term + 1; i < len(pattern); i++ {
+			targets = append(targets, i)
+			if pattern[i].Min > 0 {
+				break
+			}
+		}
+	}
+	for _, t := range targets {
+		next := &matchRun{
+			Start:  run.Start,
+			Term:   t,
+			Count:  1,
+			Rows:   append(run.Rows[:len(run.Rows):len(run.Rows)], tuple),
+			Labels: append(run.Labels[:len(run.Labels):len(run.Labels)], t),
+		}
+		if t == run.Term {
+			next.Count = run.Count + 1
+		}
+		ok, err := m.satisfy(next)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result = append(result, next)
+		}
+	}
+	return result, nil
+}
+
+// satisfy checks if the last row of the run satisfies the definition of the term it is mapped to
+func (m *matcher) satisfy(run *matchRun) (bool, error) {
+	cond := m.mr.Define(m.mr.Pattern[run.Term].Name)
+	if cond == nil {
+		return true, nil
+	}
+	data := &matchRows{m: m, run: run}
+	m.afv.SetData(data)
+	ve := &xsql.ValuerEval{Valuer: xsql.MultiAggregateValuer(data, m.fv, data, m.fv, m.afv)}
+	switch r := ve.Eval(cond).(type) {
+	case error:
+		return false, fmt.Errorf("evaluate the definition of %s error: %v", m.mr.Pattern[run.Term].Name, r)
+	case bool:
+		return r, nil
+	case nil:
+		return false, nil
+	default:
+		return false, fmt.Errorf("the definition of %s must be a bool expression but got %v", m.mr.Pattern[run.Term].Name, r)
+	}
+}
+
+// isComplete checks if all the remaining terms are optional
+func (m *matcher) isComplete(run *matchRun) bool {
+	if run.Count < m.mr.Pattern[run.Term].Min {
+		return false
+	}
+	for i := run.Term + 1; i < len(m.mr.Pattern); i++ {
+		if m.mr.Pattern[i].Min > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// measure creates the output row of a match with the partition fields and the measures
+func (m *matcher) measure(key string, run *matchRun) (*xsql.Tuple, error) {
+	last := run.Rows[len(run.Rows)-1]
+	msg := make(xsql.Message, len(m.mr.PartitionBy)+len(m.mr.Measures))
+	ve := &xsql.ValuerEval{Valuer: xsql.MultiValuer(last, m.fv)}
+	for _, d := range m.mr.PartitionBy {
+		if fr, ok := d.Expr.(*ast.FieldRef); ok {
+			msg[fr.Name] = ve.Eval(fr)
+		}
+	}
+	data := &matchRows{m: m, run: run}
+	m.afv.SetData(data)
+	ve = &xsql.ValuerEval{Valuer: xsql.MultiAggregateValuer(data, m.fv, data, m.fv, m.afv)}
+	for _, f := range m.mr.Measures {
+		r := ve.Eval(f.Expr)
+		if err, ok := r.(error); ok {
+			return nil, fmt.Errorf("evaluate measure %s of partition %s error: %v", f.AName, key, err)
+		}
+		msg[f.AName] = r
+	}
+	return &xsql.Tuple{
+		Emitter:   last.Emitter,
+		Message:   msg,
+		Timestamp: last.Timestamp,
+		Metadata:  last.Metadata,
+	}, nil
+}
+
+// matchRows is the valuer of a (partial) match. The field of a pattern variable like A.temperature is
+// evaluated with the last row mapped to A. Other fields are evaluated with the last row of the match.
+// The aggregate functions are evaluated with all rows mapped to the referred pattern variable.
+type matchRows struct {
+	m   *matcher
+	run *matchRun
+}
+
+func (r *matchRows) last() *xsql.Tuple {
+	return r.run.Rows[len(r.run.Rows)-1]
+}
+
+func (r *matchRows) Value(key, table string) (interface{}, bool) {
+	if _, ok := r.m.vars[table]; ok {
+		for i := len(r.run.Rows) - 1; i >= 0; i-- {
+			if r.m.mr.Pattern[r.run.Labels[i]].Name == table {
+				return r.run.Rows[i].Value(key, "")
+			}
+		}
+		return nil, false
+	}
+	return r.last().Value(key, "")
+}
+
+func (r *matchRows) Meta(key, table string) (interface{}, bool) {
+	return r.last().Meta(key, "")
+}
+
+func (r *matchRows) FuncValue(key string) (interface{}, bool) {
+	return r.last().FuncValue(key)
+}
+
+func (r *matchRows) AggregateEval(expr ast.Expr, v xsql.CallValuer) []interface{} {
+	variable := ""
+	ast.WalkFunc(expr, func(n ast.Node) bool {
+		if fr, ok := n.(*ast.FieldRef); ok {
+			if _, ok := r.m.vars[string(fr.StreamName)]; ok {
+				variable = string(fr.StreamName)
+				return false
+			}
+		}
+		return true
+	})
+	var result []interface{}
+	for i := range r.run.Rows {
+		if variable != "" && r.m.mr.Pattern[r.run.Labels[i]].Name != variable {
+			continue
+		}
+		single := &matchRows{m: r.m, run: &matchRun{Rows: r.run.Rows[i : i+1], Labels: r.run.Labels[i : i+1]}}
+		result = append(result, xsql.Eval(expr, xsql.MultiValuer(single, v)))
+	}
+	return result
+}
+
+// MatchNode detects the row patterns defined by MATCH_RECOGNIZE and outputs a row for each match
+type MatchNode struct {
+	*defaultSinkNode
+	mr          *ast.MatchRecognize
+	isEventTime bool
+}
+
+func NewMatchNode(name string, mr *ast.MatchRecognize, options *api.RuleOption) *MatchNode {
+	return &MatchNode{
+		defaultSinkNode: newDefaultSinkNode(name, options),
+		mr:              mr,
+		isEventTime:     options.IsEventTime,
+	}
+}
+
+func (n *MatchNode) Exec(ctx api.StreamContext, errCh chan<- error) {
+	n.ctx = ctx
+	log := ctx.GetLogger()
+	log.Debugf("MatchNode %s is started", n.name)
+	n.statManager = metric.NewStatManager(ctx, "op")
+	go func() {
+		err := infra.SafeRun(func() error {
+			fv, afv := xsql.NewFunctionValuersForOp(ctx)
+			m := newMatcher(n.mr, fv, afv)
+			if s, err := ctx.GetState(MatchStateKey); err == nil {
+				switch st := s.(type) {
+				case map[string]*matchPartition:
+					m.partitions = st
+					log.Infof("Restore match state of %d partitions", len(st))
+				case nil:
+					log.Debugf("Restore match state, nothing")
+				default:
+					return fmt.Errorf("restore match state %v error, invalid type", st)
+				}
+			} else {
+				log.Warnf("Restore match state fails: %s", err)
+			}
+			// The expired partial matches are discarded by the watermark in event time, or periodically in
+			// processing time
+			var tickerC <-chan time.Time
+			if n.mr.Within > 0 && !n.isEventTime {
+				ticker := vclock.Get(ctx).Ticker(time.Duration(n.mr.Within) * time.Millisecond)
+				defer ticker.Stop()
+				tickerC = ticker.C
+			}
+			for {
+				select {
+				case t := <-tickerC:
+					if m.expire(t.UnixMilli()) {
+						_ = ctx.PutState(MatchStateKey, m.partitions)
+					}
+				case item, opened := <-n.input:
+					processed := false
+					if item, processed = n.preprocess(item); processed {
+						break
+					}
+					n.statManager.IncTotalRecordsIn()
+					n.statManager.ProcessTimeStart()
+					if !opened {
+						n.statManager.IncTotalExceptions("input channel closed")
+						break
+					}
+					switch d := item.(type) {
+					case error:
+						n.Broadcast(d)
+						n.statManager.IncTotalExceptions(d.Error())
+					case *xsql.WatermarkTuple:
+						if m.expire(d.GetTimestamp()) {
+							_ = ctx.PutState(MatchStateKey, m.partitions)
+						}
+						n.Broadcast(d)
+					case *xsql.Tuple:
+						results, err := m.process(d)
+						if err != nil {
+							n.Broadcast(err)
+							n.statManager.IncTotalExceptions(err.Error())
+							break
+						}
+						for _, r := range results {
+							n.Broadcast(r)
+							n.statManager.IncTotalRecordsOut()
+						}
+						n.statManager.IncTotalMessagesProcessed(1)
+						_ = ctx.PutState(MatchStateKey, m.partitions)
+					default:
+						e := fmt.Errorf("run match node error: invalid input type but got %[1]T(%[1]v)", d)
+						n.Broadcast(e)
+						n.statManager.IncTotalExceptions(e.Error())
+					}
+					n.statManager.ProcessTimeEnd()
+					n.statManager.SetBufferLength(int64(len(n.input)))
+				case <-ctx.Done():
+					log.Infoln("Cancelling match node....")
+					return nil
+				}
+			}
+		})
+		if err != nil {
+			infra.DrainError(ctx, err, errCh)
+		}
+	}()
+}
@@ -0,0 +1,162 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"bytes"
+	"encoding/gob"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+)
+
+func TestMatcher(t *testing.T) {
+	tests := []struct {
+		name   string
+		sql    string
+		inputs []map[string]any
+		// the timestamp of each input is its index in seconds
+		results []map[string]any
+	}{
+		{
+			name: "followed by within without",
+			sql: `SELECT * FROM demo MATCH_RECOGNIZE (PARTITION BY id MEASURES A.ts AS startTs, B.ts AS endTs, count(X.ts) AS gap
+				PATTERN (A X* B) WITHIN 5 SS DEFINE A AS type = 'A', B AS type = 'B', X AS type != 'C')`,
+			inputs: []map[string]any{
+				{"id": 1, "type": "A", "ts": 0},
+				{"id": 2, "type": "A", "ts": 1},
+				{"id": 1, "type": "D", "ts": 2},
+				{"id": 2, "type": "C", "ts": 3},
+				{"id": 1, "type": "B", "ts": 4},
+				{"id": 2, "type": "B", "ts": 5},
+				{"id": 1, "type": "A", "ts": 6},
+				{"id": 1, "type": "D", "ts": 7},
+				{"id": 1, "type": "D", "ts": 8},
+				{"id": 1, "type": "D", "ts": 9},
+				{"id": 1, "type": "D", "ts": 10},
+				{"id": 1, "type": "D", "ts": 11},
+				{"id": 1, "type": "B", "ts": 12},
+			},
+			results: []map[string]any{
+				{"id": 1, "startTs": 0, "endTs": 4, "gap": 1},
+			},
+		},
+		{
+			name: "quantifier and aggregate",
+			sql: `SELECT * FROM demo MATCH_RECOGNIZE (MEASURES A.v AS av, avg(B.v) AS bavg, C.v AS cv
+				PATTERN (A B{2,} C) DEFINE B AS B.v > A.v, C AS C.v < A.v)`,
+			inputs: []map[string]any{
+				{"v": 1}, {"v": 3}, {"v": 4}, {"v": 5}, {"v": 0}, {"v": 2}, {"v": 3},
+			},
+			results: []map[string]any{
+				{"av": 1, "bavg": int64(4), "cv": 0},
+			},
+		},
+		{
+			name: "skip past last row",
+			sql:  `SELECT * FROM demo MATCH_RECOGNIZE (MEASURES A.v AS a, B.v AS b PATTERN (A B?) DEFINE A AS v > 0, B AS v > 10)`,
+			inputs: []map[string]any{
+				{"v": 1}, {"v": 11}, {"v": 12},
+			},
+			// The optional B at the end is not waited
+			results: []map[string]any{
+				{"a": 1, "b": nil}, {"a": 11, "b": nil}, {"a": 12, "b": nil},
+			},
+		},
+		{
+			name: "skip to next row",
+			sql:  `SELECT * FROM demo MATCH_RECOGNIZE (MEASURES A.v AS a, B.v AS b AFTER MATCH SKIP TO NEXT ROW PATTERN (A B) DEFINE A AS v > 0, B AS v > 0)`,
+			inputs: []map[string]any{
+				{"v": 1}, {"v": 2}, {"v": 3}, {"v": 0}, {"v": 4},
+			},
+			results: []map[string]any{
+				{"a": 1, "b": 2}, {"a": 2, "b": 3},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := xsql.NewParser(strings.NewReader(tt.sql)).Parse()
+			require.NoError(t, err)
+			require.NotNil(t, stmt.MatchRecognize)
+			fv, afv := xsql.NewFunctionValuersForOp(context.Background())
+			m := newMatcher(stmt.MatchRecognize, fv, afv)
+			var results []map[string]any
+			for i, input := range tt.inputs {
+				r, err := m.process(&xsql.Tuple{Emitter: "demo", Message: input, Timestamp: int64(i * 1000)})
+				require.NoError(t, err)
+				for _, tuple := range r {
+					results = append(results, tuple.Message)
+				}
+			}
+			assert.Equal(t, tt.results, results)
+		})
+	}
+}
+
+func TestMatcherState(t *testing.T) {
+	stmt, err := xsql.NewParser(strings.NewReader(`SELECT * FROM demo MATCH_RECOGNIZE (PARTITION BY id MEASURES count(B.v) AS c PATTERN (A B+ C) DEFINE A AS v = 0, C AS v = 0)`)).Parse()
+	require.NoError(t, err)
+	fv, afv := xsql.NewFunctionValuersForOp(context.Background())
+	m := newMatcher(stmt.MatchRecognize, fv, afv)
+	for i, v := range []int{0, 1, 2} {
+		r, err := m.process(&xsql.Tuple{Message: map[string]any{"id": "a", "v": v}, Timestamp: int64(i)})
+		require.NoError(t, err)
+		require.Empty(t, r)
+	}
+	var buf bytes.Buffer
+	var state any = m.partitions
+	require.NoError(t, gob.NewEncoder(&buf).Encode(&state))
+	var restored any
+	require.NoError(t, gob.NewDecoder(&buf).Decode(&restored))
+
+	m = newMatcher(stmt.MatchRecognize, fv, afv)
+	m.partitions = restored.(map[string]*matchPartition)
+	r, err := m.process(&xsql.Tuple{Message: map[string]any{"id": "a", "v": 0}, Timestamp: 3})
+	require.NoError(t, err)
+	require.Len(t, r, 1)
+	assert.Equal(t, map[string]any{"id": "a", "c": 2}, map[string]any(r[0].Message))
+}
+
+func TestMatcherExpire(t *testing.T) {
+	stmt, err := xsql.NewParser(strings.NewReader(`SELECT * FROM demo MATCH_RECOGNIZE (PARTITION BY id MEASURES count(B.v) AS c PATTERN (A B+ C) WITHIN 5 ss DEFINE A AS v = 0, C AS v = 0)`)).Parse()
+	require.NoError(t, err)
+	fv, afv := xsql.NewFunctionValuersForOp(context.Background())
+	m := newMatcher(stmt.MatchRecognize, fv, afv)
+	for i, tuple := range []*xsql.Tuple{
+		{Message: map[string]any{"id": "a", "v": 0}, Timestamp: 1000},
+		{Message: map[string]any{"id": "a", "v": 1}, Timestamp: 2000},
+		{Message: map[string]any{"id": "b", "v": 0}, Timestamp: 4000},
+	} {
+		r, err := m.process(tuple)
+		require.NoError(t, err, i)
+		require.Empty(t, r)
+	}
+	require.Len(t, m.partitions, 2)
+	// Partition a receives no more rows, but its partial match is discarded after the span
+	assert.False(t, m.expire(6000))
+	assert.True(t, m.expire(6001))
+	require.Len(t, m.partitions, 1)
+	for _, p := range m.partitions {
+		assert.Equal(t, int64(4000), p.Runs[0].Rows[0].Timestamp)
+	}
+	assert.True(t, m.expire(9001))
+	assert.Empty(t, m.partitions)
+}
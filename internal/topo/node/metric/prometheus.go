@@ -42,14 +42,37 @@ type MetricGroup struct {
 	TotalMessagesProcessed *prometheus.CounterVec
 	TotalExceptions        *prometheus.CounterVec
 	ProcessLatencyHist     *prometheus.HistogramVec
+	ProcessLatencySum      *prometheus.SummaryVec
 	ProcessLatency         *prometheus.GaugeVec
 	BufferLength           *prometheus.GaugeVec
 }
 
+// RuleMetricGroup is the metrics aggregated by rule
+type RuleMetricGroup struct {
+	ProcessLatencyHist *prometheus.HistogramVec
+	ProcessLatencySum  *prometheus.SummaryVec
+	ThrottleLevel      *prometheus.GaugeVec
+	ThrottledSeconds   *prometheus.CounterVec
+}
+
+// QueueMetricGroup is the metrics of the queues between the nodes of the rules
+type QueueMetricGroup struct {
+	Length   *prometheus.GaugeVec
+	Capacity *prometheus.GaugeVec
+}
+
 type PrometheusMetrics struct {
-	vecs []*MetricGroup
+	vecs  []*MetricGroup
+	rule  *RuleMetricGroup
+	queue *QueueMetricGroup
 }
 
+// latencyBuckets are the buckets of the process latency histograms: 10us ~ 5s
+var latencyBuckets = prometheus.ExponentialBuckets(10, 2, 20)
+
+// latencyObjectives are the quantiles of the process latency summaries: p50, p90 and p99
+var latencyObjectives = map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001}
+
 func newPrometheusMetrics() *PrometheusMetrics {
 	var (
 		labelNames = []string{"rule", "type", "op", "instance"}
@@ -76,18 +99,23 @@ func newPrometheusMetrics() *PrometheusMetrics {
 		}, labelNames)
 		processLatency := prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Name: prefix + "_" + ProcessLatencyUs,
-			Help: "Process latency in millisecond of " + prefix,
+			Help: "Process latency in microsecond of " + prefix,
 		}, labelNames)
 		processLatencyHist := prometheus.NewHistogramVec(prometheus.HistogramOpts{
 			Name:    prefix + "_" + ProcessLatencyUsHist,
-			Help:    "Histograms of process latency in millisecond of " + prefix,
-			Buckets: prometheus.ExponentialBuckets(10, 2, 20), // 10us ~ 5s
+			Help:    "Histograms of process latency in microsecond of " + prefix,
+			Buckets: latencyBuckets,
+		}, labelNames)
+		processLatencySum := prometheus.NewSummaryVec(prometheus.SummaryOpts{
+			Name:       prefix + "_" + ProcessLatencyUsSum,
+			Help:       "Quantiles of process latency in microsecond of " + prefix,
+			Objectives: latencyObjectives,
 		}, labelNames)
 		bufferLength := prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Name: prefix + "_" + BufferLength,
 			Help: "The length of the plan buffer which is shared by all instances of " + prefix,
 		}, labelNames)
-		prometheus.MustRegister(totalRecordsIn, totalRecordsOut, totalMessagesProcessed, totalExceptions, processLatency, processLatencyHist, bufferLength)
+		prometheus.MustRegister(totalRecordsIn, totalRecordsOut, totalMessagesProcessed, totalExceptions, processLatency, processLatencyHist, processLatencySum, bufferLength)
 		vecs = append(vecs, &MetricGroup{
 			TotalRecordsIn:         totalRecordsIn,
 			TotalRecordsOut:        totalRecordsOut,
@@ -95,10 +123,48 @@ func newPrometheusMetrics() *PrometheusMetrics {
 			TotalExceptions:        totalExceptions,
 			ProcessLatency:         processLatency,
 			ProcessLatencyHist:     processLatencyHist,
+			ProcessLatencySum:      processLatencySum,
 			BufferLength:           bufferLength,
 		})
 	}
-	return &PrometheusMetrics{vecs: vecs}
+	ruleLatencyHist := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kuiper_rule_" + ProcessLatencyUsHist,
+		Help:    "Histograms of process latency in microsecond of all operations of the rule",
+		Buckets: latencyBuckets,
+	}, []string{"rule"})
+	ruleLatencySum := prometheus.NewSummaryVec(prometheus.SummaryOpts{
+		Name:       "kuiper_rule_" + ProcessLatencyUsSum,
+		Help:       "Quantiles of process latency in microsecond of all operations of the rule",
+		Objectives: latencyObjectives,
+	}, []string{"rule"})
+	throttleLevel := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kuiper_rule_" + ThrottleLevel,
+		Help: "The throttle level of the rule when the host is cpu saturated. Each level halves the time to read",
+	}, []string{"rule"})
+	throttledSeconds := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kuiper_rule_throttled_seconds_total",
+		Help: "Total seconds the sources of the rule paused for the throttling",
+	}, []string{"rule"})
+	prometheus.MustRegister(ruleLatencyHist, ruleLatencySum, throttleLevel, throttledSeconds)
+	queueLabelNames := []string{"rule", "from", "to"}
+	queueLength := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kuiper_" + QueueLength,
+		Help: "The length of the queue from an operation to its downstream operation",
+	}, queueLabelNames)
+	queueCapacity := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kuiper_" + QueueCapacity,
+		Help: "The capacity of the queue from an operation to its downstream operation",
+	}, queueLabelNames)
+	prometheus.MustRegister(queueLength, queueCapacity)
+	return &PrometheusMetrics{vecs: vecs, rule: &RuleMetricGroup{
+		ProcessLatencyHist: ruleLatencyHist,
+		ProcessLatencySum:  ruleLatencySum,
+		ThrottleLevel:      throttleLevel,
+		ThrottledSeconds:   throttledSeconds,
+	}, queue: &QueueMetricGroup{
+		Length:   queueLength,
+		Capacity: queueCapacity,
+	}}
 }
 
 func (m *PrometheusMetrics) GetMetricsGroup(opType string) *MetricGroup {
@@ -112,3 +178,11 @@ func (m *PrometheusMetrics) GetMetricsGroup(opType string) *MetricGroup {
 	}
 	return nil
 }
+
+func (m *PrometheusMetrics) GetRuleMetricsGroup() *RuleMetricGroup {
+	return m.rule
+}
+
+func (m *PrometheusMetrics) GetQueueMetricsGroup() *QueueMetricGroup {
+	return m.queue
+}
@@ -14,8 +14,52 @@
 
 package metric
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	mockContext "github.com/lf-edge/ekuiper/pkg/mock/context"
+)
 
 func TestNewPrometheus(t *testing.T) {
 	newPrometheusMetrics()
 }
+
+func TestLatencyMetrics(t *testing.T) {
+	conf.InitConf()
+	conf.Config.Basic.Prometheus = true
+	// Use a new registry to avoid duplicated registration with other tests
+	r := prometheus.DefaultRegisterer
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+	defer func() {
+		conf.Config.Basic.Prometheus = false
+		prometheus.DefaultRegisterer = r
+	}()
+	var sms []StatManager
+	for _, op := range []string{"op1", "op2"} {
+		sm, err := getStatManager(mockContext.NewMockContext("latencyRule", op), DefaultStatManager{opType: "op", opId: op})
+		require.NoError(t, err)
+		sm.ProcessTimeStart()
+		sm.ProcessTimeEnd()
+		sms = append(sms, sm)
+	}
+	mg := GetPrometheusMetrics().GetMetricsGroup("op")
+	rmg := GetPrometheusMetrics().GetRuleMetricsGroup()
+	// one series per operator and one series for the rule
+	assert.Equal(t, 2, testutil.CollectAndCount(mg.ProcessLatencyHist))
+	assert.Equal(t, 2, testutil.CollectAndCount(mg.ProcessLatencySum))
+	assert.Equal(t, 1, testutil.CollectAndCount(rmg.ProcessLatencyHist))
+	assert.Equal(t, 1, testutil.CollectAndCount(rmg.ProcessLatencySum))
+	for _, sm := range sms {
+		sm.Clean("latencyRule")
+	}
+	assert.Equal(t, 0, testutil.CollectAndCount(mg.ProcessLatencyHist))
+	assert.Equal(t, 0, testutil.CollectAndCount(mg.ProcessLatencySum))
+	assert.Equal(t, 0, testutil.CollectAndCount(rmg.ProcessLatencyHist))
+	assert.Equal(t, 0, testutil.CollectAndCount(rmg.ProcessLatencySum))
+}
@@ -36,3 +36,33 @@ func TestGetMetrics(t *testing.T) {
 	assert.NotEqual(t, "", a[5])
 	assert.Equal(t, e[6:], a[6:])
 }
+
+func TestGetErrorStats(t *testing.T) {
+	ctx := mockContext.NewMockContext("rule1", "op1")
+	sm := NewStatManager(ctx, "sink")
+	stats, failing := sm.GetErrorStats()
+	assert.Nil(t, stats)
+	assert.Equal(t, "", failing)
+
+	sm.IncTotalExceptions("send fail")
+	sm.IncTotalExceptionsOf(ErrorResource, "buffer full")
+	sm.IncTotalExceptions("send fail again")
+	stats, failing = sm.GetErrorStats()
+	assert.Equal(t, ErrorSink, failing)
+	assert.Len(t, stats, 2)
+	assert.Equal(t, int64(2), stats[ErrorSink].Count)
+	assert.Equal(t, "send fail again", stats[ErrorSink].LastError)
+	assert.NotEqual(t, int64(0), stats[ErrorSink].LastTime)
+	assert.Equal(t, int64(1), stats[ErrorResource].Count)
+	// Recovered after sending successfully
+	sm.IncTotalRecordsOut()
+	stats, failing = sm.GetErrorStats()
+	assert.Equal(t, "", failing)
+	assert.Equal(t, int64(2), stats[ErrorSink].Count)
+
+	sm = NewStatManagerWithCategory(ctx, "op", ErrorDecode)
+	sm.IncTotalExceptions("invalid json")
+	stats, _ = sm.GetErrorStats()
+	assert.Equal(t, map[string]ErrorStats{ErrorDecode: stats[ErrorDecode]}, stats)
+	assert.Equal(t, int64(1), stats[ErrorDecode].Count)
+}
@@ -26,13 +26,42 @@ const (
 	MessagesProcessedTotal = "messages_processed_total"
 	ProcessLatencyUs       = "process_latency_us"
 	ProcessLatencyUsHist   = "process_latency_us_hist"
+	ProcessLatencyUsSum    = "process_latency_us_summary"
 	LastInvocation         = "last_invocation"
 	BufferLength           = "buffer_length"
 	ExceptionsTotal        = "exceptions_total"
 	LastException          = "last_exception"
 	LastExceptionTime      = "last_exception_time"
+	QueueLength            = "queue_length"
+	QueueCapacity          = "queue_capacity"
+	ThrottleLevel          = "throttle_level"
+	ThrottledMsTotal       = "throttled_ms_total"
 )
 
+// The categories of the exceptions
+const (
+	// ErrorConnection is the error to connect or read from the external system by the sources
+	ErrorConnection = "connection"
+	// ErrorDecode is the error to decode or validate the data
+	ErrorDecode = "decode"
+	// ErrorEval is the error to evaluate the data by the operators
+	ErrorEval = "eval"
+	// ErrorSink is the error to send the data to the external system by the sinks
+	ErrorSink = "sink"
+	// ErrorResource is the error for the limited resources such as the full buffer and the exceeded quota
+	ErrorResource = "resource"
+)
+
+var errorCategories = []string{ErrorConnection, ErrorDecode, ErrorEval, ErrorSink, ErrorResource}
+
+// ErrorStats is the exceptions of a category
+type ErrorStats struct {
+	Count     int64  `json:"count"`
+	LastError string `json:"lastError"`
+	// LastTime is the time in milliseconds of the last error
+	LastTime int64 `json:"lastTime"`
+}
+
 var MetricNames = []string{RecordsInTotal, RecordsOutTotal, MessagesProcessedTotal, ProcessLatencyUs, BufferLength, LastInvocation, ExceptionsTotal, LastException, LastExceptionTime}
 
 type StatManager interface {
@@ -40,6 +69,11 @@ type StatManager interface {
 	IncTotalRecordsOut()
 	IncTotalMessagesProcessed(n int64)
 	IncTotalExceptions(err string)
+	// IncTotalExceptionsOf counts the exception of the category instead of the default category of the node
+	IncTotalExceptionsOf(category string, err string)
+	// GetErrorStats returns the exceptions by category, and the category of the last exception if the node keeps
+	// failing which means there is no progress since the last exception
+	GetErrorStats() (map[string]ErrorStats, string)
 	ProcessTimeStart()
 	ProcessTimeEnd()
 	SetBufferLength(l int64)
@@ -62,8 +96,14 @@ type DefaultStatManager struct {
 	totalExceptions   int64
 	lastException     string
 	lastExceptionTime time.Time
+	// the exceptions by the index of the category
+	errorStats [5]ErrorStats
+	// the category and the progress of the last exception to check if the node keeps failing
+	lastCategory        string
+	progressAtException int64
 	// configs
 	opType           string //"source", "op", "sink"
+	category         string
 	prefix           string
 	processTimeStart time.Time
 	opId             string
@@ -71,6 +111,11 @@ type DefaultStatManager struct {
 }
 
 func NewStatManager(ctx api.StreamContext, opType string) StatManager {
+	return NewStatManagerWithCategory(ctx, opType, defaultCategory(opType))
+}
+
+// NewStatManagerWithCategory creates the stat manager whose exceptions are of the category by default
+func NewStatManagerWithCategory(ctx api.StreamContext, opType string, category string) StatManager {
 	var prefix string
 	switch opType {
 	case "source":
@@ -83,6 +128,7 @@ func NewStatManager(ctx api.StreamContext, opType string) StatManager {
 	ds := DefaultStatManager{
 		opType:     opType,
 		prefix:     prefix,
+		category:   category,
 		opId:       ctx.GetOpId(),
 		instanceId: ctx.GetInstanceId(),
 	}
@@ -93,6 +139,17 @@ func NewStatManager(ctx api.StreamContext, opType string) StatManager {
 	return sm
 }
 
+func defaultCategory(opType string) string {
+	switch opType {
+	case "source":
+		return ErrorConnection
+	case "sink":
+		return ErrorSink
+	default:
+		return ErrorEval
+	}
+}
+
 func (sm *DefaultStatManager) IncTotalRecordsIn() {
 	sm.totalRecordsIn++
 }
@@ -106,11 +163,50 @@ func (sm *DefaultStatManager) IncTotalRecordsOut() {
 }
 
 func (sm *DefaultStatManager) IncTotalExceptions(err string) {
+	sm.IncTotalExceptionsOf(sm.category, err)
+}
+
+func (sm *DefaultStatManager) IncTotalExceptionsOf(category string, err string) {
 	sm.totalExceptions++
 	var t time.Time
 	sm.processTimeStart = t
 	sm.lastException = err
 	sm.lastExceptionTime = time.Now()
+	for i, c := range errorCategories {
+		if c == category {
+			sm.errorStats[i].Count++
+			sm.errorStats[i].LastError = err
+			sm.errorStats[i].LastTime = sm.lastExceptionTime.UnixMilli()
+			break
+		}
+	}
+	sm.lastCategory = category
+	sm.progressAtException = sm.progress()
+}
+
+// progress is the count of the successfully handled records. The sources make progress by receiving the data and
+// the others by sending out the results.
+func (sm *DefaultStatManager) progress() int64 {
+	if sm.opType == "source" {
+		return sm.totalRecordsIn
+	}
+	return sm.totalRecordsOut
+}
+
+func (sm *DefaultStatManager) GetErrorStats() (map[string]ErrorStats, string) {
+	if sm.totalExceptions == 0 {
+		return nil, ""
+	}
+	result := make(map[string]ErrorStats)
+	for i, c := range errorCategories {
+		if sm.errorStats[i].Count > 0 {
+			result[c] = sm.errorStats[i]
+		}
+	}
+	if sm.progress() == sm.progressAtException {
+		return result, sm.lastCategory
+	}
+	return result, ""
 }
 
 func (sm *DefaultStatManager) ProcessTimeStart() {
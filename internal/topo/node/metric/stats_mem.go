@@ -19,6 +19,7 @@ package metric
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/pkg/api"
@@ -31,3 +32,23 @@ func getStatManager(_ api.StreamContext, sm DefaultStatManager) (StatManager, er
 		return &sm, nil
 	}
 }
+
+func SetQueueLength(_ string, _ string, _ string, _ int, _ int) {
+	// do nothing
+}
+
+func CleanQueueLength(_ string) {
+	// do nothing
+}
+
+func SetThrottleLevel(_ string, _ int) {
+	// do nothing
+}
+
+func AddThrottledTime(_ string, _ time.Duration) {
+	// do nothing
+}
+
+func CleanThrottle(_ string) {
+	// do nothing
+}
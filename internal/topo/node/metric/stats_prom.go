@@ -42,6 +42,7 @@ func getStatManager(ctx api.StreamContext, dsm DefaultStatManager) (StatManager,
 		mg.TotalExceptions.DeleteLabelValues(ctx.GetRuleId(), dsm.opType, dsm.opId, strInId)
 		mg.ProcessLatency.DeleteLabelValues(ctx.GetRuleId(), dsm.opType, dsm.opId, strInId)
 		mg.ProcessLatencyHist.DeleteLabelValues(ctx.GetRuleId(), dsm.opType, dsm.opId, strInId)
+		mg.ProcessLatencySum.DeleteLabelValues(ctx.GetRuleId(), dsm.opType, dsm.opId, strInId)
 		mg.BufferLength.DeleteLabelValues(ctx.GetRuleId(), dsm.opType, dsm.opId, strInId)
 
 		psm.pTotalRecordsIn = mg.TotalRecordsIn.WithLabelValues(ctx.GetRuleId(), dsm.opType, dsm.opId, strInId)
@@ -50,6 +51,11 @@ func getStatManager(ctx api.StreamContext, dsm DefaultStatManager) (StatManager,
 		psm.pTotalExceptions = mg.TotalExceptions.WithLabelValues(ctx.GetRuleId(), dsm.opType, dsm.opId, strInId)
 		psm.pProcessLatency = mg.ProcessLatency.WithLabelValues(ctx.GetRuleId(), dsm.opType, dsm.opId, strInId)
 		psm.pProcessLatencyHist = mg.ProcessLatencyHist.WithLabelValues(ctx.GetRuleId(), dsm.opType, dsm.opId, strInId)
+		psm.pProcessLatencySum = mg.ProcessLatencySum.WithLabelValues(ctx.GetRuleId(), dsm.opType, dsm.opId, strInId)
+		// The rule metrics are shared by all operations of the rule, so they are only removed when the rule is deleted
+		rmg := GetPrometheusMetrics().GetRuleMetricsGroup()
+		psm.pRuleLatencyHist = rmg.ProcessLatencyHist.WithLabelValues(ctx.GetRuleId())
+		psm.pRuleLatencySum = rmg.ProcessLatencySum.WithLabelValues(ctx.GetRuleId())
 		psm.pBufferLength = mg.BufferLength.WithLabelValues(ctx.GetRuleId(), dsm.opType, dsm.opId, strInId)
 		sm = psm
 	} else {
@@ -67,6 +73,9 @@ type PrometheusStatManager struct {
 	pTotalExceptions        prometheus.Counter
 	pProcessLatency         prometheus.Gauge
 	pProcessLatencyHist     prometheus.Observer
+	pProcessLatencySum      prometheus.Observer
+	pRuleLatencyHist        prometheus.Observer
+	pRuleLatencySum         prometheus.Observer
 	pBufferLength           prometheus.Gauge
 }
 
@@ -86,8 +95,12 @@ func (sm *PrometheusStatManager) IncTotalRecordsOut() {
 }
 
 func (sm *PrometheusStatManager) IncTotalExceptions(err string) {
+	sm.IncTotalExceptionsOf(sm.category, err)
+}
+
+func (sm *PrometheusStatManager) IncTotalExceptionsOf(category string, err string) {
 	sm.pTotalExceptions.Inc()
-	sm.DefaultStatManager.IncTotalExceptions(err)
+	sm.DefaultStatManager.IncTotalExceptionsOf(category, err)
 }
 
 func (sm *PrometheusStatManager) ProcessTimeEnd() {
@@ -95,6 +108,9 @@ func (sm *PrometheusStatManager) ProcessTimeEnd() {
 		sm.processLatency = int64(time.Since(sm.processTimeStart) / time.Microsecond)
 		sm.pProcessLatency.Set(float64(sm.processLatency))
 		sm.pProcessLatencyHist.Observe(float64(sm.processLatency))
+		sm.pProcessLatencySum.Observe(float64(sm.processLatency))
+		sm.pRuleLatencyHist.Observe(float64(sm.processLatency))
+		sm.pRuleLatencySum.Observe(float64(sm.processLatency))
 	}
 }
 
@@ -112,7 +128,53 @@ func (sm *PrometheusStatManager) Clean(ruleId string) {
 		mg.TotalMessagesProcessed.DeleteLabelValues(ruleId, sm.opType, sm.opId, strInId)
 		mg.TotalExceptions.DeleteLabelValues(ruleId, sm.opType, sm.opId, strInId)
 		mg.ProcessLatency.DeleteLabelValues(ruleId, sm.opType, sm.opId, strInId)
+		mg.ProcessLatencyHist.DeleteLabelValues(ruleId, sm.opType, sm.opId, strInId)
+		mg.ProcessLatencySum.DeleteLabelValues(ruleId, sm.opType, sm.opId, strInId)
 		mg.BufferLength.DeleteLabelValues(ruleId, sm.opType, sm.opId, strInId)
+		rmg := GetPrometheusMetrics().GetRuleMetricsGroup()
+		rmg.ProcessLatencyHist.DeleteLabelValues(ruleId)
+		rmg.ProcessLatencySum.DeleteLabelValues(ruleId)
 		conf.Log.Infof("finish removing rule:%v, opType:%v, opId:%v, InId:%v prometheus metrics", ruleId, sm.opType, sm.opId, strInId)
 	}
 }
+
+// SetQueueLength updates the metrics of the queue from a node to its downstream node
+func SetQueueLength(ruleId string, from string, to string, length int, capacity int) {
+	if conf.Config != nil && conf.Config.Basic.Prometheus {
+		qmg := GetPrometheusMetrics().GetQueueMetricsGroup()
+		qmg.Length.WithLabelValues(ruleId, from, to).Set(float64(length))
+		qmg.Capacity.WithLabelValues(ruleId, from, to).Set(float64(capacity))
+	}
+}
+
+// CleanQueueLength removes the metrics of all queues of the rule
+func CleanQueueLength(ruleId string) {
+	if conf.Config != nil && conf.Config.Basic.Prometheus {
+		qmg := GetPrometheusMetrics().GetQueueMetricsGroup()
+		qmg.Length.DeletePartialMatch(prometheus.Labels{"rule": ruleId})
+		qmg.Capacity.DeletePartialMatch(prometheus.Labels{"rule": ruleId})
+	}
+}
+
+// SetThrottleLevel updates the throttle level of the rule
+func SetThrottleLevel(ruleId string, level int) {
+	if conf.Config != nil && conf.Config.Basic.Prometheus {
+		GetPrometheusMetrics().GetRuleMetricsGroup().ThrottleLevel.WithLabelValues(ruleId).Set(float64(level))
+	}
+}
+
+// AddThrottledTime adds the time the sources of the rule paused for the throttling
+func AddThrottledTime(ruleId string, d time.Duration) {
+	if conf.Config != nil && conf.Config.Basic.Prometheus {
+		GetPrometheusMetrics().GetRuleMetricsGroup().ThrottledSeconds.WithLabelValues(ruleId).Add(d.Seconds())
+	}
+}
+
+// CleanThrottle removes the throttle metrics of the rule
+func CleanThrottle(ruleId string) {
+	if conf.Config != nil && conf.Config.Basic.Prometheus {
+		rmg := GetPrometheusMetrics().GetRuleMetricsGroup()
+		rmg.ThrottleLevel.DeleteLabelValues(ruleId)
+		rmg.ThrottledSeconds.DeleteLabelValues(ruleId)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"time"
+
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+// receiveBatch appends the first item and the items queued after it to the batch without blocking until the micro
+// batch size. So the operator processes the items one by one at low load and in batches when the queue grows.
+// The batch stops before the checkpoint barrier and the drain signal which must not overtake the data in the batch,
+// and the signal is returned as the next item to process after the batch.
+func (o *UnaryOperator) receiveBatch(batch []any, first any) ([]any, any) {
+	item := first
+	for {
+		if _, ok := item.(*DrainSignal); ok || isBarrier(item) {
+			return batch, item
+		}
+		batch = append(batch, item)
+		if len(batch) >= o.microBatchSize {
+			return batch, nil
+		}
+		select {
+		case item = <-o.input:
+		default:
+			return batch, nil
+		}
+	}
+}
+
+// processBatch applies the operation to each item of the batch as the unary operator does for a single item, while the
+// process time, the quota and the buffer length are updated once for the batch. The operations still evaluate the items
+// one by one, so the batch only saves the per item accounting and scheduling, not the evaluation cost.
+func (o *UnaryOperator) processBatch(ctx api.StreamContext, exeCtx api.StreamContext, batch []any, fv *xsql.FunctionValuer, afv *xsql.AggregateFunctionValuer) {
+	var (
+		cost    time.Duration
+		applied int
+	)
+	for _, item := range batch {
+		processed := false
+		if item, processed = o.preprocess(item); processed {
+			continue
+		}
+		switch d := item.(type) {
+		case error:
+			o.Broadcast(d)
+			o.statManager.IncTotalExceptions(d.Error())
+			continue
+		case *xsql.WatermarkTuple:
+			o.Broadcast(d)
+			continue
+		}
+
+		o.statManager.IncTotalRecordsIn()
+		span := startSpan(ctx, o.name, item)
+		start := time.Now()
+		result := o.op.Apply(exeCtx, item, fv, afv)
+		cost += time.Since(start)
+		applied++
+
+		switch val := result.(type) {
+		case nil:
+			span.end(nil)
+			o.statManager.IncTotalMessagesProcessed(1)
+		case error:
+			span.end(val)
+			ctx.GetLogger().Errorf("Operation %s error: %s", ctx.GetOpId(), val)
+			o.Broadcast(val)
+			o.statManager.IncTotalMessagesProcessed(1)
+			o.statManager.IncTotalExceptions(val.Error())
+		case []xsql.Row:
+			for _, v := range val {
+				span.output(v)
+			}
+			span.end(nil)
+			for _, v := range val {
+				o.Broadcast(v)
+				o.statManager.IncTotalMessagesProcessed(1)
+				o.statManager.IncTotalRecordsOut()
+			}
+		default:
+			span.output(val)
+			span.end(nil)
+			o.Broadcast(val)
+			o.statManager.IncTotalMessagesProcessed(1)
+			o.statManager.IncTotalRecordsOut()
+		}
+	}
+	if applied > 0 {
+		if o.quota != nil {
+			o.quota.Charge(cost)
+		}
+		// The latency is the average of the items in the batch
+		o.statManager.ProcessTimeStart()
+		o.statManager.SetProcessTimeStart(time.Now().Add(-cost / time.Duration(applied)))
+		o.statManager.ProcessTimeEnd()
+	}
+	o.statManager.SetBufferLength(int64(len(o.input)))
+}
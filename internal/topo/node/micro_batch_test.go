@@ -0,0 +1,119 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/topo/checkpoint"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	mockContext "github.com/lf-edge/ekuiper/pkg/mock/context"
+)
+
+func TestReceiveBatch(t *testing.T) {
+	o := New("test", &api.RuleOption{BufferLength: 10, MicroBatchSize: 3})
+	for i := 1; i < 5; i++ {
+		o.input <- i
+	}
+	batch, next := o.receiveBatch(nil, 0)
+	assert.Equal(t, []any{0, 1, 2}, batch)
+	assert.Nil(t, next)
+	// The batch stops at the control signals
+	barrier := &checkpoint.BufferOrEvent{Data: &checkpoint.Barrier{CheckpointId: 1}, Channel: "test"}
+	o.input <- barrier
+	o.input <- 5
+	batch, next = o.receiveBatch(batch[:0], <-o.input)
+	assert.Equal(t, []any{3, 4}, batch)
+	assert.Same(t, barrier, next)
+	o.input <- &DrainSignal{}
+	batch, next = o.receiveBatch(batch[:0], <-o.input)
+	assert.Equal(t, []any{5}, batch)
+	assert.IsType(t, &DrainSignal{}, next)
+	// Do not wait for more items
+	batch, next = o.receiveBatch(batch[:0], 6)
+	assert.Equal(t, []any{6}, batch)
+	assert.Nil(t, next)
+}
+
+func TestMicroBatchOperator(t *testing.T) {
+	o := New("test", &api.RuleOption{BufferLength: 20, MicroBatchSize: 4, SendError: true})
+	o.SetOperation(testOp(func(_ api.StreamContext, data any) any {
+		tuple := data.(*xsql.Tuple)
+		if tuple.Message["i"].(int) == 3 {
+			return fmt.Errorf("error at 3")
+		}
+		return tuple
+	}))
+	o.AddInputCount()
+	out := make(chan any, 20)
+	require.NoError(t, o.AddOutput(out, "test"))
+	// Queue the items before running to process them in batches
+	for i := 0; i < 10; i++ {
+		o.input <- &xsql.Tuple{Emitter: "test", Message: map[string]any{"i": i}}
+	}
+	o.input <- &DrainSignal{}
+	ctx, cancel := mockContext.NewMockContext("test1", "micro_batch").WithCancel()
+	defer cancel()
+	o.Exec(ctx, make(chan error))
+	for i := 0; i < 10; i++ {
+		r := receive(t, out)
+		if i == 3 {
+			assert.EqualError(t, r.(error), "error at 3")
+		} else {
+			assert.Equal(t, i, r.(*xsql.Tuple).Message["i"])
+		}
+	}
+	assert.IsType(t, &DrainSignal{}, receive(t, out))
+}
+
+func BenchmarkMicroBatch(b *testing.B) {
+	for _, size := range []int{0, 16, 64} {
+		b.Run(fmt.Sprintf("size_%d", size), func(b *testing.B) {
+			o := New("test", &api.RuleOption{BufferLength: 1024, MicroBatchSize: size})
+			o.SetOperation(testOp(func(_ api.StreamContext, data any) any {
+				return data
+			}))
+			o.AddInputCount()
+			out := make(chan any, 1024)
+			require.NoError(b, o.AddOutput(out, "test"))
+			ctx, cancel := mockContext.NewMockContext("test1", "micro_batch").WithCancel()
+			defer cancel()
+			o.Exec(ctx, make(chan error))
+			tuple := &xsql.Tuple{Emitter: "test", Message: map[string]any{"a": 1}}
+			done := make(chan struct{})
+			go func() {
+				// The output may be dropped when the buffer is full, so read until the drain signal
+				for r := range out {
+					if _, ok := r.(*DrainSignal); ok {
+						break
+					}
+				}
+				close(done)
+			}()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				o.input <- tuple
+			}
+			o.input <- &DrainSignal{}
+			<-done
+		})
+	}
+}
@@ -16,13 +16,16 @@ package node
 
 import (
 	"fmt"
+	"sort"
 	"sync"
+	"sync/atomic"
 
 	"github.com/lf-edge/ekuiper/internal/binder/io"
 	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/pkg/util"
 	"github.com/lf-edge/ekuiper/internal/topo/checkpoint"
 	"github.com/lf-edge/ekuiper/internal/topo/node/metric"
+	"github.com/lf-edge/ekuiper/internal/topo/quota"
 	"github.com/lf-edge/ekuiper/internal/xsql"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/ast"
@@ -40,6 +43,31 @@ type OperatorNode interface {
 	RemoveMetrics(name string)
 }
 
+// ExtraMetricsNode is the node which has its own metrics in addition to the common metrics
+type ExtraMetricsNode interface {
+	GetExtraMetrics() ([]string, []any)
+}
+
+// HealthNode is the node which reports its exceptions to evaluate the rule health
+type HealthNode interface {
+	GetName() string
+	// GetErrorStats returns the exceptions by category, and the category of the last exception if the node keeps failing
+	GetErrorStats() (map[string]metric.ErrorStats, string)
+}
+
+// QueueNode is the node which sends data to its downstream nodes by the queues
+type QueueNode interface {
+	GetName() string
+	GetOutputQueues() []Queue
+}
+
+// Queue is the snapshot of the queue to a downstream node
+type Queue struct {
+	To       string
+	Length   int
+	Capacity int
+}
+
 type SchemaNode interface {
 	// AttachSchema attach the schema to the node. The parameters are ruleId, sourceName, schema, whether is wildcard
 	AttachSchema(api.StreamContext, string, map[string]*ast.JsonStreamField, bool)
@@ -80,6 +108,10 @@ type defaultNode struct {
 	qos         api.Qos
 	outputMu    sync.RWMutex
 	outputs     map[string]chan<- any
+	quota       *quota.Limiter
+	throttle    *quota.Throttle
+	// tap receives a copy of the output for debugging, nil if the node is not tapped
+	tap atomic.Pointer[TapFunc]
 }
 
 func newDefaultNode(name string, options *api.RuleOption) *defaultNode {
@@ -117,13 +149,37 @@ func (o *defaultNode) GetMetrics() []any {
 	return nil
 }
 
+func (o *defaultNode) GetErrorStats() (map[string]metric.ErrorStats, string) {
+	if o.statManager != nil {
+		return o.statManager.GetErrorStats()
+	}
+	return nil, ""
+}
+
 func (o *defaultNode) RemoveMetrics(ruleId string) {
 	if o.statManager != nil {
 		o.statManager.Clean(ruleId)
 	}
 }
 
+// GetOutputQueues returns the queues to the downstream nodes ordered by their names. It is safe to call concurrently.
+func (o *defaultNode) GetOutputQueues() []Queue {
+	o.outputMu.RLock()
+	defer o.outputMu.RUnlock()
+	result := make([]Queue, 0, len(o.outputs))
+	for name, out := range o.outputs {
+		result = append(result, Queue{To: name, Length: len(out), Capacity: cap(out)})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].To < result[j].To
+	})
+	return result
+}
+
 func (o *defaultNode) Broadcast(val interface{}) {
+	if tap := o.tap.Load(); tap != nil {
+		(*tap)(val)
+	}
 	if _, ok := val.(error); ok && !o.sendError {
 		return
 	}
@@ -139,33 +195,54 @@ func (o *defaultNode) Broadcast(val interface{}) {
 	return
 }
 
+// doBroadcast sends the data to all outputs. The downstream nodes may modify the data in place, so each output
+// except the last one receives a copy which is cloned before any output receives the data. The last output receives
+// the original data, so that the data is passed without copy for the single consumer.
 func (o *defaultNode) doBroadcast(val interface{}) {
 	o.outputMu.RLock()
 	defer o.outputMu.RUnlock()
 	l := len(o.outputs)
 	c := 0
 	for name, out := range o.outputs {
+		c++
+		data := val
+		if c < l {
+			data = cloneData(val)
+		}
 		select {
-		case out <- val:
+		case out <- data:
 			// do nothing
 		case <-o.ctx.Done():
 			// rule stop so stop waiting
 		default:
-			o.statManager.IncTotalExceptions(fmt.Sprintf("buffer full, drop message from %s to %s", o.name, name))
+			if isDrainSignal(data) {
+				// The drain signal cannot be dropped, so wait for the downstream
+				select {
+				case out <- data:
+				case <-o.ctx.Done():
+				}
+				break
+			}
+			o.statManager.IncTotalExceptionsOf(metric.ErrorResource, fmt.Sprintf("buffer full, drop message from %s to %s", o.name, name))
 			o.ctx.GetLogger().Debugf("drop message from %s to %s", o.name, name)
 		}
-		c++
-		if c == l {
-			break
-		}
-		switch vt := val.(type) {
-		case xsql.Collection:
-			val = vt.Clone()
-			break
-		case xsql.Row:
-			val = vt.Clone()
+	}
+}
+
+// cloneData copies the data which may be modified by the downstream nodes. The other data are immutable and shared.
+func cloneData(val interface{}) interface{} {
+	switch vt := val.(type) {
+	case xsql.Collection:
+		return vt.Clone()
+	case xsql.Row:
+		return vt.Clone()
+	case *checkpoint.BufferOrEvent:
+		switch vt.Data.(type) {
+		case xsql.Collection, xsql.Row:
+			return &checkpoint.BufferOrEvent{Data: cloneData(vt.Data), Channel: vt.Channel}
 		}
 	}
+	return val
 }
 
 func (o *defaultNode) GetStreamContext() api.StreamContext {
@@ -178,6 +255,9 @@ type defaultSinkNode struct {
 	barrierHandler checkpoint.BarrierHandler
 	inputCount     int
 	bufferLen      int
+	// the count of the received drain signals and the function to flush the buffered data when drained
+	drainCount   atomic.Int32
+	drainHandler func()
 }
 
 func newDefaultSinkNode(name string, options *api.RuleOption) *defaultSinkNode {
@@ -217,10 +297,14 @@ func (o *defaultSinkNode) preprocess(data interface{}) (interface{}, bool) {
 			if o.barrierHandler.Process(b, o.ctx) {
 				return nil, true
 			} else {
-				return b.Data, false
+				data = b.Data
 			}
 		}
 	}
+	if _, ok := data.(*DrainSignal); ok {
+		o.drain()
+		return nil, true
+	}
 	return data, false
 }
 
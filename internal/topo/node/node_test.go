@@ -0,0 +1,101 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/topo/checkpoint"
+	"github.com/lf-edge/ekuiper/internal/topo/node/metric"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	mockContext "github.com/lf-edge/ekuiper/pkg/mock/context"
+)
+
+func newBroadcastNode(t testing.TB, outputs int, qos api.Qos) (*defaultNode, []chan any) {
+	ctx := mockContext.NewMockContext("test1", "broadcast")
+	n := newDefaultNode("test", &api.RuleOption{})
+	n.ctx = ctx
+	n.qos = qos
+	n.statManager = metric.NewStatManager(ctx, "op")
+	outs := make([]chan any, outputs)
+	for i := range outs {
+		outs[i] = make(chan any, 1)
+		require.NoError(t, n.AddOutput(outs[i], fmt.Sprintf("out%d", i)))
+	}
+	return n, outs
+}
+
+func TestBroadcastSingleOutput(t *testing.T) {
+	n, outs := newBroadcastNode(t, 1, api.AtMostOnce)
+	tuple := &xsql.Tuple{Emitter: "test", Message: map[string]any{"a": 1}}
+	n.Broadcast(tuple)
+	// The only consumer receives the data without copy
+	assert.Same(t, tuple, <-outs[0])
+}
+
+func TestBroadcastMultipleOutputs(t *testing.T) {
+	n, outs := newBroadcastNode(t, 3, api.AtMostOnce)
+	tuple := &xsql.Tuple{Emitter: "test", Message: map[string]any{"a": 1}}
+	n.Broadcast(tuple)
+	received := make(map[*xsql.Tuple]bool)
+	originals := 0
+	for _, out := range outs {
+		r := (<-out).(*xsql.Tuple)
+		assert.Equal(t, tuple.Message, r.Message)
+		received[r] = true
+		if r == tuple {
+			originals++
+		}
+	}
+	assert.Len(t, received, 3)
+	assert.Equal(t, 1, originals)
+}
+
+func TestBroadcastBufferOrEvent(t *testing.T) {
+	n, outs := newBroadcastNode(t, 2, api.AtLeastOnce)
+	tuple := &xsql.Tuple{Emitter: "test", Message: map[string]any{"a": 1}}
+	n.Broadcast(tuple)
+	r0 := (<-outs[0]).(*checkpoint.BufferOrEvent)
+	r1 := (<-outs[1]).(*checkpoint.BufferOrEvent)
+	assert.Equal(t, "test", r0.Channel)
+	assert.Equal(t, "test", r1.Channel)
+	assert.NotSame(t, r0.Data, r1.Data)
+	// The barrier is shared
+	barrier := &checkpoint.BufferOrEvent{Data: &checkpoint.Barrier{CheckpointId: 1}, Channel: "test"}
+	n.doBroadcast(barrier)
+	assert.Same(t, (<-outs[0]).(*checkpoint.BufferOrEvent).Data, (<-outs[1]).(*checkpoint.BufferOrEvent).Data)
+}
+
+func BenchmarkBroadcast(b *testing.B) {
+	for _, outputs := range []int{1, 2, 4} {
+		b.Run(fmt.Sprintf("outputs_%d", outputs), func(b *testing.B) {
+			n, outs := newBroadcastNode(b, outputs, api.AtMostOnce)
+			message := map[string]any{"a": 1, "b": "test", "c": 2.5}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				n.Broadcast(&xsql.Tuple{Emitter: "test", Message: message})
+				for _, out := range outs {
+					<-out
+				}
+			}
+		})
+	}
+}
@@ -15,9 +15,13 @@
 package node
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/lf-edge/ekuiper/internal/topo/node/metric"
 	"github.com/lf-edge/ekuiper/internal/xsql"
 	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
 	"github.com/lf-edge/ekuiper/pkg/infra"
 )
 
@@ -38,13 +42,24 @@ type UnaryOperator struct {
 	*defaultSinkNode
 	op        UnOperation
 	cancelled bool
+	// partitionBy is the key to distribute the rows to the instances when running concurrently
+	partitionBy ast.Expr
+	// newOp creates the operation for each instance if the operation cannot be shared
+	newOp func() UnOperation
+	// microBatchSize is the max count of the queued items to process at a time, 0 or 1 means no batching
+	microBatchSize int
+	batch          []any
 }
 
 // New NewUnary creates *UnaryOperator value
 func New(name string, options *api.RuleOption) *UnaryOperator {
-	return &UnaryOperator{
+	o := &UnaryOperator{
 		defaultSinkNode: newDefaultSinkNode(name, options),
+		microBatchSize:  options.MicroBatchSize,
 	}
+	// The operator runs in a single instance unless the parallelism is set
+	o.concurrency = 1
+	return o
 }
 
 // SetOperation sets the executor operation
@@ -52,6 +67,20 @@ func (o *UnaryOperator) SetOperation(op UnOperation) {
 	o.op = op
 }
 
+// GetOperation returns the executor operation
+func (o *UnaryOperator) GetOperation() UnOperation {
+	return o.op
+}
+
+// SetParallelism runs the operation in multiple instances. If partitionBy is set, the rows of the same key are
+// processed by the same instance in order. Otherwise, the rows are distributed in turn and the outputs keep the order.
+// If newOp is set, each instance applies its own operation created by it, otherwise the operation is shared.
+func (o *UnaryOperator) SetParallelism(concurrency int, partitionBy ast.Expr, newOp func() UnOperation) {
+	o.concurrency = concurrency
+	o.partitionBy = partitionBy
+	o.newOp = newOp
+}
+
 // Exec is the entry point for the executor
 func (o *UnaryOperator) Exec(ctx api.StreamContext, errCh chan<- error) {
 	o.ctx = ctx
@@ -66,7 +95,11 @@ func (o *UnaryOperator) Exec(ctx api.StreamContext, errCh chan<- error) {
 
 	go func() {
 		err := infra.SafeRun(func() error {
-			o.doOp(ctx.WithInstance(0), errCh)
+			if o.concurrency > 1 {
+				o.runParallel(ctx)
+			} else {
+				o.doOp(ctx.WithInstance(0), errCh)
+			}
 			return nil
 		})
 		if err != nil {
@@ -95,6 +128,16 @@ func (o *UnaryOperator) doOp(ctx api.StreamContext, errCh chan<- error) {
 		select {
 		// process incoming item
 		case item := <-o.input:
+			if o.microBatchSize > 1 && len(o.input) > 0 {
+				var next any
+				o.batch, next = o.receiveBatch(o.batch[:0], item)
+				o.processBatch(ctx, exeCtx, o.batch, fv, afv)
+				clear(o.batch)
+				if next == nil {
+					continue
+				}
+				item = next
+			}
 			processed := false
 			if item, processed = o.preprocess(item); processed {
 				break
@@ -111,13 +154,18 @@ func (o *UnaryOperator) doOp(ctx api.StreamContext, errCh chan<- error) {
 
 			o.statManager.IncTotalRecordsIn()
 			o.statManager.ProcessTimeStart()
+			span := startSpan(ctx, o.name, item)
+			start := time.Now()
 			result := o.op.Apply(exeCtx, item, fv, afv)
+			o.chargeQuota(start)
 
 			switch val := result.(type) {
 			case nil:
+				span.end(nil)
 				o.statManager.IncTotalMessagesProcessed(1)
 				continue
 			case error:
+				span.end(val)
 				logger.Errorf("Operation %s error: %s", ctx.GetOpId(), val)
 				o.Broadcast(val)
 				o.statManager.IncTotalMessagesProcessed(1)
@@ -125,6 +173,10 @@ func (o *UnaryOperator) doOp(ctx api.StreamContext, errCh chan<- error) {
 				continue
 			case []xsql.Row:
 				o.statManager.ProcessTimeEnd()
+				for _, v := range val {
+					span.output(v)
+				}
+				span.end(nil)
 				for _, v := range val {
 					o.Broadcast(v)
 					o.statManager.IncTotalMessagesProcessed(1)
@@ -133,6 +185,8 @@ func (o *UnaryOperator) doOp(ctx api.StreamContext, errCh chan<- error) {
 				o.statManager.SetBufferLength(int64(len(o.input)))
 			default:
 				o.statManager.ProcessTimeEnd()
+				span.output(val)
+				span.end(nil)
 				o.Broadcast(val)
 				o.statManager.IncTotalMessagesProcessed(1)
 				o.statManager.IncTotalRecordsOut()
@@ -146,3 +200,76 @@ func (o *UnaryOperator) doOp(ctx api.StreamContext, errCh chan<- error) {
 		}
 	}
 }
+
+func (o *UnaryOperator) runParallel(ctx api.StreamContext) {
+	if o.op == nil {
+		ctx.GetLogger().Infoln("Unary operator missing operation")
+		return
+	}
+	ctx.GetLogger().Infof("unary operator %s runs with %d instances, partition by %v", o.name, o.concurrency, o.partitionBy)
+	sm := &syncStatManager{StatManager: metric.NewStatManager(ctx, "op")}
+	o.statManager = sm
+	// Each instance has its own function valuers. The states of the functions are in the shared op state,
+	// so they are correct as long as the rows of a state key are processed by the same instance.
+	newWorker := func(i int) workerFunc {
+		wctx := ctx.WithInstance(i)
+		fv, afv := xsql.NewFunctionValuersForOp(wctx)
+		op := o.op
+		if o.newOp != nil {
+			op = o.newOp()
+		}
+		return func(item any) []any {
+			return o.work(wctx, op, sm, item, fv, afv)
+		}
+	}
+	if o.partitionBy == nil {
+		runWorkersWithOrder(ctx, o.defaultSinkNode, o.concurrency, newWorker)
+		return
+	}
+	fv, _ := xsql.NewFunctionValuersForOp(ctx)
+	runWithPartition(ctx, o.defaultSinkNode, o.concurrency, func(item any) (string, bool) {
+		row, ok := item.(xsql.Row)
+		if !ok {
+			return "", false
+		}
+		ve := xsql.GetValuerEval(row, fv)
+		key := ve.Eval(o.partitionBy)
+		xsql.PutValuerEval(ve)
+		return fmt.Sprintf("%v", key), true
+	}, newWorker)
+}
+
+// work applies the operation to an item in a concurrent instance and returns the results to send
+func (o *UnaryOperator) work(ctx api.StreamContext, op UnOperation, sm *syncStatManager, item any, fv *xsql.FunctionValuer, afv *xsql.AggregateFunctionValuer) []any {
+	switch item.(type) {
+	case error, *xsql.WatermarkTuple:
+		return []any{item}
+	}
+	span := startSpan(ctx, o.name, item)
+	start := time.Now()
+	result := op.Apply(ctx, item, fv, afv)
+	o.chargeQuota(start)
+	switch val := result.(type) {
+	case nil:
+		span.end(nil)
+		return nil
+	case error:
+		span.end(val)
+		ctx.GetLogger().Errorf("Operation %s error: %s", ctx.GetOpId(), val)
+		return []any{val}
+	case []xsql.Row:
+		sm.processTime(start)
+		results := make([]any, len(val))
+		for i, v := range val {
+			span.output(v)
+			results[i] = v
+		}
+		span.end(nil)
+		return results
+	default:
+		sm.processTime(start)
+		span.output(val)
+		span.end(nil)
+		return []any{val}
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/ekuiper/internal/topo/node/metric"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+// keyFunc returns the partition key of the item. If the item has no key, such as a collection or a watermark,
+// it returns false and the item is processed after all the items before it are done.
+type keyFunc func(item any) (string, bool)
+
+// runWithPartition distributes the items to the workers by the hash of the key, so the items of the same key are
+// processed by the same worker in order. The results of different keys may be sent out of order.
+func runWithPartition(ctx api.StreamContext, node *defaultSinkNode, numWorkers int, kf keyFunc, newWorker func(i int) workerFunc) {
+	workerChans := make([]chan any, numWorkers)
+	for i := range workerChans {
+		workerChans[i] = make(chan any)
+	}
+	// All workers share the output channel, so the results of a worker keep the order
+	output := make(chan []any)
+	for i := 0; i < numWorkers; i++ {
+		go worker(ctx, i, newWorker(i), workerChans[i], output)
+	}
+	go func() {
+		for {
+			select {
+			case data := <-output:
+				emit(node, data)
+			case <-ctx.Done():
+				ctx.GetLogger().Infof("partition merge done")
+				return
+			}
+		}
+	}()
+	node.drainHandler = func() {
+		flushWorkers(ctx, 0, workerChans)
+	}
+	send := func(i int, item any) bool {
+		select {
+		case workerChans[i] <- item:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	for {
+		node.statManager.SetBufferLength(int64(len(node.input)))
+		select {
+		case <-ctx.Done():
+			ctx.GetLogger().Infof("partition distribute done")
+			return
+		case item := <-node.input:
+			if isBarrier(item) {
+				flushWorkers(ctx, 0, workerChans)
+			}
+			processed := false
+			if item, processed = node.preprocess(item); processed {
+				continue
+			}
+			node.statManager.IncTotalRecordsIn()
+			if key, ok := kf(item); ok {
+				if !send(partitionOf(key, numWorkers), item) {
+					return
+				}
+			} else {
+				flushWorkers(ctx, 0, workerChans)
+				if !send(0, item) {
+					return
+				}
+				flushWorkers(ctx, 0, workerChans)
+			}
+		}
+	}
+}
+
+func partitionOf(key string, numWorkers int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(numWorkers))
+}
+
+// syncStatManager makes the stat manager safe to be updated by the concurrent workers
+type syncStatManager struct {
+	sync.Mutex
+	metric.StatManager
+}
+
+func (s *syncStatManager) IncTotalRecordsIn() {
+	s.Lock()
+	defer s.Unlock()
+	s.StatManager.IncTotalRecordsIn()
+}
+
+func (s *syncStatManager) IncTotalRecordsOut() {
+	s.Lock()
+	defer s.Unlock()
+	s.StatManager.IncTotalRecordsOut()
+}
+
+func (s *syncStatManager) IncTotalMessagesProcessed(n int64) {
+	s.Lock()
+	defer s.Unlock()
+	s.StatManager.IncTotalMessagesProcessed(n)
+}
+
+func (s *syncStatManager) IncTotalExceptions(err string) {
+	s.Lock()
+	defer s.Unlock()
+	s.StatManager.IncTotalExceptions(err)
+}
+
+func (s *syncStatManager) IncTotalExceptionsOf(category string, err string) {
+	s.Lock()
+	defer s.Unlock()
+	s.StatManager.IncTotalExceptionsOf(category, err)
+}
+
+func (s *syncStatManager) ProcessTimeStart() {
+	s.Lock()
+	defer s.Unlock()
+	s.StatManager.ProcessTimeStart()
+}
+
+func (s *syncStatManager) ProcessTimeEnd() {
+	s.Lock()
+	defer s.Unlock()
+	s.StatManager.ProcessTimeEnd()
+}
+
+func (s *syncStatManager) SetBufferLength(l int64) {
+	s.Lock()
+	defer s.Unlock()
+	s.StatManager.SetBufferLength(l)
+}
+
+func (s *syncStatManager) SetProcessTimeStart(t time.Time) {
+	s.Lock()
+	defer s.Unlock()
+	s.StatManager.SetProcessTimeStart(t)
+}
+
+func (s *syncStatManager) GetMetrics() []any {
+	s.Lock()
+	defer s.Unlock()
+	return s.StatManager.GetMetrics()
+}
+
+func (s *syncStatManager) GetErrorStats() (map[string]metric.ErrorStats, string) {
+	s.Lock()
+	defer s.Unlock()
+	return s.StatManager.GetErrorStats()
+}
+
+// processTime records the latency of an item which a worker starts to process at the start time
+func (s *syncStatManager) processTime(start time.Time) {
+	s.Lock()
+	defer s.Unlock()
+	s.StatManager.ProcessTimeStart()
+	s.StatManager.SetProcessTimeStart(start)
+	s.StatManager.ProcessTimeEnd()
+}
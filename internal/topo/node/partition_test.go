@@ -0,0 +1,112 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	mockContext "github.com/lf-edge/ekuiper/pkg/mock/context"
+)
+
+type testOp func(ctx api.StreamContext, data any) any
+
+func (f testOp) Apply(ctx api.StreamContext, data any, _ *xsql.FunctionValuer, _ *xsql.AggregateFunctionValuer) any {
+	return f(ctx, data)
+}
+
+func newParallelOp(t *testing.T, op testOp, concurrency int, partitionBy ast.Expr) (*UnaryOperator, chan any) {
+	o := New("test", &api.RuleOption{BufferLength: 10, Concurrency: 8})
+	assert.Equal(t, 1, o.concurrency)
+	o.SetOperation(op)
+	o.SetParallelism(concurrency, partitionBy, nil)
+	o.AddInputCount()
+	out := make(chan any, 20)
+	require.NoError(t, o.AddOutput(out, "test"))
+	return o, out
+}
+
+func TestUnaryOperatorConcurrentOrder(t *testing.T) {
+	o, out := newParallelOp(t, func(_ api.StreamContext, data any) any {
+		tuple := data.(*xsql.Tuple)
+		// The earlier item is slower
+		time.Sleep(time.Duration(5-tuple.Message["i"].(int)) * 10 * time.Millisecond)
+		return tuple
+	}, 3, nil)
+	ctx, cancel := mockContext.NewMockContext("test1", "concurrent_order").WithCancel()
+	defer cancel()
+	o.Exec(ctx, make(chan error))
+	for i := 0; i < 5; i++ {
+		o.input <- &xsql.Tuple{Emitter: "test", Message: map[string]any{"i": i}}
+	}
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, i, receive(t, out).(*xsql.Tuple).Message["i"])
+	}
+}
+
+func TestUnaryOperatorPartition(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		instances = make(map[string]map[int]bool)
+	)
+	o, out := newParallelOp(t, func(ctx api.StreamContext, data any) any {
+		tuple := data.(*xsql.Tuple)
+		k := tuple.Message["k"].(string)
+		mu.Lock()
+		if instances[k] == nil {
+			instances[k] = make(map[int]bool)
+		}
+		instances[k][ctx.GetInstanceId()] = true
+		mu.Unlock()
+		if k == "a" {
+			time.Sleep(20 * time.Millisecond)
+		}
+		return tuple
+	}, 3, &ast.FieldRef{Name: "k", StreamName: ast.DefaultStream})
+	ctx, cancel := mockContext.NewMockContext("test1", "partition").WithCancel()
+	defer cancel()
+	o.Exec(ctx, make(chan error))
+	keys := []string{"a", "b", "c", "a", "b", "c", "a", "b", "c"}
+	for i, k := range keys {
+		o.input <- &xsql.Tuple{Emitter: "test", Message: map[string]any{"k": k, "i": i}}
+	}
+	// The item without key must not overtake the rows
+	o.input <- &xsql.WatermarkTuple{Timestamp: 100}
+	o.input <- &DrainSignal{}
+	last := make(map[string]int)
+	for range keys {
+		tuple := receive(t, out).(*xsql.Tuple)
+		k := tuple.Message["k"].(string)
+		i := tuple.Message["i"].(int)
+		if l, ok := last[k]; ok {
+			assert.Less(t, l, i, "the rows of key %s are out of order", k)
+		}
+		last[k] = i
+	}
+	assert.Equal(t, &xsql.WatermarkTuple{Timestamp: 100}, receive(t, out))
+	assert.IsType(t, &DrainSignal{}, receive(t, out))
+	mu.Lock()
+	defer mu.Unlock()
+	for k, ins := range instances {
+		assert.Len(t, ins, 1, "key %s is processed by multiple instances", k)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lf-edge/ekuiper/internal/topo/node/metric"
+	"github.com/lf-edge/ekuiper/internal/topo/quota"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+// quotaCheckInterval is the interval to check whether a paused source can resume
+const quotaCheckInterval = 10 * time.Millisecond
+
+// QuotaNode is the node limited by the quota of the rule
+type QuotaNode interface {
+	SetQuota(l *quota.Limiter)
+	SetThrottle(t *quota.Throttle)
+	GetBufferedLength() int
+}
+
+// SetQuota sets the quota limiter of the rule before the node runs
+func (o *defaultNode) SetQuota(l *quota.Limiter) {
+	o.quota = l
+}
+
+// SetThrottle sets the throttle of the rule by its priority before the node runs
+func (o *defaultNode) SetThrottle(t *quota.Throttle) {
+	o.throttle = t
+}
+
+// GetBufferedLength returns the count of the tuples in the queues to the downstream nodes
+func (o *defaultNode) GetBufferedLength() int {
+	o.outputMu.RLock()
+	defer o.outputMu.RUnlock()
+	n := 0
+	for _, out := range o.outputs {
+		n += len(out)
+	}
+	return n
+}
+
+// chargeQuota charges the processing time since start to the cpu share of the rule
+func (o *defaultNode) chargeQuota(start time.Time) {
+	if o.quota != nil {
+		o.quota.Charge(time.Since(start))
+	}
+}
+
+// waitQuota is called by the sources before reading. It blocks until the rule is under the quota if the policy is pause.
+// It also pauses for the throttling of the rule.
+// Return false if the rule stops.
+func (o *defaultNode) waitQuota(ctx api.StreamContext) bool {
+	if !o.waitThrottle(ctx) {
+		return false
+	}
+	if o.quota == nil || o.quota.Policy() != api.QuotaPause {
+		return true
+	}
+	err := o.quota.Check()
+	if err == nil {
+		return true
+	}
+	ctx.GetLogger().Infof("Source %s pauses because the rule exceeds the quota: %v", o.name, err)
+	ticker := time.NewTicker(quotaCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+			if o.quota.Check() == nil {
+				ctx.GetLogger().Infof("Source %s resumes", o.name)
+				return true
+			}
+		}
+	}
+}
+
+// waitThrottle pauses the source out of the time share of the rule when it is throttled for its low priority.
+// Return false if the rule stops.
+func (o *defaultNode) waitThrottle(ctx api.StreamContext) bool {
+	if o.throttle == nil {
+		return true
+	}
+	d := o.throttle.Delay(time.Now())
+	if d == 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		o.throttle.AddWaited(d)
+		return true
+	}
+}
+
+// checkQuota is called by the sources after reading a message. It returns whether to drop the message
+// by the drop policy or the error to fail the rule by the error policy.
+func (o *defaultNode) checkQuota() (bool, error) {
+	if o.quota == nil || o.quota.Policy() == api.QuotaPause {
+		return false, nil
+	}
+	err := o.quota.Check()
+	if err == nil {
+		return false, nil
+	}
+	if o.quota.Policy() == api.QuotaError {
+		return false, fmt.Errorf("rule exceeds the quota: %v", err)
+	}
+	o.statManager.IncTotalExceptionsOf(metric.ErrorResource, fmt.Sprintf("quota exceeded: %v", err))
+	return true, nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/topo/node/metric"
+	"github.com/lf-edge/ekuiper/internal/topo/quota"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	mockContext "github.com/lf-edge/ekuiper/pkg/mock/context"
+)
+
+func TestQuotaPolicy(t *testing.T) {
+	ctx, cancel := mockContext.NewMockContext("test1", "quota").WithCancel()
+	defer cancel()
+	n := newDefaultNode("src", &api.RuleOption{})
+	n.statManager = metric.NewStatManager(ctx, "source")
+	out := make(chan any, 10)
+	require.NoError(t, n.AddOutput(out, "op"))
+	newLimiter := func(policy string) *quota.Limiter {
+		return quota.NewLimiter(&api.RuleQuota{MaxBufferedTuples: 1, Policy: policy}, n.GetBufferedLength)
+	}
+	// No quota
+	assert.True(t, n.waitQuota(ctx))
+	drop, err := n.checkQuota()
+	assert.False(t, drop)
+	assert.NoError(t, err)
+
+	out <- 1
+	out <- 2
+	assert.Equal(t, 2, n.GetBufferedLength())
+	n.SetQuota(newLimiter(api.QuotaDrop))
+	assert.True(t, n.waitQuota(ctx))
+	drop, err = n.checkQuota()
+	assert.True(t, drop)
+	assert.NoError(t, err)
+
+	n.SetQuota(newLimiter(api.QuotaError))
+	_, err = n.checkQuota()
+	assert.EqualError(t, err, "rule exceeds the quota: buffered tuples 2 exceeds the limit 1")
+
+	// The paused source resumes after the downstream consumes
+	n.SetQuota(newLimiter(api.QuotaPause))
+	drop, err = n.checkQuota()
+	assert.False(t, drop)
+	assert.NoError(t, err)
+	resumed := make(chan bool)
+	go func() {
+		resumed <- n.waitQuota(ctx)
+	}()
+	select {
+	case <-resumed:
+		assert.Fail(t, "should pause")
+	case <-time.After(50 * time.Millisecond):
+	}
+	<-out
+	assert.True(t, <-resumed)
+	// Stop while pausing
+	out <- 3
+	go func() {
+		resumed <- n.waitQuota(ctx)
+	}()
+	cancel()
+	assert.False(t, <-resumed)
+}
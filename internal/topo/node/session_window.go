@@ -0,0 +1,258 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+// keyedSession is the session of a single key
+type keyedSession struct {
+	key    string
+	start  int64
+	last   int64
+	tuples []*xsql.Tuple
+}
+
+// end is the time when the session closes if no more events arrive
+func (s *keyedSession) end(timeout, duration int64) int64 {
+	e := s.last + timeout
+	if m := s.start + duration; m < e {
+		e = m
+	}
+	return e
+}
+
+// sessionTracker tracks a session for each group by key. A session is closed when the key
+// is idle for the timeout or the session has lasted for the max duration since it began.
+type sessionTracker struct {
+	keys     ast.Dimensions
+	timeout  int64
+	duration int64
+	sessions map[string]*keyedSession
+	fv       *xsql.FunctionValuer
+}
+
+func newSessionTracker(ctx api.StreamContext, w *WindowConfig) *sessionTracker {
+	fv, _ := xsql.NewFunctionValuersForOp(ctx)
+	return &sessionTracker{
+		keys:     w.Keys,
+		timeout:  w.Interval,
+		duration: w.Length,
+		sessions: make(map[string]*keyedSession),
+		fv:       fv,
+	}
+}
+
+func (st *sessionTracker) keyOf(tuple *xsql.Tuple) (string, error) {
+	var key string
+	ve := &xsql.ValuerEval{Valuer: xsql.MultiValuer(tuple, st.fv)}
+	for _, d := range st.keys {
+		r := ve.Eval(d.Expr)
+		if err, ok := r.(error); ok {
+			return "", fmt.Errorf("evaluate session window key error: %v", err)
+		}
+		key += fmt.Sprintf("%v,", r)
+	}
+	return key, nil
+}
+
+// add puts the tuple into the session of its key. If the existing session of the key has
+// ended before the tuple, it is returned to be emitted.
+func (st *sessionTracker) add(tuple *xsql.Tuple) (*keyedSession, error) {
+	key, err := st.keyOf(tuple)
+	if err != nil {
+		return nil, err
+	}
+	var closed *keyedSession
+	s, ok := st.sessions[key]
+	if ok && tuple.Timestamp >= s.end(st.timeout, st.duration) {
+		closed = s
+		ok = false
+	}
+	if !ok {
+		s = &keyedSession{key: key, start: tuple.Timestamp}
+		st.sessions[key] = s
+	}
+	s.last = tuple.Timestamp
+	s.tuples = append(s.tuples, tuple)
+	return closed, nil
+}
+
+// expire removes and returns all the sessions which have ended at the given time ordered by the end time
+func (st *sessionTracker) expire(now int64) []*keyedSession {
+	var result []*keyedSession
+	for k, s := range st.sessions {
+		if s.end(st.timeout, st.duration) <= now {
+			result = append(result, s)
+			delete(st.sessions, k)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		ei, ej := result[i].end(st.timeout, st.duration), result[j].end(st.timeout, st.duration)
+		if ei != ej {
+			return ei < ej
+		}
+		return result[i].key < result[j].key
+	})
+	return result
+}
+
+// nextEnd returns the earliest end time of all sessions or max int64 if no session is open
+func (st *sessionTracker) nextEnd() int64 {
+	var r int64 = math.MaxInt64
+	for _, s := range st.sessions {
+		if e := s.end(st.timeout, st.duration); e < r {
+			r = e
+		}
+	}
+	return r
+}
+
+// inputs returns all the tuples of the open sessions to save as the window state
+func (st *sessionTracker) inputs() []*xsql.Tuple {
+	var r []*xsql.Tuple
+	for _, s := range st.sessions {
+		r = append(r, s.tuples...)
+	}
+	sort.SliceStable(r, func(i, j int) bool {
+		return r[i].Timestamp < r[j].Timestamp
+	})
+	return r
+}
+
+func (o *WindowOperator) emitSession(ctx api.StreamContext, s *keyedSession, timeout, duration int64) {
+	results := &xsql.WindowTuples{
+		Content: make([]xsql.Row, 0, len(s.tuples)),
+	}
+	for _, t := range s.tuples {
+		results.Content = append(results.Content, t)
+	}
+	results.WindowRange = xsql.NewWindowRange(s.start, s.end(timeout, duration))
+	ctx.GetLogger().Debugf("session window %s of key %s triggered for %d tuples", o.name, s.key, len(s.tuples))
+	traceWindow(ctx, o.name, results)
+	o.Broadcast(results)
+	o.statManager.IncTotalRecordsOut()
+	o.statManager.IncTotalMessagesProcessed(int64(results.Len()))
+}
+
+// execKeyedSessionWindow runs the session window which has other group by dimensions. Each key
+// has its own session instead of sharing one session for all the events.
+func (o *WindowOperator) execKeyedSessionWindow(ctx api.StreamContext, inputs []*xsql.Tuple, _ chan<- error) {
+	log := ctx.GetLogger()
+	st := newSessionTracker(ctx, o.window)
+	for _, t := range inputs {
+		if s, err := st.add(t); err != nil {
+			log.Warnf("restore session window state error: %v", err)
+		} else if s != nil {
+			o.emitSession(ctx, s, st.timeout, st.duration)
+		}
+	}
+	var (
+		timer   *clock.Timer
+		timeout <-chan time.Time
+	)
+	// For processing time, the timer fires at the earliest session end
+	resetTimer := func() {
+		if o.isEventTime {
+			return
+		}
+		if timer != nil {
+			timer.Stop()
+			timer, timeout = nil, nil
+		}
+		if next := st.nextEnd(); next < math.MaxInt64 {
+			d := next - o.clock.Now().UnixMilli()
+			if d < 0 {
+				d = 0
+			}
+			timer = o.clock.Timer(time.Duration(d) * time.Millisecond)
+			timeout = timer.C
+		}
+	}
+	expire := func(now int64) {
+		for _, s := range st.expire(now) {
+			o.emitSession(ctx, s, st.timeout, st.duration)
+		}
+	}
+	o.drainHandler = func() {
+		expire(math.MaxInt64)
+	}
+	resetTimer()
+	for {
+		select {
+		case item, opened := <-o.input:
+			if !opened {
+				o.statManager.IncTotalExceptions("input channel closed")
+				break
+			}
+			processed := false
+			if item, processed = o.preprocess(item); processed {
+				break
+			}
+			switch d := item.(type) {
+			case error:
+				o.Broadcast(d)
+				o.statManager.IncTotalExceptions(d.Error())
+			case *xsql.WatermarkTuple:
+				expire(d.GetTimestamp())
+				_ = ctx.PutState(WindowInputsKey, st.inputs())
+			case *xsql.Tuple:
+				log.Debugf("session window receive tuple %s", d.Message)
+				o.statManager.IncTotalRecordsIn()
+				o.statManager.ProcessTimeStart()
+				s, err := st.add(d)
+				if err != nil {
+					o.Broadcast(err)
+					o.statManager.IncTotalExceptions(err.Error())
+					break
+				}
+				if s != nil {
+					o.emitSession(ctx, s, st.timeout, st.duration)
+				}
+				resetTimer()
+				o.statManager.ProcessTimeEnd()
+				o.statManager.SetBufferLength(int64(len(o.input)))
+				_ = ctx.PutState(WindowInputsKey, st.inputs())
+			default:
+				e := fmt.Errorf("run Window error: expect xsql.Tuple type but got %[1]T(%[1]v)", d)
+				o.Broadcast(e)
+				o.statManager.IncTotalExceptions(e.Error())
+			}
+		case now := <-timeout:
+			log.Debugf("session window triggered by timeout at %d", now.UnixMilli())
+			o.statManager.ProcessTimeStart()
+			expire(now.UnixMilli())
+			resetTimer()
+			o.statManager.ProcessTimeEnd()
+			_ = ctx.PutState(WindowInputsKey, st.inputs())
+		case <-ctx.Done():
+			log.Infoln("Cancelling window....")
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		}
+	}
+}
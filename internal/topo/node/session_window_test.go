@@ -0,0 +1,69 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+func TestSessionTracker(t *testing.T) {
+	st := newSessionTracker(context.Background(), &WindowConfig{
+		Type:     ast.SESSION_WINDOW,
+		Length:   1000,
+		Interval: 300,
+		Keys:     ast.Dimensions{{Expr: &ast.FieldRef{Name: "id", StreamName: ast.DefaultStream}}},
+	})
+	tuple := func(id string, ts int64) *xsql.Tuple {
+		return &xsql.Tuple{Message: map[string]interface{}{"id": id}, Timestamp: ts}
+	}
+	assert.Equal(t, int64(math.MaxInt64), st.nextEnd())
+	// key a keeps active until the max duration
+	for _, ts := range []int64{0, 200, 400, 600, 800} {
+		s, err := st.add(tuple("a", ts))
+		require.NoError(t, err)
+		require.Nil(t, s)
+	}
+	s, err := st.add(tuple("b", 100))
+	require.NoError(t, err)
+	require.Nil(t, s)
+	assert.Equal(t, int64(400), st.nextEnd())
+	assert.Len(t, st.inputs(), 6)
+	// key b is idle for the timeout
+	r := st.expire(500)
+	require.Len(t, r, 1)
+	assert.Equal(t, int64(100), r[0].start)
+	assert.Len(t, r[0].tuples, 1)
+	// key a reaches the max duration
+	assert.Equal(t, int64(1000), st.nextEnd())
+	s, err = st.add(tuple("a", 1000))
+	require.NoError(t, err)
+	require.NotNil(t, s)
+	assert.Equal(t, int64(0), s.start)
+	assert.Equal(t, int64(1000), s.end(st.timeout, st.duration))
+	assert.Len(t, s.tuples, 5)
+	// a new session is started
+	assert.Equal(t, int64(1300), st.nextEnd())
+	assert.Empty(t, st.expire(1299))
+	assert.Len(t, st.expire(1300), 1)
+	assert.Empty(t, st.inputs())
+}
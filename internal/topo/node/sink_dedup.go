@@ -0,0 +1,103 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"encoding/json"
+	"path"
+
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/kv"
+)
+
+// sinkDedup records the token of the last delivered write of each target, such as the topic or the table,
+// and skips the writes which are not after it. So the results replayed after the rule restarts are not
+// delivered again for the targets which cannot deduplicate by themselves.
+// The records are persisted and cleaned when the rule is deleted.
+type sinkDedup struct {
+	api.Sink
+	// target is the template to resolve the target of a write
+	target string
+	db     kv.KeyValue
+	last   map[string]api.WriteToken
+}
+
+func newSinkDedup(ctx api.StreamContext, sink api.Sink, name string, target string) (*sinkDedup, error) {
+	db, err := store.GetCacheKV(path.Join("sink", ctx.GetRuleId(), name, "dedup"))
+	if err != nil {
+		return nil, err
+	}
+	d := &sinkDedup{
+		Sink:   sink,
+		target: target,
+		db:     db,
+		last:   make(map[string]api.WriteToken),
+	}
+	all, err := db.All()
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range all {
+		var token api.WriteToken
+		if err := json.Unmarshal([]byte(v), &token); err == nil {
+			d.last[k] = token
+		}
+	}
+	ctx.GetLogger().Infof("sink %s deduplicates by target %s with %d restored targets", name, target, len(d.last))
+	return d, nil
+}
+
+func (d *sinkDedup) CollectWithToken(ctx api.StreamContext, data interface{}, token api.WriteToken) error {
+	target, err := d.resolveTarget(ctx, data)
+	if err != nil {
+		return err
+	}
+	if last, ok := d.last[target]; ok && !last.Before(token) {
+		ctx.GetLogger().Debugf("skip the write %+v to target %s which is delivered before", token, target)
+		return nil
+	}
+	if is, ok := d.Sink.(api.IdempotentSink); ok {
+		err = is.CollectWithToken(ctx, data, token)
+	} else {
+		err = d.Sink.Collect(ctx, data)
+	}
+	if err != nil {
+		return err
+	}
+	d.last[target] = token
+	v, _ := json.Marshal(token)
+	if err := d.db.Set(target, string(v)); err != nil {
+		ctx.GetLogger().Warnf("save the delivered write token of target %s error: %v", target, err)
+	}
+	return nil
+}
+
+// resolveTarget parses the target template with the first row of the data
+func (d *sinkDedup) resolveTarget(ctx api.StreamContext, data interface{}) (string, error) {
+	if d.target == "" {
+		return "", nil
+	}
+	var row map[string]interface{}
+	switch dt := data.(type) {
+	case map[string]interface{}:
+		row = dt
+	case []map[string]interface{}:
+		if len(dt) > 0 {
+			row = dt[0]
+		}
+	}
+	return ctx.ParseTemplate(d.target, row)
+}
@@ -0,0 +1,62 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/topo/state"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+func TestSinkDedup(t *testing.T) {
+	contextLogger := conf.Log.WithField("rule", "ruleSinkDedup")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger).WithMeta("ruleSinkDedup", "op1", &state.MemoryStore{})
+	defer func() {
+		_ = store.DropCacheKVForRule("ruleSinkDedup")
+	}()
+	mockSink := &mockIdempotentSink{}
+	d, err := newSinkDedup(ctx, mockSink, "sink1", "{{.topic}}")
+	require.NoError(t, err)
+	require.NoError(t, d.CollectWithToken(ctx, map[string]interface{}{"topic": "a"}, api.WriteToken{Seq: 0}))
+	require.NoError(t, d.CollectWithToken(ctx, []map[string]interface{}{{"topic": "b"}}, api.WriteToken{Seq: 1}))
+	require.NoError(t, d.CollectWithToken(ctx, map[string]interface{}{"topic": "a"}, api.WriteToken{Seq: 2, Index: 0}))
+	assert.Equal(t, []api.WriteToken{{Seq: 0}, {Seq: 1}, {Seq: 2}}, mockSink.getTokens())
+
+	// After restart, the writes are replayed and only the undelivered ones of each target are sent
+	mockSink = &mockIdempotentSink{}
+	d, err = newSinkDedup(ctx, mockSink, "sink1", "{{.topic}}")
+	require.NoError(t, err)
+	require.NoError(t, d.CollectWithToken(ctx, map[string]interface{}{"topic": "a"}, api.WriteToken{Seq: 0}))
+	require.NoError(t, d.CollectWithToken(ctx, []map[string]interface{}{{"topic": "b"}}, api.WriteToken{Seq: 1}))
+	require.NoError(t, d.CollectWithToken(ctx, map[string]interface{}{"topic": "a"}, api.WriteToken{Seq: 2, Index: 0}))
+	require.NoError(t, d.CollectWithToken(ctx, map[string]interface{}{"topic": "a"}, api.WriteToken{Seq: 2, Index: 1}))
+	require.NoError(t, d.CollectWithToken(ctx, map[string]interface{}{"topic": "b"}, api.WriteToken{Seq: 3}))
+	assert.Equal(t, []api.WriteToken{{Seq: 2, Index: 1}, {Seq: 3}}, mockSink.getTokens())
+
+	// The sink which does not support tokens is called by Collect
+	mockSink = &mockIdempotentSink{}
+	d, err = newSinkDedup(ctx, struct{ api.Sink }{mockSink}, "sink2", "")
+	require.NoError(t, err)
+	require.NoError(t, d.CollectWithToken(ctx, map[string]interface{}{"topic": "a"}, api.WriteToken{Seq: 5}))
+	require.NoError(t, d.CollectWithToken(ctx, map[string]interface{}{"topic": "b"}, api.WriteToken{Seq: 5}))
+	assert.Equal(t, []api.WriteToken{{Epoch: -1}}, mockSink.getTokens())
+}
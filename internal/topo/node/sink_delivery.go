@@ -0,0 +1,88 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+type deliveryMark struct {
+	checkpointId int64
+	// received is the count of the results received before the barrier
+	received int64
+}
+
+// deliveryTracker reports the latest checkpoint whose results before the barrier have all left the sink, by being
+// delivered or dropped, so that the sources only commit the offsets of the delivered data. The results are counted
+// when they are received and when they leave, so the results in the cache or being retried are not delivered.
+// All methods are called in the sink instance goroutine, so no lock is needed.
+type deliveryTracker struct {
+	notify func(checkpointId int64)
+	// inOrder is whether the results leave in the received order. If not, such as the failed results are moved to
+	// the resend queue, a checkpoint is delivered only when no result is outstanding.
+	inOrder  bool
+	received int64
+	done     int64
+	// dropped returns the count of the results dropped by the caches
+	dropped func() int64
+	marks   []deliveryMark
+}
+
+// newDeliveryTracker creates the tracker. The restored results of the caches are counted as received because they
+// leave before the new results.
+func newDeliveryTracker(notify func(checkpointId int64), inOrder bool, restored int, dropped func() int64) *deliveryTracker {
+	if dropped == nil {
+		dropped = func() int64 { return 0 }
+	}
+	return &deliveryTracker{
+		notify:   notify,
+		inOrder:  inOrder,
+		received: int64(restored),
+		dropped:  dropped,
+	}
+}
+
+// barrier is called when the barrier of the checkpoint arrives
+func (d *deliveryTracker) barrier(checkpointId int64) {
+	d.marks = append(d.marks, deliveryMark{checkpointId: checkpointId, received: d.received})
+	d.check()
+}
+
+// receive is called when a result is put into the buffer
+func (d *deliveryTracker) receive() {
+	d.received++
+}
+
+// deliver is called when a result leaves the sink for good, no matter it is sent or discarded for an unrecoverable
+// error
+func (d *deliveryTracker) deliver() {
+	d.done++
+	d.check()
+}
+
+func (d *deliveryTracker) check() {
+	done := d.done + d.dropped()
+	var (
+		last  int64
+		found bool
+	)
+	for len(d.marks) > 0 {
+		m := d.marks[0]
+		if done < m.received || (!d.inOrder && done < d.received) {
+			break
+		}
+		last, found = m.checkpointId, true
+		d.marks = d.marks[1:]
+	}
+	if found {
+		d.notify(last)
+	}
+}
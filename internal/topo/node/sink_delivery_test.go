@@ -0,0 +1,72 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeliveryTracker(t *testing.T) {
+	var notified []int64
+	notify := func(checkpointId int64) {
+		notified = append(notified, checkpointId)
+	}
+	// No result before the barrier
+	d := newDeliveryTracker(notify, true, 0, nil)
+	d.barrier(1)
+	assert.Equal(t, []int64{1}, notified)
+	// The results before the barrier are in the cache
+	d.receive()
+	d.receive()
+	d.barrier(2)
+	d.receive()
+	d.barrier(3)
+	assert.Equal(t, []int64{1}, notified)
+	d.deliver()
+	assert.Equal(t, []int64{1}, notified)
+	d.deliver()
+	assert.Equal(t, []int64{1, 2}, notified)
+	d.deliver()
+	assert.Equal(t, []int64{1, 2, 3}, notified)
+	assert.Empty(t, d.marks)
+
+	// The restored results leave first and the dropped ones are counted
+	notified = nil
+	var dropped int64
+	d = newDeliveryTracker(notify, true, 2, func() int64 { return dropped })
+	d.receive()
+	d.barrier(1)
+	d.deliver()
+	d.deliver()
+	assert.Empty(t, notified)
+	dropped = 1
+	d.receive()
+	d.deliver()
+	assert.Equal(t, []int64{1}, notified)
+
+	// Out of order, only delivered when nothing is outstanding
+	notified = nil
+	d = newDeliveryTracker(notify, false, 0, nil)
+	d.receive()
+	d.barrier(1)
+	d.receive()
+	d.deliver()
+	assert.Empty(t, notified)
+	d.barrier(2)
+	d.deliver()
+	assert.Equal(t, []int64{2}, notified)
+}
@@ -16,9 +16,12 @@ package node
 
 import (
 	"fmt"
+	"sync"
+	"sync/atomic"
 
 	"github.com/lf-edge/ekuiper/internal/binder/io"
 	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/topo/checkpoint"
 	"github.com/lf-edge/ekuiper/internal/topo/context"
 	"github.com/lf-edge/ekuiper/internal/topo/node/cache"
 	nodeConf "github.com/lf-edge/ekuiper/internal/topo/node/conf"
@@ -45,6 +48,14 @@ type SinkConf struct {
 	DataField      string   `json:"dataField"`
 	BatchSize      int      `json:"batchSize"`
 	LingerInterval int      `json:"lingerInterval"`
+	MaxPayloadSize int      `json:"maxPayloadSize"`
+	SplitField     string   `json:"splitField"`
+	// TapFile is the capture file to mirror the sent payloads for replaying later
+	TapFile string `json:"tapFile"`
+	// Dedup skips the writes which are delivered before the rule restarts by the write tokens
+	Dedup bool `json:"dedup"`
+	// DedupTarget is the template to resolve the target of a write whose delivery is recorded separately
+	DedupTarget string `json:"dedupTarget"`
 	conf.SinkConf
 }
 
@@ -56,9 +67,27 @@ type SinkNode struct {
 	options map[string]interface{}
 	isMock  bool
 	// states varies after restart
-	sink api.Sink
+	sink   api.Sink
+	tokens *tokenSink
+	// drained is closed when the drain signal is received and all pending results of all instances are sent
+	drainMu       sync.Mutex
+	drained       chan struct{}
+	drainReceived atomic.Bool
+	pending       atomic.Int64
+	// caches are the sink cache and resend queue if the cache is enabled, used for metrics
+	cacheMu sync.RWMutex
+	caches  []*cache.SyncCache
+	// deliveryNotify is set by the checkpoint coordinator if the sources commit the offsets after the delivery
+	deliveryNotify func(checkpointId int64)
+	delivery       *deliveryTracker
 }
 
+const (
+	SinkCacheLength       = "cache_length"
+	SinkCacheOldestAge    = "cache_oldest_age_ms"
+	SinkCacheDroppedTotal = "cache_dropped_total"
+)
+
 func NewSinkNode(name string, sinkType string, props map[string]interface{}) *SinkNode {
 	return &SinkNode{
 		defaultSinkNode: newDefaultSinkNode(name, propsToNodeOption(props)),
@@ -67,7 +96,7 @@ func NewSinkNode(name string, sinkType string, props map[string]interface{}) *Si
 	}
 }
 
-// NewSinkNodeWithSink Only for mock source, do not use it in production
+// NewSinkNodeWithSink creates the sink node with the sink instance, such as the mock sinks in test or the collector of a replay
 func NewSinkNodeWithSink(name string, sink api.Sink, props map[string]interface{}) *SinkNode {
 	return &SinkNode{
 		defaultSinkNode: newDefaultSinkNode(name, propsToNodeOption(props)),
@@ -81,6 +110,11 @@ func (m *SinkNode) Open(ctx api.StreamContext, result chan<- error) {
 	m.ctx = ctx
 	logger := ctx.GetLogger()
 	logger.Debugf("open sink node %s", m.name)
+	m.resetDrain()
+	m.drainHandler = func() {
+		m.drainReceived.Store(true)
+		m.checkDrained()
+	}
 	go func() {
 		err := infra.SafeRun(func() error {
 			sconf, err := ParseConf(logger, m.options)
@@ -120,6 +154,13 @@ func (m *SinkNode) Open(ctx api.StreamContext, result chan<- error) {
 							return err
 						}
 						logger.Debugf("Successfully get the sink %s", m.sinkType)
+						sink, err = m.wrapTokenSink(ctx, sink, sconf)
+						if err != nil {
+							return err
+						}
+						if sconf.TapFile != "" {
+							sink = newTapSink(sink, m.sinkType, m.options, sconf.TapFile)
+						}
 						m.sink = sink
 						logger.Debugf("Now is to open sink for rule %s.\n", ctx.GetRuleId())
 						if err := sink.Open(ctx); err != nil {
@@ -127,7 +168,10 @@ func (m *SinkNode) Open(ctx api.StreamContext, result chan<- error) {
 						}
 						logger.Debugf("Successfully open sink for rule %s.\n", ctx.GetRuleId())
 					} else {
-						sink = m.sink
+						sink, err = m.wrapTokenSink(ctx, m.sink, sconf)
+						if err != nil {
+							return err
+						}
 					}
 
 					m.statManager = metric.NewStatManager(ctx, "sink")
@@ -151,9 +195,51 @@ func (m *SinkNode) Open(ctx api.StreamContext, result chan<- error) {
 						c = cache.NewSyncCache(ctx, dataCh, result, &sconf.SinkConf, sconf.BufferLength)
 						if sconf.ResendAlterQueue {
 							resendCh = make(chan []map[string]interface{}, sconf.BufferLength)
-							rq = cache.NewSyncCache(ctx, resendCh, result, &sconf.SinkConf, sconf.BufferLength)
+							// The resend queue is fed by the sink itself, so it cannot block
+							rqConf := sconf.SinkConf
+							if rqConf.OverflowPolicy == conf.OverflowBlock {
+								rqConf.OverflowPolicy = conf.OverflowDropOldest
+							}
+							rq = cache.NewSyncCache(ctx, resendCh, result, &rqConf, sconf.BufferLength)
 						}
 						dataOutCh = c.Out
+						m.setCaches(c, rq)
+					}
+					if m.deliveryNotify != nil {
+						m.delivery = newDeliveryTracker(m.deliveryNotify, rq == nil, cachesRestored(c, rq), cachesDropped(c, rq))
+					}
+					// The spans of the buffered results in order. They are ended when the results are sent.
+					// The results from the cache may be read from the disk, so their spans end when they are cached.
+					var spans []*opSpan
+
+					var normalQ, resendQ func(data []map[string]interface{})
+					// enqueue sends the data to the cache. If the cache blocks the upstream when full, keep sending out
+					// the cached data while waiting; otherwise, drop the data if the buffer is full.
+					enqueue := func(outs []map[string]interface{}) bool {
+						if !sconf.EnableCache || sconf.OverflowPolicy != conf.OverflowBlock {
+							select {
+							case dataCh <- outs:
+								return true
+							default:
+								return false
+							}
+						}
+						var rqOut <-chan []map[string]interface{}
+						if rq != nil {
+							rqOut = rq.Out
+						}
+						for {
+							select {
+							case dataCh <- outs:
+								return true
+							case data := <-dataOutCh:
+								normalQ(data)
+							case data := <-rqOut:
+								resendQ(data)
+							case <-ctx.Done():
+								return false
+							}
+						}
 					}
 
 					receiveQ := func(data interface{}) {
@@ -168,10 +254,23 @@ func (m *SinkNode) Open(ctx api.StreamContext, result chan<- error) {
 							ctx.GetLogger().Debugf("receive empty in sink")
 							return
 						}
-						select {
-						case dataCh <- outs:
-						default:
+						span := startSpan(ctx, m.name, data)
+						if enqueue(outs) {
+							m.pending.Add(1)
+							if m.delivery != nil {
+								m.delivery.receive()
+							}
+							if m.tokens != nil {
+								m.tokens.received()
+							}
+							if sconf.EnableCache {
+								span.end(nil)
+							} else {
+								spans = append(spans, span)
+							}
+						} else {
 							ctx.GetLogger().Warnf("sink node %s instance %d buffer is full, drop data %v", m.name, instance, outs)
+							span.end(fmt.Errorf("buffer is full"))
 						}
 						if resendCh != nil {
 							select {
@@ -181,16 +280,40 @@ func (m *SinkNode) Open(ctx api.StreamContext, result chan<- error) {
 							}
 						}
 					}
-					normalQ := func(data []map[string]interface{}) {
+					normalQ = func(data []map[string]interface{}) {
 						m.statManager.ProcessTimeStart()
 						m.statManager.SetBufferLength(bufferLen(dataCh, dataOutCh, c, rq))
 						ctx.GetLogger().Debugf("sending data: %v", data)
+						if m.tokens != nil {
+							m.tokens.sending()
+						}
+						var span *opSpan
+						if len(spans) > 0 {
+							span = spans[0]
+							spans = spans[1:]
+						}
 						err := doCollectMaps(ctx, sink, sconf, data, m.statManager, false)
-						if sconf.EnableCache {
+						span.end(err)
+						if !sconf.EnableCache {
+							m.pending.Add(-1)
+							m.delivered()
+						} else {
 							ack := checkAck(ctx, data, err)
+							failed := failedPart(sconf, data, err)
+							// The failed result is sent again by the cache unless it is moved to the resend queue
+							if ack || sconf.ResendAlterQueue {
+								m.pending.Add(-1)
+							}
+							// The failed items are only retried by the cache without the resend queue
+							if ack && (failed == nil || sconf.ResendAlterQueue) {
+								m.delivered()
+							}
 							if sconf.ResendAlterQueue {
 								// If ack is false, add it to the resend queue
 								if !ack {
+									if failed != nil {
+										data = failed
+									}
 									select {
 									case resendCh <- data:
 									case <-ctx.Done():
@@ -202,6 +325,11 @@ func (m *SinkNode) Open(ctx api.StreamContext, result chan<- error) {
 									m.statManager.SetBufferLength(bufferLen(dataCh, dataOutCh, c, rq) - 1)
 								case <-ctx.Done():
 								}
+							} else if failed != nil {
+								select {
+								case c.Retry <- failed:
+								case <-ctx.Done():
+								}
 							} else {
 								select {
 								case c.Ack <- ack:
@@ -213,9 +341,10 @@ func (m *SinkNode) Open(ctx api.StreamContext, result chan<- error) {
 							}
 						}
 						m.statManager.ProcessTimeEnd()
+						m.checkDrained()
 					}
 
-					resendQ := func(data []map[string]interface{}) {
+					resendQ = func(data []map[string]interface{}) {
 						ctx.GetLogger().Debugf("resend data: %v", data)
 						m.statManager.SetBufferLength(bufferLen(dataCh, dataOutCh, c, rq))
 						if sconf.ResendIndicatorField != "" {
@@ -225,10 +354,18 @@ func (m *SinkNode) Open(ctx api.StreamContext, result chan<- error) {
 						}
 						err := doCollectMaps(ctx, sink, sconf, data, m.statManager, true)
 						ack := checkAck(ctx, data, err)
+						if failed := failedPart(sconf, data, err); failed != nil {
+							select {
+							case rq.Retry <- failed:
+							case <-ctx.Done():
+							}
+							return
+						}
 						select {
 						case rq.Ack <- ack:
 							if ack {
 								m.statManager.SetBufferLength(bufferLen(dataCh, dataOutCh, c, rq) - 1)
+								m.delivered()
 							}
 						case <-ctx.Done():
 						}
@@ -326,6 +463,80 @@ func (m *SinkNode) Open(ctx api.StreamContext, result chan<- error) {
 	}()
 }
 
+// SetDeliveryNotifier is called by the checkpoint coordinator to be notified of the latest checkpoint whose results
+// before the barrier have left the sink
+func (m *SinkNode) SetDeliveryNotifier(notify func(checkpointId int64)) {
+	m.deliveryNotify = notify
+}
+
+// delivered is called when a result leaves the sink
+func (m *SinkNode) delivered() {
+	if m.delivery != nil {
+		m.delivery.deliver()
+	}
+}
+
+func cachesRestored(caches ...*cache.SyncCache) int {
+	n := 0
+	for _, c := range caches {
+		if c != nil {
+			n += c.Restored
+		}
+	}
+	return n
+}
+
+func cachesDropped(caches ...*cache.SyncCache) func() int64 {
+	return func() int64 {
+		var n int64
+		for _, c := range caches {
+			if c != nil {
+				n += c.Dropped()
+			}
+		}
+		return n
+	}
+}
+
+func (m *SinkNode) setCaches(caches ...*cache.SyncCache) {
+	m.cacheMu.Lock()
+	defer m.cacheMu.Unlock()
+	m.caches = m.caches[:0]
+	for _, c := range caches {
+		if c != nil {
+			m.caches = append(m.caches, c)
+		}
+	}
+}
+
+// GetExtraMetrics returns the count of the cached items, the age of the oldest one and the count of the dropped items
+// if the cache is enabled
+func (m *SinkNode) GetExtraMetrics() ([]string, []any) {
+	m.cacheMu.RLock()
+	defer m.cacheMu.RUnlock()
+	if len(m.caches) == 0 {
+		return nil, nil
+	}
+	var (
+		length  int64
+		oldest  int64
+		dropped int64
+	)
+	for _, c := range m.caches {
+		l, ts := c.Stats()
+		length += l
+		dropped += c.Dropped()
+		if ts > 0 && (oldest == 0 || ts < oldest) {
+			oldest = ts
+		}
+	}
+	var age int64
+	if oldest > 0 {
+		age = conf.GetNowInMilli() - oldest
+	}
+	return []string{SinkCacheLength, SinkCacheOldestAge, SinkCacheDroppedTotal}, []any{length, age, dropped}
+}
+
 func bufferLen(dataCh chan []map[string]interface{}, dataOutCh <-chan []map[string]interface{}, c *cache.SyncCache, rq *cache.SyncCache) int64 {
 	l := len(dataCh)
 	if dataCh != dataOutCh {
@@ -340,6 +551,25 @@ func bufferLen(dataCh chan []map[string]interface{}, dataOutCh <-chan []map[stri
 	return int64(l)
 }
 
+// failedPart returns the failed items of a batch if the sink reports that only some items fail
+func failedPart(sconf *SinkConf, data []map[string]interface{}, err error) []map[string]interface{} {
+	// the indexes are relative to the sent batch which is not the whole data when sending singly or in splits
+	if err == nil || sconf.SendSingle || sconf.MaxPayloadSize > 0 {
+		return nil
+	}
+	indexes, ok := errorx.GetFailedItems(err)
+	if !ok {
+		return nil
+	}
+	var result []map[string]interface{}
+	for _, i := range indexes {
+		if i >= 0 && i < len(data) {
+			result = append(result, data[i])
+		}
+	}
+	return result
+}
+
 func checkAck(ctx api.StreamContext, data interface{}, err error) bool {
 	if err != nil {
 		if errorx.IsIOError(err) { // do not log to prevent a lot of logs!
@@ -372,7 +602,7 @@ func ParseConf(logger api.Logger, props map[string]any) (*SinkConf, error) {
 	}
 	if sconf.Format == "" {
 		sconf.Format = "json"
-	} else if sconf.Format != message.FormatJson && sconf.Format != message.FormatProtobuf && sconf.Format != message.FormatBinary && sconf.Format != message.FormatCustom && sconf.Format != message.FormatDelimited {
+	} else if sconf.Format != message.FormatJson && sconf.Format != message.FormatFastJson && sconf.Format != message.FormatProtobuf && sconf.Format != message.FormatBinary && sconf.Format != message.FormatCustom && sconf.Format != message.FormatDelimited {
 		logger.Warnf("invalid type for format property, should be json protobuf or binary but found %s", sconf.Format)
 		sconf.Format = "json"
 	}
@@ -391,6 +621,9 @@ func ParseConf(logger api.Logger, props map[string]any) (*SinkConf, error) {
 	if sconf.LingerInterval < 0 {
 		return nil, fmt.Errorf("invalid lingerInterval %d", sconf.LingerInterval)
 	}
+	if sconf.MaxPayloadSize < 0 {
+		return nil, fmt.Errorf("invalid maxPayloadSize %d", sconf.MaxPayloadSize)
+	}
 	err = sconf.SinkConf.Validate()
 	if err != nil {
 		return nil, fmt.Errorf("invalid cache properties: %v", err)
@@ -402,11 +635,51 @@ func (m *SinkNode) reset() {
 	if !m.isMock {
 		m.sink = nil
 	}
+	m.tokens = nil
+	m.delivery = nil
 	m.statManager = nil
 }
 
+// wrapTokenSink wraps the idempotent sink to generate the write tokens. If dedup is enabled, the sink is wrapped
+// to skip the delivered writes by the tokens first. The tokens are only meaningful when the results after a checkpoint
+// can be replayed in the same order, so the qos must be at least once. The cache is not supported because the cached
+// results may be resent out of order.
+func (m *SinkNode) wrapTokenSink(ctx api.StreamContext, sink api.Sink, sconf *SinkConf) (api.Sink, error) {
+	_, ok := sink.(api.IdempotentSink)
+	if !ok && !sconf.Dedup {
+		return sink, nil
+	}
+	if m.qos < api.AtLeastOnce {
+		if sconf.Dedup {
+			ctx.GetLogger().Warnf("sink node %s does not deduplicate because the qos is at most once", m.name)
+		}
+		return sink, nil
+	}
+	if sconf.EnableCache {
+		ctx.GetLogger().Warnf("sink node %s does not send write tokens because the cache is enabled", m.name)
+		return sink, nil
+	}
+	if sconf.Dedup {
+		d, err := newSinkDedup(ctx, sink, m.name, sconf.DedupTarget)
+		if err != nil {
+			return nil, fmt.Errorf("fail to restore the delivered writes: %v", err)
+		}
+		sink = d
+	}
+	t, err := newTokenSink(ctx, sink.(api.IdempotentSink))
+	if err != nil {
+		return nil, fmt.Errorf("fail to restore the write sequence: %v", err)
+	}
+	ctx.GetLogger().Infof("sink node %s sends write tokens from sequence %d", m.name, t.seq)
+	m.tokens = t
+	return t, nil
+}
+
 func doCollectMaps(ctx api.StreamContext, sink api.Sink, sconf *SinkConf, outs []map[string]interface{}, stats metric.StatManager, isResend bool) error {
 	if !sconf.SendSingle {
+		if sconf.MaxPayloadSize > 0 {
+			return doCollectSplits(ctx, sink, sconf, outs, stats, isResend)
+		}
 		return doCollectData(ctx, sink, outs, stats, isResend)
 	} else {
 		var err error
@@ -517,7 +790,10 @@ func getSink(name string, action map[string]interface{}) (api.Sink, error) {
 	)
 	s, err = io.Sink(name)
 	if s != nil {
-		newAction := nodeConf.GetSinkConf(name, action)
+		newAction, err := nodeConf.GetSinkConf(name, action)
+		if err != nil {
+			return nil, err
+		}
 		err = s.Configure(newAction)
 		if err != nil {
 			return nil, err
@@ -537,7 +813,16 @@ func (m *SinkNode) AddOutput(_ chan<- interface{}, name string) error {
 	return fmt.Errorf("fail to add output %s, sink %s cannot add output", name, m.name)
 }
 
-// Broadcast Override defaultNode
-func (m *SinkNode) Broadcast(_ interface{}) {
-	// do nothing, may be called by checkpoint
+// Broadcast Override defaultNode. It is called by the checkpoint responder before taking the snapshot.
+func (m *SinkNode) Broadcast(val interface{}) {
+	b, ok := val.(*checkpoint.Barrier)
+	if !ok {
+		return
+	}
+	if m.tokens != nil {
+		m.tokens.checkpoint(m.ctx, b.CheckpointId)
+	}
+	if m.delivery != nil {
+		m.delivery.barrier(b.CheckpointId)
+	}
 }
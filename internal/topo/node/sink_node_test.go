@@ -34,6 +34,7 @@ import (
 	"github.com/lf-edge/ekuiper/internal/topo/topotest/mocknode"
 	"github.com/lf-edge/ekuiper/internal/topo/transform"
 	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/errorx"
 )
 
 func init() {
@@ -234,6 +235,68 @@ func TestOmitEmpty_Apply(t *testing.T) {
 	}
 }
 
+func TestSinkSplit(t *testing.T) {
+	conf.InitConf()
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		data   []map[string]interface{}
+		result [][]byte
+	}{
+		{
+			name:   "no split",
+			config: map[string]interface{}{"maxPayloadSize": 65},
+			data:   []map[string]interface{}{{"ab": "hello1"}, {"ab": "hello2"}, {"ab": "hello3"}, {"ab": "hello4"}},
+			result: [][]byte{[]byte(`[{"ab":"hello1"},{"ab":"hello2"},{"ab":"hello3"},{"ab":"hello4"}]`)},
+		},
+		{
+			name:   "split into halves",
+			config: map[string]interface{}{"maxPayloadSize": 40},
+			data:   []map[string]interface{}{{"ab": "hello1"}, {"ab": "hello2"}, {"ab": "hello3"}, {"ab": "hello4"}},
+			result: [][]byte{[]byte(`[{"ab":"hello1"},{"ab":"hello2"}]`), []byte(`[{"ab":"hello3"},{"ab":"hello4"}]`)},
+		},
+		{
+			name:   "drop oversized message",
+			config: map[string]interface{}{"maxPayloadSize": 20},
+			data:   []map[string]interface{}{{"ab": "hello1"}, {"ab": "hello world"}, {"ab": "hello3"}},
+			result: [][]byte{[]byte(`[{"ab":"hello1"}]`), []byte(`[{"ab":"hello3"}]`)},
+		},
+		{
+			name:   "split metadata",
+			config: map[string]interface{}{"maxPayloadSize": 60, "splitField": "split"},
+			data:   []map[string]interface{}{{"ab": "hello1"}, {"ab": "hello2"}},
+			result: [][]byte{
+				[]byte(`[{"ab":"hello1","split":{"index":0,"total":2}}]`),
+				[]byte(`[{"ab":"hello2","split":{"index":1,"total":2}}]`),
+			},
+		},
+		{
+			name:   "send single is not split",
+			config: map[string]interface{}{"maxPayloadSize": 10, "sendSingle": true},
+			data:   []map[string]interface{}{{"ab": "hello1"}},
+			result: [][]byte{[]byte(`{"ab":"hello1"}`)},
+		},
+	}
+	contextLogger := conf.Log.WithField("rule", "TestSinkSplit")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSink := mocknode.NewMockSink()
+			s := NewSinkNodeWithSink("mockSink", mockSink, tt.config)
+			s.Open(ctx, make(chan error))
+			s.input <- tt.data
+			time.Sleep(100 * time.Millisecond)
+			assert.Equal(t, tt.result, mockSink.GetResults())
+			// The input tuples may be shared by other sinks and must not be modified
+			for _, item := range tt.data {
+				assert.NotContains(t, item, "split")
+			}
+		})
+	}
+	_, err := ParseConf(contextLogger, map[string]interface{}{"maxPayloadSize": -1})
+	assert.EqualError(t, err, "invalid maxPayloadSize -1")
+}
+
 func TestFormat_Apply(t *testing.T) {
 	conf.InitConf()
 	etcDir, err := conf.GetDataLoc()
@@ -731,3 +794,21 @@ func TestSinkCache(t *testing.T) {
 		})
 	}
 }
+
+func TestFailedPart(t *testing.T) {
+	data := []map[string]interface{}{{"a": 1}, {"a": 2}, {"a": 3}}
+	tests := []struct {
+		sconf *SinkConf
+		err   error
+		exp   []map[string]interface{}
+	}{
+		{sconf: &SinkConf{}, err: nil, exp: nil},
+		{sconf: &SinkConf{}, err: errorx.NewIOErr("io error"), exp: nil},
+		{sconf: &SinkConf{}, err: errorx.NewPartialIOErr("partial", []int{0, 2, 5}), exp: []map[string]interface{}{{"a": 1}, {"a": 3}}},
+		{sconf: &SinkConf{SendSingle: true}, err: errorx.NewPartialIOErr("partial", []int{0}), exp: nil},
+		{sconf: &SinkConf{MaxPayloadSize: 100}, err: errorx.NewPartialIOErr("partial", []int{0}), exp: nil},
+	}
+	for i, tt := range tests {
+		assert.Equal(t, tt.exp, failedPart(tt.sconf, data, tt.err), "case %d", i)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/lf-edge/ekuiper/internal/topo/node/metric"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+// doCollectSplits splits the batch into several messages whose encoded payload does not exceed the maxPayloadSize.
+// A single message which exceeds the limit by itself is dropped with an error, the other splits are still sent.
+func doCollectSplits(ctx api.StreamContext, sink api.Sink, sconf *SinkConf, outs []map[string]interface{}, stats metric.StatManager, isResend bool) error {
+	if sconf.SplitField != "" {
+		// The tuples may be shared with the other sinks, so write the split metadata to the copies. Measure with the
+		// largest possible split metadata so that the final payload is still within the limit
+		placeholder := splitMeta(len(outs), len(outs))
+		copies := make([]map[string]interface{}, len(outs))
+		for i, item := range outs {
+			copies[i] = maps.Clone(item)
+			copies[i][sconf.SplitField] = placeholder
+		}
+		outs = copies
+	}
+	splits, errs := splitBatch(ctx, outs, sconf.MaxPayloadSize)
+	var err error
+	for _, e := range errs {
+		stats.IncTotalExceptions(e.Error())
+		err = e
+	}
+	if len(splits) > 1 {
+		ctx.GetLogger().Debugf("sink node %s splits the batch of %d messages into %d splits", ctx.GetOpId(), len(outs), len(splits))
+	}
+	for i, split := range splits {
+		if sconf.SplitField != "" {
+			meta := splitMeta(i, len(splits))
+			for _, item := range split {
+				item[sconf.SplitField] = meta
+			}
+		}
+		newErr := doCollectData(ctx, sink, split, stats, isResend)
+		if newErr != nil {
+			err = newErr
+		}
+	}
+	return err
+}
+
+func splitMeta(index, total int) map[string]interface{} {
+	return map[string]interface{}{
+		"index": index,
+		"total": total,
+	}
+}
+
+// splitBatch halves the batch until the encoded payload of each split fits the limit
+func splitBatch(ctx api.StreamContext, outs []map[string]interface{}, limit int) ([][]map[string]interface{}, []error) {
+	if len(outs) == 0 {
+		return nil, nil
+	}
+	payload, _, err := ctx.TransformOutput(outs)
+	if err != nil {
+		return nil, []error{err}
+	}
+	if len(payload) <= limit {
+		return [][]map[string]interface{}{outs}, nil
+	}
+	if len(outs) == 1 {
+		return nil, []error{fmt.Errorf("the encoded payload of a single message is %d bytes which exceeds the maxPayloadSize %d", len(payload), limit)}
+	}
+	mid := len(outs) / 2
+	left, errs := splitBatch(ctx, outs[:mid], limit)
+	right, rerrs := splitBatch(ctx, outs[mid:], limit)
+	return append(left, right...), append(errs, rerrs...)
+}
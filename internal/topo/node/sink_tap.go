@@ -0,0 +1,274 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/topo/transform"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+// CaptureRecord is a line of the capture file written by the sink tap
+type CaptureRecord struct {
+	Timestamp int64  `json:"timestamp"`
+	RuleId    string `json:"ruleId"`
+	SinkType  string `json:"sinkType"`
+	// Props are the resolved dynamic properties such as the topic or the headers
+	Props map[string]any `json:"props,omitempty"`
+	// Data is the data sent to the sink which is used to resolve the dynamic properties when replaying
+	Data any `json:"data"`
+	// Payload is the encoded payload
+	Payload []byte `json:"payload"`
+}
+
+// tapSink mirrors the successfully sent data of the wrapped sink to a capture file
+type tapSink struct {
+	api.Sink
+	sinkType string
+	props    map[string]any
+	file     string
+
+	mu sync.Mutex
+	f  *os.File
+	w  *bufio.Writer
+}
+
+func newTapSink(sink api.Sink, sinkType string, props map[string]any, file string) *tapSink {
+	return &tapSink{
+		Sink:     sink,
+		sinkType: sinkType,
+		props:    props,
+		file:     file,
+	}
+}
+
+func (t *tapSink) Open(ctx api.StreamContext) error {
+	if err := t.Sink.Open(ctx); err != nil {
+		return err
+	}
+	fp, err := capturePath(t.file)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fp), os.ModePerm); err != nil {
+		return fmt.Errorf("fail to create the directory of tap file %s: %v", fp, err)
+	}
+	f, err := os.OpenFile(fp, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("fail to open tap file %s: %v", fp, err)
+	}
+	t.f = f
+	t.w = bufio.NewWriter(f)
+	ctx.GetLogger().Infof("tap sink %s traffic to %s", t.sinkType, fp)
+	return nil
+}
+
+func (t *tapSink) Collect(ctx api.StreamContext, data interface{}) error {
+	err := t.Sink.Collect(ctx, data)
+	if err == nil {
+		t.capture(ctx, data)
+	}
+	return err
+}
+
+func (t *tapSink) CollectResend(ctx api.StreamContext, data interface{}) error {
+	var err error
+	if rs, ok := t.Sink.(api.ResendSink); ok {
+		err = rs.CollectResend(ctx, data)
+	} else {
+		err = t.Sink.Collect(ctx, data)
+	}
+	if err == nil {
+		t.capture(ctx, data)
+	}
+	return err
+}
+
+func (t *tapSink) Close(ctx api.StreamContext) error {
+	err := t.Sink.Close(ctx)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.f != nil {
+		if e := t.w.Flush(); e != nil {
+			ctx.GetLogger().Warnf("fail to flush tap file: %v", e)
+		}
+		if e := t.f.Close(); e != nil {
+			ctx.GetLogger().Warnf("fail to close tap file: %v", e)
+		}
+		t.f = nil
+	}
+	return err
+}
+
+// capture writes the record of the sent data. The failure of capturing does not affect the sink.
+func (t *tapSink) capture(ctx api.StreamContext, data interface{}) {
+	payload, _, err := ctx.TransformOutput(data)
+	if err != nil {
+		ctx.GetLogger().Warnf("tap sink fails to encode the payload: %v", err)
+		return
+	}
+	r := &CaptureRecord{
+		Timestamp: conf.GetNowInMilli(),
+		RuleId:    ctx.GetRuleId(),
+		SinkType:  t.sinkType,
+		Props:     resolveDynamicProps(ctx, t.props, data),
+		Data:      data,
+		Payload:   payload,
+	}
+	line, err := json.Marshal(r)
+	if err != nil {
+		ctx.GetLogger().Warnf("tap sink fails to encode the capture record: %v", err)
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.f == nil {
+		return
+	}
+	if _, err := t.w.Write(append(line, '\n')); err != nil {
+		ctx.GetLogger().Warnf("tap sink fails to write the capture record: %v", err)
+		return
+	}
+	if err := t.w.Flush(); err != nil {
+		ctx.GetLogger().Warnf("tap sink fails to flush the capture record: %v", err)
+	}
+}
+
+// resolveDynamicProps resolves the properties with data template. The static properties are not recorded as they may contain credentials.
+func resolveDynamicProps(ctx api.StreamContext, props map[string]any, data any) map[string]any {
+	var result map[string]any
+	for k, v := range props {
+		switch vt := v.(type) {
+		case string:
+			if strings.Contains(vt, "{{") {
+				if s, err := ctx.ParseTemplate(vt, data); err == nil {
+					if result == nil {
+						result = make(map[string]any)
+					}
+					result[k] = s
+				}
+			}
+		case map[string]any:
+			if sub := resolveDynamicProps(ctx, vt, data); sub != nil {
+				if result == nil {
+					result = make(map[string]any)
+				}
+				result[k] = sub
+			}
+		}
+	}
+	return result
+}
+
+// capturePath resolves the relative capture file path to the data directory
+func capturePath(file string) (string, error) {
+	if filepath.IsAbs(file) {
+		return file, nil
+	}
+	dataDir, err := conf.GetDataLoc()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, file), nil
+}
+
+// ReplayCapture resends the recorded payloads of a capture file to the sink of the sinkType configured by props.
+// The interval is the milliseconds to wait between two records. Return the count of the sent records.
+func ReplayCapture(ctx api.StreamContext, sinkType string, props map[string]any, file string, interval int) (int, error) {
+	fp, err := capturePath(file)
+	if err != nil {
+		return 0, err
+	}
+	f, err := os.Open(fp)
+	if err != nil {
+		return 0, fmt.Errorf("fail to open capture file %s: %v", fp, err)
+	}
+	defer f.Close()
+	sink, err := getSink(sinkType, props)
+	if err != nil {
+		return 0, err
+	}
+	dctx, ok := ctx.(*context.DefaultContext)
+	if !ok {
+		return 0, fmt.Errorf("invalid context")
+	}
+	if err := sink.Open(dctx); err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = sink.Close(dctx)
+	}()
+	count := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		r := &CaptureRecord{}
+		if err := json.Unmarshal(line, r); err != nil {
+			return count, fmt.Errorf("invalid capture record %d: %v", count+1, err)
+		}
+		if interval > 0 && count > 0 {
+			select {
+			case <-ctx.Done():
+				return count, ctx.Err()
+			case <-time.After(time.Duration(interval) * time.Millisecond):
+			}
+		}
+		payload := r.Payload
+		// Send the recorded payload as is instead of encoding the data again
+		var tf transform.TransFunc = func(_ interface{}) ([]byte, bool, error) {
+			return payload, true, nil
+		}
+		rctx := context.WithValue(dctx, context.TransKey, tf)
+		if err := sink.Collect(rctx, toSinkData(r.Data)); err != nil {
+			return count, fmt.Errorf("fail to replay capture record %d: %v", count+1, err)
+		}
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		return count, fmt.Errorf("fail to read capture file %s: %v", fp, err)
+	}
+	return count, nil
+}
+
+// toSinkData converts the decoded array of objects back to the type sent by the sink node
+func toSinkData(data any) any {
+	arr, ok := data.([]any)
+	if !ok {
+		return data
+	}
+	result := make([]map[string]any, 0, len(arr))
+	for _, v := range arr {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return data
+		}
+		result = append(result, m)
+	}
+	return result
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/topo/topotest/mocknode"
+	"github.com/lf-edge/ekuiper/internal/topo/transform"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/modules"
+)
+
+func TestTapAndReplay(t *testing.T) {
+	conf.InitConf()
+	tapFile := filepath.Join(t.TempDir(), "capture.jsonl")
+	props := map[string]any{
+		"topic":    "devices/{{.id}}",
+		"password": "secret",
+		"headers":  map[string]any{"device": "{{.id}}", "static": "v"},
+	}
+	tf, err := transform.GenTransform(`{"device":{{.id}},"temp":{{.temperature}}}`, "json", "", "", "", nil)
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), context.TransKey, tf)
+
+	mockSink := mocknode.NewMockSink()
+	s := newTapSink(mockSink, "mock", props, tapFile)
+	require.NoError(t, s.Open(ctx))
+	require.NoError(t, s.Collect(ctx, map[string]any{"id": 1, "temperature": 20.5}))
+	require.NoError(t, s.CollectResend(ctx, map[string]any{"id": 2, "temperature": 21}))
+	require.NoError(t, s.Close(ctx))
+	expected := [][]byte{[]byte(`{"device":1,"temp":20.5}`), []byte(`{"device":2,"temp":21}`)}
+	assert.Equal(t, expected, mockSink.GetResults())
+
+	f, err := os.Open(tapFile)
+	require.NoError(t, err)
+	var records []*CaptureRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		r := &CaptureRecord{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), r))
+		records = append(records, r)
+	}
+	require.NoError(t, f.Close())
+	require.Len(t, records, 2)
+	assert.Equal(t, "mock", records[0].SinkType)
+	assert.Equal(t, expected[0], records[0].Payload)
+	assert.Equal(t, map[string]any{"topic": "devices/1", "headers": map[string]any{"device": "1"}}, records[0].Props)
+	assert.Equal(t, expected[1], records[1].Payload)
+
+	// Replay to a sink with a different data template, the recorded payloads are sent as is
+	replaySink := mocknode.NewMockSink()
+	modules.RegisterSink("tapReplayMock", func() api.Sink { return replaySink })
+	n, err := ReplayCapture(context.Background(), "tapReplayMock", map[string]any{}, tapFile, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, expected, replaySink.GetResults())
+
+	assert.Equal(t, []map[string]any{{"id": 1}}, toSinkData([]any{map[string]any{"id": 1}}))
+	assert.Equal(t, []any{1}, toSinkData([]any{1}))
+
+	_, err = ReplayCapture(context.Background(), "tapReplayMock", map[string]any{}, filepath.Join(t.TempDir(), "none.jsonl"), 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,94 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+const (
+	writeEpochKey = "$$writeEpoch"
+	writeSeqKey   = "$$writeSeq"
+)
+
+type pendingResult struct {
+	epoch int64
+	seq   int64
+}
+
+// tokenSink calls the wrapped idempotent sink with a write token for each write.
+// The token of a result is decided when it is received, so the results before and after a checkpoint barrier
+// are distinguished even if they are still buffered when the barrier arrives.
+// All methods are called in the sink instance goroutine, so no lock is needed.
+type tokenSink struct {
+	api.IdempotentSink
+	// epoch is the id of the last checkpoint and seq is the sequence of the next received result
+	epoch int64
+	seq   int64
+	// the buffered results in order
+	pending []pendingResult
+	// the result being sent and the index of the next write of it
+	current pendingResult
+	index   int
+}
+
+func newTokenSink(ctx api.StreamContext, sink api.IdempotentSink) (*tokenSink, error) {
+	t := &tokenSink{IdempotentSink: sink}
+	// Restore the sequence of the checkpoint so that the replayed results get the same tokens
+	for k, p := range map[string]*int64{writeEpochKey: &t.epoch, writeSeqKey: &t.seq} {
+		v, err := ctx.GetState(k)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			*p, err = cast.ToInt64(v, cast.CONVERT_SAMEKIND)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return t, nil
+}
+
+// checkpoint starts a new epoch. It must be called before the snapshot of the checkpoint.
+func (t *tokenSink) checkpoint(ctx api.StreamContext, checkpointId int64) {
+	t.epoch = checkpointId
+	_ = ctx.PutState(writeEpochKey, checkpointId)
+	_ = ctx.PutState(writeSeqKey, t.seq)
+}
+
+// received is called when a result is put into the buffer
+func (t *tokenSink) received() {
+	t.pending = append(t.pending, pendingResult{epoch: t.epoch, seq: t.seq})
+	t.seq++
+}
+
+// sending is called when a result is taken out of the buffer to send
+func (t *tokenSink) sending() {
+	if len(t.pending) == 0 {
+		return
+	}
+	t.current = t.pending[0]
+	t.pending = t.pending[1:]
+	t.index = 0
+}
+
+// Collect may be called several times for a result if it is sent by rows or split
+func (t *tokenSink) Collect(ctx api.StreamContext, data interface{}) error {
+	token := api.WriteToken{Epoch: t.current.epoch, Seq: t.current.seq, Index: t.index}
+	t.index++
+	return t.IdempotentSink.CollectWithToken(ctx, data, token)
+}
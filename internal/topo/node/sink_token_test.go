@@ -0,0 +1,137 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/topo/checkpoint"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/topo/state"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+type mockIdempotentSink struct {
+	sync.Mutex
+	tokens []api.WriteToken
+}
+
+func (m *mockIdempotentSink) Open(_ api.StreamContext) error {
+	return nil
+}
+
+func (m *mockIdempotentSink) Configure(_ map[string]interface{}) error {
+	return nil
+}
+
+func (m *mockIdempotentSink) Collect(_ api.StreamContext, _ interface{}) error {
+	m.Lock()
+	defer m.Unlock()
+	// Use a negative epoch to indicate the data is sent without a token
+	m.tokens = append(m.tokens, api.WriteToken{Epoch: -1})
+	return nil
+}
+
+func (m *mockIdempotentSink) CollectWithToken(_ api.StreamContext, _ interface{}, token api.WriteToken) error {
+	m.Lock()
+	defer m.Unlock()
+	m.tokens = append(m.tokens, token)
+	return nil
+}
+
+func (m *mockIdempotentSink) Close(_ api.StreamContext) error {
+	return nil
+}
+
+func (m *mockIdempotentSink) getTokens() []api.WriteToken {
+	m.Lock()
+	defer m.Unlock()
+	return m.tokens
+}
+
+func TestSinkWriteToken(t *testing.T) {
+	conf.InitConf()
+	contextLogger := conf.Log.WithField("rule", "TestSinkWriteToken")
+	tests := []struct {
+		name   string
+		qos    api.Qos
+		seq    interface{}
+		result []api.WriteToken
+		state  int64
+	}{
+		{
+			name: "new rule",
+			qos:  api.AtLeastOnce,
+			result: []api.WriteToken{
+				{Epoch: 0, Seq: 0, Index: 0}, {Epoch: 0, Seq: 0, Index: 1}, {Epoch: 0, Seq: 1}, {Epoch: 100, Seq: 2},
+			},
+			state: 2,
+		},
+		{
+			name: "restored rule",
+			qos:  api.ExactlyOnce,
+			seq:  int64(10),
+			result: []api.WriteToken{
+				{Epoch: 50, Seq: 10, Index: 0}, {Epoch: 50, Seq: 10, Index: 1}, {Epoch: 50, Seq: 11}, {Epoch: 100, Seq: 12},
+			},
+			state: 12,
+		},
+		{
+			name: "qos at most once",
+			qos:  api.AtMostOnce,
+			result: []api.WriteToken{
+				{Epoch: -1}, {Epoch: -1}, {Epoch: -1}, {Epoch: -1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cctx, cancel := context.WithValue(context.Background(), context.LoggerKey, contextLogger).WithCancel()
+			defer cancel()
+			ctx := cctx.WithMeta("TestSinkWriteToken", "sink", &state.MemoryStore{})
+			if tt.seq != nil {
+				require.NoError(t, ctx.PutState(writeEpochKey, int64(50)))
+				require.NoError(t, ctx.PutState(writeSeqKey, tt.seq))
+			}
+			mockSink := &mockIdempotentSink{}
+			s := NewSinkNodeWithSink("sink", mockSink, map[string]interface{}{"sendSingle": true})
+			s.SetQos(tt.qos)
+			s.SetBarrierHandler(checkpoint.NewBarrierTracker(checkpoint.NewResponderExecutor(make(chan *checkpoint.Signal, 10), s), 1))
+			s.Open(ctx, make(chan error))
+			s.input <- []map[string]interface{}{{"a": 1}, {"a": 2}}
+			s.input <- []map[string]interface{}{{"a": 3}}
+			if tt.qos >= api.AtLeastOnce {
+				s.input <- &checkpoint.BufferOrEvent{Data: &checkpoint.Barrier{CheckpointId: 100, OpId: "test"}, Channel: "test"}
+			}
+			s.input <- []map[string]interface{}{{"a": 4}}
+			time.Sleep(100 * time.Millisecond)
+			assert.Equal(t, tt.result, mockSink.getTokens())
+			if tt.qos >= api.AtLeastOnce {
+				epoch, err := ctx.GetState(writeEpochKey)
+				require.NoError(t, err)
+				assert.Equal(t, int64(100), epoch)
+				seq, err := ctx.GetState(writeSeqKey)
+				require.NoError(t, err)
+				assert.Equal(t, tt.state, seq)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2021-2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+// SourceBound makes a source node read a bounded stream, such as the history data in a backfill.
+// The stream ends when the source finishes reading, and only the tuples whose event time is in [Start, End) are sent.
+type SourceBound struct {
+	// Start and End are the event time range in milliseconds. 0 means no bound.
+	Start int64
+	End   int64
+	// OnEnd is called after the end of the stream is sent to the downstream
+	OnEnd func()
+	// ReadOnce asks the source to read its data once instead of polling or watching, such as a rule running once.
+	// The source receives it as the bounded property.
+	ReadOnce bool
+	// the max event time of the sent tuples
+	maxTs int64
+}
+
+// SetBound is only supported by the source which is not shared, because the end of the stream is decided by
+// the source instance of the rule.
+func (m *SourceNode) SetBound(b *SourceBound) {
+	m.bound = b
+}
+
+func (m *SourceNode) GetBound() *SourceBound {
+	return m.bound
+}
+
+// accept checks if the tuple is in the range and records its event time
+func (b *SourceBound) accept(val interface{}) bool {
+	t, ok := val.(*xsql.Tuple)
+	if !ok {
+		return true
+	}
+	if (b.Start > 0 && t.Timestamp < b.Start) || (b.End > 0 && t.Timestamp >= b.End) {
+		return false
+	}
+	if t.Timestamp > b.maxTs {
+		b.maxTs = t.Timestamp
+	}
+	return true
+}
+
+// watermark is the event time until which the stream is complete
+func (b *SourceBound) watermark() int64 {
+	if b.End > 0 {
+		return b.End
+	}
+	return b.maxTs + 1
+}
+
+// sourceEndTuple is put into the buffer of the source instance after the bounded source finishes reading,
+// so that it is received after all the data.
+type sourceEndTuple struct {
+	*api.DefaultSourceTuple
+}
+
+// endOfStream is sent by a bounded source node after all its data. The watermark operator advances the watermark of
+// the stream to the end, so the pending events and windows are emitted.
+type endOfStream struct {
+	emitter   string
+	watermark int64
+}
+
+func (m *SourceNode) end() {
+	wm := m.bound.watermark()
+	m.ctx.GetLogger().Infof("bounded source %s ends with watermark %d", m.name, wm)
+	if m.isEventTime {
+		m.Broadcast(&endOfStream{emitter: m.name, watermark: wm})
+	}
+	if m.bound.OnEnd != nil {
+		m.bound.OnEnd()
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"sync"
+
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+// maxPendingOffsets is the max count of the recorded offsets waiting for the checkpoints to complete and deliver
+const maxPendingOffsets = 100
+
+// offsetTracker records the offset of the last emitted data for each checkpoint of the source nodes in ack mode. The
+// offset is committed to the source after the checkpoint completes and the sinks deliver the results.
+type offsetTracker struct {
+	// ack mode, the offset is committed to the source after the checkpoint completes and the sinks deliver the results
+	enableAck      bool
+	offsetMu       sync.Mutex
+	lastOffset     interface{}
+	pendingOffsets map[int64]interface{}
+}
+
+// setLastOffset records the offset of the emitted data. The offset of the tuple takes precedence over the offset of
+// the source.
+func (t *offsetTracker) setLastOffset(data api.SourceTuple, offset interface{}) {
+	if ot, ok := data.(api.OffsetTuple); ok {
+		offset = ot.Offset()
+	}
+	if offset == nil {
+		return
+	}
+	t.offsetMu.Lock()
+	t.lastOffset = offset
+	t.offsetMu.Unlock()
+}
+
+// prepareCommit records the offset of the last emitted data to commit when the checkpoint completes and the results
+// are delivered
+func (t *offsetTracker) prepareCommit(checkpointId int64) {
+	if !t.enableAck {
+		return
+	}
+	t.offsetMu.Lock()
+	defer t.offsetMu.Unlock()
+	if t.lastOffset == nil {
+		return
+	}
+	if t.pendingOffsets == nil {
+		t.pendingOffsets = make(map[int64]interface{})
+	}
+	t.pendingOffsets[checkpointId] = t.lastOffset
+	// The commit waits for the sinks to deliver, so discard the oldest offset which is covered by the later ones
+	if len(t.pendingOffsets) > maxPendingOffsets {
+		oldest := checkpointId
+		for id := range t.pendingOffsets {
+			if id < oldest {
+				oldest = id
+			}
+		}
+		delete(t.pendingOffsets, oldest)
+	}
+}
+
+// takeOffset returns the offset recorded for the checkpoint to commit.
+// The offsets of the previous checkpoints are covered and discarded.
+func (t *offsetTracker) takeOffset(checkpointId int64) (interface{}, bool) {
+	if !t.enableAck {
+		return nil, false
+	}
+	t.offsetMu.Lock()
+	defer t.offsetMu.Unlock()
+	offset, ok := t.pendingOffsets[checkpointId]
+	for id := range t.pendingOffsets {
+		if id <= checkpointId {
+			delete(t.pendingOffsets, id)
+		}
+	}
+	return offset, ok
+}
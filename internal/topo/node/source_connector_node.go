@@ -17,6 +17,7 @@ package node
 import (
 	"fmt"
 
+	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/topo/node/metric"
 	"github.com/lf-edge/ekuiper/internal/xsql"
 	"github.com/lf-edge/ekuiper/pkg/api"
@@ -32,6 +33,10 @@ type SourceConnectorNode struct {
 
 	s       api.SourceConnector
 	buffLen int
+	// the rate to trace the source tuples
+	traceRate float64
+	props     map[string]any
+	offsetTracker
 }
 
 // NewSourceConnectorNode creates a SourceConnectorNode
@@ -40,6 +45,8 @@ func NewSourceConnectorNode(name string, ss api.SourceConnector, dataSource stri
 		defaultNode: newDefaultNode(name, rOpt),
 		s:           ss,
 		buffLen:     rOpt.BufferLength,
+		traceRate:   rOpt.TraceSampleRate,
+		props:       props,
 	}
 	return m, m.setup(dataSource, props)
 }
@@ -51,6 +58,12 @@ func (m *SourceConnectorNode) setup(dataSource string, props map[string]any) err
 	if err != nil {
 		return err
 	}
+	// The planner only enables ack for the connector read by a single rule. The connector may disable it.
+	m.enableAck, _ = props["enableAck"].(bool)
+	if _, ok := m.s.(api.OffsetCommitter); m.enableAck && !ok {
+		conf.Log.Warnf("source %s does not support ack, the offsets are not committed", m.name)
+		m.enableAck = false
+	}
 	return nil
 }
 
@@ -77,6 +90,10 @@ func (m *SourceConnectorNode) Run(ctx api.StreamContext, ctrlCh chan<- error) {
 		if err != nil {
 			return err
 		}
+		recorder, err := newSourceRecorder(ctx, m.props)
+		if err != nil {
+			return err
+		}
 		// subscribe and send data through channel
 		// Align to old code, use a channel to send data
 		buffer := make(chan api.SourceTuple, m.buffLen)
@@ -87,6 +104,9 @@ func (m *SourceConnectorNode) Run(ctx api.StreamContext, ctrlCh chan<- error) {
 		}
 		for {
 			m.statManager.SetBufferLength(int64(len(buffer)))
+			if !m.waitQuota(ctx) {
+				return nil
+			}
 			select {
 			case <-ctx.Done():
 				ctx.GetLogger().Infof("source connector %s is finished", m.name)
@@ -99,10 +119,24 @@ func (m *SourceConnectorNode) Run(ctx api.StreamContext, ctrlCh chan<- error) {
 				}
 				m.statManager.ProcessTimeStart()
 				m.statManager.IncTotalRecordsIn()
+				if drop, err := m.checkQuota(); err != nil {
+					return err
+				} else if drop {
+					break
+				}
+				if recorder != nil {
+					recordTuple(ctx, recorder, vu8, vu8.Timestamp().UnixMilli())
+				}
 				if raw, ok := vu8.(api.RawTuple); ok && raw.Raw() != nil {
 					tuple := &xsql.Tuple{Emitter: m.name, Raw: raw.Raw(), Timestamp: vu8.Timestamp().UnixMilli(), Metadata: vu8.Meta()}
+					span := startRootSpan(ctx, m.name, m.traceRate)
+					span.output(tuple)
+					span.end(nil)
 					m.Broadcast(tuple)
 					m.statManager.IncTotalRecordsOut()
+					if m.enableAck {
+						m.setLastOffset(vu8, nil)
+					}
 				} else {
 					err := fmt.Errorf("expect api.RawTuple but got %T", vu8)
 					m.Broadcast(&xsql.ErrorSourceTuple{
@@ -119,3 +153,22 @@ func (m *SourceConnectorNode) Run(ctx api.StreamContext, ctrlCh chan<- error) {
 		infra.DrainError(ctx, poe, ctrlCh)
 	}
 }
+
+// PrepareCommit records the offset of the last emitted data to commit when the checkpoint completes and the results
+// are delivered
+func (m *SourceConnectorNode) PrepareCommit(checkpointId int64) {
+	m.prepareCommit(checkpointId)
+}
+
+// Commit commits the offset recorded for the checkpoint to the source connector
+func (m *SourceConnectorNode) Commit(checkpointId int64) error {
+	offset, ok := m.takeOffset(checkpointId)
+	if !ok {
+		return nil
+	}
+	if oc, ok := m.s.(api.OffsetCommitter); ok {
+		m.ctx.GetLogger().Debugf("Source connector commit offset %v for checkpoint %d", offset, checkpointId)
+		return oc.CommitOffset(m.ctx, offset)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"sync"
+
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"github.com/lf-edge/ekuiper/pkg/kv"
+)
+
+type dedupConf struct {
+	// DedupField is the payload field of the message id
+	DedupField string `json:"dedupField"`
+	// DedupMetaField is the metadata key of the message id, it is used if DedupField is not set
+	DedupMetaField string `json:"dedupMetaField"`
+	// DedupWindow is the time window in milliseconds to remember the message ids
+	DedupWindow int64 `json:"dedupWindow"`
+}
+
+// sourceDedup drops the messages whose id has been seen in the time window.
+// The seen ids are kept in memory and saved at the checkpoints and when the rule stops, so that the redelivered
+// messages after restart are dropped too.
+type sourceDedup struct {
+	sync.Mutex
+	field     string
+	metaField string
+	window    int64
+	db        kv.KeyValue
+	seen      map[string]int64
+	lastClean int64
+	// dirty is the ids added or removed since the last flush
+	dirty map[string]struct{}
+	// flushMu serializes the flushes so that the older changes are not saved after the newer ones
+	flushMu sync.Mutex
+}
+
+// newSourceDedup creates the deduplicator from the source props. Return nil if deduplication is not enabled.
+func newSourceDedup(ctx api.StreamContext, name string, props map[string]interface{}) (*sourceDedup, error) {
+	c := &dedupConf{
+		DedupWindow: 3600000,
+	}
+	if err := cast.MapToStruct(props, c); err != nil {
+		return nil, fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	if c.DedupField == "" && c.DedupMetaField == "" {
+		return nil, nil
+	}
+	if c.DedupWindow <= 0 {
+		return nil, fmt.Errorf("dedupWindow must be positive but got %d", c.DedupWindow)
+	}
+	db, err := store.GetCacheKV(path.Join("source", ctx.GetRuleId(), name, "dedup"))
+	if err != nil {
+		return nil, err
+	}
+	d := &sourceDedup{
+		field:     c.DedupField,
+		metaField: c.DedupMetaField,
+		window:    c.DedupWindow,
+		db:        db,
+		seen:      make(map[string]int64),
+		dirty:     make(map[string]struct{}),
+	}
+	all, err := db.All()
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range all {
+		if ts, err := strconv.ParseInt(v, 10, 64); err == nil {
+			d.seen[k] = ts
+		}
+	}
+	ctx.GetLogger().Infof("source %s deduplicates by field %s, meta %s in %d ms with %d restored ids", name, d.field, d.metaField, d.window, len(d.seen))
+	return d, nil
+}
+
+func (d *sourceDedup) id(data api.SourceTuple) (string, bool) {
+	var (
+		v  interface{}
+		ok bool
+	)
+	if d.field != "" {
+		v, ok = data.Message()[d.field]
+	} else {
+		v, ok = data.Meta()[d.metaField]
+	}
+	if !ok || v == nil {
+		return "", false
+	}
+	return cast.ToStringAlways(v), true
+}
+
+// isDuplicate checks if the message id has been seen in the window and records it otherwise.
+// Messages without id are never duplicated.
+func (d *sourceDedup) isDuplicate(ctx api.StreamContext, data api.SourceTuple, now int64) bool {
+	id, ok := d.id(data)
+	if !ok {
+		return false
+	}
+	d.Lock()
+	defer d.Unlock()
+	if ts, ok := d.seen[id]; ok && now-ts < d.window {
+		return true
+	}
+	d.seen[id] = now
+	d.dirty[id] = struct{}{}
+	if now-d.lastClean >= d.window {
+		d.clean(ctx, now)
+	}
+	return false
+}
+
+// clean removes the expired ids
+func (d *sourceDedup) clean(_ api.StreamContext, now int64) {
+	for id, ts := range d.seen {
+		if now-ts >= d.window {
+			delete(d.seen, id)
+			d.dirty[id] = struct{}{}
+		}
+	}
+	d.lastClean = now
+}
+
+// flush saves the ids changed since the last flush. It is called when the checkpoint barrier is sent and when the
+// rule stops instead of for each message.
+func (d *sourceDedup) flush(ctx api.StreamContext) {
+	d.flushMu.Lock()
+	defer d.flushMu.Unlock()
+	d.Lock()
+	changes := make(map[string]int64, len(d.dirty))
+	for id := range d.dirty {
+		// The removed ids are saved as 0
+		changes[id] = d.seen[id]
+	}
+	d.dirty = make(map[string]struct{})
+	d.Unlock()
+	for id, ts := range changes {
+		var err error
+		if ts == 0 {
+			err = d.db.Delete(id)
+		} else {
+			err = d.db.Set(id, strconv.FormatInt(ts, 10))
+		}
+		if err != nil {
+			ctx.GetLogger().Warnf("save dedup id %s error: %v", id, err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/topo/state"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+func TestSourceDedup(t *testing.T) {
+	contextLogger := conf.Log.WithField("rule", "ruleDedup")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger).WithMeta("ruleDedup", "op1", &state.MemoryStore{})
+	d, err := newSourceDedup(ctx, "demo", map[string]interface{}{})
+	require.NoError(t, err)
+	require.Nil(t, d)
+	_, err = newSourceDedup(ctx, "demo", map[string]interface{}{"dedupField": "id", "dedupWindow": 0})
+	require.EqualError(t, err, "dedupWindow must be positive but got 0")
+
+	d, err = newSourceDedup(ctx, "demo", map[string]interface{}{"dedupField": "id", "dedupWindow": 100})
+	require.NoError(t, err)
+	require.NotNil(t, d)
+	msg := func(id interface{}) api.SourceTuple {
+		return api.NewDefaultSourceTuple(map[string]interface{}{"id": id}, nil)
+	}
+	assert.False(t, d.isDuplicate(ctx, msg(1), 1000))
+	assert.True(t, d.isDuplicate(ctx, msg(1), 1050))
+	assert.False(t, d.isDuplicate(ctx, msg(2), 1050))
+	// no id
+	assert.False(t, d.isDuplicate(ctx, api.NewDefaultSourceTuple(map[string]interface{}{"a": 1}, nil), 1050))
+	assert.False(t, d.isDuplicate(ctx, api.NewDefaultSourceTuple(map[string]interface{}{"a": 1}, nil), 1060))
+	// the seen ids are saved only when flushed
+	all, err := d.db.All()
+	require.NoError(t, err)
+	assert.Empty(t, all)
+	d.flush(ctx)
+	all, err = d.db.All()
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+	// the seen ids survive restart
+	d, err = newSourceDedup(ctx, "demo", map[string]interface{}{"dedupField": "id", "dedupWindow": 100})
+	require.NoError(t, err)
+	assert.True(t, d.isDuplicate(ctx, msg(2), 1100))
+	// expired
+	assert.False(t, d.isDuplicate(ctx, msg(1), 1100))
+	assert.Len(t, d.seen, 2)
+	assert.False(t, d.isDuplicate(ctx, msg(3), 1200))
+	assert.Len(t, d.seen, 1)
+	d.flush(ctx)
+	all, err = d.db.All()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"3": "1200"}, all)
+
+	// dedup by metadata
+	d, err = newSourceDedup(ctx, "demo2", map[string]interface{}{"dedupMetaField": "messageId"})
+	require.NoError(t, err)
+	meta := func(id interface{}) api.SourceTuple {
+		return api.NewDefaultSourceTuple(map[string]interface{}{"id": 1}, map[string]interface{}{"messageId": id})
+	}
+	assert.False(t, d.isDuplicate(ctx, meta("a"), 1000))
+	assert.True(t, d.isDuplicate(ctx, meta("a"), 2000))
+	assert.False(t, d.isDuplicate(ctx, meta("b"), 2000))
+	d.flush(ctx)
+
+	require.NoError(t, store.DropCacheKVForRule("ruleDedup"))
+	d, err = newSourceDedup(ctx, "demo2", map[string]interface{}{"dedupMetaField": "messageId"})
+	require.NoError(t, err)
+	assert.Empty(t, d.seen)
+}
@@ -16,12 +16,18 @@ package node
 
 import (
 	"fmt"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
 
 	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/converter"
+	"github.com/lf-edge/ekuiper/internal/io/record"
 	"github.com/lf-edge/ekuiper/internal/topo/context"
 	nodeConf "github.com/lf-edge/ekuiper/internal/topo/node/conf"
 	"github.com/lf-edge/ekuiper/internal/topo/node/metric"
+	"github.com/lf-edge/ekuiper/internal/topo/vclock"
 	"github.com/lf-edge/ekuiper/internal/xsql"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/ast"
@@ -41,6 +47,28 @@ type SourceNode struct {
 	IsWildcard   bool
 	IsSchemaless bool
 	si           *sourceInstance
+	offsetTracker
+	// partition is the partition to read in partitioned mode, -1 to read all
+	partition  int
+	partitions int
+	dedup      *sourceDedup
+	recorder   *record.Recorder
+	// the clock of the rule to timestamp the received data. The event clock is advanced by the data.
+	clock      clock.Clock
+	eventClock *vclock.EventClock
+	// the rate to trace the source tuples
+	traceRate float64
+	// the stream ends when the source finishes reading if bounded
+	bound       *SourceBound
+	isEventTime bool
+	// stop reading and send the drain signal, it is created lazily
+	drainMu sync.Mutex
+	drainCh chan struct{}
+	// the columns used by the rule, the source may only read them
+	projection []string
+	// the filter condition of the rule which the source may apply, in sql and the equality conditions
+	predicate       string
+	predicateEquals map[string]interface{}
 }
 
 func NewSourceNode(name string, st ast.StreamType, op UnOperation, options *ast.Options, rOptions *api.RuleOption, isWildcard, isSchemaless bool, schema map[string]*ast.JsonStreamField) *SourceNode {
@@ -52,7 +80,7 @@ func NewSourceNode(name string, st ast.StreamType, op UnOperation, options *ast.
 			t = "file"
 		}
 	}
-	return &SourceNode{
+	n := &SourceNode{
 		streamType:   st,
 		sourceType:   t,
 		defaultNode:  newDefaultNode(name, rOptions),
@@ -61,14 +89,48 @@ func NewSourceNode(name string, st ast.StreamType, op UnOperation, options *ast.
 		schema:       schema,
 		IsWildcard:   isWildcard,
 		IsSchemaless: isSchemaless,
+		traceRate:    rOptions.TraceSampleRate,
+		isEventTime:  rOptions.IsEventTime,
+		partition:    sourcePartition(st, rOptions),
+		partitions:   rOptions.Partitions,
+	}
+	n.enableAck = rOptions.EnableAck
+	return n
+}
+
+// sourcePartition returns the partition of the stream to read. The tables are read fully by each partition.
+func sourcePartition(st ast.StreamType, rOptions *api.RuleOption) int {
+	if st == ast.TypeStream && rOptions.Partitions > 0 {
+		return rOptions.Partition
 	}
+	return -1
 }
 
 func (m *SourceNode) SetProps(props map[string]interface{}) {
 	m.props = props
 }
 
-const OffsetKey = "$$offset"
+// SetProjection sets the columns used by the rule. They are passed to the source by the projection property so that
+// the pull sources like sql can only query these columns.
+func (m *SourceNode) SetProjection(cols []string) {
+	m.projection = cols
+}
+
+// SetPredicate sets the filter condition of the rule on the source columns. It is passed to the source by the predicate
+// property as the sql where condition and by the predicateEquals property as the equality conditions so that the pull
+// sources can filter the data at the remote. The rule still filters the data, so it is fine for the sources to ignore.
+func (m *SourceNode) SetPredicate(predicate string, equals map[string]interface{}) {
+	m.predicate = predicate
+	m.predicateEquals = equals
+}
+
+const (
+	OffsetKey          = "$$offset"
+	ProjectionKey      = "projection"
+	BoundedKey         = "bounded"
+	PredicateKey       = "predicate"
+	PredicateEqualsKey = "predicateEquals"
+)
 
 func (m *SourceNode) Open(ctx api.StreamContext, errCh chan<- error) {
 	m.ctx = ctx
@@ -76,7 +138,10 @@ func (m *SourceNode) Open(ctx api.StreamContext, errCh chan<- error) {
 	logger.Infof("open source node %s with option %v", m.name, m.options)
 	go func() {
 		panicOrError := infra.SafeRun(func() error {
-			props := nodeConf.GetSourceConf(m.sourceType, m.options)
+			props, err := nodeConf.GetSourceConf(m.sourceType, m.options)
+			if err != nil {
+				return err
+			}
 			// merge the props
 			for k, v := range m.props {
 				props[k] = v
@@ -98,10 +163,32 @@ func (m *SourceNode) Open(ctx api.StreamContext, errCh chan<- error) {
 				}
 			}
 			m.bufferLength = bl
+			if m.enableAck && m.options.SHARED {
+				logger.Warnf("ack is disabled for source %s because it is shared by multiple rules", m.name)
+				m.enableAck = false
+			}
+			if m.enableAck {
+				props["enableAck"] = true
+			}
+			if m.partition >= 0 && m.options.SHARED {
+				return fmt.Errorf("shared source %s cannot be read by partition", m.name)
+			}
 			if m.streamType == ast.TypeTable {
 				props["isTable"] = true
 			}
 			props["delimiter"] = m.options.DELIMITER
+			if len(m.projection) > 0 {
+				props[ProjectionKey] = m.projection
+			}
+			if m.predicate != "" {
+				props[PredicateKey] = m.predicate
+			}
+			if len(m.predicateEquals) > 0 {
+				props[PredicateEqualsKey] = m.predicateEquals
+			}
+			if m.bound != nil && m.bound.ReadOnce {
+				props[BoundedKey] = true
+			}
 			m.options.Schema = nil
 			m.options.IsWildCard = m.IsWildcard
 			m.options.IsSchemaLess = m.IsSchemaless
@@ -110,6 +197,18 @@ func (m *SourceNode) Open(ctx api.StreamContext, errCh chan<- error) {
 				m.options.Schema = m.schema
 				m.options.StreamName = m.name
 			}
+			dedup, err := newSourceDedup(ctx, m.name, props)
+			if err != nil {
+				return err
+			}
+			m.dedup = dedup
+			recorder, err := newSourceRecorder(ctx, props)
+			if err != nil {
+				return err
+			}
+			m.recorder = recorder
+			m.clock = vclock.Get(ctx)
+			m.eventClock, _ = m.clock.(*vclock.EventClock)
 			converterTool, err := converter.GetOrCreateConverter(m.options)
 			if err != nil {
 				msg := fmt.Sprintf("cannot get converter from format %s, schemaId %s: %v", m.options.FORMAT, m.options.SCHEMAID, err)
@@ -137,14 +236,32 @@ func (m *SourceNode) Open(ctx api.StreamContext, errCh chan<- error) {
 					}
 					buffer = si.dataCh
 					m.si = si
+					if m.enableAck {
+						if _, ok := si.source.(api.OffsetCommitter); !ok {
+							logger.Warnf("source %s does not support ack, the offsets are not committed", m.name)
+						}
+					}
 
+					drainCh := m.drainChan()
 					defer func() {
 						logger.Infof("source %s done", m.name)
+						if m.dedup != nil {
+							m.dedup.flush(ctx)
+						}
 						m.close()
 						buffer.Close()
+						// discard the drain request after the rule stops
+						select {
+						case <-drainCh:
+						default:
+						}
 					}()
 					logger.Infof("Start source %s instance %d successfully", m.name, instance)
 					for {
+						if !m.waitQuota(ctx) {
+							m.schema = nil
+							return nil
+						}
 						select {
 						case <-ctx.Done():
 							// We should clear the schema after we close the topo in order to avoid the following problem:
@@ -157,22 +274,48 @@ func (m *SourceNode) Open(ctx api.StreamContext, errCh chan<- error) {
 							return nil
 						case err := <-si.errorCh:
 							return err
+						case <-drainCh:
+							// Stop reading so that the data in flight can be drained until the rule is cancelled
+							logger.Infof("Source %s stops reading to drain the rule", ctx.GetOpId())
+							m.Broadcast(&DrainSignal{})
+							<-ctx.Done()
+							m.schema = nil
+							return nil
 						case data := <-buffer.Out:
+							if _, ok := data.(*sourceEndTuple); ok {
+								m.end()
+								continue
+							}
 							if t, ok := data.(*xsql.ErrorSourceTuple); ok {
 								logger.Errorf("Source %s error: %v", ctx.GetOpId(), t.Error)
 								m.statManager.IncTotalExceptions(t.Error.Error())
 								continue
 							}
 							m.statManager.IncTotalRecordsIn()
-							rcvTime := conf.GetNow()
+							if drop, err := m.checkQuota(); err != nil {
+								return err
+							} else if drop {
+								continue
+							}
+							if m.dedup != nil && m.dedup.isDuplicate(ctx, data, conf.GetNowInMilli()) {
+								logger.Debugf("Source %s drops duplicate message %v", ctx.GetOpId(), data.Message())
+								continue
+							}
+							rcvTime := m.clock.Now()
 							if !data.Timestamp().IsZero() {
 								rcvTime = data.Timestamp()
 							}
+							if m.recorder != nil {
+								recordTuple(ctx, m.recorder, data, rcvTime.UnixMilli())
+							}
 							m.statManager.SetProcessTimeStart(rcvTime)
 							tuple := &xsql.Tuple{Emitter: m.name, Message: data.Message(), Timestamp: rcvTime.UnixMilli(), Metadata: data.Meta()}
+							span := startRootSpan(ctx, m.name, m.traceRate)
 							var processedData interface{}
 							if m.preprocessOp != nil {
+								start := time.Now()
 								processedData = m.preprocessOp.Apply(ctx, tuple, nil, nil)
+								m.chargeQuota(start)
 							} else {
 								processedData = tuple
 							}
@@ -180,28 +323,46 @@ func (m *SourceNode) Open(ctx api.StreamContext, errCh chan<- error) {
 							// blocking
 							switch val := processedData.(type) {
 							case nil:
+								span.end(nil)
 								continue
 							case error:
+								span.end(val)
 								logger.Errorf("Source %s preprocess error: %s", ctx.GetOpId(), val)
 								m.Broadcast(val)
-								m.statManager.IncTotalExceptions(val.Error())
+								m.statManager.IncTotalExceptionsOf(metric.ErrorDecode, val.Error())
 							default:
+								if m.bound != nil && !m.bound.accept(val) {
+									span.end(nil)
+									continue
+								}
+								if m.eventClock != nil {
+									if t, ok := val.(*xsql.Tuple); ok {
+										m.eventClock.Advance(t.Timestamp)
+									}
+								}
+								span.output(val)
+								span.end(nil)
 								m.Broadcast(val)
 								m.statManager.IncTotalRecordsOut()
 								m.statManager.IncTotalMessagesProcessed(1)
 							}
 							m.statManager.SetBufferLength(int64(buffer.GetLength()))
+							var offset interface{}
 							if rw, ok := si.source.(api.Rewindable); ok {
-								if offset, err := rw.GetOffset(); err != nil {
+								if o, err := rw.GetOffset(); err != nil {
 									infra.DrainError(ctx, err, errCh)
 								} else {
-									err = ctx.PutState(OffsetKey, offset)
+									err = ctx.PutState(OffsetKey, o)
 									if err != nil {
 										return err
 									}
-									logger.Debugf("Source save offset %v", offset)
+									logger.Debugf("Source save offset %v", o)
+									offset = o
 								}
 							}
+							if m.enableAck {
+								m.setLastOffset(data, offset)
+							}
 						}
 					}
 				})
@@ -217,6 +378,30 @@ func (m *SourceNode) Open(ctx api.StreamContext, errCh chan<- error) {
 	}()
 }
 
+// PrepareCommit records the offset of the last emitted data to commit when the checkpoint completes and the results
+// are delivered
+func (m *SourceNode) PrepareCommit(checkpointId int64) {
+	m.prepareCommit(checkpointId)
+	// Save the ids of the messages before the barrier with the checkpoint
+	if m.dedup != nil {
+		m.dedup.flush(m.ctx)
+	}
+}
+
+// Commit commits the offset recorded for the checkpoint to the source.
+// The offsets of the previous checkpoints are covered and discarded.
+func (m *SourceNode) Commit(checkpointId int64) error {
+	offset, ok := m.takeOffset(checkpointId)
+	if !ok || m.si == nil {
+		return nil
+	}
+	if oc, ok := m.si.source.(api.OffsetCommitter); ok {
+		m.ctx.GetLogger().Debugf("Source commit offset %v for checkpoint %d", offset, checkpointId)
+		return oc.CommitOffset(m.ctx, offset)
+	}
+	return nil
+}
+
 func (m *SourceNode) reset() {
 	m.statManager = nil
 }
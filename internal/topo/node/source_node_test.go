@@ -20,6 +20,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/lf-edge/ekuiper/internal/topo/context"
 	nodeConf "github.com/lf-edge/ekuiper/internal/topo/node/conf"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/ast"
@@ -46,7 +47,8 @@ func TestGetConf_Apply(t *testing.T) {
 		DATASOURCE: "/feed",
 		TYPE:       "httppull",
 	}, &api.RuleOption{SendError: false}, false, false, nil)
-	conf := nodeConf.GetSourceConf(n.sourceType, n.options)
+	conf, err := nodeConf.GetSourceConf(n.sourceType, n.options)
+	assert.NoError(t, err)
 	if !reflect.DeepEqual(result, conf) {
 		t.Errorf("result mismatch:\n\nexp=%s\n\ngot=%s\n\n", result, conf)
 	}
@@ -72,7 +74,8 @@ func TestGetConfAndConvert_Apply(t *testing.T) {
 		DATASOURCE: "/feed",
 		TYPE:       "httppull",
 	}, &api.RuleOption{SendError: false}, false, false, nil)
-	conf := nodeConf.GetSourceConf(n.sourceType, n.options)
+	conf, err := nodeConf.GetSourceConf(n.sourceType, n.options)
+	assert.NoError(t, err)
 	assert.Equal(t, result, conf)
 
 	r := &httpPullSourceConfig{
@@ -89,7 +92,7 @@ func TestGetConfAndConvert_Apply(t *testing.T) {
 	}
 
 	cfg := &httpPullSourceConfig{}
-	err := cast.MapToStruct(conf, cfg)
+	err = cast.MapToStruct(conf, cfg)
 	if err != nil {
 		t.Errorf("map to sturct error %s", err)
 		return
@@ -109,3 +112,75 @@ type httpPullSourceConfig struct {
 	InsecureSkipVerify bool                   `json:"insecureSkipVerify"`
 	Headers            map[string]interface{} `json:"headers"`
 }
+
+type mockCommitSource struct {
+	committed []interface{}
+}
+
+func (m *mockCommitSource) Open(_ api.StreamContext, _ chan<- api.SourceTuple, _ chan<- error) {}
+
+func (m *mockCommitSource) Configure(_ string, _ map[string]interface{}) error {
+	return nil
+}
+
+func (m *mockCommitSource) Close(_ api.StreamContext) error {
+	return nil
+}
+
+func (m *mockCommitSource) CommitOffset(_ api.StreamContext, offset interface{}) error {
+	m.committed = append(m.committed, offset)
+	return nil
+}
+
+type mockOffsetTuple struct {
+	*api.DefaultSourceTuple
+	offset interface{}
+}
+
+func (t *mockOffsetTuple) Offset() interface{} {
+	return t.offset
+}
+
+func TestSourceNodeCommit(t *testing.T) {
+	src := &mockCommitSource{}
+	n := NewSourceNode("test", ast.TypeStream, nil, &ast.Options{
+		DATASOURCE: "/feed",
+		TYPE:       "mqtt",
+	}, &api.RuleOption{EnableAck: true, Qos: api.AtLeastOnce}, false, false, nil)
+	n.ctx = context.Background()
+	n.si = &sourceInstance{source: src}
+	// no data emitted yet, nothing to commit
+	n.PrepareCommit(1)
+	assert.NoError(t, n.Commit(1))
+	assert.Empty(t, src.committed)
+
+	n.setLastOffset(api.NewDefaultSourceTuple(nil, nil), 10)
+	n.PrepareCommit(2)
+	n.setLastOffset(api.NewDefaultSourceTuple(nil, nil), 11)
+	n.PrepareCommit(3)
+	// the offset of the tuple takes precedence over the source offset
+	n.setLastOffset(&mockOffsetTuple{DefaultSourceTuple: api.NewDefaultSourceTuple(nil, nil), offset: "a"}, 20)
+	n.PrepareCommit(4)
+	// tuple without offset does not advance the offset
+	n.setLastOffset(&mockOffsetTuple{DefaultSourceTuple: api.NewDefaultSourceTuple(nil, nil)}, 21)
+	n.PrepareCommit(5)
+	// checkpoint 3 covers checkpoint 2
+	assert.NoError(t, n.Commit(3))
+	assert.NoError(t, n.Commit(2))
+	assert.NoError(t, n.Commit(5))
+	assert.Equal(t, []interface{}{11, "a"}, src.committed)
+	assert.Empty(t, n.pendingOffsets)
+
+	// ack disabled
+	src = &mockCommitSource{}
+	n = NewSourceNode("test", ast.TypeStream, nil, &ast.Options{
+		DATASOURCE: "/feed",
+		TYPE:       "mqtt",
+	}, &api.RuleOption{Qos: api.AtLeastOnce}, false, false, nil)
+	n.ctx = context.Background()
+	n.si = &sourceInstance{source: src}
+	n.setLastOffset(api.NewDefaultSourceTuple(nil, nil), 10)
+	n.PrepareCommit(1)
+	assert.NoError(t, n.Commit(1))
+	assert.Empty(t, src.committed)
+}
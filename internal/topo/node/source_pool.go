@@ -23,6 +23,7 @@ import (
 
 	"github.com/lf-edge/ekuiper/internal/binder/io"
 	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/pkg/namespace"
 	kctx "github.com/lf-edge/ekuiper/internal/topo/context"
 	"github.com/lf-edge/ekuiper/internal/topo/state"
 	"github.com/lf-edge/ekuiper/pkg/api"
@@ -41,7 +42,9 @@ var pool = &sourcePool{
 func getSourceInstance(node *SourceNode, index int) (*sourceInstance, error) {
 	var si *sourceInstance
 	if node.options.SHARED {
-		rkey := fmt.Sprintf("%s.%s", node.sourceType, node.name)
+		// The shared source is in the namespace of the rule
+		ns, _ := namespace.Split(node.ctx.GetRuleId())
+		rkey := fmt.Sprintf("%s.%s", node.sourceType, namespace.Qualify(ns, node.name))
 		s, ok := pool.load(rkey)
 		if !ok {
 			ns, err := io.Source(node.sourceType)
@@ -83,6 +86,12 @@ func getSourceInstance(node *SourceNode, index int) (*sourceInstance, error) {
 					nctx := node.ctx.WithInstance(index)
 					defer si.source.Close(nctx)
 					si.source.Open(nctx, si.dataCh.In, si.errorCh)
+					if node.bound != nil {
+						select {
+						case si.dataCh.In <- &sourceEndTuple{DefaultSourceTuple: api.NewDefaultSourceTuple(nil, nil)}:
+						case <-nctx.Done():
+						}
+					}
 					return nil
 				})
 				if err != nil {
@@ -105,7 +114,9 @@ func getSourceInstance(node *SourceNode, index int) (*sourceInstance, error) {
 // ONLY apply to shared instance
 func removeSourceInstance(node *SourceNode) {
 	for i := 0; i < node.concurrency; i++ {
-		rkey := fmt.Sprintf("%s.%s", node.sourceType, node.name)
+		// The shared source is in the namespace of the rule
+		ns, _ := namespace.Split(node.ctx.GetRuleId())
+		rkey := fmt.Sprintf("%s.%s", node.sourceType, namespace.Qualify(ns, node.name))
 		pool.deleteInstance(rkey, node, i)
 	}
 }
@@ -345,6 +356,15 @@ func (ss *sourceSingleton) detach(instanceKey string) bool {
 }
 
 func start(poolCtx api.StreamContext, node *SourceNode, s api.Source) (*sourceInstance, error) {
+	if node.partition >= 0 {
+		ps, ok := s.(api.PartitionSource)
+		if !ok {
+			return nil, fmt.Errorf("source type %s does not support partitioned mode", node.sourceType)
+		}
+		if err := ps.SetPartition(node.partition, node.partitions); err != nil {
+			return nil, err
+		}
+	}
 	err := s.Configure(node.options.DATASOURCE, node.props)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,70 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/io/record"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+type recordConf struct {
+	// RecordFile is the file to record the received payloads for replaying later
+	RecordFile string `json:"recordFile"`
+}
+
+// newSourceRecorder creates the recorder from the source props, which is closed when the rule stops.
+// Return nil if recording is not enabled.
+func newSourceRecorder(ctx api.StreamContext, props map[string]interface{}) (*record.Recorder, error) {
+	c := &recordConf{}
+	if err := cast.MapToStruct(props, c); err != nil {
+		return nil, fmt.Errorf("read properties %v fail with error: %v", props, err)
+	}
+	if c.RecordFile == "" {
+		return nil, nil
+	}
+	r, err := record.NewRecorder(c.RecordFile)
+	if err != nil {
+		return nil, err
+	}
+	ctx.GetLogger().Infof("source %s records the payloads to %s", ctx.GetOpId(), r.Path())
+	go func() {
+		<-ctx.Done()
+		if err := r.Close(); err != nil {
+			ctx.GetLogger().Warnf("fail to close record file: %v", err)
+		}
+	}()
+	return r, nil
+}
+
+// recordTuple records the raw payload of the tuple if available, otherwise the decoded message.
+// The failure of recording does not affect the source.
+func recordTuple(ctx api.StreamContext, r *record.Recorder, data api.SourceTuple, ts int64) {
+	if ts <= 0 {
+		ts = conf.GetNowInMilli()
+	}
+	rec := &record.Record{Timestamp: ts, Meta: data.Meta()}
+	if raw, ok := data.(api.RawTuple); ok && raw.Raw() != nil {
+		rec.Payload = raw.Raw()
+	} else {
+		rec.Message = data.Message()
+	}
+	if err := r.Record(rec); err != nil {
+		ctx.GetLogger().Warnf("source fails to record the payload: %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+// TapFunc receives the output of a node including the errors. It is called in the goroutine of the node before
+// the output is sent to the downstream nodes, so it must not block or modify the value.
+type TapFunc func(val any)
+
+// TappableNode is the node whose output can be tapped to debug the rule
+type TappableNode interface {
+	GetName() string
+	// SetTap sets the tap if the node is not tapped, and returns false if it is tapped already
+	SetTap(f TapFunc) bool
+	RemoveTap()
+}
+
+func (o *defaultNode) SetTap(f TapFunc) bool {
+	return o.tap.CompareAndSwap(nil, &f)
+}
+
+func (o *defaultNode) RemoveTap() {
+	o.tap.Store(nil)
+}
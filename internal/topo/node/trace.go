@@ -0,0 +1,99 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"context"
+	"math/rand"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+const tracerName = "github.com/lf-edge/ekuiper/internal/topo"
+
+// opSpan is the tracing span of an operator to process a traced data.
+// All methods can be called with a nil span which means the data is not traced.
+type opSpan struct {
+	ctx  context.Context
+	span trace.Span
+}
+
+// startRootSpan starts a new trace for a source tuple if it is sampled by the rate
+func startRootSpan(ctx api.StreamContext, name string, rate float64) *opSpan {
+	if rate <= 0 || (rate < 1 && rand.Float64() >= rate) {
+		return nil
+	}
+	return newOpSpan(ctx, context.Background(), name)
+}
+
+// startSpan starts the span of the operator as the child of the span of the input
+func startSpan(ctx api.StreamContext, name string, data any) *opSpan {
+	c, ok := data.(xsql.TraceCarrier)
+	if !ok || c.GetTracerCtx() == nil {
+		return nil
+	}
+	return newOpSpan(ctx, c.GetTracerCtx(), name)
+}
+
+// traceWindow traces the window result as the child of the first traced tuple in the window
+func traceWindow(ctx api.StreamContext, name string, results *xsql.WindowTuples) {
+	for _, r := range results.Content {
+		if s := startSpan(ctx, name, r); s != nil {
+			s.span.SetAttributes(attribute.Int("rows", len(results.Content)))
+			s.output(results)
+			s.end(nil)
+			return
+		}
+	}
+}
+
+func newOpSpan(ctx api.StreamContext, parent context.Context, name string) *opSpan {
+	c, span := otel.Tracer(tracerName).Start(parent, name, trace.WithAttributes(
+		attribute.String("rule", ctx.GetRuleId()),
+		attribute.Int("instance", ctx.GetInstanceId()),
+	))
+	if !span.SpanContext().IsValid() {
+		// No tracer provider is set
+		return nil
+	}
+	return &opSpan{ctx: c, span: span}
+}
+
+// output propagates the span to the output data so that the downstream operators trace it as the child
+func (s *opSpan) output(data any) {
+	if s == nil {
+		return
+	}
+	if c, ok := data.(xsql.TraceCarrier); ok {
+		c.SetTracerCtx(s.ctx)
+	}
+}
+
+func (s *opSpan) end(err error) {
+	if s == nil {
+		return
+	}
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
@@ -0,0 +1,73 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	mockContext "github.com/lf-edge/ekuiper/pkg/mock/context"
+)
+
+func TestTraceSpans(t *testing.T) {
+	ctx := mockContext.NewMockContext("TestTraceSpans", "op")
+	// No tracer provider
+	assert.Nil(t, startRootSpan(ctx, "source", 1))
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	old := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	defer otel.SetTracerProvider(old)
+
+	assert.Nil(t, startRootSpan(ctx, "source", 0))
+	// source -> filter -> window -> sink
+	tuple := &xsql.Tuple{Message: map[string]interface{}{"a": 1}}
+	assert.Nil(t, startSpan(ctx, "filter", tuple))
+	s := startRootSpan(ctx, "source", 1)
+	require.NotNil(t, s)
+	s.output(tuple)
+	s.end(nil)
+	s = startSpan(ctx, "filter", tuple)
+	require.NotNil(t, s)
+	s.output(tuple)
+	s.end(nil)
+	w := &xsql.WindowTuples{Content: []xsql.Row{&xsql.Tuple{}, tuple}}
+	traceWindow(ctx, "window", w)
+	s = startSpan(ctx, "sink", w.Clone())
+	require.NotNil(t, s)
+	s.end(errors.New("send error"))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 4)
+	var names []string
+	for i, sp := range spans {
+		names = append(names, sp.Name())
+		assert.Equal(t, spans[0].SpanContext().TraceID(), sp.SpanContext().TraceID())
+		if i > 0 {
+			assert.Equal(t, spans[i-1].SpanContext().SpanID(), sp.Parent().SpanID())
+		}
+	}
+	assert.Equal(t, []string{"source", "filter", "window", "sink"}, names)
+	assert.Equal(t, codes.Error, spans[3].Status().Code)
+}
@@ -19,6 +19,7 @@ import (
 	"math"
 	"sort"
 
+	"github.com/lf-edge/ekuiper/internal/io/memory/pubsub"
 	"github.com/lf-edge/ekuiper/internal/topo/node/metric"
 	"github.com/lf-edge/ekuiper/internal/xsql"
 	"github.com/lf-edge/ekuiper/pkg/api"
@@ -31,8 +32,10 @@ import (
 type WatermarkOp struct {
 	*defaultSinkNode
 	// config
-	lateTolerance int64
-	sendWatermark bool
+	lateTolerance   int64
+	allowedLateness int64
+	lateDataTopic   string
+	sendWatermark   bool
 	// state
 	events          []*xsql.Tuple // All the cached events in order
 	streamWMs       map[string]int64
@@ -55,6 +58,8 @@ func NewWatermarkOp(name string, sendWatermark bool, streams []string, options *
 	return &WatermarkOp{
 		defaultSinkNode: newDefaultSinkNode(name, options),
 		lateTolerance:   options.LateTol,
+		allowedLateness: options.AllowedLateness,
+		lateDataTopic:   options.LateDataTopic,
 		sendWatermark:   sendWatermark,
 		streamWMs:       wms,
 	}
@@ -97,11 +102,30 @@ func (w *WatermarkOp) Exec(ctx api.StreamContext, errCh chan<- error) {
 	}
 
 	ctx.GetLogger().Infof("Start with state lastWatermarkTs: %d", w.lastWatermarkTs)
+	if w.lateDataTopic != "" {
+		pubsub.CreatePub(w.lateDataTopic)
+	}
+	// No more data will come, so advance the watermark of all streams to send out all pending events
+	w.drainHandler = func() {
+		var wm int64
+		for _, v := range w.streamWMs {
+			if v > wm {
+				wm = v
+			}
+		}
+		for emitter := range w.streamWMs {
+			w.streamWMs[emitter] = wm + w.lateTolerance
+		}
+		w.trigger(ctx)
+	}
 	go func() {
 		err := infra.SafeRun(func() error {
 			for {
 				select {
 				case <-ctx.Done():
+					if w.lateDataTopic != "" {
+						pubsub.RemovePub(w.lateDataTopic)
+					}
 					ctx.GetLogger().Infof("watermark node %s is finished", w.name)
 					return nil
 				case item, opened := <-w.input:
@@ -126,7 +150,16 @@ func (w *WatermarkOp) Exec(ctx api.StreamContext, errCh chan<- error) {
 						if w.track(ctx, d.Emitter, d.GetTimestamp()) {
 							// If not drop, check if it can be sent out
 							w.addAndTrigger(ctx, d)
+						} else {
+							w.handleLate(ctx, d)
+						}
+					case *endOfStream:
+						// The stream is complete until the end, so advance its watermark to send out the pending events
+						if wm := d.watermark + w.lateTolerance; wm > w.streamWMs[d.emitter] {
+							w.streamWMs[d.emitter] = wm
+							_ = ctx.PutState(StreamWMKey, w.streamWMs)
 						}
+						w.trigger(ctx)
 					default:
 						e := fmt.Errorf("run watermark op error: expect *xsql.Tuple type but got %[1]T(%[1]v)", d)
 						w.Broadcast(e)
@@ -152,6 +185,26 @@ func (w *WatermarkOp) track(ctx api.StreamContext, emitter string, ts int64) boo
 	return r
 }
 
+// handleLate sends out the late event if it is within the allowed lateness.
+// Otherwise, the event is sent to the late data topic if set or dropped.
+func (w *WatermarkOp) handleLate(ctx api.StreamContext, d *xsql.Tuple) {
+	if d.GetTimestamp() >= w.lastWatermarkTs-w.allowedLateness {
+		ctx.GetLogger().Debugf("send out late event at %d with watermark %d", d.GetTimestamp(), w.lastWatermarkTs)
+		w.Broadcast(d)
+		w.statManager.IncTotalRecordsOut()
+		w.statManager.IncTotalMessagesProcessed(1)
+		w.statManager.ProcessTimeEnd()
+		return
+	}
+	if w.lateDataTopic != "" {
+		ctx.GetLogger().Debugf("send late event at %d to topic %s", d.GetTimestamp(), w.lateDataTopic)
+		pubsub.Produce(ctx, w.lateDataTopic, d.ToMap())
+	} else {
+		ctx.GetLogger().Debugf("drop late event at %d with watermark %d", d.GetTimestamp(), w.lastWatermarkTs)
+	}
+	w.statManager.ProcessTimeEnd()
+}
+
 // Add an event and check if watermark proceeds
 // If yes, send out all events before the watermark
 func (w *WatermarkOp) addAndTrigger(ctx api.StreamContext, d *xsql.Tuple) {
@@ -166,13 +219,17 @@ func (w *WatermarkOp) addAndTrigger(ctx api.StreamContext, d *xsql.Tuple) {
 		copy(w.events[index+1:], w.events[index:])
 		w.events[index] = d
 	}
+	w.trigger(ctx)
+}
 
+// trigger sends out all events before the watermark if it proceeds
+func (w *WatermarkOp) trigger(ctx api.StreamContext) {
 	watermark := w.computeWatermarkTs()
 	ctx.GetLogger().Debugf("compute watermark event at %d with last %d", watermark, w.lastWatermarkTs)
 	// Make sure watermark time proceeds
 	if watermark > w.lastWatermarkTs {
 		// Send out all events before the watermark
-		if watermark >= w.events[0].GetTimestamp() {
+		if len(w.events) > 0 && watermark >= w.events[0].GetTimestamp() {
 			// Find out the last event to send in this watermark change
 			c := len(w.events)
 			for i, e := range w.events {
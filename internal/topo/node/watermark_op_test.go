@@ -21,6 +21,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/io/memory/pubsub"
 	"github.com/lf-edge/ekuiper/internal/topo/context"
 	"github.com/lf-edge/ekuiper/internal/topo/state"
 	"github.com/lf-edge/ekuiper/internal/xsql"
@@ -506,3 +507,43 @@ func TestMultiStreamWatermark(t *testing.T) {
 		})
 	}
 }
+
+func TestLateDataWatermark(t *testing.T) {
+	conf.InitConf()
+	contextLogger := conf.Log.WithField("rule", "TestLateDataWatermark")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger)
+	tempStore, _ := state.CreateStore("TestLateDataWatermark", api.AtMostOnce)
+	nctx, cancel := ctx.WithMeta("TestLateDataWatermark", "test", tempStore).WithCancel()
+	defer cancel()
+	w := NewWatermarkOp("mock", false, []string{"demo"}, &api.RuleOption{
+		IsEventTime:     true,
+		AllowedLateness: 10,
+		LateDataTopic:   "late",
+	})
+	errCh := make(chan error)
+	outputCh := make(chan interface{}, 50)
+	w.outputs["mock"] = outputCh
+	w.Exec(nctx, errCh)
+	lateCh := pubsub.CreateSub("late", nil, "TestLateDataWatermark", 10)
+	defer pubsub.CloseSourceConsumerChannel("late", "TestLateDataWatermark")
+
+	for _, ts := range []int64{20, 30, 15, 25} {
+		w.input <- &xsql.Tuple{Emitter: "demo", Message: map[string]interface{}{"ts": ts}, Timestamp: ts}
+	}
+	// The tuple within the allowed lateness is sent out
+	for _, ts := range []int64{20, 30, 25} {
+		select {
+		case r := <-outputCh:
+			assert.Equal(t, ts, r.(*xsql.Tuple).Timestamp)
+		case <-time.After(5 * time.Second):
+			t.Fatal("receive timeout")
+		}
+	}
+	// The tuple beyond the allowed lateness is sent to the late data topic
+	select {
+	case r := <-lateCh:
+		assert.Equal(t, map[string]interface{}{"ts": int64(15)}, r.Message())
+	case <-time.After(5 * time.Second):
+		t.Fatal("receive late data timeout")
+	}
+}
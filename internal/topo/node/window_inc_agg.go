@@ -0,0 +1,239 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+// incAggregator calculates the aggregates of a sliding window incrementally. The tuples are added when received and
+// evicted from the head when expired, in the same order as the window inputs. Sum, count and avg are updated in O(1)
+// and min, max are kept by the monotonic deques in amortized O(1). A nil aggregator does nothing.
+type incAggregator struct {
+	aggs []*incAgg
+	// the argument values of the tuples in the window
+	entries [][]interface{}
+	// the sequence of the first entry
+	head int64
+}
+
+// incAgg is the state of an aggregate call. The values of different kinds cannot be aggregated incrementally in the
+// same way as the aggregate functions do, so the result is not provided when the window has mixed kinds of values.
+type incAgg struct {
+	name   string
+	arg    ast.Expr
+	field  string
+	count  int
+	ints   int
+	floats int
+	others int
+
+	intSum     int64
+	floatSum   float64
+	intDeque   *monoDeque[int64]
+	floatDeque *monoDeque[float64]
+}
+
+func newIncAggregator(calls []*ast.Call) *incAggregator {
+	if len(calls) == 0 {
+		return nil
+	}
+	a := &incAggregator{aggs: make([]*incAgg, 0, len(calls))}
+	for _, c := range calls {
+		if len(c.Args) != 1 || c.CachedField == "" {
+			continue
+		}
+		agg := &incAgg{name: c.Name, arg: c.Args[0], field: c.CachedField}
+		switch c.Name {
+		case "min":
+			agg.intDeque, agg.floatDeque = &monoDeque[int64]{}, &monoDeque[float64]{}
+		case "max":
+			agg.intDeque, agg.floatDeque = &monoDeque[int64]{isMax: true}, &monoDeque[float64]{isMax: true}
+		}
+		a.aggs = append(a.aggs, agg)
+	}
+	return a
+}
+
+// add the tuple to the tail of the window
+func (a *incAggregator) add(t *xsql.Tuple) {
+	if a == nil {
+		return
+	}
+	seq := a.head + int64(len(a.entries))
+	vals := make([]interface{}, len(a.aggs))
+	for i, agg := range a.aggs {
+		var v interface{}
+		if _, ok := agg.arg.(*ast.Wildcard); ok {
+			v = true
+		} else {
+			v = xsql.Eval(agg.arg, xsql.MultiValuer(t))
+			if _, ok := v.(error); ok {
+				v = nil
+			}
+			if iv, ok := v.(int); ok {
+				v = int64(iv)
+			}
+		}
+		vals[i] = v
+		agg.add(seq, v)
+	}
+	a.entries = append(a.entries, vals)
+}
+
+// evict the first n tuples of the window
+func (a *incAggregator) evict(n int) {
+	if a == nil {
+		return
+	}
+	for ; n > 0 && len(a.entries) > 0; n-- {
+		for i, agg := range a.aggs {
+			agg.evict(a.head, a.entries[0][i])
+		}
+		a.entries[0] = nil
+		a.entries = a.entries[1:]
+		a.head++
+	}
+}
+
+// fill sets the results of the aggregates to the cached fields of the window
+func (a *incAggregator) fill(row *xsql.AffiliateRow) {
+	if a == nil {
+		return
+	}
+	for _, agg := range a.aggs {
+		if v, ok := agg.result(len(a.entries)); ok {
+			row.Set(agg.field, v)
+		}
+	}
+}
+
+func (agg *incAgg) add(seq int64, v interface{}) {
+	if v == nil {
+		return
+	}
+	agg.count++
+	switch vt := v.(type) {
+	case int64:
+		agg.ints++
+		agg.intSum += vt
+		agg.intDeque.push(seq, vt)
+	case float64:
+		agg.floats++
+		agg.floatSum += vt
+		agg.floatDeque.push(seq, vt)
+	default:
+		agg.others++
+	}
+}
+
+func (agg *incAgg) evict(seq int64, v interface{}) {
+	if v == nil {
+		return
+	}
+	agg.count--
+	switch vt := v.(type) {
+	case int64:
+		agg.ints--
+		agg.intSum -= vt
+		agg.intDeque.evict(seq)
+	case float64:
+		agg.floats--
+		agg.floatSum -= vt
+		agg.floatDeque.evict(seq)
+		// reset to avoid accumulating the rounding errors
+		if agg.floats == 0 {
+			agg.floatSum = 0
+		}
+	default:
+		agg.others--
+	}
+}
+
+// result returns the same result as the aggregate function over the window content. Return false if the result
+// must be calculated by the function.
+func (agg *incAgg) result(size int) (interface{}, bool) {
+	if size == 0 {
+		return nil, true
+	}
+	if agg.name == "count" {
+		return agg.count, true
+	}
+	if agg.others > 0 || (agg.ints > 0 && agg.floats > 0) {
+		return nil, false
+	}
+	if agg.count == 0 {
+		return nil, true
+	}
+	switch agg.name {
+	case "sum":
+		if agg.ints > 0 {
+			return agg.intSum, true
+		}
+		return agg.floatSum, true
+	case "avg":
+		if agg.ints > 0 {
+			return agg.intSum / int64(agg.count), true
+		}
+		return agg.floatSum / float64(agg.count), true
+	case "min", "max":
+		if agg.ints > 0 {
+			return agg.intDeque.first(), true
+		}
+		return agg.floatDeque.first(), true
+	}
+	return nil, false
+}
+
+type monoItem[T int64 | float64] struct {
+	seq int64
+	v   T
+}
+
+// monoDeque keeps the candidates of the min or max value in the window. The values are monotonic from the head so
+// that the head is always the result.
+type monoDeque[T int64 | float64] struct {
+	isMax bool
+	items []monoItem[T]
+}
+
+func (d *monoDeque[T]) push(seq int64, v T) {
+	if d == nil {
+		return
+	}
+	for n := len(d.items); n > 0; n-- {
+		last := d.items[n-1].v
+		if (d.isMax && last > v) || (!d.isMax && last < v) {
+			break
+		}
+		d.items = d.items[:n-1]
+	}
+	d.items = append(d.items, monoItem[T]{seq: seq, v: v})
+}
+
+// evict the value of the expired tuple which is always the oldest
+func (d *monoDeque[T]) evict(seq int64) {
+	if d == nil {
+		return
+	}
+	if len(d.items) > 0 && d.items[0].seq == seq {
+		d.items = d.items[1:]
+	}
+}
+
+func (d *monoDeque[T]) first() T {
+	return d.items[0].v
+}
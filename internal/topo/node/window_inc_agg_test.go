@@ -0,0 +1,86 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+func TestIncAggregator(t *testing.T) {
+	calls := []*ast.Call{
+		{Name: "sum", FuncType: ast.FuncTypeAgg, Args: []ast.Expr{&ast.FieldRef{Name: "a", StreamName: "demo"}}, CachedField: "sum"},
+		{Name: "count", FuncType: ast.FuncTypeAgg, Args: []ast.Expr{&ast.Wildcard{Token: ast.ASTERISK}}, CachedField: "count"},
+		{Name: "avg", FuncType: ast.FuncTypeAgg, Args: []ast.Expr{&ast.FieldRef{Name: "a", StreamName: "demo"}}, CachedField: "avg"},
+		{Name: "min", FuncType: ast.FuncTypeAgg, Args: []ast.Expr{&ast.FieldRef{Name: "a", StreamName: "demo"}}, CachedField: "min"},
+		{Name: "max", FuncType: ast.FuncTypeAgg, Args: []ast.Expr{&ast.FieldRef{Name: "b", StreamName: "demo"}}, CachedField: "max"},
+	}
+	a := newIncAggregator(calls)
+	steps := []struct {
+		add    map[string]interface{}
+		evict  int
+		result map[string]interface{}
+	}{
+		{
+			add:    map[string]interface{}{"a": int64(3), "b": 1.5},
+			result: map[string]interface{}{"sum": int64(3), "count": 1, "avg": int64(3), "min": int64(3), "max": 1.5},
+		},
+		{
+			add:    map[string]interface{}{"a": int64(1), "b": 3.5},
+			result: map[string]interface{}{"sum": int64(4), "count": 2, "avg": int64(2), "min": int64(1), "max": 3.5},
+		},
+		{
+			add:    map[string]interface{}{"a": int64(5)},
+			result: map[string]interface{}{"sum": int64(9), "count": 3, "avg": int64(3), "min": int64(1), "max": 3.5},
+		},
+		{
+			add:    map[string]interface{}{"a": int64(2), "b": 2.5},
+			evict:  2,
+			result: map[string]interface{}{"sum": int64(7), "count": 2, "avg": int64(3), "min": int64(2), "max": 2.5},
+		},
+		{
+			// mixed kinds are calculated by the function
+			add:    map[string]interface{}{"a": 1.5, "b": 0.5},
+			result: map[string]interface{}{"count": 3, "max": 2.5},
+		},
+		{
+			add:    map[string]interface{}{"a": 4.5},
+			evict:  2,
+			result: map[string]interface{}{"sum": 6.0, "count": 2, "avg": 3.0, "min": 1.5, "max": 0.5},
+		},
+		{
+			evict:  2,
+			result: map[string]interface{}{"sum": nil, "count": nil, "avg": nil, "min": nil, "max": nil},
+		},
+	}
+	for i, step := range steps {
+		if step.add != nil {
+			a.add(&xsql.Tuple{Emitter: "demo", Message: step.add})
+		}
+		a.evict(step.evict)
+		row := &xsql.AffiliateRow{}
+		a.fill(row)
+		for _, c := range calls {
+			v, ok := row.Value(c.CachedField, "")
+			expected, has := step.result[c.CachedField]
+			assert.Equal(t, has, ok, "step %d %s", i, c.Name)
+			assert.Equal(t, expected, v, "step %d %s", i, c.Name)
+		}
+	}
+}
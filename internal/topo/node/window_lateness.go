@@ -0,0 +1,115 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+// emittedWindow is a window which has been emitted but can still be updated by the late tuples. It is saved in the
+// state so that the late tuples still update it after the rule restores from a checkpoint.
+type emittedWindow struct {
+	Start   int64
+	End     int64
+	Content []*xsql.Tuple
+}
+
+// retainWindow keeps the content of the window which is going to be emitted so that the late tuples can update it
+func (o *WindowOperator) retainWindow(inputs []*xsql.Tuple, windowEnd int64) {
+	w := &emittedWindow{
+		Start: windowEnd - o.window.Length,
+		End:   windowEnd,
+	}
+	for _, tuple := range inputs {
+		if tuple.Timestamp >= w.Start && tuple.Timestamp < w.End {
+			w.Content = append(w.Content, tuple)
+		}
+	}
+	o.emittedWindows = append(o.emittedWindows, w)
+}
+
+// gcEmittedWindows drops the windows which are beyond the allowed lateness of the watermark
+func (o *WindowOperator) gcEmittedWindows(watermark int64) {
+	i := 0
+	for ; i < len(o.emittedWindows); i++ {
+		if o.emittedWindows[i].End+o.allowedLateness > watermark {
+			break
+		}
+	}
+	o.emittedWindows = o.emittedWindows[i:]
+}
+
+// restoreEmittedWindows restores the emitted windows from the state and returns the end of the last emitted window
+func (o *WindowOperator) restoreEmittedWindows(ctx api.StreamContext) (int64, error) {
+	s, err := ctx.GetState(EmittedWindowsKey)
+	if err != nil || s == nil {
+		return 0, err
+	}
+	ws, ok := s.([]*emittedWindow)
+	if !ok {
+		return 0, fmt.Errorf("restore window state `emittedWindows` %v error, invalid type", s)
+	}
+	o.emittedWindows = ws
+	var end int64
+	for _, w := range ws {
+		if w.End > end {
+			end = w.End
+		}
+	}
+	return end, nil
+}
+
+// updateEmittedWindows adds the late tuple into the emitted windows and emits the updated results.
+// Return false if the tuple does not belong to any emitted window.
+func (o *WindowOperator) updateEmittedWindows(ctx api.StreamContext, tuple *xsql.Tuple) bool {
+	found := false
+	for _, w := range o.emittedWindows {
+		if tuple.Timestamp < w.Start || tuple.Timestamp >= w.End {
+			continue
+		}
+		found = true
+		w.Content = insertTuple(w.Content, tuple)
+		content := make([]xsql.Row, 0, len(w.Content))
+		for _, t := range w.Content {
+			content = append(content, o.spill.resolve(ctx, t))
+		}
+		o.spill.done()
+		results := &xsql.WindowTuples{
+			Content:     content,
+			WindowRange: xsql.NewWindowRange(w.Start, w.End),
+		}
+		ctx.GetLogger().Debugf("window %s updated by late tuple at %d: %v", o.name, tuple.Timestamp, results)
+		traceWindow(ctx, o.name, results)
+		o.Broadcast(results)
+		o.statManager.IncTotalRecordsOut()
+		o.statManager.IncTotalMessagesProcessed(int64(results.Len()))
+	}
+	return found
+}
+
+// insertTuple inserts the tuple into the tuples sorted by timestamp
+func insertTuple(tuples []*xsql.Tuple, tuple *xsql.Tuple) []*xsql.Tuple {
+	index := sort.Search(len(tuples), func(i int) bool {
+		return tuples[i].Timestamp > tuple.Timestamp
+	})
+	tuples = append(tuples, nil)
+	copy(tuples[index+1:], tuples[index:])
+	tuples[index] = tuple
+	return tuples
+}
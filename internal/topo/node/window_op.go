@@ -24,6 +24,7 @@ import (
 
 	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/topo/node/metric"
+	"github.com/lf-edge/ekuiper/internal/topo/vclock"
 	"github.com/lf-edge/ekuiper/internal/xsql"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/ast"
@@ -40,6 +41,10 @@ type WindowConfig struct {
 	Delay            int64
 	RawInterval      int
 	TimeUnit         ast.Token
+	// Keys are the other group by dimensions. For session window, each key has its own session
+	Keys ast.Dimensions
+	// IncAggs are the aggregate calls calculated incrementally by the sliding window
+	IncAggs []*ast.Call
 }
 
 type WindowOperator struct {
@@ -52,6 +57,8 @@ type WindowOperator struct {
 	trigger         *EventTimeTrigger // For event time only
 
 	ticker *clock.Ticker // For processing time only
+	// the clock of the rule to trigger the processing time window
+	clock clock.Clock
 	// states
 	triggerTime      int64
 	msgCount         int
@@ -59,17 +66,31 @@ type WindowOperator struct {
 	triggerTS        []int64
 	triggerCondition ast.Expr
 	stateFuncs       []*ast.Call
+	// early firing options to emit the partial results before the window closes
+	earlyFireInterval  int64
+	earlyFireOnElement bool
+	// allowed lateness for event time window to update the emitted windows
+	allowedLateness int64
+	emittedWindows  []*emittedWindow
+	// spill the buffered tuples to disk when exceeding the memory limit
+	memoryLimit int64
+	spill       *windowSpiller
+	// calculate the aggregates of the processing time sliding window incrementally
+	incAgg *incAggregator
 }
 
 const (
 	WindowInputsKey = "$$windowInputs"
 	TriggerTimeKey  = "$$triggerTime"
 	MsgCountKey     = "$$msgCount"
+	// EmittedWindowsKey is the emitted windows which can still be updated by the late tuples
+	EmittedWindowsKey = "$$emittedWindows"
 )
 
 func init() {
 	gob.Register([]*xsql.Tuple{})
 	gob.Register([]map[string]interface{}{})
+	gob.Register([]*emittedWindow{})
 }
 
 func NewWindowOp(name string, w WindowConfig, options *api.RuleOption) (*WindowOperator, error) {
@@ -94,6 +115,44 @@ func NewWindowOp(name string, w WindowConfig, options *api.RuleOption) (*WindowO
 		o.triggerCondition = w.TriggerCondition
 		o.stateFuncs = w.StateFuncs
 	}
+	if options.EarlyFireInterval > 0 || options.EarlyFireOnElement {
+		if options.IsEventTime {
+			return nil, fmt.Errorf("early firing is only supported for processing time windows")
+		}
+		switch w.Type {
+		case ast.TUMBLING_WINDOW, ast.HOPPING_WINDOW:
+		case ast.SESSION_WINDOW:
+			if len(w.Keys) > 0 {
+				return nil, fmt.Errorf("early firing is not supported for session window with group by keys")
+			}
+		default:
+			return nil, fmt.Errorf("early firing is not supported for %s", w.Type)
+		}
+		o.earlyFireInterval = options.EarlyFireInterval
+		o.earlyFireOnElement = options.EarlyFireOnElement
+	}
+	if options.IsEventTime && options.AllowedLateness > 0 {
+		switch w.Type {
+		case ast.TUMBLING_WINDOW, ast.HOPPING_WINDOW:
+			o.allowedLateness = options.AllowedLateness
+		case ast.NOT_WINDOW:
+		default:
+			return nil, fmt.Errorf("allowedLateness is not supported for %s", w.Type)
+		}
+	}
+	if options.WindowMemoryLimit > 0 {
+		switch w.Type {
+		case ast.TUMBLING_WINDOW, ast.HOPPING_WINDOW, ast.SLIDING_WINDOW:
+			o.memoryLimit = options.WindowMemoryLimit
+		case ast.SESSION_WINDOW:
+			if len(w.Keys) == 0 {
+				o.memoryLimit = options.WindowMemoryLimit
+			}
+		}
+	}
+	if w.Type == ast.SLIDING_WINDOW && !options.IsEventTime && w.Delay == 0 {
+		o.incAgg = newIncAggregator(w.IncAggs)
+	}
 	o.delayTS = make([]int64, 0)
 	o.triggerTS = make([]int64, 0)
 	o.isOverlapWindow = isOverlapWindow(w.Type)
@@ -108,6 +167,7 @@ func (o *WindowOperator) Exec(ctx api.StreamContext, errCh chan<- error) {
 	log := ctx.GetLogger()
 	log.Debugf("Window operator %s is started", o.name)
 	o.statManager = metric.NewStatManager(ctx, "op")
+	o.clock = vclock.Get(ctx)
 	var inputs []*xsql.Tuple
 	if s, err := ctx.GetState(WindowInputsKey); err == nil {
 		switch st := s.(type) {
@@ -124,7 +184,7 @@ func (o *WindowOperator) Exec(ctx api.StreamContext, errCh chan<- error) {
 		log.Warnf("Restore window state fails: %s", err)
 	}
 	if !o.isEventTime {
-		o.triggerTime = conf.GetNowInMilli()
+		o.triggerTime = o.clock.Now().UnixMilli()
 	}
 	if s, err := ctx.GetState(TriggerTimeKey); err == nil && s != nil {
 		if si, ok := s.(int64); ok {
@@ -143,7 +203,31 @@ func (o *WindowOperator) Exec(ctx api.StreamContext, errCh chan<- error) {
 		}
 	}
 	log.Infof("Start with window state triggerTime: %d, msgCount: %d", o.triggerTime, o.msgCount)
-	if o.isEventTime {
+	if o.memoryLimit > 0 {
+		s, err := newWindowSpiller(ctx, o.name, o.memoryLimit, inputs)
+		if err != nil {
+			infra.DrainError(ctx, err, errCh)
+			return
+		}
+		o.spill = s
+	}
+	if o.incAgg != nil {
+		for _, t := range inputs {
+			o.incAgg.add(o.spill.resolve(ctx, t))
+		}
+		o.spill.done()
+	}
+	if o.window.Type == ast.SESSION_WINDOW && len(o.window.Keys) > 0 {
+		go func() {
+			err := infra.SafeRun(func() error {
+				o.execKeyedSessionWindow(ctx, inputs, errCh)
+				return nil
+			})
+			if err != nil {
+				infra.DrainError(ctx, err, errCh)
+			}
+		}()
+	} else if o.isEventTime {
 		go func() {
 			err := infra.SafeRun(func() error {
 				o.execEventWindow(ctx, inputs, errCh)
@@ -202,9 +286,10 @@ func getAlignedWindowEndTime(n time.Time, interval int, timeUnit ast.Token) time
 }
 
 func getFirstTimer(ctx api.StreamContext, rawInerval int, timeUnit ast.Token) (int64, *clock.Timer) {
-	next := getAlignedWindowEndTime(conf.GetNow(), rawInerval, timeUnit)
+	c := vclock.Get(ctx)
+	next := getAlignedWindowEndTime(c.Now(), rawInerval, timeUnit)
 	ctx.GetLogger().Infof("align window timer to %v(%d)", next, next.UnixMilli())
-	return next.UnixMilli(), conf.GetTimerByTime(next)
+	return next.UnixMilli(), vclock.TimerByTime(c, next)
 }
 
 func (o *WindowOperator) execProcessingWindow(ctx api.StreamContext, inputs []*xsql.Tuple, errCh chan<- error) {
@@ -218,6 +303,9 @@ func (o *WindowOperator) execProcessingWindow(ctx api.StreamContext, inputs []*x
 		firstC      <-chan time.Time
 		timeout     <-chan time.Time
 		c           <-chan time.Time
+		// The end time of the current window for early firing
+		currentEnd int64
+		earlyC     <-chan time.Time
 	)
 	switch o.window.Type {
 	case ast.NOT_WINDOW:
@@ -240,11 +328,17 @@ func (o *WindowOperator) execProcessingWindow(ctx api.StreamContext, inputs []*x
 		o.duration = o.window.Length
 	}
 
+	if o.earlyFireInterval > 0 {
+		earlyTicker := o.clock.Ticker(time.Duration(o.earlyFireInterval) * time.Millisecond)
+		defer earlyTicker.Stop()
+		earlyC = earlyTicker.C
+	}
+	currentEnd = firstTime
 	if firstTicker != nil {
 		firstC = firstTicker.C
 		// resume the previous window
 		if len(inputs) > 0 && o.triggerTime > 0 {
-			nextTick := conf.GetNowInMilli() + o.interval
+			nextTick := o.clock.Now().UnixMilli() + o.interval
 			next := o.triggerTime
 			switch o.window.Type {
 			case ast.TUMBLING_WINDOW, ast.HOPPING_WINDOW:
@@ -297,6 +391,10 @@ func (o *WindowOperator) execProcessingWindow(ctx api.StreamContext, inputs []*x
 			}
 		}
 	}
+	// The variables are captured so that the open window is flushed with the latest inputs
+	o.drainHandler = func() {
+		o.flush(ctx, inputs, currentEnd, o.clock.Now().UnixMilli())
+	}
 	delayCh := make(chan int64, 100)
 	for {
 		select {
@@ -326,6 +424,8 @@ func (o *WindowOperator) execProcessingWindow(ctx api.StreamContext, inputs []*x
 			case *xsql.Tuple:
 				log.Debugf("Event window receive tuple %s", d.Message)
 				inputs = append(inputs, d)
+				o.incAgg.add(d)
+				o.spill.add(ctx, d, inputs)
 				switch o.window.Type {
 				case ast.NOT_WINDOW:
 					inputs = o.scan(inputs, d.Timestamp, ctx)
@@ -333,7 +433,7 @@ func (o *WindowOperator) execProcessingWindow(ctx api.StreamContext, inputs []*x
 					if o.isMatchCondition(ctx, d) {
 						if o.window.Delay > 0 {
 							go func(ts int64) {
-								after := time.After(time.Duration(o.window.Delay) * time.Millisecond)
+								after := o.clock.After(time.Duration(o.window.Delay) * time.Millisecond)
 								select {
 								case <-after:
 									delayCh <- ts
@@ -353,7 +453,7 @@ func (o *WindowOperator) execProcessingWindow(ctx api.StreamContext, inputs []*x
 						timeoutTicker.Stop()
 						timeoutTicker.Reset(time.Duration(o.window.Interval) * time.Millisecond)
 					} else {
-						timeoutTicker = conf.GetTimer(o.window.Interval)
+						timeoutTicker = o.clock.Timer(time.Duration(o.window.Interval) * time.Millisecond)
 						timeout = timeoutTicker.C
 						o.triggerTime = d.Timestamp
 						_ = ctx.PutState(TriggerTimeKey, o.triggerTime)
@@ -373,14 +473,15 @@ func (o *WindowOperator) execProcessingWindow(ctx api.StreamContext, inputs []*x
 						return
 					} else {
 						log.Debugf(fmt.Sprintf("It has %d of count window.", tl.count()))
-						triggerTime := conf.GetNowInMilli()
+						triggerTime := o.clock.Now().UnixMilli()
 						for tl.hasMoreCountWindow() {
 							tsets := tl.nextCountWindow()
 							windowStart := triggerTime
-							triggerTime = conf.GetNowInMilli()
+							triggerTime = o.clock.Now().UnixMilli()
 							windowEnd := triggerTime
 							tsets.WindowRange = xsql.NewWindowRange(windowStart, windowEnd)
 							log.Debugf("Sent: %v", tsets)
+							traceWindow(ctx, o.name, tsets)
 							o.Broadcast(tsets)
 							o.statManager.IncTotalRecordsOut()
 							o.statManager.IncTotalMessagesProcessed(int64(tsets.Len()))
@@ -388,6 +489,9 @@ func (o *WindowOperator) execProcessingWindow(ctx api.StreamContext, inputs []*x
 						inputs = tl.getRestTuples()
 					}
 				}
+				if o.earlyFireOnElement {
+					o.emitPartial(ctx, inputs, currentEnd, d.Timestamp)
+				}
 				o.statManager.ProcessTimeEnd()
 				o.statManager.SetBufferLength(int64(len(o.input)))
 				_ = ctx.PutState(WindowInputsKey, inputs)
@@ -404,25 +508,30 @@ func (o *WindowOperator) execProcessingWindow(ctx api.StreamContext, inputs []*x
 			c = o.ticker.C
 			inputs = o.tick(ctx, inputs, firstTime, log)
 			nextTime = firstTime
+			currentEnd = nextTime + o.duration
 		case now := <-c:
 			nextTime += o.duration
 			log.Debugf("Successive tick at %v(%d), defined at %d", now, now.UnixMilli(), nextTime)
 			// If the deviation is less than 50ms, then process it. Otherwise, time may change and we'll start a new timer
 			if now.UnixMilli()-nextTime < 50 {
 				inputs = o.tick(ctx, inputs, nextTime, log)
+				currentEnd = nextTime + o.duration
 			} else {
 				log.Infof("Skip the tick at %v(%d) since it's too late", now, now.UnixMilli())
 				o.ticker.Stop()
 				firstTime, firstTicker = getFirstTimer(ctx, o.window.RawInterval, o.window.TimeUnit)
 				firstC = firstTicker.C
+				currentEnd = firstTime
 			}
+		case now := <-earlyC:
+			o.emitPartial(ctx, inputs, currentEnd, cast.TimeToUnixMilli(now))
 		case now := <-timeout:
 			if len(inputs) > 0 {
 				o.statManager.ProcessTimeStart()
 				log.Debugf("triggered by timeout")
 				inputs = o.scan(inputs, cast.TimeToUnixMilli(now), ctx)
-				_ = inputs
 				// expire all inputs, so that when timer scans there is no item
+				o.spill.remove(inputs)
 				inputs = make([]*xsql.Tuple, 0)
 				o.statManager.ProcessTimeEnd()
 				_ = ctx.PutState(WindowInputsKey, inputs)
@@ -443,11 +552,11 @@ func (o *WindowOperator) execProcessingWindow(ctx api.StreamContext, inputs []*x
 func (o *WindowOperator) setupTicker() {
 	switch o.window.Type {
 	case ast.TUMBLING_WINDOW:
-		o.ticker = conf.GetTicker(o.window.Length)
+		o.ticker = o.clock.Ticker(time.Duration(o.window.Length) * time.Millisecond)
 	case ast.HOPPING_WINDOW:
-		o.ticker = conf.GetTicker(o.window.Interval)
+		o.ticker = o.clock.Ticker(time.Duration(o.window.Interval) * time.Millisecond)
 	case ast.SESSION_WINDOW:
-		o.ticker = conf.GetTicker(o.window.Length)
+		o.ticker = o.clock.Ticker(time.Duration(o.window.Length) * time.Millisecond)
 	}
 }
 
@@ -576,7 +685,7 @@ func (o *WindowOperator) handleInputs(inputs []*xsql.Tuple, right int64, ctx api
 			meet = tuple.Timestamp < right
 		}
 		if meet {
-			content = append(content, tuple)
+			content = append(content, o.spill.resolve(ctx, tuple))
 			if nextleft < 0 && o.isOverlapWindow {
 				nextleft = i
 			}
@@ -586,9 +695,14 @@ func (o *WindowOperator) handleInputs(inputs []*xsql.Tuple, right int64, ctx api
 			}
 		}
 	}
+	o.spill.done()
 	if nextleft < 0 {
+		o.spill.remove(inputs)
+		o.incAgg.evict(len(inputs))
 		return inputs[:0], content
 	}
+	o.spill.remove(inputs[:nextleft])
+	o.incAgg.evict(nextleft)
 	return inputs[nextleft:], content
 }
 
@@ -609,6 +723,7 @@ func (o *WindowOperator) scan(inputs []*xsql.Tuple, triggerTime int64, ctx api.S
 	results := &xsql.WindowTuples{
 		Content: content,
 	}
+	o.incAgg.fill(&results.AffiliateRow)
 	switch o.window.Type {
 	case ast.TUMBLING_WINDOW, ast.SESSION_WINDOW:
 		windowStart = o.triggerTime
@@ -623,6 +738,7 @@ func (o *WindowOperator) scan(inputs []*xsql.Tuple, triggerTime int64, ctx api.S
 	results.WindowRange = xsql.NewWindowRange(windowStart, windowEnd)
 	log.Debugf("window %s triggered for %d tuples", o.name, len(inputs))
 	log.Debugf("Sent: %v", results)
+	traceWindow(ctx, o.name, results)
 	o.Broadcast(results)
 	o.statManager.IncTotalRecordsOut()
 	o.statManager.IncTotalMessagesProcessed(int64(results.Len()))
@@ -632,6 +748,69 @@ func (o *WindowOperator) scan(inputs []*xsql.Tuple, triggerTime int64, ctx api.S
 	return inputs
 }
 
+// emitPartial sends the partial result of the current window which ends at windowEnd.
+// The inputs are not changed so that they are still emitted when the window closes.
+func (o *WindowOperator) emitPartial(ctx api.StreamContext, inputs []*xsql.Tuple, windowEnd int64, now int64) {
+	var windowStart int64
+	if o.window.Type == ast.SESSION_WINDOW {
+		if len(inputs) > 0 {
+			windowStart = inputs[0].Timestamp
+		}
+		windowEnd = now
+	} else {
+		windowStart = windowEnd - o.window.Length
+	}
+	content := make([]xsql.Row, 0, len(inputs))
+	for _, tuple := range inputs {
+		if tuple.Timestamp >= windowStart && tuple.Timestamp < windowEnd {
+			content = append(content, o.spill.resolve(ctx, tuple))
+		}
+	}
+	o.spill.done()
+	if len(content) == 0 {
+		return
+	}
+	results := &xsql.WindowTuples{
+		Content:     content,
+		WindowRange: xsql.NewPartialWindowRange(windowStart, windowEnd),
+	}
+	ctx.GetLogger().Debugf("window %s emits partial result at %d: %v", o.name, now, results)
+	traceWindow(ctx, o.name, results)
+	o.Broadcast(results)
+	o.statManager.IncTotalRecordsOut()
+}
+
+// flush sends the content of the open window as a partial result when the rule is draining
+func (o *WindowOperator) flush(ctx api.StreamContext, inputs []*xsql.Tuple, windowEnd int64, now int64) {
+	switch o.window.Type {
+	case ast.TUMBLING_WINDOW, ast.HOPPING_WINDOW:
+		if windowEnd > 0 {
+			o.emitPartial(ctx, inputs, windowEnd, now)
+		}
+	case ast.SESSION_WINDOW:
+		o.emitPartial(ctx, inputs, windowEnd, now)
+	case ast.COUNT_WINDOW:
+		if o.msgCount == 0 || len(inputs) == 0 {
+			return
+		}
+		if l := int(o.window.Length); len(inputs) > l {
+			inputs = inputs[len(inputs)-l:]
+		}
+		content := make([]xsql.Row, 0, len(inputs))
+		for _, tuple := range inputs {
+			content = append(content, o.spill.resolve(ctx, tuple))
+		}
+		o.spill.done()
+		results := &xsql.WindowTuples{
+			Content:     content,
+			WindowRange: xsql.NewPartialWindowRange(inputs[0].Timestamp, now),
+		}
+		traceWindow(ctx, o.name, results)
+		o.Broadcast(results)
+		o.statManager.IncTotalRecordsOut()
+	}
+}
+
 func (o *WindowOperator) calDelta(triggerTime int64, log api.Logger) int64 {
 	var delta int64
 	lastTriggerTime := o.triggerTime
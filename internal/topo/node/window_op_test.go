@@ -15,6 +15,8 @@
 package node
 
 import (
+	"bytes"
+	"encoding/gob"
 	"fmt"
 	"reflect"
 	"testing"
@@ -23,7 +25,10 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/topo/node/metric"
+	"github.com/lf-edge/ekuiper/internal/topo/state"
 	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/ast"
 )
 
@@ -378,3 +383,130 @@ func TestGCInputsForConditionNotMatch(t *testing.T) {
 		},
 	}, inputs)
 }
+
+func TestEarlyFireOption(t *testing.T) {
+	tests := []struct {
+		w   WindowConfig
+		o   *api.RuleOption
+		err string
+	}{
+		{
+			w: WindowConfig{Type: ast.TUMBLING_WINDOW, Length: 1000},
+			o: &api.RuleOption{EarlyFireInterval: 100},
+		},
+		{
+			w: WindowConfig{Type: ast.SESSION_WINDOW, Length: 1000, Interval: 100},
+			o: &api.RuleOption{EarlyFireOnElement: true},
+		},
+		{
+			w:   WindowConfig{Type: ast.SLIDING_WINDOW, Length: 1000},
+			o:   &api.RuleOption{EarlyFireOnElement: true},
+			err: "early firing is not supported for SLIDING_WINDOW",
+		},
+		{
+			w:   WindowConfig{Type: ast.SESSION_WINDOW, Length: 1000, Interval: 100, Keys: ast.Dimensions{{Expr: &ast.FieldRef{Name: "id"}}}},
+			o:   &api.RuleOption{EarlyFireOnElement: true},
+			err: "early firing is not supported for session window with group by keys",
+		},
+		{
+			w:   WindowConfig{Type: ast.TUMBLING_WINDOW, Length: 1000},
+			o:   &api.RuleOption{EarlyFireInterval: 100, IsEventTime: true},
+			err: "early firing is only supported for processing time windows",
+		},
+	}
+	for i, tt := range tests {
+		_, err := NewWindowOp("test", tt.w, tt.o)
+		if tt.err == "" {
+			require.NoError(t, err, i)
+		} else {
+			require.EqualError(t, err, tt.err, i)
+		}
+	}
+}
+
+func TestEmitPartial(t *testing.T) {
+	o, err := NewWindowOp("test", WindowConfig{Type: ast.HOPPING_WINDOW, Length: 2000, Interval: 1000}, &api.RuleOption{EarlyFireOnElement: true})
+	require.NoError(t, err)
+	ctx := context.Background().WithMeta("test", "test", &state.MemoryStore{})
+	o.ctx = ctx
+	o.statManager = metric.NewStatManager(ctx, "op")
+	out := make(chan interface{}, 10)
+	o.outputs = map[string]chan<- interface{}{"out": out}
+	inputs := []*xsql.Tuple{{Timestamp: 500}, {Timestamp: 1500}, {Timestamp: 2500}}
+	// the current window is [1000, 3000)
+	o.emitPartial(ctx, inputs, 3000, 2600)
+	r := (<-out).(*xsql.WindowTuples)
+	require.Equal(t, []xsql.Row{inputs[1], inputs[2]}, r.Content)
+	v, _ := r.WindowRange.FuncValue("window_partial")
+	require.Equal(t, true, v)
+	v, _ = r.WindowRange.FuncValue("window_start")
+	require.Equal(t, int64(1000), v)
+	// nothing to emit
+	o.emitPartial(ctx, inputs[:1], 3000, 2600)
+	require.Len(t, out, 0)
+}
+
+func TestAllowedLatenessOption(t *testing.T) {
+	o, err := NewWindowOp("test", WindowConfig{Type: ast.HOPPING_WINDOW, Length: 2000, Interval: 1000}, &api.RuleOption{IsEventTime: true, AllowedLateness: 1000})
+	require.NoError(t, err)
+	require.Equal(t, int64(1000), o.allowedLateness)
+	// ignored for processing time
+	o, err = NewWindowOp("test", WindowConfig{Type: ast.SLIDING_WINDOW, Length: 2000}, &api.RuleOption{AllowedLateness: 1000})
+	require.NoError(t, err)
+	require.Equal(t, int64(0), o.allowedLateness)
+	_, err = NewWindowOp("test", WindowConfig{Type: ast.SLIDING_WINDOW, Length: 2000}, &api.RuleOption{IsEventTime: true, AllowedLateness: 1000})
+	require.EqualError(t, err, "allowedLateness is not supported for SLIDING_WINDOW")
+}
+
+func TestUpdateEmittedWindows(t *testing.T) {
+	o, err := NewWindowOp("test", WindowConfig{Type: ast.HOPPING_WINDOW, Length: 2000, Interval: 1000}, &api.RuleOption{IsEventTime: true, AllowedLateness: 1000})
+	require.NoError(t, err)
+	ctx := context.Background().WithMeta("test", "test", &state.MemoryStore{})
+	o.ctx = ctx
+	o.statManager = metric.NewStatManager(ctx, "op")
+	out := make(chan interface{}, 10)
+	o.outputs = map[string]chan<- interface{}{"out": out}
+	inputs := []*xsql.Tuple{{Timestamp: 500}, {Timestamp: 1500}, {Timestamp: 2500}}
+	o.retainWindow(inputs, 2000)
+	o.retainWindow(inputs, 3000)
+	// the late tuple updates both windows
+	require.True(t, o.updateEmittedWindows(ctx, &xsql.Tuple{Timestamp: 1200}))
+	r := (<-out).(*xsql.WindowTuples)
+	require.Equal(t, []xsql.Row{inputs[0], &xsql.Tuple{Timestamp: 1200}, inputs[1]}, r.Content)
+	r = (<-out).(*xsql.WindowTuples)
+	require.Equal(t, []xsql.Row{&xsql.Tuple{Timestamp: 1200}, inputs[1], inputs[2]}, r.Content)
+	// the first window is beyond the allowed lateness
+	o.gcEmittedWindows(3000)
+	require.Len(t, o.emittedWindows, 1)
+	require.False(t, o.updateEmittedWindows(ctx, &xsql.Tuple{Timestamp: 800}))
+	require.Len(t, out, 0)
+}
+
+func TestRestoreEmittedWindows(t *testing.T) {
+	o, err := NewWindowOp("test", WindowConfig{Type: ast.HOPPING_WINDOW, Length: 2000, Interval: 1000}, &api.RuleOption{IsEventTime: true, AllowedLateness: 1000})
+	require.NoError(t, err)
+	ctx := context.Background().WithMeta("test", "test", &state.MemoryStore{})
+	inputs := []*xsql.Tuple{{Emitter: "demo", Message: xsql.Message{"a": 1}, Timestamp: 500}, {Emitter: "demo", Message: xsql.Message{"a": 2}, Timestamp: 1500}}
+	o.retainWindow(inputs, 2000)
+	o.retainWindow(inputs, 3000)
+	// The state is encoded in the checkpoint
+	var buf bytes.Buffer
+	var st interface{} = o.emittedWindows
+	require.NoError(t, gob.NewEncoder(&buf).Encode(&st))
+	var decoded interface{}
+	require.NoError(t, gob.NewDecoder(&buf).Decode(&decoded))
+	require.NoError(t, ctx.PutState(EmittedWindowsKey, decoded))
+
+	r, err := NewWindowOp("test", WindowConfig{Type: ast.HOPPING_WINDOW, Length: 2000, Interval: 1000}, &api.RuleOption{IsEventTime: true, AllowedLateness: 1000})
+	require.NoError(t, err)
+	end, err := r.restoreEmittedWindows(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(3000), end)
+	require.Len(t, r.emittedWindows, 2)
+	require.Equal(t, int64(1000), r.emittedWindows[1].Start)
+	require.Equal(t, xsql.Message{"a": 2}, r.emittedWindows[1].Content[0].Message)
+
+	require.NoError(t, ctx.PutState(EmittedWindowsKey, "invalid"))
+	_, err = r.restoreEmittedWindows(ctx)
+	require.EqualError(t, err, "restore window state `emittedWindows` invalid error, invalid type")
+}
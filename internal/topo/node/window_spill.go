@@ -0,0 +1,309 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+const (
+	spillSegmentKey = "$$spillSegment"
+	spillIndexKey   = "$$spillIndex"
+	spillFileExt    = ".seg"
+)
+
+// spillRecord is the content of a spilled tuple
+type spillRecord struct {
+	Message  map[string]interface{}
+	Metadata map[string]interface{}
+	CalCols  map[string]interface{}
+	AliasMap map[string]interface{}
+}
+
+// windowSpiller moves the oldest buffered tuples of a window to the compressed segments on disk when the
+// estimated memory size exceeds the limit. The spilled tuples are replaced by the stubs in the window inputs
+// which only keep the timestamp and the segment reference, so that they are still saved in the checkpoint.
+// A nil spiller does nothing.
+type windowSpiller struct {
+	dir   string
+	limit int64
+	// the estimated size of the tuples in memory
+	resident int64
+	sizes    map[*xsql.Tuple]int64
+	// the count of the live stubs of each segment
+	segRefs map[int64]int
+	nextSeg int64
+	// the segments loaded in a read
+	loaded map[int64][]*spillRecord
+}
+
+func newWindowSpiller(ctx api.StreamContext, name string, limit int64, inputs []*xsql.Tuple) (*windowSpiller, error) {
+	dataDir, err := conf.GetDataLoc()
+	if err != nil {
+		return nil, err
+	}
+	s := &windowSpiller{
+		dir:     filepath.Join(dataDir, "spill", ctx.GetRuleId(), name),
+		limit:   limit,
+		sizes:   make(map[*xsql.Tuple]int64),
+		segRefs: make(map[int64]int),
+		nextSeg: 1,
+	}
+	if err := os.MkdirAll(s.dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("fail to create window spill directory %s: %v", s.dir, err)
+	}
+	// Rebuild from the restored inputs which may contain the stubs of the spilled tuples
+	for _, t := range inputs {
+		if seg, _, ok := spillRef(t); ok {
+			s.segRefs[seg]++
+			if seg >= s.nextSeg {
+				s.nextSeg = seg + 1
+			}
+		} else {
+			size := estimateSize(t.Message)
+			s.sizes[t] = size
+			s.resident += size
+		}
+	}
+	// Clean up the segments which are not referred
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		seg, err := strconv.ParseInt(strings.TrimSuffix(e.Name(), spillFileExt), 10, 64)
+		if err != nil || s.segRefs[seg] == 0 {
+			_ = os.Remove(filepath.Join(s.dir, e.Name()))
+		}
+	}
+	ctx.GetLogger().Infof("window %s spills to %s when the memory exceeds %d bytes", name, s.dir, limit)
+	return s, nil
+}
+
+// add accounts the new tuple and spills the oldest tuples in the inputs if the memory exceeds the limit
+func (s *windowSpiller) add(ctx api.StreamContext, t *xsql.Tuple, inputs []*xsql.Tuple) {
+	if s == nil {
+		return
+	}
+	size := estimateSize(t.Message)
+	s.sizes[t] = size
+	s.resident += size
+	if s.resident <= s.limit {
+		return
+	}
+	// Spill until half of the limit to avoid spilling for each tuple
+	target := s.resident - s.limit/2
+	var (
+		records []*spillRecord
+		indexes []int
+		freed   int64
+	)
+	for i, ti := range inputs {
+		if freed >= target {
+			break
+		}
+		size, ok := s.sizes[ti]
+		if !ok {
+			continue
+		}
+		records = append(records, &spillRecord{
+			Message:  ti.Message,
+			Metadata: ti.Metadata,
+			CalCols:  ti.CalCols,
+			AliasMap: ti.AliasMap,
+		})
+		indexes = append(indexes, i)
+		freed += size
+	}
+	if len(records) == 0 {
+		return
+	}
+	seg := s.nextSeg
+	if err := s.writeSegment(seg, records); err != nil {
+		ctx.GetLogger().Errorf("window spill error: %v", err)
+		return
+	}
+	s.nextSeg++
+	s.segRefs[seg] = len(indexes)
+	for j, i := range indexes {
+		ti := inputs[i]
+		delete(s.sizes, ti)
+		// Replace with a new stub because the tuple may have been emitted and read by the downstream
+		inputs[i] = &xsql.Tuple{
+			Emitter:   ti.Emitter,
+			Timestamp: ti.Timestamp,
+			Message:   xsql.Message{spillSegmentKey: seg, spillIndexKey: j},
+		}
+	}
+	s.resident -= freed
+	ctx.GetLogger().Debugf("window spills %d tuples to segment %d", len(indexes), seg)
+}
+
+// remove releases the tuples which are removed from the window
+func (s *windowSpiller) remove(tuples []*xsql.Tuple) {
+	if s == nil {
+		return
+	}
+	for _, t := range tuples {
+		if seg, _, ok := spillRef(t); ok {
+			s.segRefs[seg]--
+			if s.segRefs[seg] <= 0 {
+				delete(s.segRefs, seg)
+				_ = os.Remove(s.segmentPath(seg))
+			}
+		} else if size, ok := s.sizes[t]; ok {
+			s.resident -= size
+			delete(s.sizes, t)
+		}
+	}
+}
+
+// resolve returns the tuple with the content read back from the segment if it is spilled.
+// Call done after the read to release the loaded segments.
+func (s *windowSpiller) resolve(ctx api.StreamContext, t *xsql.Tuple) *xsql.Tuple {
+	if s == nil {
+		return t
+	}
+	seg, index, ok := spillRef(t)
+	if !ok {
+		return t
+	}
+	if s.loaded == nil {
+		s.loaded = make(map[int64][]*spillRecord)
+	}
+	records, ok := s.loaded[seg]
+	if !ok {
+		var err error
+		records, err = s.readSegment(seg)
+		if err != nil {
+			ctx.GetLogger().Errorf("window spill read error: %v", err)
+		}
+		s.loaded[seg] = records
+	}
+	if index >= len(records) {
+		return t
+	}
+	r := records[index]
+	nt := &xsql.Tuple{
+		Emitter:   t.Emitter,
+		Timestamp: t.Timestamp,
+		Message:   r.Message,
+		Metadata:  r.Metadata,
+	}
+	nt.CalCols = r.CalCols
+	nt.AliasMap = r.AliasMap
+	return nt
+}
+
+func (s *windowSpiller) done() {
+	if s == nil {
+		return
+	}
+	s.loaded = nil
+}
+
+func (s *windowSpiller) segmentPath(seg int64) string {
+	return filepath.Join(s.dir, strconv.FormatInt(seg, 10)+spillFileExt)
+}
+
+func (s *windowSpiller) writeSegment(seg int64, records []*spillRecord) error {
+	f, err := os.OpenFile(s.segmentPath(seg), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := gzip.NewWriter(f)
+	if err := gob.NewEncoder(w).Encode(records); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func (s *windowSpiller) readSegment(seg int64) ([]*spillRecord, error) {
+	f, err := os.Open(s.segmentPath(seg))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var records []*spillRecord
+	if err := gob.NewDecoder(r).Decode(&records); err != nil {
+		return nil, fmt.Errorf("decode segment %d error: %v", seg, err)
+	}
+	return records, nil
+}
+
+func spillRef(t *xsql.Tuple) (int64, int, bool) {
+	if len(t.Message) != 2 {
+		return 0, 0, false
+	}
+	seg, ok := t.Message[spillSegmentKey].(int64)
+	if !ok {
+		return 0, 0, false
+	}
+	index, ok := t.Message[spillIndexKey].(int)
+	if !ok {
+		return 0, 0, false
+	}
+	return seg, index, true
+}
+
+// estimateSize returns the approximate memory size in bytes of a decoded message value
+func estimateSize(v interface{}) int64 {
+	switch vt := v.(type) {
+	case nil:
+		return 8
+	case string:
+		return 16 + int64(len(vt))
+	case []byte:
+		return 24 + int64(len(vt))
+	case xsql.Message:
+		return estimateSize(map[string]interface{}(vt))
+	case map[string]interface{}:
+		size := int64(48)
+		for k, e := range vt {
+			size += 16 + int64(len(k)) + estimateSize(e)
+		}
+		return size
+	case []interface{}:
+		size := int64(24)
+		for _, e := range vt {
+			size += estimateSize(e)
+		}
+		return size
+	case []map[string]interface{}:
+		size := int64(24)
+		for _, e := range vt {
+			size += estimateSize(e)
+		}
+		return size
+	default:
+		return 16
+	}
+}
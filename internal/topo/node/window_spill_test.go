@@ -0,0 +1,103 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/topo/node/metric"
+	"github.com/lf-edge/ekuiper/internal/topo/state"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+func TestWindowMemoryLimitOption(t *testing.T) {
+	o, err := NewWindowOp("test", WindowConfig{Type: ast.SLIDING_WINDOW, Length: 2000}, &api.RuleOption{WindowMemoryLimit: 1024})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1024), o.memoryLimit)
+	// not supported for count window and keyed session window
+	o, err = NewWindowOp("test", WindowConfig{Type: ast.COUNT_WINDOW, Length: 10}, &api.RuleOption{WindowMemoryLimit: 1024})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), o.memoryLimit)
+	o, err = NewWindowOp("test", WindowConfig{Type: ast.SESSION_WINDOW, Length: 2000, Interval: 100, Keys: ast.Dimensions{{Expr: &ast.FieldRef{Name: "id"}}}}, &api.RuleOption{WindowMemoryLimit: 1024})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), o.memoryLimit)
+}
+
+func TestWindowSpill(t *testing.T) {
+	o, err := NewWindowOp("spillWindow", WindowConfig{Type: ast.SLIDING_WINDOW, Length: 10000}, &api.RuleOption{WindowMemoryLimit: 1000})
+	require.NoError(t, err)
+	ctx := context.Background().WithMeta("spillRule", "spillWindow", &state.MemoryStore{})
+	o.ctx = ctx
+	o.statManager = metric.NewStatManager(ctx, "op")
+	out := make(chan interface{}, 10)
+	o.outputs = map[string]chan<- interface{}{"out": out}
+	o.spill, err = newWindowSpiller(ctx, o.name, o.memoryLimit, nil)
+	require.NoError(t, err)
+	defer os.RemoveAll(o.spill.dir)
+
+	var (
+		inputs   []*xsql.Tuple
+		expected []xsql.Row
+	)
+	for i := 0; i < 20; i++ {
+		tuple := &xsql.Tuple{
+			Emitter:   "demo",
+			Timestamp: int64(i * 100),
+			Message:   xsql.Message{"id": i, "payload": strings.Repeat("a", 100)},
+			Metadata:  xsql.Metadata{"topic": "demo"},
+		}
+		inputs = append(inputs, tuple)
+		o.spill.add(ctx, tuple, inputs)
+		expected = append(expected, &xsql.Tuple{Emitter: "demo", Timestamp: tuple.Timestamp, Message: tuple.Message, Metadata: tuple.Metadata})
+	}
+	assert.LessOrEqual(t, o.spill.resident, int64(1000))
+	_, _, ok := spillRef(inputs[0])
+	require.True(t, ok)
+	_, _, ok = spillRef(inputs[19])
+	require.False(t, ok)
+	segments, err := os.ReadDir(o.spill.dir)
+	require.NoError(t, err)
+	require.NotEmpty(t, segments)
+
+	// Restore from the inputs with the stubs
+	s, err := newWindowSpiller(ctx, o.name, o.memoryLimit, inputs)
+	require.NoError(t, err)
+	assert.Equal(t, o.spill.segRefs, s.segRefs)
+	assert.Equal(t, o.spill.nextSeg, s.nextSeg)
+	assert.Equal(t, o.spill.resident, s.resident)
+
+	// The spilled tuples are read back when triggered, and the expired ones are released
+	o.triggerTime = 11000
+	inputs = o.scan(inputs, 11500, ctx)
+	r := (<-out).(*xsql.WindowTuples)
+	assert.Equal(t, expected[15:], r.Content)
+	assert.Len(t, inputs, 5)
+	inputs = o.scan(inputs, 12000, ctx)
+	<-out
+	assert.Empty(t, inputs)
+	assert.Empty(t, o.spill.segRefs)
+	assert.Equal(t, int64(0), o.spill.resident)
+	segments, err = os.ReadDir(o.spill.dir)
+	require.NoError(t, err)
+	assert.Empty(t, segments)
+}
@@ -0,0 +1,110 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+// columnProcessor fills the default values and calculates the computed fields declared in the stream definition
+type columnProcessor struct {
+	// columns are the fields with default value or computed expression in the definition order
+	columns []ast.StreamField
+	// types are the pruned stream fields including the computed fields
+	types map[string]*ast.JsonStreamField
+	fv    *xsql.FunctionValuer
+}
+
+// setColumns keeps the fields with default value or computed expression among the selected fields
+func (c *columnProcessor) setColumns(fields ast.StreamFields, selected map[string]*ast.JsonStreamField) {
+	c.types = selected
+	for _, f := range fields {
+		if f.Default == nil && f.Computed == nil {
+			continue
+		}
+		if _, ok := selected[f.Name]; !ok {
+			continue
+		}
+		c.columns = append(c.columns, f)
+	}
+}
+
+func (c *columnProcessor) hasColumns() bool {
+	return len(c.columns) > 0
+}
+
+// withoutComputed returns the schema to validate the incoming message which does not have the computed fields
+func (c *columnProcessor) withoutComputed(schema map[string]*ast.JsonStreamField) map[string]*ast.JsonStreamField {
+	var result map[string]*ast.JsonStreamField
+	for _, f := range c.columns {
+		if f.Computed == nil {
+			continue
+		}
+		if result == nil {
+			result = make(map[string]*ast.JsonStreamField, len(schema))
+			for k, v := range schema {
+				result[k] = v
+			}
+		}
+		delete(result, f.Name)
+	}
+	if result == nil {
+		return schema
+	}
+	return result
+}
+
+// fillDefaults sets the default values for the missing or null fields
+func (c *columnProcessor) fillDefaults(message xsql.Message) {
+	for _, f := range c.columns {
+		if f.Default == nil {
+			continue
+		}
+		if v, ok := message[f.Name]; !ok || v == nil {
+			message[f.Name] = (&xsql.ValuerEval{}).Eval(f.Default.Expr)
+		}
+	}
+}
+
+// compute calculates the computed fields in the definition order, so they can refer to the previous computed fields.
+// If the field processor is set, the results are converted to the declared types.
+func (c *columnProcessor) compute(ctx api.StreamContext, tuple *xsql.Tuple, fp *defaultFieldProcessor) error {
+	if c.fv == nil {
+		c.fv, _ = xsql.NewFunctionValuersForOp(ctx)
+	}
+	ve := &xsql.ValuerEval{Valuer: xsql.MultiValuer(tuple, c.fv)}
+	for _, f := range c.columns {
+		if f.Computed == nil {
+			continue
+		}
+		v := ve.Eval(f.Computed.Expr)
+		if e, ok := v.(error); ok {
+			return fmt.Errorf("cannot compute field %s: %v", f.Name, e)
+		}
+		if fp != nil && v != nil {
+			cv, err := fp.validateAndConvertField(c.types[f.Name], v)
+			if err != nil {
+				return fmt.Errorf("computed field %s type mismatch: %v", f.Name, err)
+			}
+			v = cv
+		}
+		tuple.Message[f.Name] = v
+	}
+	return nil
+}
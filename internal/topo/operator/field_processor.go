@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"sort"
 	"time"
 
 	"github.com/lf-edge/ekuiper/internal/xsql"
@@ -52,8 +53,59 @@ func (p *defaultFieldProcessor) validateAndConvertMessage(schema map[string]*ast
 	return message, nil
 }
 
+// validateFields validates and converts all the fields of the message and returns the errors of each invalid field
+func (p *defaultFieldProcessor) validateFields(message xsql.Message) []map[string]interface{} {
+	var errs []map[string]interface{}
+	for _, name := range sortedFieldNames(p.streamFields) {
+		v, ok := message.Value(name, "")
+		if !ok {
+			errs = append(errs, map[string]interface{}{"field": name, "error": "field is not found"})
+			continue
+		}
+		if nv, err := p.validateAndConvertField(p.streamFields[name], v); err != nil {
+			errs = append(errs, map[string]interface{}{"field": name, "error": err.Error()})
+		} else {
+			message[name] = nv
+		}
+	}
+	return errs
+}
+
+// coerceMessage converts the field values to the types in schema across kinds, such as the string "1" to bigint.
+// The missing fields and the values which cannot be converted are set to nil. It returns the names of them.
+func (p *defaultFieldProcessor) coerceMessage(schema map[string]*ast.JsonStreamField, message xsql.Message) []string {
+	var nulls []string
+	for _, name := range sortedFieldNames(schema) {
+		v, ok := message.Value(name, "")
+		if ok && v != nil {
+			if nv, err := p.convertField(schema[name], v, cast.CONVERT_ALL); err == nil {
+				message[name] = nv
+				continue
+			}
+		}
+		message[name] = nil
+		nulls = append(nulls, name)
+	}
+	return nulls
+}
+
+func sortedFieldNames(schema map[string]*ast.JsonStreamField) []string {
+	names := make([]string, 0, len(schema))
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // Validate and convert field value to the type defined in schema
 func (p *defaultFieldProcessor) validateAndConvertField(sf *ast.JsonStreamField, t interface{}) (interface{}, error) {
+	return p.convertField(sf, t, cast.CONVERT_SAMEKIND)
+}
+
+// convertField converts the field value with the strictness. The strict conversion fails for the invalid nested
+// values while the coercion sets them to nil.
+func (p *defaultFieldProcessor) convertField(sf *ast.JsonStreamField, t interface{}, sn cast.Strictness) (interface{}, error) {
 	v := reflect.ValueOf(t)
 	jtype := v.Kind()
 	switch sf.Type {
@@ -61,26 +113,26 @@ func (p *defaultFieldProcessor) validateAndConvertField(sf *ast.JsonStreamField,
 		if jtype == reflect.Int64 {
 			return t, nil
 		}
-		return cast.ToInt64(t, cast.CONVERT_SAMEKIND)
+		return cast.ToInt64(t, sn)
 	case (ast.FLOAT).String():
 		if jtype == reflect.Float64 {
 			return t, nil
 		}
-		return cast.ToFloat64(t, cast.CONVERT_SAMEKIND)
+		return cast.ToFloat64(t, sn)
 	case (ast.BOOLEAN).String():
 		if jtype == reflect.Bool {
 			return t, nil
 		}
-		return cast.ToBool(t, cast.CONVERT_SAMEKIND)
+		return cast.ToBool(t, sn)
 	case (ast.STRINGS).String():
 		if jtype == reflect.String {
 			return t, nil
 		}
-		return cast.ToString(t, cast.CONVERT_SAMEKIND)
+		return cast.ToString(t, sn)
 	case (ast.DATETIME).String():
 		return cast.InterfaceToTime(t, p.timestampFormat)
 	case (ast.BYTEA).String():
-		return cast.ToByteA(t, cast.CONVERT_SAMEKIND)
+		return cast.ToByteA(t, sn)
 	case (ast.ARRAY).String():
 		if t == nil {
 			return []interface{}(nil), nil
@@ -90,8 +142,12 @@ func (p *defaultFieldProcessor) validateAndConvertField(sf *ast.JsonStreamField,
 				return nil, fmt.Errorf("cannot convert %v to []interface{}", t)
 			}
 			for i, e := range a {
-				ne, err := p.validateAndConvertField(sf.Items, e)
+				ne, err := p.convertField(sf.Items, e, sn)
 				if err != nil {
+					if sn == cast.CONVERT_ALL {
+						a[i] = nil
+						continue
+					}
 					return nil, fmt.Errorf("array element type mismatch: %v", err)
 				}
 				if ne != nil {
@@ -122,6 +178,10 @@ func (p *defaultFieldProcessor) validateAndConvertField(sf *ast.JsonStreamField,
 		} else {
 			return nil, fmt.Errorf("expect struct but found %[1]T(%[1]v)", t)
 		}
+		if sn == cast.CONVERT_ALL {
+			p.coerceMessage(sf.Properties, nextJ)
+			return nextJ, nil
+		}
 		return p.validateAndConvertMessage(sf.Properties, nextJ)
 	default:
 		return nil, fmt.Errorf("unsupported type %s", sf.Type)
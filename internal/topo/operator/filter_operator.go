@@ -39,7 +39,8 @@ func (p *FilterOp) Apply(ctx api.StreamContext, data interface{}, fv *xsql.Funct
 	case error:
 		return input
 	case xsql.Row:
-		ve := &xsql.ValuerEval{Valuer: xsql.MultiValuer(input, fv)}
+		ve := xsql.GetValuerEval(input, fv)
+		defer xsql.PutValuerEval(ve)
 		result := ve.Eval(p.Condition)
 		switch r := result.(type) {
 		case error:
@@ -59,8 +60,9 @@ func (p *FilterOp) Apply(ctx api.StreamContext, data interface{}, fv *xsql.Funct
 	case xsql.Collection:
 		var sel []int
 		err := input.Range(func(i int, r xsql.ReadonlyRow) (bool, error) {
-			ve := &xsql.ValuerEval{Valuer: xsql.MultiValuer(r, fv)}
+			ve := xsql.GetValuerEval(r, fv)
 			result := ve.Eval(p.Condition)
+			xsql.PutValuerEval(ve)
 			switch val := result.(type) {
 			case error:
 				return false, fmt.Errorf("run Where error: %s", val)
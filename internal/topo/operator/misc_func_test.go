@@ -871,6 +871,89 @@ func TestJsonPathFunc_Apply1(t *testing.T) {
 	}
 }
 
+func TestLambdaFunc_Apply1(t *testing.T) {
+	// the project op modifies the tuple, so create a new one for each case
+	newData := func() *xsql.Tuple {
+		return &xsql.Tuple{
+			Emitter: "test",
+			Message: xsql.Message{
+				"a":      []interface{}{1, 2, 3},
+				"b":      []interface{}{2, 3, 4},
+				"offset": 10,
+				"rings": []interface{}{
+					map[string]interface{}{"name": "ring of despair", "weight": 0.1},
+					map[string]interface{}{"name": "ring of strength", "weight": 2.4},
+				},
+				"obj": map[string]interface{}{"x": 1, "y": 2},
+			},
+		}
+	}
+	tests := []struct {
+		sql    string
+		result interface{}
+		err    string
+	}{
+		{
+			sql:    `SELECT array_map(x -> x * 2, a) AS r FROM test`,
+			result: []map[string]interface{}{{"r": []interface{}{int64(2), int64(4), int64(6)}}},
+		}, {
+			sql:    `SELECT array_map((x) -> x + offset, a) AS r FROM test`,
+			result: []map[string]interface{}{{"r": []interface{}{int64(11), int64(12), int64(13)}}},
+		}, {
+			sql:    `SELECT array_map(x -> upper(x->name), rings) AS r FROM test`,
+			result: []map[string]interface{}{{"r": []interface{}{"RING OF DESPAIR", "RING OF STRENGTH"}}},
+		}, {
+			sql:    `SELECT array_filter(x -> x.weight > 1, rings) AS r FROM test`,
+			result: []map[string]interface{}{{"r": []interface{}{map[string]interface{}{"name": "ring of strength", "weight": 2.4}}}},
+		}, {
+			sql:    `SELECT array_reduce((acc, x) -> acc + x, a, 0) AS r FROM test`,
+			result: []map[string]interface{}{{"r": int64(6)}},
+		}, {
+			sql:    `SELECT array_map(x -> array_filter(y -> y > x, b), a) AS r FROM test`,
+			result: []map[string]interface{}{{"r": []interface{}{[]interface{}{2, 3, 4}, []interface{}{3, 4}, []interface{}{4}}}},
+		}, {
+			sql:    `SELECT object_filter((k, v) -> v > 1, obj) AS r, object_map((k, v) -> concat(k, "=", cast(v, "string")), obj) AS s FROM test`,
+			result: []map[string]interface{}{{"r": map[string]interface{}{"y": 2}, "s": map[string]interface{}{"x": "x=1", "y": "y=2"}}},
+		}, {
+			sql:    `SELECT array_map("abs", b) AS r FROM test`,
+			result: []map[string]interface{}{{"r": []interface{}{2, 3, 4}}},
+		}, {
+			sql: `SELECT array_filter(x -> x + 1, a) AS r FROM test`,
+			err: "run Select error: alias: r expr: Call:{ name:array_filter, args:[lambdaExpr:{ params:[x], body:{ binaryExpr:{ lambdaParam:x + 1 } } }, $$default.a] } meet error, err:call func array_filter error: the lambda expression should return a bool but got 2",
+		},
+	}
+	fmt.Printf("The test bucket size is %d.\n\n", len(tests))
+	contextLogger := conf.Log.WithField("rule", "TestLambdaFunc_Apply1")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger)
+	for i, tt := range tests {
+		stmt, err := xsql.NewParser(strings.NewReader(tt.sql)).Parse()
+		if err != nil || stmt == nil {
+			t.Errorf("parse sql %s error %v", tt.sql, err)
+			continue
+		}
+		pp := &ProjectOp{}
+		parseStmt(pp, stmt.Fields)
+		fv, afv := xsql.NewFunctionValuersForOp(ctx)
+		opResult := pp.Apply(ctx, newData(), fv, afv)
+		if rt, ok := opResult.(error); ok {
+			if tt.err == "" {
+				t.Errorf("%d: got error:\n  exp=%s\n  got=%s\n\n", i, tt.result, rt)
+			} else if !reflect.DeepEqual(tt.err, testx.Errstring(rt)) {
+				t.Errorf("%d: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.err, rt)
+			}
+		} else {
+			result, _ := parseResult(opResult, pp.IsAggregate)
+			if tt.err == "" {
+				if !reflect.DeepEqual(tt.result, result) {
+					t.Errorf("%d. %q\n\nresult mismatch:\n\nexp=%#v\n\ngot=%#v\n\n", i, tt.sql, tt.result, result)
+				}
+			} else {
+				t.Errorf("%d: invalid result:\n  exp error %s\n  got=%s\n\n", i, tt.err, result)
+			}
+		}
+	}
+}
+
 func TestChangedFuncs_Apply1(t *testing.T) {
 	tests := []struct {
 		sql    string
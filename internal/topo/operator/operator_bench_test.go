@@ -0,0 +1,98 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+func benchContext() api.StreamContext {
+	return context.WithValue(context.Background(), context.LoggerKey, conf.Log.WithField("rule", "bench"))
+}
+
+func benchTuple() *xsql.Tuple {
+	return &xsql.Tuple{Emitter: "demo", Message: xsql.Message{"a": int64(3), "b": int64(4), "c": 2.5, "d": "test"}}
+}
+
+func benchProjectOp(b *testing.B, sql string) (*FilterOp, *ProjectOp) {
+	stmt, err := xsql.NewParser(strings.NewReader(sql)).Parse()
+	if err != nil {
+		b.Fatal(err)
+	}
+	pp := &ProjectOp{IsAggregate: xsql.WithAggFields(stmt)}
+	parseStmt(pp, stmt.Fields)
+	return &FilterOp{Condition: stmt.Condition}, pp
+}
+
+func BenchmarkFilter(b *testing.B) {
+	fo, _ := benchProjectOp(b, "SELECT * FROM demo WHERE a > 1 AND d = \"test\"")
+	ctx := benchContext()
+	fv, afv := xsql.NewFunctionValuersForOp(nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fo.Apply(ctx, benchTuple(), fv, afv)
+	}
+}
+
+func BenchmarkProject(b *testing.B) {
+	_, pp := benchProjectOp(b, "SELECT a, b * 2 AS e, upper(d) AS f FROM demo")
+	ctx := benchContext()
+	fv, afv := xsql.NewFunctionValuersForOp(nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pp.Apply(ctx, benchTuple(), fv, afv)
+	}
+}
+
+func BenchmarkFilterProject(b *testing.B) {
+	fo, pp := benchProjectOp(b, "SELECT a, b * 2 AS e, upper(d) AS f FROM demo WHERE a > 1")
+	ctx := benchContext()
+	fv, afv := xsql.NewFunctionValuersForOp(nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pp.Apply(ctx, fo.Apply(ctx, benchTuple(), fv, afv), fv, afv)
+	}
+}
+
+func BenchmarkProjectWindow(b *testing.B) {
+	for _, sql := range []string{
+		"SELECT a, c * 2 AS e FROM demo GROUP BY TumblingWindow(ss, 10)",
+		"SELECT sum(a) AS s, max(c) AS m, count(*) AS n FROM demo GROUP BY TumblingWindow(ss, 10)",
+	} {
+		b.Run(sql, func(b *testing.B) {
+			_, pp := benchProjectOp(b, sql)
+			ctx := benchContext()
+			fv, afv := xsql.NewFunctionValuersForOp(nil)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				w := &xsql.WindowTuples{Content: make([]xsql.Row, 100), WindowRange: xsql.NewWindowRange(0, 10000)}
+				for j := range w.Content {
+					w.Content[j] = benchTuple()
+				}
+				pp.Apply(ctx, w, fv, afv)
+			}
+		})
+	}
+}
@@ -16,8 +16,10 @@ package operator
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/io/memory/pubsub"
 	"github.com/lf-edge/ekuiper/internal/xsql"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/ast"
@@ -28,16 +30,23 @@ import (
 // Preprocessor only planned when
 // 1. eventTime, to convert the timestamp field
 // 2. schema validate and convert, when strict_validation is on and field type is not binary
+// 3. the stream fields have default values or computed expressions
 // Do not convert types
 type Preprocessor struct {
 	// Pruned stream fields. Could be streamField(with data type info) or string
 	defaultFieldProcessor
+	columnProcessor
 	// allMeta        bool
 	// metaFields     []string //only needed if not allMeta
 	isEventTime    bool
 	timestampField string
 	checkSchema    bool
 	isBinary       bool
+	// validation is the mode to handle the nonconforming messages. Empty means producing the error.
+	validation string
+	errorTopic string
+	stream     string
+	pubOnce    sync.Once
 }
 
 func NewPreprocessor(isSchemaless bool, fields map[string]*ast.JsonStreamField, _ bool, _ []string, iet bool, timestampField string, timestampFormat string, isBinary bool, strictValidation bool) (*Preprocessor, error) {
@@ -56,6 +65,22 @@ func NewPreprocessor(isSchemaless bool, fields map[string]*ast.JsonStreamField,
 	return p, nil
 }
 
+// SetValidation sets how to handle the messages of the stream which do not conform to the schema
+func (p *Preprocessor) SetValidation(stream string, mode string, errorTopic string) {
+	p.stream = stream
+	p.validation = mode
+	p.errorTopic = errorTopic
+}
+
+// SetColumns sets the fields with default value or computed expression, the computed fields are not validated in the
+// incoming message
+func (p *Preprocessor) SetColumns(fields ast.StreamFields, selected map[string]*ast.JsonStreamField) {
+	p.setColumns(fields, selected)
+	if p.checkSchema {
+		p.streamFields = p.withoutComputed(p.streamFields)
+	}
+}
+
 // Apply the preprocessor to the tuple
 /*	input: *xsql.Tuple
  *	output: *xsql.Tuple
@@ -68,11 +93,30 @@ func (p *Preprocessor) Apply(ctx api.StreamContext, data interface{}, _ *xsql.Fu
 	}
 
 	log.Debugf("preprocessor receive %s", tuple.Message)
+	if p.hasColumns() {
+		p.fillDefaults(tuple.Message)
+	}
 	if p.checkSchema {
 		if !p.isBinary {
-			err := p.validateAndConvert(tuple)
-			if err != nil {
-				return fmt.Errorf("error in preprocessor: %s", err)
+			switch p.validation {
+			case ast.ValidationCoerce:
+				if nulls := p.coerceMessage(p.streamFields, tuple.Message); len(nulls) > 0 {
+					log.Debugf("preprocessor coerce fields %v to null", nulls)
+				}
+			case ast.ValidationDrop, ast.ValidationRoute:
+				if errs := p.validateFields(tuple.Message); len(errs) > 0 {
+					if p.validation == ast.ValidationRoute {
+						p.route(ctx, tuple, errs)
+					} else {
+						log.Debugf("preprocessor drop invalid message %v: %v", tuple.Message, errs)
+					}
+					return nil
+				}
+			default:
+				err := p.validateAndConvert(tuple)
+				if err != nil {
+					return fmt.Errorf("error in preprocessor: %s", err)
+				}
 			}
 		} else {
 			for name := range p.streamFields {
@@ -82,7 +126,17 @@ func (p *Preprocessor) Apply(ctx api.StreamContext, data interface{}, _ *xsql.Fu
 			}
 		}
 	}
-	if p.isEventTime {
+	if p.hasColumns() {
+		var fp *defaultFieldProcessor
+		if p.checkSchema && !p.isBinary {
+			fp = &p.defaultFieldProcessor
+		}
+		if err := p.compute(ctx, tuple, fp); err != nil {
+			return fmt.Errorf("error in preprocessor: %s", err)
+		}
+	}
+	// Without the timestamp field, the event time is the timestamp provided by the source
+	if p.isEventTime && p.timestampField != "" {
 		if t, ok := tuple.Message[p.timestampField]; ok {
 			if ts, err := cast.InterfaceToUnixMilli(t, p.timestampFormat); err != nil {
 				return fmt.Errorf("cannot convert timestamp field %s to timestamp with error %v", p.timestampField, err)
@@ -106,3 +160,24 @@ func (p *Preprocessor) Apply(ctx api.StreamContext, data interface{}, _ *xsql.Fu
 	//}
 	return tuple
 }
+
+// route sends the invalid message with the errors of each field to the error topic, which can be read by a memory stream
+func (p *Preprocessor) route(ctx api.StreamContext, tuple *xsql.Tuple, errs []map[string]interface{}) {
+	p.pubOnce.Do(func() {
+		pubsub.CreatePub(p.errorTopic)
+		go func() {
+			<-ctx.Done()
+			pubsub.RemovePub(p.errorTopic)
+		}()
+	})
+	fieldErrs := make([]interface{}, len(errs))
+	for i, e := range errs {
+		fieldErrs[i] = e
+	}
+	pubsub.Produce(ctx, p.errorTopic, map[string]interface{}{
+		"stream":    p.stream,
+		"message":   map[string]interface{}(tuple.Message),
+		"errors":    fieldErrs,
+		"timestamp": tuple.Timestamp,
+	})
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"log"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -28,6 +29,7 @@ import (
 
 	"github.com/lf-edge/ekuiper/internal/conf"
 	"github.com/lf-edge/ekuiper/internal/converter"
+	"github.com/lf-edge/ekuiper/internal/io/memory/pubsub"
 	"github.com/lf-edge/ekuiper/internal/topo/context"
 	"github.com/lf-edge/ekuiper/internal/topo/topotest/mocknode"
 	"github.com/lf-edge/ekuiper/internal/xsql"
@@ -990,6 +992,128 @@ func TestPreprocessorError(t *testing.T) {
 	}
 }
 
+func TestPreprocessorValidation(t *testing.T) {
+	sf := ast.StreamFields{
+		{Name: "id", FieldType: &ast.BasicType{Type: ast.BIGINT}},
+		{Name: "name", FieldType: &ast.BasicType{Type: ast.STRINGS}},
+		{Name: "tags", FieldType: &ast.ArrayType{Type: ast.BIGINT}},
+	}
+	fields := sf.ToJsonSchema()
+	tests := []struct {
+		mode   string
+		data   map[string]interface{}
+		result interface{}
+		routed interface{}
+	}{
+		{
+			mode:   ast.ValidationCoerce,
+			data:   map[string]interface{}{"id": "12", "name": 3.0, "tags": []interface{}{"1", "a"}},
+			result: &xsql.Tuple{Message: xsql.Message{"id": int64(12), "name": "3", "tags": []interface{}{int64(1), nil}}},
+		},
+		{
+			mode:   ast.ValidationCoerce,
+			data:   map[string]interface{}{"id": "abc"},
+			result: &xsql.Tuple{Message: xsql.Message{"id": nil, "name": nil, "tags": nil}},
+		},
+		{
+			mode:   ast.ValidationDrop,
+			data:   map[string]interface{}{"id": 1.0, "name": "a", "tags": []interface{}{2.0}},
+			result: &xsql.Tuple{Message: xsql.Message{"id": int64(1), "name": "a", "tags": []interface{}{int64(2)}}},
+		},
+		{
+			mode: ast.ValidationDrop,
+			data: map[string]interface{}{"id": "12", "name": "a", "tags": []interface{}{}},
+		},
+		{
+			mode: ast.ValidationRoute,
+			data: map[string]interface{}{"id": "12", "tags": []interface{}{}},
+			routed: map[string]interface{}{
+				"stream":  "demo",
+				"message": map[string]interface{}{"id": "12", "tags": []interface{}{}},
+				"errors": []interface{}{
+					map[string]interface{}{"field": "id", "error": "cannot convert string(12) to int64"},
+					map[string]interface{}{"field": "name", "error": "field is not found"},
+				},
+				"timestamp": int64(0),
+			},
+		},
+	}
+	contextLogger := conf.Log.WithField("rule", "TestPreprocessorValidation")
+	ctx, cancel := context.WithValue(context.Background(), context.LoggerKey, contextLogger).WithCancel()
+	defer cancel()
+	ch := pubsub.CreateSub("validationErr", nil, "TestPreprocessorValidation", 10)
+	defer pubsub.CloseSourceConsumerChannel("validationErr", "TestPreprocessorValidation")
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			pp, err := NewPreprocessor(false, fields, false, nil, false, "", "", false, true)
+			require.NoError(t, err)
+			pp.SetValidation("demo", tt.mode, "validationErr")
+			fv, afv := xsql.NewFunctionValuersForOp(nil)
+			result := pp.Apply(ctx, &xsql.Tuple{Message: tt.data}, fv, afv)
+			if tt.result == nil {
+				assert.Nil(t, result)
+			} else {
+				assert.Equal(t, tt.result, result)
+			}
+			if tt.routed != nil {
+				select {
+				case r := <-ch:
+					assert.Equal(t, tt.routed, r.Message())
+				case <-time.After(time.Second):
+					t.Fatal("no message routed")
+				}
+			}
+		})
+	}
+}
+
+func TestPreprocessorColumns(t *testing.T) {
+	stmt, err := xsql.NewParser(strings.NewReader(`CREATE STREAM demo (temp float, unit string DEFAULT "C", tempF float AS (temp * 1.8 + 32), label string AS (concat(unit, ":", cast(tempF, "string")))) WITH (DATASOURCE="demo")`)).ParseCreateStmt()
+	require.NoError(t, err)
+	sf := stmt.(*ast.StreamStmt).StreamFields
+	tests := []struct {
+		name   string
+		strict bool
+		data   map[string]interface{}
+		result interface{}
+	}{
+		{
+			name:   "default",
+			data:   map[string]interface{}{"temp": 20.0},
+			result: &xsql.Tuple{Message: xsql.Message{"temp": 20.0, "unit": "C", "tempF": 68.0, "label": "C:68"}},
+		},
+		{
+			name:   "provided",
+			data:   map[string]interface{}{"temp": 10.0, "unit": "K", "tempF": 1.0},
+			result: &xsql.Tuple{Message: xsql.Message{"temp": 10.0, "unit": "K", "tempF": 50.0, "label": "K:50"}},
+		},
+		{
+			name:   "strict",
+			strict: true,
+			data:   map[string]interface{}{"temp": 20, "unit": nil},
+			result: &xsql.Tuple{Message: xsql.Message{"temp": 20.0, "unit": "C", "tempF": 68.0, "label": "C:68"}},
+		},
+		{
+			name:   "error",
+			data:   map[string]interface{}{"temp": "a"},
+			result: errors.New("error in preprocessor: cannot compute field tempF: invalid operation string(a) * float64(1.8)"),
+		},
+	}
+	contextLogger := conf.Log.WithField("rule", "TestPreprocessorColumns")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := sf.ToJsonSchema()
+			pp, err := NewPreprocessor(false, fields, false, nil, false, "", "", false, tt.strict)
+			require.NoError(t, err)
+			pp.SetColumns(sf, fields)
+			fv, afv := xsql.NewFunctionValuersForOp(nil)
+			result := pp.Apply(ctx, &xsql.Tuple{Message: tt.data}, fv, afv)
+			assert.Equal(t, tt.result, result)
+		})
+	}
+}
+
 func TestPreprocessorForBinary(t *testing.T) {
 	image, b64img := mocknode.GetImg()
 	tests := []struct {
@@ -60,6 +60,7 @@ func (pp *ProjectOp) Apply(ctx api.StreamContext, data interface{}, fv *xsql.Fun
 		return input
 	case xsql.Row:
 		ve := pp.getRowVE(input, nil, fv, afv)
+		defer xsql.PutValuerEval(ve)
 		if err := pp.project(input, ve); err != nil {
 			return fmt.Errorf("run Select error: %s", err)
 		} else {
@@ -96,7 +97,9 @@ func (pp *ProjectOp) Apply(ctx api.StreamContext, data interface{}, fv *xsql.Fun
 					return false, fmt.Errorf("unexpected type, cannot find aggregate data")
 				}
 				ve := pp.getVE(row, aggData, input.GetWindowRange(), fv, afv)
-				if err := pp.project(row, ve); err != nil {
+				err := pp.project(row, ve)
+				xsql.PutValuerEval(ve)
+				if err != nil {
 					return false, fmt.Errorf("run Select error: %s", err)
 				}
 				return true, nil
@@ -111,15 +114,23 @@ func (pp *ProjectOp) Apply(ctx api.StreamContext, data interface{}, fv *xsql.Fun
 	return data
 }
 
+// Copy returns a copy of the operator with its own buffers to run in another instance
+func (pp *ProjectOp) Copy() *ProjectOp {
+	c := *pp
+	c.kvs = nil
+	c.alias = nil
+	return &c
+}
+
 func (pp *ProjectOp) getVE(tuple xsql.RawRow, agg xsql.AggregateData, wr *xsql.WindowRange, fv *xsql.FunctionValuer, afv *xsql.AggregateFunctionValuer) *xsql.ValuerEval {
 	afv.SetData(agg)
 	if pp.IsAggregate {
 		return &xsql.ValuerEval{Valuer: xsql.MultiAggregateValuer(agg, fv, tuple, fv, afv, &xsql.WildcardValuer{Data: tuple})}
 	} else {
 		if wr != nil {
-			return &xsql.ValuerEval{Valuer: xsql.MultiValuer(tuple, &xsql.WindowRangeValuer{WindowRange: wr}, fv, &xsql.WildcardValuer{Data: tuple})}
+			return xsql.GetValuerEval(tuple, &xsql.WindowRangeValuer{WindowRange: wr}, fv, &xsql.WildcardValuer{Data: tuple})
 		}
-		return &xsql.ValuerEval{Valuer: xsql.MultiValuer(tuple, fv, &xsql.WildcardValuer{Data: tuple})}
+		return xsql.GetValuerEval(tuple, fv, &xsql.WildcardValuer{Data: tuple})
 	}
 }
 
@@ -25,6 +25,7 @@ import (
 type TableProcessor struct {
 	// Pruned stream fields. Could be streamField(with data type info) or string
 	defaultFieldProcessor
+	columnProcessor
 
 	checkSchema  bool
 	isBatchInput bool // whether the inputs are batched, such as file which sends multiple messages at a batch. If batch input, only fires when EOF is received. This is mutual exclusive with retainSize.
@@ -53,6 +54,15 @@ func NewTableProcessor(isSchemaless bool, name string, fields map[string]*ast.Js
 	return p, nil
 }
 
+// SetColumns sets the fields with default value or computed expression, the computed fields are not validated in the
+// incoming message
+func (p *TableProcessor) SetColumns(fields ast.StreamFields, selected map[string]*ast.JsonStreamField) {
+	p.setColumns(fields, selected)
+	if p.checkSchema {
+		p.streamFields = p.withoutComputed(p.streamFields)
+	}
+}
+
 // Apply
 //
 //	input: *xsql.Tuple or BatchCount
@@ -71,12 +81,24 @@ func (p *TableProcessor) Apply(ctx api.StreamContext, data interface{}, _ *xsql.
 		p.batchEmitted = false
 	}
 	if tuple.Message != nil {
+		if p.hasColumns() {
+			p.fillDefaults(tuple.Message)
+		}
 		if p.checkSchema {
 			err := p.validateAndConvert(tuple)
 			if err != nil {
 				return fmt.Errorf("error in preprocessor: %s", err)
 			}
 		}
+		if p.hasColumns() {
+			var fp *defaultFieldProcessor
+			if p.checkSchema {
+				fp = &p.defaultFieldProcessor
+			}
+			if err := p.compute(ctx, tuple, fp); err != nil {
+				return fmt.Errorf("error in preprocessor: %s", err)
+			}
+		}
 		var newTuples []xsql.Row
 		_ = p.output.Range(func(i int, r xsql.ReadonlyRow) (bool, error) {
 			if p.retainSize > 0 && p.output.Len() == p.retainSize && i == 0 {
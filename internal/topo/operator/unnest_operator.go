@@ -0,0 +1,102 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+// UnnestOp explodes the array evaluated by the expression into one row per element before the window.
+// For tuple, UnnestOp will do the following transform with expression `arr`:
+// {"id":1,"arr":[{"a":1},{"a":2}]} => {"id":1,"arr":[...],"a":1},{"id":1,"arr":[...],"a":2}
+// The non-object elements are set to the column Name. Each row keeps the timestamp of the original row,
+// so the event time windows still assign the rows by the time of the original event.
+// The row is dropped if the array is nil or empty.
+type UnnestOp struct {
+	Expr ast.Expr
+	Name string
+}
+
+func (p *UnnestOp) Apply(ctx api.StreamContext, data interface{}, fv *xsql.FunctionValuer, _ *xsql.AggregateFunctionValuer) interface{} {
+	ctx.GetLogger().Debugf("unnest plan receive %v", data)
+	switch input := data.(type) {
+	case error:
+		return input
+	case xsql.Row:
+		ve := &xsql.ValuerEval{Valuer: xsql.MultiValuer(input, fv)}
+		var elements []interface{}
+		switch r := ve.Eval(p.Expr).(type) {
+		case error:
+			return fmt.Errorf("run Unnest error: %s", r)
+		case nil:
+			return nil
+		case []interface{}:
+			elements = r
+		default:
+			// typed slices such as []map[string]interface{}
+			v := reflect.ValueOf(r)
+			if v.Kind() != reflect.Slice {
+				return fmt.Errorf("run Unnest error: the argument should be array but got %[1]T(%[1]v)", r)
+			}
+			elements = make([]interface{}, v.Len())
+			for i := range elements {
+				elements[i] = v.Index(i).Interface()
+			}
+		}
+		if len(elements) == 0 {
+			return nil
+		}
+		result := make([]xsql.Row, len(elements))
+		for i, e := range elements {
+			result[i] = p.explode(input, e)
+		}
+		return result
+	default:
+		return fmt.Errorf("run Unnest error: invalid input %[1]T(%[1]v)", input)
+	}
+}
+
+// explode creates the row of an element which keeps all the fields and the properties of the original row
+func (p *UnnestOp) explode(row xsql.Row, element interface{}) xsql.Row {
+	newRow := row.Clone()
+	if t, ok := newRow.(*xsql.Tuple); ok {
+		msg := make(xsql.Message, len(t.Message)+1)
+		for k, v := range t.Message {
+			msg[k] = v
+		}
+		if m, ok := element.(map[string]interface{}); ok {
+			for k, v := range m {
+				msg[k] = v
+			}
+		} else {
+			msg[p.Name] = element
+		}
+		t.Message = msg
+		return t
+	}
+	if m, ok := element.(map[string]interface{}); ok {
+		for k, v := range m {
+			newRow.Set(k, v)
+		}
+	} else {
+		newRow.Set(p.Name, element)
+	}
+	return newRow
+}
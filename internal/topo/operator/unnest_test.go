@@ -0,0 +1,126 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+)
+
+func TestUnnestOp_Apply(t *testing.T) {
+	tests := []struct {
+		sql    string
+		data   interface{}
+		result interface{}
+	}{
+		{
+			sql: "SELECT * FROM tbl CROSS JOIN UNNEST(items)",
+			data: &xsql.Tuple{
+				Emitter: "tbl",
+				Message: xsql.Message{
+					"id":    1,
+					"items": []interface{}{map[string]interface{}{"name": "a", "id": 10}, map[string]interface{}{"name": "b"}},
+				},
+				Timestamp: 1541152486013,
+			},
+			result: []xsql.Row{
+				&xsql.Tuple{
+					Emitter: "tbl",
+					Message: xsql.Message{
+						"id":    10,
+						"name":  "a",
+						"items": []interface{}{map[string]interface{}{"name": "a", "id": 10}, map[string]interface{}{"name": "b"}},
+					},
+					Timestamp: 1541152486013,
+				},
+				&xsql.Tuple{
+					Emitter: "tbl",
+					Message: xsql.Message{
+						"id":    1,
+						"name":  "b",
+						"items": []interface{}{map[string]interface{}{"name": "a", "id": 10}, map[string]interface{}{"name": "b"}},
+					},
+					Timestamp: 1541152486013,
+				},
+			},
+		},
+		{
+			sql: "SELECT * FROM tbl CROSS JOIN UNNEST(nums) AS n",
+			data: &xsql.Tuple{
+				Emitter:   "tbl",
+				Message:   xsql.Message{"id": 1, "nums": []int{1, 2}},
+				Timestamp: 1541152486013,
+			},
+			result: []xsql.Row{
+				&xsql.Tuple{
+					Emitter:   "tbl",
+					Message:   xsql.Message{"id": 1, "nums": []int{1, 2}, "n": 1},
+					Timestamp: 1541152486013,
+				},
+				&xsql.Tuple{
+					Emitter:   "tbl",
+					Message:   xsql.Message{"id": 1, "nums": []int{1, 2}, "n": 2},
+					Timestamp: 1541152486013,
+				},
+			},
+		},
+		{
+			sql: "SELECT * FROM tbl CROSS JOIN UNNEST(nums)",
+			data: &xsql.Tuple{
+				Emitter: "tbl",
+				Message: xsql.Message{"id": 1, "nums": []interface{}{}},
+			},
+			result: nil,
+		},
+		{
+			sql: "SELECT * FROM tbl CROSS JOIN UNNEST(nums)",
+			data: &xsql.Tuple{
+				Emitter: "tbl",
+				Message: xsql.Message{"id": 1},
+			},
+			result: nil,
+		},
+		{
+			sql: "SELECT * FROM tbl CROSS JOIN UNNEST(id)",
+			data: &xsql.Tuple{
+				Emitter: "tbl",
+				Message: xsql.Message{"id": 1},
+			},
+			result: errors.New("run Unnest error: the argument should be array but got int(1)"),
+		},
+	}
+	contextLogger := conf.Log.WithField("rule", "TestUnnestOp_Apply")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger)
+	for i, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			stmt, err := xsql.NewParser(strings.NewReader(tt.sql)).Parse()
+			if err != nil {
+				t.Errorf("statement %d parse error %s", i, err)
+				return
+			}
+			fv, afv := xsql.NewFunctionValuersForOp(ctx)
+			pp := &UnnestOp{Expr: stmt.Unnest.Expr, Name: stmt.Unnest.Name()}
+			result := pp.Apply(ctx, tt.data, fv, afv)
+			assert.Equal(t, tt.result, result)
+		})
+	}
+}
@@ -16,11 +16,13 @@ package operator
 
 import (
 	"fmt"
+	"reflect"
 	"sort"
 
 	"github.com/lf-edge/ekuiper/internal/xsql"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/ast"
+	"github.com/lf-edge/ekuiper/pkg/cast"
 )
 
 type WindowFuncOperator struct {
@@ -51,6 +53,66 @@ func (rh *rowNumberFuncHandle) handleCollection(input xsql.Collection) xsql.Coll
 	return input
 }
 
+// rankFuncHandle gives the same rank to the rows with the same sort values.
+// The rank of rank() has gaps after the peers while dense_rank() has no gaps.
+type rankFuncHandle struct {
+	name       string
+	dense      bool
+	sortFields ast.SortFields
+	fv         *xsql.FunctionValuer
+}
+
+func (rh *rankFuncHandle) handleTuple(input xsql.Row) xsql.Row {
+	input.Set(rh.name, 1)
+	return input
+}
+
+func (rh *rankFuncHandle) handleCollection(input xsql.Collection) xsql.Collection {
+	var (
+		prev  []interface{}
+		rank  = 0
+		index = 0
+	)
+	input.RangeSet(func(i int, r xsql.Row) (bool, error) {
+		index++
+		values := rh.sortValues(r)
+		if prev == nil || !sameSortValues(prev, values) {
+			if rh.dense {
+				rank++
+			} else {
+				rank = index
+			}
+			prev = values
+		}
+		r.Set(rh.name, rank)
+		return true, nil
+	})
+	return input
+}
+
+func (rh *rankFuncHandle) sortValues(r xsql.Row) []interface{} {
+	ve := &xsql.ValuerEval{Valuer: xsql.MultiValuer(rh.fv, r, &xsql.WildcardValuer{Data: r})}
+	values := make([]interface{}, len(rh.sortFields))
+	for i, field := range rh.sortFields {
+		values[i] = ve.Eval(field.FieldExpr)
+	}
+	return values
+}
+
+func sameSortValues(a, b []interface{}) bool {
+	for i := range a {
+		if reflect.DeepEqual(a[i], b[i]) {
+			continue
+		}
+		fa, err1 := cast.ToFloat64(a[i], cast.STRICT)
+		fb, err2 := cast.ToFloat64(b[i], cast.STRICT)
+		if err1 != nil || err2 != nil || fa != fb {
+			return false
+		}
+	}
+	return true
+}
+
 func (wf *WindowFuncOperator) Apply(ctx api.StreamContext, data interface{}, fv *xsql.FunctionValuer, afv *xsql.AggregateFunctionValuer) interface{} {
 	windowFuncField := wf.WindowFuncField
 	name := windowFuncField.Name
@@ -71,7 +133,7 @@ func (wf *WindowFuncOperator) Apply(ctx api.StreamContext, data interface{}, fv
 		pr = call.Partition
 		sortFields = call.SortFields
 	}
-	wh, err := getWindowFuncHandle(funcName, name)
+	wh, err := getWindowFuncHandle(funcName, name, sortFields, fv)
 	if err != nil {
 		return err
 	}
@@ -103,10 +165,12 @@ func (wf *WindowFuncOperator) Apply(ctx api.StreamContext, data interface{}, fv
 	return data
 }
 
-func getWindowFuncHandle(funcName, colName string) (windowFuncHandle, error) {
+func getWindowFuncHandle(funcName, colName string, sortFields ast.SortFields, fv *xsql.FunctionValuer) (windowFuncHandle, error) {
 	switch funcName {
 	case "row_number":
 		return &rowNumberFuncHandle{name: colName}, nil
+	case "rank", "dense_rank":
+		return &rankFuncHandle{name: colName, dense: funcName == "dense_rank", sortFields: sortFields, fv: fv}, nil
 	}
 	return nil, fmt.Errorf("")
 }
@@ -20,6 +20,7 @@ import (
 	"strings"
 
 	"github.com/lf-edge/ekuiper/internal/binder/function"
+	"github.com/lf-edge/ekuiper/internal/io/memory/pubsub"
 	"github.com/lf-edge/ekuiper/internal/schema"
 	"github.com/lf-edge/ekuiper/internal/xsql"
 	"github.com/lf-edge/ekuiper/pkg/ast"
@@ -54,8 +55,30 @@ func decorateStmt(s *ast.SelectStatement, store kv.KeyValue) ([]*streamInfo, []*
 	if checkAliasReferenceCycle(s) {
 		return nil, nil, nil, fmt.Errorf("select fields have cycled alias")
 	}
+	// The other clauses refer to the output fields of the match instead of the stream fields
+	analyzedStmts := streamStmts
+	if s.MatchRecognize != nil {
+		var err error
+		analyzedStmts, err = matchOutputStreams(s.MatchRecognize, streamStmts)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	// The other clauses refer to the exploded rows which have the element fields
+	if s.Unnest != nil {
+		var err error
+		analyzedStmts, err = unnestOutputStreams(s.Unnest, analyzedStmts)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		for _, si := range analyzedStmts {
+			if si.schema == nil {
+				isSchemaless = true
+			}
+		}
+	}
 	if !isSchemaless {
-		if err := aliasFieldTopoSort(s, streamStmts); err != nil {
+		if err := aliasFieldTopoSort(s, analyzedStmts); err != nil {
 			return nil, nil, nil, err
 		}
 	}
@@ -66,7 +89,7 @@ func decorateStmt(s *ast.SelectStatement, store kv.KeyValue) ([]*streamInfo, []*
 	// [fieldName][streamsName][*aliasRef] if alias, with special key alias/default. Each key has exactly one value
 	fieldsMap := newFieldsMap(isSchemaless, dsn)
 	if !isSchemaless {
-		for _, streamStmt := range streamStmts {
+		for _, streamStmt := range analyzedStmts {
 			for _, field := range streamStmt.schema {
 				fieldsMap.reserve(field.Name, streamStmt.stmt.Name)
 			}
@@ -504,6 +527,9 @@ func convertStreamInfo(streamStmt *ast.StreamStmt) (*streamInfo, error) {
 		if err != nil {
 			return nil, err
 		}
+	} else if ss == nil && streamStmt.Options.TYPE == "memory" {
+		// Use the schema registered by the producing rule for the schemaless memory stream
+		ss = pubsub.GetSchema(streamStmt.Options.DATASOURCE)
 	}
 	return &streamInfo{
 		stmt:   streamStmt,
@@ -46,6 +46,8 @@ type DataSourcePlan struct {
 	fields      map[string]*ast.JsonStreamField
 	metaMap     map[string]string
 	pruneFields []string
+	// the condition filtering the stream right after the source, set before building the topo
+	predicate ast.Expr
 }
 
 func (p DataSourcePlan) Init() *DataSourcePlan {
@@ -274,6 +276,9 @@ func (p *DataSourcePlan) PruneColumns(fields []ast.Expr) error {
 			return fmt.Errorf("unsupported field %v", field)
 		}
 	}
+	if err := p.addComputedDependencies(); err != nil {
+		return err
+	}
 	p.getAllFields()
 	if !p.isSchemaless {
 		p.handleArrowFields(arrowFileds)
@@ -281,6 +286,54 @@ func (p *DataSourcePlan) PruneColumns(fields []ast.Expr) error {
 	return nil
 }
 
+// addComputedDependencies selects the fields which the selected computed fields refer to. The computed fields can only
+// refer to the fields defined before them, so walking in reverse order covers the indirect dependencies too.
+func (p *DataSourcePlan) addComputedDependencies() error {
+	if p.isSchemaless {
+		return nil
+	}
+	sfs := p.streamStmt.StreamFields
+	for i := len(sfs) - 1; i >= 0; i-- {
+		f := sfs[i]
+		if f.Computed == nil || !p.isSelected(f.Name) {
+			continue
+		}
+		var err error
+		ast.WalkFunc(f.Computed.Expr, func(n ast.Node) bool {
+			if fr, ok := n.(*ast.FieldRef); ok && fr.IsColumn() {
+				if _, ok := p.fields[fr.Name]; !ok {
+					sf, e := p.getField(fr.Name, true)
+					if e != nil {
+						err = e
+						return false
+					}
+					p.fields[fr.Name] = sf
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *DataSourcePlan) isSelected(name string) bool {
+	if _, ok := p.fields[name]; ok {
+		return true
+	}
+	if p.isWildCard {
+		for _, pf := range p.pruneFields {
+			if pf == name {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
 func buildArrowReference(cur ast.Expr, root map[string]interface{}) (map[string]interface{}, string) {
 	switch c := cur.(type) {
 	case *ast.BinaryExpr:
@@ -372,7 +425,6 @@ func (p *DataSourcePlan) getField(name string, strict bool) (*ast.JsonStreamFiel
 }
 
 // Do not prune fields now for preprocessor
-// TODO provide field information to the source for it to prune
 func (p *DataSourcePlan) getAllFields() {
 	if !p.isWildCard {
 		p.streamFields = p.fields
@@ -405,12 +457,9 @@ func (p *DataSourcePlan) getAllFields() {
 }
 
 func (p *DataSourcePlan) getProps() error {
-	if p.iet {
-		if p.streamStmt.Options.TIMESTAMP != "" {
-			p.timestampField = p.streamStmt.Options.TIMESTAMP
-		} else {
-			return fmt.Errorf("preprocessor is set to be event time but stream option TIMESTAMP not found")
-		}
+	// The stream without TIMESTAMP is validated when transforming the source node as the source may provide the time
+	if p.iet && p.streamStmt.Options.TIMESTAMP != "" {
+		p.timestampField = p.streamStmt.Options.TIMESTAMP
 	}
 	if p.streamStmt.Options.TIMESTAMP_FORMAT != "" {
 		p.timestampFormat = p.streamStmt.Options.TIMESTAMP_FORMAT
@@ -420,3 +469,33 @@ func (p *DataSourcePlan) getProps() error {
 	}
 	return nil
 }
+
+// projection returns the sorted source columns used by the rule for the source to only read them. The computed fields
+// are not read from the source. It returns nil if all columns are needed.
+func (p *DataSourcePlan) projection() []string {
+	if p.isWildCard || p.streamStmt.Options.SHARED || len(p.streamFields) == 0 {
+		return nil
+	}
+	computed := make(map[string]struct{})
+	for _, f := range p.streamStmt.StreamFields {
+		if f.Computed != nil {
+			computed[f.Name] = struct{}{}
+		}
+	}
+	cols := make([]string, 0, len(p.streamFields)+1)
+	for name := range p.streamFields {
+		if _, ok := computed[name]; !ok {
+			cols = append(cols, name)
+		}
+	}
+	if k := p.streamStmt.Options.KEY; k != "" {
+		if _, ok := p.streamFields[k]; !ok {
+			cols = append(cols, k)
+		}
+	}
+	if len(cols) == 0 {
+		return nil
+	}
+	sort.Strings(cols)
+	return cols
+}
@@ -0,0 +1,69 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+func TestPruneComputedDependencies(t *testing.T) {
+	tests := []struct {
+		fields     []ast.Expr
+		result     []string
+		projection []string
+	}{
+		{
+			fields:     []ast.Expr{&ast.FieldRef{Name: "label", StreamName: ast.DefaultStream}},
+			result:     []string{"label", "temp", "tempF", "unit"},
+			projection: []string{"temp", "unit"},
+		},
+		{
+			fields:     []ast.Expr{&ast.FieldRef{Name: "tempF", StreamName: ast.DefaultStream}, &ast.FieldRef{Name: "id", StreamName: ast.DefaultStream}},
+			result:     []string{"id", "temp", "tempF"},
+			projection: []string{"id", "temp"},
+		},
+		{
+			fields:     []ast.Expr{&ast.FieldRef{Name: "unit", StreamName: ast.DefaultStream}},
+			result:     []string{"unit"},
+			projection: []string{"unit"},
+		},
+		{
+			fields: []ast.Expr{&ast.Wildcard{Token: ast.ASTERISK, Except: []string{"temp", "unit"}}},
+			result: []string{"id", "label", "temp", "tempF", "unit"},
+		},
+	}
+	for _, tt := range tests {
+		stmt, err := xsql.NewParser(strings.NewReader(`CREATE STREAM demo (id bigint, temp float, unit string DEFAULT "C", tempF float AS (temp * 1.8 + 32), label string AS (concat(unit, cast(tempF, "string")))) WITH (DATASOURCE="demo")`)).ParseCreateStmt()
+		require.NoError(t, err)
+		ss := stmt.(*ast.StreamStmt)
+		p := DataSourcePlan{name: ss.Name, streamStmt: ss, streamFields: ss.StreamFields.ToJsonSchema()}.Init()
+		require.NoError(t, p.PruneColumns(tt.fields))
+		names := make([]string, 0, len(p.streamFields))
+		for k := range p.streamFields {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		assert.Equal(t, tt.result, names)
+		assert.Equal(t, tt.projection, p.projection())
+	}
+}
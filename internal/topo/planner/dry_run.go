@@ -0,0 +1,79 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/errorx"
+)
+
+// PlanInfo is the plan of a rule which is not created
+type PlanInfo struct {
+	// Topo is the physical plan with the nodes and the edges
+	Topo *api.PrintableTopo `json:"topo"`
+	// LogicalPlan is the explain info of the optimized logical plan. Only the rule defined by sql has it.
+	LogicalPlan string `json:"logicalPlan,omitempty"`
+	// Optimizations are the optimization rules which have changed the logical plan, such as predicatePushDown
+	Optimizations []string `json:"optimizations"`
+}
+
+// DryRun plans the rule without creating or running it. The streams are planned as not shared, so the running
+// shared sources are not linked to the planned topo.
+func DryRun(rule *api.Rule) (*PlanInfo, error) {
+	if rule.Sql == "" {
+		tp, err := PlanByGraph(rule)
+		if err != nil {
+			return nil, err
+		}
+		return &PlanInfo{Topo: tp.GetTopo(), Optimizations: []string{}}, nil
+	}
+	stmt, err := xsql.GetStatementFromSql(rule.Sql)
+	if err != nil {
+		return nil, err
+	}
+	streamsFromStmt := xsql.GetStreams(stmt)
+	if err := validateStmt(stmt); err != nil {
+		return nil, err
+	}
+	if rule.Options.SendMetaToSink && (len(streamsFromStmt) > 1 || stmt.Dimensions != nil) {
+		return nil, fmt.Errorf("Invalid option sendMetaToSink, it can not be applied to window")
+	}
+	store, err := streamStore(rule)
+	if err != nil {
+		return nil, err
+	}
+	lp, err := buildLogicalPlan(stmt, rule.Options, store)
+	if err != nil {
+		return nil, err
+	}
+	lp, applied, err := optimizeWithTrace(lp)
+	if err != nil {
+		return nil, errorx.NewWithCode(errorx.PlanError, err.Error())
+	}
+	// Explain before creating the topo which may change the options of the plan
+	explain := explainLogicalPlan(lp, rule.Id)
+	sources := make(map[string]*SourceOverride, len(streamsFromStmt))
+	for _, s := range streamsFromStmt {
+		sources[s] = &SourceOverride{}
+	}
+	tp, err := createTopo(rule, lp, sources, nil, streamsFromStmt)
+	if err != nil {
+		return nil, err
+	}
+	return &PlanInfo{Topo: tp.GetTopo(), LogicalPlan: explain, Optimizations: applied}, nil
+}
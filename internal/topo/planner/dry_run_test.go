@@ -0,0 +1,62 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+func TestDryRun(t *testing.T) {
+	kv, err := store.GetKV("stream")
+	require.NoError(t, err)
+	s, err := json.Marshal(&xsql.StreamInfo{
+		StreamType: ast.TypeStream,
+		Statement:  `CREATE STREAM dryRunDemo (temp FLOAT, hum BIGINT, status STRING) WITH (DATASOURCE="dry", FORMAT="json", TYPE="mqtt", SHARED="true");`,
+	})
+	require.NoError(t, err)
+	require.NoError(t, kv.Set("dryRunDemo", string(s)))
+	defer kv.Delete("dryRunDemo")
+
+	info, err := DryRun(&api.Rule{
+		Id:      "dryRunRule",
+		Sql:     `SELECT temp FROM dryRunDemo WHERE hum > 10`,
+		Actions: []map[string]interface{}{{"log": map[string]interface{}{}}},
+		Options: defaultOption,
+	})
+	require.NoError(t, err)
+	assert.Contains(t, info.Optimizations, "columnPruner")
+	assert.Contains(t, info.LogicalPlan, "FilterPlan")
+	assert.Contains(t, info.LogicalPlan, "Fields:[ hum, temp ]")
+	// The shared stream is planned as a source of the rule itself
+	assert.Equal(t, []string{"source_dryRunDemo"}, info.Topo.Sources)
+	assert.Contains(t, info.Topo.Edges, "op_2_decoder")
+
+	_, err = DryRun(&api.Rule{
+		Id:      "dryRunRule",
+		Sql:     `SELECT temp FROM notExist`,
+		Actions: []map[string]interface{}{{"log": map[string]interface{}{}}},
+		Options: defaultOption,
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,99 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+const incAggPrefix = "$$inc_agg"
+
+// incAggFuncs are the aggregate functions which can be calculated incrementally by the sliding window
+var incAggFuncs = map[string]bool{
+	"sum":   true,
+	"count": true,
+	"avg":   true,
+	"min":   true,
+	"max":   true,
+}
+
+// markIncAggregates finds the aggregate calls which the sliding window can calculate incrementally and sets them to
+// the window. The window sets the results to the cached fields of the calls, so that the having and project ops do
+// not calculate them over the whole window content. Only the processing time sliding window without delay and
+// group by keys is supported, and the content of the window must not be filtered or joined after the window.
+func markIncAggregates(lp LogicalPlan, options *api.RuleOption) {
+	if options.IsEventTime {
+		return
+	}
+	var calls []*ast.Call
+	for p := lp; p != nil; {
+		switch t := p.(type) {
+		case *ProjectPlan:
+			for i := range t.fields {
+				calls = collectIncAggCalls(&t.fields[i], calls)
+			}
+		case *HavingPlan:
+			calls = collectIncAggCalls(t.condition, calls)
+		case *OrderPlan, *ProjectSetPlan:
+		case *WindowPlan:
+			if t.wtype == ast.SLIDING_WINDOW && t.delay == 0 && len(t.keys) == 0 && len(calls) > 0 {
+				for i, c := range calls {
+					c.CachedField = fmt.Sprintf("%s_%s_%d", incAggPrefix, c.Name, i)
+				}
+				t.incAggs = calls
+			}
+			return
+		default:
+			return
+		}
+		if len(p.Children()) != 1 {
+			return
+		}
+		p = p.Children()[0]
+	}
+}
+
+func collectIncAggCalls(node ast.Node, calls []*ast.Call) []*ast.Call {
+	ast.WalkFunc(node, func(n ast.Node) bool {
+		c, ok := n.(*ast.Call)
+		if !ok || c.FuncType != ast.FuncTypeAgg {
+			return true
+		}
+		if incAggFuncs[c.Name] && len(c.Args) == 1 && c.CachedField == "" && isIncAggArg(c.Name, c.Args[0]) {
+			for _, e := range calls {
+				if e == c {
+					return false
+				}
+			}
+			calls = append(calls, c)
+		}
+		return false
+	})
+	return calls
+}
+
+// isIncAggArg checks if the argument is a column which is evaluated by each tuple. Count also supports wildcard.
+func isIncAggArg(name string, arg ast.Expr) bool {
+	switch a := arg.(type) {
+	case *ast.FieldRef:
+		return a.IsColumn()
+	case *ast.Wildcard:
+		return name == "count"
+	}
+	return false
+}
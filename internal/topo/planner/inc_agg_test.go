@@ -0,0 +1,92 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+func TestMarkIncAggregates(t *testing.T) {
+	kv, err := store.GetKV("stream")
+	require.NoError(t, err)
+	s, err := json.Marshal(&xsql.StreamInfo{StreamType: ast.TypeStream, Statement: `CREATE STREAM incDemo (a BIGINT, b FLOAT, c STRING) WITH (DATASOURCE="incDemo", FORMAT="json");`})
+	require.NoError(t, err)
+	require.NoError(t, kv.Set("incDemo", string(s)))
+	tests := []struct {
+		sql     string
+		option  *api.RuleOption
+		incAggs []string
+	}{
+		{
+			sql:     `SELECT sum(a) AS s, count(*), avg(b) + 1, c FROM incDemo GROUP BY SlidingWindow(ss, 10) HAVING max(a) > 1 AND s > 2`,
+			incAggs: []string{"sum", "count", "avg", "max"},
+		},
+		{
+			sql:     `SELECT min(a), sum(abs(a)), collect(c) FROM incDemo GROUP BY SlidingWindow(ss, 10)`,
+			incAggs: []string{"min"},
+		},
+		{
+			sql: `SELECT sum(a) FROM incDemo GROUP BY SlidingWindow(ss, 10, 1)`,
+		},
+		{
+			sql: `SELECT sum(a) FROM incDemo GROUP BY SlidingWindow(ss, 10), c`,
+		},
+		{
+			sql: `SELECT sum(a) FROM incDemo GROUP BY TumblingWindow(ss, 10)`,
+		},
+		{
+			sql:    `SELECT sum(a) FROM incDemo GROUP BY SlidingWindow(ss, 10)`,
+			option: &api.RuleOption{IsEventTime: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			stmt, err := xsql.NewParser(strings.NewReader(tt.sql)).Parse()
+			require.NoError(t, err)
+			option := tt.option
+			if option == nil {
+				option = defaultOption
+			}
+			lp, err := createLogicalPlan(stmt, option, kv)
+			require.NoError(t, err)
+			markIncAggregates(lp, option)
+			var names []string
+			var wp *WindowPlan
+			for p := lp; p != nil && wp == nil; {
+				wp, _ = p.(*WindowPlan)
+				if len(p.Children()) == 0 {
+					break
+				}
+				p = p.Children()[0]
+			}
+			require.NotNil(t, wp)
+			for _, c := range wp.incAggs {
+				assert.True(t, strings.HasPrefix(c.CachedField, incAggPrefix))
+				names = append(names, c.Name)
+			}
+			assert.Equal(t, tt.incAggs, names)
+		})
+	}
+}
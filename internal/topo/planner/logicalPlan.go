@@ -134,18 +134,20 @@ func (p *baseLogicalPlan) PruneColumns(fields []ast.Expr) error {
 type PlanType string
 
 const (
-	AGGREGATE     PlanType = "AggregatePlan"
-	ANALYTICFUNCS PlanType = "AnalyticFuncsPlan"
-	DATASOURCE    PlanType = "DataSourcePlan"
-	FILTER        PlanType = "FilterPlan"
-	HAVING        PlanType = "HavingPlan"
-	JOINALIGN     PlanType = "JoinAlignPlan"
-	JOIN          PlanType = "JoinPlan"
-	LOOKUP        PlanType = "LookupPlan"
-	ORDER         PlanType = "OrderPlan"
-	PROJECT       PlanType = "ProjectPlan"
-	PROJECTSET    PlanType = "ProjectSetPlan"
-	WINDOW        PlanType = "WindowPlan"
-	WINDOWFUNC    PlanType = "WindowFuncPlan"
-	WATERMARK     PlanType = "WatermarkPlan"
+	AGGREGATE      PlanType = "AggregatePlan"
+	ANALYTICFUNCS  PlanType = "AnalyticFuncsPlan"
+	DATASOURCE     PlanType = "DataSourcePlan"
+	FILTER         PlanType = "FilterPlan"
+	HAVING         PlanType = "HavingPlan"
+	JOINALIGN      PlanType = "JoinAlignPlan"
+	JOIN           PlanType = "JoinPlan"
+	LOOKUP         PlanType = "LookupPlan"
+	MATCHRECOGNIZE PlanType = "MatchRecognizePlan"
+	ORDER          PlanType = "OrderPlan"
+	PROJECT        PlanType = "ProjectPlan"
+	PROJECTSET     PlanType = "ProjectSetPlan"
+	UNNEST         PlanType = "UnnestPlan"
+	WINDOW         PlanType = "WindowPlan"
+	WINDOWFUNC     PlanType = "WindowFuncPlan"
+	WATERMARK      PlanType = "WatermarkPlan"
 )
@@ -0,0 +1,146 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+// MatchRecognizePlan detects the row patterns of the stream. The plans above it receive the matches instead of the stream rows.
+type MatchRecognizePlan struct {
+	baseLogicalPlan
+	mr *ast.MatchRecognize
+}
+
+func (p MatchRecognizePlan) Init() *MatchRecognizePlan {
+	p.baseLogicalPlan.self = &p
+	p.baseLogicalPlan.setPlanType(MATCHRECOGNIZE)
+	return &p
+}
+
+func (p *MatchRecognizePlan) BuildExplainInfo() {
+	info := p.mr.String()
+	if len(p.mr.PartitionBy) > 0 {
+		keys := make([]string, len(p.mr.PartitionBy))
+		for i, d := range p.mr.PartitionBy {
+			keys[i] = d.Expr.String()
+		}
+		info += ", partitionBy:[ " + strings.Join(keys, ", ") + " ]"
+	}
+	p.baseLogicalPlan.ExplainInfo.Info = info
+}
+
+// PushDownPredicate the conditions above are applied to the matches, so they cannot be pushed down
+func (p *MatchRecognizePlan) PushDownPredicate(condition ast.Expr) (ast.Expr, LogicalPlan) {
+	if condition != nil {
+		f := FilterPlan{
+			condition: condition,
+		}.Init()
+		f.SetChildren([]LogicalPlan{p})
+		return nil, f
+	}
+	return nil, p.self
+}
+
+// PruneColumns the fields above refer to the match output. Only the fields used by the match are needed from the source.
+func (p *MatchRecognizePlan) PruneColumns(fields []ast.Expr) error {
+	var newFields []ast.Expr
+	for _, f := range fields {
+		if _, ok := f.(*ast.MetaRef); ok {
+			newFields = append(newFields, f)
+		}
+	}
+	collect := func(n ast.Node) bool {
+		switch f := n.(type) {
+		case *ast.FieldRef:
+			newFields = append(newFields, &ast.FieldRef{StreamName: ast.DefaultStream, Name: f.Name})
+			return false
+		case *ast.MetaRef:
+			newFields = append(newFields, &ast.MetaRef{StreamName: ast.DefaultStream, Name: f.Name})
+			return false
+		}
+		return true
+	}
+	for _, d := range p.mr.PartitionBy {
+		ast.WalkFunc(d.Expr, collect)
+	}
+	for _, m := range p.mr.Measures {
+		ast.WalkFunc(m.Expr, collect)
+	}
+	for _, d := range p.mr.Defines {
+		ast.WalkFunc(d.Condition, collect)
+	}
+	return p.baseLogicalPlan.PruneColumns(newFields)
+}
+
+// matchOutputStreams validates the fields referred in MATCH_RECOGNIZE and returns the stream infos with the
+// schema of the match output, which contains the partition fields and the measures.
+func matchOutputStreams(mr *ast.MatchRecognize, streamStmts []*streamInfo) ([]*streamInfo, error) {
+	result := make([]*streamInfo, len(streamStmts))
+	for i, si := range streamStmts {
+		if si.schema == nil {
+			result[i] = si
+			continue
+		}
+		var walkErr error
+		check := func(n ast.Node) bool {
+			if f, ok := n.(*ast.FieldRef); ok {
+				found := false
+				for _, field := range si.schema {
+					if strings.EqualFold(field.Name, f.Name) {
+						found = true
+						break
+					}
+				}
+				if !found {
+					walkErr = fmt.Errorf("unknown field %s", f.Name)
+				}
+				return false
+			}
+			return walkErr == nil
+		}
+		for _, d := range mr.PartitionBy {
+			ast.WalkFunc(d.Expr, check)
+		}
+		for _, m := range mr.Measures {
+			ast.WalkFunc(m.Expr, check)
+		}
+		for _, d := range mr.Defines {
+			ast.WalkFunc(d.Condition, check)
+		}
+		if walkErr != nil {
+			return nil, walkErr
+		}
+		result[i] = &streamInfo{stmt: si.stmt, schema: matchOutputSchema(mr, si.schema)}
+	}
+	return result, nil
+}
+
+func matchOutputSchema(mr *ast.MatchRecognize, schema ast.StreamFields) ast.StreamFields {
+	schemas := map[ast.StreamName]ast.StreamFields{ast.DefaultStream: schema}
+	var result ast.StreamFields
+	for _, d := range mr.PartitionBy {
+		if fr, ok := d.Expr.(*ast.FieldRef); ok {
+			result = append(result, ast.StreamField{Name: fr.Name, FieldType: inferFieldType(fr, schemas)})
+		}
+	}
+	for _, m := range mr.Measures {
+		result = append(result, ast.StreamField{Name: m.AName, FieldType: inferFieldType(m.Expr, schemas)})
+	}
+	return result
+}
@@ -0,0 +1,76 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+func TestMatchRecognizePlan(t *testing.T) {
+	kv, err := store.GetKV("stream")
+	require.NoError(t, err)
+	s, err := json.Marshal(&xsql.StreamInfo{
+		StreamType: ast.TypeStream,
+		Statement:  `CREATE STREAM matchDemo (id BIGINT, temp FLOAT, kind STRING, other STRING) WITH (DATASOURCE="src1", FORMAT="json");`,
+	})
+	require.NoError(t, err)
+	require.NoError(t, kv.Set("matchDemo", string(s)))
+	plan := func(sql string) (LogicalPlan, error) {
+		stmt, err := xsql.NewParser(strings.NewReader(sql)).Parse()
+		require.NoError(t, err)
+		return createLogicalPlan(stmt, defaultOption, kv)
+	}
+
+	p, err := plan(`SELECT id, maxTemp FROM matchDemo MATCH_RECOGNIZE (PARTITION BY id MEASURES max(B.temp) AS maxTemp
+		PATTERN (A B+ C) WITHIN 10 SS DEFINE A AS kind = 'start', B AS B.temp > A.temp, C AS kind = 'end') WHERE maxTemp > 30`)
+	require.NoError(t, err)
+	var types []string
+	for c := p; c != nil; {
+		types = append(types, c.Type())
+		if len(c.Children()) == 0 {
+			break
+		}
+		c = c.Children()[0]
+	}
+	// The condition is applied to the matches, so it is not pushed down
+	assert.Equal(t, []string{"ProjectPlan", "FilterPlan", "MatchRecognizePlan", "DataSourcePlan"}, types)
+	mp := p.Children()[0].Children()[0]
+	mp.BuildExplainInfo()
+	assert.Equal(t, "pattern:{ A B+ C }, within:10000, partitionBy:[ $$default.id ]", mp.(*MatchRecognizePlan).ExplainInfo.Info)
+	// Only the fields used by the match are read from the source
+	ds := mp.Children()[0].(*DataSourcePlan)
+	var fields []string
+	for f := range ds.streamFields {
+		fields = append(fields, f)
+	}
+	assert.ElementsMatch(t, []string{"id", "temp", "kind"}, fields)
+
+	_, err = plan(`SELECT id FROM matchDemo MATCH_RECOGNIZE (PARTITION BY id PATTERN (A) DEFINE A AS unknown > 1)`)
+	assert.EqualError(t, err, "unknown field unknown")
+	// The stream fields are not available after the match
+	_, err = plan(`SELECT temp FROM matchDemo MATCH_RECOGNIZE (PARTITION BY id MEASURES A.temp AS t PATTERN (A))`)
+	assert.EqualError(t, err, "unknown field temp")
+	_, err = plan(`SELECT t FROM matchDemo MATCH_RECOGNIZE (PARTITION BY id MEASURES A.temp AS t PATTERN (A))`)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,190 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"strings"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"github.com/lf-edge/ekuiper/pkg/kv"
+)
+
+type memorySchemaConf struct {
+	Topic          string   `json:"topic"`
+	RegisterSchema bool     `json:"registerSchema"`
+	DataTemplate   string   `json:"dataTemplate"`
+	DataField      string   `json:"dataField"`
+	Fields         []string `json:"fields"`
+}
+
+// memorySchemas returns the result schema of the rule by the topics of the memory sinks with registerSchema enabled.
+// The topo registers them when it opens so that the rules consuming the memory topic can validate the fields at plan
+// time.
+func memorySchemas(rule *api.Rule, stmt *ast.SelectStatement, store kv.KeyValue) map[string]ast.StreamFields {
+	var (
+		schema   ast.StreamFields
+		inferred bool
+		result   map[string]ast.StreamFields
+	)
+	for _, m := range rule.Actions {
+		for name, action := range m {
+			if name != "memory" {
+				continue
+			}
+			props, ok := action.(map[string]any)
+			if !ok {
+				continue
+			}
+			c := &memorySchemaConf{}
+			if err := cast.MapToStruct(props, c); err != nil || !c.RegisterSchema {
+				continue
+			}
+			if c.DataTemplate != "" || c.DataField != "" || strings.Contains(c.Topic, "{{") {
+				conf.Log.Warnf("rule %s cannot register the schema of memory topic %s which has dataTemplate, dataField or dynamic topic", rule.Id, c.Topic)
+				continue
+			}
+			if !inferred {
+				schema = inferResultSchema(stmt, store)
+				inferred = true
+			}
+			if schema == nil {
+				conf.Log.Warnf("rule %s cannot infer the result schema for memory topic %s", rule.Id, c.Topic)
+				continue
+			}
+			if result == nil {
+				result = make(map[string]ast.StreamFields)
+			}
+			result[c.Topic] = selectSchemaFields(schema, c.Fields)
+		}
+	}
+	return result
+}
+
+// inferResultSchema infers the result schema of the decorated select statement.
+// The type of the field which is not a direct reference to a stream field is unknown.
+// Return nil if the result fields cannot be determined such as selecting the wildcard of a schemaless stream.
+func inferResultSchema(stmt *ast.SelectStatement, store kv.KeyValue) ast.StreamFields {
+	streams := xsql.GetStreams(stmt)
+	schemas := make(map[ast.StreamName]ast.StreamFields, len(streams))
+	for _, s := range streams {
+		streamStmt, err := xsql.GetDataSource(store, s)
+		if err != nil {
+			return nil
+		}
+		si, err := convertStreamInfo(streamStmt)
+		if err != nil {
+			return nil
+		}
+		if stmt.MatchRecognize != nil && si.schema != nil {
+			si.schema = matchOutputSchema(stmt.MatchRecognize, si.schema)
+		}
+		if stmt.Unnest != nil && si.schema != nil {
+			si.schema = unnestOutputSchema(stmt.Unnest, si.schema)
+		}
+		schemas[streamStmt.Name] = si.schema
+	}
+	var result ast.StreamFields
+	for _, f := range stmt.Fields {
+		switch e := f.Expr.(type) {
+		case *ast.Wildcard:
+			for _, s := range streams {
+				sf := schemas[ast.StreamName(s)]
+				if sf == nil {
+					return nil
+				}
+				for _, field := range sf {
+					if !contains(e.Except, field.Name) && !containsField(e.Replace, field.Name) {
+						result = append(result, field)
+					}
+				}
+			}
+			for _, rf := range e.Replace {
+				result = append(result, ast.StreamField{Name: rf.AName, FieldType: inferFieldType(rf.Expr, schemas)})
+			}
+		case *ast.FieldRef:
+			if e.Name == "*" {
+				sf := schemas[e.StreamName]
+				if sf == nil {
+					return nil
+				}
+				result = append(result, sf...)
+				continue
+			}
+			result = append(result, ast.StreamField{Name: f.GetName(), FieldType: inferFieldType(e, schemas)})
+		default:
+			result = append(result, ast.StreamField{Name: f.GetName(), FieldType: inferFieldType(e, schemas)})
+		}
+	}
+	return result
+}
+
+func inferFieldType(expr ast.Expr, schemas map[ast.StreamName]ast.StreamFields) ast.FieldType {
+	if fr, ok := expr.(*ast.FieldRef); ok {
+		if fr.AliasRef != nil {
+			return inferFieldType(fr.AliasRef.Expression, schemas)
+		}
+		sf, ok := schemas[fr.StreamName]
+		if !ok && len(schemas) == 1 {
+			for _, s := range schemas {
+				sf = s
+			}
+		}
+		for _, field := range sf {
+			if strings.EqualFold(field.Name, fr.Name) {
+				return field.FieldType
+			}
+		}
+	}
+	return &ast.BasicType{Type: ast.UNKNOWN}
+}
+
+// selectSchemaFields returns the schema of the fields selected by the sink fields property
+func selectSchemaFields(schema ast.StreamFields, fields []string) ast.StreamFields {
+	if len(fields) == 0 {
+		return schema
+	}
+	result := make(ast.StreamFields, 0, len(fields))
+	for _, f := range fields {
+		for _, field := range schema {
+			if field.Name == f {
+				result = append(result, field)
+				break
+			}
+		}
+	}
+	return result
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func containsField(fields []ast.Field, name string) bool {
+	for _, f := range fields {
+		if f.AName == name {
+			return true
+		}
+	}
+	return false
+}
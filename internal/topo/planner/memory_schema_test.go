@@ -0,0 +1,122 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/io/memory/pubsub"
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+func TestMemorySchemaRegistry(t *testing.T) {
+	kv, err := store.GetKV("stream")
+	require.NoError(t, err)
+	streamSqls := map[string]string{
+		"memProducer": `CREATE STREAM memProducer (id BIGINT, temp FLOAT, name STRING) WITH (DATASOURCE="src1", FORMAT="json");`,
+		"memLess":     `CREATE STREAM memLess () WITH (DATASOURCE="src2", FORMAT="json");`,
+		"memConsumer": `CREATE STREAM memConsumer () WITH (DATASOURCE="pipeline/result", TYPE="memory", FORMAT="json");`,
+	}
+	for name, sql := range streamSqls {
+		s, err := json.Marshal(&xsql.StreamInfo{StreamType: ast.TypeStream, Statement: sql})
+		require.NoError(t, err)
+		require.NoError(t, kv.Set(name, string(s)))
+	}
+	plan := func(id string, sql string, actions []map[string]any) error {
+		stmt, err := xsql.NewParser(strings.NewReader(sql)).Parse()
+		require.NoError(t, err)
+		_, err = createLogicalPlan(stmt, defaultOption, kv)
+		if err != nil {
+			return err
+		}
+		// Register like the topo opens
+		pubsub.DropSchemas(id)
+		for topic, schema := range memorySchemas(&api.Rule{Id: id, Sql: sql, Actions: actions}, stmt, kv) {
+			pubsub.RegisterSchema(id, topic, schema)
+		}
+		return nil
+	}
+	memoryAction := func(props map[string]any) []map[string]any {
+		return []map[string]any{{"memory": props}}
+	}
+	defer pubsub.DropSchemas("producer")
+
+	// Not registered without the option
+	require.NoError(t, plan("producer", "SELECT id, temp FROM memProducer", memoryAction(map[string]any{"topic": "pipeline/result"})))
+	assert.Nil(t, pubsub.GetSchema("pipeline/result"))
+	require.NoError(t, plan("consumer", "SELECT whatever FROM memConsumer", nil))
+
+	require.NoError(t, plan("producer", "SELECT id, temp * 2 AS t2, name AS n FROM memProducer", memoryAction(map[string]any{"topic": "pipeline/result", "registerSchema": true})))
+	// The fields are sorted by the alias dependencies in the decorated statement
+	assert.ElementsMatch(t, ast.StreamFields{
+		{Name: "id", FieldType: &ast.BasicType{Type: ast.BIGINT}},
+		{Name: "t2", FieldType: &ast.BasicType{Type: ast.UNKNOWN}},
+		{Name: "n", FieldType: &ast.BasicType{Type: ast.STRINGS}},
+	}, pubsub.GetSchema("pipeline/result"))
+	require.NoError(t, plan("consumer", "SELECT id, t2 FROM memConsumer WHERE n = 'a'", nil))
+	assert.EqualError(t, plan("consumer", "SELECT id, temp FROM memConsumer", nil), "unknown field temp")
+
+	// Wildcard and the fields property
+	require.NoError(t, plan("producer", "SELECT * FROM memProducer", memoryAction(map[string]any{"topic": "pipeline/result", "registerSchema": true, "fields": []any{"name", "id"}})))
+	assert.Equal(t, ast.StreamFields{
+		{Name: "name", FieldType: &ast.BasicType{Type: ast.STRINGS}},
+		{Name: "id", FieldType: &ast.BasicType{Type: ast.BIGINT}},
+	}, pubsub.GetSchema("pipeline/result"))
+
+	// The wildcard of a schemaless stream cannot be inferred
+	require.NoError(t, plan("producer", "SELECT * FROM memLess", memoryAction(map[string]any{"topic": "pipeline/result", "registerSchema": true})))
+	assert.Nil(t, pubsub.GetSchema("pipeline/result"))
+	require.NoError(t, plan("producer", "SELECT a, b FROM memLess", memoryAction(map[string]any{"topic": "pipeline/result", "registerSchema": true})))
+	assert.Equal(t, ast.StreamFields{
+		{Name: "a", FieldType: &ast.BasicType{Type: ast.UNKNOWN}},
+		{Name: "b", FieldType: &ast.BasicType{Type: ast.UNKNOWN}},
+	}, pubsub.GetSchema("pipeline/result"))
+
+	// Conflict schemas from multiple producers
+	require.NoError(t, plan("producer2", "SELECT c FROM memLess", memoryAction(map[string]any{"topic": "pipeline/result", "registerSchema": true})))
+	assert.Nil(t, pubsub.GetSchema("pipeline/result"))
+	pubsub.DropSchemas("producer2")
+	assert.Len(t, pubsub.GetSchema("pipeline/result"), 2)
+}
+
+func TestMemorySchemaLifecycle(t *testing.T) {
+	kv, err := store.GetKV("stream")
+	require.NoError(t, err)
+	s, err := json.Marshal(&xsql.StreamInfo{StreamType: ast.TypeStream, Statement: `CREATE STREAM memLife (id BIGINT) WITH (DATASOURCE="src/life", TYPE="memory", FORMAT="json");`})
+	require.NoError(t, err)
+	require.NoError(t, kv.Set("memLife", string(s)))
+	rule := &api.Rule{
+		Id:      "memLifeRule",
+		Sql:     "SELECT id FROM memLife",
+		Actions: []map[string]any{{"memory": map[string]any{"topic": "pipeline/life", "registerSchema": true}}},
+		Options: defaultOption,
+	}
+	// Planning does not register the schema
+	tp, err := PlanSQLWithSourcesAndSinks(rule, nil, nil)
+	require.NoError(t, err)
+	assert.Nil(t, pubsub.GetSchema("pipeline/life"))
+	tp.Open()
+	assert.Equal(t, ast.StreamFields{{Name: "id", FieldType: &ast.BasicType{Type: ast.BIGINT}}}, pubsub.GetSchema("pipeline/life"))
+	tp.Cancel()
+	assert.Nil(t, pubsub.GetSchema("pipeline/life"))
+}
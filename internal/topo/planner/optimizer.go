@@ -29,3 +29,22 @@ func optimize(p LogicalPlan) (LogicalPlan, error) {
 	}
 	return p, err
 }
+
+// optimizeWithTrace optimizes the plan and returns the names of the rules which have changed the plan
+func optimizeWithTrace(p LogicalPlan) (LogicalPlan, []string, error) {
+	var err error
+	applied := make([]string, 0, len(optRuleList))
+	before := explainLogicalPlan(p, "")
+	for _, rule := range optRuleList {
+		p, err = rule.optimize(p)
+		if err != nil {
+			return nil, nil, err
+		}
+		after := explainLogicalPlan(p, "")
+		if after != before {
+			applied = append(applied, rule.name())
+		}
+		before = after
+	}
+	return p, applied, nil
+}
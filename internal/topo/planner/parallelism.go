@@ -0,0 +1,98 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/internal/topo"
+	"github.com/lf-edge/ekuiper/internal/topo/node"
+	"github.com/lf-edge/ekuiper/internal/topo/operator"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+// applyParallelism sets the parallelism option to the operators. The option is matched by the operator name, which
+// is the node name for the graph rules. For the SQL rules, it can also be matched by the operator type such as project
+// and filter, which is the operator name without the index prefix.
+func applyParallelism(tp *topo.Topo, options *api.RuleOption, sourceNames []string) error {
+	if len(options.Parallelism) == 0 {
+		return nil
+	}
+	matched := make(map[string]bool, len(options.Parallelism))
+	for _, op := range tp.GetOperators() {
+		name := op.GetName()
+		p, ok := options.Parallelism[name]
+		if !ok {
+			if _, typ, found := strings.Cut(name, "_"); found {
+				name = typ
+				p, ok = options.Parallelism[name]
+			}
+		}
+		if !ok {
+			continue
+		}
+		matched[name] = true
+		if p == nil || p.Concurrency <= 1 {
+			continue
+		}
+		uo, ok := op.(*node.UnaryOperator)
+		if !ok {
+			return fmt.Errorf("operator %s does not support parallelism", name)
+		}
+		var partitionBy ast.Expr
+		if p.PartitionBy != "" {
+			stmt, err := xsql.NewParserWithSources(strings.NewReader("select "+p.PartitionBy+" from nonexist"), sourceNames).Parse()
+			if err != nil {
+				return fmt.Errorf("invalid partitionBy %s of operator %s: %v", p.PartitionBy, name, err)
+			}
+			partitionBy = stmt.Fields[0].Expr
+		}
+		var newOp func() node.UnOperation
+		switch t := uo.GetOperation().(type) {
+		case *operator.ProjectOp:
+			newOp = func() node.UnOperation { return t.Copy() }
+		case *operator.TableProcessor:
+			return fmt.Errorf("operator %s does not support parallelism", name)
+		case *operator.AnalyticFuncsOp:
+			if partitionBy == nil {
+				return fmt.Errorf("operator %s has analytic functions, partitionBy is required to run it concurrently", name)
+			}
+		case *operator.FilterOp:
+			if len(t.StateFuncs) > 0 && partitionBy == nil {
+				return fmt.Errorf("operator %s has stateful functions, partitionBy is required to run it concurrently", name)
+			}
+		case *operator.HavingOp:
+			if len(t.StateFuncs) > 0 && partitionBy == nil {
+				return fmt.Errorf("operator %s has stateful functions, partitionBy is required to run it concurrently", name)
+			}
+		}
+		uo.SetParallelism(p.Concurrency, partitionBy, newOp)
+	}
+	var unmatched []string
+	for name := range options.Parallelism {
+		if !matched[name] {
+			unmatched = append(unmatched, name)
+		}
+	}
+	if len(unmatched) > 0 {
+		sort.Strings(unmatched)
+		return fmt.Errorf("parallelism operators %s are not found", strings.Join(unmatched, ", "))
+	}
+	return nil
+}
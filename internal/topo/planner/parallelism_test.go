@@ -0,0 +1,168 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+func TestApplyParallelism(t *testing.T) {
+	kv, err := store.GetKV("stream")
+	require.NoError(t, err)
+	s, err := json.Marshal(&xsql.StreamInfo{
+		StreamType: ast.TypeStream,
+		Statement:  `CREATE STREAM parallelDemo (a BIGINT, b FLOAT, k STRING) WITH (DATASOURCE="parallelDemo", FORMAT="json");`,
+	})
+	require.NoError(t, err)
+	require.NoError(t, kv.Set("parallelDemo", string(s)))
+	defer kv.Delete("parallelDemo")
+	tests := []struct {
+		name        string
+		sql         string
+		parallelism map[string]*api.OperatorParallelism
+		err         string
+	}{
+		{
+			name:        "project by type",
+			sql:         `SELECT a * 2 AS c FROM parallelDemo WHERE b > 1`,
+			parallelism: map[string]*api.OperatorParallelism{"project": {Concurrency: 4}, "filter": {Concurrency: 2, PartitionBy: "k"}},
+		},
+		{
+			name:        "analytic with key",
+			sql:         `SELECT lag(a) OVER (PARTITION BY k) AS l FROM parallelDemo`,
+			parallelism: map[string]*api.OperatorParallelism{"analytic": {Concurrency: 2, PartitionBy: "k"}},
+		},
+		{
+			name:        "analytic without key",
+			sql:         `SELECT lag(a) AS l FROM parallelDemo`,
+			parallelism: map[string]*api.OperatorParallelism{"analytic": {Concurrency: 2}},
+			err:         "operator analytic has analytic functions, partitionBy is required to run it concurrently",
+		},
+		{
+			name:        "window",
+			sql:         `SELECT count(*) FROM parallelDemo GROUP BY TumblingWindow(ss, 10)`,
+			parallelism: map[string]*api.OperatorParallelism{"window": {Concurrency: 2}},
+			err:         "operator window does not support parallelism",
+		},
+		{
+			name:        "invalid key",
+			sql:         `SELECT a FROM parallelDemo`,
+			parallelism: map[string]*api.OperatorParallelism{"project": {Concurrency: 2, PartitionBy: "k +"}},
+			err:         "invalid partitionBy k + of operator project: found \"FROM\", expected expression.",
+		},
+		{
+			name:        "not found",
+			sql:         `SELECT a FROM parallelDemo`,
+			parallelism: map[string]*api.OperatorParallelism{"filter": {Concurrency: 2}, "project": {Concurrency: 2}},
+			err:         "parallelism operators filter are not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opt := *defaultOption
+			opt.Parallelism = tt.parallelism
+			_, err := Plan(&api.Rule{
+				Id:      "parallelRule",
+				Sql:     tt.sql,
+				Actions: []map[string]interface{}{{"log": map[string]interface{}{}}},
+				Options: &opt,
+			})
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestApplyParallelismGraph(t *testing.T) {
+	graph := `{
+  "nodes": {
+    "abc": {
+      "type": "source",
+      "nodeType": "mqtt",
+      "props": {
+        "datasource": "demo"
+      }
+    },
+    "logfunc": {
+      "type": "operator",
+      "nodeType": "function",
+      "props": {
+        "expr": "log(temperature) as log_temperature"
+      }
+    },
+    "pick": {
+      "type": "operator",
+      "nodeType": "pick",
+      "props": {
+        "fields": ["log_temperature", "humidity"]
+      }
+    },
+    "logSink": {
+      "type": "sink",
+      "nodeType": "log",
+      "props": {}
+    }
+  },
+  "topo": {
+    "sources": ["abc"],
+    "edges": {
+      "abc": ["logfunc"],
+      "logfunc": ["pick"],
+      "pick": ["logSink"]
+    }
+  }
+}`
+	tests := []struct {
+		name        string
+		parallelism map[string]*api.OperatorParallelism
+		err         string
+	}{
+		{
+			name:        "by node name",
+			parallelism: map[string]*api.OperatorParallelism{"logfunc": {Concurrency: 2, PartitionBy: "deviceId"}, "pick": {Concurrency: 4}},
+		},
+		{
+			name:        "sink",
+			parallelism: map[string]*api.OperatorParallelism{"logSink": {Concurrency: 2}},
+			err:         "parallelism operators logSink are not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rg := &api.RuleGraph{}
+			require.NoError(t, json.Unmarshal([]byte(graph), rg))
+			opt := *defaultOption
+			opt.Parallelism = tt.parallelism
+			_, err := PlanByGraph(&api.Rule{Id: "parallelGraph", Graph: rg, Options: &opt})
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
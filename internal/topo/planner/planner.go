@@ -20,6 +20,7 @@ import (
 
 	"github.com/lf-edge/ekuiper/internal/binder/io"
 	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/pkg/namespace"
 	store2 "github.com/lf-edge/ekuiper/internal/pkg/store"
 	"github.com/lf-edge/ekuiper/internal/topo"
 	"github.com/lf-edge/ekuiper/internal/topo/node"
@@ -43,6 +44,26 @@ func Plan(rule *api.Rule) (*topo.Topo, error) {
 
 // PlanSQLWithSourcesAndSinks For test only
 func PlanSQLWithSourcesAndSinks(rule *api.Rule, mockSourcesProp map[string]map[string]any, sinks []*node.SinkNode) (*topo.Topo, error) {
+	var sources map[string]*SourceOverride
+	if len(mockSourcesProp) > 0 {
+		sources = make(map[string]*SourceOverride, len(mockSourcesProp))
+		for name, props := range mockSourcesProp {
+			sources[name] = &SourceOverride{Type: "simulator", Props: props}
+		}
+	}
+	return planSQL(rule, sources, sinks)
+}
+
+// PlanSQLWithSourceOverrides plans the rule with some streams read from other sources, such as the history data.
+// The sinks replace the actions of the rule if not empty.
+func PlanSQLWithSourceOverrides(rule *api.Rule, sources map[string]*SourceOverride, sinks []*node.SinkNode) (*topo.Topo, error) {
+	if rule.Sql == "" {
+		return nil, fmt.Errorf("source overrides are only supported by the rule defined by sql")
+	}
+	return planSQL(rule, sources, sinks)
+}
+
+func planSQL(rule *api.Rule, sources map[string]*SourceOverride, sinks []*node.SinkNode) (*topo.Topo, error) {
 	sql := rule.Sql
 
 	conf.Log.Infof("Init rule with options %+v", rule.Options)
@@ -62,7 +83,7 @@ func PlanSQLWithSourcesAndSinks(rule *api.Rule, mockSourcesProp map[string]map[s
 	if rule.Options.SendMetaToSink && (len(streamsFromStmt) > 1 || stmt.Dimensions != nil) {
 		return nil, fmt.Errorf("Invalid option sendMetaToSink, it can not be applied to window")
 	}
-	store, err := store2.GetKV("stream")
+	store, err := streamStore(rule)
 	if err != nil {
 		return nil, err
 	}
@@ -71,13 +92,22 @@ func PlanSQLWithSourcesAndSinks(rule *api.Rule, mockSourcesProp map[string]map[s
 	if err != nil {
 		return nil, err
 	}
-	tp, err := createTopo(rule, lp, mockSourcesProp, sinks, streamsFromStmt)
+	tp, err := createTopo(rule, lp, sources, sinks, streamsFromStmt)
 	if err != nil {
 		return nil, err
 	}
+	if len(sinks) == 0 {
+		tp.SetMemorySchemas(memorySchemas(rule, stmt, store))
+	}
 	return tp, nil
 }
 
+// streamStore returns the definitions of the streams in the namespace of the rule
+func streamStore(rule *api.Rule) (kv.KeyValue, error) {
+	ns, _ := namespace.Split(rule.Id)
+	return store2.GetKV(namespace.Table("stream", ns))
+}
+
 func validateStmt(stmt *ast.SelectStatement) error {
 	var vErr error
 	ast.WalkFunc(stmt, func(n ast.Node) bool {
@@ -92,7 +122,7 @@ func validateStmt(stmt *ast.SelectStatement) error {
 	return vErr
 }
 
-func createTopo(rule *api.Rule, lp LogicalPlan, mockSourcesProp map[string]map[string]any, sinks []*node.SinkNode, streamsFromStmt []string) (t *topo.Topo, err error) {
+func createTopo(rule *api.Rule, lp LogicalPlan, sources map[string]*SourceOverride, sinks []*node.SinkNode, streamsFromStmt []string) (t *topo.Topo, err error) {
 	defer func() {
 		if err != nil {
 			err = errorx.NewWithCode(errorx.ExecutorError, err.Error())
@@ -105,10 +135,15 @@ func createTopo(rule *api.Rule, lp LogicalPlan, mockSourcesProp map[string]map[s
 		return nil, err
 	}
 
-	input, _, err := buildOps(lp, tp, rule.Options, mockSourcesProp, streamsFromStmt, 0)
+	markSourcePredicates(lp)
+	markIncAggregates(lp, rule.Options)
+	input, _, err := buildOps(lp, tp, rule.Options, sources, streamsFromStmt, 0)
 	if err != nil {
 		return nil, err
 	}
+	if err = applyParallelism(tp, rule.Options, streamsFromStmt); err != nil {
+		return nil, err
+	}
 	inputs := []api.Emitter{input}
 	// Add actions
 	if len(sinks) > 0 { // For use of mock sink in testing
@@ -139,7 +174,7 @@ func GetExplainInfoFromLogicalPlan(rule *api.Rule) (string, error) {
 	if rule.Options.SendMetaToSink && (len(streamsFromStmt) > 1 || stmt.Dimensions != nil) {
 		return "", fmt.Errorf("invalid option sendMetaToSink, it can not be applied to window")
 	}
-	store, err := store2.GetKV("stream")
+	store, err := streamStore(rule)
 	if err != nil {
 		return "", err
 	}
@@ -148,6 +183,11 @@ func GetExplainInfoFromLogicalPlan(rule *api.Rule) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	return explainLogicalPlan(lp, rule.Id), nil
+}
+
+// explainLogicalPlan returns the explain info of each plan node in a line, the children are indented
+func explainLogicalPlan(lp LogicalPlan, ruleId string) string {
 	var setId func(p LogicalPlan, id int64)
 	setId = func(p LogicalPlan, id int64) {
 		p.SetID(id)
@@ -167,7 +207,7 @@ func GetExplainInfoFromLogicalPlan(rule *api.Rule) (string, error) {
 		}
 		p.BuildExplainInfo()
 		if info, ok := p.(RuleRuntimeInfo); ok {
-			info.BuildSchemaInfo(rule.Id)
+			info.BuildSchemaInfo(ruleId)
 		}
 		// Build the explainInfo of the current layer
 		res += tmp + p.Explain() + "\n"
@@ -178,11 +218,10 @@ func GetExplainInfoFromLogicalPlan(rule *api.Rule) (string, error) {
 		}
 		return res
 	}
-	res := getExplainInfo(lp, 0)
-	return res, nil
+	return getExplainInfo(lp, 0)
 }
 
-func buildOps(lp LogicalPlan, tp *topo.Topo, options *api.RuleOption, sources map[string]map[string]any, streamsFromStmt []string, index int) (api.Emitter, int, error) {
+func buildOps(lp LogicalPlan, tp *topo.Topo, options *api.RuleOption, sources map[string]*SourceOverride, streamsFromStmt []string, index int) (api.Emitter, int, error) {
 	var inputs []api.Emitter
 	newIndex := index
 	for _, c := range lp.Children() {
@@ -207,20 +246,21 @@ func buildOps(lp LogicalPlan, tp *topo.Topo, options *api.RuleOption, sources ma
 		tp.AddSrc(srcNode)
 		inputs = []api.Emitter{srcNode}
 		op = srcNode
-		if len(emitters) > 0 {
-			for i, e := range emitters {
-				if i < len(emitters)-1 {
-					tp.AddOperator(inputs, e)
-					inputs = []api.Emitter{e}
-				}
-				op = e
-				newIndex++
+		for i, e := range emitters {
+			if i < len(emitters)-1 {
+				tp.AddOperator(inputs, e)
+				inputs = []api.Emitter{e}
 			}
-		} else {
-			newIndex += indexInc
+			op = e
+			newIndex++
 		}
+		newIndex += indexInc
 	case *WatermarkPlan:
 		op = node.NewWatermarkOp(fmt.Sprintf("%d_watermark", newIndex), t.SendWatermark, t.Emitters, options)
+	case *MatchRecognizePlan:
+		op = node.NewMatchNode(fmt.Sprintf("%d_match", newIndex), t.mr, options)
+	case *UnnestPlan:
+		op = Transform(&operator.UnnestOp{Expr: t.unnest.Expr, Name: t.unnest.Name()}, fmt.Sprintf("%d_unnest", newIndex), options)
 	case *AnalyticFuncsPlan:
 		op = Transform(&operator.AnalyticFuncsOp{Funcs: t.funcs, FieldFuncs: t.fieldFuncs}, fmt.Sprintf("%d_analytic", newIndex), options)
 	case *WindowPlan:
@@ -247,6 +287,8 @@ func buildOps(lp LogicalPlan, tp *topo.Topo, options *api.RuleOption, sources ma
 			TimeUnit:         t.timeUnit,
 			TriggerCondition: t.triggerCondition,
 			StateFuncs:       t.stateFuncs,
+			Keys:             t.keys,
+			IncAggs:          t.incAggs,
 		}, options)
 		if err != nil {
 			return nil, 0, err
@@ -302,11 +344,11 @@ func convertFromDuration(t *WindowPlan) (int64, int64, int64) {
 	return int64(t.length) * unit, int64(t.interval) * unit, t.delay * unit
 }
 
-func transformSourceNode(t *DataSourcePlan, mockSourcesProp map[string]map[string]any, ruleId string, options *api.RuleOption, index int) (node.DataSourceNode, []node.OperatorNode, int, error) {
+func transformSourceNode(t *DataSourcePlan, sources map[string]*SourceOverride, ruleId string, options *api.RuleOption, index int) (node.DataSourceNode, []node.OperatorNode, int, error) {
 	isSchemaless := t.isSchemaless
-	mockSourceConf, isMock := mockSourcesProp[string(t.name)]
-	if isMock {
-		t.streamStmt.Options.TYPE = "simulator"
+	ov, isOverridden := sources[string(t.name)]
+	if isOverridden {
+		ov.apply(t.streamStmt.Options)
 	}
 	switch t.streamStmt.StreamType {
 	case ast.TypeStream:
@@ -319,20 +361,45 @@ func transformSourceNode(t *DataSourcePlan, mockSourcesProp map[string]map[strin
 		if err != nil {
 			return nil, nil, 0, err
 		}
+		if t.iet && t.timestampField == "" && !(isOverridden && ov.SourceTime) {
+			return nil, nil, 0, fmt.Errorf("preprocessor is set to be event time but stream option TIMESTAMP not found")
+		}
 		var pp node.UnOperation
-		if t.iet || (!isSchemaless && (t.streamStmt.Options.STRICT_VALIDATION || t.isBinary)) {
-			pp, err = operator.NewPreprocessor(isSchemaless, t.streamFields, t.allMeta, t.metaFields, t.iet, t.timestampField, t.timestampFormat, t.isBinary, t.streamStmt.Options.STRICT_VALIDATION)
+		validation := t.streamStmt.Options.VALIDATION
+		hasColumns := !isSchemaless && hasColumnExprs(t.streamStmt.StreamFields)
+		if t.iet || hasColumns || (!isSchemaless && (t.streamStmt.Options.STRICT_VALIDATION || validation != "" || t.isBinary)) {
+			p, err := operator.NewPreprocessor(isSchemaless, t.streamFields, t.allMeta, t.metaFields, t.iet, t.timestampField, t.timestampFormat, t.isBinary, t.streamStmt.Options.STRICT_VALIDATION || validation != "")
 			if err != nil {
 				return nil, nil, 0, err
 			}
+			p.SetValidation(string(t.name), validation, t.streamStmt.Options.ERROR_TOPIC)
+			if hasColumns {
+				p.SetColumns(t.streamStmt.StreamFields, t.streamFields)
+			}
+			pp = p
+		}
+		if _, ok := si.(api.PartitionSource); options.Partitions > 0 && !ok {
+			return nil, nil, 0, fmt.Errorf("source type %s of stream %s does not support partitioned mode", strType, t.name)
 		}
 		switch ss := si.(type) {
 		case api.SourceConnector:
-			return splitSource(t, ss, options, index, ruleId, pp)
+			if (isOverridden && ov.Bound != nil) || options.RunOnce {
+				return nil, nil, 0, fmt.Errorf("source type %s of stream %s cannot be bounded", strType, t.name)
+			}
+			return splitSource(t, ss, options, index, ruleId, pp, ov)
 		default:
+			if options.RunOnce && t.streamStmt.Options.SHARED {
+				return nil, nil, 0, fmt.Errorf("shared stream %s cannot be bounded to run once", t.name)
+			}
 			srcNode := node.NewSourceNode(string(t.name), t.streamStmt.StreamType, pp, t.streamStmt.Options, options, t.isWildCard, t.isSchemaless, t.streamFields)
-			if isMock {
-				srcNode.SetProps(mockSourceConf)
+			srcNode.SetProjection(t.projection())
+			srcNode.SetPredicate(t.sourcePredicate())
+			if isOverridden {
+				srcNode.SetProps(ov.Props)
+				srcNode.SetBound(ov.Bound)
+			}
+			if options.RunOnce && srcNode.GetBound() == nil {
+				srcNode.SetBound(&node.SourceBound{ReadOnce: true})
 			}
 			return srcNode, nil, 0, nil
 		}
@@ -345,6 +412,9 @@ func transformSourceNode(t *DataSourcePlan, mockSourcesProp map[string]map[strin
 		if err != nil {
 			return nil, nil, 0, err
 		}
+		if !isSchemaless && hasColumnExprs(t.streamStmt.StreamFields) {
+			pp.SetColumns(t.streamStmt.StreamFields, t.streamFields)
+		}
 
 		schema := t.streamFields
 		if t.isSchemaless {
@@ -352,11 +422,16 @@ func transformSourceNode(t *DataSourcePlan, mockSourcesProp map[string]map[strin
 		}
 		switch ss := si.(type) {
 		case api.SourceConnector:
-			return splitSource(t, ss, options, index, ruleId, pp)
+			// The connector source cannot be drained when the rule running once completes
+			if options.RunOnce {
+				return nil, nil, 0, fmt.Errorf("source type %s of table %s cannot run once", t.streamStmt.Options.TYPE, t.name)
+			}
+			return splitSource(t, ss, options, index, ruleId, pp, ov)
 		default:
 			srcNode := node.NewSourceNode(string(t.name), t.streamStmt.StreamType, pp, t.streamStmt.Options, options, t.isWildCard, t.isSchemaless, schema)
-			if isMock {
-				srcNode.SetProps(mockSourceConf)
+			srcNode.SetProjection(t.projection())
+			if isOverridden {
+				srcNode.SetProps(ov.Props)
 			}
 			return srcNode, nil, 0, nil
 		}
@@ -364,26 +439,51 @@ func transformSourceNode(t *DataSourcePlan, mockSourcesProp map[string]map[strin
 	return nil, nil, 0, fmt.Errorf("unknown stream type %d", t.streamStmt.StreamType)
 }
 
+// hasColumnExprs checks if any field has default value or computed expression
+func hasColumnExprs(fields ast.StreamFields) bool {
+	for _, f := range fields {
+		if f.Default != nil || f.Computed != nil {
+			return true
+		}
+	}
+	return false
+}
+
 type SourcePropsForSplit struct {
 	Decompression string `json:"decompression"`
 	SelId         string `json:"connectionSelector"`
 }
 
-func splitSource(t *DataSourcePlan, ss api.SourceConnector, options *api.RuleOption, index int, ruleId string, pp node.UnOperation) (node.DataSourceNode, []node.OperatorNode, int, error) {
+func splitSource(t *DataSourcePlan, ss api.SourceConnector, options *api.RuleOption, index int, ruleId string, pp node.UnOperation, ov *SourceOverride) (node.DataSourceNode, []node.OperatorNode, int, error) {
 	// Get all props
-	props := nodeConf.GetSourceConf(t.streamStmt.Options.TYPE, t.streamStmt.Options)
+	props, err := nodeConf.GetSourceConf(t.streamStmt.Options.TYPE, t.streamStmt.Options)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if ov != nil {
+		for k, v := range ov.Props {
+			props[k] = v
+		}
+	}
 	sp := &SourcePropsForSplit{}
 	_ = cast.MapToStruct(props, sp)
+	// The offsets are only committed to the source read by this rule alone
+	if options.EnableAck {
+		if sp.SelId != "" || t.streamStmt.Options.SHARED {
+			conf.Log.Warnf("ack is disabled for source %s because it is shared by multiple rules", t.name)
+		} else {
+			props["enableAck"] = true
+		}
+	}
+	// The shared sub topos are in the namespace of the rule
+	ns, _ := namespace.Split(ruleId)
 	// Create the connector node as source node
-	var (
-		err         error
-		srcConnNode node.DataSourceNode
-	)
+	var srcConnNode node.DataSourceNode
 	if sp.SelId == "" {
 		srcConnNode, err = node.NewSourceConnectorNode(string(t.name), ss, t.streamStmt.Options.DATASOURCE, props, options)
 	} else { // connection selector is set as a one node sub_topo
 		selName := fmt.Sprintf("%s/%s", sp.SelId, t.streamStmt.Options.DATASOURCE)
-		srcSubtopo, existed := topo.GetSubTopo(selName)
+		srcSubtopo, existed := topo.GetSubTopo(namespace.Qualify(ns, selName))
 		if !existed {
 			var scn node.DataSourceNode
 			scn, err = node.NewSourceConnectorNode(selName, ss, t.streamStmt.Options.DATASOURCE, props, options)
@@ -424,9 +524,31 @@ func splitSource(t *DataSourcePlan, ss api.SourceConnector, options *api.RuleOpt
 		index++
 	}
 
+	if isAutoShared(t, options, ov) {
+		// Share the source and the decoding. The preprocessor is specific to each rule.
+		name := autoShareName(t, props)
+		shared := ops
+		ops = nil
+		if pp != nil {
+			shared = shared[:len(shared)-1]
+			ops = append(ops, Transform(&copyTupleOp{op: pp}, fmt.Sprintf("%d_preprocessor", index-1), options))
+		}
+		srcSubtopo, existed := topo.GetSubTopo(namespace.Qualify(ns, name))
+		if !existed {
+			conf.Log.Infof("Create SubTopo %s shared automatically", name)
+			srcSubtopo.AddSrc(srcConnNode)
+			subInputs := []api.Emitter{srcSubtopo}
+			for _, e := range shared {
+				srcSubtopo.AddOperator(subInputs, e)
+				subInputs = []api.Emitter{e}
+			}
+		}
+		srcSubtopo.StoreSchema(ruleId, string(t.name), t.streamFields, t.isWildCard)
+		return srcSubtopo, ops, len(shared), nil
+	}
 	if t.streamStmt.Options.SHARED && len(ops) > 0 {
 		// Create subtopo in the end to avoid errors in the middle
-		srcSubtopo, existed := topo.GetSubTopo(string(t.name))
+		srcSubtopo, existed := topo.GetSubTopo(namespace.Qualify(ns, string(t.name)))
 		if !existed {
 			conf.Log.Infof("Create SubTopo %s", string(t.name))
 			srcSubtopo.AddSrc(srcConnNode)
@@ -442,7 +564,20 @@ func splitSource(t *DataSourcePlan, ss api.SourceConnector, options *api.RuleOpt
 	return srcConnNode, ops, 0, nil
 }
 
-func createLogicalPlan(stmt *ast.SelectStatement, opt *api.RuleOption, store kv.KeyValue) (lp LogicalPlan, err error) {
+func createLogicalPlan(stmt *ast.SelectStatement, opt *api.RuleOption, store kv.KeyValue) (LogicalPlan, error) {
+	p, err := buildLogicalPlan(stmt, opt, store)
+	if err != nil {
+		return nil, err
+	}
+	p, err = optimize(p)
+	if err != nil {
+		return nil, errorx.NewWithCode(errorx.PlanError, err.Error())
+	}
+	return p, nil
+}
+
+// buildLogicalPlan creates the logical plan which is not optimized
+func buildLogicalPlan(stmt *ast.SelectStatement, opt *api.RuleOption, store kv.KeyValue) (lp LogicalPlan, err error) {
 	defer func() {
 		if err != nil {
 			err = errorx.NewWithCode(errorx.PlanError, err.Error())
@@ -499,6 +634,27 @@ func createLogicalPlan(stmt *ast.SelectStatement, opt *api.RuleOption, store kv.
 		p.SetChildren(children)
 		children = []LogicalPlan{p}
 	}
+	if stmt.Unnest != nil {
+		if len(children) == 0 {
+			return nil, errors.New("cannot run UNNEST for TABLE sources")
+		}
+		p = UnnestPlan{
+			unnest: stmt.Unnest,
+			schema: streamStmts[0].schema,
+		}.Init()
+		p.SetChildren(children)
+		children = []LogicalPlan{p}
+	}
+	if stmt.MatchRecognize != nil {
+		if len(children) == 0 {
+			return nil, errors.New("cannot run MATCH_RECOGNIZE for TABLE sources")
+		}
+		p = MatchRecognizePlan{
+			mr: stmt.MatchRecognize,
+		}.Init()
+		p.SetChildren(children)
+		children = []LogicalPlan{p}
+	}
 	if len(analyticFuncs) > 0 || len(analyticFieldFuncs) > 0 {
 		p = AnalyticFuncsPlan{
 			funcs:      analyticFuncs,
@@ -536,6 +692,9 @@ func createLogicalPlan(stmt *ast.SelectStatement, opt *api.RuleOption, store kv.
 			if w.TriggerCondition != nil {
 				wp.triggerCondition = w.TriggerCondition
 			}
+			if w.WindowType == ast.SESSION_WINDOW {
+				wp.keys = dimensions.GetGroups()
+			}
 			// TODO calculate limit
 			// TODO incremental aggregate
 			wp.SetChildren(children)
@@ -664,7 +823,7 @@ func createLogicalPlan(stmt *ast.SelectStatement, opt *api.RuleOption, store kv.
 		p.SetChildren(children)
 	}
 
-	return optimize(p)
+	return p, nil
 }
 
 // extractSRFMapping extracts the set-returning-function in the field
@@ -20,7 +20,6 @@ import (
 	"strings"
 
 	"github.com/lf-edge/ekuiper/internal/binder/function"
-	store2 "github.com/lf-edge/ekuiper/internal/pkg/store"
 	"github.com/lf-edge/ekuiper/internal/topo"
 	"github.com/lf-edge/ekuiper/internal/topo/graph"
 	"github.com/lf-edge/ekuiper/internal/topo/node"
@@ -402,6 +401,9 @@ func PlanByGraph(rule *api.Rule) (*topo.Topo, error) {
 			tp.AddOperator(inputs, n.(node.OperatorNode))
 		}
 	}
+	if err = applyParallelism(tp, rule.Options, sourceNames); err != nil {
+		return nil, err
+	}
 	return tp, nil
 }
 
@@ -443,7 +445,7 @@ func parseSource(nodeName string, gn *api.GraphNode, rule *api.Rule, store kv.Ke
 	// If source name is specified, find the created stream/table from store
 	if sourceMeta.SourceName != "" {
 		if store == nil {
-			store, err = store2.GetKV("stream")
+			store, err = streamStore(rule)
 			if err != nil {
 				return nil, ILLEGAL, "", nil, err
 			}
@@ -4787,7 +4787,8 @@ func TestTransformSourceNode(t *testing.T) {
 			Type: "bigint",
 		},
 	}
-	props := nodeConf.GetSourceConf("mqtt", &ast.Options{TYPE: "mqtt"})
+	props, err := nodeConf.GetSourceConf("mqtt", &ast.Options{TYPE: "mqtt"})
+	assert.NoError(t, err)
 	srcNode, err := node.NewSourceConnectorNode("test", &mqtt.SourceConnector{}, "topic1", props, &api.RuleOption{SendError: false})
 	assert.NoError(t, err)
 	decodeNode, err := node.NewDecodeOp("2_decoder", "test", "test", &api.RuleOption{SendError: false}, &ast.Options{TYPE: "mqtt"}, false, false, schema)
@@ -4796,7 +4797,8 @@ func TestTransformSourceNode(t *testing.T) {
 	assert.NoError(t, err)
 	decodeNode2, err := node.NewDecodeOp("3_decoder", "test", "test", &api.RuleOption{SendError: false}, &ast.Options{TYPE: "mqtt"}, false, false, schema)
 	assert.NoError(t, err)
-	props2 := nodeConf.GetSourceConf("mqtt", &ast.Options{TYPE: "mqtt", CONF_KEY: "testCom"})
+	props2, err := nodeConf.GetSourceConf("mqtt", &ast.Options{TYPE: "mqtt", CONF_KEY: "testCom"})
+	assert.NoError(t, err)
 	srcNode2, err := node.NewSourceConnectorNode("test", &mqtt.SourceConnector{}, "topic1", props2, &api.RuleOption{SendError: false})
 	assert.NoError(t, err)
 
@@ -4842,9 +4844,13 @@ func TestTransformSourceNode(t *testing.T) {
 				iet:          false,
 				isBinary:     false,
 			},
-			node: node.NewSourceNode("test", ast.TypeStream, nil, &ast.Options{
-				TYPE: "file",
-			}, &api.RuleOption{SendError: false}, false, false, schema),
+			node: func() *node.SourceNode {
+				n := node.NewSourceNode("test", ast.TypeStream, nil, &ast.Options{
+					TYPE: "file",
+				}, &api.RuleOption{SendError: false}, false, false, schema)
+				n.SetProjection([]string{"a"})
+				return n
+			}(),
 		},
 		{
 			name: "split source node",
@@ -0,0 +1,52 @@
+// Copyright 2022-2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"github.com/lf-edge/ekuiper/internal/topo/node"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+// SourceOverride replaces the source of a stream in the planned rule, such as the mock source of the rule trial.
+// The empty fields keep the values of the stream definition.
+type SourceOverride struct {
+	Type       string                 `json:"type"`
+	DataSource string                 `json:"datasource"`
+	ConfKey    string                 `json:"confKey"`
+	Format     string                 `json:"format"`
+	Props      map[string]interface{} `json:"props"`
+	// Bound makes the source read a bounded stream which ends when the source finishes reading
+	Bound *node.SourceBound `json:"-"`
+	// SourceTime uses the timestamps of the source tuples as the event time if the stream has no TIMESTAMP option,
+	// such as the timestamps of the replayed records
+	SourceTime bool `json:"-"`
+}
+
+func (o *SourceOverride) apply(options *ast.Options) {
+	if o.Type != "" {
+		options.TYPE = o.Type
+	}
+	if o.DataSource != "" {
+		options.DATASOURCE = o.DataSource
+	}
+	if o.ConfKey != "" {
+		options.CONF_KEY = o.ConfKey
+	}
+	if o.Format != "" {
+		options.FORMAT = o.Format
+	}
+	// The overridden source is only read by this rule
+	options.SHARED = false
+}
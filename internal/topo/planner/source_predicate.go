@@ -0,0 +1,174 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+// safeIdentifier is the column name which can be written in the source sql as is
+var safeIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// markSourcePredicates sets the conditions of the filters right after the data sources to the sources
+func markSourcePredicates(lp LogicalPlan) {
+	if f, ok := lp.(*FilterPlan); ok && len(f.Children()) == 1 {
+		if ds, ok := f.Children()[0].(*DataSourcePlan); ok {
+			ds.predicate = combine(ds.predicate, f.condition)
+		}
+	}
+	for _, c := range lp.Children() {
+		markSourcePredicates(c)
+	}
+}
+
+// sourcePredicate returns the part of the pushed down condition which the source can filter by. The condition is
+// returned as the sql where condition and the equality conditions of the top level AND. The rule still filters the
+// data, so the source can filter by a weaker condition or not filter at all. For event time, the filtered data still
+// advance the watermark, so the condition is not pushed down.
+func (p *DataSourcePlan) sourcePredicate() (string, map[string]interface{}) {
+	if p.predicate == nil || p.streamStmt.Options.SHARED || p.iet {
+		return "", nil
+	}
+	expr := p.pushableExpr(p.predicate)
+	if expr == nil {
+		return "", nil
+	}
+	equals := make(map[string]interface{})
+	p.collectEquals(expr, equals)
+	if len(equals) == 0 {
+		equals = nil
+	}
+	return predicateSql(expr), equals
+}
+
+// pushableExpr extracts the conditions which only compare the source columns with the literals. For AND, the
+// unsupported side is dropped which makes the condition weaker. For OR, both sides must be supported.
+func (p *DataSourcePlan) pushableExpr(expr ast.Expr) ast.Expr {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return p.pushableExpr(e.Expr)
+	case *ast.BinaryExpr:
+		switch e.OP {
+		case ast.AND:
+			return combine(p.pushableExpr(e.LHS), p.pushableExpr(e.RHS))
+		case ast.OR:
+			l, r := p.pushableExpr(e.LHS), p.pushableExpr(e.RHS)
+			if l == nil || r == nil {
+				return nil
+			}
+			return &ast.BinaryExpr{OP: ast.OR, LHS: l, RHS: r}
+		case ast.EQ, ast.NEQ, ast.LT, ast.LTE, ast.GT, ast.GTE:
+			if (p.isSourceColumn(e.LHS) && isPushableLiteral(e.RHS)) || (isPushableLiteral(e.LHS) && p.isSourceColumn(e.RHS)) {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+// isSourceColumn checks if the field is read from the source as is. The fields with default value or computed
+// expression are not. The backquoted names which are not plain identifiers are not pushed down to avoid injecting
+// into the source sql.
+func (p *DataSourcePlan) isSourceColumn(expr ast.Expr) bool {
+	fr, ok := expr.(*ast.FieldRef)
+	if !ok || !fr.IsColumn() || (fr.StreamName != p.name && fr.StreamName != ast.DefaultStream) || !safeIdentifier.MatchString(fr.Name) {
+		return false
+	}
+	if p.isSchemaless {
+		return true
+	}
+	for _, f := range p.streamStmt.StreamFields {
+		if f.Name == fr.Name {
+			return f.Default == nil && f.Computed == nil
+		}
+	}
+	return false
+}
+
+// isPushableLiteral checks if the literal can be written in the source sql. The strings with quote, backslash or
+// control characters are not pushed down because the escaping differs by the databases, e.g. MySQL treats \' as
+// an escaped quote.
+func isPushableLiteral(expr ast.Expr) bool {
+	switch l := expr.(type) {
+	case *ast.IntegerLiteral, *ast.NumberLiteral:
+		return true
+	case *ast.StringLiteral:
+		return !strings.ContainsFunc(l.Val, func(r rune) bool {
+			return r == '\'' || r == '\\' || unicode.IsControl(r)
+		})
+	}
+	return false
+}
+
+func literalValue(expr ast.Expr) interface{} {
+	switch l := expr.(type) {
+	case *ast.IntegerLiteral:
+		return l.Val
+	case *ast.NumberLiteral:
+		return l.Val
+	case *ast.StringLiteral:
+		return l.Val
+	}
+	return nil
+}
+
+func (p *DataSourcePlan) collectEquals(expr ast.Expr, equals map[string]interface{}) {
+	be, ok := expr.(*ast.BinaryExpr)
+	if !ok {
+		return
+	}
+	switch be.OP {
+	case ast.AND:
+		p.collectEquals(be.LHS, equals)
+		p.collectEquals(be.RHS, equals)
+	case ast.EQ:
+		if fr, ok := be.LHS.(*ast.FieldRef); ok {
+			equals[fr.Name] = literalValue(be.RHS)
+		} else if fr, ok := be.RHS.(*ast.FieldRef); ok {
+			equals[fr.Name] = literalValue(be.LHS)
+		}
+	}
+}
+
+// predicateSql prints the pushable condition in the standard sql
+func predicateSql(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.BinaryExpr:
+		switch e.OP {
+		case ast.AND:
+			return predicateSql(e.LHS) + " AND " + predicateSql(e.RHS)
+		case ast.OR:
+			return "(" + predicateSql(e.LHS) + " OR " + predicateSql(e.RHS) + ")"
+		case ast.NEQ:
+			return predicateSql(e.LHS) + " <> " + predicateSql(e.RHS)
+		default:
+			return predicateSql(e.LHS) + " " + e.OP.String() + " " + predicateSql(e.RHS)
+		}
+	case *ast.FieldRef:
+		return e.Name
+	case *ast.IntegerLiteral:
+		return strconv.FormatInt(e.Val, 10)
+	case *ast.NumberLiteral:
+		return strconv.FormatFloat(e.Val, 'f', -1, 64)
+	case *ast.StringLiteral:
+		return "'" + e.Val + "'"
+	}
+	return ""
+}
@@ -0,0 +1,83 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+func TestSourcePredicate(t *testing.T) {
+	tests := []struct {
+		where     string
+		predicate string
+		equals    map[string]interface{}
+	}{
+		{
+			where:     "a > 1 AND b = 'x'",
+			predicate: "a > 1 AND b = 'x'",
+			equals:    map[string]interface{}{"b": "x"},
+		},
+		{
+			where: "a > 1 OR abs(c) > 2",
+		},
+		{
+			where:     `(a > 1 OR b != "its") AND abs(c) > 2`,
+			predicate: "(a > 1 OR b <> 'its')",
+		},
+		{
+			where:     `a > 1 AND b = "it's"`,
+			predicate: "a > 1",
+		},
+		{
+			where:     `a > 1 AND (b = "x\\' OR 1=1 --" OR b = 'y')`,
+			predicate: "a > 1",
+		},
+		{
+			where:     "a > 1 AND `x) OR 1=1 --` = 1",
+			predicate: "a > 1",
+		},
+		{
+			where:     "tempF > 10 AND a = 2 AND 1.5 <= c",
+			predicate: "a = 2 AND 1.5 <= c",
+			equals:    map[string]interface{}{"a": int64(2)},
+		},
+		{
+			where: "unit = 'C'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.where, func(t *testing.T) {
+			stmt, err := xsql.NewParser(strings.NewReader(`CREATE STREAM demo (a bigint, b string, c float, unit string DEFAULT "C", tempF float AS (c * 1.8 + 32), ` + "`x) OR 1=1 --`" + ` bigint) WITH (DATASOURCE="demo")`)).ParseCreateStmt()
+			require.NoError(t, err)
+			ss := stmt.(*ast.StreamStmt)
+			sel, err := xsql.NewParser(strings.NewReader("SELECT * FROM demo WHERE " + tt.where)).Parse()
+			require.NoError(t, err)
+			p := DataSourcePlan{name: ss.Name, streamStmt: ss, streamFields: ss.StreamFields.ToJsonSchema()}.Init()
+			rest, lp := p.PushDownPredicate(sel.Condition)
+			assert.Nil(t, rest)
+			markSourcePredicates(lp)
+			predicate, equals := p.sourcePredicate()
+			assert.Equal(t, tt.predicate, predicate)
+			assert.Equal(t, tt.equals, equals)
+		})
+	}
+}
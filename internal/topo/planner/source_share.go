@@ -0,0 +1,77 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/topo/node"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+// isAutoShared checks if the source of a stream which is not declared as shared can be shared with the other rules
+// automatically. Only the streams without overrides in the rules with at most once qos are shared, because the
+// shared source cannot be checkpointed by each rule.
+func isAutoShared(t *DataSourcePlan, options *api.RuleOption, ov *SourceOverride) bool {
+	if conf.Config == nil || conf.Config.Source == nil || !conf.Config.Source.AutoShare {
+		return false
+	}
+	return t.streamStmt.StreamType == ast.TypeStream && !t.streamStmt.Options.SHARED && ov == nil && options.Qos == api.AtMostOnce
+}
+
+// autoShareName returns the name of the sub topo shared by the rules reading the stream with the same source and
+// decode settings. The stream may be updated while the rules created before are still running, so the settings are
+// part of the name to avoid sharing the outdated source.
+func autoShareName(t *DataSourcePlan, props map[string]interface{}) string {
+	o := t.streamStmt.Options
+	settings := map[string]interface{}{
+		"type":       o.TYPE,
+		"datasource": o.DATASOURCE,
+		"confKey":    o.CONF_KEY,
+		"format":     o.FORMAT,
+		"schemaId":   o.SCHEMAID,
+		"delimiter":  o.DELIMITER,
+		"props":      props,
+	}
+	h := fnv.New32a()
+	bs, err := json.Marshal(settings)
+	if err != nil {
+		bs = []byte(fmt.Sprintf("%v", settings))
+	}
+	_, _ = h.Write(bs)
+	return fmt.Sprintf("%s_auto_%08x", t.name, h.Sum32())
+}
+
+// copyTupleOp copies the shared tuple before the rule specific preprocessing which modifies the message in place
+type copyTupleOp struct {
+	op node.UnOperation
+}
+
+func (c *copyTupleOp) Apply(ctx api.StreamContext, data interface{}, fv *xsql.FunctionValuer, afv *xsql.AggregateFunctionValuer) interface{} {
+	if t, ok := data.(*xsql.Tuple); ok {
+		nt := t.Clone().(*xsql.Tuple)
+		nt.Message = make(xsql.Message, len(t.Message))
+		for k, v := range t.Message {
+			nt.Message[k] = v
+		}
+		data = nt
+	}
+	return c.op.Apply(ctx, data, fv, afv)
+}
@@ -0,0 +1,110 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/internal/topo"
+	"github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/internal/topo/operator"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+func TestAutoShareSource(t *testing.T) {
+	kv, err := store.GetKV("stream")
+	require.NoError(t, err)
+	s, err := json.Marshal(&xsql.StreamInfo{
+		StreamType: ast.TypeStream,
+		Statement:  `CREATE STREAM autoShareDemo (temp FLOAT, hum BIGINT) WITH (DATASOURCE="autoShare", FORMAT="json", TYPE="mqtt", STRICT_VALIDATION="true");`,
+	})
+	require.NoError(t, err)
+	require.NoError(t, kv.Set("autoShareDemo", string(s)))
+	defer kv.Delete("autoShareDemo")
+	old := conf.Config.Source
+	conf.Config.Source = &conf.SourceConf{AutoShare: true}
+	defer func() {
+		conf.Config.Source = old
+	}()
+
+	decoderOf := func(rule *api.Rule) string {
+		tp, err := Plan(rule)
+		require.NoError(t, err)
+		edges := tp.GetTopo().Edges
+		for k := range edges {
+			if strings.HasSuffix(k, "_decoder") {
+				return k
+			}
+		}
+		t.Fatalf("no decoder found in %v", edges)
+		return ""
+	}
+	newRule := func(id string, qos api.Qos) *api.Rule {
+		opt := *defaultOption
+		opt.Qos = qos
+		return &api.Rule{
+			Id:      id,
+			Sql:     `SELECT temp FROM autoShareDemo`,
+			Actions: []map[string]interface{}{{"log": map[string]interface{}{}}},
+			Options: &opt,
+		}
+	}
+	// The rules share the decoder while the preprocessors are specific to each rule
+	d1 := decoderOf(newRule("autoShareRule1", api.AtMostOnce))
+	assert.True(t, strings.HasPrefix(d1, "op_autoShareDemo_auto_"))
+	d2 := decoderOf(newRule("autoShareRule2", api.AtMostOnce))
+	assert.Equal(t, d1, d2)
+	name := strings.TrimSuffix(strings.TrimPrefix(d1, "op_"), "_2_decoder")
+	sub, existed := topo.GetSubTopo(name)
+	assert.True(t, existed)
+	assert.Equal(t, 1, sub.OpsCount())
+	defer topo.RemoveSubTopo(name)
+	// The rules with checkpoints have their own sources
+	assert.Equal(t, "op_2_decoder", decoderOf(newRule("autoShareRule3", api.AtLeastOnce)))
+	// The updated stream with different settings is not shared with the rules created before
+	s, err = json.Marshal(&xsql.StreamInfo{
+		StreamType: ast.TypeStream,
+		Statement:  `CREATE STREAM autoShareDemo (temp FLOAT, hum BIGINT) WITH (DATASOURCE="autoShare", FORMAT="delimited", TYPE="mqtt");`,
+	})
+	require.NoError(t, err)
+	require.NoError(t, kv.Set("autoShareDemo", string(s)))
+	d4 := decoderOf(newRule("autoShareRule4", api.AtMostOnce))
+	assert.True(t, strings.HasPrefix(d4, "op_autoShareDemo_auto_"))
+	assert.NotEqual(t, d1, d4)
+	topo.RemoveSubTopo(strings.TrimSuffix(strings.TrimPrefix(d4, "op_"), "_2_decoder"))
+	conf.Config.Source.AutoShare = false
+	assert.Equal(t, "op_2_decoder", decoderOf(newRule("autoShareRule5", api.AtMostOnce)))
+}
+
+func TestCopyTupleOp(t *testing.T) {
+	pp, err := operator.NewPreprocessor(false, map[string]*ast.JsonStreamField{"temp": {Type: "float"}}, false, nil, false, "", "", false, true)
+	require.NoError(t, err)
+	op := &copyTupleOp{op: pp}
+	tuple := &xsql.Tuple{Emitter: "demo", Message: xsql.Message{"temp": 20}}
+	ctx := context.WithValue(context.Background(), context.LoggerKey, conf.Log)
+	r := op.Apply(ctx, tuple, nil, nil)
+	assert.Equal(t, xsql.Message{"temp": 20.0}, r.(*xsql.Tuple).Message)
+	// the shared tuple is not modified
+	assert.Equal(t, xsql.Message{"temp": 20}, tuple.Message)
+}
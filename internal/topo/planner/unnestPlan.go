@@ -0,0 +1,126 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+// UnnestPlan explodes an array of the stream into multiple rows. The plans above it receive the exploded rows.
+type UnnestPlan struct {
+	baseLogicalPlan
+	unnest *ast.Unnest
+	// schema of the source, nil if schemaless
+	schema ast.StreamFields
+}
+
+func (p UnnestPlan) Init() *UnnestPlan {
+	p.baseLogicalPlan.self = &p
+	p.baseLogicalPlan.setPlanType(UNNEST)
+	return &p
+}
+
+func (p *UnnestPlan) BuildExplainInfo() {
+	p.baseLogicalPlan.ExplainInfo.Info = p.unnest.String()
+}
+
+// PushDownPredicate the conditions above may refer to the element fields, so they cannot be pushed down
+func (p *UnnestPlan) PushDownPredicate(condition ast.Expr) (ast.Expr, LogicalPlan) {
+	return condition, p
+}
+
+// PruneColumns the element fields are produced by this plan, so only the source fields and the fields used by the
+// unnest expression are needed from the source.
+func (p *UnnestPlan) PruneColumns(fields []ast.Expr) error {
+	newFields := getFields(p.unnest.Expr)
+	for _, f := range fields {
+		var name string
+		switch ft := f.(type) {
+		case *ast.FieldRef:
+			name = ft.Name
+		case *ast.BinaryExpr:
+			ast.WalkFunc(ft, func(n ast.Node) bool {
+				if fr, ok := n.(*ast.FieldRef); ok && name == "" {
+					name = fr.Name
+				}
+				return name == ""
+			})
+		}
+		if name != "" && p.schema != nil && !hasSchemaField(p.schema, name) {
+			continue
+		}
+		newFields = append(newFields, f)
+	}
+	return p.baseLogicalPlan.PruneColumns(newFields)
+}
+
+func hasSchemaField(schema ast.StreamFields, name string) bool {
+	for _, field := range schema {
+		if strings.EqualFold(field.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// unnestOutputStreams returns the stream infos with the schema of the exploded rows, which contains the stream fields
+// and the element fields. The schema is unknown if the element type cannot be inferred.
+func unnestOutputStreams(u *ast.Unnest, streamStmts []*streamInfo) ([]*streamInfo, error) {
+	result := make([]*streamInfo, len(streamStmts))
+	for i, si := range streamStmts {
+		if si.schema == nil {
+			result[i] = si
+			continue
+		}
+		var walkErr error
+		ast.WalkFunc(u.Expr, func(n ast.Node) bool {
+			if f, ok := n.(*ast.FieldRef); ok {
+				if !hasSchemaField(si.schema, f.Name) {
+					walkErr = fmt.Errorf("unknown field %s", f.Name)
+				}
+				return false
+			}
+			return walkErr == nil
+		})
+		if walkErr != nil {
+			return nil, walkErr
+		}
+		result[i] = &streamInfo{stmt: si.stmt, schema: unnestOutputSchema(u, si.schema)}
+	}
+	return result, nil
+}
+
+func unnestOutputSchema(u *ast.Unnest, schema ast.StreamFields) ast.StreamFields {
+	at, ok := inferFieldType(u.Expr, map[ast.StreamName]ast.StreamFields{ast.DefaultStream: schema}).(*ast.ArrayType)
+	if !ok {
+		return nil
+	}
+	var elementFields ast.StreamFields
+	if rt, ok := at.FieldType.(*ast.RecType); ok && at.Type == ast.STRUCT {
+		elementFields = rt.StreamFields
+	} else {
+		elementFields = ast.StreamFields{{Name: u.Name(), FieldType: &ast.BasicType{Type: at.Type}}}
+	}
+	result := make(ast.StreamFields, 0, len(schema)+len(elementFields))
+	for _, field := range schema {
+		if !hasSchemaField(elementFields, field.Name) {
+			result = append(result, field)
+		}
+	}
+	return append(result, elementFields...)
+}
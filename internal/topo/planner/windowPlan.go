@@ -32,6 +32,10 @@ type WindowPlan struct {
 	timeUnit         ast.Token
 	limit            int // If limit is not positive, there will be no limit
 	isEventTime      bool
+	// keys are the other group by dimensions for session window
+	keys ast.Dimensions
+	// incAggs are the aggregate calls calculated incrementally by the sliding window
+	incAggs []*ast.Call
 
 	stateFuncs []*ast.Call
 }
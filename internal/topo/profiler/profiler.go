@@ -0,0 +1,240 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package profiler attributes the cost of the running rules to their topo nodes.
+// The goroutines of each node carry the pprof labels of the rule and the node name, so the samples
+// of a CPU profile can be grouped by node.
+package profiler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime/pprof"
+	"sort"
+	"time"
+
+	"github.com/google/pprof/profile"
+)
+
+const (
+	LabelRule = "rule"
+	LabelNode = "op"
+	// topFuncs is the max number of the listed functions of each node and of the allocations
+	topFuncs = 5
+)
+
+// Do runs f with the labels of the node. The goroutines created in f inherit the labels.
+func Do(ruleId string, nodeName string, f func()) {
+	pprof.Do(context.Background(), pprof.Labels(LabelRule, ruleId, LabelNode, nodeName), func(context.Context) {
+		f()
+	})
+}
+
+type FuncCost struct {
+	Name  string `json:"name"`
+	Value int64  `json:"value"`
+}
+
+type NodeCost struct {
+	Name string `json:"name"`
+	// CpuNs is the cpu time in nanoseconds consumed by the goroutines of the node
+	CpuNs int64 `json:"cpuNs"`
+	// Percent is the percentage of the cpu time of the node in the whole rule
+	Percent float64 `json:"percent"`
+	// TopFunctions are the functions which consume the most cpu time of the node by their own
+	TopFunctions []FuncCost `json:"topFunctions"`
+}
+
+type Report struct {
+	RuleId   string `json:"ruleId"`
+	Duration int64  `json:"durationMs"`
+	// TotalCpuNs is the cpu time of the whole process during the profiling
+	TotalCpuNs int64 `json:"totalCpuNs"`
+	// RuleCpuNs is the cpu time of all nodes of the rule
+	RuleCpuNs int64 `json:"ruleCpuNs"`
+	// Nodes are ranked by the cpu time desc
+	Nodes []NodeCost `json:"nodes"`
+	// Allocations are the functions which allocate the most bytes in the whole process during the profiling.
+	// The allocation profile of go does not have goroutine labels, so it cannot be attributed to nodes.
+	Allocations []FuncCost `json:"allocations"`
+}
+
+// Profile runs the cpu profile of the whole process for the duration and attributes the samples to the nodes of the rule.
+// Only one cpu profile can run at a time, so it fails if another one like the pprof endpoint is running.
+func Profile(ctx context.Context, ruleId string, duration time.Duration) (*Report, error) {
+	heapStart, err := heapProfile()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := pprof.StartCPUProfile(&buf); err != nil {
+		return nil, fmt.Errorf("start cpu profile error: %v", err)
+	}
+	start := time.Now()
+	select {
+	case <-time.After(duration):
+	case <-ctx.Done():
+	}
+	pprof.StopCPUProfile()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	cpu, err := profile.Parse(&buf)
+	if err != nil {
+		return nil, fmt.Errorf("parse cpu profile error: %v", err)
+	}
+	report, err := analyzeCPU(ruleId, cpu)
+	if err != nil {
+		return nil, err
+	}
+	report.Duration = time.Since(start).Milliseconds()
+	heapEnd, err := heapProfile()
+	if err != nil {
+		return nil, err
+	}
+	report.Allocations, err = analyzeAlloc(heapStart, heapEnd)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func heapProfile() (*profile.Profile, error) {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("heap").WriteTo(&buf, 0); err != nil {
+		return nil, fmt.Errorf("write heap profile error: %v", err)
+	}
+	p, err := profile.Parse(&buf)
+	if err != nil {
+		return nil, fmt.Errorf("parse heap profile error: %v", err)
+	}
+	return p, nil
+}
+
+func analyzeCPU(ruleId string, p *profile.Profile) (*Report, error) {
+	vi, err := valueIndex(p, "cpu")
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{RuleId: ruleId, Nodes: []NodeCost{}}
+	nodes := make(map[string]*NodeCost)
+	funcs := make(map[string]map[string]int64)
+	for _, s := range p.Sample {
+		v := s.Value[vi]
+		report.TotalCpuNs += v
+		if !hasLabel(s, LabelRule, ruleId) {
+			continue
+		}
+		names := s.Label[LabelNode]
+		if len(names) == 0 {
+			continue
+		}
+		name := names[0]
+		n, ok := nodes[name]
+		if !ok {
+			n = &NodeCost{Name: name}
+			nodes[name] = n
+			funcs[name] = make(map[string]int64)
+		}
+		n.CpuNs += v
+		report.RuleCpuNs += v
+		if f := leafFunc(s); f != "" {
+			funcs[name][f] += v
+		}
+	}
+	for name, n := range nodes {
+		if report.RuleCpuNs > 0 {
+			n.Percent = float64(n.CpuNs) * 100 / float64(report.RuleCpuNs)
+		}
+		n.TopFunctions = rank(funcs[name])
+		report.Nodes = append(report.Nodes, *n)
+	}
+	sort.SliceStable(report.Nodes, func(i, j int) bool {
+		if report.Nodes[i].CpuNs == report.Nodes[j].CpuNs {
+			return report.Nodes[i].Name < report.Nodes[j].Name
+		}
+		return report.Nodes[i].CpuNs > report.Nodes[j].CpuNs
+	})
+	return report, nil
+}
+
+// analyzeAlloc ranks the functions by the bytes allocated between the two heap profiles
+func analyzeAlloc(start, end *profile.Profile) ([]FuncCost, error) {
+	start = start.Copy()
+	start.Scale(-1)
+	delta, err := profile.Merge([]*profile.Profile{start, end})
+	if err != nil {
+		return nil, fmt.Errorf("diff heap profile error: %v", err)
+	}
+	vi, err := valueIndex(delta, "alloc_space")
+	if err != nil {
+		return nil, err
+	}
+	funcs := make(map[string]int64)
+	for _, s := range delta.Sample {
+		if f := leafFunc(s); f != "" {
+			funcs[f] += s.Value[vi]
+		}
+	}
+	for f, v := range funcs {
+		if v <= 0 {
+			delete(funcs, f)
+		}
+	}
+	return rank(funcs), nil
+}
+
+func valueIndex(p *profile.Profile, typ string) (int, error) {
+	for i, st := range p.SampleType {
+		if st.Type == typ {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("sample type %s not found in the profile", typ)
+}
+
+func hasLabel(s *profile.Sample, key string, value string) bool {
+	for _, v := range s.Label[key] {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// leafFunc returns the innermost function of the sample
+func leafFunc(s *profile.Sample) string {
+	if len(s.Location) == 0 || len(s.Location[0].Line) == 0 || s.Location[0].Line[0].Function == nil {
+		return ""
+	}
+	return s.Location[0].Line[0].Function.Name
+}
+
+func rank(funcs map[string]int64) []FuncCost {
+	result := make([]FuncCost, 0, len(funcs))
+	for name, v := range funcs {
+		result = append(result, FuncCost{Name: name, Value: v})
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Value == result[j].Value {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].Value > result[j].Value
+	})
+	if len(result) > topFuncs {
+		result = result[:topFuncs]
+	}
+	return result
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profiler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeCPU(t *testing.T) {
+	fn := func(name string) []*profile.Location {
+		return []*profile.Location{{Line: []profile.Line{{Function: &profile.Function{Name: name}}}}}
+	}
+	sample := func(rule, op, f string, v int64) *profile.Sample {
+		s := &profile.Sample{Value: []int64{1, v}, Location: fn(f), Label: map[string][]string{}}
+		if rule != "" {
+			s.Label[LabelRule] = []string{rule}
+			s.Label[LabelNode] = []string{op}
+		}
+		return s
+	}
+	p := &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "samples", Unit: "count"}, {Type: "cpu", Unit: "nanoseconds"}},
+		Sample: []*profile.Sample{
+			sample("rule1", "project", "json.Marshal", 30),
+			sample("rule1", "project", "xsql.Eval", 10),
+			sample("rule1", "filter", "xsql.Eval", 20),
+			sample("rule1", "project", "json.Marshal", 20),
+			sample("rule2", "project", "json.Marshal", 100),
+			sample("", "", "runtime.gc", 20),
+		},
+	}
+	r, err := analyzeCPU("rule1", p)
+	require.NoError(t, err)
+	assert.Equal(t, &Report{
+		RuleId:     "rule1",
+		TotalCpuNs: 200,
+		RuleCpuNs:  80,
+		Nodes: []NodeCost{
+			{Name: "project", CpuNs: 60, Percent: 75, TopFunctions: []FuncCost{{Name: "json.Marshal", Value: 50}, {Name: "xsql.Eval", Value: 10}}},
+			{Name: "filter", CpuNs: 20, Percent: 25, TopFunctions: []FuncCost{{Name: "xsql.Eval", Value: 20}}},
+		},
+	}, r)
+
+	_, err = analyzeCPU("rule1", &profile.Profile{SampleType: []*profile.ValueType{{Type: "alloc_space"}}})
+	assert.Error(t, err)
+}
+
+//go:noinline
+func burn(ctx context.Context) {
+	x := 0
+	for ctx.Err() == nil {
+		for i := 0; i < 1000000; i++ {
+			x += i
+		}
+	}
+	_ = x
+}
+
+func TestProfile(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// The label is inherited by the goroutine created inside
+	Do("busyRule", "busyOp", func() {
+		go burn(ctx)
+	})
+	r, err := Profile(context.Background(), "busyRule", 500*time.Millisecond)
+	require.NoError(t, err)
+	require.Len(t, r.Nodes, 1)
+	assert.Equal(t, "busyOp", r.Nodes[0].Name)
+	assert.Greater(t, r.Nodes[0].CpuNs, int64(0))
+	assert.Equal(t, float64(100), r.Nodes[0].Percent)
+
+	// Only one cpu profile can run at a time
+	pctx, pcancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		pcancel()
+	}()
+	done := make(chan struct{})
+	go func() {
+		_, _ = Profile(pctx, "busyRule", time.Minute)
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	_, err = Profile(context.Background(), "busyRule", time.Millisecond)
+	assert.Error(t, err)
+	<-done
+}
@@ -0,0 +1,191 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package topo
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/topo/connection/clients"
+	"github.com/lf-edge/ekuiper/internal/topo/node"
+	"github.com/lf-edge/ekuiper/internal/topo/node/metric"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+)
+
+const (
+	QueueAlertFiring   = "firing"
+	QueueAlertResolved = "resolved"
+)
+
+// QueueAlert is sent when a queue stays above the threshold and when it falls below the threshold after alerted
+type QueueAlert struct {
+	Rule     string `json:"rule"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Length   int    `json:"length"`
+	Capacity int    `json:"capacity"`
+	Status   string `json:"status"`
+	// Since is the time in milliseconds when the queue goes above the threshold
+	Since     int64 `json:"since"`
+	Timestamp int64 `json:"timestamp"`
+}
+
+type queueState struct {
+	since   time.Time
+	alerted bool
+}
+
+// queueMonitor samples the queues between the nodes of a rule to update the metrics and send the alerts
+type queueMonitor struct {
+	ruleId string
+	nodes  []node.QueueNode
+	conf   *conf.QueueAlertConf
+	// the state of the queues above the threshold, the key is from/to
+	states map[string]*queueState
+	send   func(ctx api.StreamContext, alert *QueueAlert)
+}
+
+func (s *Topo) queueNodes() []node.QueueNode {
+	var result []node.QueueNode
+	for _, src := range s.sources {
+		if qn, ok := src.(node.QueueNode); ok {
+			result = append(result, qn)
+		}
+	}
+	for _, op := range s.ops {
+		if qn, ok := op.(node.QueueNode); ok {
+			result = append(result, qn)
+		}
+	}
+	return result
+}
+
+// monitorQueues runs until the rule stops if the queue alert or the prometheus metrics is enabled
+func (s *Topo) monitorQueues(ctx api.StreamContext) {
+	c := &conf.Config.QueueAlert
+	if !c.Enable && !conf.Config.Basic.Prometheus {
+		return
+	}
+	m := &queueMonitor{
+		ruleId: s.name,
+		nodes:  s.queueNodes(),
+		conf:   c,
+		states: make(map[string]*queueState),
+		send:   sendQueueAlert,
+	}
+	go func() {
+		ticker := conf.GetTicker(c.GetInterval().Milliseconds())
+		defer func() {
+			ticker.Stop()
+			metric.CleanQueueLength(m.ruleId)
+		}()
+		for {
+			select {
+			case <-ticker.C:
+				m.check(ctx, conf.GetNow())
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (m *queueMonitor) check(ctx api.StreamContext, now time.Time) {
+	for _, n := range m.nodes {
+		for _, q := range n.GetOutputQueues() {
+			metric.SetQueueLength(m.ruleId, n.GetName(), q.To, q.Length, q.Capacity)
+			if !m.conf.Enable || q.Capacity == 0 {
+				continue
+			}
+			key := n.GetName() + "/" + q.To
+			st, ok := m.states[key]
+			if float64(q.Length) >= float64(q.Capacity)*m.conf.Threshold {
+				if !ok {
+					st = &queueState{since: now}
+					m.states[key] = st
+				}
+				if !st.alerted && now.Sub(st.since) >= m.conf.GetDuration() {
+					st.alerted = true
+					m.send(ctx, m.newAlert(n.GetName(), q, QueueAlertFiring, st.since, now))
+				}
+			} else if ok {
+				delete(m.states, key)
+				if st.alerted {
+					m.send(ctx, m.newAlert(n.GetName(), q, QueueAlertResolved, st.since, now))
+				}
+			}
+		}
+	}
+}
+
+func (m *queueMonitor) newAlert(from string, q node.Queue, status string, since time.Time, now time.Time) *QueueAlert {
+	return &QueueAlert{
+		Rule:      m.ruleId,
+		From:      from,
+		To:        q.To,
+		Length:    q.Length,
+		Capacity:  q.Capacity,
+		Status:    status,
+		Since:     since.UnixMilli(),
+		Timestamp: now.UnixMilli(),
+	}
+}
+
+// The mqtt client is shared by the alerts of all rules and created at the first alert
+var alertClient struct {
+	sync.Mutex
+	cli   api.MessageClient
+	topic string
+	qos   byte
+}
+
+func sendQueueAlert(ctx api.StreamContext, alert *QueueAlert) {
+	logger := ctx.GetLogger()
+	if alert.Status == QueueAlertFiring {
+		logger.Warnf("queue from %s to %s stays above the threshold since %d, length %d/%d", alert.From, alert.To, alert.Since, alert.Length, alert.Capacity)
+	} else {
+		logger.Infof("queue from %s to %s falls below the threshold, length %d/%d", alert.From, alert.To, alert.Length, alert.Capacity)
+	}
+	props := conf.Config.QueueAlert.Mqtt
+	if len(props) == 0 {
+		return
+	}
+	alertClient.Lock()
+	defer alertClient.Unlock()
+	if alertClient.cli == nil {
+		topic, _ := props["topic"].(string)
+		if topic == "" {
+			logger.Warnf("queueAlert.mqtt.topic is not set, the alert is only logged")
+			return
+		}
+		qos, err := cast.ToInt(props["qos"], cast.CONVERT_SAMEKIND)
+		if err != nil || qos < 0 || qos > 2 {
+			qos = 0
+		}
+		cli, err := clients.GetClient("mqtt", props)
+		if err != nil {
+			logger.Errorf("create mqtt client to send the queue alert error: %v", err)
+			return
+		}
+		alertClient.cli, alertClient.topic, alertClient.qos = cli, topic, byte(qos)
+	}
+	payload, _ := json.Marshal(alert)
+	if err := alertClient.cli.Publish(ctx, alertClient.topic, payload, map[string]any{"qos": alertClient.qos}); err != nil {
+		logger.Errorf("publish the queue alert error: %v", err)
+	}
+}
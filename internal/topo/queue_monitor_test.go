@@ -0,0 +1,88 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package topo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/topo/node"
+	"github.com/lf-edge/ekuiper/pkg/api"
+	mockContext "github.com/lf-edge/ekuiper/pkg/mock/context"
+)
+
+type mockQueueNode struct {
+	name   string
+	queues []node.Queue
+}
+
+func (m *mockQueueNode) GetName() string {
+	return m.name
+}
+
+func (m *mockQueueNode) GetOutputQueues() []node.Queue {
+	return m.queues
+}
+
+func TestQueueMonitor(t *testing.T) {
+	c := &conf.QueueAlertConf{Enable: true, Threshold: 0.5, Duration: "10s"}
+	require.NoError(t, c.Validate())
+	n := &mockQueueNode{name: "filter", queues: []node.Queue{{To: "project", Length: 2, Capacity: 10}, {To: "sink", Length: 0, Capacity: 10}}}
+	var alerts []*QueueAlert
+	m := &queueMonitor{
+		ruleId: "rule1",
+		nodes:  []node.QueueNode{n},
+		conf:   c,
+		states: make(map[string]*queueState),
+		send: func(_ api.StreamContext, alert *QueueAlert) {
+			alerts = append(alerts, alert)
+		},
+	}
+	ctx := mockContext.NewMockContext("rule1", "op1")
+	start := time.UnixMilli(1000)
+	m.check(ctx, start)
+	assert.Len(t, m.states, 0)
+	// Above the threshold but not long enough
+	n.queues[0].Length = 5
+	m.check(ctx, start.Add(5*time.Second))
+	m.check(ctx, start.Add(10*time.Second))
+	assert.Len(t, alerts, 0)
+	m.check(ctx, start.Add(15*time.Second))
+	m.check(ctx, start.Add(20*time.Second))
+	require.Len(t, alerts, 1)
+	assert.Equal(t, &QueueAlert{Rule: "rule1", From: "filter", To: "project", Length: 5, Capacity: 10, Status: QueueAlertFiring, Since: 6000, Timestamp: 16000}, alerts[0])
+	// Falls below the threshold
+	n.queues[0].Length = 4
+	m.check(ctx, start.Add(25*time.Second))
+	require.Len(t, alerts, 2)
+	assert.Equal(t, &QueueAlert{Rule: "rule1", From: "filter", To: "project", Length: 4, Capacity: 10, Status: QueueAlertResolved, Since: 6000, Timestamp: 26000}, alerts[1])
+	assert.Len(t, m.states, 0)
+	// Dropping below the threshold before alerted resets the timer and sends nothing
+	n.queues[1].Length = 10
+	m.check(ctx, start.Add(30*time.Second))
+	n.queues[1].Length = 0
+	m.check(ctx, start.Add(35*time.Second))
+	n.queues[1].Length = 10
+	m.check(ctx, start.Add(40*time.Second))
+	assert.Len(t, alerts, 2)
+	m.check(ctx, start.Add(50*time.Second))
+	require.Len(t, alerts, 3)
+	assert.Equal(t, "sink", alerts[2].To)
+	assert.Equal(t, int64(41000), alerts[2].Since)
+}
@@ -0,0 +1,105 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package quota limits the resources used by a rule. The operators charge their processing time and the
+// checkpoints report the state size to the limiter of the rule, and the sources check the limiter before
+// reading to apply the policy.
+package quota
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+// burst is the period of the cpu time which can be saved up when the rule is idle
+const burst = time.Second
+
+// Limiter is shared by all nodes of a rule. It is safe to call concurrently.
+type Limiter struct {
+	conf *api.RuleQuota
+	// buffered returns the count of the tuples buffered between the nodes
+	buffered  func() int
+	stateSize atomic.Int64
+	// cpu token bucket in nanoseconds, which is refilled by the share and becomes negative when overused
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func NewLimiter(c *api.RuleQuota, buffered func() int) *Limiter {
+	return &Limiter{
+		conf:     c,
+		buffered: buffered,
+		tokens:   c.CpuShare * float64(burst),
+		last:     conf.GetNow(),
+	}
+}
+
+func (l *Limiter) Policy() string {
+	return l.conf.Policy
+}
+
+// Charge consumes the cpu time of the processing
+func (l *Limiter) Charge(d time.Duration) {
+	if l.conf.CpuShare <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	l.tokens -= float64(d)
+}
+
+func (l *Limiter) refill() {
+	now := conf.GetNow()
+	l.tokens += float64(now.Sub(l.last)) * l.conf.CpuShare
+	if capacity := l.conf.CpuShare * float64(burst); l.tokens > capacity {
+		l.tokens = capacity
+	}
+	l.last = now
+}
+
+// SetStateSize updates the size of the states of the last checkpoint
+func (l *Limiter) SetStateSize(size int64) {
+	l.stateSize.Store(size)
+}
+
+// Check returns the error of the first exceeded limit, or nil if the rule is under the quota
+func (l *Limiter) Check() error {
+	if l.conf.CpuShare > 0 {
+		l.mu.Lock()
+		l.refill()
+		exceeded := l.tokens < 0
+		l.mu.Unlock()
+		if exceeded {
+			return fmt.Errorf("cpu share %v exceeded", l.conf.CpuShare)
+		}
+	}
+	if l.conf.MaxStateSize > 0 {
+		if size := l.stateSize.Load(); size > l.conf.MaxStateSize {
+			return fmt.Errorf("state size %d exceeds the limit %d", size, l.conf.MaxStateSize)
+		}
+	}
+	if l.conf.MaxBufferedTuples > 0 && l.buffered != nil {
+		if n := l.buffered(); n > l.conf.MaxBufferedTuples {
+			return fmt.Errorf("buffered tuples %d exceeds the limit %d", n, l.conf.MaxBufferedTuples)
+		}
+	}
+	return nil
+}
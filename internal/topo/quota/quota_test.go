@@ -0,0 +1,63 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+func TestCpuShare(t *testing.T) {
+	mc := conf.Clock.(*clock.Mock)
+	l := NewLimiter(&api.RuleQuota{CpuShare: 0.5, Policy: api.QuotaPause}, nil)
+	assert.NoError(t, l.Check())
+	// The burst is half a second of cpu time
+	l.Charge(400 * time.Millisecond)
+	assert.NoError(t, l.Check())
+	l.Charge(200 * time.Millisecond)
+	assert.EqualError(t, l.Check(), "cpu share 0.5 exceeded")
+	// Refill 100ms cpu time in 200ms
+	mc.Add(200 * time.Millisecond)
+	assert.NoError(t, l.Check())
+	// The tokens cannot exceed the burst after idle
+	mc.Add(time.Minute)
+	l.Charge(600 * time.Millisecond)
+	assert.Error(t, l.Check())
+}
+
+func TestLimits(t *testing.T) {
+	buffered := 0
+	l := NewLimiter(&api.RuleQuota{MaxBufferedTuples: 10, MaxStateSize: 1024, Policy: api.QuotaDrop}, func() int {
+		return buffered
+	})
+	assert.Equal(t, api.QuotaDrop, l.Policy())
+	// Cpu time is not limited
+	l.Charge(time.Hour)
+	assert.NoError(t, l.Check())
+	buffered = 11
+	assert.EqualError(t, l.Check(), "buffered tuples 11 exceeds the limit 10")
+	buffered = 10
+	assert.NoError(t, l.Check())
+	l.SetStateSize(2048)
+	assert.EqualError(t, l.Check(), "state size 2048 exceeds the limit 1024")
+	l.SetStateSize(512)
+	assert.NoError(t, l.Check())
+}
@@ -0,0 +1,194 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/shirou/gopsutil/cpu"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/topo/node/metric"
+)
+
+const (
+	// throttleSlice is the period in which a throttled rule reads in the beginning share and pauses in the rest
+	throttleSlice = 100 * time.Millisecond
+	// recoverRatio is the ratio of the cpu threshold. The throttling is relieved level by level when the cpu usage is below it
+	recoverRatio = 0.9
+)
+
+// Throttle is the throttling state of a rule. It is safe to call concurrently.
+type Throttle struct {
+	ruleId   string
+	priority int
+	// level is how many priorities the rule is below the cutoff. 0 means not throttled
+	level atomic.Int32
+	// waited is the total nanoseconds the sources paused for the throttling
+	waited atomic.Int64
+}
+
+// Level returns the throttle level. Each level halves the time share to read
+func (t *Throttle) Level() int {
+	return int(t.level.Load())
+}
+
+// Delay returns how long the source should pause at now before reading. A throttled rule can only read in the
+// beginning 1/2^level of each time slice.
+func (t *Throttle) Delay(now time.Time) time.Duration {
+	l := t.level.Load()
+	if l == 0 {
+		return 0
+	}
+	pos := time.Duration(now.UnixNano()) % throttleSlice
+	if pos < throttleSlice>>l {
+		return 0
+	}
+	return throttleSlice - pos
+}
+
+// AddWaited records the time the source paused
+func (t *Throttle) AddWaited(d time.Duration) {
+	t.waited.Add(int64(d))
+	metric.AddThrottledTime(t.ruleId, d)
+}
+
+// Waited returns the total time the sources paused
+func (t *Throttle) Waited() time.Duration {
+	return time.Duration(t.waited.Load())
+}
+
+// Governor adjusts the throttling of the running rules by the host cpu usage. When the host is saturated, it raises
+// the cutoff priority by one level each time, and the rules of lower priority than the cutoff are throttled. The rules
+// of the highest priority are never throttled.
+type Governor struct {
+	threshold float64
+	mu        sync.Mutex
+	rules     map[string]*Throttle
+	cutoff    int
+}
+
+func NewGovernor(threshold float64) *Governor {
+	return &Governor{
+		threshold: threshold,
+		rules:     make(map[string]*Throttle),
+	}
+}
+
+// Register adds a running rule and returns its throttle
+func (g *Governor) Register(ruleId string, priority int) *Throttle {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	t := &Throttle{ruleId: ruleId, priority: priority}
+	g.rules[ruleId] = t
+	g.apply(t)
+	return t
+}
+
+// Unregister removes the rule if its throttle is not replaced by a restart
+func (g *Governor) Unregister(ruleId string, t *Throttle) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.rules[ruleId] == t {
+		delete(g.rules, ruleId)
+		metric.CleanThrottle(ruleId)
+	}
+}
+
+// Update adjusts the cutoff by the cpu usage ratio in [0, 1]
+func (g *Governor) Update(usage float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	top := 0
+	for _, t := range g.rules {
+		if t.priority > top {
+			top = t.priority
+		}
+	}
+	switch {
+	case usage >= g.threshold:
+		if g.cutoff < top {
+			g.cutoff++
+			conf.Log.Infof("host cpu usage %.2f is saturated, throttle the rules of priority lower than %d", usage, g.cutoff)
+		}
+	case usage < g.threshold*recoverRatio:
+		if g.cutoff > 0 {
+			g.cutoff--
+			conf.Log.Infof("host cpu usage %.2f recovers, throttle the rules of priority lower than %d", usage, g.cutoff)
+		}
+	}
+	// The rules of the highest priority may be stopped
+	if g.cutoff > top {
+		g.cutoff = top
+	}
+	for _, t := range g.rules {
+		g.apply(t)
+	}
+}
+
+func (g *Governor) apply(t *Throttle) {
+	level := g.cutoff - t.priority
+	if level < 0 {
+		level = 0
+	}
+	t.level.Store(int32(level))
+	metric.SetThrottleLevel(t.ruleId, level)
+}
+
+var governor *Governor
+
+// StartGovernor samples the host cpu usage in the interval to throttle the rules until exit
+func StartGovernor(c *conf.ThrottleConf, exit <-chan struct{}) {
+	if !c.Enable {
+		return
+	}
+	governor = NewGovernor(c.CpuThreshold)
+	// The first call starts to measure
+	_, _ = cpu.Percent(0, false)
+	go func() {
+		ticker := time.NewTicker(c.GetInterval())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-exit:
+				return
+			case <-ticker.C:
+				p, err := cpu.Percent(0, false)
+				if err != nil || len(p) == 0 {
+					conf.Log.Warnf("sample cpu usage error: %v", err)
+					continue
+				}
+				governor.Update(p[0] / 100)
+			}
+		}
+	}()
+}
+
+// Register adds the rule to the governor. Return nil if the throttling is disabled
+func Register(ruleId string, priority int) *Throttle {
+	if governor == nil {
+		return nil
+	}
+	return governor.Register(ruleId, priority)
+}
+
+func Unregister(ruleId string, t *Throttle) {
+	if governor == nil || t == nil {
+		return
+	}
+	governor.Unregister(ruleId, t)
+}
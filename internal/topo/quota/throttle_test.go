@@ -0,0 +1,86 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGovernor(t *testing.T) {
+	g := NewGovernor(0.9)
+	low := g.Register("low", 0)
+	normal := g.Register("normal", 5)
+	safety := g.Register("safety", 9)
+	levels := func() []int {
+		return []int{low.Level(), normal.Level(), safety.Level()}
+	}
+	assert.Equal(t, []int{0, 0, 0}, levels())
+	// Saturated, the cutoff rises one level each time
+	g.Update(0.95)
+	assert.Equal(t, []int{1, 0, 0}, levels())
+	for i := 0; i < 5; i++ {
+		g.Update(1)
+	}
+	assert.Equal(t, []int{6, 1, 0}, levels())
+	// Between the recover ratio and the threshold, keep the throttling
+	g.Update(0.85)
+	assert.Equal(t, []int{6, 1, 0}, levels())
+	// The rules of the highest priority are never throttled
+	for i := 0; i < 10; i++ {
+		g.Update(1)
+	}
+	assert.Equal(t, []int{9, 4, 0}, levels())
+	// The new rule is throttled by the current cutoff
+	another := g.Register("another", 7)
+	assert.Equal(t, 2, another.Level())
+	// Recover level by level
+	g.Update(0.5)
+	assert.Equal(t, []int{8, 3, 0}, levels())
+	// The cutoff is capped when the rule of the highest priority stops
+	g.Unregister("safety", safety)
+	g.Update(0.85)
+	assert.Equal(t, []int{7, 2}, levels()[:2])
+	assert.Equal(t, 0, another.Level())
+	// Unregister a replaced throttle does nothing
+	restarted := g.Register("low", 0)
+	g.Unregister("low", low)
+	assert.Same(t, restarted, g.rules["low"])
+	for i := 0; i < 10; i++ {
+		g.Update(0)
+	}
+	assert.Equal(t, 0, restarted.Level())
+	assert.Equal(t, 0, normal.Level())
+}
+
+func TestThrottleDelay(t *testing.T) {
+	th := &Throttle{ruleId: "test"}
+	slice := time.Unix(1000, 0)
+	assert.Equal(t, time.Duration(0), th.Delay(slice.Add(60*time.Millisecond)))
+	// Read in the first half of each slice
+	th.level.Store(1)
+	assert.Equal(t, time.Duration(0), th.Delay(slice))
+	assert.Equal(t, time.Duration(0), th.Delay(slice.Add(49*time.Millisecond)))
+	assert.Equal(t, 40*time.Millisecond, th.Delay(slice.Add(60*time.Millisecond)))
+	// Read in the first quarter of each slice
+	th.level.Store(2)
+	assert.Equal(t, time.Duration(0), th.Delay(slice.Add(20*time.Millisecond)))
+	assert.Equal(t, 70*time.Millisecond, th.Delay(slice.Add(30*time.Millisecond)))
+	th.AddWaited(70 * time.Millisecond)
+	th.AddWaited(30 * time.Millisecond)
+	assert.Equal(t, 100*time.Millisecond, th.Waited())
+}
@@ -39,6 +39,7 @@ const (
 	RuleStopped    = "Stopped: canceled manually."
 	RuleTerminated = "Stopped: schedule terminated."
 	RuleWait       = "Stopped: waiting for next schedule."
+	RuleCompleted  = "Stopped: completed."
 )
 
 type ActionSignal int
@@ -102,6 +103,23 @@ type RuleState struct {
 	lastStartTimestamp int64
 	lastStopTimestamp  int64
 	isClosed           bool
+	// completed is set when the rule running once finishes and reset when it starts again
+	completed bool
+	// restarts is the recent failures of the running rule, the oldest first
+	restarts []RestartRecord
+}
+
+// maxRestartHistory is the count of the recent restart records to keep
+const maxRestartHistory = 20
+
+// RestartRecord is a failure of the running rule and how it restarts
+type RestartRecord struct {
+	Timestamp int64  `json:"timestamp"`
+	Error     string `json:"error"`
+	// Attempt is the count of the consecutive restarts including this one. 0 means the rule gives up restarting
+	Attempt int `json:"attempt"`
+	// Delay is the milliseconds to wait before restarting
+	Delay int `json:"delay"`
 }
 
 // NewRuleState Create and initialize a rule state.
@@ -230,11 +248,11 @@ func (rs *RuleState) runTopo(ctx context.Context) {
 		count := 0
 		d := option.Delay
 		var er error
-		ticker := time.NewTicker(time.Duration(d) * time.Millisecond)
-		defer ticker.Stop()
 		for {
+			openAt := time.Now()
+			errCh := tp.Open()
 			select {
-			case e := <-tp.Open():
+			case e := <-errCh:
 
 				er = e
 				if er != nil { // Only restart rule for errors
@@ -244,6 +262,14 @@ func (rs *RuleState) runTopo(ctx context.Context) {
 				} else { // exit normally
 					return nil
 				}
+			case <-tp.Completed():
+				rs.complete(tp)
+				return nil
+			}
+			// The failure after running healthy for the reset window starts a new round of retries
+			if option.ResetWindow > 0 && time.Since(openAt) >= time.Duration(option.ResetWindow)*time.Millisecond {
+				count = 0
+				d = option.Delay
 			}
 			if count < option.Attempts {
 				if d > option.MaxDelay {
@@ -259,19 +285,23 @@ func (rs *RuleState) runTopo(ctx context.Context) {
 				} else {
 					conf.Log.Infof("Rule %s will restart with delay %d", rs.RuleId, d)
 				}
+				rs.recordRestart(er, count+1, d)
 				// retry after delay
+				timer := time.NewTimer(time.Duration(d) * time.Millisecond)
 				select {
-				case <-ticker.C:
+				case <-timer.C:
 					break
 				case <-ctx.Done():
+					timer.Stop()
 					conf.Log.Errorf("stop rule %s retry as cancelled", rs.RuleId)
 					return nil
 				}
 				count++
 				if option.Multiplier > 0 {
-					d = option.Delay * int(math.Pow(option.Multiplier, float64(count)))
+					d = int(float64(option.Delay) * math.Pow(option.Multiplier, float64(count)))
 				}
 			} else {
+				rs.recordRestart(er, 0, 0)
 				return er
 			}
 		}
@@ -291,6 +321,21 @@ func (rs *RuleState) runTopo(ctx context.Context) {
 	}
 }
 
+// complete stops the rule running once after it finishes. The topology is kept to run again when the rule restarts.
+func (rs *RuleState) complete(tp *topo.Topo) {
+	rs.Lock()
+	defer rs.Unlock()
+	if rs.triggered != 1 || rs.Topology != tp {
+		return
+	}
+	conf.Log.Infof("rule %s completes running once", rs.RuleId)
+	rs.completed = true
+	rs.triggered = 0
+	tp.Cancel()
+	rs.lastStopTimestamp = time.Now().UnixMilli()
+	rs.ActionCh <- ActionSignalStop
+}
+
 // The action functions are state machine.
 
 func (rs *RuleState) Start() (err error) {
@@ -307,7 +352,7 @@ func (rs *RuleState) Start() (err error) {
 		return fmt.Errorf("rule %s is already deleted", rs.RuleId)
 	}
 	if rs.Rule.IsLongRunningScheduleRule() {
-		isIn, err := schedule.IsInScheduleRanges(conf.GetNow(), rs.Rule.Options.CronDatetimeRange)
+		isIn, err := schedule.IsInScheduleRangesIn(conf.GetNow(), rs.Rule.Options.CronDatetimeRange, rs.scheduleLocation())
 		if err != nil {
 			return err
 		}
@@ -348,7 +393,7 @@ func (rs *RuleState) startScheduleRule() error {
 		}
 		rs.stopAfterDuration(remainedDuration, cronCtx)
 	}
-	entryID, err := backgroundCron.AddFunc(rs.Rule.Options.Cron, func() {
+	entryID, err := backgroundCron.AddFunc(rs.cronExpr(), func() {
 		var started bool
 		var err error
 		if started, err = func() (bool, error) {
@@ -433,6 +478,7 @@ func (rs *RuleState) start() error {
 		}
 		rs.triggered = 1
 	}
+	rs.completed = false
 	if rs.Rule.IsScheduleRule() || rs.Rule.IsLongRunningScheduleRule() {
 		conf.Log.Debugf("rule %v started", rs.RuleId)
 	}
@@ -459,6 +505,21 @@ func (rs *RuleState) Stop() (err error) {
 	return rs.stop()
 }
 
+// StopWithDrain stops the rule after the data in flight is sent out by the sinks or the timeout is reached.
+// The rule keeps running while draining, but its sources stop reading.
+func (rs *RuleState) StopWithDrain(timeout time.Duration) (err error) {
+	rs.Lock()
+	tp := rs.Topology
+	running := rs.triggered == 1
+	rs.Unlock()
+	if running && tp != nil {
+		if !tp.Drain(timeout) {
+			conf.Log.Warnf("rule %s is stopped before fully drained", rs.RuleId)
+		}
+	}
+	return rs.Stop()
+}
+
 func (rs *RuleState) stopScheduleRule() {
 	if rs.Rule.IsScheduleRule() && rs.cronState.isInSchedule {
 		rs.cronState.isInSchedule = false
@@ -509,6 +570,11 @@ func (rs *RuleState) internalStop() error {
 	if rs.triggered == -1 {
 		return fmt.Errorf("rule %s is already deleted", rs.RuleId)
 	}
+	if rs.completed {
+		// the rule running once has stopped, keep its topology to run again in the next schedule
+		rs.triggered = 2
+		return nil
+	}
 	rs.triggered = 2
 	if rs.Topology != nil {
 		rs.Topology.Cancel()
@@ -583,10 +649,12 @@ func (rs *RuleState) GetState() (s string, err error) {
 }
 
 func (rs *RuleState) getStoppedRuleState() (result string) {
-	if schedule.IsAfterTimeRanges(conf.GetNow(), rs.Rule.Options.CronDatetimeRange) {
+	if schedule.IsAfterTimeRangesIn(conf.GetNow(), rs.Rule.Options.CronDatetimeRange, rs.scheduleLocation()) {
 		result = RuleTerminated
 	} else if rs.cronState.isInSchedule {
 		result = RuleWait
+	} else if rs.completed {
+		result = RuleCompleted
 	} else if rs.triggered == 0 || rs.triggered == -1 {
 		result = RuleStopped
 	} else if rs.triggered == 2 {
@@ -607,6 +675,16 @@ func (rs *RuleState) GetTopoGraph() *api.PrintableTopo {
 	}
 }
 
+// GetHealth returns the health of the running rule, or nil if the rule is not running
+func (rs *RuleState) GetHealth() *topo.Health {
+	rs.RLock()
+	defer rs.RUnlock()
+	if rs.Topology == nil || rs.triggered != 1 {
+		return nil
+	}
+	return rs.Topology.GetHealth()
+}
+
 func (rs *RuleState) isInRunningSchedule(now time.Time, d time.Duration) (bool, time.Duration, error) {
 	allowed, err := rs.isInAllowedTimeRange(now)
 	if err != nil {
@@ -615,22 +693,36 @@ func (rs *RuleState) isInRunningSchedule(now time.Time, d time.Duration) (bool,
 	if !allowed {
 		return false, 0, nil
 	}
-	cronExpr := rs.Rule.Options.Cron
-	if strings.HasPrefix(cronExpr, "mock") {
+	if strings.HasPrefix(rs.Rule.Options.Cron, "mock") {
 		return false, 0, nil
 	}
-	return schedule.IsInRunningSchedule(cronExpr, now, d)
+	return schedule.IsInRunningSchedule(rs.cronExpr(), now, d)
 }
 
 func (rs *RuleState) isInAllowedTimeRange(now time.Time) (bool, error) {
-	return schedule.IsInScheduleRanges(now, rs.Rule.Options.CronDatetimeRange)
+	return schedule.IsInScheduleRangesIn(now, rs.Rule.Options.CronDatetimeRange, rs.scheduleLocation())
+}
+
+// cronExpr returns the cron expression evaluated in the timezone of the rule
+func (rs *RuleState) cronExpr() string {
+	return schedule.CronWithTimezone(rs.Rule.Options.Cron, rs.Rule.Options.Timezone)
+}
+
+// scheduleLocation returns the location of the rule timezone, nil for the configured timezone
+func (rs *RuleState) scheduleLocation() *time.Location {
+	loc, err := schedule.LoadTimezone(rs.Rule.Options.Timezone)
+	if err != nil {
+		conf.Log.Warnf("rule %s has invalid timezone %s, use the configured timezone: %v", rs.RuleId, rs.Rule.Options.Timezone, err)
+		return nil
+	}
+	return loc
 }
 
 func (rs *RuleState) GetNextScheduleStartTime() int64 {
 	if rs.Rule.IsScheduleRule() && len(rs.Rule.Options.Cron) > 0 {
-		isIn, err := schedule.IsInScheduleRanges(time.Now(), rs.Rule.Options.CronDatetimeRange)
+		isIn, err := schedule.IsInScheduleRangesIn(time.Now(), rs.Rule.Options.CronDatetimeRange, rs.scheduleLocation())
 		if err == nil && isIn {
-			s, err := cron.ParseStandard(rs.Rule.Options.Cron)
+			s, err := cron.ParseStandard(rs.cronExpr())
 			if err == nil {
 				return s.Next(time.Now()).UnixMilli()
 			}
@@ -639,6 +731,29 @@ func (rs *RuleState) GetNextScheduleStartTime() int64 {
 	return 0
 }
 
+func (rs *RuleState) recordRestart(err error, attempt int, delay int) {
+	rs.Lock()
+	defer rs.Unlock()
+	if len(rs.restarts) >= maxRestartHistory {
+		rs.restarts = append(rs.restarts[:0], rs.restarts[1:]...)
+	}
+	rs.restarts = append(rs.restarts, RestartRecord{
+		Timestamp: time.Now().UnixMilli(),
+		Error:     err.Error(),
+		Attempt:   attempt,
+		Delay:     delay,
+	})
+}
+
+// GetRestartHistory returns the recent failures of the running rule and how it restarts, the oldest first
+func (rs *RuleState) GetRestartHistory() []RestartRecord {
+	rs.RLock()
+	defer rs.RUnlock()
+	result := make([]RestartRecord, len(rs.restarts))
+	copy(result, rs.restarts)
+	return result
+}
+
 func (rs *RuleState) GetScheduleTimestamp() (int64, int64, int64) {
 	nextStartTimestamp := rs.GetNextScheduleStartTime()
 	rs.Lock()
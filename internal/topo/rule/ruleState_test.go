@@ -17,6 +17,8 @@ package rule
 import (
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
 	"sync"
 	"testing"
@@ -27,6 +29,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/io/sink"
 	"github.com/lf-edge/ekuiper/internal/processor"
 	"github.com/lf-edge/ekuiper/internal/testx"
 	"github.com/lf-edge/ekuiper/pkg/api"
@@ -805,3 +808,118 @@ func TestRuleStateInternalStop(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, rs.triggered, 2)
 }
+
+func TestRunOnce(t *testing.T) {
+	dataDir, err := conf.GetDataLoc()
+	require.NoError(t, err)
+	file := filepath.Join(dataDir, "runOnce.json")
+	require.NoError(t, os.WriteFile(file, []byte(`[{"v":1},{"v":2},{"v":3}]`), 0o644))
+	defer os.Remove(file)
+	sp := processor.NewStreamProcessor()
+	_, _ = sp.ExecStmt(`DROP STREAM roDemo`)
+	// The file source reads the files relative to the data folder
+	_, err = sp.ExecStmt(fmt.Sprintf(`CREATE STREAM roDemo () WITH (DATASOURCE="%s/runOnce.json", TYPE="file", FORMAT="JSON")`, filepath.Base(dataDir)))
+	require.NoError(t, err)
+	defer sp.ExecStmt(`DROP STREAM roDemo`)
+	opt := *defaultOption
+	opt.Cron, opt.Duration, opt.CronDatetimeRange = "", "", nil
+	opt.RunOnce = true
+	r := &api.Rule{
+		Triggered: true,
+		Id:        "runOnce",
+		// The window is still open when the file is read, so it is emitted by draining
+		Sql: "SELECT count(*) AS c, sum(v) AS s FROM roDemo GROUP BY TumblingWindow(ss, 60)",
+		Actions: []map[string]interface{}{
+			{
+				"logToMemory": map[string]interface{}{},
+			},
+		},
+		Options: &opt,
+	}
+	rs, err := NewRuleState(r)
+	require.NoError(t, err)
+	defer rs.Close()
+	require.NoError(t, rs.Start())
+	require.Eventually(t, func() bool {
+		s, err := rs.GetState()
+		return err == nil && s == RuleCompleted
+	}, 5*time.Second, 50*time.Millisecond)
+	sink.QR.Mux.Lock()
+	assert.Equal(t, []string{`[{"c":3,"s":6}]`}, sink.QR.Results)
+	sink.QR.Mux.Unlock()
+
+	// Rerun the rule after completed, the memory sink is recreated by the new plan
+	require.NoError(t, rs.Start())
+	require.Eventually(t, func() bool {
+		s, err := rs.GetState()
+		return err == nil && s == RuleCompleted
+	}, 5*time.Second, 50*time.Millisecond)
+	sink.QR.Mux.Lock()
+	assert.Equal(t, []string{`[{"c":3,"s":6}]`}, sink.QR.Results)
+	sink.QR.Mux.Unlock()
+}
+
+func TestRestartHistory(t *testing.T) {
+	dataDir, err := conf.GetDataLoc()
+	require.NoError(t, err)
+	file := filepath.Join(dataDir, "restart.json")
+	require.NoError(t, os.WriteFile(file, []byte(`[{"v":1}]`), 0o644))
+	defer os.Remove(file)
+	sp := processor.NewStreamProcessor()
+	_, _ = sp.ExecStmt(`DROP STREAM rsDemo`)
+	_, err = sp.ExecStmt(fmt.Sprintf(`CREATE STREAM rsDemo () WITH (DATASOURCE="%s/restart.json", TYPE="file", FORMAT="JSON")`, filepath.Base(dataDir)))
+	require.NoError(t, err)
+	defer sp.ExecStmt(`DROP STREAM rsDemo`)
+	opt := *defaultOption
+	opt.Cron, opt.Duration, opt.CronDatetimeRange = "", "", nil
+	opt.RestartStrategy = &api.RestartStrategy{
+		Attempts:    2,
+		Delay:       10,
+		Multiplier:  2,
+		MaxDelay:    1000,
+		ResetWindow: 200,
+	}
+	r := &api.Rule{
+		Triggered: true,
+		Id:        "restartHistory",
+		Sql:       "SELECT * FROM rsDemo",
+		Actions: []map[string]interface{}{
+			{
+				"log": map[string]interface{}{},
+			},
+		},
+		Options: &opt,
+	}
+	rs, err := NewRuleState(r)
+	require.NoError(t, err)
+	defer rs.Close()
+	require.NoError(t, rs.Start())
+	// fail the running rule after it restarts for the count of the records
+	fail := func(records int) {
+		require.Eventually(t, func() bool {
+			return rs.Topology.HasOpen() && len(rs.GetRestartHistory()) == records
+		}, 5*time.Second, 10*time.Millisecond)
+		rs.Topology.Fail(errors.New("mock failure"))
+	}
+	fail(0)
+	fail(1)
+	// Running healthy for the reset window starts a new round of retries
+	time.Sleep(300 * time.Millisecond)
+	fail(2)
+	fail(3)
+	fail(4)
+	require.Eventually(t, func() bool {
+		s, err := rs.GetState()
+		return err == nil && s == "Stopped: mock failure." && len(rs.GetRestartHistory()) == 5
+	}, 5*time.Second, 10*time.Millisecond)
+	h := rs.GetRestartHistory()
+	attempts := make([]int, len(h))
+	delays := make([]int, len(h))
+	for i, r := range h {
+		assert.Equal(t, "mock failure", r.Error)
+		assert.True(t, r.Timestamp > 0)
+		attempts[i], delays[i] = r.Attempt, r.Delay
+	}
+	assert.Equal(t, []int{1, 2, 1, 2, 0}, attempts)
+	assert.Equal(t, []int{10, 20, 10, 20, 0}, delays)
+}
@@ -0,0 +1,245 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"encoding/gob"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+
+	kvEncoding "github.com/lf-edge/ekuiper/internal/pkg/store/encoding"
+)
+
+// checkpointDeltaKey is the reserved key of a persisted incremental checkpoint
+const checkpointDeltaKey = "$$delta"
+
+func init() {
+	gob.Register(&CheckpointDelta{})
+}
+
+// CheckpointDelta is the persisted changes of the op states since the previous checkpoint
+type CheckpointDelta struct {
+	// Prev is the id of the previous persisted checkpoint which this delta is based on
+	Prev       int64
+	Ops        map[string]*OpDelta
+	DeletedOps []string
+}
+
+// OpDelta is the changes of the state of an op
+type OpDelta struct {
+	// Set are the new or changed state values
+	Set map[string]interface{}
+	// Slices are the changes of the slice values which only drop from the head and append to the tail, such as the window inputs
+	Slices  map[string]*SliceDelta
+	Deleted []string
+}
+
+// SliceDelta drops the first Drop elements of the slice and appends the Append slice to it
+type SliceDelta struct {
+	Drop   int
+	Append interface{}
+}
+
+// persistedValue is a state value of the last persisted checkpoint to compare with
+type persistedValue struct {
+	value interface{}
+	hash  uint64
+	// hashed indicates whether the hash is valid. The value which cannot be encoded is always regarded as changed
+	hashed bool
+}
+
+type persistedStates map[string]map[string]*persistedValue
+
+func newPersistedValue(v interface{}) *persistedValue {
+	h, ok := hashValue(v)
+	return &persistedValue{value: v, hash: h, hashed: ok}
+}
+
+func hashValue(v interface{}) (uint64, bool) {
+	if v == nil {
+		return 0, false
+	}
+	b, err := kvEncoding.Encode(v)
+	if err != nil {
+		return 0, false
+	}
+	h := fnv.New64a()
+	_, _ = h.Write(b)
+	return h.Sum64(), true
+}
+
+// trackStates records the op states of a full checkpoint
+func trackStates(m map[string]interface{}) persistedStates {
+	result := make(persistedStates, len(m))
+	for opId, s := range m {
+		st, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ps := make(map[string]*persistedValue, len(st))
+		for k, v := range st {
+			ps[k] = newPersistedValue(v)
+		}
+		result[opId] = ps
+	}
+	return result
+}
+
+// diffStates returns the changes of the op states compared to the persisted ones and the new persisted states.
+// Return false if the states cannot be saved incrementally.
+func diffStates(prev persistedStates, m map[string]interface{}) (*CheckpointDelta, persistedStates, bool) {
+	delta := &CheckpointDelta{Ops: make(map[string]*OpDelta)}
+	next := make(persistedStates, len(m))
+	for opId, s := range m {
+		st, ok := s.(map[string]interface{})
+		if !ok {
+			return nil, nil, false
+		}
+		ps := make(map[string]*persistedValue, len(st))
+		if od := diffOpState(prev[opId], st, ps); od != nil {
+			delta.Ops[opId] = od
+		}
+		next[opId] = ps
+	}
+	for opId := range prev {
+		if _, ok := m[opId]; !ok {
+			delta.DeletedOps = append(delta.DeletedOps, opId)
+		}
+	}
+	return delta, next, true
+}
+
+// diffOpState returns the changes of an op state and fills the new persisted values to next. Return nil if nothing changes.
+func diffOpState(prev map[string]*persistedValue, st map[string]interface{}, next map[string]*persistedValue) *OpDelta {
+	od := &OpDelta{}
+	changed := false
+	for k, v := range st {
+		pv := prev[k]
+		if pv != nil {
+			if sd, ok := diffSlice(pv.value, v); ok {
+				// The slice is compared by the element identity, no need to hash
+				next[k] = &persistedValue{value: v}
+				if sd.Drop > 0 || sd.Append != nil {
+					if od.Slices == nil {
+						od.Slices = make(map[string]*SliceDelta)
+					}
+					od.Slices[k] = sd
+					changed = true
+				}
+				continue
+			}
+		}
+		nv := newPersistedValue(v)
+		next[k] = nv
+		if pv != nil && pv.hashed && nv.hashed && pv.hash == nv.hash {
+			continue
+		}
+		if od.Set == nil {
+			od.Set = make(map[string]interface{})
+		}
+		od.Set[k] = v
+		changed = true
+	}
+	for k := range prev {
+		if _, ok := st[k]; !ok {
+			od.Deleted = append(od.Deleted, k)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return od
+}
+
+// diffSlice compares the slices of pointers by the element identity. The new slice must be the old one
+// with some elements dropped from the head and some elements appended to the tail.
+func diffSlice(old, new interface{}) (*SliceDelta, bool) {
+	ov, nv := reflect.ValueOf(old), reflect.ValueOf(new)
+	if ov.Kind() != reflect.Slice || nv.Kind() != reflect.Slice || ov.Type() != nv.Type() || ov.Type().Elem().Kind() != reflect.Pointer {
+		return nil, false
+	}
+	ol, nl := ov.Len(), nv.Len()
+	drop := ol
+	if nl > 0 {
+		first := nv.Index(0).Pointer()
+		for i := 0; i < ol; i++ {
+			if ov.Index(i).Pointer() == first {
+				drop = i
+				break
+			}
+		}
+	}
+	kept := ol - drop
+	if kept > nl {
+		return nil, false
+	}
+	for i := 0; i < kept; i++ {
+		if ov.Index(drop+i).Pointer() != nv.Index(i).Pointer() {
+			return nil, false
+		}
+	}
+	sd := &SliceDelta{Drop: drop}
+	if nl > kept {
+		sd.Append = nv.Slice(kept, nl).Interface()
+	}
+	return sd, true
+}
+
+func applySlice(base interface{}, sd *SliceDelta) (interface{}, error) {
+	bv := reflect.ValueOf(base)
+	if bv.Kind() != reflect.Slice || sd.Drop > bv.Len() {
+		return nil, fmt.Errorf("cannot apply slice delta to %v", base)
+	}
+	r := bv.Slice(sd.Drop, bv.Len())
+	if sd.Append != nil {
+		av := reflect.ValueOf(sd.Append)
+		if av.Type() != bv.Type() {
+			return nil, fmt.Errorf("cannot append %v to %v", sd.Append, base)
+		}
+		nr := reflect.MakeSlice(bv.Type(), 0, r.Len()+av.Len())
+		r = reflect.AppendSlice(reflect.AppendSlice(nr, r), av)
+	}
+	return r.Interface(), nil
+}
+
+// applyDelta applies the changes to the op states of the previous checkpoint
+func applyDelta(m map[string]interface{}, delta *CheckpointDelta) error {
+	for _, opId := range delta.DeletedOps {
+		delete(m, opId)
+	}
+	for opId, od := range delta.Ops {
+		st, _ := m[opId].(map[string]interface{})
+		if st == nil {
+			st = make(map[string]interface{})
+		}
+		for _, k := range od.Deleted {
+			delete(st, k)
+		}
+		for k, v := range od.Set {
+			st[k] = v
+		}
+		for k, sd := range od.Slices {
+			v, err := applySlice(st[k], sd)
+			if err != nil {
+				return fmt.Errorf("restore state %s of op %s error: %v", k, opId, err)
+			}
+			st[k] = v
+		}
+		m[opId] = st
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"bytes"
+	"encoding/gob"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	kvEncoding "github.com/lf-edge/ekuiper/internal/pkg/store/encoding"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"github.com/lf-edge/ekuiper/pkg/kv"
+)
+
+type testTuple struct {
+	V int
+}
+
+// memTskv saves the encoded values in memory so that the decoded values are new objects like the real storage
+type memTskv struct {
+	data map[int64][]byte
+	last int64
+}
+
+func (m *memTskv) Set(k int64, v interface{}) (bool, error) {
+	if k <= m.last {
+		return false, nil
+	}
+	b, err := kvEncoding.Encode(v)
+	if err != nil {
+		return false, err
+	}
+	m.data[k] = b
+	m.last = k
+	return true, nil
+}
+
+func (m *memTskv) Get(k int64, v interface{}) (bool, error) {
+	b, ok := m.data[k]
+	if !ok {
+		return false, nil
+	}
+	return true, gob.NewDecoder(bytes.NewReader(b)).Decode(v)
+}
+
+func (m *memTskv) Last(v interface{}) (int64, error) {
+	if m.last == 0 {
+		return 0, nil
+	}
+	_, err := m.Get(m.last, v)
+	return m.last, err
+}
+
+func (m *memTskv) Delete(k int64) error {
+	delete(m.data, k)
+	return nil
+}
+
+func (m *memTskv) DeleteBefore(k int64) error {
+	for key := range m.data {
+		if key < k {
+			delete(m.data, key)
+		}
+	}
+	return nil
+}
+
+func (m *memTskv) Close() error {
+	return nil
+}
+
+func (m *memTskv) Drop() error {
+	m.data = map[int64][]byte{}
+	return nil
+}
+
+func newTestKVStore(db kv.Tskv) (*KVStore, error) {
+	s := &KVStore{db: db, max: 3, mapStore: &sync.Map{}, ruleId: "test", fullInterval: 3}
+	return s, s.restore()
+}
+
+func init() {
+	gob.Register([]*testTuple{})
+}
+
+func TestDiffSlice(t *testing.T) {
+	ts := []*testTuple{{1}, {2}, {3}, {4}}
+	tests := []struct {
+		name   string
+		old    interface{}
+		new    interface{}
+		delta  *SliceDelta
+		result bool
+	}{
+		{
+			name:   "drop and append",
+			old:    ts[:3],
+			new:    append([]*testTuple{}, ts[1:]...),
+			delta:  &SliceDelta{Drop: 1, Append: []*testTuple{ts[3]}},
+			result: true,
+		},
+		{
+			name:   "unchanged",
+			old:    ts[:2],
+			new:    ts[:2],
+			delta:  &SliceDelta{},
+			result: true,
+		},
+		{
+			name:   "all replaced",
+			old:    ts[:2],
+			new:    ts[2:],
+			delta:  &SliceDelta{Drop: 2, Append: ts[2:]},
+			result: true,
+		},
+		{
+			name:   "cleared",
+			old:    ts[:2],
+			new:    []*testTuple{},
+			delta:  &SliceDelta{Drop: 2},
+			result: true,
+		},
+		{
+			name: "reordered",
+			old:  ts[:3],
+			new:  []*testTuple{ts[0], ts[2]},
+		},
+		{
+			name: "not pointers",
+			old:  []int{1, 2},
+			new:  []int{2, 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, ok := diffSlice(tt.old, tt.new)
+			require.Equal(t, tt.result, ok)
+			if !ok {
+				return
+			}
+			assert.Equal(t, tt.delta, d)
+			r, err := applySlice(tt.old, d)
+			require.NoError(t, err)
+			assert.Equal(t, tt.new, r)
+		})
+	}
+}
+
+func TestIncrementalCheckpoint(t *testing.T) {
+	db := &memTskv{data: map[int64][]byte{}}
+	s, err := newTestKVStore(db)
+	require.NoError(t, err)
+
+	var inputs []*testTuple
+	for i := 1; i <= 5; i++ {
+		// Simulate a sliding window which keeps the last 3 tuples
+		inputs = append(inputs, &testTuple{V: i})
+		if len(inputs) > 3 {
+			inputs = inputs[1:]
+		}
+		st := map[string]interface{}{
+			"inputs":      inputs,
+			"triggerTime": int64(1000),
+			"count":       i,
+		}
+		if i >= 4 {
+			delete(st, "triggerTime")
+		}
+		require.NoError(t, s.SaveState(int64(i), "window", st))
+		require.NoError(t, s.SaveState(int64(i), "project", map[string]interface{}{"static": "v"}))
+		require.NoError(t, s.SaveCheckpoint(int64(i)))
+	}
+	// Checkpoint 1 and 4 are full, the others only save the changes
+	for i, isDelta := range []bool{false, true, true, false, true} {
+		var m map[string]interface{}
+		found, err := s.db.Get(int64(i+1), &m)
+		require.NoError(t, err)
+		require.True(t, found)
+		_, ok := m[checkpointDeltaKey]
+		assert.Equal(t, isDelta, ok, "checkpoint %d", i+1)
+	}
+	var m map[string]interface{}
+	_, err = s.db.Get(5, &m)
+	require.NoError(t, err)
+	delta := m[checkpointDeltaKey].(*CheckpointDelta)
+	assert.Equal(t, int64(4), delta.Prev)
+	// The unchanged op is not saved, and only the new tuple of the window inputs is saved
+	assert.Equal(t, map[string]*OpDelta{
+		"window": {
+			Set:    map[string]interface{}{"count": 5},
+			Slices: map[string]*SliceDelta{"inputs": {Drop: 1, Append: []*testTuple{{V: 5}}}},
+		},
+	}, delta.Ops)
+
+	// The checkpoints before the last full one are cleaned
+	require.NoError(t, s.Clean())
+	found, err := s.db.Get(2, &m)
+	require.NoError(t, err)
+	assert.False(t, found)
+	m = nil
+	found, err = s.db.Get(4, &m)
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	s, err = newTestKVStore(db)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{5}, s.checkpoints)
+	assert.Equal(t, int64(4), s.lastFull)
+	ws, err := s.GetOpState("window")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"inputs": []*testTuple{{V: 3}, {V: 4}, {V: 5}},
+		"count":  5,
+	}, cast.SyncMapToMap(ws))
+
+	// Continue the chain after restore, the restored slice is compared by identity too
+	restored := cast.SyncMapToMap(ws)["inputs"].([]*testTuple)
+	require.NoError(t, s.SaveState(6, "window", map[string]interface{}{
+		"inputs": append(restored[1:], &testTuple{V: 6}),
+		"count":  6,
+	}))
+	require.NoError(t, s.SaveCheckpoint(6))
+	m = nil
+	_, err = s.db.Get(6, &m)
+	require.NoError(t, err)
+	delta = m[checkpointDeltaKey].(*CheckpointDelta)
+	assert.Equal(t, &SliceDelta{Drop: 1, Append: []*testTuple{{V: 6}}}, delta.Ops["window"].Slices["inputs"])
+	assert.Equal(t, []string{"project"}, delta.DeletedOps)
+
+	s, err = newTestKVStore(db)
+	require.NoError(t, err)
+	ws, err = s.GetOpState("window")
+	require.NoError(t, err)
+	assert.Equal(t, []*testTuple{{V: 4}, {V: 5}, {V: 6}}, cast.SyncMapToMap(ws)["inputs"])
+	ps, err := s.GetOpState("project")
+	require.NoError(t, err)
+	assert.Empty(t, cast.SyncMapToMap(ps))
+}
+
+func TestObserveSize(t *testing.T) {
+	s, err := newTestKVStore(&memTskv{data: map[int64][]byte{}})
+	require.NoError(t, err)
+	var sizes []int64
+	s.ObserveSize(func(size int64) {
+		sizes = append(sizes, size)
+	})
+	require.NoError(t, s.SaveState(1, "window", map[string]interface{}{"inputs": []*testTuple{{V: 1}}}))
+	require.NoError(t, s.SaveCheckpoint(1))
+	require.NoError(t, s.SaveState(2, "window", map[string]interface{}{"inputs": []*testTuple{{V: 1}, {V: 2}, {V: 3}}}))
+	require.NoError(t, s.SaveCheckpoint(2))
+	// The size of the full states is reported even if the checkpoint is saved incrementally
+	require.Len(t, sizes, 2)
+	assert.Greater(t, sizes[0], int64(0))
+	assert.Greater(t, sizes[1], sizes[0])
+}
@@ -21,6 +21,7 @@ import (
 
 	"github.com/lf-edge/ekuiper/internal/conf"
 	ts "github.com/lf-edge/ekuiper/internal/pkg/store"
+	kvEncoding "github.com/lf-edge/ekuiper/internal/pkg/store/encoding"
 	"github.com/lf-edge/ekuiper/internal/topo/checkpoint"
 	"github.com/lf-edge/ekuiper/pkg/cast"
 	ts2 "github.com/lf-edge/ekuiper/pkg/kv"
@@ -29,6 +30,7 @@ import (
 
 func init() {
 	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
 	gob.Register(checkpoint.BufferOrEvent{})
 	gob.Register(&store.IndexFieldStore{})
 }
@@ -44,19 +46,35 @@ type KVStore struct {
 	checkpoints []int64
 	max         int
 	ruleId      string
+	// fullInterval is the count of checkpoints to save between two full checkpoints. The others only save the changes.
+	// 0 or 1 means always saving full checkpoints
+	fullInterval int
+	// the states of the last persisted checkpoint to compare with
+	persisted  persistedStates
+	lastSaved  int64
+	lastFull   int64
+	deltaCount int
+	// sizeObserver receives the encoded size of the full states at each checkpoint
+	sizeObserver func(size int64)
+}
+
+// SizeObservable is the store which reports the size of the states at each checkpoint
+type SizeObservable interface {
+	ObserveSize(f func(size int64))
 }
 
 // Store in path ./data/checkpoint/$ruleId
 // Store 2 things:
 // "checkpoints":A queue for completed checkpoint id
 // "$checkpointId":A map with key of checkpoint id and value of snapshot(gob serialized)
+// or the changes since the previous checkpoint if saving incrementally
 // Assume each operator only has one instance
-func getKVStore(ruleId string) (*KVStore, error) {
-	db, err := ts.GetTS(ruleId)
+func getKVStore(ruleId string, stateType string, fullInterval int) (*KVStore, error) {
+	db, err := ts.GetStateTS(stateType, ruleId)
 	if err != nil {
 		return nil, err
 	}
-	s := &KVStore{db: db, max: 3, mapStore: &sync.Map{}, ruleId: ruleId}
+	s := &KVStore{db: db, max: 3, mapStore: &sync.Map{}, ruleId: ruleId, fullInterval: fullInterval}
 	// read data from badger db
 	if err := s.restore(); err != nil {
 		return nil, err
@@ -71,12 +89,51 @@ func (s *KVStore) restore() error {
 		return err
 	}
 	if k > 0 {
+		s.lastFull = k
+		m, err = s.merge(m)
+		if err != nil {
+			return err
+		}
 		s.checkpoints = []int64{k}
 		s.mapStore.Store(k, cast.MapToSyncMap(m))
+		s.persisted = trackStates(m)
+		s.lastSaved = k
 	}
 	return nil
 }
 
+// merge loads the previous checkpoints until the full one and applies the changes in order
+func (s *KVStore) merge(m map[string]interface{}) (map[string]interface{}, error) {
+	var deltas []*CheckpointDelta
+	for {
+		d, ok := m[checkpointDeltaKey]
+		if !ok {
+			break
+		}
+		delta, ok := d.(*CheckpointDelta)
+		if !ok {
+			return nil, fmt.Errorf("invalid checkpoint delta %v", d)
+		}
+		deltas = append(deltas, delta)
+		m = nil
+		found, err := s.db.Get(delta.Prev, &m)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, fmt.Errorf("checkpoint %d not found", delta.Prev)
+		}
+		s.lastFull = delta.Prev
+	}
+	s.deltaCount = len(deltas)
+	for i := len(deltas) - 1; i >= 0; i-- {
+		if err := applyDelta(m, deltas[i]); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
 func (s *KVStore) SaveState(checkpointId int64, opId string, state map[string]interface{}) error {
 	logger := conf.Log
 	logger.Debugf("Save state for checkpoint %d, op %s, value %v", checkpointId, opId, state)
@@ -107,8 +164,15 @@ func (s *KVStore) SaveCheckpoint(checkpointId int64) error {
 				s.checkpoints = s.checkpoints[1:]
 				s.mapStore.Delete(cp)
 			}
-			_, err := s.db.Set(checkpointId, cast.SyncMapToMap(m))
-			if err != nil {
+			states := cast.SyncMapToMap(m)
+			if s.sizeObserver != nil {
+				if b, err := kvEncoding.Encode(states); err == nil {
+					s.sizeObserver(int64(len(b)))
+				} else {
+					conf.Log.Warnf("measure the state size of checkpoint %d error: %v", checkpointId, err)
+				}
+			}
+			if err := s.persist(checkpointId, states); err != nil {
 				return fmt.Errorf("save checkpoint err: %v", err)
 			}
 		}
@@ -116,6 +180,47 @@ func (s *KVStore) SaveCheckpoint(checkpointId int64) error {
 	return nil
 }
 
+// ObserveSize sets the function to receive the size of the states. It must be called before the checkpoints run.
+func (s *KVStore) ObserveSize(f func(size int64)) {
+	s.sizeObserver = f
+}
+
+// persist saves the full states or only the changes since the last persisted checkpoint
+func (s *KVStore) persist(checkpointId int64, m map[string]interface{}) error {
+	var (
+		v     interface{} = m
+		next  persistedStates
+		delta *CheckpointDelta
+		ok    bool
+	)
+	if s.fullInterval > 1 && s.lastSaved > 0 && s.deltaCount+1 < s.fullInterval {
+		delta, next, ok = diffStates(s.persisted, m)
+		if ok {
+			delta.Prev = s.lastSaved
+			v = map[string]interface{}{checkpointDeltaKey: delta}
+		}
+	}
+	if !ok {
+		next = trackStates(m)
+	}
+	inserted, err := s.db.Set(checkpointId, v)
+	if err != nil {
+		return err
+	}
+	if !inserted {
+		return nil
+	}
+	s.persisted = next
+	s.lastSaved = checkpointId
+	if ok {
+		s.deltaCount++
+	} else {
+		s.lastFull = checkpointId
+		s.deltaCount = 0
+	}
+	return nil
+}
+
 // GetOpState Only run in the initialization
 func (s *KVStore) GetOpState(opId string) (*sync.Map, error) {
 	if len(s.checkpoints) > 0 {
@@ -142,5 +247,10 @@ func (s *KVStore) GetOpState(opId string) (*sync.Map, error) {
 }
 
 func (s *KVStore) Clean() error {
-	return s.db.DeleteBefore(s.checkpoints[0])
+	k := s.checkpoints[0]
+	// The incremental checkpoints depend on the last full checkpoint
+	if s.lastFull > 0 && s.lastFull < k {
+		k = s.lastFull
+	}
+	return s.db.DeleteBefore(k)
 }
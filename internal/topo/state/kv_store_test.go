@@ -163,7 +163,7 @@ func TestLifecycle(t *testing.T) {
 		if err != nil {
 			t.Error(err)
 		}
-		store, err := getKVStore(ruleId)
+		store, err := getKVStore(ruleId, "", 10)
 		if err != nil {
 			t.Errorf("Get store for rule %s error: %s", ruleId, err)
 			return
@@ -226,7 +226,7 @@ func TestLifecycle(t *testing.T) {
 		}
 		// simulate restore
 		store = nil
-		store, err = getKVStore(ruleId)
+		store, err = getKVStore(ruleId, "", 10)
 		if err != nil {
 			t.Errorf("Restore store for rule %s error: %s", ruleId, err)
 			return
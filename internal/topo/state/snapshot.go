@@ -0,0 +1,87 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"sync"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	ts "github.com/lf-edge/ekuiper/internal/pkg/store"
+	kvEncoding "github.com/lf-edge/ekuiper/internal/pkg/store/encoding"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"github.com/lf-edge/ekuiper/pkg/errorx"
+)
+
+// RuleSnapshot is the operator states of the last checkpoint of a rule which can be restored in another instance
+type RuleSnapshot struct {
+	RuleId       string
+	CheckpointId int64
+	// States are the states of each operator keyed by the op id
+	States map[string]interface{}
+}
+
+// ExportSnapshot returns the encoded states of the last checkpoint of the rule
+func ExportSnapshot(ruleId string, stateType string) ([]byte, error) {
+	db, err := ts.GetStateTS(stateType, ruleId)
+	if err != nil {
+		return nil, err
+	}
+	s := &KVStore{db: db, max: 3, mapStore: &sync.Map{}, ruleId: ruleId}
+	if err := s.restore(); err != nil {
+		return nil, fmt.Errorf("read checkpoint of rule %s error: %v", ruleId, err)
+	}
+	if len(s.checkpoints) == 0 {
+		return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("no checkpoint found for rule %s, make sure the qos of the rule is at least once and it has run", ruleId))
+	}
+	k := s.checkpoints[len(s.checkpoints)-1]
+	v, _ := s.mapStore.Load(k)
+	snapshot := &RuleSnapshot{
+		RuleId:       ruleId,
+		CheckpointId: k,
+		States:       cast.SyncMapToMap(v.(*sync.Map)),
+	}
+	return kvEncoding.Encode(snapshot)
+}
+
+// RestoreSnapshot replaces the checkpoints of the rule with the snapshot. The rule must be stopped and the states
+// are restored when it starts.
+func RestoreSnapshot(ruleId string, stateType string, data []byte) (*RuleSnapshot, error) {
+	snapshot := &RuleSnapshot{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(snapshot); err != nil {
+		return nil, fmt.Errorf("invalid rule snapshot: %v", err)
+	}
+	if snapshot.States == nil {
+		return nil, fmt.Errorf("invalid rule snapshot: no states")
+	}
+	if err := ts.DropStateTS(stateType, ruleId); err != nil {
+		return nil, err
+	}
+	db, err := ts.GetStateTS(stateType, ruleId)
+	if err != nil {
+		return nil, err
+	}
+	// Save as a full checkpoint. Use the current time as the id so that the following checkpoints are newer
+	inserted, err := db.Set(conf.GetNowInMilli(), snapshot.States)
+	if err != nil {
+		return nil, fmt.Errorf("save snapshot of rule %s error: %v", ruleId, err)
+	}
+	if !inserted {
+		return nil, fmt.Errorf("save snapshot of rule %s error: checkpoint already exists", ruleId)
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/pkg/cast"
+	"github.com/lf-edge/ekuiper/pkg/errorx"
+)
+
+func TestRuleSnapshot(t *testing.T) {
+	require.NoError(t, store.SetupDefault(t.TempDir()))
+	conf.Clock.(*clock.Mock).Set(time.UnixMilli(1000))
+
+	_, err := ExportSnapshot("noCheckpoint", "")
+	var e *errorx.Error
+	require.ErrorAs(t, err, &e)
+	assert.Equal(t, errorx.NOT_FOUND, e.Code())
+
+	s, err := getKVStore("snapRule", "", 3)
+	require.NoError(t, err)
+	var inputs []*testTuple
+	for i := 1; i <= 3; i++ {
+		inputs = append(inputs, &testTuple{V: i})
+		require.NoError(t, s.SaveState(int64(i), "window", map[string]interface{}{"inputs": inputs, "count": i}))
+		require.NoError(t, s.SaveCheckpoint(int64(i)))
+	}
+
+	// The exported snapshot is merged from the incremental checkpoints
+	data, err := ExportSnapshot("snapRule", "")
+	require.NoError(t, err)
+	snapshot, err := RestoreSnapshot("snapRule2", "", data)
+	require.NoError(t, err)
+	assert.Equal(t, "snapRule", snapshot.RuleId)
+	assert.Equal(t, int64(3), snapshot.CheckpointId)
+
+	s2, err := getKVStore("snapRule2", "", 3)
+	require.NoError(t, err)
+	ws, err := s2.GetOpState("window")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"inputs": []*testTuple{{V: 1}, {V: 2}, {V: 3}},
+		"count":  3,
+	}, cast.SyncMapToMap(ws))
+
+	_, err = RestoreSnapshot("snapRule2", "", []byte("invalid"))
+	assert.Error(t, err)
+}
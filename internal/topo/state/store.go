@@ -21,8 +21,13 @@ import (
 const CheckpointListKey = "checkpoints"
 
 func CreateStore(ruleId string, qos api.Qos) (api.Store, error) {
-	if qos >= api.AtLeastOnce {
-		return getKVStore(ruleId)
+	return CreateRuleStore(ruleId, &api.RuleOption{Qos: qos})
+}
+
+// CreateRuleStore creates the checkpoint store by the rule options
+func CreateRuleStore(ruleId string, options *api.RuleOption) (api.Store, error) {
+	if options.Qos >= api.AtLeastOnce {
+		return getKVStore(ruleId, options.StateStore, options.FullCheckpointInterval)
 	} else {
 		return newMemoryStore(), nil
 	}
@@ -25,6 +25,7 @@ import (
 	kctx "github.com/lf-edge/ekuiper/internal/topo/context"
 	"github.com/lf-edge/ekuiper/internal/topo/node"
 	"github.com/lf-edge/ekuiper/internal/topo/node/metric"
+	"github.com/lf-edge/ekuiper/internal/topo/profiler"
 	"github.com/lf-edge/ekuiper/internal/topo/state"
 	"github.com/lf-edge/ekuiper/pkg/api"
 	"github.com/lf-edge/ekuiper/pkg/ast"
@@ -89,10 +90,15 @@ func (s *SrcSubTopo) Open(ctx api.StreamContext, parentErrCh chan<- error) {
 				return err
 			}
 			errCh := make(chan error, 1)
+			// The shared nodes are labeled with the sub topo name instead of the rule which opens it
 			for _, op := range s.ops {
-				op.Exec(pctx, errCh)
+				profiler.Do(s.name, op.GetName(), func() {
+					op.Exec(pctx, errCh)
+				})
 			}
-			s.source.Open(pctx, errCh)
+			profiler.Do(s.name, s.source.GetName(), func() {
+				s.source.Open(pctx, errCh)
+			})
 			s.cancel = cancel
 			ctx.GetLogger().Infof("Sub topo %s opened by rule %s with 1 ref", s.name, ctx.GetRuleId())
 			go func() {
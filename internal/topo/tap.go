@@ -0,0 +1,104 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package topo
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/topo/node"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+)
+
+// the max count of the tapped records waiting to send, the newer records are dropped when it is full
+const tapBufferLength = 1024
+
+// TapRecord is an output of the tapped node
+type TapRecord struct {
+	Node      string `json:"node"`
+	Timestamp int64  `json:"timestamp"`
+	// Data is the list of the tuples in json
+	Data  json.RawMessage `json:"data,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+// Tap taps the output of a source or operator of the topo by the name in the topo graph such as op_3_filter.
+// The records are dropped if they are not received in time. The returned function must be called to remove the tap.
+func (s *Topo) Tap(name string) (<-chan *TapRecord, func(), error) {
+	n := s.findTappableNode(name)
+	if n == nil {
+		return nil, nil, fmt.Errorf("node %s is not found or cannot be tapped", name)
+	}
+	ch := make(chan *TapRecord, tapBufferLength)
+	ok := n.SetTap(func(val any) {
+		r := newTapRecord(name, val)
+		if r == nil {
+			return
+		}
+		select {
+		case ch <- r:
+		default:
+		}
+	})
+	if !ok {
+		return nil, nil, fmt.Errorf("node %s is being tapped", name)
+	}
+	return ch, n.RemoveTap, nil
+}
+
+func (s *Topo) findTappableNode(name string) node.TappableNode {
+	for _, src := range s.sources {
+		if tn, ok := src.(node.TappableNode); ok && (name == tn.GetName() || name == "source_"+tn.GetName()) {
+			return tn
+		}
+	}
+	for _, op := range s.ops {
+		if tn, ok := op.(node.TappableNode); ok && (name == tn.GetName() || name == "op_"+tn.GetName()) {
+			return tn
+		}
+	}
+	return nil
+}
+
+// newTapRecord converts the output to the record. The tuples are encoded immediately because the downstream
+// nodes may modify them later. The control signals such as the watermark return nil.
+func newTapRecord(name string, val any) *TapRecord {
+	var data []map[string]any
+	switch vt := val.(type) {
+	case error:
+		return &TapRecord{Node: name, Timestamp: conf.GetNowInMilli(), Error: vt.Error()}
+	case *xsql.ErrorSourceTuple:
+		return &TapRecord{Node: name, Timestamp: conf.GetNowInMilli(), Error: vt.Error.Error()}
+	case *xsql.Tuple:
+		// The tuple from the source connector is not decoded yet
+		if vt.Message == nil && vt.Raw != nil {
+			data = []map[string]any{{"raw": string(vt.Raw)}}
+		} else {
+			data = []map[string]any{vt.ToMap()}
+		}
+	case xsql.Collection:
+		data = vt.ToMaps()
+	case xsql.Row:
+		data = []map[string]any{vt.ToMap()}
+	default:
+		return nil
+	}
+	bs, err := json.Marshal(data)
+	if err != nil {
+		return &TapRecord{Node: name, Timestamp: conf.GetNowInMilli(), Error: fmt.Sprintf("encode the output error: %v", err)}
+	}
+	return &TapRecord{Node: name, Timestamp: conf.GetNowInMilli(), Data: bs}
+}
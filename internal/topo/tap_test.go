@@ -0,0 +1,57 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package topo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/topo/node"
+	"github.com/lf-edge/ekuiper/internal/xsql"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+func TestTap(t *testing.T) {
+	op, err := node.NewDecompressOp("2_decompress", &api.RuleOption{BufferLength: 10}, "gzip")
+	require.NoError(t, err)
+	tp := &Topo{name: "rule1", ops: []node.OperatorNode{op}}
+
+	_, _, err = tp.Tap("op_3_filter")
+	assert.EqualError(t, err, "node op_3_filter is not found or cannot be tapped")
+	ch, remove, err := tp.Tap("op_2_decompress")
+	require.NoError(t, err)
+	_, _, err = tp.Tap("2_decompress")
+	assert.EqualError(t, err, "node 2_decompress is being tapped")
+
+	op.Broadcast(&xsql.Tuple{Message: map[string]any{"a": 1}})
+	op.Broadcast(&node.DrainSignal{})
+	op.Broadcast(errors.New("decompress error"))
+	r := <-ch
+	assert.Equal(t, "op_2_decompress", r.Node)
+	assert.JSONEq(t, `[{"a":1}]`, string(r.Data))
+	r = <-ch
+	assert.Equal(t, "decompress error", r.Error)
+	assert.Len(t, ch, 0)
+
+	remove()
+	op.Broadcast(&xsql.Tuple{Message: map[string]any{"a": 2}})
+	assert.Len(t, ch, 0)
+	_, remove, err = tp.Tap("2_decompress")
+	require.NoError(t, err)
+	remove()
+}
@@ -28,12 +28,17 @@ import (
 	rotatelogs "github.com/yisaer/file-rotatelogs"
 
 	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/internal/io/memory/pubsub"
 	"github.com/lf-edge/ekuiper/internal/topo/checkpoint"
 	kctx "github.com/lf-edge/ekuiper/internal/topo/context"
 	"github.com/lf-edge/ekuiper/internal/topo/node"
 	"github.com/lf-edge/ekuiper/internal/topo/node/metric"
+	"github.com/lf-edge/ekuiper/internal/topo/profiler"
+	"github.com/lf-edge/ekuiper/internal/topo/quota"
 	"github.com/lf-edge/ekuiper/internal/topo/state"
+	"github.com/lf-edge/ekuiper/internal/topo/vclock"
 	"github.com/lf-edge/ekuiper/pkg/api"
+	"github.com/lf-edge/ekuiper/pkg/ast"
 	"github.com/lf-edge/ekuiper/pkg/infra"
 )
 
@@ -51,6 +56,12 @@ type Topo struct {
 	topo        *api.PrintableTopo
 	mu          sync.Mutex
 	hasOpened   atomic.Bool
+	// completed is closed when the rule running once finishes
+	completed chan struct{}
+	// throttle is set when the rule is registered to be throttled by its priority
+	throttle atomic.Pointer[quota.Throttle]
+	// memorySchemas are the result schemas by the memory topics, registered while the topo is running
+	memorySchemas map[string]ast.StreamFields
 }
 
 func NewWithNameAndOptions(name string, options *api.RuleOption) (*Topo, error) {
@@ -92,6 +103,10 @@ func (s *Topo) Cancel() {
 	}
 	s.store = nil
 	s.coordinator = nil
+	quota.Unregister(s.name, s.throttle.Load())
+	if len(s.memorySchemas) > 0 {
+		pubsub.DropSchemas(s.name)
+	}
 	for _, src := range s.sources {
 		switch rt := src.(type) {
 		case node.MergeableTopo:
@@ -100,6 +115,70 @@ func (s *Topo) Cancel() {
 	}
 }
 
+// Fail stops the running topo with the error as if a node reports it, so the restart strategy of the rule applies
+func (s *Topo) Fail(err error) {
+	if !s.hasOpened.Load() || s.drain == nil {
+		return
+	}
+	infra.DrainError(s.ctx, err, s.drain)
+}
+
+// Drain stops the sources from reading and waits until the sinks send out all the data in flight including
+// the open windows. It returns false if the timeout is reached or the topo cannot be drained.
+// The topo keeps running after draining and must be cancelled.
+func (s *Topo) Drain(timeout time.Duration) bool {
+	if !s.hasOpened.Load() {
+		return true
+	}
+	logger := s.ctx.GetLogger()
+	sources := make([]node.Drainable, 0, len(s.sources))
+	for _, src := range s.sources {
+		d, ok := src.(node.Drainable)
+		if !ok {
+			logger.Warnf("source %s is shared by other rules and cannot be drained", src.GetName())
+			return false
+		}
+		sources = append(sources, d)
+	}
+	drained := make([]<-chan struct{}, 0, len(s.sinks))
+	for _, snk := range s.sinks {
+		drained = append(drained, snk.Drained())
+	}
+	logger.Infof("Draining rule with timeout %v", timeout)
+	for _, src := range sources {
+		src.Drain()
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for i, d := range drained {
+		select {
+		case <-d:
+		case <-timer.C:
+			logger.Warnf("Drain rule timeout, sink %s is not drained", s.sinks[i].GetName())
+			return false
+		case <-s.ctx.Done():
+			return false
+		}
+	}
+	logger.Infof("Rule is drained")
+	return true
+}
+
+// SetMemorySchemas sets the result schemas of the memory sinks to register when the topo opens
+func (s *Topo) SetMemorySchemas(schemas map[string]ast.StreamFields) {
+	s.memorySchemas = schemas
+}
+
+func (s *Topo) registerMemorySchemas() {
+	if len(s.memorySchemas) == 0 {
+		return
+	}
+	pubsub.DropSchemas(s.name)
+	for topic, schema := range s.memorySchemas {
+		pubsub.RegisterSchema(s.name, topic, schema)
+	}
+}
+
 func (s *Topo) AddSrc(src node.DataSourceNode) *Topo {
 	s.sources = append(s.sources, src)
 	switch rt := src.(type) {
@@ -138,6 +217,11 @@ func (s *Topo) AddOperator(inputs []api.Emitter, operator node.OperatorNode) *To
 	return s
 }
 
+// GetOperators returns the operators in the order they are added
+func (s *Topo) GetOperators() []node.OperatorNode {
+	return s.ops
+}
+
 func (s *Topo) addEdge(from api.TopNode, to api.TopNode, toType string) {
 	fromType := "op"
 	if _, ok := from.(node.DataSourceNode); ok {
@@ -192,6 +276,12 @@ func (s *Topo) prepareContext() {
 		ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
 		ctx = kctx.WithValue(ctx, kctx.RuleStartKey, conf.GetNowInMilli())
 		s.ctx, s.cancel = ctx.WithCancel()
+		if s.options != nil {
+			if c := vclock.New(s.ctx, s.options.Clock); c != nil {
+				contextLogger.Infof("run with the %s clock", s.options.Clock.Mode)
+				s.ctx = kctx.WithValue(s.ctx.(*kctx.DefaultContext), vclock.Key, c)
+			}
+		}
 	}
 }
 
@@ -203,7 +293,12 @@ func (s *Topo) Open() <-chan error {
 	}
 	s.hasOpened.Store(true)
 	s.prepareContext() // ensure context is set
+	s.registerMemorySchemas()
 	s.drain = make(chan error)
+	runOnce := s.options != nil && s.options.RunOnce
+	if runOnce {
+		s.completed = make(chan struct{})
+	}
 	log := s.ctx.GetLogger()
 	log.Infoln("Opening stream")
 	go func() {
@@ -211,23 +306,38 @@ func (s *Topo) Open() <-chan error {
 			s.mu.Lock()
 			defer s.mu.Unlock()
 			var err error
-			if s.store, err = state.CreateStore(s.name, s.options.Qos); err != nil {
+			if s.store, err = state.CreateRuleStore(s.name, s.options); err != nil {
 				return fmt.Errorf("topo %s create store error %v", s.name, err)
 			}
 			s.enableCheckpoint(s.ctx)
+			s.applyQuota()
+			s.applyThrottle()
 			// open stream sink, after log sink is ready.
+			// The goroutines of each node are labeled to attribute the profile samples to the node
 			for _, snk := range s.sinks {
-				snk.Open(s.ctx.WithMeta(s.name, snk.GetName(), s.store), s.drain)
+				profiler.Do(s.name, snk.GetName(), func() {
+					snk.Open(s.ctx.WithMeta(s.name, snk.GetName(), s.store), s.drain)
+				})
 			}
 
 			for _, op := range s.ops {
-				op.Exec(s.ctx.WithMeta(s.name, op.GetName(), s.store), s.drain)
+				profiler.Do(s.name, op.GetName(), func() {
+					op.Exec(s.ctx.WithMeta(s.name, op.GetName(), s.store), s.drain)
+				})
+			}
+
+			if runOnce {
+				s.runOnce(s.ctx)
 			}
 
 			for _, source := range s.sources {
-				source.Open(s.ctx.WithMeta(s.name, source.GetName(), s.store), s.drain)
+				profiler.Do(s.name, source.GetName(), func() {
+					source.Open(s.ctx.WithMeta(s.name, source.GetName(), s.store), s.drain)
+				})
 			}
 
+			s.monitorQueues(s.ctx)
+
 			// activate checkpoint
 			if s.coordinator != nil {
 				s.coordinator.Activate()
@@ -242,10 +352,110 @@ func (s *Topo) Open() <-chan error {
 	return s.drain
 }
 
+// Completed returns the channel which is closed when the rule running once finishes, which means all its bounded
+// sources finish reading and the sinks send out all the results. It is nil if the rule does not run once.
+func (s *Topo) Completed() <-chan struct{} {
+	return s.completed
+}
+
+// runOnce drains the rule after all the bounded sources end, so that the open windows are emitted, and then closes the
+// completed channel once all the sinks are drained. It is called after the sinks are opened and before the sources.
+func (s *Topo) runOnce(ctx api.StreamContext) {
+	ended := make(chan struct{}, len(s.sources))
+	count := 0
+	for _, src := range s.sources {
+		if sn, ok := src.(*node.SourceNode); ok && sn.GetBound() != nil {
+			sn.GetBound().OnEnd = func() {
+				ended <- struct{}{}
+			}
+			count++
+		}
+	}
+	drained := make([]<-chan struct{}, 0, len(s.sinks))
+	for _, snk := range s.sinks {
+		drained = append(drained, snk.Drained())
+	}
+	completed := s.completed
+	go func() {
+		for i := 0; i < count; i++ {
+			select {
+			case <-ended:
+			case <-ctx.Done():
+				return
+			}
+		}
+		ctx.GetLogger().Infof("All the bounded sources end, draining the rule")
+		for _, src := range s.sources {
+			if d, ok := src.(node.Drainable); ok {
+				d.Drain()
+			}
+		}
+		for _, d := range drained {
+			select {
+			case <-d:
+			case <-ctx.Done():
+				return
+			}
+		}
+		ctx.GetLogger().Infof("Rule completes")
+		close(completed)
+	}()
+}
+
 func (s *Topo) HasOpen() bool {
 	return s.hasOpened.Load()
 }
 
+// applyQuota sets the quota limiter to the nodes before they run
+func (s *Topo) applyQuota() {
+	q := s.options.Quota
+	if q == nil {
+		return
+	}
+	var nodes []node.QuotaNode
+	for _, src := range s.sources {
+		if n, ok := src.(node.QuotaNode); ok {
+			nodes = append(nodes, n)
+		}
+	}
+	for _, op := range s.ops {
+		if n, ok := op.(node.QuotaNode); ok {
+			nodes = append(nodes, n)
+		}
+	}
+	l := quota.NewLimiter(q, func() int {
+		total := 0
+		for _, n := range nodes {
+			total += n.GetBufferedLength()
+		}
+		return total
+	})
+	if q.MaxStateSize > 0 {
+		if so, ok := s.store.(state.SizeObservable); ok && s.options.Qos >= api.AtLeastOnce {
+			so.ObserveSize(l.SetStateSize)
+		} else {
+			s.ctx.GetLogger().Warnf("quota maxStateSize is ignored because it requires qos to be at least 1")
+		}
+	}
+	for _, n := range nodes {
+		n.SetQuota(l)
+	}
+}
+
+// applyThrottle registers the rule to be throttled by its priority when the host is cpu saturated
+func (s *Topo) applyThrottle() {
+	t := quota.Register(s.name, s.options.Priority)
+	if t == nil {
+		return
+	}
+	s.throttle.Store(t)
+	for _, src := range s.sources {
+		if n, ok := src.(node.QuotaNode); ok {
+			n.SetThrottle(t)
+		}
+	}
+}
+
 func (s *Topo) enableCheckpoint(ctx api.StreamContext) {
 	if s.options.Qos >= api.AtLeastOnce {
 		var (
@@ -290,6 +500,7 @@ func (s *Topo) GetMetrics() (keys []string, values []any) {
 				keys = append(keys, "source_"+sn.GetName()+"_0_"+metric.MetricNames[i])
 				values = append(values, v)
 			}
+			keys, values = appendQueueMetrics(keys, values, "source_", sn)
 		}
 	}
 	for _, so := range s.ops {
@@ -297,16 +508,44 @@ func (s *Topo) GetMetrics() (keys []string, values []any) {
 			keys = append(keys, "op_"+so.GetName()+"_0_"+metric.MetricNames[i])
 			values = append(values, v)
 		}
+		if en, ok := so.(node.ExtraMetricsNode); ok {
+			names, vals := en.GetExtraMetrics()
+			for i, v := range vals {
+				keys = append(keys, "op_"+so.GetName()+"_0_"+names[i])
+				values = append(values, v)
+			}
+		}
+		keys, values = appendQueueMetrics(keys, values, "op_", so)
 	}
 	for _, sn := range s.sinks {
 		for i, v := range sn.GetMetrics() {
 			keys = append(keys, "sink_"+sn.GetName()+"_0_"+metric.MetricNames[i])
 			values = append(values, v)
 		}
+		names, vals := sn.GetExtraMetrics()
+		for i, v := range vals {
+			keys = append(keys, "sink_"+sn.GetName()+"_0_"+names[i])
+			values = append(values, v)
+		}
+	}
+	if t := s.throttle.Load(); t != nil {
+		keys = append(keys, metric.ThrottleLevel, metric.ThrottledMsTotal)
+		values = append(values, t.Level(), t.Waited().Milliseconds())
 	}
 	return
 }
 
+// appendQueueMetrics appends the length of the queue to each downstream node like op_filter_0_buffer_length_to_project
+func appendQueueMetrics(keys []string, values []any, prefix string, n any) ([]string, []any) {
+	if qn, ok := n.(node.QueueNode); ok {
+		for _, q := range qn.GetOutputQueues() {
+			keys = append(keys, prefix+qn.GetName()+"_0_"+metric.BufferLength+"_to_"+q.To)
+			values = append(values, int64(q.Length))
+		}
+	}
+	return keys, values
+}
+
 func (s *Topo) RemoveMetrics() {
 	conf.Log.Infof("start removing %v metrics", s.name)
 	for _, sn := range s.sources {
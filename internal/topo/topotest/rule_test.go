@@ -132,6 +132,104 @@ func TestWindowFuncSQL(t *testing.T) {
 					},
 				},
 			},
+		}, {
+			Name: "TestRank",
+			Sql:  `select color, size, rank() over (partition by color order by size desc) as r from demo group by countWindow(5)`,
+			R: [][]map[string]interface{}{
+				{
+					{
+						"color": "blue",
+						"size":  float64(6),
+						"r":     float64(1),
+					},
+					{
+						"color": "blue",
+						"size":  float64(2),
+						"r":     float64(2),
+					},
+					{
+						"color": "red",
+						"size":  float64(3),
+						"r":     float64(1),
+					},
+					{
+						"color": "red",
+						"size":  float64(1),
+						"r":     float64(2),
+					},
+					{
+						"color": "yellow",
+						"size":  float64(4),
+						"r":     float64(1),
+					},
+				},
+			},
+		},
+		{
+			Name: "TestRankPeers",
+			Sql:  `select color, size, rank() over (order by color) as r from demo group by countWindow(5)`,
+			R: [][]map[string]interface{}{
+				{
+					{
+						"color": "blue",
+						"size":  float64(6),
+						"r":     float64(1),
+					},
+					{
+						"color": "blue",
+						"size":  float64(2),
+						"r":     float64(1),
+					},
+					{
+						"color": "red",
+						"size":  float64(3),
+						"r":     float64(3),
+					},
+					{
+						"color": "red",
+						"size":  float64(1),
+						"r":     float64(3),
+					},
+					{
+						"color": "yellow",
+						"size":  float64(4),
+						"r":     float64(5),
+					},
+				},
+			},
+		},
+		{
+			Name: "TestDenseRank",
+			Sql:  `select color, size, dense_rank() over (order by color) as r from demo group by countWindow(5)`,
+			R: [][]map[string]interface{}{
+				{
+					{
+						"color": "blue",
+						"size":  float64(6),
+						"r":     float64(1),
+					},
+					{
+						"color": "blue",
+						"size":  float64(2),
+						"r":     float64(1),
+					},
+					{
+						"color": "red",
+						"size":  float64(3),
+						"r":     float64(2),
+					},
+					{
+						"color": "red",
+						"size":  float64(1),
+						"r":     float64(2),
+					},
+					{
+						"color": "yellow",
+						"size":  float64(4),
+						"r":     float64(3),
+					},
+				},
+			},
 		},
 	}
 	// Data setup
@@ -159,6 +257,55 @@ func TestWindowFuncSQL(t *testing.T) {
 	}
 }
 
+func TestForecastSQL(t *testing.T) {
+	// Reset
+	streamList := []string{"demo"}
+	HandleStream(false, streamList, t)
+	tests := []RuleTest{
+		{
+			Name: "TestForecastSQL1",
+			Sql:  `select holt_winters(size, 0.5, 0.5)->forecast as f, holt_winters(size, 0.5, 0.5)->stddev as sd from demo group by countWindow(5)`,
+			R: [][]map[string]interface{}{
+				{{
+					"f":  2.796875,
+					"sd": 4.498372101382899,
+				}},
+			},
+		},
+		{
+			Name: "TestForecastSQL2",
+			Sql: `select abs(last_value(size, true) - holt_winters(size, 0.5, 0.5)->forecast) > holt_winters(size, 0.5, 0.5)->stddev * 3 as alert3,
+				abs(last_value(size, true) - holt_winters(size, 0.5, 0.5)->forecast) > holt_winters(size, 0.5, 0.5)->stddev * 0.3 as alert03
+				from demo group by countWindow(5)`,
+			R: [][]map[string]interface{}{
+				{{
+					"alert3":  false,
+					"alert03": true,
+				}},
+			},
+		},
+		{
+			Name: "TestForecastSQL3",
+			Sql:  `select arima_forecast(size, 1) as f from demo group by countWindow(2)`,
+			R: [][]map[string]interface{}{
+				{{}},
+				{{}},
+			},
+		},
+	}
+	// Data setup
+	HandleStream(true, streamList, t)
+	options := []*api.RuleOption{
+		{
+			BufferLength: 100,
+			SendError:    true,
+		},
+	}
+	for j, opt := range options {
+		DoRuleTest(t, tests, j, opt, 0)
+	}
+}
+
 func TestAccAggSQL(t *testing.T) {
 	// Reset
 	streamList := []string{"demo"}
@@ -406,6 +553,57 @@ func TestAccAggSQL(t *testing.T) {
 	}
 }
 
+func TestMatchRecognizeSQL(t *testing.T) {
+	// Reset
+	streamList := []string{"demo"}
+	HandleStream(false, streamList, t)
+	tests := []RuleTest{
+		{
+			Name: "TestMatchRecognizeSQL1",
+			Sql:  `select startSize, endSize from demo MATCH_RECOGNIZE (MEASURES A.size AS startSize, B.size AS endSize PATTERN (A B) DEFINE B AS B.size < A.size)`,
+			R: [][]map[string]interface{}{
+				{{
+					"startSize": float64(6),
+					"endSize":   float64(2),
+				}},
+				{{
+					"startSize": float64(4),
+					"endSize":   float64(1),
+				}},
+			},
+		},
+		{
+			Name: "TestMatchRecognizeSQL2",
+			Sql: `select color, startSize - endSize AS dropped from demo
+				MATCH_RECOGNIZE (PARTITION BY color MEASURES A.size AS startSize, B.size AS endSize PATTERN (A B) DEFINE B AS B.size < A.size)
+				WHERE endSize > 1`,
+			R: [][]map[string]interface{}{
+				{{
+					"color":   "blue",
+					"dropped": float64(4),
+				}},
+			},
+		},
+	}
+	// Data setup
+	HandleStream(true, streamList, t)
+	options := []*api.RuleOption{
+		{
+			BufferLength: 100,
+			SendError:    true,
+		},
+		{
+			BufferLength:       100,
+			SendError:          true,
+			Qos:                api.AtLeastOnce,
+			CheckpointInterval: 5000,
+		},
+	}
+	for j, opt := range options {
+		DoRuleTest(t, tests, j, opt, 0)
+	}
+}
+
 func TestLimitSQL(t *testing.T) {
 	// Reset
 	streamList := []string{"demo", "demoArr", "demoArr2"}
@@ -678,6 +876,79 @@ func TestSRFSQL(t *testing.T) {
 	}
 }
 
+func TestUnnestSQL(t *testing.T) {
+	// Reset
+	streamList := []string{"demoArr"}
+	HandleStream(false, streamList, t)
+	tests := []RuleTest{
+		{
+			Name: "TestUnnestSQL1",
+			Sql:  `SELECT x, a, b FROM demoArr CROSS JOIN UNNEST(arr2)`,
+			R: [][]map[string]interface{}{
+				{{
+					"x": float64(1),
+					"a": float64(1),
+					"b": float64(2),
+				}},
+				{{
+					"x": float64(1),
+					"a": float64(3),
+					"b": float64(4),
+				}},
+			},
+		},
+		{
+			Name: "TestUnnestSQL2",
+			Sql:  `SELECT y, item FROM demoArr CROSS JOIN UNNEST(arr3) AS item WHERE item > 1`,
+			R: [][]map[string]interface{}{
+				{{
+					"y":    float64(2),
+					"item": float64(2),
+				}},
+				{{
+					"y":    float64(2),
+					"item": float64(3),
+				}},
+			},
+		},
+		{
+			Name: "TestUnnestSQL3",
+			Sql:  `SELECT count(*) AS c, sum(a) AS s FROM demoArr CROSS JOIN UNNEST(arr2) GROUP BY SESSIONWINDOW(ss, 2, 1)`,
+			R: [][]map[string]interface{}{
+				{{
+					"c": float64(2),
+					"s": float64(4),
+				}},
+			},
+		},
+		{
+			Name: "TestUnnestSQL4",
+			Sql:  `SELECT x FROM demoArr CROSS JOIN UNNEST(a)`,
+			R: [][]map[string]interface{}{
+				{{
+					"error": "run Unnest error: the argument should be array but got int(6)",
+				}},
+			},
+		},
+	}
+	// Data setup
+	HandleStream(true, streamList, t)
+	options := []*api.RuleOption{
+		{
+			BufferLength: 100,
+			SendError:    true,
+		}, {
+			BufferLength:       100,
+			SendError:          true,
+			Qos:                api.AtLeastOnce,
+			CheckpointInterval: 5000,
+		},
+	}
+	for j, opt := range options {
+		DoRuleTest(t, tests, j, opt, 0)
+	}
+}
+
 func TestSingleSQL(t *testing.T) {
 	// Reset
 	streamList := []string{"demo", "demoError", "demo1", "table1", "demoTable", "demoArr"}
@@ -2192,3 +2463,56 @@ func TestAliasSQL(t *testing.T) {
 		DoRuleTest(t, tests, j, opt, 0)
 	}
 }
+
+func TestParallelism(t *testing.T) {
+	streamList := []string{"demo"}
+	HandleStream(false, streamList, t)
+	tests := []RuleTest{
+		{
+			Name: `TestParallelismRule1`,
+			Sql:  `SELECT color, lag(size) over (partition by color) as lastSize, size * 2 as d FROM demo WHERE size > 1`,
+			R: [][]map[string]interface{}{
+				{{
+					"color": "red",
+					"d":     float64(6),
+				}},
+				{{
+					"color": "blue",
+					"d":     float64(12),
+				}},
+				{{
+					"color":    "blue",
+					"lastSize": float64(6),
+					"d":        float64(4),
+				}},
+				{{
+					"color": "yellow",
+					"d":     float64(8),
+				}},
+			},
+		},
+	}
+	HandleStream(true, streamList, t)
+	parallelism := map[string]*api.OperatorParallelism{
+		"analytic": {Concurrency: 2, PartitionBy: "color"},
+		"filter":   {Concurrency: 2, PartitionBy: "color"},
+		"project":  {Concurrency: 3},
+	}
+	options := []*api.RuleOption{
+		{
+			BufferLength: 100,
+			SendError:    true,
+			Parallelism:  parallelism,
+		},
+		{
+			BufferLength:       100,
+			SendError:          true,
+			Qos:                api.AtLeastOnce,
+			CheckpointInterval: 5000,
+			Parallelism:        parallelism,
+		},
+	}
+	for j, opt := range options {
+		DoRuleTest(t, tests, j, opt, 0)
+	}
+}
@@ -741,6 +741,85 @@ func TestWindow(t *testing.T) {
 				"op_2_window_0_records_out_total":  int64(1),
 			},
 		},
+		{
+			Name: `TestWindowRule14`,
+			Sql:  `SELECT color, count(*) as c, window_start() as ws, window_end() as we FROM demo GROUP BY color, SessionWindow(ss, 10, 1)`,
+			R: [][]map[string]interface{}{
+				{{
+					"color": "red",
+					"c":     float64(1),
+					"ws":    float64(1541152486013),
+					"we":    float64(1541152487013),
+				}}, {{
+					"color": "blue",
+					"c":     float64(2),
+					"ws":    float64(1541152486822),
+					"we":    float64(1541152488632),
+				}}, {{
+					"color": "yellow",
+					"c":     float64(1),
+					"ws":    float64(1541152488442),
+					"we":    float64(1541152489442),
+				}}, {{
+					"color": "red",
+					"c":     float64(1),
+					"ws":    float64(1541152489252),
+					"we":    float64(1541152490252),
+				}},
+			},
+			M: map[string]interface{}{
+				"source_demo_0_exceptions_total":  int64(0),
+				"source_demo_0_records_in_total":  int64(5),
+				"source_demo_0_records_out_total": int64(5),
+
+				"op_2_window_0_exceptions_total":  int64(0),
+				"op_2_window_0_records_in_total":  int64(5),
+				"op_2_window_0_records_out_total": int64(4),
+			},
+		},
+		{
+			Name: `TestWindowRule15`,
+			Sql:  `SELECT sum(size) as s, count(*) as c, avg(size) as a, min(size) as lo, max(size) as hi FROM demo GROUP BY SlidingWindow(ss, 1) HAVING count(*) > 1`,
+			R: [][]map[string]interface{}{
+				{{
+					"s":  float64(9),
+					"c":  float64(2),
+					"a":  float64(4),
+					"lo": float64(3),
+					"hi": float64(6),
+				}}, {{
+					"s":  float64(8),
+					"c":  float64(2),
+					"a":  float64(4),
+					"lo": float64(2),
+					"hi": float64(6),
+				}}, {{
+					"s":  float64(6),
+					"c":  float64(2),
+					"a":  float64(3),
+					"lo": float64(2),
+					"hi": float64(4),
+				}}, {{
+					"s":  float64(5),
+					"c":  float64(2),
+					"a":  float64(2),
+					"lo": float64(1),
+					"hi": float64(4),
+				}},
+			},
+			M: map[string]interface{}{
+				"source_demo_0_exceptions_total":  int64(0),
+				"source_demo_0_records_in_total":  int64(5),
+				"source_demo_0_records_out_total": int64(5),
+
+				"op_2_window_0_exceptions_total":  int64(0),
+				"op_2_window_0_records_in_total":  int64(5),
+				"op_2_window_0_records_out_total": int64(5),
+
+				"op_4_project_0_records_in_total":  int64(4),
+				"op_4_project_0_records_out_total": int64(4),
+			},
+		},
 	}
 	HandleStream(true, streamList, t)
 	options := []*api.RuleOption{
@@ -1453,6 +1532,33 @@ func TestEventWindow(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name: `TestEventWindowRule14`,
+			Sql:  `SELECT color, count(*) as c, window_start() as ws, window_end() as we FROM demoE GROUP BY color, SessionWindow(ss, 10, 1)`,
+			R: [][]map[string]interface{}{
+				{{
+					"color": "red",
+					"c":     float64(1),
+					"ws":    float64(1541152486013),
+					"we":    float64(1541152487013),
+				}}, {{
+					"color": "blue",
+					"c":     float64(1),
+					"ws":    float64(1541152487632),
+					"we":    float64(1541152488632),
+				}}, {{
+					"color": "yellow",
+					"c":     float64(1),
+					"ws":    float64(1541152488442),
+					"we":    float64(1541152489442),
+				}}, {{
+					"color": "red",
+					"c":     float64(1),
+					"ws":    float64(1541152489252),
+					"we":    float64(1541152490252),
+				}},
+			},
+		},
 	}
 	HandleStream(true, streamList, t)
 	options := []*api.RuleOption{
@@ -1755,3 +1861,132 @@ func TestEventSlidingWindow(t *testing.T) {
 		DoRuleTest(t, tests, j, opt, 10)
 	}
 }
+
+func TestWindowEarlyFire(t *testing.T) {
+	// Reset
+	streamList := []string{"demo"}
+	HandleStream(false, streamList, t)
+	tests := []RuleTest{
+		{
+			Name: `TestWindowEarlyFireRule1`,
+			Sql:  `SELECT count(*) as c, window_start() as ws, window_partial() as p FROM demo GROUP BY tumblingwindow(ss, 2)`,
+			R: [][]map[string]interface{}{
+				{{
+					"c":  float64(1),
+					"ws": float64(1541152486000),
+					"p":  true,
+				}},
+				{{
+					"c":  float64(2),
+					"ws": float64(1541152486000),
+					"p":  true,
+				}},
+				{{
+					"c":  float64(3),
+					"ws": float64(1541152486000),
+					"p":  true,
+				}},
+				{{
+					"c":  float64(3),
+					"ws": float64(1541152486000),
+					"p":  false,
+				}},
+				{{
+					"c":  float64(1),
+					"ws": float64(1541152488000),
+					"p":  true,
+				}},
+				{{
+					"c":  float64(2),
+					"ws": float64(1541152488000),
+					"p":  true,
+				}},
+				{{
+					"c":  float64(2),
+					"ws": float64(1541152488000),
+					"p":  false,
+				}},
+			},
+			M: map[string]interface{}{
+				"source_demo_0_exceptions_total":  int64(0),
+				"source_demo_0_records_in_total":  int64(5),
+				"source_demo_0_records_out_total": int64(5),
+
+				"op_2_window_0_exceptions_total":  int64(0),
+				"op_2_window_0_records_in_total":  int64(5),
+				"op_2_window_0_records_out_total": int64(7),
+			},
+		},
+	}
+	HandleStream(true, streamList, t)
+	options := []*api.RuleOption{
+		{
+			BufferLength:       100,
+			SendError:          true,
+			EarlyFireOnElement: true,
+		},
+	}
+	for j, opt := range options {
+		DoRuleTest(t, tests, j, opt, 0)
+	}
+}
+
+func TestEventWindowLateness(t *testing.T) {
+	// Reset
+	streamList := []string{"demoE"}
+	HandleStream(false, streamList, t)
+	tests := []RuleTest{
+		{
+			Name: `TestEventWindowLatenessRule1`,
+			Sql:  `SELECT color, ts, window_start() as ws FROM demoE GROUP BY tumblingwindow(ss, 1)`,
+			R: [][]map[string]interface{}{
+				{{
+					"color": "red",
+					"ts":    float64(1541152486013),
+					"ws":    float64(1541152486013),
+				}},
+				{{
+					"color": "blue",
+					"ts":    float64(1541152487632),
+					"ws":    float64(1541152487000),
+				}},
+				{},
+				// updated by the late tuple within the allowed lateness
+				{{
+					"color": "yellow",
+					"ts":    float64(1541152488442),
+					"ws":    float64(1541152488000),
+				}},
+				{{
+					"color": "red",
+					"ts":    float64(1541152489252),
+					"ws":    float64(1541152489000),
+				}},
+				{},
+				{},
+			},
+			M: map[string]interface{}{
+				"source_demoE_0_records_in_total":  int64(6),
+				"source_demoE_0_records_out_total": int64(6),
+
+				"op_2_watermark_0_records_in_total":  int64(6),
+				"op_2_watermark_0_records_out_total": int64(5),
+
+				"op_3_window_0_records_in_total":  int64(5),
+				"op_3_window_0_records_out_total": int64(7),
+			},
+		},
+	}
+	HandleStream(true, streamList, t)
+	options := []*api.RuleOption{
+		{
+			BufferLength:    100,
+			SendError:       true,
+			IsEventTime:     true,
+			AllowedLateness: 2000,
+		},
+	}
+	for j, opt := range options {
+		DoRuleTest(t, tests, j, opt, 0)
+	}
+}
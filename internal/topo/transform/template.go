@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"text/template"
 
 	"github.com/lf-edge/ekuiper/internal/conf"
@@ -27,6 +28,25 @@ import (
 	"github.com/lf-edge/ekuiper/pkg/message"
 )
 
+// bufferPool reuses the buffers to execute the data template, so that the buffer does not grow from empty for each
+// message
+var bufferPool = sync.Pool{
+	New: func() any {
+		return new(bytes.Buffer)
+	},
+}
+
+// maxPooledBufferSize avoids holding the large buffers of the occasional big messages
+const maxPooledBufferSize = 64 * 1024
+
+func putBuffer(b *bytes.Buffer) {
+	if b.Cap() > maxPooledBufferSize {
+		return
+	}
+	b.Reset()
+	bufferPool.Put(b)
+}
+
 // TransFunc is the function to transform data
 type TransFunc func(interface{}) ([]byte, bool, error)
 
@@ -48,7 +68,7 @@ func GenTransform(dt string, format string, schemaId string, delimiter string, d
 			return nil, err
 		}
 		c.(*delimited.Converter).SetColumns(fields)
-	case message.FormatJson:
+	case message.FormatJson, message.FormatFastJson:
 		c, err = converter.GetOrCreateConverter(&ast.Options{FORMAT: format})
 		if err != nil {
 			return nil, err
@@ -71,12 +91,15 @@ func GenTransform(dt string, format string, schemaId string, delimiter string, d
 			e           error
 		)
 		if tp != nil {
-			var output bytes.Buffer
-			err := tp.Execute(&output, d)
+			output := bufferPool.Get().(*bytes.Buffer)
+			err := tp.Execute(output, d)
 			if err != nil {
+				putBuffer(output)
 				return nil, false, fmt.Errorf("fail to encode data %v with dataTemplate for error %v", d, err)
 			}
-			bs = output.Bytes()
+			// The result is sent to the sink, so copy it out of the reused buffer
+			bs = bytes.Clone(output.Bytes())
+			putBuffer(output)
 			transformed = true
 		}
 
@@ -93,7 +116,7 @@ func GenTransform(dt string, format string, schemaId string, delimiter string, d
 		}
 
 		switch format {
-		case message.FormatJson:
+		case message.FormatJson, message.FormatFastJson:
 			if transformed && !selected {
 				return bs, true, nil
 			}
@@ -0,0 +1,114 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package vclock provides the virtual clocks of the rules, so that the time windows and the timers of a rule
+// advance by the event timestamps or faster than the wall clock. The clock of a rule is saved in its context.
+package vclock
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+// Key is the context key of the clock of the rule
+const Key = "$$clock"
+
+// the real interval to advance the accelerated clock
+var driveInterval = 10 * time.Millisecond
+
+// Get returns the clock of the rule. It is the global clock if the rule has no virtual clock.
+func Get(ctx api.StreamContext) clock.Clock {
+	if c, ok := ctx.Value(Key).(clock.Clock); ok {
+		return c
+	}
+	return conf.Clock
+}
+
+// TimerByTime creates the timer which fires at the time by the clock
+func TimerByTime(c clock.Clock, t time.Time) *clock.Timer {
+	return c.Timer(t.Sub(c.Now()))
+}
+
+// New creates the virtual clock by the option which runs until the context is done.
+// Return nil if the rule uses the wall clock.
+func New(ctx context.Context, c *api.RuleClock) clock.Clock {
+	if c == nil {
+		return nil
+	}
+	switch c.Mode {
+	case api.ClockAccelerated:
+		m := clock.NewMock()
+		if c.Start > 0 {
+			m.Set(time.UnixMilli(c.Start))
+		} else {
+			m.Set(conf.GetNow())
+		}
+		go drive(ctx, m, c.Speed)
+		return m
+	case api.ClockEvent:
+		return NewEventClock(c.Start)
+	default:
+		return nil
+	}
+}
+
+// drive advances the accelerated clock by the speed times of the elapsed real time
+func drive(ctx context.Context, m *clock.Mock, speed float64) {
+	ticker := time.NewTicker(driveInterval)
+	defer ticker.Stop()
+	step := time.Duration(float64(driveInterval) * speed)
+	for {
+		select {
+		case <-ticker.C:
+			m.Add(step)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// EventClock is advanced by the timestamps of the received data. It starts at the first timestamp
+// if no start time is set, and it never goes back.
+type EventClock struct {
+	*clock.Mock
+	mu      sync.Mutex
+	started bool
+}
+
+func NewEventClock(start int64) *EventClock {
+	c := &EventClock{Mock: clock.NewMock()}
+	if start > 0 {
+		c.Mock.Set(time.UnixMilli(start))
+		c.started = true
+	}
+	return c
+}
+
+// Advance moves the clock to the timestamp in milliseconds and fires the due timers
+func (c *EventClock) Advance(ts int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := time.UnixMilli(ts)
+	if c.started && !t.After(c.Mock.Now()) {
+		return
+	}
+	c.started = true
+	c.Mock.Set(t)
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vclock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/internal/conf"
+	kctx "github.com/lf-edge/ekuiper/internal/topo/context"
+	"github.com/lf-edge/ekuiper/pkg/api"
+)
+
+func TestGet(t *testing.T) {
+	ctx := kctx.Background()
+	assert.Equal(t, conf.Clock, Get(ctx))
+	c := NewEventClock(1000)
+	ctx = kctx.WithValue(ctx, Key, c)
+	assert.Equal(t, c, Get(ctx))
+}
+
+func TestNew(t *testing.T) {
+	assert.Nil(t, New(context.Background(), nil))
+	assert.Nil(t, New(context.Background(), &api.RuleClock{Mode: api.ClockWall}))
+	c := New(context.Background(), &api.RuleClock{Mode: api.ClockEvent, Start: 1000})
+	require.IsType(t, &EventClock{}, c)
+	assert.Equal(t, int64(1000), c.Now().UnixMilli())
+}
+
+func TestEventClock(t *testing.T) {
+	c := NewEventClock(0)
+	// starts at the first timestamp without firing the timers
+	c.Advance(10000)
+	assert.Equal(t, int64(10000), c.Now().UnixMilli())
+	timer := TimerByTime(c, time.UnixMilli(12000))
+	c.Advance(11000)
+	select {
+	case <-timer.C:
+		assert.Fail(t, "timer should not fire before the time")
+	default:
+	}
+	// never goes back
+	c.Advance(9000)
+	assert.Equal(t, int64(11000), c.Now().UnixMilli())
+	c.Advance(12500)
+	select {
+	case now := <-timer.C:
+		assert.Equal(t, int64(12000), now.UnixMilli())
+	case <-time.After(time.Second):
+		assert.Fail(t, "timer should fire")
+	}
+	assert.Equal(t, int64(12500), c.Now().UnixMilli())
+}
+
+func TestAccelerated(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := New(ctx, &api.RuleClock{Mode: api.ClockAccelerated, Speed: 3600, Start: 1000})
+	require.IsType(t, &clock.Mock{}, c)
+	// An hour passes in about a second
+	timer := c.Timer(time.Hour)
+	select {
+	case <-timer.C:
+		assert.GreaterOrEqual(t, c.Now().UnixMilli(), int64(1000)+time.Hour.Milliseconds())
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "accelerated timer should fire")
+	}
+	cancel()
+	time.Sleep(2 * driveInterval)
+	now := c.Now()
+	time.Sleep(5 * driveInterval)
+	assert.Equal(t, now, c.Now())
+}
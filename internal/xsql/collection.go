@@ -15,6 +15,7 @@
 package xsql
 
 import (
+	"context"
 	"github.com/lf-edge/ekuiper/pkg/ast"
 )
 
@@ -68,6 +69,7 @@ type WindowTuples struct {
 	contentBySrc map[string][]Row // volatile, temporary cache]
 
 	AffiliateRow
+	traceCtx  context.Context // the trace context of the span which produces it, nil if not traced
 	cachedMap map[string]interface{}
 	isAgg     bool
 }
@@ -82,6 +84,7 @@ type JoinTuples struct {
 	*WindowRange
 
 	AffiliateRow
+	traceCtx  context.Context // the trace context of the span which produces it, nil if not traced
 	cachedMap map[string]interface{}
 	isAgg     bool
 }
@@ -94,6 +97,7 @@ var (
 type GroupedTuplesSet struct {
 	Groups []*GroupedTuples
 	*WindowRange
+	traceCtx context.Context // the trace context of the span which produces it, nil if not traced
 }
 
 var _ Collection = &GroupedTuplesSet{}
@@ -174,10 +178,18 @@ func (w *WindowTuples) AddTuple(tuple Row) *WindowTuples {
 }
 
 func (w *WindowTuples) AggregateEval(expr ast.Expr, v CallValuer) []interface{} {
-	var result []interface{}
+	if len(w.Content) == 0 {
+		return nil
+	}
+	result := make([]interface{}, 0, len(w.Content))
+	// The valuers are not referred after the evaluation, so they are reused for all rows
+	wv := &WildcardValuer{}
+	ve := GetValuerEval(nil, &WindowRangeValuer{WindowRange: w.WindowRange}, v, wv)
 	for _, t := range w.Content {
-		result = append(result, Eval(expr, MultiValuer(t, &WindowRangeValuer{WindowRange: w.WindowRange}, v, &WildcardValuer{t})))
+		ve.valuers[0], wv.Data = t, t
+		result = append(result, ve.Eval(expr))
 	}
+	PutValuerEval(ve)
 	return result
 }
 
@@ -241,6 +253,7 @@ func (w *WindowTuples) Clone() Collection {
 		Content:      ts,
 		WindowRange:  w.WindowRange,
 		AffiliateRow: w.AffiliateRow.Clone(),
+		traceCtx:     w.traceCtx,
 		isAgg:        w.isAgg,
 	}
 	return c
@@ -287,10 +300,18 @@ func (s *JoinTuples) Swap(i, j int) {
 func (s *JoinTuples) Index(i int) Row { return s.Content[i] }
 
 func (s *JoinTuples) AggregateEval(expr ast.Expr, v CallValuer) []interface{} {
-	var result []interface{}
+	if len(s.Content) == 0 {
+		return nil
+	}
+	result := make([]interface{}, 0, len(s.Content))
+	// The valuers are not referred after the evaluation, so they are reused for all rows
+	wv := &WildcardValuer{}
+	ve := GetValuerEval(nil, &WindowRangeValuer{WindowRange: s.WindowRange}, v, wv)
 	for _, t := range s.Content {
-		result = append(result, Eval(expr, MultiValuer(t, &WindowRangeValuer{WindowRange: s.WindowRange}, v, &WildcardValuer{t})))
+		ve.valuers[0], wv.Data = t, t
+		result = append(result, ve.Eval(expr))
 	}
+	PutValuerEval(ve)
 	return result
 }
 
@@ -379,6 +400,7 @@ func (s *JoinTuples) Clone() Collection {
 		Content:      ts,
 		WindowRange:  s.WindowRange,
 		AffiliateRow: s.AffiliateRow.Clone(),
+		traceCtx:     s.traceCtx,
 		isAgg:        s.isAgg,
 	}
 	return c
@@ -489,6 +511,7 @@ func (s *GroupedTuplesSet) Clone() Collection {
 	return &GroupedTuplesSet{
 		Groups:      ng,
 		WindowRange: s.WindowRange,
+		traceCtx:    s.traceCtx,
 	}
 }
 
@@ -536,10 +559,16 @@ func (w WindowRangeValuer) Meta(_, _ string) (interface{}, bool) {
 type WindowRange struct {
 	windowStart int64
 	windowEnd   int64
+	// partial is true if the window is emitted by early firing before it closes
+	partial bool
 }
 
 func NewWindowRange(windowStart int64, windowEnd int64) *WindowRange {
-	return &WindowRange{windowStart, windowEnd}
+	return &WindowRange{windowStart: windowStart, windowEnd: windowEnd}
+}
+
+func NewPartialWindowRange(windowStart int64, windowEnd int64) *WindowRange {
+	return &WindowRange{windowStart: windowStart, windowEnd: windowEnd, partial: true}
 }
 
 func (r *WindowRange) FuncValue(key string) (interface{}, bool) {
@@ -550,6 +579,8 @@ func (r *WindowRange) FuncValue(key string) (interface{}, bool) {
 		return r.windowEnd, true
 	case "event_time":
 		return r.windowEnd, true
+	case "window_partial":
+		return r.partial, true
 	default:
 		return nil, false
 	}
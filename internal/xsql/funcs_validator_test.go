@@ -1,4 +1,4 @@
-// Copyright 2022-2023 EMQ Technologies Co., Ltd.
+// Copyright 2022-2024 EMQ Technologies Co., Ltd.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -502,6 +502,66 @@ func TestFuncValidator(t *testing.T) {
 			stmt: nil,
 			err:  "validate function deduplicate error: Expect bool type for parameter 2",
 		},
+		{
+			s: `SELECT array_map(x -> x.name, arr) FROM tbl`,
+			stmt: &ast.SelectStatement{Fields: []ast.Field{{AName: "", Name: "array_map", Expr: &ast.Call{Name: "array_map", Args: []ast.Expr{
+				&ast.LambdaExpr{Params: []string{"x"}, Body: &ast.BinaryExpr{
+					OP:  ast.ARROW,
+					LHS: &ast.LambdaParamRef{Name: "x"},
+					RHS: &ast.JsonFieldRef{Name: "name"},
+				}},
+				&ast.FieldRef{Name: "arr", StreamName: ast.DefaultStream},
+			}}}}, Sources: []ast.Source{&ast.Table{Name: "tbl"}}},
+		},
+		{
+			s: `SELECT array_reduce((acc, x) -> acc + x * rate, arr, 0) FROM tbl`,
+			stmt: &ast.SelectStatement{Fields: []ast.Field{{AName: "", Name: "array_reduce", Expr: &ast.Call{Name: "array_reduce", Args: []ast.Expr{
+				&ast.LambdaExpr{Params: []string{"acc", "x"}, Body: &ast.BinaryExpr{
+					OP:  ast.ADD,
+					LHS: &ast.LambdaParamRef{Name: "acc"},
+					RHS: &ast.BinaryExpr{
+						OP:  ast.MUL,
+						LHS: &ast.LambdaParamRef{Name: "x"},
+						RHS: &ast.FieldRef{Name: "rate", StreamName: ast.DefaultStream},
+					},
+				}},
+				&ast.FieldRef{Name: "arr", StreamName: ast.DefaultStream},
+				&ast.IntegerLiteral{Val: 0},
+			}}}}, Sources: []ast.Source{&ast.Table{Name: "tbl"}}},
+		},
+		{
+			s: `SELECT array_map(a->fn, arr), array_map((fn), arr) FROM tbl`,
+			stmt: &ast.SelectStatement{Fields: []ast.Field{
+				{AName: "", Name: "array_map", Expr: &ast.Call{Name: "array_map", Args: []ast.Expr{
+					&ast.LambdaExpr{Params: []string{"a"}, Body: &ast.FieldRef{Name: "fn", StreamName: ast.DefaultStream}},
+					&ast.FieldRef{Name: "arr", StreamName: ast.DefaultStream},
+				}}},
+				{AName: "", Name: "array_map", Expr: &ast.Call{Name: "array_map", FuncId: 1, Args: []ast.Expr{
+					&ast.ParenExpr{Expr: &ast.FieldRef{Name: "fn", StreamName: ast.DefaultStream}},
+					&ast.FieldRef{Name: "arr", StreamName: ast.DefaultStream},
+				}}},
+			}, Sources: []ast.Source{&ast.Table{Name: "tbl"}}},
+		},
+		{
+			s:    `SELECT array_reduce(x -> x, arr, 0) FROM tbl`,
+			stmt: nil,
+			err:  "validate function array_reduce error: Expect 2 parameters for the lambda expression but found 1.",
+		},
+		{
+			s:    `SELECT array_filter("abs", arr) FROM tbl`,
+			stmt: nil,
+			err:  "validate function array_filter error: Expect lambda expression type for parameter 1",
+		},
+		{
+			s:    `SELECT object_filter((k, k) -> true, obj) FROM tbl`,
+			stmt: nil,
+			err:  "duplicate lambda parameter k",
+		},
+		{
+			s:    `SELECT object_filter((k, v) true, obj) FROM tbl`,
+			stmt: nil,
+			err:  "found \"TRUE\", expected -> after the lambda parameters.",
+		},
 	}
 
 	for _, tt := range tests {
@@ -27,6 +27,9 @@ import (
 type Scanner struct {
 	r   *bufio.Reader
 	buf *bytes.Buffer
+	// rec records the text read when it is not nil
+	rec     *strings.Builder
+	recLast int
 }
 
 func NewScanner(r io.Reader) *Scanner {
@@ -140,6 +143,12 @@ func (s *Scanner) Scan() (tok ast.Token, lit string) {
 		return ast.LBRACKET, ast.Tokens[ast.LBRACKET]
 	case ']':
 		return ast.RBRACKET, ast.Tokens[ast.RBRACKET]
+	case '{':
+		return ast.LBRACE, ast.Tokens[ast.LBRACE]
+	case '}':
+		return ast.RBRACE, ast.Tokens[ast.RBRACE]
+	case '?':
+		return ast.QUESTION, ast.Tokens[ast.QUESTION]
 	case ':':
 		return ast.COLON, ast.Tokens[ast.COLON]
 	case '#':
@@ -227,6 +236,8 @@ func (s *Scanner) ScanIdent() (tok ast.Token, lit string) {
 		return ast.OVER, lit
 	case "PARTITION":
 		return ast.PARTITION, lit
+	case "MATCH_RECOGNIZE":
+		return ast.MATCH_RECOGNIZE, lit
 	case "REPLACE":
 		return ast.REPLACE, lit
 	case "EXCEPT":
@@ -405,15 +416,41 @@ func (s *Scanner) ScanWhiteSpace() (tok ast.Token, lit string) {
 }
 
 func (s *Scanner) read() rune {
-	ch, _, err := s.r.ReadRune()
+	ch, size, err := s.r.ReadRune()
 	if err != nil {
+		s.recLast = 0
 		return eof
 	}
+	if s.rec != nil {
+		s.rec.WriteRune(ch)
+		s.recLast = size
+	}
 	return ch
 }
 
 func (s *Scanner) unread() {
-	_ = s.r.UnreadRune()
+	if err := s.r.UnreadRune(); err == nil && s.rec != nil && s.recLast > 0 {
+		t := s.rec.String()
+		s.rec.Reset()
+		s.rec.WriteString(t[:len(t)-s.recLast])
+		s.recLast = 0
+	}
+}
+
+// startRecord begins to record the original text read by the scanner
+func (s *Scanner) startRecord() {
+	s.rec = &strings.Builder{}
+	s.recLast = 0
+}
+
+// stopRecord ends the recording and returns the text read since startRecord
+func (s *Scanner) stopRecord() string {
+	if s.rec == nil {
+		return ""
+	}
+	t := s.rec.String()
+	s.rec = nil
+	return t
 }
 
 var eof = rune(0)
@@ -25,6 +25,7 @@ import (
 	"github.com/golang-collections/collections/stack"
 
 	"github.com/lf-edge/ekuiper/internal/binder/function"
+	"github.com/lf-edge/ekuiper/internal/pkg/labels"
 	"github.com/lf-edge/ekuiper/pkg/ast"
 	"github.com/lf-edge/ekuiper/pkg/message"
 	"github.com/lf-edge/ekuiper/pkg/modules"
@@ -35,15 +36,16 @@ type Parser struct {
 
 	i   int // buffer index
 	n   int // buffer char count
-	buf [3]struct {
+	buf [4]struct {
 		tok ast.Token
 		lit string
 	}
-	inFunc      string // currently parsing function name
-	f           int    // anonymous field index number
-	fn          int    // function index number
-	clause      string
-	sourceNames []string // source names in the from/join clause
+	inFunc       string // currently parsing function name
+	f            int    // anonymous field index number
+	fn           int    // function index number
+	clause       string
+	sourceNames  []string // source names in the from/join clause
+	lambdaParams []string // parameters of the lambda expressions being parsed
 }
 
 func (p *Parser) ParseCondition() (ast.Expr, error) {
@@ -166,16 +168,37 @@ func (p *Parser) Parse() (*ast.SelectStatement, error) {
 	} else {
 		selects.Sources = src
 	}
+	p.clause = "unnest"
+	if u, err := p.parseUnnest(); err != nil {
+		return nil, err
+	} else {
+		selects.Unnest = u
+	}
 	p.clause = "join"
 	if joins, err := p.parseJoins(); err != nil {
 		return nil, err
 	} else {
+		if len(joins) > 0 && selects.Unnest != nil {
+			return nil, fmt.Errorf("UNNEST cannot be used with JOIN.")
+		}
 		selects.Joins = joins
 	}
 	// The source names may be injected from outside to parse part of the sql
 	if p.sourceNames == nil {
 		p.sourceNames = getStreamNames(selects)
 	}
+	p.clause = "match_recognize"
+	if mr, err := p.parseMatchRecognize(); err != nil {
+		return nil, err
+	} else if mr != nil {
+		if len(selects.Joins) > 0 {
+			return nil, fmt.Errorf("MATCH_RECOGNIZE cannot be used with JOIN.")
+		}
+		if selects.Unnest != nil {
+			return nil, fmt.Errorf("MATCH_RECOGNIZE cannot be used with UNNEST.")
+		}
+		selects.MatchRecognize = mr
+	}
 	p.clause = "where"
 	if exp, err := p.ParseCondition(); err != nil {
 		return nil, err
@@ -331,6 +354,47 @@ func (p *Parser) parseJoins() (ast.Joins, error) {
 	}
 }
 
+// parseUnnest parses the `CROSS JOIN UNNEST(expr) [AS alias]` clause following the source. Return nil if not found.
+func (p *Parser) parseUnnest() (*ast.Unnest, error) {
+	if tok, _ := p.scanIgnoreWhitespace(); tok != ast.CROSS {
+		p.unscan()
+		return nil, nil
+	}
+	if tok, _ := p.scanIgnoreWhitespace(); tok != ast.JOIN {
+		p.unscan()
+		p.unscan()
+		return nil, nil
+	}
+	if tok, lit := p.scanIgnoreWhitespace(); tok != ast.IDENT || !strings.EqualFold(lit, "unnest") {
+		p.unscan()
+		p.unscan()
+		p.unscan()
+		return nil, nil
+	}
+	if tok, lit := p.scanIgnoreWhitespace(); tok != ast.LPAREN {
+		return nil, fmt.Errorf("found %q, expected ( after UNNEST.", lit)
+	}
+	u := &ast.Unnest{}
+	if exp, err := p.ParseExpr(); err != nil {
+		return nil, err
+	} else {
+		u.Expr = exp
+	}
+	if tok, lit := p.scanIgnoreWhitespace(); tok != ast.RPAREN {
+		return nil, fmt.Errorf("found %q, expected ) for UNNEST.", lit)
+	}
+	if tok, _ := p.scanIgnoreWhitespace(); tok == ast.AS {
+		if tok1, lit1 := p.scanIgnoreWhitespace(); tok1 == ast.IDENT {
+			u.Alias = lit1
+		} else {
+			return nil, fmt.Errorf("found %q, expected alias name for UNNEST.", lit1)
+		}
+	} else {
+		p.unscan()
+	}
+	return u, nil
+}
+
 func (p *Parser) ParseJoin(joinType ast.JoinType) (*ast.Join, error) {
 	j := &ast.Join{JoinType: joinType}
 	if src, alias, err := p.parseSourceLiteral(); err != nil {
@@ -695,6 +759,16 @@ func (p *Parser) parseUnaryExpr(isSubField bool) (ast.Expr, error) {
 				}
 				return &ast.MetaRef{StreamName: ast.DefaultStream, Name: n[0]}, nil
 			} else {
+				if !isSubField && contains(p.lambdaParams, n[0]) {
+					if len(n) == 2 {
+						return &ast.BinaryExpr{
+							LHS: &ast.LambdaParamRef{Name: n[0]},
+							OP:  ast.ARROW,
+							RHS: &ast.JsonFieldRef{Name: n[1]},
+						}, nil
+					}
+					return &ast.LambdaParamRef{Name: n[0]}, nil
+				}
 				if len(n) == 2 {
 					if len(p.sourceNames) > 0 && !contains(p.sourceNames, n[0]) {
 						return &ast.BinaryExpr{
@@ -899,6 +973,21 @@ func (p *Parser) parseCall(n string) (ast.Expr, error) {
 		}
 		p.unscan()
 
+		if len(args) == 0 && function.IsLambdaFunc(name) {
+			if lambda, err := p.parseLambda(); err != nil {
+				return nil, err
+			} else if lambda != nil {
+				args = append(args, lambda)
+				if tok, lit := p.scanIgnoreWhitespace(); tok != ast.COMMA {
+					if tok != ast.RPAREN {
+						return nil, fmt.Errorf("found function call %q, expected ), but with %q.", name, lit)
+					}
+					break
+				}
+				continue
+			}
+		}
+
 		if exp, err := p.ParseExpr(); err != nil {
 			return nil, err
 		} else {
@@ -956,6 +1045,80 @@ func (p *Parser) parseCall(n string) (ast.Expr, error) {
 	}
 }
 
+// parseLambda parses the lambda expression such as x -> x * 2 or (acc, x) -> acc + x. If the following tokens are not
+// a lambda expression, return nil and unscan the tokens.
+func (p *Parser) parseLambda() (*ast.LambdaExpr, error) {
+	var params []string
+	tok, lit := p.scanIgnoreWhitespace()
+	switch tok {
+	case ast.IDENT:
+		if tok1, _ := p.scanIgnoreWhitespace(); tok1 != ast.ARROW {
+			p.unscan()
+			p.unscan()
+			return nil, nil
+		}
+		params = []string{lit}
+	case ast.LPAREN:
+		tok1, lit1 := p.scanIgnoreWhitespace()
+		if tok1 != ast.IDENT {
+			p.unscan()
+			p.unscan()
+			return nil, nil
+		}
+		tok2, _ := p.scanIgnoreWhitespace()
+		switch tok2 {
+		case ast.RPAREN:
+			// (x) -> may also be a parenthesized field
+			if tok3, _ := p.scanIgnoreWhitespace(); tok3 != ast.ARROW {
+				p.unscan()
+				p.unscan()
+				p.unscan()
+				p.unscan()
+				return nil, nil
+			}
+			params = []string{lit1}
+		case ast.COMMA:
+			params = []string{lit1}
+			for {
+				tok3, lit3 := p.scanIgnoreWhitespace()
+				if tok3 != ast.IDENT {
+					return nil, fmt.Errorf("found %q, expected lambda parameter name.", lit3)
+				}
+				if contains(params, lit3) {
+					return nil, fmt.Errorf("duplicate lambda parameter %s", lit3)
+				}
+				params = append(params, lit3)
+				tok4, lit4 := p.scanIgnoreWhitespace()
+				if tok4 == ast.RPAREN {
+					break
+				}
+				if tok4 != ast.COMMA {
+					return nil, fmt.Errorf("found %q, expected , or ) in the lambda parameters.", lit4)
+				}
+			}
+			if tok3, lit3 := p.scanIgnoreWhitespace(); tok3 != ast.ARROW {
+				return nil, fmt.Errorf("found %q, expected -> after the lambda parameters.", lit3)
+			}
+		default:
+			p.unscan()
+			p.unscan()
+			p.unscan()
+			return nil, nil
+		}
+	default:
+		p.unscan()
+		return nil, nil
+	}
+	outer := p.lambdaParams
+	p.lambdaParams = append(append([]string{}, outer...), params...)
+	defer func() { p.lambdaParams = outer }()
+	body, err := p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.LambdaExpr{Params: params, Body: body}, nil
+}
+
 func (p *Parser) parseCaseExpr() (*ast.CaseExpr, error) {
 	c := &ast.CaseExpr{}
 	tok, _ := p.scanIgnoreWhitespace()
@@ -1198,6 +1361,33 @@ func validateStream(stmt *ast.StreamStmt) error {
 			return fmt.Errorf("option 'format=%s' is invalid", f)
 		}
 	}
+	return validateComputedFields(stmt.StreamFields)
+}
+
+// validateComputedFields checks the computed fields only refer to the fields defined before them
+func validateComputedFields(fields ast.StreamFields) error {
+	defined := make(map[string]struct{}, len(fields))
+	for _, f := range fields {
+		if f.Computed != nil {
+			if HasAggFuncs(f.Computed.Expr) {
+				return fmt.Errorf("aggregate function is not allowed in computed field %s", f.Name)
+			}
+			var err error
+			ast.WalkFunc(f.Computed.Expr, func(n ast.Node) bool {
+				if fr, ok := n.(*ast.FieldRef); ok && fr.IsColumn() {
+					if _, ok := defined[fr.Name]; !ok {
+						err = fmt.Errorf("computed field %s refers to field %s which is not defined before it", f.Name, fr.Name)
+						return false
+					}
+				}
+				return true
+			})
+			if err != nil {
+				return err
+			}
+		}
+		defined[f.Name] = struct{}{}
+	}
 	return nil
 }
 
@@ -1411,6 +1601,9 @@ func (p *Parser) parseStreamField() (*ast.StreamField, error) {
 		} else if t == ast.UNKNOWN {
 			return nil, fmt.Errorf("found %q, expect valid stream field types(BIGINT | FLOAT | STRING | DATETIME | BOOLEAN | BYTEA | ARRAY | STRUCT).", lit1)
 		}
+		if err := p.parseColumnExpr(field); err != nil {
+			return nil, err
+		}
 
 		if tok2, lit2 := p.scanIgnoreWhitespace(); tok2 == ast.COMMA {
 			// Just consume the comma.
@@ -1425,6 +1618,56 @@ func (p *Parser) parseStreamField() (*ast.StreamField, error) {
 	return field, nil
 }
 
+// parseColumnExpr parses the optional default value like `DEFAULT 0` or computed expression like `AS (a * 2)` of a field
+func (p *Parser) parseColumnExpr(field *ast.StreamField) error {
+	tok, lit := p.scanIgnoreWhitespace()
+	if tok == ast.IDENT && strings.EqualFold(lit, ast.DEFAULT) {
+		ce, err := p.parseRecordedExpr()
+		if err != nil {
+			return err
+		}
+		switch ce.Expr.(type) {
+		case *ast.IntegerLiteral, *ast.NumberLiteral, *ast.StringLiteral, *ast.BooleanLiteral:
+			field.Default = ce
+		default:
+			return fmt.Errorf("found %q, expect literal as the default value of field %s.", ce.Text, field.Name)
+		}
+	} else if tok == ast.AS {
+		if tok1, lit1 := p.scanIgnoreWhitespace(); tok1 != ast.LPAREN {
+			return fmt.Errorf("found %q, expected lparen after AS of field %s.", lit1, field.Name)
+		}
+		ce, err := p.parseRecordedExpr()
+		if err != nil {
+			return err
+		}
+		if tok2, lit2 := p.scanIgnoreWhitespace(); tok2 != ast.RPAREN {
+			return fmt.Errorf("found %q, expected rparen after the expression of field %s.", lit2, field.Name)
+		}
+		field.Computed = ce
+	} else {
+		p.unscan()
+	}
+	return nil
+}
+
+// parseRecordedExpr parses an expression which ends with comma or rparen and keeps its original text
+func (p *Parser) parseRecordedExpr() (*ast.ColumnExpr, error) {
+	p.s.startRecord()
+	expr, err := p.ParseExpr()
+	text := p.s.stopRecord()
+	if err != nil {
+		return nil, err
+	}
+	// The comma or rparen after the expression is scanned ahead and recorded, remove it
+	text = strings.TrimSpace(text)
+	tok, lit := p.scanIgnoreWhitespace()
+	p.unscan()
+	if (tok != ast.COMMA && tok != ast.RPAREN) || len(text) == 0 {
+		return nil, fmt.Errorf("found %q, expect comma or rparen.", lit)
+	}
+	return &ast.ColumnExpr{Expr: expr, Text: strings.TrimSpace(text[:len(text)-1])}, nil
+}
+
 func (p *Parser) parseStreamArrayType() (ast.FieldType, error) {
 	lStack := &stack.Stack{}
 	if tok, _ := p.scanIgnoreWhitespace(); tok == ast.LPAREN {
@@ -1483,6 +1726,11 @@ func (p *Parser) parseStreamStructType() (ast.FieldType, error) {
 	if sfs, err := p.parseStreamFields(); err != nil {
 		return nil, err
 	} else {
+		for _, sf := range sfs {
+			if sf.Default != nil || sf.Computed != nil {
+				return nil, fmt.Errorf("default value or computed expression is not supported for the field %s in struct.", sf.Name)
+			}
+		}
 		if tok2, lit2 := p.scanIgnoreWhitespace(); tok2 == ast.COMMA {
 			rf.StreamFields = sfs
 			p.unscan()
@@ -1530,6 +1778,19 @@ func (p *Parser) parseStreamOptions() (*ast.Options, error) {
 						case ast.KIND:
 							val := strings.ToLower(lit3)
 							opts.KIND = val
+						case ast.LABELS:
+							l, err := labels.Parse(lit3)
+							if err != nil {
+								return nil, fmt.Errorf("found %q, expect key=value pairs in %s option: %v.", lit3, lit1, err)
+							}
+							opts.LABELS = l
+						case ast.VALIDATION:
+							switch val := strings.ToLower(lit3); val {
+							case ast.ValidationCoerce, ast.ValidationDrop, ast.ValidationRoute:
+								opts.VALIDATION = val
+							default:
+								return nil, fmt.Errorf("found %q, expect coerce/drop/route value in %s option.", lit3, lit1)
+							}
 						default:
 							f := v.Elem().FieldByName(lit1)
 							if f.IsValid() {
@@ -1561,6 +1822,9 @@ func (p *Parser) parseStreamOptions() (*ast.Options, error) {
 	if opts.KIND == ast.StreamKindLookup && opts.TYPE == "memory" && opts.KEY == "" {
 		return nil, fmt.Errorf("Option \"key\" is required for memory lookup table.")
 	}
+	if opts.VALIDATION == ast.ValidationRoute && opts.ERROR_TOPIC == "" {
+		return nil, fmt.Errorf("Option \"error_topic\" is required for route validation.")
+	}
 	return opts, nil
 }
 
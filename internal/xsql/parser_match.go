@@ -0,0 +1,301 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xsql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+// parseMatchRecognize parses the row pattern recognition clause like
+//
+//	MATCH_RECOGNIZE (
+//	  PARTITION BY deviceId
+//	  MEASURES A.temperature AS startTemp, avg(B.temperature) AS avgTemp
+//	  AFTER MATCH SKIP PAST LAST ROW
+//	  PATTERN (A B+ C?) WITHIN 5 SS
+//	  DEFINE A AS temperature > 30, B AS B.temperature > A.temperature
+//	)
+func (p *Parser) parseMatchRecognize() (*ast.MatchRecognize, error) {
+	if tok, _ := p.scanIgnoreWhitespace(); tok != ast.MATCH_RECOGNIZE {
+		p.unscan()
+		return nil, nil
+	}
+	if tok, lit := p.scanIgnoreWhitespace(); tok != ast.LPAREN {
+		return nil, fmt.Errorf("found %q, expected ( after MATCH_RECOGNIZE.", lit)
+	}
+	// The pattern variables are referred like stream names, so do not convert them to json path
+	sourceNames := p.sourceNames
+	p.sourceNames = nil
+	defer func() { p.sourceNames = sourceNames }()
+
+	mr := &ast.MatchRecognize{}
+	if tok, _ := p.scanIgnoreWhitespace(); tok == ast.PARTITION {
+		if tok1, lit1 := p.scanIgnoreWhitespace(); tok1 != ast.BY {
+			return nil, fmt.Errorf("found %q, expected BY after PARTITION.", lit1)
+		}
+		for {
+			exp, err := p.ParseExpr()
+			if err != nil {
+				return nil, err
+			}
+			mr.PartitionBy = append(mr.PartitionBy, ast.Dimension{Expr: exp})
+			if tok, _ := p.scanIgnoreWhitespace(); tok != ast.COMMA {
+				p.unscan()
+				break
+			}
+		}
+	} else {
+		p.unscan()
+	}
+	if p.scanKeyword("MEASURES") {
+		for {
+			exp, err := p.ParseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if tok, lit := p.scanIgnoreWhitespace(); tok != ast.AS {
+				return nil, fmt.Errorf("found %q, expected AS for the measure %s.", lit, exp)
+			}
+			tok, lit := p.scanIgnoreWhitespace()
+			if tok != ast.IDENT {
+				return nil, fmt.Errorf("found %q, expected the measure name.", lit)
+			}
+			mr.Measures = append(mr.Measures, ast.Field{Name: lit, AName: lit, Expr: exp})
+			if tok, _ := p.scanIgnoreWhitespace(); tok != ast.COMMA {
+				p.unscan()
+				break
+			}
+		}
+	}
+	if p.scanKeyword("AFTER") {
+		if !p.scanKeyword("MATCH") || !p.scanKeyword("SKIP") {
+			return nil, fmt.Errorf("expected AFTER MATCH SKIP PAST LAST ROW or AFTER MATCH SKIP TO NEXT ROW.")
+		}
+		switch {
+		case p.scanKeyword("PAST"):
+			if !p.scanKeyword("LAST") || !p.scanKeyword("ROW") {
+				return nil, fmt.Errorf("expected AFTER MATCH SKIP PAST LAST ROW.")
+			}
+		case p.scanKeyword("TO"):
+			if !p.scanKeyword("NEXT") || !p.scanKeyword("ROW") {
+				return nil, fmt.Errorf("expected AFTER MATCH SKIP TO NEXT ROW.")
+			}
+			mr.SkipToNextRow = true
+		default:
+			return nil, fmt.Errorf("expected AFTER MATCH SKIP PAST LAST ROW or AFTER MATCH SKIP TO NEXT ROW.")
+		}
+	}
+	if !p.scanKeyword("PATTERN") {
+		_, lit := p.curr()
+		return nil, fmt.Errorf("found %q, expected PATTERN in MATCH_RECOGNIZE.", lit)
+	}
+	pattern, err := p.parsePattern()
+	if err != nil {
+		return nil, err
+	}
+	mr.Pattern = pattern
+	if p.scanKeyword("WITHIN") {
+		tok, lit := p.scanIgnoreWhitespace()
+		if tok != ast.INTEGER {
+			return nil, fmt.Errorf("found %q, expected integer after WITHIN.", lit)
+		}
+		v, _ := strconv.ParseInt(lit, 10, 64)
+		unit, ulit := p.scanIgnoreWhitespace()
+		if !unit.IsTimeLiteral() {
+			return nil, fmt.Errorf("found %q, expected time unit dd, hh, mi, ss or ms after WITHIN.", ulit)
+		}
+		mr.Within = v * timeUnitInMilli(unit)
+		if mr.Within <= 0 {
+			return nil, fmt.Errorf("WITHIN must be a positive duration.")
+		}
+	}
+	if p.scanKeyword("DEFINE") {
+		for {
+			tok, lit := p.scanIgnoreWhitespace()
+			if tok != ast.IDENT {
+				return nil, fmt.Errorf("found %q, expected the pattern variable to define.", lit)
+			}
+			if tok1, lit1 := p.scanIgnoreWhitespace(); tok1 != ast.AS {
+				return nil, fmt.Errorf("found %q, expected AS after the pattern variable %s.", lit1, lit)
+			}
+			exp, err := p.ParseExpr()
+			if err != nil {
+				return nil, err
+			}
+			mr.Defines = append(mr.Defines, &ast.PatternDefine{Name: lit, Condition: exp})
+			if tok, _ := p.scanIgnoreWhitespace(); tok != ast.COMMA {
+				p.unscan()
+				break
+			}
+		}
+	}
+	if tok, lit := p.scanIgnoreWhitespace(); tok != ast.RPAREN {
+		return nil, fmt.Errorf("found %q, expected ) to close MATCH_RECOGNIZE.", lit)
+	}
+	if err := validateMatchRecognize(mr, sourceNames); err != nil {
+		return nil, err
+	}
+	return mr, nil
+}
+
+// scanKeyword consumes the next token if it is the non-reserved keyword
+func (p *Parser) scanKeyword(keyword string) bool {
+	if tok, lit := p.scanIgnoreWhitespace(); tok == ast.IDENT && strings.EqualFold(lit, keyword) {
+		return true
+	}
+	p.unscan()
+	return false
+}
+
+// parsePattern parses the pattern terms inside the parentheses with quantifiers *, +, ?, {n}, {n,}, {,m} and {n,m}
+func (p *Parser) parsePattern() ([]*ast.PatternTerm, error) {
+	if tok, lit := p.scanIgnoreWhitespace(); tok != ast.LPAREN {
+		return nil, fmt.Errorf("found %q, expected ( after PATTERN.", lit)
+	}
+	var terms []*ast.PatternTerm
+	for {
+		tok, lit := p.scanIgnoreWhitespace()
+		if tok == ast.RPAREN {
+			break
+		}
+		if tok != ast.IDENT {
+			return nil, fmt.Errorf("found %q, expected pattern variable.", lit)
+		}
+		term := &ast.PatternTerm{Name: lit, Min: 1, Max: 1}
+		switch q, _ := p.scanIgnoreWhitespace(); q {
+		case ast.ASTERISK:
+			term.Min, term.Max = 0, -1
+		case ast.ADD:
+			term.Max = -1
+		case ast.QUESTION:
+			term.Min = 0
+		case ast.LBRACE:
+			if err := p.parseQuantifierRange(term); err != nil {
+				return nil, err
+			}
+		default:
+			p.unscan()
+		}
+		terms = append(terms, term)
+	}
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("PATTERN must have at least one pattern variable.")
+	}
+	return terms, nil
+}
+
+func (p *Parser) parseQuantifierRange(term *ast.PatternTerm) error {
+	term.Min, term.Max = 0, -1
+	tok, lit := p.scanIgnoreWhitespace()
+	if tok == ast.INTEGER {
+		term.Min, _ = strconv.Atoi(lit)
+		tok, lit = p.scanIgnoreWhitespace()
+		if tok == ast.RBRACE {
+			term.Max = term.Min
+			return nil
+		}
+	}
+	if tok != ast.COMMA {
+		return fmt.Errorf("found %q, expected , in the quantifier of %s.", lit, term.Name)
+	}
+	tok, lit = p.scanIgnoreWhitespace()
+	if tok == ast.INTEGER {
+		term.Max, _ = strconv.Atoi(lit)
+		tok, lit = p.scanIgnoreWhitespace()
+	}
+	if tok != ast.RBRACE {
+		return fmt.Errorf("found %q, expected } to close the quantifier of %s.", lit, term.Name)
+	}
+	if term.Max == 0 || (term.Max > 0 && term.Max < term.Min) {
+		return fmt.Errorf("invalid quantifier of %s, the max must be positive and not less than the min.", term.Name)
+	}
+	return nil
+}
+
+func timeUnitInMilli(unit ast.Token) int64 {
+	switch unit {
+	case ast.DD:
+		return 24 * 3600 * 1000
+	case ast.HH:
+		return 3600 * 1000
+	case ast.MI:
+		return 60 * 1000
+	case ast.SS:
+		return 1000
+	default:
+		return 1
+	}
+}
+
+func validateMatchRecognize(mr *ast.MatchRecognize, sourceNames []string) error {
+	vars := make(map[string]struct{}, len(mr.Pattern))
+	empty := true
+	for _, t := range mr.Pattern {
+		vars[t.Name] = struct{}{}
+		if t.Min > 0 {
+			empty = false
+		}
+	}
+	if empty {
+		return fmt.Errorf("PATTERN cannot match an empty sequence.")
+	}
+	defined := make(map[string]struct{}, len(mr.Defines))
+	for _, d := range mr.Defines {
+		if _, ok := vars[d.Name]; !ok {
+			return fmt.Errorf("pattern variable %s is defined but not used in PATTERN.", d.Name)
+		}
+		if _, ok := defined[d.Name]; ok {
+			return fmt.Errorf("pattern variable %s is defined more than once.", d.Name)
+		}
+		defined[d.Name] = struct{}{}
+	}
+	names := make(map[string]struct{}, len(mr.Measures))
+	for _, m := range mr.Measures {
+		if _, ok := names[m.AName]; ok {
+			return fmt.Errorf("duplicate measure %s.", m.AName)
+		}
+		names[m.AName] = struct{}{}
+	}
+	var err error
+	check := func(n ast.Node) bool {
+		switch e := n.(type) {
+		case *ast.FieldRef:
+			if e.StreamName != ast.DefaultStream {
+				if _, ok := vars[string(e.StreamName)]; !ok && !contains(sourceNames, string(e.StreamName)) {
+					err = fmt.Errorf("unknown pattern variable %s.", e.StreamName)
+				}
+			}
+		case *ast.Window:
+			err = fmt.Errorf("window is not allowed in MATCH_RECOGNIZE.")
+		case *ast.Wildcard:
+			err = fmt.Errorf("wildcard is not allowed in MATCH_RECOGNIZE.")
+		}
+		return err == nil
+	}
+	for _, d := range mr.PartitionBy {
+		ast.WalkFunc(d.Expr, check)
+	}
+	for _, m := range mr.Measures {
+		ast.WalkFunc(m.Expr, check)
+	}
+	for _, d := range mr.Defines {
+		ast.WalkFunc(d.Condition, check)
+	}
+	return err
+}
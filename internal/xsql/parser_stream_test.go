@@ -276,6 +276,53 @@ func TestParser_ParseCreateStream(t *testing.T) {
 			err: `found "SOURCES", unknown option keys(DATASOURCE|FORMAT|KEY|CONF_KEY|SHARED|STRICT_VALIDATION|TYPE|TIMESTAMP|TIMESTAMP_FORMAT|RETAIN_SIZE|SCHEMAID).`,
 		},
 
+		{
+			s: `CREATE STREAM demo (NAME string) WITH (DATASOURCE="users", VALIDATION="Route", ERROR_TOPIC="demoErr");`,
+			stmt: &ast.StreamStmt{
+				Name: "demo",
+				StreamFields: []ast.StreamField{
+					{Name: "NAME", FieldType: &ast.BasicType{Type: ast.STRINGS}},
+				},
+				Options: &ast.Options{
+					DATASOURCE:  "users",
+					VALIDATION:  ast.ValidationRoute,
+					ERROR_TOPIC: "demoErr",
+				},
+			},
+		},
+
+		{
+			s: `CREATE STREAM demo (NAME string) WITH (DATASOURCE="users", LABELS="app=vibration, site=plant3");`,
+			stmt: &ast.StreamStmt{
+				Name: "demo",
+				StreamFields: []ast.StreamField{
+					{Name: "NAME", FieldType: &ast.BasicType{Type: ast.STRINGS}},
+				},
+				Options: &ast.Options{
+					DATASOURCE: "users",
+					LABELS:     map[string]string{"app": "vibration", "site": "plant3"},
+				},
+			},
+		},
+
+		{
+			s:    `CREATE STREAM demo (NAME string) WITH (DATASOURCE="users", LABELS="app");`,
+			stmt: nil,
+			err:  `found "app", expect key=value pairs in LABELS option: invalid label app, it must be key=value.`,
+		},
+
+		{
+			s:    `CREATE STREAM demo (NAME string) WITH (DATASOURCE="users", VALIDATION="route");`,
+			stmt: nil,
+			err:  `Option "error_topic" is required for route validation.`,
+		},
+
+		{
+			s:    `CREATE STREAM demo (NAME string) WITH (DATASOURCE="users", VALIDATION="ignore");`,
+			stmt: nil,
+			err:  `found "ignore", expect coerce/drop/route value in VALIDATION option.`,
+		},
+
 		{
 			s: `CREATE STREAM demo ((NAME string) WITH (DATASOURCE="users", FORMAT="JSON", KEY="USERID");`,
 			stmt: &ast.StreamStmt{
@@ -448,6 +495,64 @@ func TestParser_ParseCreateStream(t *testing.T) {
 				},
 			},
 		},
+		{
+			s: `CREATE STREAM demo (
+					temp float DEFAULT -1,
+					unit string DEFAULT "C",
+					valid boolean DEFAULT true,
+					tempF float AS ( temp * 1.8 + 32 ),
+					loc struct(lat float, lng float)
+				) WITH (DATASOURCE="users");`,
+			stmt: &ast.StreamStmt{
+				Name: ast.StreamName("demo"),
+				StreamFields: []ast.StreamField{
+					{Name: "temp", FieldType: &ast.BasicType{Type: ast.FLOAT}, Default: &ast.ColumnExpr{Expr: &ast.IntegerLiteral{Val: -1}, Text: "-1"}},
+					{Name: "unit", FieldType: &ast.BasicType{Type: ast.STRINGS}, Default: &ast.ColumnExpr{Expr: &ast.StringLiteral{Val: "C"}, Text: `"C"`}},
+					{Name: "valid", FieldType: &ast.BasicType{Type: ast.BOOLEAN}, Default: &ast.ColumnExpr{Expr: &ast.BooleanLiteral{Val: true}, Text: "true"}},
+					{Name: "tempF", FieldType: &ast.BasicType{Type: ast.FLOAT}, Computed: &ast.ColumnExpr{
+						Expr: &ast.BinaryExpr{
+							OP: ast.ADD,
+							LHS: &ast.BinaryExpr{
+								OP:  ast.MUL,
+								LHS: &ast.FieldRef{Name: "temp", StreamName: ast.DefaultStream},
+								RHS: &ast.NumberLiteral{Val: 1.8},
+							},
+							RHS: &ast.IntegerLiteral{Val: 32},
+						},
+						Text: "temp * 1.8 + 32",
+					}},
+					{Name: "loc", FieldType: &ast.RecType{
+						StreamFields: []ast.StreamField{
+							{Name: "lat", FieldType: &ast.BasicType{Type: ast.FLOAT}},
+							{Name: "lng", FieldType: &ast.BasicType{Type: ast.FLOAT}},
+						},
+					}},
+				},
+				Options: &ast.Options{
+					DATASOURCE: "users",
+				},
+			},
+		},
+		{
+			s:   `CREATE STREAM demo (a bigint DEFAULT b) WITH (DATASOURCE="users");`,
+			err: `found "b", expect literal as the default value of field a.`,
+		},
+		{
+			s:   `CREATE STREAM demo (a bigint AS a + 1) WITH (DATASOURCE="users");`,
+			err: `found "a", expected lparen after AS of field a.`,
+		},
+		{
+			s:   `CREATE STREAM demo (a bigint, b bigint AS (c + 1), c bigint) WITH (DATASOURCE="users");`,
+			err: `computed field b refers to field c which is not defined before it`,
+		},
+		{
+			s:   `CREATE STREAM demo (a bigint, b bigint AS (sum(a))) WITH (DATASOURCE="users");`,
+			err: `aggregate function is not allowed in computed field b`,
+		},
+		{
+			s:   `CREATE STREAM demo (loc struct(lat float DEFAULT 0)) WITH (DATASOURCE="users");`,
+			err: `default value or computed expression is not supported for the field lat in struct.`,
+		},
 	}
 
 	fmt.Printf("The test bucket size is %d.\n\n", len(tests))
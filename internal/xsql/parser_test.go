@@ -4540,3 +4540,114 @@ func TestParser_ParseLimit(t *testing.T) {
 		require.Equal(t, tt.stmt, stmt)
 	}
 }
+
+func TestParser_MatchRecognize(t *testing.T) {
+	stmt, err := NewParser(strings.NewReader(`SELECT id, cnt FROM demo MATCH_RECOGNIZE (
+		PARTITION BY id
+		MEASURES count(B.temp) AS cnt
+		AFTER MATCH SKIP TO NEXT ROW
+		PATTERN (A B{2,5} C? D* E{,3} F{2}) WITHIN 2 MI
+		DEFINE B AS B.temp > A.temp, C AS demo.temp > 0
+	) WHERE cnt > 1`)).Parse()
+	require.NoError(t, err)
+	mr := stmt.MatchRecognize
+	require.NotNil(t, mr)
+	assert.Equal(t, ast.Dimensions{{Expr: &ast.FieldRef{Name: "id", StreamName: ast.DefaultStream}}}, mr.PartitionBy)
+	assert.Equal(t, "cnt", mr.Measures[0].AName)
+	assert.Equal(t, &ast.FieldRef{Name: "temp", StreamName: "B"}, mr.Measures[0].Expr.(*ast.Call).Args[0])
+	assert.True(t, mr.SkipToNextRow)
+	assert.Equal(t, "pattern:{ A B{2,5} C? D* E{0,3} F{2} }, within:120000", mr.String())
+	assert.Equal(t, &ast.BinaryExpr{
+		OP:  ast.GT,
+		LHS: &ast.FieldRef{Name: "temp", StreamName: "B"},
+		RHS: &ast.FieldRef{Name: "temp", StreamName: "A"},
+	}, mr.Define("B"))
+	assert.Nil(t, mr.Define("A"))
+	assert.NotNil(t, stmt.Condition)
+
+	errTests := []struct {
+		s   string
+		err string
+	}{
+		{
+			s:   `SELECT * FROM demo MATCH_RECOGNIZE (PATTERN (A B) DEFINE C AS temp > 1)`,
+			err: "pattern variable C is defined but not used in PATTERN.",
+		},
+		{
+			s:   `SELECT * FROM demo MATCH_RECOGNIZE (PATTERN (A? B*))`,
+			err: "PATTERN cannot match an empty sequence.",
+		},
+		{
+			s:   `SELECT * FROM demo MATCH_RECOGNIZE (MEASURES X.temp AS t PATTERN (A))`,
+			err: "unknown pattern variable X.",
+		},
+		{
+			s:   `SELECT * FROM demo MATCH_RECOGNIZE (MEASURES A.temp PATTERN (A))`,
+			err: "found \"PATTERN\", expected AS for the measure A.temp.",
+		},
+		{
+			s:   `SELECT * FROM demo MATCH_RECOGNIZE (PATTERN (A{3,2}))`,
+			err: "invalid quantifier of A, the max must be positive and not less than the min.",
+		},
+		{
+			s:   `SELECT * FROM demo INNER JOIN demo2 ON demo.id = demo2.id MATCH_RECOGNIZE (PATTERN (A))`,
+			err: "MATCH_RECOGNIZE cannot be used with JOIN.",
+		},
+	}
+	for _, tt := range errTests {
+		_, err := NewParser(strings.NewReader(tt.s)).Parse()
+		assert.EqualError(t, err, tt.err, tt.s)
+	}
+}
+
+func TestParser_Unnest(t *testing.T) {
+	stmt, err := NewParser(strings.NewReader(`SELECT id, name FROM demo CROSS JOIN UNNEST(demo.items) AS item WHERE price > 10`)).Parse()
+	require.NoError(t, err)
+	require.NotNil(t, stmt.Unnest)
+	assert.Equal(t, &ast.FieldRef{Name: "items", StreamName: "demo"}, stmt.Unnest.Expr)
+	assert.Equal(t, "item", stmt.Unnest.Name())
+	assert.Equal(t, "UNNEST(demo.items) AS item", stmt.Unnest.String())
+	assert.NotNil(t, stmt.Condition)
+
+	stmt, err = NewParser(strings.NewReader(`SELECT * FROM demo cross join unnest(obj->arr) GROUP BY TumblingWindow(ss, 10)`)).Parse()
+	require.NoError(t, err)
+	require.NotNil(t, stmt.Unnest)
+	assert.Equal(t, ast.DefaultUnnestName, stmt.Unnest.Name())
+	assert.Equal(t, "UNNEST(binaryExpr:{ $$default.obj -> jsonFieldName:arr })", stmt.Unnest.String())
+	assert.Nil(t, stmt.Joins)
+
+	stmt, err = NewParser(strings.NewReader(`SELECT * FROM demo CROSS JOIN demo2`)).Parse()
+	require.NoError(t, err)
+	assert.Nil(t, stmt.Unnest)
+	assert.Len(t, stmt.Joins, 1)
+
+	errTests := []struct {
+		s   string
+		err string
+	}{
+		{
+			s:   `SELECT * FROM demo CROSS JOIN UNNEST items`,
+			err: "found \"items\", expected ( after UNNEST.",
+		},
+		{
+			s:   `SELECT * FROM demo CROSS JOIN UNNEST(items AS item`,
+			err: "found \"AS\", expected ) for UNNEST.",
+		},
+		{
+			s:   `SELECT * FROM demo CROSS JOIN UNNEST(items) AS 1`,
+			err: "found \"1\", expected alias name for UNNEST.",
+		},
+		{
+			s:   `SELECT * FROM demo CROSS JOIN UNNEST(items) INNER JOIN demo2 ON demo.id = demo2.id`,
+			err: "UNNEST cannot be used with JOIN.",
+		},
+		{
+			s:   `SELECT * FROM demo CROSS JOIN UNNEST(items) MATCH_RECOGNIZE (PATTERN (A))`,
+			err: "MATCH_RECOGNIZE cannot be used with UNNEST.",
+		},
+	}
+	for _, tt := range errTests {
+		_, err := NewParser(strings.NewReader(tt.s)).Parse()
+		assert.EqualError(t, err, tt.err, tt.s)
+	}
+}
@@ -15,6 +15,7 @@
 package xsql
 
 import (
+	"context"
 	"strings"
 	"sync"
 
@@ -244,6 +245,7 @@ type Tuple struct {
 	Metadata  Metadata // immutable
 
 	AffiliateRow
+	traceCtx  context.Context        // the trace context of the span which produces it, nil if not traced
 	lock      sync.Mutex             // lock for the cachedMap, because it is possible to access by multiple sinks
 	cachedMap map[string]interface{} // clone of the row and cached for performance
 }
@@ -269,6 +271,7 @@ func (t *WatermarkTuple) IsWatermark() bool {
 type JoinTuple struct {
 	Tuples []Row // The content is immutable, but the slice may be added or removed
 	AffiliateRow
+	traceCtx  context.Context // the trace context of the span which produces it, nil if not traced
 	lock      sync.Mutex
 	cachedMap map[string]interface{} // clone of the row and cached for performance of toMap
 }
@@ -284,6 +287,7 @@ type GroupedTuples struct {
 	Content []Row
 	*WindowRange
 	AffiliateRow
+	traceCtx  context.Context // the trace context of the span which produces it, nil if not traced
 	lock      sync.Mutex
 	cachedMap map[string]interface{} // clone of the row and cached for performance of toMap
 }
@@ -376,6 +380,7 @@ func (t *Tuple) Clone() Row {
 		Raw:          t.Raw,
 		Metadata:     t.Metadata,
 		AffiliateRow: t.AffiliateRow.Clone(),
+		traceCtx:     t.traceCtx,
 	}
 }
 
@@ -440,7 +445,7 @@ func (t *Tuple) Pick(allWildcard bool, cols [][]string, wildcardEmitters map[str
 	}
 	if !allWildcard {
 		if len(cols) > 0 {
-			pickedMap := make(map[string]interface{})
+			pickedMap := make(map[string]interface{}, len(cols))
 			for _, colTab := range cols {
 				if colTab[1] == "" || colTab[1] == string(ast.DefaultStream) || colTab[1] == t.Emitter {
 					if v, ok := t.Message.Value(colTab[0], colTab[1]); ok {
@@ -455,7 +460,7 @@ func (t *Tuple) Pick(allWildcard bool, cols [][]string, wildcardEmitters map[str
 			t.cachedMap = nil
 		}
 	} else if len(except) > 0 {
-		pickedMap := make(map[string]interface{})
+		pickedMap := make(map[string]interface{}, len(t.Message))
 		for key, mess := range t.Message {
 			if !contains(except, key) {
 				pickedMap[key] = mess
@@ -552,6 +557,7 @@ func (jt *JoinTuple) Clone() Row {
 	c := &JoinTuple{
 		Tuples:       ts,
 		AffiliateRow: jt.AffiliateRow.Clone(),
+		traceCtx:     jt.traceCtx,
 	}
 	return c
 }
@@ -596,10 +602,18 @@ func (jt *JoinTuple) Pick(allWildcard bool, cols [][]string, wildcardEmitters ma
 // GroupedTuple implementation
 
 func (s *GroupedTuples) AggregateEval(expr ast.Expr, v CallValuer) []interface{} {
-	var result []interface{}
+	if len(s.Content) == 0 {
+		return nil
+	}
+	result := make([]interface{}, 0, len(s.Content))
+	// The valuers are not referred after the evaluation, so they are reused for all rows
+	wv := &WildcardValuer{}
+	ve := GetValuerEval(nil, &WindowRangeValuer{WindowRange: s.WindowRange}, v, wv)
 	for _, t := range s.Content {
-		result = append(result, Eval(expr, MultiValuer(t, &WindowRangeValuer{WindowRange: s.WindowRange}, v, &WildcardValuer{t})))
+		ve.valuers[0], wv.Data = t, t
+		result = append(result, ve.Eval(expr))
 	}
+	PutValuerEval(ve)
 	return result
 }
 
@@ -642,6 +656,7 @@ func (s *GroupedTuples) Clone() Row {
 		Content:      ts,
 		WindowRange:  s.WindowRange,
 		AffiliateRow: s.AffiliateRow.Clone(),
+		traceCtx:     s.traceCtx,
 	}
 	return c
 }
@@ -1,4 +1,4 @@
-// Copyright 2021-2023 EMQ Technologies Co., Ltd.
+// Copyright 2021-2024 EMQ Technologies Co., Ltd.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -152,6 +152,9 @@ func validateFields(stmt *ast.SelectStatement, streamNames []string) {
 	for i, join := range stmt.Joins {
 		stmt.Joins[i].Expr = validateExpr(join.Expr, streamNames)
 	}
+	if stmt.Unnest != nil {
+		stmt.Unnest.Expr = validateExpr(stmt.Unnest.Expr, streamNames)
+	}
 }
 
 // validateExpr checks if the streamName of a fieldRef is existed and covert it to json filed if not exist.
@@ -218,6 +221,9 @@ func validateExpr(expr ast.Expr, streamName []string) ast.Expr {
 	case *ast.LikePattern:
 		e.Expr = validateExpr(e.Expr, streamName)
 		return e
+	case *ast.LambdaExpr:
+		e.Body = validateExpr(e.Body, streamName)
+		return e
 	case *ast.FieldRef:
 		sn := string(expr.(*ast.FieldRef).StreamName)
 		if sn != string(ast.DefaultStream) && !contains(streamName, sn) {
@@ -0,0 +1,82 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xsql
+
+import "context"
+
+// TraceCarrier is the data which carries the trace context of the span of the operator which produces it.
+// The downstream operators start their spans as the children of it.
+// The trace context is kept in an unexported field, so it is not saved into the states.
+type TraceCarrier interface {
+	GetTracerCtx() context.Context
+	SetTracerCtx(ctx context.Context)
+}
+
+var (
+	_ TraceCarrier = &Tuple{}
+	_ TraceCarrier = &JoinTuple{}
+	_ TraceCarrier = &GroupedTuples{}
+	_ TraceCarrier = &WindowTuples{}
+	_ TraceCarrier = &JoinTuples{}
+	_ TraceCarrier = &GroupedTuplesSet{}
+)
+
+func (t *Tuple) GetTracerCtx() context.Context {
+	return t.traceCtx
+}
+
+func (t *Tuple) SetTracerCtx(ctx context.Context) {
+	t.traceCtx = ctx
+}
+
+func (jt *JoinTuple) GetTracerCtx() context.Context {
+	return jt.traceCtx
+}
+
+func (jt *JoinTuple) SetTracerCtx(ctx context.Context) {
+	jt.traceCtx = ctx
+}
+
+func (s *GroupedTuples) GetTracerCtx() context.Context {
+	return s.traceCtx
+}
+
+func (s *GroupedTuples) SetTracerCtx(ctx context.Context) {
+	s.traceCtx = ctx
+}
+
+func (w *WindowTuples) GetTracerCtx() context.Context {
+	return w.traceCtx
+}
+
+func (w *WindowTuples) SetTracerCtx(ctx context.Context) {
+	w.traceCtx = ctx
+}
+
+func (s *JoinTuples) GetTracerCtx() context.Context {
+	return s.traceCtx
+}
+
+func (s *JoinTuples) SetTracerCtx(ctx context.Context) {
+	s.traceCtx = ctx
+}
+
+func (s *GroupedTuplesSet) GetTracerCtx() context.Context {
+	return s.traceCtx
+}
+
+func (s *GroupedTuplesSet) SetTracerCtx(ctx context.Context) {
+	s.traceCtx = ctx
+}
@@ -1,4 +1,4 @@
-// Copyright 2022-2023 EMQ Technologies Co., Ltd.
+// Copyright 2022-2024 EMQ Technologies Co., Ltd.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -29,9 +29,10 @@ import (
 var (
 	// implicitValueFuncs is a set of functions that event implicitly passes the value.
 	implicitValueFuncs = map[string]bool{
-		"window_start": true,
-		"window_end":   true,
-		"event_time":   true,
+		"window_start":   true,
+		"window_end":     true,
+		"window_partial": true,
+		"event_time":     true,
 	}
 	// ImplicitStateFuncs is a set of functions that read/update global state implicitly.
 	ImplicitStateFuncs = map[string]bool{
@@ -242,6 +243,11 @@ type ValuerEval struct {
 	// IntegerFloatDivision will set the eval system to treat
 	// a division between two integers as a floating point division.
 	IntegerFloatDivision bool
+
+	// lambdaScope is the values of the lambda parameters when evaluating a lambda body
+	lambdaScope map[string]interface{}
+	// valuers is the reused buffer of the pooled evaluator
+	valuers multiValuer
 }
 
 // Eval evaluates an expression and returns a value.
@@ -288,6 +294,12 @@ func (v *ValuerEval) Eval(expr ast.Expr) interface{} {
 			// nil is also cached
 			return val
 		}
+		// The aggregate functions calculated incrementally by the window are cached if the window can provide the result
+		if expr.FuncType == ast.FuncTypeAgg && expr.CachedField != "" {
+			if val, ok := v.Valuer.Value(expr.CachedField, ""); ok {
+				return val
+			}
+		}
 		if _, ok := implicitValueFuncs[expr.Name]; ok {
 			if vv, ok := v.Valuer.(FuncValuer); ok {
 				val, ok := vv.FuncValue(expr.Name)
@@ -442,6 +454,10 @@ func (v *ValuerEval) Eval(expr ast.Expr) interface{} {
 			}
 		}
 		return nil
+	case *ast.LambdaExpr:
+		return v.evalLambda(expr)
+	case *ast.LambdaParamRef:
+		return v.lambdaScope[expr.Name]
 	case *ast.MetaRef:
 		if expr.StreamName == "" || expr.StreamName == ast.DefaultStream {
 			val, _ := v.Valuer.Meta(expr.Name, "")
@@ -505,6 +521,24 @@ func (v *ValuerEval) Eval(expr ast.Expr) interface{} {
 	}
 }
 
+// evalLambda returns the function to evaluate the lambda body. The parameters are bound in a new scope which inherits
+// the parameters of the enclosing lambdas.
+func (v *ValuerEval) evalLambda(expr *ast.LambdaExpr) function.Lambda {
+	return func(args []interface{}) interface{} {
+		scope := make(map[string]interface{}, len(v.lambdaScope)+len(expr.Params))
+		for k, val := range v.lambdaScope {
+			scope[k] = val
+		}
+		for i, p := range expr.Params {
+			if i < len(args) {
+				scope[p] = args[i]
+			}
+		}
+		ve := &ValuerEval{Valuer: v.Valuer, IntegerFloatDivision: v.IntegerFloatDivision, lambdaScope: scope}
+		return ve.Eval(expr.Body)
+	}
+}
+
 func (v *ValuerEval) evalBinaryExpr(expr *ast.BinaryExpr) interface{} {
 	lhs := v.Eval(expr.LHS)
 	switch val := lhs.(type) {
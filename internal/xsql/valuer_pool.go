@@ -0,0 +1,44 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xsql
+
+import "sync"
+
+// evalPool reuses the evaluators which the operators create for each row
+var evalPool = sync.Pool{
+	New: func() any {
+		return &ValuerEval{}
+	},
+}
+
+// GetValuerEval returns an evaluator of the valuers from the pool. It is the same as
+// &ValuerEval{Valuer: MultiValuer(valuers...)} but does not allocate for each row.
+// Call PutValuerEval once the evaluation is done, and do not refer to the evaluator after that.
+func GetValuerEval(valuers ...Valuer) *ValuerEval {
+	ve := evalPool.Get().(*ValuerEval)
+	ve.valuers = append(ve.valuers[:0], valuers...)
+	ve.Valuer = &ve.valuers
+	return ve
+}
+
+// PutValuerEval resets the evaluator and puts it back to the pool
+func PutValuerEval(ve *ValuerEval) {
+	clear(ve.valuers)
+	ve.valuers = ve.valuers[:0]
+	ve.Valuer = nil
+	ve.IntegerFloatDivision = false
+	ve.lambdaScope = nil
+	evalPool.Put(ve)
+}
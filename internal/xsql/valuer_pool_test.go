@@ -0,0 +1,41 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xsql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lf-edge/ekuiper/pkg/ast"
+)
+
+func TestValuerEvalPool(t *testing.T) {
+	expr := &ast.BinaryExpr{OP: ast.ADD, LHS: &ast.FieldRef{Name: "a", StreamName: ast.DefaultStream}, RHS: &ast.FieldRef{Name: "b", StreamName: ast.DefaultStream}}
+	rows := []*Tuple{
+		{Emitter: "test", Message: map[string]interface{}{"a": int64(1), "b": int64(2)}},
+		{Emitter: "test", Message: map[string]interface{}{"a": int64(3), "b": int64(4)}},
+		// b is missing, must not be read from the previous row
+		{Emitter: "test", Message: map[string]interface{}{"a": int64(5)}},
+	}
+	for _, r := range rows {
+		ve := GetValuerEval(r, &FunctionValuer{})
+		expected := (&ValuerEval{Valuer: MultiValuer(r, &FunctionValuer{})}).Eval(expr)
+		assert.Equal(t, expected, ve.Eval(expr))
+		PutValuerEval(ve)
+		assert.Nil(t, ve.Valuer)
+		assert.Empty(t, ve.valuers)
+	}
+}
@@ -152,6 +152,30 @@ type ResendSink interface {
 	CollectResend(ctx StreamContext, data interface{}) error
 }
 
+// WriteToken identifies a write of the sink. Seq is the sequence of the result since the rule is created and Index is
+// the index of the write in the result when the result is sent by rows or split. Seq is saved in the checkpoint, so the
+// results replayed after recovering from the checkpoint are written with the same Seq and Index and the sink can skip
+// the writes which are already done. Epoch is the id of the last checkpoint before the result which can be used to
+// group the writes, such as to commit a transaction. Notice that Epoch may differ for the replayed results.
+type WriteToken struct {
+	Epoch int64 `json:"epoch"`
+	Seq   int64 `json:"seq"`
+	Index int   `json:"index"`
+}
+
+// Before reports whether the write of the token is done before the write of the other token
+func (t WriteToken) Before(o WriteToken) bool {
+	return t.Seq < o.Seq || (t.Seq == o.Seq && t.Index < o.Index)
+}
+
+// IdempotentSink is implemented by the sinks which can deduplicate the writes, such as by a transactional producer
+// or by upsert keys. When the qos of the rule is at least once and the sink cache is disabled,
+// CollectWithToken is called instead of Collect.
+type IdempotentSink interface {
+	Sink
+	CollectWithToken(ctx StreamContext, data interface{}, token WriteToken) error
+}
+
 type Emitter interface {
 	AddOutput(chan<- interface{}, string) error
 }
@@ -170,21 +194,85 @@ type Rewindable interface {
 	ResetOffset(input map[string]interface{}) error
 }
 
+// PartitionSource is implemented by the sources which can read a single partition of the data source, such as a
+// partition of a Kafka topic. The rule in partitioned mode runs an instance for each partition and each instance
+// reads one partition by the source.
+type PartitionSource interface {
+	// SetPartition is called before Configure to read the partition in [0, partitions) only
+	SetPartition(partition int, partitions int) error
+}
+
+// OffsetCommitter is implemented by the rewindable sources which can commit the consumed position
+// to the external system, such as the consumer group offset of Kafka. When the rule enables ack,
+// the offset got by GetOffset is only committed when a checkpoint completes, that is after all the
+// sinks have received the data before the checkpoint barrier.
+type OffsetCommitter interface {
+	CommitOffset(ctx StreamContext, offset interface{}) error
+}
+
+// OffsetTuple is implemented by the source tuples which carry the offset to commit after the tuple is delivered.
+// A source which reads ahead should set it, otherwise the offset got by GetOffset may cover the data not emitted yet.
+type OffsetTuple interface {
+	Offset() interface{}
+}
+
 type RuleOption struct {
-	Debug              bool             `json:"debug" yaml:"debug"`
-	LogFilename        string           `json:"logFilename" yaml:"logFilename"`
-	IsEventTime        bool             `json:"isEventTime" yaml:"isEventTime"`
-	LateTol            int64            `json:"lateTolerance" yaml:"lateTolerance"`
-	Concurrency        int              `json:"concurrency" yaml:"concurrency"`
-	BufferLength       int              `json:"bufferLength" yaml:"bufferLength"`
-	SendMetaToSink     bool             `json:"sendMetaToSink" yaml:"sendMetaToSink"`
-	SendError          bool             `json:"sendError" yaml:"sendError"`
-	Qos                Qos              `json:"qos" yaml:"qos"`
-	CheckpointInterval int              `json:"checkpointInterval" yaml:"checkpointInterval"`
-	RestartStrategy    *RestartStrategy `json:"restartStrategy" yaml:"restartStrategy"`
-	Cron               string           `json:"cron" yaml:"cron"`
-	Duration           string           `json:"duration" yaml:"duration"`
-	CronDatetimeRange  []DatetimeRange  `json:"cronDatetimeRange" yaml:"cronDatetimeRange"`
+	Debug                  bool             `json:"debug" yaml:"debug"`
+	LogFilename            string           `json:"logFilename" yaml:"logFilename"`
+	IsEventTime            bool             `json:"isEventTime" yaml:"isEventTime"`
+	LateTol                int64            `json:"lateTolerance" yaml:"lateTolerance"`
+	AllowedLateness        int64            `json:"allowedLateness" yaml:"allowedLateness"`
+	LateDataTopic          string           `json:"lateDataTopic" yaml:"lateDataTopic"`
+	Concurrency            int              `json:"concurrency" yaml:"concurrency"`
+	BufferLength           int              `json:"bufferLength" yaml:"bufferLength"`
+	SendMetaToSink         bool             `json:"sendMetaToSink" yaml:"sendMetaToSink"`
+	SendError              bool             `json:"sendError" yaml:"sendError"`
+	Qos                    Qos              `json:"qos" yaml:"qos"`
+	CheckpointInterval     int              `json:"checkpointInterval" yaml:"checkpointInterval"`
+	FullCheckpointInterval int              `json:"fullCheckpointInterval" yaml:"fullCheckpointInterval"`
+	EnableAck              bool             `json:"enableAck" yaml:"enableAck"`
+	EarlyFireInterval      int64            `json:"earlyFireInterval" yaml:"earlyFireInterval"`
+	EarlyFireOnElement     bool             `json:"earlyFireOnElement" yaml:"earlyFireOnElement"`
+	RestartStrategy        *RestartStrategy `json:"restartStrategy" yaml:"restartStrategy"`
+	Cron                   string           `json:"cron" yaml:"cron"`
+	Duration               string           `json:"duration" yaml:"duration"`
+	CronDatetimeRange      []DatetimeRange  `json:"cronDatetimeRange" yaml:"cronDatetimeRange"`
+	WindowMemoryLimit      int64            `json:"windowMemoryLimit" yaml:"windowMemoryLimit"`
+	StateStore             string           `json:"stateStore" yaml:"stateStore"`
+	TraceSampleRate        float64          `json:"traceSampleRate" yaml:"traceSampleRate"`
+	Quota                  *RuleQuota       `json:"quota,omitempty" yaml:"quota,omitempty"`
+	Clock                  *RuleClock       `json:"clock,omitempty" yaml:"clock,omitempty"`
+	// MicroBatchSize is the max count of the queued tuples an operator processes at a time when the queue grows
+	MicroBatchSize int `json:"microBatchSize,omitempty" yaml:"microBatchSize,omitempty"`
+	// Parallelism is the parallelism of the operators by the operator name
+	Parallelism map[string]*OperatorParallelism `json:"parallelism,omitempty" yaml:"parallelism,omitempty"`
+	// Timezone is the IANA timezone to evaluate the cron and the datetime strings of the cronDatetimeRange.
+	// If not set, the cron uses the local timezone and the datetime strings use the configured timezone.
+	Timezone string `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+	// RunOnce runs the rule as a batch job. The rule stops after its bounded sources finish reading and the sinks
+	// send out all the results.
+	RunOnce bool `json:"runOnce,omitempty" yaml:"runOnce,omitempty"`
+	// Priority is the importance of the rule from 0 to MaxRulePriority. When the host is cpu saturated, the rules of
+	// lower priority are throttled first.
+	Priority int `json:"priority,omitempty" yaml:"priority,omitempty"`
+	// NodeSelector constrains the rule to run on the cluster nodes which have all the labels
+	NodeSelector map[string]string `json:"nodeSelector,omitempty" yaml:"nodeSelector,omitempty"`
+	// Partitions runs the rule in partitioned mode by the count of the partitions of the source. Each partition runs
+	// as an instance with its own state, and the instances are placed across the cluster nodes.
+	Partitions int `json:"partitions,omitempty" yaml:"partitions,omitempty"`
+	// Partition is the partition to read by the rule instance in partitioned mode. It is set at runtime.
+	Partition int `json:"-" yaml:"-"`
+}
+
+// MaxRulePriority is the highest priority of a rule
+const MaxRulePriority = 9
+
+// OperatorParallelism is the count of the instances to run an operator concurrently
+type OperatorParallelism struct {
+	Concurrency int `json:"concurrency" yaml:"concurrency"`
+	// PartitionBy is the expression to distribute the rows to the instances. The rows of the same key are processed
+	// by the same instance in order. If not set, the rows are distributed in turn and the outputs keep the input order.
+	PartitionBy string `json:"partitionBy,omitempty" yaml:"partitionBy,omitempty"`
 }
 
 type DatetimeRange struct {
@@ -200,6 +288,51 @@ type RestartStrategy struct {
 	Multiplier   float64 `json:"multiplier" yaml:"multiplier"`
 	MaxDelay     int     `json:"maxDelay" yaml:"maxDelay"`
 	JitterFactor float64 `json:"jitterFactor" yaml:"jitterFactor"`
+	// ResetWindow is the milliseconds the rule runs without failure after a restart to reset the attempts and the delay.
+	// 0 means never reset.
+	ResetWindow int `json:"resetWindow,omitempty" yaml:"resetWindow,omitempty"`
+}
+
+// The modes of the clock of a rule
+const (
+	// ClockWall is the system clock
+	ClockWall = "wall"
+	// ClockAccelerated runs faster than the system clock by the speed
+	ClockAccelerated = "accelerated"
+	// ClockEvent advances by the timestamps of the data received by the sources
+	ClockEvent = "event"
+)
+
+// RuleClock is the clock to trigger the time windows and the timers of a rule, so that the rule with long windows
+// can be tested in a short time.
+type RuleClock struct {
+	Mode string `json:"mode" yaml:"mode"`
+	// Speed is the multiple of the system clock for the accelerated mode
+	Speed float64 `json:"speed" yaml:"speed"`
+	// Start is the start time in milliseconds of the virtual clock. By default, the accelerated clock starts at
+	// the current time and the event clock starts at the first timestamp.
+	Start int64 `json:"start" yaml:"start"`
+}
+
+// The policies when a rule exceeds the quota
+const (
+	// QuotaPause stops the sources from reading until the rule is under the quota
+	QuotaPause = "pause"
+	// QuotaDrop drops the incoming data until the rule is under the quota
+	QuotaDrop = "drop"
+	// QuotaError fails the rule
+	QuotaError = "error"
+)
+
+// RuleQuota limits the resources used by a rule. The zero value of a limit means no limit.
+type RuleQuota struct {
+	// MaxBufferedTuples is the max count of the tuples buffered in the queues between the nodes
+	MaxBufferedTuples int `json:"maxBufferedTuples" yaml:"maxBufferedTuples"`
+	// MaxStateSize is the max size in bytes of the encoded states, which is measured at each checkpoint
+	MaxStateSize int64 `json:"maxStateSize" yaml:"maxStateSize"`
+	// CpuShare is the cpu cores the operators can use, such as 0.5 for half a core
+	CpuShare float64 `json:"cpuShare" yaml:"cpuShare"`
+	Policy   string  `json:"policy" yaml:"policy"`
 }
 
 type PrintableTopo struct {
@@ -237,6 +370,8 @@ type Rule struct {
 	Graph     *RuleGraph               `json:"graph,omitempty"`
 	Actions   []map[string]interface{} `json:"actions,omitempty"`
 	Options   *RuleOption              `json:"options,omitempty"`
+	// Labels are the user defined key values to select the rules such as in the bulk operations
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 func (r *Rule) IsLongRunningScheduleRule() bool {
@@ -258,14 +393,15 @@ func GetDefaultRule(name, sql string) *Rule {
 		Id:  name,
 		Sql: sql,
 		Options: &RuleOption{
-			IsEventTime:        false,
-			LateTol:            1000,
-			Concurrency:        1,
-			BufferLength:       1024,
-			SendMetaToSink:     false,
-			SendError:          true,
-			Qos:                AtMostOnce,
-			CheckpointInterval: 300000,
+			IsEventTime:            false,
+			LateTol:                1000,
+			Concurrency:            1,
+			BufferLength:           1024,
+			SendMetaToSink:         false,
+			SendError:              true,
+			Qos:                    AtMostOnce,
+			CheckpointInterval:     300000,
+			FullCheckpointInterval: 10,
 			RestartStrategy: &RestartStrategy{
 				Attempts:     0,
 				Delay:        1000,
@@ -323,6 +459,17 @@ type FunctionContext interface {
 	GetFuncId() int
 }
 
+// KeyedStateContext is an optional interface of the FunctionContext to keep the state per key. The function asserts
+// its context to it before using the keyed states.
+type KeyedStateContext interface {
+	// GetKeyedState returns the state of the key such as a device id. Return nil if not found or expired.
+	GetKeyedState(key string) (interface{}, error)
+	// PutKeyedState saves the state of the key which expires after the ttl. The state never expires if the ttl is not positive.
+	// The keyed states are saved in the rule state and covered by the checkpoints.
+	PutKeyedState(key string, value interface{}, ttl time.Duration) error
+	DeleteKeyedState(key string) error
+}
+
 type Function interface {
 	// Validate The argument is a list of xsql.Expr
 	Validate(args []interface{}) error
@@ -333,6 +480,12 @@ type Function interface {
 	IsAggregate() bool
 }
 
+// FunctionStatus is an optional interface for the function plugins to report their runtime status such as the loaded
+// resources. The status is exposed by the rest api.
+type FunctionStatus interface {
+	Status() interface{}
+}
+
 const (
 	AtMostOnce Qos = iota
 	AtLeastOnce
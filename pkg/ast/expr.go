@@ -1,4 +1,4 @@
-// Copyright 2022-2023 EMQ Technologies Co., Ltd.
+// Copyright 2022-2024 EMQ Technologies Co., Ltd.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -18,6 +18,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 type Node interface {
@@ -454,6 +455,33 @@ func (fr *JsonFieldRef) String() string {
 	return "jsonFieldName:" + fr.Name
 }
 
+// LambdaExpr is the lambda argument of the higher order functions such as x -> x * 2 or (acc, x) -> acc + x
+type LambdaExpr struct {
+	Params []string
+	Body   Expr
+}
+
+func (le *LambdaExpr) expr() {}
+func (le *LambdaExpr) node() {}
+func (le *LambdaExpr) String() string {
+	b := ""
+	if le.Body != nil {
+		b += ", body:{ " + le.Body.String() + " }"
+	}
+	return "lambdaExpr:{ params:[" + strings.Join(le.Params, ", ") + "]" + b + " }"
+}
+
+// LambdaParamRef refers to a parameter of the enclosing lambda expression
+type LambdaParamRef struct {
+	Name string
+}
+
+func (lr *LambdaParamRef) expr() {}
+func (lr *LambdaParamRef) node() {}
+func (lr *LambdaParamRef) String() string {
+	return "lambdaParam:" + lr.Name
+}
+
 type ColFuncField struct {
 	Name string
 	Expr Expr
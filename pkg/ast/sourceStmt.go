@@ -32,6 +32,13 @@ var StreamTypeMap = map[StreamType]string{
 const (
 	StreamKindLookup = "lookup"
 	StreamKindScan   = "scan"
+
+	// ValidationCoerce converts the values to the declared types if possible, otherwise set them to null
+	ValidationCoerce = "coerce"
+	// ValidationDrop drops the nonconforming messages
+	ValidationDrop = "drop"
+	// ValidationRoute sends the nonconforming messages with the validation errors to the error topic
+	ValidationRoute = "route"
 )
 
 type StreamType int
@@ -48,6 +55,16 @@ type StreamStmt struct {
 type StreamField struct {
 	Name string
 	FieldType
+	// Default is the value to fill when the field is missing or null in the message
+	Default *ColumnExpr
+	// Computed is the expression to calculate the field from the other fields when ingesting
+	Computed *ColumnExpr
+}
+
+// ColumnExpr is the expression in the field definition along with its original text to print the definition back
+type ColumnExpr struct {
+	Expr Expr
+	Text string
 }
 
 type JsonStreamField struct {
@@ -237,6 +254,12 @@ type Options struct {
 	KIND string `json:"kind,omitempty"`
 	// for delimited format only
 	DELIMITER string `json:"delimiter,omitempty"`
+	// VALIDATION is the mode to handle the messages which do not conform to the stream schema
+	VALIDATION string `json:"validation,omitempty"`
+	// ERROR_TOPIC is the memory topic to route the nonconforming messages for the route validation
+	ERROR_TOPIC string `json:"errorTopic,omitempty"`
+	// LABELS are the user defined key values to select the streams and tables
+	LABELS map[string]string `json:"labels,omitempty"`
 
 	RuleID       string                      `json:"-"`
 	Schema       map[string]*JsonStreamField `json:"-"`
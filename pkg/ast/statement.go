@@ -14,7 +14,10 @@
 
 package ast
 
-import "strconv"
+import (
+	"strconv"
+	"strings"
+)
 
 type Statement interface {
 	stmt()
@@ -30,6 +33,10 @@ type SelectStatement struct {
 	Dimensions Dimensions
 	Having     Expr
 	SortFields SortFields
+	// MatchRecognize is the row pattern recognition clause following the source
+	MatchRecognize *MatchRecognize
+	// Unnest explodes an array of the source into multiple rows before the other clauses
+	Unnest *Unnest
 
 	Statement
 }
@@ -252,6 +259,103 @@ func (wd *Window) String() string {
 
 type SortFields []SortField
 
+// DefaultUnnestName is the column name of the non-object elements if no alias is specified for UNNEST
+const DefaultUnnestName = "unnest"
+
+// Unnest explodes the array evaluated by the expression into one row per element. The object element fields are
+// merged into the row, the other elements are set to the column of the alias.
+type Unnest struct {
+	Expr  Expr
+	Alias string
+
+	Node
+}
+
+func (u *Unnest) String() string {
+	r := "UNNEST(" + u.Expr.String() + ")"
+	if u.Alias != "" {
+		r += " AS " + u.Alias
+	}
+	return r
+}
+
+// Name returns the column name of the non-object elements
+func (u *Unnest) Name() string {
+	if u.Alias != "" {
+		return u.Alias
+	}
+	return DefaultUnnestName
+}
+
+// MatchRecognize detects the row pattern in each partition and outputs one row per match
+type MatchRecognize struct {
+	PartitionBy Dimensions
+	Measures    Fields
+	// SkipToNextRow resumes the matching from the row after the first row of the last match.
+	// By default, the matching resumes from the row after the last row of the last match.
+	SkipToNextRow bool
+	Pattern       []*PatternTerm
+	// Within is the max duration in milliseconds between the first row and the last row of a match. 0 means no limit
+	Within  int64
+	Defines []*PatternDefine
+
+	Node
+}
+
+func (m *MatchRecognize) String() string {
+	terms := make([]string, len(m.Pattern))
+	for i, t := range m.Pattern {
+		terms[i] = t.String()
+	}
+	r := "pattern:{ " + strings.Join(terms, " ") + " }"
+	if m.Within > 0 {
+		r += ", within:" + strconv.FormatInt(m.Within, 10)
+	}
+	return r
+}
+
+// Define returns the condition of the pattern variable. Return nil if the variable is not defined which means always true
+func (m *MatchRecognize) Define(name string) Expr {
+	for _, d := range m.Defines {
+		if d.Name == name {
+			return d.Condition
+		}
+	}
+	return nil
+}
+
+// PatternTerm is a pattern variable with its quantifier. Max is -1 if the quantifier is unbounded.
+type PatternTerm struct {
+	Name string
+	Min  int
+	Max  int
+}
+
+func (t *PatternTerm) String() string {
+	switch {
+	case t.Min == 1 && t.Max == 1:
+		return t.Name
+	case t.Min == 0 && t.Max == -1:
+		return t.Name + "*"
+	case t.Min == 1 && t.Max == -1:
+		return t.Name + "+"
+	case t.Min == 0 && t.Max == 1:
+		return t.Name + "?"
+	case t.Max == -1:
+		return t.Name + "{" + strconv.Itoa(t.Min) + ",}"
+	case t.Min == t.Max:
+		return t.Name + "{" + strconv.Itoa(t.Min) + "}"
+	default:
+		return t.Name + "{" + strconv.Itoa(t.Min) + "," + strconv.Itoa(t.Max) + "}"
+	}
+}
+
+// PatternDefine is the condition for a row to be mapped to the pattern variable
+type PatternDefine struct {
+	Name      string
+	Condition Expr
+}
+
 func (d SortFields) node() {}
 
 const (
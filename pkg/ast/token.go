@@ -82,6 +82,9 @@ const (
 	COLON     //:
 	SEMICOLON //;
 	COLSEP    //\007
+	LBRACE    // {
+	RBRACE    // }
+	QUESTION  // ?
 
 	// Keywords
 	SELECT
@@ -109,6 +112,7 @@ const (
 	END
 	OVER
 	PARTITION
+	MATCH_RECOGNIZE
 
 	TRUE
 	FALSE
@@ -163,6 +167,9 @@ var Tokens = []string{
 	SEMICOLON: ";",
 	COLON:     ":",
 	COLSEP:    "\007",
+	LBRACE:    "{",
+	RBRACE:    "}",
+	QUESTION:  "?",
 
 	SELECT:    "SELECT",
 	FROM:      "FROM",
@@ -187,6 +194,8 @@ var Tokens = []string{
 	OVER:      "OVER",
 	PARTITION: "PARTITION",
 
+	MATCH_RECOGNIZE: "MATCH_RECOGNIZE",
+
 	AND:        "AND",
 	OR:         "OR",
 	TRUE:       "TRUE",
@@ -218,6 +227,7 @@ const (
 	STREAMS    = "STREAMS"
 	TABLES     = "TABLES"
 	WITH       = "WITH"
+	DEFAULT    = "DEFAULT"
 
 	DATASOURCE        = "DATASOURCE"
 	KEY               = "KEY"
@@ -232,6 +242,9 @@ const (
 	SCHEMAID          = "SCHEMAID"
 	KIND              = "KIND"
 	DELIMITER         = "DELIMITER"
+	VALIDATION        = "VALIDATION"
+	ERROR_TOPIC       = "ERROR_TOPIC"
+	LABELS            = "LABELS"
 
 	XBIGINT   = "BIGINT"
 	XFLOAT    = "FLOAT"
@@ -257,6 +270,9 @@ var StreamTokens = map[string]struct{}{
 	SCHEMAID:          {},
 	KIND:              {},
 	DELIMITER:         {},
+	VALIDATION:        {},
+	ERROR_TOPIC:       {},
+	LABELS:            {},
 }
 
 var StreamDataTypes = map[string]DataType{
@@ -1,4 +1,4 @@
-// Copyright 2021-2023 EMQ Technologies Co., Ltd.
+// Copyright 2021-2024 EMQ Technologies Co., Ltd.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -36,12 +36,18 @@ func Walk(v Visitor, node Node) {
 		Walk(v, n.Fields)
 		Walk(v, n.Sources)
 		Walk(v, n.Joins)
+		if n.Unnest != nil {
+			Walk(v, n.Unnest)
+		}
 		Walk(v, n.Condition)
 		Walk(v, n.Dimensions)
 		Walk(v, n.Having)
 		Walk(v, n.SortFields)
 		Walk(v, n.Limit)
 
+	case *Unnest:
+		Walk(v, n.Expr)
+
 	case Fields:
 		for _, f := range n {
 			Walk(v, &f)
@@ -141,6 +147,9 @@ func Walk(v Visitor, node Node) {
 	case *ColFuncField:
 		Walk(v, n.Expr)
 
+	case *LambdaExpr:
+		Walk(v, n.Body)
+
 	case *ValueSetExpr:
 		for _, l := range n.LiteralExprs {
 			Walk(v, l)
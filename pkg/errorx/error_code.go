@@ -50,6 +50,36 @@ func IsIOError(err error) bool {
 	return false
 }
 
+// PartialIOError is a recoverable error when only some items of a batch fail to send
+type PartialIOError struct {
+	msg string
+	// Failed is the indexes of the failed items in the batch
+	Failed []int
+}
+
+func NewPartialIOErr(msg string, failed []int) error {
+	return &PartialIOError{
+		msg:    msg,
+		Failed: failed,
+	}
+}
+
+func (e *PartialIOError) Error() string {
+	return e.msg
+}
+
+func (e *PartialIOError) Code() ErrorCode {
+	return IOErr
+}
+
+// GetFailedItems returns the indexes of the failed items if the error is a PartialIOError
+func GetFailedItems(err error) ([]int, bool) {
+	if pe, ok := err.(*PartialIOError); ok {
+		return pe.Failed, true
+	}
+	return nil, false
+}
+
 func NewParserError(msg string) error {
 	return &Error{
 		code: ParserError,
@@ -38,3 +38,14 @@ func TestErrorResult(t *testing.T) {
 	assert.Equal(t, "not found", err.Error())
 	assert.Equal(t, NOT_FOUND, err.Code())
 }
+
+func TestPartialIOError(t *testing.T) {
+	err := NewPartialIOErr("partial failure", []int{1, 3})
+	assert.True(t, IsIOError(err))
+	assert.Equal(t, "partial failure", err.Error())
+	failed, ok := GetFailedItems(err)
+	assert.True(t, ok)
+	assert.Equal(t, []int{1, 3}, failed)
+	_, ok = GetFailedItems(NewIOErr("io error"))
+	assert.False(t, ok)
+}
@@ -21,6 +21,7 @@ import (
 const (
 	FormatBinary    = "binary"
 	FormatJson      = "json"
+	FormatFastJson  = "fastjson"
 	FormatProtobuf  = "protobuf"
 	FormatDelimited = "delimited"
 	FormatCustom    = "custom"
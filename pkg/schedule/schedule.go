@@ -16,6 +16,7 @@ package schedule
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/robfig/cron/v3"
@@ -26,7 +27,30 @@ import (
 
 const layout = "2006-01-02 15:04:05"
 
+// LoadTimezone loads the location of the IANA timezone name. The empty name returns nil which means the configured timezone.
+func LoadTimezone(tz string) (*time.Location, error) {
+	if tz == "" {
+		return nil, nil
+	}
+	return time.LoadLocation(tz)
+}
+
+// CronWithTimezone prefixes the cron expression with the timezone so that the cron is evaluated in that timezone.
+// The expression which already specifies its timezone is kept as is.
+func CronWithTimezone(cronExpr string, tz string) string {
+	if tz == "" || strings.HasPrefix(cronExpr, "TZ=") || strings.HasPrefix(cronExpr, "CRON_TZ=") {
+		return cronExpr
+	}
+	return "CRON_TZ=" + tz + " " + cronExpr
+}
+
 func IsInScheduleRanges(now time.Time, timeRanges []api.DatetimeRange) (bool, error) {
+	return IsInScheduleRangesIn(now, timeRanges, nil)
+}
+
+// IsInScheduleRangesIn checks whether now is in the ranges whose begin and end datetime strings are in the location.
+// Nil location means the configured timezone.
+func IsInScheduleRangesIn(now time.Time, timeRanges []api.DatetimeRange, loc *time.Location) (bool, error) {
 	if len(timeRanges) < 1 {
 		return true, nil
 	}
@@ -40,7 +64,7 @@ func IsInScheduleRanges(now time.Time, timeRanges []api.DatetimeRange) (bool, er
 				return true, nil
 			}
 		} else {
-			isIn, err := isInScheduleRange(now, tRange.Begin, tRange.End)
+			isIn, err := isInScheduleRange(now, tRange.Begin, tRange.End, loc)
 			if err != nil {
 				return false, err
 			}
@@ -52,8 +76,8 @@ func IsInScheduleRanges(now time.Time, timeRanges []api.DatetimeRange) (bool, er
 	return false, nil
 }
 
-func isInScheduleRange(now time.Time, start string, end string) (bool, error) {
-	return isInTimeRange(now, start, end)
+func isInScheduleRange(now time.Time, start string, end string, loc *time.Location) (bool, error) {
+	return isInTimeRange(now, start, end, loc)
 }
 
 func isInScheduleRangeByTS(now time.Time, startTS int64, endTS int64) (bool, error) {
@@ -73,12 +97,12 @@ func isInScheduleRangeByTS(now time.Time, startTS int64, endTS int64) (bool, err
 	return false, nil
 }
 
-func isInTimeRange(now time.Time, start string, end string) (bool, error) {
-	s, err := cast.InterfaceToTime(start, layout)
+func isInTimeRange(now time.Time, start string, end string, loc *time.Location) (bool, error) {
+	s, err := parseTime(start, loc)
 	if err != nil {
 		return false, err
 	}
-	e, err := cast.InterfaceToTime(end, layout)
+	e, err := parseTime(end, loc)
 	if err != nil {
 		return false, err
 	}
@@ -91,6 +115,12 @@ func isInTimeRange(now time.Time, start string, end string) (bool, error) {
 }
 
 func IsAfterTimeRanges(now time.Time, ranges []api.DatetimeRange) bool {
+	return IsAfterTimeRangesIn(now, ranges, nil)
+}
+
+// IsAfterTimeRangesIn checks whether now is after all the ranges whose datetime strings are in the location.
+// Nil location means the configured timezone.
+func IsAfterTimeRangesIn(now time.Time, ranges []api.DatetimeRange, loc *time.Location) bool {
 	if len(ranges) < 1 {
 		return false
 	}
@@ -101,7 +131,7 @@ func IsAfterTimeRanges(now time.Time, ranges []api.DatetimeRange) bool {
 				return false
 			}
 		} else {
-			isAfter, err := isAfterTimeRange(now, r.End)
+			isAfter, err := isAfterTimeRange(now, r.End, loc)
 			if err != nil || !isAfter {
 				return false
 			}
@@ -122,14 +152,21 @@ func isAfterTimeByTS(now time.Time, end int64) (bool, error) {
 	return isAfterTime(now, e), nil
 }
 
-func isAfterTimeRange(now time.Time, end string) (bool, error) {
-	e, err := cast.InterfaceToTime(end, layout)
+func isAfterTimeRange(now time.Time, end string, loc *time.Location) (bool, error) {
+	e, err := parseTime(end, loc)
 	if err != nil {
 		return false, err
 	}
 	return isAfterTime(now, e), nil
 }
 
+func parseTime(t string, loc *time.Location) (time.Time, error) {
+	if loc == nil {
+		return cast.InterfaceToTime(t, layout)
+	}
+	return time.ParseInLocation(layout, t, loc)
+}
+
 // IsInRunningSchedule checks whether the rule should be running, eg:
 // If the duration is 10min, and cron is "0 0 * * *", and the current time is 00:00:02
 // And the rule should be started immediately instead of checking it on the next day.
@@ -210,3 +210,48 @@ func TestValidateSchedule(t *testing.T) {
 		}
 	}
 }
+
+func TestCronWithTimezone(t *testing.T) {
+	require.Equal(t, "4 15 * * *", CronWithTimezone("4 15 * * *", ""))
+	require.Equal(t, "CRON_TZ=Asia/Shanghai 4 15 * * *", CronWithTimezone("4 15 * * *", "Asia/Shanghai"))
+	require.Equal(t, "TZ=UTC 4 15 * * *", CronWithTimezone("TZ=UTC 4 15 * * *", "Asia/Shanghai"))
+	// 15:04:02 in UTC is 23:04:02 in Shanghai
+	now, err := time.Parse(layout, "2006-01-02 15:04:02")
+	require.NoError(t, err)
+	isIn, _, err := IsInRunningSchedule(CronWithTimezone("4 23 * * *", "Asia/Shanghai"), now, 3*time.Second)
+	require.NoError(t, err)
+	require.True(t, isIn)
+	isIn, _, err = IsInRunningSchedule(CronWithTimezone("4 15 * * *", "Asia/Shanghai"), now, 3*time.Second)
+	require.NoError(t, err)
+	require.False(t, isIn)
+}
+
+func TestScheduleRangesInTimezone(t *testing.T) {
+	loc, err := LoadTimezone("")
+	require.NoError(t, err)
+	require.Nil(t, loc)
+	_, err = LoadTimezone("Mars/Base")
+	require.Error(t, err)
+	loc, err = LoadTimezone("Asia/Shanghai")
+	require.NoError(t, err)
+	// 15:04:01 in UTC is 23:04:01 in Shanghai
+	now := time.Date(2006, 1, 2, 15, 4, 1, 0, time.UTC)
+	ranges := []api.DatetimeRange{
+		{
+			Begin: "2006-01-02 23:00:00",
+			End:   "2006-01-02 23:10:00",
+		},
+	}
+	isIn, err := IsInScheduleRangesIn(now, ranges, loc)
+	require.NoError(t, err)
+	require.True(t, isIn)
+	require.False(t, IsAfterTimeRangesIn(now, ranges, loc))
+	utc, err := LoadTimezone("UTC")
+	require.NoError(t, err)
+	isIn, err = IsInScheduleRangesIn(now, ranges, utc)
+	require.NoError(t, err)
+	require.False(t, isIn)
+	require.False(t, IsAfterTimeRangesIn(now, ranges, utc))
+	_, err = IsInScheduleRangesIn(now, []api.DatetimeRange{{Begin: "bad", End: "bad"}}, loc)
+	require.Error(t, err)
+}
@@ -51,6 +51,16 @@ type Source interface {
 	Closable
 }
 
+type LookupSource interface {
+	// Open creates the connection to the external data source
+	Open(ctx StreamContext) error
+	// Configure Called during initialization. Configure the source with the data source(e.g. table name) and the properties read from the yaml
+	Configure(datasource string, props map[string]interface{}) error
+	// Lookup receive lookup values to construct the query and return query results
+	Lookup(ctx StreamContext, fields []string, keys []string, values []interface{}) ([]SourceTuple, error)
+	Closable
+}
+
 type Function interface {
 	// The argument is a list of xsql.Expr
 	Validate(args []interface{}) error
@@ -104,4 +114,11 @@ type StreamContext interface {
 type FunctionContext interface {
 	StreamContext
 	GetFuncId() int
+	// IncrCounter and the other state functions keep the state of the function instance.
+	// The states are saved by eKuiper along with the rule checkpoint and restored when the rule restarts.
+	IncrCounter(key string, amount int) error
+	GetCounter(key string) (int, error)
+	PutState(key string, value interface{}) error
+	GetState(key string) (interface{}, error)
+	DeleteState(key string) error
 }
@@ -148,6 +148,26 @@ func CreateFuncChannel(symbolName string) (DataInOutChannel, error) {
 	return &NanomsgRepChannel{sock: sock}, nil
 }
 
+func CreateLookupChannel(ctx api.StreamContext) (DataInOutChannel, error) {
+	var (
+		sock mangos.Socket
+		err  error
+	)
+	if sock, err = req.NewSocket(); err != nil {
+		return nil, fmt.Errorf("can't get new req socket: %s", err)
+	}
+	// The recv should not have timeout because it is event driven
+	setSockOptions(sock, map[string]interface{}{
+		mangos.OptionSendDeadline: 1000 * time.Millisecond,
+		mangos.OptionRetryTime:    0,
+	})
+	url := fmt.Sprintf("ipc:///tmp/lookup_%s_%s_%d.ipc", ctx.GetRuleId(), ctx.GetOpId(), ctx.GetInstanceId())
+	if err = sock.DialOptions(url, dialOptions); err != nil {
+		return nil, fmt.Errorf("can't dial on req socket: %s", err.Error())
+	}
+	return &NanomsgRepChannel{sock: sock}, nil
+}
+
 func CreateSinkChannel(ctx api.StreamContext) (DataInChannel, error) {
 	var (
 		sock mangos.Socket
@@ -23,6 +23,9 @@ import (
 type DefaultFuncContext struct {
 	api.StreamContext
 	funcId int
+	// states are loaded from eKuiper for each execution and sent back if dirty
+	states map[string]interface{}
+	dirty  bool
 }
 
 func NewDefaultFuncContext(ctx api.StreamContext, id int) *DefaultFuncContext {
@@ -36,6 +39,65 @@ func (c *DefaultFuncContext) GetFuncId() int {
 	return c.funcId
 }
 
+// ResetStates sets the states received from eKuiper before the execution
+func (c *DefaultFuncContext) ResetStates(states map[string]interface{}) {
+	if states == nil {
+		states = make(map[string]interface{})
+	}
+	c.states = states
+	c.dirty = false
+}
+
+// DirtyStates returns the states and whether they are changed during the execution
+func (c *DefaultFuncContext) DirtyStates() (map[string]interface{}, bool) {
+	return c.states, c.dirty
+}
+
+func (c *DefaultFuncContext) IncrCounter(key string, amount int) error {
+	v, err := c.GetCounter(key)
+	if err != nil {
+		return err
+	}
+	return c.PutState(key, v+amount)
+}
+
+func (c *DefaultFuncContext) GetCounter(key string) (int, error) {
+	v, ok := c.states[key]
+	if !ok || v == nil {
+		return 0, nil
+	}
+	// The numbers are decoded as float64 from json
+	switch n := v.(type) {
+	case int:
+		return n, nil
+	case float64:
+		return int(n), nil
+	default:
+		return 0, fmt.Errorf("state %s is not a counter but %v", key, v)
+	}
+}
+
+func (c *DefaultFuncContext) PutState(key string, value interface{}) error {
+	if c.states == nil {
+		c.states = make(map[string]interface{})
+	}
+	c.states[key] = value
+	c.dirty = true
+	return nil
+}
+
+func (c *DefaultFuncContext) GetState(key string) (interface{}, error) {
+	return c.states[key], nil
+}
+
+func (c *DefaultFuncContext) DeleteState(key string) error {
+	if _, ok := c.states[key]; ok {
+		delete(c.states, key)
+		c.dirty = true
+	}
+	return nil
+}
+
 func (c *DefaultFuncContext) convertKey(key string) string {
 	return fmt.Sprintf("$$func%d_%s", c.funcId, key)
 }
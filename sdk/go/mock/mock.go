@@ -128,16 +128,42 @@ func newMockContext(ruleId string, opId string) api.StreamContext {
 type mockFuncContext struct {
 	api.StreamContext
 	funcId int
+	states map[string]interface{}
 }
 
 func (fc *mockFuncContext) GetFuncId() int {
 	return fc.funcId
 }
 
+func (fc *mockFuncContext) IncrCounter(key string, amount int) error {
+	v, _ := fc.GetCounter(key)
+	return fc.PutState(key, v+amount)
+}
+
+func (fc *mockFuncContext) GetCounter(key string) (int, error) {
+	v, _ := fc.states[key].(int)
+	return v, nil
+}
+
+func (fc *mockFuncContext) PutState(key string, value interface{}) error {
+	fc.states[key] = value
+	return nil
+}
+
+func (fc *mockFuncContext) GetState(key string) (interface{}, error) {
+	return fc.states[key], nil
+}
+
+func (fc *mockFuncContext) DeleteState(key string) error {
+	delete(fc.states, key)
+	return nil
+}
+
 func newMockFuncContext(ctx api.StreamContext, id int) api.FunctionContext {
 	return &mockFuncContext{
 		StreamContext: ctx,
 		funcId:        id,
+		states:        make(map[string]interface{}),
 	}
 }
 
@@ -0,0 +1,50 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mock
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lf-edge/ekuiper/sdk/go/api"
+)
+
+type LookupTest struct {
+	Fields []string
+	Keys   []string
+	Values []interface{}
+	Result []api.SourceTuple
+}
+
+func TestLookup(r api.LookupSource, tests []LookupTest, t *testing.T) {
+	ctx := newMockContext("rule1", "op1")
+	err := r.Open(ctx)
+	if err != nil {
+		t.Errorf("open error: %v", err)
+		return
+	}
+	for i, tt := range tests {
+		result, err := r.Lookup(ctx, tt.Fields, tt.Keys, tt.Values)
+		if err != nil {
+			t.Errorf("%d lookup error: %v", i, err)
+		} else if !reflect.DeepEqual(tt.Result, result) {
+			t.Errorf("%d result mismatch:\n\nexp=%#v\n\ngot=%#v\n\n", i, tt.Result, result)
+		}
+	}
+	err = r.Close(ctx)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+}
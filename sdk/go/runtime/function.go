@@ -82,6 +82,9 @@ func (s *funcRuntime) run() {
 				return encodeReply(false, err.Error())
 			}
 			r, b := s.s.Exec(farg, fctx)
+			if states, dirty := fctx.DirtyStates(); dirty {
+				return encodeStatesReply(b, r, states)
+			}
 			return encodeReply(b, r)
 		case "IsAggregate":
 			result := s.s.IsAggregate()
@@ -117,7 +120,16 @@ func encodeReply(state bool, arg interface{}) []byte {
 	return r
 }
 
-func parseFuncContextArgs(args []interface{}) ([]interface{}, api.FunctionContext, error) {
+func encodeStatesReply(state bool, arg interface{}, states map[string]interface{}) []byte {
+	r, _ := json.Marshal(FuncReply{
+		State:  state,
+		Result: arg,
+		States: states,
+	})
+	return r
+}
+
+func parseFuncContextArgs(args []interface{}) ([]interface{}, *context.DefaultFuncContext, error) {
 	if len(args) < 1 {
 		return nil, nil, fmt.Errorf("exec function context not found")
 	}
@@ -138,11 +150,14 @@ func parseFuncContextArgs(args []interface{}) ([]interface{}, api.FunctionContex
 	}
 	key := fmt.Sprintf("%s_%s_%d_%d", m.RuleId, m.OpId, m.InstanceId, m.FuncId)
 	if c, ok := exeFuncCtxMap.Load(key); ok {
-		return fargs, c.(api.FunctionContext), nil
+		fctx := c.(*context.DefaultFuncContext)
+		fctx.ResetStates(m.States)
+		return fargs, fctx, nil
 	} else {
 		contextLogger := context.LogEntry("rule", m.RuleId)
 		ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger).WithMeta(m.RuleId, m.OpId)
 		fctx := context.NewDefaultFuncContext(ctx, m.FuncId)
+		fctx.ResetStates(m.States)
 		exeFuncCtxMap.Store(key, fctx)
 		return fargs, fctx, nil
 	}
@@ -55,6 +55,7 @@ type (
 	NewSourceFunc   func() api.Source
 	NewFunctionFunc func() api.Function
 	NewSinkFunc     func() api.Sink
+	NewLookupFunc   func() api.LookupSource
 )
 
 // PluginConfig construct once and then read only
@@ -63,6 +64,8 @@ type PluginConfig struct {
 	Sources   map[string]NewSourceFunc
 	Functions map[string]NewFunctionFunc
 	Sinks     map[string]NewSinkFunc
+	// LookupSources are the lookup table sources whose names are usually the same as the scan sources
+	LookupSources map[string]NewLookupFunc
 }
 
 func (conf *PluginConfig) Get(pluginType string, symbolName string) (builderFunc interface{}) {
@@ -79,6 +82,10 @@ func (conf *PluginConfig) Get(pluginType string, symbolName string) (builderFunc
 		if f, ok := conf.Sinks[symbolName]; ok {
 			return f
 		}
+	case TYPE_LOOKUP:
+		if f, ok := conf.LookupSources[symbolName]; ok {
+			return f
+		}
 	}
 	return nil
 }
@@ -134,6 +141,16 @@ func Start(args []string, conf *PluginConfig) {
 					regKey := fmt.Sprintf("%s_%s_%d_%s", ctrl.Meta.RuleId, ctrl.Meta.OpId, ctrl.Meta.InstanceId, ctrl.SymbolName)
 					reg.Set(regKey, sr)
 					logger.Infof("running sink %s", ctrl.SymbolName)
+				case TYPE_LOOKUP:
+					lf := f.(NewLookupFunc)
+					lr, err := setupLookupRuntime(ctrl, lf())
+					if err != nil {
+						return []byte(err.Error())
+					}
+					go lr.run()
+					regKey := fmt.Sprintf("%s_%s_%d_%s", ctrl.Meta.RuleId, ctrl.Meta.OpId, ctrl.Meta.InstanceId, ctrl.SymbolName)
+					reg.Set(regKey, lr)
+					logger.Infof("running lookup source %s", ctrl.SymbolName)
 				case TYPE_FUNC:
 					regKey := fmt.Sprintf("func_%s", ctrl.SymbolName)
 					_, ok := reg.Get(regKey)
@@ -168,6 +185,8 @@ func Start(args []string, conf *PluginConfig) {
 					}
 				}
 				return []byte(REPLY_OK)
+			case CMD_PING:
+				return []byte(REPLY_OK)
 			default:
 				return []byte(fmt.Sprintf("invalid command received: %s", c.Cmd))
 			}
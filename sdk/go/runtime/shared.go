@@ -18,6 +18,7 @@ const (
 	TYPE_SOURCE = "source"
 	TYPE_SINK   = "sink"
 	TYPE_FUNC   = "func"
+	TYPE_LOOKUP = "lookup"
 )
 
 type Meta struct {
@@ -28,7 +29,8 @@ type Meta struct {
 
 type FuncMeta struct {
 	Meta
-	FuncId int `json:"funcId"`
+	FuncId int                    `json:"funcId"`
+	States map[string]interface{} `json:"states,omitempty"`
 }
 
 type Control struct {
@@ -47,6 +49,7 @@ type Command struct {
 const (
 	CMD_START = "start"
 	CMD_STOP  = "stop"
+	CMD_PING  = "ping"
 )
 
 const (
@@ -65,4 +68,12 @@ type FuncData struct {
 type FuncReply struct {
 	State  bool        `json:"state"`
 	Result interface{} `json:"result"`
+	// States is only set if the function states are changed. An empty map means all states are deleted
+	States map[string]interface{} `json:"states"`
+}
+
+type LookupData struct {
+	Fields []string      `json:"fields"`
+	Keys   []string      `json:"keys"`
+	Values []interface{} `json:"values"`
 }
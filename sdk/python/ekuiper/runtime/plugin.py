@@ -89,6 +89,8 @@ def command_reply(req: bytes) -> bytes:
         logging.debug("receive command {}".format(cmd))
         ctrl = json.loads(cmd['arg'])
         logging.debug(ctrl)
+        if cmd['cmd'] == shared.CMD_PING:
+            return b'ok'
         if cmd['cmd'] == shared.CMD_START:
             f = conf.get(ctrl['pluginType'], ctrl['symbolName'])
             if f is None:
@@ -14,6 +14,7 @@
 
 CMD_START = "start"
 CMD_STOP = "stop"
+CMD_PING = "ping"
 
 TYPE_SOURCE = "source"
 TYPE_SINK = "sink"